@@ -0,0 +1,4 @@
+// Package jsonpatch applies to JSON documents the JSON Merge Patches of RFC 7386, which give
+// the new values of the members to change, and the JSON Patches of RFC 6902, which list the
+// operations to perform on the values located by JSON pointers
+package jsonpatch
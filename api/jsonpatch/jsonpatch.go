@@ -0,0 +1,345 @@
+package jsonpatch
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// The media types of the patches
+const (
+	MergePatchType = "application/merge-patch+json"
+	JSONPatchType  = "application/json-patch+json"
+)
+
+// ErrTestFailed is returned when a test operation of a JSON Patch doesn't match the document
+var ErrTestFailed = errors.New("jsonpatch: test failed")
+
+// Operation is an operation of a JSON Patch: add, remove, replace, move, copy or test
+type Operation struct {
+	Op string `json:"op"`
+	// Path is the JSON pointer of the value to operate on
+	Path string `json:"path"`
+	// From is the JSON pointer of the value to move or copy
+	From string `json:"from,omitempty"`
+	// Value is the value to add, replace or test
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// decode decodes a JSON value, keeping the numbers as they are
+func decode(data []byte) (any, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var val any
+	if err := dec.Decode(&val); err != nil {
+		return nil, err
+	}
+	if dec.More() {
+		return nil, errors.New("trailing data")
+	}
+	return val, nil
+}
+
+// MergePatch applies the merge patch to the document, and returns the patched document.
+// The members of the patch objects replace the ones of the document, recursively; the null
+// members remove them. A patch which isn't an object replaces the whole document.
+func MergePatch(doc, patch []byte) ([]byte, error) {
+	target, err := decode(doc)
+	if err != nil {
+		return nil, fmt.Errorf("jsonpatch: malformed document: %w", err)
+	}
+	p, err := decode(patch)
+	if err != nil {
+		return nil, fmt.Errorf("jsonpatch: malformed merge patch: %w", err)
+	}
+	return json.Marshal(merge(target, p))
+}
+
+func merge(target, patch any) any {
+	members, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+	obj, ok := target.(map[string]any)
+	if !ok {
+		obj = make(map[string]any)
+	}
+	for name, val := range members {
+		if val == nil {
+			delete(obj, name)
+		} else {
+			obj[name] = merge(obj[name], val)
+		}
+	}
+	return obj
+}
+
+// Apply performs the operations of the JSON Patch on the document in turn, and returns the patched
+// document. The patch is applied entirely or not at all: the first failing operation fails it.
+// Fails with ErrTestFailed if a test operation doesn't match.
+func Apply(doc []byte, ops []Operation) ([]byte, error) {
+	target, err := decode(doc)
+	if err != nil {
+		return nil, fmt.Errorf("jsonpatch: malformed document: %w", err)
+	}
+	for i, op := range ops {
+		if target, err = apply(target, op); err != nil {
+			return nil, fmt.Errorf("jsonpatch: operation %d (%s %q): %w", i, op.Op, op.Path, err)
+		}
+	}
+	return json.Marshal(target)
+}
+
+func apply(doc any, op Operation) (any, error) {
+	path, err := parsePointer(op.Path)
+	if err != nil {
+		return nil, err
+	}
+	var val any
+	switch op.Op {
+	case "add", "replace", "test":
+		if op.Value == nil {
+			return nil, errors.New("missing value")
+		}
+		if val, err = decode(op.Value); err != nil {
+			return nil, fmt.Errorf("malformed value: %w", err)
+		}
+	case "move", "copy":
+		from, err := parsePointer(op.From)
+		if err != nil {
+			return nil, err
+		}
+		if val, err = get(doc, from); err != nil {
+			return nil, err
+		}
+		if op.Op == "copy" {
+			val = clone(val)
+			break
+		}
+		if len(path) > len(from) && reflect.DeepEqual(path[:len(from)], from) {
+			return nil, errors.New("can't move a value into itself")
+		}
+		if doc, err = remove(doc, from); err != nil {
+			return nil, err
+		}
+	}
+
+	switch op.Op {
+	case "add", "move", "copy":
+		return add(doc, path, val)
+	case "remove":
+		return remove(doc, path)
+	case "replace":
+		if _, err := get(doc, path); err != nil {
+			return nil, err
+		}
+		return set(doc, path, val)
+	case "test":
+		cur, err := get(doc, path)
+		if err != nil {
+			return nil, err
+		}
+		if !equal(cur, val) {
+			return nil, ErrTestFailed
+		}
+		return doc, nil
+	default:
+		return nil, fmt.Errorf("unknown operation %q", op.Op)
+	}
+}
+
+// parsePointer splits the JSON pointer in its unescaped reference tokens
+func parsePointer(ptr string) ([]string, error) {
+	if ptr == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(ptr, "/") {
+		return nil, fmt.Errorf("invalid JSON pointer %q", ptr)
+	}
+	tokens := strings.Split(ptr[1:], "/")
+	for i, tok := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(tok, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+// index parses the reference token of an element of an array of size n. The "-" token,
+// past the last element, is accepted if end is true.
+func index(tok string, n int, end bool) (int, error) {
+	if tok == "-" && end {
+		return n, nil
+	}
+	i, err := strconv.Atoi(tok)
+	if err != nil || i < 0 || (tok != "0" && strings.HasPrefix(tok, "0")) {
+		return 0, fmt.Errorf("invalid array index %q", tok)
+	}
+	if i > n || (i == n && !end) {
+		return 0, fmt.Errorf("array index %d out of bounds", i)
+	}
+	return i, nil
+}
+
+// get returns the value at the path
+func get(doc any, path []string) (any, error) {
+	for _, tok := range path {
+		switch cur := doc.(type) {
+		case map[string]any:
+			val, ok := cur[tok]
+			if !ok {
+				return nil, fmt.Errorf("member %q not found", tok)
+			}
+			doc = val
+		case []any:
+			i, err := index(tok, len(cur), false)
+			if err != nil {
+				return nil, err
+			}
+			doc = cur[i]
+		default:
+			return nil, fmt.Errorf("can't reference %q in a scalar value", tok)
+		}
+	}
+	return doc, nil
+}
+
+// add adds the value at the path, replacing the member of an object or inserting the element of
+// an array, and returns the patched document
+func add(doc any, path []string, val any) (any, error) {
+	if len(path) == 0 {
+		return val, nil
+	}
+	parent, err := get(doc, path[:len(path)-1])
+	if err != nil {
+		return nil, err
+	}
+	tok := path[len(path)-1]
+	switch cur := parent.(type) {
+	case map[string]any:
+		cur[tok] = val
+		return doc, nil
+	case []any:
+		i, err := index(tok, len(cur), true)
+		if err != nil {
+			return nil, err
+		}
+		cur = append(cur[:i:i], append([]any{val}, cur[i:]...)...)
+		return set(doc, path[:len(path)-1], cur)
+	default:
+		return nil, fmt.Errorf("can't add %q to a scalar value", tok)
+	}
+}
+
+// set replaces the existing value at the path, and returns the patched document
+func set(doc any, path []string, val any) (any, error) {
+	if len(path) == 0 {
+		return val, nil
+	}
+	parent, err := get(doc, path[:len(path)-1])
+	if err != nil {
+		return nil, err
+	}
+	tok := path[len(path)-1]
+	switch cur := parent.(type) {
+	case map[string]any:
+		cur[tok] = val
+	case []any:
+		i, err := index(tok, len(cur), false)
+		if err != nil {
+			return nil, err
+		}
+		cur[i] = val
+	}
+	return doc, nil
+}
+
+// remove removes the value at the path, which must exist, and returns the patched document
+func remove(doc any, path []string) (any, error) {
+	if len(path) == 0 {
+		return nil, errors.New("can't remove the whole document")
+	}
+	parent, err := get(doc, path[:len(path)-1])
+	if err != nil {
+		return nil, err
+	}
+	tok := path[len(path)-1]
+	switch cur := parent.(type) {
+	case map[string]any:
+		if _, ok := cur[tok]; !ok {
+			return nil, fmt.Errorf("member %q not found", tok)
+		}
+		delete(cur, tok)
+		return doc, nil
+	case []any:
+		i, err := index(tok, len(cur), false)
+		if err != nil {
+			return nil, err
+		}
+		cur = append(cur[:i:i], cur[i+1:]...)
+		return set(doc, path[:len(path)-1], cur)
+	default:
+		return nil, fmt.Errorf("can't remove %q from a scalar value", tok)
+	}
+}
+
+// clone returns a deep copy of the decoded JSON value
+func clone(val any) any {
+	switch val := val.(type) {
+	case map[string]any:
+		res := make(map[string]any, len(val))
+		for name, member := range val {
+			res[name] = clone(member)
+		}
+		return res
+	case []any:
+		res := make([]any, len(val))
+		for i, elem := range val {
+			res[i] = clone(elem)
+		}
+		return res
+	default:
+		return val
+	}
+}
+
+// equal compares the decoded JSON values, the numbers by value
+func equal(a, b any) bool {
+	switch a := a.(type) {
+	case json.Number:
+		b, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		fa, erra := a.Float64()
+		fb, errb := b.Float64()
+		return erra == nil && errb == nil && fa == fb
+	case map[string]any:
+		b, ok := b.(map[string]any)
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for name, val := range a {
+			other, ok := b[name]
+			if !ok || !equal(val, other) {
+				return false
+			}
+		}
+		return true
+	case []any:
+		b, ok := b.([]any)
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if !equal(a[i], b[i]) {
+				return false
+			}
+		}
+		return true
+	default:
+		return a == b
+	}
+}
@@ -0,0 +1,3 @@
+// Package openapi generates the OpenAPI 3 document describing the API out of its routes and
+// of the Go types of its payloads, and validates the request bodies against it
+package openapi
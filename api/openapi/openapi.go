@@ -0,0 +1,208 @@
+package openapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Version is the version of the OpenAPI specification the documents follow
+const Version = "3.0.3"
+
+// Schema is the subset of the OpenAPI schema objects describing the Go types
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+}
+
+// Info describes the API
+type Info struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+// Parameter describes a parameter of an operation
+type Parameter struct {
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required"`
+	Schema   *Schema `json:"schema"`
+}
+
+// MediaType describes the content of a request or response body
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// RequestBody describes the request body of an operation
+type RequestBody struct {
+	Content map[string]MediaType `json:"content"`
+}
+
+// Response describes a response of an operation
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+// Operation describes an operation on a path
+type Operation struct {
+	OperationID string              `json:"operationId"`
+	Parameters  []Parameter         `json:"parameters,omitempty"`
+	RequestBody *RequestBody        `json:"requestBody,omitempty"`
+	Responses   map[string]Response `json:"responses"`
+}
+
+// Components holds the schemas of the named types, referenced by the other schemas
+type Components struct {
+	Schemas map[string]*Schema `json:"schemas"`
+}
+
+// Document is an OpenAPI 3 document. The paths map each path to the operations on it by method, lowercase.
+type Document struct {
+	OpenAPI    string                           `json:"openapi"`
+	Info       Info                             `json:"info"`
+	Paths      map[string]map[string]*Operation `json:"paths"`
+	Components Components                       `json:"components"`
+	// response is the schema of the responses of all the operations
+	response *Schema
+}
+
+// pathParamRe matches the parameters in the path templates, e.g. {todoID}
+var pathParamRe = regexp.MustCompile(`{([^}]+)}`)
+
+const contentType = "application/json"
+
+// New creates the document of the API, whose operations all respond with a JSON encoded response
+// of the given type
+func New(info Info, response reflect.Type) *Document {
+	doc := &Document{
+		OpenAPI:    Version,
+		Info:       info,
+		Paths:      make(map[string]map[string]*Operation),
+		Components: Components{Schemas: make(map[string]*Schema)},
+	}
+	doc.response = doc.SchemaOf(response)
+	return doc
+}
+
+// AddOperation describes the operation with the given method on the path template, like "/todos/{todoID}",
+// whose request body is JSON encoded as the given type. The nil type means no body.
+func (doc *Document) AddOperation(method, path, id string, body reflect.Type) *Operation {
+	op := &Operation{
+		OperationID: id,
+		Responses: map[string]Response{
+			"default": {
+				Description: "the outcome of the operation",
+				Content:     map[string]MediaType{contentType: {Schema: doc.response}},
+			},
+		},
+	}
+	for _, match := range pathParamRe.FindAllStringSubmatch(path, -1) {
+		op.Parameters = append(op.Parameters, Parameter{
+			Name:     match[1],
+			In:       "path",
+			Required: true,
+			Schema:   &Schema{Type: "string"},
+		})
+	}
+	if body != nil {
+		op.RequestBody = &RequestBody{
+			Content: map[string]MediaType{contentType: {Schema: doc.SchemaOf(body)}},
+		}
+	}
+	if doc.Paths[path] == nil {
+		doc.Paths[path] = make(map[string]*Operation)
+	}
+	doc.Paths[path][strings.ToLower(method)] = op
+	return op
+}
+
+var (
+	timeType = reflect.TypeOf(time.Time{})
+	rawType  = reflect.TypeOf(json.RawMessage{})
+)
+
+// SchemaOf returns the schema of the values of the type as encoded by encoding/json.
+// The named structs are added to the components, and referenced.
+func (doc *Document) SchemaOf(t reflect.Type) *Schema {
+	switch t {
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case rawType:
+		return &Schema{}
+	}
+	switch t.Kind() {
+	case reflect.Pointer:
+		sc := *doc.SchemaOf(t.Elem())
+		if sc.Ref != "" {
+			// the siblings of the references are ignored
+			return &sc
+		}
+		sc.Nullable = true
+		return &sc
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Nullable: t.Kind() == reflect.Slice, Items: doc.SchemaOf(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", Nullable: true, AdditionalProperties: doc.SchemaOf(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return doc.structSchema(t)
+		}
+		if _, ok := doc.Components.Schemas[t.Name()]; !ok {
+			// registered first, for the recursive types
+			doc.Components.Schemas[t.Name()] = &Schema{}
+			*doc.Components.Schemas[t.Name()] = *doc.structSchema(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + t.Name()}
+	default:
+		// anything goes
+		return &Schema{}
+	}
+}
+
+// structSchema returns the schema of the struct, with the exported fields as properties
+func (doc *Document) structSchema(t reflect.Type) *Schema {
+	sc := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	for _, field := range reflect.VisibleFields(t) {
+		if !field.IsExported() || field.Anonymous {
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		sc.Properties[name] = doc.SchemaOf(field.Type)
+	}
+	return sc
+}
+
+// resolve returns the schema the given one references, if it does
+func (doc *Document) resolve(sc *Schema) *Schema {
+	for sc.Ref != "" {
+		sc = doc.Components.Schemas[strings.TrimPrefix(sc.Ref, "#/components/schemas/")]
+	}
+	return sc
+}
@@ -0,0 +1,90 @@
+package openapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testNote struct {
+	Text    string            `json:"text"`
+	Pinned  bool              `json:"pinned,omitempty"`
+	Stars   int               `json:"stars,omitempty"`
+	Due     *time.Time        `json:"due,omitempty"`
+	Tags    []string          `json:"tags,omitempty"`
+	Attrs   map[string]string `json:"attrs,omitempty"`
+	Replies []testNote        `json:"replies,omitempty"`
+	Raw     json.RawMessage   `json:"raw,omitempty"`
+	Ignored string            `json:"-"`
+	hidden  string
+}
+
+type testResponse struct {
+	OK bool `json:"ok"`
+}
+
+func TestSchemaOf(t *testing.T) {
+	doc := New(Info{Title: "test", Version: "v1"}, reflect.TypeOf(testResponse{}))
+	op := doc.AddOperation("PUT", "/notes/{noteID}", "note.update", reflect.TypeOf(testNote{}))
+
+	assert.Equal(t, []Parameter{{Name: "noteID", In: "path", Required: true, Schema: &Schema{Type: "string"}}}, op.Parameters)
+	assert.Same(t, op, doc.Paths["/notes/{noteID}"]["put"])
+	assert.Equal(t, &Schema{Ref: "#/components/schemas/testNote"}, op.RequestBody.Content["application/json"].Schema)
+	assert.Equal(t, &Schema{Ref: "#/components/schemas/testResponse"}, op.Responses["default"].Content["application/json"].Schema)
+
+	note := doc.Components.Schemas["testNote"]
+	require.NotNil(t, note)
+	assert.Equal(t, map[string]*Schema{
+		"text":    {Type: "string"},
+		"pinned":  {Type: "boolean"},
+		"stars":   {Type: "integer"},
+		"due":     {Type: "string", Format: "date-time", Nullable: true},
+		"tags":    {Type: "array", Nullable: true, Items: &Schema{Type: "string"}},
+		"attrs":   {Type: "object", Nullable: true, AdditionalProperties: &Schema{Type: "string"}},
+		"replies": {Type: "array", Nullable: true, Items: &Schema{Ref: "#/components/schemas/testNote"}},
+		"raw":     {},
+	}, note.Properties)
+
+	data, err := json.Marshal(doc)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"openapi":"3.0.3"`)
+}
+
+func TestValidateBody(t *testing.T) {
+	doc := New(Info{Title: "test", Version: "v1"}, reflect.TypeOf(testResponse{}))
+	op := doc.AddOperation("PUT", "/notes/{noteID}", "note.update", reflect.TypeOf(testNote{}))
+
+	for _, body := range []string{
+		`{"text":"foo"}`,
+		`{"text":"foo","pinned":true,"stars":3,"due":"2024-11-11T18:00:00Z","tags":["a"],"attrs":{"k":"v"}}`,
+		`{"text":"foo","due":null,"tags":null,"replies":[{"text":"bar"}],"raw":[1,"x"]}`,
+		// the unknown fields are ignored
+		`{"text":"foo","other":1}`,
+	} {
+		assert.NoError(t, doc.ValidateBody(op, []byte(body)), body)
+	}
+
+	err := doc.ValidateBody(op, []byte(`{"text":3,"stars":1.5,"due":"tomorrow","tags":[1],"attrs":{"k/v":false},"replies":[{"pinned":"yes"}]}`))
+	var invalid ValidationError
+	require.ErrorAs(t, err, &invalid)
+	assert.Equal(t, []FieldError{
+		{Field: "/attrs/k~1v", Text: "must be a string"},
+		{Field: "/due", Text: "must be a date-time, e.g. 2024-11-11T18:00:00Z"},
+		{Field: "/replies/0/pinned", Text: "must be a boolean"},
+		{Field: "/stars", Text: "must be an integer"},
+		{Field: "/tags/0", Text: "must be a string"},
+		{Field: "/text", Text: "must be a string"},
+	}, invalid.Fields)
+
+	for _, body := range []string{`{"text":`, `{} {}`, `[]`, `{"text":null}`} {
+		assert.ErrorAs(t, doc.ValidateBody(op, []byte(body)), &invalid, body)
+	}
+
+	// the operations without body accept anything
+	op = doc.AddOperation("GET", "/notes", "note.index", nil)
+	assert.NoError(t, doc.ValidateBody(op, []byte(`garbage`)))
+}
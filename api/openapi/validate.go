@@ -0,0 +1,137 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FieldError describes why a value of the request body is invalid
+type FieldError struct {
+	// Field is the JSON pointer of the invalid value, e.g. "/checklist/0/text". Empty for the whole body.
+	Field string
+	Text  string
+}
+
+func (e FieldError) Error() string {
+	if e.Field == "" {
+		return e.Text
+	}
+	return e.Field + ": " + e.Text
+}
+
+// ValidationError is returned when a request body doesn't match the schema of the operation
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func (e ValidationError) Error() string {
+	msgs := make([]string, 0, len(e.Fields))
+	for _, fe := range e.Fields {
+		msgs = append(msgs, fe.Error())
+	}
+	return "invalid request body: " + strings.Join(msgs, "; ")
+}
+
+// ValidateBody checks the JSON encoded request body of the operation against its schema.
+// Fails with ValidationError if the body doesn't match. The operations without body accept anything.
+func (doc *Document) ValidateBody(op *Operation, body []byte) error {
+	if op.RequestBody == nil {
+		return nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var val any
+	if err := dec.Decode(&val); err != nil {
+		return ValidationError{Fields: []FieldError{{Text: fmt.Sprintf("malformed JSON: %v", err)}}}
+	}
+	if dec.More() {
+		return ValidationError{Fields: []FieldError{{Text: "malformed JSON: trailing data"}}}
+	}
+	var errs []FieldError
+	doc.validate(op.RequestBody.Content[contentType].Schema, "", val, &errs)
+	sort.Slice(errs, func(i, j int) bool {
+		return errs[i].Field < errs[j].Field
+	})
+	if len(errs) > 0 {
+		return ValidationError{Fields: errs}
+	}
+	return nil
+}
+
+// validate appends to errs the problems of the decoded value at the pointer against the schema
+func (doc *Document) validate(sc *Schema, pointer string, val any, errs *[]FieldError) {
+	sc = doc.resolve(sc)
+	fail := func(format string, args ...any) {
+		*errs = append(*errs, FieldError{Field: pointer, Text: fmt.Sprintf(format, args...)})
+	}
+	if sc.Type == "" {
+		return
+	}
+	if val == nil {
+		if !sc.Nullable {
+			fail("must not be null")
+		}
+		return
+	}
+	switch sc.Type {
+	case "boolean":
+		if _, ok := val.(bool); !ok {
+			fail("must be a boolean")
+		}
+	case "integer":
+		if n, ok := val.(json.Number); !ok {
+			fail("must be an integer")
+		} else if _, err := strconv.ParseInt(string(n), 10, 64); err != nil {
+			fail("must be an integer")
+		}
+	case "number":
+		if _, ok := val.(json.Number); !ok {
+			fail("must be a number")
+		}
+	case "string":
+		s, ok := val.(string)
+		if !ok {
+			fail("must be a string")
+			return
+		}
+		if sc.Format == "date-time" {
+			if _, err := time.Parse(time.RFC3339, s); err != nil {
+				fail("must be a date-time, e.g. 2024-11-11T18:00:00Z")
+			}
+		}
+	case "array":
+		elems, ok := val.([]any)
+		if !ok {
+			fail("must be an array")
+			return
+		}
+		for i, elem := range elems {
+			doc.validate(sc.Items, pointer+"/"+strconv.Itoa(i), elem, errs)
+		}
+	case "object":
+		fields, ok := val.(map[string]any)
+		if !ok {
+			fail("must be an object")
+			return
+		}
+		for name, field := range fields {
+			fieldSc, ok := sc.Properties[name]
+			if !ok {
+				fieldSc = sc.AdditionalProperties
+			}
+			if fieldSc != nil {
+				doc.validate(fieldSc, pointer+"/"+escapePointer(name), field, errs)
+			}
+		}
+	}
+}
+
+// escapePointer escapes the name as a reference token of a JSON pointer
+func escapePointer(name string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(name)
+}
@@ -31,12 +31,452 @@ type Todo struct {
 	Title string `json:"title"`
 	// Assignee is the identifier of the agent working on the Todo
 	Assignee string `json:"assignee,omitempty"`
+	// Owner is the name of the user the todo belongs to, if any
+	Owner string `json:"owner,omitempty"`
 	// Description is a longer description of the todo
 	Description string `json:"description,omitempty"`
 	// Status is the current processing status of the todo
 	Status Status `json:"status"`
 	// LastUpdateTime records the last time a todo was modified in any way in the system
 	LastUpdateTime time.Time `json:"updated"`
+	// Due is when the todo is expected to be completed, if set
+	Due *time.Time `json:"due,omitempty"`
+	// Remind is when to remind the assignee of the todo, if set
+	Remind *time.Time `json:"remind,omitempty"`
+	// Recur is the recurrence rule of the todo, if it recurs: e.g. "weekly", "FREQ=MONTHLY;BYMONTHDAY=-1" or "0 9 * * 1-5"
+	Recur string `json:"recur,omitempty"`
+	// Series is the ID of the first occurrence of a recurring todo, shared by all of its occurrences
+	Series ID `json:"series,omitempty"`
+	// BlockedBy are the IDs of the todos to complete before this one
+	BlockedBy []ID `json:"blocked_by,omitempty"`
+	// Tags are the labels attached to the todo
+	Tags []string `json:"tags,omitempty"`
+	// Contexts are where, or with what, the todo can be done, like home or phone, without their @
+	Contexts []string `json:"contexts,omitempty"`
+	// Fields are the values of the custom fields of the todo, by name, as its list defines them
+	Fields map[string]string `json:"fields,omitempty"`
+	// Links are the links of the todo to other todos and to URLs
+	Links []Link `json:"links,omitempty"`
+	// Priority is the priority of the todo, from "P0" (urgent) to "P3" (low), if set
+	Priority string `json:"priority,omitempty"`
+	// Time are the time entries tracked working on the todo, oldest first
+	Time []TimeEntry `json:"time,omitempty"`
+	// Pomodoros is the number of pomodoros completed on the todo
+	Pomodoros int `json:"pomodoros,omitempty"`
+	// Comments is the number of comments on the todo
+	Comments int `json:"comments,omitempty"`
+	// Checklist are the entries of the checklist of the todo
+	Checklist []CheckItem `json:"checklist,omitempty"`
+	// ChecklistSummary tells how many entries of the checklist are done out of all of them, e.g. "3/7"
+	ChecklistSummary string `json:"checklist_summary,omitempty"`
+	// Archived tells whether the todo is archived, i.e. hidden from the default listings
+	Archived bool `json:"archived,omitempty"`
+	// Rank is the place of the todo in the manual order of its list, which sort=rank lists the
+	// todos in. Set by the server, empty if the todo was never moved.
+	Rank string `json:"rank,omitempty"`
+}
+
+// TodoPatch describes the changes to the fields of a todo: the missing ones are left as they are
+type TodoPatch struct {
+	Title       *string `json:"title,omitempty"`
+	Description *string `json:"description,omitempty"`
+	Assignee    *string `json:"assignee,omitempty"`
+	Status      *Status `json:"status,omitempty"`
+	// Owner hands the todo over to another user
+	Owner *string `json:"owner,omitempty"`
+}
+
+// Tag describes a label which can be attached to the todos
+type Tag struct {
+	// Name is the label attached to the todos
+	Name string `json:"name"`
+	// Color is the color to show the tag with, as "#rrggbb". Empty if not set.
+	Color string `json:"color,omitempty"`
+}
+
+// Filter describes a search query saved under a name, listing the todos matching it
+type Filter struct {
+	Name string `json:"name"`
+	// Query is the search query, e.g. `status:open due<"next monday"`
+	Query string `json:"query"`
+	// Owner is the user who saved the filter. Set by the server.
+	Owner string `json:"owner,omitempty"`
+	// Shared tells whether the other users see the filter as well
+	Shared bool `json:"shared,omitempty"`
+}
+
+// Board groups the todos in columns by the value of a field, kanban style
+type Board struct {
+	// By is the field the todos are grouped by, "status" or "priority"
+	By      string        `json:"by"`
+	Columns []BoardColumn `json:"columns"`
+}
+
+// BoardColumn are the todos of a board with the same value of its field, in their manual order
+type BoardColumn struct {
+	// Name is the value of the field, e.g. "pending", or "P1" for the priorities
+	Name  string `json:"name"`
+	Items []Item `json:"items"`
+}
+
+// BoardMove moves a todo to a column of a board, at a position in the column
+type BoardMove struct {
+	ID ID `json:"id"`
+	// By is the field the board groups the todos by, "status" or "priority"
+	By     string `json:"by"`
+	Column string `json:"column"`
+	// Position is the position of the todo in the column, 0 being the top
+	Position int `json:"position"`
+}
+
+// Move moves a todo in the manual order of its list
+type Move struct {
+	// Before is the ID of the todo to move the todo right before. Empty moves it last.
+	Before ID `json:"before,omitempty"`
+}
+
+// Change describes the change of a field of a todo
+type Change struct {
+	// Field is the name of the changed field in the stored todo
+	Field string `json:"field"`
+	// From is the JSON encoded value before the change. Empty if it wasn't set.
+	From json.RawMessage `json:"from,omitempty"`
+	// To is the JSON encoded value after the change. Empty if it was unset.
+	To json.RawMessage `json:"to,omitempty"`
+}
+
+// Revision describes a mutation of a todo
+type Revision struct {
+	// Rev numbers the revisions of the todo, starting from 1
+	Rev int `json:"rev"`
+	// Time is when the mutation was made
+	Time time.Time `json:"time"`
+	// Actor is who made the mutation. Empty if unknown.
+	Actor   string   `json:"actor,omitempty"`
+	Changes []Change `json:"changes"`
+}
+
+// ChangeEntry describes an entry of the changelog, the ordered log of the mutations of all the todos
+type ChangeEntry struct {
+	// Seq orders the entries, and is the cursor to list the changes after the entry
+	Seq uint64 `json:"seq"`
+	// Type is "created", "updated" or "deleted"
+	Type string `json:"type"`
+	ID   ID     `json:"id"`
+	// Rev is the revision of the todo after the change, see its history
+	Rev  int       `json:"rev"`
+	Time time.Time `json:"time"`
+	// Actor is who made the mutation. Empty if unknown.
+	Actor string `json:"actor,omitempty"`
+	// Item is the todo after the change. Missing if it was deleted.
+	Item *Item `json:"item,omitempty"`
+}
+
+// BulkResult describes the outcome of a bulk operation on a todo
+type BulkResult struct {
+	ID ID `json:"id"`
+	// NewID is the ID of the todo after the operation, if it changed
+	NewID ID `json:"new_id,omitempty"`
+	// Error tells why the todo was skipped. Empty if it was updated.
+	Error string `json:"error,omitempty"`
+	// Status is the HTTP status code of the operation on the todo, in the batches
+	Status int `json:"status,omitempty"`
+}
+
+// BatchOp describes an operation of a batch
+type BatchOp struct {
+	// Op is "create", "update" or "delete"
+	Op string `json:"op"`
+	// ID is the todo to update or delete
+	ID ID `json:"id,omitempty"`
+	// List is the list to create the todo in. Empty for none.
+	List string `json:"list,omitempty"`
+	// Todo is the todo to create
+	Todo *Todo `json:"todo,omitempty"`
+	// Patch are the changes to the todo to update
+	Patch *TodoPatch `json:"patch,omitempty"`
+	// Rev is the revision the todo to update or delete must have, if set
+	Rev *int `json:"rev,omitempty"`
+}
+
+// Batch describes the operations to apply at once
+type Batch struct {
+	Ops []BatchOp `json:"ops"`
+}
+
+// Retag describes the tags to attach to and detach from many todos at once
+type Retag struct {
+	IDs    []ID     `json:"ids"`
+	Add    []string `json:"add,omitempty"`
+	Remove []string `json:"remove,omitempty"`
+}
+
+// TimeEntry describes a time span spent working on a todo
+type TimeEntry struct {
+	Start time.Time `json:"start"`
+	// End is when the work stopped. Empty while the timer runs.
+	End *time.Time `json:"end,omitempty"`
+	// Pomodoro tells whether the entry is a pomodoro, a work interval run to its end
+	Pomodoro bool `json:"pomodoro,omitempty"`
+}
+
+// TimeReport describes the time tracked on the todos within a period, in hours
+type TimeReport struct {
+	From  time.Time `json:"from"`
+	To    time.Time `json:"to"`
+	Total float64   `json:"total"`
+	// Pomodoros is how many pomodoros ended within the period
+	Pomodoros int `json:"pomodoros"`
+	// ByTask is the time tracked on each todo
+	ByTask map[ID]float64 `json:"by_task"`
+	// ByTag is the time tracked on the todos with each tag
+	ByTag map[string]float64 `json:"by_tag"`
+	// ByDay is the time tracked each day, as "2006-01-02"
+	ByDay map[string]float64 `json:"by_day"`
+}
+
+// Stats describes the productivity on the todos within a period
+type Stats struct {
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+	// Period is the length of the periods of Completions, "day" or "week"
+	Period string `json:"period"`
+	// Completions are how many todos were completed in each period
+	Completions []StatsBucket `json:"completions"`
+	StatsCounts
+	// ByTag are the counts of the todos with each tag
+	ByTag map[string]StatsCounts `json:"by_tag"`
+	// ByContext are the counts of the todos with each context
+	ByContext map[string]StatsCounts `json:"by_context"`
+}
+
+// StatsBucket is how many todos were completed in the period starting at Start
+type StatsBucket struct {
+	Start     time.Time `json:"start"`
+	Completed int       `json:"completed"`
+}
+
+// StatsCounts counts the todos created, completed and due within a period, and the open ones
+type StatsCounts struct {
+	Created   int `json:"created"`
+	Completed int `json:"completed"`
+	// TimeToComplete is how long the todos completed took on average, in hours
+	TimeToComplete float64 `json:"time_to_complete"`
+	// Due is how many todos were due, and Late how many of them were not completed by then
+	Due  int `json:"due"`
+	Late int `json:"late"`
+	// OverdueRate is Late over Due, from 0 to 1
+	OverdueRate float64 `json:"overdue_rate"`
+	Open        int     `json:"open"`
+	Overdue     int     `json:"overdue"`
+	// Pomodoros is how many pomodoros ended within the period
+	Pomodoros int `json:"pomodoros"`
+}
+
+// Burndown describes how many todos of a project, with the tag, in the list, were open over time
+type Burndown struct {
+	Tag    string          `json:"tag,omitempty"`
+	List   string          `json:"list,omitempty"`
+	From   time.Time       `json:"from"`
+	To     time.Time       `json:"to"`
+	Points []BurndownPoint `json:"points"`
+}
+
+// BurndownPoint counts the todos of a project open, and completed, at a time
+type BurndownPoint struct {
+	Time      time.Time `json:"time"`
+	Open      int       `json:"open"`
+	Completed int       `json:"completed"`
+}
+
+// Comment describes a comment on a todo
+type Comment struct {
+	// ID identifies the comment within the todo. Ignored when adding comments.
+	ID     int    `json:"id"`
+	Author string `json:"author,omitempty"`
+	// Body is the text of the comment, in Markdown
+	Body    string    `json:"body"`
+	Created time.Time `json:"created,omitempty"`
+	// Edited is the last time the body was edited, if it ever was
+	Edited *time.Time `json:"edited,omitempty"`
+}
+
+// CheckItem describes an entry of the checklist of a todo
+type CheckItem struct {
+	// ID identifies the entry within the todo. Ignored when adding entries.
+	ID   int    `json:"id"`
+	Text string `json:"text"`
+	Done bool   `json:"done,omitempty"`
+}
+
+// APIKey describes an API key
+type APIKey struct {
+	// ID identifies the key. Ignored when creating keys.
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	// Scope is what the requests authenticated with the key can do: "read", "write" or "admin"
+	Scope   string    `json:"scope"`
+	Created time.Time `json:"created,omitempty"`
+	// Static tells whether the key was loaded from the keys file, rather than created through the API
+	Static bool `json:"static,omitempty"`
+	// Key is the key itself, only returned when the key is created
+	Key string `json:"key,omitempty"`
+}
+
+// User describes a user of the API, who owns todos
+type User struct {
+	// Name identifies the user, and is the name of the API keys of the user
+	Name        string `json:"name"`
+	DisplayName string `json:"display_name,omitempty"`
+	Email       string `json:"email,omitempty"`
+	// Role is "user", who can change only their todos, or "admin", who can change all of them
+	Role    string    `json:"role"`
+	Created time.Time `json:"created,omitempty"`
+}
+
+// Member is a member of a list of todos
+type Member struct {
+	User string `json:"user"`
+	// Role is "viewer", who can see the todos of the list, "editor", who can change them as well,
+	// or "admin", who can manage the members as well
+	Role string `json:"role"`
+}
+
+// List is a list of todos. The todos of the lists with members are accessible only to the members.
+type List struct {
+	Name    string   `json:"name"`
+	Members []Member `json:"members,omitempty"`
+	// Fields are the custom fields the todos of the list have
+	Fields []FieldDef `json:"fields,omitempty"`
+}
+
+// Link links a todo to another todo, or to a URL
+type Link struct {
+	// Type is "relates-to", "duplicates" or "caused-by", or for the backlinks how the todo relates to
+	// the other one: "relates-to", "duplicated-by" or "causes"
+	Type string `json:"type"`
+	// ID is the ID of the other todo. Empty for the links to URLs.
+	ID ID `json:"id,omitempty"`
+	// URL is the page linked, as an absolute http or https URL. Empty for the links to todos.
+	URL string `json:"url,omitempty"`
+	// Title is the title of the other todo, if visible. Set by the server.
+	Title string `json:"title,omitempty"`
+	// Back tells whether the other todo links to this one. Set by the server.
+	Back bool `json:"back,omitempty"`
+}
+
+// FieldDef defines a custom field of the todos of a list
+type FieldDef struct {
+	Name string `json:"name"`
+	// Type is the type of the values: "string", "number", "enum", "date" (YYYY-MM-DD) or "url"
+	Type string `json:"type"`
+	// Values are the values of the enum fields
+	Values []string `json:"values,omitempty"`
+}
+
+// Webhook is a URL the events of the todos are delivered to
+type Webhook struct {
+	ID  string `json:"id,omitempty"`
+	URL string `json:"url"`
+	// Events are the kinds of the events delivered: "created", "assigned", "completed" or "overdue"
+	Events []string `json:"events"`
+	// Secret keys the signatures of the deliveries, only returned when the webhook is created.
+	// Generated if empty.
+	Secret  string    `json:"secret,omitempty"`
+	Created time.Time `json:"created,omitempty"`
+}
+
+// Notifier posts the events of the todos to a Slack or Discord channel
+type Notifier struct {
+	Name string `json:"name"`
+	// Kind is "slack" or "discord"
+	Kind string `json:"kind"`
+	// URL is the incoming webhook of the channel the messages are posted to. Only its host is
+	// returned, the rest is a secret.
+	URL string `json:"url"`
+	// Events are the kinds of the events posted. Empty means "assigned", "overdue" and "completed".
+	Events []string `json:"events,omitempty"`
+	// Channels are the incoming webhooks of the channels the messages about the todos of each list
+	// are posted to instead, by list name. Only their hosts are returned.
+	Channels map[string]string `json:"channels,omitempty"`
+	// Templates format the messages of each kind of event, as Go text/templates of the fields of
+	// the todo, e.g. "{{.Title}} is due {{.Due}}"
+	Templates map[string]string `json:"templates,omitempty"`
+	// Static tells whether the notifier was loaded from the notifiers file, rather than created
+	// through the API
+	Static  bool      `json:"static,omitempty"`
+	Created time.Time `json:"created,omitempty"`
+}
+
+// Job is a maintenance job the server runs on a schedule
+type Job struct {
+	Name string `json:"name"`
+	// Schedule is a cron expression, like "30 3 * * *", or a macro, like "@daily" or "@every 6h"
+	Schedule string `json:"schedule"`
+	// Jitter is the longest random delay of the runs, e.g. "10m0s"
+	Jitter string `json:"jitter,omitempty"`
+	// Next is when the job runs next, jitter included
+	Next    *time.Time `json:"next,omitempty"`
+	Running bool       `json:"running,omitempty"`
+	// Runs are the latest runs of the job, newest first
+	Runs []JobRun `json:"runs"`
+}
+
+// JobRun is a run of a maintenance job
+type JobRun struct {
+	Started  time.Time `json:"started"`
+	Finished time.Time `json:"finished"`
+	// Result is what the job did, e.g. "12 objects purged"
+	Result string `json:"result,omitempty"`
+	// Error is why the run failed, empty if it didn't
+	Error string `json:"error,omitempty"`
+}
+
+// Delivery is the delivery of a event to a webhook
+type Delivery struct {
+	ID    string `json:"id"`
+	Event string `json:"event"`
+	Todo  string `json:"todo"`
+	// Time is when the event happened
+	Time     time.Time `json:"time"`
+	Attempts int       `json:"attempts"`
+	// Status is the HTTP status code of the response to the last attempt, if any
+	Status int `json:"status,omitempty"`
+	// Error tells why the last attempt failed
+	Error     string `json:"error,omitempty"`
+	Delivered bool   `json:"delivered"`
+	// Done tells whether the delivery won't be attempted anymore
+	Done bool `json:"done"`
+}
+
+// TokenRequest describes the bearer token to issue
+type TokenRequest struct {
+	// Scope of the token, which the credentials of the request must allow. Empty means their scope.
+	Scope string `json:"scope,omitempty"`
+	// TTL is how long the token lasts, e.g. "15m". Empty means as long as allowed.
+	TTL string `json:"ttl,omitempty"`
+}
+
+// Token is a bearer token authenticating the requests until it expires
+type Token struct {
+	Token   string    `json:"token"`
+	Scope   string    `json:"scope"`
+	Expires time.Time `json:"expires"`
+}
+
+// Health is the answer of the health probes
+type Health struct {
+	// Status is "ok", "unavailable" if the store is not usable, or "draining" if the server is
+	// shutting down
+	Status string `json:"status"`
+	// Backend is the kind of the store backend, e.g. "sqlite"
+	Backend string `json:"backend"`
+	// SchemaVersion is the version of the schema of the stored todos
+	SchemaVersion int `json:"schema_version"`
+	// Uptime is how long the server has been running, e.g. "1h2m3s"
+	Uptime string `json:"uptime"`
+	// Error tells why the store is not usable
+	Error string `json:"error,omitempty"`
 }
 
 // ToJSON returns a bytestream JSON encoding of the Todo; if succesfull, err is nil;
@@ -76,12 +516,31 @@ type Item struct {
 	Todo *Todo `json:"todo,omitempty"`
 }
 
+// Event describes a change of a todo
+type Event struct {
+	// ID identifies the event, to resume the stream of the events after it
+	ID uint64 `json:"id"`
+	// Type is "created", "updated" or "deleted"
+	Type string `json:"type"`
+	// Item is the todo after the change. Only the ID is set if it was deleted.
+	Item Item `json:"item"`
+}
+
 // Errors give informations about a processing error
 type Error struct {
 	// Processing error code. If positive, it is a HTTP status code
 	Code int `json:"code"`
 	// Optional human friendly description of the error
 	Text string `json:"text,omitempty"`
+	// Fields describes the invalid values of the request body, if the request was invalid
+	Fields []FieldError `json:"fields,omitempty"`
+}
+
+// FieldError describes an invalid value of a request body
+type FieldError struct {
+	// Field is the JSON pointer of the value, e.g. "/checklist/0/text". Empty for the whole body.
+	Field string `json:"field,omitempty"`
+	Text  string `json:"text"`
 }
 
 // Result represent the status of a succesfull processing.
@@ -89,6 +548,49 @@ type Result struct {
 	// Items includes the updated objects as returned by the operation.
 	// Can be empty in succesfull operations (e.g. a query produced no values)
 	Items []Item `json:"items,omitempty"`
+	// Tags includes the tags returned by the operation
+	Tags []Tag `json:"tags,omitempty"`
+	// History includes the revisions returned by the operation
+	History []Revision `json:"history,omitempty"`
+	// Report includes the outcome of a bulk operation on each todo
+	Report []BulkResult `json:"report,omitempty"`
+	// TimeReport includes the time tracked on the todos
+	TimeReport *TimeReport `json:"time_report,omitempty"`
+	// Stats includes the statistics of the productivity on the todos
+	Stats *Stats `json:"stats,omitempty"`
+	// Burndown includes the open todos of a project over time
+	Burndown *Burndown `json:"burndown,omitempty"`
+	// Comments includes the comments returned by the operation
+	Comments []Comment `json:"comments,omitempty"`
+	// Users includes the users returned by the operation
+	Users []User `json:"users,omitempty"`
+	// Lists includes the lists of todos returned by the operation
+	Lists []List `json:"lists,omitempty"`
+	// Filters includes the saved filters returned by the operation
+	Filters []Filter `json:"filters,omitempty"`
+	// Links includes the links and the backlinks of a todo returned by the operation
+	Links []Link `json:"links,omitempty"`
+	// Board includes the board returned by the operation
+	Board *Board `json:"board,omitempty"`
+	// Webhooks includes the webhooks returned by the operation
+	Webhooks []Webhook `json:"webhooks,omitempty"`
+	// Deliveries includes the deliveries to a webhook returned by the operation
+	Deliveries []Delivery `json:"deliveries,omitempty"`
+	// Notifiers includes the notifiers returned by the operation
+	Notifiers []Notifier `json:"notifiers,omitempty"`
+	// Jobs includes the maintenance jobs returned by the operation
+	Jobs []Job `json:"jobs,omitempty"`
+	// Changes includes the entries of the changelog returned by the operation
+	Changes []ChangeEntry `json:"changes,omitempty"`
+	// Cursor is the cursor to list the following changes of the changelog from
+	Cursor string `json:"cursor,omitempty"`
+	// Keys includes the API keys returned by the operation
+	Keys []APIKey `json:"keys,omitempty"`
+	// Token is the bearer token issued by the operation
+	Token *Token `json:"token,omitempty"`
+	// NextPageToken is the token of the next page of the items listed by the operation.
+	// Empty on the last page.
+	NextPageToken string `json:"next_page_token,omitempty"`
 	// Optional human friendly description of the operation
 	Text string `json:"text,omitempty"`
 }
@@ -0,0 +1,207 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	apiv1 "github.com/gotestbootcamp/go-todo-app/api/v1"
+	"github.com/gotestbootcamp/go-todo-app/auth/oidc"
+)
+
+// APIKeyHeader is the request header carrying the API key, unless the request has a bearer token
+const APIKeyHeader = "X-API-Key"
+
+// KeyParam is the query parameter carrying the API key on the routes accepting it, see KeyFromQuery
+const KeyParam = "key"
+
+// DefaultTokenTTL is how long the tokens last by default
+const DefaultTokenTTL = time.Hour
+
+var (
+	ErrNoCredentials = errors.New("auth: missing credentials")
+	ErrInvalidKey    = errors.New("auth: invalid API key")
+)
+
+// ErrScope is returned when a request requires a scope its credentials don't allow
+type ErrScope struct {
+	Required Scope
+	Actual   Scope
+}
+
+func (e ErrScope) Error() string {
+	return fmt.Sprintf("auth: scope %s required, the credentials allow %s", e.Required, e.Actual)
+}
+
+// Identity is who makes a request, as authenticated by its API key, token or session
+type Identity struct {
+	// Name is the name of the API key, or of the user logged in
+	Name  string
+	Scope Scope
+	// KeyID is the ID of the API key, or of the key the token was issued in exchange of.
+	// Empty for the sessions.
+	KeyID string
+}
+
+type identityKey struct{}
+
+// NewContext returns a copy of the context carrying the identity
+func NewContext(ctx context.Context, id Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, id)
+}
+
+// FromContext returns the identity carried by the context. False if there's none, i.e. if the
+// request was not authenticated.
+func FromContext(ctx context.Context) (Identity, bool) {
+	id, ok := ctx.Value(identityKey{}).(Identity)
+	return id, ok
+}
+
+// Authenticator authenticates the requests with the API keys of its keyring, with the bearer
+// tokens of its signer, or with the session cookies of the users logged in with its providers
+type Authenticator struct {
+	Keys   *Keyring
+	Tokens *Signer
+	// TokenTTL is the longest lifetime of the tokens IssueToken issues
+	TokenTTL time.Duration
+
+	// Providers are the OpenID Connect providers the users log in with, by name
+	Providers map[string]*oidc.Provider
+	// MapClaims maps the users logged in with the providers to their identity
+	MapClaims ClaimsMapper
+	// CheckSession checks the users of the sessions again on every request, nil not to
+	CheckSession SessionChecker
+	// SessionTTL is the lifetime of the sessions
+	SessionTTL time.Duration
+	// SecureCookies restricts the cookies to HTTPS, for the servers behind a TLS terminating proxy
+	SecureCookies bool
+}
+
+// NewAuthenticator creates the authenticator of the keys and tokens
+func NewAuthenticator(keys *Keyring, tokens *Signer) *Authenticator {
+	return &Authenticator{Keys: keys, Tokens: tokens, TokenTTL: DefaultTokenTTL, SessionTTL: DefaultSessionTTL}
+}
+
+// authenticate returns the identity authenticated by the bearer token, or else by the API key.
+// The tokens issued in exchange of a key are valid as long as the key.
+func (au *Authenticator) authenticate(token, apiKey string) (Identity, error) {
+	switch {
+	case token != "":
+		id, err := au.Tokens.Verify(token)
+		if err != nil {
+			return Identity{}, err
+		}
+		if _, ok := au.Keys.Get(id.KeyID); !ok {
+			return Identity{}, ErrInvalidToken
+		}
+		return id, nil
+	case apiKey != "":
+		key, ok := au.Keys.Lookup(apiKey)
+		if !ok {
+			return Identity{}, ErrInvalidKey
+		}
+		return Identity{Name: key.Name, Scope: key.Scope, KeyID: key.ID}, nil
+	default:
+		return Identity{}, ErrNoCredentials
+	}
+}
+
+// Authenticate returns the identity authenticated by the bearer token of the Authorization
+// header of the request, or by the API key given as the password of its basic credentials,
+// whatever the user name, for the clients only supporting those like the CalDAV ones, or else
+// by the API key of its X-API-Key header, or else by its session cookie
+func (au *Authenticator) Authenticate(r *http.Request) (Identity, error) {
+	var token, apiKey string
+	if val := r.Header.Get("Authorization"); val != "" {
+		scheme, cred, _ := strings.Cut(val, " ")
+		switch {
+		case strings.EqualFold(scheme, "Bearer"):
+			token = strings.TrimSpace(cred)
+		case strings.EqualFold(scheme, "Basic"):
+			_, apiKey, _ = r.BasicAuth()
+		default:
+			return Identity{}, fmt.Errorf("auth: unsupported authorization scheme %q", scheme)
+		}
+	}
+	if apiKey == "" {
+		apiKey = r.Header.Get(APIKeyHeader)
+	}
+	if token == "" && apiKey == "" {
+		return au.session(r)
+	}
+	return au.authenticate(token, apiKey)
+}
+
+// KeyFromQuery returns the handler calling next with the API key of the KeyParam query parameter
+// of the request, if any, moved to its X-API-Key header, for the clients which can't set the
+// headers of their requests, like the calendar apps subscribing to a feed. The parameter is removed
+// from the URL of the request, for the access log not to record the key.
+func KeyFromQuery(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if !query.Has(KeyParam) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		r = r.Clone(r.Context())
+		r.Header.Set(APIKeyHeader, query.Get(KeyParam))
+		query.Del(KeyParam)
+		r.URL.RawQuery = query.Encode()
+		r.RequestURI = r.URL.RequestURI()
+		next.ServeHTTP(w, r)
+	})
+}
+
+// IssueToken returns a token authenticating the identity with the given scope, which the identity
+// must allow, until it expires after ttl, along with the expiration time. The TokenTTL bounds ttl;
+// zero means the TokenTTL.
+func (au *Authenticator) IssueToken(id Identity, scope Scope, ttl time.Duration) (string, time.Time, error) {
+	if !id.Scope.Allows(scope) {
+		return "", time.Time{}, ErrScope{Required: scope, Actual: id.Scope}
+	}
+	if ttl <= 0 || ttl > au.TokenTTL {
+		ttl = au.TokenTTL
+	}
+	id.Scope = scope
+	return au.Tokens.Issue(id, ttl)
+}
+
+// Require returns the handler calling next with the identity of the request in its context, see
+// FromContext, if the request is authenticated with a scope allowing the required one.
+// Answers 401 if the request is not authenticated, and 403 if its scope doesn't allow the required one.
+func (au *Authenticator) Require(required Scope, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, err := au.Authenticate(r)
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="todo"`)
+			sendError(w, http.StatusUnauthorized, err)
+			return
+		}
+		if !id.Scope.Allows(required) {
+			slog.WarnContext(r.Context(), "auth: denied", "method", r.Method, "path", r.URL.Path, "name", id.Name, "scope", id.Scope)
+			sendError(w, http.StatusForbidden, ErrScope{Required: required, Actual: id.Scope})
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(NewContext(r.Context(), id)))
+	})
+}
+
+func sendError(w http.ResponseWriter, code int, err error) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(code)
+	resp := apiv1.Response{
+		Status: apiv1.ResponseError,
+		Error: &apiv1.Error{
+			Code: code,
+			Text: err.Error(),
+		},
+	}
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		panic(err)
+	}
+}
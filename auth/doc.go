@@ -0,0 +1,9 @@
+// Package auth authenticates the clients of the APIs, and authorizes their requests by scope.
+// The clients authenticate with an API key, either static, loaded from a file on startup, or
+// managed through the API and stored along with the todos, or with a short-lived bearer token
+// signed with HMAC-SHA256, which the clients obtain in exchange of their API key. The clients which
+// only support the basic authentication, like the CalDAV ones, send the API key as the password.
+// The users can also log in with an OpenID Connect provider, see package oidc, and are then
+// authenticated by a session cookie.
+// Each key and token grants a scope: read-only, read-write, or admin, which also manages the keys.
+package auth
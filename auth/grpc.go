@@ -0,0 +1,72 @@
+package auth
+
+import (
+	"context"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// APIKeyMetadata is the request metadata carrying the API key, unless the "authorization"
+// metadata carries a bearer token
+const APIKeyMetadata = "x-api-key"
+
+// authenticateRPC returns the context of the gRPC call carrying its identity, if the call is
+// authenticated with a scope allowing the one scopeOf requires for the method
+func (au *Authenticator) authenticateRPC(ctx context.Context, method string, scopeOf func(method string) Scope) (context.Context, error) {
+	var token, apiKey string
+	if vals := metadata.ValueFromIncomingContext(ctx, "authorization"); len(vals) > 0 {
+		scheme, cred, _ := strings.Cut(vals[0], " ")
+		if !strings.EqualFold(scheme, "Bearer") {
+			return nil, status.Errorf(codes.Unauthenticated, "unsupported authorization scheme %q", scheme)
+		}
+		token = strings.TrimSpace(cred)
+	}
+	if vals := metadata.ValueFromIncomingContext(ctx, APIKeyMetadata); len(vals) > 0 {
+		apiKey = vals[0]
+	}
+	id, err := au.authenticate(token, apiKey)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+	if required := scopeOf(method); !id.Scope.Allows(required) {
+		return nil, status.Error(codes.PermissionDenied, ErrScope{Required: required, Actual: id.Scope}.Error())
+	}
+	return NewContext(ctx, id), nil
+}
+
+// UnaryInterceptor returns the interceptor authenticating the unary calls, like Require does with
+// the HTTP requests. scopeOf returns the scope required by each method, given its full name.
+func (au *Authenticator) UnaryInterceptor(scopeOf func(method string) Scope) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, err := au.authenticateRPC(ctx, info.FullMethod, scopeOf)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamInterceptor is like UnaryInterceptor, for the streaming calls
+func (au *Authenticator) StreamInterceptor(scopeOf func(method string) Scope) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := au.authenticateRPC(ss.Context(), info.FullMethod, scopeOf)
+		if err != nil {
+			return err
+		}
+		return handler(srv, authenticatedStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// authenticatedStream is a grpc.ServerStream whose context carries the identity of the call
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (as authenticatedStream) Context() context.Context {
+	return as.ctx
+}
@@ -0,0 +1,246 @@
+package auth
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gotestbootcamp/go-todo-app/store"
+)
+
+// keyPrefix marks the IDs of the items holding the managed API keys
+var keyPrefix = string(store.MetaID("apikeys/"))
+
+// ErrNoKey is returned when there is no API key with the given ID
+type ErrNoKey struct {
+	ID string
+}
+
+func (e ErrNoKey) Error() string {
+	return fmt.Sprintf("auth: no API key %q", e.ID)
+}
+
+// ErrStaticKey is returned when revoking a static key, which only the keys file can remove
+var ErrStaticKey = errors.New("auth: the static keys can't be revoked")
+
+// Key describes an API key. The key itself is not kept, only its hash.
+type Key struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	// Scope is what the requests authenticated with the key can do
+	Scope   Scope     `json:"scope"`
+	Created time.Time `json:"created"`
+	// Static tells whether the key was loaded from a file, rather than created through the API
+	Static bool `json:"-"`
+	// Hash is the SHA-256 hash of the key, hex encoded
+	Hash string `json:"hash"`
+}
+
+func hashKey(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// Keyring holds the API keys: the static ones, and the ones managed through the API, stored
+// as metadata items of the storage. It is safe for concurrent use.
+type Keyring struct {
+	st store.Storage
+
+	lock sync.RWMutex
+	keys map[string]Key
+	// byHash maps the hashes of the keys to their IDs
+	byHash map[string]string
+}
+
+// NewKeyring creates the keyring of the keys stored in st. The nil storage makes a keyring
+// holding just the static keys.
+func NewKeyring(st store.Storage) (*Keyring, error) {
+	kr := &Keyring{
+		st:     st,
+		keys:   make(map[string]Key),
+		byHash: make(map[string]string),
+	}
+	if st == nil {
+		return kr, nil
+	}
+	err := store.Walk(st, func(item store.Item) error {
+		if !strings.HasPrefix(string(item.ID), keyPrefix) {
+			return nil
+		}
+		var key Key
+		if err := json.Unmarshal(item.Blob, &key); err != nil {
+			return fmt.Errorf("auth: can't decode the API key %v: %w", item.ID, err)
+		}
+		kr.add(key)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("auth: loaded %d API keys", len(kr.keys))
+	return kr, nil
+}
+
+// add must be called with the lock held, or before sharing the keyring
+func (kr *Keyring) add(key Key) {
+	kr.keys[key.ID] = key
+	kr.byHash[key.Hash] = key.ID
+}
+
+// AddStatic adds a static key, which authenticates the requests until the process exits.
+// Its ID is its name, prefixed by "static-".
+func (kr *Keyring) AddStatic(name string, scope Scope, secret string) error {
+	if !scope.Valid() {
+		return fmt.Errorf("invalid scope %q", scope)
+	}
+	if secret == "" {
+		return errors.New("auth: empty API key")
+	}
+	key := Key{
+		ID:      "static-" + name,
+		Name:    name,
+		Scope:   scope,
+		Created: time.Now(),
+		Static:  true,
+		Hash:    hashKey(secret),
+	}
+	kr.lock.Lock()
+	defer kr.lock.Unlock()
+	if _, ok := kr.keys[key.ID]; ok {
+		return fmt.Errorf("auth: duplicated static key %q", name)
+	}
+	if _, ok := kr.byHash[key.Hash]; ok {
+		return fmt.Errorf("auth: the static key %q is already in use", name)
+	}
+	kr.add(key)
+	return nil
+}
+
+// LoadFile adds the static keys listed in the file, one per line as "name scope key".
+// The empty lines and the ones starting with "#" are skipped.
+func (kr *Keyring) LoadFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			return fmt.Errorf("%s:%d: want \"name scope key\"", path, n)
+		}
+		if err := kr.AddStatic(fields[0], Scope(fields[1]), fields[2]); err != nil {
+			return fmt.Errorf("%s:%d: %w", path, n, err)
+		}
+	}
+	return sc.Err()
+}
+
+// Create generates a new API key with the given name and scope, and stores it. Returns the key
+// along with its secret, which can't be retrieved later.
+// Fails with store.ErrUnsupported if the keyring has no storage.
+func (kr *Keyring) Create(name string, scope Scope) (Key, string, error) {
+	if !scope.Valid() {
+		return Key{}, "", fmt.Errorf("invalid scope %q", scope)
+	}
+	if strings.TrimSpace(name) == "" {
+		return Key{}, "", errors.New("auth: the API keys need a name")
+	}
+	if kr.st == nil {
+		return Key{}, "", store.ErrUnsupported
+	}
+	buf := make([]byte, 40)
+	if _, err := rand.Read(buf); err != nil {
+		return Key{}, "", err
+	}
+	secret := "todo_" + base64.RawURLEncoding.EncodeToString(buf[8:])
+	key := Key{
+		ID:      hex.EncodeToString(buf[:8]),
+		Name:    name,
+		Scope:   scope,
+		Created: time.Now().UTC(),
+		Hash:    hashKey(secret),
+	}
+	blob, err := json.Marshal(key)
+	if err != nil {
+		return Key{}, "", err
+	}
+	kr.lock.Lock()
+	defer kr.lock.Unlock()
+	if err := kr.st.Create(store.ID(keyPrefix+key.ID), blob); err != nil {
+		return Key{}, "", err
+	}
+	kr.add(key)
+	log.Printf("auth: created API key %s %q, scope %s", key.ID, name, scope)
+	return key, secret, nil
+}
+
+// Revoke removes the managed API key with the given ID.
+// Fails with ErrNoKey if there's no such key, and with ErrStaticKey if it's static.
+func (kr *Keyring) Revoke(id string) error {
+	kr.lock.Lock()
+	defer kr.lock.Unlock()
+	key, ok := kr.keys[id]
+	if !ok {
+		return ErrNoKey{ID: id}
+	}
+	if key.Static {
+		return ErrStaticKey
+	}
+	if err := kr.st.Delete(store.ID(keyPrefix + id)); err != nil {
+		return err
+	}
+	delete(kr.keys, id)
+	delete(kr.byHash, key.Hash)
+	log.Printf("auth: revoked API key %s %q", id, key.Name)
+	return nil
+}
+
+// List returns the API keys, sorted by ID
+func (kr *Keyring) List() []Key {
+	kr.lock.RLock()
+	defer kr.lock.RUnlock()
+	res := make([]Key, 0, len(kr.keys))
+	for _, key := range kr.keys {
+		res = append(res, key)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].ID < res[j].ID
+	})
+	return res
+}
+
+// Get returns the API key with the given ID. False if there's no such key.
+func (kr *Keyring) Get(id string) (Key, bool) {
+	kr.lock.RLock()
+	defer kr.lock.RUnlock()
+	key, ok := kr.keys[id]
+	return key, ok
+}
+
+// Lookup returns the API key with the given secret. False if there's no such key.
+func (kr *Keyring) Lookup(secret string) (Key, bool) {
+	kr.lock.RLock()
+	defer kr.lock.RUnlock()
+	id, ok := kr.byHash[hashKey(secret)]
+	if !ok {
+		return Key{}, false
+	}
+	return kr.keys[id], true
+}
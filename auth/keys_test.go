@@ -0,0 +1,84 @@
+package auth
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gotestbootcamp/go-todo-app/store"
+)
+
+func TestKeyringCreateRevoke(t *testing.T) {
+	mem, err := store.NewMemory()
+	require.NoError(t, err)
+	kr, err := NewKeyring(mem)
+	require.NoError(t, err)
+
+	key, secret, err := kr.Create("ci", ScopeRead)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(secret, "todo_"))
+	assert.Equal(t, "ci", key.Name)
+	assert.Equal(t, ScopeRead, key.Scope)
+	assert.NotContains(t, key.Hash, secret)
+
+	got, ok := kr.Lookup(secret)
+	require.True(t, ok)
+	assert.Equal(t, key.ID, got.ID)
+	_, ok = kr.Lookup(secret + "x")
+	assert.False(t, ok)
+
+	// the keys survive restarts
+	reloaded, err := NewKeyring(mem)
+	require.NoError(t, err)
+	got, ok = reloaded.Lookup(secret)
+	require.True(t, ok)
+	assert.Equal(t, key.ID, got.ID)
+	assert.Equal(t, []Key{got}, reloaded.List())
+
+	require.NoError(t, reloaded.Revoke(key.ID))
+	_, ok = reloaded.Lookup(secret)
+	assert.False(t, ok)
+	assert.ErrorAs(t, reloaded.Revoke(key.ID), &ErrNoKey{})
+	reloaded, err = NewKeyring(mem)
+	require.NoError(t, err)
+	assert.Empty(t, reloaded.List())
+
+	_, _, err = kr.Create("", ScopeRead)
+	assert.Error(t, err)
+	_, _, err = kr.Create("ops", Scope("root"))
+	assert.Error(t, err)
+}
+
+func TestKeyringStatic(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys")
+	require.NoError(t, os.WriteFile(path, []byte(`
+# name scope key
+alice admin s3cret-alice
+bob   read  s3cret-bob
+`), 0o600))
+
+	kr, err := NewKeyring(nil)
+	require.NoError(t, err)
+	require.NoError(t, kr.LoadFile(path))
+
+	key, ok := kr.Lookup("s3cret-bob")
+	require.True(t, ok)
+	assert.Equal(t, Key{ID: "static-bob", Name: "bob", Scope: ScopeRead, Created: key.Created, Static: true, Hash: hashKey("s3cret-bob")}, key)
+	assert.Len(t, kr.List(), 2)
+
+	assert.ErrorIs(t, kr.Revoke("static-bob"), ErrStaticKey)
+	_, _, err = kr.Create("ci", ScopeRead)
+	assert.ErrorIs(t, err, store.ErrUnsupported)
+
+	assert.Error(t, kr.AddStatic("bob", ScopeRead, "other"))
+	assert.Error(t, kr.AddStatic("carol", ScopeRead, "s3cret-bob"))
+
+	require.NoError(t, os.WriteFile(path, []byte("dave write\n"), 0o600))
+	assert.ErrorContains(t, kr.LoadFile(path), "keys:1")
+	require.NoError(t, os.WriteFile(path, []byte("dave root s3cret\n"), 0o600))
+	assert.ErrorContains(t, kr.LoadFile(path), "invalid scope")
+}
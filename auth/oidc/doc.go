@@ -0,0 +1,5 @@
+// Package oidc delegates the authentication of the users to OpenID Connect providers, like Google
+// or Keycloak, or to plain OAuth2 providers with a user info endpoint, like GitHub, with the
+// authorization code flow and PKCE. It verifies the ID tokens signed with RS256 or ES256 against
+// the keys the providers publish.
+package oidc
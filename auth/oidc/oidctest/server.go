@@ -0,0 +1,176 @@
+// Package oidctest provides a fake OpenID Connect provider, for the tests of its clients
+package oidctest
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// KeyID is the ID of the key the server signs the ID tokens with
+const KeyID = "test-key"
+
+// Server is an OpenID Connect provider, authenticating as the user with the given Claims whoever
+// it's sent to. Its client has the given ID and secret.
+type Server struct {
+	*httptest.Server
+	ClientID     string
+	ClientSecret string
+
+	key *rsa.PrivateKey
+
+	lock sync.Mutex
+	// claims are the claims about the user, added to the ID tokens
+	claims map[string]any
+	// logins are the logins in progress, by code
+	logins map[string]login
+}
+
+// login is a login in progress, awaiting the exchange of its code
+type login struct {
+	redirect  string
+	nonce     string
+	challenge string
+}
+
+// NewServer starts the provider of the client with the given ID and secret. The caller must
+// Close it once done.
+func NewServer(clientID, clientSecret string) *Server {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(err)
+	}
+	srv := &Server{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		key:          key,
+		claims:       map[string]any{},
+		logins:       map[string]login{},
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", srv.discovery)
+	mux.HandleFunc("/keys", srv.keys)
+	mux.HandleFunc("/authorize", srv.authorize)
+	mux.HandleFunc("/token", srv.token)
+	srv.Server = httptest.NewServer(mux)
+	return srv
+}
+
+// SetClaims sets the claims about the user the next logins authenticate
+func (srv *Server) SetClaims(claims map[string]any) {
+	srv.lock.Lock()
+	defer srv.lock.Unlock()
+	srv.claims = claims
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		panic(err)
+	}
+}
+
+func (srv *Server) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, map[string]string{
+		"issuer":                 srv.URL,
+		"authorization_endpoint": srv.URL + "/authorize",
+		"token_endpoint":         srv.URL + "/token",
+		"jwks_uri":               srv.URL + "/keys",
+	})
+}
+
+func (srv *Server) keys(w http.ResponseWriter, r *http.Request) {
+	enc := base64.RawURLEncoding
+	writeJSON(w, map[string]any{
+		"keys": []map[string]string{{
+			"kid": KeyID,
+			"kty": "RSA",
+			"use": "sig",
+			"n":   enc.EncodeToString(srv.key.N.Bytes()),
+			"e":   enc.EncodeToString(big.NewInt(int64(srv.key.E)).Bytes()),
+		}},
+	})
+}
+
+// authorize sends the user back to the redirect URI, with a code and the state
+func (srv *Server) authorize(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if q.Get("client_id") != srv.ClientID || q.Get("code_challenge_method") != "S256" {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		panic(err)
+	}
+	code := base64.RawURLEncoding.EncodeToString(buf)
+	srv.lock.Lock()
+	srv.logins[code] = login{redirect: q.Get("redirect_uri"), nonce: q.Get("nonce"), challenge: q.Get("code_challenge")}
+	srv.lock.Unlock()
+	back, err := url.Parse(q.Get("redirect_uri"))
+	if err != nil {
+		http.Error(w, "invalid redirect_uri", http.StatusBadRequest)
+		return
+	}
+	back.RawQuery = url.Values{"code": {code}, "state": {q.Get("state")}}.Encode()
+	http.Redirect(w, r, back.String(), http.StatusFound)
+}
+
+// token exchanges the code for an ID token, signed with RS256
+func (srv *Server) token(w http.ResponseWriter, r *http.Request) {
+	code := r.PostFormValue("code")
+	srv.lock.Lock()
+	lg, ok := srv.logins[code]
+	delete(srv.logins, code)
+	claims := map[string]any{}
+	for k, v := range srv.claims {
+		claims[k] = v
+	}
+	srv.lock.Unlock()
+	sum := sha256.Sum256([]byte(r.PostFormValue("code_verifier")))
+	if !ok || r.PostFormValue("client_id") != srv.ClientID || r.PostFormValue("client_secret") != srv.ClientSecret ||
+		r.PostFormValue("redirect_uri") != lg.redirect || base64.RawURLEncoding.EncodeToString(sum[:]) != lg.challenge {
+		w.WriteHeader(http.StatusBadRequest)
+		writeJSON(w, map[string]string{"error": "invalid_grant"})
+		return
+	}
+	claims["iss"] = srv.URL
+	claims["aud"] = srv.ClientID
+	claims["exp"] = time.Now().Add(time.Hour).Unix()
+	claims["iat"] = time.Now().Unix()
+	claims["nonce"] = lg.nonce
+	writeJSON(w, map[string]string{
+		"access_token": "access-" + code,
+		"token_type":   "Bearer",
+		"id_token":     srv.Sign(claims),
+	})
+}
+
+// Sign returns the ID token with the given claims, signed with RS256
+func (srv *Server) Sign(claims map[string]any) string {
+	enc := base64.RawURLEncoding
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT", "kid": KeyID})
+	if err != nil {
+		panic(err)
+	}
+	body, err := json.Marshal(claims)
+	if err != nil {
+		panic(err)
+	}
+	payload := enc.EncodeToString(header) + "." + enc.EncodeToString(body)
+	digest := sha256.Sum256([]byte(payload))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, srv.key, crypto.SHA256, digest[:])
+	if err != nil {
+		panic(err)
+	}
+	return payload + "." + enc.EncodeToString(sig)
+}
@@ -0,0 +1,287 @@
+package oidc
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Config describes a provider, and the client registered with it
+type Config struct {
+	// Issuer is the URL the provider publishes its configuration under, at
+	// /.well-known/openid-configuration. Empty for the plain OAuth2 providers.
+	Issuer string
+	// AuthURL, TokenURL and UserInfoURL are the endpoints of the provider, overriding the published ones
+	AuthURL     string
+	TokenURL    string
+	UserInfoURL string
+	// EmailsURL lists the verified email addresses of the user, for the providers whose user info lacks
+	// them, like GitHub
+	EmailsURL    string
+	ClientID     string
+	ClientSecret string
+	// RedirectURL is where the provider sends the users back to, once authenticated
+	RedirectURL string
+	// Scopes are the scopes requested to the provider
+	Scopes []string
+	// UserClaim is the claim naming the user, e.g. "preferred_username"
+	UserClaim string
+}
+
+// Presets are the configurations of the well known providers, lacking the client settings
+var Presets = map[string]Config{
+	"google": {
+		Issuer:    "https://accounts.google.com",
+		Scopes:    []string{"openid", "email", "profile"},
+		UserClaim: "email",
+	},
+	"github": {
+		AuthURL:     "https://github.com/login/oauth/authorize",
+		TokenURL:    "https://github.com/login/oauth/access_token",
+		UserInfoURL: "https://api.github.com/user",
+		EmailsURL:   "https://api.github.com/user/emails",
+		Scopes:      []string{"read:user", "user:email"},
+		UserClaim:   "login",
+	},
+}
+
+// DefaultScopes are the scopes requested to the providers whose configuration has none
+var DefaultScopes = []string{"openid", "email", "profile"}
+
+// DefaultUserClaim is the claim naming the users, for the providers whose configuration has none
+const DefaultUserClaim = "preferred_username"
+
+// Claims are the claims about the authenticated user, from the ID token or the user info
+type Claims map[string]any
+
+// String returns the value of the claim, if it's a string
+func (c Claims) String(name string) string {
+	s, _ := c[name].(string)
+	return s
+}
+
+// Email returns the email address of the user, if verified by the provider
+func (c Claims) Email() (string, bool) {
+	verified, _ := c["email_verified"].(bool)
+	email := c.String("email")
+	return email, verified && email != ""
+}
+
+// Provider authenticates the users with an OpenID Connect or OAuth2 provider
+type Provider struct {
+	// Name identifies the provider, e.g. "google"
+	Name string
+	// UserClaim is the claim naming the user
+	UserClaim string
+
+	cfg     Config
+	client  *http.Client
+	jwksURL string
+	keys    *keySet
+}
+
+// discovery is the published configuration of a provider
+type discovery struct {
+	Issuer      string `json:"issuer"`
+	AuthURL     string `json:"authorization_endpoint"`
+	TokenURL    string `json:"token_endpoint"`
+	UserInfoURL string `json:"userinfo_endpoint"`
+	JWKSURL     string `json:"jwks_uri"`
+}
+
+// NewProvider creates the provider with the given name and configuration, fetching the published
+// configuration of the OpenID Connect providers. The nil client means http.DefaultClient.
+func NewProvider(ctx context.Context, name string, cfg Config, client *http.Client) (*Provider, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	if cfg.ClientID == "" || cfg.RedirectURL == "" {
+		return nil, fmt.Errorf("oidc: provider %q: the client ID and the redirect URL are required", name)
+	}
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = DefaultScopes
+	}
+	if cfg.UserClaim == "" {
+		cfg.UserClaim = DefaultUserClaim
+	}
+	p := &Provider{Name: name, UserClaim: cfg.UserClaim, cfg: cfg, client: client}
+	if cfg.Issuer != "" {
+		var disc discovery
+		wellKnown := strings.TrimSuffix(cfg.Issuer, "/") + "/.well-known/openid-configuration"
+		if err := p.getJSON(ctx, wellKnown, "", &disc); err != nil {
+			return nil, fmt.Errorf("oidc: provider %q: %w", name, err)
+		}
+		if disc.Issuer != cfg.Issuer {
+			return nil, fmt.Errorf("oidc: provider %q: issuer %q published as %q", name, cfg.Issuer, disc.Issuer)
+		}
+		p.cfg.AuthURL = firstOf(cfg.AuthURL, disc.AuthURL)
+		p.cfg.TokenURL = firstOf(cfg.TokenURL, disc.TokenURL)
+		p.cfg.UserInfoURL = firstOf(cfg.UserInfoURL, disc.UserInfoURL)
+		p.jwksURL = disc.JWKSURL
+		p.keys = newKeySet(p.jwksURL, client)
+	}
+	if p.cfg.AuthURL == "" || p.cfg.TokenURL == "" {
+		return nil, fmt.Errorf("oidc: provider %q: no authorization or token endpoint", name)
+	}
+	if p.keys == nil && p.cfg.UserInfoURL == "" {
+		return nil, fmt.Errorf("oidc: provider %q: neither an issuer nor a user info endpoint", name)
+	}
+	log.Printf("oidc: provider %q ready, authorizing on %s", name, p.cfg.AuthURL)
+	return p, nil
+}
+
+func firstOf(vals ...string) string {
+	for _, val := range vals {
+		if val != "" {
+			return val
+		}
+	}
+	return ""
+}
+
+// challenge returns the PKCE S256 challenge of the verifier
+func challenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// AuthCodeURL returns the URL to send the users to, to authenticate with the provider. The provider
+// sends them back to the redirect URL with the state, along with the code to Exchange.
+func (p *Provider) AuthCodeURL(state, nonce, verifier string) string {
+	vals := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {p.cfg.ClientID},
+		"redirect_uri":          {p.cfg.RedirectURL},
+		"scope":                 {strings.Join(p.cfg.Scopes, " ")},
+		"state":                 {state},
+		"code_challenge":        {challenge(verifier)},
+		"code_challenge_method": {"S256"},
+	}
+	if p.keys != nil {
+		vals.Set("nonce", nonce)
+	}
+	sep := "?"
+	if strings.Contains(p.cfg.AuthURL, "?") {
+		sep = "&"
+	}
+	return p.cfg.AuthURL + sep + vals.Encode()
+}
+
+// tokenResponse is the response of the token endpoint
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	IDToken     string `json:"id_token"`
+	Error       string `json:"error"`
+	Description string `json:"error_description"`
+}
+
+// Exchange exchanges the code the provider sent the user back with for the claims about the user:
+// the ones of the verified ID token, whose nonce must be the given one, or the user info.
+func (p *Provider) Exchange(ctx context.Context, code, verifier, nonce string) (Claims, error) {
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.cfg.RedirectURL},
+		"client_id":     {p.cfg.ClientID},
+		"client_secret": {p.cfg.ClientSecret},
+		"code_verifier": {verifier},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.cfg.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("oidc: token request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	var tok tokenResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&tok); err != nil {
+		return nil, fmt.Errorf("oidc: malformed token response (status %d): %w", resp.StatusCode, err)
+	}
+	if tok.Error != "" {
+		return nil, fmt.Errorf("oidc: token request denied: %s %s", tok.Error, tok.Description)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("oidc: token request failed with status %d", resp.StatusCode)
+	}
+
+	var claims Claims
+	switch {
+	case tok.IDToken != "" && p.keys != nil:
+		claims, err = p.verify(ctx, tok.IDToken, nonce)
+	case tok.AccessToken != "" && p.cfg.UserInfoURL != "":
+		err = p.getJSON(ctx, p.cfg.UserInfoURL, tok.AccessToken, &claims)
+	default:
+		err = errors.New("oidc: the provider returned neither an ID token nor an access token")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := claims.Email(); !ok && p.cfg.EmailsURL != "" {
+		if err := p.addEmail(ctx, tok.AccessToken, claims); err != nil {
+			return nil, err
+		}
+	}
+	return claims, nil
+}
+
+// addEmail adds to the claims the primary verified email address listed by the emails endpoint
+func (p *Provider) addEmail(ctx context.Context, accessToken string, claims Claims) error {
+	var emails []struct {
+		Email    string `json:"email"`
+		Primary  bool   `json:"primary"`
+		Verified bool   `json:"verified"`
+	}
+	if err := p.getJSON(ctx, p.cfg.EmailsURL, accessToken, &emails); err != nil {
+		return err
+	}
+	for _, e := range emails {
+		if e.Primary && e.Verified {
+			claims["email"] = e.Email
+			claims["email_verified"] = true
+		}
+	}
+	return nil
+}
+
+// getJSON decodes the JSON document at the URL, requested with the access token unless empty
+func (p *Provider) getJSON(ctx context.Context, url, accessToken string, v any) error {
+	return getJSON(ctx, p.client, url, accessToken, v)
+}
+
+func getJSON(ctx context.Context, client *http.Client, url, accessToken string, v any) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if accessToken != "" {
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("oidc: request to %s failed: %w", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("oidc: request to %s failed with status %d", url, resp.StatusCode)
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(v); err != nil {
+		return fmt.Errorf("oidc: malformed response from %s: %w", url, err)
+	}
+	return nil
+}
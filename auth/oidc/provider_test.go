@@ -0,0 +1,152 @@
+package oidc
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gotestbootcamp/go-todo-app/auth/oidc/oidctest"
+)
+
+// noRedirects is the client stopping at the redirects, to follow the logins step by step
+var noRedirects = &http.Client{
+	CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+// authorize logs in with the provider, and returns the code and the state it sends back
+func authorize(t *testing.T, authURL string) (code, state string) {
+	resp, err := noRedirects.Get(authURL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+	back, err := url.Parse(resp.Header.Get("Location"))
+	require.NoError(t, err)
+	return back.Query().Get("code"), back.Query().Get("state")
+}
+
+func TestExchange(t *testing.T) {
+	srv := oidctest.NewServer("todo", "s3cret")
+	defer srv.Close()
+	srv.SetClaims(map[string]any{"sub": "42", "email": "ann@example.com", "email_verified": true})
+	ctx := context.Background()
+
+	_, err := NewProvider(ctx, "test", Config{Issuer: srv.URL, ClientID: "todo"}, nil)
+	assert.Error(t, err, "no redirect URL")
+	_, err = NewProvider(ctx, "test", Config{Issuer: srv.URL + "/other", ClientID: "todo", RedirectURL: "http://todo/cb"}, nil)
+	assert.Error(t, err, "no such issuer")
+
+	p, err := NewProvider(ctx, "test", Config{Issuer: srv.URL, ClientID: "todo", ClientSecret: "s3cret", RedirectURL: "http://todo/cb"}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, DefaultUserClaim, p.UserClaim)
+
+	authURL := p.AuthCodeURL("state-1", "nonce-1", "verifier-1")
+	code, state := authorize(t, authURL)
+	assert.Equal(t, "state-1", state)
+	claims, err := p.Exchange(ctx, code, "verifier-1", "nonce-1")
+	require.NoError(t, err)
+	assert.Equal(t, "42", claims.String("sub"))
+	email, ok := claims.Email()
+	assert.True(t, ok)
+	assert.Equal(t, "ann@example.com", email)
+
+	code, _ = authorize(t, authURL)
+	_, err = p.Exchange(ctx, code, "verifier-1", "nonce-2")
+	assert.ErrorIs(t, err, ErrInvalidIDToken, "nonce mismatch")
+	code, _ = authorize(t, authURL)
+	_, err = p.Exchange(ctx, code, "verifier-2", "nonce-1")
+	assert.Error(t, err, "PKCE verifier mismatch")
+	_, err = p.Exchange(ctx, code, "verifier-1", "nonce-1")
+	assert.Error(t, err, "code used already")
+}
+
+func TestVerify(t *testing.T) {
+	srv := oidctest.NewServer("todo", "s3cret")
+	defer srv.Close()
+	ctx := context.Background()
+	p, err := NewProvider(ctx, "test", Config{Issuer: srv.URL, ClientID: "todo", RedirectURL: "http://todo/cb"}, nil)
+	require.NoError(t, err)
+
+	valid := func() map[string]any {
+		return map[string]any{
+			"iss":   srv.URL,
+			"aud":   []string{"other", "todo"},
+			"exp":   time.Now().Add(time.Minute).Unix(),
+			"nonce": "n",
+			"sub":   "42",
+		}
+	}
+	claims, err := p.verify(ctx, srv.Sign(valid()), "n")
+	require.NoError(t, err)
+	assert.Equal(t, "42", claims.String("sub"))
+
+	for name, change := range map[string]func(map[string]any){
+		"issuer":   func(c map[string]any) { c["iss"] = "https://evil.example.com" },
+		"audience": func(c map[string]any) { c["aud"] = "other" },
+		"expired":  func(c map[string]any) { c["exp"] = time.Now().Add(-2 * Leeway).Unix() },
+		"no exp":   func(c map[string]any) { delete(c, "exp") },
+		"nonce":    func(c map[string]any) { c["nonce"] = "m" },
+	} {
+		claims := valid()
+		change(claims)
+		_, err := p.verify(ctx, srv.Sign(claims), "n")
+		assert.ErrorIs(t, err, ErrInvalidIDToken, name)
+	}
+
+	token := srv.Sign(valid())
+	_, err = p.verify(ctx, token[:len(token)-4]+"AAAA", "n")
+	assert.ErrorIs(t, err, ErrInvalidIDToken, "bad signature")
+	_, err = p.verify(ctx, "not.a-token", "n")
+	assert.ErrorIs(t, err, ErrInvalidIDToken, "malformed")
+}
+
+func TestExchangeUserInfo(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "application/json", r.Header.Get("Accept"))
+		assert.Equal(t, "the-code", r.PostFormValue("code"))
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"access_token":"gho_token","token_type":"bearer"}`))
+	})
+	mux.HandleFunc("/user", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer gho_token", r.Header.Get("Authorization"))
+		w.Write([]byte(`{"login":"Ann","name":"Ann Smith","email":null}`))
+	})
+	mux.HandleFunc("/user/emails", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"email":"old@example.com","primary":false,"verified":true},
+			{"email":"ann@example.com","primary":true,"verified":true}]`))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	cfg := Presets["github"]
+	cfg.AuthURL = srv.URL + "/authorize"
+	cfg.TokenURL = srv.URL + "/token"
+	cfg.UserInfoURL = srv.URL + "/user"
+	cfg.EmailsURL = srv.URL + "/user/emails"
+	cfg.ClientID = "todo"
+	cfg.RedirectURL = "http://todo/cb"
+	p, err := NewProvider(context.Background(), "github", cfg, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "login", p.UserClaim)
+
+	authURL, err := url.Parse(p.AuthCodeURL("s", "n", "v"))
+	require.NoError(t, err)
+	assert.Equal(t, "read:user user:email", authURL.Query().Get("scope"))
+	assert.Empty(t, authURL.Query().Get("nonce"), "no ID tokens, no nonce")
+	assert.Equal(t, challenge("v"), authURL.Query().Get("code_challenge"))
+
+	claims, err := p.Exchange(context.Background(), "the-code", "v", "n")
+	require.NoError(t, err)
+	assert.Equal(t, "Ann", claims.String("login"))
+	email, ok := claims.Email()
+	assert.True(t, ok)
+	assert.Equal(t, "ann@example.com", email)
+}
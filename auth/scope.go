@@ -0,0 +1,43 @@
+package auth
+
+import "fmt"
+
+// Scope tells what the requests authenticated by a key or token can do. Each scope allows what
+// the previous ones do.
+type Scope string
+
+const (
+	// ScopeRead allows reading the todos
+	ScopeRead Scope = "read"
+	// ScopeWrite allows changing the todos as well
+	ScopeWrite Scope = "write"
+	// ScopeAdmin allows managing the API keys as well
+	ScopeAdmin Scope = "admin"
+)
+
+// scopeLevels orders the scopes
+var scopeLevels = map[Scope]int{
+	ScopeRead:  1,
+	ScopeWrite: 2,
+	ScopeAdmin: 3,
+}
+
+// ParseScope parses the name of a scope: read, write or admin
+func ParseScope(s string) (Scope, error) {
+	sc := Scope(s)
+	if !sc.Valid() {
+		return "", fmt.Errorf("invalid scope %q", s)
+	}
+	return sc, nil
+}
+
+// Valid returns true if the scope is known
+func (sc Scope) Valid() bool {
+	_, ok := scopeLevels[sc]
+	return ok
+}
+
+// Allows returns true if the scope allows what the required one does
+func (sc Scope) Allows(required Scope) bool {
+	return sc.Valid() && scopeLevels[sc] >= scopeLevels[required]
+}
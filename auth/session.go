@@ -0,0 +1,225 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gotestbootcamp/go-todo-app/auth/oidc"
+)
+
+const (
+	// SessionCookie is the cookie carrying the session of the users logged in with a provider
+	SessionCookie = "todo_session"
+	// stateCookie carries the state of a login in progress, from Login to Callback
+	stateCookie = "todo_login"
+)
+
+// DefaultSessionTTL is how long the sessions last by default
+const DefaultSessionTTL = 12 * time.Hour
+
+// loginTTL is how long the users have to authenticate with the provider, once sent to it
+const loginTTL = 10 * time.Minute
+
+// ErrLogin is returned when a login with a provider fails
+var ErrLogin = errors.New("auth: login failed")
+
+// ClaimsMapper returns the identity of the user authenticated by the provider with the given claims.
+// It fails if the user is not known, or not allowed to log in.
+type ClaimsMapper func(p *oidc.Provider, claims oidc.Claims) (Identity, error)
+
+// SessionChecker returns the identity of the user of a session as of now, e.g. with the scope of
+// their current role. It fails if the user can't use the session anymore, e.g. once deleted.
+type SessionChecker func(id Identity) (Identity, error)
+
+// loginState is the state of a login in progress, sealed in the state cookie
+type loginState struct {
+	Provider string `json:"p"`
+	State    string `json:"s"`
+	Nonce    string `json:"n"`
+	Verifier string `json:"v"`
+	// Return is the local path to send the user back to, once logged in
+	Return  string `json:"r"`
+	Expires int64  `json:"e"`
+}
+
+// randomString returns a random URL-safe string, for the state, the nonce and the PKCE verifier
+func randomString() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// localPath returns the path if it's a local one, or else the default path, so that the logins
+// can't send the users to other sites
+func localPath(path string) string {
+	if !strings.HasPrefix(path, "/") || strings.HasPrefix(path, "//") || strings.HasPrefix(path, "/\\") {
+		return "/me"
+	}
+	return path
+}
+
+func (au *Authenticator) setCookie(w http.ResponseWriter, name, value string, ttl time.Duration) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     "/",
+		MaxAge:   int(ttl / time.Second),
+		HttpOnly: true,
+		Secure:   au.SecureCookies,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+func (au *Authenticator) clearCookie(w http.ResponseWriter, name string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     name,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   au.SecureCookies,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// session returns the identity authenticated by the session cookie of the request
+func (au *Authenticator) session(r *http.Request) (Identity, error) {
+	cookie, err := r.Cookie(SessionCookie)
+	if err != nil {
+		return Identity{}, ErrNoCredentials
+	}
+	id, err := au.Tokens.Verify(cookie.Value)
+	if err != nil {
+		return Identity{}, err
+	}
+	// the tokens issued in exchange of API keys are not sessions
+	if id.KeyID != "" {
+		return Identity{}, ErrInvalidToken
+	}
+	if au.CheckSession == nil {
+		return id, nil
+	}
+	// the user may be gone, or have another role, since the session started
+	checked, err := au.CheckSession(id)
+	if err != nil {
+		return Identity{}, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	return checked, nil
+}
+
+// StartSession sets the session cookie authenticating the identity, until the session expires
+func (au *Authenticator) StartSession(w http.ResponseWriter, id Identity) error {
+	ttl := au.SessionTTL
+	if ttl <= 0 {
+		ttl = DefaultSessionTTL
+	}
+	id.KeyID = ""
+	token, _, err := au.Tokens.Issue(id, ttl)
+	if err != nil {
+		return err
+	}
+	au.setCookie(w, SessionCookie, token, ttl)
+	return nil
+}
+
+// Login returns the handler sending the users to the provider to authenticate, and then back to
+// the Callback of the provider. The "return" query parameter is the local path to send the users
+// to once logged in, "/me" by default.
+func (au *Authenticator) Login(provider string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		p, ok := au.Providers[provider]
+		if !ok {
+			sendError(w, http.StatusNotFound, fmt.Errorf("auth: no provider %q", provider))
+			return
+		}
+		st := loginState{
+			Provider: provider,
+			Return:   localPath(r.URL.Query().Get("return")),
+			Expires:  time.Now().Add(loginTTL).Unix(),
+		}
+		var err error
+		for _, s := range []*string{&st.State, &st.Nonce, &st.Verifier} {
+			if *s, err = randomString(); err != nil {
+				sendError(w, http.StatusInternalServerError, err)
+				return
+			}
+		}
+		sealed, err := au.Tokens.seal(st)
+		if err != nil {
+			sendError(w, http.StatusInternalServerError, err)
+			return
+		}
+		au.setCookie(w, stateCookie, sealed, loginTTL)
+		w.Header().Set("Cache-Control", "no-store")
+		http.Redirect(w, r, p.AuthCodeURL(st.State, st.Nonce, st.Verifier), http.StatusFound)
+	}
+}
+
+// Callback returns the handler the provider sends the users back to, once authenticated. It
+// starts the session of the identity MapClaims maps the claims of the user to, and sends the user
+// to the path given to Login. Answers 400 if the login is not the one in progress, 502 if the
+// provider fails, and 403 if the user is not allowed to log in.
+func (au *Authenticator) Callback(provider string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		p, ok := au.Providers[provider]
+		if !ok {
+			sendError(w, http.StatusNotFound, fmt.Errorf("auth: no provider %q", provider))
+			return
+		}
+		w.Header().Set("Cache-Control", "no-store")
+		query := r.URL.Query()
+		if e := query.Get("error"); e != "" {
+			sendError(w, http.StatusUnauthorized, fmt.Errorf("%w: %s %s", ErrLogin, e, query.Get("error_description")))
+			return
+		}
+		var st loginState
+		cookie, err := r.Cookie(stateCookie)
+		if err == nil {
+			err = au.Tokens.open(cookie.Value, &st)
+		}
+		if err != nil || st.Provider != provider || st.State == "" || st.State != query.Get("state") ||
+			time.Now().Unix() >= st.Expires {
+			sendError(w, http.StatusBadRequest, fmt.Errorf("%w: no such login in progress", ErrLogin))
+			return
+		}
+		au.clearCookie(w, stateCookie)
+
+		claims, err := p.Exchange(r.Context(), query.Get("code"), st.Verifier, st.Nonce)
+		if err != nil {
+			slog.WarnContext(r.Context(), "auth: login failed", "provider", provider, "error", err)
+			sendError(w, http.StatusBadGateway, fmt.Errorf("%w: %v", ErrLogin, err))
+			return
+		}
+		if au.MapClaims == nil {
+			sendError(w, http.StatusForbidden, fmt.Errorf("%w: no users", ErrLogin))
+			return
+		}
+		id, err := au.MapClaims(p, claims)
+		if err != nil {
+			slog.WarnContext(r.Context(), "auth: login denied", "provider", provider, "user", claims.String(p.UserClaim), "error", err)
+			sendError(w, http.StatusForbidden, fmt.Errorf("%w: %v", ErrLogin, err))
+			return
+		}
+		if err := au.StartSession(w, id); err != nil {
+			sendError(w, http.StatusInternalServerError, err)
+			return
+		}
+		slog.InfoContext(r.Context(), "auth: logged in", "provider", provider, "user", id.Name, "scope", id.Scope)
+		http.Redirect(w, r, st.Return, http.StatusFound)
+	}
+}
+
+// Logout ends the session of the request, clearing its cookie
+func (au *Authenticator) Logout(w http.ResponseWriter, r *http.Request) {
+	au.clearCookie(w, SessionCookie)
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusNoContent)
+}
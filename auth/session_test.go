@@ -0,0 +1,185 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gotestbootcamp/go-todo-app/auth/oidc"
+	"github.com/gotestbootcamp/go-todo-app/auth/oidc/oidctest"
+)
+
+func TestSignerSeal(t *testing.T) {
+	sg := NewSigner([]byte("0123456789abcdef"))
+	sealed, err := sg.seal(loginState{State: "s", Return: "/todos"})
+	require.NoError(t, err)
+	var st loginState
+	require.NoError(t, sg.open(sealed, &st))
+	assert.Equal(t, loginState{State: "s", Return: "/todos"}, st)
+
+	assert.ErrorIs(t, NewSigner([]byte("fedcba9876543210")).open(sealed, &st), ErrInvalidToken)
+	assert.ErrorIs(t, sg.open(sealed[1:], &st), ErrInvalidToken)
+	token, _, err := sg.Issue(Identity{Name: "ann", Scope: ScopeRead}, DefaultTokenTTL)
+	require.NoError(t, err)
+	assert.ErrorIs(t, sg.open(token, &st), ErrInvalidToken, "the tokens are not sealed values")
+	_, err = sg.Verify(sealed)
+	assert.ErrorIs(t, err, ErrInvalidToken, "the sealed values are not tokens")
+}
+
+func TestLocalPath(t *testing.T) {
+	assert.Equal(t, "/todos?status=pending", localPath("/todos?status=pending"))
+	for _, path := range []string{"", "todos", "//evil.example.com", "/\\evil.example.com", "https://evil.example.com/"} {
+		assert.Equal(t, "/me", localPath(path), path)
+	}
+}
+
+func TestLogin(t *testing.T) {
+	srv := oidctest.NewServer("todo", "s3cret")
+	defer srv.Close()
+	srv.SetClaims(map[string]any{"preferred_username": "ann", "email": "ann@example.com", "email_verified": true})
+
+	au := newTestAuthenticator(t)
+	p, err := oidc.NewProvider(context.Background(), "sso", oidc.Config{
+		Issuer:       srv.URL,
+		ClientID:     "todo",
+		ClientSecret: "s3cret",
+		RedirectURL:  "http://todo.example.com/auth/oidc/sso/callback",
+	}, nil)
+	require.NoError(t, err)
+	au.Providers = map[string]*oidc.Provider{"sso": p}
+	au.MapClaims = func(p *oidc.Provider, claims oidc.Claims) (Identity, error) {
+		if name := claims.String(p.UserClaim); name == "ann" {
+			return Identity{Name: name, Scope: ScopeWrite}, nil
+		}
+		return Identity{}, errors.New("unknown user")
+	}
+	var got Identity
+	protected := au.Require(ScopeWrite, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, _ = FromContext(r.Context())
+	}))
+
+	// login returns the response of the callback, once logged in with the provider
+	login := func(returnPath string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		au.Login("sso")(rec, httptest.NewRequest("GET", "/auth/oidc/sso/login?return="+url.QueryEscape(returnPath), nil))
+		require.Equal(t, http.StatusFound, rec.Code)
+		code, state := authorize(t, rec.Header().Get("Location"))
+
+		req := httptest.NewRequest("GET", "/auth/oidc/sso/callback?"+url.Values{"code": {code}, "state": {state}}.Encode(), nil)
+		for _, c := range rec.Result().Cookies() {
+			req.AddCookie(c)
+		}
+		rec = httptest.NewRecorder()
+		au.Callback("sso")(rec, req)
+		return rec
+	}
+
+	rec := login("/todos")
+	require.Equal(t, http.StatusFound, rec.Code, rec.Body.String())
+	assert.Equal(t, "/todos", rec.Header().Get("Location"))
+	var session *http.Cookie
+	for _, c := range rec.Result().Cookies() {
+		if c.Name == SessionCookie {
+			session = c
+		}
+	}
+	require.NotNil(t, session)
+	assert.True(t, session.HttpOnly)
+	assert.Equal(t, http.SameSiteLaxMode, session.SameSite)
+
+	req := httptest.NewRequest("POST", "/todos", nil)
+	req.AddCookie(session)
+	rec = httptest.NewRecorder()
+	protected.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, Identity{Name: "ann", Scope: ScopeWrite}, got)
+
+	// the session token is no bearer token, and the bearer tokens are no sessions
+	req = httptest.NewRequest("POST", "/todos", nil)
+	req.Header.Set("Authorization", "Bearer "+session.Value)
+	rec = httptest.NewRecorder()
+	protected.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	token, _, err := au.IssueToken(Identity{Name: "alice", Scope: ScopeAdmin, KeyID: "static-alice"}, ScopeWrite, 0)
+	require.NoError(t, err)
+	req = httptest.NewRequest("POST", "/todos", nil)
+	req.AddCookie(&http.Cookie{Name: SessionCookie, Value: token})
+	rec = httptest.NewRecorder()
+	protected.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	assert.Equal(t, "/me", login("https://evil.example.com").Header().Get("Location"))
+
+	srv.SetClaims(map[string]any{"preferred_username": "mallory"})
+	assert.Equal(t, http.StatusForbidden, login("/").Code)
+
+	// the callbacks need the state of the login in progress
+	rec = httptest.NewRecorder()
+	au.Login("sso")(rec, httptest.NewRequest("GET", "/auth/oidc/sso/login", nil))
+	code, state := authorize(t, rec.Header().Get("Location"))
+	req = httptest.NewRequest("GET", "/auth/oidc/sso/callback?"+url.Values{"code": {code}, "state": {state}}.Encode(), nil)
+	rec = httptest.NewRecorder()
+	au.Callback("sso")(rec, req)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = httptest.NewRecorder()
+	au.Login("other")(rec, httptest.NewRequest("GET", "/auth/oidc/other/login", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = httptest.NewRecorder()
+	au.Logout(rec, httptest.NewRequest("POST", "/auth/logout", nil))
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	require.Len(t, rec.Result().Cookies(), 1)
+	assert.Equal(t, SessionCookie, rec.Result().Cookies()[0].Name)
+	assert.Negative(t, rec.Result().Cookies()[0].MaxAge)
+}
+
+// noRedirects is the client stopping at the redirects, to follow the logins step by step
+var noRedirects = &http.Client{
+	CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+// authorize logs in with the provider, and returns the code and the state it sends back
+func authorize(t *testing.T, authURL string) (code, state string) {
+	resp, err := noRedirects.Get(authURL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+	back, err := url.Parse(resp.Header.Get("Location"))
+	require.NoError(t, err)
+	return back.Query().Get("code"), back.Query().Get("state")
+}
+
+func TestSessionChecked(t *testing.T) {
+	au := newTestAuthenticator(t)
+	role := ScopeWrite
+	au.CheckSession = func(id Identity) (Identity, error) {
+		if role == "" {
+			return Identity{}, errors.New("no such user")
+		}
+		return Identity{Name: id.Name, Scope: role}, nil
+	}
+	rec := httptest.NewRecorder()
+	require.NoError(t, au.StartSession(rec, Identity{Name: "ann", Scope: ScopeAdmin}))
+	session := rec.Result().Cookies()[0]
+	authenticate := func() (Identity, error) {
+		req := httptest.NewRequest("GET", "/todos", nil)
+		req.AddCookie(session)
+		return au.Authenticate(req)
+	}
+
+	id, err := authenticate()
+	require.NoError(t, err)
+	assert.Equal(t, Identity{Name: "ann", Scope: ScopeWrite}, id, "the scope of the current role")
+	role = ""
+	_, err = authenticate()
+	assert.ErrorIs(t, err, ErrInvalidToken, "the user is gone")
+}
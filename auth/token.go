@@ -0,0 +1,128 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidToken = errors.New("auth: invalid token")
+	ErrTokenExpired = errors.New("auth: token expired")
+)
+
+// tokenHeader is the encoded header of the tokens, which are JSON Web Tokens signed with HS256
+var tokenHeader = base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+
+// claims are the claims of the tokens
+type claims struct {
+	Subject string `json:"sub"`
+	Scope   Scope  `json:"scope"`
+	// KeyID is the ID of the API key the token was issued in exchange of
+	KeyID     string `json:"kid"`
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+// Signer issues and verifies the bearer tokens, signing them with HMAC-SHA256
+type Signer struct {
+	secret []byte
+	now    func() time.Time
+}
+
+// NewSigner creates the signer of the tokens with the given secret, which must be kept private:
+// whoever knows it can issue tokens
+func NewSigner(secret []byte) *Signer {
+	return &Signer{secret: secret, now: time.Now}
+}
+
+// RandomSecret generates a random secret for a Signer
+func RandomSecret() ([]byte, error) {
+	secret := make([]byte, 32)
+	_, err := rand.Read(secret)
+	return secret, err
+}
+
+func (sg *Signer) sign(payload string) string {
+	mac := hmac.New(sha256.New, sg.secret)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// Issue returns a token authenticating the identity until it expires, after ttl, along with
+// the expiration time
+func (sg *Signer) Issue(id Identity, ttl time.Duration) (string, time.Time, error) {
+	now := sg.now()
+	exp := now.Add(ttl)
+	data, err := json.Marshal(claims{
+		Subject:   id.Name,
+		Scope:     id.Scope,
+		KeyID:     id.KeyID,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: exp.Unix(),
+	})
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	payload := tokenHeader + "." + base64.RawURLEncoding.EncodeToString(data)
+	return payload + "." + sg.sign(payload), exp, nil
+}
+
+// Verify returns the identity the token authenticates.
+// Fails with ErrInvalidToken if the token is not signed by the signer, and with ErrTokenExpired
+// if it expired.
+func (sg *Signer) Verify(token string) (Identity, error) {
+	header, rest, ok := strings.Cut(token, ".")
+	if !ok || header != tokenHeader {
+		return Identity{}, ErrInvalidToken
+	}
+	body, sig, ok := strings.Cut(rest, ".")
+	if !ok || !hmac.Equal([]byte(sig), []byte(sg.sign(header+"."+body))) {
+		return Identity{}, ErrInvalidToken
+	}
+	data, err := base64.RawURLEncoding.DecodeString(body)
+	if err != nil {
+		return Identity{}, ErrInvalidToken
+	}
+	var cl claims
+	if err := json.Unmarshal(data, &cl); err != nil || !cl.Scope.Valid() {
+		return Identity{}, ErrInvalidToken
+	}
+	if sg.now().Unix() >= cl.ExpiresAt {
+		return Identity{}, ErrTokenExpired
+	}
+	return Identity{Name: cl.Subject, Scope: cl.Scope, KeyID: cl.KeyID}, nil
+}
+
+// sealedPrefix marks the values sealed by the signer, which are not tokens
+const sealedPrefix = "v1."
+
+// seal returns the value encoded and signed, for the clients to hand back unaltered, see open
+func (sg *Signer) seal(v any) (string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	payload := sealedPrefix + base64.RawURLEncoding.EncodeToString(data)
+	return payload + "." + sg.sign(payload), nil
+}
+
+// open decodes into v the value sealed by the signer. Fails with ErrInvalidToken if the value was
+// not sealed by the signer.
+func (sg *Signer) open(sealed string, v any) error {
+	payload, sig, ok := strings.Cut(strings.TrimPrefix(sealed, sealedPrefix), ".")
+	if !ok || !strings.HasPrefix(sealed, sealedPrefix) ||
+		!hmac.Equal([]byte(sig), []byte(sg.sign(sealedPrefix+payload))) {
+		return ErrInvalidToken
+	}
+	data, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil || json.Unmarshal(data, v) != nil {
+		return ErrInvalidToken
+	}
+	return nil
+}
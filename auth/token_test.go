@@ -0,0 +1,44 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSigner(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	sg := NewSigner([]byte("0123456789abcdef"))
+	sg.now = func() time.Time { return now }
+
+	id := Identity{Name: "alice", Scope: ScopeWrite, KeyID: "static-alice"}
+	token, exp, err := sg.Issue(id, time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, now.Add(time.Minute), exp)
+	assert.Len(t, strings.Split(token, "."), 3)
+
+	got, err := sg.Verify(token)
+	require.NoError(t, err)
+	assert.Equal(t, id, got)
+
+	// another secret
+	_, err = NewSigner([]byte("fedcba9876543210")).Verify(token)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+	// tampered claims
+	parts := strings.Split(token, ".")
+	other, _, err := sg.Issue(Identity{Name: "alice", Scope: ScopeAdmin, KeyID: "static-alice"}, time.Minute)
+	require.NoError(t, err)
+	_, err = sg.Verify(parts[0] + "." + strings.Split(other, ".")[1] + "." + parts[2])
+	assert.ErrorIs(t, err, ErrInvalidToken)
+	for _, bad := range []string{"", "garbage", token + "x", "a.b.c"} {
+		_, err = sg.Verify(bad)
+		assert.ErrorIs(t, err, ErrInvalidToken, bad)
+	}
+
+	now = now.Add(time.Minute)
+	_, err = sg.Verify(token)
+	assert.ErrorIs(t, err, ErrTokenExpired)
+}
@@ -0,0 +1,328 @@
+package caldav
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gotestbootcamp/go-todo-app/auth"
+	"github.com/gotestbootcamp/go-todo-app/ical"
+	"github.com/gotestbootcamp/go-todo-app/ledger"
+	"github.com/gotestbootcamp/go-todo-app/store"
+	"github.com/gotestbootcamp/go-todo-app/task"
+	"github.com/gotestbootcamp/go-todo-app/user"
+)
+
+const (
+	// Prefix is the path of the principal of the user, which is also its calendar home
+	Prefix = "/caldav/"
+	// CollectionPath is the path of the collection of the todos, each at its ID, escaped, with
+	// the .ics extension
+	CollectionPath = Prefix + "todos/"
+	// WellKnownPath redirects to the principal, for the clients to discover it, see RFC 6764
+	WellKnownPath = "/.well-known/caldav"
+)
+
+// calendarType is the content type of the todos
+const calendarType = "text/calendar; charset=utf-8"
+
+// defaultActor is who makes the requests of the open collections, in the history of the todos
+const defaultActor = "caldav"
+
+// maxBody bounds the size of the request bodies
+const maxBody = 1 << 20
+
+// Handler serves the CalDAV requests
+type Handler struct {
+	ld *ledger.Ledger
+	// auth authenticates the requests. Nil if the collection is open.
+	auth *auth.Authenticator
+	// users tell which authenticated users are admins. Nil if there are no users.
+	users *user.Directory
+	// loc is the time zone of the dates of the todos
+	loc *time.Location
+}
+
+// New creates the handler of the todos of the ledger, whose requests are authenticated by au.
+// The requests change only the todos of their user, unless admin. The nil au leaves the collection
+// open, and ignores the users.
+func New(ld *ledger.Ledger, au *auth.Authenticator, users *user.Directory) *Handler {
+	if au == nil {
+		users = nil
+	}
+	return &Handler{ld: ld, auth: au, users: users, loc: time.Local}
+}
+
+// ServeHTTP authenticates the request, with the read scope to read the todos and the write one to
+// change them, and serves it
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == WellKnownPath {
+		http.Redirect(w, r, Prefix, http.StatusMovedPermanently)
+		return
+	}
+	w.Header().Set("DAV", "1, 3, calendar-access")
+	if r.Method == http.MethodOptions {
+		w.Header().Set("Allow", "OPTIONS, GET, HEAD, PUT, DELETE, PROPFIND, REPORT")
+		return
+	}
+	ld := h.ld.As(defaultActor)
+	scope, actor := auth.ScopeAdmin, defaultActor
+	if h.auth != nil {
+		id, err := h.auth.Authenticate(r)
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", `Basic realm="todo"`)
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		required := auth.ScopeRead
+		if r.Method == http.MethodPut || r.Method == http.MethodDelete {
+			required = auth.ScopeWrite
+		}
+		if !id.Scope.Allows(required) {
+			http.Error(w, auth.ErrScope{Required: required, Actual: id.Scope}.Error(), http.StatusForbidden)
+			return
+		}
+		ld, scope, actor = h.ld.AsUser(id.Name, h.users.IsAdmin(id)), id.Scope, id.Name
+		r = r.WithContext(auth.NewContext(r.Context(), id))
+	}
+	req := &request{h: h, ld: ld.WithContext(r.Context()), scope: scope, actor: actor}
+
+	id, isTodo := todoID(r.URL.Path)
+	switch {
+	case r.Method == "PROPFIND":
+		req.propfind(w, r)
+	case r.Method == "REPORT" && r.URL.Path == CollectionPath:
+		req.report(w, r)
+	case !isTodo:
+		http.Error(w, fmt.Sprintf("no todo at %s", r.URL.Path), http.StatusMethodNotAllowed)
+	case r.Method == http.MethodGet || r.Method == http.MethodHead:
+		req.get(w, r, id)
+	case r.Method == http.MethodPut:
+		req.put(w, r, id)
+	case r.Method == http.MethodDelete:
+		req.delete(w, r, id)
+	default:
+		http.Error(w, fmt.Sprintf("method %s not allowed", r.Method), http.StatusMethodNotAllowed)
+	}
+}
+
+// request is a request of an authenticated user
+type request struct {
+	h *Handler
+	// ld is the view of the ledger of the user
+	ld *ledger.Ledger
+	// scope is the scope of the credentials of the user
+	scope auth.Scope
+	// actor is the name of the user, the assignee of the todos it completes
+	actor string
+}
+
+// todoID returns the ID of the todo at the path, false if the path isn't the one of a todo
+func todoID(path string) (store.ID, bool) {
+	name, ok := strings.CutPrefix(path, CollectionPath)
+	if !ok || !strings.HasSuffix(name, ".ics") || strings.Contains(name, "/") {
+		return store.NullID, false
+	}
+	id, err := url.PathUnescape(strings.TrimSuffix(name, ".ics"))
+	if err != nil || id == "" {
+		return store.NullID, false
+	}
+	return store.ID(id), true
+}
+
+// todoPath returns the path of the todo
+func todoPath(id store.ID) string {
+	return CollectionPath + url.PathEscape(string(id)) + ".ics"
+}
+
+// etag returns the ETag of the todo, its revision in the ledger, quoted
+func etag(rev int) string {
+	return strconv.Quote(strconv.Itoa(rev))
+}
+
+// encode returns the iCalendar object of the todo, with the UID the client created it with, or else
+// its ID
+func (h *Handler) encode(item ledger.Item) []byte {
+	var buf bytes.Buffer
+	e := ical.Encoder{Component: ical.ToDo, Location: h.loc, Undated: true}
+	uid := item.Task.UID
+	if uid == "" {
+		uid = string(item.ID)
+	}
+	// the writes to a buffer don't fail
+	_ = e.Encode(&buf, []ical.Todo{{ID: string(item.ID), UID: uid, Task: *item.Task}})
+	return buf.Bytes()
+}
+
+// todo returns the todo with its revision, failing with store.ErrNotFound if deleted
+func (req *request) todo(id store.ID) (ledger.Item, int, error) {
+	item, rev, err := req.ld.GetItem(id)
+	if err == nil && item.Task.Status == task.Deleted {
+		err = store.ErrNotFound{ID: id}
+	}
+	return item, rev, err
+}
+
+func (req *request) get(w http.ResponseWriter, r *http.Request, id store.ID) {
+	item, rev, err := req.todo(id)
+	if err != nil {
+		sendError(w, err)
+		return
+	}
+	w.Header().Set("ETag", etag(rev))
+	w.Header().Set("Content-Type", calendarType)
+	if r.Method == http.MethodGet {
+		w.Write(req.h.encode(item))
+	}
+}
+
+// put creates the todo of the VTODO of the request, or updates it at once. The new todos are
+// imported with the ID of their path, see ledger.ImportAll, keeping their UID. The clients may send the If-Match header to update the
+// todo only if its ETag matches, or If-None-Match: * to create it only if it doesn't exist.
+func (req *request) put(w http.ResponseWriter, r *http.Request, id store.ID) {
+	todos, err := ical.Decode(io.LimitReader(r.Body, maxBody), req.h.loc)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(todos) != 1 {
+		http.Error(w, fmt.Sprintf("want one VTODO, got %d", len(todos)), http.StatusBadRequest)
+		return
+	}
+	expected, err := ifMatch(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	tk := todos[0].Task
+	tk.UID = todos[0].UID
+
+	_, _, err = req.todo(id)
+	var notFound store.ErrNotFound
+	switch {
+	case errors.As(err, &notFound) && expected != ledger.AnyRevision:
+		http.Error(w, err.Error(), http.StatusPreconditionFailed)
+		return
+	case errors.As(err, &notFound):
+		report, err := req.ld.ImportAll(ledger.Items{{ID: id, Task: &tk}})
+		if err == nil {
+			err = report[0].Err
+		}
+		if err != nil {
+			sendError(w, err)
+			return
+		}
+		rev, _ := req.ld.Revision(id)
+		slog.InfoContext(r.Context(), "CalDAV: created object", "id", id)
+		w.Header().Set("ETag", etag(rev))
+		w.WriteHeader(http.StatusCreated)
+		return
+	case err != nil:
+		sendError(w, err)
+		return
+	case r.Header.Get("If-None-Match") == "*":
+		http.Error(w, fmt.Sprintf("todo %s exists", id), http.StatusPreconditionFailed)
+		return
+	}
+
+	_, rev, err := req.ld.PatchFuncIf(id, func(cur ledger.Item) (ledger.Patch, error) {
+		if cur.Task.Status == task.Deleted {
+			return ledger.Patch{}, store.ErrNotFound{ID: id}
+		}
+		patch := ledger.Patch{
+			Title:       &tk.Title,
+			Description: &tk.Description,
+			Priority:    &tk.Priority,
+			Tags:        &tk.Tags,
+			Schedule:    &ledger.Schedule{Due: tk.Due, Remind: tk.Remind, Recur: tk.Recur},
+		}
+		// the clients only tell whether the todos are completed: the active todos stay pending or
+		// assigned, and the ones completed are assigned to the user first if nobody is, like todo done
+		completing := tk.Status == task.Completed && isActive(cur.Task.Status)
+		if completing && cur.Task.Assignee == "" {
+			patch.Assignee = &req.actor
+		}
+		if completing || tk.Status != cur.Task.Status && !(isActive(tk.Status) && isActive(cur.Task.Status)) {
+			patch.Status = &tk.Status
+		}
+		return patch, nil
+	}, expected)
+	if err != nil {
+		sendError(w, err)
+		return
+	}
+	slog.InfoContext(r.Context(), "CalDAV: updated object", "id", id)
+	w.Header().Set("ETag", etag(rev))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// isActive tells whether the todos in the status are still to complete, as the clients tell them
+func isActive(status task.Status) bool {
+	return status == task.Pending || status == task.Assigned
+}
+
+// delete removes the todo from the ledger, if its ETag matches the If-Match header: the todos
+// completed can't be deleted by the workflow, and the clients expect them gone anyway
+func (req *request) delete(w http.ResponseWriter, r *http.Request, id store.ID) {
+	expected, err := ifMatch(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if _, _, err := req.todo(id); err != nil {
+		sendError(w, err)
+		return
+	}
+	if err := req.ld.DeleteIf(id, expected); err != nil {
+		sendError(w, err)
+		return
+	}
+	slog.InfoContext(r.Context(), "CalDAV: deleted object", "id", id)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ifMatch returns the revision the If-Match header of the request expects the todo to have,
+// or ledger.AnyRevision if the header is missing or "*"
+func ifMatch(r *http.Request) (int, error) {
+	val := strings.TrimSpace(r.Header.Get("If-Match"))
+	if val == "" || val == "*" {
+		return ledger.AnyRevision, nil
+	}
+	unquoted, err := strconv.Unquote(strings.TrimPrefix(val, "W/"))
+	if err != nil {
+		return 0, fmt.Errorf("invalid If-Match %q", val)
+	}
+	rev, err := strconv.Atoi(unquoted)
+	if err != nil || rev < 0 {
+		return 0, fmt.Errorf("invalid If-Match %q", val)
+	}
+	return rev, nil
+}
+
+// sendError answers the request with the error of the ledger: 404 if the todo doesn't exist, 412
+// if its revision doesn't match, 403 if the user may not change it, 409 if the workflow doesn't
+// allow its new status, 422 otherwise
+func sendError(w http.ResponseWriter, err error) {
+	var notFound store.ErrNotFound
+	var conflict store.ErrConflict
+	var illegal task.ErrIllegalTransition
+	switch {
+	case errors.As(err, &notFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.As(err, &conflict):
+		http.Error(w, err.Error(), http.StatusPreconditionFailed)
+	case errors.Is(err, ledger.ErrForbidden):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.As(err, &illegal):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+	}
+}
@@ -0,0 +1,169 @@
+package caldav
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gotestbootcamp/go-todo-app/auth"
+	"github.com/gotestbootcamp/go-todo-app/ledger"
+	"github.com/gotestbootcamp/go-todo-app/store"
+	"github.com/gotestbootcamp/go-todo-app/task"
+)
+
+func newTestHandler(t *testing.T, au *auth.Authenticator) (*Handler, *ledger.Ledger) {
+	mem, err := store.NewMemory()
+	require.NoError(t, err)
+	ld, err := ledger.New(mem)
+	require.NoError(t, err)
+	h := New(ld, au, nil)
+	h.loc = time.UTC
+	return h, ld
+}
+
+func serve(h http.Handler, method, path, body string, headers ...string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	return w
+}
+
+const testVTodo = "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nBEGIN:VTODO\r\nUID:A1B2\r\nSUMMARY:%s\r\nDUE;VALUE=DATE:20261101\r\nSTATUS:%s\r\nCATEGORIES:finance\r\nEND:VTODO\r\nEND:VCALENDAR\r\n"
+
+func vtodo(summary, status string) string {
+	return strings.NewReplacer("%s", summary).Replace(strings.Replace(testVTodo, "STATUS:%s", "STATUS:"+status, 1))
+}
+
+func TestDiscovery(t *testing.T) {
+	h, _ := newTestHandler(t, nil)
+	w := serve(h, http.MethodGet, WellKnownPath, "")
+	assert.Equal(t, http.StatusMovedPermanently, w.Code)
+	assert.Equal(t, Prefix, w.Header().Get("Location"))
+
+	w = serve(h, http.MethodOptions, CollectionPath, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("DAV"), "calendar-access")
+
+	w = serve(h, "PROPFIND", Prefix, `<?xml version="1.0"?><D:propfind xmlns:D="DAV:" xmlns:C="urn:ietf:params:xml:ns:caldav"><D:prop><D:current-user-principal/><C:calendar-home-set/><D:getlastmodified/></D:prop></D:propfind>`, "Depth", "0")
+	require.Equal(t, http.StatusMultiStatus, w.Code)
+	body := w.Body.String()
+	assert.Contains(t, body, "<D:current-user-principal><D:href>/caldav/</D:href></D:current-user-principal><C:calendar-home-set><D:href>/caldav/</D:href></C:calendar-home-set>")
+	assert.Contains(t, body, "<D:getlastmodified/></D:prop><D:status>HTTP/1.1 404 Not Found</D:status>")
+
+	w = serve(h, "PROPFIND", Prefix, "", "Depth", "1")
+	require.Equal(t, http.StatusMultiStatus, w.Code)
+	assert.Contains(t, w.Body.String(), "<D:href>/caldav/todos/</D:href>")
+	assert.Contains(t, w.Body.String(), `<C:supported-calendar-component-set><C:comp name="VTODO"/></C:supported-calendar-component-set>`)
+}
+
+func TestSync(t *testing.T) {
+	h, ld := newTestHandler(t, nil)
+	path := CollectionPath + "A1B2.ics"
+
+	w := serve(h, http.MethodPut, path, vtodo("pay rent", "NEEDS-ACTION"), "If-None-Match", "*")
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	assert.Equal(t, `"1"`, w.Header().Get("ETag"))
+	item, _, err := ld.GetItem("A1B2")
+	require.NoError(t, err)
+	assert.Equal(t, "pay rent", item.Task.Title)
+	assert.Equal(t, []string{"finance"}, item.Task.Tags)
+	assert.Equal(t, time.Date(2026, time.November, 1, 0, 0, 0, 0, time.UTC), *item.Task.Due)
+	assert.Equal(t, http.StatusPreconditionFailed, serve(h, http.MethodPut, path, vtodo("pay rent", "NEEDS-ACTION"), "If-None-Match", "*").Code)
+
+	w = serve(h, http.MethodGet, path, "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `"1"`, w.Header().Get("ETag"))
+	assert.Contains(t, w.Body.String(), "UID:A1B2\r\n")
+	assert.Contains(t, w.Body.String(), "SUMMARY:pay rent\r\n")
+
+	w = serve(h, "PROPFIND", CollectionPath, `<D:propfind xmlns:D="DAV:" xmlns:CS="http://calendarserver.org/ns/"><D:prop><D:getetag/><CS:getctag/></D:prop></D:propfind>`, "Depth", "1")
+	require.Equal(t, http.StatusMultiStatus, w.Code)
+	assert.Contains(t, w.Body.String(), `<D:response><D:href>/caldav/todos/A1B2.ics</D:href><D:propstat><D:prop><D:getetag>&#34;1&#34;</D:getetag></D:prop>`)
+	ctag := w.Body.String()
+
+	// the stale updates fail
+	assert.Equal(t, http.StatusPreconditionFailed, serve(h, http.MethodPut, path, vtodo("pay the rent", "NEEDS-ACTION"), "If-Match", `"0"`).Code)
+	w = serve(h, http.MethodPut, path, vtodo("pay the rent", "COMPLETED"), "If-Match", `"1"`)
+	require.Equal(t, http.StatusNoContent, w.Code, w.Body.String())
+	// assigned to the user and completed at once
+	assert.Equal(t, `"2"`, w.Header().Get("ETag"))
+	item, _, err = ld.GetItem("A1B2")
+	require.NoError(t, err)
+	assert.Equal(t, "pay the rent", item.Task.Title)
+	assert.Equal(t, task.Completed, item.Task.Status)
+	assert.Equal(t, defaultActor, item.Task.Assignee)
+
+	w = serve(h, "PROPFIND", CollectionPath, `<D:propfind xmlns:D="DAV:" xmlns:CS="http://calendarserver.org/ns/"><D:prop><D:getetag/><CS:getctag/></D:prop></D:propfind>`, "Depth", "1")
+	assert.NotEqual(t, ctag, w.Body.String())
+
+	w = serve(h, "REPORT", CollectionPath, `<C:calendar-multiget xmlns:D="DAV:" xmlns:C="urn:ietf:params:xml:ns:caldav"><D:prop><D:getetag/><C:calendar-data/></D:prop><D:href>/caldav/todos/A1B2.ics</D:href><D:href>/caldav/todos/gone.ics</D:href></C:calendar-multiget>`)
+	require.Equal(t, http.StatusMultiStatus, w.Code)
+	assert.Contains(t, w.Body.String(), "STATUS:COMPLETED")
+	assert.Contains(t, w.Body.String(), "<D:href>/caldav/todos/gone.ics</D:href><D:status>HTTP/1.1 404 Not Found</D:status>")
+
+	query := `<C:calendar-query xmlns:D="DAV:" xmlns:C="urn:ietf:params:xml:ns:caldav"><D:prop><D:getetag/></D:prop><C:filter><C:comp-filter name="VCALENDAR"><C:comp-filter name="%s"/></C:comp-filter></C:filter></C:calendar-query>`
+	w = serve(h, "REPORT", CollectionPath, strings.Replace(query, "%s", "VTODO", 1))
+	assert.Contains(t, w.Body.String(), "/caldav/todos/A1B2.ics")
+	w = serve(h, "REPORT", CollectionPath, strings.Replace(query, "%s", "VEVENT", 1))
+	assert.NotContains(t, w.Body.String(), "/caldav/todos/A1B2.ics")
+
+	assert.Equal(t, http.StatusPreconditionFailed, serve(h, http.MethodDelete, path, "", "If-Match", `"1"`).Code)
+	assert.Equal(t, http.StatusNoContent, serve(h, http.MethodDelete, path, "", "If-Match", `"2"`).Code)
+	assert.Equal(t, http.StatusNotFound, serve(h, http.MethodGet, path, "").Code)
+	assert.Equal(t, http.StatusBadRequest, serve(h, http.MethodPut, CollectionPath+"bad.ics", "BEGIN:VCALENDAR\r\nEND:VCALENDAR\r\n").Code)
+}
+
+func TestPutUID(t *testing.T) {
+	h, ld := newTestHandler(t, nil)
+	path := CollectionPath + "X9.ics"
+
+	// the clients may name their objects after something else than their UID
+	require.Equal(t, http.StatusCreated, serve(h, http.MethodPut, path, vtodo("pay rent", "NEEDS-ACTION")).Code)
+	item, _, err := ld.GetItem("X9")
+	require.NoError(t, err)
+	assert.Equal(t, "A1B2", item.Task.UID)
+
+	w := serve(h, http.MethodGet, path, "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "UID:A1B2\r\n")
+	require.Equal(t, http.StatusNoContent, serve(h, http.MethodPut, path, vtodo("pay the rent", "NEEDS-ACTION"), "If-Match", `"1"`).Code)
+	assert.Contains(t, serve(h, http.MethodGet, path, "").Body.String(), "UID:A1B2\r\n")
+}
+
+func TestAuth(t *testing.T) {
+	mem, err := store.NewMemory()
+	require.NoError(t, err)
+	keys, err := auth.NewKeyring(mem)
+	require.NoError(t, err)
+	require.NoError(t, keys.AddStatic("alice", auth.ScopeWrite, "s3cret-alice"))
+	require.NoError(t, keys.AddStatic("bob", auth.ScopeRead, "s3cret-bob"))
+	h, ld := newTestHandler(t, auth.NewAuthenticator(keys, auth.NewSigner([]byte("0123456789abcdef"))))
+	path := CollectionPath + "A1B2.ics"
+
+	w := serve(h, "PROPFIND", Prefix, "")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Equal(t, `Basic realm="todo"`, w.Header().Get("WWW-Authenticate"))
+
+	req := httptest.NewRequest(http.MethodPut, path, strings.NewReader(vtodo("pay rent", "NEEDS-ACTION")))
+	req.SetBasicAuth("bob", "s3cret-bob")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	req = httptest.NewRequest(http.MethodPut, path, strings.NewReader(vtodo("pay rent", "NEEDS-ACTION")))
+	req.SetBasicAuth("alice", "s3cret-alice")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	item, _, err := ld.GetItem("A1B2")
+	require.NoError(t, err)
+	assert.Equal(t, "alice", item.Task.Owner)
+}
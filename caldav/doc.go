@@ -0,0 +1,9 @@
+// Package caldav implements a CalDAV task collection on top of the ledger, see RFC 4791, like the
+// controller package implements the REST API, for the native task clients, e.g. Apple Reminders,
+// Tasks.org or Thunderbird, to sync the todos as VTODO components, see package ical.
+//
+// The principal of the user, its calendar home and the collection of its todos are at fixed paths,
+// which the clients discover from /.well-known/caldav. The clients sync comparing the ETags of the
+// todos, their revisions in the ledger, and the CTag of the collection, which changes with any of
+// them; they authenticate with the API key as the password of their basic credentials.
+package caldav
@@ -0,0 +1,343 @@
+package caldav
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/gotestbootcamp/go-todo-app/auth"
+	"github.com/gotestbootcamp/go-todo-app/ledger"
+	"github.com/gotestbootcamp/go-todo-app/model"
+	"github.com/gotestbootcamp/go-todo-app/task"
+)
+
+// The namespaces of the properties, with the prefixes of the responses
+const (
+	nsDAV    = "DAV:"
+	nsCalDAV = "urn:ietf:params:xml:ns:caldav"
+	// nsCS is the namespace of the extensions of the Calendar Server, like the CTag
+	nsCS = "http://calendarserver.org/ns/"
+)
+
+// prefixes are the prefixes of the namespaces in the responses
+var prefixes = map[string]string{nsDAV: "D", nsCalDAV: "C", nsCS: "CS"}
+
+// calendarData is the property of the iCalendar object of a todo, which PROPFIND doesn't list
+var calendarData = xml.Name{Space: nsCalDAV, Local: "calendar-data"}
+
+// props are the properties of a resource, by name, as XML
+type props map[xml.Name]string
+
+// resource is a resource of a multi-status response, with its properties
+type resource struct {
+	href  string
+	props props
+}
+
+// propNames are the names of the properties of a request
+type propNames struct {
+	Names []struct {
+		XMLName xml.Name
+	} `xml:",any"`
+}
+
+// propfindBody is the body of a PROPFIND request: either the properties to get, or all of them
+type propfindBody struct {
+	XMLName xml.Name   `xml:"DAV: propfind"`
+	AllProp *struct{}  `xml:"DAV: allprop"`
+	Prop    *propNames `xml:"DAV: prop"`
+}
+
+// reportBody is the body of a calendar-query or calendar-multiget REPORT request: the properties
+// to get of the todos, filtered by the kind of component, or at the paths
+type reportBody struct {
+	XMLName xml.Name
+	Prop    *propNames `xml:"DAV: prop"`
+	Hrefs   []string   `xml:"DAV: href"`
+	Filter  struct {
+		Calendar struct {
+			Components []struct {
+				Name string `xml:"name,attr"`
+			} `xml:"urn:ietf:params:xml:ns:caldav comp-filter"`
+		} `xml:"urn:ietf:params:xml:ns:caldav comp-filter"`
+	} `xml:"urn:ietf:params:xml:ns:caldav filter"`
+}
+
+// names returns the names of the properties, nil if none
+func (pn *propNames) names() []xml.Name {
+	if pn == nil {
+		return nil
+	}
+	names := make([]xml.Name, 0, len(pn.Names))
+	for _, n := range pn.Names {
+		names = append(names, n.XMLName)
+	}
+	return names
+}
+
+// decodeBody decodes the XML body of the request, if any
+func decodeBody(r *http.Request, v any) (bool, error) {
+	err := xml.NewDecoder(io.LimitReader(r.Body, maxBody)).Decode(v)
+	if errors.Is(err, io.EOF) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("invalid XML body: %w", err)
+	}
+	return true, nil
+}
+
+// propfind answers the properties of the resource at the path, and of its members if the Depth
+// header is 1 or infinity. Without a body, or with allprop, all the properties but the iCalendar
+// objects of the todos.
+func (req *request) propfind(w http.ResponseWriter, r *http.Request) {
+	var body propfindBody
+	found, err := decodeBody(r, &body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var names []xml.Name
+	if found && body.AllProp == nil {
+		names = body.Prop.names()
+	}
+	depth := r.Header.Get("Depth")
+	members := depth == "1" || depth == "infinity"
+
+	var resources []resource
+	id, isTodo := todoID(r.URL.Path)
+	switch {
+	case r.URL.Path == Prefix:
+		resources = append(resources, resource{href: Prefix, props: req.principalProps()})
+		if members {
+			resources = append(resources, resource{href: CollectionPath, props: nil})
+		}
+	case r.URL.Path == CollectionPath:
+		resources = append(resources, resource{href: CollectionPath, props: nil})
+	case isTodo:
+		item, rev, err := req.todo(id)
+		if err != nil {
+			sendError(w, err)
+			return
+		}
+		resources = append(resources, resource{href: todoPath(id), props: req.todoProps(item, rev, false)})
+	default:
+		http.Error(w, fmt.Sprintf("nothing at %s", r.URL.Path), http.StatusNotFound)
+		return
+	}
+	// the collection lists the todos, whose revisions make its CTag
+	for i := range resources {
+		if resources[i].href != CollectionPath {
+			continue
+		}
+		todos, err := req.todos()
+		if err != nil {
+			sendError(w, err)
+			return
+		}
+		resources[i].props = req.collectionProps(todos)
+		if members && r.URL.Path == CollectionPath {
+			resources = append(resources, todos...)
+		}
+	}
+	writeMultiStatus(w, resources, names)
+}
+
+// report answers the properties of the todos of the collection: all of them for calendar-query,
+// unless it filters the VEVENT components, and the ones at the paths for calendar-multiget
+func (req *request) report(w http.ResponseWriter, r *http.Request) {
+	var body reportBody
+	if _, err := decodeBody(r, &body); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	names := body.Prop.names()
+	var resources []resource
+	switch body.XMLName {
+	case xml.Name{Space: nsCalDAV, Local: "calendar-query"}:
+		for _, comp := range body.Filter.Calendar.Components {
+			if !strings.EqualFold(comp.Name, "VTODO") {
+				writeMultiStatus(w, nil, names)
+				return
+			}
+		}
+		todos, err := req.todos()
+		if err != nil {
+			sendError(w, err)
+			return
+		}
+		resources = todos
+	case xml.Name{Space: nsCalDAV, Local: "calendar-multiget"}:
+		for _, href := range body.Hrefs {
+			path := href
+			if u, err := url.Parse(href); err == nil {
+				path = u.Path
+			}
+			res := resource{href: href}
+			if id, ok := todoID(path); ok {
+				if item, rev, err := req.todo(id); err == nil {
+					res.props = req.todoProps(item, rev, true)
+				}
+			}
+			resources = append(resources, res)
+		}
+	default:
+		http.Error(w, fmt.Sprintf("unsupported report %s", body.XMLName.Local), http.StatusForbidden)
+		return
+	}
+	writeMultiStatus(w, resources, names)
+}
+
+// todos returns the resources of the todos of the user, but the deleted ones, sorted by ID, with
+// their iCalendar objects
+func (req *request) todos() ([]resource, error) {
+	items, err := req.ld.Filter(func(todo model.Todo) bool {
+		return string(todo.Status) != string(task.Deleted)
+	})
+	if err != nil {
+		return nil, err
+	}
+	slices.SortFunc(items, func(a, b ledger.Item) int {
+		return strings.Compare(string(a.ID), string(b.ID))
+	})
+	resources := make([]resource, 0, len(items))
+	for _, item := range items {
+		rev, err := req.ld.Revision(item.ID)
+		if err != nil {
+			return nil, err
+		}
+		resources = append(resources, resource{href: todoPath(item.ID), props: req.todoProps(item, rev, true)})
+	}
+	return resources, nil
+}
+
+// principalProps returns the properties of the principal of the user, also its calendar home
+func (req *request) principalProps() props {
+	return props{
+		{Space: nsDAV, Local: "resourcetype"}:           "<D:collection/><D:principal/>",
+		{Space: nsDAV, Local: "displayname"}:            "todo",
+		{Space: nsDAV, Local: "current-user-principal"}: href(Prefix),
+		{Space: nsDAV, Local: "principal-URL"}:          href(Prefix),
+		{Space: nsCalDAV, Local: "calendar-home-set"}:   href(Prefix),
+	}
+}
+
+// collectionProps returns the properties of the collection of the todos: its CTag is a hash of the
+// paths and the ETags of the todos
+func (req *request) collectionProps(todos []resource) props {
+	hash := sha256.New()
+	for _, todo := range todos {
+		fmt.Fprintf(hash, "%s %s\n", todo.href, todo.props[xml.Name{Space: nsDAV, Local: "getetag"}])
+	}
+	privileges := "<D:privilege><D:read/></D:privilege>"
+	if req.scope.Allows(auth.ScopeWrite) {
+		privileges += "<D:privilege><D:write/></D:privilege><D:privilege><D:write-content/></D:privilege><D:privilege><D:bind/></D:privilege><D:privilege><D:unbind/></D:privilege>"
+	}
+	return props{
+		{Space: nsDAV, Local: "resourcetype"}:                        "<D:collection/><C:calendar/>",
+		{Space: nsDAV, Local: "displayname"}:                         "todo",
+		{Space: nsDAV, Local: "current-user-principal"}:              href(Prefix),
+		{Space: nsDAV, Local: "current-user-privilege-set"}:          privileges,
+		{Space: nsDAV, Local: "supported-report-set"}:                `<D:supported-report><D:report><C:calendar-query/></D:report></D:supported-report><D:supported-report><D:report><C:calendar-multiget/></D:report></D:supported-report>`,
+		{Space: nsCalDAV, Local: "supported-calendar-component-set"}: `<C:comp name="VTODO"/>`,
+		{Space: nsCS, Local: "getctag"}:                              hex.EncodeToString(hash.Sum(nil)[:16]),
+	}
+}
+
+// todoProps returns the properties of the todo, with its iCalendar object if withData
+func (req *request) todoProps(item ledger.Item, rev int, withData bool) props {
+	p := props{
+		{Space: nsDAV, Local: "resourcetype"}:           "",
+		{Space: nsDAV, Local: "getetag"}:                escape(etag(rev)),
+		{Space: nsDAV, Local: "getcontenttype"}:         escape(calendarType + "; component=vtodo"),
+		{Space: nsDAV, Local: "current-user-principal"}: href(Prefix),
+	}
+	if withData {
+		p[calendarData] = escape(string(req.h.encode(item)))
+	}
+	return p
+}
+
+// href returns the XML of the path as an href
+func href(path string) string {
+	return "<D:href>" + escape(path) + "</D:href>"
+}
+
+// escape escapes the text for XML
+func escape(s string) string {
+	var b strings.Builder
+	_ = xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+// writeMultiStatus writes the multi-status response of the properties of the resources: the names
+// given, each in a propstat of status 200 or 404, or else all of them, but the iCalendar objects.
+// The resources without properties are not found.
+func writeMultiStatus(w http.ResponseWriter, resources []resource, names []xml.Name) {
+	var b strings.Builder
+	b.WriteString(xml.Header)
+	b.WriteString(`<D:multistatus xmlns:D="DAV:" xmlns:C="urn:ietf:params:xml:ns:caldav" xmlns:CS="http://calendarserver.org/ns/">`)
+	for _, res := range resources {
+		b.WriteString("<D:response>" + href(res.href))
+		if res.props == nil {
+			b.WriteString("<D:status>HTTP/1.1 404 Not Found</D:status></D:response>")
+			continue
+		}
+		wanted := names
+		if wanted == nil {
+			for name := range res.props {
+				if name != calendarData {
+					wanted = append(wanted, name)
+				}
+			}
+			slices.SortFunc(wanted, func(a, b xml.Name) int {
+				return strings.Compare(a.Space+a.Local, b.Space+b.Local)
+			})
+		}
+		var ok, missing strings.Builder
+		for _, name := range wanted {
+			value, found := res.props[name]
+			if found {
+				writeProp(&ok, name, value)
+			} else {
+				writeProp(&missing, name, "")
+			}
+		}
+		if ok.Len() > 0 {
+			b.WriteString("<D:propstat><D:prop>" + ok.String() + "</D:prop><D:status>HTTP/1.1 200 OK</D:status></D:propstat>")
+		}
+		if missing.Len() > 0 {
+			b.WriteString("<D:propstat><D:prop>" + missing.String() + "</D:prop><D:status>HTTP/1.1 404 Not Found</D:status></D:propstat>")
+		}
+		b.WriteString("</D:response>")
+	}
+	b.WriteString("</D:multistatus>\n")
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.WriteHeader(http.StatusMultiStatus)
+	io.WriteString(w, b.String())
+}
+
+// writeProp writes the element of the property, with the XML of its value. The properties of the
+// other namespaces declare theirs.
+func writeProp(b *strings.Builder, name xml.Name, value string) {
+	tag := name.Local
+	if prefix, ok := prefixes[name.Space]; ok {
+		tag = prefix + ":" + name.Local
+	}
+	b.WriteString("<" + tag)
+	if _, ok := prefixes[name.Space]; !ok && name.Space != "" {
+		b.WriteString(` xmlns="` + escape(name.Space) + `"`)
+	}
+	if value == "" {
+		b.WriteString("/>")
+		return
+	}
+	b.WriteString(">" + value + "</" + tag + ">")
+}
@@ -0,0 +1,281 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"runtime"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/gotestbootcamp/go-todo-app/ledger"
+	"github.com/gotestbootcamp/go-todo-app/model"
+	"github.com/gotestbootcamp/go-todo-app/store"
+)
+
+// snoozeAction is the action of the desktop notifications snoozing the reminder of their todo
+const snoozeAction = "snooze"
+
+var agentCommand = Command{
+	Name: "agent",
+	Help: "watch the todos in the background, raising desktop notifications when their reminders and due dates come",
+	Setup: func(flags *flag.FlagSet) func(*App, []string) error {
+		interval := flags.Duration("interval", time.Minute, "how often to check for the reminders and the due dates come, which bounds how late the notifications are")
+		snooze := flags.Duration("snooze", 10*time.Minute, "how long the notifications snoozed wait to remind again")
+		notifier := flags.String("notifier", "", "command raising the notifications: notify-send or osascript (default: osascript on macOS, notify-send elsewhere)")
+		return func(app *App, args []string) error {
+			if len(args) != 0 || *interval <= 0 || *snooze <= 0 {
+				return errUsage
+			}
+			dn, err := newDesktopNotifier(*notifier)
+			if err != nil {
+				return err
+			}
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			a := newAgent(app.Ledger, dn, *snooze, app.Out)
+			fmt.Fprintf(app.Out, "watching the todos every %v, ^C to stop\n", *interval)
+			return a.run(ctx, *interval)
+		}
+	},
+}
+
+var snoozeCommand = Command{
+	Name: "snooze",
+	Args: "<id>",
+	Help: "remind of a todo again later, e.g. after todo agent notified of it",
+	Setup: func(flags *flag.FlagSet) func(*App, []string) error {
+		d := flags.Duration("for", 10*time.Minute, "how long to wait to remind again")
+		return func(app *App, args []string) error {
+			id, err := oneID(args)
+			if err != nil {
+				return err
+			}
+			if *d <= 0 {
+				return errUsage
+			}
+			item, err := snooze(app.Ledger, id, time.Now().Add(*d))
+			if err != nil {
+				return err
+			}
+			return app.printItem(item)
+		}
+	},
+}
+
+// snooze reschedules the reminder of the todo at the given time, leaving when it's due and how it
+// recurs as they are
+func snooze(ld *ledger.Ledger, id store.ID, until time.Time) (ledger.Item, error) {
+	item, _, err := ld.PatchFuncIf(id, func(cur ledger.Item) (ledger.Patch, error) {
+		return ledger.Patch{Schedule: &ledger.Schedule{Due: cur.Task.Due, Remind: &until, Recur: cur.Task.Recur}}, nil
+	}, ledger.AnyRevision)
+	return item, err
+}
+
+// notification is a desktop notification of a todo
+type notification struct {
+	Title string
+	Body  string
+	// Urgent notifications stay until dismissed, where supported
+	Urgent bool
+}
+
+// desktopNotifier raises the desktop notifications
+type desktopNotifier interface {
+	// Notify raises the notification, and returns the action the user chose on it, e.g.
+	// snoozeAction, or empty if none or if the notifications have no actions
+	Notify(ctx context.Context, n notification) (string, error)
+}
+
+// newDesktopNotifier returns the notifier running the command of the given name, or else the one
+// of the operating system
+func newDesktopNotifier(name string) (desktopNotifier, error) {
+	if name == "" {
+		name = "notify-send"
+		if runtime.GOOS == "darwin" {
+			name = "osascript"
+		}
+	}
+	path, err := exec.LookPath(name)
+	if err != nil {
+		return nil, fmt.Errorf("can't raise the desktop notifications: %w", err)
+	}
+	switch name {
+	case "notify-send":
+		// the actions came with libnotify 0.7.9, and make notify-send wait for the notification to close
+		help, _ := exec.Command(path, "--help").Output()
+		return notifySend{path: path, actions: strings.Contains(string(help), "--action")}, nil
+	case "osascript":
+		return osascript{path: path}, nil
+	}
+	return nil, fmt.Errorf("unknown notifier %q, want notify-send or osascript", name)
+}
+
+// notifySend raises the notifications with notify-send, of libnotify
+type notifySend struct {
+	path string
+	// actions tells whether notify-send supports the actions, offering to snooze
+	actions bool
+}
+
+func (ns notifySend) Notify(ctx context.Context, n notification) (string, error) {
+	args := []string{"--app-name=todo", "--urgency=normal"}
+	if n.Urgent {
+		args[1] = "--urgency=critical"
+	}
+	if ns.actions {
+		args = append(args, "--action="+snoozeAction+"=Snooze")
+	}
+	out, err := exec.CommandContext(ctx, ns.path, append(args, "--", n.Title, n.Body)...).Output()
+	if err != nil {
+		return "", fmt.Errorf("notify-send: %w", err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// osascript raises the notifications with AppleScript, on macOS. They have no actions: the todos
+// are snoozed with todo snooze.
+type osascript struct {
+	path string
+}
+
+func (oa osascript) Notify(ctx context.Context, n notification) (string, error) {
+	script := fmt.Sprintf("display notification %s with title \"todo\" subtitle %s", appleScriptString(n.Body), appleScriptString(n.Title))
+	if err := exec.CommandContext(ctx, oa.path, "-e", script).Run(); err != nil {
+		return "", fmt.Errorf("osascript: %w", err)
+	}
+	return "", nil
+}
+
+// appleScriptString returns the AppleScript string literal of s
+func appleScriptString(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// agent raises the desktop notifications of the reminders and of the due dates of the todos of a
+// ledger, which it reloads to see the changes the other processes make
+type agent struct {
+	ld       *ledger.Ledger
+	notifier desktopNotifier
+	// snoozeFor is how long the notifications snoozed wait to remind again
+	snoozeFor time.Duration
+	// out is where the notifications raised are logged
+	out  io.Writer
+	now  func() time.Time
+	lock sync.Mutex
+	// notifying are the notifications raised and not closed yet
+	notifying sync.WaitGroup
+}
+
+func newAgent(ld *ledger.Ledger, dn desktopNotifier, snoozeFor time.Duration, out io.Writer) *agent {
+	return &agent{
+		ld:        ld,
+		notifier:  dn,
+		snoozeFor: snoozeFor,
+		out:       out,
+		now:       time.Now,
+	}
+}
+
+// run checks every interval for the reminders and the due dates come, until the context is done.
+// Only the ones coming while it runs are notified, like ledger.Scheduler does.
+func (a *agent) run(ctx context.Context, interval time.Duration) error {
+	defer a.notifying.Wait()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	last := a.now()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			now := a.now()
+			if err := a.check(ctx, last, now); err != nil {
+				a.logf("can't check the todos: %v", err)
+				continue
+			}
+			last = now
+		}
+	}
+}
+
+// check notifies the active todos whose reminder or due date comes after from, up to until
+func (a *agent) check(ctx context.Context, from, until time.Time) error {
+	if err := a.ld.Reload(); err != nil {
+		return err
+	}
+	wf := a.ld.Workflow()
+	items, err := a.ld.Filter(func(model.Todo) bool { return true })
+	if err != nil {
+		return err
+	}
+	within := func(t *time.Time) bool {
+		return t != nil && t.After(from) && !t.After(until)
+	}
+	for _, item := range items {
+		tk := item.Task
+		if tk == nil || wf.Final(tk.Status) {
+			continue
+		}
+		switch {
+		case within(tk.Due):
+			a.notify(ctx, item, notification{Title: "Due: " + tk.Title, Body: describeTodo(item), Urgent: true})
+		case within(tk.Remind):
+			a.notify(ctx, item, notification{Title: "Reminder: " + tk.Title, Body: describeTodo(item)})
+		}
+	}
+	return nil
+}
+
+// describeTodo returns the body of the notifications of the todo
+func describeTodo(item ledger.Item) string {
+	tk := item.Task
+	parts := []string{"todo " + string(item.ID)}
+	if tk.Due != nil {
+		parts = append(parts, "due "+tk.Due.Local().Format("Mon Jan 2 15:04"))
+	}
+	if tk.Assignee != "" {
+		parts = append(parts, "@"+tk.Assignee)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// notify raises the notification of the todo, snoozing its reminder if the user chooses to. The
+// notifications waiting for the user don't hold the others.
+func (a *agent) notify(ctx context.Context, item ledger.Item, n notification) {
+	a.logf("%s (%s)", n.Title, item.ID)
+	a.notifying.Add(1)
+	go func() {
+		defer a.notifying.Done()
+		action, err := a.notifier.Notify(ctx, n)
+		if err != nil {
+			if !errors.Is(ctx.Err(), context.Canceled) {
+				a.logf("can't notify of %s: %v", item.ID, err)
+			}
+			return
+		}
+		if action != snoozeAction {
+			return
+		}
+		until := a.now().Add(a.snoozeFor)
+		if _, err := snooze(a.ld, item.ID, until); err != nil {
+			a.logf("can't snooze %s: %v", item.ID, err)
+			return
+		}
+		a.logf("snoozed %s until %s", item.ID, until.Format("15:04"))
+	}()
+}
+
+// logf prints the line to the output, which the notifications print to concurrently
+func (a *agent) logf(format string, args ...any) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	fmt.Fprintf(a.out, format+"\n", args...)
+}
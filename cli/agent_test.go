@@ -0,0 +1,115 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gotestbootcamp/go-todo-app/ledger"
+	"github.com/gotestbootcamp/go-todo-app/store"
+)
+
+// fakeNotifier records the notifications, answering them with its action
+type fakeNotifier struct {
+	lock   sync.Mutex
+	action string
+	raised []notification
+}
+
+func (fn *fakeNotifier) Notify(ctx context.Context, n notification) (string, error) {
+	fn.lock.Lock()
+	defer fn.lock.Unlock()
+	fn.raised = append(fn.raised, n)
+	return fn.action, nil
+}
+
+func TestAgent(t *testing.T) {
+	dir := t.TempDir()
+	code, _, _ := run(t, dir, "add", "pay", "rent")
+	require.Equal(t, 0, code)
+	code, _, _ = run(t, dir, "add", "buy", "milk")
+	require.Equal(t, 0, code)
+	code, _, _ = run(t, dir, "add", "walk", "dog")
+	require.Equal(t, 0, code)
+
+	ld, _, err := open(globals{dataDir: dir})
+	require.NoError(t, err)
+	defer ld.Close()
+	now := time.Now().Truncate(time.Second)
+	past, soon, later := now.Add(-time.Minute), now.Add(time.Minute), now.Add(time.Hour)
+	_, err = ld.Schedule("1", ledger.Schedule{Due: &soon})
+	require.NoError(t, err)
+	_, err = ld.Schedule("2", ledger.Schedule{Remind: &soon, Due: &later})
+	require.NoError(t, err)
+	_, err = ld.Schedule("3", ledger.Schedule{Remind: &past})
+	require.NoError(t, err)
+
+	dn := &fakeNotifier{action: snoozeAction}
+	var out bytes.Buffer
+	a := newAgent(ld, dn, 10*time.Minute, &out)
+	a.now = func() time.Time { return now.Add(2 * time.Minute) }
+
+	// the todos changed by the other processes are seen
+	code, _, _ = run(t, dir, "done", "--as", "ann", "3")
+	require.Equal(t, 0, code)
+	code, _, _ = run(t, dir, "add", "call", "mum")
+	require.Equal(t, 0, code)
+	other, _, err := open(globals{dataDir: dir})
+	require.NoError(t, err)
+	_, err = other.Schedule("4", ledger.Schedule{Remind: &soon})
+	require.NoError(t, err)
+	other.Close()
+
+	require.NoError(t, a.check(context.Background(), now, now.Add(2*time.Minute)))
+	a.notifying.Wait()
+	var titles []string
+	for _, n := range dn.raised {
+		titles = append(titles, n.Title)
+	}
+	assert.ElementsMatch(t, []string{"Due: pay rent", "Reminder: buy milk", "Reminder: call mum"}, titles)
+	assert.Contains(t, out.String(), "snoozed 2 until ")
+
+	// the reminders snoozed come again
+	item, _, err := ld.GetItem("2")
+	require.NoError(t, err)
+	require.NotNil(t, item.Task.Remind)
+	assert.True(t, now.Add(12*time.Minute).Equal(*item.Task.Remind))
+	assert.True(t, later.Equal(*item.Task.Due))
+	dn.raised, dn.action = nil, ""
+	require.NoError(t, a.check(context.Background(), now.Add(2*time.Minute), now.Add(12*time.Minute)))
+	a.notifying.Wait()
+	require.Len(t, dn.raised, 3)
+	assert.Contains(t, dn.raised[0].Title, "Reminder: ")
+	require.NoError(t, a.check(context.Background(), now.Add(12*time.Minute), now.Add(time.Hour)))
+	a.notifying.Wait()
+	require.Len(t, dn.raised, 4)
+	assert.Equal(t, notification{Title: "Due: buy milk", Body: "todo 2, due " + later.Local().Format("Mon Jan 2 15:04"), Urgent: true}, dn.raised[3])
+}
+
+func TestSnooze(t *testing.T) {
+	dir := t.TempDir()
+	code, _, _ := run(t, dir, "add", "--due", "2026-11-01", "pay", "rent")
+	require.Equal(t, 0, code)
+	code, out, _ := run(t, dir, "snooze", "--for", "1h", "1")
+	require.Equal(t, 0, code)
+	assert.Contains(t, out, "pay rent")
+
+	ld, _, err := open(globals{dataDir: dir})
+	require.NoError(t, err)
+	defer ld.Close()
+	item, _, err := ld.GetItem(store.ID("1"))
+	require.NoError(t, err)
+	require.NotNil(t, item.Task.Remind)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), *item.Task.Remind, time.Minute)
+	require.NotNil(t, item.Task.Due)
+
+	code, _, _ = run(t, dir, "snooze", "--for", "0s", "1")
+	assert.Equal(t, 2, code)
+	code, _, _ = run(t, dir, "snooze", "2")
+	assert.Equal(t, 1, code)
+}
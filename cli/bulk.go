@@ -0,0 +1,199 @@
+package cli
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/gotestbootcamp/go-todo-app/ledger"
+	"github.com/gotestbootcamp/go-todo-app/store"
+	"github.com/gotestbootcamp/go-todo-app/task"
+)
+
+// stdinArg is the argument reading the IDs from the standard input, like in "todo done -"
+const stdinArg = "-"
+
+// readIDs reads the IDs of the todos, either one per line, or as a stream of json values: the IDs,
+// the todos with an "id" field, like the ones printed with --output json, or the arrays of them
+func readIDs(r io.Reader) ([]store.ID, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return nil, nil
+	}
+	var ids []store.ID
+	if !strings.ContainsRune(`[{"`, rune(data[0])) {
+		for _, line := range strings.Split(string(data), "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				ids = append(ids, store.ID(line))
+			}
+		}
+		return ids, nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	for {
+		var v any
+		if err := dec.Decode(&v); errors.Is(err, io.EOF) {
+			return ids, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("invalid json input: %w", err)
+		}
+		if ids, err = appendIDs(ids, v); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// appendIDs appends the IDs of the json value to the list
+func appendIDs(ids []store.ID, v any) ([]store.ID, error) {
+	switch v := v.(type) {
+	case string:
+		return append(ids, store.ID(v)), nil
+	case map[string]any:
+		if id, ok := v["id"].(string); ok {
+			return append(ids, store.ID(id)), nil
+		}
+	case []any:
+		var err error
+		for _, elem := range v {
+			if ids, err = appendIDs(ids, elem); err != nil {
+				return nil, err
+			}
+		}
+		return ids, nil
+	}
+	return nil, fmt.Errorf("invalid json input: %v is not an ID, nor a todo with one", v)
+}
+
+// readLines reads the lines of the file, the standard input if "-"
+func (app *App) readLines(path string) ([]string, error) {
+	r, err := app.openInput(path)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	var lines []string
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		lines = append(lines, sc.Text())
+	}
+	return lines, sc.Err()
+}
+
+// addLines adds a todo for each of the lines not blank, in the quick-add syntax, with the fields of
+// the patch on top of the ones of the line, see createLines
+func (app *App) addLines(list string, lines []string, flagged ledger.Patch, dryRun bool) error {
+	return app.createLines(list, lines, func(line string) (ledger.Item, error) {
+		title, patch, err := parseQuickAdd(line)
+		if err != nil {
+			return ledger.Item{}, err
+		}
+		return preview(title, app.withContext(overlay(patch, flagged)))
+	}, dryRun)
+}
+
+// createLines adds a todo for each of the lines not blank, as parsed by the function, at once, and
+// prints the result of each line. The lines which can't be parsed are skipped. Dry runs print the
+// todos instead.
+func (app *App) createLines(list string, lines []string, parse func(string) (ledger.Item, error), dryRun bool) error {
+	var labels []string
+	var tasks []task.Task
+	var previews ledger.Items
+	report := make(ledger.BulkReport, 0, len(lines))
+	// the index in the report of the result of each task
+	var results []int
+	for i, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		labels = append(labels, fmt.Sprintf("line %d", i+1))
+		res := ledger.BulkResult{ID: store.NullID}
+		item, err := parse(line)
+		if err != nil {
+			res.Err = err
+		} else {
+			results = append(results, len(report))
+			tasks = append(tasks, *item.Task)
+			previews = append(previews, item)
+		}
+		report = append(report, res)
+	}
+	if dryRun {
+		if err := app.printItems(previews); err != nil {
+			return err
+		}
+		var errs []error
+		for i, res := range report {
+			if res.Err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", labels[i], res.Err))
+			}
+		}
+		return errors.Join(errs...)
+	}
+	created, err := app.Ledger.CreateAll(list, tasks)
+	if err != nil {
+		return err
+	}
+	for i, res := range created {
+		report[results[i]] = res
+	}
+	return app.printReport(report, labels, "added")
+}
+
+// printReport writes the result of the bulk operation on each todo, like "3: completed", or on
+// each of the labelled inputs if any, like "line 2: added 5". The json and yaml output formats
+// print the report as the API does. Fails if the operation failed on any todo.
+func (app *App) printReport(report ledger.BulkReport, labels []string, done string) error {
+	return app.printReportFunc(report, labels, func(int) string { return done })
+}
+
+// printReportFunc is like printReport, with what was done to each todo, given its index
+func (app *App) printReportFunc(report ledger.BulkReport, labels []string, done func(i int) string) error {
+	switch app.Format {
+	case FormatJSON:
+		if err := printJSON(app.Out, report.ToAPIv1()); err != nil {
+			return err
+		}
+		return reportErr(report)
+	case FormatYAML:
+		if err := printYAML(app.Out, report.ToAPIv1()); err != nil {
+			return err
+		}
+		return reportErr(report)
+	}
+	for i, res := range report {
+		label, result := string(res.ID), done(i)
+		if labels != nil {
+			label = labels[i]
+			result += " " + string(res.ID)
+		}
+		if res.Err != nil {
+			result = "error: " + res.Err.Error()
+		}
+		if _, err := fmt.Fprintf(app.Out, "%s: %s\n", label, result); err != nil {
+			return err
+		}
+	}
+	return reportErr(report)
+}
+
+// reportErr returns the error telling how many todos the bulk operation failed on, nil if none
+func reportErr(report ledger.BulkReport) error {
+	failed := 0
+	for _, res := range report {
+		if res.Err != nil {
+			failed++
+		}
+	}
+	if failed == 0 {
+		return nil
+	}
+	return fmt.Errorf("failed on %d of %d todos", failed, len(report))
+}
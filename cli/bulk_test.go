@@ -0,0 +1,69 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gotestbootcamp/go-todo-app/store"
+)
+
+func TestReadIDs(t *testing.T) {
+	for input, want := range map[string][]store.ID{
+		"":                                       nil,
+		"1\n work/2 \n\n3\n":                     {"1", "work/2", "3"},
+		`"1" "2"`:                                {"1", "2"},
+		`[{"id": "1", "todo": {}}, {"id": "2"}]`: {"1", "2"},
+		"{\"id\": \"1\"}\n[\"2\"]\n":             {"1", "2"},
+	} {
+		ids, err := readIDs(strings.NewReader(input))
+		require.NoError(t, err, input)
+		assert.Equal(t, want, ids, input)
+	}
+	for _, input := range []string{`[1]`, `{"title": "no id"}`, `["1"`} {
+		_, err := readIDs(strings.NewReader(input))
+		assert.Error(t, err, input)
+	}
+}
+
+func TestRunBulk(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(t.TempDir(), "tasks.txt")
+	require.NoError(t, os.WriteFile(file, []byte("buy milk !p1 #shop\n\nwalk the dog\n!bogus\n"), 0o600))
+
+	code, out, errs := run(t, dir, "add", "--from-file", file, "--dry-run")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, out, "buy milk")
+	assert.Contains(t, errs, "line 4:")
+	code, out, _ = run(t, dir, "list")
+	require.Equal(t, 0, code)
+	assert.NotContains(t, out, "buy milk")
+
+	code, out, errs = run(t, dir, "add", "--from-file", file, "--tags", "home")
+	assert.Equal(t, 1, code)
+	assert.Equal(t, "line 1: added 1\nline 3: added 2\nline 4: error: invalid priority \"bogus\"\n", out)
+	assert.Contains(t, errs, "failed on 1 of 3 todos")
+	code, out, _ = runInput(t, dir, "call mom\n", "add", "--from-file", "-", "--list", "work")
+	require.Equal(t, 0, code)
+	assert.Equal(t, "line 1: added work/3\n", out)
+	code, _, _ = run(t, dir, "add", "--from-file", file, "more", "words")
+	assert.Equal(t, 2, code)
+
+	code, out, _ = run(t, dir, "list", "--tag", "home", "--output", "json")
+	require.Equal(t, 0, code)
+	code, out, _ = runInput(t, dir, out, "done", "--as", "ann", "-")
+	require.Equal(t, 0, code)
+	assert.Equal(t, "1: completed\n2: completed\n", out)
+
+	code, out, _ = runInput(t, dir, "1\nwork/3\n9\n", "rm", "--output", "json", "-")
+	assert.Equal(t, 1, code)
+	assert.JSONEq(t, `[{"id": "1"}, {"id": "9", "error": "unknown id: 9"}, {"id": "work/3"}]`, out)
+	code, out, _ = run(t, dir, "list")
+	require.Equal(t, 0, code)
+	assert.NotContains(t, out, "buy milk")
+	assert.Contains(t, out, "walk the dog")
+}
@@ -0,0 +1,308 @@
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+
+	"github.com/gotestbootcamp/go-todo-app/config"
+	"github.com/gotestbootcamp/go-todo-app/index"
+	"github.com/gotestbootcamp/go-todo-app/ledger"
+	"github.com/gotestbootcamp/go-todo-app/model"
+	"github.com/gotestbootcamp/go-todo-app/store"
+)
+
+// errUsage is returned by the commands run with invalid arguments, for Run to print their usage
+var errUsage = errors.New("invalid arguments")
+
+// App is what the commands run with: the ledger of the store, and where to write their output
+type App struct {
+	Ledger *ledger.Ledger
+	// IDs generates the IDs of the todos added
+	IDs store.IDGenerator
+	// Actor is the user the changes are recorded as made by
+	Actor string
+	// Config are the settings of the configuration file and of the environment, for the profile
+	// selected. Their List is the one the commands work on unless told otherwise.
+	Config config.ResolvedCLI
+	// In is where the commands read their input from, like the IDs of "todo done -"
+	In  io.Reader
+	Out io.Writer
+	// Format is the format of the output, e.g. FormatText or FormatJSON
+	Format string
+	// Template prints the todos with FormatTemplate
+	Template *template.Template
+
+	// globals are the global flags, with the settings of the configuration file applied
+	globals globals
+}
+
+// Command is a command of the CLI
+type Command struct {
+	Name string
+	// Args is the synopsis of the arguments, after the flags
+	Args string
+	// Help tells what the command does, in one line
+	Help string
+	// Hidden commands are left out of the usage
+	Hidden bool
+	// NoStore commands run without opening the store, their App has no Ledger
+	NoStore bool
+	// Setup registers the flags of the command, and returns the function running it with the
+	// arguments left after the flags
+	Setup func(flags *flag.FlagSet) func(app *App, args []string) error
+}
+
+// Commands are the commands of the CLI, in the order of the help
+var Commands = []Command{
+	addCommand,
+	listCommand,
+	showCommand,
+	editCommand,
+	moveCommand,
+	doneCommand,
+	snoozeCommand,
+	pomoCommand,
+	rmCommand,
+	searchCommand,
+	filterCommand,
+	fieldCommand,
+	linkCommand,
+	statsCommand,
+	burndownCommand,
+	exportCommand,
+	importCommand,
+	tuiCommand,
+	agentCommand,
+	syncCommand,
+	configCommand,
+}
+
+// Lookup returns the command with the given name. False if there's none.
+func Lookup(name string) (Command, bool) {
+	for _, cmd := range Commands {
+		if cmd.Name == name {
+			return cmd, true
+		}
+	}
+	return Command{}, false
+}
+
+// globals are the flags all the commands accept
+type globals struct {
+	dataDir  string
+	store    string
+	format   string
+	template string
+	actor    string
+	profile  string
+}
+
+func (g *globals) register(flags *flag.FlagSet) {
+	flags.StringVar(&g.profile, "profile", "", "profile of the configuration file to use, see todo config (default: $TODO_PROFILE, or the one of the file)")
+	flags.StringVar(&g.dataDir, "data-dir", DefaultDataDir(), "directory holding the todos, in the todo.db SQLite database")
+	flags.StringVar(&g.store, "store", "", "storage URI, e.g. bolt:///path/to/todo.db (overrides --data-dir)")
+	flags.StringVar(&g.format, "output", FormatText, "output format: "+strings.Join(formats, ", "))
+	flags.StringVar(&g.template, "template", "", "Go template printing every todo with --output template, with the fields named as in json, e.g. '{{.id}} {{.todo.title}}'")
+	flags.StringVar(&g.actor, "as", os.Getenv("USER"), "user the changes are recorded as made by, and the todos completed are assigned to")
+}
+
+// configure sets the globals the flags didn't set to the settings of the configuration file and
+// of the environment, see config.CLIConfig.Resolve, and returns the settings in effect
+func (g *globals) configure(flags *flag.FlagSet) (config.ResolvedCLI, error) {
+	cfg, err := config.LoadCLIConfig(config.CLIConfigPath())
+	if err != nil {
+		return config.ResolvedCLI{}, err
+	}
+	settings, err := cfg.Resolve(g.profile, os.Getenv)
+	if err != nil {
+		return config.ResolvedCLI{}, err
+	}
+	for key, global := range map[string]*string{
+		"data-dir": &g.dataDir,
+		"store":    &g.store,
+		"output":   &g.format,
+		"template": &g.template,
+	} {
+		if isSet(flags, key) {
+			_ = settings.Set(key, *global)
+			settings.Sources[key] = "flag"
+		} else if v, _ := settings.Get(key); v != "" {
+			*global = v
+		}
+	}
+	// the data directory given overrides the store of the settings, which would override it otherwise
+	if isSet(flags, "data-dir") && !isSet(flags, "store") {
+		g.store = ""
+		_ = settings.Set("store", "")
+		delete(settings.Sources, "store")
+	}
+	if isSet(flags, "profile") {
+		settings.Sources["profile"] = "flag"
+	}
+	return settings, nil
+}
+
+// isSet tells whether the flag was given
+func isSet(flags *flag.FlagSet, name string) bool {
+	found := false
+	flags.Visit(func(f *flag.Flag) {
+		found = found || f.Name == name
+	})
+	return found
+}
+
+// DefaultDataDir returns the directory holding the todos by default: todo in $XDG_DATA_HOME, or
+// else in ~/.local/share
+func DefaultDataDir() string {
+	if dir := os.Getenv("XDG_DATA_HOME"); dir != "" {
+		return filepath.Join(dir, "todo")
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "todo"
+	}
+	return filepath.Join(home, ".local", "share", "todo")
+}
+
+// Run runs the command named by the first argument with the other ones, reading its input from
+// stdin and writing its output to stdout and the errors to stderr. Returns the exit code: 0 on success, 1 if the command failed
+// and 2 if it was given invalid arguments.
+func Run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 || args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		usage(stderr)
+		return 2
+	}
+	cmd, ok := Lookup(args[0])
+	if !ok {
+		fmt.Fprintf(stderr, "todo: unknown command %q\n", args[0])
+		usage(stderr)
+		return 2
+	}
+
+	flags := flag.NewFlagSet("todo "+cmd.Name, flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: todo %s [flags] %s\n\n%s\n\nFlags:\n", cmd.Name, cmd.Args, cmd.Help)
+		flags.PrintDefaults()
+	}
+	var g globals
+	g.register(flags)
+	run := cmd.Setup(flags)
+	if err := flags.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+	settings, err := g.configure(flags)
+	if err != nil {
+		fmt.Fprintf(stderr, "todo: %v\n", err)
+		return 2
+	}
+	if !slices.Contains(formats, g.format) {
+		fmt.Fprintf(stderr, "todo: invalid output format %q, want one of %s\n", g.format, strings.Join(formats, ", "))
+		return 2
+	}
+	var tmpl *template.Template
+	if g.format == FormatTemplate {
+		if tmpl, err = parseTemplate(g.template); err != nil {
+			fmt.Fprintf(stderr, "todo: %v\n", err)
+			return 2
+		}
+	}
+
+	app := &App{Actor: g.actor, Config: settings, In: stdin, Out: stdout, Format: g.format, Template: tmpl, globals: g}
+	if !cmd.NoStore {
+		ld, ids, err := open(g)
+		if err != nil {
+			fmt.Fprintf(stderr, "todo: %v\n", err)
+			return 1
+		}
+		defer ld.Close()
+		app.Ledger, app.IDs = ld.As(g.actor), ids
+	}
+	err = run(app, flags.Args())
+	if errors.Is(err, errUsage) {
+		flags.Usage()
+		return 2
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "todo: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintf(w, "Usage: todo <command> [flags] [args]\n\nCommands:\n")
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "  serve\tserve the REST and gRPC APIs, the default\n")
+	for _, cmd := range Commands {
+		if !cmd.Hidden {
+			fmt.Fprintf(tw, "  %s\t%s\n", cmd.Name, cmd.Help)
+		}
+	}
+	tw.Flush()
+	fmt.Fprintf(w, "\nRun todo <command> -h for the flags of the command.\n")
+}
+
+// open opens the ledger of the store the flags select, indexing the text of the todos for search
+func open(g globals) (*ledger.Ledger, store.IDGenerator, error) {
+	uri, err := g.storeURI()
+	if err != nil {
+		return nil, nil, err
+	}
+	st, err := store.Open(uri)
+	if err != nil {
+		return nil, nil, fmt.Errorf("can't open the store: %w", err)
+	}
+	// the IDs are allocated by the storage if it can, e.g. by the server of a remote store
+	ids, err := store.NewIDGenerator(store.IDSequential, st)
+	if err != nil {
+		st.Close()
+		return nil, nil, err
+	}
+	// the changes are watched by the tui, only the ones made through this process though
+	st = store.NewNotifier(st)
+	ixd, err := index.NewIndexed(st, todoText)
+	if err != nil {
+		st.Close()
+		return nil, nil, fmt.Errorf("can't index the store: %w", err)
+	}
+	ld, err := ledger.New(ixd)
+	if err != nil {
+		ixd.Close()
+		return nil, nil, fmt.Errorf("can't load the store: %w", err)
+	}
+	ld.SetIDGenerator(ids)
+	return ld, ids, nil
+}
+
+// storeURI returns the URI of the store the flags select: the SQLite database of the data
+// directory unless --store is given, creating the directory if needed
+func (g globals) storeURI() (string, error) {
+	if g.store != "" {
+		return g.store, nil
+	}
+	if err := os.MkdirAll(g.dataDir, 0o700); err != nil {
+		return "", err
+	}
+	return "sqlite://" + filepath.Join(g.dataDir, "todo.db"), nil
+}
+
+// todoText returns the text of the serialized todo the search looks into
+func todoText(blob store.Blob) (string, error) {
+	todo, err := model.DeserializeTodo(blob)
+	if err != nil {
+		return "", err
+	}
+	return strings.Join([]string{todo.Title, todo.Description}, "\n"), nil
+}
@@ -0,0 +1,281 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// run runs the command on the data directory, returning its exit code, output and errors
+func run(t *testing.T, dir string, args ...string) (int, string, string) {
+	t.Helper()
+	return runInput(t, dir, "", args...)
+}
+
+// runInput is like run, with the input of the command
+func runInput(t *testing.T, dir, input string, args ...string) (int, string, string) {
+	t.Helper()
+	if os.Getenv("TODO_CONFIG") == "" {
+		// not to depend on the configuration of the user
+		t.Setenv("TODO_CONFIG", filepath.Join(t.TempDir(), "config.yaml"))
+	}
+	var stdout, stderr bytes.Buffer
+	args = append([]string{args[0], "--data-dir", dir}, args[1:]...)
+	code := Run(args, strings.NewReader(input), &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+
+	code, out, _ := run(t, dir, "add", "--tags", "home,shop", "--priority", "high", "buy", "milk")
+	require.Equal(t, 0, code)
+	assert.Contains(t, out, "Title:    buy milk")
+	assert.Contains(t, out, "Priority: P1")
+	code, _, _ = run(t, dir, "add", "walk", "dog")
+	require.Equal(t, 0, code)
+
+	code, out, _ = run(t, dir, "list", "--tag", "home")
+	require.Equal(t, 0, code)
+	assert.Contains(t, out, "buy milk")
+	assert.NotContains(t, out, "walk dog")
+
+	code, out, _ = run(t, dir, "list", "--output", "json")
+	require.Equal(t, 0, code)
+	var items []struct {
+		ID   string `json:"id"`
+		Todo struct {
+			Title string `json:"title"`
+		} `json:"todo"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(out), &items))
+	require.Len(t, items, 2)
+	assert.Equal(t, "1", items[0].ID)
+	assert.Equal(t, "walk dog", items[1].Todo.Title)
+
+	code, out, _ = run(t, dir, "edit", "--title", "walk the dog", "--due", "2026-11-01", "2")
+	require.Equal(t, 0, code)
+	assert.Contains(t, out, "Title:    walk the dog")
+	assert.Contains(t, out, "Due:      2026-11-01")
+
+	code, out, _ = run(t, dir, "done", "--as", "ann", "1")
+	require.Equal(t, 0, code)
+	assert.Contains(t, out, "Status:   completed")
+	assert.Contains(t, out, "Assignee: ann")
+
+	code, out, _ = run(t, dir, "search", "dog")
+	require.Equal(t, 0, code)
+	assert.Contains(t, out, "walk the dog")
+	assert.NotContains(t, out, "buy milk")
+	code, out, _ = run(t, dir, "search", "tag:home", "status:completed")
+	require.Equal(t, 0, code)
+	assert.Contains(t, out, "buy milk")
+	assert.NotContains(t, out, "walk the dog")
+
+	code, _, _ = run(t, dir, "filter", "save", "chores", "tag:home", "OR", "dog")
+	require.Equal(t, 0, code)
+	code, out, _ = run(t, dir, "filter")
+	require.Equal(t, 0, code)
+	assert.Contains(t, out, "chores  tag:home OR dog")
+	code, out, _ = run(t, dir, "list", "--filter", "chores", "--status", "pending")
+	require.Equal(t, 0, code)
+	assert.Contains(t, out, "walk the dog")
+	assert.NotContains(t, out, "buy milk")
+	code, _, _ = run(t, dir, "filter", "rm", "chores")
+	require.Equal(t, 0, code)
+	code, _, errs := run(t, dir, "list", "--filter", "chores")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, errs, `unknown filter "chores"`)
+
+	code, _, _ = run(t, dir, "move", "--before", "1", "2")
+	require.Equal(t, 0, code)
+	code, out, _ = run(t, dir, "list", "--sort", "rank", "--output", "tsv")
+	require.Equal(t, 0, code)
+	assert.Regexp(t, `\n2\t.*\n1\t`, out)
+	code, _, errs = run(t, dir, "list", "--sort", "size")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, errs, `unknown sort field "size"`)
+
+	code, out, _ = run(t, dir, "rm", "2")
+	require.Equal(t, 0, code)
+	assert.Empty(t, out)
+	code, _, errs = run(t, dir, "show", "2")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, errs, "todo: ")
+}
+
+func TestRunUsage(t *testing.T) {
+	dir := t.TempDir()
+
+	code, _, errs := run(t, dir, "bogus")
+	assert.Equal(t, 2, code)
+	assert.Contains(t, errs, `unknown command "bogus"`)
+
+	code, _, errs = run(t, dir, "show")
+	assert.Equal(t, 2, code)
+	assert.Contains(t, errs, "Usage: todo show")
+
+	code, _, _ = run(t, dir, "edit")
+	assert.Equal(t, 2, code, "no todo to change")
+
+	code, _, _ = run(t, dir, "list", "--output", "xml")
+	assert.Equal(t, 2, code)
+
+	code, _, errs = run(t, dir, "list", "--output", "template")
+	assert.Equal(t, 2, code)
+	assert.Contains(t, errs, "needs --template")
+	code, _, _ = run(t, dir, "list", "--output", "template", "--template", "{{.id")
+	assert.Equal(t, 2, code)
+
+	code, _, _ = run(t, dir, "add", "--due", "someday", "buy milk")
+	assert.Equal(t, 1, code)
+	code, _, errs = run(t, dir, "add", "--due", "03/04", "buy milk")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, errs, "ambiguous date")
+	_, out, _ := run(t, dir, "list")
+	assert.NotContains(t, out, "buy milk", "not added without its fields")
+
+	code, out, _ = run(t, dir, "add", "--due", "tomorrow 5pm", "buy milk")
+	assert.Equal(t, 0, code)
+	assert.Contains(t, out, time.Now().AddDate(0, 0, 1).Format("2006-01-02")+" 17:00")
+}
+
+func TestRunOutput(t *testing.T) {
+	dir := t.TempDir()
+	code, _, _ := run(t, dir, "add", "--tags", "home,shop", "--due", "2026-11-01T10:00:00Z", "buy", "milk")
+	require.Equal(t, 0, code)
+	code, _, _ = run(t, dir, "add", "--description", "around\tthe\nblock", "walk", "dog")
+	require.Equal(t, 0, code)
+
+	code, out, _ := run(t, dir, "list", "--output", "yaml")
+	require.Equal(t, 0, code)
+	assert.Contains(t, out, "- id: \"1\"\n  todo:\n")
+	assert.Contains(t, out, "    title: buy milk\n")
+
+	code, out, _ = run(t, dir, "list", "--output", "tsv")
+	require.Equal(t, 0, code)
+	lines := strings.Split(strings.TrimSuffix(out, "\n"), "\n")
+	require.Len(t, lines, 3)
+	assert.Equal(t, "id\tstatus\ttitle\tassignee\tpriority\tdue\ttags\tupdated", lines[0])
+	assert.True(t, strings.HasPrefix(lines[1], "1\tpending\tbuy milk\t\t\t2026-11-01T10:00:00Z\thome,shop\t"), lines[1])
+	assert.Len(t, strings.Split(lines[2], "\t"), 8)
+
+	code, out, _ = run(t, dir, "list", "--output", "template", "--template", `{{.id}} {{.todo.title}} {{join "+" .todo.tags}}`)
+	require.Equal(t, 0, code)
+	assert.Equal(t, "1 buy milk home+shop\n2 walk dog \n", out)
+
+	code, out, _ = run(t, dir, "show", "--output", "template", "--template", "{{.todo.description}}", "2")
+	require.Equal(t, 0, code)
+	assert.Equal(t, "around\tthe\nblock\n", out)
+}
+
+func TestRunContexts(t *testing.T) {
+	dir := t.TempDir()
+	code, _, _ := run(t, dir, "add", "call mom @phone")
+	require.Equal(t, 0, code)
+	code, _, _ = run(t, dir, "add", "buy milk #home @errands")
+	require.Equal(t, 0, code)
+	code, out, _ := run(t, dir, "add", "--contexts", "office,@phone", "plan", "trip")
+	require.Equal(t, 0, code)
+	assert.Contains(t, out, "Contexts: @office, @phone\n")
+
+	code, out, _ = run(t, dir, "list", "@phone")
+	require.Equal(t, 0, code)
+	assert.Contains(t, out, "call mom")
+	assert.Contains(t, out, "plan trip")
+	assert.NotContains(t, out, "buy milk")
+	assert.Regexp(t, `\n3 +pending +plan trip +@office,@phone *\n`, out)
+	code, out, _ = run(t, dir, "list", "--context", "errands")
+	require.Equal(t, 0, code)
+	assert.Contains(t, out, "buy milk")
+	assert.NotContains(t, out, "call mom")
+	code, _, _ = run(t, dir, "list", "home")
+	assert.Equal(t, 2, code)
+
+	// the context of the profile
+	t.Setenv("TODO_CONTEXT", "errands")
+	code, out, _ = run(t, dir, "add", "buy bread")
+	require.Equal(t, 0, code)
+	assert.Contains(t, out, "Contexts: @errands\n")
+	code, out, _ = run(t, dir, "list")
+	require.Equal(t, 0, code)
+	assert.Contains(t, out, "buy bread")
+	assert.NotContains(t, out, "call mom")
+	code, out, _ = run(t, dir, "list", "--context", "")
+	require.Equal(t, 0, code)
+	assert.Contains(t, out, "call mom")
+
+	code, out, _ = run(t, dir, "stats")
+	require.Equal(t, 0, code)
+	assert.Regexp(t, `\nCONTEXT +CREATED .*\n@errands +2 +0 `, out)
+}
+
+func TestRunStats(t *testing.T) {
+	dir := t.TempDir()
+	code, _, _ := run(t, dir, "add", "--tags", "home", "buy", "milk")
+	require.Equal(t, 0, code)
+	code, _, _ = run(t, dir, "add", "walk", "dog")
+	require.Equal(t, 0, code)
+	code, _, _ = run(t, dir, "done", "--as", "ann", "1")
+	require.Equal(t, 0, code)
+
+	code, out, _ := run(t, dir, "stats")
+	require.Equal(t, 0, code)
+	assert.Contains(t, out, "Completed 1 todos from ")
+	assert.Contains(t, out, "Each day: "+strings.Repeat("▁", 28)+"█ max 1\n")
+	assert.Contains(t, out, "Open: 1, 0 overdue\n")
+	assert.Regexp(t, `\nhome +1 +1 +0h 0m +0% +0 +0 +0\n`, out)
+
+	code, out, _ = run(t, dir, "stats", "--period", "week", "--chart", "bars")
+	require.Equal(t, 0, code)
+	assert.Regexp(t, "week of .* "+strings.Repeat("█", barWidth)+" 1\n", out)
+
+	code, out, _ = run(t, dir, "stats", "--output", "json")
+	require.Equal(t, 0, code)
+	var stats struct {
+		Completed int                       `json:"completed"`
+		ByTag     map[string]map[string]any `json:"by_tag"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(out), &stats))
+	assert.Equal(t, 1, stats.Completed)
+	assert.Contains(t, stats.ByTag, "home")
+
+	code, _, errOut := run(t, dir, "stats", "--period", "month")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, errOut, `invalid period "month"`)
+
+	assert.Equal(t, "▁▃█▅", sparkline([]int{0, 1, 4, 2}))
+	assert.Equal(t, "▁▁", sparkline([]int{0, 0}))
+}
+
+func TestRunBurndown(t *testing.T) {
+	dir := t.TempDir()
+	for _, title := range []string{"design", "build", "ship"} {
+		code, _, _ := run(t, dir, "add", "--tags", "launch", title)
+		require.Equal(t, 0, code)
+	}
+	code, _, _ := run(t, dir, "add", "unrelated")
+	require.Equal(t, 0, code)
+	code, _, _ = run(t, dir, "done", "--as", "ann", "1")
+	require.Equal(t, 0, code)
+
+	code, out, _ := run(t, dir, "burndown", "--tag", "launch")
+	require.Equal(t, 0, code)
+	assert.Contains(t, out, "TIME ")
+	assert.Regexp(t, `\n\S+ \S+ +2 +1 +`+strings.Repeat("█", barWidth)+"\n$", out)
+
+	code, out, _ = run(t, dir, "burndown", "--tag", "launch", "--csv", "--from", "today")
+	require.Equal(t, 0, code)
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	require.Len(t, lines, 3, "midnight and now")
+	assert.Equal(t, "time,open,completed", lines[0])
+	assert.True(t, strings.HasSuffix(lines[1], ",0,0"), lines[1])
+	assert.True(t, strings.HasSuffix(lines[2], ",2,1"), lines[2])
+}
@@ -0,0 +1,445 @@
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gotestbootcamp/go-todo-app/dates"
+	"github.com/gotestbootcamp/go-todo-app/ledger"
+	"github.com/gotestbootcamp/go-todo-app/store"
+	"github.com/gotestbootcamp/go-todo-app/task"
+)
+
+// fields are the flags setting the fields of a todo, shared by add and edit
+type fields struct {
+	flags       *flag.FlagSet
+	title       string
+	description string
+	assignee    string
+	status      string
+	priority    string
+	tags        string
+	contexts    string
+	due         string
+	values      fieldValues
+}
+
+func (fs *fields) register(flags *flag.FlagSet, edit bool) {
+	fs.flags = flags
+	if edit {
+		flags.StringVar(&fs.title, "title", "", "title of the todo")
+		flags.StringVar(&fs.status, "status", "", "status of the todo, as the workflow allows")
+	}
+	flags.StringVar(&fs.description, "description", "", "longer description of the todo")
+	flags.StringVar(&fs.assignee, "assignee", "", "who works on the todo, which can't be reassigned")
+	flags.StringVar(&fs.priority, "priority", "", "priority of the todo: none, low, normal, high, urgent, or P3 to P0")
+	flags.StringVar(&fs.tags, "tags", "", "comma-separated tags of the todo, replacing the ones it has")
+	flags.StringVar(&fs.contexts, "contexts", "", "comma-separated contexts of the todo, like home,phone, replacing the ones it has")
+	flags.StringVar(&fs.due, "due", "", "when the todo is due, e.g. \"tomorrow 5pm\", \"next friday\", \"in 3 days\" or 2006-01-02; empty to unset")
+	flags.Var(&fs.values, "field", "`name=value` of a custom field of the list of the todo, see todo field; empty value to unset, repeatable")
+}
+
+// set tells whether the flag was given
+func (fs *fields) set(name string) bool {
+	return isSet(fs.flags, name)
+}
+
+// given tells whether any of the fields was given
+func (fs *fields) given() bool {
+	for _, name := range []string{"title", "description", "assignee", "status", "priority", "tags", "contexts", "due", "field"} {
+		if fs.set(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// patch returns the patch of the fields given on top of the todo
+func (fs *fields) patch(cur ledger.Item) (ledger.Patch, error) {
+	var patch ledger.Patch
+	if fs.set("title") {
+		patch.Title = &fs.title
+	}
+	if fs.set("description") {
+		patch.Description = &fs.description
+	}
+	if fs.set("assignee") {
+		patch.Assignee = &fs.assignee
+	}
+	if fs.set("status") {
+		status := task.Status(fs.status)
+		patch.Status = &status
+	}
+	if fs.set("priority") {
+		pr, err := task.ParsePriority(fs.priority)
+		if err != nil {
+			return patch, err
+		}
+		patch.Priority = &pr
+	}
+	if fs.set("tags") {
+		tags := []string{}
+		if fs.tags != "" {
+			tags = strings.Split(fs.tags, ",")
+		}
+		patch.Tags = &tags
+	}
+	if fs.set("contexts") {
+		contexts := []string{}
+		if fs.contexts != "" {
+			contexts = parseContexts(fs.contexts)
+		}
+		patch.Contexts = &contexts
+	}
+	if fs.set("due") {
+		due, err := parseDue(fs.due)
+		if err != nil {
+			return patch, err
+		}
+		// the reminder and the recurrence stay as they are
+		patch.Schedule = &ledger.Schedule{Due: due, Remind: cur.Task.Remind, Recur: cur.Task.Recur}
+	}
+	if fs.set("field") {
+		values := map[string]string(fs.values)
+		patch.Fields = &values
+	}
+	return patch, nil
+}
+
+// parseContexts splits the comma-separated contexts, written with their @ or not
+func parseContexts(s string) []string {
+	contexts := strings.Split(s, ",")
+	for i, ctx := range contexts {
+		contexts[i] = strings.TrimPrefix(ctx, "@")
+	}
+	return contexts
+}
+
+// dateParser parses the due dates, relative to now
+var dateParser = dates.New()
+
+// parseDue parses a due date, absolute or relative to now like "tomorrow 5pm", see dates.Parse.
+// Empty is no due date.
+func parseDue(s string) (*time.Time, error) {
+	if s == "" {
+		return nil, nil
+	}
+	t, err := dateParser.Parse(s)
+	if err != nil {
+		return nil, fmt.Errorf("due date: %w", err)
+	}
+	return &t, nil
+}
+
+// oneID returns the ID, the only argument
+func oneID(args []string) (store.ID, error) {
+	if len(args) != 1 {
+		return store.NullID, errUsage
+	}
+	return store.ID(args[0]), nil
+}
+
+var addCommand = Command{
+	Name: "add",
+	Args: "<title>...",
+	Help: `add a todo, with the words of the arguments as title, like "Pay rent !p1 #finance @home due:friday"`,
+	Setup: func(flags *flag.FlagSet) func(*App, []string) error {
+		var fs fields
+		fs.register(flags, false)
+		dryRun := flags.Bool("dry-run", false, "print the todo parsed from the arguments, without adding it")
+		list := flags.String("list", store.DefaultList, "list to add the todo to (default: the one of the profile, or none)")
+		fromFile := flags.String("from-file", "", "add a todo for each line of the file, - for the standard input, instead of the arguments")
+		return func(app *App, args []string) error {
+			if (len(args) == 0) == (*fromFile == "") {
+				return errUsage
+			}
+			if !isSet(flags, "list") {
+				*list = app.Config.List
+			}
+			if *list != store.DefaultList {
+				if err := store.ValidateList(*list); err != nil {
+					return err
+				}
+			}
+			// the flags take precedence over the quick-add words
+			flagged, err := fs.patch(ledger.Item{Task: &task.Task{}})
+			if err != nil {
+				return err
+			}
+			if flagged.Fields != nil {
+				if err := checkFields(app.Ledger, *list, *flagged.Fields); err != nil {
+					return err
+				}
+			}
+			if *fromFile != "" {
+				lines, err := app.readLines(*fromFile)
+				if err != nil {
+					return err
+				}
+				return app.addLines(*list, lines, flagged, *dryRun)
+			}
+			title, patch, err := parseQuickAdd(strings.Join(args, " "))
+			if err != nil {
+				return err
+			}
+			patch = app.withContext(overlay(patch, flagged))
+			// the todo is checked first, not to add it without its fields
+			item, err := preview(title, patch)
+			if err != nil {
+				return err
+			}
+			if *dryRun {
+				return app.printItem(item)
+			}
+			item, err = app.add(*list, title, patch)
+			if err != nil {
+				return err
+			}
+			return app.printItem(item)
+		}
+	},
+}
+
+var listCommand = Command{
+	Name: "list",
+	Args: "[@<context>]",
+	Help: "list the todos, sorted by ID unless --sort, only the ones with the context if given",
+	Setup: func(flags *flag.FlagSet) func(*App, []string) error {
+		var status, list, filter, sort string
+		var limit int
+		var q ledger.Query
+		var values fieldValues
+		flags.StringVar(&status, "status", "", "list only the todos in the status")
+		flags.StringVar(&q.Tag, "tag", "", "list only the todos with the tag")
+		flags.StringVar(&q.Context, "context", "", "list only the todos with the context, like @home (default: the one of the profile, or all)")
+		flags.StringVar(&list, "list", "", "list only the todos of the list, empty for all (default: the one of the profile, or all)")
+		flags.StringVar(&filter, "filter", "", "list only the todos matching the saved filter, see todo filter")
+		flags.Var(&values, "field", "`name=value`: list only the todos with the value of the custom field, repeatable")
+		flags.IntVar(&limit, "limit", 0, "most todos to list (default: all)")
+		flags.StringVar(&sort, "sort", "", "comma-separated fields to sort the todos by, - first for descending: id, title, status, owner, priority, due, created, updated, rank for the order of todo move, or field.<name> for a custom field")
+		return func(app *App, args []string) error {
+			switch {
+			case len(args) > 1:
+				return errUsage
+			case len(args) == 1 && (len(args[0]) < 2 || args[0][0] != '@' || isSet(flags, "context")):
+				return errUsage
+			case len(args) == 1:
+				q.Context = args[0]
+			case !isSet(flags, "context"):
+				q.Context = app.Config.Context
+			}
+			q.Context = strings.TrimPrefix(q.Context, "@")
+			var err error
+			if q.Sort, err = ledger.ParseSort(sort); err != nil {
+				return err
+			}
+			if !isSet(flags, "list") {
+				list = app.Config.List
+			}
+			q.Status = task.Status(status)
+			q.Fields = values
+			if list == "" && filter == "" {
+				q.Limit = limit
+			}
+			items, _, err := app.Ledger.List(q)
+			if err != nil {
+				return err
+			}
+			if list != "" {
+				items = inList(items, list)
+			}
+			if filter != "" {
+				if items, err = inFilter(app.Ledger, items, filter); err != nil {
+					return err
+				}
+			}
+			if limit > 0 && len(items) > limit {
+				items = items[:limit]
+			}
+			return app.printItems(items)
+		}
+	},
+}
+
+// inFilter returns the todos matching the saved filter, in the order they are listed
+func inFilter(ld *ledger.Ledger, items ledger.Items, filter string) (ledger.Items, error) {
+	matching, err := ld.FilterTodos(filter)
+	if err != nil {
+		return nil, err
+	}
+	ids := make(map[store.ID]bool, len(matching))
+	for _, item := range matching {
+		ids[item.ID] = true
+	}
+	return slices.DeleteFunc(items, func(item ledger.Item) bool {
+		return !ids[item.ID]
+	}), nil
+}
+
+// inList returns the todos of the list
+func inList(items ledger.Items, list string) ledger.Items {
+	return slices.DeleteFunc(items, func(item ledger.Item) bool {
+		in, _ := store.SplitListID(item.ID)
+		return in != list
+	})
+}
+
+var showCommand = Command{
+	Name: "show",
+	Args: "<id>",
+	Help: "show all the fields of a todo",
+	Setup: func(flags *flag.FlagSet) func(*App, []string) error {
+		return func(app *App, args []string) error {
+			id, err := oneID(args)
+			if err != nil {
+				return err
+			}
+			item, _, err := app.Ledger.GetItem(id)
+			if err != nil {
+				return err
+			}
+			return app.printItem(item)
+		}
+	},
+}
+
+var editCommand = Command{
+	Name: "edit",
+	Args: "<id>",
+	Help: "change the fields of a todo given by the flags, or else in $EDITOR",
+	Setup: func(flags *flag.FlagSet) func(*App, []string) error {
+		var fs fields
+		fs.register(flags, true)
+		return func(app *App, args []string) error {
+			id, err := oneID(args)
+			if err != nil {
+				return err
+			}
+			if !fs.given() {
+				return app.editInEditor(id)
+			}
+			item, _, err := app.Ledger.PatchFuncIf(id, fs.patch, ledger.AnyRevision)
+			if err != nil {
+				return err
+			}
+			return app.printItem(item)
+		}
+	},
+}
+
+var moveCommand = Command{
+	Name: "move",
+	Args: "<id>",
+	Help: "move a todo in the manual order of its list, which `todo list --sort rank` lists the todos in",
+	Setup: func(flags *flag.FlagSet) func(*App, []string) error {
+		before := flags.String("before", "", "ID of the todo to move the todo right before (default: last)")
+		return func(app *App, args []string) error {
+			id, err := oneID(args)
+			if err != nil {
+				return err
+			}
+			item, err := app.Ledger.MoveBefore(id, store.ID(*before))
+			if err != nil {
+				return err
+			}
+			return app.printItem(item)
+		}
+	},
+}
+
+var doneCommand = Command{
+	Name: "done",
+	Args: "<id>|-",
+	Help: "complete a todo, assigning it to the user first if nobody is, or the ones whose IDs are read from the standard input",
+	Setup: func(flags *flag.FlagSet) func(*App, []string) error {
+		return func(app *App, args []string) error {
+			id, err := oneID(args)
+			if err != nil {
+				return err
+			}
+			if id == stdinArg {
+				ids, err := readIDs(app.In)
+				if err != nil {
+					return err
+				}
+				report, err := app.Ledger.CompleteByID(ids, app.Actor)
+				if err != nil {
+					return err
+				}
+				return app.printReport(report, nil, "completed")
+			}
+			item, err := app.complete(id)
+			if err != nil {
+				return err
+			}
+			return app.printItem(item)
+		}
+	},
+}
+
+// complete completes the todo, assigning it to the actor first if nobody is
+func (app *App) complete(id store.ID) (ledger.Item, error) {
+	item, rev, err := app.Ledger.GetItem(id)
+	if err != nil {
+		return ledger.Item{}, err
+	}
+	if item.Todo.Assignee == "" && item.Todo.IsOngoing() {
+		assignee := app.Actor
+		if assignee == "" {
+			return ledger.Item{}, errors.New("the todo is not assigned, and the user is unknown: set --as")
+		}
+		if _, rev, err = app.Ledger.PatchIf(id, ledger.Patch{Assignee: &assignee}, rev); err != nil {
+			return ledger.Item{}, err
+		}
+	}
+	item, _, err = app.Ledger.CompleteIf(id, rev)
+	return item, err
+}
+
+var rmCommand = Command{
+	Name: "rm",
+	Args: "<id>|-",
+	Help: "remove a todo, or the ones whose IDs are read from the standard input",
+	Setup: func(flags *flag.FlagSet) func(*App, []string) error {
+		return func(app *App, args []string) error {
+			id, err := oneID(args)
+			if err != nil {
+				return err
+			}
+			if id == stdinArg {
+				ids, err := readIDs(app.In)
+				if err != nil {
+					return err
+				}
+				report, err := app.Ledger.DeleteAll(ids)
+				if err != nil {
+					return err
+				}
+				return app.printReport(report, nil, "removed")
+			}
+			return app.Ledger.Delete(id)
+		}
+	},
+}
+
+var searchCommand = Command{
+	Name: "search",
+	Args: "<query>...",
+	Help: `list the todos matching the query, e.g. status:open tag:work due<2025-01-01 "exact phrase"`,
+	Setup: func(flags *flag.FlagSet) func(*App, []string) error {
+		return func(app *App, args []string) error {
+			if len(args) == 0 {
+				return errUsage
+			}
+			items, err := app.Ledger.Search(strings.Join(args, " "))
+			if err != nil {
+				return err
+			}
+			return app.printItems(items)
+		}
+	},
+}
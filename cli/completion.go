@@ -0,0 +1,358 @@
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/gotestbootcamp/go-todo-app/config"
+	"github.com/gotestbootcamp/go-todo-app/ical"
+	"github.com/gotestbootcamp/go-todo-app/ledger"
+	"github.com/gotestbootcamp/go-todo-app/store"
+	"github.com/gotestbootcamp/go-todo-app/storesync"
+)
+
+// completionScripts are the completion scripts of the shells. They run todo __complete with the
+// words of the command line, the last one being completed, and offer its candidates.
+var completionScripts = map[string]string{
+	"bash": `# bash completion of todo, e.g. in ~/.bashrc: source <(todo completion bash)
+_todo() {
+    local IFS=$'\n'
+    local candidates=($(todo __complete "${COMP_WORDS[@]:1:COMP_CWORD}" 2>/dev/null))
+    COMPREPLY=("${candidates[@]%%$'\t'*}")
+}
+complete -o default -F _todo todo
+`,
+	"zsh": `#compdef todo
+# zsh completion of todo, e.g. in ~/.zshrc: source <(todo completion zsh)
+_todo() {
+    local -a candidates
+    candidates=(${(f)"$(todo __complete "${(@)words[2,CURRENT]}" 2>/dev/null)"})
+    if (( ${#candidates} )); then
+        _describe todo "${(@)candidates//$'\t'/:}"
+    else
+        _files
+    fi
+}
+compdef _todo todo
+`,
+	"fish": `# fish completion of todo, e.g.: todo completion fish > ~/.config/fish/completions/todo.fish
+function __todo_complete
+    set -l words (commandline -opc) (commandline -ct)
+    todo __complete $words[2..-1] 2>/dev/null
+end
+complete -c todo -f -a '(__todo_complete)'
+`,
+}
+
+func init() {
+	// appended here, completing the commands refers to them
+	Commands = append(Commands, completionCommand, completeCommand)
+}
+
+var completionCommand = Command{
+	Name:    "completion",
+	Args:    "bash|zsh|fish",
+	Help:    "print the completion script of the shell, completing the commands, flags, IDs and tags",
+	NoStore: true,
+	Setup: func(flags *flag.FlagSet) func(*App, []string) error {
+		return func(app *App, args []string) error {
+			if len(args) != 1 {
+				return errUsage
+			}
+			script, ok := completionScripts[args[0]]
+			if !ok {
+				return errUsage
+			}
+			_, err := io.WriteString(app.Out, script)
+			return err
+		}
+	},
+}
+
+var completeCommand = Command{
+	Name:    "__complete",
+	Args:    "<word>...",
+	Help:    "print the candidates completing the last word of the command line, for the completion scripts",
+	Hidden:  true,
+	NoStore: true,
+	Setup: func(flags *flag.FlagSet) func(*App, []string) error {
+		return func(app *App, args []string) error {
+			if len(args) == 0 {
+				return errUsage
+			}
+			for _, cand := range complete(args) {
+				fmt.Fprintln(app.Out, cand)
+			}
+			return nil
+		}
+	},
+}
+
+// complete returns the candidates completing the last of the words of the command line, after
+// "todo", each a value optionally followed by a tab and its description
+func complete(words []string) []string {
+	cur := words[len(words)-1]
+	if len(words) == 1 {
+		cands := []string{"serve\tserve the REST and gRPC APIs", "help\tprint the commands"}
+		for _, cmd := range Commands {
+			if !cmd.Hidden {
+				cands = append(cands, cmd.Name+"\t"+cmd.Help)
+			}
+		}
+		return matching(cands, cur)
+	}
+	cmd, ok := Lookup(words[0])
+	if !ok {
+		return nil
+	}
+
+	// the flags typed so far select the store, if not the default one
+	flags := flag.NewFlagSet(cmd.Name, flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	var g globals
+	g.register(flags)
+	cmd.Setup(flags)
+	prev := words[len(words)-2]
+	_ = flags.Parse(words[1 : len(words)-1])
+	_, _ = g.configure(flags)
+
+	if strings.HasPrefix(cur, "-") && !strings.Contains(cur, "=") {
+		var cands []string
+		flags.VisitAll(func(f *flag.Flag) {
+			cands = append(cands, "--"+f.Name+"\t"+f.Usage)
+		})
+		return matching(cands, cur)
+	}
+	flagName, value := "", cur
+	if name, val, ok := strings.Cut(cur, "="); ok && strings.HasPrefix(name, "-") {
+		flagName, value = strings.TrimLeft(name, "-"), val
+	} else if f := flags.Lookup(strings.TrimLeft(prev, "-")); strings.HasPrefix(prev, "-") && f != nil && !isBoolFlag(f) {
+		flagName = f.Name
+	}
+	if flagName != "" {
+		cands := completeFlag(g, flagName, value)
+		if name, _, ok := strings.Cut(cur, "="); ok {
+			for i := range cands {
+				cands[i] = name + "=" + cands[i]
+			}
+		}
+		return cands
+	}
+	return completeArg(g, cmd.Name, cur)
+}
+
+// completeFlag returns the candidates completing the value of the flag
+func completeFlag(g globals, name, value string) []string {
+	switch name {
+	case "output":
+		return matching(formats, value)
+	case "format":
+		return matching(exportFormats, value)
+	case "component":
+		return matching([]string{string(ical.Event), string(ical.ToDo)}, value)
+	case "group-by":
+		return matching(markdownGroups, value)
+	case "ids":
+		return matching([]string{importNewIDs, importKeepIDs}, value)
+	case "period":
+		return matching(ledger.StatsPeriods, value)
+	case "chart":
+		return matching(charts, value)
+	case "strategy":
+		return matching([]string{string(storesync.Merge), string(storesync.LatestWins), string(storesync.Manual)}, value)
+	case "profile":
+		return completeProfiles(value)
+	case "priority":
+		return matching([]string{"none", "low", "normal", "high", "urgent"}, value)
+	case "status":
+		ld := openReadOnly(g)
+		if ld == nil {
+			return nil
+		}
+		defer ld.Close()
+		var cands []string
+		for _, status := range ld.Workflow().Statuses() {
+			cands = append(cands, string(status))
+		}
+		return matching(cands, value)
+	case "tag":
+		return completeTags(g, "", value)
+	case "before":
+		return completeArg(g, "move", value)
+	case "context":
+		return completeContexts(g, "", strings.TrimPrefix(value, "@"))
+	case "tags":
+		// the last of the comma-separated tags
+		i := strings.LastIndex(value, ",") + 1
+		return completeTags(g, value[:i], value[i:])
+	case "list":
+		ld := openReadOnly(g)
+		if ld == nil {
+			return nil
+		}
+		defer ld.Close()
+		lists, _ := ld.Lists()
+		return matching(lists, value)
+	case "filter":
+		ld := openReadOnly(g)
+		if ld == nil {
+			return nil
+		}
+		defer ld.Close()
+		return matching(filterNames(ld), value)
+	case "field":
+		ld := openReadOnly(g)
+		if ld == nil {
+			return nil
+		}
+		defer ld.Close()
+		var cands []string
+		for _, name := range fieldNames(ld) {
+			cands = append(cands, name+"=")
+		}
+		return matching(cands, value)
+	}
+	// e.g. the files of --data-dir, by the shell
+	return nil
+}
+
+// completeArg returns the candidates completing the argument of the command
+func completeArg(g globals, cmd, cur string) []string {
+	switch cmd {
+	case "show", "edit", "move", "done", "snooze", "pomo", "rm", "link":
+		ld := openReadOnly(g)
+		if ld == nil {
+			return nil
+		}
+		defer ld.Close()
+		items, _, err := ld.List(ledger.Query{})
+		if err != nil {
+			return nil
+		}
+		var cands []string
+		for _, item := range items {
+			if (cmd == "done" || cmd == "snooze" || cmd == "pomo") && !item.Todo.IsOngoing() {
+				continue
+			}
+			cands = append(cands, string(item.ID)+"\t"+item.Todo.Title)
+		}
+		return matching(cands, cur)
+	case "config":
+		return matching(configSubcommands, cur)
+	case "filter":
+		return matching(filterSubcommands, cur)
+	case "field":
+		return matching(fieldSubcommands, cur)
+	case "sync":
+		// or a storage URI
+		return completeProfiles(cur)
+	case "add":
+		// the words of the quick-add syntax
+		switch {
+		case strings.HasPrefix(cur, "#"):
+			return completeTags(g, "#", cur[1:])
+		case strings.HasPrefix(cur, "@"):
+			return completeContexts(g, "@", cur[1:])
+		case strings.HasPrefix(cur, "!"):
+			return matching([]string{"!p0\turgent", "!p1\thigh", "!p2\tnormal", "!p3\tlow"}, cur)
+		}
+	case "list":
+		if strings.HasPrefix(cur, "@") {
+			return completeContexts(g, "@", cur[1:])
+		}
+	}
+	return nil
+}
+
+// completeProfiles returns the profiles of the configuration file starting with the value
+func completeProfiles(value string) []string {
+	cfg, err := config.LoadCLIConfig(config.CLIConfigPath())
+	if err != nil {
+		return nil
+	}
+	return matching(cfg.ProfileNames(), value)
+}
+
+// completeTags returns the known tags starting with the value, after the prefix
+func completeTags(g globals, prefix, value string) []string {
+	ld := openReadOnly(g)
+	if ld == nil {
+		return nil
+	}
+	defer ld.Close()
+	tags, err := ld.ListTags()
+	if err != nil {
+		return nil
+	}
+	var cands []string
+	for _, tag := range tags {
+		if strings.HasPrefix(tag.Name, value) {
+			cands = append(cands, prefix+tag.Name)
+		}
+	}
+	return cands
+}
+
+// completeContexts returns the contexts of the todos starting with the value, after the prefix
+func completeContexts(g globals, prefix, value string) []string {
+	ld := openReadOnly(g)
+	if ld == nil {
+		return nil
+	}
+	defer ld.Close()
+	items, _, err := ld.List(ledger.Query{})
+	if err != nil {
+		return nil
+	}
+	var cands []string
+	for _, item := range items {
+		for _, ctx := range item.Task.Contexts {
+			if cand := prefix + ctx; strings.HasPrefix(ctx, value) && !slices.Contains(cands, cand) {
+				cands = append(cands, cand)
+			}
+		}
+	}
+	slices.Sort(cands)
+	return cands
+}
+
+// matching returns the candidates whose value starts with the prefix
+func matching(cands []string, prefix string) []string {
+	return slices.DeleteFunc(slices.Clone(cands), func(cand string) bool {
+		return !strings.HasPrefix(cand, prefix)
+	})
+}
+
+func isBoolFlag(f *flag.Flag) bool {
+	bf, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && bf.IsBoolFlag()
+}
+
+// openReadOnly opens the ledger of the store the flags select, only to read it: the data directory
+// is not created if missing, nor the store indexed. Nil if the store can't be opened.
+func openReadOnly(g globals) *ledger.Ledger {
+	uri := g.store
+	if uri == "" {
+		path := filepath.Join(g.dataDir, "todo.db")
+		if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		uri = "sqlite://" + path
+	}
+	st, err := store.Open(uri)
+	if err != nil {
+		return nil
+	}
+	ld, err := ledger.New(st)
+	if err != nil {
+		st.Close()
+		return nil
+	}
+	return ld
+}
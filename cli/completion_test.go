@@ -0,0 +1,57 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestComplete(t *testing.T) {
+	dir := t.TempDir()
+	assert.Empty(t, complete([]string{"show", "--data-dir", dir, ""}), "no store, no IDs")
+	assert.NoDirExists(t, dir+"/todo.db", "the store is not created")
+
+	code, _, _ := run(t, dir, "add", "buy milk #home @errands")
+	require.Equal(t, 0, code)
+	code, _, _ = run(t, dir, "add", "--list", "work", "ship it #release")
+	require.Equal(t, 0, code)
+	code, _, _ = run(t, dir, "done", "--as", "ann", "1")
+	require.Equal(t, 0, code)
+	_, out, _ := run(t, dir, "list", "--list", "work")
+	assert.Contains(t, out, "work/2")
+	assert.NotContains(t, out, "buy milk")
+
+	assert.Equal(t, []string{"show\tshow all the fields of a todo"}, complete([]string{"sh"}))
+	assert.Contains(t, complete([]string{""}), "serve\tserve the REST and gRPC APIs")
+	assert.NotContains(t, complete([]string{"__"}), "__complete", "hidden")
+
+	assert.Equal(t, []string{"1\tbuy milk", "work/2\tship it"}, complete([]string{"show", "--data-dir", dir, ""}))
+	assert.Equal(t, []string{"work/2\tship it"}, complete([]string{"done", "--data-dir", dir, ""}), "only the ongoing ones")
+	assert.Equal(t, []string{"work/2\tship it"}, complete([]string{"rm", "--data-dir", dir, "w"}))
+
+	assert.Equal(t, []string{"home", "release"}, complete([]string{"list", "--data-dir", dir, "--tag", ""}))
+	assert.Equal(t, []string{"home,release"}, complete([]string{"add", "--data-dir", dir, "--tags", "home,r"}))
+	assert.Equal(t, []string{"#home"}, complete([]string{"add", "--data-dir", dir, "buy", "#h"}))
+	assert.Equal(t, []string{"!p1\thigh"}, complete([]string{"add", "!p1"}))
+	assert.Equal(t, []string{"@errands"}, complete([]string{"list", "--data-dir", dir, "@e"}))
+	assert.Equal(t, []string{"errands"}, complete([]string{"list", "--data-dir", dir, "--context", ""}))
+	assert.Equal(t, []string{"work"}, complete([]string{"list", "--data-dir", dir, "--list", ""}))
+	assert.Equal(t, []string{"--list=work"}, complete([]string{"list", "--data-dir=" + dir, "--list=w"}))
+	assert.Equal(t, []string{"json"}, complete([]string{"list", "-output", "j"}))
+	assert.Contains(t, complete([]string{"list", "--data-dir", dir, "--status", ""}), "completed")
+
+	assert.Equal(t, []string{"--dry-run\tprint the todo parsed from the arguments, without adding it"}, complete([]string{"add", "--dr"}))
+	assert.Empty(t, complete([]string{"add", "--dry-run", ""}), "no value after a bool flag")
+	assert.Empty(t, complete([]string{"list", "--data-dir", ""}), "the files, by the shell")
+}
+
+func TestRunCompletion(t *testing.T) {
+	for _, shell := range []string{"bash", "zsh", "fish"} {
+		code, out, _ := run(t, t.TempDir(), "completion", shell)
+		assert.Equal(t, 0, code)
+		assert.Contains(t, out, "todo __complete", shell)
+	}
+	code, _, _ := run(t, t.TempDir(), "completion", "tcsh")
+	assert.Equal(t, 2, code)
+}
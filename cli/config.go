@@ -0,0 +1,164 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/gotestbootcamp/go-todo-app/config"
+	"github.com/gotestbootcamp/go-todo-app/store"
+	"github.com/gotestbootcamp/go-todo-app/task"
+)
+
+// configSubcommands are the subcommands of todo config
+var configSubcommands = []string{"list", "get", "set", "path"}
+
+// configEntry is a setting, as printed by todo config
+type configEntry struct {
+	Key    string `json:"key"`
+	Value  string `json:"value"`
+	Source string `json:"source"`
+}
+
+var configCommand = Command{
+	Name:    "config",
+	Args:    "[list | get <key> | set [<profile>.]<key> <value> | path]",
+	Help:    "print the settings of the configuration file and of the environment, or set them",
+	NoStore: true,
+	Setup: func(flags *flag.FlagSet) func(*App, []string) error {
+		return func(app *App, args []string) error {
+			sub := "list"
+			if len(args) > 0 {
+				sub, args = args[0], args[1:]
+			}
+			switch {
+			case sub == "list" && len(args) == 0:
+				return app.printConfig()
+			case sub == "get" && len(args) == 1:
+				for _, entry := range app.configEntries() {
+					if entry.Key == args[0] {
+						_, err := fmt.Fprintln(app.Out, entry.Value)
+						return err
+					}
+				}
+				return fmt.Errorf("unknown setting %q, want profile or one of %s", args[0], strings.Join(config.CLISettingKeys, ", "))
+			case sub == "set" && len(args) == 2:
+				return setConfig(args[0], args[1])
+			case sub == "path" && len(args) == 0:
+				_, err := fmt.Fprintln(app.Out, config.CLIConfigPath())
+				return err
+			}
+			return errUsage
+		}
+	},
+}
+
+// configEntries returns the settings in effect, along with where they come from: "flag", "env",
+// "file", "profile <name>", or "default"
+func (app *App) configEntries() []configEntry {
+	cfg := app.Config
+	source := func(key string) string {
+		if src, ok := cfg.Sources[key]; ok {
+			return src
+		}
+		return "default"
+	}
+	entries := []configEntry{{Key: "profile", Value: cfg.Profile, Source: source("profile")}}
+	for _, key := range config.CLISettingKeys {
+		value, _ := cfg.Get(key)
+		if value == "" {
+			switch key {
+			case "data-dir":
+				value = DefaultDataDir()
+			case "output":
+				value = FormatText
+			}
+		}
+		entries = append(entries, configEntry{Key: key, Value: value, Source: source(key)})
+	}
+	return entries
+}
+
+// printConfig writes the settings in effect, and where they come from
+func (app *App) printConfig() error {
+	entries := app.configEntries()
+	switch app.Format {
+	case FormatJSON:
+		return printJSON(app.Out, entries)
+	case FormatYAML:
+		return printYAML(app.Out, entries)
+	}
+	tw := tabwriter.NewWriter(app.Out, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "KEY\tVALUE\tSOURCE\n")
+	for _, entry := range entries {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", entry.Key, entry.Value, entry.Source)
+	}
+	return tw.Flush()
+}
+
+// setConfig sets the setting in the configuration file, of the profile if the key starts with its
+// name and a dot. The empty value unsets it. The profile key selects the profile used by default.
+func setConfig(key, value string) error {
+	path := config.CLIConfigPath()
+	cfg, err := config.LoadCLIConfig(path)
+	if err != nil {
+		return err
+	}
+	if key == "profile" {
+		if _, ok := cfg.Profiles[value]; !ok && value != "" {
+			return fmt.Errorf("unknown profile %q, want one of %s", value, strings.Join(cfg.ProfileNames(), ", "))
+		}
+		cfg.Profile = value
+		return cfg.Save(path)
+	}
+
+	profile, key := cutProfile(key)
+	switch key {
+	case "output":
+		if value != "" && !slices.Contains(formats, value) {
+			return fmt.Errorf("invalid output format %q, want one of %s", value, strings.Join(formats, ", "))
+		}
+	case "template":
+		if _, err := parseTemplate(value); err != nil && value != "" {
+			return err
+		}
+	case "list":
+		if value != store.DefaultList {
+			if err := store.ValidateList(value); err != nil {
+				return err
+			}
+		}
+	case "context":
+		if value = strings.TrimPrefix(value, "@"); value != "" {
+			if err := task.ValidateContext(value); err != nil {
+				return err
+			}
+		}
+	}
+	if profile == "" {
+		if err := cfg.Set(key, value); err != nil {
+			return err
+		}
+		return cfg.Save(path)
+	}
+	settings := cfg.Profiles[profile]
+	if err := settings.Set(key, value); err != nil {
+		return err
+	}
+	if cfg.Profiles == nil {
+		cfg.Profiles = make(map[string]config.CLISettings)
+	}
+	cfg.Profiles[profile] = settings
+	return cfg.Save(path)
+}
+
+// cutProfile splits the key of a setting of a profile, like "work.data-dir", in the profile and
+// the key. No profile if the key has no dot.
+func cutProfile(key string) (string, string) {
+	if profile, rest, ok := strings.Cut(key, "."); ok {
+		return profile, rest
+	}
+	return "", key
+}
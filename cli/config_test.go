@@ -0,0 +1,97 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// runConfigured runs the command with the settings of the configuration file, returning its exit
+// code, output and errors
+func runConfigured(t *testing.T, args ...string) (int, string, string) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	code := Run(args, strings.NewReader(""), &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestRunConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "todo", "config.yaml")
+	t.Setenv("TODO_CONFIG", path)
+	t.Setenv("TODO_PROFILE", "")
+	t.Setenv("TODO_OUTPUT", "")
+	work, personal := t.TempDir(), t.TempDir()
+
+	for _, args := range [][]string{
+		{"work.data-dir", work},
+		{"work.list", "team"},
+		{"personal.data-dir", personal},
+		{"output", "json"},
+		{"profile", "work"},
+	} {
+		code, _, errs := runConfigured(t, append([]string{"config", "set"}, args...)...)
+		require.Equal(t, 0, code, errs)
+	}
+	code, _, errs := runConfigured(t, "config", "set", "output", "xml")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, errs, "invalid output format")
+	code, _, errs = runConfigured(t, "config", "set", "profile", "school")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, errs, `unknown profile "school"`)
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "profile: work\noutput: json\nprofiles:\n")
+
+	// the work profile, selected by the file
+	code, out, _ := runConfigured(t, "add", "call mom")
+	require.Equal(t, 0, code)
+	var item struct {
+		ID string `json:"id"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(out), &item))
+	assert.Equal(t, "team/1", item.ID)
+
+	// the flags, then the environment, take precedence
+	code, out, _ = runConfigured(t, "add", "--profile", "personal", "--output", "text", "walk the dog")
+	require.Equal(t, 0, code)
+	assert.Contains(t, out, "ID:       1\n")
+	t.Setenv("TODO_PROFILE", "personal")
+	t.Setenv("TODO_OUTPUT", "tsv")
+	code, out, _ = runConfigured(t, "list")
+	require.Equal(t, 0, code)
+	assert.Contains(t, out, "1\tpending\twalk the dog")
+	assert.NotContains(t, out, "call mom")
+
+	code, out, _ = runConfigured(t, "config", "--output", "text")
+	require.Equal(t, 0, code)
+	assert.Regexp(t, `profile +personal +env\n`, out)
+	assert.Regexp(t, `data-dir +`+personal+` +profile personal\n`, out)
+	assert.Regexp(t, `list +default\n`, out)
+	assert.Regexp(t, `output +text +flag\n`, out)
+	code, out, _ = runConfigured(t, "config", "--profile", "work", "get", "list")
+	require.Equal(t, 0, code)
+	assert.Equal(t, "team\n", out)
+	code, out, _ = runConfigured(t, "config", "path")
+	require.Equal(t, 0, code)
+	assert.Equal(t, path+"\n", out)
+
+	code, _, errs = runConfigured(t, "list", "--profile", "school")
+	assert.Equal(t, 2, code)
+	assert.Contains(t, errs, `unknown profile "school"`)
+
+	// the data directory given takes precedence over the store of the profile
+	code, _, errs = runConfigured(t, "config", "set", "personal.store", "bolt://"+filepath.Join(personal, "todo.bolt"))
+	require.Equal(t, 0, code, errs)
+	code, out, _ = runConfigured(t, "list", "--data-dir", work)
+	require.Equal(t, 0, code)
+	assert.Contains(t, out, "call mom")
+	code, out, _ = runConfigured(t, "list")
+	require.Equal(t, 0, code)
+	assert.NotContains(t, out, "walk the dog", "in the store of the profile")
+}
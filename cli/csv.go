@@ -0,0 +1,241 @@
+package cli
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gotestbootcamp/go-todo-app/ledger"
+	"github.com/gotestbootcamp/go-todo-app/store"
+	"github.com/gotestbootcamp/go-todo-app/task"
+)
+
+// csvFields are the fields of the todos in the csv files of todo export and todo import, in the
+// order of their columns by default. The lists of tags and blockers are comma-separated, the times
+// in RFC 3339. The updated field is only exported.
+var csvFields = []string{"id", "title", "description", "status", "assignee", "priority", "tags", "due", "remind", "recur", "blocked_by", "created", "updated"}
+
+// csvColumn is a column of a csv file, holding a field of the todos
+type csvColumn struct {
+	field  string
+	header string
+}
+
+// parseColumns parses the columns of a csv file, a comma-separated list of the fields, each
+// optionally followed by = and the header of its column, like "title=Name,due=Deadline".
+// Empty is all the fields, with their names as headers.
+func parseColumns(spec string) ([]csvColumn, error) {
+	if spec == "" {
+		columns := make([]csvColumn, 0, len(csvFields))
+		for _, field := range csvFields {
+			columns = append(columns, csvColumn{field: field, header: field})
+		}
+		return columns, nil
+	}
+	var columns []csvColumn
+	for _, part := range strings.Split(spec, ",") {
+		field, header, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			header = field
+		}
+		if !slices.Contains(csvFields, field) {
+			return nil, fmt.Errorf("unknown field %q, want one of %s", field, strings.Join(csvFields, ", "))
+		}
+		if header == "" {
+			return nil, fmt.Errorf("no header for the field %s", field)
+		}
+		columns = append(columns, csvColumn{field: field, header: header})
+	}
+	return columns, nil
+}
+
+// writeCSV writes the todos as csv, with a header, in the columns
+func writeCSV(w io.Writer, items ledger.Items, columns []csvColumn) error {
+	cw := csv.NewWriter(w)
+	row := make([]string, len(columns))
+	for i, col := range columns {
+		row[i] = col.header
+	}
+	if err := cw.Write(row); err != nil {
+		return err
+	}
+	for _, item := range items {
+		for i, col := range columns {
+			row[i] = csvValue(item, col.field)
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// csvValue returns the value of the field of the todo in a csv file
+func csvValue(item ledger.Item, field string) string {
+	tk := item.Task
+	rfc3339 := func(t *time.Time) string {
+		if t == nil {
+			return ""
+		}
+		return t.Format(time.RFC3339)
+	}
+	switch field {
+	case "id":
+		return string(item.ID)
+	case "title":
+		return tk.Title
+	case "description":
+		return tk.Description
+	case "status":
+		return string(tk.Status)
+	case "assignee":
+		return tk.Assignee
+	case "priority":
+		return tk.Priority.String()
+	case "tags":
+		return strings.Join(tk.Tags, ",")
+	case "due":
+		return rfc3339(tk.Due)
+	case "remind":
+		return rfc3339(tk.Remind)
+	case "recur":
+		return tk.Recur
+	case "blocked_by":
+		return strings.Join(tk.BlockedBy, ",")
+	case "created":
+		return rfc3339(&tk.Created)
+	case "updated":
+		return rfc3339(&tk.Updated)
+	}
+	return ""
+}
+
+// readCSV reads the todos of a csv file, with a header. Mapped, the columns are the ones with the
+// headers of the columns given, the others being ignored; otherwise, the headers are the names
+// of the fields, in any order.
+func readCSV(r io.Reader, columns []csvColumn, mapped bool) ([]importRecord, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	header, err := cr.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid csv: %w", err)
+	}
+	// the field of each column of the file, empty if ignored
+	fields := make([]string, len(header))
+	for i, name := range header {
+		// the files saved by spreadsheets may start with a byte order mark
+		name = strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))
+		header[i] = name
+		if !mapped {
+			if !slices.Contains(csvFields, name) {
+				return nil, fmt.Errorf("unknown column %q, map the columns to the fields with --columns", name)
+			}
+			fields[i] = name
+			continue
+		}
+		for _, col := range columns {
+			if col.header == name {
+				fields[i] = col.field
+			}
+		}
+	}
+	if mapped {
+		for _, col := range columns {
+			if !slices.Contains(header, col.header) {
+				return nil, fmt.Errorf("no column %q in the header", col.header)
+			}
+		}
+	}
+	if !slices.Contains(fields, "title") {
+		return nil, errors.New("no column of the title in the header")
+	}
+
+	var records []importRecord
+	for {
+		row, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return records, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid csv: %w", err)
+		}
+		line, _ := cr.FieldPos(0)
+		rec := importRecord{label: fmt.Sprintf("line %d", line), task: task.New("")}
+		for i, value := range row {
+			if i < len(fields) && fields[i] != "" && rec.err == nil {
+				rec.err = rec.setCSVValue(fields[i], strings.TrimSpace(value))
+			}
+		}
+		if rec.err == nil {
+			rec.err = rec.task.Validate()
+		}
+		records = append(records, rec)
+	}
+}
+
+// setCSVValue sets the field of the todo to the value of its column
+func (rec *importRecord) setCSVValue(field, value string) error {
+	tk := &rec.task
+	parseTime := func(value string) (*time.Time, error) {
+		if value == "" {
+			return nil, nil
+		}
+		t, err := dateParser.Parse(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", field, err)
+		}
+		return &t, nil
+	}
+	split := func(value string) []string {
+		var values []string
+		for _, v := range strings.Split(value, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				values = append(values, v)
+			}
+		}
+		return values
+	}
+	var err error
+	switch field {
+	case "id":
+		rec.id = store.ID(value)
+	case "title":
+		tk.Title = value
+	case "description":
+		tk.Description = value
+	case "status":
+		if value != "" {
+			tk.Status = task.Status(value)
+		}
+	case "assignee":
+		tk.Assignee = value
+	case "priority":
+		if value != "" {
+			tk.Priority, err = task.ParsePriority(value)
+		}
+	case "tags":
+		tk.Tags = split(value)
+	case "due":
+		tk.Due, err = parseTime(value)
+	case "remind":
+		tk.Remind, err = parseTime(value)
+	case "recur":
+		tk.Recur = value
+	case "blocked_by":
+		tk.BlockedBy = split(value)
+	case "created":
+		var created *time.Time
+		if created, err = parseTime(value); created != nil {
+			tk.Created = *created
+		}
+	}
+	return err
+}
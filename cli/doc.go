@@ -0,0 +1,3 @@
+// Package cli implements the commands of the todo CLI, like add, list or done, which manage the
+// todos of a local store through the ledger, without a server
+package cli
@@ -0,0 +1,350 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+
+	"github.com/gotestbootcamp/go-todo-app/ledger"
+	"github.com/gotestbootcamp/go-todo-app/store"
+	"github.com/gotestbootcamp/go-todo-app/task"
+	"gopkg.in/yaml.v3"
+)
+
+// frontMatter delimits the fields of the todo edited, before its description
+const frontMatter = "---\n"
+
+// errorComment starts the comments telling why the edit failed, in the file reopened
+const errorComment = "# error: "
+
+// editable are the fields of a todo edited in the editor, in the yaml front matter of the file
+type editable struct {
+	Title    string   `yaml:"title"`
+	Status   string   `yaml:"status"`
+	Assignee string   `yaml:"assignee"`
+	Priority string   `yaml:"priority"`
+	Tags     []string `yaml:"tags,flow"`
+	Contexts []string `yaml:"contexts,flow"`
+	Due      string   `yaml:"due"`
+	Remind   string   `yaml:"remind"`
+	Recur    string   `yaml:"recur"`
+	// Fields are the custom fields of the todo, left out if it has none
+	Fields map[string]string `yaml:"fields,omitempty"`
+	// Description is the text after the front matter
+	Description string `yaml:"-"`
+}
+
+// editableOf returns the fields of the todo to edit
+func editableOf(item ledger.Item) editable {
+	tk := item.Task
+	return editable{
+		Title:       tk.Title,
+		Status:      string(tk.Status),
+		Assignee:    tk.Assignee,
+		Priority:    tk.Priority.String(),
+		Tags:        slices.Clone(tk.Tags),
+		Contexts:    slices.Clone(tk.Contexts),
+		Due:         formatTime(tk.Due),
+		Remind:      formatTime(tk.Remind),
+		Recur:       tk.Recur,
+		Fields:      maps.Clone(tk.Fields),
+		Description: tk.Description,
+	}
+}
+
+// marshal returns the fields as markdown, with the yaml front matter
+func (ed editable) marshal() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString(frontMatter)
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(ed); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	buf.WriteString(frontMatter)
+	if ed.Description != "" {
+		buf.WriteString(ed.Description + "\n")
+	}
+	return buf.Bytes(), nil
+}
+
+// unmarshalEditable parses the fields of the file edited, as marshal writes them
+func unmarshalEditable(data []byte) (editable, error) {
+	var ed editable
+	text := strings.ReplaceAll(string(data), "\r\n", "\n")
+	if !strings.HasPrefix(text, frontMatter) {
+		return ed, errors.New("the file doesn't start with the --- of the front matter")
+	}
+	header, body, ok := strings.Cut(text[len(frontMatter):], "\n"+frontMatter)
+	if !ok {
+		if header, ok = strings.CutSuffix(strings.TrimRight(text[len(frontMatter):], "\n"), "\n---"); !ok {
+			return ed, errors.New("the front matter doesn't end with ---")
+		}
+	}
+	dec := yaml.NewDecoder(strings.NewReader(header))
+	dec.KnownFields(true)
+	if err := dec.Decode(&ed); err != nil && !errors.Is(err, io.EOF) {
+		return ed, fmt.Errorf("invalid front matter: %w", err)
+	}
+	ed.Description = strings.TrimSpace(body)
+	return ed, nil
+}
+
+// patch returns the patch changing the todo as edited, the fields of the original being the ones
+// of the todo before the edit
+func (ed editable) patch(orig editable, cur ledger.Item) (ledger.Patch, error) {
+	var patch ledger.Patch
+	if ed.Title != orig.Title {
+		patch.Title = &ed.Title
+	}
+	if ed.Description != orig.Description {
+		patch.Description = &ed.Description
+	}
+	if ed.Assignee != orig.Assignee {
+		patch.Assignee = &ed.Assignee
+	}
+	if ed.Status != orig.Status {
+		status := task.Status(ed.Status)
+		patch.Status = &status
+	}
+	if ed.Priority != orig.Priority {
+		pr, err := task.ParsePriority(ed.Priority)
+		if err != nil {
+			return patch, err
+		}
+		patch.Priority = &pr
+	}
+	if !slices.Equal(ed.Tags, orig.Tags) {
+		tags := slices.Clone(ed.Tags)
+		if tags == nil {
+			tags = []string{}
+		}
+		patch.Tags = &tags
+	}
+	if !slices.Equal(ed.Contexts, orig.Contexts) {
+		contexts := slices.Clone(ed.Contexts)
+		if contexts == nil {
+			contexts = []string{}
+		}
+		patch.Contexts = &contexts
+	}
+	// the fields removed are unset
+	values := make(map[string]string)
+	for name, value := range ed.Fields {
+		if orig.Fields[name] != value {
+			values[name] = value
+		}
+	}
+	for name := range orig.Fields {
+		if _, ok := ed.Fields[name]; !ok {
+			values[name] = ""
+		}
+	}
+	if len(values) > 0 {
+		patch.Fields = &values
+	}
+	if ed.Due != orig.Due || ed.Remind != orig.Remind || ed.Recur != orig.Recur {
+		// the times left as they were keep their seconds
+		sched := ledger.Schedule{Due: cur.Task.Due, Remind: cur.Task.Remind, Recur: ed.Recur}
+		var err error
+		if ed.Due != orig.Due {
+			if sched.Due, err = parseDue(ed.Due); err != nil {
+				return patch, err
+			}
+		}
+		if ed.Remind != orig.Remind && ed.Remind != "" {
+			remind, err := dateParser.Parse(ed.Remind)
+			if err != nil {
+				return patch, fmt.Errorf("remind: %w", err)
+			}
+			sched.Remind = &remind
+		} else if ed.Remind == "" {
+			sched.Remind = nil
+		}
+		if sched.Recur != "" {
+			if _, err := task.ParseRecurrence(sched.Recur); err != nil {
+				return patch, fmt.Errorf("recur: %w", err)
+			}
+		}
+		patch.Schedule = &sched
+	}
+	return patch, nil
+}
+
+// editInEditor edits the todo in the editor, as markdown with the fields in the yaml front matter
+// and the description after it, like git and kubectl do. The file saved is reopened in the editor
+// with the error if it isn't valid, and the edit cancelled if saved unchanged. The changes are
+// printed as a diff, and applied only if no one changed the todo meanwhile: otherwise, the edit
+// fails with store.ErrConflict, and the file is kept.
+func (app *App) editInEditor(id store.ID) error {
+	cur, rev, err := app.Ledger.GetItem(id)
+	if err != nil {
+		return err
+	}
+	orig := editableOf(cur)
+	data, err := orig.marshal()
+	if err != nil {
+		return err
+	}
+	f, err := os.CreateTemp("", "todo-"+strings.ReplaceAll(string(id), "/", "-")+"-*.md")
+	if err != nil {
+		return err
+	}
+	path := f.Name()
+	f.Close()
+	keep := false
+	defer func() {
+		if !keep {
+			os.Remove(path)
+		}
+	}()
+
+	var lastErr error
+	for {
+		if err := os.WriteFile(path, data, 0o600); err != nil {
+			return err
+		}
+		if err := runEditor(app, path); err != nil {
+			return err
+		}
+		edited, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if bytes.Equal(edited, data) {
+			if lastErr != nil {
+				return fmt.Errorf("edit cancelled, no valid changes were saved: %w", lastErr)
+			}
+			_, err := fmt.Fprintln(app.Out, "Edit cancelled, no changes made.")
+			return err
+		}
+
+		ed, err := unmarshalEditable(edited)
+		var patch ledger.Patch
+		if err == nil {
+			patch, err = ed.patch(orig, cur)
+		}
+		if err == nil && patch == (ledger.Patch{}) {
+			_, err := fmt.Fprintln(app.Out, "Edit cancelled, no changes made.")
+			return err
+		}
+		var item ledger.Item
+		if err == nil {
+			item, err = app.patchEdited(id, patch, rev)
+		}
+		if errors.As(err, &store.ErrConflict{}) {
+			keep = true
+			return fmt.Errorf("%w: the todo changed while editing it, the edit is kept in %s", err, path)
+		}
+		if err != nil {
+			// reopen the file, with the error on top
+			lastErr = err
+			data = withError(edited, err)
+			continue
+		}
+
+		if app.Format != FormatText {
+			return app.printItem(item)
+		}
+		origData, err := orig.marshal()
+		if err != nil {
+			return err
+		}
+		newData, err := editableOf(item).marshal()
+		if err != nil {
+			return err
+		}
+		return printDiff(app.Out, string(origData), string(newData))
+	}
+}
+
+// patchEdited applies the patch to the todo, if it still is at the revision, with the ledger
+// reloaded to see the changes other processes made while editing it. The ledger isn't opened
+// again, as the datastores like bolt are locked by the process which has them open.
+func (app *App) patchEdited(id store.ID, patch ledger.Patch, rev int) (ledger.Item, error) {
+	if err := app.Ledger.Reload(); err != nil {
+		return ledger.Item{}, err
+	}
+	item, _, err := app.Ledger.PatchIf(id, patch, rev)
+	return item, err
+}
+
+// runEditor runs the editor of $VISUAL, or else $EDITOR, or vi, on the file
+func runEditor(app *App, path string) error {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	args := strings.Fields(editor)
+	if len(args) == 0 {
+		args = []string{"vi"}
+	}
+	cmd := exec.Command(args[0], append(args[1:], path)...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = app.In, app.Out, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("editor %s: %w", args[0], err)
+	}
+	return nil
+}
+
+// withError returns the file edited with the comments telling the error on top of the front
+// matter, replacing the ones of the previous error if any
+func withError(data []byte, err error) []byte {
+	lines := strings.Split(string(data), "\n")
+	var res []string
+	if len(lines) > 0 && lines[0]+"\n" == frontMatter {
+		res, lines = append(res, lines[0]), lines[1:]
+	}
+	for _, line := range strings.Split(err.Error(), "\n") {
+		res = append(res, errorComment+line)
+	}
+	for len(lines) > 0 && strings.HasPrefix(lines[0], errorComment) {
+		lines = lines[1:]
+	}
+	return []byte(strings.Join(append(res, lines...), "\n"))
+}
+
+// printDiff writes the lines removed from the old text, with a leading -, and the ones added to
+// the new, with a leading +, in the order of the texts
+func printDiff(w io.Writer, old, new string) error {
+	a, b := strings.Split(strings.TrimSuffix(old, "\n"), "\n"), strings.Split(strings.TrimSuffix(new, "\n"), "\n")
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	var buf bytes.Buffer
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			i, j = i+1, j+1
+		case j == len(b) || i < len(a) && lcs[i+1][j] >= lcs[i][j+1]:
+			fmt.Fprintf(&buf, "-%s\n", a[i])
+			i++
+		default:
+			fmt.Fprintf(&buf, "+%s\n", b[j])
+			j++
+		}
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
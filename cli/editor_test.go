@@ -0,0 +1,114 @@
+package cli
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gotestbootcamp/go-todo-app/ledger"
+	"github.com/gotestbootcamp/go-todo-app/store"
+)
+
+// setEditor sets the editor to the shell script, run with the path of the file to edit as $1
+func setEditor(t *testing.T, script string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "editor")
+	require.NoError(t, os.WriteFile(path, []byte("#!/bin/sh\n"+script+"\n"), 0o700))
+	t.Setenv("VISUAL", "")
+	t.Setenv("EDITOR", path)
+}
+
+func TestRunEditInEditor(t *testing.T) {
+	dir := t.TempDir()
+	code, _, _ := run(t, dir, "add", "walk the dog #home")
+	require.Equal(t, 0, code)
+
+	setEditor(t, `sed -i -e 's/^title: .*/title: walk the cat/' -e 's/^due: .*/due: 2026-12-01/' "$1"
+echo 'Twice a day' >> "$1"`)
+	code, out, _ := run(t, dir, "edit", "1")
+	require.Equal(t, 0, code)
+	assert.Equal(t, "-title: walk the dog\n+title: walk the cat\n-due: \"\"\n+due: 2026-12-01 00:00\n+Twice a day\n", out)
+	code, out, _ = run(t, dir, "show", "1")
+	require.Equal(t, 0, code)
+	assert.Contains(t, out, "Title:       walk the cat")
+	assert.Contains(t, out, "Description: Twice a day")
+
+	setEditor(t, "true")
+	code, out, _ = run(t, dir, "edit", "1")
+	require.Equal(t, 0, code)
+	assert.Equal(t, "Edit cancelled, no changes made.\n", out)
+
+	// the file is reopened with the error, until saved unchanged
+	setEditor(t, `grep -q '^# error: invalid priority "bogus"' "$1" || sed -i 's/^priority: .*/priority: bogus/' "$1"`)
+	code, _, errs := run(t, dir, "edit", "1")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, errs, "no valid changes were saved: invalid priority")
+	setEditor(t, `if grep -q '^# error' "$1"; then sed -i -e 's/^title: .*/title: walk the cat/' -e 's/^priority: .*/priority: high/' "$1"; else sed -i 's/^title: .*/title: " "/' "$1"; fi`)
+	code, out, _ = run(t, dir, "edit", "1")
+	require.Equal(t, 0, code)
+	assert.Equal(t, "-priority: none\n+priority: P1\n", out)
+}
+
+func TestRunEditInEditorBolt(t *testing.T) {
+	// bolt locks the file of the datastore, opened once by the process
+	uri := "bolt://" + filepath.Join(t.TempDir(), "todo.bolt")
+	dir := t.TempDir()
+	code, _, _ := run(t, dir, "add", "--store", uri, "walk the dog")
+	require.Equal(t, 0, code)
+
+	setEditor(t, `sed -i 's/^title: .*/title: walk the cat/' "$1"`)
+	code, out, errs := run(t, dir, "edit", "--store", uri, "1")
+	require.Equal(t, 0, code, errs)
+	assert.Equal(t, "-title: walk the dog\n+title: walk the cat\n", out)
+}
+
+func TestEditInEditorConflict(t *testing.T) {
+	st, err := store.NewMemory()
+	require.NoError(t, err)
+	ld, err := ledger.New(st)
+	require.NoError(t, err)
+	ids := store.NewSequentialIDs(st)
+	var out bytes.Buffer
+	app := &App{Ledger: ld, IDs: ids, Out: &out, Format: FormatText}
+	_, err = app.add(store.DefaultList, "walk the dog", ledger.Patch{})
+	require.NoError(t, err)
+	// another process changed the todo, unbeknownst to the ledger
+	other, err := ledger.New(st)
+	require.NoError(t, err)
+	title := "walk the cat"
+	_, _, err = other.PatchIf("1", ledger.Patch{Title: &title}, ledger.AnyRevision)
+	require.NoError(t, err)
+
+	setEditor(t, `sed -i 's/^title: .*/title: walk the bird/' "$1"`)
+	err = app.editInEditor("1")
+	assert.ErrorAs(t, err, &store.ErrConflict{})
+	kept := regexp.MustCompile(`kept in (\S+)$`).FindStringSubmatch(err.Error())
+	require.Len(t, kept, 2, err.Error())
+	path := kept[1]
+	defer os.Remove(path)
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "title: walk the bird")
+	todo, err := ld.Get("1")
+	require.NoError(t, err)
+	assert.Equal(t, "walk the cat", todo.Title)
+}
+
+func TestUnmarshalEditable(t *testing.T) {
+	ed, err := unmarshalEditable([]byte("---\n# a comment\ntitle: call mom\ntags: [family]\n---\n\nOn sunday\n"))
+	require.NoError(t, err)
+	assert.Equal(t, editable{Title: "call mom", Tags: []string{"family"}, Description: "On sunday"}, ed)
+	ed, err = unmarshalEditable([]byte("---\ntitle: call mom\n---"))
+	require.NoError(t, err)
+	assert.Equal(t, "call mom", ed.Title)
+
+	for _, data := range []string{"title: call mom\n", "---\ntitle: call mom\n", "---\ntitel: call mom\n---\n"} {
+		_, err := unmarshalEditable([]byte(data))
+		assert.Error(t, err, data)
+	}
+}
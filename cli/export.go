@@ -0,0 +1,425 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/gotestbootcamp/go-todo-app/ical"
+	"github.com/gotestbootcamp/go-todo-app/ledger"
+	"github.com/gotestbootcamp/go-todo-app/store"
+	"github.com/gotestbootcamp/go-todo-app/task"
+	"github.com/gotestbootcamp/go-todo-app/todotxt"
+)
+
+// The formats of the files todo export writes and todo import reads
+const (
+	// FormatTodoTxt is the format of the todo.txt files, see todotxt
+	FormatTodoTxt = "todotxt"
+	// FormatJSONLines is a todo per line, see jsonRecord
+	FormatJSONLines = "jsonl"
+	// FormatCSV is a todo per row, after a header, see csvFields
+	FormatCSV = "csv"
+	// FormatMarkdown is a GitHub-style markdown checklist, see writeMarkdown
+	FormatMarkdown = "markdown"
+	// FormatICS is an iCalendar file of the todos with a due date, only exported, see ical
+	FormatICS = "ics"
+)
+
+// exchangeFormats are the formats of the files todo export writes and todo import reads
+var exchangeFormats = []string{FormatTodoTxt, FormatJSONLines, FormatCSV, FormatMarkdown}
+
+// exportFormats are the formats of the files todo export writes
+var exportFormats = append(slices.Clone(exchangeFormats), FormatICS)
+
+// The IDs todo import gives the todos
+const (
+	// importNewIDs adds the todos with new IDs, the blockers among them following them
+	importNewIDs = "new"
+	// importKeepIDs keeps the IDs of the file, replacing the todos with the same IDs
+	importKeepIDs = "keep"
+)
+
+// jsonRecord is a todo in the json lines files of todo export and todo import, one per line: its
+// ID, and all the fields of its task, as the task package encodes them, e.g.
+//
+//	{"id":"work/1","task":{"schema":14,"title":"Pay rent","status":"pending","tags":["finance"],...}}
+//
+// The tasks encoded with older schemas are migrated on import, see task.Unmarshal.
+type jsonRecord struct {
+	ID   store.ID        `json:"id"`
+	Task json.RawMessage `json:"task"`
+}
+
+// importRecord is a todo read from a file by todo import
+type importRecord struct {
+	// label tells where the todo is in the file, like "line 3"
+	label string
+	// id is the ID of the todo in the file, empty if none
+	id   store.ID
+	task task.Task
+	// err tells why the todo can't be imported
+	err error
+}
+
+// importPlan is what todo import would do with a todo of the file, as printed by --dry-run
+type importPlan struct {
+	Source string `json:"source"`
+	// Action is "add", "update", or empty if the todo can't be imported
+	Action string   `json:"action,omitempty"`
+	ID     store.ID `json:"id,omitempty"`
+	Title  string   `json:"title,omitempty"`
+	Error  string   `json:"error,omitempty"`
+}
+
+// checkExchangeFormat fails if the format isn't one of the formats, or if the flags of the other
+// formats are set
+func checkExchangeFormat(flags *flag.FlagSet, format string, formats []string) error {
+	if !slices.Contains(formats, format) {
+		return fmt.Errorf("invalid format %q, want one of %s", format, strings.Join(formats, ", "))
+	}
+	if isSet(flags, "columns") && format != FormatCSV {
+		return errors.New("--columns only applies to the csv format")
+	}
+	if isSet(flags, "group-by") && format != FormatMarkdown {
+		return errors.New("--group-by only applies to the markdown format")
+	}
+	if isSet(flags, "component") && format != FormatICS {
+		return errors.New("--component only applies to the ics format")
+	}
+	return nil
+}
+
+// checkGroupBy fails if the todos of the markdown checklists can't be grouped that way
+func checkGroupBy(groupBy string) error {
+	if groupBy != "" && !slices.Contains(markdownGroups, groupBy) {
+		return fmt.Errorf("invalid group %q, want one of %s", groupBy, strings.Join(markdownGroups, ", "))
+	}
+	return nil
+}
+
+var exportCommand = Command{
+	Name: "export",
+	Help: "write the todos to the standard output as todo.txt, json lines, csv, a markdown checklist or an iCalendar file, for other apps or todo import",
+	Setup: func(flags *flag.FlagSet) func(*App, []string) error {
+		format := flags.String("format", FormatTodoTxt, "format of the todos: "+strings.Join(exportFormats, ", "))
+		list := flags.String("list", "", "export only the todos of the list, empty for all (default: the one of the profile, or all)")
+		columns := flags.String("columns", "", "comma-separated fields of the csv columns, each optionally followed by = and the header, e.g. title=Name,due (default: all the fields)")
+		groupBy := flags.String("group-by", "", "sections of the markdown checklist: tag or status (default: none)")
+		component := flags.String("component", string(ical.Event), "what the todos of the ics calendar are: event, which all the calendar apps show, or todo")
+		return func(app *App, args []string) error {
+			if len(args) > 0 {
+				return errUsage
+			}
+			if err := checkExchangeFormat(flags, *format, exportFormats); err != nil {
+				return err
+			}
+			if err := checkGroupBy(*groupBy); err != nil {
+				return err
+			}
+			if !slices.Contains(ical.Components, ical.Component(*component)) {
+				return fmt.Errorf("invalid component %q, want %s or %s", *component, ical.Event, ical.ToDo)
+			}
+			cols, err := parseColumns(*columns)
+			if err != nil {
+				return err
+			}
+			if !isSet(flags, "list") {
+				*list = app.Config.List
+			}
+			items, _, err := app.Ledger.List(ledger.Query{})
+			if err != nil {
+				return err
+			}
+			if *list != "" {
+				items = inList(items, *list)
+			}
+			switch *format {
+			case FormatJSONLines:
+				return writeJSONLines(app.Out, items)
+			case FormatCSV:
+				return writeCSV(app.Out, items, cols)
+			case FormatMarkdown:
+				return writeMarkdown(app.Out, items, *groupBy, app.Ledger.Workflow().Statuses())
+			case FormatICS:
+				return writeICS(app.Out, items, ical.Component(*component))
+			}
+			// the format has no room for the deleted todos
+			codec := todotxt.New()
+			for _, item := range items {
+				if item.Task.Status == task.Deleted {
+					continue
+				}
+				if _, err := fmt.Fprintln(app.Out, codec.Format(*item.Task)); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	},
+}
+
+var importCommand = Command{
+	Name: "import",
+	Args: "<file>|-",
+	Help: "add the todos of a file, or of the standard input, as todo.txt, json lines, csv or a markdown checklist",
+	Setup: func(flags *flag.FlagSet) func(*App, []string) error {
+		format := flags.String("format", FormatTodoTxt, "format of the todos: "+strings.Join(exchangeFormats, ", "))
+		list := flags.String("list", store.DefaultList, "list to add the todos to, with new IDs (default: the one of the profile, or none)")
+		columns := flags.String("columns", "", "comma-separated fields of the csv columns to read, each optionally followed by = and the header, e.g. title=Name,due=Deadline (default: the columns named after the fields)")
+		groupBy := flags.String("group-by", "", "what the headings of the markdown sections are: the tag or the status of their todos (default: nothing)")
+		ids := flags.String("ids", importNewIDs, "IDs of the todos: new, or keep the ones of the file, replacing the todos with the same IDs")
+		dryRun := flags.Bool("dry-run", false, "print what would be added or updated, without changing anything")
+		return func(app *App, args []string) error {
+			if len(args) != 1 {
+				return errUsage
+			}
+			if err := checkExchangeFormat(flags, *format, exchangeFormats); err != nil {
+				return err
+			}
+			if err := checkGroupBy(*groupBy); err != nil {
+				return err
+			}
+			cols, err := parseColumns(*columns)
+			if err != nil {
+				return err
+			}
+			switch {
+			case *ids != importNewIDs && *ids != importKeepIDs:
+				return fmt.Errorf("invalid ids %q, want %s or %s", *ids, importNewIDs, importKeepIDs)
+			case *ids == importKeepIDs && isSet(flags, "list"):
+				return errors.New("--list gives new IDs to the todos, it can't be used with --ids keep")
+			case *ids == importKeepIDs && (*format == FormatTodoTxt || *format == FormatMarkdown):
+				return fmt.Errorf("the %s files have no IDs to keep", *format)
+			}
+			if !isSet(flags, "list") {
+				*list = app.Config.List
+			}
+			if *list != store.DefaultList {
+				if err := store.ValidateList(*list); err != nil {
+					return err
+				}
+			}
+
+			var records []importRecord
+			if *format == FormatCSV {
+				r, err := app.openInput(args[0])
+				if err != nil {
+					return err
+				}
+				defer r.Close()
+				records, err = readCSV(r, cols, isSet(flags, "columns"))
+				if err != nil {
+					return err
+				}
+			} else {
+				lines, err := app.readLines(args[0])
+				if err != nil {
+					return err
+				}
+				if *format == FormatMarkdown {
+					records = readMarkdown(lines, *groupBy)
+				} else {
+					records = readRecords(lines, *format)
+				}
+			}
+			if *ids == importKeepIDs {
+				for i := range records {
+					if records[i].err == nil && records[i].id == store.NullID {
+						records[i].err = errors.New("no id to keep")
+					}
+				}
+			}
+			if *dryRun {
+				return app.printImportPlan(records, *ids)
+			}
+			if *ids == importKeepIDs {
+				return app.importKeepingIDs(records)
+			}
+			return app.importWithNewIDs(*list, records)
+		}
+	},
+}
+
+// openInput opens the file, the standard input if "-"
+func (app *App) openInput(path string) (io.ReadCloser, error) {
+	if path == stdinArg {
+		return io.NopCloser(app.In), nil
+	}
+	return os.Open(path)
+}
+
+// writeICS writes the todos with a due date as an iCalendar file, of the components of the kind
+func writeICS(w io.Writer, items ledger.Items, component ical.Component) error {
+	todos := make([]ical.Todo, 0, len(items))
+	for _, item := range items {
+		todos = append(todos, ical.Todo{ID: string(item.ID), Task: *item.Task})
+	}
+	return ical.New("todo", component).Encode(w, todos)
+}
+
+// writeJSONLines writes the todos as json lines, see jsonRecord
+func writeJSONLines(w io.Writer, items ledger.Items) error {
+	enc := json.NewEncoder(w)
+	for _, item := range items {
+		data, err := task.Marshal(*item.Task)
+		if err != nil {
+			return err
+		}
+		if err := enc.Encode(jsonRecord{ID: item.ID, Task: bytes.TrimSpace(data)}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readRecords parses the todos of the lines not blank of a file in the todo.txt or json lines format
+func readRecords(lines []string, format string) []importRecord {
+	codec := todotxt.New()
+	var records []importRecord
+	for i, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		rec := importRecord{label: fmt.Sprintf("line %d", i+1)}
+		if format == FormatTodoTxt {
+			rec.task, rec.err = codec.Parse(line)
+		} else {
+			rec.id, rec.task, rec.err = parseJSONRecord(line)
+		}
+		records = append(records, rec)
+	}
+	return records
+}
+
+// parseJSONRecord parses the ID and the task of a todo of a json lines file, see jsonRecord
+func parseJSONRecord(line string) (store.ID, task.Task, error) {
+	var rec jsonRecord
+	dec := json.NewDecoder(strings.NewReader(line))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&rec); err != nil {
+		return store.NullID, task.Task{}, fmt.Errorf("invalid json: %w", err)
+	}
+	if len(rec.Task) == 0 {
+		return rec.ID, task.Task{}, errors.New("no task")
+	}
+	tk, err := task.Unmarshal(rec.Task)
+	return rec.ID, tk, err
+}
+
+// printImportPlan writes what importing the todos would do: adding them, or updating the ones
+// with the same IDs if they are kept. Fails if any todo can't be imported.
+func (app *App) printImportPlan(records []importRecord, ids string) error {
+	plans := make([]importPlan, 0, len(records))
+	failed := 0
+	for _, rec := range records {
+		plan := importPlan{Source: rec.label, Action: "add", Title: rec.task.Title}
+		if ids == importKeepIDs {
+			plan.ID = rec.id
+			if _, _, err := app.Ledger.GetItem(rec.id); err == nil {
+				plan.Action = "update"
+			}
+		}
+		if rec.err != nil {
+			plan = importPlan{Source: rec.label, Error: rec.err.Error()}
+			failed++
+		}
+		plans = append(plans, plan)
+	}
+
+	switch app.Format {
+	case FormatJSON:
+		if err := printJSON(app.Out, plans); err != nil {
+			return err
+		}
+	case FormatYAML:
+		if err := printYAML(app.Out, plans); err != nil {
+			return err
+		}
+	default:
+		for _, plan := range plans {
+			result := "error: " + plan.Error
+			if plan.Error == "" {
+				result = strings.TrimSpace(fmt.Sprintf("would %s %s", plan.Action, plan.ID)) + fmt.Sprintf(" %q", plan.Title)
+			}
+			if _, err := fmt.Fprintf(app.Out, "%s: %s\n", plan.Source, result); err != nil {
+				return err
+			}
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("can't import %d of %d todos", failed, len(records))
+	}
+	return nil
+}
+
+// importKeepingIDs imports the todos with their IDs, at once, replacing the ones with the same IDs,
+// and prints the result of each
+func (app *App) importKeepingIDs(records []importRecord) error {
+	var items ledger.Items
+	// the index of the result of each item
+	var results []int
+	report := make(ledger.BulkReport, len(records))
+	done := make([]string, len(records))
+	for i, rec := range records {
+		report[i] = ledger.BulkResult{ID: rec.id, Err: rec.err}
+		if rec.err != nil {
+			continue
+		}
+		done[i] = "added"
+		if _, _, err := app.Ledger.GetItem(rec.id); err == nil {
+			done[i] = "updated"
+		}
+		items = append(items, ledger.Item{ID: rec.id, Task: &records[i].task})
+		results = append(results, i)
+	}
+	imported, err := app.Ledger.ImportAll(items)
+	if err != nil {
+		return err
+	}
+	for i, res := range imported {
+		report[results[i]] = res
+	}
+	return app.printReportFunc(report, labelsOf(records), func(i int) string { return done[i] })
+}
+
+// importWithNewIDs adds the todos in the list with new IDs, at once, and prints the result of each.
+// The todos blocked by, or linked to, others of the file are blocked by, or linked to, their new
+// IDs, see ledger.Ledger.ImportAllNew.
+func (app *App) importWithNewIDs(list string, records []importRecord) error {
+	var items ledger.Items
+	// the index of the result of each item
+	var results []int
+	report := make(ledger.BulkReport, len(records))
+	for i, rec := range records {
+		report[i] = ledger.BulkResult{ID: store.NullID, Err: rec.err}
+		if rec.err != nil {
+			continue
+		}
+		items = append(items, ledger.Item{ID: rec.id, Task: &records[i].task})
+		results = append(results, i)
+	}
+	created, err := app.Ledger.ImportAllNew(list, items)
+	if err != nil {
+		return err
+	}
+	for i, res := range created {
+		report[results[i]] = res
+	}
+	return app.printReport(report, labelsOf(records), "added")
+}
+
+// labelsOf returns the labels of the records
+func labelsOf(records []importRecord) []string {
+	labels := make([]string, 0, len(records))
+	for _, rec := range records {
+		labels = append(labels, rec.label)
+	}
+	return labels
+}
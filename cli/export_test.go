@@ -0,0 +1,199 @@
+package cli
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunExportImport(t *testing.T) {
+	dir := t.TempDir()
+	input := "(A) 2026-10-01 Pay rent +finance @home due:2026-11-01\n\nx 2026-10-05 2026-10-01 file taxes pri:B\n(B) +finance\n"
+
+	code, out, errs := runInput(t, dir, input, "import", "--dry-run", "-")
+	assert.Equal(t, 1, code)
+	assert.Equal(t, "line 1: would add \"Pay rent\"\nline 3: would add \"file taxes\"\nline 4: error: invalid task title: must not be blank\n", out)
+	assert.Contains(t, errs, "can't import 1 of 3 todos")
+	code, out, errs = runInput(t, dir, input, "import", "--list", "home", "-")
+	assert.Equal(t, 1, code)
+	assert.Equal(t, "line 1: added home/1\nline 3: added home/2\nline 4: error: invalid task title: must not be blank\n", out)
+	assert.Contains(t, errs, "failed on 1 of 3 todos")
+	code, _, _ = run(t, dir, "add", "walk the dog !p3 @park")
+	require.Equal(t, 0, code)
+
+	code, out, _ = run(t, dir, "export", "--list", "home")
+	require.Equal(t, 0, code)
+	assert.Equal(t, `(A) 2026-10-01 Pay rent +finance @home due:2026-11-01
+x 2026-10-05 2026-10-01 file taxes pri:B
+`, out)
+	code, out, _ = run(t, dir, "export")
+	require.Equal(t, 0, code)
+	assert.Regexp(t, `^\(D\) \d{4}-\d\d-\d\d walk the dog @park\n\(A\) `, out)
+
+	code, _, errs = run(t, dir, "export", "--format", "todo.txt")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, errs, `invalid format "todo.txt"`)
+	code, _, _ = run(t, dir, "import")
+	assert.Equal(t, 2, code)
+}
+
+func TestRunExportImportJSONLines(t *testing.T) {
+	from, to := t.TempDir(), t.TempDir()
+	for _, title := range []string{"pay rent #finance", "file taxes"} {
+		code, _, _ := run(t, from, "add", title)
+		require.Equal(t, 0, code)
+	}
+	code, out, _ := run(t, from, "export", "--format", "jsonl")
+	require.Equal(t, 0, code)
+	lines := strings.Split(strings.TrimSuffix(out, "\n"), "\n")
+	require.Len(t, lines, 2)
+	var rec struct {
+		ID   string `json:"id"`
+		Task struct {
+			Title string   `json:"title"`
+			Tags  []string `json:"tags"`
+		} `json:"task"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &rec))
+	assert.Equal(t, "1", rec.ID)
+	assert.Equal(t, "pay rent", rec.Task.Title)
+	assert.Equal(t, []string{"finance"}, rec.Task.Tags)
+
+	code, out, _ = runInput(t, to, lines[0]+"\n", "import", "--format", "jsonl", "--ids", "keep", "-")
+	require.Equal(t, 0, code)
+	assert.Equal(t, "line 1: added 1\n", out)
+	code, _, _ = run(t, from, "edit", "--title", "pay the rent", "1")
+	require.Equal(t, 0, code)
+	code, export, _ := run(t, from, "export", "--format", "jsonl")
+	require.Equal(t, 0, code)
+	code, out, _ = runInput(t, to, export, "import", "--format", "jsonl", "--ids", "keep", "--dry-run", "-")
+	require.Equal(t, 0, code)
+	assert.Equal(t, "line 1: would update 1 \"pay the rent\"\nline 2: would add 2 \"file taxes\"\n", out)
+	code, out, _ = runInput(t, to, export+"{\"id\": \"3\"}\n", "import", "--format", "jsonl", "--ids", "keep", "-")
+	assert.Equal(t, 1, code)
+	assert.Equal(t, "line 1: updated 1\nline 2: added 2\nline 3: error: no task\n", out)
+	code, out, _ = run(t, to, "show", "1")
+	require.Equal(t, 0, code)
+	assert.Contains(t, out, "pay the rent")
+
+	// the new IDs of the blockers and of the todos linked follow them
+	input := `{"id":"7","task":{"schema":8,"title":"buy a cake","status":"pending"}}
+{"id":"8","task":{"schema":13,"title":"bake a cake","status":"pending","blocked_by":["7","9"],"links":[{"type":"duplicates","id":"7"},{"type":"relates-to","id":"9"},{"type":"relates-to","url":"https://example.com/cakes"}]}}
+`
+	code, out, errs := runInput(t, to, input, "import", "--format", "jsonl", "--list", "party", "-")
+	require.Equal(t, 0, code, out+errs)
+	assert.Equal(t, "line 1: added party/3\nline 2: added party/4\n", out)
+	code, out, _ = run(t, to, "export", "--format", "jsonl", "--list", "party")
+	require.Equal(t, 0, code)
+	assert.Contains(t, out, `"title":"bake a cake",`)
+	assert.Contains(t, out, `"blocked_by":["party/3"]`)
+	assert.Contains(t, out, `"links":[{"type":"duplicates","id":"party/3"},{"type":"relates-to","url":"https://example.com/cakes"}]`)
+
+	code, _, errs = run(t, to, "import", "--ids", "keep", "-")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, errs, "no IDs to keep")
+}
+
+func TestRunExportImportCSV(t *testing.T) {
+	dir := t.TempDir()
+	code, _, _ := run(t, dir, "add", "pay rent #finance #home !p1")
+	require.Equal(t, 0, code)
+	code, out, _ := run(t, dir, "export", "--format", "csv", "--columns", "id,title,tags=Labels,priority")
+	require.Equal(t, 0, code)
+	assert.Equal(t, "id,title,Labels,priority\n1,pay rent,\"finance,home\",P1\n", out)
+	code, out, _ = run(t, dir, "export", "--format", "csv")
+	require.Equal(t, 0, code)
+	assert.True(t, strings.HasPrefix(out, "id,title,description,status,assignee,priority,tags,due,remind,recur,blocked_by,created,updated\n1,pay rent,,pending,"), out)
+
+	input := "Name,Deadline,Notes\ncall mom,2026-12-01,on sunday\n\"walk\nthe dog\",someday,\n"
+	code, _, errs := runInput(t, dir, input, "import", "--format", "csv", "-")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, errs, `unknown column "Name"`)
+	code, out, errs = runInput(t, dir, input, "import", "--format", "csv", "--columns", "title=Name,due=Deadline", "-")
+	assert.Equal(t, 1, code)
+	assert.Equal(t, "line 2: added 2\nline 3: error: due: invalid date \"someday\", try e.g. tomorrow 5pm, next friday, in 3 days or 2006-01-02\n", out)
+	assert.Contains(t, errs, "failed on 1 of 2 todos")
+	code, out, _ = run(t, dir, "show", "2")
+	require.Equal(t, 0, code)
+	assert.Contains(t, out, "2026-12-01")
+
+	code, _, errs = run(t, dir, "export", "--columns", "title")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, errs, "--columns only applies to the csv format")
+	code, _, errs = run(t, dir, "export", "--format", "csv", "--columns", "title,owner")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, errs, `unknown field "owner"`)
+}
+
+func TestRunExportImportMarkdown(t *testing.T) {
+	dir := t.TempDir()
+	input := `# Release
+
+Some text.
+
+- [ ] write the notes
+  - [x] collect the PRs
+  - [ ] thank the contributors
+* [X] tag the release
+- plain item
+`
+	code, out, _ := runInput(t, dir, input, "import", "--format", "markdown", "-")
+	require.Equal(t, 0, code)
+	assert.Equal(t, "line 5: added 1\nline 8: added 2\nline 9: added 3\n", out)
+	code, out, _ = run(t, dir, "export", "--format", "markdown")
+	require.Equal(t, 0, code)
+	assert.Equal(t, "- [ ] write the notes\n  - [x] collect the PRs\n  - [ ] thank the contributors\n- [x] tag the release\n- [ ] plain item\n", out)
+	code, out, _ = run(t, dir, "export", "--format", "markdown", "--group-by", "status")
+	require.Equal(t, 0, code)
+	assert.Equal(t, "## pending\n\n- [ ] write the notes\n  - [x] collect the PRs\n  - [ ] thank the contributors\n- [ ] plain item\n\n## completed\n\n- [x] tag the release\n", out)
+
+	// the todos with many tags are in many sections, and imported once
+	from, to := t.TempDir(), t.TempDir()
+	for _, title := range []string{"pay rent #finance #home", "walk the dog"} {
+		code, _, _ := run(t, from, "add", title)
+		require.Equal(t, 0, code)
+	}
+	code, export, _ := run(t, from, "export", "--format", "markdown", "--group-by", "tag")
+	require.Equal(t, 0, code)
+	assert.Equal(t, "- [ ] walk the dog\n\n## finance\n\n- [ ] pay rent\n\n## home\n\n- [ ] pay rent\n", export)
+	code, out, _ = runInput(t, to, export, "import", "--format", "markdown", "--group-by", "tag", "-")
+	require.Equal(t, 0, code)
+	assert.Equal(t, "line 1: added 1\nline 5: added 2\n", out)
+	code, out, _ = run(t, to, "export", "--format", "markdown", "--group-by", "tag")
+	require.Equal(t, 0, code)
+	assert.Equal(t, export, out)
+
+	code, _, errs := run(t, dir, "export", "--format", "csv", "--group-by", "tag")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, errs, "--group-by only applies to the markdown format")
+	code, _, errs = run(t, dir, "export", "--format", "markdown", "--group-by", "owner")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, errs, `invalid group "owner"`)
+}
+
+func TestRunExportICS(t *testing.T) {
+	dir := t.TempDir()
+	code, _, _ := run(t, dir, "add", "--due", "2026-11-01", "pay rent #finance")
+	require.Equal(t, 0, code)
+	code, _, _ = run(t, dir, "add", "walk the dog")
+	require.Equal(t, 0, code)
+
+	code, out, _ := run(t, dir, "export", "--format", "ics")
+	require.Equal(t, 0, code)
+	assert.True(t, strings.HasPrefix(out, "BEGIN:VCALENDAR\r\n"), out)
+	assert.Equal(t, 1, strings.Count(out, "BEGIN:VEVENT\r\n"))
+	assert.Contains(t, out, "DTSTART;VALUE=DATE:20261101\r\nDTEND;VALUE=DATE:20261102\r\nSUMMARY:pay rent\r\nCATEGORIES:finance\r\n")
+	code, out, _ = run(t, dir, "export", "--format", "ics", "--component", "todo")
+	require.Equal(t, 0, code)
+	assert.Contains(t, out, "DUE;VALUE=DATE:20261101\r\n")
+
+	code, _, errs := run(t, dir, "export", "--component", "todo")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, errs, "--component only applies to the ics format")
+	code, _, errs = run(t, dir, "import", "--format", "ics", "-")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, errs, `invalid format "ics"`)
+}
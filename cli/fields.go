@@ -0,0 +1,132 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/gotestbootcamp/go-todo-app/ledger"
+	"github.com/gotestbootcamp/go-todo-app/store"
+)
+
+// fieldSubcommands are the subcommands of todo field
+var fieldSubcommands = []string{"list", "define", "rm"}
+
+// fieldValues are the values of the custom fields given by the repeated --field name=value flags
+type fieldValues map[string]string
+
+func (fv *fieldValues) String() string {
+	if fv == nil {
+		return ""
+	}
+	return formatFields(*fv)
+}
+
+func (fv *fieldValues) Set(s string) error {
+	name, value, ok := strings.Cut(s, "=")
+	if !ok || name == "" {
+		return fmt.Errorf("invalid field %q, want name=value", s)
+	}
+	if *fv == nil {
+		*fv = make(fieldValues)
+	}
+	(*fv)[strings.TrimSpace(name)] = value
+	return nil
+}
+
+var fieldCommand = Command{
+	Name: "field",
+	Args: "[list | define <name> <type> [<value>...] | rm <name>]",
+	Help: "list the custom fields of the todos of a list, or define and remove them: string, number, enum of the values given, date or url",
+	Setup: func(flags *flag.FlagSet) func(*App, []string) error {
+		var list string
+		flags.StringVar(&list, "list", "", "list of the fields (default: the one of the profile)")
+		return func(app *App, args []string) error {
+			if !isSet(flags, "list") {
+				list = app.Config.List
+			}
+			sub := "list"
+			if len(args) > 0 {
+				sub, args = args[0], args[1:]
+			}
+			switch {
+			case sub == "list" && len(args) == 0:
+				defs, err := app.Ledger.Fields(list)
+				if err != nil {
+					return err
+				}
+				return app.printFields(defs)
+			case sub == "define" && len(args) >= 2:
+				typ, err := ledger.ParseFieldType(args[1])
+				if err != nil {
+					return err
+				}
+				def, err := app.Ledger.DefineField(list, ledger.FieldDef{Name: args[0], Type: typ, Values: args[2:]})
+				if err != nil {
+					return err
+				}
+				return app.printFields([]ledger.FieldDef{def})
+			case sub == "rm" && len(args) == 1:
+				return app.Ledger.DeleteField(list, args[0])
+			}
+			return errUsage
+		}
+	},
+}
+
+// checkFields fails if the list doesn't define the custom fields, or if the values aren't valid
+// for them, not to add todos without their fields
+func checkFields(ld *ledger.Ledger, list string, values map[string]string) error {
+	var defs []ledger.FieldDef
+	if list != store.DefaultList {
+		var err error
+		if defs, err = ld.Fields(list); err != nil {
+			return err
+		}
+	}
+	for name, value := range values {
+		i := slices.IndexFunc(defs, func(def ledger.FieldDef) bool {
+			return def.Name == name
+		})
+		if i < 0 {
+			return ledger.ErrUnknownField{List: list, Name: name}
+		}
+		if _, err := defs[i].Normalize(value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// printFields writes the definitions of the custom fields
+func (app *App) printFields(defs []ledger.FieldDef) error {
+	switch app.Format {
+	case FormatJSON:
+		return printJSON(app.Out, defs)
+	case FormatYAML:
+		return printYAML(app.Out, defs)
+	}
+	tw := tabwriter.NewWriter(app.Out, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "NAME\tTYPE\tVALUES\n")
+	for _, def := range defs {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", def.Name, def.Type, strings.Join(def.Values, ","))
+	}
+	return tw.Flush()
+}
+
+// fieldNames returns the names of the custom fields of all the lists, sorted, without duplicates
+func fieldNames(ld *ledger.Ledger) []string {
+	lists, _ := ld.Lists()
+	var names []string
+	for _, list := range lists {
+		defs, _ := ld.Fields(list)
+		for _, def := range defs {
+			names = append(names, def.Name)
+		}
+	}
+	sort.Strings(names)
+	return slices.Compact(names)
+}
@@ -0,0 +1,54 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunFields(t *testing.T) {
+	dir := t.TempDir()
+	code, out, _ := run(t, dir, "field", "--list", "work", "define", "severity", "enum", "low", "medium", "high")
+	require.Equal(t, 0, code)
+	assert.Regexp(t, `\nseverity +enum +low,medium,high\n`, out)
+	code, _, _ = run(t, dir, "field", "--list", "work", "define", "estimate", "number")
+	require.Equal(t, 0, code)
+	code, _, errs := run(t, dir, "field", "--list", "work", "define", "size", "color")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, errs, "invalid field type")
+	code, out, _ = run(t, dir, "field", "--list", "work")
+	require.Equal(t, 0, code)
+	assert.Regexp(t, `NAME +TYPE +VALUES *\nestimate +number *\nseverity +enum`, out)
+
+	code, out, _ = run(t, dir, "add", "--list", "work", "--field", "severity=high", "--field", "estimate=2.50", "fix", "login")
+	require.Equal(t, 0, code)
+	assert.Contains(t, out, "ID:       work/1\n")
+	assert.Contains(t, out, "Fields:   estimate=2.5, severity=high\n")
+	code, _, _ = run(t, dir, "add", "--list", "work", "--field", "estimate=10", "write", "docs")
+	require.Equal(t, 0, code)
+	// the todos aren't added without their fields
+	code, _, errs = run(t, dir, "add", "--list", "work", "--field", "severity=urgent", "deploy")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, errs, "want one of low, medium, high")
+	code, _, errs = run(t, dir, "add", "--field", "severity=low", "outside")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, errs, `has no field "severity"`)
+
+	code, out, _ = run(t, dir, "list", "--field", "severity=high")
+	require.Equal(t, 0, code)
+	assert.Contains(t, out, "fix login")
+	assert.NotContains(t, out, "write docs")
+	code, out, _ = run(t, dir, "list", "--sort", "-field.estimate", "--output", "tsv")
+	require.Equal(t, 0, code)
+	assert.Regexp(t, `write docs(.|\n)*fix login`, out)
+
+	code, out, _ = run(t, dir, "edit", "--field", "severity=", "work/1")
+	require.Equal(t, 0, code)
+	assert.Contains(t, out, "Fields:   estimate=2.5\n")
+
+	code, _, _ = run(t, dir, "field", "--list", "work", "rm", "severity")
+	require.Equal(t, 0, code)
+	code, _, _ = run(t, dir, "field", "--list", "work", "rm", "severity")
+	assert.Equal(t, 1, code)
+}
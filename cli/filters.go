@@ -0,0 +1,78 @@
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/gotestbootcamp/go-todo-app/ledger"
+)
+
+// filterSubcommands are the subcommands of todo filter
+var filterSubcommands = []string{"list", "save", "rm"}
+
+var filterCommand = Command{
+	Name: "filter",
+	Args: "[list | save <name> <query>... | rm <name>]",
+	Help: "list the saved filters, the search queries `todo list --filter <name>` lists the todos of, or save and remove them",
+	Setup: func(flags *flag.FlagSet) func(*App, []string) error {
+		var shared bool
+		flags.BoolVar(&shared, "shared", false, "share the filter saved with the other users of the store")
+		return func(app *App, args []string) error {
+			sub := "list"
+			if len(args) > 0 {
+				sub, args = args[0], args[1:]
+			}
+			switch {
+			case sub == "list" && len(args) == 0:
+				return app.printFilters(app.Ledger.Filters())
+			case sub == "save" && len(args) >= 2:
+				f := ledger.SavedFilter{Name: args[0], Query: strings.Join(args[1:], " "), Shared: shared}
+				saved, err := app.Ledger.CreateFilter(f)
+				var exists ledger.ErrFilterExists
+				if errors.As(err, &exists) {
+					saved, err = app.Ledger.UpdateFilter(f.Name, f)
+				}
+				if err != nil {
+					return err
+				}
+				return app.printFilters([]ledger.SavedFilter{saved})
+			case sub == "rm" && len(args) == 1:
+				return app.Ledger.DeleteFilter(args[0])
+			}
+			return errUsage
+		}
+	},
+}
+
+// printFilters writes the saved filters
+func (app *App) printFilters(filters []ledger.SavedFilter) error {
+	switch app.Format {
+	case FormatJSON:
+		return printJSON(app.Out, filters)
+	case FormatYAML:
+		return printYAML(app.Out, filters)
+	}
+	tw := tabwriter.NewWriter(app.Out, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "NAME\tQUERY\tOWNER\n")
+	for _, f := range filters {
+		owner := f.Owner
+		if f.Shared {
+			owner += " (shared)"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", f.Name, f.Query, strings.TrimSpace(owner))
+	}
+	return tw.Flush()
+}
+
+// filterNames returns the names of the saved filters, sorted, without duplicates
+func filterNames(ld *ledger.Ledger) []string {
+	var names []string
+	for _, f := range ld.Filters() {
+		names = append(names, f.Name)
+	}
+	return slices.Compact(names)
+}
@@ -0,0 +1,86 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	apiv1 "github.com/gotestbootcamp/go-todo-app/api/v1"
+	"github.com/gotestbootcamp/go-todo-app/ledger"
+	"github.com/gotestbootcamp/go-todo-app/store"
+	"github.com/gotestbootcamp/go-todo-app/task"
+)
+
+var linkCommand = Command{
+	Name: "link",
+	Args: "<id> [relates-to | duplicates | caused-by <id> | <url>]",
+	Help: "list the links of a todo and the backlinks from the other todos, or link it to another todo or to a URL",
+	Setup: func(flags *flag.FlagSet) func(*App, []string) error {
+		rm := flags.Bool("rm", false, "remove the link instead")
+		return func(app *App, args []string) error {
+			switch {
+			case len(args) == 1 && !*rm:
+				links, err := app.Ledger.Links(store.ID(args[0]))
+				if err != nil {
+					return err
+				}
+				return app.printLinks(links)
+			case len(args) != 3:
+				return errUsage
+			}
+			typ, err := task.ParseLinkType(args[1])
+			if err != nil {
+				return err
+			}
+			l := task.Link{Type: typ, ID: args[2]}
+			if strings.Contains(args[2], "://") {
+				l = task.Link{Type: typ, URL: args[2]}
+			}
+			var item ledger.Item
+			if *rm {
+				item, err = app.Ledger.Unlink(store.ID(args[0]), l)
+			} else {
+				item, err = app.Ledger.Link(store.ID(args[0]), l)
+			}
+			if err != nil {
+				return err
+			}
+			return app.printItem(item)
+		}
+	},
+}
+
+// printLinks writes the links of a todo, and its backlinks
+func (app *App) printLinks(links []ledger.TodoLink) error {
+	switch app.Format {
+	case FormatJSON, FormatYAML:
+		res := make([]apiv1.Link, 0, len(links))
+		for _, l := range links {
+			res = append(res, l.ToAPIv1())
+		}
+		if app.Format == FormatJSON {
+			return printJSON(app.Out, res)
+		}
+		return printYAML(app.Out, res)
+	}
+	tw := tabwriter.NewWriter(app.Out, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "TYPE\tTO\tTITLE\n")
+	for _, l := range links {
+		to := l.URL
+		if l.ID != "" {
+			to = string(l.ID)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", l.Type, to, l.Title)
+	}
+	return tw.Flush()
+}
+
+// formatLinks returns the links of a todo as "type target", joined by commas
+func formatLinks(links []task.Link) string {
+	res := make([]string, 0, len(links))
+	for _, l := range links {
+		res = append(res, string(l.Type)+" "+l.Target())
+	}
+	return strings.Join(res, ", ")
+}
@@ -0,0 +1,35 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunLinks(t *testing.T) {
+	dir := t.TempDir()
+	for _, title := range []string{"login fails", "can't log in"} {
+		code, _, _ := run(t, dir, "add", title)
+		require.Equal(t, 0, code)
+	}
+
+	code, out, _ := run(t, dir, "link", "2", "duplicates", "1")
+	require.Equal(t, 0, code)
+	assert.Contains(t, out, "Links:    duplicates 1\n")
+	code, _, _ = run(t, dir, "link", "1", "caused-by", "https://example.com/issues/42")
+	require.Equal(t, 0, code)
+	code, _, errs := run(t, dir, "link", "1", "blocks", "2")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, errs, "invalid link type")
+
+	code, out, _ = run(t, dir, "link", "1")
+	require.Equal(t, 0, code)
+	assert.Regexp(t, `TYPE +TO +TITLE *\ncaused-by +https://example.com/issues/42 *\nduplicated-by +2 +can't log in\n`, out)
+
+	code, out, _ = run(t, dir, "link", "--rm", "2", "duplicates", "1")
+	require.Equal(t, 0, code)
+	assert.NotContains(t, out, "Links:")
+	code, _, _ = run(t, dir, "link", "--rm", "2", "duplicates", "1")
+	assert.Equal(t, 1, code)
+}
@@ -0,0 +1,170 @@
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/gotestbootcamp/go-todo-app/ledger"
+	"github.com/gotestbootcamp/go-todo-app/task"
+)
+
+// The sections of the markdown checklists
+const (
+	// groupByTag heads the sections with the tags
+	groupByTag = "tag"
+	// groupByStatus heads the sections with the statuses
+	groupByStatus = "status"
+)
+
+// markdownGroups are the ways of grouping the todos of the markdown checklists in sections
+var markdownGroups = []string{groupByTag, groupByStatus}
+
+// markdownHeading matches the headings of the sections of a markdown file, like "## finance"
+var markdownHeading = regexp.MustCompile(`^ {0,3}#{1,6}\s+(.*?)(?:\s+#+)?\s*$`)
+
+// markdownItem matches the items of a markdown list, with their indentation, and their checkbox
+// if any, like "  - [x] Pay rent"
+var markdownItem = regexp.MustCompile(`^([ \t]*)[-*+]\s+(?:\[([ xX])\]\s+)?(.*\S)\s*$`)
+
+// nestedIndent is the least indentation of the nested items of a markdown list
+const nestedIndent = 2
+
+// writeMarkdown writes the todos as a GitHub-style markdown checklist, like "- [ ] Pay rent", with
+// the completed todos checked, and their checklist entries as nested items. The deleted todos are
+// left out. Grouped by tag, the todos are in the sections headed by each of their tags, after the
+// ones without tags; grouped by status, in the sections of their statuses, in their order.
+func writeMarkdown(w io.Writer, items ledger.Items, groupBy string, statuses []task.Status) error {
+	items = slices.DeleteFunc(slices.Clone(items), func(item ledger.Item) bool {
+		return item.Task.Status == task.Deleted
+	})
+	var buf bytes.Buffer
+	writeItems := func(items ledger.Items) {
+		for _, item := range items {
+			writeMarkdownItem(&buf, "", item.Task.Status == task.Completed, item.Task.Title)
+			for _, ci := range item.Task.Checklist {
+				writeMarkdownItem(&buf, "  ", ci.Done, ci.Text)
+			}
+		}
+	}
+	writeSection := func(heading string, items ledger.Items) {
+		if len(items) == 0 {
+			return
+		}
+		if buf.Len() > 0 {
+			buf.WriteString("\n")
+		}
+		fmt.Fprintf(&buf, "## %s\n\n", heading)
+		writeItems(items)
+	}
+
+	switch groupBy {
+	case groupByTag:
+		var tags []string
+		for _, item := range items {
+			for _, tag := range item.Task.Tags {
+				if !slices.Contains(tags, tag) {
+					tags = append(tags, tag)
+				}
+			}
+		}
+		slices.Sort(tags)
+		writeItems(slices.DeleteFunc(slices.Clone(items), func(item ledger.Item) bool {
+			return len(item.Task.Tags) > 0
+		}))
+		for _, tag := range tags {
+			writeSection(tag, slices.DeleteFunc(slices.Clone(items), func(item ledger.Item) bool {
+				return !slices.Contains(item.Task.Tags, tag)
+			}))
+		}
+	case groupByStatus:
+		for _, status := range statuses {
+			writeSection(string(status), slices.DeleteFunc(slices.Clone(items), func(item ledger.Item) bool {
+				return item.Task.Status != status
+			}))
+		}
+	default:
+		writeItems(items)
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// writeMarkdownItem writes an item of a markdown checklist, with the indentation
+func writeMarkdownItem(buf *bytes.Buffer, indent string, done bool, text string) {
+	box := " "
+	if done {
+		box = "x"
+	}
+	fmt.Fprintf(buf, "%s- [%s] %s\n", indent, box, strings.Join(strings.Fields(text), " "))
+}
+
+// readMarkdown reads the todos of the items of the lists of a markdown file, pending unless
+// checked, and the items nested in them as their checklist entries. The other lines are ignored.
+// Grouped by tag, the headings of the sections are the tags of their todos, and the todos in many
+// sections are read once, as writeMarkdown writes them; grouped by status, the headings are the
+// statuses of the todos not checked.
+func readMarkdown(lines []string, groupBy string) []importRecord {
+	var records []importRecord
+	heading := ""
+	// the index of the todo of the items nested in the previous one, -1 if none
+	parent := -1
+	// seen are the indexes of the todos with each title, and whether they are checked
+	seen := make(map[string]int)
+	// skipNested tells to skip the items nested in a todo read already
+	skipNested := false
+	for i, line := range lines {
+		if m := markdownHeading.FindStringSubmatch(line); m != nil {
+			heading, parent, skipNested = m[1], -1, false
+			continue
+		}
+		m := markdownItem.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		indent := len(strings.ReplaceAll(m[1], "\t", "    "))
+		done, text := m[2] == "x" || m[2] == "X", m[3]
+		if indent >= nestedIndent && skipNested {
+			continue
+		}
+		if indent >= nestedIndent && parent >= 0 {
+			rec := &records[parent]
+			if rec.err == nil {
+				_, rec.err = rec.task.AddCheckItem(text)
+				if rec.err == nil {
+					rec.task.Checklist[len(rec.task.Checklist)-1].Done = done
+				}
+			}
+			continue
+		}
+
+		key := fmt.Sprintf("%t %s", done, text)
+		if j, ok := seen[key]; ok && groupBy == groupByTag && heading != "" && !slices.Contains(records[j].task.Tags, heading) {
+			records[j].task.Tags = append(records[j].task.Tags, heading)
+			parent, skipNested = -1, true
+			continue
+		}
+		rec := importRecord{label: fmt.Sprintf("line %d", i+1), task: task.New(text)}
+		switch {
+		case groupBy == groupByTag && heading != "":
+			rec.task.Tags = []string{heading}
+		case groupBy == groupByStatus && heading != "":
+			rec.task.Status = task.Status(heading)
+		}
+		if done {
+			rec.task.Status = task.Completed
+		}
+		records = append(records, rec)
+		parent, skipNested = len(records)-1, false
+		seen[key] = parent
+	}
+	for i := range records {
+		if records[i].err == nil {
+			records[i].err = records[i].task.Validate()
+		}
+	}
+	return records
+}
@@ -0,0 +1,218 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+	"time"
+
+	apiv1 "github.com/gotestbootcamp/go-todo-app/api/v1"
+	"github.com/gotestbootcamp/go-todo-app/ledger"
+	"gopkg.in/yaml.v3"
+)
+
+// The output formats
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+	FormatYAML = "yaml"
+	FormatTSV  = "tsv"
+	// FormatTemplate prints every todo with the Go template given by the --template flag
+	FormatTemplate = "template"
+)
+
+// formats are the output formats the commands accept
+var formats = []string{FormatText, FormatJSON, FormatYAML, FormatTSV, FormatTemplate}
+
+// tsvColumns are the columns of the tsv output, in its header
+var tsvColumns = []string{"id", "status", "title", "assignee", "priority", "due", "tags", "updated"}
+
+// templateFuncs are the functions the templates of the template output format can call, besides
+// the ones of text/template
+var templateFuncs = template.FuncMap{
+	// join joins the values of the list with the separator, e.g. {{join "," .todo.tags}}
+	"join": func(sep string, list []any) string {
+		strs := make([]string, 0, len(list))
+		for _, v := range list {
+			strs = append(strs, fmt.Sprint(v))
+		}
+		return strings.Join(strs, sep)
+	},
+}
+
+// parseTemplate parses the template of the template output format
+func parseTemplate(text string) (*template.Template, error) {
+	if text == "" {
+		return nil, fmt.Errorf("the %s output format needs --template", FormatTemplate)
+	}
+	return template.New("todo").Funcs(templateFuncs).Parse(text)
+}
+
+// printItem writes the todo, with all its fields
+func (app *App) printItem(item ledger.Item) error {
+	switch app.Format {
+	case FormatJSON:
+		return printJSON(app.Out, item.ToAPIv1())
+	case FormatYAML:
+		return printYAML(app.Out, item.ToAPIv1())
+	case FormatTSV, FormatTemplate:
+		return app.printItems(ledger.Items{item})
+	}
+	todo := item.ToAPIv1().Todo
+	tw := tabwriter.NewWriter(app.Out, 0, 0, 1, ' ', 0)
+	fmt.Fprintf(tw, "ID:\t%s\n", item.ID)
+	fmt.Fprintf(tw, "Title:\t%s\n", todo.Title)
+	fmt.Fprintf(tw, "Status:\t%s\n", todo.Status)
+	fmt.Fprintf(tw, "Assignee:\t%s\n", todo.Assignee)
+	fmt.Fprintf(tw, "Priority:\t%s\n", todo.Priority)
+	fmt.Fprintf(tw, "Due:\t%s\n", formatTime(todo.Due))
+	fmt.Fprintf(tw, "Tags:\t%s\n", strings.Join(todo.Tags, ", "))
+	if len(todo.Contexts) > 0 {
+		fmt.Fprintf(tw, "Contexts:\t%s\n", formatContexts(todo.Contexts, ", "))
+	}
+	if len(todo.Fields) > 0 {
+		fmt.Fprintf(tw, "Fields:\t%s\n", formatFields(todo.Fields))
+	}
+	if len(item.Task.Links) > 0 {
+		fmt.Fprintf(tw, "Links:\t%s\n", formatLinks(item.Task.Links))
+	}
+	fmt.Fprintf(tw, "Updated:\t%s\n", formatTime(&todo.LastUpdateTime))
+	if todo.Pomodoros > 0 {
+		fmt.Fprintf(tw, "Pomodoros:\t%d\n", todo.Pomodoros)
+	}
+	if todo.Description != "" {
+		fmt.Fprintf(tw, "Description:\t%s\n", todo.Description)
+	}
+	return tw.Flush()
+}
+
+// printItems writes the todos, one per line in the text, tsv and template formats
+func (app *App) printItems(items ledger.Items) error {
+	switch app.Format {
+	case FormatJSON:
+		return printJSON(app.Out, items.ToAPIv1())
+	case FormatYAML:
+		return printYAML(app.Out, items.ToAPIv1())
+	case FormatTSV:
+		return printTSV(app.Out, items.ToAPIv1())
+	case FormatTemplate:
+		return printTemplate(app.Out, app.Template, items.ToAPIv1())
+	}
+	tw := tabwriter.NewWriter(app.Out, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "ID\tSTATUS\tTITLE\tASSIGNEE\tDUE\tTAGS\tCONTEXTS\tPOMODOROS\n")
+	for _, item := range items {
+		todo := item.ToAPIv1().Todo
+		pomodoros := ""
+		if todo.Pomodoros > 0 {
+			pomodoros = strconv.Itoa(todo.Pomodoros)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", item.ID, todo.Status, todo.Title, todo.Assignee,
+			formatTime(todo.Due), strings.Join(todo.Tags, ","), formatContexts(todo.Contexts, ","), pomodoros)
+	}
+	return tw.Flush()
+}
+
+// formatFields returns the custom fields as name=value, sorted by name
+func formatFields(values map[string]string) string {
+	res := make([]string, 0, len(values))
+	for name, value := range values {
+		res = append(res, name+"="+value)
+	}
+	sort.Strings(res)
+	return strings.Join(res, ", ")
+}
+
+// formatContexts returns the contexts with their @, joined by the separator
+func formatContexts(contexts []string, sep string) string {
+	res := make([]string, 0, len(contexts))
+	for _, ctx := range contexts {
+		res = append(res, "@"+ctx)
+	}
+	return strings.Join(res, sep)
+}
+
+func printJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func printYAML(w io.Writer, v any) error {
+	fields, err := jsonFields(v)
+	if err != nil {
+		return err
+	}
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(fields); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// printTSV writes the todos as tab separated values, after a header naming the columns. The tabs
+// and the new lines in the values are replaced by spaces, and the times are in RFC 3339.
+func printTSV(w io.Writer, items []apiv1.Item) error {
+	if _, err := fmt.Fprintln(w, strings.Join(tsvColumns, "\t")); err != nil {
+		return err
+	}
+	clean := strings.NewReplacer("\t", " ", "\r", " ", "\n", " ")
+	for _, item := range items {
+		due := ""
+		if item.Todo.Due != nil {
+			due = item.Todo.Due.Format(time.RFC3339)
+		}
+		row := []string{string(item.ID), string(item.Todo.Status), item.Todo.Title, item.Todo.Assignee,
+			item.Todo.Priority, due, strings.Join(item.Todo.Tags, ","), item.Todo.LastUpdateTime.Format(time.RFC3339)}
+		for i := range row {
+			row[i] = clean.Replace(row[i])
+		}
+		if _, err := fmt.Fprintln(w, strings.Join(row, "\t")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// printTemplate executes the template on every todo, each followed by a new line. The fields of
+// the todos are named as in the json output, e.g. {{.id}} or {{.todo.title}}.
+func printTemplate(w io.Writer, tmpl *template.Template, items []apiv1.Item) error {
+	for _, item := range items {
+		fields, err := jsonFields(item)
+		if err != nil {
+			return err
+		}
+		if err := tmpl.Execute(w, fields); err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintln(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// jsonFields returns the value as the generic maps and slices of its json representation, for its
+// fields to have the same names in all the output formats
+func jsonFields(v any) (any, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var fields any
+	err = json.Unmarshal(data, &fields)
+	return fields, err
+}
+
+// formatTime returns the time in the local time zone, without the seconds. Empty if nil or zero.
+func formatTime(t *time.Time) string {
+	if t == nil || t.IsZero() {
+		return ""
+	}
+	return t.Local().Format("2006-01-02 15:04")
+}
@@ -0,0 +1,130 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/gotestbootcamp/go-todo-app/ledger"
+	"github.com/gotestbootcamp/go-todo-app/store"
+	"github.com/gotestbootcamp/go-todo-app/task"
+)
+
+var pomoCommand = Command{
+	Name: "pomo",
+	Args: "<id>",
+	Help: "work on a todo in pomodoros, work intervals with breaks in between, each one completed logged in the time tracked on the todo",
+	Setup: func(flags *flag.FlagSet) func(*App, []string) error {
+		var p pomodoro
+		flags.DurationVar(&p.work, "work", 25*time.Minute, "how long the pomodoros last")
+		flags.DurationVar(&p.shortBreak, "break", 5*time.Minute, "how long the breaks after the pomodoros last")
+		flags.DurationVar(&p.longBreak, "long-break", 15*time.Minute, "how long the breaks after every --long-every pomodoros last")
+		flags.IntVar(&p.longEvery, "long-every", 4, "how many pomodoros come before a long break")
+		count := flags.Int("count", 4, "how many pomodoros to work, 0 until stopped with ^C")
+		return func(app *App, args []string) error {
+			id, err := oneID(args)
+			if err != nil {
+				return err
+			}
+			if p.work <= 0 || p.shortBreak < 0 || p.longBreak < 0 || p.longEvery <= 0 || *count < 0 {
+				return errUsage
+			}
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			p.ld, p.id, p.out, p.now, p.wait = app.Ledger, id, app.Out, time.Now, waitFor
+			return p.run(ctx, *count)
+		}
+	},
+}
+
+// pomodoro runs the work intervals of a todo, with breaks in between, logging the ones completed
+// as pomodoros in the time tracked on the todo
+type pomodoro struct {
+	ld *ledger.Ledger
+	id store.ID
+	// work is how long the pomodoros last, and shortBreak and longBreak the breaks after them,
+	// the long ones after every longEvery pomodoros
+	work, shortBreak, longBreak time.Duration
+	longEvery                   int
+	// out is where the countdowns are shown
+	out io.Writer
+	now func() time.Time
+	// wait waits for the duration, or until the context is done
+	wait func(ctx context.Context, d time.Duration) error
+}
+
+// waitFor waits for the duration, failing with the error of the context if done before
+func waitFor(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// run works count pomodoros, or until the context is done if zero, with a break after each one
+// but the last, a long one after every longEvery pomodoros. The pomodoro interrupted is logged as
+// time tracked, not as a pomodoro.
+func (p *pomodoro) run(ctx context.Context, count int) error {
+	item, _, err := p.ld.GetItem(p.id)
+	if err != nil {
+		return err
+	}
+	if item.Task.Running() {
+		return fmt.Errorf("todo %s: %w, stop it first", p.id, task.ErrTimerRunning)
+	}
+	fmt.Fprintf(p.out, "working on %s %q, ^C to stop\n", p.id, item.Task.Title)
+	for done := 0; count == 0 || done < count; {
+		start := p.now()
+		err := p.countdown(ctx, "pomodoro", p.work)
+		end := p.now()
+		// the todo may have changed meanwhile
+		if err := p.ld.Reload(); err != nil {
+			return err
+		}
+		item, lerr := p.ld.AddTime(p.id, task.TimeEntry{Start: start, End: &end, Pomodoro: err == nil})
+		if lerr != nil {
+			return fmt.Errorf("can't log the pomodoro: %w", lerr)
+		}
+		if err != nil {
+			fmt.Fprintf(p.out, "\npomodoro interrupted, %v logged\n", end.Sub(start).Round(time.Second))
+			return nil
+		}
+		done++
+		fmt.Fprintf(p.out, "\a\npomodoro %d done, %d on %s\n", done, item.Task.Pomodoros(time.Time{}, time.Time{}), p.id)
+		if count > 0 && done == count {
+			break
+		}
+		rest, name := p.shortBreak, "break"
+		if done%p.longEvery == 0 {
+			rest, name = p.longBreak, "long break"
+		}
+		if err := p.countdown(ctx, name, rest); err != nil {
+			fmt.Fprintln(p.out)
+			return nil
+		}
+		fmt.Fprintf(p.out, "\a\n%s over\n", name)
+	}
+	return nil
+}
+
+// countdown shows the time left of the phase, every second, until it ends or the context is done
+func (p *pomodoro) countdown(ctx context.Context, name string, d time.Duration) error {
+	end := p.now().Add(d)
+	for left := d; left > 0; left = end.Sub(p.now()) {
+		shown := left.Round(time.Second)
+		fmt.Fprintf(p.out, "\r%s %02d:%02d ", name, int(shown.Minutes()), int(shown.Seconds())%60)
+		if err := p.wait(ctx, min(time.Second, left)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
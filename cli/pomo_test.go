@@ -0,0 +1,68 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPomodoro(t *testing.T) {
+	dir := t.TempDir()
+	code, _, _ := run(t, dir, "add", "write", "report")
+	require.Equal(t, 0, code)
+	ld, _, err := open(globals{dataDir: dir})
+	require.NoError(t, err)
+	defer ld.Close()
+
+	now := time.Date(2024, 11, 11, 9, 0, 0, 0, time.UTC)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var out bytes.Buffer
+	var waits int
+	p := pomodoro{ld: ld, id: "1", work: 25 * time.Minute, shortBreak: 5 * time.Minute, longBreak: 15 * time.Minute, longEvery: 2, out: &out}
+	p.now = func() time.Time { return now }
+	p.wait = func(ctx context.Context, d time.Duration) error {
+		waits++
+		now = now.Add(d)
+		return ctx.Err()
+	}
+
+	require.NoError(t, p.run(ctx, 3))
+	assert.Equal(t, 25*60*3+5*60+15*60, waits, "a wait every second")
+	assert.Contains(t, out.String(), "\rpomodoro 25:00 \rpomodoro 24:59 ")
+	assert.Contains(t, out.String(), "pomodoro 1 done, 1 on 1\n")
+	assert.Contains(t, out.String(), "\rbreak 05:00 ")
+	assert.Contains(t, out.String(), "long break over\n")
+	assert.Contains(t, out.String(), "pomodoro 3 done, 3 on 1\n")
+	assert.NotContains(t, out.String(), "pomodoro 3 done, 3 on 1\n\a", "no break after the last")
+
+	// the pomodoro interrupted is logged, but doesn't count
+	out.Reset()
+	interrupt := now.Add(10 * time.Minute)
+	p.wait = func(ctx context.Context, d time.Duration) error {
+		now = now.Add(d)
+		if !now.Before(interrupt) {
+			cancel()
+		}
+		return ctx.Err()
+	}
+	require.NoError(t, p.run(ctx, 0))
+	assert.Contains(t, out.String(), "pomodoro interrupted, 10m0s logged\n")
+
+	code, out2, _ := run(t, dir, "show", "--output", "json", "1")
+	require.Equal(t, 0, code)
+	assert.Contains(t, out2, `"pomodoros": 3`)
+	item, _, err := ld.GetItem("1")
+	require.NoError(t, err)
+	require.Len(t, item.Task.Time, 4)
+	assert.False(t, item.Task.Time[3].Pomodoro)
+	assert.Equal(t, 10*time.Minute, item.Task.Time[3].End.Sub(item.Task.Time[3].Start))
+
+	_, err = ld.StartTimer("1")
+	require.NoError(t, err)
+	assert.Error(t, p.run(context.Background(), 1), "the timer runs")
+}
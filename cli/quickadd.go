@@ -0,0 +1,160 @@
+package cli
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/gotestbootcamp/go-todo-app/ledger"
+	"github.com/gotestbootcamp/go-todo-app/model"
+	"github.com/gotestbootcamp/go-todo-app/store"
+	"github.com/gotestbootcamp/go-todo-app/task"
+)
+
+// quickAddToken matches the tokens of a quick-add line: the words, with the spaces inside double
+// quotes, e.g. due:"next friday"
+var quickAddToken = regexp.MustCompile(`[^\s"]*"[^"]*"[^\s"]*|\S+`)
+
+// parseQuickAdd parses a todo entered in one line, like "Pay rent !p1 #finance @home due:friday",
+// returning its title and the patch of its other fields. The words are:
+//   - "!" and the priority, as accepted by task.ParsePriority, e.g. !p1 or !high
+//   - "#" and a tag
+//   - "@" and a context
+//   - "due:" and the due date, as accepted by parseDue, double quoted if it has spaces
+//
+// The other words, and the ones escaped by a leading backslash, make up the title.
+func parseQuickAdd(line string) (string, ledger.Patch, error) {
+	var title []string
+	var tags, contexts []string
+	var patch ledger.Patch
+	for _, word := range quickAddToken.FindAllString(line, -1) {
+		switch {
+		case strings.HasPrefix(word, `\`):
+			title = append(title, word[1:])
+		case len(word) > 1 && word[0] == '!':
+			pr, err := task.ParsePriority(word[1:])
+			if err != nil {
+				return "", patch, err
+			}
+			patch.Priority = &pr
+		case len(word) > 1 && word[0] == '#':
+			tags = append(tags, word[1:])
+		case len(word) > 1 && word[0] == '@':
+			contexts = append(contexts, word[1:])
+		case strings.HasPrefix(word, "due:") && len(word) > len("due:"):
+			due, err := parseDue(strings.Trim(word[len("due:"):], `"`))
+			if err != nil {
+				return "", patch, err
+			}
+			patch.Schedule = &ledger.Schedule{Due: due}
+		default:
+			title = append(title, word)
+		}
+	}
+	if len(tags) > 0 {
+		patch.Tags = &tags
+	}
+	if len(contexts) > 0 {
+		patch.Contexts = &contexts
+	}
+	if len(title) == 0 {
+		return "", patch, fmt.Errorf("no title in %q", line)
+	}
+	return strings.Join(title, " "), patch, nil
+}
+
+// overlay returns the patch with the fields set in the other one replaced
+func overlay(patch, other ledger.Patch) ledger.Patch {
+	if other.Title != nil {
+		patch.Title = other.Title
+	}
+	if other.Description != nil {
+		patch.Description = other.Description
+	}
+	if other.Assignee != nil {
+		patch.Assignee = other.Assignee
+	}
+	if other.Status != nil {
+		patch.Status = other.Status
+	}
+	if other.Priority != nil {
+		patch.Priority = other.Priority
+	}
+	if other.Tags != nil {
+		patch.Tags = other.Tags
+	}
+	if other.Contexts != nil {
+		patch.Contexts = other.Contexts
+	}
+	if other.Fields != nil {
+		patch.Fields = other.Fields
+	}
+	if other.Schedule != nil {
+		patch.Schedule = other.Schedule
+	}
+	if other.Owner != nil {
+		patch.Owner = other.Owner
+	}
+	return patch
+}
+
+// withContext returns the patch setting the context of the profile, if any, unless the patch sets
+// the contexts already
+func (app *App) withContext(patch ledger.Patch) ledger.Patch {
+	if patch.Contexts == nil && app.Config.Context != "" {
+		patch.Contexts = &[]string{app.Config.Context}
+	}
+	return patch
+}
+
+// add adds the todo with the title to the list, and then the fields of the patch, if any
+func (app *App) add(list, title string, patch ledger.Patch) (ledger.Item, error) {
+	id, err := app.IDs.NewID()
+	if err != nil {
+		return ledger.Item{}, err
+	}
+	id = store.ListID(list, id)
+	item, rev, err := app.Ledger.SetIf(id, model.New(title), 0)
+	if err != nil || patch == (ledger.Patch{}) {
+		return item, err
+	}
+	item, _, err = app.Ledger.PatchIf(id, patch, rev)
+	return item, err
+}
+
+// preview returns the todo add would add, without an ID
+func preview(title string, patch ledger.Patch) (ledger.Item, error) {
+	tk := task.New(title)
+	if patch.Description != nil {
+		tk.Description = *patch.Description
+	}
+	if patch.Assignee != nil {
+		tk.Assignee = *patch.Assignee
+		tk.Status = task.Assigned
+	}
+	if patch.Priority != nil {
+		tk.Priority = *patch.Priority
+	}
+	if patch.Tags != nil {
+		tk.Tags = *patch.Tags
+	}
+	if patch.Contexts != nil {
+		tk.Contexts = *patch.Contexts
+	}
+	if patch.Fields != nil {
+		tk.Fields = *patch.Fields
+	}
+	if patch.Schedule != nil {
+		tk.Due = patch.Schedule.Due
+	}
+	if err := tk.Validate(); err != nil {
+		return ledger.Item{}, err
+	}
+	return previewOf(tk), nil
+}
+
+// previewOf returns the todo of the task, without an ID
+func previewOf(tk task.Task) ledger.Item {
+	todo := model.FromTask(tk)
+	return ledger.Item{ID: store.NullID, Todo: &todo, Task: &tk}
+}
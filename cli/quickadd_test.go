@@ -0,0 +1,62 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/gotestbootcamp/go-todo-app/ledger"
+	"github.com/gotestbootcamp/go-todo-app/task"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseQuickAdd(t *testing.T) {
+	title, patch, err := parseQuickAdd(`Pay rent !p1 #finance @home due:"2026-11-01 09:00"`)
+	require.NoError(t, err)
+	assert.Equal(t, "Pay rent", title)
+	require.NotNil(t, patch.Priority)
+	assert.Equal(t, task.PriorityHigh, *patch.Priority)
+	require.NotNil(t, patch.Tags)
+	assert.Equal(t, []string{"finance"}, *patch.Tags)
+	require.NotNil(t, patch.Contexts)
+	assert.Equal(t, []string{"home"}, *patch.Contexts)
+	require.NotNil(t, patch.Schedule)
+	assert.Equal(t, "2026-11-01 09:00", patch.Schedule.Due.Format("2006-01-02 15:04"))
+
+	title, patch, err = parseQuickAdd(`call \@bob about #1 ! due: and \!p0`)
+	require.NoError(t, err)
+	assert.Equal(t, "call @bob about ! due: and !p0", title)
+	assert.Equal(t, []string{"1"}, *patch.Tags)
+	assert.Nil(t, patch.Priority)
+
+	title, patch, err = parseQuickAdd("just a title")
+	require.NoError(t, err)
+	assert.Equal(t, "just a title", title)
+	assert.Equal(t, ledger.Patch{}, patch)
+
+	for _, line := range []string{"!p1 #only-fields", "bad !p9", "bad due:someday"} {
+		_, _, err = parseQuickAdd(line)
+		assert.Error(t, err, line)
+	}
+}
+
+func TestRunAddDryRun(t *testing.T) {
+	dir := t.TempDir()
+
+	code, out, _ := run(t, dir, "add", "--dry-run", "--tags", "rent", "Pay", "rent", "!p1", "#finance", "@home")
+	require.Equal(t, 0, code)
+	assert.Contains(t, out, "Title:    Pay rent")
+	assert.Contains(t, out, "Priority: P1")
+	assert.Contains(t, out, "Tags:     rent", "the flags take precedence")
+	_, out, _ = run(t, dir, "list")
+	assert.NotContains(t, out, "Pay rent", "not added")
+
+	code, out, _ = run(t, dir, "add", "Pay rent !urgent #finance @home")
+	require.Equal(t, 0, code)
+	assert.Contains(t, out, "Priority: P0")
+	assert.Contains(t, out, "Tags:     finance\n")
+	assert.Contains(t, out, "Contexts: @home\n")
+
+	code, out, _ = run(t, dir, "add", "Print the slides @home.office")
+	require.Equal(t, 0, code)
+	assert.Contains(t, out, "Contexts: @home.office\n")
+}
@@ -0,0 +1,248 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/gotestbootcamp/go-todo-app/ledger"
+)
+
+// The charts of the todos completed todo stats draws
+const (
+	chartSparkline = "sparkline"
+	chartBars      = "bars"
+	chartNone      = "none"
+)
+
+// charts are the charts of the todos completed todo stats draws
+var charts = []string{chartSparkline, chartBars, chartNone}
+
+// sparks are the bars of the sparklines, from the lowest to the highest
+var sparks = []rune("▁▂▃▄▅▆▇█")
+
+// barWidth is how wide the longest bar of the bar charts is
+const barWidth = 40
+
+// defaultStatsPeriod is how far back todo stats looks without --from
+const defaultStatsPeriod = 28 * 24 * time.Hour
+
+var statsCommand = Command{
+	Name: "stats",
+	Help: "tell how many todos were completed each day or week, how long they took and how many were late, overall, by tag and by context",
+	Setup: func(flags *flag.FlagSet) func(*App, []string) error {
+		from := flags.String("from", "", "when the period starts, like 2025-01-01 (default 4 weeks before --to)")
+		to := flags.String("to", "", "when the period ends, like 2025-02-01 (default now)")
+		period := flags.String("period", "day", "count the todos completed each day or week")
+		chart := flags.String("chart", chartSparkline, "draw the todos completed as a sparkline, as bars, or none")
+		return func(app *App, args []string) error {
+			if len(args) > 0 {
+				return errUsage
+			}
+			if !slices.Contains(ledger.StatsPeriods, *period) {
+				return fmt.Errorf("invalid period %q, want day or week", *period)
+			}
+			if !slices.Contains(charts, *chart) {
+				return fmt.Errorf("invalid chart %q, want %s", *chart, strings.Join(charts, ", "))
+			}
+			since, until, err := parsePeriod(*from, *to)
+			if err != nil {
+				return err
+			}
+			stats, err := app.Ledger.Stats(since, until, *period)
+			if err != nil {
+				return err
+			}
+			switch app.Format {
+			case FormatJSON:
+				return printJSON(app.Out, stats.ToAPIv1())
+			case FormatYAML:
+				return printYAML(app.Out, stats.ToAPIv1())
+			}
+			return printStats(app.Out, stats, *chart)
+		}
+	},
+}
+
+var burndownCommand = Command{
+	Name: "burndown",
+	Help: "tell how many todos of a project, with the tag, in the list, were open and completed at the end of each day",
+	Setup: func(flags *flag.FlagSet) func(*App, []string) error {
+		tag := flags.String("tag", "", "count only the todos with the tag")
+		list := flags.String("list", "", "count only the todos of the list, empty for all (default: the one of the profile, or all)")
+		from := flags.String("from", "", "when the burndown starts, like 2025-01-01 (default 4 weeks before --to)")
+		to := flags.String("to", "", "when the burndown ends, like 2025-02-01 (default now)")
+		asCSV := flags.Bool("csv", false, "write the burndown as CSV, for the spreadsheets and the plotting tools")
+		return func(app *App, args []string) error {
+			if len(args) > 0 {
+				return errUsage
+			}
+			if !isSet(flags, "list") {
+				*list = app.Config.List
+			}
+			since, until, err := parsePeriod(*from, *to)
+			if err != nil {
+				return err
+			}
+			burndown, err := app.Ledger.Burndown(*tag, *list, since, until)
+			if err != nil {
+				return err
+			}
+			switch {
+			case *asCSV:
+				return burndown.WriteCSV(app.Out)
+			case app.Format == FormatJSON:
+				return printJSON(app.Out, burndown.ToAPIv1())
+			case app.Format == FormatYAML:
+				return printYAML(app.Out, burndown.ToAPIv1())
+			}
+			return printBurndown(app.Out, burndown)
+		}
+	},
+}
+
+// parsePeriod parses the times the period starts and ends, see dates.Parse: by default, the
+// 4 weeks until now
+func parsePeriod(from, to string) (time.Time, time.Time, error) {
+	until := time.Now()
+	if to != "" {
+		var err error
+		if until, err = dateParser.Parse(to); err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("to: %w", err)
+		}
+	}
+	since := until.Add(-defaultStatsPeriod)
+	if from != "" {
+		var err error
+		if since, err = dateParser.Parse(from); err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("from: %w", err)
+		}
+	}
+	return since, until, nil
+}
+
+// printBurndown writes the counts of the todos at each time of the burndown, with a bar as long
+// as the todos open
+func printBurndown(w io.Writer, burndown ledger.Burndown) error {
+	highest := 0
+	for _, p := range burndown.Points {
+		highest = max(highest, p.Open)
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "TIME\tOPEN\tCOMPLETED\t\n")
+	for _, p := range burndown.Points {
+		n := 0
+		if highest > 0 {
+			n = (p.Open*barWidth + highest - 1) / highest
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%s\n", formatTime(&p.Time), p.Open, p.Completed, strings.Repeat("█", n))
+	}
+	return tw.Flush()
+}
+
+// printStats writes the stats, with the chart of the todos completed in each period
+func printStats(w io.Writer, stats ledger.Stats, chart string) error {
+	fmt.Fprintf(w, "Completed %d todos from %s to %s, created %d\n", stats.Completed,
+		stats.From.Local().Format(time.DateOnly), stats.To.Local().Format(time.DateOnly), stats.Created)
+	counts := make([]int, 0, len(stats.Completions))
+	labels := make([]string, 0, len(stats.Completions))
+	for _, b := range stats.Completions {
+		counts = append(counts, b.Completed)
+		if stats.Period == "week" {
+			labels = append(labels, "week of "+b.Start.Format("Jan _2"))
+		} else {
+			labels = append(labels, b.Start.Format("Mon Jan _2"))
+		}
+	}
+	switch chart {
+	case chartSparkline:
+		fmt.Fprintf(w, "Each %s: %s max %d\n", stats.Period, sparkline(counts), maxCount(counts))
+	case chartBars:
+		writeBars(w, labels, counts)
+	}
+	fmt.Fprintf(w, "Average time to complete: %s\n", formatElapsed(stats.TimeToComplete, stats.Completed))
+	fmt.Fprintf(w, "Overdue rate: %.0f%%, %d of %d todos due were late\n", 100*stats.OverdueRate(), stats.Late, stats.Due)
+	fmt.Fprintf(w, "Open: %d, %d overdue\n", stats.Open, stats.Overdue)
+	fmt.Fprintf(w, "Pomodoros: %d\n", stats.Pomodoros)
+	if err := writeCounts(w, "TAG", "", stats.ByTag); err != nil {
+		return err
+	}
+	return writeCounts(w, "CONTEXT", "@", stats.ByContext)
+}
+
+// writeCounts writes a table of the counts by name, after an empty line, sorted by name, the
+// names after the prefix. Nothing if there are no counts.
+func writeCounts(w io.Writer, heading, prefix string, byName map[string]ledger.StatsCounts) error {
+	if len(byName) == 0 {
+		return nil
+	}
+	names := make([]string, 0, len(byName))
+	for name := range byName {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	fmt.Fprintln(w)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "%s\tCREATED\tCOMPLETED\tTIME TO COMPLETE\tOVERDUE RATE\tOPEN\tOVERDUE\tPOMODOROS\n", heading)
+	for _, name := range names {
+		c := byName[name]
+		fmt.Fprintf(tw, "%s%s\t%d\t%d\t%s\t%.0f%%\t%d\t%d\t%d\n", prefix, name, c.Created, c.Completed,
+			formatElapsed(c.TimeToComplete, c.Completed), 100*c.OverdueRate(), c.Open, c.Overdue, c.Pomodoros)
+	}
+	return tw.Flush()
+}
+
+// sparkline returns the counts as a line of bars, as high as the counts relative to the highest.
+// The counts not zero get a bar above the lowest.
+func sparkline(counts []int) string {
+	highest := maxCount(counts)
+	var sb strings.Builder
+	for _, c := range counts {
+		level := 0
+		if highest > 0 {
+			level = (c*(len(sparks)-1) + highest - 1) / highest
+		}
+		sb.WriteRune(sparks[level])
+	}
+	return sb.String()
+}
+
+// writeBars writes a horizontal bar for each count, after its label, as long as the count
+// relative to the highest, up to barWidth
+func writeBars(w io.Writer, labels []string, counts []int) {
+	highest := maxCount(counts)
+	tw := tabwriter.NewWriter(w, 0, 0, 1, ' ', 0)
+	for i, c := range counts {
+		n := 0
+		if highest > 0 {
+			n = (c*barWidth + highest - 1) / highest
+		}
+		fmt.Fprintf(tw, "%s\t%s %d\n", labels[i], strings.Repeat("█", n), c)
+	}
+	tw.Flush()
+}
+
+// formatElapsed returns the duration in days and hours, or in hours and minutes when shorter than
+// a day. "-" if nothing was counted.
+func formatElapsed(d time.Duration, count int) string {
+	switch {
+	case count == 0:
+		return "-"
+	case d >= 24*time.Hour:
+		return fmt.Sprintf("%dd %dh", d/(24*time.Hour), d%(24*time.Hour)/time.Hour)
+	}
+	return fmt.Sprintf("%dh %dm", d/time.Hour, d%time.Hour/time.Minute)
+}
+
+// maxCount returns the highest of the counts, 0 if none
+func maxCount(counts []int) int {
+	highest := 0
+	for _, c := range counts {
+		highest = max(highest, c)
+	}
+	return highest
+}
@@ -0,0 +1,198 @@
+package cli
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/gotestbootcamp/go-todo-app/config"
+	"github.com/gotestbootcamp/go-todo-app/store"
+	"github.com/gotestbootcamp/go-todo-app/storesync"
+	"github.com/gotestbootcamp/go-todo-app/task"
+)
+
+// syncEntry is the outcome of the sync of a todo, as printed by todo sync
+type syncEntry struct {
+	ID store.ID `json:"id"`
+	// Action is "pulled", "pushed", "merged", "renamed" or "skipped"
+	Action   string `json:"action"`
+	Deleted  bool   `json:"deleted,omitempty"`
+	Conflict bool   `json:"conflict,omitempty"`
+	// NewID is the ID the local todo was moved to, if renamed
+	NewID store.ID `json:"new_id,omitempty"`
+	Error string   `json:"error,omitempty"`
+}
+
+// syncVerbs are the actions of the sync, as printed by todo sync --dry-run
+var syncVerbs = map[storesync.Action]string{storesync.Pulled: "pull", storesync.Pushed: "push", storesync.Merged: "merge", storesync.Skipped: "skip",
+	storesync.Renamed: "rename"}
+
+var syncCommand = Command{
+	Name:    "sync",
+	Args:    "<remote>",
+	Help:    "sync the todos both ways with another store, given by its URI or by the profile using it",
+	NoStore: true,
+	Setup: func(flags *flag.FlagSet) func(*App, []string) error {
+		strategy := flags.String("strategy", string(storesync.Merge), "how to resolve the todos changed on both sides: merge their changes field by field, latest-wins to keep the one updated last, or manual to ask which version to keep")
+		dryRun := flags.Bool("dry-run", false, "print what would be synced, without changing anything")
+		return func(app *App, args []string) error {
+			if len(args) != 1 {
+				return errUsage
+			}
+			localURI, err := app.globals.storeURI()
+			if err != nil {
+				return err
+			}
+			remoteURI, err := remoteStoreURI(args[0])
+			if err != nil {
+				return err
+			}
+			if remoteURI == localURI {
+				return fmt.Errorf("%s is the store of the todos already", args[0])
+			}
+			local, err := store.Open(localURI)
+			if err != nil {
+				return fmt.Errorf("can't open the store: %w", err)
+			}
+			defer local.Close()
+			remote, err := store.Open(remoteURI)
+			if err != nil {
+				return fmt.Errorf("can't open the remote store: %w", err)
+			}
+			defer remote.Close()
+
+			// the URI may hold a password, not to keep in the state
+			sum := sha256.Sum256([]byte(remoteURI))
+			s := storesync.New(local, remote, hex.EncodeToString(sum[:8]))
+			s.Strategy, s.DryRun = storesync.Strategy(*strategy), *dryRun
+			if s.Strategy == storesync.Manual {
+				s.Resolve = app.askResolution()
+			}
+			report, err := s.Sync()
+			if err != nil {
+				return err
+			}
+			return app.printSyncReport(report, *dryRun)
+		}
+	},
+}
+
+// remoteStoreURI returns the URI of the store of the remote: the remote itself if it's a URI, or
+// else the store of the profile of the configuration file named after it
+func remoteStoreURI(remote string) (string, error) {
+	if strings.Contains(remote, "://") {
+		return remote, nil
+	}
+	cfg, err := config.LoadCLIConfig(config.CLIConfigPath())
+	if err != nil {
+		return "", err
+	}
+	if _, ok := cfg.Profiles[remote]; !ok {
+		return "", fmt.Errorf("unknown remote %q, want a storage URI or one of the profiles %s", remote, strings.Join(cfg.ProfileNames(), ", "))
+	}
+	// the environment variables select the local store, not the remote one
+	settings, err := cfg.Resolve(remote, func(string) string { return "" })
+	if err != nil {
+		return "", err
+	}
+	g := globals{store: settings.Store, dataDir: settings.DataDir}
+	if g.dataDir == "" {
+		g.dataDir = DefaultDataDir()
+	}
+	return g.storeURI()
+}
+
+// askResolution returns the resolver of the conflicts which asks which version to keep, reading
+// the answers from the input. The conflicts are skipped once the input ends.
+func (app *App) askResolution() func(storesync.Conflict) (storesync.Resolution, error) {
+	in := bufio.NewReader(app.In)
+	return func(c storesync.Conflict) (storesync.Resolution, error) {
+		fmt.Fprintf(app.Out, "%s changed on both sides:\n  local:  %s\n  remote: %s\nkeep [l]ocal, [r]emote or [s]kip? ", c.ID, describeVersion(c.Local), describeVersion(c.Remote))
+		answer, err := in.ReadString('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return storesync.Skip, err
+		}
+		switch strings.ToLower(strings.TrimSpace(answer)) {
+		case "l", "local":
+			return storesync.KeepLocal, nil
+		case "r", "remote":
+			return storesync.KeepRemote, nil
+		}
+		fmt.Fprintln(app.Out)
+		return storesync.Skip, nil
+	}
+}
+
+// describeVersion returns the title of the version of the todo in conflict, and when it was updated
+func describeVersion(blob store.Blob) string {
+	if blob == nil {
+		return "deleted"
+	}
+	tk, err := task.Unmarshal(blob)
+	if err != nil {
+		return fmt.Sprintf("%d bytes", len(blob))
+	}
+	return fmt.Sprintf("%q, updated %s", tk.Title, formatTime(&tk.Updated))
+}
+
+// printSyncReport writes the outcome of the sync of each todo which differed between the stores.
+// Fails if any todo couldn't be synced.
+func (app *App) printSyncReport(report storesync.Report, dryRun bool) error {
+	entries := make([]syncEntry, 0, len(report))
+	failed := 0
+	for _, res := range report {
+		entry := syncEntry{ID: res.ID, Action: string(res.Action), Deleted: res.Deleted, Conflict: res.Conflict, NewID: res.NewID}
+		if res.Err != nil {
+			entry.Error = res.Err.Error()
+			failed++
+		}
+		entries = append(entries, entry)
+	}
+
+	switch app.Format {
+	case FormatJSON:
+		if err := printJSON(app.Out, entries); err != nil {
+			return err
+		}
+	case FormatYAML:
+		if err := printYAML(app.Out, entries); err != nil {
+			return err
+		}
+	default:
+		for i, entry := range entries {
+			action := entry.Action
+			if dryRun {
+				action = "would " + syncVerbs[report[i].Action]
+			}
+			var notes []string
+			if entry.Deleted {
+				notes = append(notes, "deleted")
+			}
+			if entry.Conflict {
+				notes = append(notes, "conflict")
+			}
+			line := fmt.Sprintf("%s %s", action, entry.ID)
+			if entry.NewID != store.NullID {
+				line += " to " + string(entry.NewID)
+			}
+			if len(notes) > 0 {
+				line += " (" + strings.Join(notes, ", ") + ")"
+			}
+			if entry.Error != "" {
+				line += ": " + entry.Error
+			}
+			if _, err := fmt.Fprintln(app.Out, line); err != nil {
+				return err
+			}
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("can't sync %d of %d todos, retry", failed, len(entries))
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunSync(t *testing.T) {
+	laptop, server := t.TempDir(), t.TempDir()
+	remote := "sqlite://" + filepath.Join(server, "todo.db")
+
+	code, _, _ := run(t, laptop, "add", "buy", "milk")
+	require.Equal(t, 0, code)
+	code, out, _ := run(t, laptop, "sync", "--dry-run", remote)
+	require.Equal(t, 0, code)
+	assert.Equal(t, "would push 1\n", out)
+	code, out, _ = run(t, laptop, "sync", remote)
+	require.Equal(t, 0, code)
+	assert.Equal(t, "pushed 1\n", out)
+
+	// the IDs of the server follow the ones synced
+	code, _, _ = run(t, server, "add", "walk", "dog")
+	require.Equal(t, 0, code)
+	code, _, _ = run(t, server, "rm", "1")
+	require.Equal(t, 0, code)
+	code, out, _ = run(t, laptop, "sync", remote)
+	require.Equal(t, 0, code)
+	assert.Equal(t, "pulled 1 (deleted)\npulled 2\n", out)
+	code, out, _ = run(t, laptop, "list")
+	require.Equal(t, 0, code)
+	assert.Contains(t, out, "walk dog")
+	assert.NotContains(t, out, "buy milk")
+
+	code, _, _ = run(t, laptop, "edit", "--title", "walk the dog", "2")
+	require.Equal(t, 0, code)
+	code, _, _ = run(t, server, "edit", "--title", "walk the dogs", "2")
+	require.Equal(t, 0, code)
+	code, out, _ = runInput(t, laptop, "s\n", "sync", "--strategy", "manual", remote)
+	require.Equal(t, 0, code)
+	assert.Contains(t, out, "2 changed on both sides:\n  local:  \"walk the dog\"")
+	assert.Contains(t, out, "skipped 2 (conflict)\n")
+	code, out, _ = runInput(t, laptop, "r\n", "sync", "--output", "json", "--strategy", "manual", remote)
+	require.Equal(t, 0, code)
+	assert.Contains(t, out, `"id": "2",`)
+	assert.Contains(t, out, `"action": "pulled",`)
+	code, out, _ = run(t, laptop, "show", "2")
+	require.Equal(t, 0, code)
+	assert.Contains(t, out, "walk the dogs")
+
+	// the changes of different fields are merged
+	code, _, _ = run(t, laptop, "edit", "--title", "walk the dog", "2")
+	require.Equal(t, 0, code)
+	code, _, _ = run(t, server, "edit", "--description", "around the park", "2")
+	require.Equal(t, 0, code)
+	code, out, _ = run(t, laptop, "sync", remote)
+	require.Equal(t, 0, code)
+	assert.Equal(t, "merged 2 (conflict)\n", out)
+	code, out, _ = run(t, server, "show", "2")
+	require.Equal(t, 0, code)
+	assert.Contains(t, out, "walk the dog\n")
+	assert.Contains(t, out, "around the park")
+
+	// the profiles name the remotes
+	config := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(config, []byte("profiles:\n  server:\n    data-dir: "+server+"\n"), 0o600))
+	t.Setenv("TODO_CONFIG", config)
+	code, out, _ = run(t, laptop, "sync", "server")
+	require.Equal(t, 0, code)
+	assert.Empty(t, out)
+
+	code, _, errs := run(t, laptop, "sync", "laptop")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, errs, `unknown remote "laptop"`)
+	code, _, errs = run(t, laptop, "sync", "sqlite://"+filepath.Join(laptop, "todo.db"))
+	assert.Equal(t, 1, code)
+	assert.Contains(t, errs, "is the store of the todos already")
+	code, _, errs = run(t, laptop, "sync", "--strategy", "first-wins", remote)
+	assert.Equal(t, 1, code)
+	assert.Contains(t, errs, `unknown strategy "first-wins"`)
+}
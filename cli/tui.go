@@ -0,0 +1,607 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/cursor"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/gotestbootcamp/go-todo-app/ledger"
+	"github.com/gotestbootcamp/go-todo-app/store"
+	"github.com/gotestbootcamp/go-todo-app/task"
+)
+
+var tuiCommand = Command{
+	Name: "tui",
+	Help: "browse and change the todos in an interactive terminal UI",
+	Setup: func(flags *flag.FlagSet) func(*App, []string) error {
+		interval := flags.Duration("interval", 5*time.Second, "how often to load the todos again, to show the changes made by the other processes")
+		return func(app *App, args []string) error {
+			if len(args) > 0 || *interval <= 0 {
+				return errUsage
+			}
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			m := newTUI(ctx, app)
+			m.interval = *interval
+			_, err := tea.NewProgram(m, tea.WithAltScreen(), tea.WithOutput(app.Out)).Run()
+			return err
+		}
+	},
+}
+
+// tuiStatuses are the statuses the tui filters by, in turn; empty is any status
+var tuiStatuses = []task.Status{"", task.Pending, task.Assigned, task.Completed}
+
+// tuiBoards are the fields the board groups the todos by, in turn; empty is the list of the todos
+var tuiBoards = []string{"", "status", "priority"}
+
+// tuiHelp is the help of the keys, shown at the bottom
+const tuiHelp = "↑/↓ move • a add • e edit • d done • x remove • s status • t tag • f filter • b board • r reload • q quit"
+
+// tuiBoardHelp is the help of the keys on the board
+const tuiBoardHelp = "←/→ column • ↑/↓ move • </> move to column • K/J reorder • a add • e edit • d done • x remove • b board • q quit"
+
+var columnStyle = lipgloss.NewStyle().Underline(true)
+
+var selectedStyle = lipgloss.NewStyle().Reverse(true)
+
+// tuiMode is what the keys typed do
+type tuiMode int
+
+const (
+	// modeBrowse moves around the todos, and acts on the selected one
+	modeBrowse tuiMode = iota
+	// modeAdd types the title of the todo to add
+	modeAdd
+	// modeEdit types the new title of the selected todo
+	modeEdit
+	// modeTag types the tag to filter the todos by
+	modeTag
+	// modeRemove confirms the removal of the selected todo
+	modeRemove
+)
+
+// itemsMsg carries the todos listed with the filters
+type itemsMsg struct {
+	items ledger.Items
+	err   error
+}
+
+// boardMsg carries the todos grouped in the columns of the board
+type boardMsg struct {
+	board ledger.Board
+	err   error
+}
+
+// movedMsg tells that the selected todo moved to the column of the board, failed if err is not nil
+type movedMsg struct {
+	column int
+	err    error
+}
+
+// doneMsg tells that an action on the todos is over, failed if err is not nil
+type doneMsg struct {
+	err error
+}
+
+// watchMsg carries the events of the changes to the todos, started to watch
+type watchMsg struct {
+	events <-chan ledger.Event
+}
+
+// changedMsg tells that the todos changed
+type changedMsg struct{}
+
+// unwatchedMsg tells that the watch of the changes is over, to watch them again
+type unwatchedMsg struct{}
+
+// pollMsg tells that the interval is over, to load the todos again from the store
+type pollMsg struct{}
+
+// reloadedMsg tells that the todos were loaded again from the store, failed if err is not nil
+type reloadedMsg struct {
+	err error
+}
+
+// tui is the model of the terminal UI: the todos listed with the filters, or grouped in the columns
+// of the board, the one selected, and the text being typed, if any. The todos are listed again every
+// time they change.
+type tui struct {
+	ctx    context.Context
+	app    *App
+	items  ledger.Items
+	cursor int
+	status task.Status
+	tag    string
+	// filter is the name of the saved filter the todos match. Empty for none.
+	filter string
+	// board is the field the board groups the todos by, empty to list them. The items are the
+	// ones of the column selected on the board.
+	board   string
+	columns []ledger.BoardColumn
+	column  int
+	mode    tuiMode
+	input   textinput.Model
+	err     error
+	events  <-chan ledger.Event
+	// interval is how often the todos are loaded again from the store, zero never
+	interval time.Duration
+	height   int
+	width    int
+}
+
+func newTUI(ctx context.Context, app *App) *tui {
+	input := textinput.New()
+	input.Cursor.SetMode(cursor.CursorStatic)
+	return &tui{ctx: ctx, app: app, input: input}
+}
+
+func (m *tui) Init() tea.Cmd {
+	return tea.Batch(m.load(), m.watch(), m.poll())
+}
+
+// load lists the todos with the current filters, or groups them on the board
+func (m *tui) load() tea.Cmd {
+	if m.board != "" {
+		field := m.board
+		return func() tea.Msg {
+			board, err := m.app.Ledger.Board(field)
+			return boardMsg{board: board, err: err}
+		}
+	}
+	q := ledger.Query{Status: m.status, Tag: m.tag}
+	filter := m.filter
+	return func() tea.Msg {
+		items, _, err := m.app.Ledger.List(q)
+		if err == nil && filter != "" {
+			items, err = inFilter(m.app.Ledger, items, filter)
+		}
+		return itemsMsg{items: items, err: err}
+	}
+}
+
+// watch starts watching the changes to the todos
+func (m *tui) watch() tea.Cmd {
+	return func() tea.Msg {
+		events, err := m.app.Ledger.Watch(m.ctx)
+		if errors.Is(err, store.ErrUnsupported) {
+			return nil // no live refresh, then
+		}
+		if err != nil {
+			return doneMsg{err: err}
+		}
+		return watchMsg{events: events}
+	}
+}
+
+// poll waits for the interval, to load the todos again from the store
+func (m *tui) poll() tea.Cmd {
+	if m.interval <= 0 {
+		return nil
+	}
+	return tea.Tick(m.interval, func(time.Time) tea.Msg { return pollMsg{} })
+}
+
+// reload loads the todos again from the store, the ones changed by the other processes too
+func (m *tui) reload() tea.Cmd {
+	return func() tea.Msg {
+		return reloadedMsg{err: m.app.Ledger.Reload()}
+	}
+}
+
+// wait waits for the next change to the todos
+func wait(events <-chan ledger.Event) tea.Cmd {
+	return func() tea.Msg {
+		if _, ok := <-events; !ok {
+			return unwatchedMsg{}
+		}
+		return changedMsg{}
+	}
+}
+
+// do runs the action on the todos in the background
+func do(action func() error) tea.Cmd {
+	return func() tea.Msg {
+		return doneMsg{err: action()}
+	}
+}
+
+// selected returns the todo selected. False if there's none.
+func (m *tui) selected() (ledger.Item, bool) {
+	if m.cursor < 0 || m.cursor >= len(m.items) {
+		return ledger.Item{}, false
+	}
+	return m.items[m.cursor], true
+}
+
+func (m *tui) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.height, m.width = msg.Height, msg.Width
+	case itemsMsg:
+		if msg.err != nil {
+			m.err = msg.err
+			return m, nil
+		}
+		m.show(msg.items)
+	case boardMsg:
+		if msg.err != nil {
+			m.err = msg.err
+			return m, nil
+		}
+		m.columns = msg.board.Columns
+		m.column = max(0, min(m.column, len(m.columns)-1))
+		m.show(m.columnItems())
+	case movedMsg:
+		m.err = msg.err
+		if msg.err == nil {
+			m.column = msg.column
+		}
+		return m, m.load()
+	case doneMsg:
+		m.err = msg.err
+		return m, m.load()
+	case watchMsg:
+		m.events = msg.events
+		return m, wait(m.events)
+	case changedMsg:
+		return m, tea.Batch(m.load(), wait(m.events))
+	case unwatchedMsg:
+		// the watch falls behind, or the program is quitting
+		if m.ctx.Err() != nil {
+			return m, nil
+		}
+		return m, tea.Batch(m.load(), m.watch())
+	case pollMsg:
+		return m, m.reload()
+	case reloadedMsg:
+		// polled again once loaded, not to pile the reloads up on a slow store. The errors of the
+		// actions stay shown until the next one.
+		if msg.err != nil {
+			m.err = msg.err
+		}
+		return m, tea.Batch(m.load(), m.poll())
+	case tea.KeyMsg:
+		if msg.String() == "ctrl+c" {
+			return m, tea.Quit
+		}
+		if m.mode == modeBrowse {
+			return m, m.browse(msg)
+		}
+		if m.mode == modeRemove {
+			return m, m.confirm(msg)
+		}
+		return m, m.typed(msg)
+	}
+	return m, nil
+}
+
+// show lists the todos, the cursor staying on the todo selected if still listed
+func (m *tui) show(items ledger.Items) {
+	cur, _ := m.selected()
+	m.items = items
+	m.cursor = max(0, min(m.cursor, len(m.items)-1))
+	for i, item := range m.items {
+		if item.ID == cur.ID {
+			m.cursor = i
+		}
+	}
+}
+
+// columnItems returns the todos of the column selected on the board
+func (m *tui) columnItems() ledger.Items {
+	if m.column >= len(m.columns) {
+		return nil
+	}
+	return m.columns[m.column].Items
+}
+
+// browse handles the keys typed moving around the todos
+func (m *tui) browse(msg tea.KeyMsg) tea.Cmd {
+	m.err = nil
+	item, ok := m.selected()
+	if m.board != "" {
+		if cmd, handled := m.browseBoard(msg, item, ok); handled {
+			return cmd
+		}
+	}
+	switch msg.String() {
+	case "q", "esc":
+		return tea.Quit
+	case "up", "k":
+		m.cursor = max(m.cursor-1, 0)
+	case "down", "j":
+		m.cursor = min(m.cursor+1, len(m.items)-1)
+	case "home", "g":
+		m.cursor = 0
+	case "end", "G":
+		m.cursor = len(m.items) - 1
+	case "a":
+		return m.prompt(modeAdd, "Add: ", "")
+	case "e", "enter":
+		if ok {
+			return m.prompt(modeEdit, "Title: ", item.Todo.Title)
+		}
+	case "t":
+		return m.prompt(modeTag, "Tag (empty for any): ", "")
+	case "d":
+		if ok {
+			return do(func() error {
+				_, err := m.app.complete(item.ID)
+				return err
+			})
+		}
+	case "x", "delete":
+		if ok {
+			m.mode = modeRemove
+		}
+	case "s":
+		for i, status := range tuiStatuses {
+			if status == m.status {
+				m.status = tuiStatuses[(i+1)%len(tuiStatuses)]
+				break
+			}
+		}
+		return m.load()
+	case "f":
+		// the saved filters in turn, then none
+		names := append(filterNames(m.app.Ledger), "")
+		m.filter = names[(slices.Index(names, m.filter)+1)%len(names)]
+		return m.load()
+	case "b":
+		m.board = tuiBoards[(slices.Index(tuiBoards, m.board)+1)%len(tuiBoards)]
+		m.column = 0
+		return m.load()
+	case "r":
+		return do(m.app.Ledger.Reload)
+	}
+	return nil
+}
+
+// browseBoard handles the keys typed moving around the columns of the board, and moving the todo
+// selected. False if the key is not one of the board's.
+func (m *tui) browseBoard(msg tea.KeyMsg, item ledger.Item, ok bool) (tea.Cmd, bool) {
+	switch key := msg.String(); key {
+	case "left", "h", "right", "l":
+		column := m.column - 1
+		if key == "right" || key == "l" {
+			column = m.column + 1
+		}
+		if column >= 0 && column < len(m.columns) {
+			m.column = column
+			m.show(m.columnItems())
+		}
+	case "<", ">":
+		column := m.column - 1
+		if key == ">" {
+			column = m.column + 1
+		}
+		if !ok || column < 0 || column >= len(m.columns) {
+			return nil, true
+		}
+		field, name, position := m.board, m.columns[column].Name, m.cursor
+		return func() tea.Msg {
+			_, err := m.app.Ledger.MoveOnBoard(field, item.ID, name, position)
+			return movedMsg{column: column, err: err}
+		}, true
+	case "K", "J":
+		position := m.cursor - 1
+		if key == "J" {
+			position = m.cursor + 1
+		}
+		if !ok || position < 0 || position >= len(m.items) {
+			return nil, true
+		}
+		field, name := m.board, m.columns[m.column].Name
+		return do(func() error {
+			_, err := m.app.Ledger.MoveOnBoard(field, item.ID, name, position)
+			return err
+		}), true
+	default:
+		return nil, false
+	}
+	return nil, true
+}
+
+// prompt starts typing the text of the mode, from the given value
+func (m *tui) prompt(mode tuiMode, prompt, value string) tea.Cmd {
+	m.mode = mode
+	m.input.Prompt = prompt
+	m.input.SetValue(value)
+	m.input.CursorEnd()
+	return m.input.Focus()
+}
+
+// typed handles the keys typed in the text input
+func (m *tui) typed(msg tea.KeyMsg) tea.Cmd {
+	switch msg.String() {
+	case "esc":
+		m.mode = modeBrowse
+		m.input.Blur()
+		return nil
+	case "enter":
+		mode, text := m.mode, strings.TrimSpace(m.input.Value())
+		m.mode = modeBrowse
+		m.input.Blur()
+		return m.submit(mode, text)
+	}
+	var cmd tea.Cmd
+	m.input, cmd = m.input.Update(msg)
+	return cmd
+}
+
+// submit applies the text typed in the mode
+func (m *tui) submit(mode tuiMode, text string) tea.Cmd {
+	switch mode {
+	case modeAdd:
+		if text == "" {
+			return nil
+		}
+		return do(func() error {
+			title, patch, err := parseQuickAdd(text)
+			if err != nil {
+				return err
+			}
+			_, err = m.app.add(store.DefaultList, title, patch)
+			return err
+		})
+	case modeEdit:
+		item, ok := m.selected()
+		if !ok || text == "" || text == item.Todo.Title {
+			return nil
+		}
+		return do(func() error {
+			_, _, err := m.app.Ledger.PatchIf(item.ID, ledger.Patch{Title: &text}, ledger.AnyRevision)
+			return err
+		})
+	case modeTag:
+		m.tag = text
+		return m.load()
+	}
+	return nil
+}
+
+// confirm handles the key confirming the removal of the selected todo, or not
+func (m *tui) confirm(msg tea.KeyMsg) tea.Cmd {
+	m.mode = modeBrowse
+	item, ok := m.selected()
+	if msg.String() != "y" || !ok {
+		return nil
+	}
+	return do(func() error {
+		return m.app.Ledger.Delete(item.ID)
+	})
+}
+
+func (m *tui) View() string {
+	if m.board != "" {
+		return m.viewBoard()
+	}
+	var b strings.Builder
+	status, tag := string(m.status), m.tag
+	if status == "" {
+		status = "any"
+	}
+	if tag == "" {
+		tag = "any"
+	}
+	fmt.Fprintf(&b, "Todos: %d • status: %s • tag: %s", len(m.items), status, tag)
+	if m.filter != "" {
+		fmt.Fprintf(&b, " • filter: %s", m.filter)
+	}
+	b.WriteString("\n\n")
+
+	// the todos around the selected one, when they don't fit
+	first, last := 0, len(m.items)
+	if rows := m.height - 5; m.height > 0 && rows > 0 && last > rows {
+		first = max(0, min(m.cursor-rows/2, last-rows))
+		last = first + rows
+	}
+	for i := first; i < last; i++ {
+		line := tuiLine(m.items[i])
+		if i == m.cursor {
+			line = selectedStyle.Render(line)
+		}
+		b.WriteString(line + "\n")
+	}
+	if len(m.items) == 0 {
+		b.WriteString("No todos.\n")
+	}
+
+	b.WriteString("\n")
+	m.viewFooter(&b, tuiHelp)
+	return b.String()
+}
+
+// viewBoard renders the columns of the board side by side, the todos of each in their order
+func (m *tui) viewBoard() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Board by %s", m.board)
+	if m.column < len(m.columns) {
+		fmt.Fprintf(&b, " • column: %s", m.columns[m.column].Name)
+	}
+	b.WriteString("\n\n")
+
+	width := 24
+	if m.width > 0 && len(m.columns) > 0 {
+		width = max(12, m.width/len(m.columns)-1)
+	}
+	rows := len(m.items)
+	if m.height > 6 {
+		rows = m.height - 6
+	}
+	blocks := make([]string, 0, len(m.columns))
+	for i, col := range m.columns {
+		header := clip(fmt.Sprintf("%s (%d)", col.Name, len(col.Items)), width)
+		if i == m.column {
+			header = columnStyle.Render(header)
+		}
+		lines := []string{header}
+		// the todos around the selected one, when they don't fit
+		first, last := 0, min(len(col.Items), rows)
+		if i == m.column && len(col.Items) > rows {
+			first = max(0, min(m.cursor-rows/2, len(col.Items)-rows))
+			last = first + rows
+		}
+		for j := first; j < last; j++ {
+			line := clip(fmt.Sprintf("%s %s", col.Items[j].ID, col.Items[j].Todo.Title), width)
+			if i == m.column && j == m.cursor {
+				line = selectedStyle.Render(line)
+			}
+			lines = append(lines, line)
+		}
+		blocks = append(blocks, lipgloss.NewStyle().Width(width+1).Render(strings.Join(lines, "\n")))
+	}
+	b.WriteString(lipgloss.JoinHorizontal(lipgloss.Top, blocks...))
+	b.WriteString("\n\n")
+	m.viewFooter(&b, tuiBoardHelp)
+	return b.String()
+}
+
+// viewFooter renders the prompt, the error or else the help of the keys
+func (m *tui) viewFooter(b *strings.Builder, help string) {
+	switch {
+	case m.mode == modeRemove:
+		item, _ := m.selected()
+		fmt.Fprintf(b, "Remove %s %q? (y/n)", item.ID, item.Todo.Title)
+	case m.mode != modeBrowse:
+		b.WriteString(m.input.View())
+	case m.err != nil:
+		fmt.Fprintf(b, "Error: %v", m.err)
+	default:
+		b.WriteString(help)
+	}
+}
+
+// clip cuts the text to n characters, ending with an ellipsis if cut
+func clip(s string, n int) string {
+	if r := []rune(s); len(r) > n {
+		return string(r[:n-1]) + "…"
+	}
+	return s
+}
+
+// tuiLine returns the line of the todo in the list
+func tuiLine(item ledger.Item) string {
+	line := fmt.Sprintf("%-4s %-10s %s", item.ID, item.Todo.Status, item.Todo.Title)
+	if item.Todo.Assignee != "" {
+		line += " @" + item.Todo.Assignee
+	}
+	if item.Task != nil {
+		for _, tag := range item.Task.Tags {
+			line += " #" + tag
+		}
+		if n := item.Task.Pomodoros(time.Time{}, time.Time{}); n > 0 {
+			line += fmt.Sprintf(" 🍅%d", n)
+		}
+	}
+	return line
+}
@@ -0,0 +1,227 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/gotestbootcamp/go-todo-app/ledger"
+	"github.com/gotestbootcamp/go-todo-app/model"
+	"github.com/gotestbootcamp/go-todo-app/store"
+	"github.com/gotestbootcamp/go-todo-app/task"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestTUI(t *testing.T) *tui {
+	t.Helper()
+	ld, ids, err := open(globals{dataDir: t.TempDir()})
+	require.NoError(t, err)
+	t.Cleanup(func() { ld.Close() })
+	m := newTUI(context.Background(), &App{Ledger: ld, IDs: ids, Actor: "ann"})
+	step(m, m.load())
+	return m
+}
+
+// step runs the command, and the ones following from it, updating the model with their messages
+func step(m *tui, cmd tea.Cmd) {
+	if cmd == nil {
+		return
+	}
+	switch msg := cmd().(type) {
+	case nil:
+	case tea.BatchMsg:
+		for _, cmd := range msg {
+			step(m, cmd)
+		}
+	default:
+		_, cmd = m.Update(msg)
+		step(m, cmd)
+	}
+}
+
+// press types the keys, either named like "enter" or as text, one rune at a time
+func press(m *tui, keys ...string) {
+	named := map[string]tea.KeyType{"enter": tea.KeyEnter, "esc": tea.KeyEsc, "up": tea.KeyUp, "down": tea.KeyDown}
+	for _, key := range keys {
+		var msgs []tea.KeyMsg
+		if typ, ok := named[key]; ok {
+			msgs = append(msgs, tea.KeyMsg{Type: typ})
+		} else {
+			for _, r := range key {
+				msgs = append(msgs, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+			}
+		}
+		for _, msg := range msgs {
+			_, cmd := m.Update(msg)
+			step(m, cmd)
+		}
+	}
+}
+
+func titles(m *tui) []string {
+	var titles []string
+	for _, item := range m.items {
+		titles = append(titles, item.Todo.Title)
+	}
+	return titles
+}
+
+func TestTUI(t *testing.T) {
+	m := newTestTUI(t)
+	assert.Contains(t, m.View(), "No todos.")
+
+	press(m, "a", "buy milk", "enter", "a", "walk dog", "enter", "a", "nope", "esc")
+	require.Equal(t, []string{"buy milk", "walk dog"}, titles(m))
+	assert.Equal(t, 0, m.cursor)
+
+	press(m, "down", "e", " twice", "enter")
+	assert.Equal(t, []string{"buy milk", "walk dog twice"}, titles(m))
+	assert.Equal(t, 1, m.cursor)
+
+	press(m, "up", "d")
+	require.NoError(t, m.err)
+	assert.Equal(t, task.Completed, m.items[0].Task.Status)
+	assert.Equal(t, "ann", m.items[0].Todo.Assignee)
+
+	press(m, "s")
+	assert.Equal(t, task.Pending, m.status)
+	assert.Equal(t, []string{"walk dog twice"}, titles(m))
+	assert.Equal(t, 0, m.cursor, "on the first todo, when the one selected is gone")
+	press(m, "s", "s", "s")
+	assert.Empty(t, m.status, "back to any status")
+
+	press(m, "x", "n")
+	assert.Len(t, m.items, 2, "not removed without confirmation")
+	press(m, "x")
+	assert.Contains(t, m.View(), `Remove 1 "buy milk"? (y/n)`)
+	press(m, "y")
+	assert.Equal(t, []string{"walk dog twice"}, titles(m))
+}
+
+func TestTUITagFilter(t *testing.T) {
+	m := newTestTUI(t)
+	_, _, err := m.app.Ledger.SetIf("1", model.New("buy milk"), 0)
+	require.NoError(t, err)
+	tags := []string{"home"}
+	_, _, err = m.app.Ledger.PatchIf("1", ledger.Patch{Tags: &tags}, ledger.AnyRevision)
+	require.NoError(t, err)
+	_, _, err = m.app.Ledger.SetIf("2", model.New("walk dog"), 0)
+	require.NoError(t, err)
+
+	press(m, "t", "home", "enter")
+	assert.Equal(t, []string{"buy milk"}, titles(m))
+	assert.Contains(t, m.View(), "buy milk #home")
+	assert.Contains(t, m.View(), "tag: home")
+	press(m, "t", "enter")
+	assert.Len(t, m.items, 2, "no tag typed, any tag")
+}
+
+func TestTUISavedFilter(t *testing.T) {
+	m := newTestTUI(t)
+	_, _, err := m.app.Ledger.SetIf("1", model.New("buy milk"), 0)
+	require.NoError(t, err)
+	_, _, err = m.app.Ledger.SetIf("2", model.New("walk dog"), 0)
+	require.NoError(t, err)
+	_, err = m.app.Ledger.CreateFilter(ledger.SavedFilter{Name: "errands", Query: "buy OR groceries"})
+	require.NoError(t, err)
+
+	press(m, "f")
+	assert.Equal(t, []string{"buy milk"}, titles(m))
+	assert.Contains(t, m.View(), "filter: errands")
+	press(m, "f")
+	assert.Len(t, m.items, 2, "no filter")
+}
+
+func TestTUIBoard(t *testing.T) {
+	m := newTestTUI(t)
+	press(m, "a", "buy milk", "enter", "a", "walk dog", "enter")
+
+	press(m, "b")
+	assert.Equal(t, "status", m.board)
+	assert.Equal(t, []string{"buy milk", "walk dog"}, titles(m))
+	assert.Contains(t, m.View(), "pending (2)")
+
+	press(m, "down", "K")
+	assert.Equal(t, []string{"walk dog", "buy milk"}, titles(m))
+	assert.Equal(t, 0, m.cursor)
+	press(m, ">")
+	require.NoError(t, m.err)
+	assert.Equal(t, 1, m.column)
+	assert.Equal(t, []string{"walk dog"}, titles(m))
+	press(m, "h")
+	assert.Equal(t, []string{"buy milk"}, titles(m))
+	press(m, "l", ">")
+	require.NoError(t, m.err)
+	assert.Equal(t, 2, m.column)
+	assert.Equal(t, []string{"walk dog"}, titles(m))
+	// the workflow doesn't allow reopening the completed todos
+	press(m, "<")
+	assert.Error(t, m.err)
+	assert.Equal(t, 2, m.column)
+
+	press(m, "b")
+	assert.Equal(t, "priority", m.board)
+	assert.Contains(t, m.View(), "none (1)")
+	press(m, "b")
+	assert.Equal(t, "", m.board)
+	assert.Len(t, m.items, 2)
+}
+
+func TestTUIWatch(t *testing.T) {
+	m := newTestTUI(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	m.ctx = ctx
+
+	_, wait := m.Update(m.watch()())
+	require.NotNil(t, m.events)
+	_, _, err := m.app.Ledger.SetIf(store.ID("1"), model.New("buy milk"), 0)
+	require.NoError(t, err)
+
+	// the todos are listed again, and the next change waited for
+	_, cmd := m.Update(wait())
+	batch, ok := cmd().(tea.BatchMsg)
+	require.True(t, ok)
+	require.Len(t, batch, 2)
+	step(m, batch[0])
+	assert.Equal(t, []string{"buy milk"}, titles(m))
+
+	cancel()
+	_, cmd = m.Update(batch[1]())
+	assert.Nil(t, cmd, "no watch again when done")
+}
+
+func TestTUIPoll(t *testing.T) {
+	dir := t.TempDir()
+	ld, ids, err := open(globals{dataDir: dir})
+	require.NoError(t, err)
+	t.Cleanup(func() { ld.Close() })
+	m := newTUI(context.Background(), &App{Ledger: ld, IDs: ids, Actor: "ann"})
+	m.interval = time.Hour
+	step(m, m.load())
+
+	// another process changes the store
+	other, _, err := open(globals{dataDir: dir})
+	require.NoError(t, err)
+	t.Cleanup(func() { other.Close() })
+	_, _, err = other.SetIf(store.ID("1"), model.New("buy milk"), 0)
+	require.NoError(t, err)
+
+	_, cmd := m.Update(pollMsg{})
+	_, cmd = m.Update(cmd())
+	batch, ok := cmd().(tea.BatchMsg)
+	require.True(t, ok)
+	require.Len(t, batch, 2, "the todos listed, and polled again")
+	step(m, batch[0])
+	require.NoError(t, m.err)
+	assert.Equal(t, []string{"buy milk"}, titles(m))
+
+	// the error of an action stays shown across the polls
+	failed := errors.New("can't complete")
+	m.Update(doneMsg{err: failed})
+	_, cmd = m.Update(pollMsg{})
+	m.Update(cmd())
+	assert.ErrorIs(t, m.err, failed)
+}
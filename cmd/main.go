@@ -1,47 +1,911 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"expvar"
+	"fmt"
 	"log"
+	"log/slog"
+	"net"
 	"net/http"
 	"os"
+	"os/signal"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
 
+	"github.com/gotestbootcamp/go-todo-app/auth"
+	"github.com/gotestbootcamp/go-todo-app/auth/oidc"
+	"github.com/gotestbootcamp/go-todo-app/caldav"
+	"github.com/gotestbootcamp/go-todo-app/cli"
 	"github.com/gotestbootcamp/go-todo-app/config"
 	"github.com/gotestbootcamp/go-todo-app/controller"
+	"github.com/gotestbootcamp/go-todo-app/index"
+	"github.com/gotestbootcamp/go-todo-app/jobs"
 	"github.com/gotestbootcamp/go-todo-app/ledger"
+	ledgermetrics "github.com/gotestbootcamp/go-todo-app/ledger/metrics"
+	"github.com/gotestbootcamp/go-todo-app/logging"
+	"github.com/gotestbootcamp/go-todo-app/mail"
+	"github.com/gotestbootcamp/go-todo-app/middleware"
+	"github.com/gotestbootcamp/go-todo-app/model"
+	"github.com/gotestbootcamp/go-todo-app/notify"
+	"github.com/gotestbootcamp/go-todo-app/rpc"
+	"github.com/gotestbootcamp/go-todo-app/server"
 	"github.com/gotestbootcamp/go-todo-app/store"
-	"github.com/gotestbootcamp/go-todo-app/store/fake"
+	"github.com/gotestbootcamp/go-todo-app/store/metrics"
+	"github.com/gotestbootcamp/go-todo-app/task"
+	"github.com/gotestbootcamp/go-todo-app/tracing"
+	"github.com/gotestbootcamp/go-todo-app/user"
+	"github.com/gotestbootcamp/go-todo-app/webhook"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 )
 
 func main() {
-	cfg, err := config.FromFlags(os.Args[1:]...)
+	args := os.Args[1:]
+	// serving is the default command, the other ones manage a local store
+	if len(args) > 0 && args[0] == "serve" {
+		args = args[1:]
+	} else if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		// only the warnings of the ledger, not to clutter the output
+		logger, _ := logging.New(os.Stderr, "warn", "text")
+		slog.SetDefault(logger)
+		os.Exit(cli.Run(args, os.Stdin, os.Stdout, os.Stderr))
+	}
+	cfg, err := config.FromFlags(args...)
 	if err != nil {
 		log.Printf("error parsing flags: %v", err)
 		os.Exit(0)
 	}
+	logger, err := logging.New(os.Stderr, cfg.Log.Level, cfg.Log.Format)
+	if err != nil {
+		log.Fatalf("error setting up the logging: %v", err)
+	}
+	// the legacy log calls go through the logger too
+	slog.SetDefault(logger)
 	log.Printf("ready: configuration:\n%s", cfg.String())
 
+	// the tracing starts first, to trace the loading of the store too
+	shutdownTracing := func(context.Context) error { return nil }
+	if cfg.Tracing.Endpoint != "" {
+		shutdownTracing, err = tracing.Setup(context.Background(), tracing.Options{
+			Endpoint:    cfg.Tracing.Endpoint,
+			ServiceName: cfg.Tracing.ServiceName,
+			SampleRatio: cfg.Tracing.SampleRatio,
+		})
+		if err != nil {
+			log.Fatalf("error setting up the tracing: %v", err)
+		}
+		log.Printf("ready: tracing to %q", cfg.Tracing.Endpoint)
+	}
+
 	var st store.Storage
-	if cfg.Redis.URL != "" {
-		log.Printf("store: using backend \"redis\"")
-		st, err = store.NewRedis(cfg.Redis.URL, cfg.Redis.Password, cfg.Redis.Database)
+	var kind string
+	if cfg.Store != "" {
+		log.Printf("store: using storage %q", cfg.Store)
+		kind, _, _ = strings.Cut(cfg.Store, "://")
+		st, err = store.Open(cfg.Store)
+	} else if cfg.Redis.URL != "" {
+		kind = "redis"
+		st, err = newRedis(cfg.Redis)
+	} else if cfg.SQLite.Path != "" {
+		kind = "sqlite"
+		st, err = store.NewSQLite(cfg.SQLite.Path)
+	} else if cfg.Bolt.Path != "" {
+		kind = "bolt"
+		st, err = store.NewBolt(cfg.Bolt.Path)
+	} else if cfg.Postgres.URL != "" {
+		kind = "postgres"
+		st, err = store.NewPostgres(cfg.Postgres.URL)
+	} else if cfg.S3.Endpoint != "" {
+		kind = "s3"
+		st, err = store.NewS3(store.S3Options{
+			Endpoint:  cfg.S3.Endpoint,
+			AccessKey: cfg.S3.AccessKey,
+			SecretKey: cfg.S3.SecretKey,
+			Region:    cfg.S3.Region,
+			Bucket:    cfg.S3.Bucket,
+			Prefix:    cfg.S3.Prefix,
+			Insecure:  cfg.S3.Insecure,
+		})
 	} else {
-		log.Printf("store: using backend \"fake\"")
-		st, err = fake.NewMem()
+		kind = "memory"
+		st, err = store.NewMemory()
 	}
+	log.Printf("store: using backend %q", kind)
 	if err != nil {
-		log.Printf("error creating store backend: %v", err)
+		log.Fatalf("error creating store backend: %v", err)
+	}
+	// the ids come from the backend itself, which the decorators hide
+	backend := st
+	var metricsPath string
+	var metricsHandler http.Handler
+	if cfg.Metrics != "" {
+		var rec store.Recorder
+		rec, metricsPath, metricsHandler, err = newMetrics(cfg.Metrics)
+		if err != nil {
+			log.Fatalf("error setting up the metrics: %v", err)
+		}
+		log.Printf("store: metrics enabled (%s)", cfg.Metrics)
+		st = store.NewInstrumented(st, rec)
+	}
+	if cfg.EncryptionPassphraseFile != "" {
+		st, err = newEncrypted(st, cfg.EncryptionPassphraseFile)
+		if err != nil {
+			log.Fatalf("error setting up the encryption: %v", err)
+		}
+		log.Printf("store: encryption enabled")
+	}
+	if cfg.Compress {
+		log.Printf("store: compression enabled")
+		st = store.NewCompressed(st)
+	}
+	if cfg.CacheSize > 0 {
+		log.Printf("store: caching up to %d objects", cfg.CacheSize)
+		st = store.NewCached(st, cfg.CacheSize)
+	}
+	// the damaged objects are quarantined below the trash, not to be restored from it
+	untrashed := st
+	var trash *store.Trash
+	if cfg.Trash {
+		log.Printf("store: trash enabled")
+		trash = store.NewTrash(st)
+		trash.Retention = cfg.TrashRetention
+		st = trash
+	}
+	// the changes are watched above the decorators transforming the stored objects
+	st = store.NewNotifier(st)
+	if cfg.Verify || cfg.Repair {
+		if err := verifyStore(untrashed, cfg.Repair); err != nil {
+			log.Fatalf("error verifying store: %v", err)
+		}
+	}
+	if cfg.Migrate {
+		rep, err := task.Migrate(st)
+		if err != nil {
+			log.Fatalf("error migrating store: %v", err)
+		}
+		log.Printf("store: checked %d objects, migrated %d", rep.Checked, len(rep.Migrated))
+		for id, err := range rep.Failed {
+			log.Printf("store: can't migrate object %q: %v", id, err)
+		}
+	}
+	if cfg.Compact {
+		count, err := store.Compact(st)
+		if err != nil {
+			log.Fatalf("error compacting store: %v", err)
+		}
+		log.Printf("store: compacted, %d objects rewritten or removed", count)
+	}
+	if cfg.Search {
+		ixd, err := index.NewIndexed(st, todoText)
+		if err != nil {
+			log.Fatalf("error indexing store: %v", err)
+		}
+		log.Printf("store: search enabled")
+		st = ixd
+	}
+	if len(cfg.IndexFields) > 0 {
+		fi := index.NewFieldIndexed(st)
+		for _, field := range cfg.IndexFields {
+			extract, ok := todoFields[field]
+			if !ok {
+				log.Fatalf("error indexing store: unknown field %q", field)
+			}
+			if err := fi.Register(field, extract); err != nil {
+				log.Fatalf("error indexing store: %v", err)
+			}
+		}
+		log.Printf("store: indexed fields %v", fi.Fields())
+		st = fi
 	}
 	log.Printf("ready: store backend")
 
-	ldg, err := ledger.New(st)
+	workflow := task.DefaultWorkflow()
+	if cfg.Workflow != "" {
+		workflow, err = task.ParseWorkflow(cfg.Workflow)
+		if err != nil {
+			log.Fatalf("error parsing the workflow: %v", err)
+		}
+	}
+	log.Printf("ledger: workflow %s", workflow)
+	ldg, err := ledger.NewWithWorkflow(st, workflow)
 	if err != nil {
-		log.Printf("error parsing flags: %v", err)
+		log.Fatalf("error loading the ledger: %v", err)
 	}
+	if cfg.Metrics != "" {
+		if err := newLedgerMetrics(cfg.Metrics, ldg); err != nil {
+			log.Fatalf("error setting up the ledger metrics: %v", err)
+		}
+	}
+	ldg.OnUnblocked(func(item ledger.Item) {
+		slog.Info("unblocked", "id", item.ID, "title", item.Task.Title, "assignee", item.Task.Assignee)
+	})
 	log.Printf("ready: data ledger")
 
-	ctrl := controller.New(ldg)
+	interrupted, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-interrupted.Done()
+		// a second interrupt kills the server, without waiting for the shutdown
+		stop()
+		log.Printf("shutting down, interrupt again to exit right away")
+	}()
+	// the servers and the scheduler run until interrupted, or until any server fails
+	ctx, cancel := context.WithCancel(interrupted)
+	defer cancel()
+
+	// background are the goroutines using the ledger, which must stop before it is closed
+	var background sync.WaitGroup
+
+	ids, err := store.NewIDGenerator(cfg.IDStrategy, backend)
+	if err != nil {
+		log.Fatalf("error setting up the id generation: %v", err)
+	}
+	log.Printf("store: %s ids", cfg.IDStrategy)
+	ldg.SetIDGenerator(ids)
+	ldg.SetChangeLogPolicy(ledger.ChangeLogPolicy{MaxEvents: cfg.Jobs.ChangesMaxEvents, MaxAge: cfg.Jobs.ChangesMaxAge})
+	var au *auth.Authenticator
+	var users *user.Directory
+	if cfg.Auth.Enabled() {
+		au, err = newAuthenticator(cfg.Auth, st)
+		if err != nil {
+			log.Fatalf("error setting up the authentication: %v", err)
+		}
+		log.Printf("auth: %d API keys, tokens valid up to %v", len(au.Keys.List()), au.TokenTTL)
+		users, err = user.NewDirectory(st)
+		if err != nil {
+			log.Fatalf("error loading the users: %v", err)
+		}
+		if err := setupOIDC(ctx, au, cfg.Auth, users); err != nil {
+			log.Fatalf("error setting up the OIDC providers: %v", err)
+		}
+	} else {
+		log.Printf("auth: WARNING: authentication disabled, the API is open to anyone")
+	}
+	var mailer *mail.Mailer
+	if cfg.Mail.SMTPURL != "" {
+		mailer, err = newMailer(cfg.Mail, ldg, users)
+		if err != nil {
+			log.Fatalf("error setting up the emails: %v", err)
+		}
+		var digest *mail.Schedule
+		if cfg.Mail.Digest != "" {
+			sc, err := mail.ParseSchedule(cfg.Mail.Digest)
+			if err != nil {
+				log.Fatalf("error setting up the emails: %v", err)
+			}
+			digest = &sc
+			log.Printf("ready: %s digests to %q", sc, mailer.To)
+		}
+		background.Add(1)
+		go func() {
+			defer background.Done()
+			mailer.Run(ctx, webhook.NewWatcher(ldg), digest)
+		}()
+	}
+	if cfg.ReminderInterval > 0 {
+		sched := ledger.NewScheduler(ldg, cfg.ReminderInterval, func(item ledger.Item) {
+			slog.Info("reminder", "id", item.ID, "title", item.Task.Title, "assignee", item.Task.Assignee, "due", item.Task.Due)
+			if mailer != nil {
+				if err := mailer.Remind(ctx, item, false); err != nil {
+					log.Printf("error mailing the reminder: %v", err)
+				}
+			}
+		})
+		background.Add(1)
+		go func() {
+			defer background.Done()
+			sched.Run(ctx)
+		}()
+		log.Printf("ready: reminders every %v", cfg.ReminderInterval)
+	}
+	scheduler, err := newScheduler(cfg, st, trash, ldg, mailer)
+	if err != nil {
+		log.Fatalf("error scheduling the jobs: %v", err)
+	}
+	background.Add(1)
+	go func() {
+		defer background.Done()
+		scheduler.Run(ctx)
+	}()
+	ctrl := controller.NewWithAuth(ldg, ids, au, users)
+	if cfg.Metrics != "" {
+		rec, err := newRequestMetrics(cfg.Metrics)
+		if err != nil {
+			log.Fatalf("error setting up the request metrics: %v", err)
+		}
+		ctrl.Instrument(rec)
+	}
+	if cfg.Tracing.Endpoint != "" {
+		ctrl.Trace()
+	}
 	log.Printf("ready: controller")
 
+	hooks, err := webhook.NewDispatcher(st)
+	if err != nil {
+		log.Fatalf("error loading the webhooks: %v", err)
+	}
+	ctrl.ServeWebhooks(hooks)
+	background.Add(1)
+	go func() {
+		defer background.Done()
+		if err := hooks.Run(ctx, webhook.NewWatcher(ldg)); err != nil {
+			log.Printf("webhooks: WARNING: the events are not delivered: %v", err)
+		}
+	}()
+	log.Printf("ready: %d webhooks", len(hooks.List()))
+
+	notifiers, err := notify.NewNotifiers(st)
+	if err != nil {
+		log.Fatalf("error loading the notifiers: %v", err)
+	}
+	if cfg.NotifiersFile != "" {
+		if err := notifiers.LoadFile(cfg.NotifiersFile); err != nil {
+			log.Fatalf("error loading the notifiers: %v", err)
+		}
+	}
+	ctrl.ServeNotifiers(notifiers)
+	background.Add(1)
+	go func() {
+		defer background.Done()
+		if err := notifiers.Run(ctx, webhook.NewWatcher(ldg)); err != nil {
+			log.Printf("notify: WARNING: the events are not posted: %v", err)
+		}
+	}()
+	log.Printf("ready: %d notifiers", len(notifiers.List()))
+	ctrl.ServeJobs(scheduler)
+
+	var api, dav http.Handler = ctrl, middleware.Logger(caldav.New(ldg, au, users), "caldav")
+	// the clients using the store remotely change it under the ledger, which reloads the items they
+	// change, and take the IDs of the todos they create from the server. They can read the API keys
+	// too: only the administrators can.
+	storeHandler := store.NewHandler(st)
+	storeHandler.IDs = ids
+	storeHandler.OnChange = func(id store.ID) {
+		if err := ldg.ReloadItem(id); err != nil {
+			log.Printf("error reloading the ledger: %v", err)
+		}
+	}
+	var remote http.Handler = http.StripPrefix(strings.TrimSuffix(store.RemotePath, "/"), middleware.Logger(storeHandler, "store"))
+	if au != nil {
+		remote = au.Require(auth.ScopeAdmin, remote)
+	}
+	if cfg.IdempotencyTTL > 0 {
+		idem, err := middleware.NewIdempotency(st, cfg.IdempotencyTTL)
+		if err != nil {
+			log.Fatalf("error loading the idempotent responses: %v", err)
+		}
+		idem.Key = clientKey(cfg.RateLimit.TrustProxy, au)
+		api = idem.Handle(api)
+		log.Printf("ready: idempotency keys, kept for %v", idem.TTL)
+	}
+	if cfg.RateLimit.Rate > 0 {
+		limiter := newRateLimiter(cfg.RateLimit, au)
+		if cfg.Metrics != "" {
+			if limiter.Recorder, err = newLimitMetrics(cfg.Metrics); err != nil {
+				log.Fatalf("error setting up the rate limiting metrics: %v", err)
+			}
+		}
+		api, dav, remote = limiter.Limit(api), limiter.Limit(dav), limiter.Limit(remote)
+		log.Printf("ready: rate limit %v/s per client, burst %d", limiter.Rate, limiter.Burst)
+	}
+
+	tlsCfg, redirect, err := newTLS(cfg.TLS, cfg.Address)
+	if err != nil {
+		log.Fatalf("error setting up TLS: %v", err)
+	}
+
+	// the probes and the metrics are neither authenticated nor rate limited
+	health := server.NewHealth(kind, task.SchemaVersion, func(ctx context.Context) error {
+		return store.Ping(ctx, backend)
+	})
+	mux := http.NewServeMux()
+	mux.Handle("/", api)
+	mux.Handle(caldav.Prefix, dav)
+	mux.Handle(caldav.WellKnownPath, dav)
+	mux.Handle(store.RemotePath, remote)
+	probes := health.Handler()
+	for _, path := range []string{"/livez", "/healthz", "/readyz"} {
+		mux.Handle(path, probes)
+	}
+	if metricsHandler != nil {
+		mux.Handle(metricsPath, metricsHandler)
+	}
+
 	log.Printf("start serving on address %q", cfg.Address)
-	log.Fatal(http.ListenAndServe(cfg.Address, ctrl))
+	srv := server.New(cfg.Address, middleware.RequestID(mux))
+	srv.ShutdownTimeout = cfg.ShutdownTimeout
+	srv.Health = health
+	srv.DrainDelay = cfg.DrainDelay
+	srv.TLS = tlsCfg
+	errc := make(chan error, 3)
+	go func() {
+		errc <- srv.Run(ctx)
+	}()
+	running := 1
+	if redirect != nil {
+		redirectSrv := server.New(cfg.TLS.RedirectAddress, redirect)
+		redirectSrv.ShutdownTimeout = cfg.ShutdownTimeout
+		go func() {
+			errc <- redirectSrv.Run(ctx)
+		}()
+		running++
+		log.Printf("start redirecting to HTTPS on address %q", cfg.TLS.RedirectAddress)
+	}
+	if cfg.GRPCAddress != "" {
+		var opts []grpc.ServerOption
+		if tlsCfg != nil {
+			opts = append(opts, grpc.Creds(credentials.NewTLS(tlsCfg)))
+		}
+		unary := []grpc.UnaryServerInterceptor{rpc.UnaryLogger}
+		// the watches end on shutdown, like the event streams
+		stream := []grpc.StreamServerInterceptor{rpc.StreamLogger, server.EndStreams(ctx)}
+		if au != nil {
+			unary = append(unary, au.UnaryInterceptor(rpc.RequiredScope))
+			stream = append(stream, au.StreamInterceptor(rpc.RequiredScope))
+		}
+		opts = append(opts, grpc.ChainUnaryInterceptor(unary...), grpc.ChainStreamInterceptor(stream...))
+		if cfg.Tracing.Endpoint != "" {
+			opts = append(opts, grpc.StatsHandler(otelgrpc.NewServerHandler()))
+		}
+		gsrv := grpc.NewServer(opts...)
+		svc := rpc.New(ldg, ids)
+		svc.SetUsers(users)
+		svc.Register(gsrv)
+		grpcSrv := server.NewGRPC(cfg.GRPCAddress, gsrv)
+		grpcSrv.ShutdownTimeout = cfg.ShutdownTimeout
+		go func() {
+			errc <- grpcSrv.Run(ctx)
+		}()
+		running++
+		log.Printf("start serving gRPC on address %q", cfg.GRPCAddress)
+	}
+	var serveErr error
+	for ; running > 0; running-- {
+		if err := <-errc; err != nil && serveErr == nil {
+			serveErr = err
+			cancel()
+		}
+	}
+	// the ledger is closed even if serving failed, releasing the store, e.g. its redis lease
+	background.Wait()
+	if err := ldg.Close(); err != nil {
+		log.Printf("error closing the ledger: %v", err)
+	}
+	flushCtx, cancelFlush := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancelFlush()
+	if err := shutdownTracing(flushCtx); err != nil {
+		log.Printf("error flushing the traces: %v", err)
+	}
+	if serveErr != nil {
+		log.Fatalf("error serving: %v", serveErr)
+	}
+	log.Printf("bye")
+}
+
+// newMetrics returns the recorder for the store metrics, and the HTTP handler exposing them along with its path
+func newMetrics(kind string) (store.Recorder, string, http.Handler, error) {
+	switch kind {
+	case "prometheus":
+		rec, err := metrics.NewPrometheus(prometheus.DefaultRegisterer, "todo")
+		return rec, "/metrics", promhttp.Handler(), err
+	case "expvar":
+		return metrics.NewExpvar("store"), "/debug/vars", expvar.Handler(), nil
+	default:
+		return nil, "", nil, fmt.Errorf("unknown metrics kind %q", kind)
+	}
+}
+
+// newTLS returns the TLS configuration of the server listening on addr, nil to serve over plain
+// HTTP, along with the handler of the plain HTTP requests, redirecting them to HTTPS and answering
+// the ACME challenges, nil if there's no redirect address
+func newTLS(cfg config.TLSConfig, addr string) (*tls.Config, http.Handler, error) {
+	if !cfg.Enabled() {
+		if cfg.RedirectAddress != "" {
+			return nil, nil, errors.New("the redirection to HTTPS needs a certificate, see --tls-cert, --tls-autocert or --tls-self-signed")
+		}
+		log.Printf("tls: disabled, serving over plain HTTP")
+		return nil, nil, nil
+	}
+	sources := 0
+	for _, set := range []bool{cfg.CertFile != "" || cfg.KeyFile != "", len(cfg.AutocertDomains) > 0, cfg.SelfSigned} {
+		if set {
+			sources++
+		}
+	}
+	if sources > 1 {
+		return nil, nil, errors.New("the certificate comes from only one of --tls-cert, --tls-autocert and --tls-self-signed")
+	}
+
+	var redirect http.Handler
+	if cfg.RedirectAddress != "" {
+		redirect = server.RedirectHTTPS(addr)
+	}
+	switch {
+	case len(cfg.AutocertDomains) > 0:
+		tlsCfg, challenges := server.AutocertTLS(cfg.AutocertDomains, cfg.AutocertCache, cfg.AutocertEmail, redirect)
+		if cfg.RedirectAddress != "" {
+			redirect = challenges
+		}
+		log.Printf("tls: certificates of %q from Let's Encrypt, cached in %q", cfg.AutocertDomains, cfg.AutocertCache)
+		return tlsCfg, redirect, nil
+	case cfg.SelfSigned:
+		hosts := []string{"localhost", "127.0.0.1", "::1"}
+		if host, _, _ := net.SplitHostPort(addr); host != "" && !slices.Contains(hosts, host) {
+			hosts = append(hosts, host)
+		}
+		tlsCfg, err := server.SelfSignedTLS(hosts...)
+		if err != nil {
+			return nil, nil, err
+		}
+		log.Printf("tls: WARNING: self-signed certificate for %q, for the development only", hosts)
+		return tlsCfg, redirect, nil
+	default:
+		if cfg.CertFile == "" || cfg.KeyFile == "" {
+			return nil, nil, errors.New("the certificate needs both --tls-cert and --tls-key")
+		}
+		tlsCfg, err := server.LoadTLS(cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			return nil, nil, err
+		}
+		log.Printf("tls: certificate %q", cfg.CertFile)
+		return tlsCfg, redirect, nil
+	}
+}
+
+// newRequestMetrics returns the recorder for the request metrics, exposed along with the store ones
+func newRequestMetrics(kind string) (middleware.RequestRecorder, error) {
+	switch kind {
+	case "prometheus":
+		return middleware.NewPrometheusRequests(prometheus.DefaultRegisterer, "todo")
+	case "expvar":
+		return middleware.NewExpvarRequests("http"), nil
+	default:
+		return nil, fmt.Errorf("unknown metrics kind %q", kind)
+	}
+}
+
+// newLedgerMetrics publishes the metrics of the ledger, the todos by status and the lock
+// contention, along with the store ones
+func newLedgerMetrics(kind string, ld *ledger.Ledger) error {
+	switch kind {
+	case "prometheus":
+		_, err := ledgermetrics.NewPrometheus(prometheus.DefaultRegisterer, "todo", ld)
+		return err
+	case "expvar":
+		ledgermetrics.PublishExpvar("ledger", ld)
+		return nil
+	default:
+		return fmt.Errorf("unknown metrics kind %q", kind)
+	}
+}
+
+// newLimitMetrics returns the recorder for the rate limiting metrics, exposed along with the store ones
+func newLimitMetrics(kind string) (middleware.LimitRecorder, error) {
+	switch kind {
+	case "prometheus":
+		return middleware.NewPrometheusLimits(prometheus.DefaultRegisterer, "todo")
+	case "expvar":
+		return middleware.NewExpvarLimits("ratelimit"), nil
+	default:
+		return nil, fmt.Errorf("unknown metrics kind %q", kind)
+	}
+}
+
+// newRateLimiter returns the limiter of the clients, see clientKey
+func newRateLimiter(cfg config.RateLimitConfig, au *auth.Authenticator) *middleware.RateLimiter {
+	limiter := middleware.NewRateLimiter(cfg.Rate, cfg.Burst)
+	limiter.Key = clientKey(cfg.TrustProxy, au)
+	return limiter
+}
+
+// clientKey identifies the clients by the API key their credentials come from, or by their user if
+// logged in, or else by their IP address
+func clientKey(trustProxy bool, au *auth.Authenticator) middleware.KeyFunc {
+	byIP := middleware.ClientIP(trustProxy)
+	return func(r *http.Request) (string, string) {
+		if au != nil {
+			if id, err := au.Authenticate(r); err == nil {
+				if id.KeyID != "" {
+					return id.KeyID, "key"
+				}
+				return id.Name, "user"
+			}
+		}
+		return byIP(r)
+	}
+}
+
+// newAuthenticator returns the authenticator of the static keys of the keys file, and of the keys
+// managed through the API, stored in st
+func newAuthenticator(cfg config.AuthConfig, st store.Storage) (*auth.Authenticator, error) {
+	keys, err := auth.NewKeyring(st)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.KeysFile != "" {
+		if err := keys.LoadFile(cfg.KeysFile); err != nil {
+			return nil, err
+		}
+	}
+	var secret []byte
+	if cfg.TokenSecretFile != "" {
+		data, err := os.ReadFile(cfg.TokenSecretFile)
+		if err != nil {
+			return nil, err
+		}
+		secret = bytes.TrimSpace(data)
+		if len(secret) < 16 {
+			return nil, fmt.Errorf("the token secret in %s is too short, want at least 16 bytes", cfg.TokenSecretFile)
+		}
+	} else if secret, err = auth.RandomSecret(); err != nil {
+		return nil, err
+	}
+	au := auth.NewAuthenticator(keys, auth.NewSigner(secret))
+	if cfg.TokenTTL > 0 {
+		au.TokenTTL = cfg.TokenTTL
+	}
+	return au, nil
+}
+
+// newEncrypted returns the storage encrypting the objects of st with the passphrase in the file, once
+// it encrypted the objects stored in clear, e.g. before the encryption was enabled
+func newEncrypted(st store.Storage, passphraseFile string) (*store.Encrypted, error) {
+	data, err := os.ReadFile(passphraseFile)
+	if err != nil {
+		return nil, err
+	}
+	enc, err := store.NewEncryptedFromPassphrase(st, string(bytes.TrimSpace(data)))
+	if err != nil {
+		return nil, err
+	}
+	count, err := enc.Reencrypt()
+	if err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		log.Printf("store: encrypted %d objects", count)
+	}
+	return enc, nil
+}
+
+// newMailer returns the mailer of the todos of the ledger, sending the emails through the SMTP server
+func newMailer(cfg config.MailConfig, ldg *ledger.Ledger, users *user.Directory) (*mail.Mailer, error) {
+	var password string
+	if cfg.PasswordFile != "" {
+		data, err := os.ReadFile(cfg.PasswordFile)
+		if err != nil {
+			return nil, err
+		}
+		password = string(bytes.TrimSpace(data))
+	}
+	tr, err := mail.NewSMTP(cfg.SMTPURL, password)
+	if err != nil {
+		return nil, err
+	}
+	mailer, err := mail.NewMailer(ldg, tr, cfg.From)
+	if err != nil {
+		return nil, err
+	}
+	mailer.To, mailer.Users = cfg.To, users
+	if cfg.TemplatesDir != "" {
+		if mailer.Templates, err = mail.LoadTemplates(cfg.TemplatesDir); err != nil {
+			return nil, err
+		}
+	}
+	log.Printf("ready: emails through %s", tr.Address)
+	return mailer, nil
+}
+
+// newScheduler returns the scheduler of the maintenance jobs of the configuration, on the store and
+// the ledger
+func newScheduler(cfg config.Config, st store.Storage, trash *store.Trash, ldg *ledger.Ledger, mailer *mail.Mailer) (*jobs.Scheduler, error) {
+	scheduler := jobs.NewScheduler()
+	for _, jc := range cfg.Jobs.Jobs {
+		schedule, err := jobs.ParseSchedule(jc.Schedule)
+		if err != nil {
+			return nil, fmt.Errorf("job %q: %w", jc.Name, err)
+		}
+		var fn jobs.Func
+		switch jc.Name {
+		case jobs.CompactJob:
+			fn = jobs.Compact(st)
+		case jobs.TrashPurgeJob:
+			if trash == nil || cfg.TrashRetention <= 0 {
+				return nil, fmt.Errorf("job %q: needs -trash and -trash-retention", jc.Name)
+			}
+			fn = jobs.PurgeTrash(trash, cfg.TrashRetention)
+		case jobs.ArchiveJob:
+			if cfg.Jobs.ArchiveAfter <= 0 {
+				return nil, fmt.Errorf("job %q: needs -archive-after", jc.Name)
+			}
+			fn = jobs.Archive(ldg, cfg.Jobs.ArchiveAfter)
+		case jobs.OverdueJob:
+			fn = jobs.Overdue(ldg, func(ctx context.Context, item ledger.Item) error {
+				log.Printf("OVERDUE: %v %q (assignee %q, due %v)", item.ID, item.Task.Title, item.Task.Assignee, item.Task.Due)
+				if mailer == nil {
+					return nil
+				}
+				return mailer.Remind(ctx, item, true)
+			})
+		case jobs.BackupJob:
+			if cfg.Jobs.BackupDir == "" {
+				return nil, fmt.Errorf("job %q: needs -backup-dir", jc.Name)
+			}
+			fn = jobs.Backup(st, cfg.Jobs.BackupDir, cfg.Jobs.BackupKeep)
+		case jobs.ChangelogJob:
+			policy := ledger.ChangeLogPolicy{
+				MaxEvents:    cfg.Jobs.ChangesMaxEvents,
+				MaxAge:       cfg.Jobs.ChangesMaxAge,
+				CompactAfter: cfg.Jobs.ChangesCompactAfter,
+			}
+			if policy == (ledger.ChangeLogPolicy{}) {
+				return nil, fmt.Errorf("job %q: needs -changes-max-events, -changes-max-age or -changes-compact-after", jc.Name)
+			}
+			fn = jobs.CompactChanges(ldg, policy)
+		default:
+			return nil, fmt.Errorf("unknown job %q, want one of %s", jc.Name, strings.Join(jobs.Builtin, ", "))
+		}
+		if err := scheduler.Register(jobs.Job{Name: jc.Name, Schedule: schedule, Jitter: jc.Jitter, Func: fn}); err != nil {
+			return nil, err
+		}
+		log.Printf("ready: job %s on %q", jc.Name, jc.Schedule)
+	}
+	return scheduler, nil
+}
+
+// setupOIDC adds to the authenticator the OpenID Connect providers, whose users log in as the users
+// of the directory with the same email address
+func setupOIDC(ctx context.Context, au *auth.Authenticator, cfg config.AuthConfig, users *user.Directory) error {
+	if len(cfg.Providers) == 0 {
+		return nil
+	}
+	if cfg.RedirectBase == "" {
+		return fmt.Errorf("the OIDC providers need the external URL of the server, see --oidc-redirect-base")
+	}
+	base := strings.TrimSuffix(cfg.RedirectBase, "/")
+	au.Providers = make(map[string]*oidc.Provider, len(cfg.Providers))
+	for _, pc := range cfg.Providers {
+		pcfg := oidc.Presets[pc.Name]
+		pcfg.ClientID = pc.ClientID
+		pcfg.RedirectURL = base + "/auth/oidc/" + pc.Name + "/callback"
+		if pc.Issuer != "" {
+			pcfg.Issuer = pc.Issuer
+		}
+		if pc.UserClaim != "" {
+			pcfg.UserClaim = pc.UserClaim
+		}
+		if pc.ClientSecretFile != "" {
+			data, err := os.ReadFile(pc.ClientSecretFile)
+			if err != nil {
+				return err
+			}
+			pcfg.ClientSecret = string(bytes.TrimSpace(data))
+		}
+		p, err := oidc.NewProvider(ctx, pc.Name, pcfg, nil)
+		if err != nil {
+			return err
+		}
+		au.Providers[pc.Name] = p
+		log.Printf("auth: users log in with %s on %s/auth/oidc/%s/login", pc.Name, base, pc.Name)
+	}
+	au.MapClaims = users.ClaimsMapper(cfg.AutoCreate)
+	au.CheckSession = users.SessionChecker()
+	au.SecureCookies = strings.HasPrefix(base, "https://")
+	if cfg.SessionTTL > 0 {
+		au.SessionTTL = cfg.SessionTTL
+	}
+	return nil
+}
+
+func newRedis(cfg config.RedisConfig) (*store.Redis, error) {
+	rd, err := store.NewRedis(cfg.URL, cfg.Password, cfg.Database)
+	if err != nil {
+		return nil, err
+	}
+	ctx := context.Background()
+	if cfg.Persistence != "" {
+		if err := rd.SetPersistence(ctx, store.RedisPersistence(cfg.Persistence)); err != nil {
+			rd.Close()
+			return nil, err
+		}
+	}
+	if cfg.LockLease > 0 {
+		if err := rd.Acquire(ctx, cfg.LockLease); err != nil {
+			rd.Close()
+			return nil, err
+		}
+	}
+	return rd, nil
+}
+
+// todoText returns the searchable text of a serialized todo
+func todoText(blob store.Blob) (string, error) {
+	todo, err := model.DeserializeTodo(blob)
+	if err != nil {
+		return "", err
+	}
+	return todo.Title + "\n" + todo.Description, nil
+}
+
+// todoFields are the fields of the serialized todos which can be indexed
+var todoFields = map[string]index.FieldExtractor{
+	"status": taskField(func(tk task.Task) []string {
+		return []string{string(tk.Status)}
+	}),
+	"assignee": taskField(func(tk task.Task) []string {
+		if tk.Assignee == "" {
+			return nil
+		}
+		return []string{tk.Assignee}
+	}),
+	"owner": taskField(func(tk task.Task) []string {
+		if tk.Owner == "" {
+			return nil
+		}
+		return []string{tk.Owner}
+	}),
+	"priority": taskField(func(tk task.Task) []string {
+		return []string{strconv.Itoa(int(tk.Priority))}
+	}),
+	"due": taskField(func(tk task.Task) []string {
+		if tk.Due == nil {
+			return nil
+		}
+		return []string{tk.Due.Format(time.DateOnly)}
+	}),
+	"tag": taskField(func(tk task.Task) []string {
+		return tk.Tags
+	}),
+	"series": taskField(func(tk task.Task) []string {
+		if tk.Series == "" {
+			return nil
+		}
+		return []string{tk.Series}
+	}),
+}
+
+// taskField returns a extractor of the given field of the serialized tasks
+func taskField(field func(task.Task) []string) index.FieldExtractor {
+	return func(blob store.Blob) ([]string, error) {
+		tk, err := task.Unmarshal(blob)
+		if err != nil {
+			return nil, err
+		}
+		return field(tk), nil
+	}
+}
+
+func verifyStore(st store.Storage, repair bool) error {
+	rep, err := store.Verify(st, store.VerifyOptions{
+		Parse: func(blob store.Blob) error {
+			_, err := model.DeserializeTodo(blob)
+			return err
+		},
+		Repair: repair,
+	})
+	if err != nil {
+		return err
+	}
+	log.Printf("store: verified %d objects, %d problems", rep.Checked, len(rep.Problems))
+	for _, problem := range rep.Problems {
+		log.Printf("store: problem: %s", problem.String())
+	}
+	if !repair && !rep.OK() {
+		return errors.New("store content is damaged, run with --repair to quarantine the damaged objects")
+	}
+	for _, id := range rep.Quarantined {
+		log.Printf("store: quarantined object %q", id)
+	}
+	return nil
 }
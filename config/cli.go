@@ -0,0 +1,209 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// CLISettings holds the tunables of the todo CLI a profile selects. Empty means unset.
+type CLISettings struct {
+	// DataDir is the directory holding the todos, in the todo.db SQLite database
+	DataDir string `yaml:"data-dir,omitempty"`
+	// Store is the URI of the storage, taking precedence over DataDir
+	Store string `yaml:"store,omitempty"`
+	// List is the list the todos are added to, and listed from
+	List string `yaml:"list,omitempty"`
+	// Context is the context the todos are added with, and listed from, like home
+	Context string `yaml:"context,omitempty"`
+	// Output is the output format of the commands, e.g. "text" or "json"
+	Output string `yaml:"output,omitempty"`
+	// Template prints the todos with the template output format
+	Template string `yaml:"template,omitempty"`
+}
+
+// CLISettingKeys are the names of the CLI settings, as in the configuration file, the environment
+// variables are named after them, e.g. TODO_DATA_DIR
+var CLISettingKeys = []string{"data-dir", "store", "list", "context", "output", "template"}
+
+// field returns the setting with the given key. Nil if unknown.
+func (cs *CLISettings) field(key string) *string {
+	switch key {
+	case "data-dir":
+		return &cs.DataDir
+	case "store":
+		return &cs.Store
+	case "list":
+		return &cs.List
+	case "context":
+		return &cs.Context
+	case "output":
+		return &cs.Output
+	case "template":
+		return &cs.Template
+	}
+	return nil
+}
+
+// Get returns the value of the setting with the given key, see CLISettingKeys
+func (cs CLISettings) Get(key string) (string, error) {
+	if f := cs.field(key); f != nil {
+		return *f, nil
+	}
+	return "", fmt.Errorf("unknown setting %q, want one of %s", key, strings.Join(CLISettingKeys, ", "))
+}
+
+// Set sets the value of the setting with the given key, see CLISettingKeys. Empty unsets it.
+func (cs *CLISettings) Set(key, value string) error {
+	f := cs.field(key)
+	if f == nil {
+		return fmt.Errorf("unknown setting %q, want one of %s", key, strings.Join(CLISettingKeys, ", "))
+	}
+	*f = value
+	return nil
+}
+
+// CLIConfig is the configuration file of the todo CLI: the settings of all the profiles, and the
+// profiles overriding them, e.g.
+//
+//	profile: work
+//	output: text
+//	profiles:
+//	  work:
+//	    data-dir: ~/work/todo
+//	    list: team
+//	  personal:
+//	    store: bolt:///home/me/todo.db
+type CLIConfig struct {
+	// Profile is the profile selected by default. Empty selects none.
+	Profile     string `yaml:"profile,omitempty"`
+	CLISettings `yaml:",inline"`
+	Profiles    map[string]CLISettings `yaml:"profiles,omitempty"`
+}
+
+// CLIConfigPath returns the path of the configuration file of the CLI: $TODO_CONFIG, or else
+// todo/config.yaml in $XDG_CONFIG_HOME, or in ~/.config
+func CLIConfigPath() string {
+	if path := os.Getenv("TODO_CONFIG"); path != "" {
+		return path
+	}
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "todo", "config.yaml")
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.Join(".config", "todo", "config.yaml")
+	}
+	return filepath.Join(home, ".config", "todo", "config.yaml")
+}
+
+// LoadCLIConfig reads the configuration file of the CLI. A missing file is an empty configuration.
+func LoadCLIConfig(path string) (CLIConfig, error) {
+	var cfg CLIConfig
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return cfg, nil
+	}
+	if err != nil {
+		return cfg, err
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&cfg); err != nil && !errors.Is(err, io.EOF) {
+		return cfg, fmt.Errorf("invalid configuration file %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// Save writes the configuration file of the CLI, creating its directory if needed
+func (cfg CLIConfig) Save(path string) error {
+	data, err := yaml.Marshal(cfg)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o600)
+}
+
+// ProfileNames returns the names of the profiles, sorted
+func (cfg CLIConfig) ProfileNames() []string {
+	names := make([]string, 0, len(cfg.Profiles))
+	for name := range cfg.Profiles {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// ResolvedCLI are the settings of the CLI for a profile, see CLIConfig.Resolve
+type ResolvedCLI struct {
+	// Profile is the profile selected, empty if none
+	Profile string
+	CLISettings
+	// Sources tell where each setting set, and the profile, comes from: "file", "profile <name>"
+	// or "env"
+	Sources map[string]string
+}
+
+// cliLayer are settings overriding the ones of the layers below
+type cliLayer struct {
+	source   string
+	settings CLISettings
+}
+
+// Resolve returns the settings of the profile, or else of the one $TODO_PROFILE or the file
+// selects. From the highest precedence, the settings come from:
+//   - the environment variables, e.g. TODO_DATA_DIR for data-dir
+//   - the profile
+//   - the file, outside of the profiles
+//
+// The flags of the CLI take precedence over all of them. The directories starting with ~/ are
+// in the home directory. Fails if the profile is unknown.
+func (cfg CLIConfig) Resolve(profile string, getenv func(string) string) (ResolvedCLI, error) {
+	res := ResolvedCLI{Profile: profile, Sources: make(map[string]string)}
+	if res.Profile == "" && getenv("TODO_PROFILE") != "" {
+		res.Profile, res.Sources["profile"] = getenv("TODO_PROFILE"), "env"
+	}
+	if res.Profile == "" && cfg.Profile != "" {
+		res.Profile, res.Sources["profile"] = cfg.Profile, "file"
+	}
+	profile = res.Profile
+	layers := []cliLayer{{"file", cfg.CLISettings}}
+	if profile != "" {
+		settings, ok := cfg.Profiles[profile]
+		if !ok {
+			return ResolvedCLI{}, fmt.Errorf("unknown profile %q, want one of %s", profile, strings.Join(cfg.ProfileNames(), ", "))
+		}
+		layers = append(layers, cliLayer{"profile " + profile, settings})
+	}
+	var env CLISettings
+	for _, key := range CLISettingKeys {
+		_ = env.Set(key, getenv("TODO_"+strings.ToUpper(strings.ReplaceAll(key, "-", "_"))))
+	}
+	layers = append(layers, cliLayer{"env", env})
+
+	for _, layer := range layers {
+		for _, key := range CLISettingKeys {
+			if v, _ := layer.settings.Get(key); v != "" {
+				_ = res.Set(key, v)
+				res.Sources[key] = layer.source
+			}
+		}
+	}
+	if rest, ok := strings.CutPrefix(res.DataDir, "~/"); ok {
+		if home, err := os.UserHomeDir(); err == nil {
+			res.DataDir = filepath.Join(home, rest)
+		}
+	}
+	return res, nil
+}
@@ -4,6 +4,7 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"strings"
 )
 
 // FromFlags creates a Config object out of the command line args
@@ -14,9 +15,100 @@ func FromFlags(args ...string) (Config, error) {
 
 	flags := flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
 	flags.StringVar(&conf.Address, "url", conf.Address, "url to listen to")
+	flags.StringVar(&conf.GRPCAddress, "grpc-url", conf.GRPCAddress, "url to serve the gRPC API on (default: disabled)")
+	flags.StringVar(&conf.Store, "store", conf.Store, "storage URI, e.g. memory://, sqlite:///path/to/todo.db (overrides the backend-specific flags)")
 	flags.StringVar(&conf.Redis.URL, "redis-url", conf.Redis.URL, "redis URL")
 	flags.StringVar(&conf.Redis.Password, "redis-password", conf.Redis.Password, "redis password")
 	flags.IntVar(&conf.Redis.Database, "redis-database", conf.Redis.Database, "redis database index")
+	flags.StringVar(&conf.Redis.Persistence, "redis-persistence", conf.Redis.Persistence, "redis server persistence: none, rdb or aof (default: leave unchanged)")
+	flags.DurationVar(&conf.Redis.LockLease, "redis-lock-lease", conf.Redis.LockLease, "own the redis storage exclusively, with the given lease (default: shared)")
+	flags.StringVar(&conf.SQLite.Path, "sqlite-path", conf.SQLite.Path, "sqlite database file path")
+	flags.StringVar(&conf.Bolt.Path, "bolt-path", conf.Bolt.Path, "bbolt database file path")
+	flags.StringVar(&conf.Postgres.URL, "postgres-url", conf.Postgres.URL, "postgres connection URL")
+	flags.StringVar(&conf.S3.Endpoint, "s3-endpoint", conf.S3.Endpoint, "S3 endpoint, as host[:port]")
+	flags.StringVar(&conf.S3.AccessKey, "s3-access-key", conf.S3.AccessKey, "S3 access key")
+	flags.StringVar(&conf.S3.SecretKey, "s3-secret-key", conf.S3.SecretKey, "S3 secret key")
+	flags.StringVar(&conf.S3.Region, "s3-region", conf.S3.Region, "S3 region")
+	flags.StringVar(&conf.S3.Bucket, "s3-bucket", conf.S3.Bucket, "S3 bucket")
+	flags.StringVar(&conf.S3.Prefix, "s3-prefix", conf.S3.Prefix, "S3 object key prefix")
+	flags.BoolVar(&conf.S3.Insecure, "s3-insecure", conf.S3.Insecure, "connect to S3 without TLS")
+	flags.BoolVar(&conf.Trash, "trash", conf.Trash, "move deleted objects in the trash instead of removing them")
+	flags.DurationVar(&conf.TrashRetention, "trash-retention", conf.TrashRetention, "how long the compaction keeps the deleted objects in the trash (default: forever)")
+	flags.BoolVar(&conf.Compress, "compress", conf.Compress, "compress the stored objects")
+	flags.StringVar(&conf.EncryptionPassphraseFile, "encryption-passphrase-file", conf.EncryptionPassphraseFile, "file holding the passphrase the stored objects are encrypted with, the ones stored in clear being encrypted on startup (default: stored in clear)")
+	flags.StringVar(&conf.Metrics, "metrics", conf.Metrics, "expose the metrics of the requests, the store and the ledger: prometheus (on /metrics) or expvar (on /debug/vars)")
+	flags.IntVar(&conf.CacheSize, "cache-size", conf.CacheSize, "how many objects to keep cached in memory (0 disables the cache)")
+	flags.BoolVar(&conf.Verify, "verify", conf.Verify, "check the integrity of the stored objects on startup")
+	flags.BoolVar(&conf.Repair, "repair", conf.Repair, "check the integrity of the stored objects on startup, and quarantine the damaged ones")
+	flags.BoolVar(&conf.Search, "search", conf.Search, "enable the full-text search of the objects, on /search")
+	flags.Func("index-fields", "comma-separated fields of the objects to index, on /find/{field}/{value}: status, assignee, owner, priority, due, tag, series", func(val string) error {
+		conf.IndexFields = strings.Split(val, ",")
+		return nil
+	})
+	flags.BoolVar(&conf.Migrate, "migrate", conf.Migrate, "rewrite on startup the objects stored with an older schema version")
+	flags.BoolVar(&conf.Compact, "compact", conf.Compact, "reclaim the garbage accumulated in the store on startup")
+	flags.StringVar(&conf.Workflow, "workflow", conf.Workflow, "statuses of the objects and transitions between them, e.g. \"todo>in-progress,done; in-progress>done\" (default: pending>assigned,deleted; assigned>completed,deleted)")
+	flags.DurationVar(&conf.ReminderInterval, "reminder-interval", conf.ReminderInterval, "how often to check the reminders of the objects (0 disables the reminders)")
+	flags.StringVar(&conf.NotifiersFile, "notifiers-file", conf.NotifiersFile, "YAML file listing the static notifiers posting the events of the objects to Slack or Discord channels, see the notify package")
+	flags.DurationVar(&conf.ShutdownTimeout, "shutdown-timeout", conf.ShutdownTimeout, "how long to wait for the requests in flight on shutdown")
+	flags.DurationVar(&conf.DrainDelay, "drain-delay", conf.DrainDelay, "how long to keep serving once interrupted, answering 503 on /readyz, before shutting down")
+	flags.DurationVar(&conf.IdempotencyTTL, "idempotency-ttl", conf.IdempotencyTTL, "how long to keep the responses to the requests with an Idempotency-Key header, replayed to their retries (0 disables the idempotency keys)")
+	flags.StringVar(&conf.Auth.KeysFile, "api-keys-file", conf.Auth.KeysFile, "file listing the static API keys, one per line as \"name scope key\", with scope read, write or admin (default: no authentication, unless there are OIDC providers)")
+	flags.StringVar(&conf.Auth.TokenSecretFile, "token-secret-file", conf.Auth.TokenSecretFile, "file holding the secret signing the bearer tokens and the sessions (default: random, they don't survive restarts)")
+	flags.DurationVar(&conf.Auth.TokenTTL, "token-ttl", conf.Auth.TokenTTL, "longest lifetime of the bearer tokens")
+	flags.Func("oidc-provider", "OpenID Connect provider the users log in with, on /auth/oidc/{name}/login, as \"name=google,client-id=ID,client-secret-file=PATH[,issuer=URL][,user-claim=CLAIM]\"; name google and github need no issuer (repeatable)", func(val string) error {
+		pc, err := ParseOIDCProvider(val)
+		if err != nil {
+			return err
+		}
+		conf.Auth.Providers = append(conf.Auth.Providers, pc)
+		return nil
+	})
+	flags.StringVar(&conf.Auth.RedirectBase, "oidc-redirect-base", conf.Auth.RedirectBase, "external URL of the server, the OIDC providers send the users back to, e.g. https://todo.example.com")
+	flags.BoolVar(&conf.Auth.AutoCreate, "oidc-auto-create", conf.Auth.AutoCreate, "create the users logging in with a verified email address unknown so far")
+	flags.DurationVar(&conf.Auth.SessionTTL, "session-ttl", conf.Auth.SessionTTL, "lifetime of the sessions of the users logged in with an OIDC provider")
+	flags.Float64Var(&conf.RateLimit.Rate, "rate-limit", conf.RateLimit.Rate, "requests per second each client may make, identified by its API key or user, or else by its IP address (default: unlimited)")
+	flags.IntVar(&conf.RateLimit.Burst, "rate-burst", conf.RateLimit.Burst, "requests each client may make at once, before the rate limit applies")
+	flags.BoolVar(&conf.RateLimit.TrustProxy, "rate-limit-trust-proxy", conf.RateLimit.TrustProxy, "identify the clients without credentials by the X-Forwarded-For header, set by a reverse proxy")
+	flags.StringVar(&conf.TLS.CertFile, "tls-cert", conf.TLS.CertFile, "PEM file of the TLS certificate, to serve over HTTPS")
+	flags.StringVar(&conf.TLS.KeyFile, "tls-key", conf.TLS.KeyFile, "PEM file of the private key of the TLS certificate")
+	flags.Func("tls-autocert", "comma-separated domains to obtain the TLS certificates of from Let's Encrypt, to serve over HTTPS", func(val string) error {
+		conf.TLS.AutocertDomains = strings.Split(val, ",")
+		return nil
+	})
+	flags.StringVar(&conf.TLS.AutocertCache, "tls-autocert-cache", conf.TLS.AutocertCache, "directory caching the certificates obtained from Let's Encrypt")
+	flags.StringVar(&conf.TLS.AutocertEmail, "tls-autocert-email", conf.TLS.AutocertEmail, "contact address to register with Let's Encrypt")
+	flags.BoolVar(&conf.TLS.SelfSigned, "tls-self-signed", conf.TLS.SelfSigned, "serve over HTTPS with a certificate generated on startup, for the development only")
+	flags.StringVar(&conf.TLS.RedirectAddress, "http-redirect-address", conf.TLS.RedirectAddress, "address to listen on for the plain HTTP requests, redirected to HTTPS, and for the Let's Encrypt challenges, e.g. :80 (default: disabled)")
+	flags.StringVar(&conf.Log.Level, "log-level", conf.Log.Level, "lowest level of the records logged: debug, info, warn or error")
+	flags.StringVar(&conf.Log.Format, "log-format", conf.Log.Format, "format of the records logged: text or json")
+	flags.StringVar(&conf.Tracing.Endpoint, "otlp-endpoint", conf.Tracing.Endpoint, "URL of the OTLP/HTTP collector to export the traces to, e.g. http://localhost:4318 (default: no tracing)")
+	flags.StringVar(&conf.Tracing.ServiceName, "trace-service-name", conf.Tracing.ServiceName, "name of the service the traces are reported under")
+	flags.Float64Var(&conf.Tracing.SampleRatio, "trace-sample-ratio", conf.Tracing.SampleRatio, "ratio of the traces sampled, from 0 to 1, unless the callers sampled them")
+	flags.StringVar(&conf.IDStrategy, "id-strategy", conf.IDStrategy, "how to generate the IDs of the new objects: sequential, ulid or uuidv7")
+	flags.StringVar(&conf.Mail.SMTPURL, "smtp-url", conf.Mail.SMTPURL, "SMTP server sending the reminders and the digests by email, as smtp://[user@]host[:port] with STARTTLS, or smtps:// with TLS (default: no emails)")
+	flags.StringVar(&conf.Mail.PasswordFile, "smtp-password-file", conf.Mail.PasswordFile, "file holding the password of the user of the SMTP server")
+	flags.StringVar(&conf.Mail.From, "mail-from", conf.Mail.From, "sender of the emails, e.g. \"Todo <todo@example.com>\"")
+	flags.Func("mail-to", "comma-separated recipients of the digests, and of the reminders of the objects of nobody with an email address", func(val string) error {
+		conf.Mail.To = strings.Split(val, ",")
+		return nil
+	})
+	flags.StringVar(&conf.Mail.Digest, "mail-digest", conf.Mail.Digest, "when to email the digests of the open, overdue and completed objects: daily or weekly, optionally followed by the weekday and the time, e.g. \"weekly fri 17:00\" (default: no digests)")
+	flags.StringVar(&conf.Mail.TemplatesDir, "mail-templates", conf.Mail.TemplatesDir, "directory holding the templates of the emails overriding the default ones: reminder.txt, reminder.html, digest.txt and digest.html")
+	flags.Func("job", "maintenance job to run on a schedule, as \"name=schedule[;jitter=duration]\", with name compact, trash-purge, archive, overdue, backup or changelog, and schedule a cron expression or a macro, e.g. \"backup=30 3 * * *;jitter=10m\" or \"compact=@daily\" (repeatable)", func(val string) error {
+		jc, err := ParseJob(val)
+		if err != nil {
+			return err
+		}
+		conf.Jobs.Jobs = append(conf.Jobs.Jobs, jc)
+		return nil
+	})
+	flags.DurationVar(&conf.Jobs.ArchiveAfter, "archive-after", conf.Jobs.ArchiveAfter, "how long the completed objects stay before the archive job archives them")
+	flags.StringVar(&conf.Jobs.BackupDir, "backup-dir", conf.Jobs.BackupDir, "directory the backup job writes the snapshots of the store to")
+	flags.IntVar(&conf.Jobs.BackupKeep, "backup-keep", conf.Jobs.BackupKeep, "how many of the latest snapshots the backup job keeps (0 keeps them all)")
+	flags.IntVar(&conf.Jobs.ChangesMaxEvents, "changes-max-events", conf.Jobs.ChangesMaxEvents, "how many of the latest changes the changelog keeps (0 keeps them all)")
+	flags.DurationVar(&conf.Jobs.ChangesMaxAge, "changes-max-age", conf.Jobs.ChangesMaxAge, "how long the changelog keeps the changes (0 keeps them forever)")
+	flags.DurationVar(&conf.Jobs.ChangesCompactAfter, "changes-compact-after", conf.Jobs.ChangesCompactAfter, "how long the changelog job keeps the changes superseded by a later change of the same object (0 keeps them)")
 
 	flags.Usage = func() {
 		w := flags.Output()
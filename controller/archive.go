@@ -0,0 +1,103 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	apiv1 "github.com/gotestbootcamp/go-todo-app/api/v1"
+	"github.com/gotestbootcamp/go-todo-app/ledger"
+	"github.com/gotestbootcamp/go-todo-app/store"
+)
+
+// defaultArchiveAge is how long the todos must be completed for Archive to archive them,
+// when the request doesn't tell
+const defaultArchiveAge = 30 * 24 * time.Hour
+
+/*
+curl -X POST http://localhost:8080/archive?older_than=168h
+*/
+func (ctrl *Controller) Archive(w http.ResponseWriter, r *http.Request) {
+	olderThan := defaultArchiveAge
+	if val := r.URL.Query().Get("older_than"); val != "" {
+		var err error
+		olderThan, err = time.ParseDuration(val)
+		if err != nil || olderThan < 0 {
+			sendError(w, http.StatusBadRequest, fmt.Errorf("invalid duration %q", val))
+			return
+		}
+	}
+	items, err := ctrl.ledger(r).Archive(olderThan)
+	if err != nil {
+		sendError(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+	slog.InfoContext(r.Context(), "API: archived objects", "count", len(items))
+
+	resp := apiv1.Response{
+		Status: apiv1.ResponseSuccess,
+		Result: &apiv1.Result{
+			Items: items.ToAPIv1(),
+		},
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		panic(err)
+	}
+}
+
+func (ctrl *Controller) ArchivedIndex(w http.ResponseWriter, r *http.Request) {
+	items, err := ctrl.ledger(r).ListArchived()
+	if err != nil {
+		sendError(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	resp := apiv1.Response{
+		Status: apiv1.ResponseSuccess,
+		Result: &apiv1.Result{
+			Items: items.ToAPIv1(),
+		},
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		panic(err)
+	}
+}
+
+/*
+curl -X POST http://localhost:8080/todos/1/unarchive
+*/
+func (ctrl *Controller) TodoUnarchive(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	todoID := vars["todoID"]
+	item, err := ctrl.ledger(r).Unarchive(store.ID(todoID))
+	var notFound store.ErrNotFound
+	if errors.As(err, &notFound) {
+		sendError(w, http.StatusNotFound, err)
+		return
+	}
+	var exists ledger.ErrExists
+	if errors.As(err, &exists) {
+		sendError(w, http.StatusConflict, err)
+		return
+	}
+	if err != nil {
+		sendError(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	slog.InfoContext(r.Context(), "API: unarchived object", "id", todoID, "todo", item.Todo)
+
+	resItem := item.ToAPIv1()
+	sendItem(w, resItem.ID, resItem.Todo)
+}
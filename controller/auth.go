@@ -0,0 +1,189 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	apiv1 "github.com/gotestbootcamp/go-todo-app/api/v1"
+	"github.com/gotestbootcamp/go-todo-app/auth"
+	"github.com/gotestbootcamp/go-todo-app/store"
+)
+
+func keyToAPIv1(key auth.Key) apiv1.APIKey {
+	return apiv1.APIKey{
+		ID:      key.ID,
+		Name:    key.Name,
+		Scope:   string(key.Scope),
+		Created: key.Created,
+		Static:  key.Static,
+	}
+}
+
+func sendKeys(w http.ResponseWriter, code int, keys ...apiv1.APIKey) {
+	resp := apiv1.Response{
+		Status: apiv1.ResponseSuccess,
+		Result: &apiv1.Result{
+			Keys: keys,
+		},
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		panic(err)
+	}
+}
+
+/*
+Issues a bearer token in exchange of the credentials of the request, with their scope or a narrower one.
+
+curl -X POST -H "X-API-Key: $TODO_KEY" -d '{"scope":"read","ttl":"15m"}' http://localhost:8080/auth/token
+*/
+func (ctrl *Controller) AuthToken(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	var req apiv1.TokenRequest
+	if err := json.NewDecoder(io.LimitReader(r.Body, 1048576)).Decode(&req); err != nil && err != io.EOF {
+		sendError(w, http.StatusBadRequest, err)
+		return
+	}
+	id, _ := auth.FromContext(r.Context())
+	scope := id.Scope
+	if req.Scope != "" {
+		var err error
+		if scope, err = auth.ParseScope(req.Scope); err != nil {
+			sendError(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+	var ttl time.Duration
+	if req.TTL != "" {
+		var err error
+		if ttl, err = time.ParseDuration(req.TTL); err != nil || ttl <= 0 {
+			sendError(w, http.StatusBadRequest, fmt.Errorf("invalid ttl %q", req.TTL))
+			return
+		}
+	}
+
+	token, expires, err := ctrl.auth.IssueToken(id, scope, ttl)
+	var denied auth.ErrScope
+	if errors.As(err, &denied) {
+		sendError(w, http.StatusForbidden, err)
+		return
+	}
+	if err != nil {
+		sendError(w, http.StatusInternalServerError, err)
+		return
+	}
+	slog.InfoContext(r.Context(), "API: issued token", "name", id.Name, "scope", scope, "expires", expires)
+
+	resp := apiv1.Response{
+		Status: apiv1.ResponseSuccess,
+		Result: &apiv1.Result{
+			Token: &apiv1.Token{
+				Token:   token,
+				Scope:   string(scope),
+				Expires: expires,
+			},
+		},
+	}
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		panic(err)
+	}
+}
+
+/*
+curl -H "X-API-Key: $TODO_ADMIN_KEY" http://localhost:8080/auth/keys
+*/
+func (ctrl *Controller) KeyIndex(w http.ResponseWriter, r *http.Request) {
+	keys := ctrl.auth.Keys.List()
+	res := make([]apiv1.APIKey, 0, len(keys))
+	for _, key := range keys {
+		res = append(res, keyToAPIv1(key))
+	}
+	sendKeys(w, http.StatusOK, res...)
+}
+
+/*
+Creates an API key. The response holds the key itself, which can't be retrieved later.
+
+curl -X POST -H "X-API-Key: $TODO_ADMIN_KEY" -d '{"name":"ci","scope":"read"}' http://localhost:8080/auth/keys
+*/
+func (ctrl *Controller) KeyCreate(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	var req apiv1.APIKey
+	if err := json.NewDecoder(io.LimitReader(r.Body, 1048576)).Decode(&req); err != nil {
+		sendError(w, http.StatusBadRequest, err)
+		return
+	}
+	scope, err := auth.ParseScope(req.Scope)
+	if err != nil {
+		sendError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	key, secret, err := ctrl.auth.Keys.Create(req.Name, scope)
+	if errors.Is(err, store.ErrUnsupported) {
+		sendError(w, http.StatusNotImplemented, err)
+		return
+	}
+	if err != nil {
+		sendError(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	res := keyToAPIv1(key)
+	res.Key = secret
+	w.Header().Set("Cache-Control", "no-store")
+	sendKeys(w, http.StatusCreated, res)
+}
+
+/*
+Revokes the API key, and the tokens issued in exchange of it.
+
+curl -X DELETE -H "X-API-Key: $TODO_ADMIN_KEY" http://localhost:8080/auth/keys/3f2a9c0d1e4b5a6f
+*/
+func (ctrl *Controller) KeyRevoke(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	err := ctrl.auth.Keys.Revoke(vars["keyID"])
+	var noKey auth.ErrNoKey
+	switch {
+	case errors.As(err, &noKey):
+		sendError(w, http.StatusNotFound, err)
+		return
+	case errors.Is(err, auth.ErrStaticKey):
+		sendError(w, http.StatusConflict, err)
+		return
+	case err != nil:
+		sendError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+/*
+Sends the browser to the OpenID Connect provider to log in, and then back to the local path of the
+"return" parameter.
+
+open http://localhost:8080/auth/oidc/google/login?return=/todos
+*/
+func (ctrl *Controller) OIDCLogin(w http.ResponseWriter, r *http.Request) {
+	ctrl.auth.Login(mux.Vars(r)["provider"])(w, r)
+}
+
+/*
+Where the OpenID Connect provider sends the browser back to, which starts the session of the user
+logged in.
+*/
+func (ctrl *Controller) OIDCCallback(w http.ResponseWriter, r *http.Request) {
+	ctrl.auth.Callback(mux.Vars(r)["provider"])(w, r)
+}
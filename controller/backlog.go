@@ -12,7 +12,7 @@ import (
 )
 
 func (ctrl *Controller) BacklogIndex(w http.ResponseWriter, r *http.Request) {
-	items, err := ctrl.ld.Filter(func(todo model.Todo) bool {
+	items, err := ctrl.ledger(r).Filter(func(todo model.Todo) bool {
 		return todo.IsOngoing()
 	})
 	if err != nil {
@@ -41,7 +41,7 @@ func (ctrl *Controller) BacklogAssigned(w http.ResponseWriter, r *http.Request)
 		sendError(w, http.StatusInternalServerError, fmt.Errorf("missing assignee"))
 		return
 	}
-	items, err := ctrl.ld.Filter(func(todo model.Todo) bool {
+	items, err := ctrl.ledger(r).Filter(func(todo model.Todo) bool {
 		return todo.IsOngoing() && todo.Assignee == assignee
 	})
 	if err != nil {
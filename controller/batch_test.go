@@ -0,0 +1,75 @@
+package controller_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	apiv1 "github.com/gotestbootcamp/go-todo-app/api/v1"
+	"github.com/gotestbootcamp/go-todo-app/controller"
+	"github.com/gotestbootcamp/go-todo-app/store"
+)
+
+func TestBatchApply(t *testing.T) {
+	ld := memoryStorage()
+	ctrl := controller.NewWithAuth(ld, store.NewSequentialIDs(nil), nil, nil)
+
+	do := func(body string) (int, apiv1.Response) {
+		req := httptest.NewRequest("POST", "/tasks:batch", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		ctrl.ServeHTTP(w, req)
+		var resp apiv1.Response
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return w.Code, resp
+	}
+	code, resp := do(`{"ops":[{"op":"create","todo":{"title":"pay rent"}},{"op":"create","todo":{"title":"buy milk"}}]}`)
+	require.Equal(t, http.StatusOK, code)
+	require.Len(t, resp.Result.Report, 2)
+	assert.Equal(t, apiv1.ID("1"), resp.Result.Report[0].ID)
+	assert.Equal(t, apiv1.ID("2"), resp.Result.Report[1].ID)
+
+	code, resp = do(`{"ops":[
+		{"op":"create","todo":{"title":"call mum"}},
+		{"op":"update","id":"1","patch":{"title":"pay the rent"},"rev":1},
+		{"op":"update","id":"2","patch":{"title":"buy bread"},"rev":7},
+		{"op":"delete","id":"9"},
+		{"op":"delete","id":"2"}
+	]}`)
+	require.Equal(t, http.StatusOK, code)
+	report := resp.Result.Report
+	require.Len(t, report, 5)
+	assert.Equal(t, http.StatusCreated, report[0].Status, report[0].Error)
+	assert.NotEmpty(t, report[0].ID)
+	assert.Equal(t, http.StatusOK, report[1].Status)
+	assert.Equal(t, http.StatusPreconditionFailed, report[2].Status)
+	assert.NotEmpty(t, report[2].Error)
+	assert.Equal(t, http.StatusNotFound, report[3].Status)
+	assert.Equal(t, http.StatusOK, report[4].Status)
+
+	todo, err := ld.Get(store.ID(report[0].ID))
+	require.NoError(t, err)
+	assert.Equal(t, "call mum", todo.Title)
+	todo, err = ld.Get("1")
+	require.NoError(t, err)
+	assert.Equal(t, "pay the rent", todo.Title)
+	_, err = ld.Get("2")
+	assert.ErrorIs(t, err, store.ErrNotFound{ID: "2"})
+
+	// the malformed batches are rejected as a whole
+	code, _ = do(`{"ops":[{"op":"create","todo":{"title":"x"}},{"op":"move","id":"1"}]}`)
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _ = do(`{"ops":[{"op":"update","patch":{"title":"x"}}]}`)
+	assert.Equal(t, http.StatusBadRequest, code)
+	ops := make([]string, 501)
+	for i := range ops {
+		ops[i] = fmt.Sprintf(`{"op":"delete","id":"%d"}`, i)
+	}
+	code, _ = do(`{"ops":[` + strings.Join(ops, ",") + `]}`)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, code)
+}
@@ -0,0 +1,115 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	apiv1 "github.com/gotestbootcamp/go-todo-app/api/v1"
+	"github.com/gotestbootcamp/go-todo-app/ledger"
+	"github.com/gotestbootcamp/go-todo-app/store"
+)
+
+func (ctrl *Controller) BlockedIndex(w http.ResponseWriter, r *http.Request) {
+	items, err := ctrl.ledger(r).ListBlocked()
+	if err != nil {
+		sendError(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	resp := apiv1.Response{
+		Status: apiv1.ResponseSuccess,
+		Result: &apiv1.Result{
+			Items: items.ToAPIv1(),
+		},
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		panic(err)
+	}
+}
+
+/*
+curl http://localhost:8080/todos/1/blocks
+*/
+func (ctrl *Controller) TodoBlocks(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	items, err := ctrl.ledger(r).Blocks(store.ID(vars["todoID"]))
+	var notFound store.ErrNotFound
+	if errors.As(err, &notFound) {
+		sendError(w, http.StatusNotFound, err)
+		return
+	}
+	if err != nil {
+		sendError(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	resp := apiv1.Response{
+		Status: apiv1.ResponseSuccess,
+		Result: &apiv1.Result{
+			Items: items.ToAPIv1(),
+		},
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		panic(err)
+	}
+}
+
+/*
+curl -X PUT http://localhost:8080/todos/2/blockers/1
+*/
+func (ctrl *Controller) TodoBlock(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	todoID := vars["todoID"]
+	item, err := ctrl.ledger(r).Block(store.ID(todoID), store.ID(vars["blockerID"]))
+	var notFound store.ErrNotFound
+	var cycle ledger.ErrDependencyCycle
+	switch {
+	case errors.As(err, &notFound):
+		sendError(w, http.StatusNotFound, err)
+		return
+	case errors.As(err, &cycle):
+		sendError(w, http.StatusConflict, err)
+		return
+	case err != nil:
+		sendError(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	slog.InfoContext(r.Context(), "API: blocked object", "id", todoID, "blocker", vars["blockerID"])
+
+	resItem := item.ToAPIv1()
+	sendItem(w, resItem.ID, resItem.Todo)
+}
+
+/*
+curl -X DELETE http://localhost:8080/todos/2/blockers/1
+*/
+func (ctrl *Controller) TodoUnblock(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	todoID := vars["todoID"]
+	item, err := ctrl.ledger(r).Unblock(store.ID(todoID), store.ID(vars["blockerID"]))
+	var notFound store.ErrNotFound
+	if errors.As(err, &notFound) {
+		sendError(w, http.StatusNotFound, err)
+		return
+	}
+	if err != nil {
+		sendError(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	slog.InfoContext(r.Context(), "API: unblocked object", "id", todoID, "blocker", vars["blockerID"])
+
+	resItem := item.ToAPIv1()
+	sendItem(w, resItem.ID, resItem.Todo)
+}
@@ -0,0 +1,83 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+
+	apiv1 "github.com/gotestbootcamp/go-todo-app/api/v1"
+	"github.com/gotestbootcamp/go-todo-app/ledger"
+	"github.com/gotestbootcamp/go-todo-app/store"
+)
+
+// sendBoard sends the board the view of the request sees, grouping the todos by the field
+func (ctrl *Controller) sendBoard(w http.ResponseWriter, r *http.Request, field string) {
+	board, err := ctrl.ledger(r).Board(field)
+	var unknown ledger.ErrUnknownBoardField
+	switch {
+	case errors.As(err, &unknown):
+		sendError(w, http.StatusBadRequest, err)
+		return
+	case err != nil:
+		sendError(w, http.StatusInternalServerError, err)
+		return
+	}
+	res := board.ToAPIv1()
+	resp := apiv1.Response{
+		Status: apiv1.ResponseSuccess,
+		Result: &apiv1.Result{
+			Board: &res,
+		},
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		panic(err)
+	}
+}
+
+/*
+Groups the todos in a column for each status, or for each priority with by=priority, in their manual order.
+
+curl -H "X-API-Key: $TODO_KEY" http://localhost:8080/board?by=priority
+*/
+func (ctrl *Controller) BoardShow(w http.ResponseWriter, r *http.Request) {
+	field := r.URL.Query().Get("by")
+	if field == "" {
+		field = "status"
+	}
+	ctrl.sendBoard(w, r, field)
+}
+
+/*
+Moves the todo at the position of the column of the board, changing its status or its priority to the
+column's, and returns the board. The position is kept until the todo is moved again.
+
+curl -X POST -H "X-API-Key: $TODO_KEY" -d '{"id":"1","by":"status","column":"assigned","position":0}' http://localhost:8080/board/move
+*/
+func (ctrl *Controller) BoardMove(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	var req apiv1.BoardMove
+	if err := json.NewDecoder(io.LimitReader(r.Body, 1048576)).Decode(&req); err != nil {
+		sendError(w, http.StatusBadRequest, err)
+		return
+	}
+	if req.By == "" {
+		req.By = "status"
+	}
+	_, err := ctrl.ledger(r).MoveOnBoard(req.By, store.ID(req.ID), req.Column, req.Position)
+	var unknown ledger.ErrUnknownBoardField
+	switch {
+	case errors.As(err, &unknown):
+		sendError(w, http.StatusBadRequest, err)
+		return
+	case err != nil:
+		sendWriteError(w, err)
+		return
+	}
+	slog.InfoContext(r.Context(), "API: moved object on board", "id", req.ID, "by", req.By, "column", req.Column, "position", req.Position)
+	ctrl.sendBoard(w, r, req.By)
+}
@@ -0,0 +1,57 @@
+package controller_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	apiv1 "github.com/gotestbootcamp/go-todo-app/api/v1"
+	"github.com/gotestbootcamp/go-todo-app/controller"
+	"github.com/gotestbootcamp/go-todo-app/model"
+	"github.com/gotestbootcamp/go-todo-app/store"
+)
+
+func TestBoard(t *testing.T) {
+	ld := memoryStorage()
+	require.NoError(t, ld.Set("1", model.New("pay rent")))
+	require.NoError(t, ld.Set("2", model.New("buy milk")))
+	ctrl := controller.NewWithAuth(ld, store.NewSequentialIDs(nil), nil, nil)
+
+	// columns returns the IDs of the todos in each column of the board
+	columns := func(board *apiv1.Board) map[string][]apiv1.ID {
+		require.NotNil(t, board)
+		res := make(map[string][]apiv1.ID)
+		for _, col := range board.Columns {
+			res[col.Name] = []apiv1.ID{}
+			for _, item := range col.Items {
+				res[col.Name] = append(res[col.Name], item.ID)
+			}
+		}
+		return res
+	}
+
+	code, resp := serve(t, ctrl, "GET", "/board", "")
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "status", resp.Result.Board.By)
+	assert.Equal(t, map[string][]apiv1.ID{"pending": {"1", "2"}, "assigned": {}, "completed": {}}, columns(resp.Result.Board))
+
+	code, resp = serve(t, ctrl, "POST", "/board/move", `{"id":"2","by":"status","column":"pending","position":0}`)
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, []apiv1.ID{"2", "1"}, columns(resp.Result.Board)["pending"])
+	code, resp = serve(t, ctrl, "POST", "/board/move", `{"id":"1","column":"assigned","position":0}`)
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, map[string][]apiv1.ID{"pending": {"2"}, "assigned": {"1"}, "completed": {}}, columns(resp.Result.Board))
+	code, _ = serve(t, ctrl, "POST", "/board/move", `{"id":"2","column":"completed","position":0}`)
+	assert.Equal(t, http.StatusConflict, code)
+	code, _ = serve(t, ctrl, "POST", "/board/move", `{"id":"9","column":"pending","position":0}`)
+	assert.Equal(t, http.StatusNotFound, code)
+
+	code, resp = serve(t, ctrl, "POST", "/board/move", `{"id":"2","by":"priority","column":"P0","position":0}`)
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "priority", resp.Result.Board.By)
+	assert.Equal(t, []apiv1.ID{"2"}, columns(resp.Result.Board)["P0"])
+	code, _ = serve(t, ctrl, "GET", "/board?by=title", "")
+	assert.Equal(t, http.StatusBadRequest, code)
+}
@@ -0,0 +1,188 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+
+	apiv1 "github.com/gotestbootcamp/go-todo-app/api/v1"
+	"github.com/gotestbootcamp/go-todo-app/ledger"
+	"github.com/gotestbootcamp/go-todo-app/model"
+	"github.com/gotestbootcamp/go-todo-app/store"
+	"github.com/gotestbootcamp/go-todo-app/task"
+)
+
+// bulkFilter returns the filter of the todos a bulk operation applies to, set by the
+// status and assignee query parameters. No parameters match all the todos.
+func bulkFilter(r *http.Request) ledger.Wants {
+	query := r.URL.Query()
+	status, assignee := query.Get("status"), query.Get("assignee")
+	return func(todo model.Todo) bool {
+		return (status == "" || string(todo.Status) == status) &&
+			(assignee == "" || todo.Assignee == assignee)
+	}
+}
+
+/*
+curl -X POST http://localhost:8080/bulk/complete?assignee=fede
+*/
+func (ctrl *Controller) BulkComplete(w http.ResponseWriter, r *http.Request) {
+	report, err := ctrl.ledger(r).CompleteAll(bulkFilter(r))
+	if err != nil {
+		sendError(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+	slog.InfoContext(r.Context(), "API: completed in bulk", "count", len(report))
+	sendReport(w, report)
+}
+
+/*
+curl -X POST -d '{"ids":["1","2"],"add":["work"],"remove":["home"]}' http://localhost:8080/bulk/retag
+*/
+func (ctrl *Controller) BulkRetag(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	var retag apiv1.Retag
+	if err := json.NewDecoder(io.LimitReader(r.Body, 1048576)).Decode(&retag); err != nil {
+		sendError(w, http.StatusBadRequest, err)
+		return
+	}
+	ids := make([]store.ID, 0, len(retag.IDs))
+	for _, id := range retag.IDs {
+		ids = append(ids, store.ID(id))
+	}
+	report, err := ctrl.ledger(r).RetagAll(ids, retag.Add, retag.Remove)
+	if err != nil {
+		sendError(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+	slog.InfoContext(r.Context(), "API: retagged in bulk", "count", len(report))
+	sendReport(w, report)
+}
+
+/*
+curl -X POST 'http://localhost:8080/bulk/move?to=work&status=pending'
+*/
+func (ctrl *Controller) BulkMove(w http.ResponseWriter, r *http.Request) {
+	list := r.URL.Query().Get("to")
+	report, err := ctrl.ledger(r).MoveAll(bulkFilter(r), list)
+	if err != nil {
+		sendError(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+	slog.InfoContext(r.Context(), "API: moved in bulk", "count", len(report), "list", list)
+	sendReport(w, report)
+}
+
+/*
+Applies the operations of the batch, creating, updating and deleting todos, at once. The report
+tells the status of each operation, in their order: the ones failing are skipped, the others applied.
+
+curl -X POST -d '{"ops":[{"op":"create","todo":{"title":"pay rent"}},{"op":"update","id":"1","patch":{"status":"completed"},"rev":3},{"op":"delete","id":"2"}]}' http://localhost:8080/tasks:batch
+*/
+func (ctrl *Controller) BatchApply(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	var batch apiv1.Batch
+	if err := json.NewDecoder(io.LimitReader(r.Body, 8*1048576)).Decode(&batch); err != nil {
+		sendError(w, http.StatusBadRequest, err)
+		return
+	}
+	ops := make([]ledger.BatchOp, 0, len(batch.Ops))
+	for i, apiOp := range batch.Ops {
+		op, err := batchOpFromAPIv1(apiOp)
+		if err != nil {
+			sendError(w, http.StatusBadRequest, fmt.Errorf("operation %d: %w", i, err))
+			return
+		}
+		ops = append(ops, op)
+	}
+	report, err := ctrl.ledger(r).Batch(ops)
+	if errors.Is(err, ledger.ErrBatchTooLarge) {
+		sendError(w, http.StatusRequestEntityTooLarge, err)
+		return
+	}
+	if err != nil {
+		sendError(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+	slog.InfoContext(r.Context(), "API: applied a batch", "count", len(report))
+
+	results := report.ToAPIv1()
+	for i, res := range report {
+		switch {
+		case res.Err != nil:
+			results[i].Status = writeStatus(res.Err)
+		case ops[i].Kind == ledger.BatchCreate:
+			results[i].Status = http.StatusCreated
+		default:
+			results[i].Status = http.StatusOK
+		}
+	}
+	resp := apiv1.Response{
+		Status: apiv1.ResponseSuccess,
+		Result: &apiv1.Result{
+			Report: results,
+		},
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		panic(err)
+	}
+}
+
+// batchOpFromAPIv1 converts the operation of a batch, which must tell the todo it applies to
+func batchOpFromAPIv1(apiOp apiv1.BatchOp) (ledger.BatchOp, error) {
+	op := ledger.BatchOp{
+		Kind:     ledger.BatchOpKind(apiOp.Op),
+		ID:       store.ID(apiOp.ID),
+		List:     apiOp.List,
+		Expected: ledger.AnyRevision,
+	}
+	if apiOp.Rev != nil {
+		op.Expected = *apiOp.Rev
+	}
+	switch op.Kind {
+	case ledger.BatchCreate:
+		if apiOp.Todo == nil {
+			return op, errors.New("missing todo to create")
+		}
+		op.Task = model.NewFromAPIv1(*apiOp.Todo).ToTask()
+	case ledger.BatchUpdate:
+		if apiOp.Patch == nil {
+			return op, errors.New("missing patch")
+		}
+		op.Patch = ledger.Patch{
+			Title:       apiOp.Patch.Title,
+			Description: apiOp.Patch.Description,
+			Assignee:    apiOp.Patch.Assignee,
+			Status:      (*task.Status)(apiOp.Patch.Status),
+			Owner:       apiOp.Patch.Owner,
+		}
+	case ledger.BatchDelete:
+	default:
+		return op, fmt.Errorf("unknown operation %q, want create, update or delete", apiOp.Op)
+	}
+	if op.Kind != ledger.BatchCreate && op.ID == store.NullID {
+		return op, errors.New("missing id")
+	}
+	return op, nil
+}
+
+func sendReport(w http.ResponseWriter, report ledger.BulkReport) {
+	resp := apiv1.Response{
+		Status: apiv1.ResponseSuccess,
+		Result: &apiv1.Result{
+			Report: report.ToAPIv1(),
+		},
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		panic(err)
+	}
+}
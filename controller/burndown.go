@@ -0,0 +1,95 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	apiv1 "github.com/gotestbootcamp/go-todo-app/api/v1"
+	"github.com/gotestbootcamp/go-todo-app/ledger"
+)
+
+// burndown returns the burndown of the project of the tag and the list of the query, from and to
+// the times of the query, the last 4 weeks by default. The periods longer than ledger.MaxBurndownDays
+// are bad requests.
+func (ctrl *Controller) burndown(r *http.Request) (ledger.Burndown, int, error) {
+	query := r.URL.Query()
+	to := time.Now()
+	if val := query.Get("to"); val != "" {
+		var err error
+		if to, err = parseQueryTime(val); err != nil {
+			return ledger.Burndown{}, http.StatusBadRequest, err
+		}
+	}
+	from := to.Add(-defaultStatsPeriod)
+	if val := query.Get("from"); val != "" {
+		var err error
+		if from, err = parseQueryTime(val); err != nil {
+			return ledger.Burndown{}, http.StatusBadRequest, err
+		}
+	}
+	if from.After(to) {
+		return ledger.Burndown{}, http.StatusBadRequest, fmt.Errorf("period from %v ends before it starts", from)
+	}
+	burndown, err := ctrl.ledger(r).Burndown(query.Get("tag"), query.Get("list"), from, to)
+	switch {
+	case errors.Is(err, ledger.ErrPeriodTooLong):
+		return ledger.Burndown{}, http.StatusBadRequest, err
+	case err != nil:
+		return ledger.Burndown{}, http.StatusUnprocessableEntity, err
+	}
+	return burndown, http.StatusOK, nil
+}
+
+/*
+Tells how many todos of the project, with the tag, in the list, were open and completed at the end
+of each day, as their history tells.
+
+curl -H "X-API-Key: $TODO_KEY" 'http://localhost:8080/burndown?tag=launch&from=2024-11-01'
+*/
+func (ctrl *Controller) Burndown(w http.ResponseWriter, r *http.Request) {
+	burndown, code, err := ctrl.burndown(r)
+	if err != nil {
+		sendError(w, code, err)
+		return
+	}
+	apiBurndown := burndown.ToAPIv1()
+	resp := apiv1.Response{
+		Status: apiv1.ResponseSuccess,
+		Result: &apiv1.Result{
+			Burndown: &apiBurndown,
+		},
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		panic(err)
+	}
+}
+
+/*
+Answers the burndown as CSV, for the spreadsheets and the plotting tools: a row for each time,
+with the counts of the todos open and completed.
+
+curl -H "X-API-Key: $TODO_KEY" 'http://localhost:8080/burndown.csv?list=work'
+*/
+func (ctrl *Controller) BurndownCSV(w http.ResponseWriter, r *http.Request) {
+	burndown, code, err := ctrl.burndown(r)
+	if err != nil {
+		sendError(w, code, err)
+		return
+	}
+	var buf bytes.Buffer
+	if err := burndown.WriteCSV(&buf); err != nil {
+		sendError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="burndown.csv"`)
+	w.WriteHeader(http.StatusOK)
+	w.Write(buf.Bytes())
+}
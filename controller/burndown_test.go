@@ -0,0 +1,66 @@
+package controller_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	apiv1 "github.com/gotestbootcamp/go-todo-app/api/v1"
+	"github.com/gotestbootcamp/go-todo-app/controller"
+	"github.com/gotestbootcamp/go-todo-app/model"
+	"github.com/gotestbootcamp/go-todo-app/store"
+	"github.com/gotestbootcamp/go-todo-app/task"
+)
+
+func TestBurndown(t *testing.T) {
+	ld := memoryStorage()
+	for _, id := range []store.ID{"1", "2", "3"} {
+		require.NoError(t, ld.Set(id, model.New("todo "+string(id))))
+	}
+	for _, id := range []store.ID{"1", "2"} {
+		_, err := ld.TagTodo(id, "launch")
+		require.NoError(t, err)
+	}
+	_, err := ld.Transition("1", task.Assigned)
+	require.NoError(t, err)
+	_, err = ld.Transition("1", task.Completed)
+	require.NoError(t, err)
+	ctrl := controller.NewWithAuth(ld, store.NewSequentialIDs(nil), nil, nil)
+
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		ctrl.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		return w
+	}
+
+	w := get("/burndown?tag=launch")
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp apiv1.Response
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	burndown := resp.Result.Burndown
+	require.NotNil(t, burndown)
+	assert.Equal(t, "launch", burndown.Tag)
+	require.NotEmpty(t, burndown.Points)
+	assert.Equal(t, apiv1.BurndownPoint{Time: burndown.Points[0].Time}, burndown.Points[0], "none 4 weeks ago")
+	last := burndown.Points[len(burndown.Points)-1]
+	assert.Equal(t, 1, last.Open)
+	assert.Equal(t, 1, last.Completed)
+
+	w = get("/burndown.csv?tag=launch")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/csv; charset=utf-8", w.Header().Get("Content-Type"))
+	lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+	assert.Equal(t, "time,open,completed", lines[0])
+	assert.Len(t, lines, len(burndown.Points)+1)
+	assert.True(t, strings.HasSuffix(lines[len(lines)-1], ",1,1"), lines[len(lines)-1])
+
+	assert.Equal(t, http.StatusBadRequest, get("/burndown?from=yesterday").Code)
+	assert.Equal(t, http.StatusBadRequest, get("/burndown.csv?from=2024-12-01&to=2024-11-01").Code)
+	assert.Equal(t, http.StatusBadRequest, get("/burndown?from=1970-01-01").Code)
+	assert.Equal(t, http.StatusUnprocessableEntity, get("/burndown?list=.hidden").Code)
+}
@@ -0,0 +1,60 @@
+package controller
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/gotestbootcamp/go-todo-app/ical"
+	"github.com/gotestbootcamp/go-todo-app/ledger"
+)
+
+/*
+Answers the todos with a due date as an iCalendar feed, for the calendar apps to subscribe to:
+as events, or as to-dos with component=todo. The apps which can't set the headers authenticate
+with the API key in the key query parameter.
+
+curl http://localhost:8080/calendar.ics
+curl http://localhost:8080/calendar.ics?component=todo&tag=finance
+*/
+func (ctrl *Controller) Calendar(w http.ResponseWriter, r *http.Request) {
+	component := ical.Event
+	if val := r.URL.Query().Get("component"); val != "" {
+		component = ical.Component(val)
+		if !slices.Contains(ical.Components, component) {
+			sendError(w, http.StatusBadRequest, fmt.Errorf("invalid component %q, want %s or %s", val, ical.Event, ical.ToDo))
+			return
+		}
+	}
+	items, _, err := ctrl.ledger(r).List(ledger.Query{
+		Tag:  r.URL.Query().Get("tag"),
+		Sort: []ledger.SortKey{{Field: "due"}},
+	})
+	if err != nil {
+		sendError(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	todos := make([]ical.Todo, 0, len(items))
+	for _, item := range items {
+		if item.Task.Due == nil {
+			continue
+		}
+		todos = append(todos, ical.Todo{ID: string(item.ID), Task: *item.Task})
+	}
+	var buf bytes.Buffer
+	if err := ical.New("todo", component).Encode(&buf, todos); err != nil {
+		sendError(w, http.StatusInternalServerError, err)
+		return
+	}
+	// the apps polling the feed get 304 as long as it doesn't change
+	sum := sha256.Sum256(buf.Bytes())
+	w.Header().Set("ETag", strconv.Quote(hex.EncodeToString(sum[:16])))
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	http.ServeContent(w, r, "calendar.ics", time.Time{}, bytes.NewReader(buf.Bytes()))
+}
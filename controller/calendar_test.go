@@ -0,0 +1,54 @@
+package controller_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gotestbootcamp/go-todo-app/controller"
+	"github.com/gotestbootcamp/go-todo-app/store"
+	"github.com/gotestbootcamp/go-todo-app/task"
+)
+
+func TestCalendar(t *testing.T) {
+	ld := memoryStorage()
+	due := time.Now().Add(48 * time.Hour)
+	rent := task.New("pay rent")
+	rent.Due = &due
+	rent.Tags = []string{"finance"}
+	report, err := ld.CreateAll(store.DefaultList, []task.Task{rent, task.New("someday")})
+	require.NoError(t, err)
+	require.Len(t, report, 2)
+	require.NoError(t, report[0].Err)
+	handler := controller.NewWithIDs(ld, store.NewSequentialIDs(nil))
+
+	get := func(url, etag string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, url, nil)
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+	w := get("/calendar.ics", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/calendar; charset=utf-8", w.Header().Get("Content-Type"))
+	body := w.Body.String()
+	assert.True(t, strings.HasPrefix(body, "BEGIN:VCALENDAR\r\n"), body)
+	assert.Equal(t, 1, strings.Count(body, "BEGIN:VEVENT\r\n"))
+	assert.Contains(t, body, "SUMMARY:pay rent\r\n")
+	assert.Equal(t, http.StatusNotModified, get("/calendar.ics", w.Header().Get("ETag")).Code)
+
+	w = get("/calendar.ics?component=todo&tag=home", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), "BEGIN:VTODO")
+	w = get("/calendar.ics?component=todo&tag=finance", "")
+	assert.Contains(t, w.Body.String(), "BEGIN:VTODO\r\n")
+	assert.Equal(t, http.StatusBadRequest, get("/calendar.ics?component=journal", "").Code)
+}
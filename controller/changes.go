@@ -0,0 +1,72 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	apiv1 "github.com/gotestbootcamp/go-todo-app/api/v1"
+	"github.com/gotestbootcamp/go-todo-app/ledger"
+)
+
+// maxChanges bounds the entries of the changelog returned by a request
+const maxChanges = 1000
+
+/*
+Lists the changes of the todos after the cursor given by the since query parameter, oldest first,
+for the integrations to poll them incrementally: the cursor returned lists the following ones.
+Without since, the changes are listed from the oldest one the changelog keeps. Answers 410 Gone if
+the changelog doesn't keep the changes after the cursor anymore: the todos must be listed again.
+
+curl 'http://localhost:8080/changes?limit=100'
+curl 'http://localhost:8080/changes?since=42'
+*/
+func (ctrl *Controller) ChangeIndex(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	var since uint64
+	if val := query.Get("since"); val != "" {
+		var err error
+		if since, err = strconv.ParseUint(val, 10, 64); err != nil {
+			sendError(w, http.StatusBadRequest, fmt.Errorf("invalid cursor %q", val))
+			return
+		}
+	}
+	limit := maxChanges
+	if val := query.Get("limit"); val != "" {
+		var err error
+		if limit, err = strconv.Atoi(val); err != nil || limit < 1 || limit > maxChanges {
+			sendError(w, http.StatusBadRequest, fmt.Errorf("invalid limit %q", val))
+			return
+		}
+	}
+
+	entries, next, err := ctrl.ledger(r).Changes(since, limit)
+	if errors.Is(err, ledger.ErrCursorExpired) {
+		sendError(w, http.StatusGone, err)
+		return
+	}
+	if err != nil {
+		sendError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	changes := make([]apiv1.ChangeEntry, 0, len(entries))
+	for _, entry := range entries {
+		changes = append(changes, entry.ToAPIv1())
+	}
+	resp := apiv1.Response{
+		Status: apiv1.ResponseSuccess,
+		Result: &apiv1.Result{
+			Changes: changes,
+			Cursor:  strconv.FormatUint(next, 10),
+		},
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		panic(err)
+	}
+}
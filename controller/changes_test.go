@@ -0,0 +1,61 @@
+package controller_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	apiv1 "github.com/gotestbootcamp/go-todo-app/api/v1"
+	"github.com/gotestbootcamp/go-todo-app/controller"
+	"github.com/gotestbootcamp/go-todo-app/ledger"
+	"github.com/gotestbootcamp/go-todo-app/model"
+	"github.com/gotestbootcamp/go-todo-app/store"
+)
+
+func TestChanges(t *testing.T) {
+	ld := memoryStorage()
+	require.NoError(t, ld.Set("1", model.New("pay rent")))
+	require.NoError(t, ld.Set("2", model.New("buy milk")))
+	require.NoError(t, ld.Delete("2"))
+	ctrl := controller.NewWithAuth(ld, store.NewSequentialIDs(nil), nil, nil)
+
+	do := func(url string) (int, apiv1.Response) {
+		req := httptest.NewRequest("GET", url, nil)
+		w := httptest.NewRecorder()
+		ctrl.ServeHTTP(w, req)
+		var resp apiv1.Response
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return w.Code, resp
+	}
+	code, resp := do("/changes?limit=2")
+	require.Equal(t, http.StatusOK, code)
+	require.Len(t, resp.Result.Changes, 2)
+	assert.Equal(t, "2", resp.Result.Cursor)
+	first := resp.Result.Changes[0]
+	assert.Equal(t, uint64(1), first.Seq)
+	assert.Equal(t, "created", first.Type)
+	assert.Equal(t, apiv1.ID("1"), first.ID)
+	require.NotNil(t, first.Item)
+	assert.Equal(t, "pay rent", first.Item.Todo.Title)
+
+	code, resp = do("/changes?since=2")
+	require.Equal(t, http.StatusOK, code)
+	require.Len(t, resp.Result.Changes, 1)
+	assert.Equal(t, "deleted", resp.Result.Changes[0].Type)
+	assert.Nil(t, resp.Result.Changes[0].Item)
+	assert.Equal(t, "3", resp.Result.Cursor)
+
+	code, _ = do("/changes?since=soon")
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _ = do("/changes?limit=0")
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	_, err := ld.CompactChanges(ledger.ChangeLogPolicy{MaxEvents: 1})
+	require.NoError(t, err)
+	code, _ = do("/changes?since=1")
+	assert.Equal(t, http.StatusGone, code)
+}
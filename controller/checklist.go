@@ -0,0 +1,80 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+
+	apiv1 "github.com/gotestbootcamp/go-todo-app/api/v1"
+	"github.com/gotestbootcamp/go-todo-app/ledger"
+	"github.com/gotestbootcamp/go-todo-app/store"
+	"github.com/gotestbootcamp/go-todo-app/task"
+)
+
+/*
+curl -X POST -d '{"text":"buy the eggs"}' http://localhost:8080/todos/1/checklist
+*/
+func (ctrl *Controller) CheckItemCreate(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	var apiItem apiv1.CheckItem
+	if err := json.NewDecoder(io.LimitReader(r.Body, 1048576)).Decode(&apiItem); err != nil {
+		sendError(w, http.StatusBadRequest, err)
+		return
+	}
+	vars := mux.Vars(r)
+	item, err := ctrl.ledger(r).AddCheckItem(store.ID(vars["todoID"]), apiItem.Text)
+	sendChecklist(w, r, "added an entry to", item, err)
+}
+
+/*
+curl -X POST http://localhost:8080/todos/1/checklist/2/toggle
+*/
+func (ctrl *Controller) CheckItemToggle(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	itemID, err := strconv.Atoi(vars["itemID"])
+	if err != nil {
+		sendError(w, http.StatusBadRequest, fmt.Errorf("invalid checklist entry %q", vars["itemID"]))
+		return
+	}
+	item, err := ctrl.ledger(r).ToggleCheckItem(store.ID(vars["todoID"]), itemID)
+	sendChecklist(w, r, "toggled an entry of", item, err)
+}
+
+/*
+curl -X DELETE http://localhost:8080/todos/1/checklist/2
+*/
+func (ctrl *Controller) CheckItemDelete(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	itemID, err := strconv.Atoi(vars["itemID"])
+	if err != nil {
+		sendError(w, http.StatusBadRequest, fmt.Errorf("invalid checklist entry %q", vars["itemID"]))
+		return
+	}
+	item, err := ctrl.ledger(r).RemoveCheckItem(store.ID(vars["todoID"]), itemID)
+	sendChecklist(w, r, "removed an entry from", item, err)
+}
+
+// sendChecklist sends the item whose checklist was changed
+func sendChecklist(w http.ResponseWriter, r *http.Request, verb string, item ledger.Item, err error) {
+	var notFound store.ErrNotFound
+	var noCheckItem task.ErrNoCheckItem
+	if errors.As(err, &notFound) || errors.As(err, &noCheckItem) {
+		sendError(w, http.StatusNotFound, err)
+		return
+	}
+	if err != nil {
+		sendError(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	slog.InfoContext(r.Context(), "API: "+verb+" the checklist of object", "id", item.ID)
+
+	resItem := item.ToAPIv1()
+	sendItem(w, resItem.ID, resItem.Todo)
+}
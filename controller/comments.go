@@ -0,0 +1,131 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+
+	apiv1 "github.com/gotestbootcamp/go-todo-app/api/v1"
+	"github.com/gotestbootcamp/go-todo-app/ledger"
+	"github.com/gotestbootcamp/go-todo-app/store"
+	"github.com/gotestbootcamp/go-todo-app/task"
+)
+
+/*
+curl http://localhost:8080/todos/1/comments
+*/
+func (ctrl *Controller) CommentIndex(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	comments, err := ctrl.ledger(r).Comments(store.ID(vars["todoID"]))
+	if err != nil {
+		sendCommentError(w, err)
+		return
+	}
+	sendComments(w, http.StatusOK, comments...)
+}
+
+/*
+curl -X POST -H 'X-Actor: fede' -d '{"body":"waiting for *the quote*"}' http://localhost:8080/todos/1/comments
+*/
+func (ctrl *Controller) CommentCreate(w http.ResponseWriter, r *http.Request) {
+	body, err := decodeComment(r)
+	if err != nil {
+		sendError(w, http.StatusBadRequest, err)
+		return
+	}
+	vars := mux.Vars(r)
+	cm, err := ctrl.ledger(r).AddComment(store.ID(vars["todoID"]), body)
+	if err != nil {
+		sendCommentError(w, err)
+		return
+	}
+	slog.InfoContext(r.Context(), "API: added comment", "id", vars["todoID"], "comment", cm.ID)
+	sendComments(w, http.StatusCreated, cm)
+}
+
+/*
+curl -X PUT -d '{"body":"got *the quote*"}' http://localhost:8080/todos/1/comments/1
+*/
+func (ctrl *Controller) CommentUpdate(w http.ResponseWriter, r *http.Request) {
+	body, err := decodeComment(r)
+	if err != nil {
+		sendError(w, http.StatusBadRequest, err)
+		return
+	}
+	vars := mux.Vars(r)
+	commentID, err := strconv.Atoi(vars["commentID"])
+	if err != nil {
+		sendError(w, http.StatusBadRequest, fmt.Errorf("invalid comment %q", vars["commentID"]))
+		return
+	}
+	cm, err := ctrl.ledger(r).EditComment(store.ID(vars["todoID"]), commentID, body)
+	if err != nil {
+		sendCommentError(w, err)
+		return
+	}
+	slog.InfoContext(r.Context(), "API: edited comment", "id", vars["todoID"], "comment", cm.ID)
+	sendComments(w, http.StatusOK, cm)
+}
+
+/*
+curl -X DELETE http://localhost:8080/todos/1/comments/1
+*/
+func (ctrl *Controller) CommentDelete(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	commentID, err := strconv.Atoi(vars["commentID"])
+	if err != nil {
+		sendError(w, http.StatusBadRequest, fmt.Errorf("invalid comment %q", vars["commentID"]))
+		return
+	}
+	if err := ctrl.ledger(r).DeleteComment(store.ID(vars["todoID"]), commentID); err != nil {
+		sendCommentError(w, err)
+		return
+	}
+	slog.InfoContext(r.Context(), "API: deleted comment", "id", vars["todoID"], "comment", commentID)
+	sendComments(w, http.StatusOK)
+}
+
+// decodeComment returns the body of the comment sent with the request
+func decodeComment(r *http.Request) (string, error) {
+	defer r.Body.Close()
+	var apiComment apiv1.Comment
+	if err := json.NewDecoder(io.LimitReader(r.Body, 1048576)).Decode(&apiComment); err != nil {
+		return "", err
+	}
+	return apiComment.Body, nil
+}
+
+func sendCommentError(w http.ResponseWriter, err error) {
+	var notFound store.ErrNotFound
+	var noComment task.ErrNoComment
+	if errors.As(err, &notFound) || errors.As(err, &noComment) {
+		sendError(w, http.StatusNotFound, err)
+		return
+	}
+	sendError(w, http.StatusUnprocessableEntity, err)
+}
+
+func sendComments(w http.ResponseWriter, code int, comments ...task.Comment) {
+	apiComments := make([]apiv1.Comment, 0, len(comments))
+	for _, cm := range comments {
+		apiComments = append(apiComments, ledger.CommentToAPIv1(cm))
+	}
+	resp := apiv1.Response{
+		Status: apiv1.ResponseSuccess,
+		Result: &apiv1.Result{
+			Comments: apiComments,
+		},
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		panic(err)
+	}
+}
@@ -12,7 +12,7 @@ import (
 )
 
 func (ctrl *Controller) CompletedIndex(w http.ResponseWriter, r *http.Request) {
-	items, err := ctrl.ld.Filter(func(todo model.Todo) bool {
+	items, err := ctrl.ledger(r).Filter(func(todo model.Todo) bool {
 		return todo.Status == apiv1.Completed
 	})
 	if err != nil {
@@ -41,7 +41,7 @@ func (ctrl *Controller) CompletedAssigned(w http.ResponseWriter, r *http.Request
 		sendError(w, http.StatusInternalServerError, fmt.Errorf("missing assignee"))
 		return
 	}
-	items, err := ctrl.ld.Filter(func(todo model.Todo) bool {
+	items, err := ctrl.ledger(r).Filter(func(todo model.Todo) bool {
 		return todo.Status == apiv1.Completed && todo.Assignee == assignee
 	})
 	if err != nil {
@@ -0,0 +1,53 @@
+package controller_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gotestbootcamp/go-todo-app/api/jsonpatch"
+	apiv1 "github.com/gotestbootcamp/go-todo-app/api/v1"
+	"github.com/gotestbootcamp/go-todo-app/controller"
+	"github.com/gotestbootcamp/go-todo-app/model"
+	"github.com/gotestbootcamp/go-todo-app/store"
+)
+
+func TestTodoContexts(t *testing.T) {
+	ld := memoryStorage()
+	for _, id := range []store.ID{"1", "2", "3"} {
+		require.NoError(t, ld.Set(id, model.New("todo "+string(id))))
+	}
+	ctrl := controller.NewWithAuth(ld, store.NewSequentialIDs(nil), nil, nil)
+
+	do := func(method, path, body string) (int, apiv1.Response) {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", jsonpatch.MergePatchType)
+		w := httptest.NewRecorder()
+		ctrl.ServeHTTP(w, req)
+		var resp apiv1.Response
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return w.Code, resp
+	}
+	code, resp := do("PATCH", "/todos/1", `{"contexts":["home","phone"]}`)
+	require.Equal(t, http.StatusOK, code)
+	require.Len(t, resp.Result.Items, 1)
+	assert.Equal(t, []string{"home", "phone"}, resp.Result.Items[0].Todo.Contexts)
+	code, _ = do("PATCH", "/todos/3", `{"contexts":["home"]}`)
+	require.Equal(t, http.StatusOK, code)
+
+	code, resp = do("GET", "/todos?context=home", "")
+	require.Equal(t, http.StatusOK, code)
+	var ids []apiv1.ID
+	for _, item := range resp.Result.Items {
+		ids = append(ids, item.ID)
+	}
+	assert.Equal(t, []apiv1.ID{"1", "3"}, ids)
+
+	code, _ = do("PATCH", "/todos/2", `{"contexts":["@home"]}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, code)
+}
@@ -2,21 +2,57 @@ package controller
 
 import (
 	"encoding/json"
-	"log"
+	"errors"
+	"log/slog"
 	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
 
 	"github.com/gorilla/mux"
 
+	"github.com/gotestbootcamp/go-todo-app/api/openapi"
 	apiv1 "github.com/gotestbootcamp/go-todo-app/api/v1"
+	"github.com/gotestbootcamp/go-todo-app/auth"
+	"github.com/gotestbootcamp/go-todo-app/jobs"
 	"github.com/gotestbootcamp/go-todo-app/ledger"
 	"github.com/gotestbootcamp/go-todo-app/middleware"
+	"github.com/gotestbootcamp/go-todo-app/notify"
+	"github.com/gotestbootcamp/go-todo-app/store"
+	"github.com/gotestbootcamp/go-todo-app/user"
 	"github.com/gotestbootcamp/go-todo-app/uuid"
+	"github.com/gotestbootcamp/go-todo-app/webhook"
 )
 
+// ActorHeader is the request header naming who makes the request, recorded in the history of the todos
+const ActorHeader = "X-Actor"
+
 type Controller struct {
-	router  *mux.Router
-	ld      *ledger.Ledger
-	uuidGen uuid.UUIDGenerator
+	router *mux.Router
+	ld     *ledger.Ledger
+	ids    store.IDGenerator
+	// api is the OpenAPI document describing the routes
+	api *openapi.Document
+	// auth authenticates the requests. Nil if the API is open.
+	auth *auth.Authenticator
+	// users are the users owning the todos. Nil if the API is open.
+	users *user.Directory
+	// webhooks are the webhooks the events of the todos are delivered to. Nil unless served.
+	webhooks *webhook.Dispatcher
+	// notifiers post the events of the todos to the chat services. Nil unless served.
+	notifiers *notify.Notifiers
+	// scheduler runs the maintenance jobs. Nil unless served.
+	scheduler *jobs.Scheduler
+}
+
+// remoteUUIDs generates the IDs with the remote UUID service
+type remoteUUIDs struct {
+	gen uuid.UUIDGenerator
+}
+
+func (ru remoteUUIDs) NewID() (store.ID, error) {
+	id, err := ru.gen.NewUUID()
+	return store.ID(id), err
 }
 
 type Route struct {
@@ -24,13 +60,44 @@ type Route struct {
 	Method  string
 	Pattern string
 	Handler http.HandlerFunc
+	// Body is a value of the type of the JSON request body, validated before the handler runs. Nil if none.
+	Body any
+	// Scope is the scope the requests need, when the controller authenticates them. Empty requires
+	// auth.ScopeRead for GET, and auth.ScopeWrite otherwise.
+	Scope auth.Scope
+	// Public routes don't need any authentication
+	Public bool
+	// KeyParam routes also take the API key in the query parameter auth.KeyParam, for the clients
+	// which can't set the headers, see auth.KeyFromQuery
+	KeyParam bool
 }
 
+// New creates the controller of the ledger, which gets the IDs of the new
+// todos from the remote UUID service
 func New(ld *ledger.Ledger) http.Handler {
+	return NewWithIDs(ld, remoteUUIDs{gen: uuid.New()})
+}
+
+// NewWithIDs creates the controller of the ledger, which gets the IDs
+// of the new todos from the given generator
+func NewWithIDs(ld *ledger.Ledger, ids store.IDGenerator) http.Handler {
+	return NewWithAuth(ld, ids, nil, nil)
+}
+
+// NewWithAuth is like NewWithIDs, with the requests authenticated by au, which also serves the
+// routes managing the API keys and tokens, and the users. The requests change only the todos of
+// their user, unless admin. The nil au leaves the API open, and ignores the users.
+func NewWithAuth(ld *ledger.Ledger, ids store.IDGenerator, au *auth.Authenticator, users *user.Directory) *Controller {
+	if au == nil {
+		users = nil
+	}
 	ctrl := Controller{
-		ld:      ld,
-		uuidGen: uuid.New(),
-		router:  mux.NewRouter().StrictSlash(true),
+		ld:     ld,
+		ids:    ids,
+		auth:   au,
+		users:  users,
+		router: mux.NewRouter().StrictSlash(true).UseEncodedPath(),
+		api:    openapi.New(openapi.Info{Title: "todo", Version: "v1"}, reflect.TypeOf(apiv1.Response{})),
 	}
 	routes := []Route{
 		Route{
@@ -68,6 +135,7 @@ func New(ld *ledger.Ledger) http.Handler {
 			Method:  "POST",
 			Pattern: "/todos",
 			Handler: ctrl.TodoCreate,
+			Body:    apiv1.Todo{},
 		},
 		Route{
 			Name:    "todo.show",
@@ -81,6 +149,7 @@ func New(ld *ledger.Ledger) http.Handler {
 			Method:  "PUT",
 			Pattern: "/todos/{todoID}",
 			Handler: ctrl.TodoUpdate,
+			Body:    apiv1.Todo{},
 		},
 		// you can complete a TODO just once
 		Route{
@@ -88,6 +157,7 @@ func New(ld *ledger.Ledger) http.Handler {
 			Method:  "POST",
 			Pattern: "/todos/{todoID}/complete",
 			Handler: ctrl.TodoComplete,
+			Body:    apiv1.Todo{},
 		},
 		// you can delete a TODO just once
 		Route{
@@ -95,6 +165,434 @@ func New(ld *ledger.Ledger) http.Handler {
 			Method:  "POST",
 			Pattern: "/todos/{todoID}/delete",
 			Handler: ctrl.TodoDelete,
+			Body:    apiv1.Todo{},
+		},
+		Route{
+			Name:    "todo.search",
+			Method:  "GET",
+			Pattern: "/search",
+			Handler: ctrl.TodoSearch,
+		},
+		Route{
+			Name:    "todo.find",
+			Method:  "GET",
+			Pattern: "/find/{field}/{value}",
+			Handler: ctrl.TodoFind,
+		},
+		// you can move a TODO only along the transitions of the workflow
+		Route{
+			Name:    "todo.transition",
+			Method:  "POST",
+			Pattern: "/todos/{todoID}/transition/{status}",
+			Handler: ctrl.TodoTransition,
+		},
+		// the todos of a list keep the manual order they are moved in
+		Route{
+			Name:    "todo.move",
+			Method:  "POST",
+			Pattern: "/todos/{todoID}/move",
+			Handler: ctrl.TodoMove,
+			Body:    apiv1.Move{},
+		},
+		Route{
+			Name:    "todo.schedule",
+			Method:  "PUT",
+			Pattern: "/todos/{todoID}/schedule",
+			Handler: ctrl.TodoSchedule,
+			Body:    apiv1.Todo{},
+		},
+		Route{
+			Name:    "overdue.index",
+			Method:  "GET",
+			Pattern: "/overdue",
+			Handler: ctrl.OverdueIndex,
+		},
+		Route{
+			Name:    "due.index",
+			Method:  "GET",
+			Pattern: "/due",
+			Handler: ctrl.DueIndex,
+		},
+		// the calendar apps subscribing to the feed can't set the headers
+		Route{
+			Name:     "calendar",
+			Method:   "GET",
+			Pattern:  "/calendar.ics",
+			Handler:  ctrl.Calendar,
+			KeyParam: true,
+		},
+		Route{
+			Name:    "todo.block",
+			Method:  "PUT",
+			Pattern: "/todos/{todoID}/blockers/{blockerID}",
+			Handler: ctrl.TodoBlock,
+		},
+		Route{
+			Name:    "todo.unblock",
+			Method:  "DELETE",
+			Pattern: "/todos/{todoID}/blockers/{blockerID}",
+			Handler: ctrl.TodoUnblock,
+		},
+		Route{
+			Name:    "todo.blocks",
+			Method:  "GET",
+			Pattern: "/todos/{todoID}/blocks",
+			Handler: ctrl.TodoBlocks,
+		},
+		Route{
+			Name:    "blocked.index",
+			Method:  "GET",
+			Pattern: "/blocked",
+			Handler: ctrl.BlockedIndex,
+		},
+		Route{
+			Name:    "todo.tag",
+			Method:  "PUT",
+			Pattern: "/todos/{todoID}/tags/{tag}",
+			Handler: ctrl.TodoTag,
+		},
+		Route{
+			Name:    "todo.untag",
+			Method:  "DELETE",
+			Pattern: "/todos/{todoID}/tags/{tag}",
+			Handler: ctrl.TodoUntag,
+		},
+		Route{
+			Name:    "tag.index",
+			Method:  "GET",
+			Pattern: "/tags",
+			Handler: ctrl.TagIndex,
+		},
+		Route{
+			Name:    "tag.define",
+			Method:  "PUT",
+			Pattern: "/tags/{tag}",
+			Handler: ctrl.TagDefine,
+			Body:    apiv1.Tag{},
+		},
+		Route{
+			Name:    "tag.delete",
+			Method:  "DELETE",
+			Pattern: "/tags/{tag}",
+			Handler: ctrl.TagDelete,
+		},
+		// renaming a tag rewrites all the todos with the tag
+		Route{
+			Name:    "tag.rename",
+			Method:  "POST",
+			Pattern: "/tags/{tag}/rename/{newTag}",
+			Handler: ctrl.TagRename,
+		},
+		Route{
+			Name:    "tagged.index",
+			Method:  "GET",
+			Pattern: "/tagged",
+			Handler: ctrl.TaggedIndex,
+		},
+		Route{
+			Name:    "list.index",
+			Method:  "GET",
+			Pattern: "/lists",
+			Handler: ctrl.ListIndex,
+		},
+		// the saved filters list the todos matching their search query, like the lists
+		Route{
+			Name:    "filter.index",
+			Method:  "GET",
+			Pattern: "/filters",
+			Handler: ctrl.FilterIndex,
+		},
+		Route{
+			Name:    "filter.create",
+			Method:  "POST",
+			Pattern: "/filters",
+			Handler: ctrl.FilterCreate,
+			Body:    apiv1.Filter{},
+		},
+		Route{
+			Name:    "filter.update",
+			Method:  "PUT",
+			Pattern: "/filters/{name}",
+			Handler: ctrl.FilterUpdate,
+			Body:    apiv1.Filter{},
+		},
+		Route{
+			Name:    "filter.delete",
+			Method:  "DELETE",
+			Pattern: "/filters/{name}",
+			Handler: ctrl.FilterDelete,
+		},
+		Route{
+			Name:    "filter.todos",
+			Method:  "GET",
+			Pattern: "/filters/{name}/todos",
+			Handler: ctrl.FilterTodos,
+		},
+		// the boards group the todos in columns, kanban style
+		Route{
+			Name:    "board.show",
+			Method:  "GET",
+			Pattern: "/board",
+			Handler: ctrl.BoardShow,
+		},
+		Route{
+			Name:    "board.move",
+			Method:  "POST",
+			Pattern: "/board/move",
+			Handler: ctrl.BoardMove,
+			Body:    apiv1.BoardMove{},
+		},
+		Route{
+			Name:    "list.members.index",
+			Method:  "GET",
+			Pattern: "/lists/{list}/members",
+			Handler: ctrl.MemberIndex,
+		},
+		// only the admins of a list manage its members, as the ledger enforces
+		Route{
+			Name:    "list.members.invite",
+			Method:  "POST",
+			Pattern: "/lists/{list}/members",
+			Handler: ctrl.MemberInvite,
+			Body:    apiv1.Member{},
+		},
+		Route{
+			Name:    "list.members.update",
+			Method:  "PUT",
+			Pattern: "/lists/{list}/members/{user}",
+			Handler: ctrl.MemberUpdate,
+			Body:    apiv1.Member{},
+		},
+		Route{
+			Name:    "list.members.remove",
+			Method:  "DELETE",
+			Pattern: "/lists/{list}/members/{user}",
+			Handler: ctrl.MemberRemove,
+		},
+		Route{
+			Name:    "list.fields.index",
+			Method:  "GET",
+			Pattern: "/lists/{list}/fields",
+			Handler: ctrl.FieldIndex,
+		},
+		// only the admins of a list define its custom fields, as the ledger enforces
+		Route{
+			Name:    "list.fields.define",
+			Method:  "PUT",
+			Pattern: "/lists/{list}/fields/{name}",
+			Handler: ctrl.FieldDefine,
+			Body:    apiv1.FieldDef{},
+		},
+		Route{
+			Name:    "list.fields.remove",
+			Method:  "DELETE",
+			Pattern: "/lists/{list}/fields/{name}",
+			Handler: ctrl.FieldRemove,
+		},
+		Route{
+			Name:    "todo.priority",
+			Method:  "PUT",
+			Pattern: "/todos/{todoID}/priority/{priority}",
+			Handler: ctrl.TodoPriority,
+		},
+		Route{
+			Name:    "urgent.index",
+			Method:  "GET",
+			Pattern: "/urgent",
+			Handler: ctrl.UrgentIndex,
+		},
+		Route{
+			Name:    "todo.history",
+			Method:  "GET",
+			Pattern: "/todos/{todoID}/history",
+			Handler: ctrl.TodoHistory,
+		},
+		Route{
+			Name:    "todo.revert",
+			Method:  "POST",
+			Pattern: "/todos/{todoID}/revert/{rev}",
+			Handler: ctrl.TodoRevert,
+		},
+		Route{
+			Name:    "undo",
+			Method:  "POST",
+			Pattern: "/undo",
+			Handler: ctrl.Undo,
+		},
+		Route{
+			Name:    "redo",
+			Method:  "POST",
+			Pattern: "/redo",
+			Handler: ctrl.Redo,
+		},
+		Route{
+			Name:    "bulk.complete",
+			Method:  "POST",
+			Pattern: "/bulk/complete",
+			Handler: ctrl.BulkComplete,
+		},
+		Route{
+			Name:    "bulk.retag",
+			Method:  "POST",
+			Pattern: "/bulk/retag",
+			Handler: ctrl.BulkRetag,
+			Body:    apiv1.Retag{},
+		},
+		Route{
+			Name:    "bulk.move",
+			Method:  "POST",
+			Pattern: "/bulk/move",
+			Handler: ctrl.BulkMove,
+		},
+		Route{
+			Name:    "tasks.batch",
+			Method:  "POST",
+			Pattern: "/tasks:batch",
+			Handler: ctrl.BatchApply,
+			Body:    apiv1.Batch{},
+		},
+		Route{
+			Name:    "archive",
+			Method:  "POST",
+			Pattern: "/archive",
+			Handler: ctrl.Archive,
+		},
+		Route{
+			Name:    "archived.index",
+			Method:  "GET",
+			Pattern: "/archived",
+			Handler: ctrl.ArchivedIndex,
+		},
+		Route{
+			Name:    "todo.unarchive",
+			Method:  "POST",
+			Pattern: "/todos/{todoID}/unarchive",
+			Handler: ctrl.TodoUnarchive,
+		},
+		Route{
+			Name:    "todo.timer.start",
+			Method:  "POST",
+			Pattern: "/todos/{todoID}/timer/start",
+			Handler: ctrl.TimerStart,
+		},
+		Route{
+			Name:    "todo.timer.stop",
+			Method:  "POST",
+			Pattern: "/todos/{todoID}/timer/stop",
+			Handler: ctrl.TimerStop,
+		},
+		Route{
+			Name:    "timereport",
+			Method:  "GET",
+			Pattern: "/timereport",
+			Handler: ctrl.TimeReport,
+		},
+		Route{
+			Name:    "stats",
+			Method:  "GET",
+			Pattern: "/stats",
+			Handler: ctrl.Stats,
+		},
+		Route{
+			Name:    "burndown",
+			Method:  "GET",
+			Pattern: "/burndown",
+			Handler: ctrl.Burndown,
+		},
+		Route{
+			Name:    "burndown.csv",
+			Method:  "GET",
+			Pattern: "/burndown.csv",
+			Handler: ctrl.BurndownCSV,
+		},
+		Route{
+			Name:    "todo.comments.index",
+			Method:  "GET",
+			Pattern: "/todos/{todoID}/comments",
+			Handler: ctrl.CommentIndex,
+		},
+		Route{
+			Name:    "todo.comments.create",
+			Method:  "POST",
+			Pattern: "/todos/{todoID}/comments",
+			Handler: ctrl.CommentCreate,
+			Body:    apiv1.Comment{},
+		},
+		Route{
+			Name:    "todo.comments.update",
+			Method:  "PUT",
+			Pattern: "/todos/{todoID}/comments/{commentID}",
+			Handler: ctrl.CommentUpdate,
+			Body:    apiv1.Comment{},
+		},
+		Route{
+			Name:    "todo.comments.delete",
+			Method:  "DELETE",
+			Pattern: "/todos/{todoID}/comments/{commentID}",
+			Handler: ctrl.CommentDelete,
+		},
+		Route{
+			Name:    "todo.checklist.create",
+			Method:  "POST",
+			Pattern: "/todos/{todoID}/checklist",
+			Handler: ctrl.CheckItemCreate,
+			Body:    apiv1.CheckItem{},
+		},
+		Route{
+			Name:    "todo.checklist.toggle",
+			Method:  "POST",
+			Pattern: "/todos/{todoID}/checklist/{itemID}/toggle",
+			Handler: ctrl.CheckItemToggle,
+		},
+		Route{
+			Name:    "todo.checklist.delete",
+			Method:  "DELETE",
+			Pattern: "/todos/{todoID}/checklist/{itemID}",
+			Handler: ctrl.CheckItemDelete,
+		},
+		Route{
+			Name:    "todo.links.index",
+			Method:  "GET",
+			Pattern: "/todos/{todoID}/links",
+			Handler: ctrl.LinkIndex,
+		},
+		Route{
+			Name:    "todo.links.create",
+			Method:  "POST",
+			Pattern: "/todos/{todoID}/links",
+			Handler: ctrl.LinkCreate,
+			Body:    apiv1.Link{},
+		},
+		Route{
+			Name:    "todo.links.delete",
+			Method:  "DELETE",
+			Pattern: "/todos/{todoID}/links",
+			Handler: ctrl.LinkDelete,
+		},
+		Route{
+			Name:    "todo.patch",
+			Method:  "PATCH",
+			Pattern: "/todos/{todoID}",
+			Handler: ctrl.TodoPatch,
+			Body:    apiv1.TodoPatch{},
+		},
+		Route{
+			Name:    "todo.remove",
+			Method:  "DELETE",
+			Pattern: "/todos/{todoID}",
+			Handler: ctrl.TodoRemove,
+		},
+		Route{
+			Name:    "events",
+			Method:  "GET",
+			Pattern: "/events",
+			Handler: ctrl.Events,
+		},
+		Route{
+			Name:    "changes.index",
+			Method:  "GET",
+			Pattern: "/changes",
+			Handler: ctrl.ChangeIndex,
 		},
 		Route{
 			Name:    "todo.merge",
@@ -104,18 +602,222 @@ func New(ld *ledger.Ledger) http.Handler {
 		},
 	}
 
-	for _, route := range routes {
-		ctrl.router.Methods(route.Method).Path(route.Pattern).Name(route.Name).Handler(middleware.Logger(route.Handler, route.Name))
-		log.Printf("API: method: %-8s route: %s", route.Method, route.Pattern)
+	if au != nil {
+		routes = append(routes,
+			Route{
+				Name:    "auth.token",
+				Method:  "POST",
+				Pattern: "/auth/token",
+				Handler: ctrl.AuthToken,
+				Body:    apiv1.TokenRequest{},
+				Scope:   auth.ScopeRead,
+			},
+			Route{
+				Name:    "auth.keys.index",
+				Method:  "GET",
+				Pattern: "/auth/keys",
+				Handler: ctrl.KeyIndex,
+				Scope:   auth.ScopeAdmin,
+			},
+			Route{
+				Name:    "auth.keys.create",
+				Method:  "POST",
+				Pattern: "/auth/keys",
+				Handler: ctrl.KeyCreate,
+				Body:    apiv1.APIKey{},
+				Scope:   auth.ScopeAdmin,
+			},
+			Route{
+				Name:    "auth.keys.revoke",
+				Method:  "DELETE",
+				Pattern: "/auth/keys/{keyID}",
+				Handler: ctrl.KeyRevoke,
+				Scope:   auth.ScopeAdmin,
+			},
+		)
+		if len(au.Providers) > 0 {
+			routes = append(routes,
+				Route{
+					Name:    "auth.oidc.login",
+					Method:  "GET",
+					Pattern: "/auth/oidc/{provider}/login",
+					Handler: ctrl.OIDCLogin,
+					Public:  true,
+				},
+				Route{
+					Name:    "auth.oidc.callback",
+					Method:  "GET",
+					Pattern: "/auth/oidc/{provider}/callback",
+					Handler: ctrl.OIDCCallback,
+					Public:  true,
+				},
+				Route{
+					Name:    "auth.logout",
+					Method:  "POST",
+					Pattern: "/auth/logout",
+					Handler: au.Logout,
+					Public:  true,
+				},
+			)
+		}
+	}
+	if users != nil {
+		routes = append(routes,
+			Route{
+				Name:    "me",
+				Method:  "GET",
+				Pattern: "/me",
+				Handler: ctrl.Me,
+			},
+			Route{
+				Name:    "users.index",
+				Method:  "GET",
+				Pattern: "/users",
+				Handler: ctrl.UserIndex,
+			},
+			Route{
+				Name:    "users.show",
+				Method:  "GET",
+				Pattern: "/users/{name}",
+				Handler: ctrl.UserShow,
+			},
+			Route{
+				Name:    "users.create",
+				Method:  "POST",
+				Pattern: "/users",
+				Handler: ctrl.UserCreate,
+				Body:    apiv1.User{},
+				Scope:   auth.ScopeAdmin,
+			},
+			Route{
+				Name:    "users.update",
+				Method:  "PUT",
+				Pattern: "/users/{name}",
+				Handler: ctrl.UserUpdate,
+				Body:    apiv1.User{},
+				Scope:   auth.ScopeAdmin,
+			},
+			Route{
+				Name:    "users.delete",
+				Method:  "DELETE",
+				Pattern: "/users/{name}",
+				Handler: ctrl.UserDelete,
+				Scope:   auth.ScopeAdmin,
+			},
+		)
 	}
+
+	routes = append(routes, Route{
+		Name:    "openapi",
+		Method:  "GET",
+		Pattern: "/openapi.json",
+		Handler: ctrl.OpenAPI,
+		Public:  true,
+	})
+
+	ctrl.handle(routes...)
 	return &ctrl
 }
 
+// handle adds the routes to the router and to the OpenAPI document
+func (ctrl *Controller) handle(routes ...Route) {
+	for _, route := range routes {
+		var body reflect.Type
+		if route.Body != nil {
+			body = reflect.TypeOf(route.Body)
+		}
+		op := ctrl.api.AddOperation(route.Method, route.Pattern, route.Name, body)
+		var handler http.Handler = route.Handler
+		if body != nil {
+			handler = ctrl.validated(op, route.Handler)
+		}
+		if ctrl.auth != nil && !route.Public {
+			handler = ctrl.auth.Require(route.scope(), handler)
+		}
+		handler = middleware.Logger(unescapeVars(handler), route.Name)
+		if ctrl.auth != nil && route.KeyParam {
+			handler = auth.KeyFromQuery(handler)
+		}
+		ctrl.router.Methods(route.Method).Path(route.Pattern).Name(route.Name).Handler(handler)
+		slog.Debug("API: route", "method", route.Method, "pattern", route.Pattern, "name", route.Name)
+	}
+}
+
+// unescapeVars unescapes the variables of the route, which the router matches escaped so that the
+// IDs of the todos of the lists, like "work/1", can be given as "work%2F1"
+func unescapeVars(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		for name, val := range vars {
+			unescaped, err := url.PathUnescape(val)
+			if err != nil {
+				sendError(w, http.StatusBadRequest, err)
+				return
+			}
+			vars[name] = unescaped
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Instrument records the requests the controller routes, by the name of their route
+func (ctrl *Controller) Instrument(rec middleware.RequestRecorder) {
+	ctrl.router.Use(middleware.Instrument(rec))
+}
+
+// Trace traces the requests the controller routes, by the name of their route
+func (ctrl *Controller) Trace() {
+	ctrl.router.Use(middleware.Trace())
+}
+
+// scope returns the scope the requests of the route need
+func (route Route) scope() auth.Scope {
+	switch {
+	case route.Scope != "":
+		return route.Scope
+	case route.Method == "GET":
+		return auth.ScopeRead
+	default:
+		return auth.ScopeWrite
+	}
+}
+
+// actor returns who makes the request: the name of the API key of the authenticated requests,
+// regardless of the X-Actor header
+func (ctrl *Controller) actor(r *http.Request) string {
+	if id, ok := auth.FromContext(r.Context()); ok {
+		return id.Name
+	}
+	return r.Header.Get(ActorHeader)
+}
+
+// ledger returns the view of the ledger recording the mutations as made by the actor of the request,
+// and including the archived todos if the request sets the include_archived query parameter, whose
+// storage operations are traced as part of the request.
+// The authenticated requests act as the user named like their API key, who owns the todos they create.
+func (ctrl *Controller) ledger(r *http.Request) *ledger.Ledger {
+	var ld *ledger.Ledger
+	if id, ok := auth.FromContext(r.Context()); ok {
+		ld = ctrl.ld.AsUser(id.Name, ctrl.users.IsAdmin(id))
+	} else {
+		ld = ctrl.ld.As(r.Header.Get(ActorHeader))
+	}
+	if include, _ := strconv.ParseBool(r.URL.Query().Get("include_archived")); include {
+		ld = ld.WithArchived()
+	}
+	return ld.WithContext(r.Context())
+}
+
 func (ctrl *Controller) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 	ctrl.router.ServeHTTP(w, req)
 }
 
+// sendError answers the request with the error. The ledger forbids changing the todos of the other
+// users the same way whatever the change, so ledger.ErrForbidden is always answered 403.
 func sendError(w http.ResponseWriter, code int, err error) {
+	if errors.Is(err, ledger.ErrForbidden) {
+		code = http.StatusForbidden
+	}
 	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
 	w.WriteHeader(code)
 	resp := apiv1.Response{
@@ -131,6 +833,11 @@ func sendError(w http.ResponseWriter, code int, err error) {
 }
 
 func sendItem(w http.ResponseWriter, id apiv1.ID, todo *apiv1.Todo) {
+	sendItemStatus(w, http.StatusCreated, id, todo)
+}
+
+// sendItemStatus sends the todo with the given status code
+func sendItemStatus(w http.ResponseWriter, code int, id apiv1.ID, todo *apiv1.Todo) {
 	resp := apiv1.Response{
 		Status: apiv1.ResponseSuccess,
 		Result: &apiv1.Result{
@@ -144,7 +851,7 @@ func sendItem(w http.ResponseWriter, id apiv1.ID, todo *apiv1.Todo) {
 	}
 
 	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
-	w.WriteHeader(http.StatusCreated)
+	w.WriteHeader(code)
 	if err := json.NewEncoder(w).Encode(resp); err != nil {
 		panic(err)
 	}
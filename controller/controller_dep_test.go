@@ -6,13 +6,19 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
 	apiv1 "github.com/gotestbootcamp/go-todo-app/api/v1"
 	"github.com/gotestbootcamp/go-todo-app/controller"
 	"github.com/gotestbootcamp/go-todo-app/ledger"
 	"github.com/gotestbootcamp/go-todo-app/model"
+	"github.com/gotestbootcamp/go-todo-app/store"
 	"github.com/gotestbootcamp/go-todo-app/store/fake"
+	"github.com/gotestbootcamp/go-todo-app/task"
 )
 
 // exercise
@@ -57,6 +63,46 @@ func memoryStorage() *ledger.Ledger {
 
 	return ldg
 }
+
+// fixedID generates always the same ID
+type fixedID store.ID
+
+func (id fixedID) NewID() (store.ID, error) {
+	return store.ID(id), nil
+}
+
+func TestTodoCreateExisting(t *testing.T) {
+	ld := memoryStorage()
+	require.NoError(t, ld.Set("1", model.New("created by a client of the store")))
+	handler := controller.NewWithAuth(ld, fixedID("1"), nil, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/todos", strings.NewReader(`{"title":"foo"}`))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	todo, err := ld.Get("1")
+	require.NoError(t, err)
+	assert.Equal(t, "created by a client of the store", todo.Title)
+}
+
+func TestTodoCreateWorkflow(t *testing.T) {
+	st, err := fake.NewMem()
+	require.NoError(t, err)
+	wf, err := task.ParseWorkflow("todo>doing,done")
+	require.NoError(t, err)
+	ld, err := ledger.NewWithWorkflow(st, wf)
+	require.NoError(t, err)
+	handler := controller.NewWithAuth(ld, fixedID("1"), nil, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/todos", strings.NewReader(`{"title":"foo"}`))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	todo, err := ld.Get("1")
+	require.NoError(t, err)
+	assert.Equal(t, apiv1.Status("todo"), todo.Status)
+}
+
 func bodyFromTodo(t model.Todo) io.Reader {
 	serialized, err := t.Serialize()
 	if err != nil {
@@ -66,3 +112,16 @@ func bodyFromTodo(t model.Todo) io.Reader {
 	res := bytes.NewReader(serialized)
 	return res
 }
+
+// serve serves the request with the handler, returning the status code and the response, if any
+func serve(t *testing.T, h http.Handler, method, url, body string) (int, apiv1.Response) {
+	t.Helper()
+	req := httptest.NewRequest(method, url, strings.NewReader(body))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	var resp apiv1.Response
+	if w.Body.Len() > 0 {
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	}
+	return w.Code, resp
+}
@@ -0,0 +1,67 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	apiv1 "github.com/gotestbootcamp/go-todo-app/api/v1"
+)
+
+// defaultDueWithin is the horizon of DueIndex when the request doesn't set one
+const defaultDueWithin = 24 * time.Hour
+
+func (ctrl *Controller) OverdueIndex(w http.ResponseWriter, r *http.Request) {
+	items, err := ctrl.ledger(r).ListOverdue()
+	if err != nil {
+		sendError(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	resp := apiv1.Response{
+		Status: apiv1.ResponseSuccess,
+		Result: &apiv1.Result{
+			Items: items.ToAPIv1(),
+		},
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		panic(err)
+	}
+}
+
+/*
+curl http://localhost:8080/due?within=72h
+*/
+func (ctrl *Controller) DueIndex(w http.ResponseWriter, r *http.Request) {
+	within := defaultDueWithin
+	if val := r.URL.Query().Get("within"); val != "" {
+		var err error
+		within, err = time.ParseDuration(val)
+		if err != nil || within < 0 {
+			sendError(w, http.StatusBadRequest, fmt.Errorf("invalid duration %q", val))
+			return
+		}
+	}
+	items, err := ctrl.ledger(r).ListDueWithin(within)
+	if err != nil {
+		sendError(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	resp := apiv1.Response{
+		Status: apiv1.ResponseSuccess,
+		Result: &apiv1.Result{
+			Items: items.ToAPIv1(),
+		},
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,63 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gotestbootcamp/go-todo-app/api/jsonpatch"
+	"github.com/gotestbootcamp/go-todo-app/ledger"
+	"github.com/gotestbootcamp/go-todo-app/store"
+	"github.com/gotestbootcamp/go-todo-app/task"
+)
+
+// The ETag of a todo is its revision in the ledger, quoted: e.g. "3"
+
+// setETag sets the ETag header of the response to the revision of the todo
+func setETag(w http.ResponseWriter, rev int) {
+	w.Header().Set("ETag", strconv.Quote(strconv.Itoa(rev)))
+}
+
+// ifMatch returns the revision the If-Match header of the request expects the todo to have,
+// or ledger.AnyRevision if the header is missing or "*"
+func ifMatch(r *http.Request) (int, error) {
+	val := strings.TrimSpace(r.Header.Get("If-Match"))
+	if val == "" || val == "*" {
+		return ledger.AnyRevision, nil
+	}
+	unquoted, err := strconv.Unquote(val)
+	if err != nil {
+		return 0, fmt.Errorf("invalid If-Match %q", val)
+	}
+	rev, err := strconv.Atoi(unquoted)
+	if err != nil || rev < 0 {
+		return 0, fmt.Errorf("invalid If-Match %q", val)
+	}
+	return rev, nil
+}
+
+// sendWriteError sends the error of a conditional mutation of a todo: 404 if the todo doesn't
+// exist, 412 if its revision doesn't match the If-Match header, 409 if the workflow doesn't allow
+// its new status or if a test of its JSON Patch fails, 422 otherwise
+func sendWriteError(w http.ResponseWriter, err error) {
+	sendError(w, writeStatus(err), err)
+}
+
+// writeStatus returns the status code of the mutation failed with the error, see sendWriteError
+func writeStatus(err error) int {
+	var notFound store.ErrNotFound
+	var conflict store.ErrConflict
+	var illegal task.ErrIllegalTransition
+	switch {
+	case errors.As(err, &notFound):
+		return http.StatusNotFound
+	case errors.As(err, &conflict):
+		return http.StatusPreconditionFailed
+	case errors.As(err, &illegal), errors.Is(err, jsonpatch.ErrTestFailed):
+		return http.StatusConflict
+	default:
+		return http.StatusUnprocessableEntity
+	}
+}
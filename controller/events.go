@@ -0,0 +1,151 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/websocket"
+
+	"github.com/gotestbootcamp/go-todo-app/ledger"
+	"github.com/gotestbootcamp/go-todo-app/server"
+	"github.com/gotestbootcamp/go-todo-app/store"
+)
+
+// keepAlive is how often the idle server-sent event streams send a comment, for the proxies
+// not to close them
+const keepAlive = 15 * time.Second
+
+/*
+Streams the changes of the todos as server-sent events, or over WebSocket if the request upgrades, from
+the pages of the server only. The events resume after the one given by the Last-Event-ID header or by the since query parameter.
+
+curl -N http://localhost:8080/events
+curl -N -H "Last-Event-ID: 1728981234567890" http://localhost:8080/events
+websocat ws://localhost:8080/events?since=1728981234567890
+*/
+func (ctrl *Controller) Events(w http.ResponseWriter, r *http.Request) {
+	lastID := r.Header.Get("Last-Event-ID")
+	if lastID == "" {
+		lastID = r.URL.Query().Get("since")
+	}
+	var since uint64
+	if lastID != "" {
+		var err error
+		if since, err = strconv.ParseUint(lastID, 10, 64); err != nil {
+			sendError(w, http.StatusBadRequest, fmt.Errorf("invalid event ID %q", lastID))
+			return
+		}
+	}
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	go func() {
+		// the streams end when the server shuts down
+		select {
+		case <-server.Stopping(r.Context()):
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	events, err := ctrl.ledger(r).Subscribe(ctx, since)
+	switch {
+	case errors.Is(err, store.ErrUnsupported):
+		sendError(w, http.StatusNotImplemented, err)
+		return
+	case errors.Is(err, ledger.ErrEventsExpired):
+		sendError(w, http.StatusGone, err)
+		return
+	case err != nil:
+		sendError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+		srv := websocket.Server{
+			Handshake: checkOrigin,
+			Handler: func(ws *websocket.Conn) {
+				streamWebSocket(ws, events, cancel)
+			},
+		}
+		srv.ServeHTTP(w, r)
+		return
+	}
+	streamSSE(w, r, events)
+}
+
+// checkOrigin fails the WebSocket handshakes of the pages of the other sites, not to let them read the
+// events with the cookies of the users. The clients not sending the Origin header, not browsers, may
+// connect.
+func checkOrigin(_ *websocket.Config, r *http.Request) error {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return nil
+	}
+	u, err := url.Parse(origin)
+	if err != nil || !strings.EqualFold(u.Host, r.Host) {
+		return fmt.Errorf("origin %q not allowed", origin)
+	}
+	return nil
+}
+
+// streamSSE sends the events as server-sent events, until the channel is closed
+func streamSSE(w http.ResponseWriter, r *http.Request, events <-chan ledger.Event) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		sendError(w, http.StatusInternalServerError, errors.New("streaming unsupported"))
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	ticker := time.NewTicker(keepAlive)
+	defer ticker.Stop()
+	for {
+		select {
+		case ev, ok := <-events:
+			if !ok {
+				return
+			}
+			data, err := json.Marshal(ev.ToAPIv1())
+			if err != nil {
+				slog.ErrorContext(r.Context(), "API: events: can't encode event", "seq", ev.Seq, "error", err)
+				return
+			}
+			if _, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", ev.Seq, ev.Type, data); err != nil {
+				return
+			}
+		case <-ticker.C:
+			if _, err := io.WriteString(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
+
+// streamWebSocket sends the events as JSON messages, until the channel is closed. The client closing
+// the connection calls cancel.
+func streamWebSocket(ws *websocket.Conn, events <-chan ledger.Event, cancel context.CancelFunc) {
+	defer ws.Close()
+	go func() {
+		// the messages of the client are ignored
+		io.Copy(io.Discard, ws)
+		cancel()
+	}()
+	for ev := range events {
+		if err := websocket.JSON.Send(ws, ev.ToAPIv1()); err != nil {
+			return
+		}
+	}
+}
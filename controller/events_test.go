@@ -0,0 +1,41 @@
+package controller_test
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/websocket"
+
+	apiv1 "github.com/gotestbootcamp/go-todo-app/api/v1"
+	"github.com/gotestbootcamp/go-todo-app/controller"
+	"github.com/gotestbootcamp/go-todo-app/ledger"
+	"github.com/gotestbootcamp/go-todo-app/model"
+	"github.com/gotestbootcamp/go-todo-app/store"
+)
+
+func TestEventsWebSocketOrigin(t *testing.T) {
+	st, err := store.NewMemory()
+	require.NoError(t, err)
+	ld, err := ledger.New(store.NewNotifier(st))
+	require.NoError(t, err)
+	defer ld.Close()
+	srv := httptest.NewServer(controller.New(ld))
+	defer srv.Close()
+	url := "ws" + strings.TrimPrefix(srv.URL, "http") + "/events"
+
+	// the pages of the other sites can't read the events
+	_, err = websocket.Dial(url, "", "http://evil.example")
+	assert.Error(t, err)
+
+	ws, err := websocket.Dial(url, "", srv.URL)
+	require.NoError(t, err)
+	defer ws.Close()
+	require.NoError(t, ld.Set("1", model.New("pay rent")))
+	var event apiv1.Event
+	require.NoError(t, websocket.JSON.Receive(ws, &event))
+	assert.Equal(t, "created", event.Type)
+	assert.Equal(t, apiv1.ID("1"), event.Item.ID)
+}
@@ -0,0 +1,77 @@
+package controller_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gotestbootcamp/go-todo-app/api/jsonpatch"
+	apiv1 "github.com/gotestbootcamp/go-todo-app/api/v1"
+	"github.com/gotestbootcamp/go-todo-app/controller"
+	"github.com/gotestbootcamp/go-todo-app/ledger"
+	"github.com/gotestbootcamp/go-todo-app/model"
+	"github.com/gotestbootcamp/go-todo-app/store"
+)
+
+func TestListFields(t *testing.T) {
+	ld := memoryStorage()
+	ctrl := controller.NewWithAuth(ld, store.NewSequentialIDs(nil), nil, nil)
+
+	do := func(method, path, body string) (int, apiv1.Response) {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", jsonpatch.MergePatchType)
+		w := httptest.NewRecorder()
+		ctrl.ServeHTTP(w, req)
+		var resp apiv1.Response
+		if w.Body.Len() > 0 {
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		}
+		return w.Code, resp
+	}
+	code, resp := do("PUT", "/lists/work/fields/severity", `{"type":"enum","values":["low","high"]}`)
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, []apiv1.FieldDef{{Name: "severity", Type: "enum", Values: []string{"low", "high"}}}, resp.Result.Lists[0].Fields)
+	code, _ = do("PUT", "/lists/work/fields/estimate", `{"type":"number"}`)
+	require.Equal(t, http.StatusOK, code)
+	code, _ = do("PUT", "/lists/work/fields/size", `{"type":"color"}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, code)
+	code, resp = do("GET", "/lists/work/fields", "")
+	require.Equal(t, http.StatusOK, code)
+	assert.Len(t, resp.Result.Lists[0].Fields, 2)
+
+	for id, estimate := range map[string]string{"1": "12", "2": "3", "3": "-4"} {
+		id := store.ListID("work", store.ID(id))
+		require.NoError(t, ld.Set(id, model.New("todo "+string(id))))
+		_, _, err := ld.PatchIf(id, ledger.Patch{Fields: &map[string]string{"estimate": estimate, "severity": "low"}}, -1)
+		require.NoError(t, err)
+	}
+	require.NoError(t, ld.Set("4", model.New("outside of the list")))
+	listed := func(resp apiv1.Response) []apiv1.ID {
+		var ids []apiv1.ID
+		for _, item := range resp.Result.Items {
+			ids = append(ids, item.ID)
+		}
+		return ids
+	}
+	code, resp = do("GET", "/todos?field.severity=low&sort=-field.estimate", "")
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, []apiv1.ID{"work/1", "work/2", "work/3"}, listed(resp))
+	assert.Equal(t, map[string]string{"estimate": "12", "severity": "low"}, resp.Result.Items[0].Todo.Fields)
+	code, resp = do("GET", "/todos?field.estimate=3.0", "")
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, []apiv1.ID{"work/2"}, listed(resp))
+
+	// the todos outside of the list have none of its fields
+	code, _ = do("PATCH", "/todos/4", `{"fields":{"severity":"low"}}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, code)
+
+	code, _ = do("DELETE", "/lists/work/fields/severity", "")
+	assert.Equal(t, http.StatusNoContent, code)
+	code, _ = do("DELETE", "/lists/work/fields/severity", "")
+	assert.Equal(t, http.StatusNotFound, code)
+}
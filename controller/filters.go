@@ -0,0 +1,155 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+
+	"github.com/gorilla/mux"
+
+	apiv1 "github.com/gotestbootcamp/go-todo-app/api/v1"
+	"github.com/gotestbootcamp/go-todo-app/ledger"
+	"github.com/gotestbootcamp/go-todo-app/search"
+	"github.com/gotestbootcamp/go-todo-app/store"
+)
+
+func sendFilters(w http.ResponseWriter, code int, filters ...ledger.SavedFilter) {
+	res := make([]apiv1.Filter, 0, len(filters))
+	for _, f := range filters {
+		res = append(res, f.ToAPIv1())
+	}
+	resp := apiv1.Response{
+		Status: apiv1.ResponseSuccess,
+		Result: &apiv1.Result{
+			Filters: res,
+		},
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		panic(err)
+	}
+}
+
+// sendFilterError answers the request with the error of a saved filter
+func sendFilterError(w http.ResponseWriter, err error) {
+	var unknown ledger.ErrUnknownFilter
+	var exists ledger.ErrFilterExists
+	switch {
+	case errors.As(err, &unknown):
+		sendError(w, http.StatusNotFound, err)
+	case errors.As(err, &exists):
+		sendError(w, http.StatusConflict, err)
+	default:
+		sendError(w, http.StatusUnprocessableEntity, err)
+	}
+}
+
+/*
+Lists the saved filters of the actor, and the ones the other users share.
+
+curl -H "X-API-Key: $TODO_KEY" http://localhost:8080/filters
+*/
+func (ctrl *Controller) FilterIndex(w http.ResponseWriter, r *http.Request) {
+	sendFilters(w, http.StatusOK, ctrl.ledger(r).Filters()...)
+}
+
+/*
+Saves the search query under a name, see TodoSearch, shared with the other users if shared is true.
+
+curl -X POST -H "X-API-Key: $TODO_KEY" -d '{"name":"This week","query":"status:open due<\"next monday\"","shared":true}' http://localhost:8080/filters
+*/
+func (ctrl *Controller) FilterCreate(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	var req apiv1.Filter
+	if err := json.NewDecoder(io.LimitReader(r.Body, 1048576)).Decode(&req); err != nil {
+		sendError(w, http.StatusBadRequest, err)
+		return
+	}
+	f, err := ctrl.ledger(r).CreateFilter(ledger.SavedFilter{Name: req.Name, Query: req.Query, Shared: req.Shared})
+	if err != nil {
+		sendFilterError(w, err)
+		return
+	}
+	slog.InfoContext(r.Context(), "API: saved filter", "name", f.Name)
+
+	w.Header().Set("Location", "/filters/"+url.PathEscape(f.Name))
+	sendFilters(w, http.StatusCreated, f)
+}
+
+/*
+Replaces the query of the saved filter, renaming it if the name differs.
+
+curl -X PUT -H "X-API-Key: $TODO_KEY" -d '{"name":"This week","query":"status:open due<\"next monday\" -tag:someday"}' http://localhost:8080/filters/This%20week
+*/
+func (ctrl *Controller) FilterUpdate(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	var req apiv1.Filter
+	if err := json.NewDecoder(io.LimitReader(r.Body, 1048576)).Decode(&req); err != nil {
+		sendError(w, http.StatusBadRequest, err)
+		return
+	}
+	name := mux.Vars(r)["name"]
+	f, err := ctrl.ledger(r).UpdateFilter(name, ledger.SavedFilter{Name: req.Name, Query: req.Query, Shared: req.Shared})
+	if err != nil {
+		sendFilterError(w, err)
+		return
+	}
+	slog.InfoContext(r.Context(), "API: updated filter", "name", name, "new", f.Name)
+	sendFilters(w, http.StatusOK, f)
+}
+
+/*
+curl -X DELETE -H "X-API-Key: $TODO_KEY" http://localhost:8080/filters/This%20week
+*/
+func (ctrl *Controller) FilterDelete(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+	if err := ctrl.ledger(r).DeleteFilter(name); err != nil {
+		sendFilterError(w, err)
+		return
+	}
+	slog.InfoContext(r.Context(), "API: deleted filter", "name", name)
+	sendFilters(w, http.StatusOK)
+}
+
+/*
+Lists the todos matching the query of the saved filter, like a list of its own.
+
+curl -H "X-API-Key: $TODO_KEY" http://localhost:8080/filters/This%20week/todos
+*/
+func (ctrl *Controller) FilterTodos(w http.ResponseWriter, r *http.Request) {
+	items, err := ctrl.ledger(r).FilterTodos(mux.Vars(r)["name"])
+	var unknown ledger.ErrUnknownFilter
+	var syntaxErr search.SyntaxError
+	switch {
+	case errors.As(err, &unknown):
+		sendError(w, http.StatusNotFound, err)
+		return
+	case errors.As(err, &syntaxErr):
+		sendError(w, http.StatusUnprocessableEntity, err)
+		return
+	case errors.Is(err, store.ErrUnsupported):
+		sendError(w, http.StatusNotImplemented, err)
+		return
+	case err != nil:
+		sendError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	resp := apiv1.Response{
+		Status: apiv1.ResponseSuccess,
+		Result: &apiv1.Result{
+			Items: items.ToAPIv1(),
+		},
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,71 @@
+package controller_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	apiv1 "github.com/gotestbootcamp/go-todo-app/api/v1"
+	"github.com/gotestbootcamp/go-todo-app/controller"
+	"github.com/gotestbootcamp/go-todo-app/model"
+	"github.com/gotestbootcamp/go-todo-app/store"
+)
+
+func TestFilters(t *testing.T) {
+	ld := memoryStorage()
+	require.NoError(t, ld.Set("1", model.New("pay rent")))
+	require.NoError(t, ld.Set("2", model.New("buy milk")))
+	_, err := ld.TagTodo("2", "home")
+	require.NoError(t, err)
+	ctrl := controller.NewWithAuth(ld, store.NewSequentialIDs(nil), nil, nil)
+
+	do := func(method, path, body string) (*httptest.ResponseRecorder, apiv1.Response) {
+		var r io.Reader
+		if body != "" {
+			r = strings.NewReader(body)
+		}
+		req := httptest.NewRequest(method, path, r)
+		w := httptest.NewRecorder()
+		ctrl.ServeHTTP(w, req)
+		var resp apiv1.Response
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return w, resp
+	}
+	w, resp := do("POST", "/filters", `{"name":"At home","query":"tag:home"}`)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	assert.Equal(t, "/filters/At%20home", w.Header().Get("Location"))
+	assert.Equal(t, []apiv1.Filter{{Name: "At home", Query: "tag:home"}}, resp.Result.Filters)
+	w, _ = do("POST", "/filters", `{"name":"At home","query":"tag:work"}`)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	w, _ = do("POST", "/filters", `{"name":"Broken","query":"tag:home (milk"}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+
+	w, resp = do("GET", "/filters", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []apiv1.Filter{{Name: "At home", Query: "tag:home"}}, resp.Result.Filters)
+	w, resp = do("GET", "/filters/At%20home/todos", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Len(t, resp.Result.Items, 1)
+	assert.Equal(t, apiv1.ID("2"), resp.Result.Items[0].ID)
+
+	w, resp = do("PUT", "/filters/At%20home", `{"name":"Elsewhere","query":"-tag:home"}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, []apiv1.Filter{{Name: "Elsewhere", Query: "-tag:home"}}, resp.Result.Filters)
+	w, _ = do("GET", "/filters/At%20home/todos", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w, resp = do("GET", "/filters/Elsewhere/todos", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Len(t, resp.Result.Items, 1)
+	assert.Equal(t, apiv1.ID("1"), resp.Result.Items[0].ID)
+
+	w, _ = do("DELETE", "/filters/Elsewhere", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	w, _ = do("DELETE", "/filters/Elsewhere", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
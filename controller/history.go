@@ -0,0 +1,78 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+
+	apiv1 "github.com/gotestbootcamp/go-todo-app/api/v1"
+	"github.com/gotestbootcamp/go-todo-app/ledger"
+	"github.com/gotestbootcamp/go-todo-app/store"
+)
+
+/*
+curl http://localhost:8080/todos/1/history
+*/
+func (ctrl *Controller) TodoHistory(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	revs, err := ctrl.ledger(r).History(store.ID(vars["todoID"]))
+	var notFound store.ErrNotFound
+	if errors.As(err, &notFound) {
+		sendError(w, http.StatusNotFound, err)
+		return
+	}
+	if err != nil {
+		sendError(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	history := make([]apiv1.Revision, 0, len(revs))
+	for _, rev := range revs {
+		history = append(history, rev.ToAPIv1())
+	}
+	resp := apiv1.Response{
+		Status: apiv1.ResponseSuccess,
+		Result: &apiv1.Result{
+			History: history,
+		},
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		panic(err)
+	}
+}
+
+/*
+curl -X POST -H 'X-Actor: alice' http://localhost:8080/todos/1/revert/2
+*/
+func (ctrl *Controller) TodoRevert(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	todoID := vars["todoID"]
+	rev, err := strconv.Atoi(vars["rev"])
+	if err != nil {
+		sendError(w, http.StatusBadRequest, fmt.Errorf("invalid revision %q", vars["rev"]))
+		return
+	}
+	item, err := ctrl.ledger(r).Revert(store.ID(todoID), rev)
+	var noRevision ledger.ErrNoRevision
+	if errors.As(err, &noRevision) {
+		sendError(w, http.StatusNotFound, err)
+		return
+	}
+	if err != nil {
+		sendError(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	slog.InfoContext(r.Context(), "API: reverted object", "id", todoID, "revision", rev, "todo", item.Todo)
+
+	resItem := item.ToAPIv1()
+	sendItem(w, resItem.ID, resItem.Todo)
+}
@@ -0,0 +1,101 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	apiv1 "github.com/gotestbootcamp/go-todo-app/api/v1"
+	"github.com/gotestbootcamp/go-todo-app/auth"
+	"github.com/gotestbootcamp/go-todo-app/jobs"
+)
+
+// ServeJobs serves the routes inspecting the maintenance jobs of the scheduler, to the admins when
+// the controller authenticates the requests
+func (ctrl *Controller) ServeJobs(s *jobs.Scheduler) {
+	ctrl.scheduler = s
+	ctrl.handle(
+		Route{
+			Name:    "jobs.index",
+			Method:  "GET",
+			Pattern: "/admin/jobs",
+			Handler: ctrl.JobIndex,
+			Scope:   auth.ScopeAdmin,
+		},
+		Route{
+			Name:    "jobs.show",
+			Method:  "GET",
+			Pattern: "/admin/jobs/{name}",
+			Handler: ctrl.JobShow,
+			Scope:   auth.ScopeAdmin,
+		},
+	)
+}
+
+// jobToAPIv1 converts the status of the job
+func jobToAPIv1(st jobs.Status) apiv1.Job {
+	res := apiv1.Job{
+		Name:     st.Name,
+		Schedule: st.Schedule,
+		Running:  st.Running,
+		Runs:     make([]apiv1.JobRun, 0, len(st.Runs)),
+	}
+	if st.Jitter > 0 {
+		res.Jitter = st.Jitter.String()
+	}
+	if !st.Next.IsZero() {
+		res.Next = &st.Next
+	}
+	for _, run := range st.Runs {
+		res.Runs = append(res.Runs, apiv1.JobRun{
+			Started:  run.Started,
+			Finished: run.Finished,
+			Result:   run.Result,
+			Error:    run.Error,
+		})
+	}
+	return res
+}
+
+func sendJobs(w http.ResponseWriter, jobs ...apiv1.Job) {
+	resp := apiv1.Response{
+		Status: apiv1.ResponseSuccess,
+		Result: &apiv1.Result{
+			Jobs: jobs,
+		},
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		panic(err)
+	}
+}
+
+/*
+Lists the maintenance jobs, with when they run next and their latest runs.
+
+curl -H "X-API-Key: $TODO_ADMIN_KEY" http://localhost:8080/admin/jobs
+*/
+func (ctrl *Controller) JobIndex(w http.ResponseWriter, r *http.Request) {
+	statuses := ctrl.scheduler.List()
+	res := make([]apiv1.Job, 0, len(statuses))
+	for _, st := range statuses {
+		res = append(res, jobToAPIv1(st))
+	}
+	sendJobs(w, res...)
+}
+
+/*
+curl -H "X-API-Key: $TODO_ADMIN_KEY" http://localhost:8080/admin/jobs/backup
+*/
+func (ctrl *Controller) JobShow(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+	st, ok := ctrl.scheduler.Get(name)
+	if !ok {
+		sendError(w, http.StatusNotFound, jobs.ErrNoJob{Name: name})
+		return
+	}
+	sendJobs(w, jobToAPIv1(st))
+}
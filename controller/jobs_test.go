@@ -0,0 +1,49 @@
+package controller_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	apiv1 "github.com/gotestbootcamp/go-todo-app/api/v1"
+	"github.com/gotestbootcamp/go-todo-app/controller"
+	"github.com/gotestbootcamp/go-todo-app/jobs"
+	"github.com/gotestbootcamp/go-todo-app/store"
+)
+
+func TestJobs(t *testing.T) {
+	scheduler := jobs.NewScheduler()
+	daily, err := jobs.ParseSchedule("@daily")
+	require.NoError(t, err)
+	require.NoError(t, scheduler.Register(jobs.Job{Name: "backup", Schedule: daily, Jitter: 10 * time.Minute, Func: func(context.Context) (string, error) {
+		return "done", nil
+	}}))
+	ctrl := controller.NewWithAuth(memoryStorage(), store.NewSequentialIDs(nil), nil, nil)
+	ctrl.ServeJobs(scheduler)
+
+	do := func(url string) (int, apiv1.Response) {
+		req := httptest.NewRequest("GET", url, nil)
+		w := httptest.NewRecorder()
+		ctrl.ServeHTTP(w, req)
+		var resp apiv1.Response
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return w.Code, resp
+	}
+	code, resp := do("/admin/jobs")
+	require.Equal(t, http.StatusOK, code)
+	require.Len(t, resp.Result.Jobs, 1)
+	assert.Equal(t, apiv1.Job{Name: "backup", Schedule: "@daily", Jitter: "10m0s", Runs: []apiv1.JobRun{}}, resp.Result.Jobs[0])
+
+	code, resp = do("/admin/jobs/backup")
+	require.Equal(t, http.StatusOK, code)
+	require.Len(t, resp.Result.Jobs, 1)
+	assert.Equal(t, "backup", resp.Result.Jobs[0].Name)
+	code, _ = do("/admin/jobs/restore")
+	assert.Equal(t, http.StatusNotFound, code)
+}
@@ -0,0 +1,101 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	apiv1 "github.com/gotestbootcamp/go-todo-app/api/v1"
+	"github.com/gotestbootcamp/go-todo-app/ledger"
+	"github.com/gotestbootcamp/go-todo-app/store"
+	"github.com/gotestbootcamp/go-todo-app/task"
+)
+
+/*
+Lists the links of the todo, to other todos and to URLs, followed by the backlinks from the other
+todos, whose type tells how the todo relates to them: relates-to, duplicated-by or causes.
+
+curl http://localhost:8080/todos/1/links
+*/
+func (ctrl *Controller) LinkIndex(w http.ResponseWriter, r *http.Request) {
+	links, err := ctrl.ledger(r).Links(store.ID(mux.Vars(r)["todoID"]))
+	var notFound store.ErrNotFound
+	if errors.As(err, &notFound) {
+		sendError(w, http.StatusNotFound, err)
+		return
+	}
+	if err != nil {
+		sendError(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+	res := make([]apiv1.Link, 0, len(links))
+	for _, l := range links {
+		res = append(res, l.ToAPIv1())
+	}
+
+	resp := apiv1.Response{
+		Status: apiv1.ResponseSuccess,
+		Result: &apiv1.Result{
+			Links: res,
+		},
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		panic(err)
+	}
+}
+
+/*
+Links the todo to another todo, by ID, or to a URL, as relates-to, duplicates or caused-by.
+
+curl -X POST -d '{"type":"duplicates","id":"2"}' http://localhost:8080/todos/1/links
+curl -X POST -d '{"type":"caused-by","url":"https://example.com/issues/42"}' http://localhost:8080/todos/1/links
+*/
+func (ctrl *Controller) LinkCreate(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	var apiLink apiv1.Link
+	if err := json.NewDecoder(io.LimitReader(r.Body, 1048576)).Decode(&apiLink); err != nil {
+		sendError(w, http.StatusBadRequest, err)
+		return
+	}
+	l := task.Link{Type: task.LinkType(apiLink.Type), ID: string(apiLink.ID), URL: apiLink.URL}
+	item, err := ctrl.ledger(r).Link(store.ID(mux.Vars(r)["todoID"]), l)
+	sendLinked(w, r, "linked", item, err)
+}
+
+/*
+Removes a link of the todo, given by the type and the ID or the URL it points to.
+
+curl -X DELETE 'http://localhost:8080/todos/1/links?type=duplicates&id=2'
+*/
+func (ctrl *Controller) LinkDelete(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	l := task.Link{Type: task.LinkType(query.Get("type")), ID: query.Get("id"), URL: query.Get("url")}
+	item, err := ctrl.ledger(r).Unlink(store.ID(mux.Vars(r)["todoID"]), l)
+	sendLinked(w, r, "unlinked", item, err)
+}
+
+// sendLinked sends the item whose links were changed
+func sendLinked(w http.ResponseWriter, r *http.Request, verb string, item ledger.Item, err error) {
+	var notFound store.ErrNotFound
+	var noLink task.ErrNoLink
+	if errors.As(err, &notFound) || errors.As(err, &noLink) {
+		sendError(w, http.StatusNotFound, err)
+		return
+	}
+	if err != nil {
+		sendError(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	slog.InfoContext(r.Context(), "API: "+verb+" object", "id", item.ID)
+
+	resItem := item.ToAPIv1()
+	sendItem(w, resItem.ID, resItem.Todo)
+}
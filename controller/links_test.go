@@ -0,0 +1,61 @@
+package controller_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gotestbootcamp/go-todo-app/api/jsonpatch"
+	apiv1 "github.com/gotestbootcamp/go-todo-app/api/v1"
+	"github.com/gotestbootcamp/go-todo-app/controller"
+	"github.com/gotestbootcamp/go-todo-app/model"
+	"github.com/gotestbootcamp/go-todo-app/store"
+)
+
+func TestTodoLinks(t *testing.T) {
+	ld := memoryStorage()
+	require.NoError(t, ld.Set("1", model.New("login fails")))
+	require.NoError(t, ld.Set("2", model.New("can't log in")))
+	ctrl := controller.NewWithAuth(ld, store.NewSequentialIDs(nil), nil, nil)
+
+	do := func(method, path, body string) (int, apiv1.Response) {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", jsonpatch.MergePatchType)
+		w := httptest.NewRecorder()
+		ctrl.ServeHTTP(w, req)
+		var resp apiv1.Response
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return w.Code, resp
+	}
+	code, resp := do("POST", "/todos/2/links", `{"type":"duplicates","id":"1"}`)
+	require.Equal(t, http.StatusCreated, code)
+	assert.Equal(t, []apiv1.Link{{Type: "duplicates", ID: "1"}}, resp.Result.Items[0].Todo.Links)
+	code, _ = do("POST", "/todos/1/links", `{"type":"caused-by","url":"https://example.com/issues/42"}`)
+	require.Equal(t, http.StatusCreated, code)
+	code, _ = do("POST", "/todos/1/links", `{"type":"caused-by","url":"example.com"}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, code)
+	code, _ = do("POST", "/todos/1/links", `{"type":"relates-to","id":"3"}`)
+	assert.Equal(t, http.StatusNotFound, code)
+
+	code, resp = do("GET", "/todos/1/links", "")
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, []apiv1.Link{
+		{Type: "caused-by", URL: "https://example.com/issues/42"},
+		{Type: "duplicated-by", ID: "2", Title: "can't log in", Back: true},
+	}, resp.Result.Links)
+
+	// the links aren't patched
+	code, _ = do("PATCH", "/todos/2", `{"links":[]}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, code)
+
+	code, resp = do("DELETE", "/todos/2/links?type=duplicates&id=1", "")
+	require.Equal(t, http.StatusCreated, code)
+	assert.Empty(t, resp.Result.Items[0].Todo.Links)
+	code, _ = do("DELETE", "/todos/2/links?type=duplicates&id=1", "")
+	assert.Equal(t, http.StatusNotFound, code)
+}
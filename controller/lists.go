@@ -0,0 +1,240 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	apiv1 "github.com/gotestbootcamp/go-todo-app/api/v1"
+	"github.com/gotestbootcamp/go-todo-app/ledger"
+)
+
+func sendLists(w http.ResponseWriter, code int, lists ...apiv1.List) {
+	resp := apiv1.Response{
+		Status: apiv1.ResponseSuccess,
+		Result: &apiv1.Result{
+			Lists: lists,
+		},
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		panic(err)
+	}
+}
+
+func membersToAPIv1(members []ledger.Member) []apiv1.Member {
+	res := make([]apiv1.Member, 0, len(members))
+	for _, m := range members {
+		res = append(res, m.ToAPIv1())
+	}
+	return res
+}
+
+// sendMemberError answers the request with the error of a change of the members of a list
+func sendMemberError(w http.ResponseWriter, err error) {
+	var noMember ledger.ErrNoMember
+	var exists ledger.ErrMemberExists
+	var lastAdmin ledger.ErrLastAdmin
+	switch {
+	case errors.As(err, &noMember):
+		sendError(w, http.StatusNotFound, err)
+	case errors.As(err, &exists), errors.As(err, &lastAdmin):
+		sendError(w, http.StatusConflict, err)
+	default:
+		sendError(w, http.StatusUnprocessableEntity, err)
+	}
+}
+
+/*
+Lists the lists of todos visible to the actor, along with their members.
+
+curl -H "X-API-Key: $TODO_KEY" http://localhost:8080/lists
+*/
+func (ctrl *Controller) ListIndex(w http.ResponseWriter, r *http.Request) {
+	ld := ctrl.ledger(r)
+	names, err := ld.Lists()
+	if err != nil {
+		sendError(w, http.StatusInternalServerError, err)
+		return
+	}
+	res := make([]apiv1.List, 0, len(names))
+	for _, name := range names {
+		members, err := ld.Members(name)
+		if err != nil {
+			sendError(w, http.StatusInternalServerError, err)
+			return
+		}
+		res = append(res, apiv1.List{Name: name, Members: membersToAPIv1(members)})
+	}
+	sendLists(w, http.StatusOK, res...)
+}
+
+/*
+curl -H "X-API-Key: $TODO_KEY" http://localhost:8080/lists/work/members
+*/
+func (ctrl *Controller) MemberIndex(w http.ResponseWriter, r *http.Request) {
+	list := mux.Vars(r)["list"]
+	members, err := ctrl.ledger(r).Members(list)
+	if err != nil {
+		sendError(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+	sendLists(w, http.StatusOK, apiv1.List{Name: list, Members: membersToAPIv1(members)})
+}
+
+// decodeMember decodes the member of the request body, with a valid role
+func decodeMember(r *http.Request) (apiv1.Member, ledger.ListRole, error) {
+	defer r.Body.Close()
+	var req apiv1.Member
+	if err := json.NewDecoder(io.LimitReader(r.Body, 1048576)).Decode(&req); err != nil {
+		return req, "", err
+	}
+	role, err := ledger.ParseListRole(req.Role)
+	return req, role, err
+}
+
+/*
+Invites a user to the list. Only the admins of the list invite, but the first member of a list may
+be its creator, as admin: from then on, only the members access the todos of the list.
+
+curl -X POST -H "X-API-Key: $TODO_KEY" -d '{"user":"bob","role":"editor"}' http://localhost:8080/lists/work/members
+*/
+func (ctrl *Controller) MemberInvite(w http.ResponseWriter, r *http.Request) {
+	req, role, err := decodeMember(r)
+	if err != nil {
+		sendError(w, http.StatusBadRequest, err)
+		return
+	}
+	// the users may not have a record yet, when joining their own list
+	if req.User != ctrl.actor(r) {
+		if err := ctrl.checkUser(req.User); err != nil {
+			sendMemberError(w, err)
+			return
+		}
+	}
+	list := mux.Vars(r)["list"]
+	member, err := ctrl.ledger(r).AddMember(list, req.User, role)
+	if err != nil {
+		sendMemberError(w, err)
+		return
+	}
+	slog.InfoContext(r.Context(), "API: invited member", "list", list, "user", member.User, "role", member.Role)
+	sendLists(w, http.StatusCreated, apiv1.List{Name: list, Members: []apiv1.Member{member.ToAPIv1()}})
+}
+
+/*
+Changes the role of a member of the list.
+
+curl -X PUT -H "X-API-Key: $TODO_KEY" -d '{"role":"viewer"}' http://localhost:8080/lists/work/members/bob
+*/
+func (ctrl *Controller) MemberUpdate(w http.ResponseWriter, r *http.Request) {
+	req, role, err := decodeMember(r)
+	if err != nil {
+		sendError(w, http.StatusBadRequest, err)
+		return
+	}
+	vars := mux.Vars(r)
+	if req.User != "" && req.User != vars["user"] {
+		sendError(w, http.StatusUnprocessableEntity, errors.New("the user of the body is not the one of the path"))
+		return
+	}
+	member, err := ctrl.ledger(r).SetRole(vars["list"], vars["user"], role)
+	if err != nil {
+		sendMemberError(w, err)
+		return
+	}
+	slog.InfoContext(r.Context(), "API: changed member role", "list", vars["list"], "user", member.User, "role", member.Role)
+	sendLists(w, http.StatusOK, apiv1.List{Name: vars["list"], Members: []apiv1.Member{member.ToAPIv1()}})
+}
+
+/*
+Removes a member from the list. Any member may leave the list.
+
+curl -X DELETE -H "X-API-Key: $TODO_KEY" http://localhost:8080/lists/work/members/bob
+*/
+func (ctrl *Controller) MemberRemove(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	if err := ctrl.ledger(r).RemoveMember(vars["list"], vars["user"]); err != nil {
+		sendMemberError(w, err)
+		return
+	}
+	slog.InfoContext(r.Context(), "API: removed member", "list", vars["list"], "user", vars["user"])
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// sendFieldError answers the request with the error of a change of the custom fields of a list
+func sendFieldError(w http.ResponseWriter, err error) {
+	if errors.As(err, &ledger.ErrUnknownField{}) {
+		sendError(w, http.StatusNotFound, err)
+		return
+	}
+	sendError(w, http.StatusUnprocessableEntity, err)
+}
+
+/*
+Lists the custom fields of the todos of the list.
+
+curl -H "X-API-Key: $TODO_KEY" http://localhost:8080/lists/work/fields
+*/
+func (ctrl *Controller) FieldIndex(w http.ResponseWriter, r *http.Request) {
+	list := mux.Vars(r)["list"]
+	defs, err := ctrl.ledger(r).Fields(list)
+	if err != nil {
+		sendError(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+	res := apiv1.List{Name: list, Fields: make([]apiv1.FieldDef, 0, len(defs))}
+	for _, def := range defs {
+		res.Fields = append(res.Fields, def.ToAPIv1())
+	}
+	sendLists(w, http.StatusOK, res)
+}
+
+/*
+Defines a custom field of the todos of the list, of type string, number, enum, date or url, or
+changes its definition. Only the admins of the list define its fields.
+
+curl -X PUT -H "X-API-Key: $TODO_KEY" -d '{"type":"enum","values":["low","medium","high"]}' http://localhost:8080/lists/work/fields/severity
+*/
+func (ctrl *Controller) FieldDefine(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	var req apiv1.FieldDef
+	if err := json.NewDecoder(io.LimitReader(r.Body, 1048576)).Decode(&req); err != nil {
+		sendError(w, http.StatusBadRequest, err)
+		return
+	}
+	vars := mux.Vars(r)
+	if req.Name != "" && req.Name != vars["name"] {
+		sendError(w, http.StatusUnprocessableEntity, errors.New("the name of the body is not the one of the path"))
+		return
+	}
+	def := ledger.FieldDef{Name: vars["name"], Type: ledger.FieldType(req.Type), Values: req.Values}
+	def, err := ctrl.ledger(r).DefineField(vars["list"], def)
+	if err != nil {
+		sendFieldError(w, err)
+		return
+	}
+	slog.InfoContext(r.Context(), "API: defined field", "list", vars["list"], "name", def.Name, "type", def.Type)
+	sendLists(w, http.StatusOK, apiv1.List{Name: vars["list"], Fields: []apiv1.FieldDef{def.ToAPIv1()}})
+}
+
+/*
+Removes a custom field of the list. The todos keep their values of the field.
+
+curl -X DELETE -H "X-API-Key: $TODO_KEY" http://localhost:8080/lists/work/fields/severity
+*/
+func (ctrl *Controller) FieldRemove(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	if err := ctrl.ledger(r).DeleteField(vars["list"], vars["name"]); err != nil {
+		sendFieldError(w, err)
+		return
+	}
+	slog.InfoContext(r.Context(), "API: removed field", "list", vars["list"], "name", vars["name"])
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,92 @@
+package controller_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gotestbootcamp/go-todo-app/auth"
+	"github.com/gotestbootcamp/go-todo-app/controller"
+	"github.com/gotestbootcamp/go-todo-app/ledger"
+	"github.com/gotestbootcamp/go-todo-app/model"
+	"github.com/gotestbootcamp/go-todo-app/store"
+)
+
+func TestPrivateListHidden(t *testing.T) {
+	keys, err := auth.NewKeyring(nil)
+	require.NoError(t, err)
+	require.NoError(t, keys.AddStatic("alice", auth.ScopeWrite, "s3cret-alice"))
+	require.NoError(t, keys.AddStatic("bob", auth.ScopeWrite, "s3cret-bob"))
+	au := auth.NewAuthenticator(keys, auth.NewSigner([]byte("0123456789abcdef")))
+	ld := memoryStorage()
+	ctrl := controller.NewWithAuth(ld, store.NewSequentialIDs(nil), au, nil)
+
+	_, err = ld.AddMember("work", "alice", ledger.RoleListAdmin)
+	require.NoError(t, err)
+	require.NoError(t, ld.Set(store.ListID("work", "1"), model.New("secret")))
+	_, _, err = ld.PatchIf(store.ListID("work", "1"), ledger.Patch{Tags: &[]string{"hidden"}}, ledger.AnyRevision)
+	require.NoError(t, err)
+	due := time.Now().Add(-time.Hour)
+	_, err = ld.Schedule(store.ListID("work", "1"), ledger.Schedule{Due: &due})
+	require.NoError(t, err)
+	require.NoError(t, ld.Set(store.ListID("work", "2"), model.New("blocked")))
+	_, err = ld.Block(store.ListID("work", "2"), store.ListID("work", "1"))
+	require.NoError(t, err)
+	require.NoError(t, ld.Set("3", model.New("public")))
+
+	do := func(key, method, path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set(auth.APIKeyHeader, key)
+		w := httptest.NewRecorder()
+		ctrl.ServeHTTP(w, req)
+		return w
+	}
+
+	// the todos of the list are addressed escaping the slash of their IDs
+	w := do("s3cret-alice", "GET", "/todos/work%2F1")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "secret")
+
+	for _, path := range []string{
+		"/todos/work%2F1",
+		"/todos/work%2F1/history",
+		"/todos/work%2F1/comments",
+		"/todos/work%2F1/blocks",
+	} {
+		w := do("s3cret-bob", "GET", path)
+		assert.Equal(t, http.StatusForbidden, w.Code, path)
+	}
+	for _, path := range []string{
+		"/todos/work%2F1/timer/start",
+		"/todomerge/work%2F1/3",
+	} {
+		w := do("s3cret-bob", "POST", path)
+		assert.Equal(t, http.StatusForbidden, w.Code, path)
+	}
+	w = do("s3cret-bob", "DELETE", "/todos/work%2F1")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	// the lists leave the todos out
+	for _, path := range []string{
+		"/backlog",
+		"/completed",
+		"/overdue",
+		"/due?within=48h",
+		"/blocked",
+		"/urgent",
+		"/tagged?all=hidden",
+		"/archived",
+		"/timereport",
+	} {
+		w := do("s3cret-bob", "GET", path)
+		require.Equal(t, http.StatusOK, w.Code, path)
+		assert.NotContains(t, w.Body.String(), "work/", path)
+	}
+	w = do("s3cret-alice", "GET", "/overdue")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "work/1")
+}
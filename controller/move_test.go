@@ -0,0 +1,44 @@
+package controller_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	apiv1 "github.com/gotestbootcamp/go-todo-app/api/v1"
+	"github.com/gotestbootcamp/go-todo-app/controller"
+	"github.com/gotestbootcamp/go-todo-app/model"
+	"github.com/gotestbootcamp/go-todo-app/store"
+)
+
+func TestTodoMove(t *testing.T) {
+	ld := memoryStorage()
+	for _, id := range []store.ID{"1", "2", "3"} {
+		require.NoError(t, ld.Set(id, model.New("todo "+string(id))))
+	}
+	ctrl := controller.NewWithAuth(ld, store.NewSequentialIDs(nil), nil, nil)
+
+	code, resp := serve(t, ctrl, "POST", "/todos/3/move", `{"before":"1"}`)
+	require.Equal(t, http.StatusOK, code)
+	require.Len(t, resp.Result.Items, 1)
+	assert.NotEmpty(t, resp.Result.Items[0].Todo.Rank)
+	code, _ = serve(t, ctrl, "POST", "/todos/1/move", `{}`)
+	require.Equal(t, http.StatusOK, code)
+
+	code, resp = serve(t, ctrl, "GET", "/todos?sort=rank", "")
+	require.Equal(t, http.StatusOK, code)
+	var ids []apiv1.ID
+	for _, item := range resp.Result.Items {
+		ids = append(ids, item.ID)
+	}
+	assert.Equal(t, []apiv1.ID{"3", "2", "1"}, ids)
+
+	code, _ = serve(t, ctrl, "POST", "/todos/9/move", `{"before":"1"}`)
+	assert.Equal(t, http.StatusNotFound, code)
+	code, _ = serve(t, ctrl, "POST", "/todos/1/move", `{"before":"9"}`)
+	assert.Equal(t, http.StatusNotFound, code)
+	code, _ = serve(t, ctrl, "POST", "/todos/1/move", `{"before":"1"}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, code)
+}
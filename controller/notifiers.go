@@ -0,0 +1,195 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+
+	"github.com/gorilla/mux"
+
+	apiv1 "github.com/gotestbootcamp/go-todo-app/api/v1"
+	"github.com/gotestbootcamp/go-todo-app/auth"
+	"github.com/gotestbootcamp/go-todo-app/notify"
+	"github.com/gotestbootcamp/go-todo-app/webhook"
+)
+
+// ServeNotifiers serves the routes managing the Slack and Discord notifiers, to the admins when
+// the controller authenticates the requests
+func (ctrl *Controller) ServeNotifiers(ns *notify.Notifiers) {
+	ctrl.notifiers = ns
+	ctrl.handle(
+		Route{
+			Name:    "notifiers.index",
+			Method:  "GET",
+			Pattern: "/notifiers",
+			Handler: ctrl.NotifierIndex,
+			Scope:   auth.ScopeAdmin,
+		},
+		Route{
+			Name:    "notifiers.show",
+			Method:  "GET",
+			Pattern: "/notifiers/{name}",
+			Handler: ctrl.NotifierShow,
+			Scope:   auth.ScopeAdmin,
+		},
+		Route{
+			Name:    "notifiers.create",
+			Method:  "POST",
+			Pattern: "/notifiers",
+			Handler: ctrl.NotifierCreate,
+			Body:    apiv1.Notifier{},
+			Scope:   auth.ScopeAdmin,
+		},
+		Route{
+			Name:    "notifiers.delete",
+			Method:  "DELETE",
+			Pattern: "/notifiers/{name}",
+			Handler: ctrl.NotifierDelete,
+			Scope:   auth.ScopeAdmin,
+		},
+	)
+}
+
+// redactURL returns the URL of a incoming webhook without its path, which is its secret
+func redactURL(s string) string {
+	u, err := url.Parse(s)
+	if err != nil {
+		return ""
+	}
+	return u.Scheme + "://" + u.Host + "/..."
+}
+
+func notifierToAPIv1(n notify.Notifier) apiv1.Notifier {
+	res := apiv1.Notifier{
+		Name:    n.Name,
+		Kind:    string(n.Kind),
+		URL:     redactURL(n.URL),
+		Static:  n.Static,
+		Created: n.Created,
+	}
+	for _, ev := range n.Events {
+		res.Events = append(res.Events, string(ev))
+	}
+	if len(n.Channels) > 0 {
+		res.Channels = make(map[string]string, len(n.Channels))
+		for list, u := range n.Channels {
+			res.Channels[list] = redactURL(u)
+		}
+	}
+	if len(n.Templates) > 0 {
+		res.Templates = make(map[string]string, len(n.Templates))
+		for ev, text := range n.Templates {
+			res.Templates[string(ev)] = text
+		}
+	}
+	return res
+}
+
+func sendNotifiers(w http.ResponseWriter, code int, notifiers ...apiv1.Notifier) {
+	resp := apiv1.Response{
+		Status: apiv1.ResponseSuccess,
+		Result: &apiv1.Result{
+			Notifiers: notifiers,
+		},
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		panic(err)
+	}
+}
+
+/*
+curl -H "X-API-Key: $TODO_ADMIN_KEY" http://localhost:8080/notifiers
+*/
+func (ctrl *Controller) NotifierIndex(w http.ResponseWriter, r *http.Request) {
+	notifiers := ctrl.notifiers.List()
+	res := make([]apiv1.Notifier, 0, len(notifiers))
+	for _, n := range notifiers {
+		res = append(res, notifierToAPIv1(n))
+	}
+	sendNotifiers(w, http.StatusOK, res...)
+}
+
+/*
+curl -H "X-API-Key: $TODO_ADMIN_KEY" http://localhost:8080/notifiers/team
+*/
+func (ctrl *Controller) NotifierShow(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+	n, ok := ctrl.notifiers.Get(name)
+	if !ok {
+		sendError(w, http.StatusNotFound, notify.ErrNoNotifier{Name: name})
+		return
+	}
+	sendNotifiers(w, http.StatusOK, notifierToAPIv1(n))
+}
+
+/*
+Creates a notifier posting the events of the todos to a Slack or Discord channel, or to the channel
+of the list of each todo.
+
+curl -X POST -H "X-API-Key: $TODO_ADMIN_KEY" -d '{"name":"team","kind":"slack","url":"https://hooks.slack.com/services/T000/B000/XXXX","events":["assigned","overdue"],"channels":{"ops":"https://hooks.slack.com/services/T000/B001/YYYY"}}' http://localhost:8080/notifiers
+*/
+func (ctrl *Controller) NotifierCreate(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	var req apiv1.Notifier
+	if err := json.NewDecoder(io.LimitReader(r.Body, 1048576)).Decode(&req); err != nil {
+		sendError(w, http.StatusBadRequest, err)
+		return
+	}
+	n := notify.Notifier{
+		Name:     req.Name,
+		Kind:     notify.Kind(req.Kind),
+		URL:      req.URL,
+		Channels: req.Channels,
+	}
+	for _, ev := range req.Events {
+		n.Events = append(n.Events, webhook.Event(ev))
+	}
+	if len(req.Templates) > 0 {
+		n.Templates = make(map[webhook.Event]string, len(req.Templates))
+		for ev, text := range req.Templates {
+			n.Templates[webhook.Event(ev)] = text
+		}
+	}
+	if err := n.Validate(); err != nil {
+		sendError(w, http.StatusBadRequest, err)
+		return
+	}
+	n, err := ctrl.notifiers.Create(n)
+	var exists notify.ErrExists
+	if errors.As(err, &exists) {
+		sendError(w, http.StatusConflict, err)
+		return
+	}
+	if err != nil {
+		sendError(w, http.StatusInternalServerError, err)
+		return
+	}
+	sendNotifiers(w, http.StatusCreated, notifierToAPIv1(n))
+}
+
+/*
+Deletes a notifier created through the API. The static ones, loaded from the notifiers file, can't be.
+
+curl -X DELETE -H "X-API-Key: $TODO_ADMIN_KEY" http://localhost:8080/notifiers/team
+*/
+func (ctrl *Controller) NotifierDelete(w http.ResponseWriter, r *http.Request) {
+	err := ctrl.notifiers.Delete(mux.Vars(r)["name"])
+	var noNotifier notify.ErrNoNotifier
+	switch {
+	case errors.As(err, &noNotifier):
+		sendError(w, http.StatusNotFound, err)
+		return
+	case errors.Is(err, notify.ErrStatic):
+		sendError(w, http.StatusConflict, err)
+		return
+	case err != nil:
+		sendError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
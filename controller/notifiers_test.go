@@ -0,0 +1,60 @@
+package controller_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	apiv1 "github.com/gotestbootcamp/go-todo-app/api/v1"
+	"github.com/gotestbootcamp/go-todo-app/controller"
+	"github.com/gotestbootcamp/go-todo-app/notify"
+	"github.com/gotestbootcamp/go-todo-app/store"
+)
+
+func TestNotifiers(t *testing.T) {
+	mem, err := store.NewMemory()
+	require.NoError(t, err)
+	notifiers, err := notify.NewNotifiers(mem)
+	require.NoError(t, err)
+	require.NoError(t, notifiers.AddStatic(notify.Notifier{Name: "ops", Kind: notify.Discord, URL: "https://discord.com/api/webhooks/1/x"}))
+	ctrl := controller.NewWithAuth(memoryStorage(), store.NewSequentialIDs(nil), nil, nil)
+	ctrl.ServeNotifiers(notifiers)
+
+	code, resp := serve(t, ctrl, "POST", "/notifiers", `{"name":"team","kind":"slack","url":"https://hooks.slack.com/services/T000/B000/XXXX","events":["assigned"],"channels":{"home":"https://hooks.slack.com/services/T000/B001/YYYY"},"templates":{"assigned":"{{.Title}} for {{.Assignee}}"}}`)
+	require.Equal(t, http.StatusCreated, code)
+	require.Len(t, resp.Result.Notifiers, 1)
+	// the paths of the incoming webhooks are secret
+	assert.Equal(t, apiv1.Notifier{
+		Name:      "team",
+		Kind:      "slack",
+		URL:       "https://hooks.slack.com/...",
+		Events:    []string{"assigned"},
+		Channels:  map[string]string{"home": "https://hooks.slack.com/..."},
+		Templates: map[string]string{"assigned": "{{.Title}} for {{.Assignee}}"},
+		Created:   resp.Result.Notifiers[0].Created,
+	}, resp.Result.Notifiers[0])
+	code, _ = serve(t, ctrl, "POST", "/notifiers", `{"name":"team","kind":"slack","url":"https://hooks.slack.com/services/T000/B000/XXXX"}`)
+	assert.Equal(t, http.StatusConflict, code)
+	code, _ = serve(t, ctrl, "POST", "/notifiers", `{"name":"chat","kind":"teams","url":"https://example.com/hook"}`)
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	code, resp = serve(t, ctrl, "GET", "/notifiers", "")
+	require.Equal(t, http.StatusOK, code)
+	require.Len(t, resp.Result.Notifiers, 2)
+	assert.Equal(t, "ops", resp.Result.Notifiers[0].Name)
+	assert.True(t, resp.Result.Notifiers[0].Static)
+	code, resp = serve(t, ctrl, "GET", "/notifiers/team", "")
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "team", resp.Result.Notifiers[0].Name)
+
+	code, _ = serve(t, ctrl, "DELETE", "/notifiers/ops", "")
+	assert.Equal(t, http.StatusConflict, code)
+	code, _ = serve(t, ctrl, "DELETE", "/notifiers/team", "")
+	assert.Equal(t, http.StatusNoContent, code)
+	code, _ = serve(t, ctrl, "GET", "/notifiers/team", "")
+	assert.Equal(t, http.StatusNotFound, code)
+	code, _ = serve(t, ctrl, "DELETE", "/notifiers/team", "")
+	assert.Equal(t, http.StatusNotFound, code)
+}
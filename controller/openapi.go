@@ -0,0 +1,68 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gotestbootcamp/go-todo-app/api/jsonpatch"
+	"github.com/gotestbootcamp/go-todo-app/api/openapi"
+	apiv1 "github.com/gotestbootcamp/go-todo-app/api/v1"
+)
+
+/*
+curl http://localhost:8080/openapi.json
+*/
+func (ctrl *Controller) OpenAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(ctrl.api); err != nil {
+		panic(err)
+	}
+}
+
+// validated returns the handler checking the request body against the schema of the operation
+// before calling next. The empty bodies and the JSON patches are left to next.
+func (ctrl *Controller) validated(op *openapi.Operation, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(io.LimitReader(r.Body, 1048576))
+		r.Body.Close()
+		if err != nil {
+			sendError(w, http.StatusBadRequest, err)
+			return
+		}
+		if mt := mediaType(r); mt != jsonpatch.MergePatchType && mt != jsonpatch.JSONPatchType && len(bytes.TrimSpace(body)) > 0 {
+			if err := ctrl.api.ValidateBody(op, body); err != nil {
+				sendValidationError(w, err)
+				return
+			}
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		next(w, r)
+	}
+}
+
+// sendValidationError sends the error of an invalid request body, describing each invalid value
+func sendValidationError(w http.ResponseWriter, err error) {
+	resp := apiv1.Response{
+		Status: apiv1.ResponseError,
+		Error: &apiv1.Error{
+			Code: http.StatusBadRequest,
+			Text: err.Error(),
+		},
+	}
+	var invalid openapi.ValidationError
+	if errors.As(err, &invalid) {
+		for _, fe := range invalid.Fields {
+			resp.Error.Fields = append(resp.Error.Fields, apiv1.FieldError(fe))
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusBadRequest)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		panic(err)
+	}
+}
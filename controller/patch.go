@@ -0,0 +1,169 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/gotestbootcamp/go-todo-app/api/jsonpatch"
+	apiv1 "github.com/gotestbootcamp/go-todo-app/api/v1"
+	"github.com/gotestbootcamp/go-todo-app/ledger"
+	"github.com/gotestbootcamp/go-todo-app/task"
+)
+
+// acceptPatch are the media types of the bodies of PATCH /todos/{todoID}
+const acceptPatch = "application/json, " + jsonpatch.MergePatchType + ", " + jsonpatch.JSONPatchType
+
+// mediaType returns the media type of the request body, without parameters.
+// Empty if the request doesn't tell it, or if it's invalid.
+func mediaType(r *http.Request) string {
+	mt, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		return ""
+	}
+	return mt
+}
+
+// documentPatch returns the function computing the ledger.Patch of the todo from the JSON document
+// returned by patched, given the JSON document of the current todo
+func documentPatch(patched func(doc []byte) ([]byte, error)) func(ledger.Item) (ledger.Patch, error) {
+	return func(item ledger.Item) (ledger.Patch, error) {
+		cur := *item.ToAPIv1().Todo
+		doc, err := json.Marshal(cur)
+		if err != nil {
+			return ledger.Patch{}, err
+		}
+		if doc, err = patched(doc); err != nil {
+			return ledger.Patch{}, err
+		}
+		return patchOf(cur, doc)
+	}
+}
+
+// patchOf returns the ledger.Patch changing the todo as the patched JSON document of the todo.
+// Fails if the document changes read-only fields, like the time entries.
+func patchOf(cur apiv1.Todo, doc []byte) (ledger.Patch, error) {
+	dec := json.NewDecoder(bytes.NewReader(doc))
+	dec.DisallowUnknownFields()
+	var next apiv1.Todo
+	if err := dec.Decode(&next); err != nil {
+		return ledger.Patch{}, fmt.Errorf("invalid patched todo: %w", err)
+	}
+
+	var patch ledger.Patch
+	if next.Title != cur.Title {
+		patch.Title = &next.Title
+	}
+	if next.Description != cur.Description {
+		patch.Description = &next.Description
+	}
+	if next.Assignee != cur.Assignee {
+		patch.Assignee = &next.Assignee
+	}
+	if next.Owner != cur.Owner {
+		patch.Owner = &next.Owner
+	}
+	if next.Status != cur.Status {
+		status := task.Status(next.Status)
+		patch.Status = &status
+	}
+	if next.Priority != cur.Priority {
+		priority := task.PriorityNone
+		if next.Priority != "" {
+			var err error
+			if priority, err = task.ParsePriority(next.Priority); err != nil {
+				return ledger.Patch{}, err
+			}
+		}
+		patch.Priority = &priority
+	}
+	if !slices.Equal(next.Tags, cur.Tags) {
+		patch.Tags = &next.Tags
+	}
+	if !slices.Equal(next.Contexts, cur.Contexts) {
+		patch.Contexts = &next.Contexts
+	}
+	if fields := changedFields(cur.Fields, next.Fields); len(fields) > 0 {
+		patch.Fields = &fields
+	}
+	if !equalTimes(next.Due, cur.Due) || !equalTimes(next.Remind, cur.Remind) || next.Recur != cur.Recur {
+		patch.Schedule = &ledger.Schedule{Due: next.Due, Remind: next.Remind, Recur: next.Recur}
+	}
+
+	// the other fields must be left as they are
+	writable := next
+	writable.LastUpdateTime = cur.LastUpdateTime
+	writable.Series = cur.Series
+	writable.BlockedBy = cur.BlockedBy
+	writable.Time = cur.Time
+	writable.Comments = cur.Comments
+	writable.Checklist = cur.Checklist
+	writable.ChecklistSummary = cur.ChecklistSummary
+	writable.Archived = cur.Archived
+	writable.Links = cur.Links
+	if field, changed := changedField(writable, next); changed {
+		return ledger.Patch{}, fmt.Errorf("field %q is read-only", field)
+	}
+	return patch, nil
+}
+
+// changedFields returns the custom fields whose values differ between the todos, the ones
+// removed with empty values
+func changedFields(cur, next map[string]string) map[string]string {
+	res := make(map[string]string)
+	for name, value := range next {
+		if cur[name] != value {
+			res[name] = value
+		}
+	}
+	for name := range cur {
+		if _, ok := next[name]; !ok {
+			res[name] = ""
+		}
+	}
+	return res
+}
+
+func equalTimes(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
+// changedField returns the first JSON field, by name, whose encoding differs between the todos
+func changedField(a, b apiv1.Todo) (string, bool) {
+	fields := func(todo apiv1.Todo) map[string]json.RawMessage {
+		res := make(map[string]json.RawMessage)
+		data, err := json.Marshal(todo)
+		if err == nil {
+			err = json.Unmarshal(data, &res)
+		}
+		if err != nil {
+			panic(err)
+		}
+		return res
+	}
+	fa, fb := fields(a), fields(b)
+	var changed []string
+	for name, val := range fa {
+		if !bytes.Equal(val, fb[name]) {
+			changed = append(changed, name)
+		}
+	}
+	for name := range fb {
+		if _, ok := fa[name]; !ok {
+			changed = append(changed, name)
+		}
+	}
+	if len(changed) == 0 {
+		return "", false
+	}
+	sort.Strings(changed)
+	return changed[0], true
+}
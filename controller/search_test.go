@@ -0,0 +1,45 @@
+package controller_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	apiv1 "github.com/gotestbootcamp/go-todo-app/api/v1"
+	"github.com/gotestbootcamp/go-todo-app/controller"
+	"github.com/gotestbootcamp/go-todo-app/model"
+	"github.com/gotestbootcamp/go-todo-app/store"
+)
+
+func TestTodoSearch(t *testing.T) {
+	ld := memoryStorage()
+	require.NoError(t, ld.Set("1", model.New("pay rent")))
+	require.NoError(t, ld.Set("2", model.New("buy milk")))
+	_, err := ld.TagTodo("2", "home")
+	require.NoError(t, err)
+	ctrl := controller.NewWithAuth(ld, store.NewSequentialIDs(nil), nil, nil)
+
+	do := func(query string) (int, apiv1.Response) {
+		req := httptest.NewRequest("GET", "/search?q="+url.QueryEscape(query), nil)
+		w := httptest.NewRecorder()
+		ctrl.ServeHTTP(w, req)
+		var resp apiv1.Response
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return w.Code, resp
+	}
+	code, resp := do("status:open -tag:home")
+	require.Equal(t, http.StatusOK, code)
+	require.Len(t, resp.Result.Items, 1)
+	assert.Equal(t, apiv1.ID("1"), resp.Result.Items[0].ID)
+
+	code, _ = do("tag:home (milk")
+	assert.Equal(t, http.StatusBadRequest, code)
+	// the storage can't search the words
+	code, _ = do("tag:home milk")
+	assert.Equal(t, http.StatusNotImplemented, code)
+}
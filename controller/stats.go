@@ -0,0 +1,77 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	apiv1 "github.com/gotestbootcamp/go-todo-app/api/v1"
+	"github.com/gotestbootcamp/go-todo-app/ledger"
+)
+
+// defaultStatsPeriod is the period Stats covers, up to now, when the request doesn't tell
+const defaultStatsPeriod = 28 * 24 * time.Hour
+
+/*
+Tells how many todos were completed each day, or each week with period=week, how long they took,
+and how many of the ones due were late, overall and by tag, over up to 366 days or weeks.
+
+curl -H "X-API-Key: $TODO_KEY" 'http://localhost:8080/stats?from=2024-11-01&to=2024-12-01&period=week'
+*/
+func (ctrl *Controller) Stats(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	to := time.Now()
+	if val := query.Get("to"); val != "" {
+		var err error
+		if to, err = parseQueryTime(val); err != nil {
+			sendError(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+	from := to.Add(-defaultStatsPeriod)
+	if val := query.Get("from"); val != "" {
+		var err error
+		if from, err = parseQueryTime(val); err != nil {
+			sendError(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+	if from.After(to) {
+		sendError(w, http.StatusBadRequest, fmt.Errorf("period from %v ends before it starts", from))
+		return
+	}
+	period := query.Get("period")
+	if period == "" {
+		period = "day"
+	}
+	if !slices.Contains(ledger.StatsPeriods, period) {
+		sendError(w, http.StatusBadRequest, fmt.Errorf("invalid period %q, want day or week", period))
+		return
+	}
+
+	stats, err := ctrl.ledger(r).Stats(from, to, period)
+	switch {
+	case errors.Is(err, ledger.ErrPeriodTooLong):
+		sendError(w, http.StatusBadRequest, err)
+		return
+	case err != nil:
+		sendError(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+	apiStats := stats.ToAPIv1()
+	resp := apiv1.Response{
+		Status: apiv1.ResponseSuccess,
+		Result: &apiv1.Result{
+			Stats: &apiStats,
+		},
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,61 @@
+package controller_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	apiv1 "github.com/gotestbootcamp/go-todo-app/api/v1"
+	"github.com/gotestbootcamp/go-todo-app/controller"
+	"github.com/gotestbootcamp/go-todo-app/model"
+	"github.com/gotestbootcamp/go-todo-app/store"
+	"github.com/gotestbootcamp/go-todo-app/task"
+)
+
+func TestStats(t *testing.T) {
+	ld := memoryStorage()
+	require.NoError(t, ld.Set("1", model.New("pay rent")))
+	require.NoError(t, ld.Set("2", model.New("buy milk")))
+	_, err := ld.TagTodo("1", "home")
+	require.NoError(t, err)
+	_, err = ld.Transition("1", task.Assigned)
+	require.NoError(t, err)
+	_, err = ld.Transition("1", task.Completed)
+	require.NoError(t, err)
+	ctrl := controller.NewWithAuth(ld, store.NewSequentialIDs(nil), nil, nil)
+
+	get := func(path string) (int, apiv1.Response) {
+		w := httptest.NewRecorder()
+		ctrl.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		var resp apiv1.Response
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return w.Code, resp
+	}
+
+	code, resp := get("/stats")
+	require.Equal(t, http.StatusOK, code)
+	stats := resp.Result.Stats
+	require.NotNil(t, stats)
+	assert.Equal(t, "day", stats.Period)
+	assert.Len(t, stats.Completions, 29, "the last 4 weeks, today included")
+	assert.Equal(t, 1, stats.Completions[len(stats.Completions)-1].Completed)
+	assert.Equal(t, 2, stats.Created)
+	assert.Equal(t, 1, stats.Completed)
+	assert.Equal(t, 1, stats.Open)
+	assert.Equal(t, apiv1.StatsCounts{Created: 1, Completed: 1, TimeToComplete: stats.TimeToComplete}, stats.ByTag["home"])
+
+	code, resp = get("/stats?period=week")
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "week", resp.Result.Stats.Period)
+	assert.LessOrEqual(t, len(resp.Result.Stats.Completions), 5)
+
+	for _, path := range []string{"/stats?period=month", "/stats?from=yesterday", "/stats?from=2024-12-01&to=2024-11-01",
+		"/stats?from=1970-01-01"} {
+		code, _ = get(path)
+		assert.Equal(t, http.StatusBadRequest, code, path)
+	}
+}
@@ -0,0 +1,185 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	apiv1 "github.com/gotestbootcamp/go-todo-app/api/v1"
+	"github.com/gotestbootcamp/go-todo-app/ledger"
+	"github.com/gotestbootcamp/go-todo-app/store"
+)
+
+func (ctrl *Controller) TagIndex(w http.ResponseWriter, r *http.Request) {
+	tags, err := ctrl.ledger(r).ListTags()
+	if err != nil {
+		sendError(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+	sendTags(w, http.StatusOK, tags)
+}
+
+/*
+curl -X PUT -d '{"color":"#ff8800"}' http://localhost:8080/tags/urgent
+*/
+func (ctrl *Controller) TagDefine(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	var apiTag apiv1.Tag
+	if err := json.NewDecoder(io.LimitReader(r.Body, 1048576)).Decode(&apiTag); err != nil && err != io.EOF {
+		sendError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	vars := mux.Vars(r)
+	tag, err := ctrl.ledger(r).DefineTag(vars["tag"], apiTag.Color)
+	if err != nil {
+		sendError(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+	slog.InfoContext(r.Context(), "API: defined tag", "tag", tag.Name)
+	sendTags(w, http.StatusCreated, []ledger.Tag{tag})
+}
+
+func (ctrl *Controller) TagDelete(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	err := ctrl.ledger(r).DeleteTag(vars["tag"])
+	var unknown ledger.ErrUnknownTag
+	if errors.As(err, &unknown) {
+		sendError(w, http.StatusNotFound, err)
+		return
+	}
+	if err != nil {
+		sendError(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+	slog.InfoContext(r.Context(), "API: deleted tag", "tag", vars["tag"])
+	sendTags(w, http.StatusOK, nil)
+}
+
+/*
+curl -X POST http://localhost:8080/tags/urgent/rename/asap
+*/
+func (ctrl *Controller) TagRename(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	err := ctrl.ledger(r).RenameTag(vars["tag"], vars["newTag"])
+	var unknown ledger.ErrUnknownTag
+	var exists ledger.ErrTagExists
+	switch {
+	case errors.As(err, &unknown):
+		sendError(w, http.StatusNotFound, err)
+		return
+	case errors.As(err, &exists):
+		sendError(w, http.StatusConflict, err)
+		return
+	case err != nil:
+		sendError(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+	slog.InfoContext(r.Context(), "API: renamed tag", "tag", vars["tag"], "new", vars["newTag"])
+	sendTags(w, http.StatusOK, nil)
+}
+
+/*
+curl http://localhost:8080/tagged?all=work,urgent
+curl http://localhost:8080/tagged?any=home,errands
+*/
+func (ctrl *Controller) TaggedIndex(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	allOf, anyOf := query.Get("all"), query.Get("any")
+	var items ledger.Items
+	var err error
+	switch {
+	case allOf != "" && anyOf == "":
+		items, err = ctrl.ledger(r).ListByTags(ledger.MatchAll, strings.Split(allOf, ",")...)
+	case anyOf != "" && allOf == "":
+		items, err = ctrl.ledger(r).ListByTags(ledger.MatchAny, strings.Split(anyOf, ",")...)
+	default:
+		sendError(w, http.StatusBadRequest, fmt.Errorf("expected either the all or the any tags"))
+		return
+	}
+	if err != nil {
+		sendError(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	resp := apiv1.Response{
+		Status: apiv1.ResponseSuccess,
+		Result: &apiv1.Result{
+			Items: items.ToAPIv1(),
+		},
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		panic(err)
+	}
+}
+
+/*
+curl -X PUT http://localhost:8080/todos/1/tags/urgent
+*/
+func (ctrl *Controller) TodoTag(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	todoID := vars["todoID"]
+	item, err := ctrl.ledger(r).TagTodo(store.ID(todoID), vars["tag"])
+	var notFound store.ErrNotFound
+	if errors.As(err, &notFound) {
+		sendError(w, http.StatusNotFound, err)
+		return
+	}
+	if err != nil {
+		sendError(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	slog.InfoContext(r.Context(), "API: tagged object", "id", todoID, "todo", item.Todo)
+
+	resItem := item.ToAPIv1()
+	sendItem(w, resItem.ID, resItem.Todo)
+}
+
+func (ctrl *Controller) TodoUntag(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	todoID := vars["todoID"]
+	item, err := ctrl.ledger(r).UntagTodo(store.ID(todoID), vars["tag"])
+	var notFound store.ErrNotFound
+	if errors.As(err, &notFound) {
+		sendError(w, http.StatusNotFound, err)
+		return
+	}
+	if err != nil {
+		sendError(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	slog.InfoContext(r.Context(), "API: untagged object", "id", todoID, "todo", item.Todo)
+
+	resItem := item.ToAPIv1()
+	sendItem(w, resItem.ID, resItem.Todo)
+}
+
+func sendTags(w http.ResponseWriter, code int, tags []ledger.Tag) {
+	apiTags := make([]apiv1.Tag, 0, len(tags))
+	for _, tag := range tags {
+		apiTags = append(apiTags, tag.ToAPIv1())
+	}
+	resp := apiv1.Response{
+		Status: apiv1.ResponseSuccess,
+		Result: &apiv1.Result{
+			Tags: apiTags,
+		},
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,118 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	apiv1 "github.com/gotestbootcamp/go-todo-app/api/v1"
+	"github.com/gotestbootcamp/go-todo-app/ledger"
+	"github.com/gotestbootcamp/go-todo-app/store"
+	"github.com/gotestbootcamp/go-todo-app/task"
+)
+
+// defaultReportPeriod is the period TimeReport covers, up to now, when the request doesn't tell
+const defaultReportPeriod = 7 * 24 * time.Hour
+
+/*
+curl -X POST http://localhost:8080/todos/1/timer/start
+*/
+func (ctrl *Controller) TimerStart(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	item, err := ctrl.ledger(r).StartTimer(store.ID(vars["todoID"]))
+	sendTimer(w, r, "started", item, err)
+}
+
+/*
+curl -X POST http://localhost:8080/todos/1/timer/stop
+*/
+func (ctrl *Controller) TimerStop(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	item, err := ctrl.ledger(r).StopTimer(store.ID(vars["todoID"]))
+	sendTimer(w, r, "stopped", item, err)
+}
+
+// sendTimer sends the item whose timer was started or stopped
+func sendTimer(w http.ResponseWriter, r *http.Request, verb string, item ledger.Item, err error) {
+	var notFound store.ErrNotFound
+	if errors.As(err, &notFound) {
+		sendError(w, http.StatusNotFound, err)
+		return
+	}
+	if errors.Is(err, task.ErrTimerRunning) || errors.Is(err, task.ErrTimerStopped) {
+		sendError(w, http.StatusConflict, err)
+		return
+	}
+	if err != nil {
+		sendError(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	slog.InfoContext(r.Context(), "API: "+verb+" the timer of object", "id", item.ID)
+
+	resItem := item.ToAPIv1()
+	sendItem(w, resItem.ID, resItem.Todo)
+}
+
+// parseQueryTime parses a time given in the query, either a RFC 3339 time or a day
+func parseQueryTime(val string) (time.Time, error) {
+	if tm, err := time.Parse(time.RFC3339, val); err == nil {
+		return tm, nil
+	}
+	tm, err := time.ParseInLocation(time.DateOnly, val, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q", val)
+	}
+	return tm, nil
+}
+
+/*
+curl 'http://localhost:8080/timereport?from=2024-11-01&to=2024-12-01'
+*/
+func (ctrl *Controller) TimeReport(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	to := time.Now()
+	if val := query.Get("to"); val != "" {
+		var err error
+		if to, err = parseQueryTime(val); err != nil {
+			sendError(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+	from := to.Add(-defaultReportPeriod)
+	if val := query.Get("from"); val != "" {
+		var err error
+		if from, err = parseQueryTime(val); err != nil {
+			sendError(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+	if from.After(to) {
+		sendError(w, http.StatusBadRequest, fmt.Errorf("period from %v ends before it starts", from))
+		return
+	}
+
+	rep, err := ctrl.ledger(r).TimeReport(from, to)
+	if err != nil {
+		sendError(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+	apiRep := rep.ToAPIv1()
+	resp := apiv1.Response{
+		Status: apiv1.ResponseSuccess,
+		Result: &apiv1.Result{
+			TimeReport: &apiRep,
+		},
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		panic(err)
+	}
+}
@@ -2,21 +2,125 @@ package controller
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/gorilla/mux"
 
+	"github.com/gotestbootcamp/go-todo-app/api/jsonpatch"
 	apiv1 "github.com/gotestbootcamp/go-todo-app/api/v1"
+	"github.com/gotestbootcamp/go-todo-app/ledger"
 	"github.com/gotestbootcamp/go-todo-app/model"
+	"github.com/gotestbootcamp/go-todo-app/search"
 	"github.com/gotestbootcamp/go-todo-app/store"
+	"github.com/gotestbootcamp/go-todo-app/task"
 )
 
+/*
+Lists the todos, optionally selected by status, tag, context, owner, due date and custom fields,
+sorted and paged. The owner "me" selects the todos of who makes the request, and field.<name>
+the todos with the value of the custom field, which sort=field.<name> sorts by.
+The next page is listed with the same query and the page token returned by the previous one.
+
+curl 'http://localhost:8080/todos?status=pending&tag=home&due_before=2024-12-01&sort=priority,-due&limit=20'
+curl -H "X-API-Key: $TODO_KEY" 'http://localhost:8080/todos?owner=me&status=pending'
+curl 'http://localhost:8080/todos?context=home'
+curl 'http://localhost:8080/todos?field.severity=high&sort=-field.estimate'
+curl 'http://localhost:8080/todos?sort=priority,-due&limit=20&page_token=eyJzIjoi...'
+*/
 func (ctrl *Controller) TodoIndex(w http.ResponseWriter, r *http.Request) {
-	items, err := ctrl.ld.Filter(func(todo model.Todo) bool {
-		return true
-	})
+	query := r.URL.Query()
+	q := ledger.Query{
+		Status:    task.Status(query.Get("status")),
+		Tag:       query.Get("tag"),
+		Context:   query.Get("context"),
+		Owner:     query.Get("owner"),
+		PageToken: query.Get("page_token"),
+	}
+	if q.Owner == "me" {
+		if q.Owner = ctrl.actor(r); q.Owner == "" {
+			sendError(w, http.StatusBadRequest, errors.New("owner \"me\" requires the request to name who makes it"))
+			return
+		}
+	}
+	for key := range query {
+		if name, ok := strings.CutPrefix(key, "field."); ok {
+			if q.Fields == nil {
+				q.Fields = make(map[string]string)
+			}
+			q.Fields[name] = query.Get(key)
+		}
+	}
+	if val := query.Get("due_before"); val != "" {
+		before, err := parseQueryTime(val)
+		if err != nil {
+			sendError(w, http.StatusBadRequest, err)
+			return
+		}
+		q.DueBefore = &before
+	}
+	if val := query.Get("limit"); val != "" {
+		limit, err := strconv.Atoi(val)
+		if err != nil || limit < 1 {
+			sendError(w, http.StatusBadRequest, fmt.Errorf("invalid limit %q", val))
+			return
+		}
+		q.Limit = limit
+	}
+	var err error
+	if q.Sort, err = ledger.ParseSort(query.Get("sort")); err != nil {
+		sendError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	items, next, err := ctrl.ledger(r).List(q)
+	if errors.Is(err, ledger.ErrInvalidPageToken) {
+		sendError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err != nil {
+		sendError(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	resp := apiv1.Response{
+		Status: apiv1.ResponseSuccess,
+		Result: &apiv1.Result{
+			Items:         items.ToAPIv1(),
+			NextPageToken: next,
+		},
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		panic(err)
+	}
+}
+
+/*
+Searches the todos matching the query, e.g. status:open tag:work due<2025-01-01 "exact phrase",
+see search.Parse.
+
+curl http://localhost:8080/search?q=groceries&include_archived=true
+curl -G --data-urlencode 'q=status:open tag:work due<2025-01-01 "exact phrase"' http://localhost:8080/search
+*/
+func (ctrl *Controller) TodoSearch(w http.ResponseWriter, r *http.Request) {
+	items, err := ctrl.ledger(r).Search(r.URL.Query().Get("q"))
+	var syntaxErr search.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		sendError(w, http.StatusBadRequest, err)
+		return
+	}
+	if errors.Is(err, store.ErrUnsupported) {
+		sendError(w, http.StatusNotImplemented, err)
+		return
+	}
 	if err != nil {
 		sendError(w, http.StatusUnprocessableEntity, err)
 		return
@@ -36,23 +140,57 @@ func (ctrl *Controller) TodoIndex(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+/*
+curl http://localhost:8080/find/status/completed
+*/
+func (ctrl *Controller) TodoFind(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	items, err := ctrl.ledger(r).FindBy(vars["field"], vars["value"])
+	if errors.Is(err, store.ErrUnsupported) {
+		sendError(w, http.StatusNotImplemented, err)
+		return
+	}
+	if err != nil {
+		sendError(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	resp := apiv1.Response{
+		Status: apiv1.ResponseSuccess,
+		Result: &apiv1.Result{
+			Items: items.ToAPIv1(),
+		},
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		panic(err)
+	}
+}
+
+/*
+curl -i http://localhost:8080/todos/1
+*/
 func (ctrl *Controller) TodoShow(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	todoID := vars["todoID"]
-	todo, err := ctrl.ld.Get(store.ID(todoID))
+	item, rev, err := ctrl.ledger(r).GetItem(store.ID(todoID))
 	if err != nil {
 		sendError(w, http.StatusNotFound, err)
 		return
 	}
 
-	apiTodo := todo.ToAPIv1()
-	sendItem(w, apiv1.ID(todoID), &apiTodo)
+	resItem := item.ToAPIv1()
+	setETag(w, rev)
+	w.Header().Set("Accept-Patch", acceptPatch)
+	sendItemStatus(w, http.StatusOK, resItem.ID, resItem.Todo)
 }
 
 /*
 Test with this curl command:
 
-curl -H "Content-Type: application/json" -d '{"name":"New Todo"}' http://localhost:8080/todos
+curl -H "Content-Type: application/json" -d '{"title":"New Todo"}' http://localhost:8080/todos
 */
 func (ctrl *Controller) TodoCreate(w http.ResponseWriter, r *http.Request) {
 	apiTodo, code, err := todoFromRequest(r)
@@ -61,38 +199,58 @@ func (ctrl *Controller) TodoCreate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	ld := ctrl.ledger(r)
 	todo := model.NewFromAPIv1(apiTodo)
-	log.Printf("API: got object %v", todo)
+	// the todos start in the initial status of the workflow the ledger enforces
+	todo.Status = apiv1.Status(ld.Workflow().Initial())
+	slog.DebugContext(r.Context(), "API: got object", "todo", todo)
 
-	todoID, err := ctrl.uuidGen.NewUUID()
+	todoID, err := ctrl.ids.NewID()
 	if err != nil {
 		sendError(w, http.StatusServiceUnavailable, err)
 		return
 	}
 
-	if err := ctrl.ld.Set(store.ID(todoID), todo); err != nil {
+	// the todos are never overwritten, e.g. if created meanwhile by the clients of the store
+	_, rev, err := ld.SetIf(todoID, todo, 0)
+	var conflict store.ErrConflict
+	switch {
+	case errors.As(err, &conflict):
+		sendError(w, http.StatusConflict, err)
+		return
+	case err != nil:
 		sendError(w, http.StatusUnprocessableEntity, err)
 		return
 	}
 
+	w.Header().Set("Location", "/todos/"+string(todoID))
+	setETag(w, rev)
 	sendItem(w, apiv1.ID(todoID), nil)
 }
 
+/*
+curl -X PUT -H 'If-Match: "2"' -d '{"description":"milk and eggs"}' http://localhost:8080/todos/1
+*/
 func (ctrl *Controller) TodoUpdate(w http.ResponseWriter, r *http.Request) {
 	apiTodo, code, err := todoFromRequest(r)
 	if err != nil {
 		sendError(w, code, err)
 		return
 	}
+	expected, err := ifMatch(r)
+	if err != nil {
+		sendError(w, http.StatusBadRequest, err)
+		return
+	}
 
 	vars := mux.Vars(r)
 	todoID := vars["todoID"]
-	todo, err := ctrl.ld.Get(store.ID(todoID))
+	todo, err := ctrl.ledger(r).Get(store.ID(todoID))
 	if err != nil {
 		sendError(w, http.StatusNotFound, err)
 		return
 	}
-	log.Printf("API: got object %v", todoID)
+	slog.DebugContext(r.Context(), "API: got object", "id", todoID)
 
 	if err := todo.Describe(apiTodo.Description); err != nil {
 		sendError(w, http.StatusUnprocessableEntity, err)
@@ -103,53 +261,227 @@ func (ctrl *Controller) TodoUpdate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	log.Printf("API: updated object %v as: %q", todoID, todo)
+	slog.InfoContext(r.Context(), "API: updated object", "id", todoID, "todo", todo)
 
-	err = ctrl.ld.Set(store.ID(todoID), todo)
+	_, rev, err := ctrl.ledger(r).SetIf(store.ID(todoID), todo, expected)
 	if err != nil {
-		sendError(w, http.StatusUnprocessableEntity, err)
+		sendWriteError(w, err)
 		return
 	}
 
 	resTodo := todo.ToAPIv1()
-	sendItem(w, apiv1.ID(todoID), &resTodo)
+	setETag(w, rev)
+	sendItemStatus(w, http.StatusOK, apiv1.ID(todoID), &resTodo)
 }
 
+/*
+curl -X POST -H 'If-Match: "3"' -d '{}' http://localhost:8080/todos/1/complete
+*/
 func (ctrl *Controller) TodoComplete(w http.ResponseWriter, r *http.Request) {
 	_, code, err := todoFromRequest(r)
 	if err != nil {
 		sendError(w, code, err)
 		return
 	}
+	expected, err := ifMatch(r)
+	if err != nil {
+		sendError(w, http.StatusBadRequest, err)
+		return
+	}
 
 	vars := mux.Vars(r)
 	todoID := vars["todoID"]
-	todo, err := ctrl.ld.Get(store.ID(todoID))
+	item, rev, err := ctrl.ledger(r).CompleteIf(store.ID(todoID), expected)
+	if err != nil {
+		sendWriteError(w, err)
+		return
+	}
+
+	slog.InfoContext(r.Context(), "API: completed object", "id", todoID, "todo", item.Todo)
+
+	resItem := item.ToAPIv1()
+	setETag(w, rev)
+	sendItemStatus(w, http.StatusOK, resItem.ID, resItem.Todo)
+}
+
+/*
+curl -X POST -d '{}' http://localhost:8080/todos/1/delete
+*/
+func (ctrl *Controller) TodoDelete(w http.ResponseWriter, r *http.Request) {
+	_, code, err := todoFromRequest(r)
+	if err != nil {
+		sendError(w, code, err)
+		return
+	}
+	expected, err := ifMatch(r)
+	if err != nil {
+		sendError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	vars := mux.Vars(r)
+	todoID := vars["todoID"]
+	todo, err := ctrl.ledger(r).Get(store.ID(todoID))
 	if err != nil {
 		sendError(w, http.StatusNotFound, err)
 		return
 	}
-	log.Printf("API: got object %v", todoID)
+	slog.DebugContext(r.Context(), "API: got object", "id", todoID)
 
-	if err := todo.Complete(); err != nil {
+	if err := todo.Delete(); err != nil {
 		sendError(w, http.StatusUnprocessableEntity, err)
 		return
 	}
 
-	log.Printf("API: completed object %v as: %q", todoID, todo)
+	slog.InfoContext(r.Context(), "API: deleted object", "id", todoID, "todo", todo)
+
+	_, rev, err := ctrl.ledger(r).SetIf(store.ID(todoID), todo, expected)
+	if err != nil {
+		sendWriteError(w, err)
+		return
+	}
+
+	resTodo := todo.ToAPIv1()
+	setETag(w, rev)
+	sendItemStatus(w, http.StatusOK, apiv1.ID(todoID), &resTodo)
+}
+
+/*
+Patches the todo. The body is a JSON Merge Patch (RFC 7386) or a JSON Patch (RFC 6902) of the todo,
+as told by the Content-Type header, or else a JSON object with the fields to change. The patch
+is applied atomically; If-Match makes it fail if the todo changed since it was read.
+
+curl -X PATCH -H 'If-Match: "2"' -d '{"assignee":"bob"}' http://localhost:8080/todos/1
+curl -X PATCH -H 'Content-Type: application/merge-patch+json' -d '{"due":null,"tags":["home"]}' http://localhost:8080/todos/1
+curl -X PATCH -H 'Content-Type: application/json-patch+json' -d '[{"op":"test","path":"/status","value":"pending"},{"op":"add","path":"/tags/-","value":"home"}]' http://localhost:8080/todos/1
+*/
+func (ctrl *Controller) TodoPatch(w http.ResponseWriter, r *http.Request) {
+	expected, err := ifMatch(r)
+	if err != nil {
+		sendError(w, http.StatusBadRequest, err)
+		return
+	}
+	defer r.Body.Close()
+	body := io.LimitReader(r.Body, 1048576)
+
+	var patch func(ledger.Item) (ledger.Patch, error)
+	switch mediaType(r) {
+	case jsonpatch.MergePatchType:
+		merge, err := io.ReadAll(body)
+		if err != nil || !json.Valid(merge) {
+			sendError(w, http.StatusBadRequest, errors.New("malformed merge patch"))
+			return
+		}
+		patch = documentPatch(func(doc []byte) ([]byte, error) {
+			return jsonpatch.MergePatch(doc, merge)
+		})
+	case jsonpatch.JSONPatchType:
+		dec := json.NewDecoder(body)
+		dec.DisallowUnknownFields()
+		var ops []jsonpatch.Operation
+		if err := dec.Decode(&ops); err != nil {
+			sendError(w, http.StatusBadRequest, err)
+			return
+		}
+		patch = documentPatch(func(doc []byte) ([]byte, error) {
+			return jsonpatch.Apply(doc, ops)
+		})
+	default:
+		dec := json.NewDecoder(body)
+		dec.DisallowUnknownFields()
+		var fields apiv1.TodoPatch
+		if err := dec.Decode(&fields); err != nil {
+			sendError(w, http.StatusBadRequest, err)
+			return
+		}
+		patch = func(ledger.Item) (ledger.Patch, error) {
+			return ledger.Patch{
+				Title:       fields.Title,
+				Description: fields.Description,
+				Assignee:    fields.Assignee,
+				Status:      (*task.Status)(fields.Status),
+				Owner:       fields.Owner,
+			}, nil
+		}
+	}
+	// the todos can be handed over only to the known users
+	changes := patch
+	patch = func(cur ledger.Item) (ledger.Patch, error) {
+		p, err := changes(cur)
+		if err == nil && p.Owner != nil {
+			err = ctrl.checkUser(*p.Owner)
+		}
+		return p, err
+	}
+
+	vars := mux.Vars(r)
+	todoID := vars["todoID"]
+	item, rev, err := ctrl.ledger(r).PatchFuncIf(store.ID(todoID), patch, expected)
+	if err != nil {
+		sendWriteError(w, err)
+		return
+	}
 
-	err = ctrl.ld.Set(store.ID(todoID), todo)
+	slog.InfoContext(r.Context(), "API: patched object", "id", todoID, "todo", item.Todo)
+
+	resItem := item.ToAPIv1()
+	setETag(w, rev)
+	sendItemStatus(w, http.StatusOK, resItem.ID, resItem.Todo)
+}
+
+/*
+curl -X DELETE -H 'If-Match: "4"' http://localhost:8080/todos/1
+*/
+func (ctrl *Controller) TodoRemove(w http.ResponseWriter, r *http.Request) {
+	expected, err := ifMatch(r)
 	if err != nil {
+		sendError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	vars := mux.Vars(r)
+	todoID := vars["todoID"]
+	if err := ctrl.ledger(r).DeleteIf(store.ID(todoID), expected); err != nil {
+		sendWriteError(w, err)
+		return
+	}
+	slog.InfoContext(r.Context(), "API: removed object", "id", todoID)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+/*
+curl -X POST http://localhost:8080/todos/1/transition/in-progress
+*/
+func (ctrl *Controller) TodoTransition(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	todoID := vars["todoID"]
+	todo, err := ctrl.ledger(r).Transition(store.ID(todoID), task.Status(vars["status"]))
+	var notFound store.ErrNotFound
+	var illegal task.ErrIllegalTransition
+	switch {
+	case errors.As(err, &notFound):
+		sendError(w, http.StatusNotFound, err)
+		return
+	case errors.As(err, &illegal):
+		sendError(w, http.StatusConflict, err)
+		return
+	case err != nil:
 		sendError(w, http.StatusUnprocessableEntity, err)
 		return
 	}
 
+	slog.InfoContext(r.Context(), "API: moved object", "id", todoID, "todo", todo)
+
 	resTodo := todo.ToAPIv1()
 	sendItem(w, apiv1.ID(todoID), &resTodo)
 }
 
-func (ctrl *Controller) TodoDelete(w http.ResponseWriter, r *http.Request) {
-	_, code, err := todoFromRequest(r)
+/*
+curl -X PUT -d '{"due":"2024-11-11T18:00:00Z","remind":"2024-11-11T09:00:00Z","recur":"weekly"}' http://localhost:8080/todos/1/schedule
+*/
+func (ctrl *Controller) TodoSchedule(w http.ResponseWriter, r *http.Request) {
+	apiTodo, code, err := todoFromRequest(r)
 	if err != nil {
 		sendError(w, code, err)
 		return
@@ -157,28 +489,51 @@ func (ctrl *Controller) TodoDelete(w http.ResponseWriter, r *http.Request) {
 
 	vars := mux.Vars(r)
 	todoID := vars["todoID"]
-	todo, err := ctrl.ld.Get(store.ID(todoID))
-	if err != nil {
+	item, err := ctrl.ledger(r).Schedule(store.ID(todoID), ledger.Schedule{
+		Due:    apiTodo.Due,
+		Remind: apiTodo.Remind,
+		Recur:  apiTodo.Recur,
+	})
+	var notFound store.ErrNotFound
+	if errors.As(err, &notFound) {
 		sendError(w, http.StatusNotFound, err)
 		return
 	}
-	log.Printf("API: got object %v", todoID)
-
-	if err := todo.Delete(); err != nil {
+	if err != nil {
 		sendError(w, http.StatusUnprocessableEntity, err)
 		return
 	}
 
-	log.Printf("API: deleted object %v as: %q", todoID, todo)
+	slog.InfoContext(r.Context(), "API: scheduled object", "id", todoID, "todo", item.Todo)
+
+	resItem := item.ToAPIv1()
+	sendItem(w, resItem.ID, resItem.Todo)
+}
+
+/*
+Moves the todo right before another todo of its list, or last without before, in the manual order sort=rank lists the todos in.
 
-	err = ctrl.ld.Set(store.ID(todoID), todo)
+curl -X POST -d '{"before":"3"}' http://localhost:8080/todos/1/move
+*/
+func (ctrl *Controller) TodoMove(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	var req apiv1.Move
+	if err := json.NewDecoder(io.LimitReader(r.Body, 1048576)).Decode(&req); err != nil {
+		sendError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	todoID := mux.Vars(r)["todoID"]
+	item, err := ctrl.ledger(r).MoveBefore(store.ID(todoID), store.ID(req.Before))
 	if err != nil {
-		sendError(w, http.StatusUnprocessableEntity, err)
+		sendWriteError(w, err)
 		return
 	}
 
-	resTodo := todo.ToAPIv1()
-	sendItem(w, apiv1.ID(todoID), &resTodo)
+	slog.InfoContext(r.Context(), "API: moved object", "id", todoID, "before", req.Before, "rank", item.Task.Rank)
+
+	resItem := item.ToAPIv1()
+	sendItemStatus(w, http.StatusOK, resItem.ID, resItem.Todo)
 }
 
 func (ctrl *Controller) TodoMerge(w http.ResponseWriter, r *http.Request) {
@@ -186,41 +541,48 @@ func (ctrl *Controller) TodoMerge(w http.ResponseWriter, r *http.Request) {
 	id1 := vars["todoID1"]
 	id2 := vars["todoID2"]
 
-	todo1, err := ctrl.ld.Get(store.ID(id1))
+	todo1, err := ctrl.ledger(r).Get(store.ID(id1))
 	if err != nil {
 		sendError(w, http.StatusNotFound, err)
 		return
 	}
-	todo2, err := ctrl.ld.Get(store.ID(id2))
+	todo2, err := ctrl.ledger(r).Get(store.ID(id2))
 	if err != nil {
 		sendError(w, http.StatusNotFound, err)
 		return
 	}
-	log.Printf("API: got objects %v - %v", todo1, todo2)
+	slog.DebugContext(r.Context(), "API: got objects", "todo", todo1, "other", todo2)
 
 	merged, err := model.Merge(todo1, todo2)
 	if err != nil {
 		sendError(w, http.StatusUnprocessableEntity, err)
 		return
 	}
+	// both the todos must be removable before removing either
+	for _, id := range []string{id1, id2} {
+		if err := ctrl.ledger(r).CanChange(store.ID(id)); err != nil {
+			sendError(w, http.StatusForbidden, err)
+			return
+		}
+	}
 
-	err = ctrl.ld.Delete(store.ID(id1))
+	err = ctrl.ledger(r).Delete(store.ID(id1))
 	if err != nil {
 		sendError(w, http.StatusUnprocessableEntity, err)
 		return
 	}
-	err = ctrl.ld.Delete(store.ID(id2))
+	err = ctrl.ledger(r).Delete(store.ID(id2))
 	if err != nil {
 		sendError(w, http.StatusUnprocessableEntity, err)
 		return
 	}
 
-	mergedID, err := ctrl.uuidGen.NewUUID()
+	mergedID, err := ctrl.ids.NewID()
 	if err != nil {
 		sendError(w, http.StatusUnprocessableEntity, err)
 		return
 	}
-	err = ctrl.ld.Set(store.ID(mergedID), merged)
+	err = ctrl.ledger(r).Set(mergedID, merged)
 	if err != nil {
 		sendError(w, http.StatusUnprocessableEntity, err)
 		return
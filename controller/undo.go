@@ -0,0 +1,54 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	apiv1 "github.com/gotestbootcamp/go-todo-app/api/v1"
+	"github.com/gotestbootcamp/go-todo-app/ledger"
+)
+
+/*
+curl -X POST http://localhost:8080/undo
+*/
+func (ctrl *Controller) Undo(w http.ResponseWriter, r *http.Request) {
+	items, err := ctrl.ledger(r).Undo()
+	sendReplayed(w, r, "undid", items, err)
+}
+
+/*
+curl -X POST http://localhost:8080/redo
+*/
+func (ctrl *Controller) Redo(w http.ResponseWriter, r *http.Request) {
+	items, err := ctrl.ledger(r).Redo()
+	sendReplayed(w, r, "redid", items, err)
+}
+
+// sendReplayed sends the items restored by undoing or redoing an operation
+func sendReplayed(w http.ResponseWriter, r *http.Request, verb string, items ledger.Items, err error) {
+	if errors.Is(err, ledger.ErrNothingToUndo) || errors.Is(err, ledger.ErrNothingToRedo) {
+		sendError(w, http.StatusConflict, err)
+		return
+	}
+	if err != nil {
+		sendError(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	slog.InfoContext(r.Context(), "API: "+verb+" the last operation", "restored", len(items))
+
+	resp := apiv1.Response{
+		Status: apiv1.ResponseSuccess,
+		Result: &apiv1.Result{
+			Items: items.ToAPIv1(),
+		},
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,190 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	apiv1 "github.com/gotestbootcamp/go-todo-app/api/v1"
+	"github.com/gotestbootcamp/go-todo-app/auth"
+	"github.com/gotestbootcamp/go-todo-app/user"
+)
+
+func userToAPIv1(u user.User) apiv1.User {
+	return apiv1.User{
+		Name:        u.Name,
+		DisplayName: u.DisplayName,
+		Email:       u.Email,
+		Role:        string(u.Role),
+		Created:     u.Created,
+	}
+}
+
+func sendUsers(w http.ResponseWriter, code int, users ...apiv1.User) {
+	resp := apiv1.Response{
+		Status: apiv1.ResponseSuccess,
+		Result: &apiv1.Result{
+			Users: users,
+		},
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		panic(err)
+	}
+}
+
+// checkUser fails with user.ErrNoUser if the todos can't be handed over to the user with the given name.
+// Any name is allowed if the controller has no users, and the empty name leaves the todos to no user.
+func (ctrl *Controller) checkUser(name string) error {
+	if ctrl.users == nil || name == "" {
+		return nil
+	}
+	if _, ok := ctrl.users.Get(name); !ok {
+		return user.ErrNoUser{Name: name}
+	}
+	return nil
+}
+
+/*
+Shows who makes the request: their user, or the user they would be if they had no record.
+
+curl -H "X-API-Key: $TODO_KEY" http://localhost:8080/me
+*/
+func (ctrl *Controller) Me(w http.ResponseWriter, r *http.Request) {
+	id, _ := auth.FromContext(r.Context())
+	u, ok := ctrl.users.Get(id.Name)
+	if !ok {
+		u = user.User{Name: id.Name, Role: user.RoleUser}
+	}
+	if ctrl.users.IsAdmin(id) {
+		u.Role = user.RoleAdmin
+	}
+	sendUsers(w, http.StatusOK, userToAPIv1(u))
+}
+
+/*
+curl -H "X-API-Key: $TODO_KEY" http://localhost:8080/users
+*/
+func (ctrl *Controller) UserIndex(w http.ResponseWriter, r *http.Request) {
+	users := ctrl.users.List()
+	res := make([]apiv1.User, 0, len(users))
+	for _, u := range users {
+		res = append(res, userToAPIv1(u))
+	}
+	sendUsers(w, http.StatusOK, res...)
+}
+
+/*
+curl -H "X-API-Key: $TODO_KEY" http://localhost:8080/users/alice
+*/
+func (ctrl *Controller) UserShow(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+	u, ok := ctrl.users.Get(name)
+	if !ok {
+		sendError(w, http.StatusNotFound, user.ErrNoUser{Name: name})
+		return
+	}
+	sendUsers(w, http.StatusOK, userToAPIv1(u))
+}
+
+/*
+Creates a user, who makes the requests with the API keys of the same name.
+
+curl -X POST -H "X-API-Key: $TODO_ADMIN_KEY" -d '{"name":"alice","display_name":"Alice","role":"user"}' http://localhost:8080/users
+*/
+func (ctrl *Controller) UserCreate(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	var req apiv1.User
+	if err := json.NewDecoder(io.LimitReader(r.Body, 1048576)).Decode(&req); err != nil {
+		sendError(w, http.StatusBadRequest, err)
+		return
+	}
+	if req.Role == "" {
+		req.Role = string(user.RoleUser)
+	}
+
+	u, err := ctrl.users.Create(user.User{
+		Name:        req.Name,
+		DisplayName: req.DisplayName,
+		Email:       req.Email,
+		Role:        user.Role(req.Role),
+	})
+	var exists user.ErrExists
+	if errors.As(err, &exists) {
+		sendError(w, http.StatusConflict, err)
+		return
+	}
+	if err != nil {
+		sendError(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+	slog.InfoContext(r.Context(), "API: created user", "user", u.Name)
+
+	w.Header().Set("Location", "/users/"+u.Name)
+	sendUsers(w, http.StatusCreated, userToAPIv1(u))
+}
+
+/*
+Replaces the display name, the email and the role of the user.
+
+curl -X PUT -H "X-API-Key: $TODO_ADMIN_KEY" -d '{"name":"alice","role":"admin"}' http://localhost:8080/users/alice
+*/
+func (ctrl *Controller) UserUpdate(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	var req apiv1.User
+	if err := json.NewDecoder(io.LimitReader(r.Body, 1048576)).Decode(&req); err != nil {
+		sendError(w, http.StatusBadRequest, err)
+		return
+	}
+	name := mux.Vars(r)["name"]
+	if req.Name != "" && req.Name != name {
+		sendError(w, http.StatusUnprocessableEntity, errors.New("the users can't be renamed"))
+		return
+	}
+	if req.Role == "" {
+		req.Role = string(user.RoleUser)
+	}
+
+	u, err := ctrl.users.Update(user.User{
+		Name:        name,
+		DisplayName: req.DisplayName,
+		Email:       req.Email,
+		Role:        user.Role(req.Role),
+	})
+	var noUser user.ErrNoUser
+	if errors.As(err, &noUser) {
+		sendError(w, http.StatusNotFound, err)
+		return
+	}
+	if err != nil {
+		sendError(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+	sendUsers(w, http.StatusOK, userToAPIv1(u))
+}
+
+/*
+Removes the user. Their todos are left to them, for the admins to hand over.
+
+curl -X DELETE -H "X-API-Key: $TODO_ADMIN_KEY" http://localhost:8080/users/alice
+*/
+func (ctrl *Controller) UserDelete(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+	err := ctrl.users.Delete(name)
+	var noUser user.ErrNoUser
+	if errors.As(err, &noUser) {
+		sendError(w, http.StatusNotFound, err)
+		return
+	}
+	if err != nil {
+		sendError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
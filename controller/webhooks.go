@@ -0,0 +1,203 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	apiv1 "github.com/gotestbootcamp/go-todo-app/api/v1"
+	"github.com/gotestbootcamp/go-todo-app/auth"
+	"github.com/gotestbootcamp/go-todo-app/webhook"
+)
+
+// ServeWebhooks serves the routes managing the webhooks of the dispatcher, to the admins when the
+// controller authenticates the requests
+func (ctrl *Controller) ServeWebhooks(d *webhook.Dispatcher) {
+	ctrl.webhooks = d
+	ctrl.handle(
+		Route{
+			Name:    "webhooks.index",
+			Method:  "GET",
+			Pattern: "/webhooks",
+			Handler: ctrl.WebhookIndex,
+			Scope:   auth.ScopeAdmin,
+		},
+		Route{
+			Name:    "webhooks.show",
+			Method:  "GET",
+			Pattern: "/webhooks/{webhookID}",
+			Handler: ctrl.WebhookShow,
+			Scope:   auth.ScopeAdmin,
+		},
+		Route{
+			Name:    "webhooks.create",
+			Method:  "POST",
+			Pattern: "/webhooks",
+			Handler: ctrl.WebhookCreate,
+			Body:    apiv1.Webhook{},
+			Scope:   auth.ScopeAdmin,
+		},
+		Route{
+			Name:    "webhooks.delete",
+			Method:  "DELETE",
+			Pattern: "/webhooks/{webhookID}",
+			Handler: ctrl.WebhookDelete,
+			Scope:   auth.ScopeAdmin,
+		},
+		Route{
+			Name:    "webhooks.deliveries",
+			Method:  "GET",
+			Pattern: "/webhooks/{webhookID}/deliveries",
+			Handler: ctrl.WebhookDeliveries,
+			Scope:   auth.ScopeAdmin,
+		},
+	)
+}
+
+// webhookToAPIv1 converts the webhook, without its secret
+func webhookToAPIv1(h webhook.Hook) apiv1.Webhook {
+	events := make([]string, 0, len(h.Events))
+	for _, ev := range h.Events {
+		events = append(events, string(ev))
+	}
+	return apiv1.Webhook{
+		ID:      h.ID,
+		URL:     h.URL,
+		Events:  events,
+		Created: h.Created,
+	}
+}
+
+func sendWebhooks(w http.ResponseWriter, code int, hooks ...apiv1.Webhook) {
+	resp := apiv1.Response{
+		Status: apiv1.ResponseSuccess,
+		Result: &apiv1.Result{
+			Webhooks: hooks,
+		},
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		panic(err)
+	}
+}
+
+func sendDeliveries(w http.ResponseWriter, deliveries ...apiv1.Delivery) {
+	resp := apiv1.Response{
+		Status: apiv1.ResponseSuccess,
+		Result: &apiv1.Result{
+			Deliveries: deliveries,
+		},
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		panic(err)
+	}
+}
+
+/*
+curl -H "X-API-Key: $TODO_ADMIN_KEY" http://localhost:8080/webhooks
+*/
+func (ctrl *Controller) WebhookIndex(w http.ResponseWriter, r *http.Request) {
+	hooks := ctrl.webhooks.List()
+	res := make([]apiv1.Webhook, 0, len(hooks))
+	for _, h := range hooks {
+		res = append(res, webhookToAPIv1(h))
+	}
+	sendWebhooks(w, http.StatusOK, res...)
+}
+
+/*
+curl -H "X-API-Key: $TODO_ADMIN_KEY" http://localhost:8080/webhooks/0123456789abcdef
+*/
+func (ctrl *Controller) WebhookShow(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["webhookID"]
+	h, ok := ctrl.webhooks.Get(id)
+	if !ok {
+		sendError(w, http.StatusNotFound, webhook.ErrNoHook{ID: id})
+		return
+	}
+	sendWebhooks(w, http.StatusOK, webhookToAPIv1(h))
+}
+
+/*
+Registers a webhook, returning the secret signing its deliveries, generated unless given.
+
+curl -X POST -H "X-API-Key: $TODO_ADMIN_KEY" -d '{"url":"https://example.com/hook","events":["created","overdue"]}' http://localhost:8080/webhooks
+*/
+func (ctrl *Controller) WebhookCreate(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	var req apiv1.Webhook
+	if err := json.NewDecoder(io.LimitReader(r.Body, 1048576)).Decode(&req); err != nil {
+		sendError(w, http.StatusBadRequest, err)
+		return
+	}
+	events := make([]webhook.Event, 0, len(req.Events))
+	for _, ev := range req.Events {
+		events = append(events, webhook.Event(ev))
+	}
+	hook := webhook.Hook{URL: req.URL, Events: events, Secret: req.Secret}
+	if err := hook.Validate(); err != nil {
+		sendError(w, http.StatusBadRequest, err)
+		return
+	}
+	hook, err := ctrl.webhooks.Register(hook)
+	if err != nil {
+		sendError(w, http.StatusInternalServerError, err)
+		return
+	}
+	res := webhookToAPIv1(hook)
+	res.Secret = hook.Secret
+	sendWebhooks(w, http.StatusCreated, res)
+}
+
+/*
+curl -X DELETE -H "X-API-Key: $TODO_ADMIN_KEY" http://localhost:8080/webhooks/0123456789abcdef
+*/
+func (ctrl *Controller) WebhookDelete(w http.ResponseWriter, r *http.Request) {
+	err := ctrl.webhooks.Delete(mux.Vars(r)["webhookID"])
+	var noHook webhook.ErrNoHook
+	if errors.As(err, &noHook) {
+		sendError(w, http.StatusNotFound, err)
+		return
+	}
+	if err != nil {
+		sendError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+/*
+Lists the latest deliveries to the webhook, newest first.
+
+curl -H "X-API-Key: $TODO_ADMIN_KEY" http://localhost:8080/webhooks/0123456789abcdef/deliveries
+*/
+func (ctrl *Controller) WebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	deliveries, err := ctrl.webhooks.Deliveries(mux.Vars(r)["webhookID"])
+	if err != nil {
+		sendError(w, http.StatusNotFound, err)
+		return
+	}
+	res := make([]apiv1.Delivery, 0, len(deliveries))
+	for _, d := range deliveries {
+		res = append(res, apiv1.Delivery{
+			ID:        d.ID,
+			Event:     string(d.Event),
+			Todo:      string(d.Todo),
+			Time:      d.Time,
+			Attempts:  d.Attempts,
+			Status:    d.Status,
+			Error:     d.Error,
+			Delivered: d.Delivered,
+			Done:      d.Done,
+		})
+	}
+	sendDeliveries(w, res...)
+}
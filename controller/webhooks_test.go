@@ -0,0 +1,56 @@
+package controller_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gotestbootcamp/go-todo-app/controller"
+	"github.com/gotestbootcamp/go-todo-app/store"
+	"github.com/gotestbootcamp/go-todo-app/webhook"
+)
+
+func TestWebhooks(t *testing.T) {
+	mem, err := store.NewMemory()
+	require.NoError(t, err)
+	hooks, err := webhook.NewDispatcher(mem)
+	require.NoError(t, err)
+	ctrl := controller.NewWithAuth(memoryStorage(), store.NewSequentialIDs(nil), nil, nil)
+	ctrl.ServeWebhooks(hooks)
+
+	code, resp := serve(t, ctrl, "POST", "/webhooks", `{"url":"https://example.com/hook","events":["created","overdue"]}`)
+	require.Equal(t, http.StatusCreated, code)
+	require.Len(t, resp.Result.Webhooks, 1)
+	created := resp.Result.Webhooks[0]
+	assert.NotEmpty(t, created.ID)
+	assert.NotEmpty(t, created.Secret)
+	assert.Equal(t, []string{"created", "overdue"}, created.Events)
+
+	code, _ = serve(t, ctrl, "POST", "/webhooks", `{"url":"https://example.com/hook","events":["archived"]}`)
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _ = serve(t, ctrl, "POST", "/webhooks", `{"url":"example.com","events":["created"]}`)
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	// the secret is returned only when created
+	code, resp = serve(t, ctrl, "GET", "/webhooks", "")
+	require.Equal(t, http.StatusOK, code)
+	require.Len(t, resp.Result.Webhooks, 1)
+	assert.Empty(t, resp.Result.Webhooks[0].Secret)
+	code, resp = serve(t, ctrl, "GET", "/webhooks/"+created.ID, "")
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "https://example.com/hook", resp.Result.Webhooks[0].URL)
+	code, resp = serve(t, ctrl, "GET", "/webhooks/"+created.ID+"/deliveries", "")
+	require.Equal(t, http.StatusOK, code)
+	assert.Empty(t, resp.Result.Deliveries)
+
+	code, _ = serve(t, ctrl, "DELETE", "/webhooks/"+created.ID, "")
+	assert.Equal(t, http.StatusNoContent, code)
+	code, _ = serve(t, ctrl, "DELETE", "/webhooks/"+created.ID, "")
+	assert.Equal(t, http.StatusNotFound, code)
+	code, _ = serve(t, ctrl, "GET", "/webhooks/"+created.ID, "")
+	assert.Equal(t, http.StatusNotFound, code)
+	code, _ = serve(t, ctrl, "GET", "/webhooks/"+created.ID+"/deliveries", "")
+	assert.Equal(t, http.StatusNotFound, code)
+}
@@ -0,0 +1,251 @@
+package dates
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrAmbiguous is wrapped by the errors of the dates which could mean different times
+var ErrAmbiguous = errors.New("ambiguous date")
+
+// Parser parses dates relative to the current time, see Parse
+type Parser struct {
+	// Now returns the current time
+	Now func() time.Time
+	// Location is the time zone of the dates, and of the times of the day
+	Location *time.Location
+	// WeekStart is the first day of the weeks
+	WeekStart time.Weekday
+}
+
+// New returns a Parser of the dates in the local time zone, with the weeks starting on the day of
+// the locale of the environment
+func New() Parser {
+	return Parser{Now: time.Now, Location: time.Local, WeekStart: WeekStartFromEnv()}
+}
+
+var weekdays = map[string]time.Weekday{
+	"sunday":    time.Sunday,
+	"monday":    time.Monday,
+	"tuesday":   time.Tuesday,
+	"wednesday": time.Wednesday,
+	"thursday":  time.Thursday,
+	"friday":    time.Friday,
+	"saturday":  time.Saturday,
+}
+
+// lookupWeekday returns the weekday, given by its name or its first three letters
+func lookupWeekday(word string) (time.Weekday, bool) {
+	for name, day := range weekdays {
+		if word == name || word == name[:3] {
+			return day, true
+		}
+	}
+	return time.Sunday, false
+}
+
+var (
+	// numericDate matches the dates with the day and the month in either order, e.g. 03/04/2025
+	numericDate = regexp.MustCompile(`^\d{1,2}[/.-]\d{1,2}([/.-]\d{2,4})?$`)
+	clock12     = regexp.MustCompile(`^(\d{1,2})(?::(\d{2}))?(am|pm)$`)
+	clock24     = regexp.MustCompile(`^(\d{1,2}):(\d{2})$`)
+)
+
+// Parse parses the date, either absolute in RFC 3339 or as 2006-01-02 [15:04], or relative to
+// now, in one of the forms:
+//   - "today", "tomorrow", a weekday like "friday" or "fri", the next one after today
+//   - "this friday", the day of the current week, or "next friday", the day of the next week
+//   - "next week", "next month" or "next year", their first day
+//   - "in 3 days", or "in an hour", with the units minute, hour, day, week, month and year
+//
+// followed by the time of the day, optionally after "at", like "5pm", "5:30pm", "17:00", "noon"
+// or "midnight". The days without time are at midnight, the durations keep the time of now.
+// A time of the day alone is today. The words are case insensitive.
+//
+// The dates whose meaning depends on the habits of the reader fail with ErrAmbiguous, e.g.
+// "03/04", "at 5" or "friday" on a friday.
+func (p Parser) Parse(s string) (time.Time, error) {
+	now := p.Now().In(p.Location)
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	for _, layout := range []string{time.DateOnly, "2006-01-02 15:04"} {
+		if t, err := time.ParseInLocation(layout, s, p.Location); err == nil {
+			return t, nil
+		}
+	}
+
+	text := strings.ToLower(strings.Join(strings.Fields(s), " "))
+	if numericDate.MatchString(text) {
+		return time.Time{}, fmt.Errorf("%w %q: the day and the month could be either way, use 2006-01-02", ErrAmbiguous, s)
+	}
+	words := strings.Fields(text)
+	if len(words) == 0 {
+		return time.Time{}, errors.New("empty date")
+	}
+
+	day, rest, isDuration, err := p.parseDay(now, words)
+	if errors.Is(err, ErrAmbiguous) {
+		return time.Time{}, err
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w, try e.g. tomorrow 5pm, next friday, in 3 days or 2006-01-02", err)
+	}
+	if len(rest) == 0 {
+		return day, nil
+	}
+	if isDuration {
+		return time.Time{}, fmt.Errorf("invalid date %q: no time of the day after a duration", s)
+	}
+	hour, min, err := parseClock(rest)
+	if err != nil {
+		return time.Time{}, err
+	}
+	t := time.Date(day.Year(), day.Month(), day.Day(), hour, min, 0, 0, p.Location)
+	if len(rest) == len(words) && t.Before(now) {
+		return time.Time{}, fmt.Errorf("%w %q: the time is past today, say today or tomorrow", ErrAmbiguous, s)
+	}
+	return t, nil
+}
+
+// parseDay parses the day at the start of the words, returning it at midnight along with the
+// words left. Durations, e.g. "in 3 days", keep the time of now instead.
+func (p Parser) parseDay(now time.Time, words []string) (time.Time, []string, bool, error) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, p.Location)
+	weekStart := today.AddDate(0, 0, -p.weekIndex(today.Weekday()))
+	next := ""
+	if len(words) > 1 {
+		next = words[1]
+	}
+	switch first := words[0]; first {
+	case "today":
+		return today, words[1:], false, nil
+	case "tomorrow":
+		return today.AddDate(0, 0, 1), words[1:], false, nil
+	case "this", "next":
+		if first == "next" {
+			switch next {
+			case "week":
+				return weekStart.AddDate(0, 0, 7), words[2:], false, nil
+			case "month":
+				return time.Date(now.Year(), now.Month()+1, 1, 0, 0, 0, 0, p.Location), words[2:], false, nil
+			case "year":
+				return time.Date(now.Year()+1, time.January, 1, 0, 0, 0, 0, p.Location), words[2:], false, nil
+			}
+		}
+		wd, ok := lookupWeekday(next)
+		if !ok {
+			return time.Time{}, nil, false, fmt.Errorf("invalid date: %q is not a weekday", next)
+		}
+		day := weekStart.AddDate(0, 0, p.weekIndex(wd))
+		if first == "next" {
+			return day.AddDate(0, 0, 7), words[2:], false, nil
+		}
+		if day.Before(today) {
+			return time.Time{}, nil, false, fmt.Errorf("invalid date: this %s is past, say next %s", next, next)
+		}
+		return day, words[2:], false, nil
+	case "in":
+		if len(words) < 3 {
+			return time.Time{}, nil, false, errors.New("invalid date: in how long")
+		}
+		t, err := addDuration(now, words[1], words[2])
+		return t, words[3:], true, err
+	}
+	if wd, ok := lookupWeekday(words[0]); ok {
+		days := (int(wd) - int(today.Weekday()) + 7) % 7
+		if days == 0 {
+			return time.Time{}, nil, false, fmt.Errorf("%w %q: %s is today, say today or next %s", ErrAmbiguous, words[0], words[0], words[0])
+		}
+		return today.AddDate(0, 0, days), words[1:], false, nil
+	}
+	// a time of the day alone
+	if _, _, err := parseClock(words); err == nil || errors.Is(err, ErrAmbiguous) {
+		return today, words, false, nil
+	}
+	return time.Time{}, nil, false, fmt.Errorf("invalid date %q", strings.Join(words, " "))
+}
+
+// weekIndex returns the index of the weekday in the weeks, from 0 for WeekStart
+func (p Parser) weekIndex(wd time.Weekday) int {
+	return (int(wd) - int(p.WeekStart) + 7) % 7
+}
+
+// addDuration adds the count, a number or "a" or "an", of the unit to the time
+func addDuration(t time.Time, count, unit string) (time.Time, error) {
+	n, err := strconv.Atoi(count)
+	if count == "a" || count == "an" {
+		n, err = 1, nil
+	}
+	if err != nil || n < 0 {
+		return time.Time{}, fmt.Errorf("invalid date: %q is not a count", count)
+	}
+	switch strings.TrimSuffix(unit, "s") {
+	case "minute", "min":
+		return t.Add(time.Duration(n) * time.Minute), nil
+	case "hour":
+		return t.Add(time.Duration(n) * time.Hour), nil
+	case "day":
+		return t.AddDate(0, 0, n), nil
+	case "week":
+		return t.AddDate(0, 0, 7*n), nil
+	case "month":
+		return addMonths(t, n), nil
+	case "year":
+		return addMonths(t, 12*n), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid date: unknown unit %q", unit)
+}
+
+// addMonths adds the months to the time, on the last day of the month if it has less days,
+// e.g. on February 29 one month after January 31, 2024
+func addMonths(t time.Time, n int) time.Time {
+	first := time.Date(t.Year(), t.Month()+time.Month(n), 1, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+	last := first.AddDate(0, 1, -1).Day()
+	return first.AddDate(0, 0, min(t.Day(), last)-1)
+}
+
+// parseClock parses the time of the day, optionally after "at"
+func parseClock(words []string) (int, int, error) {
+	if len(words) > 0 && words[0] == "at" {
+		words = words[1:]
+	}
+	text := strings.Join(words, "")
+	switch text {
+	case "noon":
+		return 12, 0, nil
+	case "midnight":
+		return 0, 0, nil
+	}
+	if m := clock12.FindStringSubmatch(text); m != nil {
+		hour, _ := strconv.Atoi(m[1])
+		min, _ := strconv.Atoi(m[2])
+		if hour < 1 || hour > 12 || min > 59 {
+			return 0, 0, fmt.Errorf("invalid time of the day %q", strings.Join(words, " "))
+		}
+		hour %= 12
+		if m[3] == "pm" {
+			hour += 12
+		}
+		return hour, min, nil
+	}
+	if m := clock24.FindStringSubmatch(text); m != nil {
+		hour, _ := strconv.Atoi(m[1])
+		min, _ := strconv.Atoi(m[2])
+		if hour > 23 || min > 59 {
+			return 0, 0, fmt.Errorf("invalid time of the day %q", strings.Join(words, " "))
+		}
+		return hour, min, nil
+	}
+	if hour, err := strconv.Atoi(text); err == nil && hour >= 0 && hour <= 23 {
+		if hour >= 1 && hour <= 12 {
+			return 0, 0, fmt.Errorf("%w %q: say %dam, %dpm or %d:00", ErrAmbiguous, strings.Join(words, " "), hour, hour, hour+12)
+		}
+		return hour, 0, nil
+	}
+	return 0, 0, fmt.Errorf("invalid time of the day %q", strings.Join(words, " "))
+}
@@ -0,0 +1,105 @@
+package dates
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	// a wednesday
+	now := time.Date(2026, time.October, 14, 10, 30, 0, 0, time.UTC)
+	p := Parser{Now: func() time.Time { return now }, Location: time.UTC, WeekStart: time.Monday}
+	date := func(month time.Month, day, hour, min int) time.Time {
+		return time.Date(2026, month, day, hour, min, 0, 0, time.UTC)
+	}
+	for s, expected := range map[string]time.Time{
+		"2026-11-01":           date(time.November, 1, 0, 0),
+		"2026-11-01 09:15":     date(time.November, 1, 9, 15),
+		"2026-11-01T09:15:00Z": date(time.November, 1, 9, 15),
+
+		"today":               date(time.October, 14, 0, 0),
+		"Tomorrow 5pm":        date(time.October, 15, 17, 0),
+		"tomorrow at 5:30 pm": date(time.October, 15, 17, 30),
+		"tomorrow midnight":   date(time.October, 15, 0, 0),
+		"friday":              date(time.October, 16, 0, 0),
+		"fri noon":            date(time.October, 16, 12, 0),
+		"tuesday":             date(time.October, 20, 0, 0),
+		"this friday":         date(time.October, 16, 0, 0),
+		"this  wednesday 9am": date(time.October, 14, 9, 0),
+		"next friday":         date(time.October, 23, 0, 0),
+		"next monday 08:00":   date(time.October, 19, 8, 0),
+		"next sunday":         date(time.October, 25, 0, 0),
+		"next week":           date(time.October, 19, 0, 0),
+		"next month":          date(time.November, 1, 0, 0),
+		"next year":           time.Date(2027, time.January, 1, 0, 0, 0, 0, time.UTC),
+		"in 3 days":           date(time.October, 17, 10, 30),
+		"in a week":           date(time.October, 21, 10, 30),
+		"in an hour":          date(time.October, 14, 11, 30),
+		"in 90 minutes":       date(time.October, 14, 12, 0),
+		"in 4 months":         time.Date(2027, time.February, 14, 10, 30, 0, 0, time.UTC),
+		"5pm":                 date(time.October, 14, 17, 0),
+		"at 17:00":            date(time.October, 14, 17, 0),
+		"at 18":               date(time.October, 14, 18, 0),
+		"today 12am":          date(time.October, 14, 0, 0),
+		"tomorrow 12pm":       date(time.October, 15, 12, 0),
+	} {
+		tm, err := p.Parse(s)
+		if assert.NoError(t, err, s) {
+			assert.Equal(t, expected, tm, s)
+		}
+	}
+
+	for _, s := range []string{"03/04", "4.3.2026", "at 5", "tomorrow 5", "wednesday", "9am"} {
+		_, err := p.Parse(s)
+		assert.ErrorIs(t, err, ErrAmbiguous, s)
+	}
+	for _, s := range []string{"", "someday", "in 3 fortnights", "in two days", "in 2 days 5pm", "this monday", "next moon", "tomorrow 25:00", "today 13pm"} {
+		_, err := p.Parse(s)
+		if assert.Error(t, err, s) {
+			assert.NotErrorIs(t, err, ErrAmbiguous, s)
+		}
+	}
+}
+
+func TestParseWeekStart(t *testing.T) {
+	// a wednesday
+	now := time.Date(2026, time.October, 14, 10, 30, 0, 0, time.UTC)
+	p := Parser{Now: func() time.Time { return now }, Location: time.UTC, WeekStart: time.Sunday}
+
+	tm, err := p.Parse("next sunday")
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2026, time.October, 18, 0, 0, 0, 0, time.UTC), tm)
+	tm, err = p.Parse("next week")
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2026, time.October, 18, 0, 0, 0, 0, time.UTC), tm)
+	_, err = p.Parse("this sunday")
+	assert.Error(t, err, "past, the week started on sunday")
+}
+
+func TestAddMonths(t *testing.T) {
+	jan31 := time.Date(2024, time.January, 31, 9, 0, 0, 0, time.UTC)
+	assert.Equal(t, time.Date(2024, time.February, 29, 9, 0, 0, 0, time.UTC), addMonths(jan31, 1))
+	assert.Equal(t, time.Date(2024, time.March, 31, 9, 0, 0, 0, time.UTC), addMonths(jan31, 2))
+	assert.Equal(t, time.Date(2025, time.February, 28, 9, 0, 0, 0, time.UTC), addMonths(jan31, 13))
+}
+
+func TestWeekStart(t *testing.T) {
+	for locale, expected := range map[string]time.Weekday{
+		"en_US.UTF-8":     time.Sunday,
+		"en_GB.UTF-8":     time.Monday,
+		"de_DE":           time.Monday,
+		"pt_br.utf8@euro": time.Sunday,
+		"ar_EG.UTF-8":     time.Saturday,
+		"C":               time.Monday,
+		"":                time.Monday,
+	} {
+		assert.Equal(t, expected, WeekStart(locale), locale)
+	}
+	t.Setenv("LC_ALL", "")
+	t.Setenv("LC_TIME", "en_US.UTF-8")
+	t.Setenv("LANG", "de_DE.UTF-8")
+	assert.Equal(t, time.Sunday, WeekStartFromEnv())
+}
@@ -0,0 +1,3 @@
+// Package dates parses the dates as people type them, like "tomorrow 5pm", "next friday" or
+// "in 3 days", relative to the current time and with the weeks starting as in the locale.
+package dates
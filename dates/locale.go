@@ -0,0 +1,56 @@
+package dates
+
+import (
+	"os"
+	"strings"
+	"time"
+)
+
+// weekStarts are the first days of the week of the regions where it's not monday, as in the
+// CLDR week data
+var weekStarts = map[string]time.Weekday{
+	// sunday
+	"AG": time.Sunday, "AS": time.Sunday, "BR": time.Sunday, "BS": time.Sunday, "BT": time.Sunday,
+	"BW": time.Sunday, "BZ": time.Sunday, "CA": time.Sunday, "CN": time.Sunday, "CO": time.Sunday,
+	"DM": time.Sunday, "DO": time.Sunday, "ET": time.Sunday, "GT": time.Sunday, "GU": time.Sunday,
+	"HK": time.Sunday, "HN": time.Sunday, "ID": time.Sunday, "IL": time.Sunday, "IN": time.Sunday,
+	"JM": time.Sunday, "JP": time.Sunday, "KE": time.Sunday, "KH": time.Sunday, "KR": time.Sunday,
+	"LA": time.Sunday, "MH": time.Sunday, "MM": time.Sunday, "MO": time.Sunday, "MT": time.Sunday,
+	"MX": time.Sunday, "MZ": time.Sunday, "NI": time.Sunday, "NP": time.Sunday, "PA": time.Sunday,
+	"PE": time.Sunday, "PH": time.Sunday, "PK": time.Sunday, "PR": time.Sunday, "PT": time.Sunday,
+	"PY": time.Sunday, "SA": time.Sunday, "SG": time.Sunday, "SV": time.Sunday, "TH": time.Sunday,
+	"TT": time.Sunday, "TW": time.Sunday, "UM": time.Sunday, "US": time.Sunday, "VE": time.Sunday,
+	"VI": time.Sunday, "WS": time.Sunday, "YE": time.Sunday, "ZA": time.Sunday, "ZW": time.Sunday,
+	// saturday
+	"AE": time.Saturday, "AF": time.Saturday, "BH": time.Saturday, "DJ": time.Saturday,
+	"DZ": time.Saturday, "EG": time.Saturday, "IQ": time.Saturday, "IR": time.Saturday,
+	"JO": time.Saturday, "KW": time.Saturday, "LY": time.Saturday, "OM": time.Saturday,
+	"QA": time.Saturday, "SD": time.Saturday, "SY": time.Saturday,
+}
+
+// WeekStart returns the first day of the week in the POSIX locale, e.g. sunday for "en_US.UTF-8".
+// Monday, as in ISO 8601, if the locale has no region, or it's unknown.
+func WeekStart(locale string) time.Weekday {
+	// language[_territory][.codeset][@modifier]
+	locale, _, _ = strings.Cut(locale, "@")
+	locale, _, _ = strings.Cut(locale, ".")
+	_, region, ok := strings.Cut(locale, "_")
+	if !ok {
+		return time.Monday
+	}
+	if day, ok := weekStarts[strings.ToUpper(region)]; ok {
+		return day
+	}
+	return time.Monday
+}
+
+// WeekStartFromEnv returns the first day of the week in the locale of the dates in the
+// environment: the first set of LC_ALL, LC_TIME and LANG
+func WeekStartFromEnv() time.Weekday {
+	for _, name := range []string{"LC_ALL", "LC_TIME", "LANG"} {
+		if locale := os.Getenv(name); locale != "" {
+			return WeekStart(locale)
+		}
+	}
+	return time.Monday
+}
@@ -0,0 +1,298 @@
+package ical
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gotestbootcamp/go-todo-app/task"
+)
+
+// floatingLayout is the layout of the DATE-TIME values in a time zone, or in none
+const floatingLayout = "20060102T150405"
+
+// statuses are the statuses of the tasks of the VTODO components in each status
+var statuses = map[string]task.Status{
+	"NEEDS-ACTION": task.Pending,
+	"IN-PROCESS":   task.Assigned,
+	"COMPLETED":    task.Completed,
+	"CANCELLED":    task.Deleted,
+}
+
+// durationValue matches the values of the DURATION properties, like -PT15M or P1DT12H
+var durationValue = regexp.MustCompile(`^([+-])?P(?:(\d+)W)?(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+)S)?)?$`)
+
+// contentLine is a property of a component, or the BEGIN and END of a component
+type contentLine struct {
+	name   string
+	params map[string]string
+	value  string
+}
+
+// Decode reads the to-dos of the iCalendar object, with their UID as ID. The dates and the times
+// without a time zone are in the location; the reminder of a to-do is the trigger of its first
+// alarm, and the spaces of its categories are dashes in its tags. The other components, and the
+// properties the tasks have no room for, are ignored.
+// Fails if a to-do has no UID, or isn't a valid task, e.g. if its recurrence rule isn't supported.
+func Decode(r io.Reader, loc *time.Location) ([]Todo, error) {
+	lines, err := readLines(r)
+	if err != nil {
+		return nil, err
+	}
+	var todos []Todo
+	// the components the current line is in, the innermost last
+	var stack []string
+	var todo *Todo
+	// trigger is the trigger of the first alarm of the to-do, relative to its due date unless absolute
+	var trigger string
+	var absolute bool
+	for n, line := range lines {
+		cl, err := parseLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n+1, err)
+		}
+		switch cl.name {
+		case "BEGIN":
+			stack = append(stack, strings.ToUpper(cl.value))
+			if len(stack) == 2 && stack[1] == "VTODO" {
+				todo, trigger, absolute = &Todo{Task: task.New("")}, "", false
+			}
+			continue
+		case "END":
+			if len(stack) == 0 || stack[len(stack)-1] != strings.ToUpper(cl.value) {
+				return nil, fmt.Errorf("line %d: unexpected END:%s", n+1, cl.value)
+			}
+			stack = stack[:len(stack)-1]
+			if len(stack) == 1 && todo != nil {
+				if err := todo.finish(trigger, absolute, loc); err != nil {
+					return nil, err
+				}
+				todos = append(todos, *todo)
+				todo = nil
+			}
+			continue
+		}
+		switch {
+		case todo == nil:
+		case len(stack) == 3 && stack[2] == "VALARM" && cl.name == "TRIGGER" && trigger == "":
+			trigger, absolute = cl.value, cl.params["VALUE"] == "DATE-TIME"
+		case len(stack) == 2:
+			if err := todo.setProperty(cl, loc); err != nil {
+				return nil, fmt.Errorf("line %d: %s: %w", n+1, cl.name, err)
+			}
+		}
+	}
+	if len(stack) > 0 {
+		return nil, fmt.Errorf("no END:%s", stack[len(stack)-1])
+	}
+	return todos, nil
+}
+
+// setProperty sets the field of the task of the to-do to the property
+func (todo *Todo) setProperty(cl contentLine, loc *time.Location) error {
+	tk := &todo.Task
+	var err error
+	switch cl.name {
+	case "UID":
+		todo.UID = unescapeText(cl.value)
+		todo.ID = todo.UID
+	case "SUMMARY":
+		tk.Title = unescapeText(cl.value)
+	case "DESCRIPTION":
+		tk.Description = unescapeText(cl.value)
+	case "DUE":
+		var due time.Time
+		due, err = parseTime(cl, loc)
+		tk.Due = &due
+	case "CREATED":
+		tk.Created, err = parseTime(cl, loc)
+	case "STATUS":
+		status, ok := statuses[strings.ToUpper(cl.value)]
+		if !ok {
+			return fmt.Errorf("unknown status %q", cl.value)
+		}
+		tk.Status = status
+	case "PRIORITY":
+		var priority int
+		if priority, err = strconv.Atoi(cl.value); err == nil {
+			tk.Priority = parsePriority(priority)
+		}
+	case "CATEGORIES":
+		// the tags have no spaces
+		for _, tag := range splitText(cl.value) {
+			if tag = strings.Join(strings.Fields(tag), "-"); tag != "" && !slices.Contains(tk.Tags, tag) {
+				tk.Tags = append(tk.Tags, tag)
+			}
+		}
+	case "RRULE":
+		tk.Recur = cl.value
+	}
+	return err
+}
+
+// finish sets the reminder of the task of the to-do to the trigger of its alarm, if any, and
+// validates the task
+func (todo *Todo) finish(trigger string, absolute bool, loc *time.Location) error {
+	if todo.UID == "" {
+		return errors.New("VTODO without UID")
+	}
+	tk := &todo.Task
+	switch {
+	case trigger == "":
+	case absolute:
+		remind, err := parseTime(contentLine{name: "TRIGGER", value: trigger}, loc)
+		if err != nil {
+			return fmt.Errorf("%s: TRIGGER: %w", todo.UID, err)
+		}
+		tk.Remind = &remind
+	case tk.Due != nil:
+		d, err := parseDuration(trigger)
+		if err != nil {
+			return fmt.Errorf("%s: TRIGGER: %w", todo.UID, err)
+		}
+		remind := tk.Due.Add(d)
+		tk.Remind = &remind
+	}
+	if err := tk.Validate(); err != nil {
+		return fmt.Errorf("%s: %w", todo.UID, err)
+	}
+	return nil
+}
+
+// parsePriority returns the priority of the tasks of the PRIORITY value: from 1 to 4 the high ones,
+// 5 the normal one, from 6 to 9 the low one, as RFC 5545 suggests. Zero is no priority.
+func parsePriority(priority int) task.Priority {
+	switch {
+	case priority <= 0 || priority > 9:
+		return task.PriorityNone
+	case priority == 1:
+		return task.PriorityUrgent
+	case priority < 5:
+		return task.PriorityHigh
+	case priority == 5:
+		return task.PriorityNormal
+	default:
+		return task.PriorityLow
+	}
+}
+
+// parseTime parses the value of a DATE or DATE-TIME property: in UTC, in the time zone of its
+// TZID parameter, or else in the location
+func parseTime(cl contentLine, loc *time.Location) (time.Time, error) {
+	if tzid := cl.params["TZID"]; tzid != "" {
+		if tz, err := time.LoadLocation(strings.TrimPrefix(tzid, "/")); err == nil {
+			loc = tz
+		}
+	}
+	var t time.Time
+	var err error
+	switch {
+	case cl.params["VALUE"] == "DATE" || len(cl.value) == len(dateLayout):
+		t, err = time.ParseInLocation(dateLayout, cl.value, loc)
+	case strings.HasSuffix(cl.value, "Z"):
+		t, err = time.Parse(dateTimeLayout, cl.value)
+	default:
+		t, err = time.ParseInLocation(floatingLayout, cl.value, loc)
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q", cl.value)
+	}
+	return t, nil
+}
+
+// parseDuration parses the value of a DURATION property, like -PT15M
+func parseDuration(s string) (time.Duration, error) {
+	m := durationValue.FindStringSubmatch(s)
+	if m == nil || s == "P" || strings.HasSuffix(s, "T") {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	var d time.Duration
+	for i, unit := range []time.Duration{7 * 24 * time.Hour, 24 * time.Hour, time.Hour, time.Minute, time.Second} {
+		n, _ := strconv.Atoi(m[i+2])
+		d += time.Duration(n) * unit
+	}
+	if m[1] == "-" {
+		d = -d
+	}
+	return d, nil
+}
+
+// readLines reads the content lines of the iCalendar object, unfolded, without the blank ones
+func readLines(r io.Reader) ([]string, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		if len(lines) > 0 && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines, scanner.Err()
+}
+
+// parseLine parses the content line, like DUE;TZID=Europe/Rome:20261101T170000, with the name and
+// the parameters in upper case
+func parseLine(line string) (contentLine, error) {
+	cl := contentLine{params: make(map[string]string)}
+	// the value starts at the first colon which isn't quoted in a parameter
+	quoted := false
+	colon := -1
+	for i, c := range line {
+		if c == '"' {
+			quoted = !quoted
+		} else if c == ':' && !quoted {
+			colon = i
+			break
+		}
+	}
+	if colon < 0 {
+		return cl, fmt.Errorf("invalid content line %q", line)
+	}
+	cl.value = line[colon+1:]
+	parts := strings.Split(line[:colon], ";")
+	cl.name = strings.ToUpper(parts[0])
+	if cl.name == "" {
+		return cl, fmt.Errorf("invalid content line %q", line)
+	}
+	for _, param := range parts[1:] {
+		name, value, _ := strings.Cut(param, "=")
+		cl.params[strings.ToUpper(name)] = strings.Trim(value, `"`)
+	}
+	return cl, nil
+}
+
+// textUnescaper unescapes the values of the TEXT properties
+var textUnescaper = strings.NewReplacer(`\\`, `\`, `\;`, ";", `\,`, ",", `\n`, "\n", `\N`, "\n")
+
+// unescapeText unescapes the value of a TEXT property
+func unescapeText(s string) string {
+	return textUnescaper.Replace(s)
+}
+
+// splitText splits the value of a TEXT property with many values at the commas not escaped, and
+// unescapes the values
+func splitText(s string) []string {
+	var values []string
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case ',':
+			values = append(values, unescapeText(s[start:i]))
+			start = i + 1
+		}
+	}
+	return append(values, unescapeText(s[start:]))
+}
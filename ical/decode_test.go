@@ -0,0 +1,115 @@
+package ical
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gotestbootcamp/go-todo-app/task"
+)
+
+func TestDecode(t *testing.T) {
+	rome, err := time.LoadLocation("Europe/Rome")
+	require.NoError(t, err)
+	input := strings.ReplaceAll(`BEGIN:VCALENDAR
+VERSION:2.0
+PRODID:-//Apple Inc.//iOS 18//EN
+BEGIN:VTIMEZONE
+TZID:Europe/Rome
+END:VTIMEZONE
+BEGIN:VTODO
+UID:A1B2-C3
+CREATED:20261001T093000Z
+SUMMARY:Pay rent\, on time\; rea
+ lly
+DESCRIPTION:ask about\nthe trip
+DUE;TZID=Europe/Rome:20261102T170000
+STATUS:NEEDS-ACTION
+PRIORITY:2
+CATEGORIES:finance,Home Stuff,finance
+RRULE:FREQ=WEEKLY;INTERVAL=2
+BEGIN:VALARM
+ACTION:DISPLAY
+TRIGGER:-PT1H30M
+END:VALARM
+END:VTODO
+BEGIN:VEVENT
+UID:event
+SUMMARY:ignored
+END:VEVENT
+BEGIN:VTODO
+UID:done
+SUMMARY:file taxes
+DUE;VALUE=DATE:20261101
+STATUS:COMPLETED
+BEGIN:VALARM
+TRIGGER;VALUE=DATE-TIME:20261031T080000Z
+END:VALARM
+END:VTODO
+END:VCALENDAR
+`, "\n", "\r\n")
+
+	todos, err := Decode(strings.NewReader(input), time.UTC)
+	require.NoError(t, err)
+	require.Len(t, todos, 2)
+	tk := todos[0].Task
+	assert.Equal(t, "A1B2-C3", todos[0].ID)
+	assert.Equal(t, "A1B2-C3", todos[0].UID)
+	assert.Equal(t, "Pay rent, on time; really", tk.Title)
+	assert.Equal(t, "ask about\nthe trip", tk.Description)
+	assert.Equal(t, time.Date(2026, time.October, 1, 9, 30, 0, 0, time.UTC), tk.Created)
+	require.NotNil(t, tk.Due)
+	assert.True(t, time.Date(2026, time.November, 2, 17, 0, 0, 0, rome).Equal(*tk.Due))
+	require.NotNil(t, tk.Remind)
+	assert.Equal(t, 90*time.Minute, tk.Due.Sub(*tk.Remind))
+	assert.Equal(t, task.Pending, tk.Status)
+	assert.Equal(t, task.PriorityHigh, tk.Priority)
+	assert.Equal(t, []string{"finance", "Home-Stuff"}, tk.Tags)
+	assert.Equal(t, "FREQ=WEEKLY;INTERVAL=2", tk.Recur)
+
+	tk = todos[1].Task
+	assert.Equal(t, task.Completed, tk.Status)
+	assert.Equal(t, time.Date(2026, time.November, 1, 0, 0, 0, 0, time.UTC), *tk.Due)
+	assert.Equal(t, time.Date(2026, time.October, 31, 8, 0, 0, 0, time.UTC), *tk.Remind)
+
+	for _, input := range []string{
+		"BEGIN:VCALENDAR\nBEGIN:VTODO\nSUMMARY:no uid\nEND:VTODO\nEND:VCALENDAR\n",
+		"BEGIN:VCALENDAR\nBEGIN:VTODO\nUID:1\nEND:VTODO\nEND:VCALENDAR\n",
+		"BEGIN:VCALENDAR\nBEGIN:VTODO\nUID:1\nSUMMARY:x\nRRULE:FREQ=DAILY;COUNT=3\nEND:VTODO\nEND:VCALENDAR\n",
+		"BEGIN:VCALENDAR\nBEGIN:VTODO\nUID:1\nSUMMARY:x\nDUE:tomorrow\nEND:VTODO\nEND:VCALENDAR\n",
+		"BEGIN:VCALENDAR\nBEGIN:VTODO\nUID:1\nSUMMARY:x\nEND:VCALENDAR\n",
+		"BEGIN:VCALENDAR\nBEGIN:VTODO\nUID:1\nSUMMARY:x\nEND:VTODO\n",
+		"BEGIN:VCALENDAR\nnot a content line\nEND:VCALENDAR\n",
+	} {
+		_, err := Decode(strings.NewReader(input), time.UTC)
+		assert.Error(t, err, input)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	e := Encoder{Component: ToDo, Location: time.UTC, Undated: true}
+	todos := testTodos()
+	require.NoError(t, e.Encode(&buf, todos))
+	decoded, err := Decode(&buf, time.UTC)
+	require.NoError(t, err)
+	require.Len(t, decoded, 4)
+	for i, todo := range decoded {
+		want := todos[i].Task
+		assert.Equal(t, todos[i].ID+"@"+uidDomain, todo.ID)
+		assert.Equal(t, want.Title, todo.Task.Title)
+		assert.Equal(t, want.Description, todo.Task.Description)
+		assert.Equal(t, want.Status, todo.Task.Status)
+		assert.Equal(t, want.Priority, todo.Task.Priority)
+		assert.Equal(t, want.Tags, todo.Task.Tags)
+		assert.Equal(t, want.Due, todo.Task.Due)
+		assert.Equal(t, want.Remind, todo.Task.Remind)
+		assert.Equal(t, want.Created.Truncate(time.Second).UTC(), todo.Task.Created)
+	}
+	assert.Equal(t, "monthly", todos[0].Task.Recur)
+	assert.Equal(t, "FREQ=MONTHLY", decoded[0].Task.Recur)
+}
@@ -0,0 +1,5 @@
+// Package ical writes the tasks with a due date as iCalendar objects, see RFC 5545, for the
+// calendar apps to show them when they are due: either as events, which all the apps subscribing
+// to a calendar show, or as to-dos, which only the apps managing tasks show, with their status,
+// their priority and their recurrence.
+package ical
@@ -0,0 +1,241 @@
+package ical
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gotestbootcamp/go-todo-app/task"
+)
+
+// Component is the kind of the calendar components of the tasks
+type Component string
+
+const (
+	// Event writes the tasks as VEVENT components, at their due time
+	Event Component = "event"
+	// ToDo writes the tasks as VTODO components, due at their due time
+	ToDo Component = "todo"
+)
+
+// Components are the kinds of the calendar components of the tasks
+var Components = []Component{Event, ToDo}
+
+// prodID identifies the app which wrote the calendars
+const prodID = "-//gotestbootcamp//go-todo-app//EN"
+
+// uidDomain makes the UIDs of the components unique, as RFC 5545 recommends
+const uidDomain = "go-todo-app"
+
+// The layouts of the values of the DATE-TIME properties, always in UTC, and of the DATE ones
+const (
+	dateTimeLayout = "20060102T150405Z"
+	dateLayout     = "20060102"
+)
+
+// maxLineOctets is the length of the content lines, past which they are folded
+const maxLineOctets = 75
+
+// todoStatuses are the statuses of the VTODO components of the tasks in each status
+var todoStatuses = map[task.Status]string{
+	task.Pending:   "NEEDS-ACTION",
+	task.Assigned:  "IN-PROCESS",
+	task.Completed: "COMPLETED",
+}
+
+// priorities are the PRIORITY values of the priorities of the tasks, from 1, the highest, to 9
+var priorities = map[task.Priority]int{
+	task.PriorityUrgent: 1,
+	task.PriorityHigh:   3,
+	task.PriorityNormal: 5,
+	task.PriorityLow:    9,
+}
+
+// recurKeywords are the RRULE values of the recurrence rules of task.ParseRecurrence repeating
+// every period
+var recurKeywords = map[string]string{"daily": "FREQ=DAILY", "weekly": "FREQ=WEEKLY", "monthly": "FREQ=MONTHLY", "yearly": "FREQ=YEARLY"}
+
+// Todo is a task of a calendar, with its ID
+type Todo struct {
+	ID string
+	// UID is the unique identifier of the component of the task. Empty for the ID at uidDomain.
+	UID  string
+	Task task.Task
+}
+
+// Encoder writes the calendars of the tasks
+type Encoder struct {
+	// Name is the name of the calendars, which the apps show. Empty if none.
+	Name string
+	// Component is the kind of the components of the tasks
+	Component Component
+	// Location is the time zone of the dates: the tasks due at midnight are due on the day,
+	// without a time
+	Location *time.Location
+	// Undated includes the tasks without a due date too, as to-dos without a DUE
+	Undated bool
+}
+
+// New returns an Encoder of the calendar with the name, of the components of the kind, with the
+// dates in the local time zone
+func New(name string, component Component) Encoder {
+	return Encoder{Name: name, Component: component, Location: time.Local}
+}
+
+// Encode writes the calendar of the tasks with a due date, like
+//
+//	BEGIN:VCALENDAR
+//	VERSION:2.0
+//	PRODID:-//gotestbootcamp//go-todo-app//EN
+//	BEGIN:VEVENT
+//	UID:1@go-todo-app
+//	DTSTAMP:20261002T093000Z
+//	DTSTART;VALUE=DATE:20261101
+//	DTEND;VALUE=DATE:20261102
+//	SUMMARY:Pay rent
+//	END:VEVENT
+//	END:VCALENDAR
+//
+// The deleted tasks are left out, and the completed ones too as events. The events of the tasks
+// due at a time start and end then, the other ones last the whole day. Their reminder, if any,
+// is an alarm.
+func (e Encoder) Encode(w io.Writer, todos []Todo) error {
+	bw := bufio.NewWriter(w)
+	line := func(name, value string) {
+		writeLine(bw, name+":"+value)
+	}
+	line("BEGIN", "VCALENDAR")
+	line("VERSION", "2.0")
+	line("PRODID", prodID)
+	line("CALSCALE", "GREGORIAN")
+	if e.Name != "" {
+		line("X-WR-CALNAME", escapeText(e.Name))
+	}
+	for _, todo := range todos {
+		tk := todo.Task
+		if (tk.Due == nil && !(e.Undated && e.Component == ToDo)) || tk.Status == task.Deleted || (e.Component == Event && tk.Status == task.Completed) {
+			continue
+		}
+		e.encodeTodo(line, todo)
+	}
+	line("END", "VCALENDAR")
+	return bw.Flush()
+}
+
+// encodeTodo writes the component of the task, which has a due date unless a to-do
+func (e Encoder) encodeTodo(line func(name, value string), todo Todo) {
+	tk := todo.Task
+	component := "VTODO"
+	if e.Component == Event {
+		component = "VEVENT"
+	}
+	uid := todo.UID
+	if uid == "" {
+		uid = todo.ID + "@" + uidDomain
+	}
+	line("BEGIN", component)
+	line("UID", escapeText(uid))
+	// without a METHOD, the time stamp is the last modification of the task
+	line("DTSTAMP", formatDateTime(tk.Updated))
+	line("CREATED", formatDateTime(tk.Created))
+	line("LAST-MODIFIED", formatDateTime(tk.Updated))
+	var due time.Time
+	allDay := false
+	if tk.Due != nil {
+		due = tk.Due.In(e.Location)
+		allDay = due.Equal(time.Date(due.Year(), due.Month(), due.Day(), 0, 0, 0, 0, e.Location))
+	}
+	switch {
+	case tk.Due == nil:
+	case e.Component == ToDo && allDay:
+		line("DUE;VALUE=DATE", due.Format(dateLayout))
+	case e.Component == ToDo:
+		line("DUE", formatDateTime(due))
+	case allDay:
+		line("DTSTART;VALUE=DATE", due.Format(dateLayout))
+		line("DTEND;VALUE=DATE", due.AddDate(0, 0, 1).Format(dateLayout))
+	default:
+		line("DTSTART", formatDateTime(due))
+	}
+	line("SUMMARY", escapeText(tk.Title))
+	if tk.Description != "" {
+		line("DESCRIPTION", escapeText(tk.Description))
+	}
+	if len(tk.Tags) > 0 {
+		tags := make([]string, 0, len(tk.Tags))
+		for _, tag := range tk.Tags {
+			tags = append(tags, escapeText(tag))
+		}
+		line("CATEGORIES", strings.Join(tags, ","))
+	}
+	if rule := recurrenceRule(tk.Recur); rule != "" {
+		line("RRULE", rule)
+	}
+	if e.Component == ToDo {
+		if status, ok := todoStatuses[tk.Status]; ok {
+			line("STATUS", status)
+		}
+		if tk.Status == task.Completed {
+			line("COMPLETED", formatDateTime(tk.Updated))
+		}
+		if priority, ok := priorities[tk.Priority]; ok {
+			line("PRIORITY", fmt.Sprint(priority))
+		}
+	}
+	if tk.Remind != nil {
+		line("BEGIN", "VALARM")
+		line("ACTION", "DISPLAY")
+		line("DESCRIPTION", escapeText(tk.Title))
+		line("TRIGGER;VALUE=DATE-TIME", formatDateTime(*tk.Remind))
+		line("END", "VALARM")
+	}
+	line("END", component)
+}
+
+// recurrenceRule returns the RRULE value of the recurrence rule of a task, empty if it doesn't
+// recur, or if its rule is a cron expression, which RRULE has no room for
+func recurrenceRule(recur string) string {
+	if rule, ok := recurKeywords[recur]; ok {
+		return rule
+	}
+	rule := strings.TrimPrefix(recur, "RRULE:")
+	if !strings.Contains(rule, "FREQ=") || strings.Contains(rule, " ") {
+		return ""
+	}
+	return rule
+}
+
+// formatDateTime formats the time as the value of a DATE-TIME property, in UTC
+func formatDateTime(t time.Time) string {
+	return t.UTC().Format(dateTimeLayout)
+}
+
+// textEscaper escapes the values of the TEXT properties
+var textEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`, "\r", `\n`)
+
+// escapeText escapes the value of a TEXT property
+func escapeText(s string) string {
+	return textEscaper.Replace(s)
+}
+
+// writeLine writes the content line, ended by CRLF, folded every maxLineOctets octets, without
+// splitting the UTF-8 sequences, with the following lines starting with a space
+func writeLine(w *bufio.Writer, s string) {
+	limit := maxLineOctets
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		w.WriteString(s[:cut])
+		w.WriteString("\r\n ")
+		s = s[cut:]
+		// the leading space counts
+		limit = maxLineOctets - 1
+	}
+	w.WriteString(s)
+	w.WriteString("\r\n")
+}
@@ -0,0 +1,115 @@
+package ical
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gotestbootcamp/go-todo-app/task"
+)
+
+func testTodos() []Todo {
+	created := time.Date(2026, time.October, 1, 9, 30, 0, 0, time.UTC)
+	due := time.Date(2026, time.November, 1, 0, 0, 0, 0, time.UTC)
+	at := time.Date(2026, time.November, 2, 17, 0, 0, 0, time.UTC)
+	remind := at.Add(-time.Hour)
+
+	rent := task.New("Pay rent, on time; really")
+	rent.Created, rent.Updated = created, created.Add(24*time.Hour)
+	rent.Due = &due
+	rent.Tags = []string{"finance", "home"}
+	rent.Recur = "monthly"
+	rent.Priority = task.PriorityUrgent
+
+	call := task.New("call mom")
+	call.Created, call.Updated = created, created
+	call.Due = &at
+	call.Remind = &remind
+	call.Description = "ask about\nthe trip"
+	call.Recur = "0 9 * * 1-5"
+
+	done := task.New("file taxes")
+	done.Created, done.Updated = created, created
+	done.Due = &due
+	done.Status = task.Completed
+
+	return []Todo{
+		{ID: "home/1", Task: rent},
+		{ID: "2", Task: call},
+		{ID: "3", Task: done},
+		{ID: "4", Task: task.New("someday")},
+	}
+}
+
+func TestEncodeEvents(t *testing.T) {
+	var buf bytes.Buffer
+	e := Encoder{Name: "todo", Component: Event, Location: time.UTC}
+	require.NoError(t, e.Encode(&buf, testTodos()))
+	assert.Equal(t, strings.ReplaceAll(`BEGIN:VCALENDAR
+VERSION:2.0
+PRODID:-//gotestbootcamp//go-todo-app//EN
+CALSCALE:GREGORIAN
+X-WR-CALNAME:todo
+BEGIN:VEVENT
+UID:home/1@go-todo-app
+DTSTAMP:20261002T093000Z
+CREATED:20261001T093000Z
+LAST-MODIFIED:20261002T093000Z
+DTSTART;VALUE=DATE:20261101
+DTEND;VALUE=DATE:20261102
+SUMMARY:Pay rent\, on time\; really
+CATEGORIES:finance,home
+RRULE:FREQ=MONTHLY
+END:VEVENT
+BEGIN:VEVENT
+UID:2@go-todo-app
+DTSTAMP:20261001T093000Z
+CREATED:20261001T093000Z
+LAST-MODIFIED:20261001T093000Z
+DTSTART:20261102T170000Z
+SUMMARY:call mom
+DESCRIPTION:ask about\nthe trip
+BEGIN:VALARM
+ACTION:DISPLAY
+DESCRIPTION:call mom
+TRIGGER;VALUE=DATE-TIME:20261102T160000Z
+END:VALARM
+END:VEVENT
+END:VCALENDAR
+`, "\n", "\r\n"), buf.String())
+}
+
+func TestEncodeToDos(t *testing.T) {
+	var buf bytes.Buffer
+	e := Encoder{Component: ToDo, Location: time.UTC}
+	require.NoError(t, e.Encode(&buf, testTodos()))
+	out := buf.String()
+	assert.NotContains(t, out, "X-WR-CALNAME")
+	assert.Equal(t, 3, strings.Count(out, "BEGIN:VTODO\r\n"))
+	assert.Contains(t, out, "DUE;VALUE=DATE:20261101\r\nSUMMARY:Pay rent")
+	assert.Contains(t, out, "RRULE:FREQ=MONTHLY\r\nSTATUS:NEEDS-ACTION\r\nPRIORITY:1\r\n")
+	assert.Contains(t, out, "DUE:20261102T170000Z\r\n")
+	assert.Contains(t, out, "SUMMARY:file taxes\r\nSTATUS:COMPLETED\r\nCOMPLETED:20261001T093000Z\r\nEND:VTODO\r\n")
+}
+
+func TestFolding(t *testing.T) {
+	var buf bytes.Buffer
+	due := time.Date(2026, time.November, 1, 0, 0, 0, 0, time.UTC)
+	tk := task.New(strings.Repeat("é", 50))
+	tk.Due = &due
+	e := Encoder{Component: Event, Location: time.UTC}
+	require.NoError(t, e.Encode(&buf, []Todo{{ID: "1", Task: tk}}))
+	var summary []string
+	for _, line := range strings.Split(buf.String(), "\r\n") {
+		assert.LessOrEqual(t, len(line), maxLineOctets)
+		if strings.HasPrefix(line, "SUMMARY:") || (len(summary) > 0 && strings.HasPrefix(line, " ")) {
+			summary = append(summary, line)
+		}
+	}
+	require.Len(t, summary, 2)
+	assert.Equal(t, "SUMMARY:"+strings.Repeat("é", 50), summary[0]+summary[1][1:])
+}
@@ -0,0 +1,7 @@
+// Package index implements search indexes over the items of a Store, kept in memory,
+// built when the storage is opened and kept up to date with the changes performed through it.
+// Indexed maintains a full-text inverted index, mapping each term to the items containing it:
+// searches match the query terms exactly, by prefix, or fuzzily to tolerate typos.
+// FieldIndexed maintains secondary indexes on structured fields, e.g. the status, to look up
+// the items by field value without decoding all their blobs.
+package index
@@ -0,0 +1,245 @@
+package index
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+
+	"github.com/gotestbootcamp/go-todo-app/store"
+)
+
+// FieldExtractor returns the values of a field of a blob. Multi-valued fields,
+// like tags, return all their values; fields without value return none.
+type FieldExtractor func(blob store.Blob) ([]string, error)
+
+// Finder is implemented by the storages which can look up their items by field value
+type Finder interface {
+	// FindBy returns the IDs of the items whose field has the given value, sorted
+	FindBy(field, value string) ([]store.ID, error)
+}
+
+// fielder is implemented by the storages indexing fields of their items
+type fielder interface {
+	Fields() []string
+}
+
+// Fields returns the fields of the items the storage looks up by value, see FindBy, sorted.
+// Nil if the storage can't look up its items by field.
+func Fields(st store.Storage) []string {
+	fd, ok := st.(fielder)
+	if !ok {
+		return nil
+	}
+	return fd.Fields()
+}
+
+// Search returns the IDs of the items of the storage matching the full-text query.
+// Fails with store.ErrUnsupported if the storage can't search its items.
+func Search(st store.Storage, query string) ([]store.ID, error) {
+	sr, ok := st.(Searcher)
+	if !ok {
+		return nil, store.ErrUnsupported
+	}
+	return sr.Search(query)
+}
+
+// FindBy returns the IDs of the items of the storage whose field has the given value.
+// Fails with store.ErrUnsupported if the storage can't look up its items by field.
+func FindBy(st store.Storage, field, value string) ([]store.ID, error) {
+	fd, ok := st.(Finder)
+	if !ok {
+		return nil, store.ErrUnsupported
+	}
+	return fd.FindBy(field, value)
+}
+
+var _ store.Storage = &FieldIndexed{}
+var _ store.Compacter = &FieldIndexed{}
+var _ Finder = &FieldIndexed{}
+var _ Searcher = &FieldIndexed{}
+var _ store.Watcher = &FieldIndexed{}
+
+// FieldIndexed is a Storage decorator which keeps secondary indexes on the registered
+// fields of the items, updated on every change, so the items can be looked up by field
+// value without decoding all their blobs.
+// Changes performed bypassing the FieldIndexed, e.g. by another process sharing the same
+// backend, are not detected: use it only when the process owns the storage.
+type FieldIndexed struct {
+	inner store.Storage
+
+	lock    sync.RWMutex
+	extract map[string]FieldExtractor
+	// values maps each field to its values, each value to the items having it
+	values map[string]map[string]map[store.ID]struct{}
+	// docs maps each item to its field values, to unindex them
+	docs map[store.ID]map[string][]string
+}
+
+// NewFieldIndexed creates a new FieldIndexed decorating the given storage,
+// without any field registered
+func NewFieldIndexed(inner store.Storage) *FieldIndexed {
+	return &FieldIndexed{
+		inner:   inner,
+		extract: make(map[string]FieldExtractor),
+		values:  make(map[string]map[string]map[store.ID]struct{}),
+		docs:    make(map[store.ID]map[string][]string),
+	}
+}
+
+// Register indexes the given field of all the items, extracting its values with the given
+// function. The items whose field can't be extracted are not indexed on that field.
+// Registering a field twice fails.
+func (fi *FieldIndexed) Register(field string, extract FieldExtractor) error {
+	fi.lock.Lock()
+	defer fi.lock.Unlock()
+	if _, ok := fi.extract[field]; ok {
+		return fmt.Errorf("field %q already registered", field)
+	}
+	fi.extract[field] = extract
+	fi.values[field] = make(map[string]map[store.ID]struct{})
+	return store.Walk(fi.inner, func(item store.Item) error {
+		if store.IsQuarantined(item.ID) {
+			return nil
+		}
+		fi.addField(item.ID, field, item.Blob)
+		return nil
+	})
+}
+
+// Fields returns the registered fields, sorted
+func (fi *FieldIndexed) Fields() []string {
+	fi.lock.RLock()
+	defer fi.lock.RUnlock()
+	res := make([]string, 0, len(fi.extract))
+	for field := range fi.extract {
+		res = append(res, field)
+	}
+	sort.Strings(res)
+	return res
+}
+
+// FindBy returns the IDs of the items whose field has the given value, sorted.
+// Fails if the field is not registered.
+func (fi *FieldIndexed) FindBy(field, value string) ([]store.ID, error) {
+	fi.lock.RLock()
+	defer fi.lock.RUnlock()
+	values, ok := fi.values[field]
+	if !ok {
+		return nil, fmt.Errorf("field %q not indexed", field)
+	}
+	res := make([]store.ID, 0, len(values[value]))
+	for id := range values[value] {
+		res = append(res, id)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i] < res[j]
+	})
+	return res, nil
+}
+
+// Search forwards the full-text search to the inner storage
+func (fi *FieldIndexed) Search(query string) ([]store.ID, error) {
+	return Search(fi.inner, query)
+}
+
+// add indexes all the registered fields of the item
+func (fi *FieldIndexed) add(objectID store.ID, blob store.Blob) {
+	fi.lock.Lock()
+	defer fi.lock.Unlock()
+	fi.remove(objectID)
+	for field := range fi.extract {
+		fi.addField(objectID, field, blob)
+	}
+}
+
+// addField must be called with the lock held
+func (fi *FieldIndexed) addField(objectID store.ID, field string, blob store.Blob) {
+	if store.IsMeta(objectID) {
+		return
+	}
+	vals, err := fi.extract[field](blob)
+	if err != nil {
+		log.Printf("index: can't extract field %q of object %v: %v", field, objectID, err)
+		return
+	}
+	if len(vals) == 0 {
+		return
+	}
+	doc, ok := fi.docs[objectID]
+	if !ok {
+		doc = make(map[string][]string)
+		fi.docs[objectID] = doc
+	}
+	doc[field] = vals
+	for _, val := range vals {
+		ids, ok := fi.values[field][val]
+		if !ok {
+			ids = make(map[store.ID]struct{})
+			fi.values[field][val] = ids
+		}
+		ids[objectID] = struct{}{}
+	}
+}
+
+// remove must be called with the lock held
+func (fi *FieldIndexed) remove(objectID store.ID) {
+	for field, vals := range fi.docs[objectID] {
+		for _, val := range vals {
+			ids := fi.values[field][val]
+			delete(ids, objectID)
+			if len(ids) == 0 {
+				delete(fi.values[field], val)
+			}
+		}
+	}
+	delete(fi.docs, objectID)
+}
+
+// Watch forwards the watch of the changes to the inner storage
+func (fi *FieldIndexed) Watch(ctx context.Context) (<-chan store.Event, error) {
+	return store.Watch(ctx, fi.inner)
+}
+
+func (fi *FieldIndexed) Compact() (int, error) {
+	return store.Compact(fi.inner)
+}
+
+func (fi *FieldIndexed) Close() error {
+	return fi.inner.Close()
+}
+
+func (fi *FieldIndexed) Create(objectID store.ID, data store.Blob) error {
+	if err := fi.inner.Create(objectID, data); err != nil {
+		return err
+	}
+	fi.add(objectID, data)
+	return nil
+}
+
+func (fi *FieldIndexed) LoadAll() ([]store.Item, error) {
+	return fi.inner.LoadAll()
+}
+
+func (fi *FieldIndexed) Load(objectID store.ID) (store.Blob, error) {
+	return fi.inner.Load(objectID)
+}
+
+func (fi *FieldIndexed) Save(objectID store.ID, blob store.Blob) error {
+	if err := fi.inner.Save(objectID, blob); err != nil {
+		return err
+	}
+	fi.add(objectID, blob)
+	return nil
+}
+
+func (fi *FieldIndexed) Delete(objectID store.ID) error {
+	if err := fi.inner.Delete(objectID); err != nil {
+		return err
+	}
+	fi.lock.Lock()
+	defer fi.lock.Unlock()
+	fi.remove(objectID)
+	return nil
+}
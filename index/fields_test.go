@@ -0,0 +1,108 @@
+package index
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gotestbootcamp/go-todo-app/store"
+)
+
+// fieldOf extracts the fields of blobs like "status=done tag=home tag=urgent"
+func fieldOf(name string) FieldExtractor {
+	return func(blob store.Blob) ([]string, error) {
+		if len(blob) == 0 {
+			return nil, errors.New("empty blob")
+		}
+		var vals []string
+		for _, pair := range strings.Fields(string(blob)) {
+			key, val, _ := strings.Cut(pair, "=")
+			if key == name {
+				vals = append(vals, val)
+			}
+		}
+		return vals, nil
+	}
+}
+
+func TestFieldIndexed(t *testing.T) {
+	mem, err := store.NewMemory()
+	require.NoError(t, err)
+	require.NoError(t, mem.Create("1", store.Blob("status=done tag=home")))
+	require.NoError(t, mem.Create("2", store.Blob("status=pending tag=home tag=urgent")))
+	require.NoError(t, mem.Create("3", store.Blob{}))
+
+	fi := NewFieldIndexed(mem)
+	require.NoError(t, fi.Register("status", fieldOf("status")))
+	require.NoError(t, fi.Register("tag", fieldOf("tag")))
+	assert.Error(t, fi.Register("tag", fieldOf("tag")))
+	assert.Equal(t, []string{"status", "tag"}, fi.Fields())
+
+	find := func(field, value string) []store.ID {
+		ids, err := fi.FindBy(field, value)
+		require.NoError(t, err)
+		return ids
+	}
+	assert.Equal(t, []store.ID{"1"}, find("status", "done"))
+	assert.Equal(t, []store.ID{"1", "2"}, find("tag", "home"))
+	assert.Equal(t, []store.ID{"2"}, find("tag", "urgent"))
+	assert.Empty(t, find("tag", "work"))
+	_, err = fi.FindBy("due", "2024-11-11")
+	assert.Error(t, err)
+
+	require.NoError(t, fi.Save("2", store.Blob("status=done tag=work")))
+	require.NoError(t, fi.Create("4", store.Blob("status=pending")))
+	assert.Equal(t, []store.ID{"1", "2"}, find("status", "done"))
+	assert.Equal(t, []store.ID{"4"}, find("status", "pending"))
+	assert.Equal(t, []store.ID{"1"}, find("tag", "home"))
+	assert.Empty(t, find("tag", "urgent"))
+
+	require.NoError(t, fi.Delete("1"))
+	assert.Equal(t, []store.ID{"2"}, find("status", "done"))
+	assert.Empty(t, find("tag", "home"))
+
+	// failed changes don't touch the indexes
+	assert.Error(t, fi.Save("5", store.Blob("status=done")))
+	assert.Equal(t, []store.ID{"2"}, find("status", "done"))
+}
+
+func TestIndexesStacked(t *testing.T) {
+	mem, err := store.NewMemory()
+	require.NoError(t, err)
+	ixd, err := NewIndexed(mem, plainText)
+	require.NoError(t, err)
+	fi := NewFieldIndexed(ixd)
+	require.NoError(t, fi.Register("status", fieldOf("status")))
+	outer, err := NewIndexed(fi, plainText)
+	require.NoError(t, err)
+
+	require.NoError(t, outer.Create("1", store.Blob("status=done")))
+	ids, err := FindBy(outer, "status", "done")
+	require.NoError(t, err)
+	assert.Equal(t, []store.ID{"1"}, ids)
+	ids, err = Search(fi, "status")
+	require.NoError(t, err)
+	assert.Equal(t, []store.ID{"1"}, ids)
+
+	assert.Equal(t, []string{"status"}, Fields(outer))
+
+	_, err = FindBy(mem, "status", "done")
+	assert.ErrorIs(t, err, store.ErrUnsupported)
+	_, err = Search(mem, "done")
+	assert.ErrorIs(t, err, store.ErrUnsupported)
+	assert.Nil(t, Fields(mem))
+
+	// the changes are watched through the indexes
+	nt := store.NewNotifier(mem)
+	outer, err = NewIndexed(NewFieldIndexed(nt), plainText)
+	require.NoError(t, err)
+	events, err := store.Watch(context.Background(), outer)
+	require.NoError(t, err)
+	require.NoError(t, outer.Create("2", store.Blob("status=pending")))
+	ev := <-events
+	assert.Equal(t, store.ID("2"), ev.ID)
+}
@@ -0,0 +1,238 @@
+package index
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/gotestbootcamp/go-todo-app/store"
+)
+
+// The scores of the matches of a query term against a indexed term
+const (
+	scoreFuzzy  = 1
+	scorePrefix = 2
+	scoreExact  = 3
+)
+
+// Searcher is implemented by the storages which can search their items
+type Searcher interface {
+	// Search returns the IDs of the items matching all the terms of the query,
+	// best matches first
+	Search(query string) ([]store.ID, error)
+}
+
+var _ Searcher = &Index{}
+
+// Index is a inverted index of the terms of texts identified by IDs.
+// It is safe for concurrent use.
+type Index struct {
+	lock     sync.RWMutex
+	postings map[string]map[store.ID]struct{}
+	docs     map[store.ID][]string
+	// terms holds all the indexed terms, sorted. Nil if outdated.
+	terms []string
+}
+
+// New creates a new empty Index
+func New() *Index {
+	return &Index{
+		postings: make(map[string]map[store.ID]struct{}),
+		docs:     make(map[store.ID][]string),
+	}
+}
+
+// Tokenize splits the text in terms: lowercase sequences of letters and digits
+func Tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+// Len returns the number of texts indexed
+func (ix *Index) Len() int {
+	ix.lock.RLock()
+	defer ix.lock.RUnlock()
+	return len(ix.docs)
+}
+
+// Add indexes the given text, replacing the text previously indexed with the same ID, if any
+func (ix *Index) Add(id store.ID, text string) {
+	ix.lock.Lock()
+	defer ix.lock.Unlock()
+	ix.remove(id)
+	seen := map[string]bool{}
+	var terms []string
+	for _, term := range Tokenize(text) {
+		if seen[term] {
+			continue
+		}
+		seen[term] = true
+		terms = append(terms, term)
+		ids, ok := ix.postings[term]
+		if !ok {
+			ids = make(map[store.ID]struct{})
+			ix.postings[term] = ids
+			ix.terms = nil
+		}
+		ids[id] = struct{}{}
+	}
+	ix.docs[id] = terms
+}
+
+// Remove removes the text with the given ID from the index. Removing a missing ID does nothing.
+func (ix *Index) Remove(id store.ID) {
+	ix.lock.Lock()
+	defer ix.lock.Unlock()
+	ix.remove(id)
+}
+
+// remove must be called with the lock held
+func (ix *Index) remove(id store.ID) {
+	for _, term := range ix.docs[id] {
+		ids := ix.postings[term]
+		delete(ids, id)
+		if len(ids) == 0 {
+			delete(ix.postings, term)
+			ix.terms = nil
+		}
+	}
+	delete(ix.docs, id)
+}
+
+// Search returns the IDs of the texts matching all the terms of the query, best matches first.
+// A query term matches the indexed terms equal to it, starting with it, or, for terms
+// long enough, differing from it by a few characters. Exact matches rank first.
+// The empty query matches nothing.
+func (ix *Index) Search(query string) ([]store.ID, error) {
+	terms := Tokenize(query)
+	if len(terms) == 0 {
+		return []store.ID{}, nil
+	}
+	ix.lock.Lock()
+	defer ix.lock.Unlock()
+	if ix.terms == nil {
+		ix.terms = make([]string, 0, len(ix.postings))
+		for term := range ix.postings {
+			ix.terms = append(ix.terms, term)
+		}
+		sort.Strings(ix.terms)
+	}
+
+	var scores map[store.ID]int
+	for _, term := range terms {
+		matches := ix.match(term)
+		if scores == nil {
+			scores = matches
+			continue
+		}
+		for id, score := range scores {
+			if more, ok := matches[id]; ok {
+				scores[id] = score + more
+			} else {
+				delete(scores, id)
+			}
+		}
+	}
+
+	res := make([]store.ID, 0, len(scores))
+	for id := range scores {
+		res = append(res, id)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if scores[res[i]] != scores[res[j]] {
+			return scores[res[i]] > scores[res[j]]
+		}
+		return res[i] < res[j]
+	})
+	return res, nil
+}
+
+// match returns the IDs of the texts matching the given query term, with the
+// score of the best match of each. Must be called with the lock held.
+func (ix *Index) match(query string) map[store.ID]int {
+	scores := make(map[store.ID]int)
+	add := func(term string, score int) {
+		for id := range ix.postings[term] {
+			if score > scores[id] {
+				scores[id] = score
+			}
+		}
+	}
+	// the terms with the query as prefix, exact match included, are contiguous
+	for idx := sort.SearchStrings(ix.terms, query); idx < len(ix.terms) && strings.HasPrefix(ix.terms[idx], query); idx++ {
+		if ix.terms[idx] == query {
+			add(ix.terms[idx], scoreExact)
+		} else {
+			add(ix.terms[idx], scorePrefix)
+		}
+	}
+	maxEdits := fuzziness(query)
+	if maxEdits == 0 {
+		return scores
+	}
+	qr := []rune(query)
+	for _, term := range ix.terms {
+		tr := []rune(term)
+		if abs(len(tr)-len(qr)) > maxEdits || strings.HasPrefix(term, query) {
+			continue
+		}
+		if editDistance(qr, tr, maxEdits) <= maxEdits {
+			add(term, scoreFuzzy)
+		}
+	}
+	return scores
+}
+
+// fuzziness returns how many edits are tolerated matching the given query term:
+// none for short terms, which would match too much
+func fuzziness(term string) int {
+	switch n := len([]rune(term)); {
+	case n < 4:
+		return 0
+	case n < 8:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// editDistance returns the edit distance between a and b, counting insertions, deletions,
+// substitutions and transpositions of adjacent characters, or any value greater than
+// max if it exceeds max
+func editDistance(a, b []rune, max int) int {
+	prevPrev := make([]int, len(b)+1)
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		rowMin := cur[0]
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				cur[j] = min(cur[j], prevPrev[j-2]+1)
+			}
+			rowMin = min(rowMin, cur[j])
+		}
+		if rowMin > max {
+			return rowMin
+		}
+		prevPrev, prev, cur = prev, cur, prevPrev
+	}
+	return prev[len(b)]
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
@@ -0,0 +1,91 @@
+package index
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gotestbootcamp/go-todo-app/store"
+)
+
+func newTestIndex() *Index {
+	ix := New()
+	ix.Add("1", "Buy groceries: milk, eggs")
+	ix.Add("2", "Write the quarterly report")
+	ix.Add("3", "Report the broken printer")
+	ix.Add("4", "Groceries for the party")
+	return ix
+}
+
+func TestTokenize(t *testing.T) {
+	assert.Equal(t, []string{"buy", "groceries", "milk", "eggs", "2x"}, Tokenize("Buy groceries: milk, eggs (2x)"))
+	assert.Empty(t, Tokenize(" -- "))
+}
+
+func TestSearch(t *testing.T) {
+	ix := newTestIndex()
+
+	for _, tc := range []struct {
+		query    string
+		expected []store.ID
+	}{
+		{"groceries", []store.ID{"1", "4"}},
+		{"GROCERIES milk", []store.ID{"1"}},
+		{"report", []store.ID{"2", "3"}},
+		// prefix
+		{"quart", []store.ID{"2"}},
+		{"gro", []store.ID{"1", "4"}},
+		// fuzzy
+		{"grocerys", []store.ID{"1", "4"}},
+		{"reprot", []store.ID{"2", "3"}},
+		{"prnter", []store.ID{"3"}},
+		// short terms must match exactly or by prefix
+		{"mlk", []store.ID{}},
+		{"report milk", []store.ID{}},
+		{"", []store.ID{}},
+	} {
+		ids, err := ix.Search(tc.query)
+		require.NoError(t, err, tc.query)
+		assert.Equal(t, tc.expected, ids, tc.query)
+	}
+}
+
+func TestSearchRanking(t *testing.T) {
+	ix := New()
+	ix.Add("1", "reporting")
+	ix.Add("2", "report")
+	ix.Add("3", "repot")
+
+	ids, err := ix.Search("report")
+	require.NoError(t, err)
+	assert.Equal(t, []store.ID{"2", "1", "3"}, ids)
+}
+
+func TestAddRemove(t *testing.T) {
+	ix := newTestIndex()
+	assert.Equal(t, 4, ix.Len())
+
+	ix.Add("1", "Buy flowers")
+	ids, err := ix.Search("groceries")
+	require.NoError(t, err)
+	assert.Equal(t, []store.ID{"4"}, ids)
+	ids, err = ix.Search("flowers")
+	require.NoError(t, err)
+	assert.Equal(t, []store.ID{"1"}, ids)
+
+	ix.Remove("4")
+	ix.Remove("5")
+	assert.Equal(t, 3, ix.Len())
+	ids, err = ix.Search("groceries party")
+	require.NoError(t, err)
+	assert.Empty(t, ids)
+}
+
+func TestEditDistance(t *testing.T) {
+	assert.Equal(t, 0, editDistance([]rune("report"), []rune("report"), 2))
+	assert.Equal(t, 1, editDistance([]rune("report"), []rune("repot"), 2))
+	assert.Equal(t, 1, editDistance([]rune("report"), []rune("reprot"), 2))
+	assert.Equal(t, 2, editDistance([]rune("report"), []rune("rpeotr"), 2))
+	assert.Greater(t, editDistance([]rune("report"), []rune("printer"), 2), 2)
+}
@@ -0,0 +1,153 @@
+package index
+
+import (
+	"context"
+	"log"
+
+	"github.com/gotestbootcamp/go-todo-app/store"
+)
+
+// Extractor returns the text to index of a blob
+type Extractor func(blob store.Blob) (string, error)
+
+var _ store.Storage = &Indexed{}
+var _ store.Compacter = &Indexed{}
+var _ Searcher = &Indexed{}
+var _ Finder = &Indexed{}
+var _ store.Watcher = &Indexed{}
+var _ store.Batcher = &Indexed{}
+
+// Indexed is a Storage decorator which keeps a full-text Index of the items, updated
+// on every change, so they can be searched without loading them all.
+// Changes performed bypassing the Indexed, e.g. by another process sharing the same
+// backend, are not detected: use it only when the process owns the storage.
+type Indexed struct {
+	inner   store.Storage
+	extract Extractor
+	index   *Index
+}
+
+// NewIndexed creates a new Indexed decorating the given storage, indexing all its
+// items right away. The items whose text can't be extracted are not indexed.
+func NewIndexed(inner store.Storage, extract Extractor) (*Indexed, error) {
+	ixd := &Indexed{
+		inner:   inner,
+		extract: extract,
+		index:   New(),
+	}
+	err := store.Walk(inner, func(item store.Item) error {
+		if store.IsQuarantined(item.ID) {
+			return nil
+		}
+		ixd.add(item.ID, item.Blob)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ixd, nil
+}
+
+func (ixd *Indexed) add(objectID store.ID, blob store.Blob) {
+	if store.IsMeta(objectID) {
+		return
+	}
+	text, err := ixd.extract(blob)
+	if err != nil {
+		log.Printf("index: can't extract the text of object %v: %v", objectID, err)
+		ixd.index.Remove(objectID)
+		return
+	}
+	ixd.index.Add(objectID, text)
+}
+
+// Search returns the IDs of the items matching all the terms of the query, best
+// matches first. See Index.Search for the matching rules.
+func (ixd *Indexed) Search(query string) ([]store.ID, error) {
+	return ixd.index.Search(query)
+}
+
+// FindBy forwards the lookup by field value to the inner storage
+func (ixd *Indexed) FindBy(field, value string) ([]store.ID, error) {
+	return FindBy(ixd.inner, field, value)
+}
+
+// Fields returns the fields indexed by the inner storage
+func (ixd *Indexed) Fields() []string {
+	return Fields(ixd.inner)
+}
+
+// Watch forwards the watch of the changes to the inner storage
+func (ixd *Indexed) Watch(ctx context.Context) (<-chan store.Event, error) {
+	return store.Watch(ctx, ixd.inner)
+}
+
+func (ixd *Indexed) Compact() (int, error) {
+	return store.Compact(ixd.inner)
+}
+
+func (ixd *Indexed) Close() error {
+	return ixd.inner.Close()
+}
+
+func (ixd *Indexed) Create(objectID store.ID, data store.Blob) error {
+	if err := ixd.inner.Create(objectID, data); err != nil {
+		return err
+	}
+	ixd.add(objectID, data)
+	return nil
+}
+
+func (ixd *Indexed) LoadAll() ([]store.Item, error) {
+	return ixd.inner.LoadAll()
+}
+
+func (ixd *Indexed) Load(objectID store.ID) (store.Blob, error) {
+	return ixd.inner.Load(objectID)
+}
+
+func (ixd *Indexed) Save(objectID store.ID, blob store.Blob) error {
+	if err := ixd.inner.Save(objectID, blob); err != nil {
+		return err
+	}
+	ixd.add(objectID, blob)
+	return nil
+}
+
+func (ixd *Indexed) Delete(objectID store.ID) error {
+	if err := ixd.inner.Delete(objectID); err != nil {
+		return err
+	}
+	ixd.index.Remove(objectID)
+	return nil
+}
+
+func (ixd *Indexed) CreateMany(items []store.Item) error {
+	if err := store.CreateMany(ixd.inner, items); err != nil {
+		return err
+	}
+	for _, item := range items {
+		ixd.add(item.ID, item.Blob)
+	}
+	return nil
+}
+
+func (ixd *Indexed) SaveAll(items []store.Item) error {
+	if err := store.SaveAll(ixd.inner, items); err != nil {
+		return err
+	}
+	for _, item := range items {
+		ixd.add(item.ID, item.Blob)
+	}
+	return nil
+}
+
+func (ixd *Indexed) DeleteAll(ids []store.ID) error {
+	if err := store.DeleteAll(ixd.inner, ids); err != nil {
+		return err
+	}
+	for _, id := range ids {
+		ixd.index.Remove(id)
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package index
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gotestbootcamp/go-todo-app/store"
+)
+
+func plainText(blob store.Blob) (string, error) {
+	if len(blob) == 0 {
+		return "", errors.New("empty blob")
+	}
+	return string(blob), nil
+}
+
+func TestIndexed(t *testing.T) {
+	mem, err := store.NewMemory()
+	require.NoError(t, err)
+	require.NoError(t, mem.Create("1", store.Blob("buy groceries")))
+	require.NoError(t, mem.Create("2", store.Blob{}))
+
+	ixd, err := NewIndexed(mem, plainText)
+	require.NoError(t, err)
+	ids, err := ixd.Search("groceries")
+	require.NoError(t, err)
+	assert.Equal(t, []store.ID{"1"}, ids)
+
+	require.NoError(t, ixd.Create("3", store.Blob("more groceries")))
+	require.NoError(t, ixd.Save("1", store.Blob("buy flowers")))
+	ids, err = ixd.Search("groceries")
+	require.NoError(t, err)
+	assert.Equal(t, []store.ID{"3"}, ids)
+	ids, err = ixd.Search("flowers")
+	require.NoError(t, err)
+	assert.Equal(t, []store.ID{"1"}, ids)
+
+	require.NoError(t, ixd.Delete("3"))
+	ids, err = ixd.Search("groceries")
+	require.NoError(t, err)
+	assert.Empty(t, ids)
+
+	// failed changes don't touch the index
+	assert.Error(t, ixd.Create("1", store.Blob("groceries")))
+	assert.Error(t, ixd.Save("4", store.Blob("groceries")))
+	ids, err = ixd.Search("groceries")
+	require.NoError(t, err)
+	assert.Empty(t, ids)
+}
+
+func TestIndexedBatch(t *testing.T) {
+	mem, err := store.NewMemory()
+	require.NoError(t, err)
+	ixd, err := NewIndexed(mem, plainText)
+	require.NoError(t, err)
+
+	require.NoError(t, ixd.CreateMany([]store.Item{{ID: "1", Blob: store.Blob("buy groceries")}, {ID: "2", Blob: store.Blob("more groceries")}}))
+	require.NoError(t, ixd.SaveAll([]store.Item{{ID: "1", Blob: store.Blob("buy flowers")}}))
+	ids, err := ixd.Search("groceries")
+	require.NoError(t, err)
+	assert.Equal(t, []store.ID{"2"}, ids)
+
+	require.NoError(t, ixd.DeleteAll([]store.ID{"1", "2"}))
+	ids, err = ixd.Search("flowers")
+	require.NoError(t, err)
+	assert.Empty(t, ids)
+
+	// a failed batch doesn't touch the index
+	require.NoError(t, ixd.Create("3", store.Blob("groceries")))
+	assert.Error(t, ixd.CreateMany([]store.Item{{ID: "4", Blob: store.Blob("flowers")}, {ID: "3", Blob: store.Blob("flowers")}}))
+	ids, err = ixd.Search("flowers")
+	require.NoError(t, err)
+	assert.Empty(t, ids)
+}
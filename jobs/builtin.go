@@ -0,0 +1,154 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gotestbootcamp/go-todo-app/ledger"
+	"github.com/gotestbootcamp/go-todo-app/store"
+)
+
+// The names of the built-in jobs
+const (
+	CompactJob    = "compact"
+	TrashPurgeJob = "trash-purge"
+	ArchiveJob    = "archive"
+	OverdueJob    = "overdue"
+	BackupJob     = "backup"
+	ChangelogJob  = "changelog"
+)
+
+// Builtin are the names of the built-in jobs
+var Builtin = []string{CompactJob, TrashPurgeJob, ArchiveJob, OverdueJob, BackupJob, ChangelogJob}
+
+// backupPrefix and backupSuffix enclose the time of the snapshots written by Backup
+const (
+	backupPrefix = "snapshot-"
+	backupSuffix = ".gz"
+	backupTime   = "20060102T150405Z"
+)
+
+// Compact returns the job reclaiming the garbage accumulated in the storage, see store.Compact
+func Compact(st store.Storage) Func {
+	return func(context.Context) (string, error) {
+		count, err := store.Compact(st)
+		return fmt.Sprintf("%d objects rewritten or removed", count), err
+	}
+}
+
+// PurgeTrash returns the job removing for good the objects in the trash for longer than olderThan
+func PurgeTrash(tr *store.Trash, olderThan time.Duration) Func {
+	return func(context.Context) (string, error) {
+		count, err := tr.PurgeTrash(olderThan)
+		return fmt.Sprintf("%d objects purged", count), err
+	}
+}
+
+// Archive returns the job archiving the todos completed and not updated for longer than olderThan,
+// see ledger.Ledger.Archive
+func Archive(ld *ledger.Ledger, olderThan time.Duration) Func {
+	return func(context.Context) (string, error) {
+		items, err := ld.Archive(olderThan)
+		return fmt.Sprintf("%d todos archived", len(items)), err
+	}
+}
+
+// Overdue returns the job calling remind with each of the todos overdue, the most overdue first,
+// e.g. to mail them to their assignees every morning
+func Overdue(ld *ledger.Ledger, remind func(ctx context.Context, item ledger.Item) error) Func {
+	return func(ctx context.Context) (string, error) {
+		items, err := ld.ListOverdue()
+		if err != nil {
+			return "", err
+		}
+		failed := 0
+		var lastErr error
+		for _, item := range items {
+			if ctx.Err() != nil {
+				return fmt.Sprintf("%d of %d todos overdue reminded", len(items)-failed, len(items)), ctx.Err()
+			}
+			if err := remind(ctx, item); err != nil {
+				failed++
+				lastErr = err
+			}
+		}
+		if failed > 0 {
+			return fmt.Sprintf("%d of %d todos overdue reminded", len(items)-failed, len(items)), fmt.Errorf("%d reminders failed, the last with: %w", failed, lastErr)
+		}
+		return fmt.Sprintf("%d todos overdue reminded", len(items)), nil
+	}
+}
+
+// CompactChanges returns the job dropping the entries of the changelog the policy doesn't keep,
+// see ledger.Ledger.CompactChanges
+func CompactChanges(ld *ledger.Ledger, policy ledger.ChangeLogPolicy) Func {
+	return func(context.Context) (string, error) {
+		count, err := ld.CompactChanges(policy)
+		return fmt.Sprintf("%d changes dropped", count), err
+	}
+}
+
+// Backup returns the job writing a snapshot of the storage in the directory, see store.Snapshot,
+// named after the time it's taken, and removing the oldest ones but the latest keep. Zero keeps
+// them all.
+func Backup(st store.Storage, dir string, keep int) Func {
+	return func(context.Context) (string, error) {
+		if err := os.MkdirAll(dir, 0o700); err != nil {
+			return "", err
+		}
+		name := backupPrefix + time.Now().UTC().Format(backupTime) + backupSuffix
+		// written aside first, not to leave a partial snapshot behind
+		f, err := os.CreateTemp(dir, ".snapshot-*")
+		if err != nil {
+			return "", err
+		}
+		defer os.Remove(f.Name())
+		if err := store.Snapshot(st, f); err != nil {
+			f.Close()
+			return "", err
+		}
+		if err := f.Close(); err != nil {
+			return "", err
+		}
+		if err := os.Rename(f.Name(), filepath.Join(dir, name)); err != nil {
+			return "", err
+		}
+		removed, err := pruneBackups(dir, keep)
+		return fmt.Sprintf("%s written, %d old snapshots removed", name, removed), err
+	}
+}
+
+// pruneBackups removes the oldest snapshots of the directory but the latest keep
+func pruneBackups(dir string, keep int) (int, error) {
+	if keep <= 0 {
+		return 0, nil
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return 0, err
+	}
+	var names []string
+	for _, e := range entries {
+		if name := e.Name(); !e.IsDir() && strings.HasPrefix(name, backupPrefix) && strings.HasSuffix(name, backupSuffix) {
+			names = append(names, name)
+		}
+	}
+	if len(names) <= keep {
+		return 0, nil
+	}
+	// the names sort by time
+	slices.Sort(names)
+	removed := 0
+	for _, name := range names[:len(names)-keep] {
+		if err := os.Remove(filepath.Join(dir, name)); err != nil {
+			return removed, err
+		}
+		removed++
+	}
+	return removed, nil
+}
@@ -0,0 +1,7 @@
+// Package jobs runs the recurring maintenance jobs of the server, like the compaction of the
+// store, the purge of its trash, the archiving of the completed todos, the reminders of the overdue
+// ones or the backups, on cron-like schedules.
+// The Scheduler runs each job registered when its Schedule tells, delayed by a random jitter not to
+// run them all at once, never running a job while its previous run isn't over. It keeps the history
+// of the latest runs of each job, which restarting clears.
+package jobs
@@ -0,0 +1,238 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MaxHistory is how many of the latest runs of each job are kept
+const MaxHistory = 20
+
+// ErrNoJob is returned when the job doesn't exist
+type ErrNoJob struct {
+	Name string
+}
+
+func (e ErrNoJob) Error() string {
+	return fmt.Sprintf("no job %q", e.Name)
+}
+
+// ErrExists is returned when registering a job with the name of another one
+type ErrExists struct {
+	Name string
+}
+
+func (e ErrExists) Error() string {
+	return fmt.Sprintf("job %q already exists", e.Name)
+}
+
+// Func runs a job until done or until the context is, and returns what it did, e.g. "12 objects
+// removed", for the history of its runs
+type Func func(ctx context.Context) (string, error)
+
+// Job is a job the Scheduler runs
+type Job struct {
+	Name     string
+	Schedule Schedule
+	// Jitter delays each run by a random duration up to it, for the jobs with the same schedule not
+	// to run all at once. Zero runs the job right on schedule.
+	Jitter time.Duration
+	Func   Func
+}
+
+// Run is a run of a job
+type Run struct {
+	Started  time.Time
+	Finished time.Time
+	// Result is what the job did, as it told
+	Result string
+	// Error is why the run failed, empty if it didn't
+	Error string
+}
+
+// Status is the state of a job, as the Scheduler reports it
+type Status struct {
+	Name     string
+	Schedule string
+	Jitter   time.Duration
+	// Next is when the job runs next, jitter included. Zero while the scheduler isn't running it.
+	Next time.Time
+	// Running tells whether the job is running right now
+	Running bool
+	// Runs are the latest runs of the job, newest first
+	Runs []Run
+}
+
+// entry is a job registered, along with its state
+type entry struct {
+	job     Job
+	next    time.Time
+	running bool
+	// runs are the latest runs, oldest first
+	runs []Run
+}
+
+// Scheduler runs the jobs on their schedules
+type Scheduler struct {
+	lock sync.Mutex
+	jobs map[string]*entry
+	// Now returns the current time. Can be replaced to control time in tests.
+	Now func() time.Time
+	// jitter returns a random duration up to the given one
+	jitter func(max time.Duration) time.Duration
+}
+
+// NewScheduler creates the scheduler, with no jobs
+func NewScheduler() *Scheduler {
+	return &Scheduler{
+		jobs: make(map[string]*entry),
+		Now:  time.Now,
+		jitter: func(max time.Duration) time.Duration {
+			if max <= 0 {
+				return 0
+			}
+			return rand.N(max)
+		},
+	}
+}
+
+// Register adds the job to the ones the scheduler runs, before it's run
+func (s *Scheduler) Register(job Job) error {
+	if job.Name == "" || strings.ContainsAny(job.Name, "/ ") {
+		return fmt.Errorf("invalid job name %q", job.Name)
+	}
+	if job.Func == nil {
+		return fmt.Errorf("job %q: no function", job.Name)
+	}
+	if job.Jitter < 0 {
+		return fmt.Errorf("job %q: negative jitter", job.Name)
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if _, ok := s.jobs[job.Name]; ok {
+		return ErrExists{Name: job.Name}
+	}
+	s.jobs[job.Name] = &entry{job: job}
+	return nil
+}
+
+// Get returns the status of the job. False if there's no such job.
+func (s *Scheduler) Get(name string) (Status, bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	e, ok := s.jobs[name]
+	if !ok {
+		return Status{}, false
+	}
+	return e.status(), true
+}
+
+// List returns the status of all the jobs, by name
+func (s *Scheduler) List() []Status {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	res := make([]Status, 0, len(s.jobs))
+	for _, e := range s.jobs {
+		res = append(res, e.status())
+	}
+	slices.SortFunc(res, func(a, b Status) int { return strings.Compare(a.Name, b.Name) })
+	return res
+}
+
+// status returns the status of the job. The caller must hold the lock.
+func (e *entry) status() Status {
+	runs := slices.Clone(e.runs)
+	slices.Reverse(runs)
+	return Status{
+		Name:     e.job.Name,
+		Schedule: e.job.Schedule.String(),
+		Jitter:   e.job.Jitter,
+		Next:     e.next,
+		Running:  e.running,
+		Runs:     runs,
+	}
+}
+
+// Run runs the jobs on their schedules until the context is done, then waits for the ones running
+// to return
+func (s *Scheduler) Run(ctx context.Context) {
+	s.lock.Lock()
+	entries := make([]*entry, 0, len(s.jobs))
+	for _, e := range s.jobs {
+		entries = append(entries, e)
+	}
+	s.lock.Unlock()
+	var running sync.WaitGroup
+	for _, e := range entries {
+		running.Add(1)
+		go func() {
+			defer running.Done()
+			s.schedule(ctx, e)
+		}()
+	}
+	running.Wait()
+}
+
+// schedule runs the job whenever its schedule tells, until the context is done
+func (s *Scheduler) schedule(ctx context.Context, e *entry) {
+	for {
+		now := s.Now()
+		next := e.job.Schedule.Next(now)
+		if next.IsZero() {
+			slog.Warn("jobs: Scheduler: the job never runs again", "job", e.job.Name)
+			return
+		}
+		next = next.Add(s.jitter(e.job.Jitter))
+		s.lock.Lock()
+		e.next = next
+		s.lock.Unlock()
+		timer := time.NewTimer(next.Sub(now))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		s.execute(ctx, e)
+	}
+}
+
+// execute runs the job once, recording the run in its history
+func (s *Scheduler) execute(ctx context.Context, e *entry) {
+	s.lock.Lock()
+	e.running = true
+	s.lock.Unlock()
+	run := Run{Started: s.Now()}
+	slog.Info("jobs: Scheduler: job started", "job", e.job.Name)
+	result, err := call(ctx, e.job.Func)
+	run.Finished, run.Result = s.Now(), result
+	if err != nil {
+		run.Error = err.Error()
+		slog.Error("jobs: Scheduler: job failed", "job", e.job.Name, "duration", run.Finished.Sub(run.Started), "error", err)
+	} else {
+		slog.Info("jobs: Scheduler: job done", "job", e.job.Name, "duration", run.Finished.Sub(run.Started), "result", result)
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	e.running = false
+	e.runs = append(e.runs, run)
+	if len(e.runs) > MaxHistory {
+		e.runs = e.runs[len(e.runs)-MaxHistory:]
+	}
+}
+
+// call calls the function of the job, turning its panics into errors not to stop the server
+func call(ctx context.Context, fn Func) (result string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return fn(ctx)
+}
@@ -0,0 +1,163 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gotestbootcamp/go-todo-app/ledger"
+	"github.com/gotestbootcamp/go-todo-app/store"
+	"github.com/gotestbootcamp/go-todo-app/task"
+)
+
+func TestSchedule(t *testing.T) {
+	// a wednesday
+	now := time.Date(2024, 3, 6, 12, 34, 56, 0, time.UTC)
+	for spec, want := range map[string]time.Time{
+		"* * * * *":            time.Date(2024, 3, 6, 12, 35, 0, 0, time.UTC),
+		"*/15 * * * *":         time.Date(2024, 3, 6, 12, 45, 0, 0, time.UTC),
+		"30 3 * * *":           time.Date(2024, 3, 7, 3, 30, 0, 0, time.UTC),
+		"0 9-17/4 * * *":       time.Date(2024, 3, 6, 13, 0, 0, 0, time.UTC),
+		"0 0 * * fri":          time.Date(2024, 3, 8, 0, 0, 0, 0, time.UTC),
+		"0 0 * * 7":            time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC),
+		"0 0 1,15 * *":         time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC),
+		"0 0 29 feb *":         time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC),
+		"0 0 20 * mon":         time.Date(2024, 3, 11, 0, 0, 0, 0, time.UTC),
+		"@hourly":              time.Date(2024, 3, 6, 13, 0, 0, 0, time.UTC),
+		"@daily":               time.Date(2024, 3, 7, 0, 0, 0, 0, time.UTC),
+		"@weekly":              time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC),
+		"@monthly":             time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC),
+		"@yearly":              time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+		"@every 90m":           now.Add(90 * time.Minute),
+		" 0   12  * * MON-FRI": time.Date(2024, 3, 7, 12, 0, 0, 0, time.UTC),
+	} {
+		s, err := ParseSchedule(spec)
+		require.NoError(t, err, spec)
+		assert.Equal(t, want, s.Next(now), spec)
+	}
+	for _, spec := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "* * * 13 *", "* * * * 8",
+		"*/0 * * * *", "5-1 * * * *", "* * * * someday", "@often", "@every 1ms", "@every soon", "0 0 30 feb *"} {
+		_, err := ParseSchedule(spec)
+		assert.Error(t, err, spec)
+	}
+}
+
+func TestScheduler(t *testing.T) {
+	s := NewScheduler()
+	every, err := ParseSchedule("@every 1s")
+	require.NoError(t, err)
+	var runs atomic.Int32
+	require.NoError(t, s.Register(Job{Name: "count", Schedule: every, Func: func(ctx context.Context) (string, error) {
+		if runs.Add(1) == 2 {
+			return "", errors.New("failed")
+		}
+		return "counted", nil
+	}}))
+	require.NoError(t, s.Register(Job{Name: "panic", Schedule: every, Jitter: time.Millisecond, Func: func(ctx context.Context) (string, error) {
+		panic("oops")
+	}}))
+	assert.Equal(t, ErrExists{Name: "count"}, s.Register(Job{Name: "count", Schedule: every, Func: func(context.Context) (string, error) { return "", nil }}))
+	assert.Error(t, s.Register(Job{Name: "no func", Schedule: every}))
+	assert.Error(t, s.Register(Job{Name: "nofunc", Schedule: every}))
+
+	statuses := s.List()
+	require.Len(t, statuses, 2)
+	assert.Equal(t, "count", statuses[0].Name)
+	assert.Equal(t, "@every 1s", statuses[0].Schedule)
+	assert.True(t, statuses[0].Next.IsZero())
+	_, ok := s.Get("nope")
+	assert.False(t, ok)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.Run(ctx)
+	}()
+	require.Eventually(t, func() bool { return runs.Load() >= 2 }, 5*time.Second, 10*time.Millisecond)
+	st, ok := s.Get("count")
+	require.True(t, ok)
+	assert.False(t, st.Next.IsZero())
+	cancel()
+	<-done
+
+	st, _ = s.Get("count")
+	require.Len(t, st.Runs, int(runs.Load()))
+	// newest first
+	assert.Equal(t, "failed", st.Runs[len(st.Runs)-2].Error)
+	assert.Equal(t, "counted", st.Runs[len(st.Runs)-1].Result)
+	assert.Empty(t, st.Runs[len(st.Runs)-1].Error)
+	assert.False(t, st.Running)
+	st, _ = s.Get("panic")
+	require.NotEmpty(t, st.Runs)
+	assert.Equal(t, "panic: oops", st.Runs[0].Error)
+	assert.Equal(t, time.Millisecond, st.Jitter)
+}
+
+func TestBuiltin(t *testing.T) {
+	mem, err := store.NewMemory()
+	require.NoError(t, err)
+	ld, err := ledger.New(mem)
+	require.NoError(t, err)
+	past := time.Now().Add(-time.Hour)
+	rent := task.New("pay rent")
+	rent.Due = &past
+	_, err = ld.CreateAll(store.DefaultList, []task.Task{rent, task.New("buy milk")})
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	var reminded []string
+	result, err := Overdue(ld, func(ctx context.Context, item ledger.Item) error {
+		reminded = append(reminded, item.Task.Title)
+		return nil
+	})(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "1 todos overdue reminded", result)
+	assert.Equal(t, []string{"pay rent"}, reminded)
+	result, err = Overdue(ld, func(context.Context, ledger.Item) error { return errors.New("no mail") })(ctx)
+	assert.ErrorContains(t, err, "no mail")
+	assert.Equal(t, "0 of 1 todos overdue reminded", result)
+
+	result, err = Archive(ld, time.Hour)(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "0 todos archived", result)
+
+	result, err = CompactChanges(ld, ledger.ChangeLogPolicy{MaxEvents: 1})(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "1 changes dropped", result)
+
+	dir := filepath.Join(t.TempDir(), "backups")
+	for _, name := range []string{"snapshot-20200101T000000Z.gz", "snapshot-20200102T000000Z.gz", "notes.txt"} {
+		require.NoError(t, os.MkdirAll(dir, 0o700))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), nil, 0o600))
+	}
+	result, err = Backup(mem, dir, 2)(ctx)
+	require.NoError(t, err)
+	assert.Contains(t, result, "written, 1 old snapshots removed")
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+	assert.Equal(t, "notes.txt", entries[0].Name())
+	assert.Equal(t, "snapshot-20200102T000000Z.gz", entries[1].Name())
+
+	// the snapshot restores the todos
+	f, err := os.Open(filepath.Join(dir, entries[2].Name()))
+	require.NoError(t, err)
+	defer f.Close()
+	restored, err := store.NewMemory()
+	require.NoError(t, err)
+	count, err := store.Restore(restored, f)
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, count, 2)
+
+	result, err = Compact(mem)(ctx)
+	require.NoError(t, err)
+	assert.Contains(t, result, "objects rewritten or removed")
+}
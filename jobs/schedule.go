@@ -0,0 +1,185 @@
+package jobs
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// macros are the shorthands of the schedules
+var macros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// field is a field of the cron expressions
+type field struct {
+	name     string
+	min, max int
+	// names are the names of the values, from min on
+	names []string
+}
+
+var fields = []field{
+	{name: "minute", min: 0, max: 59},
+	{name: "hour", min: 0, max: 23},
+	{name: "day of month", min: 1, max: 31},
+	{name: "month", min: 1, max: 12, names: []string{"jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}},
+	// 7 is sunday too
+	{name: "day of week", min: 0, max: 7, names: []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}},
+}
+
+// maxSearch bounds how far Next looks for the next time of a schedule
+const maxSearch = 5 * 366 * 24 * time.Hour
+
+// Schedule tells when a job runs: either at the times matching a cron expression, or at a fixed
+// interval
+type Schedule struct {
+	spec string
+	// every is the interval of the "@every" schedules, zero for the cron expressions
+	every time.Duration
+	// the bits of the values of each field matching
+	minute, hour, dom, month, dow uint64
+	// anyDay tells whether the day of month or the day of week is "*": both must match then,
+	// rather than either
+	anyDay bool
+}
+
+// ParseSchedule parses the schedule, either a cron expression of five fields, "minute hour
+// day-of-month month day-of-week" like "30 3 * * mon-fri", with lists, ranges and steps like
+// "*/15" or "1-5/2", a macro like "@daily" or "@hourly", or "@every" a duration like "@every 6h".
+// The times are in the local time zone.
+func ParseSchedule(spec string) (Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	s := Schedule{spec: spec}
+	if d, ok := strings.CutPrefix(spec, "@every "); ok {
+		every, err := time.ParseDuration(strings.TrimSpace(d))
+		if err != nil || every < time.Second {
+			return Schedule{}, fmt.Errorf("invalid schedule %q: want @every and a duration of a second at least", spec)
+		}
+		s.every = every
+		return s, nil
+	}
+	expr := strings.ToLower(spec)
+	if m, ok := macros[expr]; ok {
+		expr = m
+	}
+	parts := strings.Fields(expr)
+	if len(parts) != len(fields) {
+		return Schedule{}, fmt.Errorf("invalid schedule %q: want 5 fields, minute hour day-of-month month day-of-week, or a macro like @daily", spec)
+	}
+	for i, dst := range []*uint64{&s.minute, &s.hour, &s.dom, &s.month, &s.dow} {
+		bits, err := fields[i].parse(parts[i])
+		if err != nil {
+			return Schedule{}, fmt.Errorf("invalid schedule %q: %w", spec, err)
+		}
+		*dst = bits
+	}
+	// sunday is either 0 or 7
+	if s.dow&(1<<7) != 0 {
+		s.dow |= 1
+	}
+	s.anyDay = parts[2] == "*" || parts[4] == "*"
+	if s.Next(time.Now()).IsZero() {
+		return Schedule{}, fmt.Errorf("invalid schedule %q: it never comes", spec)
+	}
+	return s, nil
+}
+
+// parse returns the bits of the values of the field matching the expression
+func (f field) parse(expr string) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(expr, ",") {
+		rng, stepText, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			var err error
+			if step, err = strconv.Atoi(stepText); err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid step %q of the %s", stepText, f.name)
+			}
+		}
+		lo, hi := f.min, f.max
+		if rng != "*" {
+			loText, hiText, isRange := strings.Cut(rng, "-")
+			var err error
+			if lo, err = f.value(loText); err != nil {
+				return 0, err
+			}
+			hi = lo
+			if isRange {
+				if hi, err = f.value(hiText); err != nil {
+					return 0, err
+				}
+			} else if hasStep {
+				hi = f.max
+			}
+			if hi < lo {
+				return 0, fmt.Errorf("invalid range %q of the %s", rng, f.name)
+			}
+		}
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << v
+		}
+	}
+	return bits, nil
+}
+
+// value parses a value of the field, a number or a name
+func (f field) value(s string) (int, error) {
+	for i, name := range f.names {
+		if s == name {
+			return f.min + i, nil
+		}
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil || v < f.min || v > f.max {
+		return 0, fmt.Errorf("invalid %s %q, want %d to %d", f.name, s, f.min, f.max)
+	}
+	return v, nil
+}
+
+// String returns the schedule as parsed
+func (s Schedule) String() string {
+	return s.spec
+}
+
+// Next returns the first time of the schedule after t, or the zero time if none comes in the
+// next five years, e.g. for "0 0 30 2 *"
+func (s Schedule) Next(t time.Time) time.Time {
+	if s.every > 0 {
+		return t.Add(s.every)
+	}
+	limit := t.Add(maxSearch)
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	for t.Before(limit) {
+		switch {
+		case s.month&(1<<int(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+		case !s.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+		case s.hour&(1<<t.Hour()) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+		case s.minute&(1<<t.Minute()) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// dayMatches tells whether the day of t matches the day of month and the day of week, or either
+// when both are restricted, like cron does
+func (s Schedule) dayMatches(t time.Time) bool {
+	dom, dow := s.dom&(1<<t.Day()) != 0, s.dow&(1<<int(t.Weekday())) != 0
+	if s.anyDay {
+		return dom && dow
+	}
+	return dom || dow
+}
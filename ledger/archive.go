@@ -0,0 +1,140 @@
+package ledger
+
+import (
+	"fmt"
+	"log/slog"
+	"sort"
+	"time"
+
+	"github.com/gotestbootcamp/go-todo-app/store"
+)
+
+// ErrExists is returned when a todo would take the ID of another
+type ErrExists struct {
+	ID store.ID
+}
+
+func (e ErrExists) Error() string {
+	return fmt.Sprintf("object %v already exists", e.ID)
+}
+
+// Archive moves the completed todos not updated for the given time to the archive, at once,
+// and returns them. The archived todos are stored apart, see store.ArchiveID, and are excluded
+// from the queries of the ledger, but the ones of the view returned by WithArchived.
+// Archiving is not a mutation of the todos: it's neither recorded in their history nor undone.
+func (ld *Ledger) Archive(olderThan time.Duration) (archived Items, rerr error) {
+	if err := ld.checkAdmin("archive the todos"); err != nil {
+		return nil, err
+	}
+	ld.lock.Lock()
+	defer ld.lock.Unlock()
+	cutoff := ld.now().Add(-olderThan)
+	var items Items
+	for id, blob := range ld.blobs {
+		item, err := newItem(id, blob)
+		if err != nil {
+			return nil, err
+		}
+		if !ld.completed(*item.Task) || !item.Task.Updated.Before(cutoff) {
+			continue
+		}
+		if _, ok := ld.archive[id]; ok {
+			slog.Warn("ledger: Archive: object archived already, skipped", "id", id)
+			continue
+		}
+		item.Archived = true
+		items = append(items, item)
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].ID < items[j].ID
+	})
+
+	tx, err := ld.begin()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if rerr != nil {
+			tx.Rollback()
+		}
+	}()
+	for _, item := range items {
+		if err := tx.Delete(item.ID); err != nil {
+			return nil, err
+		}
+		if err := tx.Create(store.ArchiveID(item.ID), ld.blobs[item.ID]); err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	for _, item := range items {
+		ld.archive[item.ID] = ld.blobs[item.ID]
+		delete(ld.blobs, item.ID)
+	}
+	slog.Info("ledger: Archive: archived objects", "count", len(items), "before", cutoff)
+	return items, nil
+}
+
+// Unarchive moves the archived todo with the given ID back among the others, and returns it.
+// Fails with store.ErrNotFound if the todo is not archived, and with ErrExists if another todo took its ID.
+func (ld *Ledger) Unarchive(id store.ID) (item Item, rerr error) {
+	ld.lock.Lock()
+	defer ld.lock.Unlock()
+	blob, ok := ld.archive[id]
+	if !ok {
+		return Item{}, store.ErrNotFound{ID: id}
+	}
+	if _, ok := ld.blobs[id]; ok {
+		return Item{}, ErrExists{ID: id}
+	}
+	if err := ld.checkOwner(id, blob); err != nil {
+		return Item{}, err
+	}
+
+	tx, err := ld.begin()
+	if err != nil {
+		return Item{}, err
+	}
+	defer func() {
+		if rerr != nil {
+			tx.Rollback()
+		}
+	}()
+	if err := tx.Delete(store.ArchiveID(id)); err != nil {
+		return Item{}, err
+	}
+	if err := tx.Create(id, blob); err != nil {
+		return Item{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return Item{}, err
+	}
+	ld.blobs[id] = blob
+	delete(ld.archive, id)
+	slog.Info("ledger: Unarchive: object unarchived", "id", id)
+	return newItem(id, blob)
+}
+
+// ListArchived returns the archived todos, sorted by ID
+func (ld *Ledger) ListArchived() (Items, error) {
+	ld.lock.RLock()
+	defer ld.lock.RUnlock()
+	items := make(Items, 0, len(ld.archive))
+	for id, blob := range ld.archive {
+		if !ld.readable(id) {
+			continue
+		}
+		item, err := newItem(id, blob)
+		if err != nil {
+			return nil, err
+		}
+		item.Archived = true
+		items = append(items, item)
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].ID < items[j].ID
+	})
+	return items, nil
+}
@@ -0,0 +1,79 @@
+package ledger
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gotestbootcamp/go-todo-app/index"
+	"github.com/gotestbootcamp/go-todo-app/model"
+	"github.com/gotestbootcamp/go-todo-app/store"
+	"github.com/gotestbootcamp/go-todo-app/task"
+)
+
+func TestArchive(t *testing.T) {
+	fi := index.NewFieldIndexed(newTestMemory(t))
+	require.NoError(t, fi.Register("title", func(blob store.Blob) ([]string, error) {
+		tk, err := task.Unmarshal(blob)
+		return []string{tk.Title}, err
+	}))
+	ld, err := New(fi)
+	require.NoError(t, err)
+	for _, id := range []store.ID{"1", "2", "3"} {
+		require.NoError(t, ld.Set(id, model.New("todo "+string(id))))
+		_, err = ld.Transition(id, task.Assigned)
+		require.NoError(t, err)
+	}
+	_, err = ld.Transition("1", task.Completed)
+	require.NoError(t, err)
+	_, err = ld.Transition("2", task.Deleted)
+	require.NoError(t, err)
+
+	archived, err := ld.Archive(30 * 24 * time.Hour)
+	require.NoError(t, err)
+	assert.Empty(t, archived)
+	now := time.Now().Add(31 * 24 * time.Hour)
+	ld.now = func() time.Time { return now }
+	archived, err = ld.Archive(30 * 24 * time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, []store.ID{"1"}, idsOf(archived))
+
+	all := func(todo model.Todo) bool { return true }
+	items, err := ld.Filter(all)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []store.ID{"2", "3"}, idsOf(items))
+	_, err = ld.Get("1")
+	assert.Error(t, err)
+	items, err = ld.FindBy("title", "todo 1")
+	require.NoError(t, err)
+	assert.Empty(t, items)
+
+	// the archived todos survive the restarts
+	ld, err = New(fi)
+	require.NoError(t, err)
+	items, err = ld.WithArchived().Filter(all)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []store.ID{"1", "2", "3"}, idsOf(items))
+	items, err = ld.WithArchived().FindBy("title", "todo 1")
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	assert.Equal(t, store.ID("1"), items[0].ID)
+	assert.True(t, items[0].Archived)
+	items, err = ld.ListArchived()
+	require.NoError(t, err)
+	assert.Equal(t, []store.ID{"1"}, idsOf(items))
+
+	_, err = ld.Unarchive("3")
+	assert.ErrorIs(t, err, store.ErrNotFound{ID: "3"})
+	item, err := ld.Unarchive("1")
+	require.NoError(t, err)
+	assert.False(t, item.Archived)
+	todo, err := ld.Get("1")
+	require.NoError(t, err)
+	assert.EqualValues(t, task.Completed, todo.Status)
+	items, err = ld.ListArchived()
+	require.NoError(t, err)
+	assert.Empty(t, items)
+}
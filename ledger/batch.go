@@ -0,0 +1,210 @@
+package ledger
+
+import (
+	"fmt"
+	"log/slog"
+	"maps"
+
+	"github.com/gotestbootcamp/go-todo-app/store"
+	"github.com/gotestbootcamp/go-todo-app/task"
+)
+
+// MaxBatch bounds the operations of a batch, see Batch
+const MaxBatch = 500
+
+// ErrBatchTooLarge is returned when a batch has more than MaxBatch operations
+var ErrBatchTooLarge = fmt.Errorf("ledger: batches have at most %d operations", MaxBatch)
+
+// BatchOpKind identifies what an operation of a batch does
+type BatchOpKind string
+
+const (
+	BatchCreate BatchOpKind = "create"
+	BatchUpdate BatchOpKind = "update"
+	BatchDelete BatchOpKind = "delete"
+)
+
+// BatchOp is an operation of a batch, see Batch
+type BatchOp struct {
+	Kind BatchOpKind
+	// ID is the todo to update or delete
+	ID store.ID
+	// List is the list to create the todo in, store.DefaultList for none, and Task the todo
+	List string
+	Task task.Task
+	// Patch are the changes to the todo to update
+	Patch Patch
+	// Expected is the revision the todo to update or delete must have, see SetIf.
+	// AnyRevision skips the check.
+	Expected int
+}
+
+// Batch applies the operations creating, updating and deleting todos, in their order, and writes
+// them all at once, in a single transaction when the datastore supports them. Returns the report
+// of each operation, in their order: the operations which fail, e.g. because the todo to update
+// doesn't exist or the view may not change it, are skipped, and the others applied. The todos
+// created have a new ID, told by the report, and the ones completed create their next occurrences
+// and notify the todos they unblock, like Set. Fails with ErrBatchTooLarge if there are more than
+// MaxBatch operations.
+func (ld *Ledger) Batch(ops []BatchOp) (BulkReport, error) {
+	if len(ops) > MaxBatch {
+		return nil, ErrBatchTooLarge
+	}
+	report, unblocked, err := ld.batch(ops)
+	if err != nil {
+		return nil, err
+	}
+	ld.notifyUnblocked(unblocked)
+	return report, nil
+}
+
+func (ld *Ledger) batch(ops []BatchOp) (rep BulkReport, unblocked Items, rerr error) {
+	ld.lock.Lock()
+	defer ld.unlock()
+	tx, err := ld.begin()
+	if err != nil {
+		return nil, nil, err
+	}
+	// the blobs the batch changed, as they were before
+	prevBlobs := make(map[store.ID]store.Blob)
+	history, steps, changes := maps.Clone(ld.history), len(ld.steps), len(ld.changes)
+	defer func() {
+		if rerr != nil {
+			tx.Rollback()
+			for id, blob := range prevBlobs {
+				if blob == nil {
+					delete(ld.blobs, id)
+				} else {
+					ld.blobs[id] = blob
+				}
+			}
+			ld.history, ld.steps, ld.changes = history, ld.steps[:steps], ld.changes[:changes]
+		}
+	}()
+
+	report := make(BulkReport, 0, len(ops))
+	var updated []store.ID
+	for _, op := range ops {
+		res := BulkResult{ID: op.ID}
+		var blob store.Blob
+		var err error
+		switch op.Kind {
+		case BatchCreate:
+			res.ID, blob, err = ld.batchCreate(op)
+		case BatchUpdate:
+			blob, err = ld.batchUpdate(op)
+		case BatchDelete:
+			err = ld.batchDelete(op)
+		default:
+			err = fmt.Errorf("unknown operation %q", op.Kind)
+		}
+		if err != nil {
+			res.Err = err
+			report = append(report, res)
+			continue
+		}
+		prev, found := ld.blobs[res.ID]
+		if _, ok := prevBlobs[res.ID]; !ok {
+			prevBlobs[res.ID] = prev
+		}
+		switch {
+		case op.Kind == BatchDelete:
+			err = tx.Delete(res.ID)
+			delete(ld.blobs, res.ID)
+		case found:
+			err = tx.Save(res.ID, blob)
+			ld.blobs[res.ID] = blob
+			updated = append(updated, res.ID)
+		default:
+			err = tx.Create(res.ID, blob)
+			ld.blobs[res.ID] = blob
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := ld.record(tx, res.ID, prev, blob); err != nil {
+			return nil, nil, err
+		}
+		report = append(report, res)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, nil, err
+	}
+
+	for _, id := range updated {
+		if _, ok := ld.blobs[id]; !ok {
+			// deleted afterwards
+			continue
+		}
+		if err := ld.recur(ld.storage(), id, prevBlobs[id]); err != nil {
+			return report, unblocked, err
+		}
+		items, err := ld.unblockedBy(id, prevBlobs[id])
+		if err != nil {
+			return report, unblocked, err
+		}
+		unblocked = append(unblocked, items...)
+	}
+	slog.Info("ledger: Batch: applied operations", "count", len(ops))
+	return report, unblocked, nil
+}
+
+// batchCreate returns the ID and the blob of the todo to create. The caller must hold the lock.
+func (ld *Ledger) batchCreate(op BatchOp) (store.ID, store.Blob, error) {
+	if op.List != store.DefaultList {
+		if err := store.ValidateList(op.List); err != nil {
+			return store.NullID, nil, err
+		}
+	}
+	item, err := ld.newTask(op.List, op.Task)
+	if err != nil {
+		return store.NullID, nil, err
+	}
+	return item.ID, item.Blob, nil
+}
+
+// batchUpdate returns the blob of the todo patched. The caller must hold the lock.
+func (ld *Ledger) batchUpdate(op BatchOp) (store.Blob, error) {
+	prev, err := ld.batchTarget(op)
+	if err != nil {
+		return nil, err
+	}
+	cur, err := newItem(op.ID, prev)
+	if err != nil {
+		return nil, err
+	}
+	base, err := patchTask(*cur.Task, op.Patch)
+	if err != nil {
+		return nil, err
+	}
+	todo, err := patchTodo(*cur.Todo, op.Patch)
+	if err != nil {
+		return nil, err
+	}
+	if err := ld.workflow.Check(cur.Task.Status, task.Status(todo.Status)); err != nil {
+		return nil, err
+	}
+	return todo.SerializeOver(base)
+}
+
+// batchDelete checks the todo can be deleted. The caller must hold the lock.
+func (ld *Ledger) batchDelete(op BatchOp) error {
+	_, err := ld.batchTarget(op)
+	return err
+}
+
+// batchTarget returns the blob of the todo to update or delete, if the view may change it and its
+// revision is the expected one. The caller must hold the lock.
+func (ld *Ledger) batchTarget(op BatchOp) (store.Blob, error) {
+	blob, ok := ld.blobs[op.ID]
+	if !ok {
+		return nil, store.ErrNotFound{ID: op.ID}
+	}
+	if err := ld.checkRevision(op.ID, op.Expected); err != nil {
+		return nil, err
+	}
+	if err := ld.checkOwner(op.ID, blob); err != nil {
+		return nil, err
+	}
+	return blob, nil
+}
@@ -0,0 +1,79 @@
+package ledger
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gotestbootcamp/go-todo-app/model"
+	"github.com/gotestbootcamp/go-todo-app/store"
+	"github.com/gotestbootcamp/go-todo-app/task"
+)
+
+func TestBatch(t *testing.T) {
+	ld := newTestLedger(t, task.DefaultWorkflow())
+	for _, id := range []store.ID{"1", "2", "3"} {
+		require.NoError(t, ld.Set(id, model.New("todo "+string(id))))
+	}
+	_, err := ld.Transition("1", task.Assigned)
+	require.NoError(t, err)
+	_, err = ld.Block("3", "1")
+	require.NoError(t, err)
+	var unblocked []store.ID
+	ld.OnUnblocked(func(item Item) {
+		unblocked = append(unblocked, item.ID)
+	})
+
+	completed, title, high := task.Completed, "renamed", task.PriorityHigh
+	report, err := ld.Batch([]BatchOp{
+		{Kind: BatchCreate, Task: task.New("new")},
+		{Kind: BatchCreate, List: "work", Task: task.New("")},
+		{Kind: BatchUpdate, ID: "1", Patch: Patch{Status: &completed}, Expected: AnyRevision},
+		{Kind: BatchUpdate, ID: "2", Patch: Patch{Title: &title, Priority: &high}, Expected: AnyRevision},
+		{Kind: BatchUpdate, ID: "3", Patch: Patch{Status: &completed}, Expected: AnyRevision},
+		{Kind: BatchUpdate, ID: "2", Patch: Patch{Title: &title}, Expected: 1},
+		{Kind: BatchDelete, ID: "9", Expected: AnyRevision},
+		{Kind: BatchDelete, ID: "2", Expected: AnyRevision},
+		{Kind: "move", ID: "1"},
+	})
+	require.NoError(t, err)
+	require.Len(t, report, 9)
+	assert.NotEqual(t, store.NullID, report[0].ID)
+	assert.NoError(t, report[0].Err)
+	assert.Equal(t, store.NullID, report[1].ID)
+	assert.Error(t, report[1].Err)
+	assert.Equal(t, BulkReport{{ID: "1"}, {ID: "2"}}, report[2:4])
+	assert.ErrorIs(t, report[4].Err, task.ErrIllegalTransition{From: task.Pending, To: task.Completed})
+	assert.ErrorIs(t, report[5].Err, store.ErrConflict{ID: "2", Expected: 1, Actual: 2})
+	assert.ErrorIs(t, report[6].Err, store.ErrNotFound{ID: "9"})
+	assert.Equal(t, BulkResult{ID: "2"}, report[7])
+	assert.ErrorContains(t, report[8].Err, `unknown operation "move"`)
+	assert.Equal(t, []store.ID{"3"}, unblocked)
+
+	todo, err := ld.Get(report[0].ID)
+	require.NoError(t, err)
+	assert.Equal(t, "new", todo.Title)
+	todo, err = ld.Get("1")
+	require.NoError(t, err)
+	assert.EqualValues(t, task.Completed, todo.Status)
+	_, err = ld.Get("2")
+	assert.ErrorIs(t, err, store.ErrNotFound{ID: "2"})
+	revs, err := ld.History("2")
+	require.NoError(t, err)
+	require.Len(t, revs, 3)
+	assert.Contains(t, revs[1].Changes, Change{Field: "title", From: json.RawMessage(`"todo 2"`), To: json.RawMessage(`"renamed"`)})
+
+	// the batch is a single operation
+	_, err = ld.Undo()
+	require.NoError(t, err)
+	todo, err = ld.Get("2")
+	require.NoError(t, err)
+	assert.Equal(t, "todo 2", todo.Title)
+	_, err = ld.Get(report[0].ID)
+	assert.ErrorIs(t, err, store.ErrNotFound{ID: report[0].ID})
+
+	_, err = ld.Batch(make([]BatchOp, MaxBatch+1))
+	assert.ErrorIs(t, err, ErrBatchTooLarge)
+}
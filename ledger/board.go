@@ -0,0 +1,320 @@
+package ledger
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math"
+	"slices"
+	"strings"
+
+	apiv1 "github.com/gotestbootcamp/go-todo-app/api/v1"
+	"github.com/gotestbootcamp/go-todo-app/store"
+	"github.com/gotestbootcamp/go-todo-app/task"
+)
+
+// boardPrefix marks the IDs of the items holding the manual order of the todos in the columns of
+// the boards, one for each column
+var boardPrefix = string(store.MetaID("board/"))
+
+// boardKey returns the key of the column of the board grouping the todos by the field
+func boardKey(field, column string) string {
+	return field + "/" + column
+}
+
+func isBoardID(id store.ID) bool {
+	return strings.HasPrefix(string(id), boardPrefix)
+}
+
+// BoardFields are the fields of the todos the boards group them by
+var BoardFields = []string{"status", "priority"}
+
+// ErrUnknownBoardField is returned when grouping the todos of a board by a field not in BoardFields
+type ErrUnknownBoardField struct {
+	Field string
+}
+
+func (e ErrUnknownBoardField) Error() string {
+	return fmt.Sprintf("unknown board field %q, expected one of %s", e.Field, strings.Join(BoardFields, ", "))
+}
+
+// Board groups the todos in columns by the value of one of their fields, kanban style
+type Board struct {
+	// Field is the field the todos are grouped by, one of BoardFields
+	Field   string
+	Columns []BoardColumn
+}
+
+// BoardColumn are the todos of a board with the same value of its field, in their manual order,
+// see MoveOnBoard
+type BoardColumn struct {
+	// Name is the value of the field, e.g. "pending", or "P1" for the priorities
+	Name  string
+	Items Items
+}
+
+// ToAPIv1 converts a Board on its API layer corresponding object
+func (b Board) ToAPIv1() apiv1.Board {
+	res := apiv1.Board{By: b.Field, Columns: make([]apiv1.BoardColumn, 0, len(b.Columns))}
+	for _, col := range b.Columns {
+		res.Columns = append(res.Columns, apiv1.BoardColumn{Name: col.Name, Items: col.Items.ToAPIv1()})
+	}
+	return res
+}
+
+func (ld *Ledger) loadBoard(id store.ID, blob store.Blob) error {
+	var ids []store.ID
+	if err := json.Unmarshal(blob, &ids); err != nil {
+		return fmt.Errorf("ledger: can't decode the order of the board column %v: %w", id, err)
+	}
+	if ld.boardOrder == nil {
+		ld.boardOrder = make(map[string][]store.ID)
+	}
+	ld.boardOrder[strings.TrimPrefix(string(id), boardPrefix)] = ids
+	return nil
+}
+
+// boardColumns returns the names of the columns of the board grouping the todos by the field: the
+// statuses of the workflow but deleted, or the priorities, the most urgent first
+func (ld *Ledger) boardColumns(field string) ([]string, error) {
+	var names []string
+	switch field {
+	case "status":
+		for _, st := range ld.workflow.Statuses() {
+			if st != task.Deleted {
+				names = append(names, string(st))
+			}
+		}
+	case "priority":
+		for pr := task.PriorityUrgent; pr >= task.PriorityNone; pr-- {
+			names = append(names, pr.String())
+		}
+	default:
+		return nil, ErrUnknownBoardField{Field: field}
+	}
+	return names, nil
+}
+
+// boardColumn returns the name of the column of the task on the board grouping the todos by the field.
+// Empty if the task is not on the board: the deleted ones, and the inactive ones for the priorities.
+func (ld *Ledger) boardColumn(field string, tk task.Task) string {
+	switch {
+	case field == "status" && tk.Status != task.Deleted:
+		return string(tk.Status)
+	case field == "priority" && ld.active(tk):
+		return tk.Priority.String()
+	}
+	return ""
+}
+
+// boardItems returns the todos in each column of the board grouping them by the field, in their
+// manual order, the ones never moved last by ID. All the todos if all is true, else the ones the
+// view sees. The caller must hold the lock.
+func (ld *Ledger) boardItems(field string, all bool) (map[string]Items, error) {
+	columns := make(map[string]Items)
+	for id, blob := range ld.blobs {
+		if !all && !ld.readable(id) {
+			continue
+		}
+		item, err := newItem(id, blob)
+		if err != nil {
+			return nil, err
+		}
+		if name := ld.boardColumn(field, *item.Task); name != "" {
+			columns[name] = append(columns[name], item)
+		}
+	}
+	for name, items := range columns {
+		pos := make(map[store.ID]int)
+		for i, id := range ld.boardOrder[boardKey(field, name)] {
+			pos[id] = i + 1
+		}
+		position := func(id store.ID) int {
+			if p, ok := pos[id]; ok {
+				return p
+			}
+			return math.MaxInt
+		}
+		slices.SortFunc(items, func(a, b Item) int {
+			if c := cmp.Compare(position(a.ID), position(b.ID)); c != 0 {
+				return c
+			}
+			return strings.Compare(string(a.ID), string(b.ID))
+		})
+	}
+	return columns, nil
+}
+
+// Board returns the todos the view sees grouped by the field, one of BoardFields, in a column for
+// each status of the workflow or for each priority, the todos of each column in their manual order.
+// The statuses unknown to the workflow get columns of their own, last. The priorities group the
+// active todos only. Fails with ErrUnknownBoardField if the field is not one of BoardFields.
+func (ld *Ledger) Board(field string) (Board, error) {
+	names, err := ld.boardColumns(field)
+	if err != nil {
+		return Board{}, err
+	}
+	ld.lock.RLock()
+	defer ld.lock.RUnlock()
+	columns, err := ld.boardItems(field, false)
+	if err != nil {
+		return Board{}, err
+	}
+	var unknown []string
+	for name := range columns {
+		if !slices.Contains(names, name) {
+			unknown = append(unknown, name)
+		}
+	}
+	slices.Sort(unknown)
+	names = append(names, unknown...)
+	board := Board{Field: field, Columns: make([]BoardColumn, 0, len(names))}
+	for _, name := range names {
+		board.Columns = append(board.Columns, BoardColumn{Name: name, Items: columns[name]})
+	}
+	return board, nil
+}
+
+// MoveOnBoard moves the todo to the position in the column of the board grouping the todos by the
+// field, 0 being the top of the column and the positions past the last todo the bottom: the todo gets
+// the status or the priority of the column, if it doesn't have it already, then stays at its position,
+// for all the users, until moved again. The todo and its column are changed at once, in a single
+// transaction. Fails with ErrUnknownBoardField if the field is not one of BoardFields, with
+// store.ErrNotFound if the todo doesn't exist, and with task.ErrIllegalTransition if the workflow
+// doesn't allow moving it to the status of the column.
+func (ld *Ledger) MoveOnBoard(field string, id store.ID, column string, position int) (Item, error) {
+	item, unblocked, err := ld.moveOnBoard(field, id, column, position)
+	if err != nil {
+		return Item{}, err
+	}
+	ld.notifyUnblocked(unblocked)
+	slog.Info("ledger: MoveOnBoard: object moved", "id", id, "field", field, "column", column, "position", position)
+	return item, nil
+}
+
+func (ld *Ledger) moveOnBoard(field string, id store.ID, column string, position int) (Item, Items, error) {
+	names, err := ld.boardColumns(field)
+	if err != nil {
+		return Item{}, nil, err
+	}
+	if position < 0 {
+		return Item{}, nil, fmt.Errorf("invalid position %d in column %q", position, column)
+	}
+	var edit bulkEdit
+	switch field {
+	case "status":
+		if !slices.Contains(names, column) {
+			return Item{}, nil, fmt.Errorf("unknown column %q of the %s board", column, field)
+		}
+		edit = func(id store.ID, tk task.Task) (store.ID, task.Task, error) {
+			if err := ld.workflow.Check(tk.Status, task.Status(column)); err != nil {
+				return id, tk, err
+			}
+			tk.Status = task.Status(column)
+			return id, tk, nil
+		}
+	case "priority":
+		pr, err := task.ParsePriority(column)
+		if err != nil {
+			return Item{}, nil, err
+		}
+		column = pr.String()
+		edit = func(id store.ID, tk task.Task) (store.ID, task.Task, error) {
+			tk.Priority = pr
+			return id, tk, nil
+		}
+	}
+
+	ld.lock.Lock()
+	defer ld.unlock()
+	prevBlob, ok := ld.blobs[id]
+	if !ok {
+		return Item{}, nil, store.ErrNotFound{ID: id}
+	}
+	if err := ld.checkRead(id); err != nil {
+		return Item{}, nil, err
+	}
+	if err := ld.checkOwner(id, prevBlob); err != nil {
+		return Item{}, nil, err
+	}
+	tk, err := task.Unmarshal(prevBlob)
+	if err != nil {
+		return Item{}, nil, err
+	}
+	var order []store.ID
+	var unblocked Items
+	if ld.boardColumn(field, tk) == column {
+		order, err = ld.placeOnBoard(ld.storage(), field, id, column, position)
+	} else {
+		// the todo changes column, and is placed in it, in the same transaction
+		_, err = ld.bulkThen([]store.ID{id}, edit, func(tx store.Tx, report BulkReport) error {
+			if report[0].Err != nil {
+				return report[0].Err
+			}
+			if err := ld.recur(tx, id, prevBlob); err != nil {
+				return err
+			}
+			var err error
+			if unblocked, err = ld.unblockedBy(id, prevBlob); err != nil {
+				return err
+			}
+			order, err = ld.placeOnBoard(tx, field, id, column, position)
+			return err
+		})
+	}
+	if err != nil {
+		return Item{}, nil, err
+	}
+	if ld.boardOrder == nil {
+		ld.boardOrder = make(map[string][]store.ID)
+	}
+	ld.boardOrder[boardKey(field, column)] = order
+	item, err := newItem(id, ld.blobs[id])
+	return item, unblocked, err
+}
+
+// placeOnBoard stores with w the order of the column of the board with the todo, in the column
+// already, at the position, and returns it. The order of the other columns is left as it is.
+// The caller must hold the lock.
+func (ld *Ledger) placeOnBoard(w saver, field string, id store.ID, column string, position int) ([]store.ID, error) {
+	columns, err := ld.boardItems(field, true)
+	if err != nil {
+		return nil, err
+	}
+	// the position counts the todos the view sees, the others keep their place around them
+	var ids []store.ID
+	at := -1
+	seen := 0
+	for _, other := range columns[column] {
+		if other.ID == id {
+			continue
+		}
+		if ld.readable(other.ID) {
+			if seen == position {
+				at = len(ids)
+			}
+			seen++
+		}
+		ids = append(ids, other.ID)
+	}
+	if at < 0 {
+		at = len(ids)
+	}
+	ids = slices.Insert(ids, at, id)
+	blob, err := json.Marshal(ids)
+	if err != nil {
+		return nil, err
+	}
+	key := boardKey(field, column)
+	colID := store.ID(boardPrefix + key)
+	if _, stored := ld.boardOrder[key]; stored {
+		err = w.Save(colID, blob)
+	} else {
+		err = w.Create(colID, blob)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
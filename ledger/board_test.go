@@ -0,0 +1,143 @@
+package ledger
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gotestbootcamp/go-todo-app/model"
+	"github.com/gotestbootcamp/go-todo-app/store"
+	"github.com/gotestbootcamp/go-todo-app/task"
+)
+
+// boardIDs returns the IDs of the todos in each column of the board
+func boardIDs(t *testing.T, ld *Ledger, field string) map[string][]store.ID {
+	t.Helper()
+	board, err := ld.Board(field)
+	require.NoError(t, err)
+	require.Equal(t, field, board.Field)
+	res := make(map[string][]store.ID)
+	for _, col := range board.Columns {
+		res[col.Name] = nil
+		for _, item := range col.Items {
+			res[col.Name] = append(res[col.Name], item.ID)
+		}
+	}
+	return res
+}
+
+func TestBoard(t *testing.T) {
+	st := newTestMemory(t)
+	ld, err := NewWithWorkflow(st, task.DefaultWorkflow())
+	require.NoError(t, err)
+	for _, id := range []store.ID{"1", "2", "3", "4"} {
+		require.NoError(t, ld.Set(id, model.New("todo "+string(id))))
+	}
+	_, err = ld.Transition("4", task.Deleted)
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string][]store.ID{
+		"pending":   {"1", "2", "3"},
+		"assigned":  nil,
+		"completed": nil,
+	}, boardIDs(t, ld, "status"))
+	_, err = ld.Board("title")
+	assert.ErrorIs(t, err, ErrUnknownBoardField{Field: "title"})
+
+	// moving within a column keeps the status
+	_, err = ld.MoveOnBoard("status", "3", "pending", 0)
+	require.NoError(t, err)
+	assert.Equal(t, []store.ID{"3", "1", "2"}, boardIDs(t, ld, "status")["pending"])
+	// moving to another column changes it
+	item, err := ld.MoveOnBoard("status", "1", "assigned", 5)
+	require.NoError(t, err)
+	assert.Equal(t, "assigned", string(item.Todo.Status))
+	_, err = ld.MoveOnBoard("status", "2", "assigned", 0)
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]store.ID{
+		"pending":   {"3"},
+		"assigned":  {"2", "1"},
+		"completed": nil,
+	}, boardIDs(t, ld, "status"))
+	_, err = ld.MoveOnBoard("status", "3", "completed", 0)
+	var illegal task.ErrIllegalTransition
+	assert.ErrorAs(t, err, &illegal)
+	_, err = ld.MoveOnBoard("status", "3", "deleted", 0)
+	assert.Error(t, err)
+	_, err = ld.MoveOnBoard("status", "9", "pending", 0)
+	assert.ErrorIs(t, err, store.ErrNotFound{ID: "9"})
+
+	// the priorities group the active todos, the most urgent first
+	item, err = ld.MoveOnBoard("priority", "3", "p1", 0)
+	require.NoError(t, err)
+	assert.Equal(t, task.PriorityHigh, item.Task.Priority)
+	assert.Equal(t, map[string][]store.ID{
+		"P0":   nil,
+		"P1":   {"3"},
+		"P2":   nil,
+		"P3":   nil,
+		"none": {"1", "2"},
+	}, boardIDs(t, ld, "priority"))
+	board, err := ld.Board("priority")
+	require.NoError(t, err)
+	assert.Equal(t, "P0", board.Columns[0].Name)
+
+	// the order is stored, by column
+	blob, err := st.Load(store.ID(boardPrefix + "status/assigned"))
+	require.NoError(t, err)
+	assert.JSONEq(t, `["2", "1"]`, string(blob))
+	_, err = st.Load(store.ID(boardPrefix + "status/completed"))
+	assert.ErrorIs(t, err, store.ErrNotFound{ID: store.ID(boardPrefix + "status/completed")})
+	ld, err = NewWithWorkflow(st, task.DefaultWorkflow())
+	require.NoError(t, err)
+	assert.Equal(t, []store.ID{"2", "1"}, boardIDs(t, ld, "status")["assigned"])
+}
+
+func TestBoardUsers(t *testing.T) {
+	ld := newTestLedger(t, task.DefaultWorkflow())
+	alice, bob := ld.AsUser("alice", false), ld.AsUser("bob", false)
+	require.NoError(t, alice.Set("1", model.New("report")))
+	require.NoError(t, bob.Set("2", model.New("laundry")))
+	require.NoError(t, alice.Set("3", model.New("slides")))
+
+	assert.Equal(t, []store.ID{"1", "2", "3"}, boardIDs(t, ld, "status")["pending"])
+	// the users move their own todos only
+	_, err := alice.MoveOnBoard("status", "2", "pending", 0)
+	assert.ErrorIs(t, err, ErrForbidden)
+	_, err = bob.MoveOnBoard("status", "2", "pending", 1)
+	require.NoError(t, err)
+	assert.Equal(t, []store.ID{"1", "2", "3"}, boardIDs(t, ld, "status")["pending"])
+	_, err = alice.MoveOnBoard("status", "3", "pending", 0)
+	require.NoError(t, err)
+	assert.Equal(t, []store.ID{"3", "1", "2"}, boardIDs(t, ld, "status")["pending"])
+}
+
+// noBoards fails to store the order of the columns of the boards
+type noBoards struct {
+	store.Storage
+}
+
+func (st noBoards) Create(id store.ID, blob store.Blob) error {
+	if isBoardID(id) {
+		return errors.New("disk full")
+	}
+	return st.Storage.Create(id, blob)
+}
+
+func TestMoveOnBoardFailure(t *testing.T) {
+	ld, err := NewWithWorkflow(noBoards{newTestMemory(t)}, task.DefaultWorkflow())
+	require.NoError(t, err)
+	require.NoError(t, ld.Set("1", model.New("report")))
+
+	// the todo doesn't change column if it can't be placed in it
+	_, err = ld.MoveOnBoard("status", "1", "assigned", 0)
+	require.Error(t, err)
+	item, _, err := ld.GetItem("1")
+	require.NoError(t, err)
+	assert.Equal(t, task.Pending, item.Task.Status)
+	history, err := ld.History("1")
+	require.NoError(t, err)
+	assert.Len(t, history, 1)
+}
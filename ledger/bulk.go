@@ -0,0 +1,667 @@
+package ledger
+
+import (
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+
+	apiv1 "github.com/gotestbootcamp/go-todo-app/api/v1"
+	"github.com/gotestbootcamp/go-todo-app/model"
+	"github.com/gotestbootcamp/go-todo-app/store"
+	"github.com/gotestbootcamp/go-todo-app/task"
+)
+
+// BulkResult reports the outcome of a bulk operation on a todo
+type BulkResult struct {
+	ID store.ID
+	// NewID is the ID of the todo after the operation, if it changed, e.g. moving it to another list
+	NewID store.ID
+	// Err tells why the todo was skipped. Nil if it was updated.
+	Err error
+}
+
+// ToAPIv1 converts the BulkResult in its API v1 representation
+func (br BulkResult) ToAPIv1() apiv1.BulkResult {
+	res := apiv1.BulkResult{
+		ID:    apiv1.ID(br.ID),
+		NewID: apiv1.ID(br.NewID),
+	}
+	if br.Err != nil {
+		res.Error = br.Err.Error()
+	}
+	return res
+}
+
+// BulkReport reports the outcome of a bulk operation on each todo, sorted by ID
+type BulkReport []BulkResult
+
+// ToAPIv1 converts the BulkReport in its API v1 representation
+func (rep BulkReport) ToAPIv1() []apiv1.BulkResult {
+	res := make([]apiv1.BulkResult, 0, len(rep))
+	for _, br := range rep {
+		res = append(res, br.ToAPIv1())
+	}
+	return res
+}
+
+// bulkEdit changes the task of the todo with the given ID, and returns the ID the todo moves to,
+// or the same ID. The todo is skipped if the edit fails.
+type bulkEdit func(id store.ID, tk task.Task) (store.ID, task.Task, error)
+
+// CompleteAll moves the active todos matching the filter to the status the workflow completes them
+// with, see task.Workflow.Done, and returns the report of each of them. The todos the workflow
+// doesn't allow to complete are skipped, and all the others are completed at once. Completing the
+// todos creates their next occurrences, in the same transaction, and notifies the todos they
+// unblock, like Set.
+func (ld *Ledger) CompleteAll(wants Wants) (BulkReport, error) {
+	report, unblocked, err := ld.completeAll(wants)
+	if err != nil {
+		return nil, err
+	}
+	ld.notifyUnblocked(unblocked)
+	return report, nil
+}
+
+func (ld *Ledger) completeAll(wants Wants) (BulkReport, Items, error) {
+	ld.lock.Lock()
+	defer ld.unlock()
+	items, err := ld.filter(wants)
+	if err != nil {
+		return nil, nil, err
+	}
+	var ids []store.ID
+	for _, item := range items {
+		if ld.active(*item.Task) {
+			ids = append(ids, item.ID)
+		}
+	}
+	return ld.complete(ids, "")
+}
+
+// CompleteByID is like CompleteAll, but completes the todos with the given IDs. The active todos
+// nobody is assigned to are assigned to the assignee first, unless empty, for the workflows which
+// only complete the assigned todos. The missing todos are skipped, and so are the ones in a final
+// status already, with model.ErrFinalized.
+func (ld *Ledger) CompleteByID(ids []store.ID, assignee string) (BulkReport, error) {
+	report, unblocked, err := ld.completeByID(ids, assignee)
+	if err != nil {
+		return nil, err
+	}
+	ld.notifyUnblocked(unblocked)
+	return report, nil
+}
+
+func (ld *Ledger) completeByID(ids []store.ID, assignee string) (BulkReport, Items, error) {
+	ld.lock.Lock()
+	defer ld.unlock()
+	return ld.complete(ids, assignee)
+}
+
+// complete completes the todos with the given IDs, assigning the unassigned ones to the assignee
+// unless empty, and returns the report of each along with the todos they unblocked.
+// The caller must hold the lock.
+func (ld *Ledger) complete(ids []store.ID, assignee string) (BulkReport, Items, error) {
+	prevBlobs := make(map[store.ID]store.Blob, len(ids))
+	for _, id := range ids {
+		prevBlobs[id] = ld.blobs[id]
+	}
+	edit := func(id store.ID, tk task.Task) (store.ID, task.Task, error) {
+		if !ld.active(tk) {
+			return id, tk, model.ErrFinalized
+		}
+		if assignee != "" && tk.Assignee == "" {
+			tk.Assignee = assignee
+			if ld.workflow.Check(tk.Status, task.Assigned) == nil {
+				tk.Status = task.Assigned
+			}
+		}
+		done := ld.workflow.Done(tk.Status)
+		if err := ld.workflow.Check(tk.Status, done); err != nil {
+			return id, tk, err
+		}
+		tk.Status = done
+		return id, tk, nil
+	}
+	var unblocked Items
+	// the next occurrences are created in the same transaction
+	report, err := ld.bulkThen(ids, edit, func(tx store.Tx, report BulkReport) error {
+		for _, res := range report {
+			if res.Err != nil {
+				continue
+			}
+			if err := ld.recur(tx, res.ID, prevBlobs[res.ID]); err != nil {
+				return err
+			}
+			items, err := ld.unblockedBy(res.ID, prevBlobs[res.ID])
+			if err != nil {
+				return err
+			}
+			unblocked = append(unblocked, items...)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	slog.Info("ledger: CompleteAll: completed objects", "count", len(ids))
+	return report, unblocked, nil
+}
+
+// RetagAll attaches the tags to add to the todos with the given IDs and detaches the ones to
+// remove, at once, and returns the report of each todo. The missing todos are skipped.
+func (ld *Ledger) RetagAll(ids []store.ID, add, remove []string) (BulkReport, error) {
+	for _, tag := range add {
+		if err := task.ValidateTag(tag); err != nil {
+			return nil, err
+		}
+	}
+
+	ld.lock.Lock()
+	defer ld.unlock()
+	slog.Info("ledger: RetagAll: retagging objects", "count", len(ids), "add", add, "remove", remove)
+	return ld.bulk(ids, func(id store.ID, tk task.Task) (store.ID, task.Task, error) {
+		tk.Tags = slices.DeleteFunc(tk.Tags, func(tag string) bool {
+			return slices.Contains(remove, tag)
+		})
+		for _, tag := range add {
+			if !slices.Contains(tk.Tags, tag) {
+				tk.Tags = append(tk.Tags, tag)
+			}
+		}
+		return id, tk, nil
+	})
+}
+
+// MoveAll moves the todos matching the filter to the given list, at once, and returns the report
+// of each of them. The moved todos get the IDs qualified by the list, see store.ListID; the todos
+// whose new ID is taken already are skipped, and so are the ones the view may not move in a list
+// with members. store.DefaultList moves the todos out of any list.
+// The todos blocked by the moved ones are updated as well.
+func (ld *Ledger) MoveAll(wants Wants, list string) (BulkReport, error) {
+	if list != store.DefaultList {
+		if err := store.ValidateList(list); err != nil {
+			return nil, err
+		}
+	}
+
+	ld.lock.Lock()
+	defer ld.unlock()
+	items, err := ld.filter(wants)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]store.ID, 0, len(items))
+	for _, item := range items {
+		ids = append(ids, item.ID)
+	}
+	taken := make(map[store.ID]bool)
+	slog.Info("ledger: MoveAll: moving objects", "count", len(ids), "list", list)
+	return ld.bulk(ids, func(id store.ID, tk task.Task) (store.ID, task.Task, error) {
+		_, local := store.SplitListID(id)
+		newID := store.ListID(list, local)
+		if newID == id {
+			return id, tk, nil
+		}
+		if _, ok := ld.blobs[newID]; ok || taken[newID] {
+			return id, tk, ErrExists{ID: newID}
+		}
+		if err := ld.checkOwner(newID, nil); err != nil {
+			return id, tk, err
+		}
+		taken[newID] = true
+		return newID, tk, nil
+	})
+}
+
+// CreateAll creates todos with the given tasks in the list, with new IDs, at once, and returns
+// the report of each task, in their order. The tasks which aren't valid, or whose status the
+// workflow doesn't start with, are skipped, with a null ID. store.DefaultList creates the todos
+// out of any list.
+func (ld *Ledger) CreateAll(list string, tasks []task.Task) (BulkReport, error) {
+	if list != store.DefaultList {
+		if err := store.ValidateList(list); err != nil {
+			return nil, err
+		}
+	}
+
+	ld.lock.Lock()
+	defer ld.unlock()
+	report := make(BulkReport, 0, len(tasks))
+	var items []store.Item
+	for _, tk := range tasks {
+		res := BulkResult{ID: store.NullID}
+		item, err := ld.newTask(list, tk)
+		if err != nil {
+			res.Err = err
+		} else {
+			res.ID = item.ID
+			items = append(items, item)
+		}
+		report = append(report, res)
+	}
+	if len(items) == 0 {
+		return report, nil
+	}
+	if err := store.CreateMany(ld.storer, items); err != nil {
+		return nil, err
+	}
+	for _, item := range items {
+		ld.blobs[item.ID] = item.Blob
+		if err := ld.record(ld.storer, item.ID, nil, item.Blob); err != nil {
+			return nil, err
+		}
+	}
+	slog.Info("ledger: CreateAll: created objects", "count", len(items), "list", list)
+	return report, nil
+}
+
+// newTask returns the item of the task to create in the list, with a new ID. The task keeps
+// when it was last updated, if set, e.g. when it was completed. The caller must hold the lock.
+func (ld *Ledger) newTask(list string, tk task.Task) (store.Item, error) {
+	if err := ld.checkNew(tk); err != nil {
+		return store.Item{}, err
+	}
+	id, err := ld.newTaskID(list)
+	if err != nil {
+		return store.Item{}, err
+	}
+	blob, err := ld.newTaskBlob(tk)
+	return store.Item{ID: id, Blob: blob}, err
+}
+
+// checkNew checks the task is valid, and in a status the workflow starts with
+func (ld *Ledger) checkNew(tk task.Task) error {
+	if err := tk.Validate(); err != nil {
+		return err
+	}
+	return ld.workflow.Check("", tk.Status)
+}
+
+// newTaskID returns a new ID for a todo of the list, which the view may create.
+// The caller must hold the lock.
+func (ld *Ledger) newTaskID(list string) (store.ID, error) {
+	id, err := ld.ids.NewID()
+	if err != nil {
+		return store.NullID, err
+	}
+	id = store.ListID(list, id)
+	if _, ok := ld.blobs[id]; ok {
+		return store.NullID, ErrExists{ID: id}
+	}
+	if err := ld.checkOwner(id, nil); err != nil {
+		return store.NullID, err
+	}
+	return id, nil
+}
+
+// newTaskBlob returns the blob of the task to create, owned by the user of the view
+func (ld *Ledger) newTaskBlob(tk task.Task) (store.Blob, error) {
+	if tk.Updated.IsZero() {
+		tk.Updated = ld.now()
+	}
+	blob, err := task.Marshal(tk)
+	if err != nil {
+		return nil, err
+	}
+	return ld.own(blob)
+}
+
+// ImportAll stores the tasks of the items with their IDs, at once, creating the todos missing and
+// replacing the others, and returns the report of each item, in their order. The items which aren't
+// valid, whose status the workflow doesn't allow after the current one, which the view may not
+// change, or whose ID is repeated, are skipped. The replaced todos keep their owner.
+func (ld *Ledger) ImportAll(items Items) (rep BulkReport, rerr error) {
+	ld.lock.Lock()
+	defer ld.unlock()
+	tx, err := ld.begin()
+	if err != nil {
+		return nil, err
+	}
+	history, steps, changes := maps.Clone(ld.history), len(ld.steps), len(ld.changes)
+	defer func() {
+		if rerr != nil {
+			tx.Rollback()
+			ld.history, ld.steps, ld.changes = history, ld.steps[:steps], ld.changes[:changes]
+		}
+	}()
+
+	report := make(BulkReport, 0, len(items))
+	updated := make(map[store.ID]store.Blob, len(items))
+	for _, item := range items {
+		res := BulkResult{ID: item.ID}
+		blob, err := ld.importedTask(item.ID, *item.Task)
+		if _, ok := updated[item.ID]; ok && err == nil {
+			err = fmt.Errorf("duplicated id %s", item.ID)
+		}
+		if err != nil {
+			res.Err = err
+			report = append(report, res)
+			continue
+		}
+		prev, found := ld.blobs[item.ID]
+		if found {
+			err = tx.Save(item.ID, blob)
+		} else {
+			err = tx.Create(item.ID, blob)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if err := ld.record(tx, item.ID, prev, blob); err != nil {
+			return nil, err
+		}
+		updated[item.ID] = blob
+		report = append(report, res)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	maps.Copy(ld.blobs, updated)
+	slog.Info("ledger: ImportAll: imported objects", "count", len(updated))
+	return report, nil
+}
+
+// ImportAllNew creates todos with the tasks of the items in the list, with new IDs, at once, like
+// CreateAll, and returns the report of each item, in their order. The tasks blocked by, or linked
+// to, the others of the items are blocked by, or linked to, their new IDs, and the other blockers
+// and links to todos dropped, as the occurrences of the series: the IDs of the items are the ones
+// the tasks refer to each other with. The todos are written in a single transaction when the
+// datastore supports them.
+func (ld *Ledger) ImportAllNew(list string, items Items) (rep BulkReport, rerr error) {
+	if list != store.DefaultList {
+		if err := store.ValidateList(list); err != nil {
+			return nil, err
+		}
+	}
+
+	ld.lock.Lock()
+	defer ld.unlock()
+	tx, err := ld.begin()
+	if err != nil {
+		return nil, err
+	}
+	history, steps, changes := maps.Clone(ld.history), len(ld.steps), len(ld.changes)
+	defer func() {
+		if rerr != nil {
+			tx.Rollback()
+			ld.history, ld.steps, ld.changes = history, ld.steps[:steps], ld.changes[:changes]
+		}
+	}()
+
+	// the new IDs first, for the tasks to refer to each other with them
+	report := make(BulkReport, len(items))
+	newIDs := make(map[string]store.ID, len(items))
+	for i, item := range items {
+		report[i] = BulkResult{ID: store.NullID}
+		if report[i].Err = ld.checkNew(*item.Task); report[i].Err != nil {
+			continue
+		}
+		if report[i].ID, report[i].Err = ld.newTaskID(list); report[i].Err != nil {
+			continue
+		}
+		if item.ID != store.NullID {
+			newIDs[string(item.ID)] = report[i].ID
+		}
+	}
+	created := make(map[store.ID]store.Blob, len(items))
+	for i, item := range items {
+		if report[i].Err != nil {
+			continue
+		}
+		id := report[i].ID
+		tk := *item.Task
+		tk.Series, tk.BlockedBy, tk.Links = "", nil, nil
+		for _, blocker := range item.Task.BlockedBy {
+			if newID, ok := newIDs[blocker]; ok && newID != id {
+				tk.BlockedBy = append(tk.BlockedBy, string(newID))
+			}
+		}
+		for _, l := range item.Task.Links {
+			if l.ID != "" {
+				newID, ok := newIDs[l.ID]
+				if !ok || newID == id {
+					continue
+				}
+				l.ID = string(newID)
+			}
+			tk.Links = append(tk.Links, l)
+		}
+		blob, err := ld.newTaskBlob(tk)
+		if err != nil {
+			report[i] = BulkResult{ID: store.NullID, Err: err}
+			continue
+		}
+		if err := tx.Create(id, blob); err != nil {
+			return nil, err
+		}
+		if err := ld.record(tx, id, nil, blob); err != nil {
+			return nil, err
+		}
+		created[id] = blob
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	maps.Copy(ld.blobs, created)
+	slog.Info("ledger: ImportAllNew: imported objects", "count", len(created), "list", list)
+	return report, nil
+}
+
+// importedTask returns the blob of the task to store with the given ID, creating the todo if
+// missing. The task keeps when it was last updated, if set. The caller must hold the lock.
+func (ld *Ledger) importedTask(id store.ID, tk task.Task) (store.Blob, error) {
+	if id == store.NullID || store.IsMeta(id) {
+		return nil, fmt.Errorf("invalid id %q", id)
+	}
+	if list, _ := store.SplitListID(id); list != store.DefaultList {
+		if err := store.ValidateList(list); err != nil {
+			return nil, err
+		}
+	}
+	if err := tk.Validate(); err != nil {
+		return nil, err
+	}
+	prev, found := ld.blobs[id]
+	if err := ld.checkOwner(id, prev); err != nil {
+		return nil, err
+	}
+	var curStatus task.Status
+	if found {
+		cur, err := task.Unmarshal(prev)
+		if err != nil {
+			return nil, err
+		}
+		curStatus, tk.Owner = cur.Status, cur.Owner
+	}
+	if err := ld.workflow.Check(curStatus, tk.Status); err != nil {
+		return nil, err
+	}
+	if tk.Updated.IsZero() {
+		tk.Updated = ld.now()
+	}
+	blob, err := task.Marshal(tk)
+	if err != nil || found {
+		return blob, err
+	}
+	return ld.own(blob)
+}
+
+// DeleteAll removes the todos with the given IDs from the ledger, at once, and returns the report
+// of each todo, sorted by ID. The missing todos, and the ones the view may not change, are skipped.
+func (ld *Ledger) DeleteAll(ids []store.ID) (BulkReport, error) {
+	ld.lock.Lock()
+	defer ld.unlock()
+	ids = slices.Clone(ids)
+	slices.Sort(ids)
+	ids = slices.Compact(ids)
+	report := make(BulkReport, 0, len(ids))
+	var deleted []store.ID
+	for _, id := range ids {
+		res := BulkResult{ID: id}
+		if blob, ok := ld.blobs[id]; ok {
+			res.Err = ld.checkOwner(id, blob)
+		} else {
+			res.Err = store.ErrNotFound{ID: id}
+		}
+		if res.Err == nil {
+			deleted = append(deleted, id)
+		}
+		report = append(report, res)
+	}
+	if len(deleted) == 0 {
+		return report, nil
+	}
+	if err := store.DeleteAll(ld.storer, deleted); err != nil {
+		return nil, err
+	}
+	for _, id := range deleted {
+		prev := ld.blobs[id]
+		delete(ld.blobs, id)
+		if err := ld.record(ld.storer, id, prev, nil); err != nil {
+			return nil, err
+		}
+	}
+	slog.Info("ledger: DeleteAll: deleted objects", "count", len(deleted))
+	return report, nil
+}
+
+// bulk edits the todos with the given IDs, and writes all the edited ones in a single transaction,
+// together with the todos blocked by the moved ones. Returns the report of each todo, sorted by ID.
+// The caller must hold the lock.
+func (ld *Ledger) bulk(ids []store.ID, edit bulkEdit) (BulkReport, error) {
+	return ld.bulkThen(ids, edit, nil)
+}
+
+// bulkThen is like bulk, and calls then, unless nil, with the transaction and the report once the
+// todos are edited, for it to write the changes following from the edits in the same transaction.
+// The ledger has the edited todos by then, and gets back the previous ones if the transaction
+// fails. The caller must hold the lock.
+func (ld *Ledger) bulkThen(ids []store.ID, edit bulkEdit, then func(store.Tx, BulkReport) error) (rep BulkReport, rerr error) {
+	tx, err := ld.begin()
+	if err != nil {
+		return nil, err
+	}
+	history, steps, changes := maps.Clone(ld.history), len(ld.steps), len(ld.changes)
+	var blobs map[store.ID]store.Blob
+	if then != nil {
+		blobs = maps.Clone(ld.blobs)
+	}
+	defer func() {
+		if rerr != nil {
+			tx.Rollback()
+			ld.history, ld.steps, ld.changes = history, ld.steps[:steps], ld.changes[:changes]
+			if blobs != nil {
+				ld.blobs = blobs
+			}
+		}
+	}()
+
+	ids = slices.Clone(ids)
+	slices.Sort(ids)
+	ids = slices.Compact(ids)
+	edited := make(map[store.ID]task.Task)
+	moved := make(map[store.ID]store.ID)
+	var report BulkReport
+	for _, id := range ids {
+		res := BulkResult{ID: id}
+		tk, err := ld.loadTask(id)
+		if err == nil {
+			err = ld.checkOwner(id, ld.blobs[id])
+		}
+		if err == nil {
+			res.NewID, tk, err = edit(id, tk)
+		}
+		switch {
+		case err != nil:
+			res.NewID, res.Err = "", err
+		case res.NewID == id:
+			res.NewID = ""
+			edited[id] = tk
+		default:
+			moved[id] = res.NewID
+			edited[id] = tk
+		}
+		report = append(report, res)
+	}
+
+	// keep the dependencies on the moved todos
+	if len(moved) > 0 {
+		for id, blob := range ld.blobs {
+			if _, ok := edited[id]; ok {
+				continue
+			}
+			tk, err := task.Unmarshal(blob)
+			if err != nil {
+				return nil, err
+			}
+			if slices.ContainsFunc(tk.BlockedBy, func(blocker string) bool {
+				_, ok := moved[store.ID(blocker)]
+				return ok
+			}) {
+				edited[id] = tk
+			}
+		}
+	}
+	updated := make(map[store.ID]store.Blob, len(edited))
+	for id, tk := range edited {
+		for i, blocker := range tk.BlockedBy {
+			if newID, ok := moved[store.ID(blocker)]; ok {
+				tk.BlockedBy[i] = string(newID)
+			}
+		}
+		if err := ld.bulkSave(tx, id, moved[id], tk, updated); err != nil {
+			return nil, err
+		}
+	}
+
+	apply := func() {
+		for id := range moved {
+			delete(ld.blobs, id)
+		}
+		maps.Copy(ld.blobs, updated)
+	}
+	if then != nil {
+		apply()
+		if err := then(tx, report); err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	if then == nil {
+		apply()
+	}
+	return report, nil
+}
+
+// bulkSave writes the task of the todo with the given ID with the transaction, moving it to newID
+// unless empty, and records the change in the history. The new blob is added to updated.
+// The caller must hold the lock.
+func (ld *Ledger) bulkSave(tx store.Tx, id, newID store.ID, tk task.Task, updated map[store.ID]store.Blob) error {
+	prev := ld.blobs[id]
+	tk.Updated = ld.now()
+	blob, err := task.Marshal(tk)
+	if err != nil {
+		return err
+	}
+	if newID == "" {
+		updated[id] = blob
+		if err := tx.Save(id, blob); err != nil {
+			return err
+		}
+		return ld.record(tx, id, prev, blob)
+	}
+	updated[newID] = blob
+	if err := tx.Delete(id); err != nil {
+		return err
+	}
+	if err := tx.Create(newID, blob); err != nil {
+		return err
+	}
+	if err := ld.record(tx, id, prev, nil); err != nil {
+		return err
+	}
+	return ld.record(tx, newID, nil, blob)
+}
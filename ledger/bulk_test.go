@@ -0,0 +1,342 @@
+package ledger
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gotestbootcamp/go-todo-app/model"
+	"github.com/gotestbootcamp/go-todo-app/store"
+	"github.com/gotestbootcamp/go-todo-app/task"
+)
+
+func TestCompleteAll(t *testing.T) {
+	ld := newTestLedger(t, task.DefaultWorkflow())
+	for _, id := range []store.ID{"1", "2", "3"} {
+		require.NoError(t, ld.Set(id, model.New("todo "+string(id))))
+	}
+	_, err := ld.Transition("1", task.Assigned)
+	require.NoError(t, err)
+	_, err = ld.Transition("2", task.Assigned)
+	require.NoError(t, err)
+	_, err = ld.Block("3", "2")
+	require.NoError(t, err)
+	var unblocked []store.ID
+	ld.OnUnblocked(func(item Item) {
+		unblocked = append(unblocked, item.ID)
+	})
+
+	report, err := ld.CompleteAll(func(todo model.Todo) bool {
+		return true
+	})
+	require.NoError(t, err)
+	require.Len(t, report, 3)
+	assert.Equal(t, BulkResult{ID: "1"}, report[0])
+	assert.Equal(t, BulkResult{ID: "2"}, report[1])
+	assert.ErrorIs(t, report[2].Err, task.ErrIllegalTransition{From: task.Pending, To: task.Completed})
+	assert.Equal(t, []store.ID{"3"}, unblocked)
+
+	todo, err := ld.Get("2")
+	require.NoError(t, err)
+	assert.EqualValues(t, task.Completed, todo.Status)
+	todo, err = ld.Get("3")
+	require.NoError(t, err)
+	assert.EqualValues(t, task.Pending, todo.Status)
+
+	// the bulk mutation is a single operation
+	_, err = ld.Undo()
+	require.NoError(t, err)
+	todo, err = ld.Get("1")
+	require.NoError(t, err)
+	assert.EqualValues(t, task.Assigned, todo.Status)
+}
+
+// failingIDs fails to allocate any ID
+type failingIDs struct{}
+
+func (failingIDs) NewID() (store.ID, error) {
+	return store.NullID, errors.New("no more ids")
+}
+
+func TestCompleteAllWorkflow(t *testing.T) {
+	wf, err := task.ParseWorkflow("todo>doing,done; doing>done")
+	require.NoError(t, err)
+	st := newTestMemory(t)
+	ld, err := NewWithWorkflow(st, wf)
+	require.NoError(t, err)
+	tk := task.New("water the plants")
+	tk.Status, tk.Recur = "doing", "daily"
+	_, err = ld.ImportAll(Items{{ID: "1", Task: &tk}})
+	require.NoError(t, err)
+
+	// the todo stays as it was if its next occurrence can't be created
+	ld.SetIDGenerator(failingIDs{})
+	_, err = ld.CompleteAll(func(model.Todo) bool { return true })
+	assert.Error(t, err)
+	todo, err := ld.Get("1")
+	require.NoError(t, err)
+	assert.EqualValues(t, "doing", todo.Status)
+	ld, err = NewWithWorkflow(st, wf)
+	require.NoError(t, err)
+	todo, err = ld.Get("1")
+	require.NoError(t, err)
+	assert.EqualValues(t, "doing", todo.Status)
+
+	report, err := ld.CompleteAll(func(model.Todo) bool { return true })
+	require.NoError(t, err)
+	assert.Equal(t, BulkReport{{ID: "1"}}, report)
+	ld, err = NewWithWorkflow(st, wf)
+	require.NoError(t, err)
+	todo, err = ld.Get("1")
+	require.NoError(t, err)
+	assert.EqualValues(t, "done", todo.Status)
+	items, err := ld.Filter(func(todo model.Todo) bool { return todo.Status == "todo" })
+	require.NoError(t, err)
+	require.Len(t, items, 1, "the next occurrence")
+	assert.Equal(t, "1", items[0].Task.Series)
+}
+
+func TestCompleteByID(t *testing.T) {
+	ld := newTestLedger(t, task.DefaultWorkflow())
+	for _, id := range []store.ID{"1", "2", "3"} {
+		require.NoError(t, ld.Set(id, model.New("todo "+string(id))))
+	}
+	bob := "bob"
+	_, _, err := ld.PatchIf("2", Patch{Assignee: &bob}, AnyRevision)
+	require.NoError(t, err)
+	_, err = ld.Transition("3", task.Deleted)
+	require.NoError(t, err)
+
+	report, err := ld.CompleteByID([]store.ID{"3", "2", "1", "4"}, "ann")
+	require.NoError(t, err)
+	require.Len(t, report, 4)
+	assert.Equal(t, BulkReport{{ID: "1"}, {ID: "2"}}, report[:2])
+	assert.ErrorIs(t, report[2].Err, model.ErrFinalized)
+	assert.ErrorIs(t, report[3].Err, store.ErrNotFound{ID: "4"})
+
+	todo, err := ld.Get("1")
+	require.NoError(t, err)
+	assert.EqualValues(t, task.Completed, todo.Status)
+	assert.Equal(t, "ann", todo.Assignee)
+	todo, err = ld.Get("2")
+	require.NoError(t, err)
+	assert.EqualValues(t, task.Completed, todo.Status)
+	assert.Equal(t, "bob", todo.Assignee)
+}
+
+func TestRetagAll(t *testing.T) {
+	ld := newTestLedger(t, task.DefaultWorkflow())
+	for _, id := range []store.ID{"1", "2"} {
+		require.NoError(t, ld.Set(id, model.New("todo "+string(id))))
+	}
+	_, err := ld.TagTodo("1", "home")
+	require.NoError(t, err)
+
+	_, err = ld.RetagAll([]store.ID{"1", "2"}, []string{"two words"}, nil)
+	assert.Error(t, err)
+	report, err := ld.RetagAll([]store.ID{"2", "1", "4"}, []string{"work"}, []string{"home"})
+	require.NoError(t, err)
+	require.Len(t, report, 3)
+	assert.Equal(t, BulkReport{{ID: "1"}, {ID: "2"}}, report[:2])
+	assert.ErrorIs(t, report[2].Err, store.ErrNotFound{ID: "4"})
+
+	items, err := ld.ListByTag("work")
+	require.NoError(t, err)
+	assert.Equal(t, []store.ID{"1", "2"}, idsOf(items))
+	items, err = ld.ListByTag("home")
+	require.NoError(t, err)
+	assert.Empty(t, items)
+}
+
+func TestMoveAll(t *testing.T) {
+	st := newTestMemory(t)
+	ld, err := New(st)
+	require.NoError(t, err)
+	for _, id := range []store.ID{"1", "2", "3", "work/3"} {
+		require.NoError(t, ld.Set(id, model.New("todo "+string(id))))
+	}
+	_, err = ld.Block("2", "1")
+	require.NoError(t, err)
+
+	_, err = ld.MoveAll(func(todo model.Todo) bool { return true }, ".meta")
+	assert.Error(t, err)
+	report, err := ld.MoveAll(func(todo model.Todo) bool {
+		return todo.Title != "todo 2"
+	}, "work")
+	require.NoError(t, err)
+	require.Len(t, report, 3)
+	assert.Equal(t, BulkResult{ID: "1", NewID: "work/1"}, report[0])
+	assert.Equal(t, store.ID("3"), report[1].ID)
+	assert.ErrorIs(t, report[1].Err, ErrExists{ID: "work/3"})
+	assert.Equal(t, BulkResult{ID: "work/3"}, report[2])
+
+	ld, err = New(st)
+	require.NoError(t, err)
+	_, err = ld.Get("1")
+	assert.Error(t, err)
+	_, err = ld.Get("work/1")
+	require.NoError(t, err)
+	blockers, err := ld.Blocks("work/1")
+	require.NoError(t, err)
+	assert.Equal(t, []store.ID{"2"}, idsOf(blockers))
+	lists, err := store.Lists(st)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"work"}, lists)
+}
+
+func TestCreateAll(t *testing.T) {
+	st := newTestMemory(t)
+	ld, err := New(st)
+	require.NoError(t, err)
+	require.NoError(t, ld.Set("1", model.New("todo 1")))
+
+	_, err = ld.CreateAll(".meta", []task.Task{task.New("todo")})
+	assert.Error(t, err)
+	unknown := task.New("unknown status")
+	unknown.Status = "someday"
+	report, err := ld.CreateAll("work", []task.Task{task.New("todo 2"), task.New(""), unknown})
+	require.NoError(t, err)
+	require.Len(t, report, 3)
+	assert.Equal(t, BulkResult{ID: "work/2"}, report[0])
+	assert.Equal(t, store.NullID, report[1].ID)
+	assert.Error(t, report[1].Err)
+	assert.Error(t, report[2].Err)
+
+	ld, err = New(st)
+	require.NoError(t, err)
+	todo, err := ld.Get("work/2")
+	require.NoError(t, err)
+	assert.Equal(t, "todo 2", todo.Title)
+
+	// the bulk creation is a single operation
+	_, err = ld.Undo()
+	require.NoError(t, err)
+	_, err = ld.Get("work/2")
+	assert.Error(t, err)
+}
+
+func TestImportAll(t *testing.T) {
+	st := newTestMemory(t)
+	ld, err := New(st)
+	require.NoError(t, err)
+	require.NoError(t, ld.Set("1", model.New("todo 1")))
+	require.NoError(t, ld.Set("2", model.New("todo 2")))
+	_, err = ld.Transition("2", task.Deleted)
+	require.NoError(t, err)
+
+	item := func(id store.ID, title string, status task.Status) Item {
+		tk := task.New(title)
+		tk.Status = status
+		return Item{ID: id, Task: &tk}
+	}
+	report, err := ld.ImportAll(Items{
+		item("1", "todo 1 imported", task.Assigned),
+		item("work/3", "todo 3", task.Pending),
+		item("2", "todo 2 imported", task.Pending),
+		item("work/3", "todo 3 again", task.Pending),
+		item(".meta/tags", "tags", task.Pending),
+		item("4", "", task.Pending),
+	})
+	require.NoError(t, err)
+	require.Len(t, report, 6)
+	assert.Equal(t, BulkReport{{ID: "1"}, {ID: "work/3"}}, report[:2])
+	for _, res := range report[2:] {
+		assert.Error(t, res.Err, res.ID)
+	}
+
+	ld, err = New(st)
+	require.NoError(t, err)
+	todo, err := ld.Get("1")
+	require.NoError(t, err)
+	assert.Equal(t, "todo 1 imported", todo.Title)
+	assert.Equal(t, string(task.Assigned), string(todo.Status))
+	todo, err = ld.Get("work/3")
+	require.NoError(t, err)
+	assert.Equal(t, "todo 3", todo.Title)
+	_, err = ld.Get("4")
+	assert.Error(t, err)
+
+	// the import is a single operation
+	_, err = ld.Undo()
+	require.NoError(t, err)
+	todo, err = ld.Get("1")
+	require.NoError(t, err)
+	assert.Equal(t, "todo 1", todo.Title)
+	_, err = ld.Get("work/3")
+	assert.Error(t, err)
+}
+
+func TestImportAllNew(t *testing.T) {
+	st := newTestMemory(t)
+	ld, err := New(st)
+	require.NoError(t, err)
+	require.NoError(t, ld.Set("1", model.New("todo 1")))
+
+	done := time.Date(2026, 10, 5, 0, 0, 0, 0, time.UTC)
+	cake := task.New("buy a cake")
+	cake.Status, cake.Updated, cake.Series = task.Completed, done, "7"
+	bake := task.New("bake a cake")
+	bake.BlockedBy = []string{"7", "8", "9"}
+	bake.Links = []task.Link{
+		{Type: task.Duplicates, ID: "7"},
+		{Type: task.RelatesTo, ID: "9"},
+		{Type: task.RelatesTo, URL: "https://example.com/cakes"},
+	}
+	report, err := ld.ImportAllNew("party", Items{
+		{ID: "7", Task: &cake},
+		{ID: "8", Task: &bake},
+		{ID: "10", Task: &task.Task{Status: task.Pending}},
+	})
+	require.NoError(t, err)
+	require.Len(t, report, 3)
+	assert.Equal(t, BulkReport{{ID: "party/2"}, {ID: "party/3"}}, report[:2])
+	assert.Equal(t, store.NullID, report[2].ID)
+	assert.Error(t, report[2].Err)
+
+	ld, err = New(st)
+	require.NoError(t, err)
+	item, _, err := ld.GetItem("party/2")
+	require.NoError(t, err)
+	assert.Empty(t, item.Task.Series)
+	assert.Equal(t, done, item.Task.Updated.UTC())
+	item, _, err = ld.GetItem("party/3")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"party/2"}, item.Task.BlockedBy)
+	assert.Equal(t, []task.Link{
+		{Type: task.Duplicates, ID: "party/2"},
+		{Type: task.RelatesTo, URL: "https://example.com/cakes"},
+	}, item.Task.Links)
+
+	// the import is a single operation
+	_, err = ld.Undo()
+	require.NoError(t, err)
+	_, err = ld.Get("party/2")
+	assert.Error(t, err)
+	_, err = ld.Get("party/3")
+	assert.Error(t, err)
+}
+
+func TestDeleteAll(t *testing.T) {
+	ld := newTestLedger(t, task.DefaultWorkflow())
+	for _, id := range []store.ID{"1", "2", "3"} {
+		require.NoError(t, ld.Set(id, model.New("todo "+string(id))))
+	}
+
+	report, err := ld.DeleteAll([]store.ID{"3", "1", "4", "1"})
+	require.NoError(t, err)
+	require.Len(t, report, 3)
+	assert.Equal(t, BulkReport{{ID: "1"}, {ID: "3"}}, report[:2])
+	assert.ErrorIs(t, report[2].Err, store.ErrNotFound{ID: "4"})
+	items, err := ld.Filter(func(model.Todo) bool { return true })
+	require.NoError(t, err)
+	assert.Equal(t, []store.ID{"2"}, idsOf(items))
+
+	_, err = ld.Undo()
+	require.NoError(t, err)
+	items, err = ld.Filter(func(model.Todo) bool { return true })
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []store.ID{"1", "2", "3"}, idsOf(items))
+}
@@ -0,0 +1,204 @@
+package ledger
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strconv"
+	"time"
+
+	apiv1 "github.com/gotestbootcamp/go-todo-app/api/v1"
+	"github.com/gotestbootcamp/go-todo-app/store"
+	"github.com/gotestbootcamp/go-todo-app/task"
+)
+
+// MaxBurndownDays bounds the days the burndowns cover, a point each: the longer periods fail with
+// ErrPeriodTooLong
+const MaxBurndownDays = 366
+
+// ErrPeriodTooLong is returned when a report would cover too long a period
+var ErrPeriodTooLong = errors.New("period too long")
+
+// Burndown is how many todos of a project were open over time, see Ledger.Burndown
+type Burndown struct {
+	// Tag and List select the todos of the project: the ones with the tag, in the list. Empty
+	// selects the todos with any tag, in any list.
+	Tag, List string
+	From, To  time.Time
+	// Points are the counts of the todos at From, at the end of each day, and at To, oldest first
+	Points []BurndownPoint
+}
+
+// BurndownPoint counts the todos of a project at a time
+type BurndownPoint struct {
+	Time time.Time
+	// Open is how many todos were active, and Completed how many were completed
+	Open, Completed int
+}
+
+// ToAPIv1 converts the Burndown in its API v1 representation
+func (b Burndown) ToAPIv1() apiv1.Burndown {
+	res := apiv1.Burndown{Tag: b.Tag, List: b.List, From: b.From, To: b.To, Points: make([]apiv1.BurndownPoint, 0, len(b.Points))}
+	for _, p := range b.Points {
+		res.Points = append(res.Points, apiv1.BurndownPoint(p))
+	}
+	return res
+}
+
+// WriteCSV writes the points of the burndown as CSV, after a header naming the columns: the time,
+// in RFC 3339, and the counts of the todos open and completed
+func (b Burndown) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"time", "open", "completed"}); err != nil {
+		return err
+	}
+	for _, p := range b.Points {
+		if err := cw.Write([]string{p.Time.Format(time.RFC3339), strconv.Itoa(p.Open), strconv.Itoa(p.Completed)}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// snapshot is the state of a todo from a time on. Nil tasks don't exist.
+type snapshot struct {
+	at time.Time
+	tk *task.Task
+}
+
+// timeline returns the states of the todo over time, oldest first, from its history. The todos
+// whose history is incomplete, or missing, like the ones stored before it was tracked, were as
+// their oldest state tells since their creation, active until updated if they are not anymore.
+// The caller must hold the lock.
+func (ld *Ledger) timeline(id store.ID) ([]snapshot, error) {
+	var res []snapshot
+	for i, rev := range ld.history[id] {
+		if i == 0 && !slices.ContainsFunc(rev.Changes, func(ch Change) bool { return ch.Field == "created" }) {
+			// the todo existed before its first recorded mutation: undo it
+			prev, err := previousState(rev)
+			if err != nil {
+				return nil, fmt.Errorf("ledger: can't decode the revision %d of %v: %w", rev.Rev, id, err)
+			}
+			res = append(res, snapshot{at: prev.Created, tk: &prev})
+		}
+		sn := snapshot{at: rev.Time}
+		if len(rev.State) > 0 {
+			tk, err := task.Unmarshal(store.Blob(rev.State))
+			if err != nil {
+				return nil, fmt.Errorf("ledger: can't decode the revision %d of %v: %w", rev.Rev, id, err)
+			}
+			sn.tk = &tk
+		}
+		res = append(res, sn)
+	}
+	if len(res) > 0 {
+		return res, nil
+	}
+
+	blob, ok := ld.blobs[id]
+	if !ok {
+		if blob, ok = ld.archive[id]; !ok {
+			return nil, nil
+		}
+	}
+	tk, err := task.Unmarshal(blob)
+	if err != nil {
+		return nil, err
+	}
+	if ld.active(tk) {
+		return []snapshot{{at: tk.Created, tk: &tk}}, nil
+	}
+	open := tk
+	open.Status = ld.workflow.Initial()
+	return []snapshot{{at: tk.Created, tk: &open}, {at: tk.Updated, tk: &tk}}, nil
+}
+
+// previousState returns the task before the revision, undoing its changes
+func previousState(rev Revision) (task.Task, error) {
+	fields := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(rev.State, &fields); err != nil {
+		return task.Task{}, err
+	}
+	for _, ch := range rev.Changes {
+		if len(ch.From) == 0 {
+			delete(fields, ch.Field)
+		} else {
+			fields[ch.Field] = ch.From
+		}
+	}
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return task.Task{}, err
+	}
+	return task.Unmarshal(data)
+}
+
+// Burndown returns how many todos of the project, the ones the view sees with the tag, in the
+// list, were open and completed at the given time, at the end of each following day, and at the
+// other time, as their history tells: the todos count from their creation until removed or
+// deleted, for the project while they have its tag, and the archived ones count as well. Empty
+// selects the todos with any tag, in any list. Fails with ErrPeriodTooLong if the period is longer
+// than MaxBurndownDays.
+func (ld *Ledger) Burndown(tag, list string, from, to time.Time) (Burndown, error) {
+	if list != store.DefaultList {
+		if err := store.ValidateList(list); err != nil {
+			return Burndown{}, err
+		}
+	}
+	if from.After(to) {
+		return Burndown{}, fmt.Errorf("period from %v ends before it starts", from)
+	}
+	if days := to.Sub(from) / (24 * time.Hour); days > MaxBurndownDays {
+		return Burndown{}, fmt.Errorf("%w: %d days, at most %d", ErrPeriodTooLong, days, MaxBurndownDays)
+	}
+	burndown := Burndown{Tag: tag, List: list, From: from, To: to, Points: []BurndownPoint{{Time: from}}}
+	y, m, d := from.Date()
+	for t := time.Date(y, m, d+1, 0, 0, 0, 0, from.Location()); t.Before(to); t = t.AddDate(0, 0, 1) {
+		burndown.Points = append(burndown.Points, BurndownPoint{Time: t})
+	}
+	if to.After(from) {
+		burndown.Points = append(burndown.Points, BurndownPoint{Time: to})
+	}
+
+	ld.lock.RLock()
+	defer ld.lock.RUnlock()
+	ids := make(map[store.ID]bool)
+	for _, blobs := range []map[store.ID]store.Blob{ld.blobs, ld.archive} {
+		for id := range blobs {
+			ids[id] = true
+		}
+	}
+	for id := range ld.history {
+		ids[id] = true
+	}
+	for id := range ids {
+		if inList, _ := store.SplitListID(id); (list != store.DefaultList && inList != list) || !ld.readable(id) {
+			continue
+		}
+		states, err := ld.timeline(id)
+		if err != nil {
+			return Burndown{}, err
+		}
+		for i, p := range burndown.Points {
+			// the last state from before the time of the point
+			j := sort.Search(len(states), func(j int) bool { return states[j].at.After(p.Time) }) - 1
+			if j < 0 || states[j].tk == nil {
+				continue
+			}
+			tk := *states[j].tk
+			switch {
+			case tag != "" && !slices.Contains(tk.Tags, tag):
+			case ld.active(tk):
+				burndown.Points[i].Open++
+			case ld.completed(tk):
+				burndown.Points[i].Completed++
+			}
+		}
+	}
+	return burndown, nil
+}
@@ -0,0 +1,77 @@
+package ledger
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gotestbootcamp/go-todo-app/model"
+	"github.com/gotestbootcamp/go-todo-app/store"
+	"github.com/gotestbootcamp/go-todo-app/task"
+)
+
+func TestBurndown(t *testing.T) {
+	st := newTestMemory(t)
+	day := func(d, h int) time.Time { return time.Date(2024, 11, d, h, 0, 0, 0, time.UTC) }
+	// the todos stored before the history was tracked
+	for id, tk := range map[store.ID]task.Task{
+		"4": {Title: "legacy done", Status: task.Completed, Tags: []string{"proj"}, Created: day(1, 0), Updated: day(5, 12)},
+		"5": {Title: "legacy open", Status: task.Pending, Tags: []string{"proj"}, Created: day(1, 0), Updated: day(1, 0)},
+	} {
+		tk.Schema = task.SchemaVersion
+		blob, err := task.Marshal(tk)
+		require.NoError(t, err)
+		require.NoError(t, st.Create(id, blob))
+	}
+	ld, err := NewWithWorkflow(st, task.DefaultWorkflow())
+	require.NoError(t, err)
+	now := day(4, 9)
+	ld.now = func() time.Time { return now }
+	for _, id := range []store.ID{"1", "2", "3", "work/1"} {
+		require.NoError(t, ld.Set(id, model.New("todo "+string(id))))
+		if id != "3" {
+			_, err = ld.TagTodo(id, "proj")
+			require.NoError(t, err)
+		}
+	}
+	now = day(5, 10)
+	_, err = ld.Transition("1", task.Assigned)
+	require.NoError(t, err)
+	_, err = ld.Transition("1", task.Completed)
+	require.NoError(t, err)
+	now = day(6, 12)
+	_, err = ld.Transition("2", task.Deleted)
+	require.NoError(t, err)
+	_, err = ld.Transition("5", task.Assigned)
+	require.NoError(t, err)
+
+	burndown, err := ld.Burndown("proj", "", day(4, 0), day(7, 0))
+	require.NoError(t, err)
+	assert.Equal(t, []BurndownPoint{
+		{Time: day(4, 0), Open: 2},
+		{Time: day(5, 0), Open: 5},
+		{Time: day(6, 0), Open: 3, Completed: 2},
+		{Time: day(7, 0), Open: 2, Completed: 2},
+	}, burndown.Points)
+
+	burndown, err = ld.Burndown("", "work", day(4, 0), day(5, 6))
+	require.NoError(t, err)
+	assert.Equal(t, []BurndownPoint{
+		{Time: day(4, 0)},
+		{Time: day(5, 0), Open: 1},
+		{Time: day(5, 6), Open: 1},
+	}, burndown.Points)
+	var buf bytes.Buffer
+	require.NoError(t, burndown.WriteCSV(&buf))
+	assert.Equal(t, "time,open,completed\n2024-11-04T00:00:00Z,0,0\n2024-11-05T00:00:00Z,1,0\n2024-11-05T06:00:00Z,1,0\n", buf.String())
+
+	_, err = ld.Burndown("", "a/b", day(4, 0), day(7, 0))
+	assert.Error(t, err)
+	_, err = ld.Burndown("", "", day(7, 0), day(4, 0))
+	assert.Error(t, err)
+	_, err = ld.Burndown("", "", day(4, 0), day(4, 0).AddDate(0, 0, MaxBurndownDays+1))
+	assert.ErrorIs(t, err, ErrPeriodTooLong)
+}
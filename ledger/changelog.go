@@ -0,0 +1,297 @@
+package ledger
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	apiv1 "github.com/gotestbootcamp/go-todo-app/api/v1"
+	"github.com/gotestbootcamp/go-todo-app/store"
+)
+
+// ErrCursorExpired is returned when listing the changes after a cursor the changelog doesn't keep the
+// following changes of anymore, or never issued: the client must resync, listing the todos again.
+var ErrCursorExpired = errors.New("ledger: the changes after the cursor are not kept anymore")
+
+// changePrefix marks the IDs of the items holding the entries of the changelog
+var changePrefix = string(store.MetaID("changes/"))
+
+// changeLogID is the ID of the stored state of the changelog
+var changeLogID = store.MetaID("changelog")
+
+// changeID is zero padded, for the entries to sort by Seq in the datastores
+func changeID(seq uint64) store.ID {
+	return store.ID(fmt.Sprintf("%s%020d", changePrefix, seq))
+}
+
+func isChangeID(id store.ID) bool {
+	return strings.HasPrefix(string(id), changePrefix)
+}
+
+// ChangeEntry is an entry of the changelog, the global log of the mutations of the todos
+type ChangeEntry struct {
+	// Seq orders the entries, increasing, and is the cursor to list the changes after the entry
+	Seq uint64 `json:"seq"`
+	// ID is the todo changed, and Rev its revision after the change, see History
+	ID  store.ID `json:"id"`
+	Rev int      `json:"rev"`
+	// Time is when the mutation was made
+	Time time.Time `json:"time"`
+	// Actor is who made the mutation. Empty if unknown.
+	Actor string          `json:"actor,omitempty"`
+	Type  store.EventType `json:"type"`
+	// State is the encoded todo after the change, as the revision recorded it. Empty if the todo was removed.
+	State json.RawMessage `json:"-"`
+}
+
+// ToAPIv1 converts the ChangeEntry in its API v1 representation, with the todo after the change
+// unless removed
+func (e ChangeEntry) ToAPIv1() apiv1.ChangeEntry {
+	res := apiv1.ChangeEntry{
+		Seq:   e.Seq,
+		Type:  string(e.Type),
+		ID:    apiv1.ID(e.ID),
+		Rev:   e.Rev,
+		Time:  e.Time,
+		Actor: e.Actor,
+	}
+	if len(e.State) > 0 {
+		if item, err := newItem(e.ID, store.Blob(e.State)); err == nil {
+			api := item.ToAPIv1()
+			res.Item = &api
+		}
+	}
+	return res
+}
+
+// changeLog is the stored state of the changelog: the changes up to Truncated were dropped
+type changeLog struct {
+	Truncated uint64 `json:"truncated"`
+}
+
+// ChangeLogPolicy tells which entries CompactChanges drops from the changelog
+type ChangeLogPolicy struct {
+	// MaxEvents is how many of the latest entries are kept at most. Zero keeps them all.
+	MaxEvents int
+	// MaxAge is how long the entries are kept. Zero keeps them forever.
+	MaxAge time.Duration
+	// CompactAfter is how long the entries superseded by a later change of the same todo are kept.
+	// Zero keeps them.
+	CompactAfter time.Duration
+}
+
+// DefaultChangeLogPolicy is the policy the ledger applies to its changelog unless told otherwise,
+// see SetChangeLogPolicy
+var DefaultChangeLogPolicy = ChangeLogPolicy{MaxEvents: 10000}
+
+// SetChangeLogPolicy sets the policy the ledger applies to its changelog as the todos change: once
+// it holds a tenth more entries than its MaxEvents, or entries a tenth older than its MaxAge, the
+// changelog is compacted, see CompactChanges. The zero policy keeps all the entries.
+func (ld *Ledger) SetChangeLogPolicy(policy ChangeLogPolicy) {
+	ld.lock.Lock()
+	defer ld.lock.Unlock()
+	ld.changePolicy = policy
+}
+
+func (ld *Ledger) loadChange(id store.ID, blob store.Blob) error {
+	var entry ChangeEntry
+	if err := json.Unmarshal(blob, &entry); err != nil {
+		return fmt.Errorf("ledger: can't decode the change %v: %w", id, err)
+	}
+	ld.changes = append(ld.changes, entry)
+	return nil
+}
+
+// reloadChange loads again the entry of the changelog with the given ID, replacing the one with
+// the same Seq if any. The caller must hold the lock.
+func (ld *Ledger) reloadChange(id store.ID, blob store.Blob) error {
+	var entry ChangeEntry
+	if err := json.Unmarshal(blob, &entry); err != nil {
+		return fmt.Errorf("ledger: can't decode the change %v: %w", id, err)
+	}
+	ld.changes = append(slices.DeleteFunc(slices.Clone(ld.changes), func(other ChangeEntry) bool {
+		return other.Seq == entry.Seq
+	}), entry)
+	ld.sortChanges()
+	return nil
+}
+
+func (ld *Ledger) loadChangeLog(blob store.Blob) error {
+	if err := json.Unmarshal(blob, &ld.changeLog); err != nil {
+		return fmt.Errorf("ledger: can't decode the changelog: %w", err)
+	}
+	ld.changeLogStored = true
+	return nil
+}
+
+func (ld *Ledger) sortChanges() {
+	sort.Slice(ld.changes, func(i, j int) bool {
+		return ld.changes[i].Seq < ld.changes[j].Seq
+	})
+}
+
+// lastChange returns the Seq of the last change logged, zero if none. The caller must hold the lock.
+func (ld *Ledger) lastChange() uint64 {
+	// the last entry is never superseded, nor dropped unless all of them are
+	if n := len(ld.changes); n > 0 {
+		return ld.changes[n-1].Seq
+	}
+	return ld.changeLog.Truncated
+}
+
+// logChange appends the revision of the todo to the changelog, storing the entry with w.
+// The caller must hold the lock.
+func (ld *Ledger) logChange(w creator, id store.ID, rev Revision, prev store.Blob) error {
+	entry := ChangeEntry{
+		Seq:   ld.lastChange() + 1,
+		ID:    id,
+		Rev:   rev.Rev,
+		Time:  rev.Time,
+		Actor: rev.Actor,
+		Type:  store.EventUpdated,
+	}
+	switch {
+	case prev == nil:
+		entry.Type = store.EventCreated
+	case len(rev.State) == 0:
+		entry.Type = store.EventDeleted
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if err := w.Create(changeID(entry.Seq), data); err != nil {
+		return err
+	}
+	ld.changes = append(ld.changes, entry)
+	return nil
+}
+
+// Changes returns the changes of the todos the view may see after the cursor since, oldest first,
+// at most limit unless zero, and the cursor to list the following ones. The zero since lists the
+// changes from the oldest one kept. Fails with ErrCursorExpired if the changelog dropped changes after
+// since, see CompactChanges, or if it never issued the cursor.
+func (ld *Ledger) Changes(since uint64, limit int) ([]ChangeEntry, uint64, error) {
+	ld.lock.RLock()
+	defer ld.lock.RUnlock()
+	last := ld.lastChange()
+	if since != 0 && (since < ld.changeLog.Truncated || since > last) {
+		return nil, 0, ErrCursorExpired
+	}
+	start := sort.Search(len(ld.changes), func(i int) bool {
+		return ld.changes[i].Seq > since
+	})
+	entries := []ChangeEntry{}
+	next := since
+	for _, entry := range ld.changes[start:] {
+		if limit > 0 && len(entries) == limit {
+			return entries, next, nil
+		}
+		next = entry.Seq
+		if !ld.readable(entry.ID) {
+			continue
+		}
+		if i := ld.revisionAt(entry.ID, entry.Rev); i >= 0 {
+			entry.State = ld.history[entry.ID][i].State
+		}
+		entries = append(entries, entry)
+	}
+	return entries, max(next, last), nil
+}
+
+// CompactChanges drops the entries of the changelog the policy doesn't keep, and returns how many.
+// The oldest ones beyond its MaxEvents or MaxAge are truncated: listing the changes after them fails
+// with ErrCursorExpired. The ones superseded for CompactAfter are dropped too, for the clients to list
+// the latest change of each todo only.
+func (ld *Ledger) CompactChanges(policy ChangeLogPolicy) (int, error) {
+	ld.lock.Lock()
+	defer ld.lock.Unlock()
+	return ld.compactChanges(policy)
+}
+
+// truncateChanges compacts the changelog with the policy of the ledger, once it holds a tenth more
+// entries than the policy keeps, not to compact it on each change. The caller must hold the lock.
+func (ld *Ledger) truncateChanges() {
+	policy := ld.changePolicy
+	over := policy.MaxEvents > 0 && len(ld.changes) > policy.MaxEvents+policy.MaxEvents/10
+	if policy.MaxAge > 0 && len(ld.changes) > 0 && ld.now().Sub(ld.changes[0].Time) > policy.MaxAge+policy.MaxAge/10 {
+		over = true
+	}
+	if !over {
+		return
+	}
+	if _, err := ld.compactChanges(policy); err != nil {
+		slog.Error("ledger: failed to compact the changelog", "error", err)
+	}
+}
+
+// compactChanges is CompactChanges. The caller must hold the lock.
+func (ld *Ledger) compactChanges(policy ChangeLogPolicy) (int, error) {
+	now := ld.now()
+	truncated := 0
+	for truncated < len(ld.changes) {
+		entry := ld.changes[truncated]
+		if (policy.MaxEvents <= 0 || len(ld.changes)-truncated <= policy.MaxEvents) &&
+			(policy.MaxAge <= 0 || now.Sub(entry.Time) <= policy.MaxAge) {
+			break
+		}
+		truncated++
+	}
+	latest := make(map[store.ID]uint64)
+	for _, entry := range ld.changes {
+		latest[entry.ID] = entry.Seq
+	}
+	var dropped []uint64
+	kept := make([]ChangeEntry, 0, len(ld.changes)-truncated)
+	for i, entry := range ld.changes {
+		superseded := policy.CompactAfter > 0 && latest[entry.ID] != entry.Seq && now.Sub(entry.Time) > policy.CompactAfter
+		if i < truncated || superseded {
+			dropped = append(dropped, entry.Seq)
+			continue
+		}
+		kept = append(kept, entry)
+	}
+	if len(dropped) == 0 {
+		return 0, nil
+	}
+
+	stored := ld.changeLog
+	if truncated > 0 {
+		stored.Truncated = ld.changes[truncated-1].Seq
+	}
+	tx, err := ld.begin()
+	if err != nil {
+		return 0, err
+	}
+	data, err := json.Marshal(stored)
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	if ld.changeLogStored {
+		err = tx.Save(changeLogID, data)
+	} else {
+		err = tx.Create(changeLogID, data)
+	}
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	for _, seq := range dropped {
+		if err := tx.Delete(changeID(seq)); err != nil {
+			tx.Rollback()
+			return 0, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	ld.changes, ld.changeLog, ld.changeLogStored = kept, stored, true
+	slog.Info("ledger: CompactChanges: dropped changes", "count", len(dropped), "truncated", truncated)
+	return len(dropped), nil
+}
@@ -0,0 +1,200 @@
+package ledger
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gotestbootcamp/go-todo-app/model"
+	"github.com/gotestbootcamp/go-todo-app/store"
+	"github.com/gotestbootcamp/go-todo-app/task"
+)
+
+func TestChanges(t *testing.T) {
+	st := newTestMemory(t)
+	ld, err := New(st)
+	require.NoError(t, err)
+	now := time.Date(2024, 3, 6, 12, 0, 0, 0, time.UTC)
+	ld.now = func() time.Time { return now }
+
+	entries, next, err := ld.Changes(0, 0)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+	assert.Zero(t, next)
+
+	require.NoError(t, ld.Set("1", model.New("foo")))
+	require.NoError(t, ld.Set("2", model.New("bar")))
+	_, err = ld.As("alice").SetPriority("1", task.PriorityHigh)
+	require.NoError(t, err)
+	// changing nothing logs nothing
+	_, err = ld.SetPriority("1", task.PriorityHigh)
+	require.NoError(t, err)
+	require.NoError(t, ld.Delete("2"))
+
+	entries, next, err = ld.Changes(0, 0)
+	require.NoError(t, err)
+	require.Len(t, entries, 4)
+	assert.Equal(t, uint64(4), next)
+	for i, entry := range entries {
+		assert.Equal(t, uint64(i+1), entry.Seq)
+	}
+	assert.Equal(t, ChangeEntry{Seq: 1, ID: "1", Rev: 1, Time: now, Type: store.EventCreated, State: entries[0].State}, entries[0])
+	assert.Contains(t, string(entries[0].State), `"title":"foo"`)
+	assert.Equal(t, store.EventUpdated, entries[2].Type)
+	assert.Equal(t, "alice", entries[2].Actor)
+	assert.Equal(t, 2, entries[2].Rev)
+	assert.Equal(t, store.EventDeleted, entries[3].Type)
+	assert.Empty(t, entries[3].State)
+
+	// polling resumes after the cursor
+	entries, next, err = ld.Changes(1, 2)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, uint64(3), next)
+	entries, next, err = ld.Changes(next, 2)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, uint64(4), next)
+	entries, next, err = ld.Changes(next, 2)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+	assert.Equal(t, uint64(4), next)
+	_, _, err = ld.Changes(5, 0)
+	assert.ErrorIs(t, err, ErrCursorExpired)
+
+	// the changelog survives the restarts
+	ld, err = New(st)
+	require.NoError(t, err)
+	ld.now = func() time.Time { return now }
+	entries, _, err = ld.Changes(0, 0)
+	require.NoError(t, err)
+	require.Len(t, entries, 4)
+	assert.Equal(t, "alice", entries[2].Actor)
+}
+
+func TestChangesView(t *testing.T) {
+	ld, err := New(newTestMemory(t))
+	require.NoError(t, err)
+	require.NoError(t, ld.Set("1", model.New("foo")))
+	_, err = ld.AddMember("ops", "carol", RoleListAdmin)
+	require.NoError(t, err)
+	_, err = ld.AddMember("ops", "alice", RoleEditor)
+	require.NoError(t, err)
+	_, err = ld.AddMember("home", "bob", RoleListAdmin)
+	require.NoError(t, err)
+	require.NoError(t, ld.Set(store.ListID("ops", "2"), model.New("bar")))
+	require.NoError(t, ld.Set(store.ListID("home", "3"), model.New("baz")))
+
+	entries, next, err := ld.AsUser("alice", false).Changes(0, 0)
+	require.NoError(t, err)
+	var ids []store.ID
+	for _, entry := range entries {
+		ids = append(ids, entry.ID)
+	}
+	assert.Equal(t, []store.ID{"1", store.ListID("ops", "2")}, ids)
+	// the changes the view can't see move the cursor too
+	assert.Equal(t, uint64(3), next)
+}
+
+func TestCompactChanges(t *testing.T) {
+	st := newTestMemory(t)
+	ld, err := New(st)
+	require.NoError(t, err)
+	now := time.Date(2024, 3, 6, 12, 0, 0, 0, time.UTC)
+	ld.now = func() time.Time { return now }
+
+	require.NoError(t, ld.Set("1", model.New("foo")))
+	require.NoError(t, ld.Set("2", model.New("bar")))
+	now = now.Add(time.Hour)
+	_, err = ld.SetPriority("1", task.PriorityHigh)
+	require.NoError(t, err)
+	_, err = ld.SetPriority("2", task.PriorityHigh)
+	require.NoError(t, err)
+	now = now.Add(time.Hour)
+	require.NoError(t, ld.Set("3", model.New("baz")))
+	_, err = ld.SetPriority("1", task.PriorityLow)
+	require.NoError(t, err)
+
+	count, err := ld.CompactChanges(ChangeLogPolicy{})
+	require.NoError(t, err)
+	assert.Zero(t, count)
+
+	// the changes superseded for longer than an hour go
+	count, err = ld.CompactChanges(ChangeLogPolicy{CompactAfter: 30 * time.Minute})
+	require.NoError(t, err)
+	assert.Equal(t, 3, count)
+	seqs := func(ld *Ledger) []uint64 {
+		entries, _, err := ld.Changes(0, 0)
+		require.NoError(t, err)
+		var seqs []uint64
+		for _, entry := range entries {
+			seqs = append(seqs, entry.Seq)
+		}
+		return seqs
+	}
+	assert.Equal(t, []uint64{4, 5, 6}, seqs(ld))
+	// the cursors of the compacted changes are still valid
+	entries, _, err := ld.Changes(2, 0)
+	require.NoError(t, err)
+	assert.Len(t, entries, 3)
+
+	// the oldest changes beyond the limits are truncated
+	count, err = ld.CompactChanges(ChangeLogPolicy{MaxEvents: 2})
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+	assert.Equal(t, []uint64{5, 6}, seqs(ld))
+	_, _, err = ld.Changes(3, 0)
+	assert.ErrorIs(t, err, ErrCursorExpired)
+	_, _, err = ld.Changes(4, 0)
+	assert.NoError(t, err)
+
+	// the changelog survives the restarts, and the numbering goes on once all are truncated
+	ld, err = New(st)
+	require.NoError(t, err)
+	ld.now = func() time.Time { return now }
+	assert.Equal(t, []uint64{5, 6}, seqs(ld))
+	_, _, err = ld.Changes(3, 0)
+	assert.ErrorIs(t, err, ErrCursorExpired)
+	now = now.Add(time.Hour)
+	count, err = ld.CompactChanges(ChangeLogPolicy{MaxAge: time.Minute})
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+	entries, next, err := ld.Changes(0, 0)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+	assert.Equal(t, uint64(6), next)
+	require.NoError(t, ld.Delete("3"))
+	assert.Equal(t, []uint64{7}, seqs(ld))
+}
+
+func TestChangeLogPolicy(t *testing.T) {
+	st := newTestMemory(t)
+	ld, err := New(st)
+	require.NoError(t, err)
+	ld.SetChangeLogPolicy(ChangeLogPolicy{MaxEvents: 10})
+
+	for i := range 11 {
+		require.NoError(t, ld.Set("1", model.New(fmt.Sprintf("todo %d", i))))
+	}
+	entries, _, err := ld.Changes(0, 0)
+	require.NoError(t, err)
+	assert.Len(t, entries, 11)
+	// compacted once the changelog holds a tenth more entries than the policy keeps
+	require.NoError(t, ld.Set("1", model.New("todo 11")))
+	entries, next, err := ld.Changes(0, 0)
+	require.NoError(t, err)
+	assert.Len(t, entries, 10)
+	assert.Equal(t, uint64(12), next)
+	_, _, err = ld.Changes(1, 0)
+	assert.ErrorIs(t, err, ErrCursorExpired)
+	_, err = st.Load(changeID(2))
+	assert.ErrorIs(t, err, store.ErrNotFound{ID: changeID(2)})
+
+	// the changelog is bounded by default
+	ld, err = New(newTestMemory(t))
+	require.NoError(t, err)
+	assert.Equal(t, DefaultChangeLogPolicy, ld.changePolicy)
+}
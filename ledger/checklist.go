@@ -0,0 +1,57 @@
+package ledger
+
+import (
+	"log/slog"
+
+	"github.com/gotestbootcamp/go-todo-app/store"
+)
+
+// AddCheckItem appends an entry with the given text to the checklist of the todo, and returns
+// the updated Item
+func (ld *Ledger) AddCheckItem(id store.ID, text string) (Item, error) {
+	ld.lock.Lock()
+	defer ld.unlock()
+	tk, err := ld.loadTask(id)
+	if err != nil {
+		return Item{}, err
+	}
+	ci, err := tk.AddCheckItem(text)
+	if err != nil {
+		return Item{}, err
+	}
+	slog.Info("ledger: AddCheckItem: added checklist entry", "id", id, "entry", ci.ID)
+	return ld.saveTask(id, tk)
+}
+
+// ToggleCheckItem flips the done flag of the checklist entry of the todo, and returns the updated Item.
+// Fails with task.ErrNoCheckItem if the todo has no such entry.
+func (ld *Ledger) ToggleCheckItem(id store.ID, itemID int) (Item, error) {
+	ld.lock.Lock()
+	defer ld.unlock()
+	tk, err := ld.loadTask(id)
+	if err != nil {
+		return Item{}, err
+	}
+	ci, err := tk.ToggleCheckItem(itemID)
+	if err != nil {
+		return Item{}, err
+	}
+	slog.Info("ledger: ToggleCheckItem: toggled checklist entry", "id", id, "entry", itemID, "done", ci.Done)
+	return ld.saveTask(id, tk)
+}
+
+// RemoveCheckItem removes the entry from the checklist of the todo, and returns the updated Item.
+// Fails with task.ErrNoCheckItem if the todo has no such entry.
+func (ld *Ledger) RemoveCheckItem(id store.ID, itemID int) (Item, error) {
+	ld.lock.Lock()
+	defer ld.unlock()
+	tk, err := ld.loadTask(id)
+	if err != nil {
+		return Item{}, err
+	}
+	if err := tk.RemoveCheckItem(itemID); err != nil {
+		return Item{}, err
+	}
+	slog.Info("ledger: RemoveCheckItem: removed checklist entry", "id", id, "entry", itemID)
+	return ld.saveTask(id, tk)
+}
@@ -0,0 +1,34 @@
+package ledger
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gotestbootcamp/go-todo-app/model"
+	"github.com/gotestbootcamp/go-todo-app/task"
+)
+
+func TestChecklist(t *testing.T) {
+	ld := newTestLedger(t, task.DefaultWorkflow())
+	require.NoError(t, ld.Set("1", model.New("groceries")))
+	for _, text := range []string{"eggs", "milk", "bread"} {
+		_, err := ld.AddCheckItem("1", text)
+		require.NoError(t, err)
+	}
+	item, err := ld.ToggleCheckItem("1", 2)
+	require.NoError(t, err)
+	assert.Equal(t, "1/3", item.ToAPIv1().Todo.ChecklistSummary)
+	_, err = ld.ToggleCheckItem("1", 4)
+	assert.ErrorIs(t, err, task.ErrNoCheckItem{ID: 4})
+	item, err = ld.RemoveCheckItem("1", 1)
+	require.NoError(t, err)
+	assert.Equal(t, "1/2", item.Task.ChecklistSummary())
+
+	// the summary is available to the list views
+	items, err := ld.Filter(func(todo model.Todo) bool { return true })
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	assert.Equal(t, "1/2", items.ToAPIv1()[0].Todo.ChecklistSummary)
+}
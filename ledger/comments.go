@@ -0,0 +1,93 @@
+package ledger
+
+import (
+	"log/slog"
+
+	apiv1 "github.com/gotestbootcamp/go-todo-app/api/v1"
+	"github.com/gotestbootcamp/go-todo-app/store"
+	"github.com/gotestbootcamp/go-todo-app/task"
+)
+
+// CommentToAPIv1 converts the comment in its API v1 representation
+func CommentToAPIv1(cm task.Comment) apiv1.Comment {
+	return apiv1.Comment{
+		ID:      cm.ID,
+		Author:  cm.Author,
+		Body:    cm.Body,
+		Created: cm.Created,
+		Edited:  cm.Edited,
+	}
+}
+
+// Comments returns the comments on the todo, oldest first
+func (ld *Ledger) Comments(id store.ID) ([]task.Comment, error) {
+	ld.lock.RLock()
+	defer ld.lock.RUnlock()
+	tk, err := ld.loadTask(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := ld.checkRead(id); err != nil {
+		return nil, err
+	}
+	return tk.Comments, nil
+}
+
+// AddComment adds a comment with the given Markdown body to the todo, authored by the actor
+// of the ledger, see As, and returns it
+func (ld *Ledger) AddComment(id store.ID, body string) (task.Comment, error) {
+	ld.lock.Lock()
+	defer ld.unlock()
+	tk, err := ld.loadTask(id)
+	if err != nil {
+		return task.Comment{}, err
+	}
+	cm, err := tk.AddComment(ld.actor, body, ld.now())
+	if err != nil {
+		return task.Comment{}, err
+	}
+	if _, err := ld.saveTask(id, tk); err != nil {
+		return task.Comment{}, err
+	}
+	slog.Info("ledger: AddComment: added comment", "id", id, "comment", cm.ID)
+	return cm, nil
+}
+
+// EditComment replaces the body of the comment on the todo, and returns the edited comment.
+// Fails with task.ErrNoComment if the todo has no such comment.
+func (ld *Ledger) EditComment(id store.ID, commentID int, body string) (task.Comment, error) {
+	ld.lock.Lock()
+	defer ld.unlock()
+	tk, err := ld.loadTask(id)
+	if err != nil {
+		return task.Comment{}, err
+	}
+	cm, err := tk.EditComment(commentID, body, ld.now())
+	if err != nil {
+		return task.Comment{}, err
+	}
+	if _, err := ld.saveTask(id, tk); err != nil {
+		return task.Comment{}, err
+	}
+	slog.Info("ledger: EditComment: edited comment", "id", id, "comment", commentID)
+	return cm, nil
+}
+
+// DeleteComment removes the comment from the todo.
+// Fails with task.ErrNoComment if the todo has no such comment.
+func (ld *Ledger) DeleteComment(id store.ID, commentID int) error {
+	ld.lock.Lock()
+	defer ld.unlock()
+	tk, err := ld.loadTask(id)
+	if err != nil {
+		return err
+	}
+	if err := tk.DeleteComment(commentID); err != nil {
+		return err
+	}
+	if _, err := ld.saveTask(id, tk); err != nil {
+		return err
+	}
+	slog.Info("ledger: DeleteComment: deleted comment", "id", id, "comment", commentID)
+	return nil
+}
@@ -0,0 +1,41 @@
+package ledger
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gotestbootcamp/go-todo-app/model"
+	"github.com/gotestbootcamp/go-todo-app/store"
+	"github.com/gotestbootcamp/go-todo-app/task"
+)
+
+func TestComments(t *testing.T) {
+	st := newTestMemory(t)
+	ld, err := New(st)
+	require.NoError(t, err)
+	require.NoError(t, ld.Set("1", model.New("foo")))
+	_, err = ld.AddComment("2", "hello")
+	assert.ErrorIs(t, err, store.ErrNotFound{ID: "2"})
+
+	cm, err := ld.As("fede").AddComment("1", "waiting for *the quote*")
+	require.NoError(t, err)
+	assert.Equal(t, "fede", cm.Author)
+	_, err = ld.AddComment("1", "pinged")
+	require.NoError(t, err)
+	_, err = ld.EditComment("1", 1, "got *the quote*")
+	require.NoError(t, err)
+	_, err = ld.EditComment("1", 3, "nope")
+	assert.ErrorIs(t, err, task.ErrNoComment{ID: 3})
+	require.NoError(t, ld.DeleteComment("1", 2))
+
+	// the comments are stored with the todo
+	ld, err = New(st)
+	require.NoError(t, err)
+	comments, err := ld.Comments("1")
+	require.NoError(t, err)
+	require.Len(t, comments, 1)
+	assert.Equal(t, "got *the quote*", comments[0].Body)
+	assert.NotNil(t, comments[0].Edited)
+}
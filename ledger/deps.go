@@ -0,0 +1,198 @@
+package ledger
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/gotestbootcamp/go-todo-app/store"
+	"github.com/gotestbootcamp/go-todo-app/task"
+)
+
+// ErrDependencyCycle is returned when a todo would end up blocked by itself, directly or not
+type ErrDependencyCycle struct {
+	ID      store.ID
+	Blocker store.ID
+}
+
+func (e ErrDependencyCycle) Error() string {
+	return fmt.Sprintf("object %v can't be blocked by %v: dependency cycle", e.ID, e.Blocker)
+}
+
+// UnblockedFunc is called with a todo whose last active blocker was completed
+type UnblockedFunc func(item Item)
+
+// OnUnblocked registers fn to be called with the todos whose last active blocker was completed,
+// i.e. moved to a final status of the workflow. fn is called once the update is done, so it may
+// use the ledger.
+func (ld *Ledger) OnUnblocked(fn UnblockedFunc) {
+	ld.lock.Lock()
+	defer ld.lock.Unlock()
+	ld.onUnblocked = append(ld.onUnblocked, fn)
+}
+
+func (ld *Ledger) notifyUnblocked(items Items) {
+	ld.lock.RLock()
+	fns := ld.onUnblocked
+	ld.lock.RUnlock()
+	for _, item := range items {
+		slog.Info("ledger: object unblocked", "id", item.ID)
+		for _, fn := range fns {
+			fn(item)
+		}
+	}
+}
+
+// Block makes the todo blocked by the blocker, which should be completed first, and returns the updated Item.
+// Fails with store.ErrNotFound if either todo doesn't exist, and with ErrDependencyCycle if the blocker
+// is blocked by the todo already, directly or not.
+func (ld *Ledger) Block(id, blocker store.ID) (Item, error) {
+	ld.lock.Lock()
+	defer ld.unlock()
+	if _, ok := ld.blobs[blocker]; !ok {
+		return Item{}, store.ErrNotFound{ID: blocker}
+	}
+	if err := ld.checkRead(blocker); err != nil {
+		return Item{}, err
+	}
+	tk, err := ld.loadTask(id)
+	if err != nil {
+		return Item{}, err
+	}
+	if slices.Contains(tk.BlockedBy, string(blocker)) {
+		return newItem(id, ld.blobs[id])
+	}
+	if ld.blockedBy(blocker, id) {
+		return Item{}, ErrDependencyCycle{ID: id, Blocker: blocker}
+	}
+	tk.BlockedBy = append(tk.BlockedBy, string(blocker))
+	slog.Info("ledger: Block: object blocked", "id", id, "blocker", blocker)
+	return ld.saveTask(id, tk)
+}
+
+// Unblock removes the blocker from the blockers of the todo, and returns the updated Item.
+// Fails with store.ErrNotFound if the todo doesn't exist.
+func (ld *Ledger) Unblock(id, blocker store.ID) (Item, error) {
+	ld.lock.Lock()
+	defer ld.unlock()
+	tk, err := ld.loadTask(id)
+	if err != nil {
+		return Item{}, err
+	}
+	pos := slices.Index(tk.BlockedBy, string(blocker))
+	if pos < 0 {
+		return newItem(id, ld.blobs[id])
+	}
+	tk.BlockedBy = slices.Delete(tk.BlockedBy, pos, pos+1)
+	slog.Info("ledger: Unblock: object unblocked", "id", id, "blocker", blocker)
+	return ld.saveTask(id, tk)
+}
+
+// ListBlocked returns the active todos with active blockers, the earliest due first.
+// The blockers in a final status of the workflow, or removed from the ledger, don't block anymore.
+func (ld *Ledger) ListBlocked() (Items, error) {
+	items, err := ld.filterTasks(func(tk task.Task) bool {
+		return ld.active(tk) && len(ld.activeBlockers(tk)) > 0
+	}, dueOf)
+	slog.Debug("ledger: ListBlocked: objects blocked", "count", len(items))
+	return items, err
+}
+
+// Blocks returns the todos blocked by the todo with the given ID, the earliest due first.
+// Fails with store.ErrNotFound if the todo doesn't exist, and with ErrForbidden if the view may not see it.
+func (ld *Ledger) Blocks(id store.ID) (Items, error) {
+	ld.lock.RLock()
+	_, ok := ld.blobs[id]
+	err := ld.checkRead(id)
+	ld.lock.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, store.ErrNotFound{ID: id}
+	}
+	return ld.filterTasks(func(tk task.Task) bool {
+		return slices.Contains(tk.BlockedBy, string(id))
+	}, dueOf)
+}
+
+// activeBlockers returns the blockers of the task which still block it. The caller must hold the lock.
+func (ld *Ledger) activeBlockers(tk task.Task) []store.ID {
+	var res []store.ID
+	for _, id := range tk.BlockedBy {
+		blob, ok := ld.blobs[store.ID(id)]
+		if !ok {
+			continue
+		}
+		blocker, err := task.Unmarshal(blob)
+		if err != nil || ld.active(blocker) {
+			// a blocker which can't be decoded can't be completed either
+			res = append(res, store.ID(id))
+		}
+	}
+	return res
+}
+
+// blockedBy returns true if the todo with the given ID is blocked by target, directly or not.
+// The caller must hold the lock.
+func (ld *Ledger) blockedBy(id, target store.ID) bool {
+	seen := map[store.ID]bool{}
+	stack := []store.ID{id}
+	for len(stack) > 0 {
+		cur := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if cur == target {
+			return true
+		}
+		if seen[cur] {
+			continue
+		}
+		seen[cur] = true
+		tk, err := ld.loadTask(cur)
+		if err != nil {
+			continue
+		}
+		for _, blocker := range tk.BlockedBy {
+			stack = append(stack, store.ID(blocker))
+		}
+	}
+	return false
+}
+
+// unblockedBy returns the todos unblocked by the todo with the given ID, if it was just moved
+// from the status encoded in prevBlob to a final status. The caller must hold the lock.
+func (ld *Ledger) unblockedBy(id store.ID, prevBlob store.Blob) (Items, error) {
+	if prevBlob == nil {
+		return nil, nil
+	}
+	tk, err := ld.loadTask(id)
+	if err != nil {
+		return nil, err
+	}
+	prev, err := task.Unmarshal(prevBlob)
+	if err != nil {
+		return nil, err
+	}
+	if ld.active(tk) || !ld.active(prev) {
+		return nil, nil
+	}
+	var items Items
+	for depID, blob := range ld.blobs {
+		dep, err := task.Unmarshal(blob)
+		if err != nil {
+			continue
+		}
+		if slices.Contains(dep.BlockedBy, string(id)) && ld.active(dep) && len(ld.activeBlockers(dep)) == 0 {
+			item, err := newItem(depID, blob)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		}
+	}
+	slices.SortFunc(items, func(a, b Item) int {
+		return strings.Compare(string(a.ID), string(b.ID))
+	})
+	return items, nil
+}
@@ -0,0 +1,96 @@
+package ledger
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gotestbootcamp/go-todo-app/model"
+	"github.com/gotestbootcamp/go-todo-app/store"
+	"github.com/gotestbootcamp/go-todo-app/task"
+)
+
+func idsOf(items Items) []store.ID {
+	var res []store.ID
+	for _, item := range items {
+		res = append(res, item.ID)
+	}
+	return res
+}
+
+func TestBlock(t *testing.T) {
+	ld := newTestLedger(t, task.DefaultWorkflow())
+	for _, id := range []store.ID{"1", "2", "3"} {
+		require.NoError(t, ld.Set(id, model.New("todo "+string(id))))
+	}
+
+	item, err := ld.Block("2", "1")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1"}, item.Task.BlockedBy)
+	_, err = ld.Block("3", "2")
+	require.NoError(t, err)
+	// idempotent
+	item, err = ld.Block("2", "1")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1"}, item.Task.BlockedBy)
+
+	_, err = ld.Block("1", "3")
+	assert.ErrorIs(t, err, ErrDependencyCycle{ID: "1", Blocker: "3"})
+	_, err = ld.Block("1", "1")
+	assert.ErrorIs(t, err, ErrDependencyCycle{ID: "1", Blocker: "1"})
+	_, err = ld.Block("1", "4")
+	assert.ErrorIs(t, err, store.ErrNotFound{ID: "4"})
+	_, err = ld.Block("4", "1")
+	assert.ErrorIs(t, err, store.ErrNotFound{ID: "4"})
+
+	blocked, err := ld.ListBlocked()
+	require.NoError(t, err)
+	assert.Equal(t, []store.ID{"2", "3"}, idsOf(blocked))
+	blocks, err := ld.Blocks("1")
+	require.NoError(t, err)
+	assert.Equal(t, []store.ID{"2"}, idsOf(blocks))
+
+	item, err = ld.Unblock("3", "2")
+	require.NoError(t, err)
+	assert.Empty(t, item.Task.BlockedBy)
+	blocked, err = ld.ListBlocked()
+	require.NoError(t, err)
+	assert.Equal(t, []store.ID{"2"}, idsOf(blocked))
+	// now allowed
+	_, err = ld.Block("1", "3")
+	require.NoError(t, err)
+}
+
+func TestUnblockedNotification(t *testing.T) {
+	ld := newTestLedger(t, task.DefaultWorkflow())
+	for _, id := range []store.ID{"1", "2", "3"} {
+		require.NoError(t, ld.Set(id, model.New("todo "+string(id))))
+	}
+	_, err := ld.Block("3", "1")
+	require.NoError(t, err)
+	_, err = ld.Block("3", "2")
+	require.NoError(t, err)
+
+	var unblocked []store.ID
+	ld.OnUnblocked(func(item Item) {
+		unblocked = append(unblocked, item.ID)
+		// may use the ledger
+		_, err := ld.Get(item.ID)
+		assert.NoError(t, err)
+	})
+
+	_, err = ld.Transition("1", task.Deleted)
+	require.NoError(t, err)
+	assert.Empty(t, unblocked)
+	_, err = ld.Transition("2", task.Assigned)
+	require.NoError(t, err)
+	assert.Empty(t, unblocked)
+	_, err = ld.Transition("2", task.Completed)
+	require.NoError(t, err)
+	assert.Equal(t, []store.ID{"3"}, unblocked)
+
+	blocked, err := ld.ListBlocked()
+	require.NoError(t, err)
+	assert.Empty(t, blocked)
+}
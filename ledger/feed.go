@@ -0,0 +1,190 @@
+package ledger
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// ErrEventsExpired is returned when resuming the changes after an event the feed doesn't keep anymore,
+// e.g. because it happened before a restart: the subscriber must resync, listing the todos again.
+var ErrEventsExpired = errors.New("ledger: the events to resume from expired")
+
+const (
+	// feedSize is how many of the latest events the feed keeps, for the subscribers to resume
+	feedSize = 1000
+	// feedBuffer is how many events can be pending for a slow subscriber
+	feedBuffer = 64
+)
+
+// feed numbers the changes of the todos, and keeps the latest ones so that the subscribers can resume
+// after the last event they received
+type feed struct {
+	lock sync.Mutex
+	// recent are the latest events, oldest first
+	recent []Event
+	// next is the sequence number of the next event
+	next   uint64
+	subs   map[chan Event]struct{}
+	cancel context.CancelFunc
+}
+
+// Subscribe returns a channel receiving the changes of the todos, numbered by their Seq, until the given
+// context is done: then the channel is closed. The channel is closed as well if the subscriber falls
+// behind, so it can resume. The zero since receives the changes from now on, otherwise the ones after
+// the event with that Seq. Fails with ErrEventsExpired if the feed doesn't keep that event anymore,
+// and with store.ErrUnsupported if the datastore can't be watched, see Watch.
+func (ld *Ledger) Subscribe(ctx context.Context, since uint64) (<-chan Event, error) {
+	fd, err := ld.startFeed()
+	if err != nil {
+		return nil, err
+	}
+	fd.lock.Lock()
+	defer fd.lock.Unlock()
+	oldest := fd.next - uint64(len(fd.recent))
+	var replay []Event
+	if since != 0 {
+		if since+1 < oldest || since >= fd.next {
+			return nil, ErrEventsExpired
+		}
+		replay = fd.recent[since+1-oldest:]
+	}
+	ch := make(chan Event, len(replay)+feedBuffer)
+	for _, ev := range replay {
+		ch <- ev
+	}
+	fd.subs[ch] = struct{}{}
+
+	go func() {
+		<-ctx.Done()
+		fd.unsubscribe(ch)
+	}()
+	if ld.restricted() {
+		return ld.readableEvents(ch), nil
+	}
+	return ch, nil
+}
+
+// readableEvents returns the channel receiving the events the view may see of the given channel,
+// closed once the given one is
+func (ld *Ledger) readableEvents(events <-chan Event) <-chan Event {
+	ch := make(chan Event, feedBuffer)
+	go func() {
+		defer close(ch)
+		for ev := range events {
+			if !ld.canWatch(ev.Item.ID) {
+				continue
+			}
+			select {
+			case ch <- ev:
+			default:
+				// the subscriber is too slow: the feed would drop it as well
+				return
+			}
+		}
+	}()
+	return ch
+}
+
+// startFeed returns the feed of the ledger, starting it on the first call
+func (ld *Ledger) startFeed() (*feed, error) {
+	ld.feedLock.Lock()
+	defer ld.feedLock.Unlock()
+	if ld.feed != nil {
+		return ld.feed, nil
+	}
+	// the feed is shared by the views, so it sees all the todos
+	root := &Ledger{state: ld.state}
+	ctx, cancel := context.WithCancel(context.Background())
+	events, err := root.Watch(ctx)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	// the sequence starts from the current time, so that the events numbered before a restart expire
+	ld.feed = &feed{
+		next:   uint64(time.Now().UnixMicro()),
+		subs:   make(map[chan Event]struct{}),
+		cancel: cancel,
+	}
+	go ld.feed.run(ctx, root, events)
+	return ld.feed, nil
+}
+
+// stopFeed stops the feed of the ledger, if it was started, closing the channels of the subscribers
+func (ld *Ledger) stopFeed() {
+	ld.feedLock.Lock()
+	defer ld.feedLock.Unlock()
+	if ld.feed == nil {
+		return
+	}
+	ld.feed.cancel()
+	ld.feed.reset()
+	ld.feed = nil
+}
+
+// run numbers the events and publishes them, until the context is done
+func (fd *feed) run(ctx context.Context, ld *Ledger, events <-chan Event) {
+	for {
+		for ev := range events {
+			fd.publish(ev)
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		// the feed fell behind the datastore: the events in between are lost
+		slog.Warn("ledger: feed: fell behind the changes, resetting")
+		fd.reset()
+		var err error
+		events, err = ld.Watch(ctx)
+		if err != nil {
+			slog.Error("ledger: feed: can't watch the changes", "error", err)
+			return
+		}
+	}
+}
+
+func (fd *feed) publish(ev Event) {
+	fd.lock.Lock()
+	defer fd.lock.Unlock()
+	ev.Seq = fd.next
+	fd.next++
+	fd.recent = append(fd.recent, ev)
+	if len(fd.recent) > feedSize {
+		fd.recent = fd.recent[len(fd.recent)-feedSize:]
+	}
+	for ch := range fd.subs {
+		select {
+		case ch <- ev:
+		default:
+			// the subscriber is too slow: drop it, so it can notice and resume
+			delete(fd.subs, ch)
+			close(ch)
+		}
+	}
+}
+
+// reset forgets the events, so they can't be resumed from, and closes the channels of the subscribers
+func (fd *feed) reset() {
+	fd.lock.Lock()
+	defer fd.lock.Unlock()
+	// skips a number, so that resuming after the last event expires as well
+	fd.next++
+	fd.recent = nil
+	for ch := range fd.subs {
+		delete(fd.subs, ch)
+		close(ch)
+	}
+}
+
+func (fd *feed) unsubscribe(ch chan Event) {
+	fd.lock.Lock()
+	defer fd.lock.Unlock()
+	if _, ok := fd.subs[ch]; !ok {
+		return // already gone
+	}
+	delete(fd.subs, ch)
+	close(ch)
+}
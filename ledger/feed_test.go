@@ -0,0 +1,95 @@
+package ledger
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gotestbootcamp/go-todo-app/model"
+	"github.com/gotestbootcamp/go-todo-app/store"
+)
+
+// receive returns the next event of the channel, failing if none arrives soon
+func receive(t *testing.T, events <-chan Event) Event {
+	t.Helper()
+	select {
+	case ev, ok := <-events:
+		require.True(t, ok, "channel closed")
+		return ev
+	case <-time.After(time.Second):
+		t.Fatal("no event")
+		return Event{}
+	}
+}
+
+func TestSubscribe(t *testing.T) {
+	ld, err := New(store.NewNotifier(newTestMemory(t)))
+	require.NoError(t, err)
+	defer ld.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events, err := ld.Subscribe(ctx, 0)
+	require.NoError(t, err)
+
+	require.NoError(t, ld.Set("1", model.New("foo")))
+	require.NoError(t, ld.Set("2", model.New("bar")))
+	require.NoError(t, ld.Delete("1"))
+	first := receive(t, events)
+	assert.Equal(t, store.EventCreated, first.Type)
+	assert.Equal(t, store.ID("1"), first.Item.ID)
+	second := receive(t, events)
+	assert.Equal(t, first.Seq+1, second.Seq)
+	third := receive(t, events)
+	assert.Equal(t, store.EventDeleted, third.Type)
+
+	// resuming replays the events after the given one
+	resumed, err := ld.Subscribe(ctx, first.Seq)
+	require.NoError(t, err)
+	assert.Equal(t, second, receive(t, resumed))
+	assert.Equal(t, third, receive(t, resumed))
+	resumed, err = ld.Subscribe(ctx, third.Seq)
+	require.NoError(t, err)
+	require.NoError(t, ld.Set("3", model.New("baz")))
+	assert.Equal(t, third.Seq+1, receive(t, resumed).Seq)
+
+	// the events before the feed started expired, and the future ones are unknown
+	_, err = ld.Subscribe(ctx, first.Seq-2)
+	assert.ErrorIs(t, err, ErrEventsExpired)
+	_, err = ld.Subscribe(ctx, third.Seq+10)
+	assert.ErrorIs(t, err, ErrEventsExpired)
+}
+
+func TestSubscribeSlow(t *testing.T) {
+	ld, err := New(store.NewNotifier(newTestMemory(t)))
+	require.NoError(t, err)
+	defer ld.Close()
+	events, err := ld.Subscribe(context.Background(), 0)
+	require.NoError(t, err)
+	for i := range feedBuffer + 1 {
+		require.NoError(t, ld.Set("1", model.New(strconv.Itoa(i))))
+		// the feed must keep up with the datastore
+		require.Eventually(t, func() bool {
+			ld.feed.lock.Lock()
+			defer ld.feed.lock.Unlock()
+			return len(ld.feed.recent) == i+1
+		}, time.Second, time.Millisecond)
+	}
+
+	// the slow subscribers are dropped, and can resume
+	var last Event
+	for ev := range events {
+		last = ev
+	}
+	resumed, err := ld.Subscribe(context.Background(), last.Seq)
+	require.NoError(t, err)
+	assert.Equal(t, last.Seq+1, receive(t, resumed).Seq)
+
+	// closing the ledger closes the channels
+	require.NoError(t, ld.Close())
+	for range resumed {
+	}
+}
@@ -0,0 +1,350 @@
+package ledger
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"maps"
+	"math"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	apiv1 "github.com/gotestbootcamp/go-todo-app/api/v1"
+	"github.com/gotestbootcamp/go-todo-app/store"
+	"github.com/gotestbootcamp/go-todo-app/task"
+)
+
+// fieldsID is the ID of the item holding the definitions of the custom fields of the lists
+var fieldsID = store.MetaID("fields")
+
+// fieldSortPrefix prefixes the names of the custom fields to sort the todos by, see ParseSort
+const fieldSortPrefix = "field."
+
+// FieldType is the type of the values of a custom field
+type FieldType string
+
+const (
+	// FieldString holds any text
+	FieldString FieldType = "string"
+	// FieldNumber holds a decimal number, e.g. "3" or "-0.5"
+	FieldNumber FieldType = "number"
+	// FieldEnum holds one of the values of the definition
+	FieldEnum FieldType = "enum"
+	// FieldDate holds a date, as YYYY-MM-DD
+	FieldDate FieldType = "date"
+	// FieldURL holds an absolute http or https URL
+	FieldURL FieldType = "url"
+)
+
+// ParseFieldType returns the type with the given name
+func ParseFieldType(s string) (FieldType, error) {
+	switch t := FieldType(s); t {
+	case FieldString, FieldNumber, FieldEnum, FieldDate, FieldURL:
+		return t, nil
+	}
+	return "", fmt.Errorf("invalid field type %q, want string, number, enum, date or url", s)
+}
+
+// ErrUnknownField is returned when the list defines no custom field with the name
+type ErrUnknownField struct {
+	List string
+	Name string
+}
+
+func (e ErrUnknownField) Error() string {
+	return fmt.Sprintf("list %q has no field %q", e.List, e.Name)
+}
+
+// FieldDef defines a custom field of the todos of a list, e.g. a "severity" enum of low, medium
+// and high. The todos of the list hold the values of the field in task.Task.Fields.
+type FieldDef struct {
+	Name string    `json:"name"`
+	Type FieldType `json:"type"`
+	// Values are the values of the enum fields, in order
+	Values []string `json:"values,omitempty"`
+}
+
+// ToAPIv1 converts the FieldDef in its API v1 representation
+func (d FieldDef) ToAPIv1() apiv1.FieldDef {
+	return apiv1.FieldDef{Name: d.Name, Type: string(d.Type), Values: d.Values}
+}
+
+// validate checks the definition has a valid name and type, and that the enums have distinct
+// values, and only they
+func (d FieldDef) validate() error {
+	if err := task.ValidateFieldName(d.Name); err != nil {
+		return err
+	}
+	if _, err := ParseFieldType(string(d.Type)); err != nil {
+		return err
+	}
+	if d.Type != FieldEnum {
+		if len(d.Values) > 0 {
+			return fmt.Errorf("values of field %q, which is no enum", d.Name)
+		}
+		return nil
+	}
+	if len(d.Values) == 0 {
+		return fmt.Errorf("missing values of enum field %q", d.Name)
+	}
+	for i, value := range d.Values {
+		if strings.TrimSpace(value) == "" || utf8.RuneCountInString(value) > task.MaxFieldValueLength {
+			return fmt.Errorf("invalid value %q of enum field %q", value, d.Name)
+		}
+		if slices.Contains(d.Values[:i], value) {
+			return fmt.Errorf("duplicate value %q of enum field %q", value, d.Name)
+		}
+	}
+	return nil
+}
+
+// Normalize returns the value of the field in its canonical form, as the todos hold it, e.g. "1.5"
+// for the number "1.50". Fails if the value isn't valid for the type of the field.
+func (d FieldDef) Normalize(value string) (string, error) {
+	invalid := func(want string) error {
+		return fmt.Errorf("invalid value %q of field %q, want %s", value, d.Name, want)
+	}
+	if value == "" || utf8.RuneCountInString(value) > task.MaxFieldValueLength {
+		return "", invalid(fmt.Sprintf("1 to %d characters", task.MaxFieldValueLength))
+	}
+	switch d.Type {
+	case FieldNumber:
+		n, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil || math.IsInf(n, 0) || math.IsNaN(n) {
+			return "", invalid("a number")
+		}
+		return strconv.FormatFloat(n, 'g', -1, 64), nil
+	case FieldEnum:
+		if !slices.Contains(d.Values, value) {
+			return "", invalid("one of " + strings.Join(d.Values, ", "))
+		}
+	case FieldDate:
+		if _, err := time.Parse(time.DateOnly, strings.TrimSpace(value)); err != nil {
+			return "", invalid("a date as YYYY-MM-DD")
+		}
+		return strings.TrimSpace(value), nil
+	case FieldURL:
+		u, err := url.Parse(strings.TrimSpace(value))
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return "", invalid("an http or https URL")
+		}
+		return u.String(), nil
+	}
+	return value, nil
+}
+
+// sortKey returns the sort key of the normalized value of the field: the numbers sort by value,
+// the enums by their order in the definition, and the rest as strings
+func (d FieldDef) sortKey(value string) string {
+	switch d.Type {
+	case FieldNumber:
+		n, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			break
+		}
+		// the bits of the positive floats sort as their values, the ones of the negatives in reverse
+		bits := math.Float64bits(n)
+		if n < 0 {
+			bits = ^bits
+		} else {
+			bits |= 1 << 63
+		}
+		return fmt.Sprintf("%016x", bits)
+	case FieldEnum:
+		if i := slices.Index(d.Values, value); i >= 0 {
+			return fmt.Sprintf("%04d", i)
+		}
+	}
+	return value
+}
+
+// listFields are the definitions of the custom fields of each list, sorted by name. Replaced as a
+// whole on changes, so that the copies outlive the lock.
+type listFields map[string][]FieldDef
+
+func (ld *Ledger) loadFields(blob store.Blob) error {
+	if err := json.Unmarshal(blob, &ld.fieldDefs); err != nil {
+		return fmt.Errorf("ledger: can't decode the fields of the lists: %w", err)
+	}
+	ld.fieldsStored = true
+	return nil
+}
+
+// def returns the definition of the field of the list holding the todo with the given ID.
+// False if the list defines no such field.
+func (lf listFields) def(id store.ID, name string) (FieldDef, bool) {
+	list, _ := store.SplitListID(id)
+	for _, def := range lf[list] {
+		if def.Name == name {
+			return def, true
+		}
+	}
+	return FieldDef{}, false
+}
+
+// Fields returns the definitions of the custom fields of the list, sorted by name.
+// Fails with ErrForbidden if the view may not see the list.
+func (ld *Ledger) Fields(list string) ([]FieldDef, error) {
+	if err := store.ValidateList(list); err != nil {
+		return nil, err
+	}
+	ld.lock.RLock()
+	defer ld.lock.RUnlock()
+	if !ld.canSee(list) {
+		return nil, fmt.Errorf("%w: %q is not a member of list %q", ErrForbidden, ld.user, list)
+	}
+	return slices.Clone(ld.fieldDefs[list]), nil
+}
+
+// DefineField defines the custom field of the list, or changes its definition. The values the
+// todos hold already are kept, even if no longer valid. Only the admins of the list may define its
+// fields, else fails with ErrForbidden.
+func (ld *Ledger) DefineField(list string, def FieldDef) (FieldDef, error) {
+	if err := store.ValidateList(list); err != nil {
+		return FieldDef{}, err
+	}
+	if err := def.validate(); err != nil {
+		return FieldDef{}, err
+	}
+	ld.lock.Lock()
+	defer ld.lock.Unlock()
+	if err := ld.checkManage(list); err != nil {
+		return FieldDef{}, err
+	}
+	defs := slices.DeleteFunc(slices.Clone(ld.fieldDefs[list]), func(other FieldDef) bool {
+		return other.Name == def.Name
+	})
+	if len(defs) >= task.MaxFields {
+		return FieldDef{}, fmt.Errorf("list %q has %d fields already", list, task.MaxFields)
+	}
+	if err := ld.saveFields(list, append(defs, def)); err != nil {
+		return FieldDef{}, err
+	}
+	slog.Info("ledger: DefineField: field defined", "list", list, "name", def.Name, "type", def.Type)
+	return def, nil
+}
+
+// DeleteField removes the definition of the custom field of the list. The todos keep their values
+// of the field, which can no longer be changed but to unset them. Only the admins of the list may
+// remove its fields, else fails with ErrForbidden. Fails with ErrUnknownField if the list has no
+// field with the name.
+func (ld *Ledger) DeleteField(list, name string) error {
+	if err := store.ValidateList(list); err != nil {
+		return err
+	}
+	ld.lock.Lock()
+	defer ld.lock.Unlock()
+	if err := ld.checkManage(list); err != nil {
+		return err
+	}
+	defs := ld.fieldDefs[list]
+	i := slices.IndexFunc(defs, func(def FieldDef) bool {
+		return def.Name == name
+	})
+	if i < 0 {
+		return ErrUnknownField{List: list, Name: name}
+	}
+	if err := ld.saveFields(list, slices.Delete(slices.Clone(defs), i, i+1)); err != nil {
+		return err
+	}
+	slog.Info("ledger: DeleteField: field removed", "list", list, "name", name)
+	return nil
+}
+
+// saveFields stores the given definitions of the fields of the list, sorted by name.
+// The caller must hold the lock.
+func (ld *Ledger) saveFields(list string, defs []FieldDef) error {
+	slices.SortFunc(defs, func(a, b FieldDef) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	all := maps.Clone(ld.fieldDefs)
+	if all == nil {
+		all = make(listFields)
+	}
+	if len(defs) == 0 {
+		delete(all, list)
+	} else {
+		all[list] = defs
+	}
+	blob, err := json.Marshal(all)
+	if err != nil {
+		return err
+	}
+	if ld.fieldsStored {
+		err = ld.storage().Save(fieldsID, blob)
+	} else {
+		err = ld.storage().Create(fieldsID, blob)
+	}
+	if err != nil {
+		return err
+	}
+	ld.fieldDefs = all
+	ld.fieldsStored = true
+	return nil
+}
+
+// patchFields sets the custom fields of the task with the given ID to the values, normalized as
+// the list of the todo defines them; the empty values unset the fields. Fails with ErrUnknownField
+// setting a field the list doesn't define.
+func (lf listFields) patch(id store.ID, tk *task.Task, values map[string]string) error {
+	fields := maps.Clone(tk.Fields)
+	if fields == nil {
+		fields = make(map[string]string)
+	}
+	for name, value := range values {
+		if value == "" {
+			delete(fields, name)
+			continue
+		}
+		def, ok := lf.def(id, name)
+		if !ok {
+			list, _ := store.SplitListID(id)
+			return ErrUnknownField{List: list, Name: name}
+		}
+		normalized, err := def.Normalize(value)
+		if err != nil {
+			return err
+		}
+		fields[name] = normalized
+	}
+	tk.Fields = fields
+	if len(fields) == 0 {
+		tk.Fields = nil
+	}
+	return nil
+}
+
+// matchFields tells whether the task with the given ID holds the values of the fields, normalized
+// as the list of the todo defines them
+func (lf listFields) match(id store.ID, tk task.Task, values map[string]string) bool {
+	for name, value := range values {
+		if def, ok := lf.def(id, name); ok {
+			normalized, err := def.Normalize(value)
+			if err != nil {
+				return false
+			}
+			value = normalized
+		}
+		if tk.Fields[name] != value {
+			return false
+		}
+	}
+	return true
+}
+
+// fieldSortKey returns the sort key of the custom field of the task with the given ID, see
+// FieldDef.sortKey. False if the todo has no value.
+func (lf listFields) sortKey(id store.ID, tk task.Task, name string) (string, bool) {
+	value, ok := tk.Fields[name]
+	if !ok {
+		return "", false
+	}
+	if def, ok := lf.def(id, name); ok {
+		return def.sortKey(value), true
+	}
+	return value, true
+}
@@ -0,0 +1,125 @@
+package ledger
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gotestbootcamp/go-todo-app/model"
+	"github.com/gotestbootcamp/go-todo-app/store"
+	"github.com/gotestbootcamp/go-todo-app/task"
+)
+
+func TestFieldDefNormalize(t *testing.T) {
+	for _, tc := range []struct {
+		def   FieldDef
+		value string
+		want  string
+	}{
+		{FieldDef{Name: "notes", Type: FieldString}, " as is ", " as is "},
+		{FieldDef{Name: "estimate", Type: FieldNumber}, "1.50", "1.5"},
+		{FieldDef{Name: "estimate", Type: FieldNumber}, "-2", "-2"},
+		{FieldDef{Name: "severity", Type: FieldEnum, Values: []string{"low", "high"}}, "high", "high"},
+		{FieldDef{Name: "release", Type: FieldDate}, "2024-03-01", "2024-03-01"},
+		{FieldDef{Name: "ticket", Type: FieldURL}, "https://example.com/T-1", "https://example.com/T-1"},
+	} {
+		got, err := tc.def.Normalize(tc.value)
+		require.NoError(t, err, tc.value)
+		assert.Equal(t, tc.want, got)
+	}
+
+	for _, tc := range []struct {
+		def   FieldDef
+		value string
+	}{
+		{FieldDef{Name: "notes", Type: FieldString}, ""},
+		{FieldDef{Name: "estimate", Type: FieldNumber}, "three"},
+		{FieldDef{Name: "estimate", Type: FieldNumber}, "Inf"},
+		{FieldDef{Name: "severity", Type: FieldEnum, Values: []string{"low", "high"}}, "urgent"},
+		{FieldDef{Name: "release", Type: FieldDate}, "01/03/2024"},
+		{FieldDef{Name: "ticket", Type: FieldURL}, "ftp://example.com"},
+		{FieldDef{Name: "ticket", Type: FieldURL}, "example.com"},
+	} {
+		_, err := tc.def.Normalize(tc.value)
+		assert.Error(t, err, tc.value)
+	}
+}
+
+func TestFields(t *testing.T) {
+	ld := newTestLedger(t, task.DefaultWorkflow())
+	alice, bob := ld.AsUser("alice", false), ld.AsUser("bob", false)
+	work := func(id string) store.ID {
+		return store.ListID("work", store.ID(id))
+	}
+	_, err := alice.AddMember("work", "alice", RoleListAdmin)
+	require.NoError(t, err)
+	_, err = alice.AddMember("work", "bob", RoleEditor)
+	require.NoError(t, err)
+
+	// only the admins of the list define its fields
+	severity := FieldDef{Name: "severity", Type: FieldEnum, Values: []string{"low", "medium", "high"}}
+	_, err = bob.DefineField("work", severity)
+	assert.ErrorIs(t, err, ErrForbidden)
+	_, err = alice.DefineField("work", severity)
+	require.NoError(t, err)
+	_, err = alice.DefineField("work", FieldDef{Name: "estimate", Type: FieldNumber})
+	require.NoError(t, err)
+	_, err = alice.DefineField("work", FieldDef{Name: "Size", Type: FieldNumber})
+	assert.Error(t, err)
+	_, err = alice.DefineField("work", FieldDef{Name: "size", Type: "color"})
+	assert.Error(t, err)
+	_, err = alice.DefineField("work", FieldDef{Name: "size", Type: FieldEnum})
+	assert.Error(t, err)
+	defs, err := bob.Fields("work")
+	require.NoError(t, err)
+	assert.Equal(t, []FieldDef{{Name: "estimate", Type: FieldNumber}, severity}, defs)
+
+	// the editors set the values, normalized
+	for id, estimate := range map[string]string{"1": "10", "2": "9.50", "3": "-1"} {
+		require.NoError(t, bob.Set(work(id), model.New("todo "+id)))
+		_, _, err = bob.PatchIf(work(id), Patch{Fields: &map[string]string{"estimate": estimate}}, -1)
+		require.NoError(t, err)
+	}
+	item, _, err := bob.PatchIf(work("2"), Patch{Fields: &map[string]string{"severity": "high"}}, -1)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"estimate": "9.5", "severity": "high"}, item.Task.Fields)
+	assert.Equal(t, map[string]string{"estimate": "9.5", "severity": "high"}, item.ToAPIv1().Todo.Fields)
+	_, _, err = bob.PatchIf(work("1"), Patch{Fields: &map[string]string{"severity": "urgent"}}, -1)
+	assert.Error(t, err)
+	_, _, err = bob.PatchIf(work("1"), Patch{Fields: &map[string]string{"team": "core"}}, -1)
+	assert.ErrorAs(t, err, &ErrUnknownField{})
+	require.NoError(t, bob.Set("4", model.New("outside of the list")))
+	_, _, err = bob.PatchIf("4", Patch{Fields: &map[string]string{"estimate": "1"}}, -1)
+	assert.ErrorAs(t, err, &ErrUnknownField{})
+
+	// the todos are listed by value, the numbers sorted as such
+	items, _, err := ld.List(Query{Sort: []SortKey{{Field: "field.estimate"}}})
+	require.NoError(t, err)
+	assert.Equal(t, []store.ID{work("3"), work("2"), work("1"), "4"}, ids(items))
+	items, _, err = ld.List(Query{Fields: map[string]string{"estimate": "9.500"}})
+	require.NoError(t, err)
+	assert.Equal(t, []store.ID{work("2")}, ids(items))
+	_, err = ParseSort("-field.estimate,title")
+	require.NoError(t, err)
+	_, err = ParseSort("field.")
+	assert.Error(t, err)
+
+	// removing a definition keeps the values, which can still be unset
+	assert.ErrorIs(t, bob.DeleteField("work", "severity"), ErrForbidden)
+	require.NoError(t, alice.DeleteField("work", "severity"))
+	assert.ErrorAs(t, alice.DeleteField("work", "severity"), &ErrUnknownField{})
+	item, _, err = bob.GetItem(work("2"))
+	require.NoError(t, err)
+	assert.Equal(t, "high", item.Task.Fields["severity"])
+	item, _, err = bob.PatchIf(work("2"), Patch{Fields: &map[string]string{"severity": "", "estimate": ""}}, -1)
+	require.NoError(t, err)
+	assert.Nil(t, item.Task.Fields)
+
+	// the definitions survive restarts
+	reloaded, err := New(ld.storer)
+	require.NoError(t, err)
+	defs, err = reloaded.Fields("work")
+	require.NoError(t, err)
+	assert.Equal(t, []FieldDef{{Name: "estimate", Type: FieldNumber}}, defs)
+}
@@ -0,0 +1,256 @@
+package ledger
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	apiv1 "github.com/gotestbootcamp/go-todo-app/api/v1"
+	"github.com/gotestbootcamp/go-todo-app/dates"
+	"github.com/gotestbootcamp/go-todo-app/search"
+	"github.com/gotestbootcamp/go-todo-app/store"
+)
+
+// filtersID is the ID of the item holding the saved filters
+var filtersID = store.MetaID("filters")
+
+// MaxFilterName is the maximum length of the names of the saved filters, in characters
+const MaxFilterName = 64
+
+// ErrUnknownFilter is returned when the view sees no saved filter with the name
+type ErrUnknownFilter struct {
+	Name string
+}
+
+func (e ErrUnknownFilter) Error() string {
+	return fmt.Sprintf("unknown filter %q", e.Name)
+}
+
+// ErrFilterExists is returned when saving a filter with the name of another one of the same user
+type ErrFilterExists struct {
+	Name string
+}
+
+func (e ErrFilterExists) Error() string {
+	return fmt.Sprintf("filter %q already exists", e.Name)
+}
+
+// SavedFilter is a search query saved under a name, e.g. "This week" for `status:open due<"next monday"`,
+// which lists the todos matching it like a list of its own, see FilterTodos. The relative dates of the
+// query are relative to when the todos are listed.
+type SavedFilter struct {
+	Name  string `json:"name"`
+	Query string `json:"query"`
+	// Owner is the user who saved the filter, who may change it. Empty if saved by no user.
+	Owner string `json:"owner,omitempty"`
+	// Shared tells whether the other users see the filter as well
+	Shared bool `json:"shared,omitempty"`
+}
+
+// ToAPIv1 converts a SavedFilter on its API layer corresponding object
+func (f SavedFilter) ToAPIv1() apiv1.Filter {
+	return apiv1.Filter{
+		Name:   f.Name,
+		Query:  f.Query,
+		Owner:  f.Owner,
+		Shared: f.Shared,
+	}
+}
+
+func (ld *Ledger) loadFilters(blob store.Blob) error {
+	if err := json.Unmarshal(blob, &ld.filters); err != nil {
+		return fmt.Errorf("ledger: can't decode the saved filters: %w", err)
+	}
+	ld.filtersStored = true
+	return nil
+}
+
+// dates returns the parser of the dates of the queries, relative to now
+func (ld *Ledger) dates() dates.Parser {
+	return dates.Parser{Now: ld.now, Location: time.Local, WeekStart: dates.WeekStartFromEnv()}
+}
+
+// validateFilter checks the filter has a name, at most MaxFilterName characters long without
+// slashes, and a query search.Parse parses
+func (ld *Ledger) validateFilter(f SavedFilter) error {
+	switch {
+	case strings.TrimSpace(f.Name) == "":
+		return fmt.Errorf("missing filter name")
+	case utf8.RuneCountInString(f.Name) > MaxFilterName:
+		return fmt.Errorf("filter name longer than %d characters", MaxFilterName)
+	case strings.ContainsFunc(f.Name, func(r rune) bool { return r == '/' || unicode.IsControl(r) }):
+		return fmt.Errorf("invalid filter name %q", f.Name)
+	case strings.TrimSpace(f.Query) == "":
+		return fmt.Errorf("missing query of filter %q", f.Name)
+	}
+	_, err := search.Parse(f.Query, ld.dates())
+	return err
+}
+
+// visible returns true if the view sees the filter: its own ones, and the ones shared
+func (ld *Ledger) visible(f SavedFilter) bool {
+	return f.Owner == ld.user || f.Shared
+}
+
+// findFilter returns the position of the filter with the name the view sees, its own one if the
+// name is shared by several filters. -1 if there's none. The caller must hold the lock.
+func (ld *Ledger) findFilter(name string) int {
+	found := -1
+	for i, f := range ld.filters {
+		if f.Name != name || !ld.visible(f) {
+			continue
+		}
+		if f.Owner == ld.user {
+			return i
+		}
+		if found < 0 {
+			found = i
+		}
+	}
+	return found
+}
+
+// saveFilters stores the filters, sorted by name and owner. The caller must hold the lock.
+func (ld *Ledger) saveFilters(filters []SavedFilter) error {
+	slices.SortFunc(filters, func(a, b SavedFilter) int {
+		if c := strings.Compare(a.Name, b.Name); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Owner, b.Owner)
+	})
+	blob, err := json.Marshal(filters)
+	if err != nil {
+		return err
+	}
+	if ld.filtersStored {
+		err = ld.storage().Save(filtersID, blob)
+	} else {
+		err = ld.storage().Create(filtersID, blob)
+	}
+	if err != nil {
+		return err
+	}
+	ld.filters = filters
+	ld.filtersStored = true
+	return nil
+}
+
+// Filters returns the saved filters the view sees, its own ones and the ones shared by the other
+// users, sorted by name
+func (ld *Ledger) Filters() []SavedFilter {
+	ld.lock.RLock()
+	defer ld.lock.RUnlock()
+	res := make([]SavedFilter, 0, len(ld.filters))
+	for _, f := range ld.filters {
+		if ld.visible(f) {
+			res = append(res, f)
+		}
+	}
+	return res
+}
+
+// GetFilter returns the saved filter with the name, the one of the user of the view if the other
+// users share some with the same name. Fails with ErrUnknownFilter if the view sees none.
+func (ld *Ledger) GetFilter(name string) (SavedFilter, error) {
+	ld.lock.RLock()
+	defer ld.lock.RUnlock()
+	i := ld.findFilter(name)
+	if i < 0 {
+		return SavedFilter{}, ErrUnknownFilter{Name: name}
+	}
+	return ld.filters[i], nil
+}
+
+// CreateFilter saves the filter, owned by the user of the view. Fails with ErrFilterExists if the
+// user has a filter with the same name already.
+func (ld *Ledger) CreateFilter(f SavedFilter) (SavedFilter, error) {
+	f.Owner = ld.user
+	if err := ld.validateFilter(f); err != nil {
+		return SavedFilter{}, err
+	}
+	ld.lock.Lock()
+	defer ld.lock.Unlock()
+	if i := ld.findFilter(f.Name); i >= 0 && ld.filters[i].Owner == f.Owner {
+		return SavedFilter{}, ErrFilterExists{Name: f.Name}
+	}
+	if err := ld.saveFilters(append(slices.Clone(ld.filters), f)); err != nil {
+		return SavedFilter{}, err
+	}
+	slog.Info("ledger: CreateFilter: filter saved", "name", f.Name, "owner", f.Owner, "shared", f.Shared)
+	return f, nil
+}
+
+// UpdateFilter replaces the saved filter with the name, renaming it if the name of f differs.
+// Only the owner of the filter, or an admin, may change it, else fails with ErrForbidden.
+// Fails with ErrUnknownFilter if the view sees no filter with the name, and with ErrFilterExists
+// if the owner has another filter with the new name.
+func (ld *Ledger) UpdateFilter(name string, f SavedFilter) (SavedFilter, error) {
+	ld.lock.Lock()
+	defer ld.lock.Unlock()
+	i, err := ld.ownFilter(name)
+	if err != nil {
+		return SavedFilter{}, err
+	}
+	f.Owner = ld.filters[i].Owner
+	if err := ld.validateFilter(f); err != nil {
+		return SavedFilter{}, err
+	}
+	filters := slices.Clone(ld.filters)
+	if f.Name != name && slices.ContainsFunc(filters, func(other SavedFilter) bool {
+		return other.Name == f.Name && other.Owner == f.Owner
+	}) {
+		return SavedFilter{}, ErrFilterExists{Name: f.Name}
+	}
+	filters[i] = f
+	if err := ld.saveFilters(filters); err != nil {
+		return SavedFilter{}, err
+	}
+	slog.Info("ledger: UpdateFilter: filter saved", "name", name, "new", f.Name, "owner", f.Owner, "shared", f.Shared)
+	return f, nil
+}
+
+// DeleteFilter removes the saved filter with the name. Only the owner of the filter, or an admin,
+// may remove it, else fails with ErrForbidden. Fails with ErrUnknownFilter if the view sees no
+// filter with the name.
+func (ld *Ledger) DeleteFilter(name string) error {
+	ld.lock.Lock()
+	defer ld.lock.Unlock()
+	i, err := ld.ownFilter(name)
+	if err != nil {
+		return err
+	}
+	owner := ld.filters[i].Owner
+	if err := ld.saveFilters(slices.Delete(slices.Clone(ld.filters), i, i+1)); err != nil {
+		return err
+	}
+	slog.Info("ledger: DeleteFilter: filter removed", "name", name, "owner", owner)
+	return nil
+}
+
+// ownFilter returns the position of the filter with the name the view may change. The caller
+// must hold the lock.
+func (ld *Ledger) ownFilter(name string) (int, error) {
+	i := ld.findFilter(name)
+	if i < 0 {
+		return -1, ErrUnknownFilter{Name: name}
+	}
+	if owner := ld.filters[i].Owner; owner != ld.user && ld.restricted() {
+		return -1, fmt.Errorf("%w: filter %q belongs to %q", ErrForbidden, name, owner)
+	}
+	return i, nil
+}
+
+// FilterTodos returns the todos matching the query of the saved filter with the name, like Search.
+// Fails with ErrUnknownFilter if the view sees no filter with the name.
+func (ld *Ledger) FilterTodos(name string) (Items, error) {
+	f, err := ld.GetFilter(name)
+	if err != nil {
+		return nil, err
+	}
+	return ld.Search(f.Query)
+}
@@ -0,0 +1,75 @@
+package ledger
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gotestbootcamp/go-todo-app/model"
+	"github.com/gotestbootcamp/go-todo-app/search"
+	"github.com/gotestbootcamp/go-todo-app/store"
+	"github.com/gotestbootcamp/go-todo-app/task"
+)
+
+func TestSavedFilters(t *testing.T) {
+	st := newTestMemory(t)
+	ld, err := NewWithWorkflow(st, task.DefaultWorkflow())
+	require.NoError(t, err)
+	alice, bob := ld.AsUser("alice", false), ld.AsUser("bob", false)
+	require.NoError(t, alice.Set("1", model.New("report")))
+	require.NoError(t, bob.Set("2", model.New("laundry")))
+	_, err = bob.Transition("2", task.Assigned)
+	require.NoError(t, err)
+
+	f, err := alice.CreateFilter(SavedFilter{Name: "Mine", Query: "owner:alice", Owner: "bob"})
+	require.NoError(t, err)
+	assert.Equal(t, SavedFilter{Name: "Mine", Query: "owner:alice", Owner: "alice"}, f)
+	_, err = bob.CreateFilter(SavedFilter{Name: "Mine", Query: "owner:bob"})
+	require.NoError(t, err)
+	_, err = bob.CreateFilter(SavedFilter{Name: "Waiting on others", Query: "status:assigned", Shared: true})
+	require.NoError(t, err)
+	_, err = bob.CreateFilter(SavedFilter{Name: "Mine", Query: "owner:bob"})
+	assert.ErrorIs(t, err, ErrFilterExists{Name: "Mine"})
+	_, err = alice.CreateFilter(SavedFilter{Name: "Broken", Query: "size:large"})
+	var syntaxErr search.SyntaxError
+	assert.ErrorAs(t, err, &syntaxErr)
+	_, err = alice.CreateFilter(SavedFilter{Name: "a/b", Query: "report"})
+	assert.Error(t, err)
+
+	// the users see their own filters, and the shared ones
+	assert.Equal(t, []SavedFilter{
+		{Name: "Mine", Query: "owner:alice", Owner: "alice"},
+		{Name: "Waiting on others", Query: "status:assigned", Owner: "bob", Shared: true},
+	}, alice.Filters())
+	assert.Len(t, bob.Filters(), 2)
+	items, err := alice.FilterTodos("Mine")
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	assert.Equal(t, store.ID("1"), items[0].ID)
+	items, err = alice.FilterTodos("Waiting on others")
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	assert.Equal(t, store.ID("2"), items[0].ID)
+	_, err = ld.AsUser("carol", false).FilterTodos("Mine")
+	assert.ErrorIs(t, err, ErrUnknownFilter{Name: "Mine"})
+
+	// only their owner changes them
+	_, err = alice.UpdateFilter("Waiting on others", SavedFilter{Name: "Waiting", Query: "status:pending"})
+	assert.ErrorIs(t, err, ErrForbidden)
+	assert.ErrorIs(t, alice.DeleteFilter("Waiting on others"), ErrForbidden)
+	f, err = bob.UpdateFilter("Waiting on others", SavedFilter{Name: "Waiting", Query: "status:assigned -tag:someday"})
+	require.NoError(t, err)
+	assert.Equal(t, SavedFilter{Name: "Waiting", Query: "status:assigned -tag:someday", Owner: "bob"}, f)
+	_, err = bob.UpdateFilter("Waiting", SavedFilter{Name: "Mine", Query: "report"})
+	assert.ErrorIs(t, err, ErrFilterExists{Name: "Mine"})
+	assert.Len(t, alice.Filters(), 1, "no longer shared")
+	require.NoError(t, bob.DeleteFilter("Mine"))
+	assert.ErrorIs(t, bob.DeleteFilter("Mine"), ErrUnknownFilter{Name: "Mine"})
+
+	// the filters are stored
+	ld, err = New(st)
+	require.NoError(t, err)
+	assert.Equal(t, []SavedFilter{{Name: "Mine", Query: "owner:alice", Owner: "alice"}}, ld.AsUser("alice", false).Filters())
+	assert.Len(t, ld.AsUser("bob", false).Filters(), 1)
+}
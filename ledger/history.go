@@ -0,0 +1,381 @@
+package ledger
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	apiv1 "github.com/gotestbootcamp/go-todo-app/api/v1"
+	"github.com/gotestbootcamp/go-todo-app/store"
+	"github.com/gotestbootcamp/go-todo-app/task"
+)
+
+// AnyRevision makes SetIf and DeleteIf skip the check of the revision
+const AnyRevision = -1
+
+// maxRevisions bounds the revisions kept in the history of each todo: the oldest ones are forgotten
+const maxRevisions = 100
+
+// historyPrefix marks the IDs of the items holding the revisions of the todos
+var historyPrefix = string(store.MetaID("history/"))
+
+func historyID(id store.ID, rev int) store.ID {
+	return store.ID(historyPrefix + string(id) + "/" + strconv.Itoa(rev))
+}
+
+func isHistoryID(id store.ID) bool {
+	return strings.HasPrefix(string(id), historyPrefix)
+}
+
+// ErrNoRevision is returned when a todo has no such revision
+type ErrNoRevision struct {
+	ID  store.ID
+	Rev int
+}
+
+func (e ErrNoRevision) Error() string {
+	return fmt.Sprintf("object %v has no revision %d", e.ID, e.Rev)
+}
+
+// Change is the change of a field of a todo. The values are JSON encoded like in the stored tasks:
+// From is empty if the field was not set before, To if it was unset.
+type Change struct {
+	Field string          `json:"field"`
+	From  json.RawMessage `json:"from,omitempty"`
+	To    json.RawMessage `json:"to,omitempty"`
+}
+
+// Revision records a mutation of a todo
+type Revision struct {
+	// Rev numbers the revisions of the todo, starting from 1, and keeps increasing when a todo is
+	// created again with the ID of a removed one
+	Rev int `json:"rev"`
+	// Time is when the mutation was made
+	Time time.Time `json:"time"`
+	// Actor is who made the mutation. Empty if unknown.
+	Actor   string   `json:"actor,omitempty"`
+	Changes []Change `json:"changes"`
+	// State is the encoded todo after the mutation. Empty if the todo was removed.
+	State json.RawMessage `json:"state,omitempty"`
+}
+
+// created tells whether the revision created the todo: the title of the todos is never unset
+func (rv Revision) created() bool {
+	return slices.ContainsFunc(rv.Changes, func(ch Change) bool {
+		return ch.Field == "title" && len(ch.From) == 0
+	})
+}
+
+// ToAPIv1 converts the Revision in its API v1 representation, without the state
+func (rv Revision) ToAPIv1() apiv1.Revision {
+	changes := make([]apiv1.Change, 0, len(rv.Changes))
+	for _, ch := range rv.Changes {
+		changes = append(changes, apiv1.Change(ch))
+	}
+	return apiv1.Revision{
+		Rev:     rv.Rev,
+		Time:    rv.Time,
+		Actor:   rv.Actor,
+		Changes: changes,
+	}
+}
+
+func (ld *Ledger) loadRevision(id store.ID, blob store.Blob) error {
+	var rev Revision
+	if err := json.Unmarshal(blob, &rev); err != nil {
+		return fmt.Errorf("ledger: can't decode the revision %v: %w", id, err)
+	}
+	rest := strings.TrimPrefix(string(id), historyPrefix)
+	todoID := store.ID(rest[:strings.LastIndex(rest, "/")])
+	ld.history[todoID] = append(ld.history[todoID], rev)
+	return nil
+}
+
+// reloadRevision loads again the revision with the given ID, replacing the one with the same number
+// if any. The caller must hold the lock.
+func (ld *Ledger) reloadRevision(id store.ID, blob store.Blob) error {
+	var rev Revision
+	if err := json.Unmarshal(blob, &rev); err != nil {
+		return fmt.Errorf("ledger: can't decode the revision %v: %w", id, err)
+	}
+	rest := strings.TrimPrefix(string(id), historyPrefix)
+	todoID := store.ID(rest[:strings.LastIndex(rest, "/")])
+	revs := slices.DeleteFunc(slices.Clone(ld.history[todoID]), func(other Revision) bool {
+		return other.Rev == rev.Rev
+	})
+	revs = append(revs, rev)
+	sort.Slice(revs, func(i, j int) bool {
+		return revs[i].Rev < revs[j].Rev
+	})
+	ld.history[todoID] = revs
+	return nil
+}
+
+func (ld *Ledger) sortHistory() {
+	for _, revs := range ld.history {
+		sort.Slice(revs, func(i, j int) bool {
+			return revs[i].Rev < revs[j].Rev
+		})
+	}
+}
+
+// taskFields returns the encoded fields of the encoded task, but the ones changing on every
+// mutation. Nil blobs have no fields.
+func taskFields(blob store.Blob) (map[string]json.RawMessage, error) {
+	fields := make(map[string]json.RawMessage)
+	if blob == nil {
+		return fields, nil
+	}
+	tk, err := task.Unmarshal(blob)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(tk)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	delete(fields, "schema")
+	delete(fields, "updated")
+	return fields, nil
+}
+
+// diff returns the changes of the fields from the prev to the next encoded task, sorted by field
+func diff(prev, next store.Blob) ([]Change, error) {
+	from, err := taskFields(prev)
+	if err != nil {
+		return nil, err
+	}
+	to, err := taskFields(next)
+	if err != nil {
+		return nil, err
+	}
+	var changes []Change
+	for field, val := range from {
+		if !bytes.Equal(val, to[field]) {
+			changes = append(changes, Change{Field: field, From: val, To: to[field]})
+		}
+	}
+	for field, val := range to {
+		if _, ok := from[field]; !ok {
+			changes = append(changes, Change{Field: field, To: val})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Field < changes[j].Field
+	})
+	return changes, nil
+}
+
+// creator is implemented by both store.Storage and store.Tx
+type creator interface {
+	Create(store.ID, store.Blob) error
+}
+
+// saver is implemented by both store.Storage and store.Tx, like creator
+type saver interface {
+	creator
+	Save(store.ID, store.Blob) error
+}
+
+// writer is implemented by both store.Storage and store.Tx, like saver
+type writer interface {
+	saver
+	Delete(store.ID) error
+}
+
+// record appends to the history of the todo its mutation from prev to blob, storing the revision with w,
+// and logs it in the changelog. Nil blobs record the creation and the removal of the todo. The mutations changing nothing are not
+// recorded. A todo created with the ID of a removed one starts a new history, and the revisions beyond
+// maxRevisions are forgotten, the oldest first. The caller must hold the lock.
+func (ld *Ledger) record(w writer, id store.ID, prev, blob store.Blob) error {
+	return ld.recordRevision(w, id, prev, blob, true)
+}
+
+// recordRestored is like record, but the todo recreated keeps its history, e.g. when undoing its
+// removal. The caller must hold the lock.
+func (ld *Ledger) recordRestored(w writer, id store.ID, prev, blob store.Blob) error {
+	return ld.recordRevision(w, id, prev, blob, false)
+}
+
+func (ld *Ledger) recordRevision(w writer, id store.ID, prev, blob store.Blob, renew bool) error {
+	changes, err := diff(prev, blob)
+	if err != nil {
+		return err
+	}
+	if len(changes) == 0 && prev != nil && blob != nil {
+		return nil
+	}
+	rev := Revision{
+		Rev:     ld.revision(id) + 1,
+		Time:    ld.now(),
+		Actor:   ld.actor,
+		Changes: changes,
+		State:   json.RawMessage(bytes.TrimSpace(blob)),
+	}
+	data, err := json.Marshal(rev)
+	if err != nil {
+		return err
+	}
+	if err := w.Create(historyID(id, rev.Rev), data); err != nil {
+		return err
+	}
+	if err := ld.logChange(w, id, rev, prev); err != nil {
+		return err
+	}
+	revs := ld.history[id]
+	forget := 0
+	if renew && prev == nil && blob != nil {
+		forget = len(revs)
+	} else if len(revs) >= maxRevisions {
+		forget = len(revs) - maxRevisions + 1
+	}
+	for _, old := range revs[:forget] {
+		if err := w.Delete(historyID(id, old.Rev)); err != nil {
+			return err
+		}
+	}
+	// clipped, for the histories the transactions restore on failure to stay as they were
+	ld.history[id] = append(slices.Clip(revs[forget:]), rev)
+	ld.steps = append(ld.steps, step{ID: id, Rev: rev.Rev})
+	return nil
+}
+
+// revision returns the current revision of the todo, zero if none was recorded. The caller must hold
+// the lock.
+func (ld *Ledger) revision(id store.ID) int {
+	if revs := ld.history[id]; len(revs) > 0 {
+		return revs[len(revs)-1].Rev
+	}
+	return 0
+}
+
+// revisionAt returns the index of the revision in the history of the todo, -1 if the history doesn't
+// keep it. The caller must hold the lock.
+func (ld *Ledger) revisionAt(id store.ID, rev int) int {
+	i, ok := slices.BinarySearchFunc(ld.history[id], rev, func(rv Revision, rev int) int {
+		return cmp.Compare(rv.Rev, rev)
+	})
+	if !ok {
+		return -1
+	}
+	return i
+}
+
+// History returns the revisions of the todo, oldest first.
+// Fails with store.ErrNotFound if the todo doesn't exist, and never did.
+func (ld *Ledger) History(id store.ID) ([]Revision, error) {
+	ld.lock.RLock()
+	defer ld.lock.RUnlock()
+	revs, ok := ld.history[id]
+	if !ok {
+		if _, ok := ld.blobs[id]; !ok {
+			return nil, store.ErrNotFound{ID: id}
+		}
+	}
+	if err := ld.checkRead(id); err != nil {
+		return nil, err
+	}
+	return slices.Clone(revs), nil
+}
+
+// Revision returns the current revision of the todo, i.e. the number of its recorded mutations.
+// Zero if none was recorded, like for the todos stored before the history was tracked.
+// Fails with store.ErrNotFound if the todo doesn't exist.
+func (ld *Ledger) Revision(id store.ID) (int, error) {
+	ld.lock.RLock()
+	defer ld.lock.RUnlock()
+	if _, ok := ld.blobs[id]; !ok {
+		return 0, store.ErrNotFound{ID: id}
+	}
+	if err := ld.checkRead(id); err != nil {
+		return 0, err
+	}
+	return ld.revision(id), nil
+}
+
+// checkRevision fails with store.ErrConflict if the current revision of the todo isn't the expected one.
+// A todo which doesn't exist has revision zero. The caller must hold the lock.
+func (ld *Ledger) checkRevision(id store.ID, expected int) error {
+	if expected == AnyRevision {
+		return nil
+	}
+	actual := 0
+	if _, ok := ld.blobs[id]; ok {
+		actual = ld.revision(id)
+	}
+	if actual != expected {
+		return store.ErrConflict{ID: id, Expected: store.Revision(expected), Actual: store.Revision(actual)}
+	}
+	return nil
+}
+
+// Revert restores the todo as it was after the given revision, and returns the restored Item.
+// The todo is recreated if it was removed. The revert is a mutation, recorded in the history as well.
+// The workflow is not enforced: the status is restored as well.
+// Fails with ErrNoRevision if the todo has no such revision, or not anymore, or if it removed the todo.
+func (ld *Ledger) Revert(id store.ID, rev int) (Item, error) {
+	ld.lock.Lock()
+	defer ld.unlock()
+	i := ld.revisionAt(id, rev)
+	if i < 0 || len(ld.history[id][i].State) == 0 {
+		return Item{}, ErrNoRevision{ID: id, Rev: rev}
+	}
+	if err := ld.restore(ld.storage(), id, ld.history[id][i].State); err != nil {
+		return Item{}, err
+	}
+	slog.Info("ledger: Revert: object reverted", "id", id, "revision", rev)
+	return newItem(id, ld.blobs[id])
+}
+
+// restore sets the todo to the encoded state, as updated now, or removes it if the state is empty,
+// writing it with w and recording the change in the history. The caller must hold the lock.
+func (ld *Ledger) restore(w writer, id store.ID, state json.RawMessage) error {
+	prev, found := ld.blobs[id]
+	owned := prev
+	if !found {
+		// the todo is recreated as the state tells
+		owned = store.Blob(state)
+	}
+	if err := ld.checkOwner(id, owned); err != nil {
+		return err
+	}
+	if len(state) == 0 {
+		if !found {
+			return nil
+		}
+		if err := w.Delete(id); err != nil {
+			return err
+		}
+		delete(ld.blobs, id)
+		return ld.recordRestored(w, id, prev, nil)
+	}
+	tk, err := task.Unmarshal(state)
+	if err != nil {
+		return err
+	}
+	tk.Updated = ld.now()
+	blob, err := task.Marshal(tk)
+	if err != nil {
+		return err
+	}
+	if found {
+		err = w.Save(id, blob)
+	} else {
+		err = w.Create(id, blob)
+	}
+	if err != nil {
+		return err
+	}
+	ld.blobs[id] = blob
+	return ld.recordRestored(w, id, prev, blob)
+}
@@ -0,0 +1,177 @@
+package ledger
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gotestbootcamp/go-todo-app/model"
+	"github.com/gotestbootcamp/go-todo-app/store"
+	"github.com/gotestbootcamp/go-todo-app/task"
+)
+
+func TestHistory(t *testing.T) {
+	st := newTestMemory(t)
+	ld, err := New(st)
+	require.NoError(t, err)
+	_, err = ld.History("1")
+	assert.ErrorIs(t, err, store.ErrNotFound{ID: "1"})
+
+	require.NoError(t, ld.Set("1", model.New("foo")))
+	_, err = ld.As("alice").TagTodo("1", "work")
+	require.NoError(t, err)
+	_, err = ld.As("bob").SetPriority("1", task.PriorityHigh)
+	require.NoError(t, err)
+	// changing nothing records nothing
+	_, err = ld.SetPriority("1", task.PriorityHigh)
+	require.NoError(t, err)
+	require.NoError(t, ld.Delete("1"))
+
+	revs, err := ld.History("1")
+	require.NoError(t, err)
+	require.Len(t, revs, 4)
+	for i, rev := range revs {
+		assert.Equal(t, i+1, rev.Rev)
+	}
+	assert.Empty(t, revs[0].Actor)
+	assert.Equal(t, "alice", revs[1].Actor)
+	assert.Equal(t, []Change{{Field: "tags", To: json.RawMessage(`["work"]`)}}, revs[1].Changes)
+	assert.Equal(t, "bob", revs[2].Actor)
+	assert.Equal(t, []Change{{Field: "priority", To: json.RawMessage(`3`)}}, revs[2].Changes)
+	assert.Empty(t, revs[3].State)
+
+	// reverting recreates the removed todo
+	_, err = ld.Revert("1", 4)
+	assert.ErrorIs(t, err, ErrNoRevision{ID: "1", Rev: 4})
+	_, err = ld.Revert("1", 9)
+	assert.ErrorIs(t, err, ErrNoRevision{ID: "1", Rev: 9})
+	item, err := ld.As("carol").Revert("1", 2)
+	require.NoError(t, err)
+	assert.Equal(t, "foo", item.Todo.Title)
+	assert.Equal(t, []string{"work"}, item.Task.Tags)
+	assert.Equal(t, task.PriorityNone, item.Task.Priority)
+
+	// the history survives the restarts
+	ld, err = New(st)
+	require.NoError(t, err)
+	revs, err = ld.History("1")
+	require.NoError(t, err)
+	require.Len(t, revs, 5)
+	assert.Equal(t, "carol", revs[4].Actor)
+	assert.NotEmpty(t, revs[4].State)
+	_, err = ld.Get("1")
+	require.NoError(t, err)
+}
+
+func TestHistoryRecreated(t *testing.T) {
+	st := newTestMemory(t)
+	ld, err := New(st)
+	require.NoError(t, err)
+	require.NoError(t, ld.Set("1", model.New("foo")))
+	require.NoError(t, ld.Set("1", model.New("bar")))
+	require.NoError(t, ld.Delete("1"))
+
+	// the todo created with the ID of a removed one has a history of its own, its revisions
+	// numbered after the ones of the removed todo
+	require.NoError(t, ld.Set("1", model.New("baz")))
+	revs, err := ld.History("1")
+	require.NoError(t, err)
+	require.Len(t, revs, 1)
+	assert.Equal(t, 4, revs[0].Rev)
+	rev, err := ld.Revision("1")
+	require.NoError(t, err)
+	assert.Equal(t, 4, rev)
+	_, err = ld.Revert("1", 1)
+	assert.ErrorIs(t, err, ErrNoRevision{ID: "1", Rev: 1})
+	_, err = st.Load(historyID("1", 1))
+	assert.ErrorIs(t, err, store.ErrNotFound{ID: historyID("1", 1)})
+	ld, err = New(st)
+	require.NoError(t, err)
+	revs, err = ld.History("1")
+	require.NoError(t, err)
+	assert.Len(t, revs, 1)
+
+	// undoing the creation removes the todo again
+	_, err = ld.Undo()
+	require.NoError(t, err)
+	_, err = ld.Get("1")
+	assert.ErrorIs(t, err, store.ErrNotFound{ID: "1"})
+}
+
+func TestHistoryRetention(t *testing.T) {
+	st := newTestMemory(t)
+	ld, err := New(st)
+	require.NoError(t, err)
+	for i := range maxRevisions + 5 {
+		require.NoError(t, ld.Set("1", model.New(fmt.Sprintf("todo %d", i))))
+	}
+
+	revs, err := ld.History("1")
+	require.NoError(t, err)
+	require.Len(t, revs, maxRevisions)
+	assert.Equal(t, 6, revs[0].Rev)
+	assert.Equal(t, maxRevisions+5, revs[maxRevisions-1].Rev)
+	_, err = st.Load(historyID("1", 5))
+	assert.ErrorIs(t, err, store.ErrNotFound{ID: historyID("1", 5)})
+	item, err := ld.Revert("1", 6)
+	require.NoError(t, err)
+	assert.Equal(t, "todo 5", item.Todo.Title)
+}
+
+func TestHistoryRetag(t *testing.T) {
+	ld := newTestLedger(t, task.DefaultWorkflow())
+	require.NoError(t, ld.Set("1", model.New("foo")))
+	_, err := ld.TagTodo("1", "work")
+	require.NoError(t, err)
+	require.NoError(t, ld.As("alice").RenameTag("work", "job"))
+
+	revs, err := ld.History("1")
+	require.NoError(t, err)
+	require.Len(t, revs, 3)
+	assert.Equal(t, "alice", revs[2].Actor)
+	assert.Equal(t, []Change{{Field: "tags", From: json.RawMessage(`["work"]`), To: json.RawMessage(`["job"]`)}}, revs[2].Changes)
+}
+
+func TestSetIfDeleteIf(t *testing.T) {
+	ld, err := New(newTestMemory(t))
+	require.NoError(t, err)
+	_, err = ld.Revision("1")
+	assert.ErrorIs(t, err, store.ErrNotFound{ID: "1"})
+
+	_, rev, err := ld.SetIf("1", model.New("foo"), 0)
+	require.NoError(t, err)
+	assert.Equal(t, 1, rev)
+	_, _, err = ld.SetIf("1", model.New("bar"), 0)
+	assert.ErrorIs(t, err, store.ErrConflict{ID: "1", Expected: 0, Actual: 1})
+
+	todo := model.New("bar")
+	_, rev, err = ld.SetIf("1", todo, 1)
+	require.NoError(t, err)
+	assert.Equal(t, 2, rev)
+	// changing nothing keeps the revision
+	_, rev, err = ld.SetIf("1", todo, 2)
+	require.NoError(t, err)
+	assert.Equal(t, 2, rev)
+	_, rev, err = ld.SetIf("1", model.New("baz"), AnyRevision)
+	require.NoError(t, err)
+	assert.Equal(t, 3, rev)
+	rev, err = ld.Revision("1")
+	require.NoError(t, err)
+	assert.Equal(t, 3, rev)
+	item, rev, err := ld.GetItem("1")
+	require.NoError(t, err)
+	assert.Equal(t, "baz", item.Task.Title)
+	assert.Equal(t, 3, rev)
+
+	err = ld.DeleteIf("1", 2)
+	assert.ErrorIs(t, err, store.ErrConflict{ID: "1", Expected: 2, Actual: 3})
+	got, err := ld.Get("1")
+	require.NoError(t, err)
+	assert.Equal(t, "baz", got.Title)
+	require.NoError(t, ld.DeleteIf("1", 3))
+	_, err = ld.Get("1")
+	assert.ErrorIs(t, err, store.ErrNotFound{ID: "1"})
+}
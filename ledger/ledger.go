@@ -1,33 +1,163 @@
 package ledger
 
 import (
+	"context"
 	"errors"
-	"log"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
 
 	apiv1 "github.com/gotestbootcamp/go-todo-app/api/v1"
+	"github.com/gotestbootcamp/go-todo-app/index"
 	"github.com/gotestbootcamp/go-todo-app/model"
 	"github.com/gotestbootcamp/go-todo-app/store"
+	"github.com/gotestbootcamp/go-todo-app/task"
+	"github.com/gotestbootcamp/go-todo-app/tracing"
 )
 
 var (
 	ErrNotFound = errors.New("object not found")
 )
 
-// Ledger represents a Todo object store
+// Ledger represents a Todo object store.
+// It is safe for concurrent use.
 type Ledger struct {
+	*state
+	// actor is who the mutations are recorded as made by, see As
+	actor string
+	// withArchived tells whether the queries include the archived todos, see WithArchived
+	withArchived bool
+	// user is the user the todos created are owned by, and admin tells whether the user may
+	// change the todos of the others, see AsUser
+	user  string
+	admin bool
+	// ctx is the context of the storage operations, see WithContext
+	ctx context.Context
+}
+
+// state is the state shared by a Ledger and its views returned by As
+type state struct {
 	storer store.Storage
-	blobs  map[store.ID]store.Blob
+	// traced traces the operations on the storer, see storage
+	traced   *store.Traced
+	workflow *task.Workflow
+	// ids generates the IDs of the todos the ledger creates, like the next occurrences
+	ids store.IDGenerator
+	// onUnblocked are called with the todos whose last active blocker was completed
+	onUnblocked []UnblockedFunc
+	// tags is the registry of the defined tags, and tagsStored tells whether it was ever stored
+	tags       map[string]Tag
+	tagsStored bool
+	// members are the roles of the members of each list, and membersStored tells whether they were
+	// ever stored, see AddMember
+	members       map[string]map[string]ListRole
+	membersStored bool
+	// filters are the saved filters of all the users, sorted by name and owner, and filtersStored
+	// tells whether they were ever stored, see CreateFilter
+	filters       []SavedFilter
+	filtersStored bool
+	// fieldDefs are the definitions of the custom fields of the lists, and fieldsStored tells
+	// whether they were ever stored, see DefineField
+	fieldDefs    listFields
+	fieldsStored bool
+	// boardOrder is the manual order of the todos in each column of the boards, by "field/column",
+	// each stored on its own, see MoveOnBoard
+	boardOrder map[string][]store.ID
+	// now returns the current time, to tell the overdue todos
+	now func() time.Time
+
+	lock  rwLock
+	blobs map[store.ID]store.Blob
+	// archive are the archived todos, by the ID they had before archiving
+	archive map[store.ID]store.Blob
+	// history are the revisions of each todo, oldest first
+	history map[store.ID][]Revision
+	// ops are the logs of the operations which can be undone, by actor, and opsStored tells whether
+	// they were ever stored
+	ops       opLogs
+	opsStored bool
+	// steps are the revisions recorded by the ongoing mutation, see unlock
+	steps []step
+	// changes are the entries of the changelog, oldest first, and changeLog tells which were
+	// truncated, see CompactChanges. changeLogStored tells whether it was ever stored, and
+	// changePolicy is the policy applied as the todos change, see SetChangeLogPolicy.
+	changes         []ChangeEntry
+	changeLog       changeLog
+	changeLogStored bool
+	changePolicy    ChangeLogPolicy
+
+	// feed numbers the changes for the subscribers, once started by Subscribe
+	feedLock sync.Mutex
+	feed     *feed
+}
+
+// Schedule tells when a todo is due, when to remind of it, and how it recurs.
+// The nil times and the empty rule unset the corresponding fields.
+type Schedule struct {
+	Due    *time.Time
+	Remind *time.Time
+	// Recur is the recurrence rule, as accepted by task.ParseRecurrence
+	Recur string
 }
 
 // Item binds a Todo object with its ID. Note that IDs are managed and owned by the Ledger.
 type Item struct {
 	ID   store.ID    `json:"id"`
 	Todo *model.Todo `json:"todo,omitempty"`
+	// Task holds all the fields of the stored todo, including the ones the Todo lacks
+	Task *task.Task `json:"task,omitempty"`
+	// Archived tells whether the todo is archived, see Archive
+	Archived bool `json:"archived,omitempty"`
+}
+
+// newItem decodes the stored blob of the todo with the given ID
+func newItem(id store.ID, blob store.Blob) (Item, error) {
+	tk, err := task.Unmarshal(blob)
+	if err != nil {
+		return Item{}, err
+	}
+	todo := model.FromTask(tk)
+	return Item{
+		ID:   id,
+		Todo: &todo,
+		Task: &tk,
+	}, nil
 }
 
 // ToAPIv1 converts a Item on its API layer corresponding object
 func (it Item) ToAPIv1() apiv1.Item {
 	apiTodo := it.Todo.ToAPIv1()
+	if it.Task != nil {
+		apiTodo.Owner = it.Task.Owner
+		apiTodo.Due = it.Task.Due
+		apiTodo.Remind = it.Task.Remind
+		apiTodo.Recur = it.Task.Recur
+		apiTodo.Series = apiv1.ID(it.Task.Series)
+		apiTodo.Tags = it.Task.Tags
+		apiTodo.Contexts = it.Task.Contexts
+		apiTodo.Fields = it.Task.Fields
+		for _, l := range it.Task.Links {
+			apiTodo.Links = append(apiTodo.Links, apiv1.Link{Type: string(l.Type), ID: apiv1.ID(l.ID), URL: l.URL})
+		}
+		for _, entry := range it.Task.Time {
+			apiTodo.Time = append(apiTodo.Time, apiv1.TimeEntry(entry))
+		}
+		apiTodo.Pomodoros = it.Task.Pomodoros(time.Time{}, time.Time{})
+		apiTodo.Comments = len(it.Task.Comments)
+		for _, ci := range it.Task.Checklist {
+			apiTodo.Checklist = append(apiTodo.Checklist, apiv1.CheckItem(ci))
+		}
+		apiTodo.ChecklistSummary = it.Task.ChecklistSummary()
+		apiTodo.Archived = it.Archived
+		apiTodo.Rank = it.Task.Rank
+		if it.Task.Priority != task.PriorityNone {
+			apiTodo.Priority = it.Task.Priority.String()
+		}
+		for _, id := range it.Task.BlockedBy {
+			apiTodo.BlockedBy = append(apiTodo.BlockedBy, apiv1.ID(id))
+		}
+	}
 	return apiv1.Item{
 		ID:   apiv1.ID(it.ID),
 		Todo: &apiTodo,
@@ -50,28 +180,254 @@ func (its Items) ToAPIv1() []apiv1.Item {
 // Returns true if the given todo object should be included in the resulting collection.
 type Wants func(todo model.Todo) bool
 
-// New creates and initializes a new Ledger based on the given datastore and its contents.
+// New creates and initializes a new Ledger based on the given datastore and its contents,
+// enforcing the task.DefaultWorkflow.
 // To initialize itself, a Ledger eagerly loads all the content of the datastore.
 // Returns error if the initialization fails; in this case, the returned ledger instance must be ignored.
 func New(storer store.Storage) (*Ledger, error) {
-	items, err := storer.LoadAll()
+	return NewWithWorkflow(storer, task.DefaultWorkflow())
+}
+
+// NewWithWorkflow creates and initializes a new Ledger like New, enforcing the given workflow:
+// the todos can only move between its statuses along its transitions.
+func NewWithWorkflow(storer store.Storage, workflow *task.Workflow) (*Ledger, error) {
+	traced := store.NewTraced(storer, tracing.Tracer())
+	items, err := traced.LoadAll()
 	if err != nil {
 		return nil, err
 	}
-	ld := Ledger{
-		storer: storer,
-		blobs:  make(map[store.ID]store.Blob, len(items)),
+	ld := Ledger{state: &state{
+		storer:   storer,
+		traced:   traced,
+		blobs:    make(map[store.ID]store.Blob, len(items)),
+		archive:  make(map[store.ID]store.Blob),
+		history:  make(map[store.ID][]Revision),
+		workflow: workflow,
+		tags:     make(map[string]Tag),
+		ids:      store.NewSequentialIDs(storer),
+		now:      time.Now,
+
+		changePolicy: DefaultChangeLogPolicy,
+	}}
+	if err := ld.load(items); err != nil {
+		return nil, err
 	}
+	slog.Info("ledger: loaded", "blobs", len(ld.blobs), "archived", len(ld.archive))
+	return &ld, nil
+}
+
+// load loads the items of the datastore in the empty state of the ledger
+func (ld *Ledger) load(items []store.Item) error {
 	for _, item := range items {
+		if item.ID == tagsID {
+			if err := ld.loadTags(item.Blob); err != nil {
+				return err
+			}
+			continue
+		}
+		if item.ID == membersID {
+			if err := ld.loadMembers(item.Blob); err != nil {
+				return err
+			}
+			continue
+		}
+		if item.ID == filtersID {
+			if err := ld.loadFilters(item.Blob); err != nil {
+				return err
+			}
+			continue
+		}
+		if item.ID == fieldsID {
+			if err := ld.loadFields(item.Blob); err != nil {
+				return err
+			}
+			continue
+		}
+		if isBoardID(item.ID) {
+			if err := ld.loadBoard(item.ID, item.Blob); err != nil {
+				return err
+			}
+			continue
+		}
+		if item.ID == opsID {
+			if err := ld.loadOps(item.Blob); err != nil {
+				return err
+			}
+			continue
+		}
+		if item.ID == changeLogID {
+			if err := ld.loadChangeLog(item.Blob); err != nil {
+				return err
+			}
+			continue
+		}
+		if store.IsArchived(item.ID) {
+			ld.archive[store.UnarchiveID(item.ID)] = item.Blob
+			continue
+		}
+		if isHistoryID(item.ID) {
+			if err := ld.loadRevision(item.ID, item.Blob); err != nil {
+				return err
+			}
+			continue
+		}
+		if isChangeID(item.ID) {
+			if err := ld.loadChange(item.ID, item.Blob); err != nil {
+				return err
+			}
+			continue
+		}
+		if store.IsQuarantined(item.ID) || store.IsMeta(item.ID) {
+			continue
+		}
 		ld.blobs[item.ID] = item.Blob
 	}
-	log.Printf("ledger: loaded %d blobs", len(ld.blobs))
-	return &ld, nil
+	ld.sortHistory()
+	ld.sortChanges()
+	return nil
+}
+
+// Reload loads again the todos, and the rest of the state of the ledger, from the datastore, for
+// the ledger to see the changes others made to the datastore since it was loaded. The watchers aren't
+// told of the changes. See ReloadItem to load a single item.
+func (ld *Ledger) Reload() error {
+	// loaded with the lock held, not to miss the changes made meanwhile through the ledger
+	ld.lock.Lock()
+	defer ld.lock.Unlock()
+	items, err := ld.traced.LoadAll()
+	if err != nil {
+		return err
+	}
+	fresh := Ledger{state: &state{
+		blobs:   make(map[store.ID]store.Blob, len(items)),
+		archive: make(map[store.ID]store.Blob),
+		history: make(map[store.ID][]Revision),
+		tags:    make(map[string]Tag),
+	}}
+	if err := fresh.load(items); err != nil {
+		return err
+	}
+	ld.blobs, ld.archive, ld.history = fresh.blobs, fresh.archive, fresh.history
+	ld.tags, ld.tagsStored = fresh.tags, fresh.tagsStored
+	ld.members, ld.membersStored = fresh.members, fresh.membersStored
+	ld.filters, ld.filtersStored = fresh.filters, fresh.filtersStored
+	ld.fieldDefs, ld.fieldsStored = fresh.fieldDefs, fresh.fieldsStored
+	ld.boardOrder = fresh.boardOrder
+	ld.ops, ld.opsStored = fresh.ops, fresh.opsStored
+	ld.changes, ld.changeLog, ld.changeLogStored = fresh.changes, fresh.changeLog, fresh.changeLogStored
+	slog.Info("ledger: Reload: reloaded", "blobs", len(ld.blobs), "archived", len(ld.archive))
+	return nil
+}
+
+// ReloadItem loads again the item with the given ID from the datastore, after others changed it,
+// e.g. through store.Handler. The todos, the archived ones, their revisions and the entries of the
+// changelog are loaded on their own; the changes of the rest of the state reload it all, see Reload.
+// The operations on the todo can't be undone anymore, not to revert the change. The watchers are
+// told of the change by the datastore, if a store.Notifier.
+func (ld *Ledger) ReloadItem(id store.ID) error {
+	if store.IsMeta(id) && !isHistoryID(id) && !isChangeID(id) {
+		return ld.Reload()
+	}
+	reload, err := ld.reloadItem(id)
+	if err != nil || !reload {
+		return err
+	}
+	return ld.Reload()
+}
+
+// reloadItem loads again the item with the given ID, see ReloadItem. True if the whole state must be
+// loaded again instead.
+func (ld *Ledger) reloadItem(id store.ID) (bool, error) {
+	ld.lock.Lock()
+	defer ld.lock.Unlock()
+	blob, err := ld.traced.Load(id)
+	var notFound store.ErrNotFound
+	switch {
+	case errors.As(err, &notFound):
+		blob = nil
+	case err != nil:
+		return false, err
+	}
+	switch {
+	case isHistoryID(id) || isChangeID(id):
+		// the revisions and the changes are removed only compacting them
+		if blob == nil {
+			return true, nil
+		}
+		if isHistoryID(id) {
+			return false, ld.reloadRevision(id, blob)
+		}
+		return false, ld.reloadChange(id, blob)
+	case store.IsArchived(id):
+		if blob == nil {
+			delete(ld.archive, store.UnarchiveID(id))
+		} else {
+			ld.archive[store.UnarchiveID(id)] = blob
+		}
+	case store.IsQuarantined(id):
+	default:
+		if blob == nil {
+			delete(ld.blobs, id)
+		} else {
+			ld.blobs[id] = blob
+		}
+		ld.ops.forget(id)
+	}
+	slog.Debug("ledger: ReloadItem: reloaded", "id", id, "deleted", blob == nil)
+	return false, nil
+}
+
+// As returns a view of the ledger which records the mutations as made by the given actor,
+// e.g. the user making them. The view shares the todos with the ledger.
+func (ld *Ledger) As(actor string) *Ledger {
+	return &Ledger{state: ld.state, actor: actor, withArchived: ld.withArchived, ctx: ld.ctx}
+}
+
+// AsUser returns a view of the ledger which records the mutations as made by the given user, like
+// As, and creates the todos owned by the user. Unless admin, the view may only change the todos the
+// user owns, and fails with ErrForbidden otherwise; the todos of the lists with members are the
+// exception, which the view may see and change as the role of the user in the list allows, see
+// AddMember. The view shares the todos with the ledger.
+func (ld *Ledger) AsUser(user string, admin bool) *Ledger {
+	return &Ledger{state: ld.state, actor: user, withArchived: ld.withArchived, user: user, admin: admin, ctx: ld.ctx}
+}
+
+// WithArchived returns a view of the ledger whose Filter, Search and FindBy include the archived
+// todos as well. The view shares the todos with the ledger.
+func (ld *Ledger) WithArchived() *Ledger {
+	return &Ledger{state: ld.state, actor: ld.actor, withArchived: true, user: ld.user, admin: ld.admin, ctx: ld.ctx}
+}
+
+// WithContext returns a view of the ledger whose storage operations are traced as part of the
+// request the context belongs to, e.g. as children of the span of the HTTP request. The view
+// shares the todos with the ledger.
+func (ld *Ledger) WithContext(ctx context.Context) *Ledger {
+	view := *ld
+	view.ctx = ctx
+	return &view
+}
 
+// context returns the context of the view, see WithContext
+func (ld *Ledger) context() context.Context {
+	if ld.ctx == nil {
+		return context.Background()
+	}
+	return ld.ctx
+}
+
+// storage returns the storage of the todos, tracing the operations with the context of the view
+func (ld *Ledger) storage() store.Storage {
+	return store.Bind(ld.traced, ld.context())
+}
+
+// begin starts a transaction on the storage of the todos, tracing it with the context of the view
+func (ld *Ledger) begin() (store.Tx, error) {
+	return ld.traced.BeginCtx(ld.context())
 }
 
 // Close deinitializes this ledger and closes the attached datastore.
 func (ld *Ledger) Close() error {
+	ld.stopFeed()
 	return ld.storer.Close()
 }
 
@@ -79,58 +435,335 @@ func (ld *Ledger) Close() error {
 // On failure, the error value is not nil and the resulting collection
 // must be ignored.
 func (ld *Ledger) Filter(wants Wants) (Items, error) {
+	ld.lock.RLock()
+	defer ld.lock.RUnlock()
+	return ld.filter(wants)
+}
+
+// filter returns the Items which match the filter. The caller must hold the lock.
+func (ld *Ledger) filter(wants Wants) (Items, error) {
 	var items []Item
-	log.Printf("ledger: Filter: scanning %d blobs", len(ld.blobs))
+	slog.Debug("ledger: Filter: scanning", "blobs", len(ld.blobs))
+	for id, blob := range ld.blobs {
+		if !ld.readable(id) {
+			continue
+		}
+		item, err := newItem(id, blob)
+		if err != nil {
+			return items, err
+		}
+		if !wants(*item.Todo) {
+			continue
+		}
+		slog.Debug("ledger: Filter: object included", "id", id)
+		items = append(items, item)
+	}
+	if !ld.withArchived {
+		return items, nil
+	}
+	for id, blob := range ld.archive {
+		if !ld.readable(id) {
+			continue
+		}
+		item, err := newItem(id, blob)
+		if err != nil {
+			return items, err
+		}
+		if wants(*item.Todo) {
+			item.Archived = true
+			items = append(items, item)
+		}
+	}
+	return items, nil
+}
+
+// filterTasks returns the Items whose task satisfies wants, sorted by the time
+// returned by when, then by ID; the ones without time come last
+func (ld *Ledger) filterTasks(wants func(tk task.Task) bool, when func(tk task.Task) *time.Time) (Items, error) {
+	ld.lock.RLock()
+	defer ld.lock.RUnlock()
+	var items Items
 	for id, blob := range ld.blobs {
-		todo, err := model.DeserializeTodo(blob)
+		if !ld.readable(id) {
+			continue
+		}
+		item, err := newItem(id, blob)
 		if err != nil {
 			return items, err
 		}
-		if !wants(todo) {
+		if wants(*item.Task) {
+			items = append(items, item)
+		}
+	}
+	sort.Slice(items, func(i, j int) bool {
+		di, dj := when(*items[i].Task), when(*items[j].Task)
+		switch {
+		case di == nil || dj == nil:
+			if di != dj {
+				return dj == nil
+			}
+		case !di.Equal(*dj):
+			return di.Before(*dj)
+		}
+		return items[i].ID < items[j].ID
+	})
+	return items, nil
+}
+
+// completed returns true if the task was completed, i.e. moved to a final status other than deleted
+func (ld *Ledger) completed(tk task.Task) bool {
+	return tk.Status != task.Deleted && ld.workflow.Final(tk.Status)
+}
+
+// active returns true if the task can still be worked on, i.e. its status is not final
+func (ld *Ledger) active(tk task.Task) bool {
+	return !ld.workflow.Final(tk.Status)
+}
+
+// ListOverdue returns the active todos whose due date has passed, the most overdue first.
+// The todos in a final status of the workflow, e.g. completed, are never overdue.
+func (ld *Ledger) ListOverdue() (Items, error) {
+	now := ld.now()
+	items, err := ld.filterTasks(func(tk task.Task) bool {
+		return ld.active(tk) && tk.Overdue(now)
+	}, dueOf)
+	slog.Debug("ledger: ListOverdue: objects overdue", "count", len(items))
+	return items, err
+}
+
+// ListDueWithin returns the active todos due from now up to d from now, the earliest due first.
+// The todos already overdue are not included: see ListOverdue.
+func (ld *Ledger) ListDueWithin(d time.Duration) (Items, error) {
+	now := ld.now()
+	until := now.Add(d)
+	items, err := ld.filterTasks(func(tk task.Task) bool {
+		return ld.active(tk) && tk.Due != nil && !tk.Overdue(now) && !tk.Due.After(until)
+	}, dueOf)
+	slog.Debug("ledger: ListDueWithin: objects due", "count", len(items), "within", d)
+	return items, err
+}
+
+// remindersBetween returns the active todos whose reminder comes due after from, up to until
+func (ld *Ledger) remindersBetween(from, until time.Time) (Items, error) {
+	return ld.filterTasks(func(tk task.Task) bool {
+		return ld.active(tk) && tk.Remind != nil && tk.Remind.After(from) && !tk.Remind.After(until)
+	}, func(tk task.Task) *time.Time {
+		return tk.Remind
+	})
+}
+
+func dueOf(tk task.Task) *time.Time {
+	return tk.Due
+}
+
+// FindBy returns the Items whose indexed field has the given value.
+// Fails with store.ErrUnsupported if the datastore doesn't index fields.
+func (ld *Ledger) FindBy(field, value string) (Items, error) {
+	ids, err := index.FindBy(ld.storer, field, value)
+	if err != nil {
+		return nil, err
+	}
+	items, err := ld.itemsOf(ids)
+	slog.Debug("ledger: FindBy: objects matched", "field", field, "value", value, "count", len(items))
+	return items, err
+}
+
+// itemsOf returns the Items with the given IDs, in the same order, skipping the unknown ones.
+// The IDs of the archived todos are skipped as well, unless the ledger includes them.
+func (ld *Ledger) itemsOf(ids []store.ID) (Items, error) {
+	ld.lock.RLock()
+	defer ld.lock.RUnlock()
+	items := make(Items, 0, len(ids))
+	for _, id := range ids {
+		blob, ok := ld.blobs[id]
+		archived := false
+		if !ok && ld.withArchived && store.IsArchived(id) {
+			id = store.UnarchiveID(id)
+			blob, ok = ld.archive[id]
+			archived = true
+		}
+		if !ok || !ld.readable(id) {
 			continue
 		}
-		log.Printf("ledger: Filter: object %v included", id)
-		items = append(items, Item{
-			ID:   id,
-			Todo: &todo,
-		})
+		item, err := newItem(id, blob)
+		if err != nil {
+			return items, err
+		}
+		item.Archived = archived
+		items = append(items, item)
 	}
 	return items, nil
 }
 
 // Get returns a todo object from its id. On failure, error is not nil
 func (ld *Ledger) Get(id store.ID) (model.Todo, error) {
+	ld.lock.RLock()
+	defer ld.lock.RUnlock()
 	blob, ok := ld.blobs[id]
 	if !ok {
 		return model.Todo{}, store.ErrNotFound{ID: id}
 	}
+	if err := ld.checkRead(id); err != nil {
+		return model.Todo{}, err
+	}
 	todo, err := model.DeserializeTodo(blob)
 	if err != nil {
 		return model.Todo{}, err
 	}
-	log.Printf("ledger: Set: retrieved from cache object %v: %s", id, todo.String())
+	slog.Debug("ledger: Set: retrieved from cache", "id", id, "todo", todo)
 	return todo, nil
 }
 
+// GetItem returns the todo with the given ID along with its revision, see Revision.
+// Fails with store.ErrNotFound if the todo doesn't exist.
+func (ld *Ledger) GetItem(id store.ID) (Item, int, error) {
+	ld.lock.RLock()
+	defer ld.lock.RUnlock()
+	blob, ok := ld.blobs[id]
+	if !ok {
+		return Item{}, 0, store.ErrNotFound{ID: id}
+	}
+	if err := ld.checkRead(id); err != nil {
+		return Item{}, 0, err
+	}
+	item, err := newItem(id, blob)
+	return item, ld.revision(id), err
+}
+
+// SetIDGenerator sets how the ledger generates the IDs of the todos it creates, like the
+// next occurrences of the recurring todos. It must be the same generator used for the IDs
+// of the other todos, if any, not to generate duplicated IDs. The default generates
+// sequential IDs.
+func (ld *Ledger) SetIDGenerator(ids store.IDGenerator) {
+	ld.lock.Lock()
+	defer ld.lock.Unlock()
+	ld.ids = ids
+}
+
+// Workflow returns the workflow the ledger enforces
+func (ld *Ledger) Workflow() *task.Workflow {
+	return ld.workflow
+}
+
 // Set creates or updates Todo objects in the store.
-func (ld *Ledger) Set(id store.ID, todo model.Todo) (rerr error) {
-	blob, err := todo.Serialize()
+// Fails with task.ErrIllegalTransition if the workflow doesn't allow the todo status.
+// Completing a recurring todo, i.e. moving it to a final status of the workflow other than
+// deleted, creates its next occurrence. Moving a todo to a final status notifies the todos
+// it was the last active blocker of, see OnUnblocked.
+func (ld *Ledger) Set(id store.ID, todo model.Todo) error {
+	if id == store.NullID {
+		return errors.New("can't set null id")
+	}
+
+	_, _, err := ld.SetIf(id, todo, AnyRevision)
+	return err
+}
+
+// SetIf is like Set, but sets the todo only if its current revision is the expected one, see Revision:
+// zero expects the todo not to exist, AnyRevision skips the check. Returns the updated todo along with
+// its new revision. Fails with store.ErrConflict if the revision doesn't match.
+func (ld *Ledger) SetIf(id store.ID, todo model.Todo, expected int) (Item, int, error) {
+	if id == store.NullID {
+		return Item{}, 0, errors.New("can't set null id")
+	}
+
+	item, rev, unblocked, err := ld.update(id, expected, func(prev store.Blob) (model.Todo, store.Blob, error) {
+		return todo, prev, nil
+	})
 	if err != nil {
+		return Item{}, 0, err
+	}
+	ld.notifyUnblocked(unblocked)
+	return item, rev, nil
+}
+
+// checkTransition checks the workflow allows the todo to move from its current status to the new
+// one. Assigning the todo moves it to the assigned status first, if the workflow has it, e.g. for
+// the todo to be assigned and completed at once.
+func (ld *Ledger) checkTransition(cur, todo model.Todo) error {
+	from, to := task.Status(cur.Status), task.Status(todo.Status)
+	err := ld.workflow.Check(from, to)
+	if err == nil || from == "" || cur.Assignee != "" || todo.Assignee == "" {
+		return err
+	}
+	if ld.workflow.Check(from, task.Assigned) != nil || ld.workflow.Check(task.Assigned, to) != nil {
 		return err
 	}
-	log.Printf("ledger: Set: %s (blob=%d bytes)", todo.String(), len(blob))
+	return nil
+}
 
-	if id == store.NullID {
-		return errors.New("can't set null id")
+// update sets the todo returned by edit if its revision is the expected one, and returns the updated
+// todo, its new revision and the todos it unblocked. Edit is given the current encoded todo, nil if
+// it doesn't exist, and returns the todo to set along with the encoded task holding the fields the
+// todo doesn't, nil to create it.
+func (ld *Ledger) update(id store.ID, expected int, edit func(prev store.Blob) (model.Todo, store.Blob, error)) (Item, int, Items, error) {
+	ld.lock.Lock()
+	defer ld.unlock()
+	if err := ld.checkRevision(id, expected); err != nil {
+		return Item{}, 0, nil, err
+	}
+	prevBlob := ld.blobs[id]
+	if err := ld.checkOwner(id, prevBlob); err != nil {
+		return Item{}, 0, nil, err
+	}
+	todo, base, err := edit(prevBlob)
+	if err != nil {
+		return Item{}, 0, nil, err
+	}
+	if err := ld.set(id, todo, base); err != nil {
+		return Item{}, 0, nil, err
+	}
+	if err := ld.record(ld.storer, id, prevBlob, ld.blobs[id]); err != nil {
+		return Item{}, 0, nil, err
+	}
+	if err := ld.recur(ld.storage(), id, prevBlob); err != nil {
+		return Item{}, 0, nil, err
+	}
+	unblocked, err := ld.unblockedBy(id, prevBlob)
+	if err != nil {
+		return Item{}, 0, nil, err
 	}
+	item, err := newItem(id, ld.blobs[id])
+	return item, ld.revision(id), unblocked, err
+}
 
-	log.Printf("ledger: Set: updating object %v", id)
+// set creates or updates a Todo object in the store, encoding it over base, which holds the task
+// fields the todo doesn't. The caller must hold the lock.
+func (ld *Ledger) set(id store.ID, todo model.Todo, base store.Blob) (rerr error) {
 	curBlob, found := ld.blobs[id]
+	var cur model.Todo
+	if found {
+		var err error
+		if cur, err = model.DeserializeTodo(curBlob); err != nil {
+			return err
+		}
+	}
+	if err := ld.checkTransition(cur, todo); err != nil {
+		return err
+	}
+
+	var blob []byte
+	var err error
+	if base != nil {
+		// keep the task fields the todo doesn't hold
+		blob, err = todo.SerializeOver(base)
+	} else {
+		blob, err = todo.Serialize()
+	}
+	if err != nil {
+		return err
+	}
+	if !found {
+		if blob, err = ld.own(blob); err != nil {
+			return err
+		}
+	}
+	slog.Debug("ledger: Set: updating object", "id", id, "todo", todo, "bytes", len(blob))
 	if !found {
 		ld.blobs[id] = blob
-		log.Printf("ledger: Set: created cache object %v", id)
-		rerr = ld.storer.Create(id, blob)
-		log.Printf("ledger: Set: created store object %v err=%v", id, err)
+		slog.Debug("ledger: Set: created cache object", "id", id)
+		rerr = ld.storage().Create(id, blob)
+		slog.Debug("ledger: Set: created store object", "id", id, "error", rerr)
 		return rerr
 	}
 	// rollback
@@ -138,26 +771,170 @@ func (ld *Ledger) Set(id store.ID, todo model.Todo) (rerr error) {
 		if rerr == nil {
 			return
 		}
-		log.Printf("ledger: Set: rollbacking object %v", id)
+		slog.Warn("ledger: Set: rollbacking object", "id", id, "error", rerr)
 		ld.blobs[id] = curBlob
 	}()
 	ld.blobs[id] = blob
-	log.Printf("ledger: Set: updated cache object %v", id)
-	rerr = ld.storer.Save(id, blob)
-	log.Printf("ledger: Set: updated store object %v err=%v", id, err)
+	slog.Debug("ledger: Set: updated cache object", "id", id)
+	rerr = ld.storage().Save(id, blob)
+	slog.Debug("ledger: Set: updated store object", "id", id, "error", rerr)
 	return rerr
 }
 
+// recur creates the next occurrence of the todo with the given ID, if it recurs and it was just
+// completed, i.e. moved from the status encoded in prevBlob to a final status other than deleted.
+// The completed todo starts the series if it isn't part of one already. The changes are written
+// with w, e.g. the transaction completing the todo. The caller must hold the lock.
+func (ld *Ledger) recur(w writer, id store.ID, prevBlob store.Blob) error {
+	tk, err := task.Unmarshal(ld.blobs[id])
+	if err != nil {
+		return err
+	}
+	if tk.Recur == "" || !ld.completed(tk) {
+		return nil
+	}
+	if prevBlob != nil {
+		prev, err := task.Unmarshal(prevBlob)
+		if err != nil {
+			return err
+		}
+		if prev.Status == tk.Status {
+			return nil
+		}
+	}
+	next, ok, err := tk.NextOccurrence(ld.now())
+	if err != nil {
+		return err
+	}
+	if !ok {
+		slog.Info("ledger: Set: series ended", "id", id)
+		return nil
+	}
+
+	if tk.Series == "" {
+		tk.Series = string(id)
+		blob, err := task.Marshal(tk)
+		if err != nil {
+			return err
+		}
+		if err := w.Save(id, blob); err != nil {
+			return err
+		}
+		prev := ld.blobs[id]
+		ld.blobs[id] = blob
+		if err := ld.record(w, id, prev, blob); err != nil {
+			return err
+		}
+	}
+	next.Series = tk.Series
+	next.Status = ld.workflow.Initial()
+	nextID, err := ld.ids.NewID()
+	if err != nil {
+		return err
+	}
+	blob, err := task.Marshal(next)
+	if err != nil {
+		return err
+	}
+	if err := w.Create(nextID, blob); err != nil {
+		return err
+	}
+	ld.blobs[nextID] = blob
+	slog.Info("ledger: Set: created next occurrence", "id", nextID, "of", id, "due", next.Due)
+	return ld.record(w, nextID, nil, blob)
+}
+
+// Transition moves a Todo to the given status, and returns the updated Todo.
+// Fails with task.ErrIllegalTransition if the workflow doesn't allow the transition.
+func (ld *Ledger) Transition(id store.ID, status task.Status) (model.Todo, error) {
+	todo, err := ld.Get(id)
+	if err != nil {
+		return model.Todo{}, err
+	}
+	todo.Status = apiv1.Status(status)
+	todo.LastUpdateTime = time.Now()
+	if err := ld.Set(id, todo); err != nil {
+		return model.Todo{}, err
+	}
+	slog.Info("ledger: Transition: object moved", "id", id, "status", status)
+	return todo, nil
+}
+
+// Schedule sets when the todo is due, when to remind of it and how it recurs,
+// and returns the updated Item.
+func (ld *Ledger) Schedule(id store.ID, sched Schedule) (Item, error) {
+	ld.lock.Lock()
+	defer ld.unlock()
+	tk, err := ld.loadTask(id)
+	if err != nil {
+		return Item{}, err
+	}
+	tk.Due = sched.Due
+	tk.Remind = sched.Remind
+	tk.Recur = sched.Recur
+	slog.Info("ledger: Schedule: object scheduled", "id", id, "due", sched.Due, "remind", sched.Remind, "recur", sched.Recur)
+	return ld.saveTask(id, tk)
+}
+
+// loadTask returns the task of the existing todo with the given ID. The caller must hold the lock.
+func (ld *Ledger) loadTask(id store.ID) (task.Task, error) {
+	blob, ok := ld.blobs[id]
+	if !ok {
+		return task.Task{}, store.ErrNotFound{ID: id}
+	}
+	return task.Unmarshal(blob)
+}
+
+// saveTask updates the existing todo with the given ID, and returns the updated Item.
+// The caller must hold the lock.
+func (ld *Ledger) saveTask(id store.ID, tk task.Task) (Item, error) {
+	if err := ld.checkOwner(id, ld.blobs[id]); err != nil {
+		return Item{}, err
+	}
+	tk.Updated = ld.now()
+	blob, err := task.Marshal(tk)
+	if err != nil {
+		return Item{}, err
+	}
+	if err := ld.storage().Save(id, blob); err != nil {
+		return Item{}, err
+	}
+	prev := ld.blobs[id]
+	ld.blobs[id] = blob
+	if err := ld.record(ld.storer, id, prev, blob); err != nil {
+		return Item{}, err
+	}
+	return newItem(id, blob)
+}
+
 // Delete removes a Todo from the ledger. The ledger may recycle IDs of deleted objects.
 // On failure, error is not nil.
 func (ld *Ledger) Delete(id store.ID) error {
-	log.Printf("ledger: Delete: deleting object %v", id)
-	err := ld.storer.Delete(id)
+	return ld.DeleteIf(id, AnyRevision)
+}
+
+// DeleteIf is like Delete, but removes the todo only if its current revision is the expected one,
+// see Revision. AnyRevision skips the check. Fails with store.ErrConflict if the revision doesn't match.
+func (ld *Ledger) DeleteIf(id store.ID, expected int) error {
+	ld.lock.Lock()
+	defer ld.unlock()
+	if err := ld.checkRevision(id, expected); err != nil {
+		return err
+	}
+	if err := ld.checkOwner(id, ld.blobs[id]); err != nil {
+		return err
+	}
+	slog.Debug("ledger: Delete: deleting object", "id", id)
+	err := ld.storage().Delete(id)
 	if err != nil {
-		log.Printf("ledger: Delete:failed to delete object %v: %v", id, err)
+		slog.Error("ledger: Delete: failed to delete object", "id", id, "error", err)
 		return err
 	}
+	prev, found := ld.blobs[id]
 	delete(ld.blobs, id)
-	log.Printf("ledger: Delete: deleted object %v", id)
-	return nil
+	slog.Info("ledger: Delete: deleted object", "id", id)
+	if !found {
+		return nil
+	}
+	return ld.record(ld.storer, id, prev, nil)
 }
@@ -0,0 +1,309 @@
+package ledger
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	apiv1 "github.com/gotestbootcamp/go-todo-app/api/v1"
+	"github.com/gotestbootcamp/go-todo-app/model"
+	"github.com/gotestbootcamp/go-todo-app/store"
+	"github.com/gotestbootcamp/go-todo-app/task"
+)
+
+func newTestMemory(t *testing.T) *store.Memory {
+	st, err := store.NewMemory()
+	require.NoError(t, err)
+	return st
+}
+
+func newTestLedger(t *testing.T, workflow *task.Workflow) *Ledger {
+	ld, err := NewWithWorkflow(newTestMemory(t), workflow)
+	require.NoError(t, err)
+	return ld
+}
+
+func TestSetDefaultWorkflow(t *testing.T) {
+	ld := newTestLedger(t, task.DefaultWorkflow())
+
+	todo := model.New("foo")
+	require.NoError(t, ld.Set("1", todo))
+	require.NoError(t, todo.Assign("fede"))
+	require.NoError(t, ld.Set("1", todo))
+
+	todo.Status = apiv1.Pending
+	assert.ErrorIs(t, ld.Set("1", todo), task.ErrIllegalTransition{From: task.Assigned, To: task.Pending})
+	got, err := ld.Get("1")
+	require.NoError(t, err)
+	assert.Equal(t, apiv1.Assigned, got.Status)
+
+	assert.ErrorIs(t, ld.Set("2", model.Todo{Title: "bar", Status: "done"}), task.ErrIllegalTransition{To: "done"})
+
+	// the todos assigned as they are completed go through the assigned status
+	require.NoError(t, ld.Set("3", model.New("baz")))
+	assert.ErrorIs(t, ld.Set("3", model.Todo{Title: "baz", Status: apiv1.Completed}), task.ErrIllegalTransition{From: task.Pending, To: task.Completed})
+	require.NoError(t, ld.Set("3", model.Todo{Title: "baz", Status: apiv1.Completed, Assignee: "fede"}))
+}
+
+func TestTransition(t *testing.T) {
+	wf, err := task.ParseWorkflow("todo>in-progress; in-progress>blocked,done; blocked>in-progress")
+	require.NoError(t, err)
+	ld := newTestLedger(t, wf)
+
+	todo := model.New("foo")
+	todo.Status = "todo"
+	require.NoError(t, ld.Set("1", todo))
+
+	for _, status := range []task.Status{"in-progress", "blocked", "in-progress", "done"} {
+		got, err := ld.Transition("1", status)
+		require.NoError(t, err, status)
+		assert.Equal(t, apiv1.Status(status), got.Status)
+	}
+	_, err = ld.Transition("1", "todo")
+	assert.ErrorIs(t, err, task.ErrIllegalTransition{From: "done", To: "todo"})
+	_, err = ld.Transition("2", "done")
+	assert.ErrorIs(t, err, store.ErrNotFound{ID: "2"})
+
+	// the default statuses are not part of this workflow
+	todo = model.New("bar")
+	assert.ErrorIs(t, ld.Set("3", todo), task.ErrIllegalTransition{To: task.Pending})
+}
+
+func TestSchedule(t *testing.T) {
+	ld := newTestLedger(t, task.DefaultWorkflow())
+	require.NoError(t, ld.Set("1", model.New("foo")))
+
+	due := time.Date(2024, time.November, 11, 18, 0, 0, 0, time.UTC)
+	remind := due.Add(-time.Hour)
+	item, err := ld.Schedule("1", Schedule{Due: &due, Remind: &remind})
+	require.NoError(t, err)
+	assert.Equal(t, "foo", item.Todo.Title)
+	assert.True(t, due.Equal(*item.Task.Due))
+	assert.True(t, remind.Equal(*item.Task.Remind))
+
+	// survives the updates of the todo
+	todo, err := ld.Get("1")
+	require.NoError(t, err)
+	require.NoError(t, todo.Assign("fede"))
+	require.NoError(t, ld.Set("1", todo))
+	items, err := ld.Filter(func(model.Todo) bool { return true })
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	assert.True(t, due.Equal(*items[0].Task.Due))
+	assert.True(t, due.Equal(*items[0].ToAPIv1().Todo.Due))
+
+	item, err = ld.Schedule("1", Schedule{})
+	require.NoError(t, err)
+	assert.Nil(t, item.Task.Due)
+	assert.Nil(t, item.Task.Remind)
+
+	_, err = ld.Schedule("2", Schedule{Due: &due})
+	assert.ErrorIs(t, err, store.ErrNotFound{ID: "2"})
+}
+
+func TestListDue(t *testing.T) {
+	ld := newTestLedger(t, task.DefaultWorkflow())
+	now := time.Date(2024, time.November, 11, 10, 0, 0, 0, time.UTC)
+	ld.now = func() time.Time { return now }
+
+	for id, offset := range map[store.ID]time.Duration{
+		"yesterday":  -24 * time.Hour,
+		"last-hour":  -time.Hour,
+		"next-hour":  time.Hour,
+		"tomorrow":   24 * time.Hour,
+		"next-week":  7 * 24 * time.Hour,
+		"done-late":  -time.Hour,
+		"done-early": time.Hour,
+	} {
+		require.NoError(t, ld.Set(id, model.New(string(id))))
+		due := now.Add(offset)
+		_, err := ld.Schedule(id, Schedule{Due: &due})
+		require.NoError(t, err)
+	}
+	require.NoError(t, ld.Set("unscheduled", model.New("unscheduled")))
+	for _, id := range []store.ID{"done-late", "done-early"} {
+		_, err := ld.Transition(id, task.Assigned)
+		require.NoError(t, err)
+		_, err = ld.Transition(id, task.Completed)
+		require.NoError(t, err)
+	}
+
+	overdue, err := ld.ListOverdue()
+	require.NoError(t, err)
+	assert.Equal(t, []store.ID{"yesterday", "last-hour"}, idsOf(overdue))
+
+	due, err := ld.ListDueWithin(24 * time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, []store.ID{"next-hour", "tomorrow"}, idsOf(due))
+
+	due, err = ld.ListDueWithin(0)
+	require.NoError(t, err)
+	assert.Empty(t, due)
+}
+
+func TestRecurring(t *testing.T) {
+	ld := newTestLedger(t, task.DefaultWorkflow())
+	now := time.Date(2024, time.January, 29, 10, 0, 0, 0, time.UTC)
+	ld.now = func() time.Time { return now }
+
+	require.NoError(t, ld.Set("1", model.New("water the plants")))
+	due := now.Add(8 * time.Hour)
+	_, err := ld.Schedule("1", Schedule{Due: &due, Recur: "daily"})
+	require.NoError(t, err)
+
+	_, err = ld.Transition("1", task.Assigned)
+	require.NoError(t, err)
+	_, err = ld.Transition("1", task.Completed)
+	require.NoError(t, err)
+
+	items, err := ld.Filter(func(model.Todo) bool { return true })
+	require.NoError(t, err)
+	require.Len(t, items, 2)
+	byID := map[store.ID]Item{}
+	for _, item := range items {
+		byID[item.ID] = item
+	}
+	assert.Equal(t, "1", byID["1"].Task.Series)
+	next := byID["2"]
+	require.NotNil(t, next.Task)
+	assert.Equal(t, "water the plants", next.Task.Title)
+	assert.Equal(t, task.Pending, next.Task.Status)
+	assert.Equal(t, "1", next.Task.Series)
+	assert.Equal(t, "daily", next.Task.Recur)
+	assert.Equal(t, due.AddDate(0, 0, 1), *next.Task.Due)
+
+	// completing again changes nothing
+	_, err = ld.Transition("1", task.Completed)
+	require.NoError(t, err)
+	items, err = ld.Filter(func(model.Todo) bool { return true })
+	require.NoError(t, err)
+	assert.Len(t, items, 2)
+
+	// deleting doesn't recur
+	_, err = ld.Transition("2", task.Deleted)
+	require.NoError(t, err)
+	items, err = ld.Filter(func(model.Todo) bool { return true })
+	require.NoError(t, err)
+	assert.Len(t, items, 2)
+}
+
+func TestListByUrgency(t *testing.T) {
+	ld := newTestLedger(t, task.DefaultWorkflow())
+	now := time.Now()
+	ld.now = func() time.Time { return now }
+	for _, id := range []store.ID{"1", "2", "3", "4", "5"} {
+		require.NoError(t, ld.Set(id, model.New("todo "+string(id))))
+	}
+	_, err := ld.SetPriority("1", task.PriorityLow)
+	require.NoError(t, err)
+	_, err = ld.SetPriority("2", task.PriorityUrgent)
+	require.NoError(t, err)
+	due := now.Add(-24 * time.Hour)
+	_, err = ld.Schedule("3", Schedule{Due: &due})
+	require.NoError(t, err)
+	_, err = ld.SetPriority("5", task.PriorityUrgent)
+	require.NoError(t, err)
+	_, err = ld.Transition("5", task.Deleted)
+	require.NoError(t, err)
+
+	items, err := ld.ListByUrgency()
+	require.NoError(t, err)
+	assert.Equal(t, []store.ID{"3", "2", "1", "4"}, idsOf(items))
+	assert.Equal(t, "P0", items[1].ToAPIv1().Todo.Priority)
+
+	_, err = ld.SetPriority("6", task.PriorityLow)
+	assert.ErrorIs(t, err, store.ErrNotFound{ID: "6"})
+}
+
+func TestReload(t *testing.T) {
+	st := newTestMemory(t)
+	ld, err := New(st)
+	require.NoError(t, err)
+	require.NoError(t, ld.Set("1", model.New("foo")))
+
+	// another ledger on the same datastore, like a client of the store API
+	other, err := New(st)
+	require.NoError(t, err)
+	require.NoError(t, other.Set("2", model.New("bar")))
+	_, err = other.DefineTag("home", "#00ff00")
+	require.NoError(t, err)
+	require.NoError(t, other.Delete("1"))
+	_, err = ld.Get("2")
+	assert.Error(t, err)
+
+	require.NoError(t, ld.Reload())
+	got, err := ld.Get("2")
+	require.NoError(t, err)
+	assert.Equal(t, "bar", got.Title)
+	_, err = ld.Get("1")
+	assert.Error(t, err)
+	rev, err := ld.Revision("2")
+	require.NoError(t, err)
+	assert.Equal(t, 1, rev)
+	tags, err := ld.ListTags()
+	require.NoError(t, err)
+	assert.Equal(t, []Tag{{Name: "home", Color: "#00ff00"}}, tags)
+}
+
+func TestReloadItem(t *testing.T) {
+	st := newTestMemory(t)
+	ld, err := New(st)
+	require.NoError(t, err)
+	ids := store.NewSequentialIDs(st)
+	ld.SetIDGenerator(ids)
+	h := store.NewHandler(st)
+	h.IDs = ids
+	h.OnChange = func(id store.ID) {
+		assert.NoError(t, ld.ReloadItem(id))
+	}
+	srv := httptest.NewServer(http.StripPrefix("/store", h))
+	defer srv.Close()
+	rm, err := store.NewRemote(srv.URL, "")
+	require.NoError(t, err)
+
+	id, err := ids.NewID()
+	require.NoError(t, err)
+	_, _, err = ld.SetIf(id, model.New("from the server"), 0)
+	require.NoError(t, err)
+	// a client of the store API, whose IDs the server allocates
+	client, err := New(rm)
+	require.NoError(t, err)
+	id, err = store.NewSequentialIDs(rm).NewID()
+	require.NoError(t, err)
+	assert.Equal(t, store.ID("2"), id)
+	_, _, err = client.SetIf(id, model.New("from the client"), 0)
+	require.NoError(t, err)
+	id, err = ids.NewID()
+	require.NoError(t, err)
+	assert.Equal(t, store.ID("3"), id)
+
+	got, err := ld.Get("2")
+	require.NoError(t, err)
+	assert.Equal(t, "from the client", got.Title)
+	rev, err := ld.Revision("2")
+	require.NoError(t, err)
+	assert.Equal(t, 1, rev)
+	require.NoError(t, client.Delete("2"))
+	_, err = ld.Get("2")
+	assert.Error(t, err)
+
+	// the changes made under the server aren't undone
+	_, err = ld.SetPriority("1", task.PriorityUrgent)
+	require.NoError(t, err)
+	blob, err := rm.Load("1")
+	require.NoError(t, err)
+	require.NoError(t, rm.Save("1", blob))
+	for {
+		if _, err := ld.Undo(); err != nil {
+			assert.ErrorIs(t, err, ErrNothingToUndo)
+			break
+		}
+	}
+	item, _, err := ld.GetItem("1")
+	require.NoError(t, err)
+	assert.Equal(t, task.PriorityUrgent, item.Task.Priority)
+}
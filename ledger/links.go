@@ -0,0 +1,135 @@
+package ledger
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	apiv1 "github.com/gotestbootcamp/go-todo-app/api/v1"
+	"github.com/gotestbootcamp/go-todo-app/store"
+	"github.com/gotestbootcamp/go-todo-app/task"
+)
+
+// TodoLink is a link of a todo, to another todo or to a URL, or a backlink from another todo
+type TodoLink struct {
+	// Type is the type of the link, or how the todo relates to the other one for the backlinks,
+	// e.g. "duplicated-by", see task.LinkType.Inverse
+	Type string
+	// ID is the ID of the other todo. Empty for the links to URLs.
+	ID store.ID
+	// URL is the page linked. Empty for the links to todos.
+	URL string
+	// Title is the title of the other todo. Empty for the URLs, and for the todos removed or the view
+	// may not see.
+	Title string
+	// Back tells whether the other todo links to this one
+	Back bool
+}
+
+// ToAPIv1 converts the TodoLink in its API v1 representation
+func (l TodoLink) ToAPIv1() apiv1.Link {
+	return apiv1.Link{Type: l.Type, ID: apiv1.ID(l.ID), URL: l.URL, Title: l.Title, Back: l.Back}
+}
+
+// Link links the todo to another todo, or to a URL, and returns the updated Item. Linking a todo
+// twice the same way does nothing. Fails with store.ErrNotFound if either todo doesn't exist.
+func (ld *Ledger) Link(id store.ID, l task.Link) (Item, error) {
+	ld.lock.Lock()
+	defer ld.unlock()
+	if l.ID != "" {
+		if store.ID(l.ID) == id {
+			return Item{}, fmt.Errorf("object %v can't link to itself", id)
+		}
+		if _, ok := ld.blobs[store.ID(l.ID)]; !ok {
+			return Item{}, store.ErrNotFound{ID: store.ID(l.ID)}
+		}
+		if err := ld.checkRead(store.ID(l.ID)); err != nil {
+			return Item{}, err
+		}
+	}
+	tk, err := ld.loadTask(id)
+	if err != nil {
+		return Item{}, err
+	}
+	added, err := tk.AddLink(l)
+	if err != nil {
+		return Item{}, err
+	}
+	if !added {
+		return newItem(id, ld.blobs[id])
+	}
+	slog.Info("ledger: Link: object linked", "id", id, "type", l.Type, "target", l.Target())
+	return ld.saveTask(id, tk)
+}
+
+// Unlink removes the link from the todo, and returns the updated Item.
+// Fails with store.ErrNotFound if the todo doesn't exist, and with task.ErrNoLink if it hasn't the link.
+func (ld *Ledger) Unlink(id store.ID, l task.Link) (Item, error) {
+	ld.lock.Lock()
+	defer ld.unlock()
+	tk, err := ld.loadTask(id)
+	if err != nil {
+		return Item{}, err
+	}
+	if err := tk.RemoveLink(l); err != nil {
+		return Item{}, err
+	}
+	slog.Info("ledger: Unlink: object unlinked", "id", id, "type", l.Type, "target", l.Target())
+	return ld.saveTask(id, tk)
+}
+
+// Links returns the links of the todo, in the order they were added, followed by the backlinks
+// from the other todos the view sees, sorted by ID and type. The links to the todos removed since
+// are kept. Fails with store.ErrNotFound if the todo doesn't exist.
+func (ld *Ledger) Links(id store.ID) ([]TodoLink, error) {
+	ld.lock.RLock()
+	defer ld.lock.RUnlock()
+	if err := ld.checkRead(id); err != nil {
+		return nil, err
+	}
+	tk, err := ld.loadTask(id)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]TodoLink, 0, len(tk.Links))
+	for _, l := range tk.Links {
+		res = append(res, TodoLink{Type: string(l.Type), ID: store.ID(l.ID), URL: l.URL, Title: ld.titleOf(store.ID(l.ID))})
+	}
+	var back []TodoLink
+	for otherID, blob := range ld.blobs {
+		if otherID == id || !ld.readable(otherID) {
+			continue
+		}
+		other, err := task.Unmarshal(blob)
+		if err != nil {
+			continue
+		}
+		for _, l := range other.Links {
+			if store.ID(l.ID) == id {
+				back = append(back, TodoLink{Type: l.Type.Inverse(), ID: otherID, Title: other.Title, Back: true})
+			}
+		}
+	}
+	slices.SortFunc(back, func(a, b TodoLink) int {
+		if c := strings.Compare(string(a.ID), string(b.ID)); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Type, b.Type)
+	})
+	return append(res, back...), nil
+}
+
+// titleOf returns the title of the todo with the given ID, empty if the todo doesn't exist or the
+// view may not see it. The caller must hold the lock.
+func (ld *Ledger) titleOf(id store.ID) string {
+	blob, ok := ld.blobs[id]
+	if id == "" || !ok || !ld.readable(id) {
+		return ""
+	}
+	tk, err := task.Unmarshal(blob)
+	if err != nil {
+		return ""
+	}
+	return tk.Title
+}
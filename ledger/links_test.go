@@ -0,0 +1,84 @@
+package ledger
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gotestbootcamp/go-todo-app/model"
+	"github.com/gotestbootcamp/go-todo-app/store"
+	"github.com/gotestbootcamp/go-todo-app/task"
+)
+
+func TestLinks(t *testing.T) {
+	ld := newTestLedger(t, task.DefaultWorkflow())
+	for id, title := range map[store.ID]string{"1": "login fails", "2": "can't log in", "3": "password reset"} {
+		require.NoError(t, ld.Set(id, model.New(title)))
+	}
+
+	_, err := ld.Link("2", task.Link{Type: task.Duplicates, ID: "1"})
+	require.NoError(t, err)
+	_, err = ld.Link("3", task.Link{Type: task.RelatesTo, ID: "1"})
+	require.NoError(t, err)
+	item, err := ld.Link("1", task.Link{Type: task.CausedBy, URL: "https://example.com/issues/42"})
+	require.NoError(t, err)
+	assert.Equal(t, []task.Link{{Type: task.CausedBy, URL: "https://example.com/issues/42"}}, item.Task.Links)
+	_, err = ld.Link("1", task.Link{Type: task.RelatesTo, ID: "1"})
+	assert.Error(t, err, "no links to itself")
+	_, err = ld.Link("1", task.Link{Type: task.RelatesTo, ID: "4"})
+	assert.ErrorIs(t, err, store.ErrNotFound{ID: "4"})
+	_, err = ld.Link("4", task.Link{Type: task.RelatesTo, ID: "1"})
+	assert.ErrorIs(t, err, store.ErrNotFound{ID: "4"})
+	_, err = ld.Link("1", task.Link{Type: "blocks", ID: "2"})
+	assert.Error(t, err)
+
+	links, err := ld.Links("1")
+	require.NoError(t, err)
+	assert.Equal(t, []TodoLink{
+		{Type: "caused-by", URL: "https://example.com/issues/42"},
+		{Type: "duplicated-by", ID: "2", Title: "can't log in", Back: true},
+		{Type: "relates-to", ID: "3", Title: "password reset", Back: true},
+	}, links)
+	links, err = ld.Links("2")
+	require.NoError(t, err)
+	assert.Equal(t, []TodoLink{{Type: "duplicates", ID: "1", Title: "login fails"}}, links)
+	_, err = ld.Links("4")
+	assert.ErrorIs(t, err, store.ErrNotFound{ID: "4"})
+
+	// the links survive their targets
+	require.NoError(t, ld.Delete("1"))
+	links, err = ld.Links("2")
+	require.NoError(t, err)
+	assert.Equal(t, []TodoLink{{Type: "duplicates", ID: "1"}}, links)
+	_, err = ld.Unlink("2", task.Link{Type: task.RelatesTo, ID: "1"})
+	assert.ErrorAs(t, err, &task.ErrNoLink{})
+	item, err = ld.Unlink("2", task.Link{Type: task.Duplicates, ID: "1"})
+	require.NoError(t, err)
+	assert.Empty(t, item.Task.Links)
+}
+
+func TestLinksMembers(t *testing.T) {
+	ld := newTestLedger(t, task.DefaultWorkflow())
+	alice, bob := ld.AsUser("alice", false), ld.AsUser("bob", false)
+	secret := store.ListID("secret", "1")
+	require.NoError(t, alice.Set(secret, model.New("secret")))
+	_, err := alice.AddMember("secret", "alice", RoleListAdmin)
+	require.NoError(t, err)
+	require.NoError(t, bob.Set("2", model.New("public")))
+
+	// the views link only to the todos they see, and see only their backlinks
+	_, err = bob.Link("2", task.Link{Type: task.RelatesTo, ID: string(secret)})
+	assert.ErrorIs(t, err, ErrForbidden)
+	require.NoError(t, alice.Set("3", model.New("alice's")))
+	_, err = alice.Link(secret, task.Link{Type: task.RelatesTo, ID: "3"})
+	require.NoError(t, err)
+	_, err = bob.Link("2", task.Link{Type: task.RelatesTo, ID: "3"})
+	require.NoError(t, err)
+	links, err := bob.Links("3")
+	require.NoError(t, err)
+	assert.Equal(t, []TodoLink{{Type: "relates-to", ID: "2", Title: "public", Back: true}}, links)
+	links, err = alice.Links("3")
+	require.NoError(t, err)
+	assert.Len(t, links, 2)
+}
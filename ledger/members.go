@@ -0,0 +1,328 @@
+package ledger
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"maps"
+	"sort"
+
+	apiv1 "github.com/gotestbootcamp/go-todo-app/api/v1"
+	"github.com/gotestbootcamp/go-todo-app/store"
+	"github.com/gotestbootcamp/go-todo-app/task"
+)
+
+// membersID is the ID of the item holding the members of the lists
+var membersID = store.MetaID("members")
+
+// ListRole tells what a member of a list can do with its todos
+type ListRole string
+
+const (
+	// RoleViewer can see the todos of the list
+	RoleViewer ListRole = "viewer"
+	// RoleEditor can change the todos of the list as well, and create new ones in it
+	RoleEditor ListRole = "editor"
+	// RoleListAdmin can manage the members of the list as well
+	RoleListAdmin ListRole = "admin"
+)
+
+var roleRanks = map[ListRole]int{
+	RoleViewer:    1,
+	RoleEditor:    2,
+	RoleListAdmin: 3,
+}
+
+// ParseListRole returns the role with the given name
+func ParseListRole(s string) (ListRole, error) {
+	role := ListRole(s)
+	if _, ok := roleRanks[role]; !ok {
+		return "", fmt.Errorf("invalid role %q, want viewer, editor or admin", s)
+	}
+	return role, nil
+}
+
+// Allows tells whether the role can do what the required one can
+func (r ListRole) Allows(required ListRole) bool {
+	return roleRanks[r] >= roleRanks[required]
+}
+
+// Member is a user member of a list
+type Member struct {
+	User string   `json:"user"`
+	Role ListRole `json:"role"`
+}
+
+// ToAPIv1 converts the Member in its API v1 representation
+func (m Member) ToAPIv1() apiv1.Member {
+	return apiv1.Member{User: m.User, Role: string(m.Role)}
+}
+
+// ErrNoMember is returned when the user is not a member of the list
+type ErrNoMember struct {
+	List string
+	User string
+}
+
+func (e ErrNoMember) Error() string {
+	return fmt.Sprintf("%q is not a member of list %q", e.User, e.List)
+}
+
+// ErrMemberExists is returned when inviting to a list one of its members
+type ErrMemberExists struct {
+	List string
+	User string
+}
+
+func (e ErrMemberExists) Error() string {
+	return fmt.Sprintf("%q is a member of list %q already", e.User, e.List)
+}
+
+// ErrLastAdmin is returned when a change would leave the members of a list without an admin
+type ErrLastAdmin struct {
+	List string
+}
+
+func (e ErrLastAdmin) Error() string {
+	return fmt.Sprintf("list %q must keep an admin while it has members", e.List)
+}
+
+func (ld *Ledger) loadMembers(blob store.Blob) error {
+	if err := json.Unmarshal(blob, &ld.members); err != nil {
+		return fmt.Errorf("ledger: can't decode the members of the lists: %w", err)
+	}
+	ld.membersStored = true
+	return nil
+}
+
+// private tells whether the list has members, who are then the only ones to access its todos.
+// The caller must hold the lock.
+func (ld *Ledger) private(list string) bool {
+	return len(ld.members[list]) > 0
+}
+
+// roleIn returns the role of the user of the view in the list, empty if not a member.
+// The caller must hold the lock.
+func (ld *Ledger) roleIn(list string) ListRole {
+	return ld.members[list][ld.user]
+}
+
+// canSee tells whether the view may see the todos of the list: the todos of the lists with members
+// are hidden from the users who are not. The caller must hold the lock.
+func (ld *Ledger) canSee(list string) bool {
+	return !ld.restricted() || !ld.private(list) || ld.roleIn(list) != ""
+}
+
+// readable tells whether the view may see the todo with the given ID. The caller must hold the lock.
+func (ld *Ledger) readable(id store.ID) bool {
+	list, _ := store.SplitListID(id)
+	return ld.canSee(list)
+}
+
+// checkRead fails with ErrForbidden if the view may not see the todo with the given ID.
+// The caller must hold the lock.
+func (ld *Ledger) checkRead(id store.ID) error {
+	if list, _ := store.SplitListID(id); !ld.canSee(list) {
+		return fmt.Errorf("%w: %q is not a member of list %q", ErrForbidden, ld.user, list)
+	}
+	return nil
+}
+
+// checkManage fails with ErrForbidden if the view may not manage the members of the list.
+// The caller must hold the lock.
+func (ld *Ledger) checkManage(list string) error {
+	if ld.restricted() && ld.roleIn(list) != RoleListAdmin {
+		return fmt.Errorf("%w: %q is not an admin of list %q", ErrForbidden, ld.user, list)
+	}
+	return nil
+}
+
+// Lists returns the names of the lists the view may see, i.e. holding todos or having members,
+// sorted. The todos created outside of any list are in store.DefaultList, never returned.
+func (ld *Ledger) Lists() ([]string, error) {
+	ld.lock.RLock()
+	defer ld.lock.RUnlock()
+	seen := make(map[string]bool)
+	for id := range ld.blobs {
+		if list, _ := store.SplitListID(id); list != store.DefaultList {
+			seen[list] = true
+		}
+	}
+	for list := range ld.members {
+		seen[list] = true
+	}
+	res := make([]string, 0, len(seen))
+	for list := range seen {
+		if ld.canSee(list) {
+			res = append(res, list)
+		}
+	}
+	sort.Strings(res)
+	return res, nil
+}
+
+// Members returns the members of the list, sorted by user. The lists without members are open to
+// all the users, as the todos outside of any list. Fails with ErrForbidden if the view may not see
+// the list.
+func (ld *Ledger) Members(list string) ([]Member, error) {
+	if err := store.ValidateList(list); err != nil {
+		return nil, err
+	}
+	ld.lock.RLock()
+	defer ld.lock.RUnlock()
+	if !ld.canSee(list) {
+		return nil, fmt.Errorf("%w: %q is not a member of list %q", ErrForbidden, ld.user, list)
+	}
+	res := make([]Member, 0, len(ld.members[list]))
+	for user, role := range ld.members[list] {
+		res = append(res, Member{User: user, Role: role})
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].User < res[j].User
+	})
+	return res, nil
+}
+
+// AddMember invites the user to the list, with the given role. Only the admins of the list may
+// invite, but to the lists without members, the users may invite themselves as admins, if all the
+// todos of the list are theirs. Fails with ErrMemberExists if the user is a member already.
+func (ld *Ledger) AddMember(list, user string, role ListRole) (Member, error) {
+	return ld.setMember(list, user, role, false)
+}
+
+// SetRole changes the role of the member of the list. Only the admins of the list may change the
+// roles. Fails with ErrNoMember if the user is not a member, and with ErrLastAdmin if the list
+// would have no admins left.
+func (ld *Ledger) SetRole(list, user string, role ListRole) (Member, error) {
+	return ld.setMember(list, user, role, true)
+}
+
+func (ld *Ledger) setMember(list, user string, role ListRole, existing bool) (Member, error) {
+	if err := store.ValidateList(list); err != nil {
+		return Member{}, err
+	}
+	if _, err := ParseListRole(string(role)); err != nil {
+		return Member{}, err
+	}
+	if user == "" {
+		return Member{}, fmt.Errorf("invalid empty user")
+	}
+
+	ld.lock.Lock()
+	defer ld.lock.Unlock()
+	check := ld.checkInvite
+	if existing {
+		check = func(list, _ string, _ ListRole) error {
+			return ld.checkManage(list)
+		}
+	}
+	if err := check(list, user, role); err != nil {
+		return Member{}, err
+	}
+	_, ok := ld.members[list][user]
+	switch {
+	case existing && !ok:
+		return Member{}, ErrNoMember{List: list, User: user}
+	case !existing && ok:
+		return Member{}, ErrMemberExists{List: list, User: user}
+	}
+	members := maps.Clone(ld.members[list])
+	if members == nil {
+		members = make(map[string]ListRole)
+	}
+	members[user] = role
+	if err := ld.saveMembers(list, members); err != nil {
+		return Member{}, err
+	}
+	slog.Info("ledger: setMember: member set", "list", list, "user", user, "role", role)
+	return Member{User: user, Role: role}, nil
+}
+
+// checkInvite fails with ErrForbidden if the view may not make the user a member of the list with
+// the given role. The caller must hold the lock.
+func (ld *Ledger) checkInvite(list, user string, role ListRole) error {
+	if !ld.restricted() || ld.private(list) {
+		return ld.checkManage(list)
+	}
+	// the first member of a list makes it private: the users may do so only with the lists of
+	// their todos alone
+	if user != ld.user || role != RoleListAdmin {
+		return fmt.Errorf("%w: the first member of list %q must be %q, as admin", ErrForbidden, list, ld.user)
+	}
+	for id, blob := range ld.blobs {
+		if l, _ := store.SplitListID(id); l != list {
+			continue
+		}
+		tk, err := task.Unmarshal(blob)
+		if err != nil {
+			return err
+		}
+		if tk.Owner != ld.user {
+			return fmt.Errorf("%w: list %q holds todos of others", ErrForbidden, list)
+		}
+	}
+	return nil
+}
+
+// RemoveMember removes the user from the members of the list. Only the admins of the list may
+// remove the members, but any member may leave. Removing the last member opens the list to all the
+// users again. Fails with ErrNoMember if the user is not a member, and with ErrLastAdmin if the
+// members left would have no admin.
+func (ld *Ledger) RemoveMember(list, user string) error {
+	if err := store.ValidateList(list); err != nil {
+		return err
+	}
+	ld.lock.Lock()
+	defer ld.lock.Unlock()
+	if user != ld.user {
+		if err := ld.checkManage(list); err != nil {
+			return err
+		}
+	}
+	if _, ok := ld.members[list][user]; !ok {
+		return ErrNoMember{List: list, User: user}
+	}
+	members := maps.Clone(ld.members[list])
+	delete(members, user)
+	if err := ld.saveMembers(list, members); err != nil {
+		return err
+	}
+	slog.Info("ledger: RemoveMember: member removed", "list", list, "user", user)
+	return nil
+}
+
+// saveMembers stores the given members of the list, which must keep an admin unless empty.
+// The caller must hold the lock.
+func (ld *Ledger) saveMembers(list string, members map[string]ListRole) error {
+	hasAdmin := false
+	for _, role := range members {
+		hasAdmin = hasAdmin || role == RoleListAdmin
+	}
+	if len(members) > 0 && !hasAdmin {
+		return ErrLastAdmin{List: list}
+	}
+	all := maps.Clone(ld.members)
+	if all == nil {
+		all = make(map[string]map[string]ListRole)
+	}
+	if len(members) == 0 {
+		delete(all, list)
+	} else {
+		all[list] = members
+	}
+	blob, err := json.Marshal(all)
+	if err != nil {
+		return err
+	}
+	if ld.membersStored {
+		err = ld.storage().Save(membersID, blob)
+	} else {
+		err = ld.storage().Create(membersID, blob)
+	}
+	if err != nil {
+		return err
+	}
+	ld.members = all
+	ld.membersStored = true
+	return nil
+}
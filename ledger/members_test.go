@@ -0,0 +1,160 @@
+package ledger
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gotestbootcamp/go-todo-app/model"
+	"github.com/gotestbootcamp/go-todo-app/store"
+	"github.com/gotestbootcamp/go-todo-app/task"
+)
+
+func TestListRole(t *testing.T) {
+	role, err := ParseListRole("editor")
+	require.NoError(t, err)
+	assert.Equal(t, RoleEditor, role)
+	_, err = ParseListRole("owner")
+	assert.Error(t, err)
+
+	assert.True(t, RoleListAdmin.Allows(RoleEditor))
+	assert.True(t, RoleEditor.Allows(RoleEditor))
+	assert.False(t, RoleViewer.Allows(RoleEditor))
+	assert.False(t, ListRole("").Allows(RoleViewer))
+}
+
+func TestMembers(t *testing.T) {
+	ld := newTestLedger(t, task.DefaultWorkflow())
+	alice, bob, carol := ld.AsUser("alice", false), ld.AsUser("bob", false), ld.AsUser("carol", false)
+	work := func(id string) store.ID {
+		return store.ListID("work", store.ID(id))
+	}
+	require.NoError(t, alice.Set(work("1"), model.New("report")))
+	require.NoError(t, bob.Set("2", model.New("laundry")))
+
+	// the first member of a list must be its creator, as admin, holding all its todos
+	_, err := bob.AddMember("work", "bob", RoleListAdmin)
+	assert.ErrorIs(t, err, ErrForbidden)
+	_, err = alice.AddMember("work", "bob", RoleListAdmin)
+	assert.ErrorIs(t, err, ErrForbidden)
+	_, err = alice.AddMember("work", "alice", RoleListAdmin)
+	require.NoError(t, err)
+	_, err = alice.AddMember("work", "alice", RoleEditor)
+	assert.ErrorAs(t, err, &ErrMemberExists{})
+	_, err = alice.AddMember("work", "bob", RoleViewer)
+	require.NoError(t, err)
+	members, err := bob.Members("work")
+	require.NoError(t, err)
+	assert.Equal(t, []Member{{User: "alice", Role: RoleListAdmin}, {User: "bob", Role: RoleViewer}}, members)
+
+	// the viewers see the todos, and the other users don't
+	_, _, err = bob.GetItem(work("1"))
+	require.NoError(t, err)
+	_, _, err = carol.GetItem(work("1"))
+	assert.ErrorIs(t, err, ErrForbidden)
+	_, err = carol.Members("work")
+	assert.ErrorIs(t, err, ErrForbidden)
+	_, err = carol.History(work("1"))
+	assert.ErrorIs(t, err, ErrForbidden)
+	items, _, err := carol.List(Query{})
+	require.NoError(t, err)
+	assert.Equal(t, []store.ID{"2"}, ids(items))
+	lists, err := carol.Lists()
+	require.NoError(t, err)
+	assert.Empty(t, lists)
+	lists, err = bob.Lists()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"work"}, lists)
+
+	// only the editors change them, whoever owns them
+	_, err = bob.TagTodo(work("1"), "q3")
+	assert.ErrorIs(t, err, ErrForbidden)
+	assert.ErrorIs(t, bob.Set(work("2"), model.New("slides")), ErrForbidden)
+	_, err = bob.SetRole("work", "bob", RoleEditor)
+	assert.ErrorIs(t, err, ErrForbidden, "only the admins change the roles")
+	_, err = alice.SetRole("work", "bob", RoleEditor)
+	require.NoError(t, err)
+	_, err = bob.TagTodo(work("1"), "q3")
+	require.NoError(t, err)
+	require.NoError(t, bob.Set(work("2"), model.New("slides")))
+	_, err = alice.SetRole("work", "carol", RoleEditor)
+	assert.ErrorAs(t, err, &ErrNoMember{})
+
+	// moving the todos in the list needs the editor role as well
+	require.NoError(t, bob.Set("3", model.New("ironing")))
+	require.NoError(t, carol.Set("4", model.New("groceries")))
+	moves := func(title string) Wants {
+		return func(todo model.Todo) bool { return todo.Title == title }
+	}
+	report, err := carol.MoveAll(moves("groceries"), "work")
+	require.NoError(t, err)
+	require.Len(t, report, 1)
+	assert.ErrorIs(t, report[0].Err, ErrForbidden)
+	report, err = bob.MoveAll(moves("ironing"), "work")
+	require.NoError(t, err)
+	require.Len(t, report, 1)
+	require.NoError(t, report[0].Err)
+	assert.Equal(t, work("3"), report[0].NewID)
+
+	// the lists keep an admin
+	_, err = alice.SetRole("work", "alice", RoleEditor)
+	assert.ErrorAs(t, err, &ErrLastAdmin{})
+	assert.ErrorAs(t, alice.RemoveMember("work", "alice"), &ErrLastAdmin{})
+	assert.ErrorIs(t, bob.RemoveMember("work", "alice"), ErrForbidden)
+	// the members leave when they like
+	require.NoError(t, bob.RemoveMember("work", "bob"))
+	_, _, err = bob.GetItem(work("1"))
+	assert.ErrorIs(t, err, ErrForbidden)
+
+	// the admins of the ledger see and change all the lists
+	admin := ld.AsUser("root", true)
+	_, err = admin.TagTodo(work("1"), "q4")
+	require.NoError(t, err)
+	_, err = admin.AddMember("work", "carol", RoleViewer)
+	require.NoError(t, err)
+
+	// the members survive restarts
+	reloaded, err := New(ld.storer)
+	require.NoError(t, err)
+	members, err = reloaded.Members("work")
+	require.NoError(t, err)
+	assert.Equal(t, []Member{{User: "alice", Role: RoleListAdmin}, {User: "carol", Role: RoleViewer}}, members)
+
+	// removing the last member opens the list again
+	require.NoError(t, alice.RemoveMember("work", "carol"))
+	require.NoError(t, alice.RemoveMember("work", "alice"))
+	_, _, err = carol.GetItem(work("1"))
+	require.NoError(t, err)
+}
+
+func ids(items Items) []store.ID {
+	res := make([]store.ID, 0, len(items))
+	for _, item := range items {
+		res = append(res, item.ID)
+	}
+	return res
+}
+
+func TestSubscribeMembers(t *testing.T) {
+	ld, err := New(store.NewNotifier(newTestMemory(t)))
+	require.NoError(t, err)
+	defer ld.Close()
+	alice, bob := ld.AsUser("alice", false), ld.AsUser("bob", false)
+	_, err = alice.AddMember("work", "alice", RoleListAdmin)
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	// bob subscribes first: the feed is shared, and sees all the todos anyway
+	bobs, err := bob.Subscribe(ctx, 0)
+	require.NoError(t, err)
+	alices, err := alice.Subscribe(ctx, 0)
+	require.NoError(t, err)
+
+	require.NoError(t, alice.Set(store.ListID("work", "1"), model.New("report")))
+	require.NoError(t, bob.Set("2", model.New("laundry")))
+	assert.Equal(t, store.ListID("work", "1"), receive(t, alices).Item.ID)
+	assert.Equal(t, store.ID("2"), receive(t, alices).Item.ID)
+	assert.Equal(t, store.ID("2"), receive(t, bobs).Item.ID, "the todos of work are hidden")
+}
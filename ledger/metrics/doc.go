@@ -0,0 +1,3 @@
+// Package metrics publishes the metrics of the ledger through expvar or Prometheus: the todos by
+// status, and the contention of the lock of the ledger
+package metrics
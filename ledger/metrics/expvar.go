@@ -0,0 +1,31 @@
+package metrics
+
+import (
+	"expvar"
+
+	"github.com/gotestbootcamp/go-todo-app/ledger"
+)
+
+// PublishExpvar publishes the metrics of the ledger, which must not be a restricted view, as a
+// expvar map with the given name, gathered when served by the expvar handler on /debug/vars:
+// - todos: the number of the todos not archived, by status
+// - lock_waits and lock_read_waits: the number of times the mutations and the reads waited for the
+// lock of the ledger
+// - lock_wait_nanos and lock_read_wait_nanos: the time spent waiting for the lock, in nanoseconds
+// Like expvar.Publish, panics if the name is already in use.
+func PublishExpvar(name string, ld *ledger.Ledger) {
+	expvar.Publish(name, expvar.Func(func() any {
+		vars := map[string]any{}
+		if counts, err := ld.CountByStatus(); err == nil {
+			vars["todos"] = counts
+		} else {
+			vars["todos_error"] = err.Error()
+		}
+		stats := ld.LockStats()
+		vars["lock_waits"] = stats.Waits
+		vars["lock_read_waits"] = stats.ReadWaits
+		vars["lock_wait_nanos"] = stats.Waited.Nanoseconds()
+		vars["lock_read_wait_nanos"] = stats.ReadWaited.Nanoseconds()
+		return vars
+	}))
+}
@@ -0,0 +1,62 @@
+package metrics
+
+import (
+	"expvar"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gotestbootcamp/go-todo-app/ledger"
+	"github.com/gotestbootcamp/go-todo-app/model"
+	"github.com/gotestbootcamp/go-todo-app/store"
+	"github.com/gotestbootcamp/go-todo-app/task"
+)
+
+func newTestLedger(t *testing.T) *ledger.Ledger {
+	st, err := store.NewMemory()
+	require.NoError(t, err)
+	ld, err := ledger.New(st)
+	require.NoError(t, err)
+	require.NoError(t, ld.Set("1", model.New("write the docs")))
+	require.NoError(t, ld.Set("2", model.New("fix the bug")))
+	_, err = ld.Transition("2", task.Assigned)
+	require.NoError(t, err)
+	return ld
+}
+
+func TestPrometheus(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	_, err := NewPrometheus(reg, "test", newTestLedger(t))
+	require.NoError(t, err)
+
+	expected := `
+# HELP test_todos Number of the todos not archived.
+# TYPE test_todos gauge
+test_todos{status="assigned"} 1
+test_todos{status="completed"} 0
+test_todos{status="deleted"} 0
+test_todos{status="pending"} 1
+# HELP test_ledger_lock_waits_total Number of the times the lockers waited for the lock of the ledger.
+# TYPE test_ledger_lock_waits_total counter
+test_ledger_lock_waits_total{mode="read"} 0
+test_ledger_lock_waits_total{mode="write"} 0
+`
+	assert.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(expected),
+		"test_todos", "test_ledger_lock_waits_total"))
+
+	// collectors can't be registered twice
+	_, err = NewPrometheus(reg, "test", newTestLedger(t))
+	assert.Error(t, err)
+}
+
+func TestExpvar(t *testing.T) {
+	PublishExpvar("test_ledger", newTestLedger(t))
+	assert.JSONEq(t, `{
+		"todos": {"pending": 1, "assigned": 1, "completed": 0, "deleted": 0},
+		"lock_waits": 0, "lock_read_waits": 0, "lock_wait_nanos": 0, "lock_read_wait_nanos": 0
+	}`, expvar.Get("test_ledger").String())
+}
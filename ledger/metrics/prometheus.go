@@ -0,0 +1,59 @@
+package metrics
+
+import (
+	"github.com/gotestbootcamp/go-todo-app/ledger"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var _ prometheus.Collector = &Prometheus{}
+
+// Prometheus exposes the metrics of the ledger as a Prometheus collector, gathering them on scrape:
+// - <namespace>_todos: gauge of the todos not archived, by status
+// - <namespace>_ledger_lock_waits_total: counter of the times the lockers waited for the lock of
+// the ledger, by mode, "read" or "write"
+// - <namespace>_ledger_lock_wait_seconds_total: counter of the time spent waiting for the lock, by mode
+type Prometheus struct {
+	ld     *ledger.Ledger
+	todos  *prometheus.Desc
+	waits  *prometheus.Desc
+	waited *prometheus.Desc
+}
+
+// NewPrometheus creates the collector of the metrics of the ledger, which must not be a restricted
+// view, and registers it in the given registerer, e.g. prometheus.DefaultRegisterer
+func NewPrometheus(reg prometheus.Registerer, namespace string, ld *ledger.Ledger) (*Prometheus, error) {
+	pm := &Prometheus{
+		ld: ld,
+		todos: prometheus.NewDesc(prometheus.BuildFQName(namespace, "", "todos"),
+			"Number of the todos not archived.", []string{"status"}, nil),
+		waits: prometheus.NewDesc(prometheus.BuildFQName(namespace, "ledger", "lock_waits_total"),
+			"Number of the times the lockers waited for the lock of the ledger.", []string{"mode"}, nil),
+		waited: prometheus.NewDesc(prometheus.BuildFQName(namespace, "ledger", "lock_wait_seconds_total"),
+			"Time spent waiting for the lock of the ledger.", []string{"mode"}, nil),
+	}
+	if err := reg.Register(pm); err != nil {
+		return nil, err
+	}
+	return pm, nil
+}
+
+func (pm *Prometheus) Describe(ch chan<- *prometheus.Desc) {
+	ch <- pm.todos
+	ch <- pm.waits
+	ch <- pm.waited
+}
+
+func (pm *Prometheus) Collect(ch chan<- prometheus.Metric) {
+	counts, err := pm.ld.CountByStatus()
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(pm.todos, err)
+	}
+	for status, count := range counts {
+		ch <- prometheus.MustNewConstMetric(pm.todos, prometheus.GaugeValue, float64(count), string(status))
+	}
+	stats := pm.ld.LockStats()
+	ch <- prometheus.MustNewConstMetric(pm.waits, prometheus.CounterValue, float64(stats.Waits), "write")
+	ch <- prometheus.MustNewConstMetric(pm.waits, prometheus.CounterValue, float64(stats.ReadWaits), "read")
+	ch <- prometheus.MustNewConstMetric(pm.waited, prometheus.CounterValue, stats.Waited.Seconds(), "write")
+	ch <- prometheus.MustNewConstMetric(pm.waited, prometheus.CounterValue, stats.ReadWaited.Seconds(), "read")
+}
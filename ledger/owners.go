@@ -0,0 +1,79 @@
+package ledger
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/gotestbootcamp/go-todo-app/store"
+	"github.com/gotestbootcamp/go-todo-app/task"
+)
+
+// ErrForbidden is returned when a view returned by AsUser changes a todo of another user, or
+// does what only the admins can
+var ErrForbidden = errors.New("forbidden")
+
+// restricted tells whether the view may only change the todos of its user
+func (ld *Ledger) restricted() bool {
+	return ld.user != "" && !ld.admin
+}
+
+// CanChange fails with ErrForbidden if the view may not change the todo with the given ID, for
+// the changes made of several mutations, which must not stop halfway
+func (ld *Ledger) CanChange(id store.ID) error {
+	ld.lock.RLock()
+	defer ld.lock.RUnlock()
+	return ld.checkOwner(id, ld.blobs[id])
+}
+
+// checkOwner fails with ErrForbidden if the view may not change the encoded todo with the given ID:
+// the todos of the lists with members may be changed by their editors, and the others by their
+// owner. The nil blob, a todo yet to be created, is allowed outside of the lists with members.
+func (ld *Ledger) checkOwner(id store.ID, blob store.Blob) error {
+	if !ld.restricted() {
+		return nil
+	}
+	if list, _ := store.SplitListID(id); ld.private(list) {
+		if !ld.roleIn(list).Allows(RoleEditor) {
+			return fmt.Errorf("%w: %q is not an editor of list %q", ErrForbidden, ld.user, list)
+		}
+		return nil
+	}
+	if blob == nil {
+		return nil
+	}
+	tk, err := task.Unmarshal(blob)
+	if err != nil {
+		return err
+	}
+	if tk.Owner != ld.user {
+		return fmt.Errorf("%w: %v is not a todo of %q", ErrForbidden, id, ld.user)
+	}
+	return nil
+}
+
+// checkAdmin fails with ErrForbidden if the view may only change the todos of its user,
+// as it may not do what the admins do
+func (ld *Ledger) checkAdmin(what string) error {
+	if ld.restricted() {
+		return fmt.Errorf("%w: only the admins can %s", ErrForbidden, what)
+	}
+	return nil
+}
+
+// own returns the encoded todo, about to be created, owned by the user of the view, if it has one
+// and the todo has no owner yet. Unless admin, the user may not create the todos of the others, and
+// owns them instead.
+func (ld *Ledger) own(blob []byte) ([]byte, error) {
+	if ld.user == "" {
+		return blob, nil
+	}
+	tk, err := task.Unmarshal(blob)
+	if err != nil {
+		return nil, err
+	}
+	if tk.Owner == ld.user || tk.Owner != "" && !ld.restricted() {
+		return blob, nil
+	}
+	tk.Owner = ld.user
+	return task.Marshal(tk)
+}
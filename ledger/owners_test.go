@@ -0,0 +1,130 @@
+package ledger
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gotestbootcamp/go-todo-app/model"
+	"github.com/gotestbootcamp/go-todo-app/store"
+	"github.com/gotestbootcamp/go-todo-app/task"
+)
+
+func TestAsUser(t *testing.T) {
+	ld := newTestLedger(t, task.DefaultWorkflow())
+	alice, bob := ld.AsUser("alice", false), ld.AsUser("bob", false)
+	admin := ld.AsUser("root", true)
+
+	require.NoError(t, alice.Set("1", model.New("groceries")))
+	require.NoError(t, bob.Set("2", model.New("laundry")))
+	require.NoError(t, ld.Set("3", model.New("legacy")))
+	item, _, err := ld.GetItem("1")
+	require.NoError(t, err)
+	assert.Equal(t, "alice", item.Task.Owner)
+	assert.Equal(t, "alice", item.ToAPIv1().Todo.Owner)
+	item, _, err = ld.GetItem("3")
+	require.NoError(t, err)
+	assert.Empty(t, item.Task.Owner)
+
+	// the users change only their todos
+	_, err = alice.Transition("1", task.Deleted)
+	require.NoError(t, err)
+	for name, err := range map[string]error{
+		"set":      alice.Set("2", model.New("mine")),
+		"delete":   alice.Delete("2"),
+		"unowned":  alice.Delete("3"),
+		"tag":      second(alice.TagTodo("2", "home")),
+		"comment":  second(alice.AddComment("2", "hi")),
+		"priority": second(alice.SetPriority("2", task.PriorityHigh)),
+		"revert":   second(alice.Revert("2", 1)),
+		"rename":   alice.RenameTag("home", "house"),
+		"archive":  second(alice.Archive(time.Hour)),
+		"change":   alice.CanChange("2"),
+	} {
+		assert.ErrorIs(t, err, ErrForbidden, name)
+	}
+	report, err := alice.CompleteAll(func(model.Todo) bool { return true })
+	require.NoError(t, err)
+	for _, res := range report {
+		assert.ErrorIs(t, res.Err, ErrForbidden, res.ID)
+	}
+	item, _, err = ld.GetItem("2")
+	require.NoError(t, err)
+	assert.Equal(t, "laundry", item.Todo.Title)
+	// nor undo the changes of the others, but only theirs
+	require.NoError(t, bob.Set("2", model.New("ironing")))
+	items, err := alice.Undo()
+	require.NoError(t, err)
+	assert.Equal(t, []store.ID{"1"}, idsOf(items))
+	item, _, err = ld.GetItem("2")
+	require.NoError(t, err)
+	assert.Equal(t, "ironing", item.Todo.Title)
+
+	// the admins change all of them
+	require.NoError(t, admin.Delete("3"))
+	_, err = admin.TagTodo("2", "home")
+	require.NoError(t, err)
+
+	// handing over a todo
+	owner := "alice"
+	_, _, err = bob.PatchIf("2", Patch{Owner: &owner}, AnyRevision)
+	require.NoError(t, err)
+	_, err = alice.TagTodo("2", "mine")
+	require.NoError(t, err)
+	_, err = bob.TagTodo("2", "mine")
+	assert.ErrorIs(t, err, ErrForbidden)
+
+	items, _, err = ld.List(Query{Owner: "alice"})
+	require.NoError(t, err)
+	assert.Len(t, items, 2)
+	items, _, err = ld.List(Query{Owner: "bob"})
+	require.NoError(t, err)
+	assert.Empty(t, items)
+}
+
+func TestAsUserOwner(t *testing.T) {
+	ld := newTestLedger(t, task.DefaultWorkflow())
+	alice, admin := ld.AsUser("alice", false), ld.AsUser("root", true)
+	tk := task.New("laundry")
+	tk.Owner = "bob"
+
+	// the users create only their todos, the admins those of anyone
+	report, err := alice.CreateAll(store.DefaultList, []task.Task{tk})
+	require.NoError(t, err)
+	require.NoError(t, report[0].Err)
+	item, _, err := ld.GetItem(report[0].ID)
+	require.NoError(t, err)
+	assert.Equal(t, "alice", item.Task.Owner)
+	report, err = admin.CreateAll(store.DefaultList, []task.Task{tk})
+	require.NoError(t, err)
+	require.NoError(t, report[0].Err)
+	item, _, err = ld.GetItem(report[0].ID)
+	require.NoError(t, err)
+	assert.Equal(t, "bob", item.Task.Owner)
+}
+
+// second returns the second of the values, the error of the calls returning a value and an error
+func second[T any](_ T, err error) error {
+	return err
+}
+
+func TestAsUserRecurring(t *testing.T) {
+	ld := newTestLedger(t, task.DefaultWorkflow())
+	alice := ld.AsUser("alice", false)
+	todo := model.New("standup")
+	require.NoError(t, alice.Set("1", todo))
+	_, err := alice.Schedule("1", Schedule{Recur: "daily"})
+	require.NoError(t, err)
+	_, err = alice.Transition("1", task.Assigned)
+	require.NoError(t, err)
+	_, err = alice.Transition("1", task.Completed)
+	require.NoError(t, err)
+
+	items, err := ld.Filter(func(todo model.Todo) bool { return todo.Status == "pending" })
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	assert.NotEqual(t, store.ID("1"), items[0].ID)
+	assert.Equal(t, "alice", items[0].Task.Owner)
+}
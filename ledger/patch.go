@@ -0,0 +1,165 @@
+package ledger
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	apiv1 "github.com/gotestbootcamp/go-todo-app/api/v1"
+	"github.com/gotestbootcamp/go-todo-app/model"
+	"github.com/gotestbootcamp/go-todo-app/store"
+	"github.com/gotestbootcamp/go-todo-app/task"
+)
+
+// Patch are the changes to the fields of a todo: the nil ones are left as they are
+type Patch struct {
+	Title       *string
+	Description *string
+	// Assignee assigns the todo, which can't be assigned already
+	Assignee *string
+	Status   *task.Status
+	Priority *task.Priority
+	// Tags replaces the tags attached to the todo
+	Tags *[]string
+	// Contexts replaces the contexts of the todo
+	Contexts *[]string
+	// Schedule replaces when the todo is due, when to remind of it, and how it recurs
+	Schedule *Schedule
+	// Owner hands the todo over to another user. Empty leaves the todo to no user.
+	Owner *string
+	// Fields sets the custom fields of the todo, which its list must define, see DefineField;
+	// the empty values unset the fields, and the ones missing are left as they are
+	Fields *map[string]string
+}
+
+// PatchIf applies the patch to the todo if its revision is the expected one, see SetIf,
+// and returns the updated todo along with its new revision.
+// Fails with store.ErrNotFound if the todo doesn't exist.
+func (ld *Ledger) PatchIf(id store.ID, patch Patch, expected int) (Item, int, error) {
+	return ld.PatchFuncIf(id, func(Item) (Patch, error) {
+		return patch, nil
+	}, expected)
+}
+
+// PatchFuncIf is like PatchIf, but the patch is returned by fn given the current todo, which no other
+// mutation can change until the patch is applied, whether the revision is checked or not.
+func (ld *Ledger) PatchFuncIf(id store.ID, fn func(Item) (Patch, error), expected int) (Item, int, error) {
+	item, rev, unblocked, err := ld.update(id, expected, func(prev store.Blob) (model.Todo, store.Blob, error) {
+		if prev == nil {
+			return model.Todo{}, nil, store.ErrNotFound{ID: id}
+		}
+		cur, err := newItem(id, prev)
+		if err != nil {
+			return model.Todo{}, nil, err
+		}
+		patch, err := fn(cur)
+		if err != nil {
+			return model.Todo{}, nil, err
+		}
+		if patch.Fields != nil {
+			if err := ld.fieldDefs.patch(id, cur.Task, *patch.Fields); err != nil {
+				return model.Todo{}, nil, err
+			}
+		}
+		base, err := patchTask(*cur.Task, patch)
+		if err != nil {
+			return model.Todo{}, nil, err
+		}
+		todo, err := patchTodo(*cur.Todo, patch)
+		if err != nil {
+			return model.Todo{}, nil, err
+		}
+		slog.Debug("ledger: PatchIf: patching object", "id", id, "todo", todo)
+		return todo, base, nil
+	})
+	if err != nil {
+		return Item{}, 0, err
+	}
+	ld.notifyUnblocked(unblocked)
+	return item, rev, nil
+}
+
+// patchTodo applies the changes of the patch to the fields of the todo
+func patchTodo(todo model.Todo, patch Patch) (model.Todo, error) {
+	if patch.Title != nil {
+		todo.Title = *patch.Title
+		todo.LastUpdateTime = time.Now()
+	}
+	if patch.Description != nil {
+		if err := todo.Describe(*patch.Description); err != nil {
+			return todo, err
+		}
+	}
+	if patch.Assignee != nil {
+		if err := todo.Assign(*patch.Assignee); err != nil {
+			return todo, err
+		}
+	}
+	if patch.Status != nil {
+		todo.Status = apiv1.Status(*patch.Status)
+		todo.LastUpdateTime = time.Now()
+	}
+	if patch.Priority != nil || patch.Tags != nil || patch.Contexts != nil || patch.Schedule != nil || patch.Owner != nil || patch.Fields != nil {
+		todo.LastUpdateTime = time.Now()
+	}
+	return todo, nil
+}
+
+// patchTask applies the changes of the patch to the fields of the task a Todo doesn't hold,
+// and returns the encoded task
+func patchTask(tk task.Task, patch Patch) (store.Blob, error) {
+	if patch.Priority != nil {
+		if !patch.Priority.Valid() {
+			return nil, fmt.Errorf("invalid priority %d", *patch.Priority)
+		}
+		tk.Priority = *patch.Priority
+	}
+	if patch.Tags != nil {
+		tk.Tags = nil
+		for _, tag := range *patch.Tags {
+			if err := task.ValidateTag(tag); err != nil {
+				return nil, err
+			}
+			if !slices.Contains(tk.Tags, tag) {
+				tk.Tags = append(tk.Tags, tag)
+			}
+		}
+	}
+	if patch.Contexts != nil {
+		tk.Contexts = nil
+		for _, ctx := range *patch.Contexts {
+			if err := task.ValidateContext(ctx); err != nil {
+				return nil, err
+			}
+			if !slices.Contains(tk.Contexts, ctx) {
+				tk.Contexts = append(tk.Contexts, ctx)
+			}
+		}
+	}
+	if patch.Schedule != nil {
+		tk.Due = patch.Schedule.Due
+		tk.Remind = patch.Schedule.Remind
+		tk.Recur = patch.Schedule.Recur
+	}
+	if patch.Owner != nil {
+		tk.Owner = *patch.Owner
+	}
+	return task.Marshal(tk)
+}
+
+// CompleteIf completes the assigned todo if its revision is the expected one, see SetIf,
+// and returns the updated todo along with its new revision. The todo is completed as it is,
+// like PatchFuncIf does, not to undo the changes made meanwhile.
+// Fails with store.ErrNotFound if the todo doesn't exist.
+func (ld *Ledger) CompleteIf(id store.ID, expected int) (Item, int, error) {
+	return ld.PatchFuncIf(id, func(cur Item) (Patch, error) {
+		todo := *cur.Todo
+		if err := todo.Complete(); err != nil {
+			return Patch{}, err
+		}
+		slog.Debug("ledger: CompleteIf: completing object", "id", id)
+		status := task.Status(todo.Status)
+		return Patch{Status: &status}, nil
+	}, expected)
+}
@@ -0,0 +1,123 @@
+package ledger
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	apiv1 "github.com/gotestbootcamp/go-todo-app/api/v1"
+	"github.com/gotestbootcamp/go-todo-app/model"
+	"github.com/gotestbootcamp/go-todo-app/store"
+	"github.com/gotestbootcamp/go-todo-app/task"
+)
+
+func TestPatchIf(t *testing.T) {
+	ld := newTestLedger(t, task.DefaultWorkflow())
+	require.NoError(t, ld.Set("1", model.New("foo")))
+
+	title, assignee := "bar", "fede"
+	priority := task.PriorityHigh
+	tags := []string{"home", "home", "urgent"}
+	contexts := []string{"office", "phone"}
+	due := time.Now().Add(time.Hour).Truncate(time.Second)
+	item, rev, err := ld.PatchIf("1", Patch{
+		Title:    &title,
+		Assignee: &assignee,
+		Priority: &priority,
+		Tags:     &tags,
+		Contexts: &contexts,
+		Schedule: &Schedule{Due: &due},
+	}, 1)
+	require.NoError(t, err)
+	assert.Equal(t, 2, rev)
+	assert.Equal(t, "bar", item.Todo.Title)
+	assert.Equal(t, apiv1.Assigned, item.Todo.Status)
+	assert.Equal(t, task.PriorityHigh, item.Task.Priority)
+	assert.Equal(t, []string{"home", "urgent"}, item.Task.Tags)
+	assert.Equal(t, []string{"office", "phone"}, item.Task.Contexts)
+	assert.True(t, due.Equal(*item.Task.Due))
+
+	// the fields not patched are kept
+	tags = nil
+	item, rev, err = ld.PatchIf("1", Patch{Tags: &tags}, AnyRevision)
+	require.NoError(t, err)
+	assert.Equal(t, 3, rev)
+	assert.Empty(t, item.Task.Tags)
+	assert.Equal(t, []string{"office", "phone"}, item.Task.Contexts)
+	assert.Equal(t, task.PriorityHigh, item.Task.Priority)
+
+	_, _, err = ld.PatchIf("1", Patch{Title: &title}, 2)
+	assert.ErrorIs(t, err, store.ErrConflict{ID: "1", Expected: 2, Actual: 3})
+	_, _, err = ld.PatchIf("2", Patch{Title: &title}, AnyRevision)
+	assert.ErrorIs(t, err, store.ErrNotFound{ID: "2"})
+	_, _, err = ld.PatchIf("1", Patch{Assignee: &assignee}, AnyRevision)
+	assert.ErrorIs(t, err, model.ErrAlreadyAssigned)
+	contexts = []string{"@office"}
+	_, _, err = ld.PatchIf("1", Patch{Contexts: &contexts}, AnyRevision)
+	assert.Error(t, err)
+	bad := task.Priority(7)
+	_, _, err = ld.PatchIf("1", Patch{Priority: &bad}, AnyRevision)
+	assert.Error(t, err)
+
+	rev, err = ld.Revision("1")
+	require.NoError(t, err)
+	assert.Equal(t, 3, rev)
+}
+
+func TestCompleteIf(t *testing.T) {
+	ld := newTestLedger(t, task.DefaultWorkflow())
+	require.NoError(t, ld.Set("1", model.New("foo")))
+	_, _, err := ld.CompleteIf("1", AnyRevision)
+	assert.ErrorIs(t, err, model.ErrNotAssigned)
+	_, _, err = ld.CompleteIf("2", AnyRevision)
+	assert.ErrorIs(t, err, store.ErrNotFound{ID: "2"})
+
+	// the changes made while completing the todo are kept
+	assignee := "fede"
+	_, _, err = ld.PatchIf("1", Patch{Assignee: &assignee}, AnyRevision)
+	require.NoError(t, err)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := range 100 {
+			title := fmt.Sprintf("foo %d", i)
+			_, _, err := ld.PatchIf("1", Patch{Title: &title}, AnyRevision)
+			assert.NoError(t, err)
+		}
+	}()
+	_, _, err = ld.CompleteIf("1", AnyRevision)
+	require.NoError(t, err)
+	wg.Wait()
+	todo, err := ld.Get("1")
+	require.NoError(t, err)
+	assert.Equal(t, "foo 99", todo.Title)
+	assert.EqualValues(t, task.Completed, todo.Status)
+}
+
+func TestPatchFuncIf(t *testing.T) {
+	ld := newTestLedger(t, task.DefaultWorkflow())
+	require.NoError(t, ld.Set("1", model.New("foo")))
+
+	// the patch is computed from the current todo
+	item, _, err := ld.PatchFuncIf("1", func(cur Item) (Patch, error) {
+		title := cur.Todo.Title + "bar"
+		return Patch{Title: &title}, nil
+	}, AnyRevision)
+	require.NoError(t, err)
+	assert.Equal(t, "foobar", item.Todo.Title)
+
+	failed := errors.New("failed")
+	_, _, err = ld.PatchFuncIf("1", func(Item) (Patch, error) {
+		return Patch{}, failed
+	}, AnyRevision)
+	assert.ErrorIs(t, err, failed)
+	todo, err := ld.Get("1")
+	require.NoError(t, err)
+	assert.Equal(t, "foobar", todo.Title)
+}
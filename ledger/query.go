@@ -0,0 +1,316 @@
+package ledger
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gotestbootcamp/go-todo-app/index"
+	"github.com/gotestbootcamp/go-todo-app/model"
+	"github.com/gotestbootcamp/go-todo-app/store"
+	"github.com/gotestbootcamp/go-todo-app/task"
+)
+
+// ErrInvalidPageToken is returned by List when the page token wasn't returned by a List with the same sort
+var ErrInvalidPageToken = errors.New("ledger: invalid page token")
+
+// keyTime encodes the times of the sort keys, in UTC, so that they sort as strings
+const keyTime = "2006-01-02T15:04:05.000000000Z"
+
+// sortFields extract the sort keys of the todos, comparing as strings. False if the todo has no value.
+var sortFields = map[string]func(id store.ID, tk task.Task) (string, bool){
+	"id": func(id store.ID, tk task.Task) (string, bool) {
+		return idKey(id), true
+	},
+	"title": func(id store.ID, tk task.Task) (string, bool) {
+		return strings.ToLower(tk.Title), true
+	},
+	"status": func(id store.ID, tk task.Task) (string, bool) {
+		return string(tk.Status), true
+	},
+	"owner": func(id store.ID, tk task.Task) (string, bool) {
+		return tk.Owner, tk.Owner != ""
+	},
+	"priority": func(id store.ID, tk task.Task) (string, bool) {
+		return strconv.Itoa(int(tk.Priority)), true
+	},
+	"due": func(id store.ID, tk task.Task) (string, bool) {
+		if tk.Due == nil {
+			return "", false
+		}
+		return tk.Due.UTC().Format(keyTime), true
+	},
+	"created": func(id store.ID, tk task.Task) (string, bool) {
+		return tk.Created.UTC().Format(keyTime), true
+	},
+	"updated": func(id store.ID, tk task.Task) (string, bool) {
+		return tk.Updated.UTC().Format(keyTime), true
+	},
+	"rank": func(id store.ID, tk task.Task) (string, bool) {
+		return tk.Rank, tk.Rank != ""
+	},
+}
+
+// idDigits is how many digits the sequential IDs are padded to in their sort keys, the most of an uint64
+const idDigits = 20
+
+// idKey returns the key sorting the IDs in their natural order, as strings: by list, and then the
+// sequential IDs by number, e.g. 2 before 10, and the others as they are
+func idKey(id store.ID) string {
+	list, local := store.SplitListID(id)
+	if len(local) < idDigits && strings.Trim(string(local), "0123456789") == "" && local != "" {
+		local = store.ID(strings.Repeat("0", idDigits-len(local))) + local
+	}
+	if list == store.DefaultList {
+		return string(local)
+	}
+	return list + "/" + string(local)
+}
+
+// compareIDs compares the IDs in their natural order, see idKey
+func compareIDs(a, b store.ID) int {
+	return strings.Compare(idKey(a), idKey(b))
+}
+
+// sortKey returns the sort key of the todo for the field, the custom ones by their definitions
+func sortKey(defs listFields, field string, id store.ID, tk task.Task) (string, bool) {
+	if name, ok := strings.CutPrefix(field, fieldSortPrefix); ok {
+		return defs.sortKey(id, tk, name)
+	}
+	return sortFields[field](id, tk)
+}
+
+// SortKey is a field to sort the todos by
+type SortKey struct {
+	Field string
+	// Desc sorts the todos by descending values of the field
+	Desc bool
+}
+
+// ParseSort parses the comma separated fields to sort the todos by, each prefixed by "-" to sort
+// by descending values: id, title, status, owner, priority, due, created, updated and rank, the
+// manual order of MoveBefore, or "field." and the name of a custom field, see DefineField.
+// The empty string sorts by ID.
+func ParseSort(s string) ([]SortKey, error) {
+	var keys []SortKey
+	for _, field := range strings.Split(s, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		key := SortKey{Field: strings.TrimPrefix(field, "-"), Desc: strings.HasPrefix(field, "-")}
+		if name, ok := strings.CutPrefix(key.Field, fieldSortPrefix); ok {
+			if task.ValidateFieldName(name) != nil {
+				return nil, fmt.Errorf("unknown sort field %q", key.Field)
+			}
+		} else if _, ok := sortFields[key.Field]; !ok {
+			return nil, fmt.Errorf("unknown sort field %q", key.Field)
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// formatSort is the inverse of ParseSort
+func formatSort(keys []SortKey) string {
+	fields := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if key.Desc {
+			fields = append(fields, "-"+key.Field)
+		} else {
+			fields = append(fields, key.Field)
+		}
+	}
+	return strings.Join(fields, ",")
+}
+
+// Query selects the todos listed by List, and how. The zero Query lists all the todos by ID.
+type Query struct {
+	// Status selects the todos in the status. Empty selects any status.
+	Status task.Status
+	// Tag selects the todos with the tag. Empty selects any tag.
+	Tag string
+	// Context selects the todos with the context. Empty selects any context.
+	Context string
+	// Owner selects the todos owned by the user. Empty selects the todos of any user, or none.
+	Owner string
+	// DueBefore selects the todos due before the time. Nil selects the todos with any due date, or none.
+	DueBefore *time.Time
+	// Fields selects the todos with the values of the custom fields, compared as the lists define them
+	Fields map[string]string
+	// Sort are the fields to sort the todos by; the ties are sorted by ID
+	Sort []SortKey
+	// Limit is the most todos to list. Non positive lists all of them.
+	Limit int
+	// PageToken resumes the listing after the last todo of a previous page. Empty lists from the first.
+	PageToken string
+}
+
+// cursor is the last todo of a page, encoded in the page tokens
+type cursor struct {
+	Sort string    `json:"s"`
+	Keys []*string `json:"k"`
+	ID   store.ID  `json:"id"`
+}
+
+func (cur cursor) encode() string {
+	data, err := json.Marshal(cur)
+	if err != nil {
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(token, sort string) (cursor, error) {
+	var cur cursor
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return cur, ErrInvalidPageToken
+	}
+	if err := json.Unmarshal(data, &cur); err != nil || cur.Sort != sort {
+		return cur, ErrInvalidPageToken
+	}
+	return cur, nil
+}
+
+// sortedItem is an Item along with its sort keys
+type sortedItem struct {
+	Item
+	keys []*string
+}
+
+// compare compares the items a and b, described by their sort keys and IDs. The items without
+// value of a field sort last, in either order.
+func compare(sort []SortKey, a []*string, aID store.ID, b []*string, bID store.ID) int {
+	for i, key := range sort {
+		switch {
+		case a[i] == nil && b[i] == nil:
+			continue
+		case a[i] == nil:
+			return 1
+		case b[i] == nil:
+			return -1
+		}
+		c := strings.Compare(*a[i], *b[i])
+		if key.Desc {
+			c = -c
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return compareIDs(aID, bID)
+}
+
+// List returns a page of the todos selected by the query, sorted, along with the token of the next
+// page, empty on the last one. The todos in a status or with a tag are looked up through the indexes
+// of the datastore, if it indexes those fields.
+// Fails with ErrInvalidPageToken if the page token is not valid for the sort of the query.
+func (ld *Ledger) List(q Query) (Items, string, error) {
+	sort := formatSort(q.Sort)
+	var after *cursor
+	if q.PageToken != "" {
+		cur, err := decodeCursor(q.PageToken, sort)
+		if err != nil {
+			return nil, "", err
+		}
+		if len(cur.Keys) != len(q.Sort) {
+			return nil, "", ErrInvalidPageToken
+		}
+		after = &cur
+	}
+
+	var candidates []store.ID
+	indexed := false
+	fields := index.Fields(ld.storer)
+	for field, value := range map[string]string{"status": string(q.Status), "tag": q.Tag, "owner": q.Owner} {
+		if value == "" || !slices.Contains(fields, field) {
+			continue
+		}
+		ids, err := index.FindBy(ld.storer, field, value)
+		if err != nil {
+			return nil, "", err
+		}
+		if indexed {
+			found := make(map[store.ID]bool, len(candidates))
+			for _, id := range candidates {
+				found[id] = true
+			}
+			ids = slices.DeleteFunc(ids, func(id store.ID) bool {
+				return !found[id]
+			})
+		}
+		candidates, indexed = ids, true
+	}
+
+	ld.lock.RLock()
+	defs := ld.fieldDefs
+	ld.lock.RUnlock()
+	wants := func(id store.ID, tk task.Task) bool {
+		if q.Status != "" && tk.Status != q.Status {
+			return false
+		}
+		if q.Tag != "" && !slices.Contains(tk.Tags, q.Tag) {
+			return false
+		}
+		if q.Context != "" && !slices.Contains(tk.Contexts, q.Context) {
+			return false
+		}
+		if q.Owner != "" && tk.Owner != q.Owner {
+			return false
+		}
+		if !defs.match(id, tk, q.Fields) {
+			return false
+		}
+		return q.DueBefore == nil || (tk.Due != nil && tk.Due.Before(*q.DueBefore))
+	}
+	var items Items
+	var err error
+	if indexed {
+		items, err = ld.itemsOf(candidates)
+	} else {
+		items, err = ld.Filter(func(model.Todo) bool { return true })
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	sorted := make([]sortedItem, 0, len(items))
+	for _, item := range items {
+		if !wants(item.ID, *item.Task) {
+			continue
+		}
+		si := sortedItem{Item: item, keys: make([]*string, len(q.Sort))}
+		for i, key := range q.Sort {
+			if val, ok := sortKey(defs, key.Field, item.ID, *item.Task); ok {
+				si.keys[i] = &val
+			}
+		}
+		if after != nil && compare(q.Sort, si.keys, si.ID, after.Keys, after.ID) <= 0 {
+			continue
+		}
+		sorted = append(sorted, si)
+	}
+	slices.SortFunc(sorted, func(a, b sortedItem) int {
+		return compare(q.Sort, a.keys, a.ID, b.keys, b.ID)
+	})
+
+	next := ""
+	if q.Limit > 0 && len(sorted) > q.Limit {
+		sorted = sorted[:q.Limit]
+		last := sorted[len(sorted)-1]
+		next = cursor{Sort: sort, Keys: last.keys, ID: last.ID}.encode()
+	}
+	res := make(Items, 0, len(sorted))
+	for _, si := range sorted {
+		res = append(res, si.Item)
+	}
+	slog.Debug("ledger: List: listed objects", "count", len(res), "indexed", indexed)
+	return res, next, nil
+}
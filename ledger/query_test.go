@@ -0,0 +1,150 @@
+package ledger
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gotestbootcamp/go-todo-app/index"
+	"github.com/gotestbootcamp/go-todo-app/model"
+	"github.com/gotestbootcamp/go-todo-app/store"
+	"github.com/gotestbootcamp/go-todo-app/task"
+)
+
+func TestParseSort(t *testing.T) {
+	keys, err := ParseSort("priority, -due")
+	require.NoError(t, err)
+	assert.Equal(t, []SortKey{{Field: "priority"}, {Field: "due", Desc: true}}, keys)
+	assert.Equal(t, "priority,-due", formatSort(keys))
+
+	keys, err = ParseSort("")
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+
+	_, err = ParseSort("priority,size")
+	assert.EqualError(t, err, `unknown sort field "size"`)
+}
+
+func TestList(t *testing.T) {
+	status := func(blob store.Blob) ([]string, error) {
+		tk, err := task.Unmarshal(blob)
+		return []string{string(tk.Status)}, err
+	}
+	fi := index.NewFieldIndexed(newTestMemory(t))
+	require.NoError(t, fi.Register("status", status))
+
+	for name, st := range map[string]store.Storage{"scan": newTestMemory(t), "indexed": fi} {
+		t.Run(name, func(t *testing.T) {
+			ld, err := New(st)
+			require.NoError(t, err)
+			now := time.Now().Truncate(time.Second)
+			for i, title := range []string{"a", "b", "c", "d", "e"} {
+				id := store.ID(title)
+				require.NoError(t, ld.Set(id, model.New(title)))
+				_, err := ld.SetPriority(id, task.Priority(i%3))
+				require.NoError(t, err)
+				if i < 3 {
+					due := now.Add(time.Duration(i) * time.Hour)
+					_, err = ld.Schedule(id, Schedule{Due: &due})
+					require.NoError(t, err)
+				}
+			}
+			_, err = ld.TagTodo("b", "home")
+			require.NoError(t, err)
+			_, err = ld.TagTodo("d", "home")
+			require.NoError(t, err)
+			_, err = ld.Transition("d", task.Assigned)
+			require.NoError(t, err)
+			contexts := []string{"phone"}
+			_, _, err = ld.PatchIf("c", Patch{Contexts: &contexts}, AnyRevision)
+			require.NoError(t, err)
+
+			list := func(q Query) ([]store.ID, string) {
+				items, next, err := ld.List(q)
+				require.NoError(t, err)
+				ids := make([]store.ID, 0, len(items))
+				for _, item := range items {
+					ids = append(ids, item.ID)
+				}
+				return ids, next
+			}
+			ids, next := list(Query{})
+			assert.Equal(t, []store.ID{"a", "b", "c", "d", "e"}, ids)
+			assert.Empty(t, next)
+
+			ids, _ = list(Query{Status: task.Pending})
+			assert.Equal(t, []store.ID{"a", "b", "c", "e"}, ids)
+			ids, _ = list(Query{Status: task.Pending, Tag: "home"})
+			assert.Equal(t, []store.ID{"b"}, ids)
+			ids, _ = list(Query{Status: task.Pending, Context: "phone"})
+			assert.Equal(t, []store.ID{"c"}, ids)
+			before := now.Add(90 * time.Minute)
+			ids, _ = list(Query{DueBefore: &before})
+			assert.Equal(t, []store.ID{"a", "b"}, ids)
+
+			// the priorities are 0, 1, 2, 0, 1; the todos without due date come last
+			sort, err := ParseSort("priority,-due")
+			require.NoError(t, err)
+			ids, _ = list(Query{Sort: sort})
+			assert.Equal(t, []store.ID{"a", "d", "b", "e", "c"}, ids)
+
+			var pages [][]store.ID
+			token := ""
+			for {
+				ids, next := list(Query{Sort: sort, Limit: 2, PageToken: token})
+				pages = append(pages, ids)
+				if next == "" {
+					break
+				}
+				token = next
+			}
+			assert.Equal(t, [][]store.ID{{"a", "d"}, {"b", "e"}, {"c"}}, pages)
+
+			// the pages resume after the last todo, even if it changed since
+			ids, next = list(Query{Sort: sort, Limit: 2})
+			require.Equal(t, []store.ID{"a", "d"}, ids)
+			_, err = ld.SetPriority("d", task.PriorityUrgent)
+			require.NoError(t, err)
+			ids, _ = list(Query{Sort: sort, Limit: 2, PageToken: next})
+			assert.Equal(t, []store.ID{"b", "e"}, ids)
+
+			_, _, err = ld.List(Query{PageToken: next})
+			assert.ErrorIs(t, err, ErrInvalidPageToken)
+			_, _, err = ld.List(Query{PageToken: strings.Repeat("x", 5)})
+			assert.ErrorIs(t, err, ErrInvalidPageToken)
+		})
+	}
+}
+
+func TestListNumericIDs(t *testing.T) {
+	ld, err := New(newTestMemory(t))
+	require.NoError(t, err)
+	var want []store.ID
+	for i := 1; i <= 12; i++ {
+		id := store.ID(strconv.Itoa(i))
+		require.NoError(t, ld.Set(id, model.New("todo "+string(id))))
+		want = append(want, id)
+	}
+	require.NoError(t, ld.Set("work/2", model.New("in a list")))
+	require.NoError(t, ld.Set("work/10", model.New("in a list")))
+	want = append(want, "work/2", "work/10")
+
+	ids := func(q Query) []store.ID {
+		items, _, err := ld.List(q)
+		require.NoError(t, err)
+		var ids []store.ID
+		for _, item := range items {
+			ids = append(ids, item.ID)
+		}
+		return ids
+	}
+	assert.Equal(t, want, ids(Query{}), "by number, not as strings")
+	assert.Equal(t, want, ids(Query{Sort: []SortKey{{Field: "id"}}}))
+	slices.Reverse(want)
+	assert.Equal(t, want, ids(Query{Sort: []SortKey{{Field: "id", Desc: true}}}))
+}
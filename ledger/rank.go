@@ -0,0 +1,186 @@
+package ledger
+
+import (
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/gotestbootcamp/go-todo-app/store"
+	"github.com/gotestbootcamp/go-todo-app/task"
+)
+
+// respreadRankLength is the length past which the ranks of a list are spread evenly again, well
+// before task.MaxRankLength
+const respreadRankLength = task.MaxRankLength / 4
+
+// rankedTask is a task of a list, in the manual order of the list
+type rankedTask struct {
+	id store.ID
+	tk task.Task
+}
+
+// compareRanks sorts the tasks in the manual order: by rank, the ones without rank last by ID, in
+// numeric order
+func compareRanks(a, b rankedTask) int {
+	switch {
+	case a.tk.Rank == "" && b.tk.Rank != "":
+		return 1
+	case a.tk.Rank != "" && b.tk.Rank == "":
+		return -1
+	}
+	if c := strings.Compare(a.tk.Rank, b.tk.Rank); c != 0 {
+		return c
+	}
+	return compareIDs(a.id, b.id)
+}
+
+// MoveBefore moves the todo right before the other todo of its list, in the manual order the todos
+// are sorted by with the "rank" sort key, or last if other is store.NullID, and returns the moved
+// todo. Only the rank of the moved todo changes, see task.RankBetween, but for the todos of the list
+// never moved before it, which get their ranks too, in their order by ID, and for the moves that
+// would make the rank too long, which spread the ranks of the whole list evenly again. The todos
+// are rewritten atomically if the datastore supports transactions.
+// Fails with store.ErrNotFound if either todo doesn't exist.
+func (ld *Ledger) MoveBefore(id, other store.ID) (Item, error) {
+	if id == other {
+		return Item{}, fmt.Errorf("can't move %v before itself", id)
+	}
+	list, _ := store.SplitListID(id)
+	if otherList, _ := store.SplitListID(other); other != store.NullID && otherList != list {
+		return Item{}, fmt.Errorf("can't move %v before %v, of another list", id, other)
+	}
+	ld.lock.Lock()
+	defer ld.unlock()
+	if _, ok := ld.blobs[id]; !ok {
+		return Item{}, store.ErrNotFound{ID: id}
+	}
+	if _, ok := ld.blobs[other]; other != store.NullID && !ok {
+		return Item{}, store.ErrNotFound{ID: other}
+	}
+	if err := ld.checkOwner(id, ld.blobs[id]); err != nil {
+		return Item{}, err
+	}
+	return ld.moveBefore(list, id, other)
+}
+
+// moveBefore ranks the todo right before the other todo of the list, see MoveBefore. The caller
+// must hold the lock.
+func (ld *Ledger) moveBefore(list string, id, other store.ID) (item Item, rerr error) {
+	tx, err := ld.begin()
+	if err != nil {
+		return Item{}, err
+	}
+	history, steps, changes := maps.Clone(ld.history), len(ld.steps), len(ld.changes)
+	defer func() {
+		if rerr != nil {
+			tx.Rollback()
+			ld.history, ld.steps, ld.changes = history, ld.steps[:steps], ld.changes[:changes]
+		}
+	}()
+
+	var moved task.Task
+	var order []rankedTask
+	for tid, blob := range ld.blobs {
+		if in, _ := store.SplitListID(tid); in != list {
+			continue
+		}
+		tk, err := task.Unmarshal(blob)
+		if err != nil {
+			return Item{}, err
+		}
+		if tid == id {
+			moved = tk
+			continue
+		}
+		order = append(order, rankedTask{id: tid, tk: tk})
+	}
+	slices.SortFunc(order, compareRanks)
+	pos := len(order)
+	if other != store.NullID {
+		pos = slices.IndexFunc(order, func(rt rankedTask) bool { return rt.id == other })
+	}
+
+	updated := make(map[store.ID]store.Blob)
+	save := func(tid store.ID, tk task.Task) error {
+		blob, err := task.Marshal(tk)
+		if err != nil {
+			return err
+		}
+		if err := tx.Save(tid, blob); err != nil {
+			return err
+		}
+		if err := ld.record(tx, tid, ld.blobs[tid], blob); err != nil {
+			return err
+		}
+		updated[tid] = blob
+		return nil
+	}
+	// the todos whose rank changes, by position in the order
+	changed := make(map[int]bool)
+	// the todos never moved up to the other one are ranked after the ones moved, as they were
+	// sorted; being sorted last, the ones past it can stay unranked
+	unranked := slices.IndexFunc(order, func(rt rankedTask) bool { return rt.tk.Rank == "" })
+	if unranked >= 0 && unranked <= pos {
+		last := ""
+		if unranked > 0 {
+			last = order[unranked-1].tk.Rank
+		}
+		end := min(pos+1, len(order))
+		ranks, err := task.RanksAfter(last, end-unranked)
+		if err != nil {
+			return Item{}, err
+		}
+		for i := unranked; i < end; i++ {
+			order[i].tk.Rank = ranks[i-unranked]
+			changed[i] = true
+		}
+	}
+	before, after := "", ""
+	if pos > 0 {
+		before = order[pos-1].tk.Rank
+	}
+	if pos < len(order) {
+		after = order[pos].tk.Rank
+	}
+	moved.Rank, err = task.RankBetween(before, after)
+	if err != nil || len(moved.Rank) > respreadRankLength {
+		// the ranks lengthen with the moves to the same spot: the ones of the list are spread
+		// evenly again, before they grow too long to move there anymore
+		ranks, err := task.RanksAfter("", len(order)+1)
+		if err != nil {
+			return Item{}, err
+		}
+		moved.Rank = ranks[pos]
+		for i := range order {
+			rank := ranks[i]
+			if i >= pos {
+				rank = ranks[i+1]
+			}
+			if order[i].tk.Rank != rank {
+				order[i].tk.Rank = rank
+				changed[i] = true
+			}
+		}
+		slog.Info("ledger: MoveBefore: ranks spread again", "list", list, "count", len(order)+1)
+	}
+	for i := range order {
+		if changed[i] {
+			if err := save(order[i].id, order[i].tk); err != nil {
+				return Item{}, err
+			}
+		}
+	}
+	moved.Updated = ld.now()
+	if err := save(id, moved); err != nil {
+		return Item{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return Item{}, err
+	}
+	maps.Copy(ld.blobs, updated)
+	slog.Info("ledger: MoveBefore: object moved", "id", id, "before", other, "rank", moved.Rank, "ranked", len(updated)-1)
+	return newItem(id, updated[id])
+}
@@ -0,0 +1,112 @@
+package ledger
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gotestbootcamp/go-todo-app/model"
+	"github.com/gotestbootcamp/go-todo-app/store"
+	"github.com/gotestbootcamp/go-todo-app/task"
+)
+
+// rankedIDs returns the IDs of the todos in their manual order
+func rankedIDs(t *testing.T, ld *Ledger) []store.ID {
+	t.Helper()
+	items, _, err := ld.List(Query{Sort: []SortKey{{Field: "rank"}}})
+	require.NoError(t, err)
+	var ids []store.ID
+	for _, item := range items {
+		ids = append(ids, item.ID)
+	}
+	return ids
+}
+
+func TestMoveBefore(t *testing.T) {
+	st := newTestMemory(t)
+	ld, err := NewWithWorkflow(st, task.DefaultWorkflow())
+	require.NoError(t, err)
+	for _, id := range []store.ID{"1", "2", "3", "4", "5", "work/1"} {
+		require.NoError(t, ld.Set(id, model.New("todo "+string(id))))
+	}
+
+	item, err := ld.MoveBefore("3", "2")
+	require.NoError(t, err)
+	assert.NotEmpty(t, item.Task.Rank)
+	assert.Equal(t, []store.ID{"1", "3", "2", "4", "5", "work/1"}, rankedIDs(t, ld))
+	// the todos past the one moved before keep no rank
+	_, err = ld.MoveBefore("1", "4")
+	require.NoError(t, err)
+	assert.Equal(t, []store.ID{"3", "2", "1", "4", "5", "work/1"}, rankedIDs(t, ld))
+	ranks := make(map[store.ID]string)
+	for _, id := range []store.ID{"1", "2", "3", "4", "5"} {
+		item, _, err := ld.GetItem(id)
+		require.NoError(t, err)
+		ranks[id] = item.Task.Rank
+	}
+	assert.NotEmpty(t, ranks["4"])
+	assert.Empty(t, ranks["5"])
+
+	// only the todo moved changes, once all are ranked
+	_, err = ld.MoveBefore("4", "3")
+	require.NoError(t, err)
+	assert.Equal(t, []store.ID{"4", "3", "2", "1", "5", "work/1"}, rankedIDs(t, ld))
+	for _, id := range []store.ID{"1", "2", "3"} {
+		item, _, err := ld.GetItem(id)
+		require.NoError(t, err)
+		assert.Equal(t, ranks[id], item.Task.Rank, "rank of %v", id)
+	}
+	_, err = ld.MoveBefore("4", store.NullID)
+	require.NoError(t, err)
+	assert.Equal(t, []store.ID{"3", "2", "1", "5", "4", "work/1"}, rankedIDs(t, ld))
+
+	_, err = ld.MoveBefore("1", "work/1")
+	assert.Error(t, err, "another list")
+	_, err = ld.MoveBefore("1", "1")
+	assert.Error(t, err)
+	_, err = ld.MoveBefore("1", "9")
+	assert.ErrorIs(t, err, store.ErrNotFound{ID: "9"})
+	_, err = ld.AsUser("bob", false).MoveBefore("1", "3")
+	assert.ErrorIs(t, err, ErrForbidden)
+
+	// the ranks are stored
+	ld, err = NewWithWorkflow(st, task.DefaultWorkflow())
+	require.NoError(t, err)
+	assert.Equal(t, []store.ID{"3", "2", "1", "5", "4", "work/1"}, rankedIDs(t, ld))
+}
+
+func TestMoveBeforeSameSpot(t *testing.T) {
+	st := newTestMemory(t)
+	ld, err := NewWithWorkflow(st, task.DefaultWorkflow())
+	require.NoError(t, err)
+	var ids []store.ID
+	for i := 1; i <= 12; i++ {
+		id := store.ID(strconv.Itoa(i))
+		require.NoError(t, ld.Set(id, model.New("todo "+string(id))))
+		ids = append(ids, id)
+	}
+
+	// the todos never moved are in numeric order
+	_, err = ld.MoveBefore("11", "2")
+	require.NoError(t, err)
+	assert.Equal(t, []store.ID{"1", "11", "2", "3", "4", "5", "6", "7", "8", "9", "10", "12"}, rankedIDs(t, ld))
+
+	// the ranks are spread again before growing too long
+	for i := range 600 {
+		id := store.ID("3")
+		if i%2 == 1 {
+			id = "4"
+		}
+		item, err := ld.MoveBefore(id, "2")
+		require.NoError(t, err, "move %d", i)
+		assert.LessOrEqual(t, len(item.Task.Rank), task.MaxRankLength)
+	}
+	assert.Equal(t, []store.ID{"1", "11", "3", "4", "2", "5", "6", "7", "8", "9", "10", "12"}, rankedIDs(t, ld))
+	for _, id := range ids {
+		item, _, err := ld.GetItem(id)
+		require.NoError(t, err)
+		assert.LessOrEqual(t, len(item.Task.Rank), respreadRankLength, "rank of %v", id)
+	}
+}
@@ -0,0 +1,60 @@
+package ledger
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// ReminderFunc is called by a Scheduler with each todo whose reminder came due
+type ReminderFunc func(item Item)
+
+// Scheduler fires the reminders of the todos of a Ledger when they come due.
+// Only the reminders coming due while the scheduler runs are fired: the ones
+// past when it starts are ignored, so restarting doesn't fire them again.
+// Reminders of todos in a final status of the workflow are never fired.
+type Scheduler struct {
+	ld       *Ledger
+	interval time.Duration
+	fire     ReminderFunc
+}
+
+// NewScheduler creates a Scheduler checking every interval for the reminders of the ledger
+// come due, and calling fire with each of them. The interval bounds how late a reminder fires.
+func NewScheduler(ld *Ledger, interval time.Duration, fire ReminderFunc) *Scheduler {
+	return &Scheduler{
+		ld:       ld,
+		interval: interval,
+		fire:     fire,
+	}
+}
+
+// Run fires the reminders coming due until the context is done
+func (sc *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(sc.interval)
+	defer ticker.Stop()
+	last := sc.ld.now()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			now := sc.ld.now()
+			sc.fireBetween(last, now)
+			last = now
+		}
+	}
+}
+
+// fireBetween fires the reminders coming due after from, up to until, earliest due first
+func (sc *Scheduler) fireBetween(from, until time.Time) {
+	items, err := sc.ld.remindersBetween(from, until)
+	if err != nil {
+		slog.Error("ledger: Scheduler: failed to check the reminders", "error", err)
+		return
+	}
+	for _, item := range items {
+		slog.Info("ledger: Scheduler: reminder came due", "id", item.ID)
+		sc.fire(item)
+	}
+}
@@ -0,0 +1,69 @@
+package ledger
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gotestbootcamp/go-todo-app/model"
+	"github.com/gotestbootcamp/go-todo-app/store"
+	"github.com/gotestbootcamp/go-todo-app/task"
+)
+
+func TestSchedulerFireBetween(t *testing.T) {
+	ld := newTestLedger(t, task.DefaultWorkflow())
+	now := time.Date(2024, time.November, 11, 10, 0, 0, 0, time.UTC)
+	for id, offset := range map[store.ID]time.Duration{
+		"past":    -time.Minute,
+		"start":   0,
+		"soon":    30 * time.Second,
+		"end":     time.Minute,
+		"later":   2 * time.Minute,
+		"deleted": 30 * time.Second,
+	} {
+		require.NoError(t, ld.Set(id, model.New(string(id))))
+		remind := now.Add(offset)
+		_, err := ld.Schedule(id, Schedule{Remind: &remind})
+		require.NoError(t, err)
+	}
+	_, err := ld.Transition("deleted", task.Deleted)
+	require.NoError(t, err)
+
+	var fired []store.ID
+	sc := NewScheduler(ld, time.Minute, func(item Item) {
+		fired = append(fired, item.ID)
+	})
+	sc.fireBetween(now, now.Add(time.Minute))
+	assert.Equal(t, []store.ID{"soon", "end"}, fired)
+}
+
+func TestSchedulerRun(t *testing.T) {
+	ld := newTestLedger(t, task.DefaultWorkflow())
+	require.NoError(t, ld.Set("1", model.New("foo")))
+	remind := time.Now().Add(20 * time.Millisecond)
+	_, err := ld.Schedule("1", Schedule{Remind: &remind})
+	require.NoError(t, err)
+
+	fired := make(chan store.ID, 1)
+	sc := NewScheduler(ld, 10*time.Millisecond, func(item Item) {
+		fired <- item.ID
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		sc.Run(ctx)
+		close(done)
+	}()
+
+	select {
+	case id := <-fired:
+		assert.Equal(t, store.ID("1"), id)
+	case <-time.After(5 * time.Second):
+		t.Fatal("reminder not fired")
+	}
+	cancel()
+	<-done
+}
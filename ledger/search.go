@@ -0,0 +1,279 @@
+package ledger
+
+import (
+	"cmp"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gotestbootcamp/go-todo-app/index"
+	"github.com/gotestbootcamp/go-todo-app/model"
+	"github.com/gotestbootcamp/go-todo-app/search"
+	"github.com/gotestbootcamp/go-todo-app/store"
+	"github.com/gotestbootcamp/go-todo-app/task"
+)
+
+// Search returns the Items matching the query, parsed by search.Parse with the dates relative to
+// now: the best matches of its words first, then by ID. The words are looked up through the
+// full-text index of the datastore, and the values of the fields, e.g. a status or a tag, through
+// its field indexes when it has them. The empty query matches nothing.
+// Fails with search.SyntaxError if the query is malformed, and with store.ErrUnsupported if it
+// has words but the datastore can't search its content.
+func (ld *Ledger) Search(query string) (Items, error) {
+	if strings.TrimSpace(query) == "" {
+		return Items{}, nil
+	}
+	expr, err := search.Parse(query, ld.dates())
+	if err != nil {
+		return nil, err
+	}
+	sr := searcher{ld: ld, fields: index.Fields(ld.storer), texts: make(map[string]map[store.ID]bool)}
+	candidates, indexed, err := sr.lookup(expr)
+	if err != nil {
+		return nil, err
+	}
+	var items Items
+	if indexed {
+		ids := make([]store.ID, 0, len(candidates))
+		for id := range candidates {
+			ids = append(ids, id)
+		}
+		slices.Sort(ids)
+		items, err = ld.itemsOf(ids)
+	} else {
+		items, err = ld.Filter(func(model.Todo) bool { return true })
+	}
+	if err != nil {
+		return nil, err
+	}
+	items = slices.DeleteFunc(items, func(item Item) bool {
+		return !sr.match(expr, item)
+	})
+
+	// the best matches of the words first
+	rank := make(map[store.ID]int)
+	if words := positiveWords(expr); len(words) > 0 {
+		ids, err := index.Search(ld.storer, strings.Join(words, " "))
+		if err != nil {
+			return nil, err
+		}
+		for i, id := range ids {
+			rank[id] = len(ids) - i
+		}
+	}
+	slices.SortStableFunc(items, func(a, b Item) int {
+		if c := cmp.Compare(rank[b.ID], rank[a.ID]); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.ID, b.ID)
+	})
+	slog.Debug("ledger: Search: objects matched", "query", expr.String(), "count", len(items), "indexed", indexed)
+	return items, nil
+}
+
+// positiveWords returns the words of the texts of the expression which the todos must have
+func positiveWords(expr search.Expr) []string {
+	switch expr := expr.(type) {
+	case search.And:
+		var words []string
+		for _, x := range expr {
+			words = append(words, positiveWords(x)...)
+		}
+		return words
+	case search.Text:
+		return []string{expr.Words}
+	default:
+		return nil
+	}
+}
+
+// searcher evaluates a query against the indexes of the datastore
+type searcher struct {
+	ld *Ledger
+	// fields are the fields the datastore indexes
+	fields []string
+	// texts are the todos having the words of each text of the query. Nil for the words without
+	// letters nor digits, which any todo has.
+	texts map[string]map[store.ID]bool
+}
+
+// lookup returns the IDs of the todos which may match the expression, as told by the indexes of
+// the datastore. False if any todo may match. Looks up the todos having the texts of the
+// expression, for match.
+func (sr *searcher) lookup(expr search.Expr) (map[store.ID]bool, bool, error) {
+	switch expr := expr.(type) {
+	case search.And:
+		var res map[store.ID]bool
+		indexed := false
+		for _, x := range expr {
+			ids, ok, err := sr.lookup(x)
+			if err != nil {
+				return nil, false, err
+			}
+			if !ok {
+				continue
+			}
+			if indexed {
+				prev := res
+				res = make(map[store.ID]bool)
+				for id := range ids {
+					if prev[id] {
+						res[id] = true
+					}
+				}
+			} else {
+				res, indexed = ids, true
+			}
+		}
+		return res, indexed, nil
+	case search.Or:
+		res := make(map[store.ID]bool)
+		indexed := true
+		for _, x := range expr {
+			ids, ok, err := sr.lookup(x)
+			if err != nil {
+				return nil, false, err
+			}
+			indexed = indexed && ok
+			for id := range ids {
+				res[id] = true
+			}
+		}
+		return res, indexed, nil
+	case search.Not:
+		_, _, err := sr.lookup(expr.X)
+		return nil, false, err
+	case search.Text:
+		ids, ok := sr.texts[expr.Words]
+		if !ok {
+			if len(index.Tokenize(expr.Words)) > 0 {
+				found, err := index.Search(sr.ld.storer, expr.Words)
+				if err != nil {
+					return nil, false, err
+				}
+				ids = make(map[store.ID]bool, len(found))
+				for _, id := range found {
+					ids[id] = true
+				}
+			}
+			sr.texts[expr.Words] = ids
+		}
+		return ids, ids != nil, nil
+	case search.Field:
+		value, ok := sr.indexedValue(expr)
+		if !ok {
+			return nil, false, nil
+		}
+		found, err := index.FindBy(sr.ld.storer, expr.Name, value)
+		if err != nil {
+			return nil, false, err
+		}
+		ids := make(map[store.ID]bool, len(found))
+		for _, id := range found {
+			ids[id] = true
+		}
+		return ids, true, nil
+	default:
+		return nil, false, nil
+	}
+}
+
+// indexedValue returns the value to look up the todos matching the field with, in the index of
+// the field. False if the datastore doesn't index the field, or the field doesn't look up a value.
+func (sr *searcher) indexedValue(field search.Field) (string, bool) {
+	if !slices.Contains(sr.fields, field.Name) || field.Op != search.Eq {
+		return "", false
+	}
+	switch field.Name {
+	case "status":
+		return field.Value, sr.ld.workflow.Has(task.Status(field.Value))
+	case "tag", "owner", "assignee":
+		return field.Value, true
+	case "priority":
+		return strconv.Itoa(int(field.Priority)), true
+	default:
+		return "", false
+	}
+}
+
+// match returns true if the todo matches the expression
+func (sr *searcher) match(expr search.Expr, item Item) bool {
+	tk := *item.Task
+	switch expr := expr.(type) {
+	case search.And:
+		for _, x := range expr {
+			if !sr.match(x, item) {
+				return false
+			}
+		}
+		return true
+	case search.Or:
+		for _, x := range expr {
+			if sr.match(x, item) {
+				return true
+			}
+		}
+		return false
+	case search.Not:
+		return !sr.match(expr.X, item)
+	case search.Text:
+		ids := sr.texts[expr.Words]
+		if ids == nil {
+			return true
+		}
+		if !ids[item.ID] {
+			return false
+		}
+		return !expr.Phrase || strings.Contains(strings.ToLower(tk.Title+"\n"+tk.Description), strings.ToLower(expr.Words))
+	case search.Field:
+		return sr.matchField(expr, item.ID, tk)
+	default:
+		return false
+	}
+}
+
+// matchField returns true if the field of the todo matches. The statuses open and closed, unless
+// the workflow has them, match the todos in a status which isn't final, or is.
+func (sr *searcher) matchField(field search.Field, id store.ID, tk task.Task) bool {
+	switch field.Name {
+	case "status":
+		switch {
+		case sr.ld.workflow.Has(task.Status(field.Value)):
+			return tk.Status == task.Status(field.Value)
+		case field.Value == "open":
+			return sr.ld.active(tk)
+		case field.Value == "closed":
+			return !sr.ld.active(tk)
+		}
+		return tk.Status == task.Status(field.Value)
+	case "tag":
+		return slices.Contains(tk.Tags, field.Value)
+	case "context":
+		return slices.Contains(tk.Contexts, field.Value)
+	case "owner":
+		return tk.Owner == field.Value
+	case "assignee":
+		return tk.Assignee == field.Value
+	case "list":
+		list, _ := store.SplitListID(id)
+		return list == field.Value
+	case "priority":
+		return field.Op.Holds(cmp.Compare(tk.Priority, field.Priority))
+	case "due":
+		return tk.Due != nil && field.Op.Holds(compareDay(*tk.Due, field.Day))
+	case "created":
+		return field.Op.Holds(compareDay(tk.Created, field.Day))
+	case "updated":
+		return field.Op.Holds(compareDay(tk.Updated, field.Day))
+	default:
+		return false
+	}
+}
+
+// compareDay compares the day of the time with the day, at midnight in its time zone
+func compareDay(t time.Time, day time.Time) int {
+	t = t.In(day.Location())
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, day.Location()).Compare(day)
+}
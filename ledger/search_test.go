@@ -0,0 +1,93 @@
+package ledger
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gotestbootcamp/go-todo-app/index"
+	"github.com/gotestbootcamp/go-todo-app/model"
+	"github.com/gotestbootcamp/go-todo-app/search"
+	"github.com/gotestbootcamp/go-todo-app/store"
+	"github.com/gotestbootcamp/go-todo-app/task"
+)
+
+func TestSearch(t *testing.T) {
+	text := func(blob store.Blob) (string, error) {
+		tk, err := task.Unmarshal(blob)
+		return tk.Title + "\n" + tk.Description, err
+	}
+	ixd, err := index.NewIndexed(newTestMemory(t), text)
+	require.NoError(t, err)
+	fi := index.NewFieldIndexed(ixd)
+	require.NoError(t, fi.Register("status", func(blob store.Blob) ([]string, error) {
+		tk, err := task.Unmarshal(blob)
+		return []string{string(tk.Status)}, err
+	}))
+	ld, err := New(fi)
+	require.NoError(t, err)
+	now := time.Now()
+
+	for id, title := range map[store.ID]string{"1": "pay the rent", "2": "rent a car", "3": "buy milk", "4": "buy bread"} {
+		require.NoError(t, ld.Set(id, model.New(title)))
+	}
+	_, err = ld.TagTodo("1", "home")
+	require.NoError(t, err)
+	_, err = ld.TagTodo("3", "home")
+	require.NoError(t, err)
+	contexts := []string{"errands"}
+	_, _, err = ld.PatchIf("3", Patch{Contexts: &contexts}, AnyRevision)
+	require.NoError(t, err)
+	_, err = ld.SetPriority("2", task.PriorityHigh)
+	require.NoError(t, err)
+	_, err = ld.Transition("3", task.Assigned)
+	require.NoError(t, err)
+	_, err = ld.Transition("4", task.Assigned)
+	require.NoError(t, err)
+	_, err = ld.Transition("4", task.Completed)
+	require.NoError(t, err)
+	tomorrow := now.Add(24 * time.Hour)
+	_, err = ld.Schedule("1", Schedule{Due: &tomorrow})
+	require.NoError(t, err)
+
+	find := func(query string) []store.ID {
+		items, err := ld.Search(query)
+		require.NoError(t, err, query)
+		ids := make([]store.ID, 0, len(items))
+		for _, item := range items {
+			ids = append(ids, item.ID)
+		}
+		return ids
+	}
+	assert.Equal(t, []store.ID{"1", "2"}, find("rent"))
+	assert.Equal(t, []store.ID{"1"}, find(`"the rent"`))
+	assert.Equal(t, []store.ID{"1", "3"}, find("tag:home"))
+	assert.Equal(t, []store.ID{"3"}, find("context:errands"))
+	assert.Equal(t, []store.ID{"1", "2", "3"}, find("status:open"))
+	assert.Equal(t, []store.ID{"4"}, find("status:closed"))
+	assert.Equal(t, []store.ID{"3"}, find("status:assigned"))
+	assert.Equal(t, []store.ID{"3"}, find("buy -status:completed"))
+	assert.Equal(t, []store.ID{"2"}, find("rent priority>=high"))
+	assert.Equal(t, []store.ID{"1"}, find("due:tomorrow"))
+	assert.Equal(t, []store.ID{"1"}, find("due>today"))
+	assert.Empty(t, find("due<today"))
+	assert.Equal(t, []store.ID{"1", "2", "3"}, find("tag:home OR car OR (buy -status:completed)"))
+	assert.Equal(t, []store.ID{"1", "2", "3", "4"}, find("created:today"))
+	assert.Empty(t, find(""))
+
+	_, err = ld.Search("size:large")
+	var syntaxErr search.SyntaxError
+	assert.ErrorAs(t, err, &syntaxErr)
+
+	// the fields don't need the full-text index, the words do
+	ld, err = New(newTestMemory(t))
+	require.NoError(t, err)
+	require.NoError(t, ld.Set("1", model.New("pay the rent")))
+	items, err := ld.Search("status:pending")
+	require.NoError(t, err)
+	assert.Len(t, items, 1)
+	_, err = ld.Search("status:pending rent")
+	assert.ErrorIs(t, err, store.ErrUnsupported)
+}
@@ -0,0 +1,287 @@
+package ledger
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	apiv1 "github.com/gotestbootcamp/go-todo-app/api/v1"
+	"github.com/gotestbootcamp/go-todo-app/store"
+	"github.com/gotestbootcamp/go-todo-app/task"
+)
+
+// rwLock is a sync.RWMutex counting how many times, and for how long, the lockers had to wait
+// for the others, see LockStats
+type rwLock struct {
+	sync.RWMutex
+	waits, readWaits   atomic.Uint64
+	waited, readWaited atomic.Int64
+}
+
+func (l *rwLock) Lock() {
+	if l.TryLock() {
+		return
+	}
+	start := time.Now()
+	l.RWMutex.Lock()
+	l.waits.Add(1)
+	l.waited.Add(int64(time.Since(start)))
+}
+
+func (l *rwLock) RLock() {
+	if l.TryRLock() {
+		return
+	}
+	start := time.Now()
+	l.RWMutex.RLock()
+	l.readWaits.Add(1)
+	l.readWaited.Add(int64(time.Since(start)))
+}
+
+// LockStats tells how contended the lock of the ledger is, since it was created
+type LockStats struct {
+	// Waits is how many times the mutations waited for the lock, and Waited for how long overall
+	Waits  uint64
+	Waited time.Duration
+	// ReadWaits is how many times the reads waited for the mutations, and ReadWaited for how long overall
+	ReadWaits  uint64
+	ReadWaited time.Duration
+}
+
+// LockStats returns the contention of the lock of the ledger, shared by its views
+func (ld *Ledger) LockStats() LockStats {
+	return LockStats{
+		Waits:      ld.lock.waits.Load(),
+		Waited:     time.Duration(ld.lock.waited.Load()),
+		ReadWaits:  ld.lock.readWaits.Load(),
+		ReadWaited: time.Duration(ld.lock.readWaited.Load()),
+	}
+}
+
+// CountByStatus returns how many todos the view can see have each status of the workflow, zero
+// included, not counting the archived ones
+func (ld *Ledger) CountByStatus() (map[task.Status]int, error) {
+	ld.lock.RLock()
+	defer ld.lock.RUnlock()
+	counts := make(map[task.Status]int)
+	for _, st := range ld.workflow.Statuses() {
+		counts[st] = 0
+	}
+	for id, blob := range ld.blobs {
+		if !ld.readable(id) {
+			continue
+		}
+		tk, err := task.Unmarshal(blob)
+		if err != nil {
+			return nil, err
+		}
+		counts[tk.Status]++
+	}
+	return counts, nil
+}
+
+// StatsPeriods are the lengths of the periods Stats counts the todos completed in
+var StatsPeriods = []string{"day", "week"}
+
+// MaxStatsBuckets bounds the days or weeks Stats counts the todos completed in: the longer periods
+// fail with ErrPeriodTooLong
+const MaxStatsBuckets = 366
+
+// Stats tells how productive the users were on the todos within a period
+type Stats struct {
+	From, To time.Time
+	// Period is the length of the periods of Completions, one of StatsPeriods
+	Period string
+	// Completions are how many todos were completed in each period from From to To, the first and
+	// the last ones possibly partial
+	Completions []StatsBucket
+	StatsCounts
+	// ByTag are the counts of the todos with each tag. The todos with many tags count for each of them.
+	ByTag map[string]StatsCounts
+	// ByContext are the counts of the todos with each context, like ByTag
+	ByContext map[string]StatsCounts
+}
+
+// StatsBucket is how many todos were completed in a period starting at Start
+type StatsBucket struct {
+	Start     time.Time
+	Completed int
+}
+
+// StatsCounts counts the todos of Stats
+type StatsCounts struct {
+	// Created and Completed are how many todos were created, and completed, within the period
+	Created, Completed int
+	// TimeToComplete is how long the todos completed within the period took on average, since
+	// their creation
+	TimeToComplete time.Duration
+	// Due is how many todos were due within the period, until now, and Late how many of them were
+	// not completed by their due date
+	Due, Late int
+	// Open is how many todos are active now, and Overdue how many of them are past their due date
+	Open, Overdue int
+	// Pomodoros is how many pomodoros ended within the period, see task.TimeEntry.Pomodoro
+	Pomodoros int
+}
+
+// OverdueRate returns the share of the todos due within the period not completed by their due
+// date, from 0 to 1. 0 if no todo was due.
+func (c StatsCounts) OverdueRate() float64 {
+	if c.Due == 0 {
+		return 0
+	}
+	return float64(c.Late) / float64(c.Due)
+}
+
+// ToAPIv1 converts the StatsCounts in their API v1 representation
+func (c StatsCounts) ToAPIv1() apiv1.StatsCounts {
+	return apiv1.StatsCounts{
+		Created:        c.Created,
+		Completed:      c.Completed,
+		TimeToComplete: c.TimeToComplete.Hours(),
+		Due:            c.Due,
+		Late:           c.Late,
+		OverdueRate:    c.OverdueRate(),
+		Open:           c.Open,
+		Overdue:        c.Overdue,
+		Pomodoros:      c.Pomodoros,
+	}
+}
+
+// ToAPIv1 converts the Stats in their API v1 representation
+func (s Stats) ToAPIv1() apiv1.Stats {
+	res := apiv1.Stats{
+		From:        s.From,
+		To:          s.To,
+		Period:      s.Period,
+		Completions: make([]apiv1.StatsBucket, 0, len(s.Completions)),
+		StatsCounts: s.StatsCounts.ToAPIv1(),
+		ByTag:       make(map[string]apiv1.StatsCounts, len(s.ByTag)),
+		ByContext:   make(map[string]apiv1.StatsCounts, len(s.ByContext)),
+	}
+	for _, b := range s.Completions {
+		res.Completions = append(res.Completions, apiv1.StatsBucket{Start: b.Start, Completed: b.Completed})
+	}
+	for tag, c := range s.ByTag {
+		res.ByTag[tag] = c.ToAPIv1()
+	}
+	for ctx, c := range s.ByContext {
+		res.ByContext[ctx] = c.ToAPIv1()
+	}
+	return res
+}
+
+// add counts the task in the counts, completed when it was last updated unless active
+func (c *StatsCounts) add(tk task.Task, from, to, now time.Time, active bool) {
+	within := func(t time.Time) bool { return !t.Before(from) && t.Before(to) }
+	if within(tk.Created) {
+		c.Created++
+	}
+	if !active && within(tk.Updated) {
+		c.Completed++
+		c.TimeToComplete += tk.Updated.Sub(tk.Created)
+	}
+	if tk.Due != nil && within(*tk.Due) && tk.Due.Before(now) {
+		c.Due++
+		if active || tk.Updated.After(*tk.Due) {
+			c.Late++
+		}
+	}
+	c.Pomodoros += tk.Pomodoros(from, to)
+	if active {
+		c.Open++
+		if tk.Overdue(now) {
+			c.Overdue++
+		}
+	}
+}
+
+// average turns the total time to complete into the average
+func (c *StatsCounts) average() {
+	if c.Completed > 0 {
+		c.TimeToComplete /= time.Duration(c.Completed)
+	}
+}
+
+// Stats tells how productive the users were on the todos the view sees from the given time until
+// the other: how many todos were completed in each day or week, the period, how long they took,
+// and how many of the ones due were late, overall, by tag and by context. The todos in a final status of the
+// workflow but deleted count as completed when they were last updated; the archived todos count
+// as well. The weeks start on the first day of the week of the locale. Fails with ErrPeriodTooLong
+// if the period has more than MaxStatsBuckets days or weeks.
+func (ld *Ledger) Stats(from, to time.Time, period string) (Stats, error) {
+	if !slices.Contains(StatsPeriods, period) {
+		return Stats{}, fmt.Errorf("unknown period %q, expected day or week", period)
+	}
+	if from.After(to) {
+		return Stats{}, fmt.Errorf("period from %v ends before it starts", from)
+	}
+	y, m, d := from.Date()
+	start := time.Date(y, m, d, 0, 0, 0, 0, from.Location())
+	days := 1
+	if period == "week" {
+		weekStart := ld.dates().WeekStart
+		start = start.AddDate(0, 0, -(int(start.Weekday())-int(weekStart)+7)%7)
+		days = 7
+	}
+	if buckets := to.Sub(start) / (time.Duration(days) * 24 * time.Hour); buckets > MaxStatsBuckets {
+		return Stats{}, fmt.Errorf("%w: %d %ss, at most %d", ErrPeriodTooLong, buckets, period, MaxStatsBuckets)
+	}
+	stats := Stats{From: from, To: to, Period: period, ByTag: make(map[string]StatsCounts),
+		ByContext: make(map[string]StatsCounts)}
+	for t := start; t.Before(to); t = t.AddDate(0, 0, days) {
+		stats.Completions = append(stats.Completions, StatsBucket{Start: t})
+	}
+
+	ld.lock.RLock()
+	defer ld.lock.RUnlock()
+	now := ld.now()
+	for _, blobs := range []map[store.ID]store.Blob{ld.blobs, ld.archive} {
+		for id, blob := range blobs {
+			if !ld.readable(id) {
+				continue
+			}
+			tk, err := task.Unmarshal(blob)
+			if err != nil {
+				return Stats{}, err
+			}
+			if tk.Status == task.Deleted {
+				continue
+			}
+			active := ld.active(tk)
+			stats.add(tk, from, to, now, active)
+			for _, tag := range tk.Tags {
+				counts := stats.ByTag[tag]
+				counts.add(tk, from, to, now, active)
+				stats.ByTag[tag] = counts
+			}
+			for _, ctx := range tk.Contexts {
+				counts := stats.ByContext[ctx]
+				counts.add(tk, from, to, now, active)
+				stats.ByContext[ctx] = counts
+			}
+			if !active && !tk.Updated.Before(from) && tk.Updated.Before(to) {
+				// the buckets start at midnight, their lengths vary with the daylight saving time
+				i, _ := slices.BinarySearchFunc(stats.Completions, tk.Updated, func(b StatsBucket, t time.Time) int {
+					return b.Start.Compare(t)
+				})
+				if i == len(stats.Completions) || stats.Completions[i].Start.After(tk.Updated) {
+					i--
+				}
+				stats.Completions[i].Completed++
+			}
+		}
+	}
+	stats.average()
+	for tag, counts := range stats.ByTag {
+		counts.average()
+		stats.ByTag[tag] = counts
+	}
+	for ctx, counts := range stats.ByContext {
+		counts.average()
+		stats.ByContext[ctx] = counts
+	}
+	return stats, nil
+}
@@ -0,0 +1,111 @@
+package ledger
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gotestbootcamp/go-todo-app/model"
+	"github.com/gotestbootcamp/go-todo-app/store"
+	"github.com/gotestbootcamp/go-todo-app/task"
+)
+
+func TestCountByStatus(t *testing.T) {
+	ld, err := New(newTestMemory(t))
+	require.NoError(t, err)
+	for _, id := range []store.ID{"1", "2", "3"} {
+		require.NoError(t, ld.Set(id, model.New("todo "+string(id))))
+	}
+	_, err = ld.Transition("1", task.Assigned)
+	require.NoError(t, err)
+
+	counts, err := ld.CountByStatus()
+	require.NoError(t, err)
+	assert.Equal(t, map[task.Status]int{task.Pending: 2, task.Assigned: 1, task.Completed: 0, task.Deleted: 0}, counts)
+}
+
+func TestLockStats(t *testing.T) {
+	ld, err := New(newTestMemory(t))
+	require.NoError(t, err)
+	_, err = ld.Get("1")
+	assert.Error(t, err)
+	assert.Equal(t, LockStats{}, ld.LockStats(), "no contention")
+
+	ld.lock.Lock()
+	done := make(chan struct{})
+	go func() {
+		ld.Get("1")
+		close(done)
+	}()
+	time.Sleep(20 * time.Millisecond)
+	ld.lock.Unlock()
+	<-done
+
+	stats := ld.LockStats()
+	assert.Equal(t, uint64(1), stats.ReadWaits)
+	assert.GreaterOrEqual(t, stats.ReadWaited, 10*time.Millisecond)
+	assert.Zero(t, stats.Waits)
+}
+
+func TestStats(t *testing.T) {
+	t.Setenv("LC_ALL", "C")
+	st := newTestMemory(t)
+	day := func(d, h int) time.Time { return time.Date(2024, 11, d, h, 0, 0, 0, time.UTC) }
+	due := func(d int) *time.Time {
+		t := day(d, 0)
+		return &t
+	}
+	tasks := map[store.ID]task.Task{
+		"1":                  {Title: "done on time", Status: task.Completed, Tags: []string{"work"}, Due: due(6), Created: day(4, 9), Updated: day(5, 9)},
+		"2":                  {Title: "done late", Status: task.Completed, Tags: []string{"work", "home"}, Due: due(10), Created: day(1, 4), Updated: day(12, 10)},
+		"3":                  {Title: "overdue", Status: task.Pending, Tags: []string{"home"}, Contexts: []string{"office"}, Due: due(8), Created: day(6, 0), Updated: day(6, 0)},
+		"4":                  {Title: "due later", Status: task.Assigned, Contexts: []string{"office"}, Due: due(20), Created: day(10, 0), Updated: day(10, 0)},
+		"5":                  {Title: "deleted", Status: task.Deleted, Created: day(5, 0), Updated: day(5, 1)},
+		store.ArchiveID("6"): {Title: "archived", Status: task.Completed, Created: day(11, 0), Updated: day(11, 6)},
+	}
+	for id, tk := range tasks {
+		tk.Schema = task.SchemaVersion
+		blob, err := task.Marshal(tk)
+		require.NoError(t, err)
+		require.NoError(t, st.Create(id, blob))
+	}
+	ld, err := NewWithWorkflow(st, task.DefaultWorkflow())
+	require.NoError(t, err)
+	now := day(13, 12)
+	ld.now = func() time.Time { return now }
+
+	stats, err := ld.Stats(day(4, 0), now, "day")
+	require.NoError(t, err)
+	require.Len(t, stats.Completions, 10)
+	completed := make(map[int]int)
+	for _, b := range stats.Completions {
+		if b.Completed > 0 {
+			completed[b.Start.Day()] = b.Completed
+		}
+	}
+	assert.Equal(t, map[int]int{5: 1, 11: 1, 12: 1}, completed)
+	assert.Equal(t, StatsCounts{Created: 4, Completed: 3, TimeToComplete: 100 * time.Hour, Due: 3, Late: 2, Open: 2, Overdue: 1}, stats.StatsCounts)
+	assert.InDelta(t, 2.0/3, stats.OverdueRate(), 1e-9)
+	assert.Equal(t, map[string]StatsCounts{
+		"work": {Created: 1, Completed: 2, TimeToComplete: 147 * time.Hour, Due: 2, Late: 1},
+		"home": {Created: 1, Completed: 1, TimeToComplete: 270 * time.Hour, Due: 2, Late: 2, Open: 1, Overdue: 1},
+	}, stats.ByTag)
+	assert.Equal(t, map[string]StatsCounts{"office": {Created: 2, Due: 1, Late: 1, Open: 2, Overdue: 1}}, stats.ByContext)
+
+	// the weeks start on monday in the C locale, the first before the period
+	stats, err = ld.Stats(day(6, 0), now, "week")
+	require.NoError(t, err)
+	assert.Equal(t, []StatsBucket{{Start: day(4, 0)}, {Start: day(11, 0), Completed: 2}}, stats.Completions)
+	assert.Equal(t, 3, stats.Created, "from the period only")
+
+	_, err = ld.Stats(day(4, 0), now, "month")
+	assert.Error(t, err)
+	_, err = ld.Stats(now, day(4, 0), "day")
+	assert.Error(t, err)
+	_, err = ld.Stats(now.AddDate(-2, 0, 0), now, "day")
+	assert.ErrorIs(t, err, ErrPeriodTooLong)
+	_, err = ld.Stats(now.AddDate(-2, 0, 0), now, "week")
+	require.NoError(t, err)
+}
@@ -0,0 +1,343 @@
+package ledger
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"maps"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	apiv1 "github.com/gotestbootcamp/go-todo-app/api/v1"
+	"github.com/gotestbootcamp/go-todo-app/store"
+	"github.com/gotestbootcamp/go-todo-app/task"
+)
+
+// tagsID is the ID of the item holding the registry of the defined tags
+var tagsID = store.MetaID("tags")
+
+var colorRe = regexp.MustCompile(`^#[0-9a-f]{6}$`)
+
+// ErrUnknownTag is returned when a tag is neither defined nor attached to any todo
+type ErrUnknownTag struct {
+	Name string
+}
+
+func (e ErrUnknownTag) Error() string {
+	return fmt.Sprintf("unknown tag %q", e.Name)
+}
+
+// ErrTagExists is returned when renaming a tag as a tag already known
+type ErrTagExists struct {
+	Name string
+}
+
+func (e ErrTagExists) Error() string {
+	return fmt.Sprintf("tag %q already exists", e.Name)
+}
+
+// Tag describes a tag of the todos. The tags attached to the todos don't need to be defined
+// first: defining them lets set their attributes, like the color.
+type Tag struct {
+	Name string `json:"name"`
+	// Color is the color to show the tag with, as "#rrggbb". Empty if not set.
+	Color string `json:"color,omitempty"`
+}
+
+// ToAPIv1 converts a Tag on its API layer corresponding object
+func (tg Tag) ToAPIv1() apiv1.Tag {
+	return apiv1.Tag{
+		Name:  tg.Name,
+		Color: tg.Color,
+	}
+}
+
+// TagMatch tells how ListByTags matches the todos against multiple tags
+type TagMatch int
+
+const (
+	// MatchAll matches the todos with all the tags
+	MatchAll TagMatch = iota
+	// MatchAny matches the todos with any of the tags
+	MatchAny
+)
+
+func (ld *Ledger) loadTags(blob store.Blob) error {
+	var tags []Tag
+	if err := json.Unmarshal(blob, &tags); err != nil {
+		return fmt.Errorf("ledger: can't decode the tags: %w", err)
+	}
+	for _, tag := range tags {
+		ld.tags[tag.Name] = tag
+	}
+	ld.tagsStored = true
+	return nil
+}
+
+// encodeTags encodes the registry of the tags, sorted by name
+func encodeTags(tags map[string]Tag) ([]byte, error) {
+	res := make([]Tag, 0, len(tags))
+	for _, tag := range tags {
+		res = append(res, tag)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Name < res[j].Name
+	})
+	return json.Marshal(res)
+}
+
+// DefineTag adds the tag to the registry, or updates its color if it's defined already.
+// The color is either empty or "#rrggbb".
+func (ld *Ledger) DefineTag(name, color string) (Tag, error) {
+	if err := task.ValidateTag(name); err != nil {
+		return Tag{}, err
+	}
+	color = strings.ToLower(color)
+	if color != "" && !colorRe.MatchString(color) {
+		return Tag{}, fmt.Errorf("invalid color %q", color)
+	}
+
+	ld.lock.Lock()
+	defer ld.lock.Unlock()
+	tags := maps.Clone(ld.tags)
+	tag := Tag{Name: name, Color: color}
+	tags[name] = tag
+	blob, err := encodeTags(tags)
+	if err != nil {
+		return Tag{}, err
+	}
+	if ld.tagsStored {
+		err = ld.storage().Save(tagsID, blob)
+	} else {
+		err = ld.storage().Create(tagsID, blob)
+	}
+	if err != nil {
+		return Tag{}, err
+	}
+	ld.tags = tags
+	ld.tagsStored = true
+	slog.Info("ledger: DefineTag: tag defined", "tag", name, "color", color)
+	return tag, nil
+}
+
+// ListTags returns the known tags, i.e. the defined ones and the ones attached to the todos, sorted by name
+func (ld *Ledger) ListTags() ([]Tag, error) {
+	ld.lock.RLock()
+	defer ld.lock.RUnlock()
+	known := maps.Clone(ld.tags)
+	for id, blob := range ld.blobs {
+		if !ld.readable(id) {
+			continue
+		}
+		tk, err := task.Unmarshal(blob)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range tk.Tags {
+			if _, ok := known[name]; !ok {
+				known[name] = Tag{Name: name}
+			}
+		}
+	}
+	res := make([]Tag, 0, len(known))
+	for _, tag := range known {
+		res = append(res, tag)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Name < res[j].Name
+	})
+	return res, nil
+}
+
+// TagTodo attaches the tags to the todo, and returns the updated Item.
+// Fails with store.ErrNotFound if the todo doesn't exist.
+func (ld *Ledger) TagTodo(id store.ID, tags ...string) (Item, error) {
+	ld.lock.Lock()
+	defer ld.unlock()
+	tk, err := ld.loadTask(id)
+	if err != nil {
+		return Item{}, err
+	}
+	for _, tag := range tags {
+		if err := task.ValidateTag(tag); err != nil {
+			return Item{}, err
+		}
+		if !slices.Contains(tk.Tags, tag) {
+			tk.Tags = append(tk.Tags, tag)
+		}
+	}
+	slog.Info("ledger: TagTodo: object tagged", "id", id, "tags", tags)
+	return ld.saveTask(id, tk)
+}
+
+// UntagTodo detaches the tags from the todo, and returns the updated Item.
+// Fails with store.ErrNotFound if the todo doesn't exist.
+func (ld *Ledger) UntagTodo(id store.ID, tags ...string) (Item, error) {
+	ld.lock.Lock()
+	defer ld.unlock()
+	tk, err := ld.loadTask(id)
+	if err != nil {
+		return Item{}, err
+	}
+	tk.Tags = slices.DeleteFunc(tk.Tags, func(tag string) bool {
+		return slices.Contains(tags, tag)
+	})
+	slog.Info("ledger: UntagTodo: object untagged", "id", id, "tags", tags)
+	return ld.saveTask(id, tk)
+}
+
+// ListByTag returns the todos with the given tag, the earliest due first
+func (ld *Ledger) ListByTag(tag string) (Items, error) {
+	return ld.ListByTags(MatchAll, tag)
+}
+
+// ListByTags returns the todos with all or any of the given tags, depending on match,
+// the earliest due first
+func (ld *Ledger) ListByTags(match TagMatch, tags ...string) (Items, error) {
+	items, err := ld.filterTasks(func(tk task.Task) bool {
+		for _, tag := range tags {
+			found := slices.Contains(tk.Tags, tag)
+			if found && match == MatchAny {
+				return true
+			}
+			if !found && match == MatchAll {
+				return false
+			}
+		}
+		return match == MatchAll && len(tags) > 0
+	}, dueOf)
+	slog.Debug("ledger: ListByTags: objects matched", "tags", tags, "count", len(items))
+	return items, err
+}
+
+// RenameTag renames the tag, both in the registry and in all the todos. The todos are rewritten
+// atomically if the datastore supports transactions.
+// Fails with ErrUnknownTag if the tag is not known, and with ErrTagExists if the new name is.
+func (ld *Ledger) RenameTag(name, newName string) error {
+	if err := ld.checkAdmin("rename the tags"); err != nil {
+		return err
+	}
+	if err := task.ValidateTag(newName); err != nil {
+		return err
+	}
+	ld.lock.Lock()
+	defer ld.unlock()
+	known, err := ld.knownTag(newName)
+	if err != nil {
+		return err
+	}
+	if known {
+		return ErrTagExists{Name: newName}
+	}
+	slog.Info("ledger: RenameTag: renaming tag", "tag", name, "new", newName)
+	return ld.retag(name, newName)
+}
+
+// DeleteTag removes the tag, both from the registry and from all the todos. The todos are
+// rewritten atomically if the datastore supports transactions.
+// Fails with ErrUnknownTag if the tag is not known.
+func (ld *Ledger) DeleteTag(name string) error {
+	if err := ld.checkAdmin("delete the tags"); err != nil {
+		return err
+	}
+	ld.lock.Lock()
+	defer ld.unlock()
+	slog.Info("ledger: DeleteTag: deleting tag", "tag", name)
+	return ld.retag(name, "")
+}
+
+// knownTag returns true if the tag is defined, or attached to any todo. The caller must hold the lock.
+func (ld *Ledger) knownTag(name string) (bool, error) {
+	if _, ok := ld.tags[name]; ok {
+		return true, nil
+	}
+	for _, blob := range ld.blobs {
+		tk, err := task.Unmarshal(blob)
+		if err != nil {
+			return false, err
+		}
+		if slices.Contains(tk.Tags, name) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// retag replaces the tag with newName in the registry and in all the todos, in a single transaction;
+// the empty newName removes the tag. The caller must hold the lock.
+func (ld *Ledger) retag(name, newName string) (rerr error) {
+	tx, err := ld.begin()
+	if err != nil {
+		return err
+	}
+	history, steps, changes := maps.Clone(ld.history), len(ld.steps), len(ld.changes)
+	defer func() {
+		if rerr != nil {
+			tx.Rollback()
+			ld.history, ld.steps, ld.changes = history, ld.steps[:steps], ld.changes[:changes]
+		}
+	}()
+
+	found := false
+	updated := make(map[store.ID]store.Blob)
+	for id, blob := range ld.blobs {
+		tk, err := task.Unmarshal(blob)
+		if err != nil {
+			return err
+		}
+		pos := slices.Index(tk.Tags, name)
+		if pos < 0 {
+			continue
+		}
+		found = true
+		if newName == "" {
+			tk.Tags = slices.Delete(tk.Tags, pos, pos+1)
+		} else {
+			tk.Tags[pos] = newName
+		}
+		tk.Updated = ld.now()
+		blob, err := task.Marshal(tk)
+		if err != nil {
+			return err
+		}
+		if err := tx.Save(id, blob); err != nil {
+			return err
+		}
+		if err := ld.record(tx, id, ld.blobs[id], blob); err != nil {
+			return err
+		}
+		updated[id] = blob
+	}
+
+	tags := ld.tags
+	if tag, ok := ld.tags[name]; ok {
+		found = true
+		tags = maps.Clone(ld.tags)
+		delete(tags, name)
+		if newName != "" {
+			tag.Name = newName
+			tags[newName] = tag
+		}
+		blob, err := encodeTags(tags)
+		if err != nil {
+			return err
+		}
+		// defined tags imply a stored registry
+		if err := tx.Save(tagsID, blob); err != nil {
+			return err
+		}
+	}
+	if !found {
+		return ErrUnknownTag{Name: name}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	maps.Copy(ld.blobs, updated)
+	ld.tags = tags
+	slog.Info("ledger: retag: rewrote objects", "count", len(updated))
+	return nil
+}
@@ -0,0 +1,113 @@
+package ledger
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gotestbootcamp/go-todo-app/model"
+	"github.com/gotestbootcamp/go-todo-app/store"
+	"github.com/gotestbootcamp/go-todo-app/store/fake"
+)
+
+func TestTags(t *testing.T) {
+	st := newTestMemory(t)
+	ld, err := New(st)
+	require.NoError(t, err)
+	for _, id := range []store.ID{"1", "2", "3"} {
+		require.NoError(t, ld.Set(id, model.New("todo "+string(id))))
+	}
+
+	_, err = ld.TagTodo("1", "work", "urgent")
+	require.NoError(t, err)
+	_, err = ld.TagTodo("2", "work")
+	require.NoError(t, err)
+	item, err := ld.TagTodo("3", "home", "urgent", "home")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"home", "urgent"}, item.Task.Tags)
+	_, err = ld.TagTodo("3", "two words")
+	assert.Error(t, err)
+	_, err = ld.TagTodo("4", "work")
+	assert.ErrorIs(t, err, store.ErrNotFound{ID: "4"})
+
+	_, err = ld.DefineTag("urgent", "#FF0000")
+	require.NoError(t, err)
+	_, err = ld.DefineTag("someday", "")
+	require.NoError(t, err)
+	_, err = ld.DefineTag("urgent", "red")
+	assert.Error(t, err)
+
+	tags, err := ld.ListTags()
+	require.NoError(t, err)
+	assert.Equal(t, []Tag{{Name: "home"}, {Name: "someday"}, {Name: "urgent", Color: "#ff0000"}, {Name: "work"}}, tags)
+
+	items, err := ld.ListByTag("work")
+	require.NoError(t, err)
+	assert.Equal(t, []store.ID{"1", "2"}, idsOf(items))
+	items, err = ld.ListByTags(MatchAll, "work", "urgent")
+	require.NoError(t, err)
+	assert.Equal(t, []store.ID{"1"}, idsOf(items))
+	items, err = ld.ListByTags(MatchAny, "home", "urgent")
+	require.NoError(t, err)
+	assert.Equal(t, []store.ID{"1", "3"}, idsOf(items))
+
+	item, err = ld.UntagTodo("3", "home")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"urgent"}, item.Task.Tags)
+
+	// the registry survives the restarts
+	ld, err = New(st)
+	require.NoError(t, err)
+	tags, err = ld.ListTags()
+	require.NoError(t, err)
+	assert.Equal(t, []Tag{{Name: "someday"}, {Name: "urgent", Color: "#ff0000"}, {Name: "work"}}, tags)
+	items, err = ld.Filter(func(model.Todo) bool { return true })
+	require.NoError(t, err)
+	assert.Len(t, items, 3)
+}
+
+func TestRenameTag(t *testing.T) {
+	fakeMem, err := fake.NewMem()
+	require.NoError(t, err)
+	for name, st := range map[string]store.Storage{
+		"memory": newTestMemory(t),
+		// not transactional
+		"fake": fakeMem,
+	} {
+		t.Run(name, func(t *testing.T) {
+			ld, err := New(st)
+			require.NoError(t, err)
+			for _, id := range []store.ID{"1", "2", "3"} {
+				require.NoError(t, ld.Set(id, model.New("todo "+string(id))))
+			}
+			_, err = ld.TagTodo("1", "work", "urgent")
+			require.NoError(t, err)
+			_, err = ld.TagTodo("2", "urgent")
+			require.NoError(t, err)
+			_, err = ld.DefineTag("urgent", "#ff0000")
+			require.NoError(t, err)
+
+			assert.ErrorIs(t, ld.RenameTag("urgent", "work"), ErrTagExists{Name: "work"})
+			assert.ErrorIs(t, ld.RenameTag("later", "someday"), ErrUnknownTag{Name: "later"})
+			require.NoError(t, ld.RenameTag("urgent", "asap"))
+
+			items, err := ld.ListByTag("asap")
+			require.NoError(t, err)
+			assert.Equal(t, []store.ID{"1", "2"}, idsOf(items))
+			assert.Equal(t, []string{"work", "asap"}, items[0].Task.Tags)
+			items, err = ld.ListByTag("urgent")
+			require.NoError(t, err)
+			assert.Empty(t, items)
+			tags, err := ld.ListTags()
+			require.NoError(t, err)
+			assert.Equal(t, []Tag{{Name: "asap", Color: "#ff0000"}, {Name: "work"}}, tags)
+
+			require.NoError(t, ld.DeleteTag("asap"))
+			assert.ErrorIs(t, ld.DeleteTag("asap"), ErrUnknownTag{Name: "asap"})
+			tags, err = ld.ListTags()
+			require.NoError(t, err)
+			assert.Equal(t, []Tag{{Name: "work"}}, tags)
+		})
+	}
+}
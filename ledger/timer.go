@@ -0,0 +1,151 @@
+package ledger
+
+import (
+	"log/slog"
+	"time"
+
+	apiv1 "github.com/gotestbootcamp/go-todo-app/api/v1"
+	"github.com/gotestbootcamp/go-todo-app/store"
+	"github.com/gotestbootcamp/go-todo-app/task"
+)
+
+// dayLayout formats the days of TimeReport.ByDay
+const dayLayout = "2006-01-02"
+
+// TimeReport aggregates the time tracked on the todos within a period
+type TimeReport struct {
+	From, To time.Time
+	// Total is the time tracked on all the todos
+	Total time.Duration
+	// Pomodoros is how many pomodoros ended within the period, see task.TimeEntry.Pomodoro
+	Pomodoros int
+	// ByTask is the time tracked on each todo, by ID
+	ByTask map[store.ID]time.Duration
+	// ByTag is the time tracked on the todos with each tag. The time of the todos
+	// with many tags counts for each of them.
+	ByTag map[string]time.Duration
+	// ByDay is the time tracked each day, as "2006-01-02" in the location of From
+	ByDay map[string]time.Duration
+}
+
+// ToAPIv1 converts the TimeReport in its API v1 representation
+func (rep TimeReport) ToAPIv1() apiv1.TimeReport {
+	res := apiv1.TimeReport{
+		From:      rep.From,
+		To:        rep.To,
+		Total:     rep.Total.Hours(),
+		Pomodoros: rep.Pomodoros,
+		ByTask:    make(map[apiv1.ID]float64, len(rep.ByTask)),
+		ByTag:     make(map[string]float64, len(rep.ByTag)),
+		ByDay:     make(map[string]float64, len(rep.ByDay)),
+	}
+	for id, d := range rep.ByTask {
+		res.ByTask[apiv1.ID(id)] = d.Hours()
+	}
+	for tag, d := range rep.ByTag {
+		res.ByTag[tag] = d.Hours()
+	}
+	for day, d := range rep.ByDay {
+		res.ByDay[day] = d.Hours()
+	}
+	return res
+}
+
+// StartTimer starts tracking the time spent on the todo, and returns the updated Item.
+// Fails with task.ErrTimerRunning if the timer of the todo runs already.
+func (ld *Ledger) StartTimer(id store.ID) (Item, error) {
+	ld.lock.Lock()
+	defer ld.unlock()
+	tk, err := ld.loadTask(id)
+	if err != nil {
+		return Item{}, err
+	}
+	if err := tk.Start(ld.now()); err != nil {
+		return Item{}, err
+	}
+	slog.Info("ledger: StartTimer: timer started", "id", id)
+	return ld.saveTask(id, tk)
+}
+
+// StopTimer stops tracking the time spent on the todo, and returns the updated Item.
+// Fails with task.ErrTimerStopped if the timer of the todo doesn't run.
+func (ld *Ledger) StopTimer(id store.ID) (Item, error) {
+	ld.lock.Lock()
+	defer ld.unlock()
+	tk, err := ld.loadTask(id)
+	if err != nil {
+		return Item{}, err
+	}
+	if err := tk.Stop(ld.now()); err != nil {
+		return Item{}, err
+	}
+	slog.Info("ledger: StopTimer: timer stopped", "id", id)
+	return ld.saveTask(id, tk)
+}
+
+// AddTime adds the time entry, which ended already, to the ones of the todo, and returns the
+// updated Item: todo pomo logs the pomodoros with it. Fails with task.ErrTimerRunning if the timer
+// of the todo runs, and with a task.ValidationError if the entry overlaps another.
+func (ld *Ledger) AddTime(id store.ID, entry task.TimeEntry) (Item, error) {
+	ld.lock.Lock()
+	defer ld.unlock()
+	tk, err := ld.loadTask(id)
+	if err != nil {
+		return Item{}, err
+	}
+	if err := tk.AddTime(entry); err != nil {
+		return Item{}, err
+	}
+	slog.Info("ledger: AddTime: time entry added", "id", id, "start", entry.Start, "end", entry.End, "pomodoro", entry.Pomodoro)
+	return ld.saveTask(id, tk)
+}
+
+// TimeReport aggregates the time tracked on the todos from the given time until the other,
+// by todo, by tag and by day, and the pomodoros. The running timers count until now; the archived todos count as well.
+func (ld *Ledger) TimeReport(from, to time.Time) (TimeReport, error) {
+	ld.lock.RLock()
+	defer ld.lock.RUnlock()
+	rep := TimeReport{
+		From:   from,
+		To:     to,
+		ByTask: make(map[store.ID]time.Duration),
+		ByTag:  make(map[string]time.Duration),
+		ByDay:  make(map[string]time.Duration),
+	}
+	now := ld.now()
+	for _, blobs := range []map[store.ID]store.Blob{ld.blobs, ld.archive} {
+		for id, blob := range blobs {
+			if !ld.readable(id) {
+				continue
+			}
+			tk, err := task.Unmarshal(blob)
+			if err != nil {
+				return TimeReport{}, err
+			}
+			rep.Pomodoros += tk.Pomodoros(from, to)
+			tracked := tk.Tracked(from, to, now)
+			if tracked == 0 {
+				continue
+			}
+			rep.Total += tracked
+			rep.ByTask[id] += tracked
+			for _, tag := range tk.Tags {
+				rep.ByTag[tag] += tracked
+			}
+			y, m, d := from.Date()
+			for day := time.Date(y, m, d, 0, 0, 0, 0, from.Location()); day.Before(to); day = day.AddDate(0, 0, 1) {
+				start, end := day, day.AddDate(0, 0, 1)
+				if start.Before(from) {
+					start = from
+				}
+				if end.After(to) {
+					end = to
+				}
+				if daily := tk.Tracked(start, end, now); daily > 0 {
+					rep.ByDay[day.Format(dayLayout)] += daily
+				}
+			}
+		}
+	}
+	return rep, nil
+}
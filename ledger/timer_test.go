@@ -0,0 +1,87 @@
+package ledger
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gotestbootcamp/go-todo-app/model"
+	"github.com/gotestbootcamp/go-todo-app/store"
+	"github.com/gotestbootcamp/go-todo-app/task"
+)
+
+func TestTimeReport(t *testing.T) {
+	st := newTestMemory(t)
+	ld, err := New(st)
+	require.NoError(t, err)
+	now := time.Date(2024, 11, 11, 22, 0, 0, 0, time.UTC)
+	ld.now = func() time.Time { return now }
+	for _, id := range []store.ID{"1", "2"} {
+		require.NoError(t, ld.Set(id, model.New("todo "+string(id))))
+	}
+	_, err = ld.TagTodo("1", "acme", "web")
+	require.NoError(t, err)
+
+	_, err = ld.StopTimer("1")
+	assert.ErrorIs(t, err, task.ErrTimerStopped)
+	item, err := ld.StartTimer("1")
+	require.NoError(t, err)
+	assert.True(t, item.Task.Running())
+	_, err = ld.StartTimer("1")
+	assert.ErrorIs(t, err, task.ErrTimerRunning)
+	_, err = ld.StartTimer("2")
+	require.NoError(t, err)
+	now = now.Add(time.Hour)
+	_, err = ld.StopTimer("2")
+	require.NoError(t, err)
+
+	// the running timers survive the restarts
+	ld, err = New(st)
+	require.NoError(t, err)
+	now = now.Add(3 * time.Hour)
+	ld.now = func() time.Time { return now }
+
+	from := time.Date(2024, 11, 11, 0, 0, 0, 0, time.UTC)
+	rep, err := ld.TimeReport(from, from.AddDate(0, 0, 7))
+	require.NoError(t, err)
+	assert.Equal(t, 5*time.Hour, rep.Total)
+	assert.Equal(t, map[store.ID]time.Duration{"1": 4 * time.Hour, "2": time.Hour}, rep.ByTask)
+	assert.Equal(t, map[string]time.Duration{"acme": 4 * time.Hour, "web": 4 * time.Hour}, rep.ByTag)
+	assert.Equal(t, map[string]time.Duration{"2024-11-11": 3 * time.Hour, "2024-11-12": 2 * time.Hour}, rep.ByDay)
+
+	rep, err = ld.TimeReport(from.AddDate(0, 0, 1), from.AddDate(0, 0, 2))
+	require.NoError(t, err)
+	assert.Equal(t, 2*time.Hour, rep.Total)
+	assert.Equal(t, map[store.ID]time.Duration{"1": 2 * time.Hour}, rep.ByTask)
+}
+
+func TestAddTime(t *testing.T) {
+	ld, err := New(newTestMemory(t))
+	require.NoError(t, err)
+	require.NoError(t, ld.Set("1", model.New("write report")))
+	start := time.Date(2024, 11, 11, 9, 0, 0, 0, time.UTC)
+	end := start.Add(25 * time.Minute)
+
+	item, err := ld.AddTime("1", task.TimeEntry{Start: start, End: &end, Pomodoro: true})
+	require.NoError(t, err)
+	assert.Equal(t, 1, item.ToAPIv1().Todo.Pomodoros)
+	_, err = ld.AddTime("1", task.TimeEntry{Start: start.Add(10 * time.Minute), End: &end})
+	var invalid task.ValidationError
+	assert.ErrorAs(t, err, &invalid)
+	_, err = ld.AddTime("2", task.TimeEntry{Start: start, End: &end})
+	assert.ErrorAs(t, err, &store.ErrNotFound{})
+	_, err = ld.StartTimer("1")
+	require.NoError(t, err)
+	_, err = ld.AddTime("1", task.TimeEntry{Start: start.Add(-time.Hour), End: &start})
+	assert.ErrorIs(t, err, task.ErrTimerRunning)
+
+	rep, err := ld.TimeReport(start, start.Add(time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, 1, rep.Pomodoros)
+	assert.Equal(t, 25*time.Minute, rep.Total)
+	stats, err := ld.Stats(start, start.Add(time.Hour), "day")
+	require.NoError(t, err)
+	assert.Equal(t, 1, stats.Pomodoros)
+}
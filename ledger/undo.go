@@ -0,0 +1,248 @@
+package ledger
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"sort"
+
+	"github.com/gotestbootcamp/go-todo-app/store"
+)
+
+var (
+	ErrNothingToUndo = errors.New("nothing to undo")
+	ErrNothingToRedo = errors.New("nothing to redo")
+)
+
+// opsID is the ID of the stored operation log
+var opsID = store.MetaID("oplog")
+
+// maxOps bounds the operations which can be undone: the oldest ones are forgotten
+const maxOps = 100
+
+// step is a revision of a todo made by an operation
+type step struct {
+	ID  store.ID `json:"id"`
+	Rev int      `json:"rev"`
+}
+
+// opLog is the log of the operations which can be undone, oldest first.
+// The last Undone operations were undone, and can be redone.
+type opLog struct {
+	Ops    [][]step `json:"ops"`
+	Undone int      `json:"undone"`
+}
+
+// opLogs are the logs of the operations of each actor, who undoes and redoes only their own
+type opLogs map[string]opLog
+
+func (ld *Ledger) loadOps(blob store.Blob) error {
+	var logs opLogs
+	if err := json.Unmarshal(blob, &logs); err != nil {
+		// the log stored before the actors had their own is the one of the ledger
+		var log opLog
+		if json.Unmarshal(blob, &log) != nil {
+			return fmt.Errorf("ledger: can't decode the operation log: %w", err)
+		}
+		logs = opLogs{"": log}
+	}
+	ld.ops = logs
+	ld.opsStored = true
+	return nil
+}
+
+// forget drops the operations on the todo with the given ID, which can't be undone nor redone anymore
+func (log *opLog) forget(id store.ID) {
+	done := len(log.Ops) - log.Undone
+	var ops [][]step
+	for i, op := range log.Ops {
+		if !slices.ContainsFunc(op, func(st step) bool { return st.ID == id }) {
+			ops = append(ops, op)
+		} else if i >= done {
+			log.Undone--
+		}
+	}
+	log.Ops = ops
+}
+
+// forget drops the operations of all the actors on the todo with the given ID, see opLog.forget
+func (logs opLogs) forget(id store.ID) {
+	for actor, log := range logs {
+		log.forget(id)
+		logs[actor] = log
+	}
+}
+
+// saveOps stores the operation log. The caller must hold the lock.
+func (ld *Ledger) saveOps() error {
+	blob, err := json.Marshal(ld.ops)
+	if err != nil {
+		return err
+	}
+	if ld.opsStored {
+		err = ld.storage().Save(opsID, blob)
+	} else {
+		err = ld.storage().Create(opsID, blob)
+	}
+	if err != nil {
+		return err
+	}
+	ld.opsStored = true
+	return nil
+}
+
+// unlock releases the lock taken by a mutation, logging the revisions it recorded as
+// a single operation of the actor, which can be undone. The operations the actor undid can't be
+// redone anymore.
+// The changelog is compacted too, as its policy tells, see SetChangeLogPolicy.
+func (ld *Ledger) unlock() {
+	defer ld.lock.Unlock()
+	if len(ld.steps) == 0 {
+		return
+	}
+	ld.truncateChanges()
+	log := ld.ops[ld.actor]
+	ops := append(log.Ops[:len(log.Ops)-log.Undone], ld.steps)
+	if len(ops) > maxOps {
+		ops = ops[len(ops)-maxOps:]
+	}
+	if ld.ops == nil {
+		ld.ops = make(opLogs)
+	}
+	ld.ops[ld.actor] = opLog{Ops: ops}
+	ld.steps = nil
+	if err := ld.saveOps(); err != nil {
+		slog.Error("ledger: failed to store the operation log", "error", err)
+	}
+}
+
+// Undo reverses the last operation of the actor not undone yet, like creating, editing, completing
+// or deleting todos, and returns the restored Items. The todos the operation created are deleted,
+// and the ones it deleted are recreated. The operation is undone all together or not at all.
+// Fails with ErrNothingToUndo if the actor has no operations to undo.
+func (ld *Ledger) Undo() (Items, error) {
+	ld.lock.Lock()
+	defer ld.lock.Unlock()
+	log := ld.ops[ld.actor]
+	if log.Undone == len(log.Ops) {
+		return nil, ErrNothingToUndo
+	}
+	op := log.Ops[len(log.Ops)-log.Undone-1]
+	items, err := ld.replay(op, true)
+	if err != nil {
+		return nil, err
+	}
+	log.Undone++
+	ld.ops[ld.actor] = log
+	slog.Info("ledger: Undo: reversed revisions", "count", len(op))
+	return items, ld.saveOps()
+}
+
+// Redo reapplies the last operation the actor undid, and returns the restored Items. The operation
+// is redone all together or not at all. Fails with ErrNothingToRedo if the actor has no operations
+// to redo.
+func (ld *Ledger) Redo() (Items, error) {
+	ld.lock.Lock()
+	defer ld.lock.Unlock()
+	log := ld.ops[ld.actor]
+	if log.Undone == 0 {
+		return nil, ErrNothingToRedo
+	}
+	op := log.Ops[len(log.Ops)-log.Undone]
+	items, err := ld.replay(op, false)
+	if err != nil {
+		return nil, err
+	}
+	log.Undone--
+	ld.ops[ld.actor] = log
+	slog.Info("ledger: Redo: reapplied revisions", "count", len(op))
+	return items, ld.saveOps()
+}
+
+// replay restores the todos as they were before the steps of the operation if undo, in reverse
+// order, or as they were after them otherwise, and returns the restored Items, but the deleted ones.
+// The restores are recorded in the history, but they aren't operations on their own. The todos are
+// restored atomically if the datastore supports transactions, and none is if any fails.
+// The caller must hold the lock.
+func (ld *Ledger) replay(op []step, undo bool) (items Items, rerr error) {
+	tx, err := ld.begin()
+	if err != nil {
+		return nil, err
+	}
+	history, changes := maps.Clone(ld.history), len(ld.changes)
+	// the todos as they were before the replay, nil the ones missing
+	blobs := make(map[store.ID]store.Blob)
+	defer func() {
+		ld.steps = nil
+		if rerr != nil {
+			tx.Rollback()
+			ld.history, ld.changes = history, ld.changes[:changes]
+			for id, blob := range blobs {
+				if blob == nil {
+					delete(ld.blobs, id)
+				} else {
+					ld.blobs[id] = blob
+				}
+			}
+		}
+	}()
+	if undo {
+		op = slices.Clone(op)
+		slices.Reverse(op)
+	}
+	// the view may replay only the operations on its todos, not to stop halfway
+	for _, st := range op {
+		if err := ld.checkOwner(st.ID, ld.blobs[st.ID]); err != nil {
+			return nil, err
+		}
+	}
+	restored := make(map[store.ID]bool)
+	for _, st := range op {
+		revs := ld.history[st.ID]
+		i := ld.revisionAt(st.ID, st.Rev)
+		if i < 0 {
+			return nil, ErrNoRevision{ID: st.ID, Rev: st.Rev}
+		}
+		state := revs[i].State
+		if undo {
+			switch {
+			case i > 0:
+				state = revs[i-1].State
+			case revs[i].created():
+				state = nil
+			default:
+				// the history doesn't keep the revision before anymore
+				return nil, ErrNoRevision{ID: st.ID, Rev: st.Rev - 1}
+			}
+		}
+		if _, ok := blobs[st.ID]; !ok {
+			blobs[st.ID] = ld.blobs[st.ID]
+		}
+		if err := ld.restore(tx, st.ID, state); err != nil {
+			return nil, err
+		}
+		restored[st.ID] = true
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	for id := range restored {
+		blob, ok := ld.blobs[id]
+		if !ok {
+			continue
+		}
+		item, err := newItem(id, blob)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].ID < items[j].ID
+	})
+	return items, nil
+}
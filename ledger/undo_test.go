@@ -0,0 +1,142 @@
+package ledger
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gotestbootcamp/go-todo-app/model"
+	"github.com/gotestbootcamp/go-todo-app/store"
+	"github.com/gotestbootcamp/go-todo-app/task"
+)
+
+func TestUndo(t *testing.T) {
+	st := newTestMemory(t)
+	ld, err := New(st)
+	require.NoError(t, err)
+	_, err = ld.Undo()
+	assert.ErrorIs(t, err, ErrNothingToUndo)
+
+	require.NoError(t, ld.Set("1", model.New("foo")))
+	_, err = ld.SetPriority("1", task.PriorityHigh)
+	require.NoError(t, err)
+	require.NoError(t, ld.Delete("1"))
+
+	// undo the deletion
+	items, err := ld.Undo()
+	require.NoError(t, err)
+	assert.Equal(t, []store.ID{"1"}, idsOf(items))
+	assert.Equal(t, task.PriorityHigh, items[0].Task.Priority)
+	// undo the edit
+	items, err = ld.Undo()
+	require.NoError(t, err)
+	assert.Equal(t, task.PriorityNone, items[0].Task.Priority)
+	// undo the creation
+	items, err = ld.Undo()
+	require.NoError(t, err)
+	assert.Empty(t, items)
+	_, err = ld.Get("1")
+	assert.Error(t, err)
+	_, err = ld.Undo()
+	assert.ErrorIs(t, err, ErrNothingToUndo)
+
+	// the log survives the restarts
+	ld, err = New(st)
+	require.NoError(t, err)
+	_, err = ld.Redo()
+	require.NoError(t, err)
+	items, err = ld.Redo()
+	require.NoError(t, err)
+	assert.Equal(t, task.PriorityHigh, items[0].Task.Priority)
+
+	// a new operation drops the ones left to redo
+	_, err = ld.SetPriority("1", task.PriorityLow)
+	require.NoError(t, err)
+	_, err = ld.Redo()
+	assert.ErrorIs(t, err, ErrNothingToRedo)
+	items, err = ld.Undo()
+	require.NoError(t, err)
+	assert.Equal(t, task.PriorityHigh, items[0].Task.Priority)
+}
+
+func TestUndoRecurring(t *testing.T) {
+	ld := newTestLedger(t, task.DefaultWorkflow())
+	require.NoError(t, ld.Set("1", model.New("foo")))
+	_, err := ld.Schedule("1", Schedule{Recur: "daily"})
+	require.NoError(t, err)
+	_, err = ld.Transition("1", task.Assigned)
+	require.NoError(t, err)
+	_, err = ld.Transition("1", task.Completed)
+	require.NoError(t, err)
+	require.Len(t, ld.blobs, 2)
+
+	// completing and creating the next occurrence are a single operation
+	items, err := ld.Undo()
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	assert.Equal(t, task.Assigned, items[0].Task.Status)
+	assert.Len(t, ld.blobs, 1)
+
+	_, err = ld.Redo()
+	require.NoError(t, err)
+	assert.Len(t, ld.blobs, 2)
+}
+
+func TestUndoActors(t *testing.T) {
+	st := newTestMemory(t)
+	ld, err := New(st)
+	require.NoError(t, err)
+	alice, bob := ld.As("alice"), ld.As("bob")
+	require.NoError(t, alice.Set("1", model.New("groceries")))
+	require.NoError(t, bob.Set("2", model.New("laundry")))
+
+	// each actor undoes their own operations
+	items, err := alice.Undo()
+	require.NoError(t, err)
+	assert.Empty(t, items)
+	_, err = ld.Get("1")
+	assert.Error(t, err)
+	_, err = ld.Get("2")
+	require.NoError(t, err)
+	_, err = alice.Undo()
+	assert.ErrorIs(t, err, ErrNothingToUndo)
+	_, err = ld.Undo()
+	assert.ErrorIs(t, err, ErrNothingToUndo)
+
+	// nor the operations of the others drop the ones left to redo
+	_, err = bob.SetPriority("2", task.PriorityHigh)
+	require.NoError(t, err)
+	ld, err = New(st)
+	require.NoError(t, err)
+	items, err = ld.As("alice").Redo()
+	require.NoError(t, err)
+	assert.Equal(t, []store.ID{"1"}, idsOf(items))
+	items, err = ld.As("bob").Undo()
+	require.NoError(t, err)
+	assert.Equal(t, task.PriorityNone, items[0].Task.Priority)
+}
+
+func TestUndoAtomic(t *testing.T) {
+	st := newTestMemory(t)
+	ld, err := NewWithWorkflow(st, task.DefaultWorkflow())
+	require.NoError(t, err)
+	require.NoError(t, ld.Set("1", model.New("foo")))
+	_, err = ld.Schedule("1", Schedule{Recur: "daily"})
+	require.NoError(t, err)
+	_, err = ld.Transition("1", task.Assigned)
+	require.NoError(t, err)
+	_, err = ld.Transition("1", task.Completed)
+	require.NoError(t, err)
+	require.Len(t, ld.blobs, 2)
+
+	// the next occurrence is removed first, then the revision of the completion is missing
+	ld.history["1"] = ld.history["1"][len(ld.history["1"])-1:]
+	_, err = ld.Undo()
+	assert.ErrorIs(t, err, ErrNoRevision{ID: "1", Rev: 4})
+	assert.Len(t, ld.blobs, 2)
+	assert.Len(t, ld.history["2"], 1)
+	ld, err = NewWithWorkflow(st, task.DefaultWorkflow())
+	require.NoError(t, err)
+	assert.Len(t, ld.blobs, 2)
+}
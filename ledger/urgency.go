@@ -0,0 +1,49 @@
+package ledger
+
+import (
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/gotestbootcamp/go-todo-app/store"
+	"github.com/gotestbootcamp/go-todo-app/task"
+)
+
+// SetPriority sets the priority of the todo, and returns the updated Item.
+// Fails with store.ErrNotFound if the todo doesn't exist.
+func (ld *Ledger) SetPriority(id store.ID, priority task.Priority) (Item, error) {
+	ld.lock.Lock()
+	defer ld.unlock()
+	tk, err := ld.loadTask(id)
+	if err != nil {
+		return Item{}, err
+	}
+	tk.Priority = priority
+	slog.Info("ledger: SetPriority: object prioritized", "id", id, "priority", priority)
+	return ld.saveTask(id, tk)
+}
+
+// ListByUrgency returns the active todos, the most urgent first: see task.Task.Urgency
+func (ld *Ledger) ListByUrgency() (Items, error) {
+	now := ld.now()
+	items, err := ld.filterTasks(ld.active, dueOf)
+	if err != nil {
+		return nil, err
+	}
+	urgency := make(map[store.ID]float64, len(items))
+	for _, item := range items {
+		urgency[item.ID] = item.Task.Urgency(now)
+	}
+	slices.SortFunc(items, func(a, b Item) int {
+		switch ua, ub := urgency[a.ID], urgency[b.ID]; {
+		case ua > ub:
+			return -1
+		case ua < ub:
+			return 1
+		default:
+			return strings.Compare(string(a.ID), string(b.ID))
+		}
+	})
+	slog.Debug("ledger: ListByUrgency: objects active", "count", len(items))
+	return items, nil
+}
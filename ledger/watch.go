@@ -0,0 +1,74 @@
+package ledger
+
+import (
+	"context"
+
+	apiv1 "github.com/gotestbootcamp/go-todo-app/api/v1"
+	"github.com/gotestbootcamp/go-todo-app/store"
+)
+
+// Event describes a change of a todo
+type Event struct {
+	// Seq numbers the events received with Subscribe, increasing. Zero for the ones received with Watch.
+	Seq  uint64
+	Type store.EventType
+	// Item is the todo after the change. Only the ID is set if the todo was deleted.
+	Item Item
+}
+
+// ToAPIv1 converts the Event in its API v1 representation
+func (ev Event) ToAPIv1() apiv1.Event {
+	item := apiv1.Item{ID: apiv1.ID(ev.Item.ID)}
+	if ev.Item.Todo != nil {
+		item = ev.Item.ToAPIv1()
+	}
+	return apiv1.Event{
+		ID:   ev.Seq,
+		Type: string(ev.Type),
+		Item: item,
+	}
+}
+
+// Watch returns a channel which receives the changes of the todos until the given context is done,
+// then it's closed. The channel is closed as well if the watcher falls behind, so it can resync.
+// The archived todos are not watched: archiving deletes the todo.
+// Fails with store.ErrUnsupported if the datastore can't be watched, see store.Notifier.
+func (ld *Ledger) Watch(ctx context.Context) (<-chan Event, error) {
+	changes, err := store.Watch(ctx, ld.storer)
+	if err != nil {
+		return nil, err
+	}
+	events := make(chan Event)
+	go func() {
+		defer close(events)
+		for change := range changes {
+			if store.IsMeta(change.ID) || store.IsArchived(change.ID) || !ld.canWatch(change.ID) {
+				continue
+			}
+			ev := Event{Type: change.Type, Item: Item{ID: change.ID}}
+			if change.Type != store.EventDeleted {
+				item, err := newItem(change.ID, change.Blob)
+				if err != nil {
+					continue
+				}
+				ev.Item = item
+			}
+			select {
+			case events <- ev:
+			case <-ctx.Done():
+				// drain, until the storage closes the changes
+				for range changes {
+				}
+				return
+			}
+		}
+	}()
+	return events, nil
+}
+
+// canWatch tells whether the view may see the changes of the todo with the given ID
+func (ld *Ledger) canWatch(id store.ID) bool {
+	ld.lock.RLock()
+	defer ld.lock.RUnlock()
+	return ld.readable(id)
+}
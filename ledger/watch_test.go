@@ -0,0 +1,56 @@
+package ledger
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gotestbootcamp/go-todo-app/model"
+	"github.com/gotestbootcamp/go-todo-app/store"
+)
+
+func TestWatch(t *testing.T) {
+	ld, err := New(store.NewNotifier(newTestMemory(t)))
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events, err := ld.Watch(ctx)
+	require.NoError(t, err)
+
+	require.NoError(t, ld.Set("1", model.New("foo")))
+	_, err = ld.TagTodo("1", "work")
+	require.NoError(t, err)
+	require.NoError(t, ld.Delete("1"))
+
+	next := func() Event {
+		select {
+		case ev := <-events:
+			return ev
+		case <-time.After(time.Second):
+			t.Fatal("no event")
+			return Event{}
+		}
+	}
+	// the history and the undo log are not watched
+	ev := next()
+	assert.Equal(t, store.EventCreated, ev.Type)
+	assert.Equal(t, "foo", ev.Item.Task.Title)
+	ev = next()
+	assert.Equal(t, store.EventUpdated, ev.Type)
+	assert.Equal(t, []string{"work"}, ev.Item.Task.Tags)
+	assert.Equal(t, Event{Type: store.EventDeleted, Item: Item{ID: "1"}}, next())
+
+	cancel()
+	for range events {
+	}
+}
+
+func TestWatchUnsupported(t *testing.T) {
+	ld, err := New(newTestMemory(t))
+	require.NoError(t, err)
+	_, err = ld.Watch(context.Background())
+	assert.ErrorIs(t, err, store.ErrUnsupported)
+}
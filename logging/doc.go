@@ -0,0 +1,3 @@
+// Package logging sets up the structured logging of the server with log/slog, and carries the IDs
+// of the requests in their context, for the records logged while serving them to tell them apart
+package logging
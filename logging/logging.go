@@ -0,0 +1,101 @@
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// RequestIDKey is the attribute of the records holding the ID of the request they were logged for
+const RequestIDKey = "request_id"
+
+// TraceIDKey and SpanIDKey are the attributes of the records holding the IDs of the trace and of
+// the span they were logged in, to find the trace of the records
+const (
+	TraceIDKey = "trace_id"
+	SpanIDKey  = "span_id"
+)
+
+type requestIDKey struct{}
+
+// NewContext returns a copy of the context carrying the ID of the request
+func NewContext(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// RequestID returns the ID of the request carried by the context. Empty if there's none.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// NewRequestID returns a random ID for a request
+func NewRequestID() string {
+	buf := make([]byte, 8)
+	rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
+
+// ValidRequestID tells whether the ID of a request, set by the client, is safe to log: short, and
+// made of printable ASCII characters without spaces
+func ValidRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// New returns the logger writing to w the records of the given level and above, "debug", "info",
+// "warn" or "error", in the given format, "text" or "json". The records logged with a context,
+// e.g. with slog.InfoContext, hold the ID of the request it carries, and the IDs of its span, if any.
+func New(w io.Writer, level, format string) (*slog.Logger, error) {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return nil, fmt.Errorf("invalid log level %q", level)
+	}
+	opts := &slog.HandlerOptions{Level: lvl}
+	var handler slog.Handler
+	switch format {
+	case "text":
+		handler = slog.NewTextHandler(w, opts)
+	case "json":
+		handler = slog.NewJSONHandler(w, opts)
+	default:
+		return nil, fmt.Errorf("invalid log format %q, want text or json", format)
+	}
+	return slog.New(contextHandler{Handler: handler}), nil
+}
+
+// contextHandler adds to the records the ID of the request, and the IDs of the span, carried by
+// their context
+type contextHandler struct {
+	slog.Handler
+}
+
+func (ch contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if id := RequestID(ctx); id != "" {
+		r.AddAttrs(slog.String(RequestIDKey, id))
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		r.AddAttrs(slog.String(TraceIDKey, sc.TraceID().String()), slog.String(SpanIDKey, sc.SpanID().String()))
+	}
+	return ch.Handler.Handle(ctx, r)
+}
+
+func (ch contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{Handler: ch.Handler.WithAttrs(attrs)}
+}
+
+func (ch contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{Handler: ch.Handler.WithGroup(name)}
+}
@@ -0,0 +1,51 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestNew(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := New(&buf, "info", "json")
+	require.NoError(t, err)
+
+	logger.Debug("ledger: scanning", "count", 3)
+	assert.Empty(t, buf.String(), "below the level")
+
+	ctx := NewContext(context.Background(), "f00d")
+	logger.With("component", "api").InfoContext(ctx, "API: got object", "id", "12")
+	var rec map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &rec))
+	assert.Equal(t, "INFO", rec["level"])
+	assert.Equal(t, "API: got object", rec["msg"])
+	assert.Equal(t, "12", rec["id"])
+	assert.Equal(t, "api", rec["component"])
+	assert.Equal(t, "f00d", rec[RequestIDKey])
+
+	buf.Reset()
+	sc := trace.NewSpanContext(trace.SpanContextConfig{TraceID: trace.TraceID{1}, SpanID: trace.SpanID{2}})
+	logger.InfoContext(trace.ContextWithSpanContext(ctx, sc), "API: got object", "id", "12")
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &rec))
+	assert.Equal(t, sc.TraceID().String(), rec[TraceIDKey])
+	assert.Equal(t, sc.SpanID().String(), rec[SpanIDKey])
+
+	buf.Reset()
+	logger, err = New(&buf, "DEBUG", "text")
+	require.NoError(t, err)
+	logger.Log(context.Background(), slog.LevelDebug, "ledger: scanning", "count", 3)
+	assert.Contains(t, buf.String(), `level=DEBUG msg="ledger: scanning" count=3`)
+	assert.NotContains(t, buf.String(), RequestIDKey)
+
+	_, err = New(&buf, "verbose", "text")
+	assert.Error(t, err)
+	_, err = New(&buf, "info", "xml")
+	assert.Error(t, err)
+}
@@ -0,0 +1,7 @@
+// Package mail sends the emails of the todos through a SMTP server: the reminders, when the
+// reminder of a todo comes due or its due date passes, and the digests summarizing the todos open,
+// overdue and recently completed, daily or weekly.
+// The reminders go to the email address of the assignee of the todo, or else of its owner, or else
+// to the recipients of the digests. The messages have a plain-text and a HTML body, formatted with
+// the Templates.
+package mail
@@ -0,0 +1,263 @@
+package mail
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gotestbootcamp/go-todo-app/ledger"
+	"github.com/gotestbootcamp/go-todo-app/model"
+	"github.com/gotestbootcamp/go-todo-app/store"
+	"github.com/gotestbootcamp/go-todo-app/task"
+	"github.com/gotestbootcamp/go-todo-app/user"
+)
+
+// fakeTransport records the messages sent
+type fakeTransport struct {
+	lock sync.Mutex
+	sent []Message
+}
+
+func (ft *fakeTransport) Send(ctx context.Context, msg Message) error {
+	ft.lock.Lock()
+	defer ft.lock.Unlock()
+	ft.sent = append(ft.sent, msg)
+	return nil
+}
+
+func TestSchedule(t *testing.T) {
+	for s, want := range map[string]Schedule{
+		"daily":            {Weekday: time.Monday, Hour: 8},
+		"daily 18:30":      {Weekday: time.Monday, Hour: 18, Minute: 30},
+		"weekly":           {Weekly: true, Weekday: time.Monday, Hour: 8},
+		"Weekly Fri 17:00": {Weekly: true, Weekday: time.Friday, Hour: 17},
+		"weekly sunday":    {Weekly: true, Weekday: time.Sunday, Hour: 8},
+	} {
+		sc, err := ParseSchedule(s)
+		require.NoError(t, err, s)
+		assert.Equal(t, want, sc, s)
+	}
+	for _, s := range []string{"", "hourly", "daily 25:00", "weekly someday", "daily 08:00 utc"} {
+		_, err := ParseSchedule(s)
+		assert.Error(t, err, s)
+	}
+
+	// wednesday
+	now := time.Date(2024, 3, 6, 12, 0, 0, 0, time.UTC)
+	daily := Schedule{Hour: 8}
+	assert.Equal(t, time.Date(2024, 3, 7, 8, 0, 0, 0, time.UTC), daily.Next(now))
+	assert.Equal(t, time.Date(2024, 3, 6, 13, 0, 0, 0, time.UTC), Schedule{Hour: 13}.Next(now))
+	weekly := Schedule{Weekly: true, Weekday: time.Friday, Hour: 17}
+	assert.Equal(t, time.Date(2024, 3, 8, 17, 0, 0, 0, time.UTC), weekly.Next(now))
+	assert.Equal(t, time.Date(2024, 3, 15, 17, 0, 0, 0, time.UTC), weekly.Next(weekly.Next(now)))
+	assert.Equal(t, time.Date(2024, 3, 13, 8, 0, 0, 0, time.UTC), Schedule{Weekly: true, Weekday: time.Wednesday, Hour: 8}.Next(now))
+	assert.Equal(t, "weekly fri 17:00", weekly.String())
+}
+
+func TestMessage(t *testing.T) {
+	msg := Message{
+		From:    "Todo <todo@example.com>",
+		To:      []string{"alice@example.com", "bob@example.com"},
+		Subject: "Überfällig: pay rent",
+		Text:    "pay rent\n",
+		HTML:    "<p>pay rent</p>",
+		Date:    time.Date(2024, 3, 6, 12, 0, 0, 0, time.UTC),
+	}
+	data, err := msg.Bytes()
+	require.NoError(t, err)
+	parsed, err := mail.ReadMessage(bytes.NewReader(data))
+	require.NoError(t, err)
+	subject, err := new(mime.WordDecoder).DecodeHeader(parsed.Header.Get("Subject"))
+	require.NoError(t, err)
+	assert.Equal(t, msg.Subject, subject)
+	assert.Equal(t, "alice@example.com, bob@example.com", parsed.Header.Get("To"))
+	assert.True(t, strings.HasSuffix(parsed.Header.Get("Message-ID"), "@example.com>"))
+	media, params, err := mime.ParseMediaType(parsed.Header.Get("Content-Type"))
+	require.NoError(t, err)
+	assert.Equal(t, "multipart/alternative", media)
+	parts := multipart.NewReader(parsed.Body, params["boundary"])
+	for _, want := range []struct{ kind, body string }{{"text/plain", "pay rent\r\n"}, {"text/html", msg.HTML}} {
+		part, err := parts.NextPart()
+		require.NoError(t, err)
+		assert.Equal(t, want.kind+"; charset=utf-8", part.Header.Get("Content-Type"))
+		body, err := io.ReadAll(part)
+		require.NoError(t, err)
+		assert.Equal(t, want.body, string(body))
+	}
+	_, err = parts.NextPart()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestMailer(t *testing.T) {
+	mem, err := store.NewMemory()
+	require.NoError(t, err)
+	ld, err := ledger.New(mem)
+	require.NoError(t, err)
+	users, err := user.NewDirectory(mem)
+	require.NoError(t, err)
+	_, err = users.Create(user.User{Name: "alice", Email: "alice@example.com", Role: user.RoleUser})
+	require.NoError(t, err)
+	tr := &fakeTransport{}
+	m, err := NewMailer(ld, tr, "Todo <todo@example.com>")
+	require.NoError(t, err)
+	m.Users, m.To = users, []string{"admin@example.com"}
+
+	past, later := time.Now().Add(-time.Hour), time.Now().Add(time.Hour)
+	rent := task.New("pay rent")
+	rent.Assignee, rent.Due = "alice", &past
+	milk := task.New("buy <milk>")
+	milk.Due = &later
+	done := task.New("walk the dog")
+	done.Status = task.Completed
+	report, err := ld.CreateAll(store.DefaultList, []task.Task{rent, milk, done, task.New("someday")})
+	require.NoError(t, err)
+	require.Len(t, report, 4)
+	items, err := ld.ListOverdue()
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+
+	require.NoError(t, m.Remind(context.Background(), items[0], true))
+	require.Len(t, tr.sent, 1)
+	assert.Equal(t, []string{"alice@example.com"}, tr.sent[0].To)
+	assert.Equal(t, "Overdue: pay rent", tr.sent[0].Subject)
+	assert.Contains(t, tr.sent[0].Text, "Assigned to: alice\n")
+	assert.Contains(t, tr.sent[0].HTML, "<h2>Overdue: pay rent</h2>")
+
+	// the todos of nobody are reminded to the recipients of the digests
+	milks, err := ld.Filter(func(td model.Todo) bool { return td.Title == "buy <milk>" })
+	require.NoError(t, err)
+	require.Len(t, milks, 1)
+	require.NoError(t, m.Remind(context.Background(), milks[0], false))
+	require.Len(t, tr.sent, 2)
+	assert.Equal(t, []string{"admin@example.com"}, tr.sent[1].To)
+	assert.Equal(t, "Reminder: buy <milk>", tr.sent[1].Subject)
+	assert.Contains(t, tr.sent[1].HTML, "buy &lt;milk&gt;")
+
+	require.NoError(t, m.Digest(context.Background(), "daily", time.Now().Add(-24*time.Hour)))
+	require.Len(t, tr.sent, 3)
+	digest := tr.sent[2]
+	assert.Equal(t, []string{"admin@example.com"}, digest.To)
+	assert.Equal(t, "Your daily todo digest: 3 open, 1 overdue", digest.Subject)
+	assert.Contains(t, digest.Text, "Overdue:\n- pay rent (due ")
+	assert.Contains(t, digest.Text, "Open:\n- pay rent")
+	assert.Contains(t, digest.Text, "- someday\n")
+	assert.Contains(t, digest.Text, "Completed since ")
+	assert.Contains(t, digest.Text, "- walk the dog\n")
+	assert.Contains(t, digest.HTML, "<h3>Overdue</h3>")
+
+	// the completed todos are digested once
+	require.NoError(t, m.Digest(context.Background(), "daily", time.Now()))
+	require.Len(t, tr.sent, 4)
+	assert.NotContains(t, tr.sent[3].Text, "walk the dog")
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "reminder.txt"), []byte("Ping {{.Todo.Title}}\nDo it!\n"), 0o600))
+	m.Templates, err = LoadTemplates(dir)
+	require.NoError(t, err)
+	require.NoError(t, m.Remind(context.Background(), items[0], true))
+	require.Len(t, tr.sent, 5)
+	assert.Equal(t, "Ping pay rent", tr.sent[4].Subject)
+	assert.Equal(t, "Do it!\n", tr.sent[4].Text)
+	assert.Contains(t, tr.sent[4].HTML, "<h2>Overdue: pay rent</h2>")
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "digest.html"), []byte("{{.Nope"), 0o600))
+	_, err = LoadTemplates(dir)
+	assert.Error(t, err)
+}
+
+// fakeSMTP serves a SMTP session on the listener, recording the commands and the data received
+func fakeSMTP(t *testing.T, l net.Listener, received *bytes.Buffer) {
+	conn, err := l.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(s string) { io.WriteString(conn, s+"\r\n") }
+	reply("220 localhost ESMTP")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		received.WriteString(line)
+		cmd := strings.ToUpper(strings.Fields(line)[0])
+		switch cmd {
+		case "EHLO":
+			reply("250-localhost")
+			reply("250 AUTH PLAIN")
+		case "AUTH":
+			reply("235 ok")
+		case "DATA":
+			reply("354 go ahead")
+			for {
+				line, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if line == ".\r\n" {
+					break
+				}
+				received.WriteString(line)
+			}
+			reply("250 ok")
+		case "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("250 ok")
+		}
+	}
+}
+
+func TestSMTP(t *testing.T) {
+	l, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	defer l.Close()
+	var received bytes.Buffer
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		fakeSMTP(t, l, &received)
+	}()
+
+	tr, err := NewSMTP("smtp://todo@"+l.Addr().String(), "s3cret")
+	require.NoError(t, err)
+	assert.Equal(t, "todo", tr.Username)
+	err = tr.Send(context.Background(), Message{
+		From:    "Todo <todo@example.com>",
+		To:      []string{"Alice <alice@example.com>"},
+		Subject: "pay rent",
+		Text:    "pay rent",
+		HTML:    "<p>pay rent</p>",
+		Date:    time.Now(),
+	})
+	require.NoError(t, err)
+	<-done
+	assert.Contains(t, received.String(), "AUTH PLAIN ")
+	assert.Contains(t, received.String(), "MAIL FROM:<todo@example.com>")
+	assert.Contains(t, received.String(), "RCPT TO:<alice@example.com>")
+	assert.Contains(t, received.String(), "Subject: pay rent\r\n")
+
+	for _, u := range []string{"http://example.com", "smtp://", "smtp://[::1"} {
+		_, err := NewSMTP(u, "")
+		assert.Error(t, err, u)
+	}
+	tr, err = NewSMTP("smtps://mail.example.com", "")
+	require.NoError(t, err)
+	assert.Equal(t, "mail.example.com:465", tr.Address)
+	assert.True(t, tr.TLS)
+}
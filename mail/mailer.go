@@ -0,0 +1,218 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/mail"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/gotestbootcamp/go-todo-app/ledger"
+	"github.com/gotestbootcamp/go-todo-app/model"
+	"github.com/gotestbootcamp/go-todo-app/task"
+	"github.com/gotestbootcamp/go-todo-app/user"
+	"github.com/gotestbootcamp/go-todo-app/webhook"
+)
+
+// DefaultSendTimeout bounds the sending of each message by default
+const DefaultSendTimeout = time.Minute
+
+// Mailer mails the reminders and the digests of the todos of a ledger
+type Mailer struct {
+	ld        *ledger.Ledger
+	transport Transport
+	// From is the sender of the messages
+	From string
+	// To are the recipients of the digests, and of the reminders of the todos whose assignee and
+	// owner have no email address
+	To []string
+	// Users are the users whose email addresses the reminders are sent to. Nil if none.
+	Users *user.Directory
+	// Templates format the messages, DefaultTemplates by default
+	Templates *Templates
+	// Timeout bounds the sending of each message, DefaultSendTimeout by default
+	Timeout time.Duration
+	// Now returns the current time. Can be replaced to control time in tests.
+	Now func() time.Time
+}
+
+// NewMailer creates the mailer of the todos of the ledger, sending the messages from the address
+// through the transport
+func NewMailer(ld *ledger.Ledger, transport Transport, from string) (*Mailer, error) {
+	if _, err := mail.ParseAddress(from); err != nil {
+		return nil, fmt.Errorf("mail: invalid sender %q: %w", from, err)
+	}
+	return &Mailer{
+		ld:        ld,
+		transport: transport,
+		From:      from,
+		Templates: DefaultTemplates(),
+		Timeout:   DefaultSendTimeout,
+		Now:       time.Now,
+	}, nil
+}
+
+// recipients returns who the reminders of the todo are sent to: the assignee, if a user with a
+// email address or a email address itself, or else the owner, or else To
+func (m *Mailer) recipients(tk *task.Task) []string {
+	for _, name := range []string{tk.Assignee, tk.Owner} {
+		if name == "" {
+			continue
+		}
+		if u, ok := m.userByName(name); ok && u.Email != "" {
+			return []string{u.Email}
+		}
+		if addr, err := mail.ParseAddress(name); err == nil {
+			return []string{addr.Address}
+		}
+	}
+	return m.To
+}
+
+func (m *Mailer) userByName(name string) (user.User, bool) {
+	if m.Users == nil {
+		return user.User{}, false
+	}
+	return m.Users.Get(name)
+}
+
+// send sends the message with the subject and the bodies to the recipients
+func (m *Mailer) send(ctx context.Context, to []string, subject, text, html string) error {
+	ctx, cancel := context.WithTimeout(ctx, m.Timeout)
+	defer cancel()
+	return m.transport.Send(ctx, Message{
+		From:    m.From,
+		To:      to,
+		Subject: subject,
+		Text:    text,
+		HTML:    html,
+		Date:    m.Now(),
+	})
+}
+
+// Remind mails the reminder of the todo to its recipients: overdue tells whether its due date
+// passed, rather than its reminder came due
+func (m *Mailer) Remind(ctx context.Context, item ledger.Item, overdue bool) error {
+	if item.Task == nil {
+		return nil
+	}
+	to := m.recipients(item.Task)
+	if len(to) == 0 {
+		slog.Warn("mail: Mailer: nobody to remind", "id", item.ID)
+		return nil
+	}
+	subject, text, html, err := format(m.Templates.ReminderText, m.Templates.ReminderHTML, Reminder{Todo: newEntry(item), Overdue: overdue})
+	if err != nil {
+		return fmt.Errorf("mail: can't format the reminder: %w", err)
+	}
+	if err := m.send(ctx, to, subject, text, html); err != nil {
+		return fmt.Errorf("mail: can't send the reminder of %v: %w", item.ID, err)
+	}
+	slog.Info("mail: Mailer: reminder sent", "id", item.ID, "to", to, "overdue", overdue)
+	return nil
+}
+
+// Digest mails the digest of the todos to To: the active ones, the overdue ones, and the ones
+// completed since the given time. Nothing is sent if there's nothing to tell.
+func (m *Mailer) Digest(ctx context.Context, period string, since time.Time) error {
+	if len(m.To) == 0 {
+		return nil
+	}
+	now := m.Now()
+	wf := m.ld.Workflow()
+	items, err := m.ld.Filter(func(model.Todo) bool { return true })
+	if err != nil {
+		return err
+	}
+	overdue, err := m.ld.ListOverdue()
+	if err != nil {
+		return err
+	}
+	d := Digest{Period: period, Since: since, Now: now}
+	for _, item := range overdue {
+		d.Overdue = append(d.Overdue, newEntry(item))
+	}
+	for _, item := range items {
+		switch tk := item.Task; {
+		case tk == nil:
+		case !wf.Final(tk.Status):
+			d.Open = append(d.Open, newEntry(item))
+		case tk.Status != task.Deleted && tk.Updated.After(since):
+			d.Completed = append(d.Completed, newEntry(item))
+		}
+	}
+	if len(d.Open) == 0 && len(d.Completed) == 0 {
+		slog.Info("mail: Mailer: nothing to digest", "period", period)
+		return nil
+	}
+	slices.SortStableFunc(d.Open, compareDue)
+	subject, text, html, err := format(m.Templates.DigestText, m.Templates.DigestHTML, d)
+	if err != nil {
+		return fmt.Errorf("mail: can't format the digest: %w", err)
+	}
+	if err := m.send(ctx, m.To, subject, text, html); err != nil {
+		return fmt.Errorf("mail: can't send the digest: %w", err)
+	}
+	slog.Info("mail: Mailer: digest sent", "period", period, "to", m.To, "open", len(d.Open), "overdue", len(d.Overdue), "completed", len(d.Completed))
+	return nil
+}
+
+// compareDue sorts the todos the earliest due first, then the ones never due
+func compareDue(a, b Entry) int {
+	switch {
+	case a.Due == nil && b.Due == nil:
+		return 0
+	case a.Due == nil:
+		return 1
+	case b.Due == nil:
+		return -1
+	}
+	return a.Due.Compare(*b.Due)
+}
+
+// Run mails the reminders of the todos becoming overdue, told by the watcher, and the digests
+// when the schedule tells, until the context is done. The nil schedule sends no digests.
+func (m *Mailer) Run(ctx context.Context, w *webhook.Watcher, schedule *Schedule) error {
+	var digests sync.WaitGroup
+	defer digests.Wait()
+	if schedule != nil {
+		digests.Add(1)
+		go func() {
+			defer digests.Done()
+			m.runDigests(ctx, *schedule)
+		}()
+	}
+	err := w.Run(ctx, func(ev webhook.Event, item ledger.Item) {
+		if ev != webhook.Overdue {
+			return
+		}
+		if err := m.Remind(ctx, item, true); err != nil {
+			slog.Error("mail: Mailer: failed to remind", "id", item.ID, "error", err)
+		}
+	})
+	if err != nil {
+		slog.Warn("mail: Mailer: the overdue todos are not reminded", "error", err)
+	}
+	return err
+}
+
+// runDigests mails the digests when the schedule tells, until the context is done
+func (m *Mailer) runDigests(ctx context.Context, schedule Schedule) {
+	last := schedule.Previous(schedule.Next(m.Now()))
+	for {
+		next := schedule.Next(m.Now())
+		timer := time.NewTimer(next.Sub(m.Now()))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		if err := m.Digest(ctx, schedule.Period(), last); err != nil {
+			slog.Error("mail: Mailer: failed to send the digest", "error", err)
+		}
+		last = next
+	}
+}
@@ -0,0 +1,103 @@
+package mail
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Schedule is when the digests are sent: every day, or every week on a weekday, at a time of day
+type Schedule struct {
+	Weekly bool
+	// Weekday is the day of the weekly digests
+	Weekday time.Weekday
+	// Hour and Minute are the time of day, in the local time zone
+	Hour, Minute int
+}
+
+// ParseSchedule parses the schedule of the digests: "daily" or "weekly", optionally followed by the
+// weekday of the weekly ones, and by the time of day, e.g. "daily 08:00" or "weekly fri 17:30".
+// The digests are sent at 08:00 by default, and the weekly ones on monday.
+func ParseSchedule(s string) (Schedule, error) {
+	fields := strings.Fields(strings.ToLower(s))
+	if len(fields) == 0 {
+		return Schedule{}, fmt.Errorf("mail: empty digest schedule, want daily or weekly")
+	}
+	sc := Schedule{Weekday: time.Monday, Hour: 8}
+	switch fields[0] {
+	case "daily":
+	case "weekly":
+		sc.Weekly = true
+		if len(fields) > 1 && !strings.Contains(fields[1], ":") {
+			day, ok := weekdays[fields[1]]
+			if !ok {
+				return Schedule{}, fmt.Errorf("mail: invalid weekday %q in the digest schedule %q", fields[1], s)
+			}
+			sc.Weekday = day
+			fields = fields[1:]
+		}
+	default:
+		return Schedule{}, fmt.Errorf("mail: invalid digest schedule %q, want daily or weekly", s)
+	}
+	switch len(fields) {
+	case 1:
+	case 2:
+		t, err := time.Parse("15:04", fields[1])
+		if err != nil {
+			return Schedule{}, fmt.Errorf("mail: invalid time %q in the digest schedule %q, want hh:mm", fields[1], s)
+		}
+		sc.Hour, sc.Minute = t.Hour(), t.Minute()
+	default:
+		return Schedule{}, fmt.Errorf("mail: invalid digest schedule %q", s)
+	}
+	return sc, nil
+}
+
+// weekdays are the weekdays by name, either full or abbreviated
+var weekdays = func() map[string]time.Weekday {
+	res := make(map[string]time.Weekday)
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		name := strings.ToLower(day.String())
+		res[name], res[name[:3]] = day, day
+	}
+	return res
+}()
+
+// Period returns "daily" or "weekly"
+func (sc Schedule) Period() string {
+	if sc.Weekly {
+		return "weekly"
+	}
+	return "daily"
+}
+
+func (sc Schedule) String() string {
+	if sc.Weekly {
+		return fmt.Sprintf("weekly %s %02d:%02d", strings.ToLower(sc.Weekday.String()[:3]), sc.Hour, sc.Minute)
+	}
+	return fmt.Sprintf("daily %02d:%02d", sc.Hour, sc.Minute)
+}
+
+// Next returns when the next digest is sent after t, in the time zone of t
+func (sc Schedule) Next(t time.Time) time.Time {
+	next := time.Date(t.Year(), t.Month(), t.Day(), sc.Hour, sc.Minute, 0, 0, t.Location())
+	if sc.Weekly {
+		next = next.AddDate(0, 0, (int(sc.Weekday)-int(next.Weekday())+7)%7)
+	}
+	for !next.After(t) {
+		if sc.Weekly {
+			next = next.AddDate(0, 0, 7)
+		} else {
+			next = next.AddDate(0, 0, 1)
+		}
+	}
+	return next
+}
+
+// Previous returns when the digest before the one sent at t was sent
+func (sc Schedule) Previous(t time.Time) time.Time {
+	if sc.Weekly {
+		return t.AddDate(0, 0, -7)
+	}
+	return t.AddDate(0, 0, -1)
+}
@@ -0,0 +1,192 @@
+package mail
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Message is a email with a plain-text and a HTML body
+type Message struct {
+	From    string
+	To      []string
+	Subject string
+	Text    string
+	HTML    string
+	Date    time.Time
+}
+
+// Bytes returns the message in the RFC 5322 format, as a multipart/alternative MIME message
+func (m Message) Bytes() ([]byte, error) {
+	var boundary [12]byte
+	if _, err := rand.Read(boundary[:]); err != nil {
+		return nil, err
+	}
+	var id [16]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		return nil, err
+	}
+	domain := "localhost"
+	if from, err := mail.ParseAddress(m.From); err == nil {
+		if _, d, ok := strings.Cut(from.Address, "@"); ok {
+			domain = d
+		}
+	}
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", m.From)
+	fmt.Fprintf(&buf, "To: %s\r\n", strings.Join(m.To, ", "))
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", m.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", m.Date.Format(time.RFC1123Z))
+	fmt.Fprintf(&buf, "Message-ID: <%s@%s>\r\n", hex.EncodeToString(id[:]), domain)
+	fmt.Fprintf(&buf, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&buf, "Content-Type: multipart/alternative; boundary=%q\r\n", hex.EncodeToString(boundary[:]))
+	for _, part := range []struct{ kind, body string }{{"text/plain", m.Text}, {"text/html", m.HTML}} {
+		fmt.Fprintf(&buf, "\r\n--%s\r\n", hex.EncodeToString(boundary[:]))
+		fmt.Fprintf(&buf, "Content-Type: %s; charset=utf-8\r\n", part.kind)
+		fmt.Fprintf(&buf, "Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+		qp := quotedprintable.NewWriter(&buf)
+		if _, err := qp.Write([]byte(part.body)); err != nil {
+			return nil, err
+		}
+		if err := qp.Close(); err != nil {
+			return nil, err
+		}
+	}
+	fmt.Fprintf(&buf, "\r\n--%s--\r\n", hex.EncodeToString(boundary[:]))
+	return buf.Bytes(), nil
+}
+
+// Transport sends the messages
+type Transport interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// SMTP sends the messages through a SMTP server
+type SMTP struct {
+	// Address is the address of the server, as host:port
+	Address string
+	// TLS connects with TLS right away, rather than with STARTTLS, which is used when the server
+	// supports it
+	TLS      bool
+	Username string
+	Password string
+	// TLSConfig configures the TLS connections. Nil means the defaults.
+	TLSConfig *tls.Config
+}
+
+// NewSMTP creates the transport through the SMTP server of the URL, in the format
+// `smtp[s]://[user@]host[:port]`. The smtps URLs connect with TLS right away, on port 465 by
+// default, and the smtp ones use STARTTLS, on port 587 by default.
+func NewSMTP(rawURL, password string) (*SMTP, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("mail: invalid SMTP URL: %w", err)
+	}
+	tr := &SMTP{Password: password}
+	port := "587"
+	switch u.Scheme {
+	case "smtp":
+	case "smtps":
+		tr.TLS, port = true, "465"
+	default:
+		return nil, fmt.Errorf("mail: invalid SMTP URL %q, want smtp://host:port or smtps://host:port", rawURL)
+	}
+	if u.Hostname() == "" {
+		return nil, fmt.Errorf("mail: invalid SMTP URL %q, no host", rawURL)
+	}
+	if u.Port() != "" {
+		port = u.Port()
+	}
+	tr.Address = net.JoinHostPort(u.Hostname(), port)
+	if u.User != nil {
+		tr.Username = u.User.Username()
+		if p, ok := u.User.Password(); ok && tr.Password == "" {
+			tr.Password = p
+		}
+	}
+	return tr, nil
+}
+
+// Send sends the message to its recipients
+func (tr *SMTP) Send(ctx context.Context, msg Message) error {
+	if len(msg.To) == 0 {
+		return errors.New("mail: no recipients")
+	}
+	from, err := mail.ParseAddress(msg.From)
+	if err != nil {
+		return fmt.Errorf("mail: invalid sender %q: %w", msg.From, err)
+	}
+	data, err := msg.Bytes()
+	if err != nil {
+		return err
+	}
+	host, _, _ := net.SplitHostPort(tr.Address)
+	tlsConfig := tr.TLSConfig
+	if tlsConfig == nil {
+		tlsConfig = &tls.Config{ServerName: host}
+	}
+	dialer := &net.Dialer{Timeout: 30 * time.Second}
+	var conn net.Conn
+	if tr.TLS {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: tlsConfig}).DialContext(ctx, "tcp", tr.Address)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", tr.Address)
+	}
+	if err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+	if ok, _ := c.Extension("STARTTLS"); ok && !tr.TLS {
+		if err := c.StartTLS(tlsConfig); err != nil {
+			return err
+		}
+	}
+	if tr.Username != "" {
+		if err := c.Auth(smtp.PlainAuth("", tr.Username, tr.Password, host)); err != nil {
+			return err
+		}
+	}
+	if err := c.Mail(from.Address); err != nil {
+		return err
+	}
+	for _, to := range msg.To {
+		addr, err := mail.ParseAddress(to)
+		if err != nil {
+			return fmt.Errorf("mail: invalid recipient %q: %w", to, err)
+		}
+		if err := c.Rcpt(addr.Address); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
@@ -0,0 +1,184 @@
+package mail
+
+import (
+	"errors"
+	htmltemplate "html/template"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/gotestbootcamp/go-todo-app/ledger"
+	"github.com/gotestbootcamp/go-todo-app/store"
+	"github.com/gotestbootcamp/go-todo-app/task"
+)
+
+// The default templates of the messages. The first line of the text templates is the subject.
+const (
+	defaultReminderText = `{{if .Overdue}}Overdue{{else}}Reminder{{end}}: {{.Todo.Title}}
+{{.Todo.Title}}{{if .Todo.Due}}
+Due: {{.Todo.Due.Format "Mon Jan 2 2006 15:04"}}{{end}}{{if .Todo.Assignee}}
+Assigned to: {{.Todo.Assignee}}{{end}}{{if .Todo.Description}}
+
+{{.Todo.Description}}{{end}}
+
+Todo {{.Todo.ID}}{{if .Todo.List}} in {{.Todo.List}}{{end}}
+`
+	defaultReminderHTML = `<html><body>
+<h2>{{if .Overdue}}Overdue{{else}}Reminder{{end}}: {{.Todo.Title}}</h2>
+<ul>{{if .Todo.Due}}
+<li>Due: {{.Todo.Due.Format "Mon Jan 2 2006 15:04"}}</li>{{end}}{{if .Todo.Assignee}}
+<li>Assigned to: {{.Todo.Assignee}}</li>{{end}}
+<li>Todo {{.Todo.ID}}{{if .Todo.List}} in {{.Todo.List}}{{end}}</li>
+</ul>{{if .Todo.Description}}
+<p>{{.Todo.Description}}</p>{{end}}
+</body></html>
+`
+	defaultDigestText = `Your {{.Period}} todo digest: {{len .Open}} open, {{len .Overdue}} overdue
+{{if .Overdue}}Overdue:
+{{range .Overdue}}- {{.Title}} (due {{.Due.Format "Mon Jan 2 15:04"}}){{if .Assignee}} @{{.Assignee}}{{end}}
+{{end}}
+{{end}}{{if .Open}}Open:
+{{range .Open}}- {{.Title}}{{if .Due}} (due {{.Due.Format "Mon Jan 2 15:04"}}){{end}}{{if .Assignee}} @{{.Assignee}}{{end}}
+{{end}}
+{{end}}{{if .Completed}}Completed since {{.Since.Format "Mon Jan 2 15:04"}}:
+{{range .Completed}}- {{.Title}}{{if .Assignee}} @{{.Assignee}}{{end}}
+{{end}}{{end}}`
+	defaultDigestHTML = `<html><body>
+<h2>Your {{.Period}} todo digest</h2>{{if .Overdue}}
+<h3>Overdue</h3>
+<ul>{{range .Overdue}}
+<li><b>{{.Title}}</b>, due {{.Due.Format "Mon Jan 2 15:04"}}{{if .Assignee}}, @{{.Assignee}}{{end}}</li>{{end}}
+</ul>{{end}}{{if .Open}}
+<h3>Open</h3>
+<ul>{{range .Open}}
+<li>{{.Title}}{{if .Due}}, due {{.Due.Format "Mon Jan 2 15:04"}}{{end}}{{if .Assignee}}, @{{.Assignee}}{{end}}</li>{{end}}
+</ul>{{end}}{{if .Completed}}
+<h3>Completed since {{.Since.Format "Mon Jan 2 15:04"}}</h3>
+<ul>{{range .Completed}}
+<li>{{.Title}}{{if .Assignee}}, @{{.Assignee}}{{end}}</li>{{end}}
+</ul>{{end}}
+</body></html>
+`
+)
+
+// Entry is a todo, as the templates see it
+type Entry struct {
+	// ID is the ID of the todo in its list
+	ID store.ID
+	// List is the list of the todo, empty if none
+	List        string
+	Title       string
+	Description string
+	Assignee    string
+	Owner       string
+	Status      task.Status
+	Priority    task.Priority
+	// Due is when the todo is due. Nil if not set.
+	Due  *time.Time
+	Tags []string
+}
+
+func newEntry(item ledger.Item) Entry {
+	list, id := store.SplitListID(item.ID)
+	e := Entry{ID: id, List: list}
+	if tk := item.Task; tk != nil {
+		e.Title, e.Description, e.Assignee, e.Owner = tk.Title, tk.Description, tk.Assignee, tk.Owner
+		e.Status, e.Priority, e.Due, e.Tags = tk.Status, tk.Priority, tk.Due, tk.Tags
+	}
+	return e
+}
+
+// Reminder is what the reminder templates format
+type Reminder struct {
+	Todo Entry
+	// Overdue tells whether the due date of the todo passed, rather than its reminder came due
+	Overdue bool
+}
+
+// Digest is what the digest templates format
+type Digest struct {
+	// Period is "daily" or "weekly"
+	Period string
+	// Since is when the previous digest was sent, Now when this one is
+	Since, Now time.Time
+	// Open are the active todos, Overdue the ones whose due date passed, and Completed the ones
+	// completed since the previous digest
+	Open, Overdue, Completed []Entry
+}
+
+// Templates format the messages. The first line of the text templates is the subject.
+type Templates struct {
+	ReminderText *texttemplate.Template
+	ReminderHTML *htmltemplate.Template
+	DigestText   *texttemplate.Template
+	DigestHTML   *htmltemplate.Template
+}
+
+// DefaultTemplates returns the templates compiled in
+func DefaultTemplates() *Templates {
+	return &Templates{
+		ReminderText: texttemplate.Must(texttemplate.New("reminder.txt").Parse(defaultReminderText)),
+		ReminderHTML: htmltemplate.Must(htmltemplate.New("reminder.html").Parse(defaultReminderHTML)),
+		DigestText:   texttemplate.Must(texttemplate.New("digest.txt").Parse(defaultDigestText)),
+		DigestHTML:   htmltemplate.Must(htmltemplate.New("digest.html").Parse(defaultDigestHTML)),
+	}
+}
+
+// LoadTemplates returns the templates of the files reminder.txt, reminder.html, digest.txt and
+// digest.html in the directory, or else the default ones
+func LoadTemplates(dir string) (*Templates, error) {
+	tmpls := DefaultTemplates()
+	read := func(name string) (string, bool, error) {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if errors.Is(err, fs.ErrNotExist) {
+			return "", false, nil
+		}
+		return string(data), err == nil, err
+	}
+	for name, tmpl := range map[string]**texttemplate.Template{"reminder.txt": &tmpls.ReminderText, "digest.txt": &tmpls.DigestText} {
+		text, ok, err := read(name)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			if *tmpl, err = texttemplate.New(name).Parse(text); err != nil {
+				return nil, err
+			}
+		}
+	}
+	for name, tmpl := range map[string]**htmltemplate.Template{"reminder.html": &tmpls.ReminderHTML, "digest.html": &tmpls.DigestHTML} {
+		text, ok, err := read(name)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			if *tmpl, err = htmltemplate.New(name).Parse(text); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return tmpls, nil
+}
+
+// executor is either a text or a HTML template
+type executor interface {
+	Execute(w io.Writer, data any) error
+}
+
+// format returns the subject and the bodies of the message formatted by the templates
+func format(text, html executor, data any) (subject, textBody, htmlBody string, err error) {
+	var sb strings.Builder
+	if err := text.Execute(&sb, data); err != nil {
+		return "", "", "", err
+	}
+	subject, textBody, _ = strings.Cut(sb.String(), "\n")
+	sb.Reset()
+	if err := html.Execute(&sb, data); err != nil {
+		return "", "", "", err
+	}
+	return strings.TrimSpace(subject), textBody, sb.String(), nil
+}
@@ -0,0 +1,313 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	apiv1 "github.com/gotestbootcamp/go-todo-app/api/v1"
+	"github.com/gotestbootcamp/go-todo-app/store"
+)
+
+const (
+	// IdempotencyKeyHeader is the header carrying the key the client identifies a request with, the
+	// same on each retry of the request
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayedHeader is set to "true" on the responses replayed for the retried requests
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+	// maxIdempotencyKey bounds the length of the idempotency keys
+	maxIdempotencyKey = 255
+	// maxIdempotentBody bounds the size of the bodies of the requests with a key, like the
+	// controller bounds the bodies it decodes
+	maxIdempotentBody = 1048576
+)
+
+// idempotencyPrefix marks the IDs of the items holding the responses to the requests with a key
+var idempotencyPrefix = string(store.MetaID("idempotency/"))
+
+// replayedHeaders are the headers of the responses replayed, besides the body
+var replayedHeaders = []string{"Content-Type", "Location", "ETag"}
+
+// idempotentResponse is the response stored for the retries of the request with the fingerprint
+type idempotentResponse struct {
+	Fingerprint string              `json:"fingerprint"`
+	Created     time.Time           `json:"created"`
+	Status      int                 `json:"status"`
+	Header      map[string][]string `json:"header,omitempty"`
+	Body        []byte              `json:"body,omitempty"`
+}
+
+// Idempotency makes the requests creating and updating objects, POST, PUT and PATCH, idempotent
+// when they set the Idempotency-Key header: the response to the first request with a key is
+// stored, and replayed to the retries of the request with the same key, which are not served
+// again. It is safe for concurrent use.
+type Idempotency struct {
+	// TTL is how long the responses are kept for the retries
+	TTL time.Duration
+	// Key identifies the clients, whose idempotency keys are distinct. Nil means ClientIP.
+	Key KeyFunc
+
+	st        store.Storage
+	now       func() time.Time
+	lock      sync.Mutex
+	expires   map[store.ID]time.Time
+	pending   map[store.ID]bool
+	lastSweep time.Time
+}
+
+// NewIdempotency creates the middleware keeping the responses in the storage for ttl
+func NewIdempotency(st store.Storage, ttl time.Duration) (*Idempotency, error) {
+	idem := &Idempotency{
+		TTL:     ttl,
+		st:      st,
+		now:     time.Now,
+		expires: make(map[store.ID]time.Time),
+		pending: make(map[store.ID]bool),
+	}
+	err := store.Walk(st, func(item store.Item) error {
+		if !strings.HasPrefix(string(item.ID), idempotencyPrefix) {
+			return nil
+		}
+		var resp idempotentResponse
+		if err := json.Unmarshal(item.Blob, &resp); err != nil {
+			return fmt.Errorf("middleware: can't decode the idempotent response %v: %w", item.ID, err)
+		}
+		idem.expires[item.ID] = resp.Created.Add(ttl)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return idem, nil
+}
+
+// fingerprint identifies the request, for the retries to match it
+func fingerprint(r *http.Request, body []byte) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s %s\n", r.Method, r.URL.RequestURI())
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// load returns the response stored with the ID, unless expired. The ID must be pending, for no other
+// request to change its response meanwhile; the storage is read without holding the lock.
+func (idem *Idempotency) load(id store.ID, now time.Time) (idempotentResponse, bool, error) {
+	idem.lock.Lock()
+	expires, ok := idem.expires[id]
+	idem.lock.Unlock()
+	if !ok || !now.Before(expires) {
+		return idempotentResponse{}, false, nil
+	}
+	blob, err := idem.st.Load(id)
+	var notFound store.ErrNotFound
+	if errors.As(err, &notFound) {
+		idem.lock.Lock()
+		delete(idem.expires, id)
+		idem.lock.Unlock()
+		return idempotentResponse{}, false, nil
+	}
+	if err != nil {
+		return idempotentResponse{}, false, err
+	}
+	var resp idempotentResponse
+	if err := json.Unmarshal(blob, &resp); err != nil {
+		return idempotentResponse{}, false, err
+	}
+	return resp, true, nil
+}
+
+// save stores the response with the ID, replacing the expired one if any. The ID must be pending,
+// see load.
+func (idem *Idempotency) save(id store.ID, resp idempotentResponse) error {
+	blob, err := json.Marshal(resp)
+	if err != nil {
+		return err
+	}
+	idem.lock.Lock()
+	_, stored := idem.expires[id]
+	idem.lock.Unlock()
+	if stored {
+		err = idem.st.Save(id, blob)
+	} else {
+		err = idem.st.Create(id, blob)
+	}
+	if err != nil {
+		return err
+	}
+	idem.lock.Lock()
+	idem.expires[id] = resp.Created.Add(idem.TTL)
+	idem.lock.Unlock()
+	return nil
+}
+
+// expired returns the IDs of the expired responses, marked pending for sweep to remove them. The
+// caller must hold the lock.
+func (idem *Idempotency) expired(now time.Time) []store.ID {
+	var ids []store.ID
+	for id, expires := range idem.expires {
+		if now.Before(expires) || idem.pending[id] {
+			continue
+		}
+		idem.pending[id] = true
+		ids = append(ids, id)
+	}
+	idem.lastSweep = now
+	return ids
+}
+
+// sweep removes the expired responses with the IDs, see expired, without holding the lock
+func (idem *Idempotency) sweep(ids []store.ID) {
+	for _, id := range ids {
+		var notFound store.ErrNotFound
+		err := idem.st.Delete(id)
+		if err != nil && !errors.As(err, &notFound) {
+			slog.Error("middleware: Idempotency: can't remove the expired response", "id", id, "error", err)
+		}
+		idem.lock.Lock()
+		if err == nil || errors.As(err, &notFound) {
+			delete(idem.expires, id)
+		}
+		delete(idem.pending, id)
+		idem.lock.Unlock()
+	}
+}
+
+// Handle returns the handler calling next with the requests, but the retries of the ones with an
+// idempotency key already served: they get the same response, with the Idempotent-Replayed header.
+// The retries of a request still in progress are answered 409, and the requests reusing the key of
+// a different one 422, and the ones whose body is larger than 1 MiB 413. The responses to the
+// requests failing with 5xx are not stored, for the retries to be served again.
+func (idem *Idempotency) Handle(next http.Handler) http.Handler {
+	keyOf := idem.Key
+	if keyOf == nil {
+		keyOf = ClientIP(false)
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(IdempotencyKeyHeader)
+		if key == "" || (r.Method != http.MethodPost && r.Method != http.MethodPut && r.Method != http.MethodPatch) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if len(key) > maxIdempotencyKey {
+			sendIdempotencyError(w, http.StatusBadRequest, fmt.Sprintf("the %s header is longer than %d characters", IdempotencyKeyHeader, maxIdempotencyKey))
+			return
+		}
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxIdempotentBody))
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			sendIdempotencyError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("the request is larger than %d bytes", tooLarge.Limit))
+			return
+		}
+		if err != nil {
+			sendIdempotencyError(w, http.StatusBadRequest, fmt.Sprintf("can't read the request: %v", err))
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		client, kind := keyOf(r)
+		sum := sha256.Sum256([]byte(kind + ":" + client + ":" + key))
+		id := store.ID(idempotencyPrefix + hex.EncodeToString(sum[:]))
+		fp := fingerprint(r, body)
+
+		// the storage is used without holding the lock, not to hold the other requests up: the
+		// pending ID is this request's alone meanwhile
+		idem.lock.Lock()
+		now := idem.now()
+		busy := idem.pending[id]
+		if !busy {
+			idem.pending[id] = true
+		}
+		// not the response of this request, which it replaces
+		var expired []store.ID
+		if now.Sub(idem.lastSweep) >= sweepEvery {
+			expired = idem.expired(now)
+		}
+		idem.lock.Unlock()
+		idem.sweep(expired)
+		if busy {
+			sendIdempotencyError(w, http.StatusConflict, "a request with the same idempotency key is in progress")
+			return
+		}
+		defer func() {
+			idem.lock.Lock()
+			delete(idem.pending, id)
+			idem.lock.Unlock()
+		}()
+		stored, found, err := idem.load(id, now)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "middleware: Idempotency: can't load the response", "id", id, "error", err)
+			sendIdempotencyError(w, http.StatusInternalServerError, "can't load the response of the idempotency key")
+			return
+		}
+		if found {
+			if stored.Fingerprint != fp {
+				sendIdempotencyError(w, http.StatusUnprocessableEntity, "the idempotency key was used by a different request")
+				return
+			}
+			slog.InfoContext(r.Context(), "middleware: Idempotency: replayed", "method", r.Method, "path", r.URL.Path)
+			for name, values := range stored.Header {
+				w.Header()[name] = values
+			}
+			w.Header().Set(IdempotentReplayedHeader, "true")
+			w.WriteHeader(stored.Status)
+			w.Write(stored.Body)
+			return
+		}
+
+		rec := &recordingWriter{statusWriter: statusWriter{ResponseWriter: w}}
+		next.ServeHTTP(rec, r)
+		if rec.status() >= http.StatusInternalServerError {
+			return
+		}
+		resp := idempotentResponse{
+			Fingerprint: fp,
+			Created:     now,
+			Status:      rec.status(),
+			Header:      make(map[string][]string),
+			Body:        rec.body.Bytes(),
+		}
+		for _, name := range replayedHeaders {
+			if values := w.Header().Values(name); len(values) > 0 {
+				resp.Header[name] = values
+			}
+		}
+		if err := idem.save(id, resp); err != nil {
+			slog.ErrorContext(r.Context(), "middleware: Idempotency: can't store the response", "id", id, "error", err)
+		}
+	})
+}
+
+// recordingWriter is a http.ResponseWriter recording the body of the response besides writing it
+type recordingWriter struct {
+	statusWriter
+	body bytes.Buffer
+}
+
+func (rw *recordingWriter) Write(data []byte) (int, error) {
+	rw.body.Write(data)
+	return rw.statusWriter.Write(data)
+}
+
+// sendIdempotencyError answers the request with the error, like the controller does
+func sendIdempotencyError(w http.ResponseWriter, code int, text string) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(code)
+	resp := apiv1.Response{
+		Status: apiv1.ResponseError,
+		Error: &apiv1.Error{
+			Code: code,
+			Text: text,
+		},
+	}
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,167 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gotestbootcamp/go-todo-app/store"
+)
+
+func TestIdempotency(t *testing.T) {
+	st, err := store.NewMemory()
+	require.NoError(t, err)
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	idem, err := NewIdempotency(st, time.Hour)
+	require.NoError(t, err)
+	idem.now = func() time.Time { return now }
+	created := 0
+	release := make(chan struct{})
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			<-release
+		}
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		created++
+		w.Header().Set("Location", fmt.Sprintf("/todos/%d", created))
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, `{"id":"%d"}`, created)
+	})
+	handler := idem.Handle(next)
+	do := func(method, path, key, body, remoteAddr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.RemoteAddr = remoteAddr
+		if key != "" {
+			req.Header.Set(IdempotencyKeyHeader, key)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	w := do("POST", "/todos", "k1", `{"title":"a"}`, "10.0.0.1:1234")
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Empty(t, w.Header().Get(IdempotentReplayedHeader))
+	// the retry gets the same response, without creating another todo
+	w = do("POST", "/todos", "k1", `{"title":"a"}`, "10.0.0.1:5678")
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "true", w.Header().Get(IdempotentReplayedHeader))
+	assert.Equal(t, "/todos/1", w.Header().Get("Location"))
+	assert.Equal(t, `{"id":"1"}`, w.Body.String())
+	assert.Equal(t, 1, created)
+
+	// reusing the key for another request fails, other clients have their own keys
+	assert.Equal(t, http.StatusUnprocessableEntity, do("POST", "/todos", "k1", `{"title":"b"}`, "10.0.0.1:1234").Code)
+	assert.Equal(t, http.StatusCreated, do("POST", "/todos", "k1", `{"title":"b"}`, "10.0.0.2:1234").Code)
+	// the requests without a key, or which don't create nor update, are served as they are
+	assert.Equal(t, http.StatusCreated, do("POST", "/todos", "", `{"title":"a"}`, "10.0.0.1:1234").Code)
+	assert.Equal(t, http.StatusCreated, do("DELETE", "/todos/1", "k1", "", "10.0.0.1:1234").Code)
+	assert.Equal(t, 4, created)
+	assert.Equal(t, http.StatusBadRequest, do("POST", "/todos", strings.Repeat("k", 256), "", "10.0.0.1:1234").Code)
+
+	// the server errors are served again on retry
+	assert.Equal(t, http.StatusServiceUnavailable, do("POST", "/fail", "k2", "", "10.0.0.1:1234").Code)
+	w = do("POST", "/fail", "k2", "", "10.0.0.1:1234")
+	assert.Empty(t, w.Header().Get(IdempotentReplayedHeader))
+
+	// the retries of a request in progress conflict
+	done := make(chan int)
+	go func() {
+		done <- do("PUT", "/slow", "k3", "", "10.0.0.1:1234").Code
+	}()
+	require.Eventually(t, func() bool {
+		idem.lock.Lock()
+		defer idem.lock.Unlock()
+		return len(idem.pending) == 1
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, http.StatusConflict, do("PUT", "/slow", "k3", "", "10.0.0.1:1234").Code)
+	close(release)
+	assert.Equal(t, http.StatusCreated, <-done)
+
+	// the responses survive the restarts, until they expire
+	idem, err = NewIdempotency(st, time.Hour)
+	require.NoError(t, err)
+	idem.now = func() time.Time { return now }
+	handler = idem.Handle(next)
+	w = do("POST", "/todos", "k1", `{"title":"a"}`, "10.0.0.1:1234")
+	assert.Equal(t, "true", w.Header().Get(IdempotentReplayedHeader))
+	now = now.Add(2 * time.Hour)
+	w = do("POST", "/todos", "k1", `{"title":"a"}`, "10.0.0.1:1234")
+	assert.Empty(t, w.Header().Get(IdempotentReplayedHeader))
+	assert.Equal(t, 6, created)
+	// the expired ones are swept away
+	idem.lock.Lock()
+	assert.Len(t, idem.expires, 1)
+	idem.lock.Unlock()
+}
+
+func TestIdempotencyLargeBody(t *testing.T) {
+	st, err := store.NewMemory()
+	require.NoError(t, err)
+	idem, err := NewIdempotency(st, time.Hour)
+	require.NoError(t, err)
+	served := false
+	handler := idem.Handle(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		served = true
+	}))
+
+	req := httptest.NewRequest("POST", "/todos", strings.NewReader(strings.Repeat("x", maxIdempotentBody+1)))
+	req.Header.Set(IdempotencyKeyHeader, "k1")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	assert.False(t, served)
+}
+
+// slowLoads is a storage whose loads wait for release
+type slowLoads struct {
+	store.Storage
+	release chan struct{}
+}
+
+func (sl slowLoads) Load(id store.ID) (store.Blob, error) {
+	<-sl.release
+	return sl.Storage.Load(id)
+}
+
+func TestIdempotencyUnlockedStore(t *testing.T) {
+	mem, err := store.NewMemory()
+	require.NoError(t, err)
+	st := slowLoads{Storage: mem, release: make(chan struct{})}
+	idem, err := NewIdempotency(st, time.Hour)
+	require.NoError(t, err)
+	handler := idem.Handle(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	}))
+	do := func(key string) int {
+		req := httptest.NewRequest("POST", "/todos", strings.NewReader(`{"title":"a"}`))
+		req.Header.Set(IdempotencyKeyHeader, key)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w.Code
+	}
+	require.Equal(t, http.StatusCreated, do("k1"))
+
+	// the retry loads the stored response, slowly, not holding up the requests with other keys
+	done := make(chan int)
+	go func() {
+		done <- do("k1")
+	}()
+	require.Eventually(t, func() bool {
+		idem.lock.Lock()
+		defer idem.lock.Unlock()
+		return len(idem.pending) == 1
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, http.StatusCreated, do("k2"))
+	close(st.release)
+	assert.Equal(t, http.StatusCreated, <-done)
+}
@@ -0,0 +1,91 @@
+package middleware
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// RequestRecorder records the requests served
+type RequestRecorder interface {
+	// ObserveRequest records a request of the given route, answered with the status code after elapsed
+	ObserveRequest(route, method string, code int, elapsed time.Duration)
+}
+
+// Instrument returns the middleware of the gorilla/mux routers recording the requests routed, by
+// the name of their route
+func Instrument(rec RequestRecorder) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			sw := &statusWriter{ResponseWriter: w}
+			start := time.Now()
+			next.ServeHTTP(sw, r)
+			rec.ObserveRequest(routeName(r), r.Method, sw.status(), time.Since(start))
+		})
+	}
+}
+
+// routeName returns the name of the route the gorilla/mux router matched the request with
+func routeName(r *http.Request) string {
+	if cur := mux.CurrentRoute(r); cur != nil && cur.GetName() != "" {
+		return cur.GetName()
+	}
+	return "unnamed"
+}
+
+// statusWriter is a http.ResponseWriter remembering the status code of the response
+type statusWriter struct {
+	http.ResponseWriter
+	code int
+}
+
+func (sw *statusWriter) WriteHeader(code int) {
+	if sw.code == 0 {
+		sw.code = code
+	}
+	sw.ResponseWriter.WriteHeader(code)
+}
+
+func (sw *statusWriter) Write(data []byte) (int, error) {
+	if sw.code == 0 {
+		sw.code = http.StatusOK
+	}
+	return sw.ResponseWriter.Write(data)
+}
+
+// Flush flushes the response, for the event streams
+func (sw *statusWriter) Flush() {
+	if fl, ok := sw.ResponseWriter.(http.Flusher); ok {
+		fl.Flush()
+	}
+}
+
+// Hijack hands the connection over to the handler, for the WebSocket upgrades: the response is
+// then recorded as 101 Switching Protocols, unless the handler wrote a status before
+func (sw *statusWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hj, ok := sw.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, http.ErrNotSupported
+	}
+	conn, rw, err := hj.Hijack()
+	if err == nil && sw.code == 0 {
+		sw.code = http.StatusSwitchingProtocols
+	}
+	return conn, rw, err
+}
+
+// Unwrap returns the wrapped http.ResponseWriter, for http.ResponseController
+func (sw *statusWriter) Unwrap() http.ResponseWriter {
+	return sw.ResponseWriter
+}
+
+// status returns the status code of the response, 200 if the handler set none
+func (sw *statusWriter) status() int {
+	if sw.code == 0 {
+		return http.StatusOK
+	}
+	return sw.code
+}
@@ -0,0 +1,101 @@
+package middleware
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInstrument(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	rec, err := NewPrometheusRequests(reg, "test")
+	require.NoError(t, err)
+	router := mux.NewRouter()
+	router.Methods("GET").Path("/todos/{todoID}").Name("todo.show").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if mux.Vars(r)["todoID"] == "0" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("{}"))
+	})
+	router.Methods("GET").Path("/events").Name("event.index").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, ok := w.(http.Flusher)
+		assert.True(t, ok, "the event streams flush")
+	})
+	router.Use(Instrument(rec))
+
+	for _, path := range []string{"/todos/1", "/todos/2", "/todos/0", "/events", "/unknown"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+
+	expected := `
+# HELP test_http_requests_total Number of the requests served.
+# TYPE test_http_requests_total counter
+test_http_requests_total{code="200",method="GET",route="event.index"} 1
+test_http_requests_total{code="200",method="GET",route="todo.show"} 2
+test_http_requests_total{code="404",method="GET",route="todo.show"} 1
+`
+	assert.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(expected), "test_http_requests_total"))
+	assert.Equal(t, 2, testutil.CollectAndCount(rec.duration))
+}
+
+func TestExpvarRequests(t *testing.T) {
+	er := NewExpvarRequests("test_http")
+	er.ObserveRequest("todo.show", "GET", http.StatusOK, 2)
+	er.ObserveRequest("todo.show", "GET", http.StatusNotFound, 3)
+
+	assert.Equal(t, "2", er.vars.Get("todo.show_count").String())
+	assert.Equal(t, "1", er.vars.Get("todo.show_404").String())
+	assert.Equal(t, "5", er.vars.Get("todo.show_nanos").String())
+}
+
+func TestHijack(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	rec, err := NewPrometheusRequests(reg, "test")
+	require.NoError(t, err)
+	router := mux.NewRouter()
+	upgrade := func(w http.ResponseWriter, r *http.Request) {
+		conn, rw, err := http.NewResponseController(w).Hijack()
+		if !assert.NoError(t, err) {
+			return
+		}
+		defer conn.Close()
+		rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n\r\nhello")
+		rw.Flush()
+	}
+	router.Methods("GET").Path("/ws").Name("ws").Handler(Logger(http.HandlerFunc(upgrade), "ws"))
+	router.Use(Instrument(rec), Trace())
+	srv := httptest.NewServer(RequestID(router))
+	defer srv.Close()
+
+	conn, err := net.Dial("tcp", srv.Listener.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Write([]byte("GET /ws HTTP/1.1\r\nHost: test\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n\r\n"))
+	require.NoError(t, err)
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, nil)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
+	// past the upgrade, the connection belongs to the handler
+	data, err := io.ReadAll(br)
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(data))
+
+	expected := `
+# HELP test_http_requests_total Number of the requests served.
+# TYPE test_http_requests_total counter
+test_http_requests_total{code="101",method="GET",route="ws"} 1
+`
+	assert.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(expected), "test_http_requests_total"))
+}
@@ -1,25 +1,27 @@
 package middleware
 
 import (
-	"log"
+	"log/slog"
 	"net/http"
-	"os"
 	"time"
 )
 
-// Logger logs the API endpoint wall clock execution time
+// Logger logs the requests of the named route as they complete, with their status code and their
+// wall clock execution time: it's the access log of the API
 func Logger(inner http.Handler, name string) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
+		sw := &statusWriter{ResponseWriter: w}
 
-		inner.ServeHTTP(w, r)
+		inner.ServeHTTP(sw, r)
 
-		logSink := log.New(os.Stdout, "", log.LstdFlags)
-		logSink.Printf("middleware: %-6s\t%s\t%s\t%s",
-			r.Method,
-			r.RequestURI,
-			name,
-			time.Since(start),
+		slog.InfoContext(r.Context(), "middleware: request",
+			"method", r.Method,
+			"uri", r.RequestURI,
+			"route", name,
+			"status", sw.status(),
+			"duration", time.Since(start),
+			"remote", r.RemoteAddr,
 		)
 	})
 }
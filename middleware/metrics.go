@@ -0,0 +1,137 @@
+package middleware
+
+import (
+	"expvar"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	_ LimitRecorder   = &PrometheusLimits{}
+	_ LimitRecorder   = &ExpvarLimits{}
+	_ RequestRecorder = &PrometheusRequests{}
+	_ RequestRecorder = &ExpvarRequests{}
+)
+
+// PrometheusRequests exposes the request metrics as Prometheus collectors:
+// - <namespace>_http_requests_total: counter of the requests, by route, method and status code
+// - <namespace>_http_request_duration_seconds: histogram of the requests latency, by route and method
+type PrometheusRequests struct {
+	requests *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+}
+
+// NewPrometheusRequests creates the collectors and registers them in the given registerer,
+// e.g. prometheus.DefaultRegisterer
+func NewPrometheusRequests(reg prometheus.Registerer, namespace string) (*PrometheusRequests, error) {
+	pr := &PrometheusRequests{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "requests_total",
+			Help:      "Number of the requests served.",
+		}, []string{"route", "method", "code"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "request_duration_seconds",
+			Help:      "Latency of the requests.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"route", "method"}),
+	}
+	for _, coll := range []prometheus.Collector{pr.requests, pr.duration} {
+		if err := reg.Register(coll); err != nil {
+			return nil, err
+		}
+	}
+	return pr, nil
+}
+
+func (pr *PrometheusRequests) ObserveRequest(route, method string, code int, elapsed time.Duration) {
+	pr.requests.WithLabelValues(route, method, strconv.Itoa(code)).Inc()
+	pr.duration.WithLabelValues(route, method).Observe(elapsed.Seconds())
+}
+
+// ExpvarRequests publishes the request metrics as a expvar map, served by the expvar handler
+// on /debug/vars. For each route, the map holds:
+// - <route>_count: the number of requests served
+// - <route>_<code>: the number of requests answered with the status code
+// - <route>_nanos: the total time spent serving the requests, in nanoseconds
+type ExpvarRequests struct {
+	vars *expvar.Map
+}
+
+// NewExpvarRequests publishes a new expvar map with the given name.
+// Like expvar.NewMap, panics if the name is already in use.
+func NewExpvarRequests(name string) *ExpvarRequests {
+	return &ExpvarRequests{vars: expvar.NewMap(name)}
+}
+
+func (er *ExpvarRequests) ObserveRequest(route, method string, code int, elapsed time.Duration) {
+	er.vars.Add(route+"_count", 1)
+	er.vars.Add(route+"_"+strconv.Itoa(code), 1)
+	er.vars.Add(route+"_nanos", elapsed.Nanoseconds())
+}
+
+// PrometheusLimits exposes the rate limiting metrics as Prometheus collectors:
+// - <namespace>_http_ratelimit_requests_total: counter of the rate limited requests, by client kind
+// - <namespace>_http_ratelimit_rejected_total: counter of the rejected requests, by client kind
+type PrometheusLimits struct {
+	requests *prometheus.CounterVec
+	rejected *prometheus.CounterVec
+}
+
+// NewPrometheusLimits creates the collectors and registers them in the given registerer,
+// e.g. prometheus.DefaultRegisterer
+func NewPrometheusLimits(reg prometheus.Registerer, namespace string) (*PrometheusLimits, error) {
+	pl := &PrometheusLimits{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "http_ratelimit",
+			Name:      "requests_total",
+			Help:      "Number of the rate limited requests.",
+		}, []string{"kind"}),
+		rejected: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "http_ratelimit",
+			Name:      "rejected_total",
+			Help:      "Number of the requests rejected for exceeding the rate.",
+		}, []string{"kind"}),
+	}
+	for _, coll := range []prometheus.Collector{pl.requests, pl.rejected} {
+		if err := reg.Register(coll); err != nil {
+			return nil, err
+		}
+	}
+	return pl, nil
+}
+
+func (pl *PrometheusLimits) ObserveLimit(kind string, rejected bool) {
+	pl.requests.WithLabelValues(kind).Inc()
+	if rejected {
+		pl.rejected.WithLabelValues(kind).Inc()
+	}
+}
+
+// ExpvarLimits publishes the rate limiting metrics as a expvar map, served by the expvar handler
+// on /debug/vars. For each client kind, the map holds:
+// - <kind>_requests: the number of rate limited requests
+// - <kind>_rejected: the number of requests rejected for exceeding the rate
+type ExpvarLimits struct {
+	vars *expvar.Map
+}
+
+// NewExpvarLimits publishes a new expvar map with the given name.
+// Like expvar.NewMap, panics if the name is already in use.
+func NewExpvarLimits(name string) *ExpvarLimits {
+	return &ExpvarLimits{vars: expvar.NewMap(name)}
+}
+
+func (el *ExpvarLimits) ObserveLimit(kind string, rejected bool) {
+	el.vars.Add(kind+"_requests", 1)
+	if rejected {
+		el.vars.Add(kind+"_rejected", 1)
+	}
+}
@@ -0,0 +1,159 @@
+package middleware
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	apiv1 "github.com/gotestbootcamp/go-todo-app/api/v1"
+)
+
+// LimitRecorder records the outcome of the rate limited requests
+type LimitRecorder interface {
+	// ObserveLimit records a request of a client of the given kind, see KeyFunc, and whether it
+	// was rejected for exceeding the rate
+	ObserveLimit(kind string, rejected bool)
+}
+
+// KeyFunc returns the key identifying the client making the request, and its kind, e.g. "key"
+// for the clients identified by their API key and "ip" for the ones identified by their address
+type KeyFunc func(r *http.Request) (key, kind string)
+
+// sweepEvery is how often the limiter forgets the idle clients
+const sweepEvery = time.Minute
+
+// bucket is the token bucket of a client
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// RateLimiter limits the rate of the requests of each client with a token bucket: each client may
+// make Burst requests at once, and then one every 1/Rate seconds. It is safe for concurrent use.
+type RateLimiter struct {
+	// Rate is the number of requests per second each client may make in the long run
+	Rate float64
+	// Burst is the number of requests each client may make at once
+	Burst int
+	// Key identifies the clients. Nil means ClientIP.
+	Key KeyFunc
+	// Recorder records the outcome of the requests. Nil if none.
+	Recorder LimitRecorder
+
+	now       func() time.Time
+	lock      sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+// NewRateLimiter creates the limiter allowing each client rate requests per second, and burst at once
+func NewRateLimiter(rate float64, burst int) *RateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &RateLimiter{
+		Rate:    rate,
+		Burst:   burst,
+		now:     time.Now,
+		buckets: make(map[string]*bucket),
+	}
+}
+
+// ClientIP identifies the clients by their IP address. If trustProxy, the address is the first
+// of the X-Forwarded-For header, if any, as set by a reverse proxy in front of the server.
+func ClientIP(trustProxy bool) KeyFunc {
+	return func(r *http.Request) (string, string) {
+		if trustProxy {
+			if fwd := r.Header.Get("X-Forwarded-For"); fwd != "" {
+				first, _, _ := strings.Cut(fwd, ",")
+				return strings.TrimSpace(first), "ip"
+			}
+		}
+		host, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			return r.RemoteAddr, "ip"
+		}
+		return host, "ip"
+	}
+}
+
+// take takes a token from the bucket of the client, if any is left. Returns the tokens left, and
+// how long until the next token otherwise.
+func (rl *RateLimiter) take(key string) (bool, int, time.Duration) {
+	rl.lock.Lock()
+	defer rl.lock.Unlock()
+	now := rl.now()
+	if now.Sub(rl.lastSweep) >= sweepEvery {
+		rl.sweep(now)
+	}
+	b, ok := rl.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(rl.Burst), last: now}
+		rl.buckets[key] = b
+	}
+	b.tokens = math.Min(float64(rl.Burst), b.tokens+now.Sub(b.last).Seconds()*rl.Rate)
+	b.last = now
+	if b.tokens < 1 {
+		wait := time.Duration((1 - b.tokens) / rl.Rate * float64(time.Second))
+		return false, 0, wait
+	}
+	b.tokens--
+	return true, int(b.tokens), 0
+}
+
+// sweep forgets the clients whose bucket filled up again, as if they never made a request.
+// The caller must hold the lock.
+func (rl *RateLimiter) sweep(now time.Time) {
+	for key, b := range rl.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*rl.Rate >= float64(rl.Burst) {
+			delete(rl.buckets, key)
+		}
+	}
+	rl.lastSweep = now
+}
+
+// Limit returns the handler calling next unless the client exceeds its rate. The requests
+// exceeding it are answered 429, with the Retry-After header telling how many seconds to wait.
+// The X-RateLimit-Limit and X-RateLimit-Remaining headers tell the burst and the requests left.
+func (rl *RateLimiter) Limit(next http.Handler) http.Handler {
+	keyOf := rl.Key
+	if keyOf == nil {
+		keyOf = ClientIP(false)
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key, kind := keyOf(r)
+		ok, remaining, wait := rl.take(kind + ":" + key)
+		if rl.Recorder != nil {
+			rl.Recorder.ObserveLimit(kind, !ok)
+		}
+		w.Header().Set("X-RateLimit-Limit", strconv.Itoa(rl.Burst))
+		w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+		if ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+		retry := int(math.Ceil(wait.Seconds()))
+		slog.WarnContext(r.Context(), "middleware: rate limited",
+			"method", r.Method, "path", r.URL.Path, "client", kind, "key", key, "retry", retry)
+		w.Header().Set("Retry-After", strconv.Itoa(retry))
+		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+		w.WriteHeader(http.StatusTooManyRequests)
+		resp := apiv1.Response{
+			Status: apiv1.ResponseError,
+			Error: &apiv1.Error{
+				Code: http.StatusTooManyRequests,
+				Text: fmt.Sprintf("rate limit exceeded, retry in %ds", retry),
+			},
+		}
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
+			panic(err)
+		}
+	})
+}
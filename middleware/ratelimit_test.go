@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// limits counts the observed requests, by kind
+type limits map[string][2]int
+
+func (l limits) ObserveLimit(kind string, rejected bool) {
+	counts := l[kind]
+	counts[0]++
+	if rejected {
+		counts[1]++
+	}
+	l[kind] = counts
+}
+
+func TestRateLimiter(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	rl := NewRateLimiter(2, 3)
+	rl.now = func() time.Time { return now }
+	rec := limits{}
+	rl.Recorder = rec
+	handler := rl.Limit(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	do := func(remoteAddr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/todos", nil)
+		req.RemoteAddr = remoteAddr
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	for i := 2; i >= 0; i-- {
+		w := do("10.0.0.1:1234")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "3", w.Header().Get("X-RateLimit-Limit"))
+		assert.Equal(t, string(rune('0'+i)), w.Header().Get("X-RateLimit-Remaining"))
+	}
+	w := do("10.0.0.1:5678")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code, "same client, another port")
+	assert.Equal(t, "1", w.Header().Get("Retry-After"))
+	assert.Contains(t, w.Body.String(), "rate limit exceeded")
+	assert.Equal(t, http.StatusOK, do("10.0.0.2:1234").Code, "another client")
+
+	// the tokens come back at the rate
+	now = now.Add(500 * time.Millisecond)
+	assert.Equal(t, http.StatusOK, do("10.0.0.1:1234").Code)
+	assert.Equal(t, http.StatusTooManyRequests, do("10.0.0.1:1234").Code)
+	assert.Equal(t, limits{"ip": {7, 2}}, rec)
+
+	// the idle clients are forgotten
+	now = now.Add(sweepEvery)
+	do("10.0.0.3:1234")
+	assert.Len(t, rl.buckets, 1)
+}
+
+func TestClientIP(t *testing.T) {
+	req := httptest.NewRequest("GET", "/todos", nil)
+	req.RemoteAddr = "10.0.0.1:1234"
+	req.Header.Set("X-Forwarded-For", "192.0.2.7, 10.0.0.1")
+	key, kind := ClientIP(false)(req)
+	assert.Equal(t, "10.0.0.1", key)
+	assert.Equal(t, "ip", kind)
+	key, _ = ClientIP(true)(req)
+	assert.Equal(t, "192.0.2.7", key)
+}
@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gotestbootcamp/go-todo-app/logging"
+)
+
+// RequestIDHeader is the header carrying the ID of the request, set by the client or by a proxy,
+// and sent back in the response
+const RequestIDHeader = "X-Request-ID"
+
+// RequestID returns the handler calling next with the ID of the request in its context, see
+// logging.RequestID. The ID is the one of the X-Request-ID header, if valid, or else a random one.
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if !logging.ValidRequestID(id) {
+			id = logging.NewRequestID()
+		}
+		w.Header().Set(RequestIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(logging.NewContext(r.Context(), id)))
+	})
+}
@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestTrace(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	defer otel.SetTracerProvider(otel.GetTracerProvider())
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr)))
+	defer otel.SetTextMapPropagator(otel.GetTextMapPropagator())
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	var inner trace.SpanContext
+	router := mux.NewRouter()
+	router.Methods("GET").Path("/todos/{todoID}").Name("todo.show").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		inner = trace.SpanContextFromContext(r.Context())
+		if mux.Vars(r)["todoID"] == "0" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	})
+	router.Use(Trace())
+
+	req := httptest.NewRequest("GET", "/todos/1", nil)
+	req.Header.Set("traceparent", "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/todos/0", nil))
+
+	spans := sr.Ended()
+	require.Len(t, spans, 2)
+	ok, failed := spans[0], spans[1]
+
+	assert.Equal(t, "todo.show", ok.Name())
+	assert.Equal(t, trace.SpanKindServer, ok.SpanKind())
+	assert.Equal(t, "0af7651916cd43dd8448eb211c80319c", ok.SpanContext().TraceID().String(), "the trace of the caller")
+	assert.Equal(t, "b7ad6b7169203331", ok.Parent().SpanID().String())
+	assert.Contains(t, ok.Attributes(), attribute.Int("http.response.status_code", http.StatusOK))
+	assert.Equal(t, codes.Unset, ok.Status().Code)
+
+	assert.False(t, failed.Parent().IsValid())
+	assert.Equal(t, failed.SpanContext(), inner, "the handler runs in the span")
+	assert.Contains(t, failed.Attributes(), attribute.String("url.path", "/todos/0"))
+	assert.Equal(t, codes.Error, failed.Status().Code)
+}
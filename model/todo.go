@@ -2,13 +2,13 @@ package model
 
 import (
 	"bytes"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"html/template"
 	"time"
 
 	apiv1 "github.com/gotestbootcamp/go-todo-app/api/v1"
+	"github.com/gotestbootcamp/go-todo-app/task"
 )
 
 var (
@@ -52,22 +52,63 @@ func (td Todo) ToAPIv1() apiv1.Todo {
 	}
 }
 
-// Serialize encodes the object in its canonical bytestream representation.
+// Serialize encodes the object in its canonical bytestream representation, a task.Task.
 // If succesfull, returns the representation; otherwise the representation
-// must be ignored, and the error will describe the failure.
+// must be ignored, and the error will describe the failure, e.g. a task.ValidationError.
 func (td Todo) Serialize() ([]byte, error) {
-	var buf bytes.Buffer
-	err := json.NewEncoder(&buf).Encode(td)
-	return buf.Bytes(), err
+	return task.Marshal(td.ToTask())
+}
+
+// SerializeOver encodes the object on top of prev, a previous representation of the same
+// object, preserving the task fields a Todo doesn't hold, like the tags.
+// If prev can't be decoded, it is like Serialize.
+func (td Todo) SerializeOver(prev []byte) ([]byte, error) {
+	tk, err := task.Unmarshal(prev)
+	if err != nil {
+		return td.Serialize()
+	}
+	td.applyTo(&tk)
+	return task.Marshal(tk)
 }
 
 // Serialize decodes the object from its canonical bytestream representation.
 // If succesfull, returns the decode object; otherwise returns a zero valued
 // object, and the error will describe the failure.
 func DeserializeTodo(data []byte) (Todo, error) {
-	var todo Todo
-	err := json.NewDecoder(bytes.NewBuffer(data)).Decode(&todo)
-	return todo, err
+	tk, err := task.Unmarshal(data)
+	if err != nil {
+		return Todo{}, err
+	}
+	return FromTask(tk), nil
+}
+
+// ToTask converts the object into the corresponding task
+func (td Todo) ToTask() task.Task {
+	tk := task.Task{
+		Schema:  task.SchemaVersion,
+		Created: td.LastUpdateTime,
+	}
+	td.applyTo(&tk)
+	return tk
+}
+
+func (td Todo) applyTo(tk *task.Task) {
+	tk.Title = td.Title
+	tk.Assignee = td.Assignee
+	tk.Description = td.Description
+	tk.Status = task.Status(td.Status)
+	tk.Updated = td.LastUpdateTime
+}
+
+// FromTask creates a new object from its corresponding task
+func FromTask(tk task.Task) Todo {
+	return Todo{
+		Title:          tk.Title,
+		Assignee:       tk.Assignee,
+		Description:    tk.Description,
+		Status:         apiv1.Status(tk.Status),
+		LastUpdateTime: tk.Updated,
+	}
 }
 
 // / NewFromAPIv1 creates a new object from its corresponding API layer object
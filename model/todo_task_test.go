@@ -0,0 +1,52 @@
+package model
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	apiv1 "github.com/gotestbootcamp/go-todo-app/api/v1"
+	"github.com/gotestbootcamp/go-todo-app/task"
+)
+
+func TestSerializeRoundTrip(t *testing.T) {
+	todo := Todo{
+		Title:          "foo",
+		Assignee:       "fede",
+		Description:    "bar",
+		Status:         apiv1.Assigned,
+		LastUpdateTime: time.Date(2024, time.November, 11, 10, 0, 0, 0, time.UTC),
+	}
+	data, err := todo.Serialize()
+	require.NoError(t, err)
+	got, err := DeserializeTodo(data)
+	require.NoError(t, err)
+	assert.Equal(t, todo, got)
+
+	_, err = Todo{Status: apiv1.Pending}.Serialize()
+	assert.Error(t, err)
+}
+
+func TestSerializeOver(t *testing.T) {
+	tk := task.New("foo")
+	tk.Tags = []string{"home"}
+	tk.Priority = task.PriorityHigh
+	prev, err := task.Marshal(tk)
+	require.NoError(t, err)
+
+	todo, err := DeserializeTodo(prev)
+	require.NoError(t, err)
+	require.NoError(t, todo.Assign("fede"))
+	data, err := todo.SerializeOver(prev)
+	require.NoError(t, err)
+
+	got, err := task.Unmarshal(data)
+	require.NoError(t, err)
+	assert.Equal(t, "fede", got.Assignee)
+	assert.Equal(t, task.Assigned, got.Status)
+	assert.Equal(t, []string{"home"}, got.Tags)
+	assert.Equal(t, task.PriorityHigh, got.Priority)
+	assert.True(t, tk.Created.Equal(got.Created))
+}
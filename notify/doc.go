@@ -0,0 +1,7 @@
+// Package notify posts messages to the Slack and Discord channels when the todos are assigned,
+// become overdue or are completed, through their incoming webhooks.
+// Each Notifier posts to the channel of its URL, or to the one of the list of the todo, formatting
+// the messages with the text/template of each event, see Message for their fields.
+// The notifiers are either static, loaded from a YAML file on startup, or managed through the
+// API, stored as metadata items of the storage.
+package notify
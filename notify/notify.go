@@ -0,0 +1,300 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/gotestbootcamp/go-todo-app/store"
+	"github.com/gotestbootcamp/go-todo-app/webhook"
+)
+
+// notifierPrefix marks the IDs of the items holding the notifiers managed through the API
+var notifierPrefix = string(store.MetaID("notifiers/"))
+
+// validName matches the names of the notifiers
+var validName = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,63}$`)
+
+// Kind is the chat service a notifier posts to
+type Kind string
+
+const (
+	Slack   Kind = "slack"
+	Discord Kind = "discord"
+)
+
+// ErrNoNotifier is returned when there is no notifier with the given name
+type ErrNoNotifier struct {
+	Name string
+}
+
+func (e ErrNoNotifier) Error() string {
+	return fmt.Sprintf("notify: no notifier %q", e.Name)
+}
+
+// ErrExists is returned when creating a notifier with the name of another one
+type ErrExists struct {
+	Name string
+}
+
+func (e ErrExists) Error() string {
+	return fmt.Sprintf("notify: the notifier %q exists already", e.Name)
+}
+
+// ErrStatic is returned when deleting a static notifier, which only the file can remove
+var ErrStatic = errors.New("notify: the static notifiers can't be deleted")
+
+// Notifier posts the events of the todos to a Slack or Discord channel
+type Notifier struct {
+	// Name identifies the notifier: lowercase letters, digits, dashes and underscores
+	Name string `json:"name" yaml:"name"`
+	Kind Kind   `json:"kind" yaml:"kind"`
+	// URL is the incoming webhook of the channel the messages are posted to
+	URL string `json:"url" yaml:"url"`
+	// Events are the kinds of the events posted. Empty means assigned, overdue and completed.
+	Events []webhook.Event `json:"events,omitempty" yaml:"events,omitempty"`
+	// Channels are the incoming webhooks of the channels the messages about the todos of each
+	// list are posted to instead, by list name
+	Channels map[string]string `json:"channels,omitempty" yaml:"channels,omitempty"`
+	// Templates format the messages of each kind of event, instead of DefaultTemplates
+	Templates map[webhook.Event]string `json:"templates,omitempty" yaml:"templates,omitempty"`
+	Created   time.Time                `json:"created" yaml:"-"`
+	// Static tells whether the notifier was loaded from a file, rather than created through the API
+	Static bool `json:"-" yaml:"-"`
+}
+
+// DefaultEvents are the kinds of the events posted by the notifiers which don't tell
+var DefaultEvents = []webhook.Event{webhook.Assigned, webhook.Overdue, webhook.Completed}
+
+// Validate checks the notifier has a valid name, a known kind, absolute http or https URLs, and
+// known kinds of events whose templates parse
+func (n Notifier) Validate() error {
+	if !validName.MatchString(n.Name) {
+		return fmt.Errorf("notify: invalid name %q, want lowercase letters, digits, dashes and underscores", n.Name)
+	}
+	if n.Kind != Slack && n.Kind != Discord {
+		return fmt.Errorf("notify: unknown kind %q, want slack or discord", n.Kind)
+	}
+	if err := validateURL(n.URL); err != nil {
+		return err
+	}
+	for _, ev := range n.Events {
+		if !slices.Contains(webhook.Events, ev) {
+			return fmt.Errorf("notify: unknown event %q", ev)
+		}
+	}
+	for list, u := range n.Channels {
+		if err := store.ValidateList(list); err != nil {
+			return err
+		}
+		if err := validateURL(u); err != nil {
+			return fmt.Errorf("%w, for the list %q", err, list)
+		}
+	}
+	for ev, text := range n.Templates {
+		if !slices.Contains(webhook.Events, ev) {
+			return fmt.Errorf("notify: template of the unknown event %q", ev)
+		}
+		if _, err := template.New(string(ev)).Parse(text); err != nil {
+			return fmt.Errorf("notify: invalid template of the %s event: %w", ev, err)
+		}
+	}
+	return nil
+}
+
+func validateURL(s string) error {
+	u, err := url.Parse(s)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("notify: invalid URL %q, want an absolute http or https URL", s)
+	}
+	return nil
+}
+
+// events returns the kinds of the events posted
+func (n Notifier) events() []webhook.Event {
+	if len(n.Events) == 0 {
+		return DefaultEvents
+	}
+	return n.Events
+}
+
+// channel returns the incoming webhook the messages about the todos of the list are posted to
+func (n Notifier) channel(list string) string {
+	if u, ok := n.Channels[list]; ok {
+		return u
+	}
+	return n.URL
+}
+
+// Notifiers holds the notifiers: the static ones, and the ones managed through the API, stored
+// as metadata items of the storage. It is safe for concurrent use.
+type Notifiers struct {
+	st store.Storage
+
+	lock      sync.RWMutex
+	notifiers map[string]Notifier
+	// posts are the messages being posted
+	posts sync.WaitGroup
+	// Client posts the messages
+	Client *http.Client
+	// Attempts is how many times a message is posted at most, DefaultAttempts by default
+	Attempts int
+	// Backoff is how long to wait before posting a message again the first time, doubling at
+	// each retry, DefaultBackoff by default
+	Backoff time.Duration
+}
+
+// NewNotifiers creates the notifiers stored in st
+func NewNotifiers(st store.Storage) (*Notifiers, error) {
+	ns := &Notifiers{
+		st:        st,
+		notifiers: make(map[string]Notifier),
+		Client:    &http.Client{Timeout: DefaultTimeout},
+		Attempts:  DefaultAttempts,
+		Backoff:   DefaultBackoff,
+	}
+	err := store.Walk(st, func(item store.Item) error {
+		if !strings.HasPrefix(string(item.ID), notifierPrefix) {
+			return nil
+		}
+		var n Notifier
+		if err := json.Unmarshal(item.Blob, &n); err != nil {
+			return fmt.Errorf("notify: can't decode the notifier %v: %w", item.ID, err)
+		}
+		ns.notifiers[n.Name] = n
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	slog.Info("notify: loaded the notifiers", "count", len(ns.notifiers))
+	return ns, nil
+}
+
+// AddStatic adds a static notifier, which posts the events until the process exits
+func (ns *Notifiers) AddStatic(n Notifier) error {
+	if err := n.Validate(); err != nil {
+		return err
+	}
+	n.Created = time.Now().UTC()
+	n.Static = true
+	ns.lock.Lock()
+	defer ns.lock.Unlock()
+	if _, ok := ns.notifiers[n.Name]; ok {
+		return ErrExists{Name: n.Name}
+	}
+	ns.notifiers[n.Name] = n
+	return nil
+}
+
+// LoadFile adds the static notifiers listed in the YAML file, e.g.
+//
+//	notifiers:
+//	  - name: team
+//	    kind: slack
+//	    url: https://hooks.slack.com/services/T000/B000/XXXX
+//	    events: [assigned, overdue]
+//	    channels:
+//	      ops: https://hooks.slack.com/services/T000/B001/YYYY
+//	    templates:
+//	      overdue: "{{.Title}} is late!"
+func (ns *Notifiers) LoadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var file struct {
+		Notifiers []Notifier `yaml:"notifiers"`
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&file); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	for _, n := range file.Notifiers {
+		if err := ns.AddStatic(n); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// Create stores the new notifier, created now, and returns it.
+// Fails with ErrExists if there is another with the same name.
+func (ns *Notifiers) Create(n Notifier) (Notifier, error) {
+	if err := n.Validate(); err != nil {
+		return Notifier{}, err
+	}
+	n.Created = time.Now().UTC()
+	n.Static = false
+	blob, err := json.Marshal(n)
+	if err != nil {
+		return Notifier{}, err
+	}
+	ns.lock.Lock()
+	defer ns.lock.Unlock()
+	if _, ok := ns.notifiers[n.Name]; ok {
+		return Notifier{}, ErrExists{Name: n.Name}
+	}
+	if err := ns.st.Create(store.ID(notifierPrefix+n.Name), blob); err != nil {
+		return Notifier{}, err
+	}
+	ns.notifiers[n.Name] = n
+	slog.Info("notify: created", "name", n.Name, "kind", n.Kind, "events", n.events())
+	return n, nil
+}
+
+// Get returns the notifier with the given name, and false if there is none
+func (ns *Notifiers) Get(name string) (Notifier, bool) {
+	ns.lock.RLock()
+	defer ns.lock.RUnlock()
+	n, ok := ns.notifiers[name]
+	return n, ok
+}
+
+// List returns the notifiers, sorted by name
+func (ns *Notifiers) List() []Notifier {
+	ns.lock.RLock()
+	defer ns.lock.RUnlock()
+	res := make([]Notifier, 0, len(ns.notifiers))
+	for _, n := range ns.notifiers {
+		res = append(res, n)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Name < res[j].Name })
+	return res
+}
+
+// Delete removes the notifier managed through the API with the given name.
+// Fails with ErrNoNotifier if there's none, and with ErrStatic if it's static.
+func (ns *Notifiers) Delete(name string) error {
+	ns.lock.Lock()
+	defer ns.lock.Unlock()
+	n, ok := ns.notifiers[name]
+	if !ok {
+		return ErrNoNotifier{Name: name}
+	}
+	if n.Static {
+		return ErrStatic
+	}
+	if err := ns.st.Delete(store.ID(notifierPrefix + name)); err != nil {
+		return err
+	}
+	delete(ns.notifiers, name)
+	slog.Info("notify: deleted", "name", name)
+	return nil
+}
@@ -0,0 +1,154 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gotestbootcamp/go-todo-app/ledger"
+	"github.com/gotestbootcamp/go-todo-app/store"
+	"github.com/gotestbootcamp/go-todo-app/task"
+	"github.com/gotestbootcamp/go-todo-app/webhook"
+)
+
+func TestNotifiers(t *testing.T) {
+	mem, err := store.NewMemory()
+	require.NoError(t, err)
+	ns, err := NewNotifiers(mem)
+	require.NoError(t, err)
+
+	file := filepath.Join(t.TempDir(), "notifiers.yaml")
+	require.NoError(t, os.WriteFile(file, []byte(`notifiers:
+  - name: team
+    kind: slack
+    url: https://hooks.slack.com/services/T000/B000/XXXX
+    events: [assigned, overdue]
+    channels:
+      ops: https://hooks.slack.com/services/T000/B001/YYYY
+    templates:
+      overdue: "{{.Title}} is late!"
+`), 0o600))
+	require.NoError(t, ns.LoadFile(file))
+	team, ok := ns.Get("team")
+	require.True(t, ok)
+	assert.True(t, team.Static)
+	assert.Equal(t, []webhook.Event{webhook.Assigned, webhook.Overdue}, team.Events)
+	assert.ErrorIs(t, ns.Delete("team"), ErrStatic)
+
+	gaming, err := ns.Create(Notifier{Name: "gaming", Kind: Discord, URL: "https://discord.com/api/webhooks/1/x"})
+	require.NoError(t, err)
+	assert.False(t, gaming.Created.IsZero())
+	_, err = ns.Create(Notifier{Name: "team", Kind: Discord, URL: "https://discord.com/api/webhooks/1/x"})
+	assert.ErrorAs(t, err, &ErrExists{})
+
+	for name, n := range map[string]Notifier{
+		"no name":          {Kind: Slack, URL: "https://hooks.slack.com/x"},
+		"invalid name":     {Name: "The Team", Kind: Slack, URL: "https://hooks.slack.com/x"},
+		"unknown kind":     {Name: "ops", Kind: "teams", URL: "https://hooks.slack.com/x"},
+		"no URL":           {Name: "ops", Kind: Slack},
+		"unknown event":    {Name: "ops", Kind: Slack, URL: "https://hooks.slack.com/x", Events: []webhook.Event{"archived"}},
+		"invalid channel":  {Name: "ops", Kind: Slack, URL: "https://hooks.slack.com/x", Channels: map[string]string{"ops": "#ops"}},
+		"invalid template": {Name: "ops", Kind: Slack, URL: "https://hooks.slack.com/x", Templates: map[webhook.Event]string{webhook.Overdue: "{{.Title"}},
+	} {
+		_, err := ns.Create(n)
+		assert.Error(t, err, name)
+	}
+
+	// only the notifiers created through the API are stored
+	ns, err = NewNotifiers(mem)
+	require.NoError(t, err)
+	assert.Equal(t, []Notifier{gaming}, ns.List())
+	require.NoError(t, ns.Delete("gaming"))
+	assert.ErrorAs(t, ns.Delete("gaming"), &ErrNoNotifier{})
+	assert.Empty(t, ns.List())
+}
+
+func TestFormat(t *testing.T) {
+	due := time.Date(2024, 3, 1, 18, 0, 0, 0, time.UTC)
+	tk := task.New("pay rent")
+	tk.Due, tk.Assignee = &due, "alice"
+	item := ledger.Item{ID: store.ListID("home", "7"), Task: &tk}
+
+	n := Notifier{Name: "team", Kind: Slack, URL: "https://hooks.slack.com/x"}
+	for ev, want := range map[webhook.Event]string{
+		webhook.Created:   `New todo 7: "pay rent" in home`,
+		webhook.Assigned:  `Todo 7 "pay rent" was assigned to alice`,
+		webhook.Completed: `Todo 7 "pay rent" was completed by alice`,
+		webhook.Overdue:   `Todo 7 "pay rent" is overdue, it was due Fri Mar 1 18:00, assigned to alice`,
+	} {
+		text, err := n.Format(ev, item)
+		require.NoError(t, err)
+		assert.Equal(t, want, text)
+	}
+
+	n.Templates = map[webhook.Event]string{webhook.Overdue: "{{.List}}/{{.ID}}: {{.Title}} is late!"}
+	text, err := n.Format(webhook.Overdue, item)
+	require.NoError(t, err)
+	assert.Equal(t, "home/7: pay rent is late!", text)
+	n.Templates[webhook.Overdue] = "{{.Owner}}"
+	_, err = n.Format(webhook.Overdue, item)
+	assert.Error(t, err)
+}
+
+func TestNotify(t *testing.T) {
+	var lock sync.Mutex
+	posted := make(map[string][]map[string]string)
+	failures := map[string]int{"/slack/ops": 1}
+	service := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+		if failures[r.URL.Path] > 0 {
+			failures[r.URL.Path]--
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		var body map[string]string
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		posted[r.URL.Path] = append(posted[r.URL.Path], body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer service.Close()
+
+	mem, err := store.NewMemory()
+	require.NoError(t, err)
+	ns, err := NewNotifiers(mem)
+	require.NoError(t, err)
+	ns.Backoff = time.Millisecond
+	_, err = ns.Create(Notifier{
+		Name:     "team",
+		Kind:     Slack,
+		URL:      service.URL + "/slack",
+		Events:   []webhook.Event{webhook.Overdue},
+		Channels: map[string]string{"ops": service.URL + "/slack/ops"},
+	})
+	require.NoError(t, err)
+	_, err = ns.Create(Notifier{Name: "gaming", Kind: Discord, URL: service.URL + "/discord"})
+	require.NoError(t, err)
+
+	due := time.Date(2024, 3, 1, 18, 0, 0, 0, time.UTC)
+	tk := task.New("pay rent")
+	tk.Due = &due
+	ns.Notify(context.Background(), webhook.Overdue, ledger.Item{ID: "1", Task: &tk})
+	ns.Notify(context.Background(), webhook.Overdue, ledger.Item{ID: store.ListID("ops", "2"), Task: &tk})
+	ns.Notify(context.Background(), webhook.Created, ledger.Item{ID: "3", Task: &tk})
+	tk.Assignee = "alice"
+	ns.Notify(context.Background(), webhook.Assigned, ledger.Item{ID: "1", Task: &tk})
+	ns.posts.Wait()
+
+	assert.Equal(t, []map[string]string{{"text": `Todo 1 "pay rent" is overdue, it was due Fri Mar 1 18:00`}}, posted["/slack"])
+	assert.Equal(t, []map[string]string{{"text": `Todo 2 "pay rent" is overdue, it was due Fri Mar 1 18:00`}}, posted["/slack/ops"])
+	assert.ElementsMatch(t, []map[string]string{
+		{"content": `Todo 1 "pay rent" is overdue, it was due Fri Mar 1 18:00`},
+		{"content": `Todo 2 "pay rent" is overdue, it was due Fri Mar 1 18:00`},
+		{"content": `Todo 1 "pay rent" was assigned to alice`},
+	}, posted["/discord"])
+}
@@ -0,0 +1,178 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"text/template"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gotestbootcamp/go-todo-app/ledger"
+	"github.com/gotestbootcamp/go-todo-app/store"
+	"github.com/gotestbootcamp/go-todo-app/task"
+	"github.com/gotestbootcamp/go-todo-app/webhook"
+)
+
+const (
+	// DefaultAttempts is how many times a message is posted at most by default
+	DefaultAttempts = 3
+	// DefaultBackoff is how long to wait before posting a message again the first time by default
+	DefaultBackoff = time.Second
+	// DefaultTimeout bounds each attempt of posting a message by default
+	DefaultTimeout = 10 * time.Second
+	// maxDiscordContent is the longest content of the Discord messages, in characters
+	maxDiscordContent = 2000
+)
+
+// DefaultTemplates format the messages of each kind of event, unless the notifier has its own
+var DefaultTemplates = map[webhook.Event]string{
+	webhook.Created:   `New todo {{.ID}}: "{{.Title}}"{{with .List}} in {{.}}{{end}}`,
+	webhook.Assigned:  `Todo {{.ID}} "{{.Title}}" was assigned to {{.Assignee}}`,
+	webhook.Completed: `Todo {{.ID}} "{{.Title}}" was completed{{with .Assignee}} by {{.}}{{end}}`,
+	webhook.Overdue:   `Todo {{.ID}} "{{.Title}}" is overdue, it was due {{.Due.Format "Mon Jan 2 15:04"}}{{with .Assignee}}, assigned to {{.}}{{end}}`,
+}
+
+// Message is what the templates format the messages of
+type Message struct {
+	Event webhook.Event
+	// ID is the ID of the todo in its list
+	ID store.ID
+	// List is the list of the todo, empty if none
+	List        string
+	Title       string
+	Description string
+	Assignee    string
+	Status      task.Status
+	Priority    task.Priority
+	// Due is when the todo is due, the zero time if never
+	Due  time.Time
+	Tags []string
+}
+
+// newMessage returns the message about the event of the todo
+func newMessage(ev webhook.Event, item ledger.Item) Message {
+	list, id := store.SplitListID(item.ID)
+	msg := Message{Event: ev, ID: id, List: list}
+	if tk := item.Task; tk != nil {
+		msg.Title, msg.Description, msg.Assignee = tk.Title, tk.Description, tk.Assignee
+		msg.Status, msg.Priority, msg.Tags = tk.Status, tk.Priority, tk.Tags
+		if tk.Due != nil {
+			msg.Due = *tk.Due
+		}
+	}
+	return msg
+}
+
+// Format returns the text of the message about the event of the todo
+func (n Notifier) Format(ev webhook.Event, item ledger.Item) (string, error) {
+	text, ok := n.Templates[ev]
+	if !ok {
+		text = DefaultTemplates[ev]
+	}
+	tmpl, err := template.New(string(ev)).Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, newMessage(ev, item)); err != nil {
+		return "", fmt.Errorf("notify: can't format the %s message: %w", ev, err)
+	}
+	return sb.String(), nil
+}
+
+// body returns the JSON body posting the text to the incoming webhook
+func (n Notifier) body(text string) ([]byte, error) {
+	if n.Kind == Discord {
+		if utf8.RuneCountInString(text) > maxDiscordContent {
+			text = string([]rune(text)[:maxDiscordContent-1]) + "…"
+		}
+		return json.Marshal(map[string]string{"content": text})
+	}
+	return json.Marshal(map[string]string{"text": text})
+}
+
+// Run posts the events told by the watcher until the context is done, then waits for the
+// messages being posted
+func (ns *Notifiers) Run(ctx context.Context, w *webhook.Watcher) error {
+	defer ns.posts.Wait()
+	return w.Run(ctx, func(ev webhook.Event, item ledger.Item) {
+		ns.Notify(ctx, ev, item)
+	})
+}
+
+// Notify posts the message about the event of the todo with the notifiers posting its kind,
+// in the background, until the context is done
+func (ns *Notifiers) Notify(ctx context.Context, ev webhook.Event, item ledger.Item) {
+	list, _ := store.SplitListID(item.ID)
+	for _, n := range ns.List() {
+		if !slices.Contains(n.events(), ev) {
+			continue
+		}
+		text, err := n.Format(ev, item)
+		if err != nil {
+			slog.Error("notify: can't post", "notifier", n.Name, "event", ev, "todo", item.ID, "error", err)
+			continue
+		}
+		ns.posts.Add(1)
+		go func() {
+			defer ns.posts.Done()
+			if err := ns.post(ctx, n, n.channel(list), text); err != nil {
+				slog.Warn("notify: post failed", "notifier", n.Name, "event", ev, "todo", item.ID, "error", err)
+				return
+			}
+			slog.Debug("notify: posted", "notifier", n.Name, "event", ev, "todo", item.ID)
+		}()
+	}
+}
+
+// post posts the text to the incoming webhook, retrying with exponential backoff while the service
+// fails or is unreachable, up to Attempts times
+func (ns *Notifiers) post(ctx context.Context, n Notifier, url, text string) error {
+	body, err := n.body(text)
+	if err != nil {
+		return err
+	}
+	backoff := ns.Backoff
+	for attempt := 1; ; attempt++ {
+		retry, err := ns.postOnce(ctx, url, body)
+		if err == nil || !retry || attempt >= ns.Attempts || ctx.Err() != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// postOnce posts the body once. Returns whether the failures can be retried, i.e. unless the
+// service rejected the message.
+func (ns *Notifiers) postOnce(ctx context.Context, url string, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := ns.Client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	switch {
+	case resp.StatusCode < 300:
+		return false, nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return true, fmt.Errorf("notify: the service answered %s", resp.Status)
+	}
+	return false, fmt.Errorf("notify: the service rejected the message: %s: %s", resp.Status, bytes.TrimSpace(msg))
+}
@@ -0,0 +1,3 @@
+// Package rpc implements the gRPC TodoService on top of the ledger, like the controller package
+// implements the REST API
+package rpc
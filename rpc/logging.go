@@ -0,0 +1,70 @@
+package rpc
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/gotestbootcamp/go-todo-app/logging"
+)
+
+// RequestIDKey is the request metadata carrying the ID of the call, set by the client or by a
+// proxy, and sent back in the header of the response
+const RequestIDKey = "x-request-id"
+
+// withRequestID returns the context of the call carrying its ID, see logging.RequestID: the one
+// of its metadata, if valid, or else a random one, which it sends back in the header
+func withRequestID(ctx context.Context) context.Context {
+	var id string
+	if vals := metadata.ValueFromIncomingContext(ctx, RequestIDKey); len(vals) > 0 {
+		id = vals[0]
+	}
+	if !logging.ValidRequestID(id) {
+		id = logging.NewRequestID()
+	}
+	grpc.SetHeader(ctx, metadata.Pairs(RequestIDKey, id))
+	return logging.NewContext(ctx, id)
+}
+
+// logCall logs the call to the method as it completes, with its status code and its wall clock
+// execution time, like middleware.Logger does with the HTTP requests
+func logCall(ctx context.Context, method string, start time.Time, err error) {
+	slog.InfoContext(ctx, "gRPC: call",
+		"method", method,
+		"code", status.Code(err).String(),
+		"duration", time.Since(start),
+	)
+}
+
+// UnaryLogger is the interceptor of the unary calls giving them an ID, see logging.RequestID, and
+// logging them. Chain it before the other interceptors, for them to log with the ID.
+func UnaryLogger(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	start := time.Now()
+	ctx = withRequestID(ctx)
+	res, err := handler(ctx, req)
+	logCall(ctx, info.FullMethod, start, err)
+	return res, err
+}
+
+// StreamLogger is like UnaryLogger, for the streaming calls
+func StreamLogger(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	start := time.Now()
+	ctx := withRequestID(ss.Context())
+	err := handler(srv, loggedStream{ServerStream: ss, ctx: ctx})
+	logCall(ctx, info.FullMethod, start, err)
+	return err
+}
+
+// loggedStream is a grpc.ServerStream whose context carries the ID of the call
+type loggedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (ls loggedStream) Context() context.Context {
+	return ls.ctx
+}
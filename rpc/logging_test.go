@@ -0,0 +1,38 @@
+package rpc
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/gotestbootcamp/go-todo-app/api/todopb"
+	"github.com/gotestbootcamp/go-todo-app/logging"
+)
+
+func TestUnaryLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := logging.New(&buf, "info", "text")
+	require.NoError(t, err)
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(logger)
+	client, _ := newTestClient(t, grpc.ChainUnaryInterceptor(UnaryLogger))
+
+	var header metadata.MD
+	ctx := metadata.AppendToOutgoingContext(context.Background(), RequestIDKey, "req-42")
+	_, err = client.CreateTodo(ctx, &todopb.CreateTodoRequest{Title: "groceries"}, grpc.Header(&header))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"req-42"}, header.Get(RequestIDKey))
+	assert.Contains(t, buf.String(), `msg="gRPC: created object" id=1 request_id=req-42`)
+	assert.Contains(t, buf.String(), `msg="gRPC: call" method=/todo.v1.TodoService/CreateTodo code=OK`)
+
+	_, err = client.GetTodo(context.Background(), &todopb.GetTodoRequest{Id: "missing"}, grpc.Header(&header))
+	assert.Error(t, err)
+	assert.Len(t, header.Get(RequestIDKey)[0], 16, "random ID")
+	assert.Contains(t, buf.String(), "code=NotFound")
+}
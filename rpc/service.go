@@ -0,0 +1,256 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/gotestbootcamp/go-todo-app/api/todopb"
+	apiv1 "github.com/gotestbootcamp/go-todo-app/api/v1"
+	"github.com/gotestbootcamp/go-todo-app/auth"
+	"github.com/gotestbootcamp/go-todo-app/ledger"
+	"github.com/gotestbootcamp/go-todo-app/model"
+	"github.com/gotestbootcamp/go-todo-app/store"
+	"github.com/gotestbootcamp/go-todo-app/task"
+	"github.com/gotestbootcamp/go-todo-app/user"
+)
+
+// ActorKey is the request metadata naming who makes the request, recorded in the history of the todos
+const ActorKey = "x-actor"
+
+// Service implements the gRPC TodoService
+type Service struct {
+	todopb.UnimplementedTodoServiceServer
+	ld  *ledger.Ledger
+	ids store.IDGenerator
+	// users tell which authenticated users are admins. Nil if there are no users.
+	users *user.Directory
+}
+
+// New creates the service of the ledger, which gets the IDs of the new todos from the given generator
+func New(ld *ledger.Ledger, ids store.IDGenerator) *Service {
+	return &Service{ld: ld, ids: ids}
+}
+
+// SetUsers sets the users owning the todos, to tell which authenticated users are admins
+func (svc *Service) SetUsers(users *user.Directory) {
+	svc.users = users
+}
+
+// Register registers the service with the gRPC server
+func (svc *Service) Register(srv *grpc.Server) {
+	todopb.RegisterTodoServiceServer(srv, svc)
+}
+
+// RequiredScope returns the scope required by the method of the service with the given full name,
+// for auth.Authenticator.UnaryInterceptor and StreamInterceptor
+func RequiredScope(fullMethod string) auth.Scope {
+	switch fullMethod {
+	case todopb.TodoService_GetTodo_FullMethodName,
+		todopb.TodoService_ListTodos_FullMethodName,
+		todopb.TodoService_WatchTodos_FullMethodName:
+		return auth.ScopeRead
+	default:
+		return auth.ScopeWrite
+	}
+}
+
+// ledger returns the view of the ledger recording the mutations as made by the actor of the request.
+// The authenticated requests act as the user named like their API key, regardless of the x-actor
+// metadata, see ledger.AsUser. The storage operations of the view are traced as part of the call.
+func (svc *Service) ledger(ctx context.Context) *ledger.Ledger {
+	if id, ok := auth.FromContext(ctx); ok {
+		return svc.ld.AsUser(id.Name, svc.users.IsAdmin(id)).WithContext(ctx)
+	}
+	var actor string
+	if vals := metadata.ValueFromIncomingContext(ctx, ActorKey); len(vals) > 0 {
+		actor = vals[0]
+	}
+	return svc.ld.As(actor).WithContext(ctx)
+}
+
+func (svc *Service) GetTodo(ctx context.Context, req *todopb.GetTodoRequest) (*todopb.Todo, error) {
+	item, rev, err := svc.ledger(ctx).GetItem(store.ID(req.GetId()))
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return todoToPB(item, rev), nil
+}
+
+func (svc *Service) ListTodos(ctx context.Context, req *todopb.ListTodosRequest) (*todopb.ListTodosResponse, error) {
+	ld := svc.ledger(ctx)
+	if req.GetIncludeArchived() {
+		ld = ld.WithArchived()
+	}
+	items, err := ld.Filter(func(todo model.Todo) bool {
+		return (req.GetStatus() == "" || string(todo.Status) == req.GetStatus()) &&
+			(req.GetAssignee() == "" || todo.Assignee == req.GetAssignee())
+	})
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	resp := &todopb.ListTodosResponse{}
+	for _, item := range items {
+		if req.GetTag() != "" && !slices.Contains(item.Task.Tags, req.GetTag()) {
+			continue
+		}
+		// the archived todos have no revision
+		rev, _ := ld.Revision(item.ID)
+		resp.Todos = append(resp.Todos, todoToPB(item, rev))
+	}
+	return resp, nil
+}
+
+func (svc *Service) CreateTodo(ctx context.Context, req *todopb.CreateTodoRequest) (*todopb.Todo, error) {
+	todo := model.NewFromAPIv1(apiv1.Todo{
+		Title:       req.GetTitle(),
+		Description: req.GetDescription(),
+		Assignee:    req.GetAssignee(),
+	})
+	ld := svc.ledger(ctx)
+	todo.Status = apiv1.Status(ld.Workflow().Initial())
+	id, err := svc.ids.NewID()
+	if err != nil {
+		return nil, status.Error(codes.Unavailable, err.Error())
+	}
+	// the todos are never overwritten, e.g. if created meanwhile by the clients of the store
+	item, rev, err := ld.SetIf(id, todo, 0)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	slog.InfoContext(ctx, "gRPC: created object", "id", id)
+	return todoToPB(item, rev), nil
+}
+
+func (svc *Service) UpdateTodo(ctx context.Context, req *todopb.UpdateTodoRequest) (*todopb.Todo, error) {
+	patch := ledger.Patch{
+		Title:       req.Title,
+		Description: req.Description,
+		Assignee:    req.Assignee,
+		Status:      (*task.Status)(req.Status),
+	}
+	item, rev, err := svc.ledger(ctx).PatchIf(store.ID(req.GetId()), patch, expected(req.Revision))
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	slog.InfoContext(ctx, "gRPC: updated object", "id", item.ID)
+	return todoToPB(item, rev), nil
+}
+
+func (svc *Service) CompleteTodo(ctx context.Context, req *todopb.CompleteTodoRequest) (*todopb.Todo, error) {
+	item, rev, err := svc.ledger(ctx).CompleteIf(store.ID(req.GetId()), expected(req.Revision))
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	slog.InfoContext(ctx, "gRPC: completed object", "id", item.ID)
+	return todoToPB(item, rev), nil
+}
+
+func (svc *Service) DeleteTodo(ctx context.Context, req *todopb.DeleteTodoRequest) (*todopb.DeleteTodoResponse, error) {
+	if err := svc.ledger(ctx).DeleteIf(store.ID(req.GetId()), expected(req.Revision)); err != nil {
+		return nil, toStatus(err)
+	}
+	slog.InfoContext(ctx, "gRPC: removed object", "id", req.GetId())
+	return &todopb.DeleteTodoResponse{}, nil
+}
+
+func (svc *Service) WatchTodos(req *todopb.WatchTodosRequest, stream todopb.TodoService_WatchTodosServer) error {
+	ctx := stream.Context()
+	events, err := svc.ledger(ctx).Watch(ctx)
+	if err != nil {
+		return toStatus(err)
+	}
+	// the headers tell the client the changes are watched from now on
+	if err := stream.SendHeader(metadata.MD{}); err != nil {
+		return err
+	}
+	for ev := range events {
+		pbEv := &todopb.TodoEvent{Type: string(ev.Type), Id: string(ev.Item.ID)}
+		if ev.Type != store.EventDeleted {
+			// the events carry no revision
+			pbEv.Todo = todoToPB(ev.Item, 0)
+		}
+		if err := stream.Send(pbEv); err != nil {
+			return err
+		}
+	}
+	if ctx.Err() != nil {
+		return toStatus(ctx.Err())
+	}
+	return status.Error(codes.Unavailable, "the watcher fell behind: list the todos again, and watch anew")
+}
+
+// expected returns the revision the todo is expected to have, if set
+func expected(rev *int64) int {
+	if rev == nil {
+		return ledger.AnyRevision
+	}
+	return int(*rev)
+}
+
+// toStatus returns the gRPC status of the error
+func toStatus(err error) error {
+	var notFound store.ErrNotFound
+	var conflict store.ErrConflict
+	var illegal task.ErrIllegalTransition
+	var code codes.Code
+	switch {
+	case errors.As(err, &notFound):
+		code = codes.NotFound
+	case errors.As(err, &conflict):
+		code = codes.Aborted
+	case errors.As(err, &illegal), errors.Is(err, model.ErrAlreadyAssigned),
+		errors.Is(err, model.ErrNotAssigned), errors.Is(err, model.ErrFinalized):
+		code = codes.FailedPrecondition
+	case errors.Is(err, store.ErrUnsupported):
+		code = codes.Unimplemented
+	case errors.Is(err, ledger.ErrForbidden):
+		code = codes.PermissionDenied
+	case errors.Is(err, context.Canceled):
+		code = codes.Canceled
+	case errors.Is(err, context.DeadlineExceeded):
+		code = codes.DeadlineExceeded
+	default:
+		code = codes.InvalidArgument
+	}
+	return status.Error(code, err.Error())
+}
+
+// todoToPB converts the todo at the given revision in its protobuf representation
+func todoToPB(item ledger.Item, rev int) *todopb.Todo {
+	apiTodo := item.ToAPIv1().Todo
+	pb := &todopb.Todo{
+		Id:          string(item.ID),
+		Title:       apiTodo.Title,
+		Assignee:    apiTodo.Assignee,
+		Description: apiTodo.Description,
+		Status:      string(apiTodo.Status),
+		Updated:     timestamp(&apiTodo.LastUpdateTime),
+		Due:         timestamp(apiTodo.Due),
+		Remind:      timestamp(apiTodo.Remind),
+		Recur:       apiTodo.Recur,
+		Tags:        apiTodo.Tags,
+		Priority:    apiTodo.Priority,
+		Archived:    apiTodo.Archived,
+		Revision:    int64(rev),
+	}
+	for _, id := range apiTodo.BlockedBy {
+		pb.BlockedBy = append(pb.BlockedBy, string(id))
+	}
+	return pb
+}
+
+func timestamp(t *time.Time) *timestamppb.Timestamp {
+	if t == nil || t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(*t)
+}
@@ -0,0 +1,198 @@
+package rpc
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/gotestbootcamp/go-todo-app/api/todopb"
+	"github.com/gotestbootcamp/go-todo-app/auth"
+	"github.com/gotestbootcamp/go-todo-app/ledger"
+	"github.com/gotestbootcamp/go-todo-app/model"
+	"github.com/gotestbootcamp/go-todo-app/store"
+	"github.com/gotestbootcamp/go-todo-app/task"
+)
+
+func newTestClient(t *testing.T, opts ...grpc.ServerOption) (todopb.TodoServiceClient, *ledger.Ledger) {
+	mem, err := store.NewMemory()
+	require.NoError(t, err)
+	ld, err := ledger.New(store.NewNotifier(mem))
+	require.NoError(t, err)
+	ids, err := store.NewIDGenerator("sequential", mem)
+	require.NoError(t, err)
+
+	ln := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer(opts...)
+	New(ld, ids).Register(srv)
+	go srv.Serve(ln)
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return ln.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return todopb.NewTodoServiceClient(conn), ld
+}
+
+func TestTodoService(t *testing.T) {
+	client, ld := newTestClient(t)
+	ctx := metadata.AppendToOutgoingContext(context.Background(), ActorKey, "alice")
+
+	created, err := client.CreateTodo(ctx, &todopb.CreateTodoRequest{Title: "groceries"})
+	require.NoError(t, err)
+	assert.Equal(t, "groceries", created.Title)
+	assert.Equal(t, "pending", created.Status)
+	assert.Equal(t, int64(1), created.Revision)
+
+	got, err := client.GetTodo(ctx, &todopb.GetTodoRequest{Id: created.Id})
+	require.NoError(t, err)
+	assert.True(t, proto.Equal(created, got))
+	_, err = client.GetTodo(ctx, &todopb.GetTodoRequest{Id: "missing"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	// the stale revisions are rejected
+	_, err = client.UpdateTodo(ctx, &todopb.UpdateTodoRequest{Id: created.Id, Assignee: proto.String("bob"), Revision: proto.Int64(0)})
+	assert.Equal(t, codes.Aborted, status.Code(err))
+	updated, err := client.UpdateTodo(ctx, &todopb.UpdateTodoRequest{Id: created.Id, Assignee: proto.String("bob"), Revision: proto.Int64(1)})
+	require.NoError(t, err)
+	assert.Equal(t, "bob", updated.Assignee)
+	assert.Equal(t, "assigned", updated.Status)
+	assert.Equal(t, "groceries", updated.Title)
+	assert.Equal(t, int64(2), updated.Revision)
+	revs, err := ld.History(store.ID(created.Id))
+	require.NoError(t, err)
+	assert.Equal(t, "alice", revs[1].Actor)
+
+	_, err = client.CreateTodo(ctx, &todopb.CreateTodoRequest{Title: "laundry"})
+	require.NoError(t, err)
+	list, err := client.ListTodos(ctx, &todopb.ListTodosRequest{Assignee: "bob"})
+	require.NoError(t, err)
+	require.Len(t, list.Todos, 1)
+	assert.Equal(t, created.Id, list.Todos[0].Id)
+
+	completed, err := client.CompleteTodo(ctx, &todopb.CompleteTodoRequest{Id: created.Id})
+	require.NoError(t, err)
+	assert.Equal(t, "completed", completed.Status)
+	_, err = client.CompleteTodo(ctx, &todopb.CompleteTodoRequest{Id: created.Id})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	_, err = client.DeleteTodo(ctx, &todopb.DeleteTodoRequest{Id: created.Id})
+	require.NoError(t, err)
+	list, err = client.ListTodos(ctx, &todopb.ListTodosRequest{})
+	require.NoError(t, err)
+	require.Len(t, list.Todos, 1)
+	assert.Equal(t, "laundry", list.Todos[0].Title)
+}
+
+func TestCreateTodoWorkflow(t *testing.T) {
+	mem, err := store.NewMemory()
+	require.NoError(t, err)
+	wf, err := task.ParseWorkflow("todo>doing,done")
+	require.NoError(t, err)
+	ld, err := ledger.NewWithWorkflow(mem, wf)
+	require.NoError(t, err)
+	svc := New(ld, store.NewSequentialIDs(mem))
+
+	created, err := svc.CreateTodo(context.Background(), &todopb.CreateTodoRequest{Title: "groceries"})
+	require.NoError(t, err)
+	assert.Equal(t, "todo", created.Status)
+}
+
+func TestWatchTodos(t *testing.T) {
+	client, _ := newTestClient(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stream, err := client.WatchTodos(ctx, &todopb.WatchTodosRequest{})
+	require.NoError(t, err)
+	// the stream is established once the headers are received
+	_, err = stream.Header()
+	require.NoError(t, err)
+
+	created, err := client.CreateTodo(ctx, &todopb.CreateTodoRequest{Title: "groceries"})
+	require.NoError(t, err)
+	_, err = client.DeleteTodo(ctx, &todopb.DeleteTodoRequest{Id: created.Id})
+	require.NoError(t, err)
+
+	ev, err := stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, "created", ev.Type)
+	assert.Equal(t, created.Id, ev.Id)
+	assert.Equal(t, "groceries", ev.Todo.Title)
+	ev, err = stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, "deleted", ev.Type)
+	assert.Nil(t, ev.Todo)
+}
+
+func TestTodoServiceAuth(t *testing.T) {
+	keys, err := auth.NewKeyring(nil)
+	require.NoError(t, err)
+	require.NoError(t, keys.AddStatic("alice", auth.ScopeWrite, "s3cret-alice"))
+	require.NoError(t, keys.AddStatic("bob", auth.ScopeRead, "s3cret-bob"))
+	au := auth.NewAuthenticator(keys, auth.NewSigner([]byte("0123456789abcdef")))
+	client, ld := newTestClient(t,
+		grpc.UnaryInterceptor(au.UnaryInterceptor(RequiredScope)),
+		grpc.StreamInterceptor(au.StreamInterceptor(RequiredScope)))
+	alice := metadata.AppendToOutgoingContext(context.Background(), auth.APIKeyMetadata, "s3cret-alice", ActorKey, "mallory")
+	bob := metadata.AppendToOutgoingContext(context.Background(), auth.APIKeyMetadata, "s3cret-bob")
+
+	_, err = client.ListTodos(context.Background(), &todopb.ListTodosRequest{})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	_, err = client.CreateTodo(bob, &todopb.CreateTodoRequest{Title: "groceries"})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	created, err := client.CreateTodo(alice, &todopb.CreateTodoRequest{Title: "groceries"})
+	require.NoError(t, err)
+	_, err = client.GetTodo(bob, &todopb.GetTodoRequest{Id: created.Id})
+	require.NoError(t, err)
+	// the actor is the authenticated one
+	revs, err := ld.History(store.ID(created.Id))
+	require.NoError(t, err)
+	assert.Equal(t, "alice", revs[0].Actor)
+	// who owns the todo
+	require.NoError(t, keys.AddStatic("carol", auth.ScopeWrite, "s3cret-carol"))
+	carol := metadata.AppendToOutgoingContext(context.Background(), auth.APIKeyMetadata, "s3cret-carol")
+	_, err = client.DeleteTodo(carol, &todopb.DeleteTodoRequest{Id: created.Id})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	_, err = client.CompleteTodo(alice, &todopb.CompleteTodoRequest{Id: created.Id})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+	// the todos of the lists are hidden from the users who aren't members
+	_, err = ld.AddMember("work", "alice", ledger.RoleListAdmin)
+	require.NoError(t, err)
+	require.NoError(t, ld.Set(store.ListID("work", "1"), model.New("secret")))
+	_, err = client.GetTodo(carol, &todopb.GetTodoRequest{Id: "work/1"})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	listed, err := client.ListTodos(carol, &todopb.ListTodosRequest{})
+	require.NoError(t, err)
+	for _, todo := range listed.Todos {
+		assert.NotEqual(t, "work/1", todo.Id)
+	}
+	_, err = client.GetTodo(alice, &todopb.GetTodoRequest{Id: "work/1"})
+	require.NoError(t, err)
+
+	token, _, err := au.IssueToken(auth.Identity{Name: "bob", Scope: auth.ScopeRead, KeyID: "static-bob"}, auth.ScopeRead, 0)
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+token))
+	defer cancel()
+	stream, err := client.WatchTodos(ctx, &todopb.WatchTodosRequest{})
+	require.NoError(t, err)
+	_, err = stream.Header()
+	require.NoError(t, err)
+	stream, err = client.WatchTodos(context.Background(), &todopb.WatchTodosRequest{})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
@@ -0,0 +1,4 @@
+// Package search parses the queries selecting the todos, shared by the API and the CLI, like
+// `status:open tag:work due<2025-01-01 "exact phrase"`, into the syntax tree the ledger evaluates
+// against the indexes of the datastore.
+package search
@@ -0,0 +1,408 @@
+package search
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/gotestbootcamp/go-todo-app/dates"
+	"github.com/gotestbootcamp/go-todo-app/task"
+)
+
+// Op compares the value of a field with the one of the query
+type Op string
+
+const (
+	// Eq matches the fields equal to the value, or the dates on the day
+	Eq Op = ":"
+	Lt Op = "<"
+	Le Op = "<="
+	Gt Op = ">"
+	Ge Op = ">="
+)
+
+// Holds returns true if c, the result of comparing the value of a field with the one of the query
+// like cmp.Compare does, satisfies the operator
+func (op Op) Holds(c int) bool {
+	switch op {
+	case Lt:
+		return c < 0
+	case Le:
+		return c <= 0
+	case Gt:
+		return c > 0
+	case Ge:
+		return c >= 0
+	default:
+		return c == 0
+	}
+}
+
+// The kinds of the values of the fields
+const (
+	kindText = iota
+	kindPriority
+	kindDate
+)
+
+// fields are the fields the queries select the todos by, and the kinds of their values
+var fields = map[string]int{
+	"status":   kindText,
+	"tag":      kindText,
+	"context":  kindText,
+	"owner":    kindText,
+	"assignee": kindText,
+	"list":     kindText,
+	"priority": kindPriority,
+	"due":      kindDate,
+	"created":  kindDate,
+	"updated":  kindDate,
+}
+
+// fieldTerm matches the terms selecting the todos by field, like status:open or due<2025-01-01
+var fieldTerm = regexp.MustCompile(`^([A-Za-z]+)(:|<=|>=|<|>)(.*)$`)
+
+// SyntaxError describes why a query can't be parsed
+type SyntaxError struct {
+	// Pos is the offset in the query of the term at fault, in bytes
+	Pos int
+	Msg string
+}
+
+func (e SyntaxError) Error() string {
+	return fmt.Sprintf("search: %s at offset %d", e.Msg, e.Pos)
+}
+
+// Expr is a node of the syntax tree of a query: And, Or, Not, Text or Field
+type Expr interface {
+	// String formats the expression in the query language
+	String() string
+}
+
+// And matches the todos matching all its expressions. The empty And matches all the todos.
+type And []Expr
+
+func (and And) String() string {
+	return join(and, " ")
+}
+
+// Or matches the todos matching any of its expressions
+type Or []Expr
+
+func (or Or) String() string {
+	return join(or, " OR ")
+}
+
+// join formats the expressions with the separator, within parentheses when nested
+func join(exprs []Expr, sep string) string {
+	parts := make([]string, 0, len(exprs))
+	for _, expr := range exprs {
+		switch expr.(type) {
+		case And, Or:
+			parts = append(parts, "("+expr.String()+")")
+		default:
+			parts = append(parts, expr.String())
+		}
+	}
+	return strings.Join(parts, sep)
+}
+
+// Not matches the todos not matching its expression
+type Not struct {
+	X Expr
+}
+
+func (not Not) String() string {
+	switch not.X.(type) {
+	case And, Or:
+		return "-(" + not.X.String() + ")"
+	}
+	return "-" + not.X.String()
+}
+
+// Text matches the todos whose title or description has the words: a word, matched by the
+// full-text search like the ones of index.Search, or an exact phrase, matched as is, ignoring case
+type Text struct {
+	Words  string
+	Phrase bool
+}
+
+func (text Text) String() string {
+	if text.Phrase {
+		return `"` + text.Words + `"`
+	}
+	return text.Words
+}
+
+// Field matches the todos whose field compares to the value with the operator. Only the priority
+// and the dates compare with the operators other than Eq.
+type Field struct {
+	// Name is the name of the field: status, tag, owner, assignee, list, priority, due, created or updated
+	Name  string
+	Op    Op
+	Value string
+	// Priority is the value of the priority field
+	Priority task.Priority
+	// Day is the value of the date fields, at midnight: their values compare with whole days
+	Day time.Time
+}
+
+func (field Field) String() string {
+	value := field.Value
+	if value == "" || strings.ContainsFunc(value, func(r rune) bool {
+		return unicode.IsSpace(r) || strings.ContainsRune(`"()`, r)
+	}) {
+		value = `"` + value + `"`
+	}
+	return field.Name + string(field.Op) + value
+}
+
+// Parse parses the query, a sequence of terms the todos must all match:
+//   - a word, e.g. groceries, or an exact phrase within double quotes, in the title or the description
+//   - a field and its value, like status:open, tag:work or context:home, quoted if it has spaces, e.g. due:"next friday"
+//   - the priority or a date compared with <, <=, > or >=, e.g. priority>=high or due<2025-01-01
+//
+// The terms prefixed with - are negated, "a OR b" matches either term, and the parentheses group
+// the terms, e.g. tag:work -(status:completed OR priority<normal). The dates are parsed by dp, and
+// compare as whole days. The empty query is the empty And. Fails with SyntaxError.
+func Parse(query string, dp dates.Parser) (Expr, error) {
+	p := &parser{lexer: lexer{query: query}, dates: dp}
+	p.next()
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.tok.kind != tokEOF {
+		return nil, SyntaxError{Pos: p.tok.pos, Msg: "unexpected )"}
+	}
+	if expr == nil {
+		return And{}, nil
+	}
+	return expr, nil
+}
+
+// The kinds of the tokens of the queries
+const (
+	tokEOF = iota
+	tokWord
+	tokPhrase
+	tokNot
+	tokOr
+	tokOpen
+	tokClose
+)
+
+type token struct {
+	kind int
+	pos  int
+	text string
+	// quoted is the value within double quotes following the text of a word, like due:"next friday"
+	quoted *string
+}
+
+// lexer splits the queries in tokens
+type lexer struct {
+	query string
+	pos   int
+}
+
+// next returns the next token of the query
+func (lx *lexer) next() (token, error) {
+	for lx.pos < len(lx.query) && unicode.IsSpace(rune(lx.query[lx.pos])) {
+		lx.pos++
+	}
+	start := lx.pos
+	if lx.pos == len(lx.query) {
+		return token{kind: tokEOF, pos: start}, nil
+	}
+	switch c := lx.query[lx.pos]; {
+	case c == '(':
+		lx.pos++
+		return token{kind: tokOpen, pos: start}, nil
+	case c == ')':
+		lx.pos++
+		return token{kind: tokClose, pos: start}, nil
+	case c == '"':
+		text, err := lx.quoted()
+		return token{kind: tokPhrase, pos: start, text: text}, err
+	case c == '-' && lx.pos+1 < len(lx.query) && !unicode.IsSpace(rune(lx.query[lx.pos+1])):
+		lx.pos++
+		return token{kind: tokNot, pos: start}, nil
+	}
+	for lx.pos < len(lx.query) {
+		c := rune(lx.query[lx.pos])
+		if unicode.IsSpace(c) || c == '(' || c == ')' || c == '"' {
+			break
+		}
+		lx.pos++
+	}
+	tok := token{kind: tokWord, pos: start, text: lx.query[start:lx.pos]}
+	if tok.text == "OR" {
+		tok.kind = tokOr
+	} else if lx.pos < len(lx.query) && lx.query[lx.pos] == '"' && strings.ContainsAny(tok.text[len(tok.text)-1:], ":<>=") {
+		value, err := lx.quoted()
+		if err != nil {
+			return tok, err
+		}
+		tok.quoted = &value
+	}
+	return tok, nil
+}
+
+// quoted returns the text within the double quotes at the position
+func (lx *lexer) quoted() (string, error) {
+	start := lx.pos
+	end := strings.IndexByte(lx.query[start+1:], '"')
+	if end < 0 {
+		return "", SyntaxError{Pos: start, Msg: "unterminated quote"}
+	}
+	lx.pos = start + 1 + end + 1
+	return lx.query[start+1 : start+1+end], nil
+}
+
+// parser parses the queries by recursive descent, looking ahead a token
+type parser struct {
+	lexer
+	dates dates.Parser
+	tok   token
+	err   error
+}
+
+func (p *parser) next() {
+	if p.err != nil {
+		return
+	}
+	p.tok, p.err = p.lexer.next()
+}
+
+// parseOr parses the terms separated by OR. Nil if there are no terms.
+func (p *parser) parseOr() (Expr, error) {
+	var or Or
+	for {
+		expr, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		if p.tok.kind != tokOr {
+			if or == nil {
+				return expr, nil
+			}
+			if expr == nil {
+				return nil, SyntaxError{Pos: p.tok.pos, Msg: "missing term after OR"}
+			}
+			return append(or, expr), nil
+		}
+		if expr == nil {
+			return nil, SyntaxError{Pos: p.tok.pos, Msg: "missing term before OR"}
+		}
+		or = append(or, expr)
+		p.next()
+	}
+}
+
+// parseAnd parses a sequence of terms. Nil if there are none.
+func (p *parser) parseAnd() (Expr, error) {
+	var and And
+	for {
+		if p.err != nil {
+			return nil, p.err
+		}
+		switch p.tok.kind {
+		case tokEOF, tokClose, tokOr:
+			switch len(and) {
+			case 0:
+				return nil, nil
+			case 1:
+				return and[0], nil
+			}
+			return and, nil
+		}
+		expr, err := p.parseTerm()
+		if err != nil {
+			return nil, err
+		}
+		and = append(and, expr)
+	}
+}
+
+// parseTerm parses a term, negated or not
+func (p *parser) parseTerm() (Expr, error) {
+	tok := p.tok
+	p.next()
+	if p.err != nil {
+		return nil, p.err
+	}
+	switch tok.kind {
+	case tokNot:
+		switch p.tok.kind {
+		case tokEOF, tokClose, tokOr:
+			return nil, SyntaxError{Pos: tok.pos, Msg: "missing term after -"}
+		}
+		expr, err := p.parseTerm()
+		if err != nil {
+			return nil, err
+		}
+		return Not{X: expr}, nil
+	case tokOpen:
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.tok.kind != tokClose {
+			return nil, SyntaxError{Pos: tok.pos, Msg: "unclosed ("}
+		}
+		if expr == nil {
+			return nil, SyntaxError{Pos: tok.pos, Msg: "empty ()"}
+		}
+		p.next()
+		return expr, nil
+	case tokPhrase:
+		return Text{Words: tok.text, Phrase: true}, nil
+	default:
+		return p.parseWord(tok)
+	}
+}
+
+// parseWord parses a word, or a field and its value
+func (p *parser) parseWord(tok token) (Expr, error) {
+	m := fieldTerm.FindStringSubmatch(tok.text)
+	if m == nil {
+		return Text{Words: tok.text}, nil
+	}
+	field := Field{Name: strings.ToLower(m[1]), Op: Op(m[2]), Value: m[3]}
+	kind, ok := fields[field.Name]
+	if !ok {
+		return nil, SyntaxError{Pos: tok.pos, Msg: fmt.Sprintf("unknown field %q", m[1])}
+	}
+	if tok.quoted != nil {
+		if field.Value != "" {
+			return nil, SyntaxError{Pos: tok.pos, Msg: fmt.Sprintf("invalid value of %s", field.Name)}
+		}
+		field.Value = *tok.quoted
+	}
+	if field.Value == "" {
+		return nil, SyntaxError{Pos: tok.pos, Msg: fmt.Sprintf("missing value of %s", field.Name)}
+	}
+	switch kind {
+	case kindPriority:
+		pr, err := task.ParsePriority(field.Value)
+		if err != nil {
+			return nil, SyntaxError{Pos: tok.pos, Msg: err.Error()}
+		}
+		field.Priority = pr
+	case kindDate:
+		t, err := p.dates.Parse(field.Value)
+		if err != nil {
+			return nil, SyntaxError{Pos: tok.pos, Msg: fmt.Sprintf("invalid %s date: %v", field.Name, err)}
+		}
+		t = t.In(p.dates.Location)
+		field.Day = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	default:
+		if field.Op != Eq {
+			return nil, SyntaxError{Pos: tok.pos, Msg: fmt.Sprintf("%s can't be compared with %s", field.Name, field.Op)}
+		}
+	}
+	return field, nil
+}
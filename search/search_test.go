@@ -0,0 +1,85 @@
+package search
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gotestbootcamp/go-todo-app/dates"
+	"github.com/gotestbootcamp/go-todo-app/task"
+)
+
+func TestParse(t *testing.T) {
+	// a wednesday
+	now := time.Date(2026, time.October, 14, 10, 30, 0, 0, time.UTC)
+	dp := dates.Parser{Now: func() time.Time { return now }, Location: time.UTC, WeekStart: time.Monday}
+	day := func(month time.Month, d int) time.Time {
+		return time.Date(2026, month, d, 0, 0, 0, 0, time.UTC)
+	}
+	for query, expected := range map[string]Expr{
+		"":          And{},
+		"groceries": Text{Words: "groceries"},
+		`status:open tag:work due<2026-11-01 "exact phrase"`: And{
+			Field{Name: "status", Op: Eq, Value: "open"},
+			Field{Name: "tag", Op: Eq, Value: "work"},
+			Field{Name: "due", Op: Lt, Value: "2026-11-01", Day: day(time.November, 1)},
+			Text{Words: "exact phrase", Phrase: true},
+		},
+		`Priority>=high due:"next friday 5pm"`: And{
+			Field{Name: "priority", Op: Ge, Value: "high", Priority: task.PriorityHigh},
+			Field{Name: "due", Op: Eq, Value: "next friday 5pm", Day: day(time.October, 23)},
+		},
+		"milk OR bread -tag:done": Or{
+			Text{Words: "milk"},
+			And{Text{Words: "bread"}, Not{X: Field{Name: "tag", Op: Eq, Value: "done"}}},
+		},
+		"tag:work -(status:completed OR priority<=p2) 10:30": And{
+			Field{Name: "tag", Op: Eq, Value: "work"},
+			Not{X: Or{
+				Field{Name: "status", Op: Eq, Value: "completed"},
+				Field{Name: "priority", Op: Le, Value: "p2", Priority: task.PriorityNormal},
+			}},
+			Text{Words: "10:30"},
+		},
+	} {
+		expr, err := Parse(query, dp)
+		require.NoError(t, err, query)
+		assert.Equal(t, expected, expr, query)
+		// the expressions format back to the queries they parse from
+		again, err := Parse(expr.String(), dp)
+		require.NoError(t, err, expr.String())
+		assert.Equal(t, expr, again, expr.String())
+	}
+
+	for query, msg := range map[string]string{
+		"size:large":      `unknown field "size"`,
+		"tag:":            "missing value of tag",
+		"tag<work":        "tag can't be compared with <",
+		"priority:soon":   `invalid priority "soon"`,
+		"due<someday":     "invalid due date",
+		`"exact phrase`:   "unterminated quote",
+		"(milk OR bread":  "unclosed (",
+		"milk)":           "unexpected )",
+		"OR milk":         "missing term before OR",
+		"milk OR":         "missing term after OR",
+		"milk - bread ()": "empty ()",
+		`tag::"work"`:     "invalid value of tag",
+	} {
+		_, err := Parse(query, dp)
+		var syntaxErr SyntaxError
+		require.ErrorAs(t, err, &syntaxErr, query)
+		assert.Contains(t, syntaxErr.Msg, msg, query)
+	}
+}
+
+func TestOpHolds(t *testing.T) {
+	assert.True(t, Eq.Holds(0))
+	assert.False(t, Eq.Holds(1))
+	assert.True(t, Lt.Holds(-1))
+	assert.False(t, Lt.Holds(0))
+	assert.True(t, Le.Holds(0))
+	assert.True(t, Gt.Holds(1))
+	assert.False(t, Ge.Holds(-1))
+}
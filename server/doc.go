@@ -0,0 +1,3 @@
+// Package server runs the HTTP server of the REST API, and shuts it down gracefully,
+// letting the requests in flight complete
+package server
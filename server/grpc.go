@@ -0,0 +1,96 @@
+package server
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+// GRPC serves a gRPC server until its context is done
+type GRPC struct {
+	srv  *grpc.Server
+	addr string
+	// ShutdownTimeout is how long Run waits for the calls in flight on shutdown, before closing
+	// their connections. The streams only end with the timeout, unless the clients close them or
+	// the server ends them with EndStreams.
+	ShutdownTimeout time.Duration
+}
+
+// NewGRPC creates the server of the gRPC services registered with srv, listening on the address
+// in the format `[host]:port`
+func NewGRPC(addr string, srv *grpc.Server) *GRPC {
+	return &GRPC{
+		srv:             srv,
+		addr:            addr,
+		ShutdownTimeout: DefaultShutdownTimeout,
+	}
+}
+
+// Run listens on the address of the server and serves the calls until the context is done,
+// then shuts down gracefully. Returns nil if the shutdown completed in time.
+func (s *GRPC) Run(ctx context.Context) error {
+	ln, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(ctx, ln)
+}
+
+// Serve is like Run, but accepts the connections on the given listener, which it closes
+func (s *GRPC) Serve(ctx context.Context, ln net.Listener) error {
+	errc := make(chan error, 1)
+	go func() {
+		errc <- s.srv.Serve(ln)
+	}()
+	slog.Info("server: serving gRPC", "address", ln.Addr())
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+
+	slog.Info("server: shutting down gRPC, waiting for the calls in flight", "timeout", s.ShutdownTimeout)
+	stopped := make(chan struct{})
+	go func() {
+		s.srv.GracefulStop()
+		close(stopped)
+	}()
+	var err error
+	select {
+	case <-stopped:
+	case <-time.After(s.ShutdownTimeout):
+		slog.Warn("server: gRPC shutdown timed out, closing the connections")
+		s.srv.Stop()
+		err = context.DeadlineExceeded
+	}
+	<-errc
+	slog.Info("server: gRPC stopped")
+	return err
+}
+
+// EndStreams returns the interceptor of the streaming calls canceling their context once ctx is
+// done, i.e. once the GRPC shuts down, for the long-lived streams like the watches to end instead
+// of holding the shutdown until its timeout
+func EndStreams(ctx context.Context) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		sctx, cancel := context.WithCancel(ss.Context())
+		defer cancel()
+		stop := context.AfterFunc(ctx, cancel)
+		defer stop()
+		return handler(srv, endingStream{ServerStream: ss, ctx: sctx})
+	}
+}
+
+// endingStream is a grpc.ServerStream whose context is canceled on shutdown, see EndStreams
+type endingStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (es endingStream) Context() context.Context {
+	return es.ctx
+}
@@ -0,0 +1,47 @@
+package server
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+)
+
+// fakeStream is a grpc.ServerStream with a context, and nothing else
+type fakeStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (fs fakeStream) Context() context.Context {
+	return fs.ctx
+}
+
+func TestEndStreams(t *testing.T) {
+	ctx, shutdown := context.WithCancel(context.Background())
+	interceptor := EndStreams(ctx)
+	info := &grpc.StreamServerInfo{FullMethod: "/todo.v1.TodoService/WatchTodos", IsServerStream: true}
+
+	started := make(chan struct{})
+	ended := make(chan error, 1)
+	go func() {
+		ended <- interceptor(nil, fakeStream{ctx: context.Background()}, info, func(srv any, ss grpc.ServerStream) error {
+			close(started)
+			<-ss.Context().Done()
+			return ss.Context().Err()
+		})
+	}()
+	<-started
+	shutdown()
+	assert.ErrorIs(t, <-ended, context.Canceled)
+
+	// the streams closed by the clients end as usual
+	client, hangUp := context.WithCancel(context.Background())
+	hangUp()
+	err := EndStreams(context.Background())(nil, fakeStream{ctx: client}, info, func(srv any, ss grpc.ServerStream) error {
+		<-ss.Context().Done()
+		return ss.Context().Err()
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+}
@@ -0,0 +1,102 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	apiv1 "github.com/gotestbootcamp/go-todo-app/api/v1"
+)
+
+// DefaultHealthTimeout is how long the health probes wait by default for the store to answer
+const DefaultHealthTimeout = 2 * time.Second
+
+// Health answers the probes of the orchestrators, see Handler: whether the process is alive,
+// whether its store is usable, and whether it's ready to serve the requests, i.e. its store is
+// usable and it's not shutting down
+type Health struct {
+	// Backend is the kind of the store backend, e.g. "sqlite"
+	Backend string
+	// SchemaVersion is the version of the schema of the stored objects
+	SchemaVersion int
+	// Timeout is how long the probes wait for the store to answer
+	Timeout time.Duration
+
+	ping     func(context.Context) error
+	started  time.Time
+	draining atomic.Bool
+}
+
+// NewHealth creates the health of the server whose store is checked by ping, e.g. store.Ping
+func NewHealth(backend string, schemaVersion int, ping func(context.Context) error) *Health {
+	return &Health{
+		Backend:       backend,
+		SchemaVersion: schemaVersion,
+		Timeout:       DefaultHealthTimeout,
+		ping:          ping,
+		started:       time.Now(),
+	}
+}
+
+// Drain makes the server not ready, for the load balancers to stop sending it the requests
+// while it shuts down. The Server whose Health it is drains on shutdown.
+func (h *Health) Drain() {
+	if !h.draining.Swap(true) {
+		slog.Info("server: draining, not ready anymore")
+	}
+}
+
+// Handler returns the handler of the probes, all answering a apiv1.Health:
+//   - /livez answers 200 as long as the process serves the requests, without checking the store
+//   - /healthz answers 200 if the store is usable, and 503 otherwise
+//   - /readyz is like /healthz, but answers 503 as well once the server drains
+func (h *Health) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /livez", func(w http.ResponseWriter, r *http.Request) {
+		h.send(w, http.StatusOK, "ok", nil)
+	})
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		h.check(w, r)
+	})
+	mux.HandleFunc("GET /readyz", func(w http.ResponseWriter, r *http.Request) {
+		if h.draining.Load() {
+			h.send(w, http.StatusServiceUnavailable, "draining", nil)
+			return
+		}
+		h.check(w, r)
+	})
+	return mux
+}
+
+// check answers whether the store is usable
+func (h *Health) check(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), h.Timeout)
+	defer cancel()
+	if err := h.ping(ctx); err != nil {
+		slog.ErrorContext(r.Context(), "server: store unavailable", "error", err)
+		h.send(w, http.StatusServiceUnavailable, "unavailable", err)
+		return
+	}
+	h.send(w, http.StatusOK, "ok", nil)
+}
+
+func (h *Health) send(w http.ResponseWriter, code int, status string, err error) {
+	resp := apiv1.Health{
+		Status:        status,
+		Backend:       h.Backend,
+		SchemaVersion: h.SchemaVersion,
+		Uptime:        time.Since(h.started).Truncate(time.Second).String(),
+	}
+	if err != nil {
+		resp.Error = err.Error()
+	}
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		panic(err)
+	}
+}
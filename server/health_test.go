@@ -0,0 +1,97 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	apiv1 "github.com/gotestbootcamp/go-todo-app/api/v1"
+)
+
+func probe(t *testing.T, handler http.Handler, path string) (int, apiv1.Health) {
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+	var res apiv1.Health
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&res))
+	return w.Code, res
+}
+
+func TestHealth(t *testing.T) {
+	var storeErr error
+	health := NewHealth("sqlite", 8, func(ctx context.Context) error { return storeErr })
+	handler := health.Handler()
+
+	for _, path := range []string{"/livez", "/healthz", "/readyz"} {
+		code, res := probe(t, handler, path)
+		assert.Equal(t, http.StatusOK, code, path)
+		assert.Equal(t, apiv1.Health{Status: "ok", Backend: "sqlite", SchemaVersion: 8, Uptime: "0s"}, res, path)
+	}
+
+	storeErr = errors.New("database is locked")
+	code, res := probe(t, handler, "/healthz")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "unavailable", res.Status)
+	assert.Equal(t, "database is locked", res.Error)
+	code, _ = probe(t, handler, "/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	code, _ = probe(t, handler, "/livez")
+	assert.Equal(t, http.StatusOK, code, "the process is alive")
+
+	storeErr = nil
+	health.Drain()
+	code, res = probe(t, handler, "/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "draining", res.Status)
+	code, _ = probe(t, handler, "/healthz")
+	assert.Equal(t, http.StatusOK, code)
+}
+
+func TestHealthTimeout(t *testing.T) {
+	health := NewHealth("redis", 8, func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	health.Timeout = 10 * time.Millisecond
+	code, res := probe(t, health.Handler(), "/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, context.DeadlineExceeded.Error(), res.Error)
+}
+
+func TestServeDrains(t *testing.T) {
+	health := NewHealth("memory", 8, func(ctx context.Context) error { return nil })
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	srv := New(ln.Addr().String(), health.Handler())
+	srv.Health = health
+	srv.DrainDelay = 200 * time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() {
+		served <- srv.Serve(ctx, ln)
+	}()
+	url := "http://" + ln.Addr().String() + "/readyz"
+	resp, err := http.Get(url)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	cancel()
+	assert.Eventually(t, func() bool {
+		resp, err := http.Get(url)
+		if err != nil {
+			return false
+		}
+		resp.Body.Close()
+		return resp.StatusCode == http.StatusServiceUnavailable
+	}, time.Second, 10*time.Millisecond, "the server answers while draining")
+	assert.NoError(t, <-served)
+}
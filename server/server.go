@@ -0,0 +1,112 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+)
+
+// DefaultShutdownTimeout is how long the server waits by default for the requests in flight on shutdown
+const DefaultShutdownTimeout = 10 * time.Second
+
+// stoppingKey is the key of the context values holding the channel closed on shutdown
+type stoppingKey struct{}
+
+// Stopping returns a channel closed when the Server serving the request with the given context
+// shuts down, for the long-lived requests like the event streams to end instead of holding the
+// shutdown until its timeout. The channel is nil, thus never closed, out of a Server.
+func Stopping(ctx context.Context) <-chan struct{} {
+	stopping, _ := ctx.Value(stoppingKey{}).(chan struct{})
+	return stopping
+}
+
+// Server serves an http.Handler until its context is done
+type Server struct {
+	srv *http.Server
+	// ShutdownTimeout is how long Run waits for the requests in flight on shutdown,
+	// before closing their connections
+	ShutdownTimeout time.Duration
+	// Health, if any, drains on shutdown, see Health.Drain
+	Health *Health
+	// DrainDelay is how long Run keeps serving the requests once its context is done, before
+	// shutting down, for the load balancers to notice the server is not ready anymore
+	DrainDelay time.Duration
+	// TLS serves the requests over HTTPS with the configuration, see LoadTLS, SelfSignedTLS and
+	// AutocertTLS. Nil serves them over plain HTTP.
+	TLS *tls.Config
+}
+
+// New creates the server of the handler, listening on the address in the format `[host]:port`
+func New(addr string, handler http.Handler) *Server {
+	return &Server{
+		srv: &http.Server{
+			Addr:              addr,
+			Handler:           handler,
+			ReadHeaderTimeout: 10 * time.Second,
+		},
+		ShutdownTimeout: DefaultShutdownTimeout,
+	}
+}
+
+// Run listens on the address of the server and serves the requests until the context is done,
+// then shuts down gracefully. Returns nil if the shutdown completed in time.
+func (s *Server) Run(ctx context.Context) error {
+	ln, err := net.Listen("tcp", s.srv.Addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(ctx, ln)
+}
+
+// Serve is like Run, but accepts the connections on the given listener, which it closes
+func (s *Server) Serve(ctx context.Context, ln net.Listener) error {
+	stopping := make(chan struct{})
+	base := context.WithValue(context.Background(), stoppingKey{}, stopping)
+	s.srv.BaseContext = func(net.Listener) context.Context { return base }
+	s.srv.RegisterOnShutdown(func() { close(stopping) })
+	errc := make(chan error, 1)
+	scheme := "http"
+	if s.TLS != nil {
+		scheme = "https"
+		s.srv.TLSConfig = s.TLS
+		go func() {
+			errc <- s.srv.ServeTLS(ln, "", "")
+		}()
+	} else {
+		go func() {
+			errc <- s.srv.Serve(ln)
+		}()
+	}
+	slog.Info("server: serving", "scheme", scheme, "address", ln.Addr())
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+
+	if s.Health != nil {
+		s.Health.Drain()
+	}
+	if s.DrainDelay > 0 {
+		slog.Info("server: waiting for the load balancers before shutting down", "delay", s.DrainDelay)
+		time.Sleep(s.DrainDelay)
+	}
+	slog.Info("server: shutting down, waiting for the requests in flight", "timeout", s.ShutdownTimeout)
+	sctx, cancel := context.WithTimeout(context.Background(), s.ShutdownTimeout)
+	defer cancel()
+	err := s.srv.Shutdown(sctx)
+	if errors.Is(err, context.DeadlineExceeded) {
+		slog.Warn("server: shutdown timed out, closing the connections")
+		s.srv.Close()
+	}
+	if serr := <-errc; !errors.Is(serr, http.ErrServerClosed) && err == nil {
+		err = serr
+	}
+	slog.Info("server: stopped")
+	return err
+}
@@ -0,0 +1,75 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServeTLS(t *testing.T) {
+	cfg, err := SelfSignedTLS()
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(cfg.Certificates[0].Certificate[0])
+	require.NoError(t, err)
+	assert.Equal(t, []string{"localhost"}, cert.DNSNames)
+	assert.Len(t, cert.IPAddresses, 2)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	srv := New(ln.Addr().String(), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.Proto)
+	}))
+	srv.TLS = cfg
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() {
+		served <- srv.Serve(ctx, ln)
+	}()
+	defer func() {
+		cancel()
+		assert.NoError(t, <-served)
+	}()
+
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	client := &http.Client{Transport: &http.Transport{
+		TLSClientConfig:   &tls.Config{RootCAs: pool},
+		ForceAttemptHTTP2: true,
+	}}
+	resp, err := client.Get("https://" + ln.Addr().String())
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "HTTP/2.0", string(body))
+
+	_, err = http.Get("https://" + ln.Addr().String())
+	assert.Error(t, err, "the certificate is not trusted")
+}
+
+func TestRedirectHTTPS(t *testing.T) {
+	for _, tc := range []struct {
+		addr, host, want string
+	}{
+		{":443", "todo.example.com", "https://todo.example.com/todos?status=pending"},
+		{":8443", "todo.example.com:8080", "https://todo.example.com:8443/todos?status=pending"},
+		{"localhost:8443", "[::1]", "https://[::1]:8443/todos?status=pending"},
+		{":443", "[::1]:80", "https://[::1]/todos?status=pending"},
+	} {
+		req := httptest.NewRequest("POST", "/todos?status=pending", strings.NewReader("{}"))
+		req.Host = tc.host
+		w := httptest.NewRecorder()
+		RedirectHTTPS(tc.addr).ServeHTTP(w, req)
+		assert.Equal(t, http.StatusPermanentRedirect, w.Code, tc.host)
+		assert.Equal(t, tc.want, w.Header().Get("Location"), tc.host)
+	}
+}
@@ -0,0 +1,22 @@
+package store
+
+import "strings"
+
+// ArchivePrefix marks the IDs of the archived items. Unlike the metadata, they are objects,
+// stored along with the others, but kept out of the default listings of the application.
+const ArchivePrefix = ".archive/"
+
+// ArchiveID returns the ID of the item with the given ID once archived
+func ArchiveID(id ID) ID {
+	return ID(ArchivePrefix + string(id))
+}
+
+// IsArchived returns true if the given ID belongs to an archived item
+func IsArchived(id ID) bool {
+	return strings.HasPrefix(string(id), ArchivePrefix)
+}
+
+// UnarchiveID returns the ID the archived item with the given ID had before archiving
+func UnarchiveID(id ID) ID {
+	return ID(strings.TrimPrefix(string(id), ArchivePrefix))
+}
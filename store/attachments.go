@@ -0,0 +1,241 @@
+package store
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// attachmentPrefix marks the IDs of the attachments in the inner storage
+const attachmentPrefix = ".attachments/"
+
+var _ Storage = &Attachments{}
+var _ Compacter = &Attachments{}
+
+// ErrTooLarge is returned when a attachment exceeds the maximum size allowed
+var ErrTooLarge = errors.New("attachment too large")
+
+// AttachmentInfo describes a attachment
+type AttachmentInfo struct {
+	Name string
+	Size int64
+}
+
+// Attachments is a Storage decorator which lets files, e.g. screenshots or PDFs, be attached
+// to the items. The attachments are stored as blobs within the inner storage, and are hidden
+// to all the Storage operations; use AttachFile, ListAttachments, OpenAttachment and
+// DeleteAttachment to manage them.
+// Deleting a item doesn't delete its attachments, so they are not lost if the item is
+// restored from the trash: Compact removes the attachments of the items gone for good.
+// To use it along with Trash, decorate the Attachments with the Trash.
+type Attachments struct {
+	inner Storage
+	// MaxSize is the maximum size, in bytes, of a attachment. Zero means unlimited.
+	MaxSize int64
+}
+
+// NewAttachments creates a new Attachments decorating the given storage
+func NewAttachments(inner Storage) *Attachments {
+	return &Attachments{inner: inner}
+}
+
+func attachmentID(itemID ID, name string) ID {
+	return ID(attachmentPrefix + string(itemID) + "/" + name)
+}
+
+func isAttachmentID(id ID) bool {
+	return strings.HasPrefix(string(id), attachmentPrefix)
+}
+
+// splitAttachmentID returns the item ID and the attachment name the given attachment ID is made of
+func splitAttachmentID(id ID) (ID, string) {
+	rest := strings.TrimPrefix(string(id), attachmentPrefix)
+	sep := strings.LastIndex(rest, "/")
+	return ID(rest[:sep]), rest[sep+1:]
+}
+
+// validateAttachmentName checks the given attachment name is usable as a file name
+func validateAttachmentName(name string) error {
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, "/\\\x00") {
+		return fmt.Errorf("invalid attachment name %q", name)
+	}
+	return nil
+}
+
+// AttachFile attaches to the item the content read from r, with the given name,
+// which must be a plain file name. An existing attachment with the same name is replaced.
+// Fails with ErrNotFound if the item doesn't exist, and with ErrTooLarge if the content
+// exceeds MaxSize.
+func (at *Attachments) AttachFile(itemID ID, name string, r io.Reader) error {
+	if err := validateAttachmentName(name); err != nil {
+		return err
+	}
+	if isAttachmentID(itemID) {
+		return ErrNotFound{ID: itemID}
+	}
+	if _, err := at.inner.Load(itemID); err != nil {
+		return err
+	}
+	if at.MaxSize > 0 {
+		r = io.LimitReader(r, at.MaxSize+1)
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	if at.MaxSize > 0 && int64(len(data)) > at.MaxSize {
+		return ErrTooLarge
+	}
+
+	id := attachmentID(itemID, name)
+	err = at.inner.Save(id, data)
+	var notFound ErrNotFound
+	if errors.As(err, &notFound) {
+		err = at.inner.Create(id, data)
+	}
+	return err
+}
+
+// ListAttachments returns the attachments of the item, sorted by name
+func (at *Attachments) ListAttachments(itemID ID) ([]AttachmentInfo, error) {
+	prefix := string(attachmentID(itemID, ""))
+	res := []AttachmentInfo{}
+	err := Walk(at.inner, func(item Item) error {
+		name, found := strings.CutPrefix(string(item.ID), prefix)
+		// skip the attachments of the items whose ID extends this one, e.g. "1/2" for "1"
+		if found && !strings.Contains(name, "/") {
+			res = append(res, AttachmentInfo{Name: name, Size: int64(len(item.Blob))})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Name < res[j].Name
+	})
+	return res, nil
+}
+
+// OpenAttachment returns the content of the named attachment of the item.
+// Fails with ErrNotFound if there is no such attachment.
+func (at *Attachments) OpenAttachment(itemID ID, name string) (io.ReadCloser, error) {
+	if err := validateAttachmentName(name); err != nil {
+		return nil, err
+	}
+	blob, err := at.inner.Load(attachmentID(itemID, name))
+	if err != nil {
+		return nil, err
+	}
+	return io.NopCloser(bytes.NewReader(blob)), nil
+}
+
+// DeleteAttachment removes the named attachment of the item.
+// Fails with ErrNotFound if there is no such attachment.
+func (at *Attachments) DeleteAttachment(itemID ID, name string) error {
+	if err := validateAttachmentName(name); err != nil {
+		return err
+	}
+	return at.inner.Delete(attachmentID(itemID, name))
+}
+
+// Compact removes the attachments of the items which don't exist anymore, neither
+// in the inner storage nor in its trash, then compacts the inner storage.
+func (at *Attachments) Compact() (int, error) {
+	var orphans []ID
+	live := map[ID]bool{}
+	// collect first: backends may not allow reads while walking
+	var ids []ID
+	err := Walk(at.inner, func(item Item) error {
+		if isAttachmentID(item.ID) {
+			ids = append(ids, item.ID)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	for _, id := range ids {
+		itemID, _ := splitAttachmentID(id)
+		found, checked := live[itemID]
+		if !checked {
+			found, err = at.itemExists(itemID)
+			if err != nil {
+				return 0, err
+			}
+			live[itemID] = found
+		}
+		if !found {
+			orphans = append(orphans, id)
+		}
+	}
+	if err := DeleteAll(at.inner, orphans); err != nil {
+		return 0, err
+	}
+	count, err := Compact(at.inner)
+	return len(orphans) + count, err
+}
+
+func (at *Attachments) itemExists(itemID ID) (bool, error) {
+	var notFound ErrNotFound
+	for _, id := range []ID{itemID, trashID(itemID)} {
+		_, err := at.inner.Load(id)
+		if err == nil {
+			return true, nil
+		}
+		if !errors.As(err, &notFound) {
+			return false, err
+		}
+	}
+	return false, nil
+}
+
+func (at *Attachments) Close() error {
+	return at.inner.Close()
+}
+
+func (at *Attachments) Create(objectID ID, data Blob) error {
+	if isAttachmentID(objectID) {
+		return ErrNotFound{ID: objectID}
+	}
+	return at.inner.Create(objectID, data)
+}
+
+func (at *Attachments) LoadAll() ([]Item, error) {
+	items, err := at.inner.LoadAll()
+	if err != nil {
+		return nil, err
+	}
+	res := make([]Item, 0, len(items))
+	for _, item := range items {
+		if isAttachmentID(item.ID) {
+			continue
+		}
+		res = append(res, item)
+	}
+	return res, nil
+}
+
+func (at *Attachments) Load(objectID ID) (Blob, error) {
+	if isAttachmentID(objectID) {
+		return nil, ErrNotFound{ID: objectID}
+	}
+	return at.inner.Load(objectID)
+}
+
+func (at *Attachments) Save(objectID ID, blob Blob) error {
+	if isAttachmentID(objectID) {
+		return ErrNotFound{ID: objectID}
+	}
+	return at.inner.Save(objectID, blob)
+}
+
+func (at *Attachments) Delete(objectID ID) error {
+	if isAttachmentID(objectID) {
+		return ErrNotFound{ID: objectID}
+	}
+	return at.inner.Delete(objectID)
+}
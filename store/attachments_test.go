@@ -0,0 +1,99 @@
+package store
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readAttachment(t *testing.T, at *Attachments, itemID ID, name string) string {
+	rc, err := at.OpenAttachment(itemID, name)
+	require.NoError(t, err)
+	defer rc.Close()
+	data, err := io.ReadAll(rc)
+	require.NoError(t, err)
+	return string(data)
+}
+
+func TestAttachments(t *testing.T) {
+	for name, inner := range batchTestStorages(t) {
+		t.Run(name, func(t *testing.T) {
+			at := NewAttachments(inner)
+			require.NoError(t, at.Create("1", Blob("foo")))
+			require.NoError(t, at.Create("1/2", Blob("bar")))
+
+			assert.ErrorIs(t, at.AttachFile("3", "shot.png", strings.NewReader("png")), ErrNotFound{ID: "3"})
+			assert.Error(t, at.AttachFile("1", "../shot.png", strings.NewReader("png")))
+			require.NoError(t, at.AttachFile("1", "shot.png", strings.NewReader("png")))
+			require.NoError(t, at.AttachFile("1", "spec.pdf", strings.NewReader("pdf")))
+			require.NoError(t, at.AttachFile("1/2", "other.txt", strings.NewReader("other")))
+			require.NoError(t, at.AttachFile("1", "shot.png", strings.NewReader("new png")))
+
+			infos, err := at.ListAttachments("1")
+			require.NoError(t, err)
+			assert.Equal(t, []AttachmentInfo{{Name: "shot.png", Size: 7}, {Name: "spec.pdf", Size: 3}}, infos)
+			assert.Equal(t, "new png", readAttachment(t, at, "1", "shot.png"))
+			_, err = at.OpenAttachment("1", "other.txt")
+			assert.ErrorIs(t, err, ErrNotFound{ID: attachmentID("1", "other.txt")})
+
+			// the attachments are hidden to the Storage operations
+			items, err := at.LoadAll()
+			require.NoError(t, err)
+			assert.ElementsMatch(t, []Item{{ID: "1", Blob: Blob("foo")}, {ID: "1/2", Blob: Blob("bar")}}, items)
+			_, err = at.Load(attachmentID("1", "shot.png"))
+			assert.ErrorIs(t, err, ErrNotFound{ID: attachmentID("1", "shot.png")})
+
+			require.NoError(t, at.DeleteAttachment("1", "spec.pdf"))
+			infos, err = at.ListAttachments("1")
+			require.NoError(t, err)
+			assert.Equal(t, []AttachmentInfo{{Name: "shot.png", Size: 7}}, infos)
+		})
+	}
+}
+
+func TestAttachmentsMaxSize(t *testing.T) {
+	mem, err := NewMemory()
+	require.NoError(t, err)
+	at := NewAttachments(mem)
+	at.MaxSize = 4
+	require.NoError(t, at.Create("1", Blob("foo")))
+
+	require.NoError(t, at.AttachFile("1", "small.txt", strings.NewReader("1234")))
+	assert.ErrorIs(t, at.AttachFile("1", "big.txt", strings.NewReader("12345")), ErrTooLarge)
+}
+
+func TestAttachmentsCompact(t *testing.T) {
+	mem, err := NewMemory()
+	require.NoError(t, err)
+	at := NewAttachments(mem)
+	tr := NewTrash(at)
+	now := time.Date(2024, time.November, 11, 10, 0, 0, 0, time.UTC)
+	tr.Now = func() time.Time { return now }
+	for _, id := range []ID{"1", "2", "3"} {
+		require.NoError(t, tr.Create(id, Blob("foo")))
+		require.NoError(t, at.AttachFile(id, "notes.txt", strings.NewReader("notes")))
+	}
+	// gone for good
+	require.NoError(t, tr.Delete("3"))
+	now = now.Add(time.Hour)
+	// trashed, can be restored along with its attachments
+	require.NoError(t, tr.Delete("2"))
+	now = now.Add(time.Hour)
+	purged, err := tr.PurgeTrash(90 * time.Minute)
+	require.NoError(t, err)
+	require.Equal(t, 1, purged)
+
+	count, err := Compact(tr)
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	for id, expected := range map[ID]int{"1": 1, "2": 1, "3": 0} {
+		infos, err := at.ListAttachments(id)
+		require.NoError(t, err)
+		assert.Len(t, infos, expected, id)
+	}
+}
@@ -0,0 +1,54 @@
+package store
+
+// Batcher is implemented by the storages which can natively process many items at once.
+// Batch operations are all-or-nothing: either all the items are processed, or none is.
+// Use the CreateMany, SaveAll and DeleteAll functions to work with any Storage.
+type Batcher interface {
+	CreateMany([]Item) error
+	SaveAll([]Item) error
+	DeleteAll([]ID) error
+}
+
+// CreateMany creates all the given items in the storage.
+// If the storage is a Batcher, the operation is atomic; otherwise the items are
+// created one by one, stopping at the first failure, so the items created before
+// the failure are left in the storage.
+func CreateMany(st Storage, items []Item) error {
+	if bt, ok := st.(Batcher); ok {
+		return bt.CreateMany(items)
+	}
+	for _, item := range items {
+		if err := st.Create(item.ID, item.Blob); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SaveAll updates all the given items in the storage.
+// Atomicity guarantees are the same as CreateMany.
+func SaveAll(st Storage, items []Item) error {
+	if bt, ok := st.(Batcher); ok {
+		return bt.SaveAll(items)
+	}
+	for _, item := range items {
+		if err := st.Save(item.ID, item.Blob); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeleteAll removes all the items with the given IDs from the storage.
+// Atomicity guarantees are the same as CreateMany.
+func DeleteAll(st Storage, ids []ID) error {
+	if bt, ok := st.(Batcher); ok {
+		return bt.DeleteAll(ids)
+	}
+	for _, id := range ids {
+		if err := st.Delete(id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
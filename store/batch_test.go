@@ -0,0 +1,80 @@
+package store
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func batchTestStorages(t *testing.T) map[string]Storage {
+	mem, err := NewMemory()
+	require.NoError(t, err)
+	sl, err := NewSQLite(filepath.Join(t.TempDir(), "todo.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { sl.Close() })
+	bl, err := NewBolt(filepath.Join(t.TempDir(), "todo.bolt"))
+	require.NoError(t, err)
+	t.Cleanup(func() { bl.Close() })
+	return map[string]Storage{
+		"memory": mem,
+		"sqlite": sl,
+		"bolt":   bl,
+	}
+}
+
+func TestBatchOperations(t *testing.T) {
+	for name, st := range batchTestStorages(t) {
+		t.Run(name, func(t *testing.T) {
+			err := CreateMany(st, []Item{
+				{ID: "1", Blob: Blob("foo")},
+				{ID: "2", Blob: Blob("bar")},
+			})
+			assert.NoError(t, err)
+
+			err = SaveAll(st, []Item{
+				{ID: "1", Blob: Blob("fizz")},
+				{ID: "2", Blob: Blob("buzz")},
+			})
+			assert.NoError(t, err)
+			items, err := st.LoadAll()
+			assert.NoError(t, err)
+			assert.Equal(t, []Item{{ID: "1", Blob: Blob("fizz")}, {ID: "2", Blob: Blob("buzz")}}, items)
+
+			assert.NoError(t, DeleteAll(st, []ID{"1", "2"}))
+			items, err = st.LoadAll()
+			assert.NoError(t, err)
+			assert.Empty(t, items)
+		})
+	}
+}
+
+func TestBatchOperationsAreAtomic(t *testing.T) {
+	for name, st := range batchTestStorages(t) {
+		t.Run(name, func(t *testing.T) {
+			require.NoError(t, st.Create("1", Blob("foo")))
+
+			// "1" already exists, so "0" must not be created
+			err := CreateMany(st, []Item{
+				{ID: "0", Blob: Blob("bar")},
+				{ID: "1", Blob: Blob("bar")},
+			})
+			assert.Error(t, err)
+
+			// "3" does not exist, so "1" must not be updated
+			err = SaveAll(st, []Item{
+				{ID: "1", Blob: Blob("bar")},
+				{ID: "3", Blob: Blob("bar")},
+			})
+			assert.ErrorIs(t, err, ErrNotFound{ID: "3"})
+
+			err = DeleteAll(st, []ID{"1", "3"})
+			assert.ErrorIs(t, err, ErrNotFound{ID: "3"})
+
+			items, err := st.LoadAll()
+			assert.NoError(t, err)
+			assert.Equal(t, []Item{{ID: "1", Blob: Blob("foo")}}, items)
+		})
+	}
+}
@@ -0,0 +1,514 @@
+package store
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var _ Storage = &Bolt{}
+var _ StorageContext = &Bolt{}
+var _ Batcher = &Bolt{}
+var _ Pager = &Bolt{}
+var _ Versioner = &Bolt{}
+var _ Transactioner = &Bolt{}
+var _ Stater = &Bolt{}
+var _ orphanFinder = &Bolt{}
+var _ importer = &Bolt{}
+var _ ChangeTracker = &Bolt{}
+
+// boltItemsBucket is the bucket holding the todo blobs. Each collection of objects
+// gets its own bucket in the database file.
+var boltItemsBucket = []byte("items")
+
+// boltRevisionsBucket holds the revision of each item, keyed by the item ID
+var boltRevisionsBucket = []byte("revisions")
+
+// boltMetaBucket holds the JSON-encoded boltMeta of each item, keyed by the item ID.
+// Its sequence is the last change sequence assigned.
+var boltMetaBucket = []byte("meta")
+
+// boltTombstonesBucket holds the change sequence of the deleted items, keyed by the item ID
+var boltTombstonesBucket = []byte("tombstones")
+
+type boltMeta struct {
+	Created  time.Time `json:"created"`
+	Updated  time.Time `json:"updated"`
+	Checksum string    `json:"checksum"`
+	// Seq is the change sequence of the last update, zero if unknown
+	Seq uint64 `json:"seq,omitempty"`
+}
+
+// Bolt is a Storage backed by a single bbolt transactional database file
+type Bolt struct {
+	db *bolt.DB
+}
+
+// NewBolt opens, or creates if missing, the bbolt database at the given path.
+// bbolt allows only one process at time to open a database file; NewBolt waits
+// at most one second for the file lock before giving up.
+// If succesfull, returns the store; otherwise returns nil and the error describing the failure.
+func NewBolt(path string) (*Bolt, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 1 * time.Second})
+	if err != nil {
+		return nil, err
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{boltItemsBucket, boltRevisionsBucket, boltMetaBucket, boltTombstonesBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &Bolt{db: db}, nil
+}
+
+func (bl *Bolt) Close() error {
+	return bl.db.Close()
+}
+
+func (bl *Bolt) Create(objectID ID, data Blob) error {
+	return bl.CreateCtx(context.Background(), objectID, data)
+}
+
+func (bl *Bolt) CreateCtx(ctx context.Context, objectID ID, data Blob) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return bl.db.Update(func(tx *bolt.Tx) error {
+		return boltCreate(tx, objectID, data)
+	})
+}
+
+func boltCreate(tx *bolt.Tx, objectID ID, data Blob) error {
+	bucket := tx.Bucket(boltItemsBucket)
+	if bucket.Get([]byte(objectID)) != nil {
+		return fmt.Errorf("item with id %v already exists", objectID)
+	}
+	return boltPut(tx, objectID, data, 1)
+}
+
+func (bl *Bolt) LoadAll() ([]Item, error) {
+	return bl.LoadAllCtx(context.Background())
+}
+
+func (bl *Bolt) LoadAllCtx(ctx context.Context) ([]Item, error) {
+	res := []Item{}
+	err := bl.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltItemsBucket).ForEach(func(k, v []byte) error {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			// data returned by bolt is only valid within the transaction
+			res = append(res, Item{ID: ID(k), Blob: cloneBlob(v)})
+			return nil
+		})
+	})
+	return res, err
+}
+
+func (bl *Bolt) LoadPage(offset, limit int) ([]Item, error) {
+	res := []Item{}
+	err := bl.db.View(func(tx *bolt.Tx) error {
+		cur := tx.Bucket(boltItemsBucket).Cursor()
+		k, v := cur.First()
+		for skip := 0; k != nil && skip < offset; skip++ {
+			k, v = cur.Next()
+		}
+		for ; k != nil && (limit <= 0 || len(res) < limit); k, v = cur.Next() {
+			res = append(res, Item{ID: ID(k), Blob: cloneBlob(v)})
+		}
+		return nil
+	})
+	return res, err
+}
+
+func (bl *Bolt) Walk(fn func(Item) error) error {
+	return bl.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltItemsBucket).ForEach(func(k, v []byte) error {
+			return fn(Item{ID: ID(k), Blob: cloneBlob(v)})
+		})
+	})
+}
+
+func (bl *Bolt) Load(objectID ID) (Blob, error) {
+	return bl.LoadCtx(context.Background(), objectID)
+}
+
+func (bl *Bolt) LoadCtx(ctx context.Context, objectID ID) (Blob, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	var blob Blob
+	err := bl.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(boltItemsBucket).Get([]byte(objectID))
+		if data == nil {
+			return ErrNotFound{ID: objectID}
+		}
+		blob = cloneBlob(data)
+		return nil
+	})
+	return blob, err
+}
+
+func (bl *Bolt) Save(objectID ID, blob Blob) error {
+	return bl.SaveCtx(context.Background(), objectID, blob)
+}
+
+func (bl *Bolt) SaveCtx(ctx context.Context, objectID ID, blob Blob) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return bl.db.Update(func(tx *bolt.Tx) error {
+		return boltSave(tx, objectID, blob)
+	})
+}
+
+func boltSave(tx *bolt.Tx, objectID ID, blob Blob) error {
+	bucket := tx.Bucket(boltItemsBucket)
+	if bucket.Get([]byte(objectID)) == nil {
+		return ErrNotFound{ID: objectID}
+	}
+	return boltPut(tx, objectID, blob, boltRevision(tx, objectID)+1)
+}
+
+func (bl *Bolt) Delete(objectID ID) error {
+	return bl.DeleteCtx(context.Background(), objectID)
+}
+
+func (bl *Bolt) DeleteCtx(ctx context.Context, objectID ID) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return bl.db.Update(func(tx *bolt.Tx) error {
+		return boltDelete(tx, objectID)
+	})
+}
+
+func boltDelete(tx *bolt.Tx, objectID ID) error {
+	bucket := tx.Bucket(boltItemsBucket)
+	if bucket.Get([]byte(objectID)) == nil {
+		return ErrNotFound{ID: objectID}
+	}
+	for _, name := range [][]byte{boltRevisionsBucket, boltMetaBucket} {
+		if err := tx.Bucket(name).Delete([]byte(objectID)); err != nil {
+			return err
+		}
+	}
+	seq, err := tx.Bucket(boltMetaBucket).NextSequence()
+	if err != nil {
+		return err
+	}
+	if err := tx.Bucket(boltTombstonesBucket).Put([]byte(objectID), boltUint64(seq)); err != nil {
+		return err
+	}
+	return bucket.Delete([]byte(objectID))
+}
+
+func boltUint64(val uint64) []byte {
+	data := make([]byte, 8)
+	binary.BigEndian.PutUint64(data, val)
+	return data
+}
+
+// boltPut stores the item blob, along with its revision and its metadata.
+// Revision 1 means a new item.
+func boltPut(tx *bolt.Tx, objectID ID, blob Blob, rev Revision) error {
+	now := time.Now()
+	meta := boltMetaOf(tx, objectID)
+	if rev == 1 {
+		meta.Created = now
+	}
+	meta.Updated = now
+	meta.Checksum = Checksum(blob)
+	if err := boltPutMeta(tx, objectID, meta); err != nil {
+		return err
+	}
+	if err := boltPutRevision(tx, objectID, rev); err != nil {
+		return err
+	}
+	return tx.Bucket(boltItemsBucket).Put([]byte(objectID), blob)
+}
+
+func (bl *Bolt) importItem(item Item, info ItemInfo) error {
+	info = importedMeta(info)
+	return bl.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(boltItemsBucket)
+		if bucket.Get([]byte(item.ID)) != nil {
+			return fmt.Errorf("item with id %v already exists", item.ID)
+		}
+		err := boltPutMeta(tx, item.ID, boltMeta{
+			Created:  info.Created,
+			Updated:  info.Updated,
+			Checksum: Checksum(item.Blob),
+		})
+		if err != nil {
+			return err
+		}
+		if err := boltPutRevision(tx, item.ID, info.Revision); err != nil {
+			return err
+		}
+		return bucket.Put([]byte(item.ID), item.Blob)
+	})
+}
+
+// boltPutMeta stores the metadata of a item, assigning it a new change sequence
+func boltPutMeta(tx *bolt.Tx, objectID ID, meta boltMeta) error {
+	bucket := tx.Bucket(boltMetaBucket)
+	seq, err := bucket.NextSequence()
+	if err != nil {
+		return err
+	}
+	meta.Seq = seq
+	data, err := json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+	if err := bucket.Put([]byte(objectID), data); err != nil {
+		return err
+	}
+	return tx.Bucket(boltTombstonesBucket).Delete([]byte(objectID))
+}
+
+func (bl *Bolt) Changes(since uint64) ([]Change, uint64, error) {
+	var res []Change
+	var last uint64
+	err := bl.db.View(func(tx *bolt.Tx) error {
+		last = tx.Bucket(boltMetaBucket).Sequence()
+		items := tx.Bucket(boltItemsBucket)
+		err := items.ForEach(func(k, _ []byte) error {
+			meta := boltMetaOf(tx, ID(k))
+			if since == 0 || meta.Seq > since {
+				res = append(res, Change{ID: ID(k), Seq: meta.Seq})
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		return tx.Bucket(boltTombstonesBucket).ForEach(func(k, v []byte) error {
+			if len(v) != 8 {
+				return nil
+			}
+			if seq := binary.BigEndian.Uint64(v); seq > since {
+				res = append(res, Change{ID: ID(k), Seq: seq, Deleted: true})
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	sortChanges(res)
+	return res, last, nil
+}
+
+// boltMetaOf returns the metadata of the given item. Items created before metadata
+// were tracked get zero metadata.
+func boltMetaOf(tx *bolt.Tx, objectID ID) boltMeta {
+	var meta boltMeta
+	data := tx.Bucket(boltMetaBucket).Get([]byte(objectID))
+	if data != nil {
+		// unparsable metadata are just as good as missing metadata
+		_ = json.Unmarshal(data, &meta)
+	}
+	return meta
+}
+
+func (bl *Bolt) Stat(objectID ID) (ItemInfo, error) {
+	var info ItemInfo
+	err := bl.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(boltItemsBucket).Get([]byte(objectID))
+		if data == nil {
+			return ErrNotFound{ID: objectID}
+		}
+		meta := boltMetaOf(tx, objectID)
+		info = ItemInfo{
+			ID:       objectID,
+			Size:     len(data),
+			Created:  meta.Created,
+			Updated:  meta.Updated,
+			Checksum: meta.Checksum,
+			Revision: boltRevision(tx, objectID),
+		}
+		if info.Checksum == "" {
+			info.Checksum = Checksum(data)
+		}
+		return nil
+	})
+	return info, err
+}
+
+// boltRevision returns the revision of the given item. Items created before
+// revisions were tracked are reported at revision 1.
+func boltRevision(tx *bolt.Tx, objectID ID) Revision {
+	data := tx.Bucket(boltRevisionsBucket).Get([]byte(objectID))
+	if len(data) != 8 {
+		return 1
+	}
+	return Revision(binary.BigEndian.Uint64(data))
+}
+
+func boltPutRevision(tx *bolt.Tx, objectID ID, rev Revision) error {
+	data := make([]byte, 8)
+	binary.BigEndian.PutUint64(data, uint64(rev))
+	return tx.Bucket(boltRevisionsBucket).Put([]byte(objectID), data)
+}
+
+func (bl *Bolt) LoadRev(objectID ID) (Blob, Revision, error) {
+	var blob Blob
+	var rev Revision
+	err := bl.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(boltItemsBucket).Get([]byte(objectID))
+		if data == nil {
+			return ErrNotFound{ID: objectID}
+		}
+		blob = cloneBlob(data)
+		rev = boltRevision(tx, objectID)
+		return nil
+	})
+	return blob, rev, err
+}
+
+func (bl *Bolt) SaveIf(objectID ID, blob Blob, expected Revision) (Revision, error) {
+	var rev Revision
+	err := bl.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(boltItemsBucket)
+		if bucket.Get([]byte(objectID)) == nil {
+			return ErrNotFound{ID: objectID}
+		}
+		rev = boltRevision(tx, objectID)
+		if rev != expected {
+			return ErrConflict{ID: objectID, Expected: expected, Actual: rev}
+		}
+		rev++
+		return boltPut(tx, objectID, blob, rev)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return rev, nil
+}
+
+// CreateMany, SaveAll and DeleteAll run in a single transaction, which is rolled
+// back entirely at the first failure.
+
+func (bl *Bolt) CreateMany(items []Item) error {
+	return bl.db.Update(func(tx *bolt.Tx) error {
+		for _, item := range items {
+			if err := boltCreate(tx, item.ID, item.Blob); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (bl *Bolt) SaveAll(items []Item) error {
+	return bl.db.Update(func(tx *bolt.Tx) error {
+		for _, item := range items {
+			if err := boltSave(tx, item.ID, item.Blob); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (bl *Bolt) DeleteAll(ids []ID) error {
+	return bl.db.Update(func(tx *bolt.Tx) error {
+		for _, id := range ids {
+			if err := boltDelete(tx, id); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Begin starts a read-write transaction. bbolt allows only one read-write transaction
+// at time, so other writers will block until this one is committed or rolled back.
+func (bl *Bolt) Begin() (Tx, error) {
+	tx, err := bl.db.Begin(true)
+	if err != nil {
+		return nil, err
+	}
+	return boltTx{tx: tx}, nil
+}
+
+type boltTx struct {
+	tx *bolt.Tx
+}
+
+// bbolt requires the values to stay unchanged until the transaction ends,
+// so blobs are copied in case the caller reuses them.
+
+func (btx boltTx) Create(objectID ID, data Blob) error {
+	return boltCreate(btx.tx, objectID, cloneBlob(data))
+}
+
+func (btx boltTx) Save(objectID ID, blob Blob) error {
+	return boltSave(btx.tx, objectID, cloneBlob(blob))
+}
+
+func (btx boltTx) Delete(objectID ID) error {
+	return boltDelete(btx.tx, objectID)
+}
+
+func (btx boltTx) Commit() error {
+	return btx.tx.Commit()
+}
+
+func (btx boltTx) Rollback() error {
+	return btx.tx.Rollback()
+}
+
+// findOrphans returns the IDs having revision or metadata entries, but no content
+func (bl *Bolt) findOrphans() ([]ID, error) {
+	var ids []ID
+	err := bl.db.View(func(tx *bolt.Tx) error {
+		items := tx.Bucket(boltItemsBucket)
+		seen := make(map[string]bool)
+		for _, name := range [][]byte{boltRevisionsBucket, boltMetaBucket} {
+			err := tx.Bucket(name).ForEach(func(k, _ []byte) error {
+				if items.Get(k) == nil && !seen[string(k)] {
+					seen[string(k)] = true
+					ids = append(ids, ID(k))
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return ids, err
+}
+
+// dropOrphans removes the revision and metadata entries of the given IDs,
+// unless their content was created meanwhile
+func (bl *Bolt) dropOrphans(ids []ID) error {
+	return bl.db.Update(func(tx *bolt.Tx) error {
+		for _, id := range ids {
+			if tx.Bucket(boltItemsBucket).Get([]byte(id)) != nil {
+				continue
+			}
+			for _, name := range [][]byte{boltRevisionsBucket, boltMetaBucket} {
+				if err := tx.Bucket(name).Delete([]byte(id)); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,48 @@
+package store
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBoltCreateSaveDeleteLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "todo.bolt")
+	st, err := NewBolt(path)
+	require.NoError(t, err)
+	defer st.Close()
+
+	assert.ErrorIs(t, st.Save("1", Blob("foobar")), ErrNotFound{ID: "1"})
+	assert.ErrorIs(t, st.Delete("1"), ErrNotFound{ID: "1"})
+
+	assert.NoError(t, st.Create("1", Blob("foobar")))
+	assert.Error(t, st.Create("1", Blob("foobar")))
+
+	assert.NoError(t, st.Save("1", Blob("fizzbuzz")))
+	blob, err := st.Load("1")
+	assert.NoError(t, err)
+	assert.Equal(t, "fizzbuzz", string(blob))
+
+	assert.NoError(t, st.Delete("1"))
+	_, err = st.Load("1")
+	assert.ErrorIs(t, err, ErrNotFound{ID: "1"})
+}
+
+func TestBoltReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "todo.bolt")
+	st, err := NewBolt(path)
+	require.NoError(t, err)
+	assert.NoError(t, st.Create("1", Blob("foo")))
+	assert.NoError(t, st.Create("2", Blob("bar")))
+	assert.NoError(t, st.Close())
+
+	st, err = NewBolt(path)
+	require.NoError(t, err)
+	defer st.Close()
+
+	items, err := st.LoadAll()
+	assert.NoError(t, err)
+	assert.Equal(t, []Item{{ID: "1", Blob: Blob("foo")}, {ID: "2", Blob: Blob("bar")}}, items)
+}
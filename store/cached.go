@@ -0,0 +1,165 @@
+package store
+
+import (
+	"container/list"
+	"sync"
+)
+
+var _ Storage = &Cached{}
+var _ Compacter = &Cached{}
+
+// CacheStats holds the counters of a Cached storage
+type CacheStats struct {
+	Hits   uint64
+	Misses uint64
+	// Size is the number of blobs currently cached
+	Size int
+}
+
+// Cached is a Storage decorator which keeps the most recently used blobs in memory,
+// so repeated loads of the same items don't hit the inner storage.
+// Changes performed bypassing the Cached, e.g. by another process sharing the same
+// backend, are not detected: use it only when the process owns the storage.
+type Cached struct {
+	inner Storage
+	size  int
+
+	lock    sync.Mutex
+	lru     *list.List // of cacheEntry, most recently used first
+	entries map[ID]*list.Element
+	hits    uint64
+	misses  uint64
+	// epoch is incremented on every invalidation, so loads racing with
+	// a change don't cache the stale content
+	epoch uint64
+}
+
+type cacheEntry struct {
+	id   ID
+	blob Blob
+}
+
+// NewCached creates a new Cached decorating the given storage, which
+// keeps at most size blobs in memory
+func NewCached(inner Storage, size int) *Cached {
+	return &Cached{
+		inner:   inner,
+		size:    size,
+		lru:     list.New(),
+		entries: make(map[ID]*list.Element),
+	}
+}
+
+// Stats returns the current cache counters
+func (ch *Cached) Stats() CacheStats {
+	ch.lock.Lock()
+	defer ch.lock.Unlock()
+	return CacheStats{
+		Hits:   ch.hits,
+		Misses: ch.misses,
+		Size:   ch.lru.Len(),
+	}
+}
+
+// get returns the cached blob, if any, and the current epoch
+func (ch *Cached) get(objectID ID) (Blob, uint64, bool) {
+	ch.lock.Lock()
+	defer ch.lock.Unlock()
+	elem, ok := ch.entries[objectID]
+	if !ok {
+		ch.misses++
+		return nil, ch.epoch, false
+	}
+	ch.hits++
+	ch.lru.MoveToFront(elem)
+	return cloneBlob(elem.Value.(cacheEntry).blob), ch.epoch, true
+}
+
+// put must be called with the lock held
+func (ch *Cached) put(objectID ID, blob Blob) {
+	if ch.size <= 0 {
+		return
+	}
+	entry := cacheEntry{id: objectID, blob: cloneBlob(blob)}
+	if elem, ok := ch.entries[objectID]; ok {
+		elem.Value = entry
+		ch.lru.MoveToFront(elem)
+		return
+	}
+	ch.entries[objectID] = ch.lru.PushFront(entry)
+	for ch.lru.Len() > ch.size {
+		oldest := ch.lru.Back()
+		delete(ch.entries, oldest.Value.(cacheEntry).id)
+		ch.lru.Remove(oldest)
+	}
+}
+
+func (ch *Cached) invalidate(objectID ID) {
+	ch.lock.Lock()
+	defer ch.lock.Unlock()
+	ch.epoch++
+	if elem, ok := ch.entries[objectID]; ok {
+		delete(ch.entries, objectID)
+		ch.lru.Remove(elem)
+	}
+}
+
+func (ch *Cached) Close() error {
+	return ch.inner.Close()
+}
+
+func (ch *Cached) Compact() (int, error) {
+	return Compact(ch.inner)
+}
+
+func (ch *Cached) Create(objectID ID, data Blob) error {
+	return ch.inner.Create(objectID, data)
+}
+
+// LoadAll always reads from the inner storage, and refreshes the cache
+// with the most recent items
+func (ch *Cached) LoadAll() ([]Item, error) {
+	ch.lock.Lock()
+	epoch := ch.epoch
+	ch.lock.Unlock()
+	items, err := ch.inner.LoadAll()
+	if err != nil {
+		return nil, err
+	}
+	ch.lock.Lock()
+	defer ch.lock.Unlock()
+	if ch.epoch != epoch {
+		return items, nil
+	}
+	for _, item := range items {
+		ch.put(item.ID, item.Blob)
+	}
+	return items, nil
+}
+
+func (ch *Cached) Load(objectID ID) (Blob, error) {
+	blob, epoch, ok := ch.get(objectID)
+	if ok {
+		return blob, nil
+	}
+	blob, err := ch.inner.Load(objectID)
+	if err != nil {
+		return nil, err
+	}
+	ch.lock.Lock()
+	if ch.epoch == epoch {
+		ch.put(objectID, blob)
+	}
+	ch.lock.Unlock()
+	return blob, nil
+}
+
+func (ch *Cached) Save(objectID ID, blob Blob) error {
+	defer ch.invalidate(objectID)
+	return ch.inner.Save(objectID, blob)
+}
+
+func (ch *Cached) Delete(objectID ID) error {
+	defer ch.invalidate(objectID)
+	return ch.inner.Delete(objectID)
+}
@@ -0,0 +1,65 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCached(t *testing.T) {
+	mem, err := NewMemory()
+	require.NoError(t, err)
+	ch := NewCached(mem, 2)
+	require.NoError(t, ch.Create("1", Blob("foo")))
+	require.NoError(t, ch.Create("2", Blob("bar")))
+	require.NoError(t, ch.Create("3", Blob("baz")))
+
+	blob, err := ch.Load("1")
+	assert.NoError(t, err)
+	assert.Equal(t, "foo", string(blob))
+	blob, err = ch.Load("1")
+	assert.NoError(t, err)
+	assert.Equal(t, "foo", string(blob))
+	assert.Equal(t, CacheStats{Hits: 1, Misses: 1, Size: 1}, ch.Stats())
+
+	// the least recently used blob is evicted
+	_, err = ch.Load("2")
+	assert.NoError(t, err)
+	_, err = ch.Load("3")
+	assert.NoError(t, err)
+	_, err = ch.Load("1")
+	assert.NoError(t, err)
+	assert.Equal(t, CacheStats{Hits: 1, Misses: 4, Size: 2}, ch.Stats())
+
+	// changes invalidate the cache
+	require.NoError(t, ch.Save("1", Blob("quux")))
+	blob, err = ch.Load("1")
+	assert.NoError(t, err)
+	assert.Equal(t, "quux", string(blob))
+
+	require.NoError(t, ch.Delete("1"))
+	_, err = ch.Load("1")
+	assert.ErrorIs(t, err, ErrNotFound{ID: "1"})
+}
+
+func TestCachedLoadAll(t *testing.T) {
+	mem, err := NewMemory()
+	require.NoError(t, err)
+	require.NoError(t, mem.Create("1", Blob("foo")))
+	require.NoError(t, mem.Create("2", Blob("bar")))
+
+	ch := NewCached(mem, 10)
+	items, err := ch.LoadAll()
+	assert.NoError(t, err)
+	assert.Len(t, items, 2)
+
+	// the cached blobs don't share memory with the ones returned
+	blob, err := ch.Load("2")
+	assert.NoError(t, err)
+	blob[0] = 'X'
+	blob, err = ch.Load("2")
+	assert.NoError(t, err)
+	assert.Equal(t, "bar", string(blob))
+	assert.Equal(t, CacheStats{Hits: 2, Misses: 0, Size: 2}, ch.Stats())
+}
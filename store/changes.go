@@ -0,0 +1,103 @@
+package store
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"sort"
+	"time"
+)
+
+// Change describes the last change of a item
+type Change struct {
+	ID ID
+	// Seq is the change sequence of the last change of the item. Zero for the
+	// items last changed before the storage tracked changes.
+	Seq uint64
+	// Deleted is true if the item was deleted
+	Deleted bool
+}
+
+// ChangeTracker is implemented by the storages which track their changes: each change
+// gets the next value of a monotonically increasing change sequence.
+type ChangeTracker interface {
+	// Changes returns the items changed after the given change sequence, including the
+	// deleted ones, sorted by sequence, along with the current change sequence.
+	// Zero means all the items.
+	Changes(since uint64) ([]Change, uint64, error)
+}
+
+// Changes returns the items changed after the given change sequence, sorted by sequence,
+// along with the current change sequence. Fails with ErrUnsupported if the storage
+// doesn't track its changes.
+func Changes(st Storage, since uint64) ([]Change, uint64, error) {
+	ct, ok := st.(ChangeTracker)
+	if !ok {
+		return nil, 0, ErrUnsupported
+	}
+	return ct.Changes(since)
+}
+
+// currentSeq returns the current change sequence of the storage
+func currentSeq(st Storage) (uint64, error) {
+	// no change can be newer than the maximum sequence
+	_, seq, err := Changes(st, math.MaxInt64)
+	return seq, err
+}
+
+func sortChanges(changes []Change) {
+	sort.SliceStable(changes, func(i, j int) bool {
+		if changes[i].Seq != changes[j].Seq {
+			return changes[i].Seq < changes[j].Seq
+		}
+		return changes[i].ID < changes[j].ID
+	})
+}
+
+// SnapshotSince writes to the given writer a incremental snapshot, holding only the items
+// changed, deleted included, after the given change sequence. Zero means all the items.
+// Returns the current change sequence, to pass to the next SnapshotSince.
+// Fails with ErrUnsupported if the storage doesn't track its changes.
+// Incremental snapshots are applied by Restore, on top of the previous ones.
+func SnapshotSince(st Storage, w io.Writer, since uint64) (uint64, error) {
+	changes, last, err := Changes(st, since)
+	if err != nil {
+		return 0, err
+	}
+	zw := gzip.NewWriter(w)
+	enc := json.NewEncoder(zw)
+	err = enc.Encode(snapshotHeader{
+		Format:  snapshotFormat,
+		Version: snapshotVersion,
+		Created: time.Now().UTC(),
+		Since:   since,
+		Seq:     last,
+	})
+	if err != nil {
+		return 0, err
+	}
+	for _, change := range changes {
+		rec := snapshotRecord{ID: change.ID, Deleted: true}
+		if !change.Deleted {
+			blob, err := st.Load(change.ID)
+			var notFound ErrNotFound
+			if errors.As(err, &notFound) {
+				// deleted meanwhile: will be in the next snapshot
+				continue
+			}
+			if err != nil {
+				return 0, err
+			}
+			rec, err = snapshotRecordOf(st, Item{ID: change.ID, Blob: blob})
+			if err != nil {
+				return 0, err
+			}
+		}
+		if err := enc.Encode(rec); err != nil {
+			return 0, err
+		}
+	}
+	return last, zw.Close()
+}
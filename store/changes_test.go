@@ -0,0 +1,100 @@
+package store
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChanges(t *testing.T) {
+	for name, st := range batchTestStorages(t) {
+		t.Run(name, func(t *testing.T) {
+			require.NoError(t, st.Create("1", Blob("foo")))
+			require.NoError(t, st.Create("2", Blob("bar")))
+			_, seq, err := Changes(st, 0)
+			require.NoError(t, err)
+
+			require.NoError(t, st.Save("1", Blob("baz")))
+			require.NoError(t, st.Delete("2"))
+			require.NoError(t, st.Create("3", Blob("quux")))
+
+			changes, last, err := Changes(st, seq)
+			assert.NoError(t, err)
+			assert.Equal(t, seq+3, last)
+			assert.Equal(t, []Change{
+				{ID: "1", Seq: seq + 1},
+				{ID: "2", Seq: seq + 2, Deleted: true},
+				{ID: "3", Seq: seq + 3},
+			}, changes)
+
+			// recreated items are no longer deleted
+			require.NoError(t, st.Create("2", Blob("bar")))
+			changes, _, err = Changes(st, last)
+			assert.NoError(t, err)
+			assert.Equal(t, []Change{{ID: "2", Seq: last + 1}}, changes)
+
+			changes, _, err = Changes(st, 0)
+			assert.NoError(t, err)
+			assert.Equal(t, []ID{"1", "3", "2"}, changeIDs(changes))
+		})
+	}
+}
+
+func changeIDs(changes []Change) []ID {
+	var ids []ID
+	for _, change := range changes {
+		ids = append(ids, change.ID)
+	}
+	return ids
+}
+
+func TestSnapshotSince(t *testing.T) {
+	for name, src := range batchTestStorages(t) {
+		t.Run(name, func(t *testing.T) {
+			dst, err := NewMemory()
+			require.NoError(t, err)
+
+			require.NoError(t, src.Create("1", Blob("foo")))
+			require.NoError(t, src.Create("2", Blob("bar")))
+			var full bytes.Buffer
+			require.NoError(t, Snapshot(src, &full))
+			count, err := Restore(dst, &full)
+			require.NoError(t, err)
+			require.Equal(t, 2, count)
+			_, seq, err := Changes(src, 0)
+			require.NoError(t, err)
+
+			require.NoError(t, src.Save("1", Blob("baz")))
+			require.NoError(t, src.Delete("2"))
+			require.NoError(t, src.Create("3", Blob("quux")))
+			var incr bytes.Buffer
+			last, err := SnapshotSince(src, &incr, seq)
+			assert.NoError(t, err)
+			assert.Equal(t, seq+3, last)
+
+			count, err = Restore(dst, &incr)
+			assert.NoError(t, err)
+			assert.Equal(t, 3, count)
+			items, err := LoadPage(dst, 0, 0)
+			assert.NoError(t, err)
+			assert.Equal(t, []Item{{ID: "1", Blob: Blob("baz")}, {ID: "3", Blob: Blob("quux")}}, items)
+
+			// nothing changed since
+			incr.Reset()
+			last2, err := SnapshotSince(src, &incr, last)
+			assert.NoError(t, err)
+			assert.Equal(t, last, last2)
+			count, err = Restore(dst, &incr)
+			assert.NoError(t, err)
+			assert.Equal(t, 0, count)
+		})
+	}
+}
+
+func TestSnapshotSinceUnsupported(t *testing.T) {
+	var buf bytes.Buffer
+	_, err := SnapshotSince(&Redis{}, &buf, 0)
+	assert.ErrorIs(t, err, ErrUnsupported)
+}
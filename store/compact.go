@@ -0,0 +1,21 @@
+package store
+
+// Compacter is implemented by the storages which accumulate garbage over time, like
+// expired trashed items or unused space, and can reclaim it.
+// Decorators implement it forwarding the compaction to the storage they decorate.
+type Compacter interface {
+	// Compact reclaims the garbage. Returns the number of items rewritten or removed.
+	Compact() (int, error)
+}
+
+// Compact reclaims the garbage accumulated by the storage, returning the number of items
+// rewritten or removed. The storages which don't need compaction are left unchanged.
+// Compaction can take long, and may block the other operations meanwhile: run it
+// when the storage is idle, e.g. on startup.
+func Compact(st Storage) (int, error) {
+	cp, ok := st.(Compacter)
+	if !ok {
+		return 0, nil
+	}
+	return cp.Compact()
+}
@@ -0,0 +1,68 @@
+package store
+
+import (
+	"crypto/rand"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompactUnsupported(t *testing.T) {
+	mem, err := NewMemory()
+	require.NoError(t, err)
+	count, err := Compact(mem)
+	assert.NoError(t, err)
+	assert.Zero(t, count)
+}
+
+func TestCompactTrash(t *testing.T) {
+	sl, path := newTestSQLite(t)
+	now := time.Date(2024, time.November, 11, 10, 0, 0, 0, time.UTC)
+	tr := NewTrash(NewCached(NewCompressed(sl), 10))
+	tr.Now = func() time.Time { return now }
+	tr.Retention = 24 * time.Hour
+
+	// incompressible, so it takes space even compressed
+	big := make(Blob, 256*1024)
+	_, err := rand.Read(big)
+	require.NoError(t, err)
+	require.NoError(t, tr.Create("1", big))
+	require.NoError(t, tr.Create("2", Blob("bar")))
+	require.NoError(t, tr.Delete("1"))
+	now = now.Add(48 * time.Hour)
+	require.NoError(t, tr.Delete("2"))
+
+	before, err := os.Stat(path)
+	require.NoError(t, err)
+
+	count, err := Compact(tr)
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	trashed, err := tr.ListTrash()
+	require.NoError(t, err)
+	require.Len(t, trashed, 1)
+	assert.Equal(t, ID("2"), trashed[0].ID)
+
+	after, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Less(t, after.Size(), before.Size())
+}
+
+func TestCompactTrashForever(t *testing.T) {
+	mem, err := NewMemory()
+	require.NoError(t, err)
+	tr := NewTrash(mem)
+	require.NoError(t, tr.Create("1", Blob("foo")))
+	require.NoError(t, tr.Delete("1"))
+
+	count, err := Compact(tr)
+	require.NoError(t, err)
+	assert.Zero(t, count)
+	trashed, err := tr.ListTrash()
+	require.NoError(t, err)
+	assert.Len(t, trashed, 1)
+}
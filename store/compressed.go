@@ -0,0 +1,164 @@
+package store
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+)
+
+// compressedMagic marks the blobs compressed by Compressed. It is followed by
+// a byte identifying the compression algorithm.
+var compressedMagic = []byte("TDZ")
+
+const compressedGzip byte = 'g'
+
+// DefaultCompressThreshold is the blob size, in bytes, below which Compressed doesn't compress
+const DefaultCompressThreshold = 512
+
+var _ Storage = &Compressed{}
+var _ Compacter = &Compressed{}
+
+// Compressed is a Storage decorator which compresses the blobs with gzip before
+// handing them to the inner storage, and decompresses them on load.
+// Compressed blobs are marked with a header, so they can coexist with uncompressed
+// ones, e.g. written before the compression was enabled, or too small to be worth
+// compressing. Use Compact to compress the existing items.
+type Compressed struct {
+	inner Storage
+	// Threshold is the blob size, in bytes, below which blobs are stored uncompressed
+	Threshold int
+	// Level is the gzip compression level
+	Level int
+}
+
+// NewCompressed creates a new Compressed decorating the given storage,
+// with the default threshold and compression level.
+func NewCompressed(inner Storage) *Compressed {
+	return &Compressed{
+		inner:     inner,
+		Threshold: DefaultCompressThreshold,
+		Level:     gzip.DefaultCompression,
+	}
+}
+
+func isCompressed(blob Blob) bool {
+	return len(blob) > len(compressedMagic) && bytes.HasPrefix(blob, compressedMagic)
+}
+
+// compress returns the blob to store for the given content. Blobs which don't
+// shrink are stored uncompressed.
+func (cm *Compressed) compress(blob Blob) (Blob, error) {
+	if len(blob) < cm.Threshold {
+		return blob, nil
+	}
+	var buf bytes.Buffer
+	buf.Write(compressedMagic)
+	buf.WriteByte(compressedGzip)
+	zw, err := gzip.NewWriterLevel(&buf, cm.Level)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := zw.Write(blob); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	if buf.Len() >= len(blob) {
+		return blob, nil
+	}
+	return buf.Bytes(), nil
+}
+
+func (cm *Compressed) decompress(objectID ID, blob Blob) (Blob, error) {
+	if !isCompressed(blob) {
+		return blob, nil
+	}
+	hdr := len(compressedMagic)
+	if blob[hdr] != compressedGzip {
+		return nil, ErrCorruptedContent{Name: string(objectID)}
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(blob[hdr+1:]))
+	if err != nil {
+		return nil, ErrCorruptedContent{Name: string(objectID)}
+	}
+	data, err := io.ReadAll(zr)
+	if err != nil {
+		return nil, ErrCorruptedContent{Name: string(objectID)}
+	}
+	return data, nil
+}
+
+// Compact compresses all the items stored uncompressed which are worth compressing,
+// e.g. the ones written before the compression was enabled, then compacts the inner storage.
+// Returns the number of items rewritten.
+func (cm *Compressed) Compact() (int, error) {
+	items, err := cm.inner.LoadAll()
+	if err != nil {
+		return 0, err
+	}
+	var todo []Item
+	for _, item := range items {
+		if isCompressed(item.Blob) {
+			continue
+		}
+		blob, err := cm.compress(item.Blob)
+		if err != nil {
+			return 0, err
+		}
+		if isCompressed(blob) {
+			todo = append(todo, Item{ID: item.ID, Blob: blob})
+		}
+	}
+	if err := SaveAll(cm.inner, todo); err != nil {
+		return 0, err
+	}
+	count, err := Compact(cm.inner)
+	return len(todo) + count, err
+}
+
+func (cm *Compressed) Close() error {
+	return cm.inner.Close()
+}
+
+func (cm *Compressed) Create(objectID ID, data Blob) error {
+	blob, err := cm.compress(data)
+	if err != nil {
+		return err
+	}
+	return cm.inner.Create(objectID, blob)
+}
+
+func (cm *Compressed) LoadAll() ([]Item, error) {
+	items, err := cm.inner.LoadAll()
+	if err != nil {
+		return nil, err
+	}
+	for idx := range items {
+		items[idx].Blob, err = cm.decompress(items[idx].ID, items[idx].Blob)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return items, nil
+}
+
+func (cm *Compressed) Load(objectID ID) (Blob, error) {
+	blob, err := cm.inner.Load(objectID)
+	if err != nil {
+		return nil, err
+	}
+	return cm.decompress(objectID, blob)
+}
+
+func (cm *Compressed) Save(objectID ID, data Blob) error {
+	blob, err := cm.compress(data)
+	if err != nil {
+		return err
+	}
+	return cm.inner.Save(objectID, blob)
+}
+
+func (cm *Compressed) Delete(objectID ID) error {
+	return cm.inner.Delete(objectID)
+}
@@ -0,0 +1,75 @@
+package store
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompressed(t *testing.T) {
+	large := Blob(strings.Repeat("todo ", 1000))
+	for name, inner := range batchTestStorages(t) {
+		t.Run(name, func(t *testing.T) {
+			cm := NewCompressed(inner)
+			require.NoError(t, cm.Create("1", large))
+			require.NoError(t, cm.Create("2", Blob("small")))
+
+			raw, err := inner.Load("1")
+			assert.NoError(t, err)
+			assert.Less(t, len(raw), len(large))
+			raw, err = inner.Load("2")
+			assert.NoError(t, err)
+			assert.Equal(t, "small", string(raw))
+
+			blob, err := cm.Load("1")
+			assert.NoError(t, err)
+			assert.Equal(t, large, blob)
+
+			require.NoError(t, cm.Save("2", large))
+			items, err := cm.LoadAll()
+			assert.NoError(t, err)
+			assert.ElementsMatch(t, []Item{{ID: "1", Blob: large}, {ID: "2", Blob: large}}, items)
+		})
+	}
+}
+
+func TestCompressedCompact(t *testing.T) {
+	large := Blob(strings.Repeat("todo ", 1000))
+	mem, err := NewMemory()
+	require.NoError(t, err)
+	// written before the compression was enabled
+	require.NoError(t, mem.Create("1", large))
+	require.NoError(t, mem.Create("2", Blob("small")))
+
+	cm := NewCompressed(mem)
+	blob, err := cm.Load("1")
+	assert.NoError(t, err)
+	assert.Equal(t, large, blob)
+
+	count, err := cm.Compact()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+	raw, err := mem.Load("1")
+	assert.NoError(t, err)
+	assert.True(t, bytes.HasPrefix(raw, compressedMagic))
+
+	count, err = cm.Compact()
+	assert.NoError(t, err)
+	assert.Equal(t, 0, count)
+
+	items, err := cm.LoadAll()
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []Item{{ID: "1", Blob: large}, {ID: "2", Blob: Blob("small")}}, items)
+}
+
+func TestCompressedCorrupted(t *testing.T) {
+	mem, err := NewMemory()
+	require.NoError(t, err)
+	require.NoError(t, mem.Create("1", Blob("TDZgnot gzip")))
+
+	_, err = NewCompressed(mem).Load("1")
+	assert.ErrorIs(t, err, ErrCorruptedContent{Name: "1"})
+}
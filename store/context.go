@@ -0,0 +1,151 @@
+package store
+
+import (
+	"context"
+)
+
+// StorageContext is the context-aware flavour of Storage. Every operation accepts
+// a context, so callers can cancel long running operations (e.g. LoadAll scans)
+// and networked backends can propagate deadlines.
+// Backends should implement both Storage and StorageContext; use WithContext and
+// WithoutContext to adapt implementations which provide only one of the two.
+type StorageContext interface {
+	Close() error
+	CreateCtx(context.Context, ID, Blob) error
+	LoadAllCtx(context.Context) ([]Item, error)
+	LoadCtx(context.Context, ID) (Blob, error)
+	SaveCtx(context.Context, ID, Blob) error
+	DeleteCtx(context.Context, ID) error
+}
+
+// WithContext returns the StorageContext flavour of the given Storage.
+// If the storage natively supports contexts, it is returned unchanged;
+// otherwise it is wrapped in an adapter which checks for context cancellation
+// before every operation, but can't interrupt an operation once started.
+func WithContext(st Storage) StorageContext {
+	if stc, ok := st.(StorageContext); ok {
+		return stc
+	}
+	return contextAdapter{st: st}
+}
+
+// WithoutContext returns the Storage flavour of the given StorageContext,
+// running all the operations with the background context.
+func WithoutContext(stc StorageContext) Storage {
+	if st, ok := stc.(Storage); ok {
+		return st
+	}
+	return backgroundAdapter{stc: stc}
+}
+
+type contextAdapter struct {
+	st Storage
+}
+
+var _ StorageContext = contextAdapter{}
+
+func (ca contextAdapter) Close() error {
+	return ca.st.Close()
+}
+
+func (ca contextAdapter) CreateCtx(ctx context.Context, id ID, blob Blob) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return ca.st.Create(id, blob)
+}
+
+func (ca contextAdapter) LoadAllCtx(ctx context.Context) ([]Item, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return ca.st.LoadAll()
+}
+
+func (ca contextAdapter) LoadCtx(ctx context.Context, id ID) (Blob, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return ca.st.Load(id)
+}
+
+func (ca contextAdapter) SaveCtx(ctx context.Context, id ID, blob Blob) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return ca.st.Save(id, blob)
+}
+
+func (ca contextAdapter) DeleteCtx(ctx context.Context, id ID) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return ca.st.Delete(id)
+}
+
+type backgroundAdapter struct {
+	stc StorageContext
+}
+
+var _ Storage = backgroundAdapter{}
+
+func (ba backgroundAdapter) Close() error {
+	return ba.stc.Close()
+}
+
+func (ba backgroundAdapter) Create(id ID, blob Blob) error {
+	return ba.stc.CreateCtx(context.Background(), id, blob)
+}
+
+func (ba backgroundAdapter) LoadAll() ([]Item, error) {
+	return ba.stc.LoadAllCtx(context.Background())
+}
+
+func (ba backgroundAdapter) Load(id ID) (Blob, error) {
+	return ba.stc.LoadCtx(context.Background(), id)
+}
+
+func (ba backgroundAdapter) Save(id ID, blob Blob) error {
+	return ba.stc.SaveCtx(context.Background(), id, blob)
+}
+
+func (ba backgroundAdapter) Delete(id ID) error {
+	return ba.stc.DeleteCtx(context.Background(), id)
+}
+
+// Bind returns the Storage running all the operations of the given storage with the given context,
+// for the callers unaware of the contexts to pass them down, e.g. to a Traced.
+func Bind(st Storage, ctx context.Context) Storage {
+	return boundAdapter{stc: WithContext(st), ctx: ctx}
+}
+
+type boundAdapter struct {
+	stc StorageContext
+	ctx context.Context
+}
+
+var _ Storage = boundAdapter{}
+
+func (ba boundAdapter) Close() error {
+	return ba.stc.Close()
+}
+
+func (ba boundAdapter) Create(id ID, blob Blob) error {
+	return ba.stc.CreateCtx(ba.ctx, id, blob)
+}
+
+func (ba boundAdapter) LoadAll() ([]Item, error) {
+	return ba.stc.LoadAllCtx(ba.ctx)
+}
+
+func (ba boundAdapter) Load(id ID) (Blob, error) {
+	return ba.stc.LoadCtx(ba.ctx, id)
+}
+
+func (ba boundAdapter) Save(id ID, blob Blob) error {
+	return ba.stc.SaveCtx(ba.ctx, id, blob)
+}
+
+func (ba boundAdapter) Delete(id ID) error {
+	return ba.stc.DeleteCtx(ba.ctx, id)
+}
@@ -0,0 +1,61 @@
+package store
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithContextAdapter(t *testing.T) {
+	mem, err := NewMemory()
+	require.NoError(t, err)
+
+	stc := WithContext(mem)
+	ctx := context.Background()
+	assert.NoError(t, stc.CreateCtx(ctx, "1", Blob("foobar")))
+	blob, err := stc.LoadCtx(ctx, "1")
+	assert.NoError(t, err)
+	assert.Equal(t, "foobar", string(blob))
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	assert.ErrorIs(t, stc.SaveCtx(canceled, "1", Blob("fizzbuzz")), context.Canceled)
+	_, err = stc.LoadAllCtx(canceled)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	// the canceled operation must not have been performed
+	blob, err = mem.Load("1")
+	assert.NoError(t, err)
+	assert.Equal(t, "foobar", string(blob))
+}
+
+func TestWithoutContextAdapter(t *testing.T) {
+	mem, err := NewMemory()
+	require.NoError(t, err)
+
+	st := WithoutContext(WithContext(mem))
+	assert.NoError(t, st.Create("1", Blob("foobar")))
+	assert.NoError(t, st.Save("1", Blob("fizzbuzz")))
+	items, err := st.LoadAll()
+	assert.NoError(t, err)
+	assert.Equal(t, []Item{{ID: "1", Blob: Blob("fizzbuzz")}}, items)
+	assert.NoError(t, st.Delete("1"))
+}
+
+func TestNativeContextSupport(t *testing.T) {
+	sl, err := NewSQLite(filepath.Join(t.TempDir(), "todo.db"))
+	require.NoError(t, err)
+	defer sl.Close()
+
+	// native implementations are returned as-is, not wrapped
+	assert.Same(t, sl, WithContext(sl))
+	assert.Same(t, sl, WithoutContext(sl))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = sl.LoadAllCtx(ctx)
+	assert.ErrorIs(t, err, context.Canceled)
+}
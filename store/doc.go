@@ -2,5 +2,11 @@
 // keys are auto-increment keys and whose values are data blobs, aka slice of bytes.
 // Implements backend to
 // - redis
+// - sqlite
+// - bbolt
+// - postgresql
+// - S3-compatible object storage
+// - the store of a todo server, over HTTP
+// - memory (non persistent)
 // - filesystem directory
 package store
@@ -0,0 +1,264 @@
+package store
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"sync"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// encryptedMagic marks the blobs encrypted by Encrypted
+var encryptedMagic = []byte("TDE1")
+
+// encryptedKeyIDLen is the length of the key fingerprint stored in each encrypted blob
+const encryptedKeyIDLen = 4
+
+// ErrUnknownKey is returned when decrypting a blob encrypted with a key not known to Encrypted
+var ErrUnknownKey = errors.New("blob encrypted with an unknown key")
+
+// passphraseSaltLen is the minimum length of the salt used by KeyFromPassphrase
+const passphraseSaltLen = 16
+
+// saltID is the ID of the item holding, in clear, the salt of the key NewEncryptedFromPassphrase
+// derives
+var saltID = MetaID("encryption-salt")
+
+// KeyFromPassphrase derives a encryption key suitable for NewEncrypted from the given
+// passphrase, using scrypt. The salt must be at least 16 bytes long, and must be stored
+// alongside the data: the same passphrase and salt always give the same key.
+func KeyFromPassphrase(passphrase string, salt []byte) ([]byte, error) {
+	if passphrase == "" {
+		return nil, errors.New("empty passphrase")
+	}
+	if len(salt) < passphraseSaltLen {
+		return nil, fmt.Errorf("salt too short: %d bytes, need at least %d", len(salt), passphraseSaltLen)
+	}
+	return scrypt.Key([]byte(passphrase), salt, 1<<15, 8, 1, 32)
+}
+
+var _ Storage = &Encrypted{}
+var _ Compacter = &Encrypted{}
+
+// Encrypted is a Storage decorator which encrypts the blobs with AES-GCM before
+// handing them to the inner storage, and decrypts them on load. The IDs are stored
+// in clear.
+// Blobs are always encrypted with the current key, but can be decrypted with any of
+// the keys added with Rotate, so keys can be replaced without downtime: rotate the key,
+// then call Reencrypt to rewrite all the items with the new key.
+type Encrypted struct {
+	inner Storage
+	lock  sync.RWMutex
+	// current is the fingerprint of the key used to encrypt
+	current string
+	aeads   map[string]cipher.AEAD
+}
+
+// NewEncrypted creates a new Encrypted decorating the given storage.
+// The key must be 16, 24 or 32 bytes long, to select AES-128, AES-192 or AES-256.
+func NewEncrypted(inner Storage, key []byte) (*Encrypted, error) {
+	enc := &Encrypted{
+		inner: inner,
+		aeads: make(map[string]cipher.AEAD),
+	}
+	if err := enc.Rotate(key); err != nil {
+		return nil, err
+	}
+	return enc, nil
+}
+
+// NewEncryptedFromPassphrase creates a new Encrypted like NewEncrypted, with the key derived from the
+// passphrase by KeyFromPassphrase. The salt is generated the first time, and stored in clear in the
+// inner storage, hidden from the users of the Encrypted.
+func NewEncryptedFromPassphrase(inner Storage, passphrase string) (*Encrypted, error) {
+	salt, err := inner.Load(saltID)
+	if errors.Is(err, ErrNotFound{ID: saltID}) {
+		salt = make([]byte, passphraseSaltLen)
+		if _, err = io.ReadFull(rand.Reader, salt); err != nil {
+			return nil, err
+		}
+		err = inner.Create(saltID, salt)
+	}
+	if err != nil {
+		return nil, err
+	}
+	key, err := KeyFromPassphrase(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	return NewEncrypted(inner, key)
+}
+
+func keyID(key []byte) string {
+	sum := sha256.Sum256(key)
+	return string(sum[:encryptedKeyIDLen])
+}
+
+// Rotate makes the given key the one used to encrypt the blobs from now on.
+// The previous keys are kept to decrypt the blobs not yet reencrypted.
+func (enc *Encrypted) Rotate(key []byte) error {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return err
+	}
+	id := keyID(key)
+	enc.lock.Lock()
+	defer enc.lock.Unlock()
+	enc.aeads[id] = aead
+	enc.current = id
+	return nil
+}
+
+// Reencrypt rewrites all the items not encrypted with the current key, including
+// the ones stored in clear, e.g. before the encryption was enabled.
+// Returns the number of items rewritten.
+func (enc *Encrypted) Reencrypt() (int, error) {
+	items, err := enc.inner.LoadAll()
+	if err != nil {
+		return 0, err
+	}
+	enc.lock.RLock()
+	current := enc.current
+	enc.lock.RUnlock()
+
+	var todo []Item
+	for _, item := range items {
+		if item.ID == saltID || isEncrypted(item.Blob) && encryptedKeyOf(item.Blob) == current {
+			continue
+		}
+		blob := item.Blob
+		if isEncrypted(blob) {
+			blob, err = enc.open(item.ID, blob)
+			if err != nil {
+				return 0, err
+			}
+		}
+		sealed, err := enc.seal(item.ID, blob)
+		if err != nil {
+			return 0, err
+		}
+		todo = append(todo, Item{ID: item.ID, Blob: sealed})
+	}
+	if err := SaveAll(enc.inner, todo); err != nil {
+		return 0, err
+	}
+	return len(todo), nil
+}
+
+func isEncrypted(blob Blob) bool {
+	return bytes.HasPrefix(blob, encryptedMagic)
+}
+
+func encryptedKeyOf(blob Blob) string {
+	hdr := len(encryptedMagic)
+	if len(blob) < hdr+encryptedKeyIDLen {
+		return ""
+	}
+	return string(blob[hdr : hdr+encryptedKeyIDLen])
+}
+
+// seal encrypts the blob with the current key. The ID is authenticated too, so
+// the blobs can't be swapped between items.
+// Layout: magic | key fingerprint | nonce | ciphertext
+func (enc *Encrypted) seal(objectID ID, blob Blob) (Blob, error) {
+	enc.lock.RLock()
+	id := enc.current
+	aead := enc.aeads[id]
+	enc.lock.RUnlock()
+
+	out := make([]byte, 0, len(encryptedMagic)+encryptedKeyIDLen+aead.NonceSize()+len(blob)+aead.Overhead())
+	out = append(out, encryptedMagic...)
+	out = append(out, id...)
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	out = append(out, nonce...)
+	return aead.Seal(out, nonce, blob, []byte(objectID)), nil
+}
+
+func (enc *Encrypted) open(objectID ID, blob Blob) (Blob, error) {
+	if !isEncrypted(blob) {
+		return nil, ErrCorruptedContent{Name: string(objectID)}
+	}
+	enc.lock.RLock()
+	aead, ok := enc.aeads[encryptedKeyOf(blob)]
+	enc.lock.RUnlock()
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+	data := blob[len(encryptedMagic)+encryptedKeyIDLen:]
+	if len(data) < aead.NonceSize() {
+		return nil, ErrCorruptedContent{Name: string(objectID)}
+	}
+	nonce, ciphertext := data[:aead.NonceSize()], data[aead.NonceSize():]
+	plain, err := aead.Open(nil, nonce, ciphertext, []byte(objectID))
+	if err != nil {
+		return nil, ErrCorruptedContent{Name: string(objectID)}
+	}
+	return plain, nil
+}
+
+func (enc *Encrypted) Close() error {
+	return enc.inner.Close()
+}
+
+func (enc *Encrypted) Compact() (int, error) {
+	return Compact(enc.inner)
+}
+
+func (enc *Encrypted) Create(objectID ID, data Blob) error {
+	sealed, err := enc.seal(objectID, data)
+	if err != nil {
+		return err
+	}
+	return enc.inner.Create(objectID, sealed)
+}
+
+func (enc *Encrypted) LoadAll() ([]Item, error) {
+	items, err := enc.inner.LoadAll()
+	if err != nil {
+		return nil, err
+	}
+	items = slices.DeleteFunc(items, func(item Item) bool {
+		return item.ID == saltID
+	})
+	for idx := range items {
+		items[idx].Blob, err = enc.open(items[idx].ID, items[idx].Blob)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return items, nil
+}
+
+func (enc *Encrypted) Load(objectID ID) (Blob, error) {
+	blob, err := enc.inner.Load(objectID)
+	if err != nil {
+		return nil, err
+	}
+	return enc.open(objectID, blob)
+}
+
+func (enc *Encrypted) Save(objectID ID, blob Blob) error {
+	sealed, err := enc.seal(objectID, blob)
+	if err != nil {
+		return err
+	}
+	return enc.inner.Save(objectID, sealed)
+}
+
+func (enc *Encrypted) Delete(objectID ID) error {
+	return enc.inner.Delete(objectID)
+}
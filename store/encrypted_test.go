@@ -0,0 +1,141 @@
+package store
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncrypted(t *testing.T) {
+	key := bytes.Repeat([]byte{0x42}, 32)
+	for name, inner := range batchTestStorages(t) {
+		t.Run(name, func(t *testing.T) {
+			enc, err := NewEncrypted(inner, key)
+			require.NoError(t, err)
+
+			require.NoError(t, enc.Create("1", Blob("secret")))
+			blob, err := enc.Load("1")
+			assert.NoError(t, err)
+			assert.Equal(t, "secret", string(blob))
+
+			raw, err := inner.Load("1")
+			assert.NoError(t, err)
+			assert.False(t, bytes.Contains(raw, []byte("secret")))
+
+			require.NoError(t, enc.Save("1", Blob("other secret")))
+			items, err := enc.LoadAll()
+			assert.NoError(t, err)
+			assert.Equal(t, []Item{{ID: "1", Blob: Blob("other secret")}}, items)
+		})
+	}
+}
+
+func TestEncryptedTampering(t *testing.T) {
+	mem, err := NewMemory()
+	require.NoError(t, err)
+	enc, err := NewEncrypted(mem, bytes.Repeat([]byte{0x42}, 16))
+	require.NoError(t, err)
+	require.NoError(t, enc.Create("1", Blob("foo")))
+	require.NoError(t, enc.Create("2", Blob("bar")))
+
+	// blobs can't be swapped between items
+	raw, err := mem.Load("2")
+	require.NoError(t, err)
+	require.NoError(t, mem.Save("1", raw))
+	_, err = enc.Load("1")
+	assert.ErrorIs(t, err, ErrCorruptedContent{Name: "1"})
+
+	// clear content is rejected
+	require.NoError(t, mem.Save("2", Blob("bar")))
+	_, err = enc.Load("2")
+	assert.ErrorIs(t, err, ErrCorruptedContent{Name: "2"})
+}
+
+func TestEncryptedRotation(t *testing.T) {
+	mem, err := NewMemory()
+	require.NoError(t, err)
+	require.NoError(t, mem.Create("0", Blob("clear")))
+
+	oldKey, err := KeyFromPassphrase("old passphrase", []byte("0123456789abcdef"))
+	require.NoError(t, err)
+	enc, err := NewEncrypted(mem, oldKey)
+	require.NoError(t, err)
+	require.NoError(t, enc.Create("1", Blob("foo")))
+
+	newKey, err := KeyFromPassphrase("new passphrase", []byte("0123456789abcdef"))
+	require.NoError(t, err)
+	require.NoError(t, enc.Rotate(newKey))
+	require.NoError(t, enc.Create("2", Blob("bar")))
+
+	// both keys are usable until the items are reencrypted
+	blob, err := enc.Load("1")
+	assert.NoError(t, err)
+	assert.Equal(t, "foo", string(blob))
+
+	count, err := enc.Reencrypt()
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	enc2, err := NewEncrypted(mem, newKey)
+	require.NoError(t, err)
+	items, err := enc2.LoadAll()
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []Item{
+		{ID: "0", Blob: Blob("clear")},
+		{ID: "1", Blob: Blob("foo")},
+		{ID: "2", Blob: Blob("bar")},
+	}, items)
+
+	enc3, err := NewEncrypted(mem, oldKey)
+	require.NoError(t, err)
+	_, err = enc3.Load("1")
+	assert.ErrorIs(t, err, ErrUnknownKey)
+}
+
+func TestKeyFromPassphrase(t *testing.T) {
+	salt := []byte("0123456789abcdef")
+	key, err := KeyFromPassphrase("passphrase", salt)
+	assert.NoError(t, err)
+	assert.Len(t, key, 32)
+	key2, err := KeyFromPassphrase("passphrase", salt)
+	assert.NoError(t, err)
+	assert.Equal(t, key, key2)
+
+	_, err = KeyFromPassphrase("", salt)
+	assert.Error(t, err)
+	_, err = KeyFromPassphrase("passphrase", []byte("short"))
+	assert.Error(t, err)
+}
+
+func TestEncryptedFromPassphrase(t *testing.T) {
+	mem, err := NewMemory()
+	require.NoError(t, err)
+	require.NoError(t, mem.Create("0", Blob("clear")))
+
+	enc, err := NewEncryptedFromPassphrase(mem, "passphrase")
+	require.NoError(t, err)
+	require.NoError(t, enc.Create("1", Blob("secret")))
+	// the objects stored in clear are encrypted, the salt stays in clear and hidden
+	count, err := enc.Reencrypt()
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+	items, err := enc.LoadAll()
+	require.NoError(t, err)
+	assert.Equal(t, []Item{{ID: "0", Blob: Blob("clear")}, {ID: "1", Blob: Blob("secret")}}, items)
+	salt, err := mem.Load(saltID)
+	require.NoError(t, err)
+	assert.Len(t, salt, passphraseSaltLen)
+
+	// the same passphrase decrypts the objects again, with the stored salt
+	enc, err = NewEncryptedFromPassphrase(mem, "passphrase")
+	require.NoError(t, err)
+	blob, err := enc.Load("1")
+	require.NoError(t, err)
+	assert.Equal(t, "secret", string(blob))
+	enc, err = NewEncryptedFromPassphrase(mem, "other passphrase")
+	require.NoError(t, err)
+	_, err = enc.Load("1")
+	assert.ErrorIs(t, err, ErrUnknownKey)
+}
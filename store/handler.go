@@ -0,0 +1,214 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// maxRemoteBlob bounds the size of the blobs the Handler accepts
+const maxRemoteBlob = 32 << 20
+
+// remoteItem is a item, as listed by the Handler
+type remoteItem struct {
+	ID   ID     `json:"id"`
+	Blob []byte `json:"blob"`
+}
+
+// Handler serves the storage over HTTP, to the Remote storages of the clients:
+//   - GET /items lists all the items, as json
+//   - GET /items/{id} returns the blob of the item, with its revision as ETag
+//   - PUT /items/{id} creates the item with If-None-Match: *, or else updates it, only if its
+//     revision is the one of If-Match, if any
+//   - DELETE /items/{id} deletes the item
+//   - POST /ids allocates a new ID, as text, if IDs is set
+//
+// The IDs are escaped in the paths. Mount it on RemotePath, stripped. The clients can read and
+// change all the items, metadata included: only the administrators should reach it.
+type Handler struct {
+	st Storage
+	// OnChange is called with the ID of the item after each change of the storage, e.g. for the
+	// ledger to reload it. Nil if none.
+	OnChange func(id ID)
+	// IDs allocates the IDs of the items the clients create, so that they don't collide with the
+	// ones the server creates: it must be the generator of the server. Nil if none.
+	IDs IDGenerator
+}
+
+// NewHandler creates the handler serving the storage
+func NewHandler(st Storage) *Handler {
+	return &Handler{st: st}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/items" {
+		if r.Method != http.MethodGet {
+			http.Error(w, fmt.Sprintf("method %s not allowed", r.Method), http.StatusMethodNotAllowed)
+			return
+		}
+		h.list(w, r)
+		return
+	}
+	if r.URL.Path == "/ids" {
+		if r.Method != http.MethodPost {
+			http.Error(w, fmt.Sprintf("method %s not allowed", r.Method), http.StatusMethodNotAllowed)
+			return
+		}
+		h.newID(w, r)
+		return
+	}
+	id, ok := strings.CutPrefix(r.URL.Path, "/items/")
+	if !ok || id == "" {
+		http.NotFound(w, r)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		h.get(w, r, ID(id))
+	case http.MethodPut:
+		h.put(w, r, ID(id))
+	case http.MethodDelete:
+		if err := WithContext(h.st).DeleteCtx(r.Context(), ID(id)); err != nil {
+			sendStoreError(w, err)
+			return
+		}
+		h.changed(r, "deleted", ID(id))
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, fmt.Sprintf("method %s not allowed", r.Method), http.StatusMethodNotAllowed)
+	}
+}
+
+func (h *Handler) list(w http.ResponseWriter, r *http.Request) {
+	items, err := WithContext(h.st).LoadAllCtx(r.Context())
+	if err != nil {
+		sendStoreError(w, err)
+		return
+	}
+	res := make([]remoteItem, 0, len(items))
+	for _, item := range items {
+		res = append(res, remoteItem{ID: item.ID, Blob: item.Blob})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(res); err != nil {
+		slog.WarnContext(r.Context(), "store: Handler: can't write the items", "error", err)
+	}
+}
+
+func (h *Handler) newID(w http.ResponseWriter, r *http.Request) {
+	if h.IDs == nil {
+		sendStoreError(w, ErrUnsupported)
+		return
+	}
+	id, err := h.IDs.NewID()
+	if err != nil {
+		sendStoreError(w, err)
+		return
+	}
+	slog.DebugContext(r.Context(), "store: Handler: id allocated", "id", id)
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(http.StatusCreated)
+	io.WriteString(w, string(id))
+}
+
+func (h *Handler) get(w http.ResponseWriter, r *http.Request, id ID) {
+	blob, rev, err := LoadRev(h.st, id)
+	if err != nil {
+		sendStoreError(w, err)
+		return
+	}
+	if rev != 0 {
+		w.Header().Set("ETag", quoteRevision(rev))
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Length", strconv.Itoa(len(blob)))
+	if r.Method == http.MethodGet {
+		w.Write(blob)
+	}
+}
+
+func (h *Handler) put(w http.ResponseWriter, r *http.Request, id ID) {
+	blob, err := io.ReadAll(io.LimitReader(r.Body, maxRemoteBlob+1))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(blob) > maxRemoteBlob {
+		http.Error(w, "blob too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	stc := WithContext(h.st)
+	ifMatch := r.Header.Get("If-Match")
+	switch {
+	case r.Header.Get("If-None-Match") == "*":
+		// the storages tell the items existing already apart from the other errors by their message
+		// only: they are checked first
+		if _, err := stc.LoadCtx(r.Context(), id); err == nil {
+			http.Error(w, fmt.Sprintf("item with id %v already exists", id), http.StatusPreconditionFailed)
+			return
+		}
+		if err := stc.CreateCtx(r.Context(), id, blob); err != nil {
+			sendStoreError(w, err)
+			return
+		}
+		h.changed(r, "created", id)
+		w.WriteHeader(http.StatusCreated)
+		return
+	case ifMatch != "":
+		expected, err := strconv.ParseUint(strings.Trim(ifMatch, `"`), 10, 64)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid If-Match %q", ifMatch), http.StatusBadRequest)
+			return
+		}
+		rev, err := SaveIf(h.st, id, blob, Revision(expected))
+		if err != nil {
+			sendStoreError(w, err)
+			return
+		}
+		w.Header().Set("ETag", quoteRevision(rev))
+	default:
+		if err := stc.SaveCtx(r.Context(), id, blob); err != nil {
+			sendStoreError(w, err)
+			return
+		}
+	}
+	h.changed(r, "updated", id)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// changed logs the change of the item, and calls OnChange
+func (h *Handler) changed(r *http.Request, change string, id ID) {
+	slog.InfoContext(r.Context(), "store: Handler: item "+change, "id", id)
+	if h.OnChange != nil {
+		h.OnChange(id)
+	}
+}
+
+// quoteRevision returns the revision as ETag
+func quoteRevision(rev Revision) string {
+	return strconv.Quote(strconv.FormatUint(uint64(rev), 10))
+}
+
+// sendStoreError answers the request with the error of the storage: 404 if the item doesn't exist,
+// 412 along with its revision if it isn't the one expected, 501 if the storage doesn't support
+// the operation, 500 otherwise
+func sendStoreError(w http.ResponseWriter, err error) {
+	var notFound ErrNotFound
+	var conflict ErrConflict
+	switch {
+	case errors.As(err, &notFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.As(err, &conflict):
+		w.Header().Set("ETag", quoteRevision(conflict.Actual))
+		http.Error(w, err.Error(), http.StatusPreconditionFailed)
+	case errors.Is(err, ErrUnsupported):
+		http.Error(w, err.Error(), http.StatusNotImplemented)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,30 @@
+package store
+
+import (
+	"context"
+	"errors"
+)
+
+// Pinger is implemented by the storage backends which can check cheaply that they are usable,
+// e.g. that their server answers, or that the process still owns them
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// pingID is the ID of the item Ping loads, which is not expected to exist
+var pingID = MetaID("ping")
+
+// Ping checks that the storage is usable: pings it if it's a Pinger, then loads an item, which
+// is not expected to exist. Ping the backend rather than its decorators, which don't tell more.
+func Ping(ctx context.Context, st Storage) error {
+	if p, ok := st.(Pinger); ok {
+		if err := p.Ping(ctx); err != nil {
+			return err
+		}
+	}
+	_, err := WithContext(st).LoadCtx(ctx, pingID)
+	if errors.As(err, &ErrNotFound{}) {
+		return nil
+	}
+	return err
+}
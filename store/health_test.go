@@ -0,0 +1,36 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPing(t *testing.T) {
+	ctx := context.Background()
+	mem, err := NewMemory()
+	require.NoError(t, err)
+	assert.NoError(t, Ping(ctx, mem))
+
+	sl, _ := newTestSQLite(t)
+	assert.NoError(t, Ping(ctx, sl))
+	require.NoError(t, sl.Close())
+	assert.Error(t, Ping(ctx, sl))
+}
+
+func TestRedisPing(t *testing.T) {
+	ctx := context.Background()
+	rd, srv := newTestRedis(t)
+	assert.NoError(t, Ping(ctx, rd))
+
+	require.NoError(t, rd.Acquire(ctx, time.Minute))
+	assert.NoError(t, Ping(ctx, rd))
+	srv.Set(redisLockKey, "another process")
+	assert.ErrorIs(t, Ping(ctx, rd), ErrLocked)
+
+	srv.Close()
+	assert.Error(t, Ping(ctx, rd))
+}
@@ -0,0 +1,190 @@
+package store
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The ID generation strategies supported by NewIDGenerator
+const (
+	// IDSequential generates increasing integers: 1, 2, 3...
+	IDSequential = "sequential"
+	// IDULID generates ULIDs, e.g. 01ARZ3NDEKTSV4RRFFQ69G5FAV
+	IDULID = "ulid"
+	// IDUUIDv7 generates version 7 UUIDs, e.g. 01890a5d-ac96-774b-bcce-b302099a8057
+	IDUUIDv7 = "uuidv7"
+)
+
+// IDGenerator generates the IDs of the new items. All the generated IDs
+// are safe to use as is in URLs and file names.
+type IDGenerator interface {
+	NewID() (ID, error)
+}
+
+// NewIDGenerator returns the generator implementing the given strategy for the
+// given storage. The empty strategy means IDSequential.
+// Sequential IDs are unique among processes only if the storage is a Sequencer:
+// use ULIDs or UUIDv7 when many processes, or a sync tool, create items concurrently.
+func NewIDGenerator(strategy string, st Storage) (IDGenerator, error) {
+	switch strategy {
+	case "", IDSequential:
+		return NewSequentialIDs(st), nil
+	case IDULID:
+		return NewULIDs(), nil
+	case IDUUIDv7:
+		return NewUUIDv7s(), nil
+	default:
+		return nil, fmt.Errorf("unknown id strategy %q", strategy)
+	}
+}
+
+// SequentialIDs generates increasing integer IDs. If the storage is a Sequencer, the IDs
+// are allocated by the storage; otherwise, they are counted in process, starting after
+// the greatest integer ID already in the storage.
+type SequentialIDs struct {
+	st Storage
+
+	lock   sync.Mutex
+	seeded bool
+	last   uint64
+}
+
+// NewSequentialIDs creates a new SequentialIDs for the given storage
+func NewSequentialIDs(st Storage) *SequentialIDs {
+	return &SequentialIDs{st: st}
+}
+
+func (sq *SequentialIDs) NewID() (ID, error) {
+	if _, ok := sq.st.(Sequencer); ok {
+		return NextID(sq.st)
+	}
+	sq.lock.Lock()
+	defer sq.lock.Unlock()
+	if !sq.seeded {
+		last, err := lastIntegerID(sq.st)
+		if err != nil {
+			return NullID, err
+		}
+		sq.last = last
+		sq.seeded = true
+	}
+	sq.last++
+	return ID(strconv.FormatUint(sq.last, 10)), nil
+}
+
+// lastIntegerID returns the greatest integer ID in the storage, including the ones
+// behind a prefix, like the trashed items, but the metadata. Zero if none.
+func lastIntegerID(st Storage) (uint64, error) {
+	var last uint64
+	err := Walk(st, func(item Item) error {
+		if IsMeta(item.ID) {
+			return nil
+		}
+		name := string(item.ID)
+		name = name[strings.LastIndex(name, "/")+1:]
+		if val, err := strconv.ParseUint(name, 10, 64); err == nil && val > last {
+			last = val
+		}
+		return nil
+	})
+	return last, err
+}
+
+// crockford is the base32 alphabet used by ULIDs
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// ULIDs generates ULIDs: 48 bits of millisecond timestamp followed by 80 random bits,
+// encoded in 26 characters. The IDs generated by a ULIDs are strictly increasing.
+type ULIDs struct {
+	lock sync.Mutex
+	// Now returns the current time. Can be replaced to control time in tests.
+	Now  func() time.Time
+	last [16]byte
+}
+
+// NewULIDs creates a new ULIDs
+func NewULIDs() *ULIDs {
+	return &ULIDs{Now: time.Now}
+}
+
+func (ul *ULIDs) NewID() (ID, error) {
+	ul.lock.Lock()
+	defer ul.lock.Unlock()
+	var raw [16]byte
+	ms := uint64(ul.Now().UnixMilli())
+	binary.BigEndian.PutUint16(raw[0:], uint16(ms>>32))
+	binary.BigEndian.PutUint32(raw[2:], uint32(ms))
+	if raw[0] == ul.last[0] && raw[1] == ul.last[1] && string(raw[2:6]) == string(ul.last[2:6]) {
+		// same millisecond: increment the random part, to keep the order
+		raw = ul.last
+		idx := 15
+		for ; idx >= 6; idx-- {
+			raw[idx]++
+			if raw[idx] != 0 {
+				break
+			}
+		}
+		if idx < 6 {
+			return NullID, fmt.Errorf("too many ULIDs generated in the same millisecond")
+		}
+	} else if _, err := rand.Read(raw[6:]); err != nil {
+		return NullID, err
+	}
+	ul.last = raw
+	return ID(encodeULID(raw)), nil
+}
+
+func encodeULID(raw [16]byte) string {
+	// 128 bits in 26 characters of 5 bits each: the first character holds only 3 bits
+	hi := binary.BigEndian.Uint64(raw[0:8])
+	lo := binary.BigEndian.Uint64(raw[8:16])
+	out := make([]byte, 26)
+	for idx := 25; idx >= 0; idx-- {
+		out[idx] = crockford[lo&0x1f]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(out)
+}
+
+// UUIDv7s generates version 7 UUIDs (RFC 9562): 48 bits of millisecond timestamp
+// followed by random bits, in the canonical textual form. The IDs generated in
+// different milliseconds sort by creation time.
+type UUIDv7s struct {
+	// Now returns the current time. Can be replaced to control time in tests.
+	Now func() time.Time
+}
+
+// NewUUIDv7s creates a new UUIDv7s
+func NewUUIDv7s() *UUIDv7s {
+	return &UUIDv7s{Now: time.Now}
+}
+
+func (uv *UUIDv7s) NewID() (ID, error) {
+	var raw [16]byte
+	if _, err := rand.Read(raw[6:]); err != nil {
+		return NullID, err
+	}
+	ms := uint64(uv.Now().UnixMilli())
+	binary.BigEndian.PutUint16(raw[0:], uint16(ms>>32))
+	binary.BigEndian.PutUint32(raw[2:], uint32(ms))
+	raw[6] = raw[6]&0x0f | 0x70 // version 7
+	raw[8] = raw[8]&0x3f | 0x80 // variant 10
+	buf := make([]byte, 36)
+	hex.Encode(buf[0:8], raw[0:4])
+	buf[8] = '-'
+	hex.Encode(buf[9:13], raw[4:6])
+	buf[13] = '-'
+	hex.Encode(buf[14:18], raw[6:8])
+	buf[18] = '-'
+	hex.Encode(buf[19:23], raw[8:10])
+	buf[23] = '-'
+	hex.Encode(buf[24:], raw[10:])
+	return ID(buf), nil
+}
@@ -0,0 +1,100 @@
+package store
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewIDGenerator(t *testing.T) {
+	st, err := NewMemory()
+	require.NoError(t, err)
+	for _, strategy := range []string{"", IDSequential, IDULID, IDUUIDv7} {
+		gen, err := NewIDGenerator(strategy, st)
+		assert.NoError(t, err, strategy)
+		assert.NotNil(t, gen, strategy)
+	}
+	_, err = NewIDGenerator("snowflake", st)
+	assert.Error(t, err)
+}
+
+func TestSequentialIDs(t *testing.T) {
+	st, err := NewMemory()
+	require.NoError(t, err)
+	require.NoError(t, st.Create("7", Blob("foo")))
+	require.NoError(t, st.Create(".trash/12", Blob("bar")))
+	require.NoError(t, st.Create("not-a-number", Blob("baz")))
+
+	gen := NewSequentialIDs(st)
+	for _, expected := range []ID{"13", "14", "15"} {
+		id, err := gen.NewID()
+		require.NoError(t, err)
+		assert.Equal(t, expected, id)
+	}
+}
+
+func TestSequentialIDsSequencer(t *testing.T) {
+	rd, _ := newTestRedis(t)
+	first := NewSequentialIDs(rd)
+	second := NewSequentialIDs(rd)
+
+	seen := map[ID]bool{}
+	for range 5 {
+		for _, gen := range []*SequentialIDs{first, second} {
+			id, err := gen.NewID()
+			require.NoError(t, err)
+			assert.False(t, seen[id], "duplicated id %s", id)
+			seen[id] = true
+		}
+	}
+}
+
+func TestULIDs(t *testing.T) {
+	format := regexp.MustCompile(`^[0-9A-HJKMNP-TV-Z]{26}$`)
+	now := time.UnixMilli(1469918176385)
+	gen := NewULIDs()
+	gen.Now = func() time.Time { return now }
+
+	first, err := gen.NewID()
+	require.NoError(t, err)
+	assert.Regexp(t, format, string(first))
+	// the timestamp is encoded in the first 10 characters
+	assert.Equal(t, "01ARYZ6S41", string(first[:10]))
+
+	prev := first
+	for range 100 {
+		id, err := gen.NewID()
+		require.NoError(t, err)
+		assert.Regexp(t, format, string(id))
+		assert.Less(t, string(prev), string(id))
+		prev = id
+	}
+	now = now.Add(time.Millisecond)
+	id, err := gen.NewID()
+	require.NoError(t, err)
+	assert.Less(t, string(prev), string(id))
+}
+
+func TestUUIDv7s(t *testing.T) {
+	format := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-7[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	now := time.UnixMilli(0x017f22e279b0)
+	gen := NewUUIDv7s()
+	gen.Now = func() time.Time { return now }
+
+	first, err := gen.NewID()
+	require.NoError(t, err)
+	assert.Regexp(t, format, string(first))
+	assert.Equal(t, "017f22e2-79b0-7", string(first[:15]))
+
+	second, err := gen.NewID()
+	require.NoError(t, err)
+	assert.NotEqual(t, first, second)
+
+	now = now.Add(time.Millisecond)
+	third, err := gen.NewID()
+	require.NoError(t, err)
+	assert.Less(t, string(second), string(third))
+}
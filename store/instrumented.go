@@ -0,0 +1,120 @@
+package store
+
+import (
+	"sync"
+	"time"
+)
+
+// The operation names reported to a Recorder
+const (
+	OpCreate  = "create"
+	OpLoadAll = "load_all"
+	OpLoad    = "load"
+	OpSave    = "save"
+	OpDelete  = "delete"
+)
+
+// Recorder collects the metrics of a storage. Implementations must be safe
+// for concurrent use. See the metrics package for ready-made implementations.
+type Recorder interface {
+	// ObserveOp records the outcome of a storage operation: its duration, and
+	// the error it failed with, nil if it succeeded
+	ObserveOp(op string, elapsed time.Duration, err error)
+	// SetItems records the number of items in the storage
+	SetItems(count int)
+}
+
+var _ Storage = &Instrumented{}
+var _ Compacter = &Instrumented{}
+
+// Instrumented is a Storage decorator which reports to a Recorder the latency
+// and the outcome of all the operations, and the number of items stored.
+// The item count is known only after the first LoadAll, and then kept up to
+// date with the changes performed through the Instrumented.
+type Instrumented struct {
+	inner Storage
+	rec   Recorder
+
+	lock  sync.Mutex
+	items int
+	known bool
+}
+
+// NewInstrumented creates a new Instrumented decorating the given storage
+func NewInstrumented(inner Storage, rec Recorder) *Instrumented {
+	return &Instrumented{
+		inner: inner,
+		rec:   rec,
+	}
+}
+
+func (in *Instrumented) observe(op string, start time.Time, err error) {
+	in.rec.ObserveOp(op, time.Since(start), err)
+}
+
+// countItems adjusts the item count by delta, once it is known
+func (in *Instrumented) countItems(delta int) {
+	in.lock.Lock()
+	defer in.lock.Unlock()
+	if !in.known {
+		return
+	}
+	in.items += delta
+	in.rec.SetItems(in.items)
+}
+
+func (in *Instrumented) Close() error {
+	return in.inner.Close()
+}
+
+func (in *Instrumented) Compact() (int, error) {
+	return Compact(in.inner)
+}
+
+func (in *Instrumented) Create(objectID ID, data Blob) error {
+	start := time.Now()
+	err := in.inner.Create(objectID, data)
+	in.observe(OpCreate, start, err)
+	if err == nil {
+		in.countItems(1)
+	}
+	return err
+}
+
+func (in *Instrumented) LoadAll() ([]Item, error) {
+	start := time.Now()
+	items, err := in.inner.LoadAll()
+	in.observe(OpLoadAll, start, err)
+	if err == nil {
+		in.lock.Lock()
+		in.items = len(items)
+		in.known = true
+		in.rec.SetItems(in.items)
+		in.lock.Unlock()
+	}
+	return items, err
+}
+
+func (in *Instrumented) Load(objectID ID) (Blob, error) {
+	start := time.Now()
+	blob, err := in.inner.Load(objectID)
+	in.observe(OpLoad, start, err)
+	return blob, err
+}
+
+func (in *Instrumented) Save(objectID ID, blob Blob) error {
+	start := time.Now()
+	err := in.inner.Save(objectID, blob)
+	in.observe(OpSave, start, err)
+	return err
+}
+
+func (in *Instrumented) Delete(objectID ID) error {
+	start := time.Now()
+	err := in.inner.Delete(objectID)
+	in.observe(OpDelete, start, err)
+	if err == nil {
+		in.countItems(-1)
+	}
+	return err
+}
@@ -0,0 +1,58 @@
+package store
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeRecorder struct {
+	lock   sync.Mutex
+	ops    map[string]int
+	errors map[string]int
+	items  int
+}
+
+func (fr *fakeRecorder) ObserveOp(op string, elapsed time.Duration, err error) {
+	fr.lock.Lock()
+	defer fr.lock.Unlock()
+	fr.ops[op]++
+	if err != nil {
+		fr.errors[op]++
+	}
+}
+
+func (fr *fakeRecorder) SetItems(count int) {
+	fr.lock.Lock()
+	defer fr.lock.Unlock()
+	fr.items = count
+}
+
+func TestInstrumented(t *testing.T) {
+	mem, err := NewMemory()
+	require.NoError(t, err)
+	require.NoError(t, mem.Create("1", Blob("foo")))
+	rec := &fakeRecorder{ops: map[string]int{}, errors: map[string]int{}}
+	in := NewInstrumented(mem, rec)
+
+	_, err = in.LoadAll()
+	require.NoError(t, err)
+	assert.Equal(t, 1, rec.items)
+
+	require.NoError(t, in.Create("2", Blob("bar")))
+	assert.Error(t, in.Create("2", Blob("bar")))
+	assert.Equal(t, 2, rec.items)
+
+	_, err = in.Load("2")
+	assert.NoError(t, err)
+	require.NoError(t, in.Save("2", Blob("baz")))
+	require.NoError(t, in.Delete("1"))
+	assert.Error(t, in.Delete("1"))
+	assert.Equal(t, 1, rec.items)
+
+	assert.Equal(t, map[string]int{OpLoadAll: 1, OpCreate: 2, OpLoad: 1, OpSave: 1, OpDelete: 2}, rec.ops)
+	assert.Equal(t, map[string]int{OpCreate: 1, OpDelete: 1}, rec.errors)
+}
@@ -0,0 +1,290 @@
+package store
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+var _ Storage = &Memory{}
+var _ Batcher = &Memory{}
+var _ Versioner = &Memory{}
+var _ Transactioner = &Memory{}
+var _ Stater = &Memory{}
+var _ importer = &Memory{}
+var _ ChangeTracker = &Memory{}
+
+// Memory is a non persistent, thread safe, Storage which keeps all the blobs in memory.
+// Unlike the fake store, it behaves like a real backend, so it is suitable for ephemeral
+// ("scratch") usage and for fast tests of the higher layers.
+type Memory struct {
+	lock  sync.RWMutex
+	blobs map[ID]Blob
+	meta  map[ID]memoryMeta
+	// seq is the last change sequence assigned
+	seq uint64
+	// tombstones holds the change sequence of the deleted items
+	tombstones map[ID]uint64
+}
+
+type memoryMeta struct {
+	rev     Revision
+	created time.Time
+	updated time.Time
+	seq     uint64
+}
+
+// NewMemory creates a new empty Memory store. Never fails; the error is returned
+// for consistency with the other backends.
+func NewMemory() (*Memory, error) {
+	return &Memory{
+		blobs:      make(map[ID]Blob),
+		meta:       make(map[ID]memoryMeta),
+		tombstones: make(map[ID]uint64),
+	}, nil
+}
+
+func (mm *Memory) Close() error {
+	return nil
+}
+
+func (mm *Memory) Create(objectID ID, data Blob) error {
+	mm.lock.Lock()
+	defer mm.lock.Unlock()
+	if _, ok := mm.blobs[objectID]; ok {
+		return fmt.Errorf("item with id %v already exists", objectID)
+	}
+	mm.put(objectID, cloneBlob(data))
+	return nil
+}
+
+func (mm *Memory) LoadAll() ([]Item, error) {
+	mm.lock.RLock()
+	defer mm.lock.RUnlock()
+	res := make([]Item, 0, len(mm.blobs))
+	for id, blob := range mm.blobs {
+		res = append(res, Item{ID: id, Blob: cloneBlob(blob)})
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].ID < res[j].ID
+	})
+	return res, nil
+}
+
+func (mm *Memory) Load(objectID ID) (Blob, error) {
+	mm.lock.RLock()
+	defer mm.lock.RUnlock()
+	blob, ok := mm.blobs[objectID]
+	if !ok {
+		return nil, ErrNotFound{ID: objectID}
+	}
+	return cloneBlob(blob), nil
+}
+
+func (mm *Memory) Save(objectID ID, blob Blob) error {
+	mm.lock.Lock()
+	defer mm.lock.Unlock()
+	if _, ok := mm.blobs[objectID]; !ok {
+		return ErrNotFound{ID: objectID}
+	}
+	mm.put(objectID, cloneBlob(blob))
+	return nil
+}
+
+func (mm *Memory) Delete(objectID ID) error {
+	mm.lock.Lock()
+	defer mm.lock.Unlock()
+	if _, ok := mm.blobs[objectID]; !ok {
+		return ErrNotFound{ID: objectID}
+	}
+	mm.remove(objectID)
+	return nil
+}
+
+func (mm *Memory) CreateMany(items []Item) error {
+	mm.lock.Lock()
+	defer mm.lock.Unlock()
+	seen := make(map[ID]struct{}, len(items))
+	for _, item := range items {
+		_, found := mm.blobs[item.ID]
+		_, dup := seen[item.ID]
+		if found || dup {
+			return fmt.Errorf("item with id %v already exists", item.ID)
+		}
+		seen[item.ID] = struct{}{}
+	}
+	for _, item := range items {
+		mm.put(item.ID, cloneBlob(item.Blob))
+	}
+	return nil
+}
+
+func (mm *Memory) SaveAll(items []Item) error {
+	mm.lock.Lock()
+	defer mm.lock.Unlock()
+	for _, item := range items {
+		if _, ok := mm.blobs[item.ID]; !ok {
+			return ErrNotFound{ID: item.ID}
+		}
+	}
+	for _, item := range items {
+		mm.put(item.ID, cloneBlob(item.Blob))
+	}
+	return nil
+}
+
+func (mm *Memory) DeleteAll(ids []ID) error {
+	mm.lock.Lock()
+	defer mm.lock.Unlock()
+	for _, id := range ids {
+		if _, ok := mm.blobs[id]; !ok {
+			return ErrNotFound{ID: id}
+		}
+	}
+	for _, id := range ids {
+		mm.remove(id)
+	}
+	return nil
+}
+
+func (mm *Memory) LoadRev(objectID ID) (Blob, Revision, error) {
+	mm.lock.RLock()
+	defer mm.lock.RUnlock()
+	blob, ok := mm.blobs[objectID]
+	if !ok {
+		return nil, 0, ErrNotFound{ID: objectID}
+	}
+	return cloneBlob(blob), mm.meta[objectID].rev, nil
+}
+
+func (mm *Memory) SaveIf(objectID ID, blob Blob, expected Revision) (Revision, error) {
+	mm.lock.Lock()
+	defer mm.lock.Unlock()
+	if _, ok := mm.blobs[objectID]; !ok {
+		return 0, ErrNotFound{ID: objectID}
+	}
+	if rev := mm.meta[objectID].rev; rev != expected {
+		return 0, ErrConflict{ID: objectID, Expected: expected, Actual: rev}
+	}
+	mm.put(objectID, cloneBlob(blob))
+	return mm.meta[objectID].rev, nil
+}
+
+func (mm *Memory) Stat(objectID ID) (ItemInfo, error) {
+	mm.lock.RLock()
+	defer mm.lock.RUnlock()
+	blob, ok := mm.blobs[objectID]
+	if !ok {
+		return ItemInfo{}, ErrNotFound{ID: objectID}
+	}
+	meta := mm.meta[objectID]
+	return ItemInfo{
+		ID:       objectID,
+		Size:     len(blob),
+		Created:  meta.created,
+		Updated:  meta.updated,
+		Checksum: Checksum(blob),
+		Revision: meta.rev,
+	}, nil
+}
+
+// put creates or updates a item, keeping its metadata up to date.
+// Must be called with the lock held.
+func (mm *Memory) put(objectID ID, blob Blob) {
+	now := time.Now()
+	meta, ok := mm.meta[objectID]
+	if !ok {
+		meta.created = now
+	}
+	meta.rev++
+	meta.updated = now
+	mm.seq++
+	meta.seq = mm.seq
+	mm.blobs[objectID] = blob
+	mm.meta[objectID] = meta
+	delete(mm.tombstones, objectID)
+}
+
+func (mm *Memory) importItem(item Item, info ItemInfo) error {
+	mm.lock.Lock()
+	defer mm.lock.Unlock()
+	if _, ok := mm.blobs[item.ID]; ok {
+		return fmt.Errorf("item with id %v already exists", item.ID)
+	}
+	info = importedMeta(info)
+	mm.seq++
+	mm.blobs[item.ID] = cloneBlob(item.Blob)
+	mm.meta[item.ID] = memoryMeta{rev: info.Revision, created: info.Created, updated: info.Updated, seq: mm.seq}
+	delete(mm.tombstones, item.ID)
+	return nil
+}
+
+// remove deletes a item along with its metadata. Must be called with the lock held.
+func (mm *Memory) remove(objectID ID) {
+	delete(mm.blobs, objectID)
+	delete(mm.meta, objectID)
+	mm.seq++
+	mm.tombstones[objectID] = mm.seq
+}
+
+func (mm *Memory) Changes(since uint64) ([]Change, uint64, error) {
+	mm.lock.RLock()
+	defer mm.lock.RUnlock()
+	var res []Change
+	for id, meta := range mm.meta {
+		if since == 0 || meta.seq > since {
+			res = append(res, Change{ID: id, Seq: meta.seq})
+		}
+	}
+	for id, seq := range mm.tombstones {
+		if seq > since {
+			res = append(res, Change{ID: id, Seq: seq, Deleted: true})
+		}
+	}
+	sortChanges(res)
+	return res, mm.seq, nil
+}
+
+func (mm *Memory) Begin() (Tx, error) {
+	return &stagedTx{apply: mm.applyTx}, nil
+}
+
+// applyTx validates all the operations first, and applies them only if all are valid
+func (mm *Memory) applyTx(ops []txOp) error {
+	mm.lock.Lock()
+	defer mm.lock.Unlock()
+
+	// tracks the existence of the items as changed by the operations
+	exists := make(map[ID]bool)
+	for _, op := range ops {
+		found, ok := exists[op.id]
+		if !ok {
+			_, found = mm.blobs[op.id]
+		}
+		if op.kind == txCreate && found {
+			return fmt.Errorf("item with id %v already exists", op.id)
+		}
+		if op.kind != txCreate && !found {
+			return ErrNotFound{ID: op.id}
+		}
+		exists[op.id] = (op.kind != txDelete)
+	}
+
+	for _, op := range ops {
+		if op.kind == txDelete {
+			mm.remove(op.id)
+		} else {
+			mm.put(op.id, op.blob)
+		}
+	}
+	return nil
+}
+
+// cloneBlob returns a copy of the given blob, so callers can't alter the stored data
+func cloneBlob(b Blob) Blob {
+	if b == nil {
+		return nil
+	}
+	return append(Blob{}, b...)
+}
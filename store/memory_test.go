@@ -0,0 +1,71 @@
+package store
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryCreateSaveDelete(t *testing.T) {
+	st, err := NewMemory()
+	require.NoError(t, err)
+
+	assert.NoError(t, st.Create("1", Blob("foobar")))
+	assert.Error(t, st.Create("1", Blob("foobar")))
+
+	assert.NoError(t, st.Save("1", Blob("fizzbuzz")))
+	blob, err := st.Load("1")
+	assert.NoError(t, err)
+	assert.Equal(t, "fizzbuzz", string(blob))
+
+	assert.NoError(t, st.Delete("1"))
+	_, err = st.Load("1")
+	assert.ErrorIs(t, err, ErrNotFound{ID: "1"})
+	assert.ErrorIs(t, st.Save("1", Blob("foobar")), ErrNotFound{ID: "1"})
+	assert.ErrorIs(t, st.Delete("1"), ErrNotFound{ID: "1"})
+}
+
+func TestMemoryBlobsAreCopied(t *testing.T) {
+	st, err := NewMemory()
+	require.NoError(t, err)
+
+	data := Blob("foobar")
+	assert.NoError(t, st.Create("1", data))
+	data[0] = 'F'
+
+	blob, err := st.Load("1")
+	assert.NoError(t, err)
+	assert.Equal(t, "foobar", string(blob))
+	blob[0] = 'F'
+
+	blob, err = st.Load("1")
+	assert.NoError(t, err)
+	assert.Equal(t, "foobar", string(blob))
+}
+
+func TestMemoryConcurrentAccess(t *testing.T) {
+	st, err := NewMemory()
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			id := ID(fmt.Sprintf("%02d", i))
+			assert.NoError(t, st.Create(id, Blob("foo")))
+			assert.NoError(t, st.Save(id, Blob("bar")))
+			_, err := st.LoadAll()
+			assert.NoError(t, err)
+		}(i)
+	}
+	wg.Wait()
+
+	items, err := st.LoadAll()
+	assert.NoError(t, err)
+	assert.Len(t, items, 16)
+	assert.Equal(t, ID("00"), items[0].ID)
+}
@@ -0,0 +1,18 @@
+package store
+
+import "strings"
+
+// MetaPrefix marks the IDs of the items holding the metadata of the application, e.g.
+// its registries, rather than its objects. Like the quarantined items, they are stored
+// along with the objects, but must not be processed as objects.
+const MetaPrefix = ".meta/"
+
+// MetaID returns the ID of the metadata item with the given name
+func MetaID(name string) ID {
+	return ID(MetaPrefix + name)
+}
+
+// IsMeta returns true if the given ID belongs to a metadata item
+func IsMeta(id ID) bool {
+	return strings.HasPrefix(string(id), MetaPrefix)
+}
@@ -0,0 +1,3 @@
+// Package metrics provides ready-made store.Recorder implementations, publishing
+// the storage metrics through expvar or Prometheus
+package metrics
@@ -0,0 +1,40 @@
+package metrics
+
+import (
+	"expvar"
+	"time"
+
+	"github.com/gotestbootcamp/go-todo-app/store"
+)
+
+var _ store.Recorder = &Expvar{}
+
+// Expvar publishes the storage metrics as a expvar map, served by the expvar
+// handler on /debug/vars. For each operation, the map holds:
+// - <op>_count: the number of operations performed
+// - <op>_errors: the number of operations failed
+// - <op>_nanos: the total time spent in the operations, in nanoseconds
+// plus items: the number of items in the storage.
+type Expvar struct {
+	vars *expvar.Map
+}
+
+// NewExpvar publishes a new expvar map with the given name.
+// Like expvar.NewMap, panics if the name is already in use.
+func NewExpvar(name string) *Expvar {
+	return &Expvar{vars: expvar.NewMap(name)}
+}
+
+func (ev *Expvar) ObserveOp(op string, elapsed time.Duration, err error) {
+	ev.vars.Add(op+"_count", 1)
+	ev.vars.Add(op+"_nanos", elapsed.Nanoseconds())
+	if err != nil {
+		ev.vars.Add(op+"_errors", 1)
+	}
+}
+
+func (ev *Expvar) SetItems(count int) {
+	items := new(expvar.Int)
+	items.Set(int64(count))
+	ev.vars.Set("items", items)
+}
@@ -0,0 +1,51 @@
+package metrics
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gotestbootcamp/go-todo-app/store"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpvar(t *testing.T) {
+	ev := NewExpvar("test_store")
+	ev.ObserveOp(store.OpLoad, time.Millisecond, nil)
+	ev.ObserveOp(store.OpLoad, time.Millisecond, errors.New("failed"))
+	ev.SetItems(3)
+
+	assert.Equal(t, "2", ev.vars.Get("load_count").String())
+	assert.Equal(t, "1", ev.vars.Get("load_errors").String())
+	assert.Equal(t, "2000000", ev.vars.Get("load_nanos").String())
+	assert.Equal(t, "3", ev.vars.Get("items").String())
+}
+
+func TestPrometheus(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	pm, err := NewPrometheus(reg, "test")
+	require.NoError(t, err)
+	pm.ObserveOp(store.OpSave, time.Millisecond, nil)
+	pm.ObserveOp(store.OpSave, time.Millisecond, errors.New("failed"))
+	pm.SetItems(3)
+
+	expected := `
+# HELP test_store_items Number of items in the storage.
+# TYPE test_store_items gauge
+test_store_items 3
+# HELP test_store_operation_errors_total Number of the failed storage operations.
+# TYPE test_store_operation_errors_total counter
+test_store_operation_errors_total{op="save"} 1
+`
+	assert.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(expected),
+		"test_store_items", "test_store_operation_errors_total"))
+	assert.Equal(t, 1, testutil.CollectAndCount(pm.duration))
+
+	// collectors can't be registered twice
+	_, err = NewPrometheus(reg, "test")
+	assert.Error(t, err)
+}
@@ -0,0 +1,63 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/gotestbootcamp/go-todo-app/store"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var _ store.Recorder = &Prometheus{}
+
+// Prometheus exposes the storage metrics as Prometheus collectors:
+// - <namespace>_store_operation_duration_seconds: histogram of the operations latency, by operation
+// - <namespace>_store_operation_errors_total: counter of the failed operations, by operation
+// - <namespace>_store_items: gauge of the number of items in the storage
+type Prometheus struct {
+	duration *prometheus.HistogramVec
+	errors   *prometheus.CounterVec
+	items    prometheus.Gauge
+}
+
+// NewPrometheus creates the collectors and registers them in the given registerer,
+// e.g. prometheus.DefaultRegisterer
+func NewPrometheus(reg prometheus.Registerer, namespace string) (*Prometheus, error) {
+	pm := &Prometheus{
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "store",
+			Name:      "operation_duration_seconds",
+			Help:      "Latency of the storage operations.",
+			Buckets:   prometheus.ExponentialBuckets(0.0001, 4, 10),
+		}, []string{"op"}),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "store",
+			Name:      "operation_errors_total",
+			Help:      "Number of the failed storage operations.",
+		}, []string{"op"}),
+		items: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "store",
+			Name:      "items",
+			Help:      "Number of items in the storage.",
+		}),
+	}
+	for _, coll := range []prometheus.Collector{pm.duration, pm.errors, pm.items} {
+		if err := reg.Register(coll); err != nil {
+			return nil, err
+		}
+	}
+	return pm, nil
+}
+
+func (pm *Prometheus) ObserveOp(op string, elapsed time.Duration, err error) {
+	pm.duration.WithLabelValues(op).Observe(elapsed.Seconds())
+	if err != nil {
+		pm.errors.WithLabelValues(op).Inc()
+	}
+}
+
+func (pm *Prometheus) SetItems(count int) {
+	pm.items.Set(float64(count))
+}
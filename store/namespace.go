@@ -0,0 +1,107 @@
+package store
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// DefaultList is the list holding the items created outside of any list
+const DefaultList = ""
+
+// listSeparator separates the list name from the ID of the item in the list
+const listSeparator = "/"
+
+// Lister is implemented by the storages which can efficiently look up the items of a list.
+// The items of a list are the ones whose ID is qualified by the list name, see ListID.
+type Lister interface {
+	// LoadAllIn returns all the items in the given list, sorted by ID
+	LoadAllIn(list string) ([]Item, error)
+	// Lists returns the names of the lists holding at least one item, sorted
+	Lists() ([]string, error)
+}
+
+// ValidateList checks that the given name can be used as a list name:
+// it must not be empty, contain the separator, or start with a dot, since
+// the names starting with a dot are reserved for internal use.
+func ValidateList(list string) error {
+	if list == "" || strings.Contains(list, listSeparator) || strings.HasPrefix(list, ".") {
+		return fmt.Errorf("invalid list name %q", list)
+	}
+	return nil
+}
+
+// ListID returns the ID of the item with the given ID in the given list.
+// The items of DefaultList have unqualified IDs.
+func ListID(list string, objectID ID) ID {
+	if list == DefaultList {
+		return objectID
+	}
+	return ID(list + listSeparator + string(objectID))
+}
+
+// SplitListID splits the given ID in the list name and the ID of the item in the list.
+// Returns DefaultList for the IDs not qualified by a valid list name.
+func SplitListID(objectID ID) (string, ID) {
+	list, local, found := strings.Cut(string(objectID), listSeparator)
+	if !found || ValidateList(list) != nil {
+		return DefaultList, objectID
+	}
+	return list, ID(local)
+}
+
+// CreateIn creates the given item in the given list. The item can then be accessed
+// through the ID returned by ListID.
+func CreateIn(st Storage, list string, objectID ID, data Blob) error {
+	if err := ValidateList(list); err != nil {
+		return err
+	}
+	return st.Create(ListID(list, objectID), data)
+}
+
+// LoadAllIn returns all the items in the given list, with their qualified IDs.
+// DefaultList selects the items created outside of any list.
+// If the storage is not a Lister, all the items are scanned.
+func LoadAllIn(st Storage, list string) ([]Item, error) {
+	if list != DefaultList {
+		if err := ValidateList(list); err != nil {
+			return nil, err
+		}
+	}
+	if ls, ok := st.(Lister); ok {
+		return ls.LoadAllIn(list)
+	}
+	res := []Item{}
+	err := Walk(st, func(item Item) error {
+		if itemList, _ := SplitListID(item.ID); itemList == list {
+			res = append(res, item)
+		}
+		return nil
+	})
+	return res, err
+}
+
+// Lists returns the names of the lists holding at least one item, sorted.
+// DefaultList is never included.
+// If the storage is not a Lister, all the items are scanned.
+func Lists(st Storage) ([]string, error) {
+	if ls, ok := st.(Lister); ok {
+		return ls.Lists()
+	}
+	seen := map[string]bool{}
+	err := Walk(st, func(item Item) error {
+		if list, _ := SplitListID(item.ID); list != DefaultList {
+			seen[list] = true
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	res := make([]string, 0, len(seen))
+	for list := range seen {
+		res = append(res, list)
+	}
+	sort.Strings(res)
+	return res, nil
+}
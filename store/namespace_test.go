@@ -0,0 +1,75 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitListID(t *testing.T) {
+	for _, tc := range []struct {
+		id    ID
+		list  string
+		local ID
+	}{
+		{"1", DefaultList, "1"},
+		{"work/1", "work", "1"},
+		{"work/sub/1", "work", "sub/1"},
+		{".trash/1", DefaultList, ".trash/1"},
+		{"/1", DefaultList, "/1"},
+	} {
+		list, local := SplitListID(tc.id)
+		assert.Equal(t, tc.list, list, tc.id)
+		assert.Equal(t, tc.local, local, tc.id)
+		if list != DefaultList {
+			assert.Equal(t, tc.id, ListID(list, local))
+		}
+	}
+}
+
+func testLists(t *testing.T, st Storage) {
+	require.NoError(t, CreateIn(st, "work", "1", Blob("report")))
+	require.NoError(t, CreateIn(st, "work", "2", Blob("meeting")))
+	require.NoError(t, CreateIn(st, "groceries", "1", Blob("milk")))
+	require.NoError(t, st.Create("1", Blob("unlisted")))
+	require.NoError(t, st.Create(".trash/personal/1", Blob("trashed")))
+	assert.Error(t, CreateIn(st, "work", "1", Blob("again")))
+	assert.Error(t, CreateIn(st, "", "3", Blob("foo")))
+	assert.Error(t, CreateIn(st, ".trash", "3", Blob("foo")))
+
+	items, err := LoadAllIn(st, "work")
+	require.NoError(t, err)
+	assert.Equal(t, []Item{{ID: "work/1", Blob: Blob("report")}, {ID: "work/2", Blob: Blob("meeting")}}, items)
+
+	items, err = LoadAllIn(st, "personal")
+	require.NoError(t, err)
+	assert.Empty(t, items)
+
+	items, err = LoadAllIn(st, DefaultList)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []Item{{ID: "1", Blob: Blob("unlisted")}, {ID: ".trash/personal/1", Blob: Blob("trashed")}}, items)
+
+	lists, err := Lists(st)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"groceries", "work"}, lists)
+
+	blob, err := st.Load(ListID("groceries", "1"))
+	require.NoError(t, err)
+	assert.Equal(t, "milk", string(blob))
+}
+
+func TestListsMemory(t *testing.T) {
+	st, err := NewMemory()
+	require.NoError(t, err)
+	testLists(t, st)
+}
+
+func TestListsSQLite(t *testing.T) {
+	st, _ := newTestSQLite(t)
+	testLists(t, st)
+}
+
+func TestListsPostgres(t *testing.T) {
+	testLists(t, newTestPostgres(t))
+}
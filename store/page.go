@@ -0,0 +1,91 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// ErrStopWalk can be returned by a Walk callback to stop the walk early without
+// reporting an error
+var ErrStopWalk = errors.New("stop walk")
+
+// Pager is implemented by the storages which can natively load their content incrementally.
+// Items are always processed in ID order.
+// Use the LoadPage and Walk functions to work with any Storage.
+type Pager interface {
+	// LoadPage returns at most limit items, skipping the first offset items.
+	// A non positive limit means no limit.
+	LoadPage(offset, limit int) ([]Item, error)
+	// Walk calls the given function on each item in turn, stopping at the first error.
+	// The function must not call back into the storage.
+	Walk(func(Item) error) error
+}
+
+// LoadPage returns at most limit items from the storage, skipping the first offset items,
+// sorted by ID. A non positive limit means no limit.
+// If the storage is not a Pager, falls back to loading all the items and slicing them.
+func LoadPage(st Storage, offset, limit int) ([]Item, error) {
+	if offset < 0 {
+		return nil, fmt.Errorf("invalid page offset: %d", offset)
+	}
+	if pg, ok := st.(Pager); ok {
+		return pg.LoadPage(offset, limit)
+	}
+	items, err := loadAllSorted(st)
+	if err != nil {
+		return nil, err
+	}
+	return pageOf(items, offset, limit), nil
+}
+
+// Walk calls the given function on each item in the storage in ID order, stopping
+// at the first error, which is returned, unless it is ErrStopWalk.
+// If the storage is not a Pager, falls back to loading all the items first.
+func Walk(st Storage, fn func(Item) error) error {
+	var err error
+	if pg, ok := st.(Pager); ok {
+		err = pg.Walk(fn)
+	} else {
+		err = walkAll(st, fn)
+	}
+	if errors.Is(err, ErrStopWalk) {
+		return nil
+	}
+	return err
+}
+
+func walkAll(st Storage, fn func(Item) error) error {
+	items, err := loadAllSorted(st)
+	if err != nil {
+		return err
+	}
+	for _, item := range items {
+		if err := fn(item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func loadAllSorted(st Storage) ([]Item, error) {
+	items, err := st.LoadAll()
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].ID < items[j].ID
+	})
+	return items, nil
+}
+
+func pageOf(items []Item, offset, limit int) []Item {
+	if offset >= len(items) {
+		return []Item{}
+	}
+	items = items[offset:]
+	if limit > 0 && limit < len(items) {
+		items = items[:limit]
+	}
+	return items
+}
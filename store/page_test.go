@@ -0,0 +1,69 @@
+package store
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadPage(t *testing.T) {
+	for name, st := range batchTestStorages(t) {
+		t.Run(name, func(t *testing.T) {
+			for i := 4; i >= 0; i-- {
+				require.NoError(t, st.Create(ID(fmt.Sprintf("%d", i)), Blob("foo")))
+			}
+
+			items, err := LoadPage(st, 1, 2)
+			assert.NoError(t, err)
+			assert.Equal(t, []ID{"1", "2"}, itemIDs(items))
+
+			items, err = LoadPage(st, 3, 0)
+			assert.NoError(t, err)
+			assert.Equal(t, []ID{"3", "4"}, itemIDs(items))
+
+			items, err = LoadPage(st, 5, 10)
+			assert.NoError(t, err)
+			assert.Empty(t, items)
+
+			_, err = LoadPage(st, -1, 10)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestWalk(t *testing.T) {
+	for name, st := range batchTestStorages(t) {
+		t.Run(name, func(t *testing.T) {
+			for i := 0; i < 5; i++ {
+				require.NoError(t, st.Create(ID(fmt.Sprintf("%d", i)), Blob("foo")))
+			}
+
+			var seen []ID
+			err := Walk(st, func(item Item) error {
+				seen = append(seen, item.ID)
+				if len(seen) == 3 {
+					return ErrStopWalk
+				}
+				return nil
+			})
+			assert.NoError(t, err)
+			assert.Equal(t, []ID{"0", "1", "2"}, seen)
+
+			expErr := fmt.Errorf("injected walk error")
+			err = Walk(st, func(item Item) error {
+				return expErr
+			})
+			assert.ErrorIs(t, err, expErr)
+		})
+	}
+}
+
+func itemIDs(items []Item) []ID {
+	ids := make([]ID, 0, len(items))
+	for _, item := range items {
+		ids = append(ids, item.ID)
+	}
+	return ids
+}
@@ -0,0 +1,100 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+)
+
+func newTestPostgres(t *testing.T) *Postgres {
+	skipWithoutDocker(t)
+
+	ctx := context.Background()
+	pgC, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
+		ContainerRequest: testcontainers.ContainerRequest{
+			Image:        "postgres:16-alpine",
+			ExposedPorts: []string{"5432/tcp"},
+			Env: map[string]string{
+				"POSTGRES_USER":     "todo",
+				"POSTGRES_PASSWORD": "todo",
+				"POSTGRES_DB":       "todo",
+			},
+			WaitingFor: wait.ForLog("database system is ready to accept connections").WithOccurrence(2),
+		},
+		Started: true,
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { pgC.Terminate(ctx) })
+	endpoint, err := pgC.PortEndpoint(ctx, "5432/tcp", "")
+	require.NoError(t, err)
+
+	st, err := NewPostgres(fmt.Sprintf("postgres://todo:todo@%s/todo?sslmode=disable", endpoint))
+	require.NoError(t, err)
+	t.Cleanup(func() { st.Close() })
+	return st
+}
+
+func TestPostgres(t *testing.T) {
+	st := newTestPostgres(t)
+
+	require.NoError(t, st.Create("1", Blob("foo")))
+	assert.Error(t, st.Create("1", Blob("bar")))
+
+	blob, err := st.Load("1")
+	assert.NoError(t, err)
+	assert.Equal(t, "foo", string(blob))
+
+	require.NoError(t, st.Save("1", Blob("bar")))
+	assert.ErrorIs(t, st.Save("2", Blob("bar")), ErrNotFound{ID: "2"})
+
+	rev, err := SaveIf(st, "1", Blob("baz"), 2)
+	assert.NoError(t, err)
+	assert.Equal(t, Revision(3), rev)
+	_, err = SaveIf(st, "1", Blob("quux"), 2)
+	var conflict ErrConflict
+	assert.True(t, errors.As(err, &conflict))
+
+	info, err := Stat(st, "1")
+	assert.NoError(t, err)
+	assert.Equal(t, Checksum(Blob("baz")), info.Checksum)
+	assert.Equal(t, 3, info.Size)
+
+	require.NoError(t, CreateMany(st, []Item{{ID: "2", Blob: Blob("a")}, {ID: "3", Blob: Blob("b")}}))
+	items, err := LoadPage(st, 1, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, []Item{{ID: "2", Blob: Blob("a")}}, items)
+
+	require.NoError(t, st.Delete("1"))
+	assert.ErrorIs(t, st.Delete("1"), ErrNotFound{ID: "1"})
+
+	id1, err := NextID(st)
+	assert.NoError(t, err)
+	id2, err := NextID(st)
+	assert.NoError(t, err)
+	assert.NotEqual(t, id1, id2)
+}
+
+func TestPostgresTx(t *testing.T) {
+	st := newTestPostgres(t)
+
+	tx, err := Begin(st)
+	require.NoError(t, err)
+	require.NoError(t, tx.Create("1", Blob("foo")))
+	require.NoError(t, tx.Rollback())
+	assert.ErrorIs(t, tx.Commit(), ErrTxDone)
+	_, err = st.Load("1")
+	assert.ErrorIs(t, err, ErrNotFound{ID: "1"})
+}
+
+func TestNextIDUnsupported(t *testing.T) {
+	mem, err := NewMemory()
+	require.NoError(t, err)
+	_, err = NextID(mem)
+	assert.ErrorIs(t, err, ErrUnsupported)
+}
@@ -2,63 +2,323 @@ package store
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"log/slog"
+	"strconv"
+	"sync"
+	"time"
 
 	"github.com/redis/go-redis/v9"
 )
 
 var _ Storage = &Redis{}
+var _ StorageContext = &Redis{}
+var _ Sequencer = &Redis{}
+var _ Pinger = &Redis{}
 
+const (
+	// redisItemsKey is the hash holding the item blobs, keyed by item ID
+	redisItemsKey = "todo:items"
+	// redisSeqKey is the counter used to allocate new IDs
+	redisSeqKey = "todo:seq"
+	// redisLockKey holds the token of the process owning the storage
+	redisLockKey = "todo:lock"
+	// redisVersionKey holds the version of the layout of the items, see redisMigrate
+	redisVersionKey = "todo:version"
+	// redisVersion is the current version of the layout: 1 stores the items in the hash of
+	// redisItemsKey, rather than in a key each
+	redisVersion = 1
+)
+
+// ErrLocked is returned when the storage is owned by another process
+var ErrLocked = errors.New("storage owned by another process")
+
+// redisSaveScript updates an item only if it exists
+var redisSaveScript = redis.NewScript(`
+if redis.call("HEXISTS", KEYS[1], ARGV[1]) == 0 then
+	return 0
+end
+redis.call("HSET", KEYS[1], ARGV[1], ARGV[2])
+return 1
+`)
+
+// redisMoveScript moves the item stored in a key of its own, with the layout of version 0, to the hash
+// of the items. The item already in the hash, written since, is kept.
+var redisMoveScript = redis.NewScript(`
+if redis.call("TYPE", KEYS[1]).ok ~= "string" then
+	return 0
+end
+redis.call("HSETNX", KEYS[2], KEYS[1], redis.call("GET", KEYS[1]))
+redis.call("DEL", KEYS[1])
+return 1
+`)
+
+// redisRenewScript extends the lock lease only if the lock is still ours
+var redisRenewScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) ~= ARGV[1] then
+	return 0
+end
+redis.call("PEXPIRE", KEYS[1], ARGV[2])
+return 1
+`)
+
+// redisReleaseScript deletes the lock only if it is still ours
+var redisReleaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) ~= ARGV[1] then
+	return 0
+end
+redis.call("DEL", KEYS[1])
+return 1
+`)
+
+// RedisPersistence selects how the redis server persists its dataset
+type RedisPersistence string
+
+const (
+	// RedisPersistenceNone disables persistence: the data is lost when the server restarts
+	RedisPersistenceNone RedisPersistence = "none"
+	// RedisPersistenceRDB takes periodic snapshots of the dataset
+	RedisPersistenceRDB RedisPersistence = "rdb"
+	// RedisPersistenceAOF logs every write, syncing the log once per second
+	RedisPersistenceAOF RedisPersistence = "aof"
+)
+
+// Redis stores all the items in a single redis hash. All the operations are atomic
+// on the server side, so many processes can share the same redis database.
 type Redis struct {
 	rdb *redis.Client
+
+	lock      sync.Mutex
+	lockToken string
+	stopRenew chan struct{}
+	renewDone chan struct{}
 }
 
+// NewRedis connects to the redis database, and migrates the items stored with the layout of the
+// previous versions, see redisMigrate
 func NewRedis(url, password string, db int) (*Redis, error) {
-	return &Redis{
-		rdb: redis.NewClient(&redis.Options{
-			Addr:     url,
-			Password: password,
-			DB:       db,
-		}),
-	}, nil
+	rdb := redis.NewClient(&redis.Options{
+		Addr:     url,
+		Password: password,
+		DB:       db,
+	})
+	if err := redisMigrate(context.Background(), rdb); err != nil {
+		rdb.Close()
+		return nil, fmt.Errorf("redis: %w", err)
+	}
+	return &Redis{rdb: rdb}, nil
 }
 
-func (rd *Redis) Close() error {
-	return rd.rdb.Close()
+// redisMigrate moves the items stored with the layout of version 0, a key each, to the hash of the
+// items, then records the version of the layout. Every key of the database but the ones of the storage
+// itself was an item, like LoadAll returned them.
+func redisMigrate(ctx context.Context, rdb *redis.Client) error {
+	version, err := rdb.Get(ctx, redisVersionKey).Int()
+	if err != nil && err != redis.Nil {
+		return err
+	}
+	if version > redisVersion {
+		return fmt.Errorf("layout version %d is newer than supported version %d", version, redisVersion)
+	}
+	if version == redisVersion {
+		return nil
+	}
+	var keys []string
+	iter := rdb.Scan(ctx, 0, "", 0).Iterator()
+	for iter.Next(ctx) {
+		switch key := iter.Val(); key {
+		case redisItemsKey, redisSeqKey, redisLockKey, redisVersionKey:
+		default:
+			keys = append(keys, key)
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return err
+	}
+	moved := 0
+	for _, key := range keys {
+		res, err := redisMoveScript.Run(ctx, rdb, []string{key, redisItemsKey}).Int()
+		if err != nil {
+			return fmt.Errorf("migration of %q failed: %w", key, err)
+		}
+		moved += res
+	}
+	if err := rdb.Set(ctx, redisVersionKey, redisVersion, 0).Err(); err != nil {
+		return err
+	}
+	slog.Info("store: redis: migrated", "version", redisVersion, "moved", moved)
+	return nil
 }
 
-func (rd *Redis) Create(objectID ID, data Blob) error {
-	_, err := rd.rdb.Get(context.Background(), string(objectID)).Result()
-	if err != nil && err != redis.Nil {
+// SetPersistence reconfigures the persistence of the redis server. This affects all
+// the databases of the server, and requires the CONFIG command to be enabled.
+func (rd *Redis) SetPersistence(ctx context.Context, mode RedisPersistence) error {
+	var params []string
+	switch mode {
+	case RedisPersistenceNone:
+		params = []string{"appendonly", "no", "save", ""}
+	case RedisPersistenceRDB:
+		params = []string{"appendonly", "no", "save", "3600 1 300 100 60 10000"}
+	case RedisPersistenceAOF:
+		params = []string{"appendonly", "yes", "appendfsync", "everysec"}
+	default:
+		return fmt.Errorf("unknown redis persistence mode %q", mode)
+	}
+	for idx := 0; idx < len(params); idx += 2 {
+		if err := rd.rdb.ConfigSet(ctx, params[idx], params[idx+1]).Err(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Acquire makes this process the owner of the storage, failing with ErrLocked if
+// another process owns it. The ownership is a lease, renewed in the background
+// until Close: if the process dies, the ownership expires after the lease duration.
+func (rd *Redis) Acquire(ctx context.Context, lease time.Duration) error {
+	rd.lock.Lock()
+	defer rd.lock.Unlock()
+	if rd.lockToken != "" {
+		return nil // already ours
+	}
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
 		return err
 	}
-	if err != redis.Nil { // The item is already there
-		return fmt.Errorf("item with id %v already exists", objectID)
+	token := hex.EncodeToString(buf)
+	ok, err := rd.rdb.SetNX(ctx, redisLockKey, token, lease).Result()
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrLocked
 	}
+	rd.lockToken = token
+	rd.stopRenew = make(chan struct{})
+	rd.renewDone = make(chan struct{})
+	go rd.renew(token, lease, rd.stopRenew, rd.renewDone)
+	return nil
+}
 
-	err = rd.rdb.Set(context.Background(), string(objectID), data, 0).Err()
+func (rd *Redis) renew(token string, lease time.Duration, stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+	ticker := time.NewTicker(lease / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			res, err := redisRenewScript.Run(context.Background(), rd.rdb, []string{redisLockKey}, token, lease.Milliseconds()).Int()
+			if err != nil {
+				slog.Error("store: redis: failed to renew the ownership", "error", err)
+				continue
+			}
+			if res == 0 {
+				slog.Error("store: redis: ownership lost")
+				return
+			}
+		}
+	}
+}
+
+// release gives up the ownership of the storage, if held
+func (rd *Redis) release(ctx context.Context) error {
+	rd.lock.Lock()
+	defer rd.lock.Unlock()
+	if rd.lockToken == "" {
+		return nil
+	}
+	close(rd.stopRenew)
+	<-rd.renewDone
+	token := rd.lockToken
+	rd.lockToken = ""
+	return redisReleaseScript.Run(ctx, rd.rdb, []string{redisLockKey}, token).Err()
+}
+
+// Ping checks that the redis server answers, and that the process still owns the storage, if it
+// acquired it. Fails with ErrLocked if the ownership was lost.
+func (rd *Redis) Ping(ctx context.Context) error {
+	if err := rd.rdb.Ping(ctx).Err(); err != nil {
+		return err
+	}
+	rd.lock.Lock()
+	token := rd.lockToken
+	rd.lock.Unlock()
+	if token == "" {
+		return nil
+	}
+	owner, err := rd.rdb.Get(ctx, redisLockKey).Result()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return err
+	}
+	if owner != token {
+		return fmt.Errorf("%w: ownership lost", ErrLocked)
+	}
+	return nil
+}
+
+func (rd *Redis) Close() error {
+	err := rd.release(context.Background())
+	if cerr := rd.rdb.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// NextID allocates a new ID incrementing a counter, so IDs are unique
+// among all the processes sharing the redis database
+func (rd *Redis) NextID() (ID, error) {
+	seq, err := rd.rdb.Incr(context.Background(), redisSeqKey).Result()
+	if err != nil {
+		return NullID, err
+	}
+	return ID(strconv.FormatInt(seq, 10)), nil
+}
+
+func (rd *Redis) Create(objectID ID, data Blob) error {
+	return rd.CreateCtx(context.Background(), objectID, data)
+}
+
+func (rd *Redis) CreateCtx(ctx context.Context, objectID ID, data Blob) error {
+	ok, err := rd.rdb.HSetNX(ctx, redisItemsKey, string(objectID), []byte(data)).Result()
 	if err != nil {
 		return err
 	}
+	if !ok {
+		return fmt.Errorf("item with id %v already exists", objectID)
+	}
 	return nil
 }
 
 func (rd *Redis) LoadAll() ([]Item, error) {
-	ctx := context.Background()
-	iter := rd.rdb.Scan(ctx, 0, "", 0).Iterator()
+	return rd.LoadAllCtx(context.Background())
+}
+
+func (rd *Redis) LoadAllCtx(ctx context.Context) ([]Item, error) {
 	res := []Item{}
+	iter := rd.rdb.HScan(ctx, redisItemsKey, 0, "", 0).Iterator()
 	for iter.Next(ctx) {
 		key := iter.Val()
-		val, _ := rd.rdb.Get(ctx, key).Result()
-		res = append(res, Item{ID: ID(key), Blob: Blob(val)})
+		if !iter.Next(ctx) {
+			break
+		}
+		res = append(res, Item{ID: ID(key), Blob: Blob(iter.Val())})
 	}
-	return res, nil
+	return res, iter.Err()
 }
 
 func (rd *Redis) Load(objectID ID) (Blob, error) {
-	data, err := rd.rdb.Get(context.Background(), string(objectID)).Result()
+	return rd.LoadCtx(context.Background(), objectID)
+}
+
+func (rd *Redis) LoadCtx(ctx context.Context, objectID ID) (Blob, error) {
+	data, err := rd.rdb.HGet(ctx, redisItemsKey, string(objectID)).Bytes()
 	if err == redis.Nil {
-		return nil, fmt.Errorf("id %s does not exist", objectID)
+		return nil, ErrNotFound{ID: objectID}
 	}
 	if err != nil {
 		return nil, err
@@ -67,30 +327,31 @@ func (rd *Redis) Load(objectID ID) (Blob, error) {
 }
 
 func (rd *Redis) Save(objectID ID, blob Blob) error {
-	// Non thread safe!
-	data, err := rd.rdb.Get(context.Background(), string(objectID)).Result()
-	if err == redis.Nil {
-		return fmt.Errorf("id %s does not exist", objectID)
-	}
+	return rd.SaveCtx(context.Background(), objectID, blob)
+}
 
-	err = rd.rdb.Set(context.Background(), string(objectID), data, 0).Err()
+func (rd *Redis) SaveCtx(ctx context.Context, objectID ID, blob Blob) error {
+	res, err := redisSaveScript.Run(ctx, rd.rdb, []string{redisItemsKey}, string(objectID), []byte(blob)).Int()
 	if err != nil {
 		return err
 	}
+	if res == 0 {
+		return ErrNotFound{ID: objectID}
+	}
 	return nil
 }
 
 func (rd *Redis) Delete(objectID ID) error {
-	// Non thread safe!
-	data, err := rd.rdb.Del(context.Background(), string(objectID)).Result()
-	if err == redis.Nil {
-		return fmt.Errorf("id %s does not exist", objectID)
-	}
+	return rd.DeleteCtx(context.Background(), objectID)
+}
 
-	err = rd.rdb.Set(context.Background(), string(objectID), data, 0).Err()
+func (rd *Redis) DeleteCtx(ctx context.Context, objectID ID) error {
+	count, err := rd.rdb.HDel(ctx, redisItemsKey, string(objectID)).Result()
 	if err != nil {
 		return err
 	}
-
+	if count == 0 {
+		return ErrNotFound{ID: objectID}
+	}
 	return nil
 }
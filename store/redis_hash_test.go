@@ -0,0 +1,105 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestRedis(t *testing.T) (*Redis, *miniredis.Miniredis) {
+	srv := miniredis.RunT(t)
+	rd, err := NewRedis(srv.Addr(), "", 0)
+	require.NoError(t, err)
+	t.Cleanup(func() { rd.Close() })
+	return rd, srv
+}
+
+func TestRedisHash(t *testing.T) {
+	rd, _ := newTestRedis(t)
+
+	require.NoError(t, rd.Create("1", Blob("foo")))
+	assert.Error(t, rd.Create("1", Blob("bar")))
+
+	blob, err := rd.Load("1")
+	assert.NoError(t, err)
+	assert.Equal(t, "foo", string(blob))
+	_, err = rd.Load("2")
+	assert.ErrorIs(t, err, ErrNotFound{ID: "2"})
+
+	require.NoError(t, rd.Save("1", Blob("bar")))
+	assert.ErrorIs(t, rd.Save("2", Blob("bar")), ErrNotFound{ID: "2"})
+	blob, err = rd.Load("1")
+	assert.NoError(t, err)
+	assert.Equal(t, "bar", string(blob))
+
+	require.NoError(t, rd.Create("2", Blob("baz")))
+	items, err := rd.LoadAll()
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []Item{{ID: "1", Blob: Blob("bar")}, {ID: "2", Blob: Blob("baz")}}, items)
+
+	require.NoError(t, rd.Delete("1"))
+	assert.ErrorIs(t, rd.Delete("1"), ErrNotFound{ID: "1"})
+
+	id1, err := NextID(rd)
+	assert.NoError(t, err)
+	id2, err := NextID(rd)
+	assert.NoError(t, err)
+	assert.Equal(t, []ID{"1", "2"}, []ID{id1, id2})
+}
+
+func TestRedisAcquire(t *testing.T) {
+	ctx := context.Background()
+	rd, srv := newTestRedis(t)
+	other, err := NewRedis(srv.Addr(), "", 0)
+	require.NoError(t, err)
+	defer other.Close()
+
+	require.NoError(t, rd.Acquire(ctx, time.Second))
+	assert.NoError(t, rd.Acquire(ctx, time.Second))
+	assert.ErrorIs(t, other.Acquire(ctx, time.Second), ErrLocked)
+
+	// the lease expires if not renewed, e.g. when the owner dies
+	require.NoError(t, rd.release(ctx))
+	require.NoError(t, other.Acquire(ctx, time.Second))
+	srv.FastForward(2 * time.Second)
+	assert.NoError(t, rd.Acquire(ctx, time.Second))
+}
+
+func TestRedisSetPersistence(t *testing.T) {
+	rd, _ := newTestRedis(t)
+	assert.Error(t, rd.SetPersistence(context.Background(), "bogus"))
+}
+
+func TestRedisMigrate(t *testing.T) {
+	srv := miniredis.RunT(t)
+	// the items stored a key each, before the hash
+	require.NoError(t, srv.Set("1", "foo"))
+	require.NoError(t, srv.Set(".meta/tags", "bar"))
+	srv.HSet(redisItemsKey, "2", "baz")
+
+	rd, err := NewRedis(srv.Addr(), "", 0)
+	require.NoError(t, err)
+	defer rd.Close()
+	items, err := rd.LoadAll()
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []Item{{ID: "1", Blob: Blob("foo")}, {ID: ".meta/tags", Blob: Blob("bar")}, {ID: "2", Blob: Blob("baz")}}, items)
+	assert.False(t, srv.Exists("1"))
+	version, err := srv.Get(redisVersionKey)
+	require.NoError(t, err)
+	assert.Equal(t, "1", version)
+
+	// the keys written since aren't items anymore
+	require.NoError(t, srv.Set("other", "qux"))
+	other, err := NewRedis(srv.Addr(), "", 0)
+	require.NoError(t, err)
+	defer other.Close()
+	assert.True(t, srv.Exists("other"))
+
+	require.NoError(t, srv.Set(redisVersionKey, "2"))
+	_, err = NewRedis(srv.Addr(), "", 0)
+	assert.ErrorContains(t, err, "newer")
+}
@@ -0,0 +1,76 @@
+package store
+
+import (
+	"net/url"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpen(t *testing.T) {
+	dir := t.TempDir()
+	for _, uri := range []string{
+		"memory://",
+		"sqlite://" + filepath.Join(dir, "todo.db"),
+		"bolt://" + filepath.Join(dir, "todo.bolt"),
+	} {
+		t.Run(uri, func(t *testing.T) {
+			st, err := Open(uri)
+			require.NoError(t, err)
+			defer st.Close()
+			require.NoError(t, st.Create("1", Blob("foo")))
+			blob, err := st.Load("1")
+			assert.NoError(t, err)
+			assert.Equal(t, "foo", string(blob))
+		})
+	}
+}
+
+func TestOpenErrors(t *testing.T) {
+	_, err := Open("todo.db")
+	assert.ErrorContains(t, err, "missing storage scheme")
+	_, err = Open("fsdir:///var/todo")
+	assert.ErrorContains(t, err, "unknown storage scheme \"fsdir\"")
+	_, err = Open("sqlite://")
+	assert.ErrorContains(t, err, "missing path")
+	_, err = Open("redis://localhost:6379/db")
+	assert.ErrorContains(t, err, "invalid redis database")
+	_, err = Open("s3://localhost:9000")
+	assert.ErrorContains(t, err, "missing bucket")
+}
+
+func TestRegister(t *testing.T) {
+	var opened *url.URL
+	Register("test-register", func(u *url.URL) (Storage, error) {
+		opened = u
+		return NewMemory()
+	})
+	assert.Contains(t, Schemes(), "test-register")
+
+	st, err := Open("test-register://somewhere/else?opt=1")
+	require.NoError(t, err)
+	defer st.Close()
+	assert.Equal(t, "somewhere", opened.Host)
+	assert.Equal(t, "1", opened.Query().Get("opt"))
+
+	assert.Panics(t, func() {
+		Register("test-register", openMemory)
+	})
+}
+
+func TestURIPath(t *testing.T) {
+	for uri, expected := range map[string]string{
+		"sqlite:///var/lib/todo.db": "/var/lib/todo.db",
+		"sqlite://todo.db":          "todo.db",
+		"sqlite://data/todo.db":     "data/todo.db",
+		"sqlite:todo.db":            "todo.db",
+	} {
+		u, err := url.Parse(uri)
+		require.NoError(t, err)
+		path, err := uriPath(u)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, path, uri)
+	}
+}
@@ -0,0 +1,328 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var _ Storage = &Remote{}
+var _ StorageContext = &Remote{}
+var _ Versioner = &Remote{}
+var _ Sequencer = &Remote{}
+
+// RemotePath is where the servers mount the Handler
+const RemotePath = "/store/"
+
+const (
+	// DefaultRemoteTimeout bounds each request of the Remote storages
+	DefaultRemoteTimeout = 30 * time.Second
+	// DefaultRemoteRetries is how many times the Remote storages retry a failed request
+	DefaultRemoteRetries = 3
+	// DefaultRemoteBackoff is how long the Remote storages wait before the first retry, doubling it
+	// at each retry
+	DefaultRemoteBackoff = 200 * time.Millisecond
+)
+
+// Remote stores the items in the storage of a server, through its Handler, so that the clients
+// need no access to the storage itself. The server allocates the IDs, see NextID. The reads and the
+// plain saves failed because of the network or the server being unavailable are retried. The other
+// requests, the creations, the conditional saves, the deletions and the allocations of the IDs, are
+// retried only when the server turns them down with 429 Too Many Requests: when their response is
+// lost, they might have been applied already, so that a retry would fail as if the item had changed
+// meanwhile, or allocate another ID.
+type Remote struct {
+	// items is the URL of the items of the Handler, ending with a slash
+	items *url.URL
+	// ids is the URL allocating the IDs
+	ids    *url.URL
+	token  string
+	client *http.Client
+	// Timeout bounds each request, DefaultRemoteTimeout by default. Zero disables it.
+	Timeout time.Duration
+	// Retries is how many times a failed request is retried, DefaultRemoteRetries by default
+	Retries int
+	// Backoff is how long to wait before the first retry, doubling it at each retry,
+	// DefaultRemoteBackoff by default
+	Backoff time.Duration
+}
+
+// NewRemote creates a new Remote storing the items in the server at the given base URL, e.g.
+// https://todo.example.com, sending the API key given as token, if any
+func NewRemote(baseURL, token string) (*Remote, error) {
+	base, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, err
+	}
+	if base.Scheme != "http" && base.Scheme != "https" {
+		return nil, fmt.Errorf("invalid server URL %q, want http or https", baseURL)
+	}
+	if base.Host == "" {
+		return nil, fmt.Errorf("missing host in server URL %q", baseURL)
+	}
+	items := base.JoinPath(RemotePath, "items/")
+	items.User, items.RawQuery, items.Fragment = nil, "", ""
+	ids := base.JoinPath(RemotePath, "ids")
+	ids.User, ids.RawQuery, ids.Fragment = nil, "", ""
+	return &Remote{
+		items:   items,
+		ids:     ids,
+		token:   token,
+		client:  &http.Client{},
+		Timeout: DefaultRemoteTimeout,
+		Retries: DefaultRemoteRetries,
+		Backoff: DefaultRemoteBackoff,
+	}, nil
+}
+
+func (rm *Remote) Close() error {
+	rm.client.CloseIdleConnections()
+	return nil
+}
+
+func (rm *Remote) Create(objectID ID, blob Blob) error {
+	return rm.CreateCtx(context.Background(), objectID, blob)
+}
+
+func (rm *Remote) CreateCtx(ctx context.Context, objectID ID, blob Blob) error {
+	resp, err := rm.do(ctx, http.MethodPut, objectID, blob, "If-None-Match", "*")
+	if err != nil {
+		return err
+	}
+	if resp.status == http.StatusPreconditionFailed {
+		return fmt.Errorf("item with id %v already exists", objectID)
+	}
+	return resp.err(objectID)
+}
+
+func (rm *Remote) LoadAll() ([]Item, error) {
+	return rm.LoadAllCtx(context.Background())
+}
+
+func (rm *Remote) LoadAllCtx(ctx context.Context) ([]Item, error) {
+	resp, err := rm.do(ctx, http.MethodGet, "", nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := resp.err(NullID); err != nil {
+		return nil, err
+	}
+	var items []remoteItem
+	if err := json.Unmarshal(resp.body, &items); err != nil {
+		return nil, fmt.Errorf("invalid items from the server: %w", err)
+	}
+	res := make([]Item, 0, len(items))
+	for _, item := range items {
+		res = append(res, Item{ID: item.ID, Blob: item.Blob})
+	}
+	return res, nil
+}
+
+func (rm *Remote) Load(objectID ID) (Blob, error) {
+	return rm.LoadCtx(context.Background(), objectID)
+}
+
+func (rm *Remote) LoadCtx(ctx context.Context, objectID ID) (Blob, error) {
+	blob, _, err := rm.loadRev(ctx, objectID)
+	return blob, err
+}
+
+// LoadRev returns the item along with its revision, zero if the storage of the server doesn't
+// track them
+func (rm *Remote) LoadRev(objectID ID) (Blob, Revision, error) {
+	return rm.loadRev(context.Background(), objectID)
+}
+
+func (rm *Remote) loadRev(ctx context.Context, objectID ID) (Blob, Revision, error) {
+	resp, err := rm.do(ctx, http.MethodGet, objectID, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	if err := resp.err(objectID); err != nil {
+		return nil, 0, err
+	}
+	return resp.body, resp.revision(), nil
+}
+
+func (rm *Remote) Save(objectID ID, blob Blob) error {
+	return rm.SaveCtx(context.Background(), objectID, blob)
+}
+
+func (rm *Remote) SaveCtx(ctx context.Context, objectID ID, blob Blob) error {
+	resp, err := rm.do(ctx, http.MethodPut, objectID, blob)
+	if err != nil {
+		return err
+	}
+	return resp.err(objectID)
+}
+
+// SaveIf updates the item only if its revision is still the expected one. Fails with
+// ErrUnsupported if the storage of the server doesn't track the revisions.
+func (rm *Remote) SaveIf(objectID ID, blob Blob, expected Revision) (Revision, error) {
+	resp, err := rm.do(context.Background(), http.MethodPut, objectID, blob, "If-Match", quoteRevision(expected))
+	if err != nil {
+		return 0, err
+	}
+	if resp.status == http.StatusPreconditionFailed {
+		return 0, ErrConflict{ID: objectID, Expected: expected, Actual: resp.revision()}
+	}
+	if err := resp.err(objectID); err != nil {
+		return 0, err
+	}
+	return resp.revision(), nil
+}
+
+// NextID allocates a new ID from the server, so that the items the clients create don't collide
+// with the ones the server creates. Fails with ErrUnsupported if the server doesn't allocate them.
+func (rm *Remote) NextID() (ID, error) {
+	resp, err := rm.doURL(context.Background(), http.MethodPost, rm.ids, NullID, nil)
+	if err != nil {
+		return NullID, err
+	}
+	if resp.status == http.StatusNotFound || resp.status == http.StatusMethodNotAllowed {
+		return NullID, fmt.Errorf("the server allocates no ids: %w", ErrUnsupported)
+	}
+	if err := resp.err(NullID); err != nil {
+		return NullID, err
+	}
+	id := ID(strings.TrimSpace(string(resp.body)))
+	if id == NullID {
+		return NullID, fmt.Errorf("invalid id from the server")
+	}
+	return id, nil
+}
+
+func (rm *Remote) Delete(objectID ID) error {
+	return rm.DeleteCtx(context.Background(), objectID)
+}
+
+func (rm *Remote) DeleteCtx(ctx context.Context, objectID ID) error {
+	resp, err := rm.do(ctx, http.MethodDelete, objectID, nil)
+	if err != nil {
+		return err
+	}
+	return resp.err(objectID)
+}
+
+// remoteResponse is the response of the server, read already
+type remoteResponse struct {
+	status int
+	header http.Header
+	body   []byte
+}
+
+// err returns the error of the response about the item, nil if it succeeded
+func (resp remoteResponse) err(objectID ID) error {
+	switch {
+	case resp.status < 300:
+		return nil
+	case resp.status == http.StatusNotFound && objectID != NullID:
+		return ErrNotFound{ID: objectID}
+	case resp.status == http.StatusNotImplemented:
+		return ErrUnsupported
+	case resp.status == http.StatusUnauthorized || resp.status == http.StatusForbidden:
+		return fmt.Errorf("server error: %s, check the API key", http.StatusText(resp.status))
+	}
+	return fmt.Errorf("server error: %s: %s", http.StatusText(resp.status), strings.TrimSpace(string(resp.body)))
+}
+
+// revision returns the revision of the item, from the ETag of the response. Zero if none.
+func (resp remoteResponse) revision() Revision {
+	rev, err := strconv.ParseUint(strings.Trim(resp.header.Get("ETag"), `"`), 10, 64)
+	if err != nil {
+		return 0
+	}
+	return Revision(rev)
+}
+
+// do sends the request about the item, or about all of them if the ID is null, along with the
+// given header pairs, retrying it while it fails because of the network or of the server being
+// unavailable, if idempotent
+func (rm *Remote) do(ctx context.Context, method string, objectID ID, body Blob, header ...string) (remoteResponse, error) {
+	u := *rm.items
+	if objectID != NullID {
+		u = *u.JoinPath(string(objectID))
+	} else {
+		u.Path = strings.TrimSuffix(u.Path, "/")
+	}
+	return rm.doURL(ctx, method, &u, objectID, body, header...)
+}
+
+// idempotent returns true if the request with the header pairs can be sent again with the same
+// outcome, whether or not the server applied it already: the reads and the unconditional saves
+func idempotent(method string, header []string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead:
+		return true
+	case http.MethodPut:
+		for i := 0; i < len(header); i += 2 {
+			if strings.HasPrefix(http.CanonicalHeaderKey(header[i]), "If-") {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// doURL sends the request to the URL, about the item with the ID if any, like do, retrying only the
+// idempotent requests, but when the server turned the request down before serving it
+func (rm *Remote) doURL(ctx context.Context, method string, u *url.URL, objectID ID, body Blob, header ...string) (remoteResponse, error) {
+	backoff := rm.Backoff
+	safe := idempotent(method, header)
+	for attempt := 0; ; attempt++ {
+		resp, err := rm.send(ctx, method, u.String(), body, header)
+		retry := resp.status == http.StatusTooManyRequests
+		if safe {
+			retry = retry || err != nil || resp.status == http.StatusBadGateway ||
+				resp.status == http.StatusServiceUnavailable || resp.status == http.StatusGatewayTimeout
+		}
+		if !retry || attempt >= rm.Retries || ctx.Err() != nil {
+			return resp, err
+		}
+		slog.WarnContext(ctx, "store: Remote: retrying", "method", method, "id", objectID, "attempt", attempt+1, "status", resp.status, "error", err)
+		select {
+		case <-ctx.Done():
+			return resp, ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// send sends the request once, within the timeout
+func (rm *Remote) send(ctx context.Context, method, u string, body Blob, header []string) (remoteResponse, error) {
+	if rm.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, rm.Timeout)
+		defer cancel()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, bytes.NewReader(body))
+	if err != nil {
+		return remoteResponse{}, err
+	}
+	if rm.token != "" {
+		req.Header.Set("X-API-Key", rm.token)
+	}
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	resp, err := rm.client.Do(req)
+	if err != nil {
+		return remoteResponse{}, err
+	}
+	defer resp.Body.Close()
+	blob, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return remoteResponse{}, err
+	}
+	return remoteResponse{status: resp.StatusCode, header: resp.Header, body: blob}, nil
+}
@@ -0,0 +1,242 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newRemote returns a Remote storing the items in the storage, through a Handler, and the
+// number of changes the handler was told of
+func newRemote(t *testing.T, st Storage) (*Remote, *atomic.Int32) {
+	changes := &atomic.Int32{}
+	h := NewHandler(st)
+	h.OnChange = func(ID) { changes.Add(1) }
+	mux := http.NewServeMux()
+	mux.Handle(RemotePath, http.StripPrefix("/store", h))
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	rm, err := NewRemote(srv.URL, "")
+	require.NoError(t, err)
+	t.Cleanup(func() { rm.Close() })
+	return rm, changes
+}
+
+func TestRemote(t *testing.T) {
+	mem, err := NewMemory()
+	require.NoError(t, err)
+	rm, changes := newRemote(t, mem)
+
+	require.NoError(t, rm.Create("1", Blob("foo")))
+	require.NoError(t, rm.Create(MetaID("sync/a b"), Blob("{}")))
+	assert.ErrorContains(t, rm.Create("1", Blob("bar")), "item with id 1 already exists")
+	blob, err := mem.Load(MetaID("sync/a b"))
+	require.NoError(t, err)
+	assert.Equal(t, "{}", string(blob))
+
+	blob, err = rm.Load("1")
+	require.NoError(t, err)
+	assert.Equal(t, "foo", string(blob))
+	require.NoError(t, rm.Save("1", Blob("bar")))
+	items, err := rm.LoadAll()
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []Item{{ID: "1", Blob: Blob("bar")}, {ID: MetaID("sync/a b"), Blob: Blob("{}")}}, items)
+
+	require.NoError(t, rm.Delete("1"))
+	var notFound ErrNotFound
+	_, err = rm.Load("1")
+	assert.ErrorAs(t, err, &notFound)
+	assert.ErrorAs(t, rm.Save("1", Blob("baz")), &notFound)
+	assert.ErrorAs(t, rm.Delete("1"), &notFound)
+	assert.EqualValues(t, 4, changes.Load())
+}
+
+func TestRemoteRevisions(t *testing.T) {
+	mem, err := NewMemory()
+	require.NoError(t, err)
+	rm, _ := newRemote(t, mem)
+
+	require.NoError(t, rm.Create("1", Blob("foo")))
+	blob, rev, err := LoadRev(rm, "1")
+	require.NoError(t, err)
+	assert.Equal(t, "foo", string(blob))
+	assert.Equal(t, Revision(1), rev)
+
+	rev, err = SaveIf(rm, "1", Blob("bar"), rev)
+	require.NoError(t, err)
+	assert.Equal(t, Revision(2), rev)
+	_, err = SaveIf(rm, "1", Blob("baz"), 1)
+	assert.Equal(t, ErrConflict{ID: "1", Expected: 1, Actual: 2}, err)
+
+	// the storages without revisions can't save conditionally
+	rm, _ = newRemote(t, WithoutContext(WithContext(mem)))
+	_, rev, err = LoadRev(rm, "1")
+	require.NoError(t, err)
+	assert.Zero(t, rev)
+	_, err = SaveIf(rm, "1", Blob("baz"), 2)
+	assert.ErrorIs(t, err, ErrUnsupported)
+}
+
+func TestRemoteIDs(t *testing.T) {
+	mem, err := NewMemory()
+	require.NoError(t, err)
+	require.NoError(t, mem.Create("3", Blob("foo")))
+	rm, _ := newRemote(t, mem)
+	_, err = rm.NextID()
+	assert.ErrorIs(t, err, ErrUnsupported)
+
+	// the server and its clients share the generator of the server
+	h := NewHandler(mem)
+	h.IDs = NewSequentialIDs(mem)
+	srv := httptest.NewServer(http.StripPrefix("/store", h))
+	defer srv.Close()
+	rm, err = NewRemote(srv.URL, "")
+	require.NoError(t, err)
+	id, err := rm.NextID()
+	require.NoError(t, err)
+	assert.Equal(t, ID("4"), id)
+	id, err = NewSequentialIDs(rm).NewID()
+	require.NoError(t, err)
+	assert.Equal(t, ID("5"), id)
+	id, err = h.IDs.NewID()
+	require.NoError(t, err)
+	assert.Equal(t, ID("6"), id)
+}
+
+func TestRemoteRetries(t *testing.T) {
+	mem, err := NewMemory()
+	require.NoError(t, err)
+	require.NoError(t, mem.Create("1", Blob("foo")))
+	h := NewHandler(mem)
+	var failures, requests atomic.Int32
+	srv := httptest.NewServer(http.StripPrefix("/store", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if failures.Add(-1) >= 0 {
+			http.Error(w, "try later", http.StatusServiceUnavailable)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})))
+	defer srv.Close()
+	rm, err := NewRemote(srv.URL, "")
+	require.NoError(t, err)
+	rm.Backoff = time.Millisecond
+
+	failures.Store(2)
+	blob, err := rm.Load("1")
+	require.NoError(t, err)
+	assert.Equal(t, "foo", string(blob))
+	assert.EqualValues(t, 3, requests.Load())
+
+	requests.Store(0)
+	failures.Store(10)
+	_, err = rm.Load("1")
+	assert.ErrorContains(t, err, "Service Unavailable: try later")
+	assert.EqualValues(t, 1+DefaultRemoteRetries, requests.Load())
+}
+
+func TestRemoteTimeout(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	st, err := Open(srv.URL + "?timeout=10ms&retries=1")
+	require.NoError(t, err)
+	rm := st.(*Remote)
+	assert.Equal(t, 10*time.Millisecond, rm.Timeout)
+	assert.Equal(t, 1, rm.Retries)
+	rm.Backoff = time.Millisecond
+	_, err = rm.Load("1")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	_, err = Open(srv.URL + "?timeout=soon")
+	assert.ErrorContains(t, err, `invalid timeout "soon"`)
+}
+
+func TestRemoteAPIKey(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-API-Key") != "s3cret" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte("[]"))
+	}))
+	defer srv.Close()
+
+	st, err := Open("http://:s3cret@" + srv.Listener.Addr().String())
+	require.NoError(t, err)
+	items, err := st.LoadAll()
+	require.NoError(t, err)
+	assert.Empty(t, items)
+	st, err = Open(srv.URL)
+	require.NoError(t, err)
+	_, err = st.LoadAll()
+	assert.ErrorContains(t, err, "Unauthorized, check the API key")
+}
+
+func TestRemoteRetriesIdempotent(t *testing.T) {
+	mem, err := NewMemory()
+	require.NoError(t, err)
+	h := NewHandler(mem)
+	var drop atomic.Bool
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.StripPrefix("/store", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if !drop.CompareAndSwap(true, false) {
+			h.ServeHTTP(w, r)
+			return
+		}
+		// served, but the response is lost
+		h.ServeHTTP(httptest.NewRecorder(), r)
+		conn, _, err := http.NewResponseController(w).Hijack()
+		require.NoError(t, err)
+		conn.Close()
+	})))
+	defer srv.Close()
+	rm, err := NewRemote(srv.URL, "")
+	require.NoError(t, err)
+	rm.Backoff = time.Millisecond
+	send := func(op func() error) (int32, error) {
+		requests.Store(0)
+		drop.Store(true)
+		err := op()
+		return requests.Load(), err
+	}
+
+	// the creation isn't retried, not to fail as if created by another
+	n, err := send(func() error { return rm.Create("1", Blob("foo")) })
+	assert.Error(t, err)
+	assert.NotContains(t, err.Error(), "already exists")
+	assert.EqualValues(t, 1, n)
+	blob, err := mem.Load("1")
+	require.NoError(t, err)
+	assert.Equal(t, "foo", string(blob), "applied nonetheless")
+
+	// the plain saves are retried
+	n, err = send(func() error { return rm.Save("1", Blob("bar")) })
+	assert.NoError(t, err)
+	assert.EqualValues(t, 2, n)
+
+	var conflict ErrConflict
+	n, err = send(func() error { _, err := rm.SaveIf("1", Blob("baz"), 1); return err })
+	assert.False(t, errors.As(err, &conflict), "no conflict with itself")
+	assert.EqualValues(t, 1, n)
+
+	var notFound ErrNotFound
+	n, err = send(func() error { return rm.Delete("1") })
+	assert.False(t, errors.As(err, &notFound), "not gone before deleted")
+	assert.EqualValues(t, 1, n)
+}
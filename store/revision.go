@@ -0,0 +1,33 @@
+package store
+
+// Versioner is implemented by the storages which track the revision of each item,
+// allowing optimistic concurrency control: Create sets the revision of a new item to 1,
+// and every update increments it.
+type Versioner interface {
+	// LoadRev returns the blob identified by the given ID along with its current revision.
+	LoadRev(ID) (Blob, Revision, error)
+	// SaveIf updates the item only if its current revision is the expected one;
+	// otherwise fails with ErrConflict. Returns the new revision of the item.
+	SaveIf(id ID, blob Blob, expected Revision) (Revision, error)
+}
+
+// LoadRev returns the blob identified by the given ID along with its current revision.
+// If the storage doesn't track revisions, the revision is always zero.
+func LoadRev(st Storage, id ID) (Blob, Revision, error) {
+	if vr, ok := st.(Versioner); ok {
+		return vr.LoadRev(id)
+	}
+	blob, err := st.Load(id)
+	return blob, 0, err
+}
+
+// SaveIf updates the item only if its current revision is the expected one,
+// otherwise fails with ErrConflict. Returns the new revision of the item.
+// Fails with ErrUnsupported if the storage doesn't track revisions.
+func SaveIf(st Storage, id ID, blob Blob, expected Revision) (Revision, error) {
+	vr, ok := st.(Versioner)
+	if !ok {
+		return 0, ErrUnsupported
+	}
+	return vr.SaveIf(id, blob, expected)
+}
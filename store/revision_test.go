@@ -0,0 +1,57 @@
+package store
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRevisions(t *testing.T) {
+	for name, st := range batchTestStorages(t) {
+		t.Run(name, func(t *testing.T) {
+			require.NoError(t, st.Create("1", Blob("foo")))
+			_, rev, err := LoadRev(st, "1")
+			assert.NoError(t, err)
+			assert.Equal(t, Revision(1), rev)
+
+			require.NoError(t, st.Save("1", Blob("bar")))
+			blob, rev, err := LoadRev(st, "1")
+			assert.NoError(t, err)
+			assert.Equal(t, Revision(2), rev)
+			assert.Equal(t, "bar", string(blob))
+
+			rev, err = SaveIf(st, "1", Blob("baz"), 2)
+			assert.NoError(t, err)
+			assert.Equal(t, Revision(3), rev)
+
+			// stale writer
+			_, err = SaveIf(st, "1", Blob("quux"), 2)
+			var conflict ErrConflict
+			assert.True(t, errors.As(err, &conflict))
+			assert.Equal(t, ErrConflict{ID: "1", Expected: 2, Actual: 3}, conflict)
+
+			blob, rev, err = LoadRev(st, "1")
+			assert.NoError(t, err)
+			assert.Equal(t, Revision(3), rev)
+			assert.Equal(t, "baz", string(blob))
+
+			_, err = SaveIf(st, "2", Blob("foo"), 1)
+			assert.ErrorIs(t, err, ErrNotFound{ID: "2"})
+
+			// revisions restart from scratch once a item is deleted
+			require.NoError(t, st.Delete("1"))
+			require.NoError(t, st.Create("1", Blob("foo")))
+			_, rev, err = LoadRev(st, "1")
+			assert.NoError(t, err)
+			assert.Equal(t, Revision(1), rev)
+		})
+	}
+}
+
+func TestRevisionsUnsupported(t *testing.T) {
+	// redis doesn't track revisions
+	_, err := SaveIf(&Redis{}, "1", Blob("foo"), 1)
+	assert.ErrorIs(t, err, ErrUnsupported)
+}
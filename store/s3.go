@@ -0,0 +1,204 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+var _ Storage = &S3{}
+var _ StorageContext = &S3{}
+
+// s3SaveRetries is how many times Save retries when the item changes under its feet
+const s3SaveRetries = 5
+
+// S3Options holds the parameters to connect to a S3-compatible bucket
+type S3Options struct {
+	// Endpoint is in the format `host[:port]`, e.g. "s3.amazonaws.com"
+	Endpoint  string
+	AccessKey string
+	SecretKey string
+	Region    string
+	Bucket    string
+	// Prefix is prepended to the object keys, so many stores can share a bucket
+	Prefix string
+	// Insecure disables TLS
+	Insecure bool
+}
+
+// S3 stores the items as objects in a S3-compatible bucket (AWS S3, MinIO...), one
+// object per item. Creations and updates rely on conditional puts, so they are
+// safe against concurrent writers sharing the same bucket.
+type S3 struct {
+	client *minio.Client
+	bucket string
+	prefix string
+}
+
+// NewS3 creates a new S3 storing the items in the given bucket, which must exist
+func NewS3(opts S3Options) (*S3, error) {
+	client, err := minio.New(opts.Endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(opts.AccessKey, opts.SecretKey, ""),
+		Secure: !opts.Insecure,
+		Region: opts.Region,
+	})
+	if err != nil {
+		return nil, err
+	}
+	ok, err := client.BucketExists(context.Background(), opts.Bucket)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("bucket %q does not exist", opts.Bucket)
+	}
+	return &S3{
+		client: client,
+		bucket: opts.Bucket,
+		prefix: opts.Prefix,
+	}, nil
+}
+
+func (s3 *S3) key(objectID ID) string {
+	return s3.prefix + string(objectID)
+}
+
+func s3ErrorCode(err error) string {
+	return minio.ToErrorResponse(err).Code
+}
+
+func (s3 *S3) Close() error {
+	return nil
+}
+
+func (s3 *S3) Create(objectID ID, data Blob) error {
+	return s3.CreateCtx(context.Background(), objectID, data)
+}
+
+func (s3 *S3) CreateCtx(ctx context.Context, objectID ID, data Blob) error {
+	opts := minio.PutObjectOptions{}
+	// fails if the object already exists
+	opts.SetMatchETagExcept("*")
+	err := s3.put(ctx, objectID, data, opts)
+	if s3ErrorCode(err) == "PreconditionFailed" {
+		return fmt.Errorf("item with id %v already exists", objectID)
+	}
+	return err
+}
+
+func (s3 *S3) put(ctx context.Context, objectID ID, data Blob, opts minio.PutObjectOptions) error {
+	opts.ContentType = "application/octet-stream"
+	_, err := s3.client.PutObject(ctx, s3.bucket, s3.key(objectID), bytes.NewReader(data), int64(len(data)), opts)
+	return err
+}
+
+func (s3 *S3) LoadAll() ([]Item, error) {
+	return s3.LoadAllCtx(context.Background())
+}
+
+// LoadAllCtx lists the bucket page by page, fetching the objects as they come
+func (s3 *S3) LoadAllCtx(ctx context.Context) ([]Item, error) {
+	res := []Item{}
+	objects := s3.client.ListObjects(ctx, s3.bucket, minio.ListObjectsOptions{
+		Prefix:    s3.prefix,
+		Recursive: true,
+	})
+	for obj := range objects {
+		if obj.Err != nil {
+			return nil, obj.Err
+		}
+		objectID := ID(strings.TrimPrefix(obj.Key, s3.prefix))
+		blob, _, err := s3.get(ctx, objectID)
+		if _, ok := err.(ErrNotFound); ok {
+			continue // deleted meanwhile
+		}
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, Item{ID: objectID, Blob: blob})
+	}
+	return res, nil
+}
+
+func (s3 *S3) Load(objectID ID) (Blob, error) {
+	return s3.LoadCtx(context.Background(), objectID)
+}
+
+func (s3 *S3) LoadCtx(ctx context.Context, objectID ID) (Blob, error) {
+	blob, _, err := s3.get(ctx, objectID)
+	return blob, err
+}
+
+// get returns the content of the object along with its ETag
+func (s3 *S3) get(ctx context.Context, objectID ID) (Blob, string, error) {
+	obj, err := s3.client.GetObject(ctx, s3.bucket, s3.key(objectID), minio.GetObjectOptions{})
+	if err != nil {
+		return nil, "", err
+	}
+	defer obj.Close()
+	// the request is sent lazily, errors show up on the first read
+	info, err := obj.Stat()
+	if err == nil {
+		var data []byte
+		data, err = io.ReadAll(obj)
+		if err == nil {
+			return Blob(data), info.ETag, nil
+		}
+	}
+	if s3ErrorCode(err) == "NoSuchKey" {
+		return nil, "", ErrNotFound{ID: objectID}
+	}
+	return nil, "", err
+}
+
+// etag returns the ETag of the object
+func (s3 *S3) etag(ctx context.Context, objectID ID) (string, error) {
+	info, err := s3.client.StatObject(ctx, s3.bucket, s3.key(objectID), minio.StatObjectOptions{})
+	if err != nil {
+		if minio.ToErrorResponse(err).StatusCode == http.StatusNotFound {
+			return "", ErrNotFound{ID: objectID}
+		}
+		return "", err
+	}
+	return info.ETag, nil
+}
+
+func (s3 *S3) Save(objectID ID, blob Blob) error {
+	return s3.SaveCtx(context.Background(), objectID, blob)
+}
+
+// SaveCtx overwrites the object only if it still exists: the put is conditional
+// on the ETag of the current object, and retried if it was changed meanwhile.
+func (s3 *S3) SaveCtx(ctx context.Context, objectID ID, blob Blob) error {
+	for attempt := 0; attempt < s3SaveRetries; attempt++ {
+		etag, err := s3.etag(ctx, objectID)
+		if err != nil {
+			return err
+		}
+		opts := minio.PutObjectOptions{}
+		opts.SetMatchETag(etag)
+		err = s3.put(ctx, objectID, blob, opts)
+		if s3ErrorCode(err) != "PreconditionFailed" {
+			return err
+		}
+	}
+	return fmt.Errorf("item with id %v keeps changing, giving up", objectID)
+}
+
+func (s3 *S3) Delete(objectID ID) error {
+	return s3.DeleteCtx(context.Background(), objectID)
+}
+
+func (s3 *S3) DeleteCtx(ctx context.Context, objectID ID) error {
+	// deletions always succeed on S3: check first to report missing items
+	if _, err := s3.etag(ctx, objectID); err != nil {
+		return err
+	}
+	return s3.client.RemoveObject(ctx, s3.bucket, s3.key(objectID), minio.RemoveObjectOptions{})
+}
@@ -0,0 +1,85 @@
+package store
+
+import (
+	"context"
+	"testing"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+)
+
+// skipWithoutDocker skips the test if no container runtime is available.
+// testcontainers panics instead of failing when it can't find one.
+func skipWithoutDocker(t *testing.T) {
+	defer func() {
+		if r := recover(); r != nil {
+			t.Skipf("docker not available: %v", r)
+		}
+	}()
+	testcontainers.SkipIfProviderIsNotHealthy(t)
+}
+
+func TestS3(t *testing.T) {
+	skipWithoutDocker(t)
+
+	ctx := context.Background()
+	minioC, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
+		ContainerRequest: testcontainers.ContainerRequest{
+			Image:        "minio/minio:latest",
+			ExposedPorts: []string{"9000/tcp"},
+			Cmd:          []string{"server", "/data"},
+			WaitingFor:   wait.ForHTTP("/minio/health/live").WithPort("9000/tcp"),
+		},
+		Started: true,
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { minioC.Terminate(ctx) })
+	endpoint, err := minioC.PortEndpoint(ctx, "9000/tcp", "")
+	require.NoError(t, err)
+
+	client, err := minio.New(endpoint, &minio.Options{
+		Creds: credentials.NewStaticV4("minioadmin", "minioadmin", ""),
+	})
+	require.NoError(t, err)
+	require.NoError(t, client.MakeBucket(ctx, "todo", minio.MakeBucketOptions{}))
+
+	st, err := NewS3(S3Options{
+		Endpoint:  endpoint,
+		AccessKey: "minioadmin",
+		SecretKey: "minioadmin",
+		Bucket:    "todo",
+		Prefix:    "items/",
+		Insecure:  true,
+	})
+	require.NoError(t, err)
+	defer st.Close()
+
+	require.NoError(t, st.Create("1", Blob("foo")))
+	assert.Error(t, st.Create("1", Blob("bar")))
+
+	blob, err := st.Load("1")
+	assert.NoError(t, err)
+	assert.Equal(t, "foo", string(blob))
+
+	require.NoError(t, st.Save("1", Blob("bar")))
+	assert.ErrorIs(t, st.Save("2", Blob("bar")), ErrNotFound{ID: "2"})
+
+	require.NoError(t, st.Create("2", Blob("baz")))
+	items, err := st.LoadAll()
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []Item{{ID: "1", Blob: Blob("bar")}, {ID: "2", Blob: Blob("baz")}}, items)
+
+	require.NoError(t, st.Delete("1"))
+	assert.ErrorIs(t, st.Delete("1"), ErrNotFound{ID: "1"})
+	_, err = st.Load("1")
+	assert.ErrorIs(t, err, ErrNotFound{ID: "1"})
+}
+
+func TestS3MissingBucket(t *testing.T) {
+	_, err := NewS3(S3Options{Endpoint: "invalid endpoint"})
+	assert.Error(t, err)
+}
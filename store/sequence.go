@@ -0,0 +1,17 @@
+package store
+
+// Sequencer is implemented by the storages which can allocate new unique IDs,
+// shared among all the processes using the same backend.
+type Sequencer interface {
+	NextID() (ID, error)
+}
+
+// NextID allocates a new unique ID from the storage.
+// Fails with ErrUnsupported if the storage can't allocate IDs.
+func NextID(st Storage) (ID, error) {
+	sq, ok := st.(Sequencer)
+	if !ok {
+		return NullID, ErrUnsupported
+	}
+	return sq.NextID()
+}
@@ -0,0 +1,192 @@
+package store
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// snapshotFormat identifies the snapshot streams
+const snapshotFormat = "todo-snapshot"
+
+// snapshotVersion is the version of the snapshot format written by Snapshot
+const snapshotVersion = 1
+
+// snapshotHeader is the first record of a snapshot stream
+type snapshotHeader struct {
+	Format  string    `json:"format"`
+	Version int       `json:"version"`
+	Created time.Time `json:"created"`
+	// Since is the change sequence a incremental snapshot starts from. Zero for full snapshots.
+	Since uint64 `json:"since,omitempty"`
+	// Seq is the change sequence the snapshot is up to date with. Zero if unknown.
+	Seq uint64 `json:"seq,omitempty"`
+}
+
+// snapshotRecord holds a item, along with its metadata
+type snapshotRecord struct {
+	ID       ID        `json:"id"`
+	Blob     []byte    `json:"blob"`
+	Created  time.Time `json:"created,omitempty"`
+	Updated  time.Time `json:"updated,omitempty"`
+	Revision Revision  `json:"revision,omitempty"`
+	Checksum string    `json:"checksum"`
+	// Deleted marks the items deleted, in incremental snapshots
+	Deleted bool `json:"deleted,omitempty"`
+}
+
+// importer is implemented by the storages which can create items
+// preserving the given metadata
+type importer interface {
+	importItem(item Item, info ItemInfo) error
+}
+
+// Snapshot writes all the items of the storage, along with their metadata, to the
+// given writer, as a gzip-compressed stream of JSON records.
+// The snapshot is consistent only if the storage is not changed meanwhile.
+func Snapshot(st Storage, w io.Writer) error {
+	hdr := snapshotHeader{
+		Format:  snapshotFormat,
+		Version: snapshotVersion,
+		Created: time.Now().UTC(),
+	}
+	if _, ok := st.(ChangeTracker); ok {
+		// taken before reading the items, so the changes performed meanwhile
+		// are in the next incremental snapshot
+		seq, err := currentSeq(st)
+		if err != nil {
+			return err
+		}
+		hdr.Seq = seq
+	}
+	zw := gzip.NewWriter(w)
+	enc := json.NewEncoder(zw)
+	if err := enc.Encode(hdr); err != nil {
+		return err
+	}
+	// collect first: backends may not allow reads while walking
+	var items []Item
+	err := Walk(st, func(item Item) error {
+		items = append(items, item)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, item := range items {
+		rec, err := snapshotRecordOf(st, item)
+		if err != nil {
+			return err
+		}
+		if err := enc.Encode(rec); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+func snapshotRecordOf(st Storage, item Item) (snapshotRecord, error) {
+	rec := snapshotRecord{
+		ID:       item.ID,
+		Blob:     item.Blob,
+		Checksum: Checksum(item.Blob),
+	}
+	if _, ok := st.(Stater); !ok {
+		return rec, nil
+	}
+	info, err := Stat(st, item.ID)
+	var notFound ErrNotFound
+	if errors.As(err, &notFound) {
+		return rec, nil // deleted meanwhile, still worth saving
+	}
+	if err != nil {
+		return rec, err
+	}
+	rec.Created = info.Created
+	rec.Updated = info.Updated
+	rec.Revision = info.Revision
+	return rec, nil
+}
+
+// Restore reads a snapshot written by Snapshot and creates all its items in the given
+// storage, which is expected to be empty: restoring a item which already exists fails.
+// Incremental snapshots written by SnapshotSince are applied instead on top of the
+// storage: the items are replaced, and the deleted ones removed.
+// The storages which track metadata get the ones recorded in the snapshot.
+// Returns the number of items restored; on failure, the items restored until then
+// are not removed.
+func Restore(st Storage, r io.Reader) (int, error) {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return 0, ErrCorruptedContent{Name: "snapshot"}
+	}
+	dec := json.NewDecoder(zr)
+	var hdr snapshotHeader
+	if err := dec.Decode(&hdr); err != nil || hdr.Format != snapshotFormat {
+		return 0, ErrCorruptedContent{Name: "snapshot"}
+	}
+	if hdr.Version > snapshotVersion {
+		return 0, fmt.Errorf("snapshot version %d is newer than supported version %d", hdr.Version, snapshotVersion)
+	}
+
+	imp, canImport := st.(importer)
+	count := 0
+	for {
+		var rec snapshotRecord
+		err := dec.Decode(&rec)
+		if err == io.EOF {
+			return count, nil
+		}
+		if err != nil {
+			return count, ErrCorruptedContent{Name: "snapshot"}
+		}
+		incremental := hdr.Since > 0
+		if incremental || rec.Deleted {
+			err := st.Delete(rec.ID)
+			var notFound ErrNotFound
+			if err != nil && !errors.As(err, &notFound) {
+				return count, err
+			}
+		}
+		if rec.Deleted {
+			count++
+			continue
+		}
+		if Checksum(rec.Blob) != rec.Checksum {
+			return count, ErrCorruptedContent{Name: string(rec.ID)}
+		}
+		item := Item{ID: rec.ID, Blob: Blob(rec.Blob)}
+		if canImport {
+			err = imp.importItem(item, ItemInfo{
+				ID:       rec.ID,
+				Created:  rec.Created,
+				Updated:  rec.Updated,
+				Revision: rec.Revision,
+			})
+		} else {
+			err = st.Create(item.ID, item.Blob)
+		}
+		if err != nil {
+			return count, err
+		}
+		count++
+	}
+}
+
+// importedMeta fills in the metadata missing from a snapshot
+func importedMeta(info ItemInfo) ItemInfo {
+	now := time.Now()
+	if info.Created.IsZero() {
+		info.Created = now
+	}
+	if info.Updated.IsZero() {
+		info.Updated = info.Created
+	}
+	if info.Revision == 0 {
+		info.Revision = 1
+	}
+	return info
+}
@@ -0,0 +1,88 @@
+package store
+
+import (
+	"bytes"
+	"compress/gzip"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSnapshotRestore(t *testing.T) {
+	src, err := NewMemory()
+	require.NoError(t, err)
+	require.NoError(t, src.Create("1", Blob("foo")))
+	require.NoError(t, src.Create("2", Blob("bar")))
+	require.NoError(t, src.Save("2", Blob("baz")))
+	srcInfo, err := Stat(src, "2")
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, Snapshot(src, &buf))
+
+	for name, dst := range batchTestStorages(t) {
+		t.Run(name, func(t *testing.T) {
+			count, err := Restore(dst, bytes.NewReader(buf.Bytes()))
+			assert.NoError(t, err)
+			assert.Equal(t, 2, count)
+
+			items, err := LoadPage(dst, 0, 0)
+			assert.NoError(t, err)
+			assert.Equal(t, []Item{{ID: "1", Blob: Blob("foo")}, {ID: "2", Blob: Blob("baz")}}, items)
+
+			info, err := Stat(dst, "2")
+			assert.NoError(t, err)
+			assert.Equal(t, Revision(2), info.Revision)
+			assert.True(t, info.Created.Equal(srcInfo.Created))
+			assert.True(t, info.Updated.Equal(srcInfo.Updated))
+
+			// the items are not overwritten
+			_, err = Restore(dst, bytes.NewReader(buf.Bytes()))
+			assert.ErrorContains(t, err, "already exists")
+		})
+	}
+}
+
+func TestSnapshotRestoreFallback(t *testing.T) {
+	src, err := NewMemory()
+	require.NoError(t, err)
+	require.NoError(t, src.Create("1", Blob("foo")))
+
+	var buf bytes.Buffer
+	require.NoError(t, Snapshot(NewTrash(src), &buf))
+
+	dst, err := NewMemory()
+	require.NoError(t, err)
+	count, err := Restore(NewTrash(dst), &buf)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+	info, err := Stat(dst, "1")
+	assert.NoError(t, err)
+	assert.Equal(t, Revision(1), info.Revision)
+	assert.WithinDuration(t, time.Now(), info.Created, time.Minute)
+}
+
+func TestRestoreCorrupted(t *testing.T) {
+	dst, err := NewMemory()
+	require.NoError(t, err)
+
+	_, err = Restore(dst, bytes.NewReader([]byte("not a snapshot")))
+	assert.ErrorIs(t, err, ErrCorruptedContent{Name: "snapshot"})
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write([]byte(`{"format":"todo-snapshot","version":1}` + "\n"))
+	zw.Write([]byte(`{"id":"1","blob":"Zm9v","checksum":"bogus"}` + "\n"))
+	zw.Close()
+	_, err = Restore(dst, &buf)
+	assert.ErrorIs(t, err, ErrCorruptedContent{Name: "1"})
+
+	buf.Reset()
+	zw = gzip.NewWriter(&buf)
+	zw.Write([]byte(`{"format":"todo-snapshot","version":99}` + "\n"))
+	zw.Close()
+	_, err = Restore(dst, &buf)
+	assert.ErrorContains(t, err, "newer than supported")
+}
@@ -0,0 +1,498 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+var _ Storage = &SQLite{}
+var _ StorageContext = &SQLite{}
+var _ Batcher = &SQLite{}
+var _ Pager = &SQLite{}
+var _ Versioner = &SQLite{}
+var _ Transactioner = &SQLite{}
+var _ Stater = &SQLite{}
+var _ importer = &SQLite{}
+var _ ChangeTracker = &SQLite{}
+var _ Lister = &SQLite{}
+var _ Compacter = &SQLite{}
+var _ Pinger = &SQLite{}
+
+// sqliteMigrations are the schema changes applied, in order, when opening a database.
+// The index in the slice, plus one, is the schema version recorded in the database.
+// Never change or remove an existing entry: append a new one instead.
+var sqliteMigrations = []string{
+	`CREATE TABLE items (
+		id   TEXT PRIMARY KEY,
+		blob BLOB NOT NULL
+	)`,
+	`ALTER TABLE items ADD COLUMN rev INTEGER NOT NULL DEFAULT 1`,
+	// timestamps are unix nanoseconds, zero if unknown
+	`ALTER TABLE items ADD COLUMN created INTEGER NOT NULL DEFAULT 0`,
+	`ALTER TABLE items ADD COLUMN updated INTEGER NOT NULL DEFAULT 0`,
+	// empty if unknown
+	`ALTER TABLE items ADD COLUMN checksum TEXT NOT NULL DEFAULT ''`,
+	// change tracking: every write assigns the next value of change_seq to the item,
+	// or to its tombstone once deleted. Zero if unknown.
+	`ALTER TABLE items ADD COLUMN seq INTEGER NOT NULL DEFAULT 0`,
+	`CREATE TABLE change_seq (value INTEGER NOT NULL)`,
+	`INSERT INTO change_seq (value) VALUES (0)`,
+	`CREATE TABLE tombstones (
+		id  TEXT PRIMARY KEY,
+		seq INTEGER NOT NULL
+	)`,
+	`CREATE TRIGGER items_insert_seq AFTER INSERT ON items BEGIN
+		UPDATE change_seq SET value = value + 1;
+		UPDATE items SET seq = (SELECT value FROM change_seq) WHERE id = NEW.id;
+		DELETE FROM tombstones WHERE id = NEW.id;
+	END`,
+	`CREATE TRIGGER items_update_seq AFTER UPDATE OF blob ON items BEGIN
+		UPDATE change_seq SET value = value + 1;
+		UPDATE items SET seq = (SELECT value FROM change_seq) WHERE id = NEW.id;
+	END`,
+	`CREATE TRIGGER items_delete_seq AFTER DELETE ON items BEGIN
+		UPDATE change_seq SET value = value + 1;
+		INSERT OR REPLACE INTO tombstones (id, seq) VALUES (OLD.id, (SELECT value FROM change_seq));
+	END`,
+	`CREATE INDEX items_seq ON items (seq)`,
+	// the list the item belongs to, see SplitListID; empty for DefaultList
+	`ALTER TABLE items ADD COLUMN list TEXT GENERATED ALWAYS AS (
+		CASE WHEN instr(id, '/') > 1 AND substr(id, 1, 1) <> '.' THEN substr(id, 1, instr(id, '/') - 1) ELSE '' END
+	) VIRTUAL`,
+	`CREATE INDEX items_list ON items (list, id)`,
+}
+
+// sqliteExecer is the subset of the functionalities shared by *sql.DB and *sql.Tx
+type sqliteExecer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// SQLite is a Storage backed by a single SQLite database file
+type SQLite struct {
+	db *sql.DB
+}
+
+// NewSQLite opens, or creates if missing, the SQLite database at the given path,
+// and brings its schema up to date.
+// If succesfull, returns the store; otherwise returns nil and the error describing the failure.
+func NewSQLite(path string) (*SQLite, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, err
+	}
+	// sqlite supports one writer at time anyway
+	db.SetMaxOpenConns(1)
+	if err := sqliteMigrate(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &SQLite{db: db}, nil
+}
+
+func sqliteMigrate(db *sql.DB) error {
+	var version int
+	if err := db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		return err
+	}
+	if version > len(sqliteMigrations) {
+		return fmt.Errorf("database schema version %d is newer than supported version %d", version, len(sqliteMigrations))
+	}
+	for ; version < len(sqliteMigrations); version++ {
+		tx, err := db.Begin()
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(sqliteMigrations[version]); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("migration to schema version %d failed: %w", version+1, err)
+		}
+		// PRAGMA doesn't support placeholders
+		if _, err := tx.Exec(fmt.Sprintf("PRAGMA user_version = %d", version+1)); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (sl *SQLite) Close() error {
+	return sl.db.Close()
+}
+
+// Ping checks that the database file can be opened
+func (sl *SQLite) Ping(ctx context.Context) error {
+	return sl.db.PingContext(ctx)
+}
+
+func (sl *SQLite) Create(objectID ID, data Blob) error {
+	return sl.CreateCtx(context.Background(), objectID, data)
+}
+
+func (sl *SQLite) CreateCtx(ctx context.Context, objectID ID, data Blob) error {
+	return sl.inTx(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		return sqliteCreate(ctx, tx, objectID, data)
+	})
+}
+
+func sqliteCreate(ctx context.Context, ex sqliteExecer, objectID ID, data Blob) error {
+	var found int
+	err := ex.QueryRowContext(ctx, "SELECT COUNT(*) FROM items WHERE id = ?", string(objectID)).Scan(&found)
+	if err != nil {
+		return err
+	}
+	if found > 0 {
+		return fmt.Errorf("item with id %v already exists", objectID)
+	}
+	now := time.Now().UnixNano()
+	_, err = ex.ExecContext(ctx, "INSERT INTO items (id, blob, created, updated, checksum) VALUES (?, ?, ?, ?, ?)",
+		string(objectID), []byte(data), now, now, Checksum(data))
+	return err
+}
+
+func (sl *SQLite) LoadAll() ([]Item, error) {
+	return sl.LoadAllCtx(context.Background())
+}
+
+func (sl *SQLite) LoadAllCtx(ctx context.Context) ([]Item, error) {
+	res := []Item{}
+	err := sl.walk(ctx, func(item Item) error {
+		res = append(res, item)
+		return nil
+	}, "SELECT id, blob FROM items ORDER BY id")
+	return res, err
+}
+
+func (sl *SQLite) LoadPage(offset, limit int) ([]Item, error) {
+	if limit <= 0 {
+		limit = -1 // no limit for sqlite
+	}
+	res := []Item{}
+	err := sl.walk(context.Background(), func(item Item) error {
+		res = append(res, item)
+		return nil
+	}, "SELECT id, blob FROM items ORDER BY id LIMIT ? OFFSET ?", limit, offset)
+	return res, err
+}
+
+func (sl *SQLite) LoadAllIn(list string) ([]Item, error) {
+	res := []Item{}
+	err := sl.walk(context.Background(), func(item Item) error {
+		res = append(res, item)
+		return nil
+	}, "SELECT id, blob FROM items WHERE list = ? ORDER BY id", list)
+	return res, err
+}
+
+func (sl *SQLite) Lists() ([]string, error) {
+	rows, err := sl.db.Query("SELECT DISTINCT list FROM items WHERE list <> '' ORDER BY list")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	res := []string{}
+	for rows.Next() {
+		var list string
+		if err := rows.Scan(&list); err != nil {
+			return nil, err
+		}
+		res = append(res, list)
+	}
+	return res, rows.Err()
+}
+
+// Compact rebuilds the database file, reclaiming the space left unused by the
+// deleted items. No item is rewritten.
+func (sl *SQLite) Compact() (int, error) {
+	_, err := sl.db.Exec("VACUUM")
+	return 0, err
+}
+
+func (sl *SQLite) Walk(fn func(Item) error) error {
+	return sl.walk(context.Background(), fn, "SELECT id, blob FROM items ORDER BY id")
+}
+
+// walk runs the given query, which must select the id and blob columns, and calls
+// fn on each row in turn, stopping at the first error.
+func (sl *SQLite) walk(ctx context.Context, fn func(Item) error, query string, args ...any) error {
+	rows, err := sl.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id string
+		var blob []byte
+		if err := rows.Scan(&id, &blob); err != nil {
+			return err
+		}
+		if err := fn(Item{ID: ID(id), Blob: Blob(blob)}); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func (sl *SQLite) Load(objectID ID) (Blob, error) {
+	return sl.LoadCtx(context.Background(), objectID)
+}
+
+func (sl *SQLite) LoadCtx(ctx context.Context, objectID ID) (Blob, error) {
+	var blob []byte
+	err := sl.db.QueryRowContext(ctx, "SELECT blob FROM items WHERE id = ?", string(objectID)).Scan(&blob)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound{ID: objectID}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return Blob(blob), nil
+}
+
+func (sl *SQLite) Save(objectID ID, blob Blob) error {
+	return sl.SaveCtx(context.Background(), objectID, blob)
+}
+
+func (sl *SQLite) SaveCtx(ctx context.Context, objectID ID, blob Blob) error {
+	return sqliteSave(ctx, sl.db, objectID, blob)
+}
+
+func sqliteSave(ctx context.Context, ex sqliteExecer, objectID ID, blob Blob) error {
+	res, err := ex.ExecContext(ctx, "UPDATE items SET blob = ?, rev = rev + 1, updated = ?, checksum = ? WHERE id = ?",
+		[]byte(blob), time.Now().UnixNano(), Checksum(blob), string(objectID))
+	if err != nil {
+		return err
+	}
+	return sqliteCheckAffected(res, objectID)
+}
+
+func (sl *SQLite) Delete(objectID ID) error {
+	return sl.DeleteCtx(context.Background(), objectID)
+}
+
+func (sl *SQLite) DeleteCtx(ctx context.Context, objectID ID) error {
+	return sqliteDelete(ctx, sl.db, objectID)
+}
+
+func sqliteDelete(ctx context.Context, ex sqliteExecer, objectID ID) error {
+	res, err := ex.ExecContext(ctx, "DELETE FROM items WHERE id = ?", string(objectID))
+	if err != nil {
+		return err
+	}
+	return sqliteCheckAffected(res, objectID)
+}
+
+// CreateMany, SaveAll and DeleteAll run in a single transaction, which is rolled
+// back entirely at the first failure.
+
+func (sl *SQLite) CreateMany(items []Item) error {
+	return sl.inTx(context.Background(), func(ctx context.Context, tx *sql.Tx) error {
+		for _, item := range items {
+			if err := sqliteCreate(ctx, tx, item.ID, item.Blob); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (sl *SQLite) SaveAll(items []Item) error {
+	return sl.inTx(context.Background(), func(ctx context.Context, tx *sql.Tx) error {
+		for _, item := range items {
+			if err := sqliteSave(ctx, tx, item.ID, item.Blob); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (sl *SQLite) DeleteAll(ids []ID) error {
+	return sl.inTx(context.Background(), func(ctx context.Context, tx *sql.Tx) error {
+		for _, id := range ids {
+			if err := sqliteDelete(ctx, tx, id); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (sl *SQLite) Begin() (Tx, error) {
+	tx, err := sl.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	return sqliteTx{tx: tx}, nil
+}
+
+type sqliteTx struct {
+	tx *sql.Tx
+}
+
+func (stx sqliteTx) Create(objectID ID, data Blob) error {
+	return sqliteCreate(context.Background(), stx.tx, objectID, data)
+}
+
+func (stx sqliteTx) Save(objectID ID, blob Blob) error {
+	return sqliteSave(context.Background(), stx.tx, objectID, blob)
+}
+
+func (stx sqliteTx) Delete(objectID ID) error {
+	return sqliteDelete(context.Background(), stx.tx, objectID)
+}
+
+func (stx sqliteTx) Commit() error {
+	return stx.tx.Commit()
+}
+
+func (stx sqliteTx) Rollback() error {
+	return stx.tx.Rollback()
+}
+
+// inTx runs the given function in a transaction, committing if the function
+// succeeds and rolling back otherwise.
+func (sl *SQLite) inTx(ctx context.Context, fn func(context.Context, *sql.Tx) error) error {
+	tx, err := sl.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := fn(ctx, tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (sl *SQLite) LoadRev(objectID ID) (Blob, Revision, error) {
+	var blob []byte
+	var rev Revision
+	err := sl.db.QueryRow("SELECT blob, rev FROM items WHERE id = ?", string(objectID)).Scan(&blob, &rev)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, 0, ErrNotFound{ID: objectID}
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	return Blob(blob), rev, nil
+}
+
+func (sl *SQLite) SaveIf(objectID ID, blob Blob, expected Revision) (Revision, error) {
+	var rev Revision
+	err := sl.inTx(context.Background(), func(ctx context.Context, tx *sql.Tx) error {
+		err := tx.QueryRowContext(ctx, "SELECT rev FROM items WHERE id = ?", string(objectID)).Scan(&rev)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNotFound{ID: objectID}
+		}
+		if err != nil {
+			return err
+		}
+		if rev != expected {
+			return ErrConflict{ID: objectID, Expected: expected, Actual: rev}
+		}
+		rev++
+		_, err = tx.ExecContext(ctx, "UPDATE items SET blob = ?, rev = ?, updated = ?, checksum = ? WHERE id = ?",
+			[]byte(blob), rev, time.Now().UnixNano(), Checksum(blob), string(objectID))
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return rev, nil
+}
+
+func (sl *SQLite) Stat(objectID ID) (ItemInfo, error) {
+	var blob []byte
+	var created, updated int64
+	info := ItemInfo{ID: objectID}
+	err := sl.db.QueryRow("SELECT blob, rev, created, updated, checksum FROM items WHERE id = ?", string(objectID)).Scan(
+		&blob, &info.Revision, &created, &updated, &info.Checksum)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ItemInfo{}, ErrNotFound{ID: objectID}
+	}
+	if err != nil {
+		return ItemInfo{}, err
+	}
+	info.Size = len(blob)
+	info.Created = sqliteTime(created)
+	info.Updated = sqliteTime(updated)
+	if info.Checksum == "" {
+		// items stored before checksums were tracked
+		info.Checksum = Checksum(blob)
+	}
+	return info, nil
+}
+
+func (sl *SQLite) importItem(item Item, info ItemInfo) error {
+	info = importedMeta(info)
+	return sl.inTx(context.Background(), func(ctx context.Context, tx *sql.Tx) error {
+		var found int
+		err := tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM items WHERE id = ?", string(item.ID)).Scan(&found)
+		if err != nil {
+			return err
+		}
+		if found > 0 {
+			return fmt.Errorf("item with id %v already exists", item.ID)
+		}
+		_, err = tx.ExecContext(ctx, "INSERT INTO items (id, blob, rev, created, updated, checksum) VALUES (?, ?, ?, ?, ?, ?)",
+			string(item.ID), []byte(item.Blob), info.Revision, info.Created.UnixNano(), info.Updated.UnixNano(), Checksum(item.Blob))
+		return err
+	})
+}
+
+func (sl *SQLite) Changes(since uint64) ([]Change, uint64, error) {
+	var res []Change
+	var last uint64
+	err := sl.inTx(context.Background(), func(ctx context.Context, tx *sql.Tx) error {
+		if err := tx.QueryRowContext(ctx, "SELECT value FROM change_seq").Scan(&last); err != nil {
+			return err
+		}
+		rows, err := tx.QueryContext(ctx, `SELECT id, seq, 0 FROM items WHERE seq > ? OR ? = 0
+			UNION ALL SELECT id, seq, 1 FROM tombstones WHERE seq > ?
+			ORDER BY 2`, since, since, since)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var change Change
+			if err := rows.Scan(&change.ID, &change.Seq, &change.Deleted); err != nil {
+				return err
+			}
+			res = append(res, change)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	sortChanges(res)
+	return res, last, nil
+}
+
+func sqliteTime(ts int64) time.Time {
+	if ts == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, ts)
+}
+
+func sqliteCheckAffected(res sql.Result, objectID ID) error {
+	count, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrNotFound{ID: objectID}
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package store
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestSQLite(t *testing.T) (*SQLite, string) {
+	path := filepath.Join(t.TempDir(), "todo.db")
+	st, err := NewSQLite(path)
+	require.NoError(t, err)
+	t.Cleanup(func() { st.Close() })
+	return st, path
+}
+
+func TestSQLiteCreateLoad(t *testing.T) {
+	st, _ := newTestSQLite(t)
+
+	err := st.Create("1", Blob("foobar"))
+	assert.NoError(t, err)
+
+	blob, err := st.Load("1")
+	assert.NoError(t, err)
+	assert.Equal(t, "foobar", string(blob))
+}
+
+func TestSQLiteCreateDuplicate(t *testing.T) {
+	st, _ := newTestSQLite(t)
+
+	assert.NoError(t, st.Create("1", Blob("foobar")))
+	assert.Error(t, st.Create("1", Blob("fizzbuzz")))
+}
+
+func TestSQLiteSaveDelete(t *testing.T) {
+	st, _ := newTestSQLite(t)
+
+	assert.ErrorIs(t, st.Save("1", Blob("foobar")), ErrNotFound{ID: "1"})
+	assert.ErrorIs(t, st.Delete("1"), ErrNotFound{ID: "1"})
+
+	assert.NoError(t, st.Create("1", Blob("foobar")))
+	assert.NoError(t, st.Save("1", Blob("fizzbuzz")))
+	blob, err := st.Load("1")
+	assert.NoError(t, err)
+	assert.Equal(t, "fizzbuzz", string(blob))
+
+	assert.NoError(t, st.Delete("1"))
+	_, err = st.Load("1")
+	assert.ErrorIs(t, err, ErrNotFound{ID: "1"})
+}
+
+func TestSQLiteReopen(t *testing.T) {
+	st, path := newTestSQLite(t)
+	assert.NoError(t, st.Create("1", Blob("foo")))
+	assert.NoError(t, st.Create("2", Blob("bar")))
+	assert.NoError(t, st.Close())
+
+	st2, err := NewSQLite(path)
+	require.NoError(t, err)
+	defer st2.Close()
+
+	items, err := st2.LoadAll()
+	assert.NoError(t, err)
+	assert.Equal(t, []Item{{ID: "1", Blob: Blob("foo")}, {ID: "2", Blob: Blob("bar")}}, items)
+}
@@ -0,0 +1,51 @@
+package store
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+)
+
+// ItemInfo holds the metadata of a stored item
+type ItemInfo struct {
+	ID   ID
+	Size int
+	// Created is the creation time of the item. Zero if unknown.
+	Created time.Time
+	// Updated is the last modification time of the item. Zero if unknown.
+	Updated time.Time
+	// Checksum is the hex-encoded SHA256 digest of the item content
+	Checksum string
+	// Revision is the current revision of the item. Zero if unknown.
+	Revision Revision
+}
+
+// Stater is implemented by the storages which persist the metadata of the items
+type Stater interface {
+	Stat(ID) (ItemInfo, error)
+}
+
+// Stat returns the metadata of the item identified by the given ID.
+// If the storage doesn't persist metadata, they are computed from the content:
+// the size and the checksum are always available, creation and modification times are not.
+func Stat(st Storage, id ID) (ItemInfo, error) {
+	if sr, ok := st.(Stater); ok {
+		return sr.Stat(id)
+	}
+	blob, rev, err := LoadRev(st, id)
+	if err != nil {
+		return ItemInfo{}, err
+	}
+	return ItemInfo{
+		ID:       id,
+		Size:     len(blob),
+		Checksum: Checksum(blob),
+		Revision: rev,
+	}, nil
+}
+
+// Checksum returns the checksum of the given blob, in the same format as ItemInfo.Checksum
+func Checksum(blob Blob) string {
+	sum := sha256.Sum256(blob)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,50 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStat(t *testing.T) {
+	for name, st := range batchTestStorages(t) {
+		t.Run(name, func(t *testing.T) {
+			before := time.Now()
+			require.NoError(t, st.Create("1", Blob("foo")))
+			info, err := Stat(st, "1")
+			assert.NoError(t, err)
+			assert.Equal(t, ID("1"), info.ID)
+			assert.Equal(t, 3, info.Size)
+			assert.Equal(t, Checksum(Blob("foo")), info.Checksum)
+			assert.Equal(t, Revision(1), info.Revision)
+			assert.False(t, info.Created.Before(before))
+			assert.Equal(t, info.Created, info.Updated)
+
+			require.NoError(t, st.Save("1", Blob("fizzbuzz")))
+			info2, err := Stat(st, "1")
+			assert.NoError(t, err)
+			assert.Equal(t, 8, info2.Size)
+			assert.Equal(t, Checksum(Blob("fizzbuzz")), info2.Checksum)
+			assert.Equal(t, Revision(2), info2.Revision)
+			assert.True(t, info2.Created.Equal(info.Created))
+			assert.False(t, info2.Updated.Before(info.Updated))
+
+			_, err = Stat(st, "2")
+			assert.ErrorIs(t, err, ErrNotFound{ID: "2"})
+		})
+	}
+}
+
+func TestStatFallback(t *testing.T) {
+	mem, err := NewMemory()
+	require.NoError(t, err)
+	// the trash doesn't persist metadata on its own
+	st := NewTrash(mem)
+	require.NoError(t, st.Create("1", Blob("foo")))
+
+	info, err := Stat(st, "1")
+	assert.NoError(t, err)
+	assert.Equal(t, ItemInfo{ID: "1", Size: 3, Checksum: Checksum(Blob("foo"))}, info)
+}
@@ -0,0 +1,184 @@
+package store
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var _ Storage = &Traced{}
+var _ StorageContext = &Traced{}
+var _ Compacter = &Traced{}
+var _ Transactioner = &Traced{}
+
+// Traced is a Storage decorator which traces all the operations, as spans named after the
+// operations, e.g. "store.load_all". The spans of the operations given a context, through its
+// StorageContext flavour, are children of the span the context carries; see Bind. The
+// transactions are traced as "store.tx" spans, the parents of the spans of their operations.
+type Traced struct {
+	inner  Storage
+	ctx    StorageContext
+	tracer trace.Tracer
+}
+
+// NewTraced creates a new Traced decorating the given storage, starting the spans with the tracer
+func NewTraced(inner Storage, tracer trace.Tracer) *Traced {
+	return &Traced{
+		inner:  inner,
+		ctx:    WithContext(inner),
+		tracer: tracer,
+	}
+}
+
+// start starts the span of the operation, on the item with the given ID if not NullID
+func (tr *Traced) start(ctx context.Context, op string, id ID) (context.Context, trace.Span) {
+	ctx, span := tr.tracer.Start(ctx, "store."+op, trace.WithSpanKind(trace.SpanKindClient))
+	if id != NullID {
+		span.SetAttributes(attribute.String("store.id", string(id)))
+	}
+	return ctx, span
+}
+
+// endSpan ends the span of the operation, recording the error it failed with, if any
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+func (tr *Traced) Close() error {
+	return tr.ctx.Close()
+}
+
+func (tr *Traced) Compact() (int, error) {
+	return Compact(tr.inner)
+}
+
+func (tr *Traced) CreateCtx(ctx context.Context, objectID ID, data Blob) error {
+	ctx, span := tr.start(ctx, OpCreate, objectID)
+	err := tr.ctx.CreateCtx(ctx, objectID, data)
+	endSpan(span, err)
+	return err
+}
+
+func (tr *Traced) LoadAllCtx(ctx context.Context) ([]Item, error) {
+	ctx, span := tr.start(ctx, OpLoadAll, NullID)
+	items, err := tr.ctx.LoadAllCtx(ctx)
+	span.SetAttributes(attribute.Int("store.items", len(items)))
+	endSpan(span, err)
+	return items, err
+}
+
+func (tr *Traced) LoadCtx(ctx context.Context, objectID ID) (Blob, error) {
+	ctx, span := tr.start(ctx, OpLoad, objectID)
+	blob, err := tr.ctx.LoadCtx(ctx, objectID)
+	endSpan(span, err)
+	return blob, err
+}
+
+func (tr *Traced) SaveCtx(ctx context.Context, objectID ID, blob Blob) error {
+	ctx, span := tr.start(ctx, OpSave, objectID)
+	err := tr.ctx.SaveCtx(ctx, objectID, blob)
+	endSpan(span, err)
+	return err
+}
+
+func (tr *Traced) DeleteCtx(ctx context.Context, objectID ID) error {
+	ctx, span := tr.start(ctx, OpDelete, objectID)
+	err := tr.ctx.DeleteCtx(ctx, objectID)
+	endSpan(span, err)
+	return err
+}
+
+// BeginCtx starts a transaction on the decorated storage, see Begin, traced as a child of the span
+// the context carries, until committed or rolled back
+func (tr *Traced) BeginCtx(ctx context.Context) (Tx, error) {
+	ctx, span := tr.tracer.Start(ctx, "store.tx", trace.WithSpanKind(trace.SpanKindClient))
+	tx, err := Begin(tr.inner)
+	if err != nil {
+		endSpan(span, err)
+		return nil, err
+	}
+	return &tracedTx{Tx: tx, tr: tr, ctx: ctx, span: span}, nil
+}
+
+func (tr *Traced) Begin() (Tx, error) {
+	return tr.BeginCtx(context.Background())
+}
+
+// tracedTx traces the operations of the transaction as children of its span
+type tracedTx struct {
+	Tx
+	tr   *Traced
+	ctx  context.Context
+	span trace.Span
+	ops  int
+	done bool
+}
+
+// trace runs the operation of the transaction on the item with the given ID in its own span
+func (ttx *tracedTx) trace(op string, id ID, run func() error) error {
+	_, span := ttx.tr.start(ttx.ctx, op, id)
+	err := run()
+	endSpan(span, err)
+	ttx.ops++
+	return err
+}
+
+func (ttx *tracedTx) Create(objectID ID, data Blob) error {
+	return ttx.trace(OpCreate, objectID, func() error { return ttx.Tx.Create(objectID, data) })
+}
+
+func (ttx *tracedTx) Save(objectID ID, blob Blob) error {
+	return ttx.trace(OpSave, objectID, func() error { return ttx.Tx.Save(objectID, blob) })
+}
+
+func (ttx *tracedTx) Delete(objectID ID) error {
+	return ttx.trace(OpDelete, objectID, func() error { return ttx.Tx.Delete(objectID) })
+}
+
+// end ends the span of the transaction, once, e.g. not again on the rollbacks deferred after a commit
+func (ttx *tracedTx) end(committed bool, err error) {
+	if ttx.done {
+		return
+	}
+	ttx.done = true
+	ttx.span.SetAttributes(attribute.Int("store.ops", ttx.ops), attribute.Bool("store.committed", committed))
+	endSpan(ttx.span, err)
+}
+
+func (ttx *tracedTx) Commit() error {
+	err := ttx.Tx.Commit()
+	ttx.end(err == nil, err)
+	return err
+}
+
+func (ttx *tracedTx) Rollback() error {
+	err := ttx.Tx.Rollback()
+	ttx.end(false, err)
+	return err
+}
+
+func (tr *Traced) Create(objectID ID, data Blob) error {
+	return tr.CreateCtx(context.Background(), objectID, data)
+}
+
+func (tr *Traced) LoadAll() ([]Item, error) {
+	return tr.LoadAllCtx(context.Background())
+}
+
+func (tr *Traced) Load(objectID ID) (Blob, error) {
+	return tr.LoadCtx(context.Background(), objectID)
+}
+
+func (tr *Traced) Save(objectID ID, blob Blob) error {
+	return tr.SaveCtx(context.Background(), objectID, blob)
+}
+
+func (tr *Traced) Delete(objectID ID) error {
+	return tr.DeleteCtx(context.Background(), objectID)
+}
@@ -0,0 +1,97 @@
+package store
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTraced(t *testing.T) {
+	mem, err := NewMemory()
+	require.NoError(t, err)
+	require.NoError(t, mem.Create("1", Blob("foo")))
+	sr := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr))
+	tracer := tp.Tracer("test")
+	tr := NewTraced(mem, tracer)
+
+	items, err := tr.LoadAll()
+	require.NoError(t, err)
+	assert.Len(t, items, 1)
+
+	ctx, parent := tracer.Start(context.Background(), "request")
+	bound := Bind(tr, ctx)
+	require.NoError(t, bound.Save("1", Blob("bar")))
+	_, err = bound.Load("2")
+	assert.Error(t, err)
+	parent.End()
+
+	spans := sr.Ended()
+	require.Len(t, spans, 4)
+	loadAll, save, load := spans[0], spans[1], spans[2]
+
+	assert.Equal(t, "store.load_all", loadAll.Name())
+	assert.False(t, loadAll.Parent().IsValid(), "no parent without context")
+	assert.Contains(t, loadAll.Attributes(), attribute.Int("store.items", 1))
+
+	assert.Equal(t, "store.save", save.Name())
+	assert.Equal(t, parent.SpanContext().SpanID(), save.Parent().SpanID())
+	assert.Contains(t, save.Attributes(), attribute.String("store.id", "1"))
+	assert.Equal(t, codes.Unset, save.Status().Code)
+
+	assert.Equal(t, "store.load", load.Name())
+	assert.Equal(t, parent.SpanContext().SpanID(), load.Parent().SpanID())
+	assert.Equal(t, codes.Error, load.Status().Code)
+	assert.Len(t, load.Events(), 1, "the error is recorded")
+}
+
+func TestTracedTx(t *testing.T) {
+	mem, err := NewMemory()
+	require.NoError(t, err)
+	sr := tracetest.NewSpanRecorder()
+	tracer := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr)).Tracer("test")
+	tr := NewTraced(mem, tracer)
+
+	ctx, parent := tracer.Start(context.Background(), "request")
+	tx, err := tr.BeginCtx(ctx)
+	require.NoError(t, err)
+	require.NoError(t, tx.Create("1", Blob("foo")))
+	require.NoError(t, tx.Create("2", Blob("bar")))
+	require.NoError(t, tx.Save("2", Blob("baz")))
+	require.NoError(t, tx.Commit())
+	assert.ErrorIs(t, tx.Rollback(), ErrTxDone)
+	parent.End()
+
+	items, err := mem.LoadAll()
+	require.NoError(t, err)
+	assert.Len(t, items, 2, "committed to the decorated storage")
+
+	spans := sr.Ended()
+	require.Len(t, spans, 5, "the rollback after the commit is no span")
+	create, save, txSpan := spans[0], spans[2], spans[3]
+	assert.Equal(t, "store.tx", txSpan.Name())
+	assert.Equal(t, parent.SpanContext().SpanID(), txSpan.Parent().SpanID())
+	assert.Contains(t, txSpan.Attributes(), attribute.Int("store.ops", 3))
+	assert.Contains(t, txSpan.Attributes(), attribute.Bool("store.committed", true))
+	assert.Equal(t, "store.create", create.Name())
+	assert.Equal(t, txSpan.SpanContext().SpanID(), create.Parent().SpanID())
+	assert.Contains(t, create.Attributes(), attribute.String("store.id", "1"))
+	assert.Equal(t, "store.save", save.Name())
+	assert.Equal(t, txSpan.SpanContext().SpanID(), save.Parent().SpanID())
+
+	tx, err = tr.Begin()
+	require.NoError(t, err)
+	require.NoError(t, tx.Delete("1"))
+	require.NoError(t, tx.Rollback())
+	spans = sr.Ended()
+	require.Len(t, spans, 7)
+	assert.Contains(t, spans[6].Attributes(), attribute.Bool("store.committed", false))
+	_, err = mem.Load("1")
+	assert.NoError(t, err, "rolled back")
+}
@@ -0,0 +1,221 @@
+package store
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"time"
+)
+
+// trashPrefix marks the IDs of the trashed items in the inner storage
+const trashPrefix = ".trash/"
+
+var _ Storage = &Trash{}
+var _ Compacter = &Trash{}
+
+// Trash is a Storage decorator which implements soft deletion: deleted items are moved
+// in the trash, within the inner storage, along with their deletion time, so they can
+// be restored later. Trashed items are hidden to all the Storage operations; use
+// ListTrash, Restore and PurgeTrash to manage them.
+type Trash struct {
+	inner Storage
+	// Now returns the current time. Can be replaced to control time in tests.
+	Now func() time.Time
+	// Retention is how long Compact keeps the items in the trash. Zero keeps them forever.
+	Retention time.Duration
+}
+
+// TrashedItem is a item in the trash
+type TrashedItem struct {
+	Item
+	DeletedAt time.Time
+}
+
+type trashEntry struct {
+	DeletedAt time.Time `json:"deleted"`
+	Blob      []byte    `json:"blob"`
+}
+
+// NewTrash creates a new Trash decorating the given storage
+func NewTrash(inner Storage) *Trash {
+	return &Trash{
+		inner: inner,
+		Now:   time.Now,
+	}
+}
+
+func trashID(id ID) ID {
+	return ID(trashPrefix + string(id))
+}
+
+func isTrashID(id ID) bool {
+	return strings.HasPrefix(string(id), trashPrefix)
+}
+
+func (tr *Trash) Close() error {
+	return tr.inner.Close()
+}
+
+func (tr *Trash) Create(objectID ID, data Blob) error {
+	if isTrashID(objectID) {
+		return ErrNotFound{ID: objectID}
+	}
+	return tr.inner.Create(objectID, data)
+}
+
+func (tr *Trash) LoadAll() ([]Item, error) {
+	items, err := tr.inner.LoadAll()
+	if err != nil {
+		return nil, err
+	}
+	res := make([]Item, 0, len(items))
+	for _, item := range items {
+		if isTrashID(item.ID) {
+			continue
+		}
+		res = append(res, item)
+	}
+	return res, nil
+}
+
+func (tr *Trash) Load(objectID ID) (Blob, error) {
+	if isTrashID(objectID) {
+		return nil, ErrNotFound{ID: objectID}
+	}
+	return tr.inner.Load(objectID)
+}
+
+func (tr *Trash) Save(objectID ID, blob Blob) error {
+	if isTrashID(objectID) {
+		return ErrNotFound{ID: objectID}
+	}
+	return tr.inner.Save(objectID, blob)
+}
+
+// Delete moves the item in the trash. If a item with the same ID is already
+// in the trash, it is replaced.
+func (tr *Trash) Delete(objectID ID) error {
+	if isTrashID(objectID) {
+		return ErrNotFound{ID: objectID}
+	}
+	blob, err := tr.inner.Load(objectID)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(trashEntry{DeletedAt: tr.Now(), Blob: blob})
+	if err != nil {
+		return err
+	}
+
+	// check before starting the transaction: backends may not allow reads meanwhile
+	_, err = tr.inner.Load(trashID(objectID))
+	alreadyTrashed := (err == nil)
+
+	tx, err := Begin(tr.inner)
+	if err != nil {
+		return err
+	}
+	if alreadyTrashed {
+		err = tx.Save(trashID(objectID), data)
+	} else {
+		err = tx.Create(trashID(objectID), data)
+	}
+	if err == nil {
+		err = tx.Delete(objectID)
+	}
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// ListTrash returns all the items in the trash, sorted by ID
+func (tr *Trash) ListTrash() ([]TrashedItem, error) {
+	items, err := tr.inner.LoadAll()
+	if err != nil {
+		return nil, err
+	}
+	res := []TrashedItem{}
+	for _, item := range items {
+		if !isTrashID(item.ID) {
+			continue
+		}
+		var entry trashEntry
+		if err := json.Unmarshal(item.Blob, &entry); err != nil {
+			return nil, ErrCorruptedContent{Name: string(item.ID)}
+		}
+		res = append(res, TrashedItem{
+			Item: Item{
+				ID:   ID(strings.TrimPrefix(string(item.ID), trashPrefix)),
+				Blob: Blob(entry.Blob),
+			},
+			DeletedAt: entry.DeletedAt,
+		})
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].ID < res[j].ID
+	})
+	return res, nil
+}
+
+// Restore moves back a item from the trash. Fails if the item is not in the trash,
+// or if another item with the same ID was created meanwhile.
+func (tr *Trash) Restore(objectID ID) error {
+	data, err := tr.inner.Load(trashID(objectID))
+	if err != nil {
+		return ErrNotFound{ID: objectID}
+	}
+	var entry trashEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return ErrCorruptedContent{Name: string(trashID(objectID))}
+	}
+
+	tx, err := Begin(tr.inner)
+	if err != nil {
+		return err
+	}
+	err = tx.Create(objectID, Blob(entry.Blob))
+	if err == nil {
+		err = tx.Delete(trashID(objectID))
+	}
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// PurgeTrash permanently removes from the trash the items deleted more than
+// olderThan ago. Returns the number of items removed.
+func (tr *Trash) PurgeTrash(olderThan time.Duration) (int, error) {
+	trashed, err := tr.ListTrash()
+	if err != nil {
+		return 0, err
+	}
+	deadline := tr.Now().Add(-olderThan)
+	var ids []ID
+	for _, item := range trashed {
+		if item.DeletedAt.Before(deadline) {
+			ids = append(ids, trashID(item.ID))
+		}
+	}
+	if err := DeleteAll(tr.inner, ids); err != nil {
+		return 0, err
+	}
+	return len(ids), nil
+}
+
+// Compact purges the items in the trash for longer than Retention, if set,
+// then compacts the inner storage.
+func (tr *Trash) Compact() (int, error) {
+	purged := 0
+	if tr.Retention > 0 {
+		var err error
+		if purged, err = tr.PurgeTrash(tr.Retention); err != nil {
+			return 0, err
+		}
+	}
+	count, err := Compact(tr.inner)
+	return purged + count, err
+}
@@ -0,0 +1,69 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrashDeleteRestore(t *testing.T) {
+	for name, inner := range batchTestStorages(t) {
+		t.Run(name, func(t *testing.T) {
+			now := time.Date(2024, time.November, 11, 10, 0, 0, 0, time.UTC)
+			st := NewTrash(inner)
+			st.Now = func() time.Time { return now }
+
+			require.NoError(t, st.Create("1", Blob("foo")))
+			require.NoError(t, st.Create("2", Blob("bar")))
+			require.NoError(t, st.Delete("1"))
+
+			items, err := st.LoadAll()
+			assert.NoError(t, err)
+			assert.Equal(t, []Item{{ID: "2", Blob: Blob("bar")}}, items)
+			_, err = st.Load("1")
+			assert.ErrorIs(t, err, ErrNotFound{ID: "1"})
+			_, err = st.Load(trashID("1"))
+			assert.ErrorIs(t, err, ErrNotFound{ID: trashID("1")})
+
+			trashed, err := st.ListTrash()
+			assert.NoError(t, err)
+			assert.Equal(t, []TrashedItem{{Item: Item{ID: "1", Blob: Blob("foo")}, DeletedAt: now}}, trashed)
+
+			require.NoError(t, st.Restore("1"))
+			blob, err := st.Load("1")
+			assert.NoError(t, err)
+			assert.Equal(t, "foo", string(blob))
+			trashed, err = st.ListTrash()
+			assert.NoError(t, err)
+			assert.Empty(t, trashed)
+
+			assert.ErrorIs(t, st.Restore("1"), ErrNotFound{ID: "1"})
+		})
+	}
+}
+
+func TestTrashPurge(t *testing.T) {
+	inner, err := NewMemory()
+	require.NoError(t, err)
+	now := time.Date(2024, time.November, 11, 10, 0, 0, 0, time.UTC)
+	st := NewTrash(inner)
+	st.Now = func() time.Time { return now }
+
+	require.NoError(t, st.Create("1", Blob("foo")))
+	require.NoError(t, st.Create("2", Blob("bar")))
+	require.NoError(t, st.Delete("1"))
+	now = now.Add(48 * time.Hour)
+	require.NoError(t, st.Delete("2"))
+	now = now.Add(1 * time.Hour)
+
+	purged, err := st.PurgeTrash(24 * time.Hour)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, purged)
+
+	trashed, err := st.ListTrash()
+	assert.NoError(t, err)
+	assert.Len(t, trashed, 1)
+	assert.Equal(t, ID("2"), trashed[0].ID)
+}
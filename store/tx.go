@@ -0,0 +1,113 @@
+package store
+
+import (
+	"errors"
+)
+
+// ErrTxDone is returned when using a transaction already committed or rolled back
+var ErrTxDone = errors.New("transaction already committed or rolled back")
+
+// Tx is a storage transaction: the operations performed through it become visible
+// all together on Commit, or are discarded on Rollback.
+// A Tx must be used by a single goroutine, and must always be terminated calling
+// either Commit or Rollback.
+type Tx interface {
+	Create(ID, Blob) error
+	Save(ID, Blob) error
+	Delete(ID) error
+	Commit() error
+	Rollback() error
+}
+
+// Transactioner is implemented by the storages which natively support transactions
+type Transactioner interface {
+	Begin() (Tx, error)
+}
+
+// Begin starts a new transaction on the given storage.
+// If the storage is not a Transactioner, returns a best effort emulation which buffers
+// the operations and performs them one by one on Commit, stopping at the first failure:
+// in this case the commit is not atomic.
+func Begin(st Storage) (Tx, error) {
+	if tr, ok := st.(Transactioner); ok {
+		return tr.Begin()
+	}
+	return &stagedTx{apply: func(ops []txOp) error {
+		for _, op := range ops {
+			if err := op.applyTo(st); err != nil {
+				return err
+			}
+		}
+		return nil
+	}}, nil
+}
+
+type txOpKind int
+
+const (
+	txCreate txOpKind = iota
+	txSave
+	txDelete
+)
+
+// txOp is a buffered transaction operation
+type txOp struct {
+	kind txOpKind
+	id   ID
+	blob Blob
+}
+
+func (op txOp) applyTo(st Storage) error {
+	switch op.kind {
+	case txCreate:
+		return st.Create(op.id, op.blob)
+	case txSave:
+		return st.Save(op.id, op.blob)
+	default:
+		return st.Delete(op.id)
+	}
+}
+
+// stagedTx buffers the operations, and hands them over to the apply function on Commit
+type stagedTx struct {
+	ops   []txOp
+	done  bool
+	apply func([]txOp) error
+}
+
+func (stx *stagedTx) stage(op txOp) error {
+	if stx.done {
+		return ErrTxDone
+	}
+	stx.ops = append(stx.ops, op)
+	return nil
+}
+
+func (stx *stagedTx) Create(id ID, blob Blob) error {
+	return stx.stage(txOp{kind: txCreate, id: id, blob: cloneBlob(blob)})
+}
+
+func (stx *stagedTx) Save(id ID, blob Blob) error {
+	return stx.stage(txOp{kind: txSave, id: id, blob: cloneBlob(blob)})
+}
+
+func (stx *stagedTx) Delete(id ID) error {
+	return stx.stage(txOp{kind: txDelete, id: id})
+}
+
+func (stx *stagedTx) Commit() error {
+	if stx.done {
+		return ErrTxDone
+	}
+	stx.done = true
+	return stx.apply(stx.ops)
+}
+
+func (stx *stagedTx) Rollback() error {
+	if stx.done {
+		return ErrTxDone
+	}
+	stx.done = true
+	stx.ops = nil
+	return nil
+}
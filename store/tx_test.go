@@ -0,0 +1,75 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTxCommit(t *testing.T) {
+	for name, st := range batchTestStorages(t) {
+		t.Run(name, func(t *testing.T) {
+			require.NoError(t, st.Create("1", Blob("foo")))
+			require.NoError(t, st.Create("2", Blob("foo")))
+
+			tx, err := Begin(st)
+			require.NoError(t, err)
+			assert.NoError(t, tx.Create("3", Blob("bar")))
+			assert.NoError(t, tx.Save("1", Blob("bar")))
+			assert.NoError(t, tx.Delete("2"))
+			assert.NoError(t, tx.Commit())
+
+			items, err := st.LoadAll()
+			assert.NoError(t, err)
+			assert.Equal(t, []Item{{ID: "1", Blob: Blob("bar")}, {ID: "3", Blob: Blob("bar")}}, items)
+		})
+	}
+}
+
+func TestTxRollback(t *testing.T) {
+	for name, st := range batchTestStorages(t) {
+		t.Run(name, func(t *testing.T) {
+			require.NoError(t, st.Create("1", Blob("foo")))
+
+			tx, err := Begin(st)
+			require.NoError(t, err)
+			assert.NoError(t, tx.Create("2", Blob("bar")))
+			assert.NoError(t, tx.Save("1", Blob("bar")))
+			assert.NoError(t, tx.Rollback())
+
+			items, err := st.LoadAll()
+			assert.NoError(t, err)
+			assert.Equal(t, []Item{{ID: "1", Blob: Blob("foo")}}, items)
+		})
+	}
+}
+
+func TestTxFailureIsAtomic(t *testing.T) {
+	for name, st := range batchTestStorages(t) {
+		t.Run(name, func(t *testing.T) {
+			tx, err := Begin(st)
+			require.NoError(t, err)
+			assert.NoError(t, tx.Create("1", Blob("foo")))
+			// backends may report the failure either immediately or on commit
+			if err := tx.Delete("missing"); err != nil {
+				assert.ErrorIs(t, err, ErrNotFound{ID: "missing"})
+				assert.NoError(t, tx.Rollback())
+			} else {
+				assert.ErrorIs(t, tx.Commit(), ErrNotFound{ID: "missing"})
+			}
+
+			items, err := st.LoadAll()
+			assert.NoError(t, err)
+			assert.Empty(t, items)
+		})
+	}
+}
+
+func TestStagedTxDone(t *testing.T) {
+	tx := &stagedTx{apply: func([]txOp) error { return nil }}
+	assert.NoError(t, tx.Commit())
+	assert.ErrorIs(t, tx.Create("1", Blob("foo")), ErrTxDone)
+	assert.ErrorIs(t, tx.Commit(), ErrTxDone)
+	assert.ErrorIs(t, tx.Rollback(), ErrTxDone)
+}
@@ -1,6 +1,12 @@
 package store
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrUnsupported is returned when the storage backend doesn't implement a optional operation
+var ErrUnsupported = errors.New("operation not supported by the storage backend")
 
 // ID is an opaque value which uniquely identifies a Todo. Can only be compared for equality
 // Note: this incidentally is 1:1 with API objects, but this is an implementation
@@ -8,6 +14,10 @@ import "fmt"
 type ID string
 type Blob []byte
 
+// Revision identifies a version of a stored item. Every successfull update of an
+// item increments its revision. The zero value means unknown revision.
+type Revision uint64
+
 func (b Blob) MarshalBinary() ([]byte, error) {
 	return b, nil
 }
@@ -42,6 +52,18 @@ func (e ErrNotFound) Error() string {
 	return fmt.Sprintf("unknown id: %v", e.ID)
 }
 
+// ErrConflict is returned when a conditional update fails because the item
+// was modified meanwhile, so its revision doesn't match the expected one.
+type ErrConflict struct {
+	ID       ID
+	Expected Revision
+	Actual   Revision
+}
+
+func (e ErrConflict) Error() string {
+	return fmt.Sprintf("conflict on id %v: expected revision %d, found %d", e.ID, e.Expected, e.Actual)
+}
+
 type ErrCorruptedContent struct {
 	Name string
 }
@@ -0,0 +1,196 @@
+package store
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// QuarantinePrefix marks the IDs of the items moved away by Verify in repair mode
+const QuarantinePrefix = ".corrupt/"
+
+// IsQuarantined returns true if the given ID belongs to a quarantined item
+func IsQuarantined(id ID) bool {
+	return strings.HasPrefix(string(id), QuarantinePrefix)
+}
+
+// ProblemKind classifies the problems found by Verify
+type ProblemKind string
+
+const (
+	// ProblemInvalidID is reported for items whose ID can't be used safely
+	ProblemInvalidID ProblemKind = "invalid-id"
+	// ProblemUnparsable is reported for items whose content is rejected by VerifyOptions.Parse
+	ProblemUnparsable ProblemKind = "unparsable"
+	// ProblemChecksum is reported for items whose content doesn't match the stored checksum
+	ProblemChecksum ProblemKind = "checksum-mismatch"
+	// ProblemOrphan is reported for backend entries (e.g. metadata) without a matching item
+	ProblemOrphan ProblemKind = "orphan"
+)
+
+// Problem describes a bad entry found by Verify
+type Problem struct {
+	ID     ID
+	Kind   ProblemKind
+	Reason string
+}
+
+func (p Problem) String() string {
+	return fmt.Sprintf("%q: %s: %s", p.ID, p.Kind, p.Reason)
+}
+
+// VerifyOptions tunes the behaviour of Verify
+type VerifyOptions struct {
+	// Parse validates the content of a item. Optional: if nil, the content is not checked.
+	Parse func(Blob) error
+	// Repair moves the bad items in quarantine, under QuarantinePrefix, and drops the
+	// orphaned entries, instead of just reporting them.
+	Repair bool
+}
+
+// VerifyReport is the outcome of Verify
+type VerifyReport struct {
+	// Checked is the number of items examined
+	Checked  int
+	Problems []Problem
+	// Quarantined lists the original IDs of the items moved in quarantine
+	Quarantined []ID
+	// Dropped lists the orphaned entries removed
+	Dropped []ID
+}
+
+// OK returns true if no problems were found
+func (rep VerifyReport) OK() bool {
+	return len(rep.Problems) == 0
+}
+
+// orphanFinder is implemented by the storages which keep per-item entries
+// besides the content, which can be left behind by a incomplete write.
+type orphanFinder interface {
+	findOrphans() ([]ID, error)
+	dropOrphans([]ID) error
+}
+
+// Verify checks the integrity of all the items of the storage: their IDs, their
+// content and, if the storage persists them, their checksums. Items already in
+// quarantine or in the trash are only checked against their checksums.
+// Fails only if the storage can't be accessed: the bad entries are reported, and,
+// in repair mode, quarantined, so they no longer prevent loading the good ones.
+func Verify(st Storage, opts VerifyOptions) (VerifyReport, error) {
+	var rep VerifyReport
+	items, err := loadAllSorted(st)
+	if err != nil {
+		return rep, err
+	}
+	_, hasMeta := st.(Stater)
+	var bad []ID
+	for _, item := range items {
+		rep.Checked++
+		problem, ok := verifyItem(st, item, hasMeta, opts.Parse)
+		if ok {
+			continue
+		}
+		rep.Problems = append(rep.Problems, problem)
+		if !IsQuarantined(item.ID) {
+			bad = append(bad, item.ID)
+		}
+	}
+
+	var orphans []ID
+	if of, ok := st.(orphanFinder); ok {
+		orphans, err = of.findOrphans()
+		if err != nil {
+			return rep, err
+		}
+		for _, id := range orphans {
+			rep.Problems = append(rep.Problems, Problem{ID: id, Kind: ProblemOrphan, Reason: "entry without content"})
+		}
+	}
+
+	if !opts.Repair {
+		return rep, nil
+	}
+	for _, id := range bad {
+		if err := quarantine(st, id); err != nil {
+			return rep, err
+		}
+		rep.Quarantined = append(rep.Quarantined, id)
+	}
+	if len(orphans) > 0 {
+		if err := st.(orphanFinder).dropOrphans(orphans); err != nil {
+			return rep, err
+		}
+		rep.Dropped = orphans
+	}
+	return rep, nil
+}
+
+func verifyItem(st Storage, item Item, hasMeta bool, parse func(Blob) error) (Problem, bool) {
+	if hasMeta {
+		info, err := Stat(st, item.ID)
+		if err == nil && info.Checksum != Checksum(item.Blob) {
+			return Problem{ID: item.ID, Kind: ProblemChecksum, Reason: "content doesn't match the stored checksum"}, false
+		}
+	}
+	if IsQuarantined(item.ID) || IsMeta(item.ID) || isTrashID(item.ID) {
+		// not regular items: their content has its own format
+		return Problem{}, true
+	}
+	if reason := checkID(item.ID); reason != "" {
+		return Problem{ID: item.ID, Kind: ProblemInvalidID, Reason: reason}, false
+	}
+	if parse != nil {
+		if err := parse(item.Blob); err != nil {
+			return Problem{ID: item.ID, Kind: ProblemUnparsable, Reason: err.Error()}, false
+		}
+	}
+	return Problem{}, true
+}
+
+// checkID returns the reason why the given ID is invalid, or empty string if it is valid
+func checkID(id ID) string {
+	if id == NullID {
+		return "empty id"
+	}
+	if !utf8.ValidString(string(id)) {
+		return "id is not valid UTF-8"
+	}
+	for _, r := range string(id) {
+		if unicode.IsControl(r) {
+			return "id contains control characters"
+		}
+	}
+	return ""
+}
+
+// quarantine moves the given item under QuarantinePrefix, replacing any previous
+// quarantined item with the same ID.
+func quarantine(st Storage, id ID) error {
+	blob, err := st.Load(id)
+	if err != nil {
+		return err
+	}
+	qid := ID(QuarantinePrefix + string(id))
+	// check before starting the transaction: backends may not allow reads meanwhile
+	_, err = st.Load(qid)
+	alreadyQuarantined := (err == nil)
+
+	tx, err := Begin(st)
+	if err != nil {
+		return err
+	}
+	if alreadyQuarantined {
+		err = tx.Save(qid, blob)
+	} else {
+		err = tx.Create(qid, blob)
+	}
+	if err == nil {
+		err = tx.Delete(id)
+	}
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
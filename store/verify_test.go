@@ -0,0 +1,102 @@
+package store
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+)
+
+func verifyParseJSON(blob Blob) error {
+	var v map[string]any
+	return json.Unmarshal(blob, &v)
+}
+
+func TestVerify(t *testing.T) {
+	for name, st := range batchTestStorages(t) {
+		t.Run(name, func(t *testing.T) {
+			require.NoError(t, CreateMany(st, []Item{
+				{ID: "1", Blob: Blob(`{"title":"foo"}`)},
+				{ID: "2", Blob: Blob(`{"title":`)},
+				{ID: "bad\x00id", Blob: Blob(`{}`)},
+			}))
+
+			rep, err := Verify(st, VerifyOptions{Parse: verifyParseJSON})
+			assert.NoError(t, err)
+			assert.False(t, rep.OK())
+			assert.Equal(t, 3, rep.Checked)
+			require.Len(t, rep.Problems, 2)
+			assert.Equal(t, ID("2"), rep.Problems[0].ID)
+			assert.Equal(t, ProblemUnparsable, rep.Problems[0].Kind)
+			assert.Equal(t, ID("bad\x00id"), rep.Problems[1].ID)
+			assert.Equal(t, ProblemInvalidID, rep.Problems[1].Kind)
+			assert.Empty(t, rep.Quarantined)
+
+			rep, err = Verify(st, VerifyOptions{Parse: verifyParseJSON, Repair: true})
+			assert.NoError(t, err)
+			assert.Equal(t, []ID{"2", "bad\x00id"}, rep.Quarantined)
+
+			_, err = st.Load("2")
+			assert.ErrorIs(t, err, ErrNotFound{ID: "2"})
+			blob, err := st.Load(QuarantinePrefix + "2")
+			assert.NoError(t, err)
+			assert.Equal(t, `{"title":`, string(blob))
+
+			// quarantined items are no longer a problem
+			rep, err = Verify(st, VerifyOptions{Parse: verifyParseJSON})
+			assert.NoError(t, err)
+			assert.True(t, rep.OK())
+		})
+	}
+}
+
+func TestVerifyChecksum(t *testing.T) {
+	sl, err := NewSQLite(filepath.Join(t.TempDir(), "todo.db"))
+	require.NoError(t, err)
+	defer sl.Close()
+	require.NoError(t, sl.Create("1", Blob("foo")))
+	// simulate a corruption behind the back of the storage
+	_, err = sl.db.Exec("UPDATE items SET blob = ? WHERE id = ?", []byte("fob"), "1")
+	require.NoError(t, err)
+
+	rep, err := Verify(sl, VerifyOptions{})
+	assert.NoError(t, err)
+	require.Len(t, rep.Problems, 1)
+	assert.Equal(t, Problem{ID: "1", Kind: ProblemChecksum, Reason: "content doesn't match the stored checksum"}, rep.Problems[0])
+}
+
+func TestVerifyOrphans(t *testing.T) {
+	bl, err := NewBolt(filepath.Join(t.TempDir(), "todo.bolt"))
+	require.NoError(t, err)
+	defer bl.Close()
+	require.NoError(t, bl.Create("1", Blob("foo")))
+	// simulate a incomplete delete
+	err = bl.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltItemsBucket).Delete([]byte("1"))
+	})
+	require.NoError(t, err)
+
+	rep, err := Verify(bl, VerifyOptions{Repair: true})
+	assert.NoError(t, err)
+	require.Len(t, rep.Problems, 1)
+	assert.Equal(t, ProblemOrphan, rep.Problems[0].Kind)
+	assert.Equal(t, []ID{"1"}, rep.Dropped)
+
+	rep, err = Verify(bl, VerifyOptions{})
+	assert.NoError(t, err)
+	assert.True(t, rep.OK())
+}
+
+func TestVerifyMeta(t *testing.T) {
+	mem, err := NewMemory()
+	require.NoError(t, err)
+	require.NoError(t, mem.Create("1", Blob(`{"title":"foo"}`)))
+	require.NoError(t, mem.Create(MetaID("settings"), Blob(`not an object`)))
+
+	rep, err := Verify(mem, VerifyOptions{Parse: verifyParseJSON})
+	require.NoError(t, err)
+	assert.True(t, rep.OK())
+}
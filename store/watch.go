@@ -0,0 +1,276 @@
+package store
+
+import (
+	"context"
+	"sync"
+)
+
+// EventType identifies the kind of change occurred in a storage
+type EventType string
+
+const (
+	EventCreated EventType = "created"
+	EventUpdated EventType = "updated"
+	EventDeleted EventType = "deleted"
+)
+
+// Event describes a change occurred in a storage
+type Event struct {
+	Type EventType
+	ID   ID
+	// Blob is the new content of the item. Empty for EventDeleted.
+	Blob Blob
+}
+
+// Watcher is implemented by the storages which can notify about their changes
+type Watcher interface {
+	// Watch returns a channel which receives all the changes of the storage
+	// until the given context is done. The channel is then closed.
+	Watch(ctx context.Context) (<-chan Event, error)
+}
+
+// Watch returns a channel which receives all the changes of the storage until the
+// given context is done. Fails with ErrUnsupported if the storage can't be watched.
+func Watch(ctx context.Context, st Storage) (<-chan Event, error) {
+	wt, ok := st.(Watcher)
+	if !ok {
+		return nil, ErrUnsupported
+	}
+	return wt.Watch(ctx)
+}
+
+// watchBuffer is how many events can be pending for a slow watcher
+const watchBuffer = 64
+
+var _ Storage = &Notifier{}
+var _ Watcher = &Notifier{}
+var _ Batcher = &Notifier{}
+var _ Pager = &Notifier{}
+var _ Versioner = &Notifier{}
+var _ Transactioner = &Notifier{}
+var _ Stater = &Notifier{}
+var _ Lister = &Notifier{}
+var _ Compacter = &Notifier{}
+
+// Notifier is a Storage decorator which notifies the watchers about all the changes
+// performed through it. Changes performed bypassing the Notifier, e.g. by another
+// process sharing the same backend, are not detected.
+// Watchers are expected to keep up with the changes: if a watcher falls behind
+// more than a few dozens events, its channel is closed so it can resync.
+type Notifier struct {
+	inner    Storage
+	lock     sync.Mutex
+	watchers map[chan Event]struct{}
+}
+
+// NewNotifier creates a new Notifier decorating the given storage
+func NewNotifier(inner Storage) *Notifier {
+	return &Notifier{
+		inner:    inner,
+		watchers: make(map[chan Event]struct{}),
+	}
+}
+
+func (nt *Notifier) Watch(ctx context.Context) (<-chan Event, error) {
+	ch := make(chan Event, watchBuffer)
+	nt.lock.Lock()
+	nt.watchers[ch] = struct{}{}
+	nt.lock.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		nt.unwatch(ch)
+	}()
+	return ch, nil
+}
+
+func (nt *Notifier) unwatch(ch chan Event) {
+	nt.lock.Lock()
+	defer nt.lock.Unlock()
+	if _, ok := nt.watchers[ch]; !ok {
+		return // already gone
+	}
+	delete(nt.watchers, ch)
+	close(ch)
+}
+
+func (nt *Notifier) publish(evs ...Event) {
+	nt.lock.Lock()
+	defer nt.lock.Unlock()
+watchers:
+	for ch := range nt.watchers {
+		for _, ev := range evs {
+			select {
+			case ch <- ev:
+			default:
+				// the watcher is too slow: drop it, so it can notice and resync
+				delete(nt.watchers, ch)
+				close(ch)
+				continue watchers
+			}
+		}
+	}
+}
+
+// Close closes the inner storage and all the watcher channels
+func (nt *Notifier) Close() error {
+	nt.lock.Lock()
+	for ch := range nt.watchers {
+		delete(nt.watchers, ch)
+		close(ch)
+	}
+	nt.lock.Unlock()
+	return nt.inner.Close()
+}
+
+func (nt *Notifier) Create(objectID ID, data Blob) error {
+	if err := nt.inner.Create(objectID, data); err != nil {
+		return err
+	}
+	nt.publish(Event{Type: EventCreated, ID: objectID, Blob: cloneBlob(data)})
+	return nil
+}
+
+func (nt *Notifier) LoadAll() ([]Item, error) {
+	return nt.inner.LoadAll()
+}
+
+func (nt *Notifier) Load(objectID ID) (Blob, error) {
+	return nt.inner.Load(objectID)
+}
+
+func (nt *Notifier) Save(objectID ID, blob Blob) error {
+	if err := nt.inner.Save(objectID, blob); err != nil {
+		return err
+	}
+	nt.publish(Event{Type: EventUpdated, ID: objectID, Blob: cloneBlob(blob)})
+	return nil
+}
+
+func (nt *Notifier) Delete(objectID ID) error {
+	if err := nt.inner.Delete(objectID); err != nil {
+		return err
+	}
+	nt.publish(Event{Type: EventDeleted, ID: objectID})
+	return nil
+}
+
+func (nt *Notifier) CreateMany(items []Item) error {
+	if err := CreateMany(nt.inner, items); err != nil {
+		return err
+	}
+	nt.publish(itemEvents(EventCreated, items)...)
+	return nil
+}
+
+func (nt *Notifier) SaveAll(items []Item) error {
+	if err := SaveAll(nt.inner, items); err != nil {
+		return err
+	}
+	nt.publish(itemEvents(EventUpdated, items)...)
+	return nil
+}
+
+func (nt *Notifier) DeleteAll(ids []ID) error {
+	if err := DeleteAll(nt.inner, ids); err != nil {
+		return err
+	}
+	evs := make([]Event, 0, len(ids))
+	for _, id := range ids {
+		evs = append(evs, Event{Type: EventDeleted, ID: id})
+	}
+	nt.publish(evs...)
+	return nil
+}
+
+func (nt *Notifier) LoadPage(offset, limit int) ([]Item, error) {
+	return LoadPage(nt.inner, offset, limit)
+}
+
+func (nt *Notifier) Walk(fn func(Item) error) error {
+	return Walk(nt.inner, fn)
+}
+
+func (nt *Notifier) LoadRev(objectID ID) (Blob, Revision, error) {
+	return LoadRev(nt.inner, objectID)
+}
+
+func (nt *Notifier) SaveIf(objectID ID, blob Blob, expected Revision) (Revision, error) {
+	rev, err := SaveIf(nt.inner, objectID, blob, expected)
+	if err != nil {
+		return rev, err
+	}
+	nt.publish(Event{Type: EventUpdated, ID: objectID, Blob: cloneBlob(blob)})
+	return rev, nil
+}
+
+func (nt *Notifier) Stat(objectID ID) (ItemInfo, error) {
+	return Stat(nt.inner, objectID)
+}
+
+func (nt *Notifier) LoadAllIn(list string) ([]Item, error) {
+	return LoadAllIn(nt.inner, list)
+}
+
+func (nt *Notifier) Lists() ([]string, error) {
+	return Lists(nt.inner)
+}
+
+func (nt *Notifier) Compact() (int, error) {
+	return Compact(nt.inner)
+}
+
+func (nt *Notifier) Begin() (Tx, error) {
+	tx, err := Begin(nt.inner)
+	if err != nil {
+		return nil, err
+	}
+	return &notifierTx{Tx: tx, nt: nt}, nil
+}
+
+// notifierTx collects the events, and publishes them only once committed
+type notifierTx struct {
+	Tx
+	nt  *Notifier
+	evs []Event
+}
+
+func (ntx *notifierTx) Create(objectID ID, data Blob) error {
+	if err := ntx.Tx.Create(objectID, data); err != nil {
+		return err
+	}
+	ntx.evs = append(ntx.evs, Event{Type: EventCreated, ID: objectID, Blob: cloneBlob(data)})
+	return nil
+}
+
+func (ntx *notifierTx) Save(objectID ID, blob Blob) error {
+	if err := ntx.Tx.Save(objectID, blob); err != nil {
+		return err
+	}
+	ntx.evs = append(ntx.evs, Event{Type: EventUpdated, ID: objectID, Blob: cloneBlob(blob)})
+	return nil
+}
+
+func (ntx *notifierTx) Delete(objectID ID) error {
+	if err := ntx.Tx.Delete(objectID); err != nil {
+		return err
+	}
+	ntx.evs = append(ntx.evs, Event{Type: EventDeleted, ID: objectID})
+	return nil
+}
+
+func (ntx *notifierTx) Commit() error {
+	if err := ntx.Tx.Commit(); err != nil {
+		return err
+	}
+	ntx.nt.publish(ntx.evs...)
+	return nil
+}
+
+func itemEvents(evType EventType, items []Item) []Event {
+	evs := make([]Event, 0, len(items))
+	for _, item := range items {
+		evs = append(evs, Event{Type: evType, ID: item.ID, Blob: cloneBlob(item.Blob)})
+	}
+	return evs
+}
@@ -0,0 +1,73 @@
+package store
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestNotifier(t *testing.T) *Notifier {
+	mem, err := NewMemory()
+	require.NoError(t, err)
+	return NewNotifier(mem)
+}
+
+func TestWatchEvents(t *testing.T) {
+	st := newTestNotifier(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	evs, err := Watch(ctx, st)
+	require.NoError(t, err)
+
+	require.NoError(t, st.Create("1", Blob("foo")))
+	require.NoError(t, st.Save("1", Blob("bar")))
+	require.NoError(t, st.Delete("1"))
+	// failed operations are not notified
+	require.Error(t, st.Delete("1"))
+
+	tx, err := st.Begin()
+	require.NoError(t, err)
+	require.NoError(t, tx.Create("2", Blob("baz")))
+	require.NoError(t, tx.Commit())
+
+	tx, err = st.Begin()
+	require.NoError(t, err)
+	require.NoError(t, tx.Create("3", Blob("baz")))
+	require.NoError(t, tx.Rollback())
+
+	assert.Equal(t, Event{Type: EventCreated, ID: "1", Blob: Blob("foo")}, <-evs)
+	assert.Equal(t, Event{Type: EventUpdated, ID: "1", Blob: Blob("bar")}, <-evs)
+	assert.Equal(t, Event{Type: EventDeleted, ID: "1"}, <-evs)
+	assert.Equal(t, Event{Type: EventCreated, ID: "2", Blob: Blob("baz")}, <-evs)
+	assert.Empty(t, evs)
+
+	cancel()
+	_, ok := <-evs
+	assert.False(t, ok, "channel should be closed once the context is done")
+}
+
+func TestWatchSlowWatcherIsDropped(t *testing.T) {
+	st := newTestNotifier(t)
+	evs, err := st.Watch(context.Background())
+	require.NoError(t, err)
+
+	require.NoError(t, st.Create("1", Blob("foo")))
+	for i := 0; i < watchBuffer; i++ {
+		require.NoError(t, st.Save("1", Blob("foo")))
+	}
+
+	count := 0
+	for range evs {
+		count++
+	}
+	assert.Equal(t, watchBuffer, count)
+}
+
+func TestWatchUnsupported(t *testing.T) {
+	mem, err := NewMemory()
+	require.NoError(t, err)
+	_, err = Watch(context.Background(), mem)
+	assert.ErrorIs(t, err, ErrUnsupported)
+}
@@ -0,0 +1,18 @@
+// Package storesync reconciles two stores of todos, e.g. the SQLite database of a laptop and the one
+// of a server, so that both hold the same items: the todos, archived and trashed included, and
+// their attachments. The metadata, like the history of the todos, the tags and the API keys,
+// stay on their side.
+// The local store keeps, for each remote, the checksum of each item as of the last sync: an item
+// changed since on a side is copied to the other, and an item of the last sync gone from a side
+// is its tombstone, which deletes it on the other. The items changed on both sides, or changed on
+// one and deleted on the other, are conflicts, resolved by a Strategy. By default the todos
+// changed on both sides are merged, as the local store also keeps the clock of each todo, when
+// each of its fields was written and the adds of its tags: each field is a last-writer-wins
+// register, the tags a observed-remove set and the comments a grow-only log, so the comments
+// deleted on a side are back if the other side changed the todo meanwhile. The items are written
+// only if their revision is still the one read, so the changes made during the sync are never lost.
+// The ledgers load the todos on startup: a server sees the changes synced to its store once
+// restarted. The todos created on both sides with the same ID, never synced, and at different
+// times, are distinct: the local one is moved to a new ID. The stores creating todos on both
+// sides should rather generate unique IDs, e.g. ULIDs.
+package storesync
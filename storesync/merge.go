@@ -0,0 +1,302 @@
+package storesync
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/gotestbootcamp/go-todo-app/store"
+	"github.com/gotestbootcamp/go-todo-app/task"
+)
+
+// mergedFields are the fields of the todos merged otherwise than by their last writer
+var mergedFields = []string{"schema", "created", "updated", "tags", "comments"}
+
+// fieldStamp is the last-writer-wins register of a field of a todo: the checksum of its value,
+// and when it was written
+type fieldStamp struct {
+	Sum  string    `json:"sum"`
+	Time time.Time `json:"time"`
+}
+
+// clock is what the sync knows of the todo both stores held after the last sync: when each of
+// its fields was written, and the dots of the adds of its tags, each unique
+type clock struct {
+	Fields map[string]fieldStamp `json:"fields,omitempty"`
+	Tags   map[string][]string   `json:"tags,omitempty"`
+}
+
+// tagSet is a observed-remove set of tags: removing a tag removes the adds observed only, so
+// that a tag added on a side and removed on the other concurrently is kept
+type tagSet struct {
+	// adds are the dots of the adds of each tag
+	adds map[string][]string
+	// removed are the dots of the adds removed
+	removed map[string]bool
+}
+
+// merge returns the union of the sets
+func (ts tagSet) merge(other tagSet) tagSet {
+	res := tagSet{adds: make(map[string][]string), removed: make(map[string]bool)}
+	for _, set := range []tagSet{ts, other} {
+		for tag, dots := range set.adds {
+			for _, dot := range dots {
+				if !slices.Contains(res.adds[tag], dot) {
+					res.adds[tag] = append(res.adds[tag], dot)
+				}
+			}
+		}
+		for dot := range set.removed {
+			res.removed[dot] = true
+		}
+	}
+	return res
+}
+
+// live returns the dots of the adds of the tag not removed, none if the set doesn't hold it
+func (ts tagSet) live(tag string) []string {
+	var dots []string
+	for _, dot := range ts.adds[tag] {
+		if !ts.removed[dot] {
+			dots = append(dots, dot)
+		}
+	}
+	return dots
+}
+
+// version is a version of a todo in conflict, as seen since the last sync
+type version struct {
+	tk task.Task
+	// fields are the encoded fields of the task, but the mergedFields
+	fields map[string]json.RawMessage
+	stamps map[string]fieldStamp
+	tags   tagSet
+}
+
+// newVersion returns the version of the todo, whose fields and tags are the ones of the clock of
+// the last sync if unchanged since, or else written when the todo was last updated
+func newVersion(base clock, tk task.Task) (version, error) {
+	v := version{tk: tk, stamps: make(map[string]fieldStamp), tags: tagSet{adds: make(map[string][]string), removed: make(map[string]bool)}}
+	data, err := json.Marshal(tk)
+	if err != nil {
+		return v, err
+	}
+	if err := json.Unmarshal(data, &v.fields); err != nil {
+		return v, err
+	}
+	for _, field := range mergedFields {
+		delete(v.fields, field)
+	}
+	// the fields unset since are written too
+	for field := range base.Fields {
+		if _, ok := v.fields[field]; !ok {
+			v.fields[field] = nil
+		}
+	}
+	for field, val := range v.fields {
+		stamp := fieldStamp{Sum: fieldSum(val), Time: tk.Updated}
+		if prev, ok := base.Fields[field]; ok && prev.Sum == stamp.Sum {
+			stamp = prev
+		}
+		v.stamps[field] = stamp
+	}
+
+	for _, tag := range tk.Tags {
+		dots, ok := base.Tags[tag]
+		if !ok {
+			dot, err := newDot()
+			if err != nil {
+				return v, err
+			}
+			dots = []string{dot}
+		}
+		v.tags.adds[tag] = dots
+	}
+	for tag, dots := range base.Tags {
+		if _, ok := v.tags.adds[tag]; !ok {
+			for _, dot := range dots {
+				v.tags.removed[dot] = true
+			}
+		}
+	}
+	return v, nil
+}
+
+// clock returns the clock of the version
+func (v version) clock() clock {
+	c := clock{Fields: make(map[string]fieldStamp), Tags: make(map[string][]string)}
+	for field, stamp := range v.stamps {
+		if v.fields[field] != nil {
+			c.Fields[field] = stamp
+		}
+	}
+	for _, tag := range v.tk.Tags {
+		c.Tags[tag] = v.tags.live(tag)
+	}
+	return c
+}
+
+// clockOf returns the clock of the todo encoded in the blob, given the one of the last sync.
+// Nil if the blob isn't a todo.
+func clockOf(base clock, blob store.Blob) *clock {
+	tk, err := task.Unmarshal(blob)
+	if err != nil {
+		return nil
+	}
+	v, err := newVersion(base, tk)
+	if err != nil {
+		return nil
+	}
+	c := v.clock()
+	return &c
+}
+
+// mergeVersions merges the versions of the todo: each field is the one written last, the remote
+// one if written at the same time, the tags are the union of the ones of the versions but the
+// ones removed on a side and not added on the other, and the comments the ones of either version.
+// Fails if the merged todo isn't valid, e.g. because it has too many tags.
+func mergeVersions(local, remote version) (task.Task, clock, error) {
+	merged := version{
+		fields: make(map[string]json.RawMessage),
+		stamps: make(map[string]fieldStamp),
+		tags:   local.tags.merge(remote.tags),
+	}
+	for _, v := range []version{local, remote} {
+		for field, stamp := range v.stamps {
+			if cur, ok := merged.stamps[field]; ok && cur.Time.After(stamp.Time) {
+				continue
+			}
+			merged.stamps[field], merged.fields[field] = stamp, v.fields[field]
+		}
+	}
+
+	fields := make(map[string]json.RawMessage, len(merged.fields))
+	for field, val := range merged.fields {
+		if val != nil {
+			fields[field] = val
+		}
+	}
+	var tags []string
+	for _, tag := range append(slices.Clone(local.tk.Tags), remote.tk.Tags...) {
+		if len(merged.tags.live(tag)) > 0 && !slices.Contains(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	created, updated := local.tk.Created, local.tk.Updated
+	if remote.tk.Created.Before(created) {
+		created = remote.tk.Created
+	}
+	if remote.tk.Updated.After(updated) {
+		updated = remote.tk.Updated
+	}
+	for field, val := range map[string]any{
+		"schema":   task.SchemaVersion,
+		"created":  created,
+		"updated":  updated,
+		"tags":     tags,
+		"comments": mergeComments(local.tk.Comments, remote.tk.Comments),
+	} {
+		data, err := json.Marshal(val)
+		if err != nil {
+			return task.Task{}, clock{}, err
+		}
+		fields[field] = data
+	}
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return task.Task{}, clock{}, err
+	}
+	if merged.tk, err = task.Unmarshal(data); err != nil {
+		return task.Task{}, clock{}, err
+	}
+	return merged.tk, merged.clock(), nil
+}
+
+// mergeComments returns the comments of either version, a grow-only log: the ones of the same
+// author created at the same time are the same comment, whose body is the one edited last. The
+// comments of the remote version get new IDs if the local one uses theirs already.
+func mergeComments(local, remote []task.Comment) []task.Comment {
+	res := slices.Clone(local)
+	lastID := 0
+	for _, cm := range res {
+		lastID = max(lastID, cm.ID)
+	}
+	for _, cm := range remote {
+		i := slices.IndexFunc(res, func(other task.Comment) bool {
+			return other.Author == cm.Author && other.Created.Equal(cm.Created)
+		})
+		switch {
+		case i >= 0:
+			if editedAt(cm).After(editedAt(res[i])) {
+				cm.ID = res[i].ID
+				res[i] = cm
+			}
+			continue
+		case slices.ContainsFunc(res, func(other task.Comment) bool { return other.ID == cm.ID }):
+			lastID++
+			cm.ID = lastID
+		}
+		lastID = max(lastID, cm.ID)
+		res = append(res, cm)
+	}
+	slices.SortFunc(res, func(a, b task.Comment) int { return a.ID - b.ID })
+	return res
+}
+
+// editedAt returns when the body of the comment was written last
+func editedAt(cm task.Comment) time.Time {
+	if cm.Edited != nil {
+		return *cm.Edited
+	}
+	return cm.Created
+}
+
+// fieldSum returns the checksum of the encoded value of a field, empty if unset
+func fieldSum(val json.RawMessage) string {
+	if val == nil {
+		return ""
+	}
+	return store.Checksum(store.Blob(val))[:16]
+}
+
+// newDot returns a new unique dot
+func newDot() (string, error) {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// merge returns the merge of the versions of the todo in conflict, and its clock, given the one
+// of the last sync. False if the strategy isn't Merge, or if the versions can't be merged, e.g.
+// because one was deleted, or they aren't todos.
+func (s *Syncer) merge(id store.ID, base clock, local, remote store.Blob) (store.Blob, clock, bool) {
+	if s.Strategy != Merge || local == nil || remote == nil {
+		return nil, clock{}, false
+	}
+	var versions []version
+	for _, blob := range []store.Blob{local, remote} {
+		tk, err := task.Unmarshal(blob)
+		if err != nil {
+			return nil, clock{}, false
+		}
+		v, err := newVersion(base, tk)
+		if err != nil {
+			return nil, clock{}, false
+		}
+		versions = append(versions, v)
+	}
+	tk, c, err := mergeVersions(versions[0], versions[1])
+	if err == nil {
+		var blob store.Blob
+		if blob, err = task.Marshal(tk); err == nil {
+			return blob, c, true
+		}
+	}
+	slog.Warn("storesync: Sync: can't merge, keeping the latest", "id", id, "error", err)
+	return nil, clock{}, false
+}
@@ -0,0 +1,197 @@
+package storesync
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gotestbootcamp/go-todo-app/store"
+	"github.com/gotestbootcamp/go-todo-app/task"
+)
+
+// editTodo changes the todo in the storage with edit, as updated at the given time
+func editTodo(t *testing.T, st store.Storage, id store.ID, updated time.Time, edit func(tk *task.Task)) {
+	blob, err := st.Load(id)
+	require.NoError(t, err)
+	tk, err := task.Unmarshal(blob)
+	require.NoError(t, err)
+	edit(&tk)
+	tk.Updated = updated
+	blob, err = task.Marshal(tk)
+	require.NoError(t, err)
+	require.NoError(t, st.Save(id, blob))
+}
+
+func loadTodo(t *testing.T, st store.Storage, id store.ID) task.Task {
+	blob, err := st.Load(id)
+	require.NoError(t, err)
+	tk, err := task.Unmarshal(blob)
+	require.NoError(t, err)
+	return tk
+}
+
+func TestSyncMerge(t *testing.T) {
+	local, remote := newStores(t)
+	tk := task.New("pay rent")
+	tk.Created, tk.Updated = epoch, epoch
+	tk.Tags = []string{"home", "bills"}
+	_, err := tk.AddComment("bob", "by friday", epoch)
+	require.NoError(t, err)
+	blob, err := task.Marshal(tk)
+	require.NoError(t, err)
+	require.NoError(t, local.Create("1", blob))
+	s := New(local, remote, "server")
+	_, err = s.Sync()
+	require.NoError(t, err)
+
+	// offline edits of different fields on each side
+	editTodo(t, local, "1", epoch.Add(time.Hour), func(tk *task.Task) {
+		tk.Title = "pay the rent"
+		tk.Tags = []string{"home"}
+		_, err := tk.AddComment("bob", "paid half", epoch.Add(time.Hour))
+		require.NoError(t, err)
+	})
+	editTodo(t, remote, "1", epoch.Add(2*time.Hour), func(tk *task.Task) {
+		tk.Description = "500 EUR"
+		tk.Priority = task.PriorityHigh
+		tk.Tags = append(tk.Tags, "urgent")
+		_, err := tk.AddComment("alice", "don't forget", epoch.Add(2*time.Hour))
+		require.NoError(t, err)
+	})
+	report, err := s.Sync()
+	require.NoError(t, err)
+	assert.Equal(t, Report{{ID: "1", Action: Merged, Conflict: true}}, report)
+	merged := loadTodo(t, local, "1")
+	assert.Equal(t, merged, loadTodo(t, remote, "1"))
+	assert.Equal(t, "pay the rent", merged.Title)
+	assert.Equal(t, "500 EUR", merged.Description)
+	assert.Equal(t, task.PriorityHigh, merged.Priority)
+	assert.Equal(t, []string{"home", "urgent"}, merged.Tags)
+	require.Len(t, merged.Comments, 3)
+	assert.Equal(t, []int{1, 2, 3}, []int{merged.Comments[0].ID, merged.Comments[1].ID, merged.Comments[2].ID})
+	assert.Equal(t, "don't forget", merged.Comments[2].Body)
+	assert.Equal(t, epoch.Add(2*time.Hour), merged.Updated)
+
+	report, err = s.Sync()
+	require.NoError(t, err)
+	assert.Empty(t, report)
+
+	// the field written last wins, even if the todo was updated since
+	editTodo(t, remote, "1", epoch.Add(3*time.Hour), func(tk *task.Task) { tk.Title = "pay rent now" })
+	editTodo(t, local, "1", epoch.Add(4*time.Hour), func(tk *task.Task) { tk.Description = "" })
+	editTodo(t, remote, "1", epoch.Add(5*time.Hour), func(tk *task.Task) { tk.Assignee = "alice" })
+	editTodo(t, local, "1", epoch.Add(6*time.Hour), func(tk *task.Task) { tk.Tags = nil })
+	report, err = s.Sync()
+	require.NoError(t, err)
+	assert.Equal(t, Report{{ID: "1", Action: Merged, Conflict: true}}, report)
+	merged = loadTodo(t, remote, "1")
+	assert.Equal(t, "pay rent now", merged.Title)
+	assert.Empty(t, merged.Description)
+	assert.Equal(t, "alice", merged.Assignee)
+	assert.Empty(t, merged.Tags)
+
+	// the same changes on both sides are no conflict to merge
+	editTodo(t, local, "1", epoch.Add(7*time.Hour), func(tk *task.Task) { tk.Title = "rent" })
+	editTodo(t, remote, "1", epoch.Add(8*time.Hour), func(tk *task.Task) { tk.Title = "rent" })
+	report, err = s.Sync()
+	require.NoError(t, err)
+	assert.Equal(t, Report{{ID: "1", Action: Pulled, Conflict: true}}, report)
+
+	// the deletions can't be merged
+	require.NoError(t, local.Delete("1"))
+	editTodo(t, remote, "1", epoch.Add(9*time.Hour), func(tk *task.Task) { tk.Title = "pay rent" })
+	report, err = s.Sync()
+	require.NoError(t, err)
+	assert.Equal(t, Report{{ID: "1", Action: Pulled, Conflict: true}}, report)
+	assert.Equal(t, "pay rent", title(t, local, "1"))
+}
+
+func TestTagSet(t *testing.T) {
+	base := clock{Tags: map[string][]string{"home": {"a"}, "bills": {"b"}}}
+	local, err := newVersion(base, task.Task{Tags: []string{"home", "urgent"}})
+	require.NoError(t, err)
+	remote, err := newVersion(base, task.Task{Tags: []string{"bills", "urgent"}})
+	require.NoError(t, err)
+	merged := local.tags.merge(remote.tags)
+	assert.Empty(t, merged.live("home"))
+	assert.Empty(t, merged.live("bills"))
+	// both sides added it
+	assert.Len(t, merged.live("urgent"), 2)
+
+	// the adds not observed by the removal win
+	removing, err := newVersion(clock{Tags: map[string][]string{"urgent": merged.live("urgent")}}, task.Task{})
+	require.NoError(t, err)
+	readding, err := newVersion(clock{}, task.Task{Tags: []string{"urgent"}})
+	require.NoError(t, err)
+	assert.Len(t, removing.tags.merge(readding.tags).live("urgent"), 1)
+	assert.Empty(t, removing.tags.merge(merged).live("urgent"))
+}
+
+func TestMergeComments(t *testing.T) {
+	edited := epoch.Add(time.Hour)
+	local := []task.Comment{
+		{ID: 1, Author: "bob", Body: "by friday", Created: epoch},
+		{ID: 2, Author: "bob", Body: "paid half", Created: epoch.Add(time.Minute)},
+	}
+	remote := []task.Comment{
+		{ID: 1, Author: "bob", Body: "by monday", Created: epoch, Edited: &edited},
+		{ID: 2, Author: "alice", Body: "don't forget", Created: epoch.Add(2 * time.Minute)},
+		{ID: 5, Author: "alice", Body: "done?", Created: epoch.Add(3 * time.Minute)},
+	}
+	assert.Equal(t, []task.Comment{
+		{ID: 1, Author: "bob", Body: "by monday", Created: epoch, Edited: &edited},
+		{ID: 2, Author: "bob", Body: "paid half", Created: epoch.Add(time.Minute)},
+		{ID: 3, Author: "alice", Body: "don't forget", Created: epoch.Add(2 * time.Minute)},
+		{ID: 5, Author: "alice", Body: "done?", Created: epoch.Add(3 * time.Minute)},
+	}, mergeComments(local, remote))
+	assert.Equal(t, local, mergeComments(local, local))
+}
+
+// failingStorage fails saving the items while fail is set
+type failingStorage struct {
+	*store.Memory
+	fail *bool
+}
+
+func (fs failingStorage) SaveIf(id store.ID, blob store.Blob, expected store.Revision) (store.Revision, error) {
+	if *fs.fail {
+		return 0, errors.New("disk full")
+	}
+	return fs.Memory.SaveIf(id, blob, expected)
+}
+
+func TestSyncMergeRetried(t *testing.T) {
+	mem, remote := newStores(t)
+	fail := false
+	local := failingStorage{Memory: mem, fail: &fail}
+	require.NoError(t, local.Create("1", todoBlob(t, "pay rent", epoch)))
+	s := New(local, remote, "server")
+	_, err := s.Sync()
+	require.NoError(t, err)
+
+	editTodo(t, local, "1", epoch.Add(time.Hour), func(tk *task.Task) { tk.Title = "pay the rent" })
+	editTodo(t, remote, "1", epoch.Add(2*time.Hour), func(tk *task.Task) { tk.Description = "500 EUR" })
+	// the merge is written to the remote store only
+	fail = true
+	report, err := s.Sync()
+	require.NoError(t, err)
+	require.Len(t, report, 1)
+	assert.Equal(t, Skipped, report[0].Action)
+	assert.Error(t, report[0].Err)
+	merged := loadTodo(t, remote, "1")
+	assert.Equal(t, "pay the rent", merged.Title)
+	assert.Equal(t, "500 EUR", merged.Description)
+
+	// and pulled by the next sync
+	fail = false
+	report, err = s.Sync()
+	require.NoError(t, err)
+	assert.Equal(t, Report{{ID: "1", Action: Pulled}}, report)
+	assert.Equal(t, merged, loadTodo(t, local, "1"))
+	report, err = s.Sync()
+	require.NoError(t, err)
+	assert.Empty(t, report)
+}
@@ -0,0 +1,504 @@
+package storesync
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gotestbootcamp/go-todo-app/store"
+	"github.com/gotestbootcamp/go-todo-app/task"
+)
+
+// Strategy tells how the conflicts are resolved
+type Strategy string
+
+const (
+	// Merge merges the todos changed on both sides field by field, keeping the value of each
+	// written last, the tags added on a side unless removed on the other, and the comments of
+	// both sides. The other conflicts, like the todos deleted on a side, are resolved like with
+	// LatestWins.
+	Merge Strategy = "merge"
+	// LatestWins keeps the version of the item updated last, the remote one if updated at the same
+	// time, and the changed one if the other is deleted, not to lose the change
+	LatestWins Strategy = "latest-wins"
+	// Manual asks Syncer.Resolve which version to keep
+	Manual Strategy = "manual"
+)
+
+// Strategies are the strategies supported
+var Strategies = []Strategy{Merge, LatestWins, Manual}
+
+// Resolution is the version of a item in conflict to keep
+type Resolution int
+
+const (
+	// Skip keeps both versions, leaving the conflict to the next sync
+	Skip Resolution = iota
+	// KeepLocal copies the local version to the remote store
+	KeepLocal
+	// KeepRemote copies the remote version to the local store
+	KeepRemote
+)
+
+// Conflict is a item changed on both sides since the last sync, or changed on one and deleted on
+// the other
+type Conflict struct {
+	ID store.ID
+	// Local and Remote are the versions of the item on each side. Nil if deleted.
+	Local, Remote store.Blob
+}
+
+// Action is what the sync did to a item
+type Action string
+
+const (
+	// Pulled items were copied from the remote store, or deleted from the local one
+	Pulled Action = "pulled"
+	// Pushed items were copied to the remote store, or deleted from it
+	Pushed Action = "pushed"
+	// Merged items were replaced on both sides by the merge of their versions
+	Merged Action = "merged"
+	// Skipped items were left as they are, because of a conflict left unresolved or a error
+	Skipped Action = "skipped"
+	// Renamed items were distinct todos created with the same ID on each side: the local one was
+	// moved to a new ID, and each copied to the other side
+	Renamed Action = "renamed"
+)
+
+// Result is the outcome of the sync of a item
+type Result struct {
+	ID     store.ID
+	Action Action
+	// Deleted tells whether the item was deleted, rather than copied
+	Deleted bool
+	// Conflict tells whether the item was in conflict
+	Conflict bool
+	// NewID is the ID the local item was moved to, if Renamed
+	NewID store.ID
+	// Err tells why the item was skipped, e.g. because it changed during the sync. Nil if it
+	// wasn't, or if it was in conflict.
+	Err error
+}
+
+// Report reports the outcome of the sync of the items which differ between the stores, sorted by ID
+type Report []Result
+
+// Conflicts returns the IDs of the items in conflict left unresolved
+func (rep Report) Conflicts() []store.ID {
+	var ids []store.ID
+	for _, res := range rep {
+		if res.Conflict && res.Action == Skipped && res.Err == nil {
+			ids = append(ids, res.ID)
+		}
+	}
+	return ids
+}
+
+// state is what the local store keeps of the last sync with a remote one
+type state struct {
+	// Synced is when the last sync ended
+	Synced time.Time `json:"synced"`
+	// Items are the checksums of the items both stores held after the last sync
+	Items map[store.ID]string `json:"items"`
+	// Clocks are the clocks of the todos both stores held after the last sync, to merge them
+	Clocks map[store.ID]clock `json:"clocks,omitempty"`
+}
+
+// Syncer reconciles a local store with a remote one
+type Syncer struct {
+	local, remote store.Storage
+	// stateID is the ID of the item of the local store holding the state of the last sync
+	stateID store.ID
+	// Strategy resolves the conflicts, Merge by default
+	Strategy Strategy
+	// Resolve tells which version of the item in conflict to keep, with the Manual strategy.
+	// Nil skips the conflicts.
+	Resolve func(Conflict) (Resolution, error)
+	// DryRun reports what the sync would do, without changing the stores
+	DryRun bool
+	// IDs generates the IDs the todos created with the same ID on each side are moved to, the
+	// sequential IDs of the local store by default
+	IDs store.IDGenerator
+	// Now returns the current time. Can be replaced to control time in tests.
+	Now func() time.Time
+}
+
+// New creates the syncer of the local store with the remote one, whose state is kept in the local
+// store under the given name, which must be different for each remote
+func New(local, remote store.Storage, name string) *Syncer {
+	return &Syncer{
+		local:    local,
+		remote:   remote,
+		stateID:  store.MetaID("sync/" + name),
+		Strategy: Merge,
+		IDs:      store.NewSequentialIDs(local),
+		Now:      time.Now,
+	}
+}
+
+// LastSync returns when the last sync with the remote store ended. Zero if never.
+func (s *Syncer) LastSync() (time.Time, error) {
+	st, _, err := s.loadState()
+	return st.Synced, err
+}
+
+// Sync copies the items changed on a side since the last sync to the other one, deleting the
+// ones deleted, and resolves the conflicts with the strategy. The items which can't be synced
+// are skipped, and reported, to be synced the next time.
+// Fails if the stores can't be read, or the strategy is unknown.
+func (s *Syncer) Sync() (Report, error) {
+	if !slices.Contains(Strategies, s.Strategy) {
+		return nil, fmt.Errorf("unknown strategy %q", s.Strategy)
+	}
+	st, stored, err := s.loadState()
+	if err != nil {
+		return nil, err
+	}
+	local, err := loadItems(s.local)
+	if err != nil {
+		return nil, fmt.Errorf("can't read the local store: %w", err)
+	}
+	remote, err := loadItems(s.remote)
+	if err != nil {
+		return nil, fmt.Errorf("can't read the remote store: %w", err)
+	}
+	ids := make([]store.ID, 0, len(local)+len(remote))
+	for _, items := range []map[store.ID]store.Blob{st.blobs(), local, remote} {
+		for id := range items {
+			ids = append(ids, id)
+		}
+	}
+	slices.Sort(ids)
+	ids = slices.Compact(ids)
+	taken := make(map[store.ID]bool, len(ids))
+	for _, id := range ids {
+		taken[id] = true
+	}
+
+	var report Report
+	for _, id := range ids {
+		if res, changed := s.syncItem(&st, id, local[id], remote[id], taken); changed {
+			report = append(report, res)
+		}
+	}
+	if s.DryRun {
+		return report, nil
+	}
+	st.Synced = s.Now()
+	if err := s.saveState(st, stored); err != nil {
+		return report, err
+	}
+	slog.Info("storesync: Sync: synced", "items", len(st.Items), "changed", len(report), "conflicts", len(report.Conflicts()))
+	return report, nil
+}
+
+// syncItem syncs the item with its local and remote versions, nil if missing, and records in the
+// state the version both stores hold afterwards, unless skipped. Returns false if the item was
+// already in sync. The IDs taken are the ones of the items of both stores.
+func (s *Syncer) syncItem(st *state, id store.ID, local, remote store.Blob, taken map[store.ID]bool) (Result, bool) {
+	localSum, remoteSum := checksum(local), checksum(remote)
+	if localSum == remoteSum {
+		st.record(id, local, nil)
+		return Result{ID: id}, false
+	}
+	if _, synced := st.Items[id]; !synced && distinct(local, remote) {
+		return s.rename(st, id, local, remote, taken), true
+	}
+	res := Result{ID: id, Action: Skipped}
+	// keep is the version both stores hold afterwards, and c its clock if merged
+	var keep store.Blob
+	var c *clock
+	switch base := st.Items[id]; {
+	case localSum == base:
+		res.Action = Pulled
+	case remoteSum == base:
+		res.Action = Pushed
+	default:
+		res.Conflict = true
+		if merged, mc, ok := s.merge(id, st.Clocks[id], local, remote); ok {
+			keep, c = merged, &mc
+			switch checksum(merged) {
+			case remoteSum:
+				res.Action = Pulled
+			case localSum:
+				res.Action = Pushed
+			default:
+				res.Action = Merged
+			}
+			break
+		}
+		var err error
+		if res.Action, err = s.resolve(Conflict{ID: id, Local: local, Remote: remote}); err != nil {
+			res.Action, res.Err = Skipped, err
+		}
+	}
+	var err error
+	switch res.Action {
+	case Pulled:
+		res.Deleted, keep = remote == nil, remote
+		err = s.apply(s.local, id, local, remote)
+	case Pushed:
+		res.Deleted, keep = local == nil, local
+		err = s.apply(s.remote, id, remote, local)
+	case Merged:
+		if err = s.apply(s.remote, id, remote, keep); err == nil {
+			if err = s.apply(s.local, id, local, keep); err != nil {
+				// the remote store holds the merge already: the local version merged is the base of
+				// the next sync, which pulls the merge, or merges it again if the todo changed meanwhile
+				st.Items[id] = localSum
+			}
+		}
+	}
+	if err != nil {
+		res.Action, res.Err = Skipped, err
+		slog.Warn("storesync: Sync: item skipped", "id", id, "error", err)
+	}
+	if res.Action != Skipped {
+		st.record(id, keep, c)
+	}
+	return res, true
+}
+
+// distinct tells whether the versions of the item, never synced, are distinct todos given the same ID
+// on each side, rather than copies of the same one: if they were created at different times
+func distinct(local, remote store.Blob) bool {
+	if local == nil || remote == nil {
+		return false
+	}
+	localTask, err := task.Unmarshal(local)
+	if err != nil {
+		return false
+	}
+	remoteTask, err := task.Unmarshal(remote)
+	if err != nil {
+		return false
+	}
+	return !localTask.Created.Equal(remoteTask.Created)
+}
+
+// rename moves the local todo created with the same ID as the remote one to a new ID, free on both
+// sides, pulling the remote one in its place, and pushes it under the new ID
+func (s *Syncer) rename(st *state, id store.ID, local, remote store.Blob, taken map[store.ID]bool) Result {
+	res := Result{ID: id, Action: Renamed}
+	newID, err := s.newID(id, taken)
+	if err != nil {
+		res.Action, res.Err = Skipped, err
+		slog.Warn("storesync: Sync: item skipped", "id", id, "error", err)
+		return res
+	}
+	res.NewID = newID
+	if s.DryRun {
+		return res
+	}
+	// the local todo is copied first, never to be lost
+	if err := s.local.Create(newID, local); err != nil {
+		res.Action, res.NewID, res.Err = Skipped, store.NullID, err
+		slog.Warn("storesync: Sync: item skipped", "id", id, "error", err)
+		return res
+	}
+	if err := s.apply(s.local, id, local, remote); err != nil {
+		if err := s.local.Delete(newID); err != nil {
+			slog.Warn("storesync: Sync: can't remove the copy", "id", newID, "error", err)
+		}
+		res.Action, res.NewID, res.Err = Skipped, store.NullID, err
+		slog.Warn("storesync: Sync: item skipped", "id", id, "error", err)
+		return res
+	}
+	st.record(id, remote, nil)
+	// else pushed by the next sync
+	if err := s.remote.Create(newID, local); err != nil {
+		slog.Warn("storesync: Sync: can't push the renamed item", "id", newID, "error", err)
+		return res
+	}
+	st.record(newID, local, nil)
+	return res
+}
+
+// newID returns a new ID for the item, in the same list, taken by none of the items of both stores
+func (s *Syncer) newID(id store.ID, taken map[store.ID]bool) (store.ID, error) {
+	prefix := string(id[:strings.LastIndex(string(id), "/")+1])
+	// the sequential IDs of the local store may be taken on the remote one
+	for range maxNewIDAttempts {
+		gen, err := s.IDs.NewID()
+		if err != nil {
+			return store.NullID, err
+		}
+		newID := store.ID(prefix + string(gen))
+		if !taken[newID] {
+			taken[newID] = true
+			return newID, nil
+		}
+	}
+	return store.NullID, fmt.Errorf("no free id to move %s to", id)
+}
+
+// maxNewIDAttempts bounds the IDs generated looking for one free on both sides
+const maxNewIDAttempts = 1000
+
+// resolve returns how the conflict is resolved by the strategy
+func (s *Syncer) resolve(c Conflict) (Action, error) {
+	if s.Strategy == Manual {
+		if s.Resolve == nil {
+			return Skipped, nil
+		}
+		resolution, err := s.Resolve(c)
+		switch {
+		case err != nil:
+			return Skipped, err
+		case resolution == KeepLocal:
+			return Pushed, nil
+		case resolution == KeepRemote:
+			return Pulled, nil
+		}
+		return Skipped, nil
+	}
+	switch {
+	case c.Remote == nil:
+		return Pushed, nil
+	case c.Local == nil:
+		return Pulled, nil
+	case updated(s.local, store.Item{ID: c.ID, Blob: c.Local}).After(updated(s.remote, store.Item{ID: c.ID, Blob: c.Remote})):
+		return Pushed, nil
+	}
+	return Pulled, nil
+}
+
+// apply replaces the version was of the item in the storage with the version to keep, nil
+// deleting it. Fails if the item changed meanwhile, i.e. if it isn't at the revision of was
+// anymore, or if it was created meanwhile.
+func (s *Syncer) apply(st store.Storage, id store.ID, was, keep store.Blob) error {
+	if s.DryRun {
+		return nil
+	}
+	if was == nil {
+		return st.Create(id, keep)
+	}
+	cur, rev, err := store.LoadRev(st, id)
+	if err != nil {
+		return err
+	}
+	if checksum(cur) != checksum(was) {
+		return fmt.Errorf("%s changed during the sync", id)
+	}
+	if keep == nil {
+		return st.Delete(id)
+	}
+	_, err = store.SaveIf(st, id, keep, rev)
+	if errors.Is(err, store.ErrUnsupported) {
+		err = st.Save(id, keep)
+	}
+	return err
+}
+
+// loadState returns the state of the last sync, and whether it's stored
+func (s *Syncer) loadState() (state, bool, error) {
+	st := state{Items: make(map[store.ID]string), Clocks: make(map[store.ID]clock)}
+	blob, err := s.local.Load(s.stateID)
+	var notFound store.ErrNotFound
+	if errors.As(err, &notFound) {
+		return st, false, nil
+	}
+	if err != nil {
+		return st, false, err
+	}
+	if err := json.Unmarshal(blob, &st); err != nil {
+		return st, true, fmt.Errorf("invalid state of the last sync: %w", err)
+	}
+	if st.Items == nil {
+		st.Items = make(map[store.ID]string)
+	}
+	if st.Clocks == nil {
+		st.Clocks = make(map[store.ID]clock)
+	}
+	return st, true, nil
+}
+
+// saveState stores the state of the sync, stored already or not
+func (s *Syncer) saveState(st state, stored bool) error {
+	blob, err := json.Marshal(st)
+	if err != nil {
+		return err
+	}
+	if stored {
+		return s.local.Save(s.stateID, blob)
+	}
+	return s.local.Create(s.stateID, blob)
+}
+
+// record records the version of the item both stores hold after the sync, nil if none, along
+// with its clock, nil to stamp the version against the clock of the last sync
+func (st state) record(id store.ID, blob store.Blob, c *clock) {
+	sum := checksum(blob)
+	if sum == "" {
+		delete(st.Items, id)
+		delete(st.Clocks, id)
+		return
+	}
+	base, stamped := st.Clocks[id]
+	if c == nil {
+		if stamped && st.Items[id] == sum {
+			return
+		}
+		c = clockOf(base, blob)
+	}
+	st.Items[id] = sum
+	if c == nil {
+		delete(st.Clocks, id)
+		return
+	}
+	st.Clocks[id] = *c
+}
+
+// blobs returns the IDs of the items of the state, without their blobs
+func (st state) blobs() map[store.ID]store.Blob {
+	blobs := make(map[store.ID]store.Blob, len(st.Items))
+	for id := range st.Items {
+		blobs[id] = nil
+	}
+	return blobs
+}
+
+// loadItems returns the items of the storage to sync, by ID: all but the metadata and the
+// quarantined ones
+func loadItems(st store.Storage) (map[store.ID]store.Blob, error) {
+	items, err := st.LoadAll()
+	if err != nil {
+		return nil, err
+	}
+	blobs := make(map[store.ID]store.Blob, len(items))
+	for _, item := range items {
+		if store.IsMeta(item.ID) || store.IsQuarantined(item.ID) {
+			continue
+		}
+		// nil stands for the items missing
+		if item.Blob == nil {
+			item.Blob = store.Blob{}
+		}
+		blobs[item.ID] = item.Blob
+	}
+	return blobs, nil
+}
+
+// checksum returns the checksum of the blob, empty if nil
+func checksum(blob store.Blob) string {
+	if blob == nil {
+		return ""
+	}
+	return store.Checksum(blob)
+}
+
+// updated returns when the version of the item was last updated: when its todo tells, or else
+// when the storage does. Zero if unknown.
+func updated(st store.Storage, item store.Item) time.Time {
+	if tk, err := task.Unmarshal(item.Blob); err == nil && !tk.Updated.IsZero() {
+		return tk.Updated
+	}
+	if info, err := store.Stat(st, item.ID); err == nil {
+		return info.Updated
+	}
+	return time.Time{}
+}
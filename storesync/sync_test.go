@@ -0,0 +1,242 @@
+package storesync
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gotestbootcamp/go-todo-app/store"
+	"github.com/gotestbootcamp/go-todo-app/task"
+)
+
+var epoch = time.Date(2026, time.October, 1, 9, 0, 0, 0, time.UTC)
+
+func todoBlob(t *testing.T, title string, updated time.Time) store.Blob {
+	tk := task.New(title)
+	tk.Created, tk.Updated = epoch, updated
+	blob, err := task.Marshal(tk)
+	require.NoError(t, err)
+	return blob
+}
+
+func newStores(t *testing.T) (*store.Memory, *store.Memory) {
+	local, err := store.NewMemory()
+	require.NoError(t, err)
+	remote, err := store.NewMemory()
+	require.NoError(t, err)
+	return local, remote
+}
+
+// title returns the title of the todo in the storage, empty if missing
+func title(t *testing.T, st store.Storage, id store.ID) string {
+	blob, err := st.Load(id)
+	var notFound store.ErrNotFound
+	if errors.As(err, &notFound) {
+		return ""
+	}
+	require.NoError(t, err)
+	tk, err := task.Unmarshal(blob)
+	require.NoError(t, err)
+	return tk.Title
+}
+
+func TestSync(t *testing.T) {
+	local, remote := newStores(t)
+	require.NoError(t, local.Create("1", todoBlob(t, "pay rent", epoch)))
+	require.NoError(t, local.Create("3", todoBlob(t, "call mom", epoch)))
+	require.NoError(t, remote.Create("2", todoBlob(t, "file taxes", epoch)))
+	require.NoError(t, remote.Create("3", todoBlob(t, "call mom", epoch)))
+	// the metadata stay on their side
+	require.NoError(t, remote.Create(store.MetaID("tags"), store.Blob("{}")))
+
+	s := New(local, remote, "server")
+	last, err := s.LastSync()
+	require.NoError(t, err)
+	assert.True(t, last.IsZero())
+	report, err := s.Sync()
+	require.NoError(t, err)
+	assert.Equal(t, Report{{ID: "1", Action: Pushed}, {ID: "2", Action: Pulled}}, report)
+	assert.Equal(t, "pay rent", title(t, remote, "1"))
+	assert.Equal(t, "file taxes", title(t, local, "2"))
+	_, err = local.Load(store.MetaID("tags"))
+	assert.Error(t, err)
+	last, err = s.LastSync()
+	require.NoError(t, err)
+	assert.False(t, last.IsZero())
+
+	report, err = s.Sync()
+	require.NoError(t, err)
+	assert.Empty(t, report)
+
+	// the changes and the deletions of each side go to the other
+	require.NoError(t, local.Save("1", todoBlob(t, "pay the rent", epoch.Add(time.Hour))))
+	require.NoError(t, remote.Delete("2"))
+	require.NoError(t, remote.Create("4", todoBlob(t, "buy milk", epoch)))
+	report, err = s.Sync()
+	require.NoError(t, err)
+	assert.Equal(t, Report{
+		{ID: "1", Action: Pushed},
+		{ID: "2", Action: Pulled, Deleted: true},
+		{ID: "4", Action: Pulled},
+	}, report)
+	assert.Equal(t, "pay the rent", title(t, remote, "1"))
+	assert.Equal(t, "", title(t, local, "2"))
+	assert.Equal(t, "buy milk", title(t, local, "4"))
+
+	// another remote has its own state
+	other, err := store.NewMemory()
+	require.NoError(t, err)
+	report, err = New(local, other, "backup").Sync()
+	require.NoError(t, err)
+	assert.Len(t, report, 3)
+	assert.Equal(t, "pay the rent", title(t, other, "1"))
+}
+
+func TestSyncConflicts(t *testing.T) {
+	local, remote := newStores(t)
+	for _, st := range []store.Storage{local, remote} {
+		require.NoError(t, st.Create("1", todoBlob(t, "pay rent", epoch)))
+		require.NoError(t, st.Create("2", todoBlob(t, "call mom", epoch)))
+		require.NoError(t, st.Create("3", todoBlob(t, "file taxes", epoch)))
+	}
+	s := New(local, remote, "server")
+	_, err := s.Sync()
+	require.NoError(t, err)
+
+	require.NoError(t, local.Save("1", todoBlob(t, "pay the rent", epoch.Add(2*time.Hour))))
+	require.NoError(t, remote.Save("1", todoBlob(t, "pay rent today", epoch.Add(time.Hour))))
+	require.NoError(t, local.Save("2", todoBlob(t, "call dad", epoch.Add(time.Hour))))
+	require.NoError(t, remote.Save("2", todoBlob(t, "call mom and dad", epoch.Add(2*time.Hour))))
+	require.NoError(t, local.Delete("3"))
+	require.NoError(t, remote.Save("3", todoBlob(t, "file the taxes", epoch.Add(time.Hour))))
+
+	// manual without a resolver leaves the conflicts as they are
+	s.Strategy = Manual
+	report, err := s.Sync()
+	require.NoError(t, err)
+	assert.Equal(t, []store.ID{"1", "2", "3"}, report.Conflicts())
+	assert.Equal(t, "pay the rent", title(t, local, "1"))
+	assert.Equal(t, "pay rent today", title(t, remote, "1"))
+
+	var conflicts []Conflict
+	s.Resolve = func(c Conflict) (Resolution, error) {
+		conflicts = append(conflicts, c)
+		if c.ID == "1" {
+			return KeepRemote, nil
+		}
+		return Skip, nil
+	}
+	report, err = s.Sync()
+	require.NoError(t, err)
+	require.Len(t, conflicts, 3)
+	assert.Nil(t, conflicts[2].Local)
+	assert.NotNil(t, conflicts[2].Remote)
+	assert.Equal(t, Result{ID: "1", Action: Pulled, Conflict: true}, report[0])
+	assert.Equal(t, []store.ID{"2", "3"}, report.Conflicts())
+	assert.Equal(t, "pay rent today", title(t, local, "1"))
+
+	// the latest change wins, and the changes over the deletions
+	s.Strategy = LatestWins
+	report, err = s.Sync()
+	require.NoError(t, err)
+	assert.Equal(t, Report{
+		{ID: "2", Action: Pulled, Conflict: true},
+		{ID: "3", Action: Pulled, Conflict: true},
+	}, report)
+	assert.Equal(t, "call mom and dad", title(t, local, "2"))
+	assert.Equal(t, "file the taxes", title(t, local, "3"))
+
+	s.Strategy = "first-wins"
+	_, err = s.Sync()
+	assert.ErrorContains(t, err, `unknown strategy "first-wins"`)
+}
+
+func TestSyncSameID(t *testing.T) {
+	local, remote := newStores(t)
+	require.NoError(t, local.Create("1", todoBlob(t, "pay rent", epoch)))
+	// created apart, at another time
+	other := task.New("walk the dog")
+	other.Created, other.Updated = epoch.Add(time.Hour), epoch.Add(time.Hour)
+	blob, err := task.Marshal(other)
+	require.NoError(t, err)
+	require.NoError(t, remote.Create("1", blob))
+	require.NoError(t, remote.Create("2", todoBlob(t, "file taxes", epoch)))
+
+	s := New(local, remote, "server")
+	report, err := s.Sync()
+	require.NoError(t, err)
+	assert.Equal(t, Report{{ID: "1", Action: Renamed, NewID: "3"}, {ID: "2", Action: Pulled}}, report)
+	for _, st := range []store.Storage{local, remote} {
+		assert.Equal(t, "walk the dog", title(t, st, "1"))
+		assert.Equal(t, "pay rent", title(t, st, "3"))
+	}
+	report, err = s.Sync()
+	require.NoError(t, err)
+	assert.Empty(t, report)
+
+	// the copies of the same todo are merged
+	require.NoError(t, local.Create("4", todoBlob(t, "call mom", epoch)))
+	require.NoError(t, remote.Create("4", todoBlob(t, "call mom", epoch.Add(time.Hour))))
+	report, err = s.Sync()
+	require.NoError(t, err)
+	assert.Equal(t, Report{{ID: "4", Action: Pulled, Conflict: true}}, report)
+}
+
+func TestSyncDryRun(t *testing.T) {
+	local, remote := newStores(t)
+	require.NoError(t, local.Create("1", todoBlob(t, "pay rent", epoch)))
+	s := New(local, remote, "server")
+	s.DryRun = true
+	report, err := s.Sync()
+	require.NoError(t, err)
+	assert.Equal(t, Report{{ID: "1", Action: Pushed}}, report)
+	assert.Equal(t, "", title(t, remote, "1"))
+	last, err := s.LastSync()
+	require.NoError(t, err)
+	assert.True(t, last.IsZero())
+}
+
+// changingStorage changes a item after loading all of them, like another process would
+type changingStorage struct {
+	*store.Memory
+	change func()
+}
+
+func (cs changingStorage) LoadAll() ([]store.Item, error) {
+	items, err := cs.Memory.LoadAll()
+	cs.change()
+	return items, err
+}
+
+func TestSyncChangedMeanwhile(t *testing.T) {
+	local, remote := newStores(t)
+	require.NoError(t, local.Create("1", todoBlob(t, "pay rent", epoch)))
+	require.NoError(t, remote.Create("1", todoBlob(t, "pay rent", epoch)))
+	s := New(local, remote, "server")
+	_, err := s.Sync()
+	require.NoError(t, err)
+
+	require.NoError(t, local.Save("1", todoBlob(t, "pay the rent", epoch.Add(time.Hour))))
+	changing := changingStorage{Memory: remote, change: func() {
+		require.NoError(t, remote.Save("1", todoBlob(t, "pay rent today", epoch.Add(2*time.Hour))))
+		require.NoError(t, remote.Create("2", todoBlob(t, "call mom", epoch)))
+	}}
+	report, err := New(local, changing, "server").Sync()
+	require.NoError(t, err)
+	require.Len(t, report, 1)
+	assert.Equal(t, Skipped, report[0].Action)
+	assert.ErrorContains(t, report[0].Err, "1 changed during the sync")
+	assert.Equal(t, "pay rent today", title(t, remote, "1"))
+
+	// the next sync sees the conflict
+	changing.change = func() {}
+	report, err = New(local, changing, "server").Sync()
+	require.NoError(t, err)
+	assert.Equal(t, Report{
+		{ID: "1", Action: Pulled, Conflict: true},
+		{ID: "2", Action: Pulled},
+	}, report)
+}
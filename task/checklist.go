@@ -0,0 +1,93 @@
+package task
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// ErrNoCheckItem is returned when a task has no checklist entry with the given ID
+type ErrNoCheckItem struct {
+	ID int
+}
+
+func (e ErrNoCheckItem) Error() string {
+	return fmt.Sprintf("no checklist entry %d", e.ID)
+}
+
+// CheckItem is an entry of the checklist of a task: a step too small to be a task of its own
+type CheckItem struct {
+	// ID identifies the entry within the task
+	ID   int    `json:"id"`
+	Text string `json:"text"`
+	Done bool   `json:"done,omitempty"`
+}
+
+// ValidateCheckItem checks the text of a checklist entry is not blank, and at most MaxCheckItemLength
+// characters long. Returns a ValidationError if it isn't.
+func ValidateCheckItem(text string) error {
+	if strings.TrimSpace(text) == "" {
+		return ValidationError{Field: "checklist", Reason: "blank entry"}
+	}
+	if utf8.RuneCountInString(text) > MaxCheckItemLength {
+		return ValidationError{Field: "checklist", Reason: fmt.Sprintf("entry longer than %d characters", MaxCheckItemLength)}
+	}
+	return nil
+}
+
+// AddCheckItem appends an entry to the checklist of the task, and returns it.
+// The entry gets an ID greater than the ones of the other entries.
+func (t *Task) AddCheckItem(text string) (CheckItem, error) {
+	if err := ValidateCheckItem(text); err != nil {
+		return CheckItem{}, err
+	}
+	if len(t.Checklist) >= MaxCheckItems {
+		return CheckItem{}, ValidationError{Field: "checklist", Reason: fmt.Sprintf("more than %d entries", MaxCheckItems)}
+	}
+	id := 1
+	if len(t.Checklist) > 0 {
+		id = t.Checklist[len(t.Checklist)-1].ID + 1
+	}
+	ci := CheckItem{ID: id, Text: text}
+	t.Checklist = append(t.Checklist, ci)
+	return ci, nil
+}
+
+// ToggleCheckItem flips the done flag of the checklist entry with the given ID, and returns the entry.
+// Fails with ErrNoCheckItem if there is no such entry.
+func (t *Task) ToggleCheckItem(id int) (CheckItem, error) {
+	for i := range t.Checklist {
+		if t.Checklist[i].ID == id {
+			t.Checklist[i].Done = !t.Checklist[i].Done
+			return t.Checklist[i], nil
+		}
+	}
+	return CheckItem{}, ErrNoCheckItem{ID: id}
+}
+
+// RemoveCheckItem removes the checklist entry with the given ID.
+// Fails with ErrNoCheckItem if there is no such entry.
+func (t *Task) RemoveCheckItem(id int) error {
+	for i := range t.Checklist {
+		if t.Checklist[i].ID == id {
+			t.Checklist = append(t.Checklist[:i:i], t.Checklist[i+1:]...)
+			return nil
+		}
+	}
+	return ErrNoCheckItem{ID: id}
+}
+
+// ChecklistSummary returns how many entries of the checklist are done out of all of them,
+// e.g. "3/7", or the empty string if the task has no checklist
+func (t Task) ChecklistSummary() string {
+	if len(t.Checklist) == 0 {
+		return ""
+	}
+	done := 0
+	for _, ci := range t.Checklist {
+		if ci.Done {
+			done++
+		}
+	}
+	return fmt.Sprintf("%d/%d", done, len(t.Checklist))
+}
@@ -0,0 +1,36 @@
+package task
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChecklist(t *testing.T) {
+	tk := New("foo")
+	assert.Empty(t, tk.ChecklistSummary())
+	_, err := tk.AddCheckItem("")
+	assert.Error(t, err)
+
+	for _, text := range []string{"eggs", "milk", "bread"} {
+		_, err := tk.AddCheckItem(text)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, "0/3", tk.ChecklistSummary())
+	ci, err := tk.ToggleCheckItem(2)
+	require.NoError(t, err)
+	assert.Equal(t, CheckItem{ID: 2, Text: "milk", Done: true}, ci)
+	_, err = tk.ToggleCheckItem(4)
+	assert.ErrorIs(t, err, ErrNoCheckItem{ID: 4})
+	assert.Equal(t, "1/3", tk.ChecklistSummary())
+	require.NoError(t, tk.RemoveCheckItem(1))
+	assert.ErrorIs(t, tk.RemoveCheckItem(1), ErrNoCheckItem{ID: 1})
+	assert.Equal(t, "1/2", tk.ChecklistSummary())
+
+	data, err := Marshal(tk)
+	require.NoError(t, err)
+	got, err := Unmarshal(data)
+	require.NoError(t, err)
+	assert.Equal(t, tk.Checklist, got.Checklist)
+}
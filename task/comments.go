@@ -0,0 +1,92 @@
+package task
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ErrNoComment is returned when a task has no comment with the given ID
+type ErrNoComment struct {
+	ID int
+}
+
+func (e ErrNoComment) Error() string {
+	return fmt.Sprintf("no comment %d", e.ID)
+}
+
+// Comment is a comment on a task
+type Comment struct {
+	// ID identifies the comment within the task
+	ID     int    `json:"id"`
+	Author string `json:"author,omitempty"`
+	// Body is the text of the comment, in Markdown
+	Body    string    `json:"body"`
+	Created time.Time `json:"created"`
+	// Edited is the last time the body was edited. Nil if it never was.
+	Edited *time.Time `json:"edited,omitempty"`
+}
+
+// ValidateComment checks the body of a comment is not blank, and at most MaxCommentLength characters long.
+// Returns a ValidationError if it isn't.
+func ValidateComment(body string) error {
+	if strings.TrimSpace(body) == "" {
+		return ValidationError{Field: "comments", Reason: "blank comment"}
+	}
+	if utf8.RuneCountInString(body) > MaxCommentLength {
+		return ValidationError{Field: "comments", Reason: fmt.Sprintf("comment longer than %d characters", MaxCommentLength)}
+	}
+	return nil
+}
+
+// AddComment appends a comment of the author to the task, and returns it.
+// The comment gets an ID greater than the ones of the other comments.
+func (t *Task) AddComment(author, body string, now time.Time) (Comment, error) {
+	if err := ValidateComment(body); err != nil {
+		return Comment{}, err
+	}
+	if len(t.Comments) >= MaxComments {
+		return Comment{}, ValidationError{Field: "comments", Reason: fmt.Sprintf("more than %d comments", MaxComments)}
+	}
+	id := 1
+	if len(t.Comments) > 0 {
+		id = t.Comments[len(t.Comments)-1].ID + 1
+	}
+	cm := Comment{
+		ID:      id,
+		Author:  author,
+		Body:    body,
+		Created: now,
+	}
+	t.Comments = append(t.Comments, cm)
+	return cm, nil
+}
+
+// EditComment replaces the body of the comment with the given ID, and returns the edited comment.
+// Fails with ErrNoComment if there is no such comment.
+func (t *Task) EditComment(id int, body string, now time.Time) (Comment, error) {
+	if err := ValidateComment(body); err != nil {
+		return Comment{}, err
+	}
+	for i := range t.Comments {
+		if t.Comments[i].ID == id {
+			t.Comments[i].Body = body
+			t.Comments[i].Edited = &now
+			return t.Comments[i], nil
+		}
+	}
+	return Comment{}, ErrNoComment{ID: id}
+}
+
+// DeleteComment removes the comment with the given ID.
+// Fails with ErrNoComment if there is no such comment.
+func (t *Task) DeleteComment(id int) error {
+	for i := range t.Comments {
+		if t.Comments[i].ID == id {
+			t.Comments = append(t.Comments[:i:i], t.Comments[i+1:]...)
+			return nil
+		}
+	}
+	return ErrNoComment{ID: id}
+}
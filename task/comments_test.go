@@ -0,0 +1,45 @@
+package task
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestComments(t *testing.T) {
+	now := time.Date(2024, 11, 11, 9, 0, 0, 0, time.UTC)
+	tk := New("foo")
+	_, err := tk.AddComment("fede", " ", now)
+	assert.Error(t, err)
+	_, err = tk.AddComment("fede", strings.Repeat("a", MaxCommentLength+1), now)
+	assert.Error(t, err)
+
+	first, err := tk.AddComment("fede", "*first*", now)
+	require.NoError(t, err)
+	assert.Equal(t, 1, first.ID)
+	second, err := tk.AddComment("", "second", now)
+	require.NoError(t, err)
+	assert.Equal(t, 2, second.ID)
+
+	edited, err := tk.EditComment(1, "**first**", now.Add(time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, "**first**", edited.Body)
+	assert.Equal(t, now.Add(time.Hour), *edited.Edited)
+	_, err = tk.EditComment(3, "third", now)
+	assert.ErrorIs(t, err, ErrNoComment{ID: 3})
+
+	third, err := tk.AddComment("", "third", now)
+	require.NoError(t, err)
+	assert.Equal(t, 3, third.ID)
+	require.NoError(t, tk.DeleteComment(2))
+	assert.ErrorIs(t, tk.DeleteComment(2), ErrNoComment{ID: 2})
+
+	data, err := Marshal(tk)
+	require.NoError(t, err)
+	got, err := Unmarshal(data)
+	require.NoError(t, err)
+	assert.Equal(t, []Comment{edited, third}, got.Comments)
+}
@@ -0,0 +1,5 @@
+// Package task defines Task, the canonical representation of the items stored by the
+// todo app, along with its strict JSON encoding and its validation rules.
+// Every encoded task records the version of its schema, so the blobs written by older
+// versions of the app can be migrated when decoded.
+package task
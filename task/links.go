@@ -0,0 +1,112 @@
+package task
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"unicode/utf8"
+)
+
+// LinkType tells how a task relates to the target of a link
+type LinkType string
+
+const (
+	// RelatesTo links related work, both ways
+	RelatesTo LinkType = "relates-to"
+	// Duplicates links a task to the one it repeats
+	Duplicates LinkType = "duplicates"
+	// CausedBy links a task to the one, or the page, of its cause
+	CausedBy LinkType = "caused-by"
+)
+
+// LinkTypes are the types of the links, in the order they're listed
+var LinkTypes = []LinkType{RelatesTo, Duplicates, CausedBy}
+
+// ParseLinkType returns the link type with the given name
+func ParseLinkType(s string) (LinkType, error) {
+	if !slices.Contains(LinkTypes, LinkType(s)) {
+		return "", fmt.Errorf("invalid link type %q, want relates-to, duplicates or caused-by", s)
+	}
+	return LinkType(s), nil
+}
+
+// Inverse returns how the target of a link of the type relates back to the task, e.g.
+// "duplicated-by" for "duplicates"
+func (lt LinkType) Inverse() string {
+	switch lt {
+	case Duplicates:
+		return "duplicated-by"
+	case CausedBy:
+		return "causes"
+	}
+	return string(lt)
+}
+
+// ErrNoLink is returned when a task has no such link
+type ErrNoLink struct {
+	Link Link
+}
+
+func (e ErrNoLink) Error() string {
+	return fmt.Sprintf("no %s link to %s", e.Link.Type, e.Link.Target())
+}
+
+// Link links a task to another one, by ID, or to an external page, by URL
+type Link struct {
+	Type LinkType `json:"type"`
+	// ID is the ID of the task linked. Empty for the links to URLs.
+	ID string `json:"id,omitempty"`
+	// URL is the absolute http or https URL of the page linked. Empty for the links to tasks.
+	URL string `json:"url,omitempty"`
+}
+
+// Target returns the ID or the URL the link points to
+func (l Link) Target() string {
+	if l.URL != "" {
+		return l.URL
+	}
+	return l.ID
+}
+
+// ValidateLink checks the link has a known type, and either an ID or an absolute http or https URL
+// at most MaxLinkURLLength characters long. Returns a ValidationError if it doesn't.
+func ValidateLink(l Link) error {
+	if _, err := ParseLinkType(string(l.Type)); err != nil {
+		return ValidationError{Field: "links", Reason: err.Error()}
+	}
+	if (l.ID == "") == (l.URL == "") {
+		return ValidationError{Field: "links", Reason: "link to either an ID or a URL"}
+	}
+	if l.URL != "" {
+		u, err := url.Parse(l.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || utf8.RuneCountInString(l.URL) > MaxLinkURLLength {
+			return ValidationError{Field: "links", Reason: fmt.Sprintf("invalid URL %q", l.URL)}
+		}
+	}
+	return nil
+}
+
+// AddLink adds the link to the task, unless it has it already. Returns false if it did.
+func (t *Task) AddLink(l Link) (bool, error) {
+	if err := ValidateLink(l); err != nil {
+		return false, err
+	}
+	if slices.Contains(t.Links, l) {
+		return false, nil
+	}
+	if len(t.Links) >= MaxLinks {
+		return false, ValidationError{Field: "links", Reason: fmt.Sprintf("more than %d links", MaxLinks)}
+	}
+	t.Links = append(t.Links, l)
+	return true, nil
+}
+
+// RemoveLink removes the link from the task. Fails with ErrNoLink if the task hasn't it.
+func (t *Task) RemoveLink(l Link) error {
+	i := slices.Index(t.Links, l)
+	if i < 0 {
+		return ErrNoLink{Link: l}
+	}
+	t.Links = slices.Delete(t.Links, i, i+1)
+	return nil
+}
@@ -0,0 +1,42 @@
+package task
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLinks(t *testing.T) {
+	tk := New("foo")
+	for _, l := range []Link{
+		{Type: "blocks", ID: "2"},
+		{Type: RelatesTo},
+		{Type: RelatesTo, ID: "2", URL: "https://example.com"},
+		{Type: CausedBy, URL: "example.com/issue/1"},
+		{Type: CausedBy, URL: "mailto:someone@example.com"},
+	} {
+		_, err := tk.AddLink(l)
+		assert.Error(t, err, l)
+	}
+
+	added, err := tk.AddLink(Link{Type: Duplicates, ID: "2"})
+	require.NoError(t, err)
+	assert.True(t, added)
+	added, err = tk.AddLink(Link{Type: Duplicates, ID: "2"})
+	require.NoError(t, err)
+	assert.False(t, added)
+	_, err = tk.AddLink(Link{Type: CausedBy, URL: "https://example.com/issue/1"})
+	require.NoError(t, err)
+	assert.Equal(t, "duplicated-by", Duplicates.Inverse())
+	assert.Equal(t, "relates-to", RelatesTo.Inverse())
+
+	assert.ErrorIs(t, tk.RemoveLink(Link{Type: RelatesTo, ID: "2"}), ErrNoLink{Link: Link{Type: RelatesTo, ID: "2"}})
+	require.NoError(t, tk.RemoveLink(Link{Type: Duplicates, ID: "2"}))
+
+	data, err := Marshal(tk)
+	require.NoError(t, err)
+	got, err := Unmarshal(data)
+	require.NoError(t, err)
+	assert.Equal(t, []Link{{Type: CausedBy, URL: "https://example.com/issue/1"}}, got.Links)
+}
@@ -0,0 +1,313 @@
+package task
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gotestbootcamp/go-todo-app/store"
+)
+
+// Migration converts a task encoded with a schema version to the next schema version
+type Migration func(data []byte) ([]byte, error)
+
+// migrations are the conversions between the schema versions, applied in order to
+// the tasks encoded with an older schema: the entry at index N converts version N to
+// version N+1, so SchemaVersion must always be the length of the slice.
+// Never change or remove an existing entry: append a new one instead.
+var migrations = []Migration{
+	migrateV0,
+	migrateV1,
+	migrateV2,
+	migrateV3,
+	migrateV4,
+	migrateV5,
+	migrateV6,
+	migrateV7,
+	migrateV8,
+	migrateV9,
+	migrateV10,
+	migrateV11,
+	migrateV12,
+	migrateV13,
+}
+
+// Version returns the schema version the task is encoded with
+func Version(data []byte) (int, error) {
+	var probe struct {
+		Schema *int `json:"schema"`
+	}
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return 0, err
+	}
+	if probe.Schema == nil {
+		return 0, nil
+	}
+	return *probe.Schema, nil
+}
+
+// upgrade migrates the encoded task to the current schema version
+func upgrade(data []byte) ([]byte, error) {
+	version, err := Version(data)
+	if err != nil {
+		return nil, err
+	}
+	if version > SchemaVersion {
+		return nil, fmt.Errorf("task schema version %d is newer than supported version %d", version, SchemaVersion)
+	}
+	for ; version < SchemaVersion; version++ {
+		data, err = migrations[version](data)
+		if err != nil {
+			return nil, fmt.Errorf("migration to task schema version %d failed: %w", version+1, err)
+		}
+	}
+	return data, nil
+}
+
+// MigrateReport describes the outcome of Migrate
+type MigrateReport struct {
+	// Checked is the number of items checked
+	Checked int
+	// Migrated are the IDs of the items rewritten with the current schema version
+	Migrated []store.ID
+	// Failed are the IDs of the items which can't be migrated, e.g. because they
+	// are not tasks, along with the reason
+	Failed map[store.ID]error
+}
+
+// Migrate rewrites with the current schema version all the items of the storage encoded
+// with an older one. Unmarshal migrates the old items anyway, so running Migrate is never
+// required, but saves migrating them on every load, and lets older versions of the app
+// reject the migrated items instead of misreading them.
+// The items which can't be migrated are reported, and left unchanged.
+func Migrate(st store.Storage) (MigrateReport, error) {
+	rep := MigrateReport{Failed: make(map[store.ID]error)}
+	var todo []store.Item
+	err := store.Walk(st, func(item store.Item) error {
+		if store.IsQuarantined(item.ID) || store.IsMeta(item.ID) {
+			return nil
+		}
+		rep.Checked++
+		version, err := Version(item.Blob)
+		if err != nil {
+			rep.Failed[item.ID] = err
+			return nil
+		}
+		if version == SchemaVersion {
+			return nil
+		}
+		tk, err := Unmarshal(item.Blob)
+		if err != nil {
+			rep.Failed[item.ID] = err
+			return nil
+		}
+		blob, err := Marshal(tk)
+		if err != nil {
+			rep.Failed[item.ID] = err
+			return nil
+		}
+		todo = append(todo, store.Item{ID: item.ID, Blob: blob})
+		return nil
+	})
+	if err != nil {
+		return rep, err
+	}
+	if err := store.SaveAll(st, todo); err != nil {
+		return rep, err
+	}
+	for _, item := range todo {
+		rep.Migrated = append(rep.Migrated, item.ID)
+	}
+	return rep, nil
+}
+
+// taskV0 is the schema of the blobs written before tasks were versioned
+type taskV0 struct {
+	Title          string
+	Assignee       string
+	Description    string
+	Status         Status
+	LastUpdateTime time.Time
+}
+
+func migrateV0(data []byte) ([]byte, error) {
+	var old taskV0
+	if err := decodeStrict(data, &old); err != nil {
+		return nil, err
+	}
+	tk := Task{
+		Schema:      1,
+		Title:       old.Title,
+		Description: old.Description,
+		Assignee:    old.Assignee,
+		Status:      old.Status,
+		// unknown, the best guess available
+		Created: old.LastUpdateTime,
+		Updated: old.LastUpdateTime,
+	}
+	// older versions didn't validate the titles
+	if strings.TrimSpace(tk.Title) == "" {
+		tk.Title = UntitledTitle
+	}
+	if title := []rune(tk.Title); len(title) > MaxTitleLength {
+		// keep the full title in the description, not to lose it
+		tk.Description = strings.TrimSuffix(tk.Title+"\n\n"+tk.Description, "\n\n")
+		tk.Title = string(title[:MaxTitleLength])
+	}
+	if !tk.Status.Valid() {
+		return nil, errors.New("unknown status " + string(tk.Status))
+	}
+	return json.Marshal(tk)
+}
+
+// migrateV1 adds the reminders: the tasks encoded with version 1 have none,
+// so only the version changes
+func migrateV1(data []byte) ([]byte, error) {
+	return setVersion(data, 2)
+}
+
+// migrateV2 adds the recurrences: the tasks encoded with version 2 don't recur,
+// so only the version changes
+func migrateV2(data []byte) ([]byte, error) {
+	return setVersion(data, 3)
+}
+
+// migrateV3 adds the dependencies: the tasks encoded with version 3 have none,
+// so only the version changes
+func migrateV3(data []byte) ([]byte, error) {
+	return setVersion(data, 4)
+}
+
+// migrateV4 adds the time entries: the tasks encoded with version 4 have none,
+// so only the version changes
+func migrateV4(data []byte) ([]byte, error) {
+	return setVersion(data, 5)
+}
+
+// migrateV5 adds the comments: the tasks encoded with version 5 have none,
+// so only the version changes
+func migrateV5(data []byte) ([]byte, error) {
+	return setVersion(data, 6)
+}
+
+// migrateV6 adds the checklists: the tasks encoded with version 6 have none,
+// so only the version changes
+func migrateV6(data []byte) ([]byte, error) {
+	return setVersion(data, 7)
+}
+
+// migrateV7 adds the owners: the tasks encoded with version 7 have none,
+// so only the version changes
+func migrateV7(data []byte) ([]byte, error) {
+	return setVersion(data, 8)
+}
+
+// migrateV8 adds the ranks: the tasks encoded with version 8 have none,
+// so only the version changes
+func migrateV8(data []byte) ([]byte, error) {
+	return setVersion(data, 9)
+}
+
+// migrateV9 adds the pomodoros: the time entries encoded with version 9 are none,
+// so only the version changes
+func migrateV9(data []byte) ([]byte, error) {
+	return setVersion(data, 10)
+}
+
+// migrateV10 adds the contexts: the tasks encoded with version 10 kept them in the tags starting
+// with @, which become their contexts, sanitized by sanitizeContext. The ones past MaxContexts stay
+// tags, without their @.
+func migrateV10(data []byte) ([]byte, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	if raw, ok := fields["tags"]; ok {
+		var tags, kept, contexts []string
+		if err := json.Unmarshal(raw, &tags); err != nil {
+			return nil, err
+		}
+		for _, tag := range tags {
+			ctx, isContext := strings.CutPrefix(tag, "@")
+			if isContext {
+				ctx = sanitizeContext(ctx)
+			}
+			switch {
+			case !isContext:
+				kept = append(kept, tag)
+			case ctx == "" || slices.Contains(contexts, ctx):
+			case len(contexts) < MaxContexts:
+				contexts = append(contexts, ctx)
+			case !slices.Contains(kept, ctx):
+				kept = append(kept, ctx)
+			}
+		}
+		if err := setField(fields, "tags", kept); err != nil {
+			return nil, err
+		}
+		if err := setField(fields, "contexts", contexts); err != nil {
+			return nil, err
+		}
+	}
+	fields["schema"] = json.RawMessage("11")
+	return json.Marshal(fields)
+}
+
+// sanitizeContext returns the context with the runes not valid in the contexts replaced by dashes,
+// cut at MaxContextLength characters
+func sanitizeContext(ctx string) string {
+	runes := []rune(strings.Map(func(r rune) rune {
+		if contextRune(r) {
+			return r
+		}
+		return '-'
+	}, ctx))
+	if len(runes) > MaxContextLength {
+		runes = runes[:MaxContextLength]
+	}
+	return string(runes)
+}
+
+// migrateV11 adds the custom fields: the tasks encoded with version 11 have none,
+// so only the version changes
+func migrateV11(data []byte) ([]byte, error) {
+	return setVersion(data, 12)
+}
+
+// migrateV12 adds the links: the tasks encoded with version 12 have none, so only the version changes
+func migrateV12(data []byte) ([]byte, error) {
+	return setVersion(data, 13)
+}
+
+// migrateV13 adds the UIDs: the tasks encoded with version 13 have none, so only the version changes
+func migrateV13(data []byte) ([]byte, error) {
+	return setVersion(data, 14)
+}
+
+// setField sets the field of the encoded task to the values, or removes it if there are none
+func setField(fields map[string]json.RawMessage, name string, values []string) error {
+	if len(values) == 0 {
+		delete(fields, name)
+		return nil
+	}
+	raw, err := json.Marshal(values)
+	if err != nil {
+		return err
+	}
+	fields[name] = raw
+	return nil
+}
+
+// setVersion sets the schema version of the encoded task, leaving the other fields as they are
+func setVersion(data []byte, version int) ([]byte, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	fields["schema"] = json.RawMessage(strconv.Itoa(version))
+	return json.Marshal(fields)
+}
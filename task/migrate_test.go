@@ -0,0 +1,94 @@
+package task
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gotestbootcamp/go-todo-app/store"
+)
+
+func TestMigrationsComplete(t *testing.T) {
+	assert.Len(t, migrations, SchemaVersion)
+}
+
+func TestVersion(t *testing.T) {
+	for data, expected := range map[string]int{
+		`{"Title":"foo"}`:            0,
+		`{"schema":1,"title":"foo"}`: 1,
+		`{"schema":7}`:               7,
+	} {
+		version, err := Version([]byte(data))
+		require.NoError(t, err, data)
+		assert.Equal(t, expected, version, data)
+	}
+	_, err := Version([]byte("foo"))
+	assert.Error(t, err)
+}
+
+func TestUnmarshalV1(t *testing.T) {
+	data := `{"schema":1,"title":"foo","status":"pending","due":"2024-11-11T00:00:00Z","created":"2024-11-10T10:00:00Z","updated":"2024-11-10T10:00:00Z"}`
+	tk, err := Unmarshal([]byte(data))
+	require.NoError(t, err)
+	assert.Equal(t, SchemaVersion, tk.Schema)
+	assert.Equal(t, "foo", tk.Title)
+	assert.NotNil(t, tk.Due)
+	assert.Nil(t, tk.Remind)
+}
+
+func TestUnmarshalV10(t *testing.T) {
+	data := `{"schema":10,"title":"foo","status":"pending","tags":["finance","@home","@two words","@home","@home.office","@"],"created":"2024-11-10T10:00:00Z","updated":"2024-11-10T10:00:00Z"}`
+	tk, err := Unmarshal([]byte(data))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"finance"}, tk.Tags)
+	assert.Equal(t, []string{"home", "two-words", "home.office"}, tk.Contexts)
+
+	data = `{"schema":10,"title":"foo","status":"pending","tags":["@a","@b","@c","@d","@e","@f","@g","@h","@i"]}`
+	tk, err = Unmarshal([]byte(data))
+	require.NoError(t, err)
+	assert.Len(t, tk.Contexts, MaxContexts)
+	assert.Equal(t, []string{"i"}, tk.Tags)
+
+	tk, err = Unmarshal([]byte(`{"schema":10,"title":"foo","status":"pending","tags":["@home"]}`))
+	require.NoError(t, err)
+	assert.Empty(t, tk.Tags)
+	assert.Equal(t, []string{"home"}, tk.Contexts)
+}
+
+func TestMigrate(t *testing.T) {
+	mem, err := store.NewMemory()
+	require.NoError(t, err)
+	current, err := Marshal(New("current"))
+	require.NoError(t, err)
+	require.NoError(t, mem.Create("1", store.Blob(`{"Title":"old","Status":"pending","LastUpdateTime":"2024-11-11T10:00:00Z"}`)))
+	require.NoError(t, mem.Create("2", store.Blob(current)))
+	require.NoError(t, mem.Create("3", store.Blob(`{"Title":"broken","Status":"done"}`)))
+	require.NoError(t, mem.Create("4", store.Blob(`not a task`)))
+
+	rep, err := Migrate(mem)
+	require.NoError(t, err)
+	assert.Equal(t, 4, rep.Checked)
+	assert.Equal(t, []store.ID{"1"}, rep.Migrated)
+	assert.Len(t, rep.Failed, 2)
+	assert.Contains(t, rep.Failed, store.ID("3"))
+	assert.Contains(t, rep.Failed, store.ID("4"))
+
+	blob, err := mem.Load("1")
+	require.NoError(t, err)
+	version, err := Version(blob)
+	require.NoError(t, err)
+	assert.Equal(t, SchemaVersion, version)
+	tk, err := Unmarshal(blob)
+	require.NoError(t, err)
+	assert.Equal(t, "old", tk.Title)
+
+	blob, err = mem.Load("3")
+	require.NoError(t, err)
+	assert.Equal(t, `{"Title":"broken","Status":"done"}`, string(blob))
+
+	// nothing left to do
+	rep, err = Migrate(mem)
+	require.NoError(t, err)
+	assert.Empty(t, rep.Migrated)
+}
@@ -0,0 +1,102 @@
+package task
+
+import (
+	"fmt"
+	"strings"
+)
+
+// rankDigits are the digits of the ranks, in ascending order, so that the ranks sort as strings
+const rankDigits = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// MaxRankLength is the maximum length of the ranks
+const MaxRankLength = 256
+
+// ValidateRank checks that the rank is made of base 62 digits, 0-9, A-Z and a-z, and doesn't
+// end with 0, for a rank to always fit before it
+func ValidateRank(rank string) error {
+	switch {
+	case len(rank) > MaxRankLength:
+		return ValidationError{Field: "rank", Reason: fmt.Sprintf("longer than %d digits", MaxRankLength)}
+	case strings.Trim(rank, rankDigits) != "":
+		return ValidationError{Field: "rank", Reason: fmt.Sprintf("invalid digits in %q", rank)}
+	case strings.HasSuffix(rank, "0"):
+		return ValidationError{Field: "rank", Reason: fmt.Sprintf("trailing zero in %q", rank)}
+	}
+	return nil
+}
+
+// RankBetween returns a rank sorting after the rank before and before the rank after, the
+// empty ones meaning no bound, e.g. RankBetween("", "") is the first rank of a list, and
+// RankBetween(last, "") the rank after the last. The ranks are fractional indexes, which
+// sort as strings: there's always another rank between two ranks, without changing them.
+func RankBetween(before, after string) (string, error) {
+	for _, rank := range []string{before, after} {
+		if err := ValidateRank(rank); err != nil {
+			return "", err
+		}
+	}
+	if after != "" && before >= after {
+		return "", fmt.Errorf("no rank between %q and %q", before, after)
+	}
+	return midpoint(before, after), nil
+}
+
+// RanksAfter returns n ascending ranks after the rank, the empty one meaning none, of the same
+// length, to rank many tasks at once: RankBetween repeated would lengthen the ranks by a digit
+// every few tasks.
+func RanksAfter(rank string, n int) ([]string, error) {
+	first, err := RankBetween(rank, "")
+	if err != nil {
+		return nil, err
+	}
+	width := 1
+	for size := len(rankDigits); size < n; size *= len(rankDigits) {
+		width++
+	}
+	ranks := make([]string, 0, n)
+	for i := range n {
+		digits := make([]byte, width)
+		for j, k := width-1, i; j >= 0; j, k = j-1, k/len(rankDigits) {
+			digits[j] = rankDigits[k%len(rankDigits)]
+		}
+		// not to end with 0
+		ranks = append(ranks, first+string(digits)+"V")
+	}
+	return ranks, nil
+}
+
+// midpoint returns the shortest rank between the ranks a and b, a < b, the empty b meaning no
+// upper bound: the digits of a missing are zeros
+func midpoint(a, b string) string {
+	if b != "" {
+		// the common prefix
+		n := 0
+		for n < len(b) && digitAt(a, n) == b[n] {
+			n++
+		}
+		if n > 0 {
+			return b[:n] + midpoint(a[min(n, len(a)):], b[n:])
+		}
+	}
+	da := strings.IndexByte(rankDigits, digitAt(a, 0))
+	db := len(rankDigits)
+	if b != "" {
+		db = strings.IndexByte(rankDigits, b[0])
+	}
+	if db-da > 1 {
+		return string(rankDigits[(da+db+1)/2])
+	}
+	// the first digits are consecutive
+	if len(b) > 1 {
+		return b[:1]
+	}
+	return string(rankDigits[da]) + midpoint(a[min(1, len(a)):], "")
+}
+
+// digitAt returns the digit of the rank at the position, 0 past its end
+func digitAt(rank string, i int) byte {
+	if i < len(rank) {
+		return rank[i]
+	}
+	return rankDigits[0]
+}
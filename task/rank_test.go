@@ -0,0 +1,77 @@
+package task
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRankBetween(t *testing.T) {
+	for _, tc := range []struct {
+		before, after, want string
+	}{
+		{"", "", "V"},
+		{"V", "", "l"},
+		{"", "V", "G"},
+		{"y", "z", "yV"},
+		{"z", "", "zV"},
+		{"", "1", "0V"},
+		{"a", "a1", "a0V"},
+		{"aV", "b", "al"},
+		{"a", "bz", "b"},
+	} {
+		got, err := RankBetween(tc.before, tc.after)
+		require.NoError(t, err, "%q %q", tc.before, tc.after)
+		assert.Equal(t, tc.want, got, "%q %q", tc.before, tc.after)
+	}
+
+	for _, tc := range [][2]string{{"b", "a"}, {"a", "a"}, {"a0", ""}, {"", "a-"}} {
+		_, err := RankBetween(tc[0], tc[1])
+		assert.Error(t, err, "%q %q", tc[0], tc[1])
+	}
+}
+
+func TestRankBetweenRandom(t *testing.T) {
+	// inserting anywhere keeps the ranks sorted, without changing them
+	rnd := rand.New(rand.NewSource(1))
+	var ranks []string
+	for range 1000 {
+		i := rnd.Intn(len(ranks) + 1)
+		before, after := "", ""
+		if i > 0 {
+			before = ranks[i-1]
+		}
+		if i < len(ranks) {
+			after = ranks[i]
+		}
+		rank, err := RankBetween(before, after)
+		require.NoError(t, err)
+		require.NoError(t, ValidateRank(rank))
+		require.Greater(t, rank, before)
+		if after != "" {
+			require.Less(t, rank, after)
+		}
+		ranks = append(ranks[:i], append([]string{rank}, ranks[i:]...)...)
+	}
+	for _, rank := range ranks {
+		assert.LessOrEqual(t, len(rank), 8)
+	}
+}
+
+func TestRanksAfter(t *testing.T) {
+	ranks, err := RanksAfter("", 3)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"V0V", "V1V", "V2V"}, ranks)
+
+	ranks, err = RanksAfter("z", 100)
+	require.NoError(t, err)
+	require.Len(t, ranks, 100)
+	assert.Equal(t, "zV00V", ranks[0])
+	assert.Equal(t, "zV1bV", ranks[99])
+	assert.IsIncreasing(t, ranks)
+	for _, rank := range ranks {
+		require.NoError(t, ValidateRank(rank))
+	}
+}
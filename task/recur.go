@@ -0,0 +1,396 @@
+package task
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Recurrence computes the occurrences of a recurring task
+type Recurrence interface {
+	// Next returns the first occurrence after t, which is usually a previous occurrence.
+	// Returns false if the recurrence ends before.
+	Next(t time.Time) (time.Time, bool)
+}
+
+// ParseRecurrence parses a recurrence rule, in one of the forms:
+//   - "daily", "weekly", "monthly" or "yearly": every day, week, month or year.
+//   - a subset of the RFC 5545 RRULE, optionally prefixed by "RRULE:", e.g. "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,FR".
+//     The supported parts are FREQ (DAILY, WEEKLY, MONTHLY or YEARLY), INTERVAL, BYDAY (without ordinals),
+//     BYMONTHDAY and UNTIL.
+//   - a cron expression with the five fields minute, hour, day of month, month and day of week, e.g. "0 9 * * 1-5".
+//     Each field is "*", a value, a range "a-b", a step "*/n" or "a-b/n", or a comma-separated list of them.
+func ParseRecurrence(rule string) (Recurrence, error) {
+	switch rule {
+	case "daily":
+		return rrule{freq: daily, interval: 1}, nil
+	case "weekly":
+		return rrule{freq: weekly, interval: 1}, nil
+	case "monthly":
+		return rrule{freq: monthly, interval: 1}, nil
+	case "yearly":
+		return rrule{freq: yearly, interval: 1}, nil
+	}
+	if len(strings.Fields(rule)) == 5 {
+		return parseCron(rule)
+	}
+	return parseRRule(rule)
+}
+
+// maxPeriods bounds the search of the next occurrence, for the rules which never match
+const maxPeriods = 1000
+
+// maxSkipped bounds the occurrences NextOccurrence skips
+const maxSkipped = 100000
+
+type frequency int
+
+const (
+	daily frequency = iota
+	weekly
+	monthly
+	yearly
+)
+
+var frequencies = map[string]frequency{
+	"DAILY":   daily,
+	"WEEKLY":  weekly,
+	"MONTHLY": monthly,
+	"YEARLY":  yearly,
+}
+
+var weekdays = map[string]time.Weekday{
+	"SU": time.Sunday,
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+}
+
+// rrule is the supported subset of the RFC 5545 recurrence rules.
+// The occurrences keep the time of the day of the previous one.
+type rrule struct {
+	freq       frequency
+	interval   int
+	byDay      [7]bool
+	hasByDay   bool
+	byMonthDay []int
+	until      *time.Time
+}
+
+func parseRRule(rule string) (Recurrence, error) {
+	rr := rrule{interval: 1}
+	hasFreq := false
+	for _, part := range strings.Split(strings.TrimPrefix(rule, "RRULE:"), ";") {
+		key, val, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid recurrence rule %q", rule)
+		}
+		switch key {
+		case "FREQ":
+			rr.freq, hasFreq = frequencies[val]
+			if !hasFreq {
+				return nil, fmt.Errorf("unsupported recurrence frequency %q", val)
+			}
+		case "INTERVAL":
+			n, err := strconv.Atoi(val)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("invalid recurrence interval %q", val)
+			}
+			rr.interval = n
+		case "BYDAY":
+			for _, name := range strings.Split(val, ",") {
+				day, ok := weekdays[name]
+				if !ok {
+					return nil, fmt.Errorf("unsupported recurrence day %q", name)
+				}
+				rr.byDay[day] = true
+			}
+			rr.hasByDay = true
+		case "BYMONTHDAY":
+			for _, s := range strings.Split(val, ",") {
+				n, err := strconv.Atoi(s)
+				if err != nil || n == 0 || n < -31 || n > 31 {
+					return nil, fmt.Errorf("invalid recurrence month day %q", s)
+				}
+				rr.byMonthDay = append(rr.byMonthDay, n)
+			}
+		case "UNTIL":
+			until, err := time.Parse("20060102T150405Z", val)
+			if err != nil {
+				// a date includes all of its day
+				until, err = time.Parse("20060102", val)
+				until = until.Add(24*time.Hour - time.Nanosecond)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("invalid recurrence end %q", val)
+			}
+			rr.until = &until
+		default:
+			return nil, fmt.Errorf("unsupported recurrence rule part %q", key)
+		}
+	}
+	switch {
+	case !hasFreq:
+		return nil, fmt.Errorf("recurrence rule %q without frequency", rule)
+	case rr.hasByDay && rr.freq == yearly:
+		return nil, fmt.Errorf("recurrence rule %q: BYDAY unsupported with yearly frequency", rule)
+	case len(rr.byMonthDay) > 0 && rr.freq != monthly:
+		return nil, fmt.Errorf("recurrence rule %q: BYMONTHDAY only supported with monthly frequency", rule)
+	}
+	return rr, nil
+}
+
+func (rr rrule) Next(t time.Time) (time.Time, bool) {
+	next, ok := rr.next(t)
+	if !ok || rr.until != nil && next.After(*rr.until) {
+		return time.Time{}, false
+	}
+	return next, true
+}
+
+func (rr rrule) next(t time.Time) (time.Time, bool) {
+	switch rr.freq {
+	case daily:
+		for i := 1; i <= maxPeriods; i++ {
+			next := t.AddDate(0, 0, i*rr.interval)
+			if !rr.hasByDay || rr.byDay[next.Weekday()] {
+				return next, true
+			}
+		}
+	case weekly:
+		if !rr.hasByDay {
+			return t.AddDate(0, 0, 7*rr.interval), true
+		}
+		// the weeks start on monday
+		monday := t.AddDate(0, 0, -(int(t.Weekday())+6)%7)
+		for i := 0; i < maxPeriods; i++ {
+			start := monday.AddDate(0, 0, 7*i*rr.interval)
+			for d := 0; d < 7; d++ {
+				next := start.AddDate(0, 0, d)
+				if next.After(t) && rr.byDay[next.Weekday()] {
+					return next, true
+				}
+			}
+		}
+	case monthly:
+		for i := 0; i < maxPeriods; i++ {
+			first := time.Date(t.Year(), t.Month()+time.Month(i*rr.interval), 1, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+			for _, day := range rr.monthDays(t, first) {
+				next := first.AddDate(0, 0, day-1)
+				if next.After(t) {
+					return next, true
+				}
+			}
+		}
+	case yearly:
+		for i := 1; i <= maxPeriods; i++ {
+			next := time.Date(t.Year()+i*rr.interval, t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+			// skip the years without the day, e.g. february 29th
+			if next.Day() == t.Day() {
+				return next, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+// monthDays returns the sorted days of the month starting at first which match the rule,
+// for a recurrence whose previous occurrence is t
+func (rr rrule) monthDays(t, first time.Time) []int {
+	length := first.AddDate(0, 1, -1).Day()
+	var days []int
+	switch {
+	case len(rr.byMonthDay) > 0:
+		for _, day := range rr.byMonthDay {
+			if day < 0 {
+				day += length + 1
+			}
+			if day >= 1 && day <= length {
+				days = append(days, day)
+			}
+		}
+	case rr.hasByDay:
+		for day := 1; day <= length; day++ {
+			days = append(days, day)
+		}
+	case t.Day() <= length:
+		// the months without the day are skipped, e.g. february for the 30th
+		days = append(days, t.Day())
+	}
+	sort.Ints(days)
+	res := days[:0]
+	for _, day := range days {
+		if !rr.hasByDay || rr.byDay[first.AddDate(0, 0, day-1).Weekday()] {
+			res = append(res, day)
+		}
+	}
+	return res
+}
+
+// cron is a recurrence defined by a cron expression.
+// The occurrences happen at the start of the matching minutes.
+type cron struct {
+	minute [60]bool
+	hour   [24]bool
+	dom    [32]bool
+	month  [13]bool
+	dow    [7]bool
+	// domAny and dowAny record whether the day of month and the day of week are unrestricted
+	domAny bool
+	dowAny bool
+}
+
+func parseCron(rule string) (Recurrence, error) {
+	var cr cron
+	fields := strings.Fields(rule)
+	var dow [8]bool
+	for i, spec := range []struct {
+		set      []bool
+		min, max int
+	}{
+		{cr.minute[:], 0, 59},
+		{cr.hour[:], 0, 23},
+		{cr.dom[:], 1, 31},
+		{cr.month[:], 1, 12},
+		// both 0 and 7 are sunday
+		{dow[:], 0, 7},
+	} {
+		if err := parseCronField(fields[i], spec.min, spec.max, spec.set); err != nil {
+			return nil, fmt.Errorf("invalid cron expression %q: %w", rule, err)
+		}
+	}
+	copy(cr.dow[:], dow[:7])
+	cr.dow[time.Sunday] = cr.dow[time.Sunday] || dow[7]
+	cr.domAny = strings.HasPrefix(fields[2], "*")
+	cr.dowAny = strings.HasPrefix(fields[4], "*")
+	return cr, nil
+}
+
+// parseCronField marks in set the values of the field, which must be between min and max
+func parseCronField(field string, min, max int, set []bool) error {
+	for _, part := range strings.Split(field, ",") {
+		rng, stepSpec, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			var err error
+			step, err = strconv.Atoi(stepSpec)
+			if err != nil || step < 1 {
+				return fmt.Errorf("invalid step %q", stepSpec)
+			}
+		}
+		lo, hi := min, max
+		if rng != "*" {
+			loSpec, hiSpec, isRange := strings.Cut(rng, "-")
+			var err error
+			if lo, err = strconv.Atoi(loSpec); err != nil {
+				return fmt.Errorf("invalid value %q", loSpec)
+			}
+			hi = lo
+			if isRange {
+				if hi, err = strconv.Atoi(hiSpec); err != nil {
+					return fmt.Errorf("invalid value %q", hiSpec)
+				}
+			} else if hasStep {
+				hi = max
+			}
+		}
+		if lo < min || hi > max || lo > hi {
+			return fmt.Errorf("%q out of range %d-%d", part, min, max)
+		}
+		for v := lo; v <= hi; v += step {
+			set[v] = true
+		}
+	}
+	return nil
+}
+
+// dayMatches follows the cron convention: if both the day of month and the day of week
+// are restricted, a day matching either of them matches
+func (cr cron) dayMatches(t time.Time) bool {
+	dom, dow := cr.dom[t.Day()], cr.dow[t.Weekday()]
+	switch {
+	case cr.domAny:
+		return dow
+	case cr.dowAny:
+		return dom
+	default:
+		return dom || dow
+	}
+}
+
+func (cr cron) Next(t time.Time) (time.Time, bool) {
+	next := t.Truncate(time.Minute).Add(time.Minute)
+	// bound the search for the expressions which never match, e.g. "0 0 30 2 *"
+	limit := next.AddDate(5, 0, 0)
+	for next.Before(limit) {
+		y, m, d := next.Date()
+		switch {
+		case !cr.month[m]:
+			next = time.Date(y, m+1, 1, 0, 0, 0, 0, next.Location())
+		case !cr.dayMatches(next):
+			next = time.Date(y, m, d+1, 0, 0, 0, 0, next.Location())
+		case !cr.hour[next.Hour()]:
+			next = time.Date(y, m, d, next.Hour()+1, 0, 0, 0, next.Location())
+		case !cr.minute[next.Minute()]:
+			next = next.Add(time.Minute)
+		default:
+			return next, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// NextOccurrence returns the next occurrence of the recurring task, completed at the given time:
+// a copy of the task due at the first occurrence of its recurrence after both its due date and now,
+// so the occurrences missed are skipped. The tasks without due date recur from now.
+// The reminder, if any, keeps the same distance from the due date; the blockers are not copied.
+// The caller is in charge of setting the status and the series of the next occurrence.
+// Returns false if the task doesn't recur, or its recurrence ended.
+func (t Task) NextOccurrence(now time.Time) (Task, bool, error) {
+	if t.Recur == "" {
+		return Task{}, false, nil
+	}
+	rec, err := ParseRecurrence(t.Recur)
+	if err != nil {
+		return Task{}, false, err
+	}
+	due := now
+	if t.Due != nil {
+		due = *t.Due
+	}
+	next := due
+	for i := 0; !next.After(now) || next.Equal(due); i++ {
+		var ok bool
+		next, ok = rec.Next(next)
+		if !ok || i == maxSkipped {
+			return Task{}, false, nil
+		}
+	}
+
+	occ := t
+	occ.Tags = append([]string(nil), t.Tags...)
+	// the blockers of the task were dealt with already
+	occ.BlockedBy = nil
+	// the time was tracked, and the comments and the links were made, on the task
+	occ.Time = nil
+	occ.Comments = nil
+	occ.Links = nil
+	// the checklist is to go through again
+	occ.Checklist = append([]CheckItem(nil), t.Checklist...)
+	for i := range occ.Checklist {
+		occ.Checklist[i].Done = false
+	}
+	occ.Due = &next
+	if t.Remind != nil {
+		remind := next.Add(t.Remind.Sub(due))
+		occ.Remind = &remind
+	}
+	occ.Created = now
+	occ.Updated = now
+	return occ, true, nil
+}
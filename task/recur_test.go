@@ -0,0 +1,133 @@
+package task
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecurrenceNext(t *testing.T) {
+	// a monday
+	start := time.Date(2024, time.January, 29, 9, 30, 0, 0, time.UTC)
+	date := func(month time.Month, day, hour, min int) time.Time {
+		return time.Date(2024, month, day, hour, min, 0, 0, time.UTC)
+	}
+	for rule, expected := range map[string][]time.Time{
+		"daily":   {date(time.January, 30, 9, 30), date(time.January, 31, 9, 30), date(time.February, 1, 9, 30)},
+		"weekly":  {date(time.February, 5, 9, 30), date(time.February, 12, 9, 30)},
+		"monthly": {date(time.February, 29, 9, 30), date(time.March, 29, 9, 30)},
+		"yearly":  {time.Date(2025, time.January, 29, 9, 30, 0, 0, time.UTC)},
+
+		"FREQ=DAILY;INTERVAL=3":                    {date(time.February, 1, 9, 30), date(time.February, 4, 9, 30)},
+		"FREQ=DAILY;BYDAY=SA,SU":                   {date(time.February, 3, 9, 30), date(time.February, 4, 9, 30), date(time.February, 10, 9, 30)},
+		"RRULE:FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,FR": {date(time.February, 2, 9, 30), date(time.February, 12, 9, 30), date(time.February, 16, 9, 30)},
+		"FREQ=MONTHLY;BYMONTHDAY=1,-1":             {date(time.January, 31, 9, 30), date(time.February, 1, 9, 30), date(time.February, 29, 9, 30)},
+		"FREQ=MONTHLY;BYMONTHDAY=30":               {date(time.January, 30, 9, 30), date(time.March, 30, 9, 30)},
+		"FREQ=MONTHLY;BYDAY=MO":                    {date(time.February, 5, 9, 30), date(time.February, 12, 9, 30)},
+		"FREQ=WEEKLY;UNTIL=20240212":               {date(time.February, 5, 9, 30), date(time.February, 12, 9, 30)},
+		"FREQ=WEEKLY;UNTIL=20240212T000000Z":       {date(time.February, 5, 9, 30)},
+
+		"0 9 * * 1-5":  {date(time.January, 30, 9, 0), date(time.January, 31, 9, 0)},
+		"*/15 * * * *": {date(time.January, 29, 9, 45), date(time.January, 29, 10, 0)},
+		"0 0 1 */3 *":  {date(time.April, 1, 0, 0), date(time.July, 1, 0, 0)},
+		"0 12 13 * 5":  {date(time.February, 2, 12, 0), date(time.February, 9, 12, 0), date(time.February, 13, 12, 0)},
+		"30 8 * * 0,7": {date(time.February, 4, 8, 30)},
+	} {
+		rec, err := ParseRecurrence(rule)
+		require.NoError(t, err, rule)
+		occ := start
+		for _, exp := range expected {
+			var ok bool
+			occ, ok = rec.Next(occ)
+			require.True(t, ok, rule)
+			assert.Equal(t, exp, occ, rule)
+		}
+	}
+}
+
+func TestRecurrenceEnds(t *testing.T) {
+	start := time.Date(2024, time.January, 29, 9, 30, 0, 0, time.UTC)
+	for _, rule := range []string{"FREQ=DAILY;UNTIL=20240129", "0 0 30 2 *"} {
+		rec, err := ParseRecurrence(rule)
+		require.NoError(t, err, rule)
+		_, ok := rec.Next(start)
+		assert.False(t, ok, rule)
+	}
+}
+
+func TestParseRecurrenceInvalid(t *testing.T) {
+	for _, rule := range []string{
+		"",
+		"hourly",
+		"FREQ=HOURLY",
+		"INTERVAL=2",
+		"FREQ=DAILY;INTERVAL=0",
+		"FREQ=DAILY;COUNT=3",
+		"FREQ=WEEKLY;BYDAY=1MO",
+		"FREQ=WEEKLY;BYMONTHDAY=1",
+		"FREQ=MONTHLY;BYMONTHDAY=32",
+		"FREQ=YEARLY;BYDAY=MO",
+		"FREQ=DAILY;UNTIL=tomorrow",
+		"60 * * * *",
+		"* * 0 * *",
+		"* * * * 1-8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+	} {
+		_, err := ParseRecurrence(rule)
+		assert.Error(t, err, rule)
+	}
+}
+
+func TestNextOccurrence(t *testing.T) {
+	due := time.Date(2024, time.January, 29, 18, 0, 0, 0, time.UTC)
+	remind := due.Add(-time.Hour)
+	tk := New("water the plants")
+	tk.Recur = "FREQ=WEEKLY;BYDAY=MO,TH"
+	tk.Due = &due
+	tk.Remind = &remind
+	tk.Tags = []string{"home"}
+	tk.Checklist = []CheckItem{{ID: 1, Text: "basil", Done: true}}
+
+	// completed early
+	now := due.Add(-24 * time.Hour)
+	next, ok, err := tk.NextOccurrence(now)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, time.Date(2024, time.February, 1, 18, 0, 0, 0, time.UTC), *next.Due)
+	assert.Equal(t, time.Date(2024, time.February, 1, 17, 0, 0, 0, time.UTC), *next.Remind)
+	assert.Equal(t, tk.Title, next.Title)
+	assert.Equal(t, tk.Recur, next.Recur)
+	assert.Equal(t, now, next.Created)
+	next.Tags[0] = "garden"
+	assert.Equal(t, []string{"home"}, tk.Tags)
+	assert.Equal(t, []CheckItem{{ID: 1, Text: "basil"}}, next.Checklist)
+	assert.True(t, tk.Checklist[0].Done)
+
+	// completed late, the missed occurrences are skipped
+	now = due.Add(7 * 24 * time.Hour)
+	next, ok, err = tk.NextOccurrence(now)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, time.Date(2024, time.February, 8, 18, 0, 0, 0, time.UTC), *next.Due)
+
+	// without due date
+	tk.Due = nil
+	tk.Remind = nil
+	next, ok, err = tk.NextOccurrence(now)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, time.Date(2024, time.February, 8, 18, 0, 0, 0, time.UTC), *next.Due)
+
+	tk.Recur = "FREQ=DAILY;UNTIL=20240201"
+	_, ok, err = tk.NextOccurrence(now)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	tk.Recur = ""
+	_, ok, err = tk.NextOccurrence(now)
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
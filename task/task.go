@@ -0,0 +1,402 @@
+package task
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// SchemaVersion is the version of the schema of the tasks encoded by Marshal.
+// Version 0 is the schema of the blobs written before tasks were versioned;
+// version 2 added the reminders, version 3 the recurrences, version 4 the dependencies,
+// version 5 the time entries, version 6 the comments, version 7 the checklists, version 8 the owners,
+// version 9 the ranks, version 10 the pomodoros, version 11 the contexts, version 12 the custom fields,
+// version 13 the links, version 14 the UIDs. Changing the schema requires a new entry in migrations.
+const SchemaVersion = 14
+
+// The limits enforced by Validate
+const (
+	MaxTitleLength = 200
+	MaxTags        = 32
+	MaxTagLength   = 64
+	MaxContexts    = 8
+	// MaxContextLength is the maximum length of the contexts, in characters
+	MaxContextLength = 32
+	MaxFields        = 32
+	// MaxFieldNameLength and MaxFieldValueLength are the maximum lengths of the names and the
+	// values of the custom fields, in characters
+	MaxFieldNameLength  = 32
+	MaxFieldValueLength = 500
+	// MaxStatusLength is the maximum length of the statuses of custom workflows
+	MaxStatusLength = 32
+	MaxComments     = 1000
+	// MaxCommentLength is the maximum length of the body of a comment, in characters
+	MaxCommentLength = 10000
+	MaxCheckItems    = 100
+	// MaxCheckItemLength is the maximum length of the text of a checklist entry, in characters
+	MaxCheckItemLength = 200
+	MaxLinks           = 100
+	// MaxLinkURLLength is the maximum length of the URLs linked, in characters
+	MaxLinkURLLength = 2000
+	// MaxUIDLength is the maximum length of the UIDs, in bytes
+	MaxUIDLength = 255
+)
+
+// UntitledTitle replaces the empty titles of the migrated tasks
+const UntitledTitle = "(untitled)"
+
+// Status is the processing status of a task
+type Status string
+
+const (
+	// Pending means a task is in the common backlog
+	Pending Status = "pending"
+	// Assigned means a task has got an assignee, and work has thus begun
+	Assigned Status = "assigned"
+	// Completed means a task has been completed by its assignee and is no longer active
+	Completed Status = "completed"
+	// Deleted means a task has been deleted, regardless of its previous state
+	Deleted Status = "deleted"
+)
+
+// Valid returns true if the status is one of the statuses of the DefaultWorkflow
+func (st Status) Valid() bool {
+	switch st {
+	case Pending, Assigned, Completed, Deleted:
+		return true
+	default:
+		return false
+	}
+}
+
+// WellFormed returns true if the status is usable in a Workflow: a non empty sequence
+// of at most MaxStatusLength lowercase letters, digits, dashes and underscores
+func (st Status) WellFormed() bool {
+	if st == "" || len(st) > MaxStatusLength {
+		return false
+	}
+	for _, r := range st {
+		if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+// Priority is the priority of a task. Greater values are more important.
+type Priority int
+
+const (
+	// PriorityNone means the priority was not set
+	PriorityNone Priority = iota
+	PriorityLow
+	PriorityNormal
+	PriorityHigh
+	PriorityUrgent
+)
+
+// Valid returns true if the priority is one of the known priorities
+func (pr Priority) Valid() bool {
+	return pr >= PriorityNone && pr <= PriorityUrgent
+}
+
+// Task is a item managed by the todo app
+type Task struct {
+	// Schema is the schema version of the encoding. Unmarshal migrates the tasks
+	// to the current SchemaVersion, which Marshal always encodes.
+	Schema int `json:"schema"`
+	// Title is a short summary of the task
+	Title string `json:"title"`
+	// Description is a longer description of the task
+	Description string `json:"description,omitempty"`
+	// Assignee is the identifier of the agent working on the task
+	Assignee string `json:"assignee,omitempty"`
+	// Owner is the name of the user the task belongs to, who may change it. Empty if the task
+	// belongs to no user, like the tasks created before the users existed.
+	Owner string `json:"owner,omitempty"`
+	// Status is the current processing status of the task
+	Status Status `json:"status"`
+	// Priority is the priority of the task
+	Priority Priority `json:"priority,omitempty"`
+	// Due is when the task is expected to be completed. Nil if not set.
+	Due *time.Time `json:"due,omitempty"`
+	// Remind is when the assignee wants to be reminded of the task. Nil if not set.
+	Remind *time.Time `json:"remind,omitempty"`
+	// Recur is the recurrence rule of the task, as accepted by ParseRecurrence. Empty if the task doesn't recur.
+	Recur string `json:"recur,omitempty"`
+	// Series is the ID of the first occurrence of a recurring task, shared by all of its occurrences.
+	// Empty until the first occurrence is completed.
+	Series string `json:"series,omitempty"`
+	// BlockedBy are the IDs of the tasks to complete before this one
+	BlockedBy []string `json:"blocked_by,omitempty"`
+	// Tags are the labels attached to the task
+	Tags []string `json:"tags,omitempty"`
+	// Contexts are where, or with what, the task can be done, like home or phone, the GTD contexts
+	// written @home and @phone
+	Contexts []string `json:"contexts,omitempty"`
+	// Fields are the values of the custom fields of the task, by name, as the lists define them
+	Fields map[string]string `json:"fields,omitempty"`
+	// Time are the time entries tracked working on the task, oldest first, see Start
+	Time []TimeEntry `json:"time,omitempty"`
+	// Comments are the comments on the task, oldest first, see AddComment
+	Comments []Comment `json:"comments,omitempty"`
+	// Checklist are the entries of the checklist of the task, see AddCheckItem
+	Checklist []CheckItem `json:"checklist,omitempty"`
+	// Links are the links of the task to other tasks and to external pages, see AddLink
+	Links []Link `json:"links,omitempty"`
+	// Rank is the place of the task in the manual order of its list, see RankBetween: the tasks
+	// sort by rank, the ones without rank last. Empty if the task was never moved.
+	Rank string `json:"rank,omitempty"`
+	// UID is the unique identifier of the calendar component the task was created from, e.g. by a
+	// CalDAV client, which expects to get it back. Empty if none.
+	UID string `json:"uid,omitempty"`
+	// Created records when the task was created
+	Created time.Time `json:"created"`
+	// Updated records the last time the task was modified in any way
+	Updated time.Time `json:"updated"`
+}
+
+// New creates a new pending Task with the given title
+func New(title string) Task {
+	now := time.Now()
+	return Task{
+		Schema:  SchemaVersion,
+		Title:   title,
+		Status:  Pending,
+		Created: now,
+		Updated: now,
+	}
+}
+
+// ValidationError describes why a task is not valid
+type ValidationError struct {
+	Field  string
+	Reason string
+}
+
+func (e ValidationError) Error() string {
+	return fmt.Sprintf("invalid task %s: %s", e.Field, e.Reason)
+}
+
+// Validate checks the task satisfies all the constraints: a title not blank and at most
+// MaxTitleLength characters long, a well formed status, a known priority, a valid recurrence rule, distinct
+// non empty blockers, at most MaxTags distinct tags, each at most MaxTagLength characters long and without spaces,
+// at most MaxContexts distinct valid contexts, at most MaxFields custom fields with valid names and
+// values not empty, at most MaxFieldValueLength characters long,
+// time entries in order, each ending after it starts, only the last one running, at most MaxComments
+// comments with distinct IDs and valid bodies, at most MaxCheckItems checklist entries with distinct IDs
+// and valid texts, at most MaxLinks distinct valid links, a UID at most MaxUIDLength bytes long
+// on a single line.
+// Returns a ValidationError describing the first constraint violated, if any.
+func (t Task) Validate() error {
+	if strings.TrimSpace(t.Title) == "" {
+		return ValidationError{Field: "title", Reason: "must not be blank"}
+	}
+	if utf8.RuneCountInString(t.Title) > MaxTitleLength {
+		return ValidationError{Field: "title", Reason: fmt.Sprintf("longer than %d characters", MaxTitleLength)}
+	}
+	if !t.Status.WellFormed() {
+		return ValidationError{Field: "status", Reason: fmt.Sprintf("malformed status %q", t.Status)}
+	}
+	if !t.Priority.Valid() {
+		return ValidationError{Field: "priority", Reason: fmt.Sprintf("unknown priority %d", t.Priority)}
+	}
+	if t.Recur != "" {
+		if _, err := ParseRecurrence(t.Recur); err != nil {
+			return ValidationError{Field: "recur", Reason: err.Error()}
+		}
+	}
+	blockers := make(map[string]bool, len(t.BlockedBy))
+	for _, id := range t.BlockedBy {
+		if id == "" {
+			return ValidationError{Field: "blocked_by", Reason: "empty blocker"}
+		}
+		if blockers[id] {
+			return ValidationError{Field: "blocked_by", Reason: fmt.Sprintf("duplicated blocker %q", id)}
+		}
+		blockers[id] = true
+	}
+	if len(t.Tags) > MaxTags {
+		return ValidationError{Field: "tags", Reason: fmt.Sprintf("more than %d tags", MaxTags)}
+	}
+	seen := make(map[string]bool, len(t.Tags))
+	for _, tag := range t.Tags {
+		if err := ValidateTag(tag); err != nil {
+			return err
+		}
+		if seen[tag] {
+			return ValidationError{Field: "tags", Reason: fmt.Sprintf("duplicated tag %q", tag)}
+		}
+		seen[tag] = true
+	}
+	if len(t.Contexts) > MaxContexts {
+		return ValidationError{Field: "contexts", Reason: fmt.Sprintf("more than %d contexts", MaxContexts)}
+	}
+	contexts := make(map[string]bool, len(t.Contexts))
+	for _, ctx := range t.Contexts {
+		if err := ValidateContext(ctx); err != nil {
+			return err
+		}
+		if contexts[ctx] {
+			return ValidationError{Field: "contexts", Reason: fmt.Sprintf("duplicated context %q", ctx)}
+		}
+		contexts[ctx] = true
+	}
+	if len(t.Fields) > MaxFields {
+		return ValidationError{Field: "fields", Reason: fmt.Sprintf("more than %d fields", MaxFields)}
+	}
+	for name, value := range t.Fields {
+		if err := ValidateFieldName(name); err != nil {
+			return err
+		}
+		if value == "" || utf8.RuneCountInString(value) > MaxFieldValueLength {
+			return ValidationError{Field: "fields", Reason: fmt.Sprintf("invalid value of field %q", name)}
+		}
+	}
+	for i, entry := range t.Time {
+		if entry.End == nil && i < len(t.Time)-1 {
+			return ValidationError{Field: "time", Reason: fmt.Sprintf("entry %d running", i)}
+		}
+		if entry.End != nil && entry.End.Before(entry.Start) {
+			return ValidationError{Field: "time", Reason: fmt.Sprintf("entry %d ends before it starts", i)}
+		}
+		if i > 0 && entry.Start.Before(*t.Time[i-1].End) {
+			return ValidationError{Field: "time", Reason: fmt.Sprintf("entry %d overlaps the previous one", i)}
+		}
+	}
+	if len(t.Comments) > MaxComments {
+		return ValidationError{Field: "comments", Reason: fmt.Sprintf("more than %d comments", MaxComments)}
+	}
+	comments := make(map[int]bool, len(t.Comments))
+	for _, cm := range t.Comments {
+		if comments[cm.ID] {
+			return ValidationError{Field: "comments", Reason: fmt.Sprintf("duplicated comment %d", cm.ID)}
+		}
+		comments[cm.ID] = true
+		if err := ValidateComment(cm.Body); err != nil {
+			return err
+		}
+	}
+	if len(t.Checklist) > MaxCheckItems {
+		return ValidationError{Field: "checklist", Reason: fmt.Sprintf("more than %d entries", MaxCheckItems)}
+	}
+	entries := make(map[int]bool, len(t.Checklist))
+	for _, ci := range t.Checklist {
+		if entries[ci.ID] {
+			return ValidationError{Field: "checklist", Reason: fmt.Sprintf("duplicated entry %d", ci.ID)}
+		}
+		entries[ci.ID] = true
+		if err := ValidateCheckItem(ci.Text); err != nil {
+			return err
+		}
+	}
+	if len(t.Links) > MaxLinks {
+		return ValidationError{Field: "links", Reason: fmt.Sprintf("more than %d links", MaxLinks)}
+	}
+	for i, l := range t.Links {
+		if err := ValidateLink(l); err != nil {
+			return err
+		}
+		if slices.Contains(t.Links[:i], l) {
+			return ValidationError{Field: "links", Reason: fmt.Sprintf("duplicated %s link to %s", l.Type, l.Target())}
+		}
+	}
+	if len(t.UID) > MaxUIDLength || strings.ContainsAny(t.UID, "\r\n") {
+		return ValidationError{Field: "uid", Reason: fmt.Sprintf("invalid uid %q", t.UID)}
+	}
+	return ValidateRank(t.Rank)
+}
+
+// Overdue returns true if the task is due before the given time
+func (t Task) Overdue(now time.Time) bool {
+	return t.Due != nil && t.Due.Before(now)
+}
+
+// ValidateTag checks the tag is not empty, at most MaxTagLength characters long, without spaces,
+// and doesn't start with @, like the contexts. Returns a ValidationError if it isn't.
+func ValidateTag(tag string) error {
+	if tag == "" || utf8.RuneCountInString(tag) > MaxTagLength || strings.IndexFunc(tag, unicode.IsSpace) >= 0 {
+		return ValidationError{Field: "tags", Reason: fmt.Sprintf("invalid tag %q", tag)}
+	}
+	if tag[0] == '@' {
+		return ValidationError{Field: "tags", Reason: fmt.Sprintf("invalid tag %q, the contexts are not tags", tag)}
+	}
+	return nil
+}
+
+// ValidateContext checks the context, written without its @, is not empty, at most
+// MaxContextLength characters long, made of letters, digits, dashes, underscores and dots, like
+// home.office. Returns a ValidationError if it isn't.
+func ValidateContext(ctx string) error {
+	valid := ctx != "" && utf8.RuneCountInString(ctx) <= MaxContextLength
+	for _, r := range ctx {
+		if !contextRune(r) {
+			valid = false
+		}
+	}
+	if !valid {
+		return ValidationError{Field: "contexts", Reason: fmt.Sprintf("invalid context %q", ctx)}
+	}
+	return nil
+}
+
+// contextRune returns true if the rune is valid in the contexts
+func contextRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_' || r == '.'
+}
+
+// ValidateFieldName checks the name of the custom field starts with a lowercase letter, followed by
+// lowercase letters, digits, dashes and underscores, at most MaxFieldNameLength characters long.
+// Returns a ValidationError if it isn't.
+func ValidateFieldName(name string) error {
+	valid := name != "" && len(name) <= MaxFieldNameLength && name[0] >= 'a' && name[0] <= 'z'
+	for _, r := range name {
+		if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+			valid = false
+		}
+	}
+	if !valid {
+		return ValidationError{Field: "fields", Reason: fmt.Sprintf("invalid field name %q", name)}
+	}
+	return nil
+}
+
+// Marshal validates the task and encodes it as JSON, with the current SchemaVersion
+func Marshal(t Task) ([]byte, error) {
+	if err := t.Validate(); err != nil {
+		return nil, err
+	}
+	t.Schema = SchemaVersion
+	var buf bytes.Buffer
+	err := json.NewEncoder(&buf).Encode(t)
+	return buf.Bytes(), err
+}
+
+// Unmarshal decodes a task encoded by Marshal, by any version of the app. Unknown fields
+// are rejected. Tasks encoded with older schemas are migrated to the current one first;
+// all the tasks are then validated.
+func Unmarshal(data []byte) (Task, error) {
+	data, err := upgrade(data)
+	if err != nil {
+		return Task{}, err
+	}
+	var tk Task
+	if err := decodeStrict(data, &tk); err != nil {
+		return Task{}, err
+	}
+	if err := tk.Validate(); err != nil {
+		return Task{}, err
+	}
+	return tk, nil
+}
+
+func decodeStrict(data []byte, v any) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	return dec.Decode(v)
+}
@@ -0,0 +1,118 @@
+package task
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate(t *testing.T) {
+	valid := New("buy milk")
+	valid.Tags = []string{"home", "errands"}
+	valid.Contexts = []string{"office", "phone"}
+	valid.Fields = map[string]string{"severity": "high", "estimate": "3"}
+	valid.Priority = PriorityHigh
+	assert.NoError(t, valid.Validate())
+
+	for name, change := range map[string]func(tk *Task){
+		"title":          func(tk *Task) { tk.Title = " " },
+		"title length":   func(tk *Task) { tk.Title = strings.Repeat("x", MaxTitleLength+1) },
+		"status":         func(tk *Task) { tk.Status = "In Progress" },
+		"priority":       func(tk *Task) { tk.Priority = PriorityUrgent + 1 },
+		"tag":            func(tk *Task) { tk.Tags = []string{"two words"} },
+		"empty tag":      func(tk *Task) { tk.Tags = []string{""} },
+		"duplicated tag": func(tk *Task) { tk.Tags = []string{"home", "home"} },
+		"tags":           func(tk *Task) { tk.Tags = make([]string, MaxTags+1) },
+		"context tag":    func(tk *Task) { tk.Tags = []string{"@home"} },
+		"context":        func(tk *Task) { tk.Contexts = []string{"@home"} },
+		"contexts":       func(tk *Task) { tk.Contexts = []string{"home", "home"} },
+		"field name":     func(tk *Task) { tk.Fields = map[string]string{"Severity": "high"} },
+		"field value":    func(tk *Task) { tk.Fields = map[string]string{"severity": ""} },
+		"recur":          func(tk *Task) { tk.Recur = "every other day" },
+		"blocker":        func(tk *Task) { tk.BlockedBy = []string{""} },
+		"blockers":       func(tk *Task) { tk.BlockedBy = []string{"1", "1"} },
+	} {
+		tk := valid
+		change(&tk)
+		var verr ValidationError
+		assert.ErrorAs(t, tk.Validate(), &verr, name)
+	}
+}
+
+func TestMarshalUnmarshal(t *testing.T) {
+	due := time.Date(2024, time.November, 11, 0, 0, 0, 0, time.UTC)
+	tk := New("write report")
+	tk.Schema = 0
+	tk.Description = "quarterly"
+	tk.Assignee = "fede"
+	tk.Owner = "alice"
+	tk.Status = Assigned
+	tk.Priority = PriorityNormal
+	tk.Due = &due
+	tk.Tags = []string{"work"}
+	tk.Rank = "V"
+
+	data, err := Marshal(tk)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"schema":14`)
+
+	got, err := Unmarshal(data)
+	require.NoError(t, err)
+	assert.Equal(t, SchemaVersion, got.Schema)
+	assert.Equal(t, tk.Title, got.Title)
+	assert.Equal(t, tk.Tags, got.Tags)
+	assert.Equal(t, "alice", got.Owner)
+	assert.Equal(t, "V", got.Rank)
+	assert.True(t, due.Equal(*got.Due))
+	assert.True(t, tk.Created.Equal(got.Created))
+
+	_, err = Marshal(Task{Status: Pending})
+	assert.Error(t, err)
+}
+
+func TestUnmarshalStrict(t *testing.T) {
+	for name, data := range map[string]string{
+		"unknown field":  `{"schema":14,"title":"foo","status":"pending","color":"red"}`,
+		"newer schema":   `{"schema":15,"title":"foo","status":"pending"}`,
+		"invalid status": `{"schema":14,"title":"foo","status":"In Progress"}`,
+		"invalid title":  `{"schema":14,"title":"","status":"pending"}`,
+		"invalid rank":   `{"schema":14,"title":"foo","status":"pending","rank":"a-b"}`,
+		"invalid uid":    `{"schema":14,"title":"foo","status":"pending","uid":"a\nb"}`,
+		"not json":       `foo`,
+	} {
+		_, err := Unmarshal([]byte(data))
+		assert.Error(t, err, name)
+	}
+}
+
+func TestUnmarshalV0(t *testing.T) {
+	data := `{"Title":"foo","Assignee":"fede","Description":"bar","Status":"assigned","LastUpdateTime":"2024-11-11T10:00:00Z"}`
+	tk, err := Unmarshal([]byte(data))
+	require.NoError(t, err)
+	updated := time.Date(2024, time.November, 11, 10, 0, 0, 0, time.UTC)
+	assert.Equal(t, Task{
+		Schema:      SchemaVersion,
+		Title:       "foo",
+		Description: "bar",
+		Assignee:    "fede",
+		Status:      Assigned,
+		Created:     updated,
+		Updated:     updated,
+	}, tk)
+
+	tk, err = Unmarshal([]byte(`{"Title":"","Status":"pending","LastUpdateTime":"2024-11-11T10:00:00Z"}`))
+	require.NoError(t, err)
+	assert.Equal(t, UntitledTitle, tk.Title)
+
+	long := strings.Repeat("x", MaxTitleLength+10)
+	tk, err = Unmarshal([]byte(`{"Title":"` + long + `","Description":"bar","Status":"pending"}`))
+	require.NoError(t, err)
+	assert.Equal(t, long[:MaxTitleLength], tk.Title)
+	assert.Equal(t, long+"\n\nbar", tk.Description)
+
+	_, err = Unmarshal([]byte(`{"Title":"foo","Status":"pending","Color":"red"}`))
+	assert.Error(t, err)
+}
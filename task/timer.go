@@ -0,0 +1,115 @@
+package task
+
+import (
+	"errors"
+	"slices"
+	"time"
+)
+
+var (
+	ErrTimerRunning = errors.New("timer running already")
+	ErrTimerStopped = errors.New("timer not running")
+)
+
+// TimeEntry is a time span spent working on a task
+type TimeEntry struct {
+	Start time.Time `json:"start"`
+	// End is when the work stopped. Nil while the timer runs.
+	End *time.Time `json:"end,omitempty"`
+	// Pomodoro tells whether the entry is a pomodoro, a work interval run to its end, see AddTime
+	Pomodoro bool `json:"pomodoro,omitempty"`
+}
+
+// Within returns how much of the entry falls between from and to. The running entries
+// last until now.
+func (e TimeEntry) Within(from, to, now time.Time) time.Duration {
+	start, end := e.Start, now
+	if e.End != nil {
+		end = *e.End
+	}
+	if start.Before(from) {
+		start = from
+	}
+	if end.After(to) {
+		end = to
+	}
+	if !end.After(start) {
+		return 0
+	}
+	return end.Sub(start)
+}
+
+// Running returns true if the timer of the task runs, i.e. its last time entry didn't end
+func (t Task) Running() bool {
+	return len(t.Time) > 0 && t.Time[len(t.Time)-1].End == nil
+}
+
+// Start starts the timer of the task at the given time, opening a new time entry.
+// Fails with ErrTimerRunning if the timer runs already.
+func (t *Task) Start(now time.Time) error {
+	if t.Running() {
+		return ErrTimerRunning
+	}
+	if len(t.Time) > 0 && now.Before(*t.Time[len(t.Time)-1].End) {
+		now = *t.Time[len(t.Time)-1].End
+	}
+	t.Time = append(t.Time, TimeEntry{Start: now})
+	return nil
+}
+
+// Stop stops the timer of the task at the given time, closing the running time entry.
+// Fails with ErrTimerStopped if the timer doesn't run.
+func (t *Task) Stop(now time.Time) error {
+	if !t.Running() {
+		return ErrTimerStopped
+	}
+	last := &t.Time[len(t.Time)-1]
+	if now.Before(last.Start) {
+		now = last.Start
+	}
+	last.End = &now
+	return nil
+}
+
+// Tracked returns the time tracked on the task between from and to, counting the running
+// timer until now
+func (t Task) Tracked(from, to, now time.Time) time.Duration {
+	var total time.Duration
+	for _, entry := range t.Time {
+		total += entry.Within(from, to, now)
+	}
+	return total
+}
+
+// AddTime adds the time entry, which ended already, e.g. a pomodoro, to the ones of the task, in
+// the order of their start. Fails with ErrTimerRunning if the timer runs, and with a
+// ValidationError if the entry overlaps another.
+func (t *Task) AddTime(entry TimeEntry) error {
+	if t.Running() {
+		return ErrTimerRunning
+	}
+	if entry.End == nil || entry.End.Before(entry.Start) {
+		return ValidationError{Field: "time", Reason: "entry ending before it starts"}
+	}
+	i := len(t.Time)
+	for i > 0 && t.Time[i-1].Start.After(entry.Start) {
+		i--
+	}
+	if (i > 0 && entry.Start.Before(*t.Time[i-1].End)) || (i < len(t.Time) && t.Time[i].Start.Before(*entry.End)) {
+		return ValidationError{Field: "time", Reason: "entry overlapping another"}
+	}
+	t.Time = slices.Insert(t.Time, i, entry)
+	return nil
+}
+
+// Pomodoros returns how many pomodoros of the task ended between from and to. Zero times don't
+// bound the period.
+func (t Task) Pomodoros(from, to time.Time) int {
+	count := 0
+	for _, entry := range t.Time {
+		if entry.Pomodoro && entry.End != nil && (from.IsZero() || !entry.End.Before(from)) && (to.IsZero() || entry.End.Before(to)) {
+			count++
+		}
+	}
+	return count
+}
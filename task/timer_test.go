@@ -0,0 +1,63 @@
+package task
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTimer(t *testing.T) {
+	start := time.Date(2024, 11, 11, 9, 0, 0, 0, time.UTC)
+	tk := New("foo")
+	assert.False(t, tk.Running())
+	assert.ErrorIs(t, tk.Stop(start), ErrTimerStopped)
+
+	require.NoError(t, tk.Start(start))
+	assert.True(t, tk.Running())
+	assert.ErrorIs(t, tk.Start(start), ErrTimerRunning)
+	require.NoError(t, tk.Stop(start.Add(2*time.Hour)))
+	// a new entry never overlaps the previous one
+	require.NoError(t, tk.Start(start.Add(time.Hour)))
+	assert.Equal(t, start.Add(2*time.Hour), tk.Time[1].Start)
+	require.NoError(t, tk.Validate())
+
+	now := start.Add(3 * time.Hour)
+	assert.Equal(t, 3*time.Hour, tk.Tracked(start, now, now))
+	assert.Equal(t, 90*time.Minute, tk.Tracked(start.Add(90*time.Minute), start.Add(4*time.Hour), now))
+	assert.Zero(t, tk.Tracked(start.Add(-time.Hour), start, now))
+
+	data, err := Marshal(tk)
+	require.NoError(t, err)
+	got, err := Unmarshal(data)
+	require.NoError(t, err)
+	assert.Equal(t, tk.Tracked(start, now, now), got.Tracked(start, now, now))
+
+	tk.Time = []TimeEntry{{Start: start}, {Start: now}}
+	assert.Error(t, tk.Validate())
+}
+
+func TestAddTime(t *testing.T) {
+	at := func(h, m int) *time.Time {
+		t := time.Date(2024, 11, 11, h, m, 0, 0, time.UTC)
+		return &t
+	}
+	tk := New("foo")
+	require.NoError(t, tk.AddTime(TimeEntry{Start: *at(10, 0), End: at(10, 25), Pomodoro: true}))
+	require.NoError(t, tk.AddTime(TimeEntry{Start: *at(9, 0), End: at(9, 10)}))
+	require.NoError(t, tk.AddTime(TimeEntry{Start: *at(10, 30), End: at(10, 55), Pomodoro: true}))
+	assert.Equal(t, *at(9, 0), tk.Time[0].Start, "in the order of their start")
+	require.NoError(t, tk.Validate())
+
+	assert.Error(t, tk.AddTime(TimeEntry{Start: *at(9, 5), End: at(9, 20)}), "overlapping")
+	assert.Error(t, tk.AddTime(TimeEntry{Start: *at(9, 50), End: at(10, 5)}), "overlapping")
+	assert.Error(t, tk.AddTime(TimeEntry{Start: *at(12, 0), End: at(11, 0)}))
+	assert.Error(t, tk.AddTime(TimeEntry{Start: *at(12, 0)}), "running")
+	require.NoError(t, tk.Start(*at(11, 0)))
+	assert.ErrorIs(t, tk.AddTime(TimeEntry{Start: *at(8, 0), End: at(8, 25)}), ErrTimerRunning)
+
+	assert.Equal(t, 2, tk.Pomodoros(time.Time{}, time.Time{}))
+	assert.Equal(t, 1, tk.Pomodoros(*at(10, 30), time.Time{}))
+	assert.Equal(t, 1, tk.Pomodoros(time.Time{}, *at(10, 55)), "ending before to")
+}
@@ -0,0 +1,79 @@
+package task
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// priorityNames are the names accepted by ParsePriority besides the numbers
+var priorityNames = map[string]Priority{
+	"none":   PriorityNone,
+	"p3":     PriorityLow,
+	"low":    PriorityLow,
+	"p2":     PriorityNormal,
+	"normal": PriorityNormal,
+	"p1":     PriorityHigh,
+	"high":   PriorityHigh,
+	"p0":     PriorityUrgent,
+	"urgent": PriorityUrgent,
+}
+
+// ParsePriority parses a priority, given either as its number, from 0 (none) to 4 (urgent), as its
+// name, e.g. "high", or in the P notation, from P0 (urgent) to P3 (low). The names are case insensitive.
+func ParsePriority(s string) (Priority, error) {
+	if pr, ok := priorityNames[strings.ToLower(s)]; ok {
+		return pr, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || !Priority(n).Valid() {
+		return PriorityNone, fmt.Errorf("invalid priority %q", s)
+	}
+	return Priority(n), nil
+}
+
+// String returns the priority in the P notation, or "none"
+func (pr Priority) String() string {
+	if pr == PriorityNone || !pr.Valid() {
+		return "none"
+	}
+	return fmt.Sprintf("P%d", PriorityUrgent-pr)
+}
+
+// The coefficients of the urgency terms
+const (
+	urgencyDueCoeff = 12.0
+	urgencyAgeCoeff = 2.0
+)
+
+// urgencyPriority is the urgency term of each priority
+var urgencyPriority = map[Priority]float64{
+	PriorityNone:   0,
+	PriorityLow:    1.8,
+	PriorityNormal: 3.9,
+	PriorityHigh:   6.0,
+	PriorityUrgent: 9.0,
+}
+
+// Urgency scores how soon the task should be worked on at the given time: the greater, the sooner.
+// It sums three terms:
+//   - the priority: from 0 for none to 9 for urgent;
+//   - the proximity of the due date: from 2.4, due in 14 days or more, growing linearly to 12, overdue
+//     by 7 days or more; 0 without due date;
+//   - the age: from 0, just created, growing linearly to 2, a year old or more.
+func (t Task) Urgency(now time.Time) float64 {
+	urgency := urgencyPriority[t.Priority]
+	if t.Due != nil {
+		days := t.Due.Sub(now).Hours() / 24
+		// 1 when overdue by 7 days, 0.2 when due in 14 days
+		factor := math.Max(0.2, math.Min(1, 0.2+(14-days)*0.8/21))
+		urgency += urgencyDueCoeff * factor
+	}
+	if !t.Created.IsZero() {
+		age := now.Sub(t.Created).Hours() / 24 / 365
+		urgency += urgencyAgeCoeff * math.Max(0, math.Min(1, age))
+	}
+	return urgency
+}
@@ -0,0 +1,70 @@
+package task
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePriority(t *testing.T) {
+	for s, expected := range map[string]Priority{
+		"P0":     PriorityUrgent,
+		"p1":     PriorityHigh,
+		"P2":     PriorityNormal,
+		"P3":     PriorityLow,
+		"urgent": PriorityUrgent,
+		"Low":    PriorityLow,
+		"none":   PriorityNone,
+		"0":      PriorityNone,
+		"4":      PriorityUrgent,
+	} {
+		pr, err := ParsePriority(s)
+		require.NoError(t, err, s)
+		assert.Equal(t, expected, pr, s)
+	}
+	for _, s := range []string{"", "P4", "5", "-1", "asap"} {
+		_, err := ParsePriority(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestPriorityString(t *testing.T) {
+	assert.Equal(t, "P0", PriorityUrgent.String())
+	assert.Equal(t, "P3", PriorityLow.String())
+	assert.Equal(t, "none", PriorityNone.String())
+	for pr := PriorityNone; pr <= PriorityUrgent; pr++ {
+		parsed, err := ParsePriority(pr.String())
+		require.NoError(t, err)
+		assert.Equal(t, pr, parsed)
+	}
+}
+
+func TestUrgency(t *testing.T) {
+	now := time.Date(2024, time.November, 11, 10, 0, 0, 0, time.UTC)
+	days := func(n int) *time.Time {
+		due := now.AddDate(0, 0, n)
+		return &due
+	}
+	tk := Task{Created: now}
+	assert.Zero(t, tk.Urgency(now))
+
+	tk.Priority = PriorityUrgent
+	assert.InDelta(t, 9, tk.Urgency(now), 0.001)
+
+	tk.Priority = PriorityNone
+	for n, expected := range map[int]float64{30: 2.4, 14: 2.4, 0: 8.8, -7: 12, -30: 12} {
+		tk.Due = days(n)
+		assert.InDelta(t, expected, tk.Urgency(now), 0.01, n)
+	}
+
+	tk.Due = nil
+	tk.Created = now.AddDate(-2, 0, 0)
+	assert.InDelta(t, 2, tk.Urgency(now), 0.001)
+
+	// due soon beats a higher priority due later
+	soon := Task{Priority: PriorityNormal, Due: days(1), Created: now}
+	later := Task{Priority: PriorityHigh, Due: days(20), Created: now}
+	assert.Greater(t, soon.Urgency(now), later.Urgency(now))
+}
@@ -0,0 +1,206 @@
+package task
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ErrIllegalTransition is returned when a task can't move from a status to another
+type ErrIllegalTransition struct {
+	From Status
+	To   Status
+}
+
+func (e ErrIllegalTransition) Error() string {
+	if e.From == "" {
+		return fmt.Sprintf("illegal initial status %q", e.To)
+	}
+	return fmt.Sprintf("illegal transition from status %q to %q", e.From, e.To)
+}
+
+// Workflow defines the statuses a task can have, and the transitions allowed between
+// them. The statuses without transitions are final. A Workflow is immutable, hence safe
+// for concurrent use.
+type Workflow struct {
+	initial     Status
+	transitions map[Status]map[Status]bool
+}
+
+// NewWorkflow creates a new Workflow, whose tasks start in the initial status, and
+// can then move along the given transitions, mapping each status to the statuses it
+// can move to. All the statuses must be well formed, see Status.WellFormed.
+func NewWorkflow(initial Status, transitions map[Status][]Status) (*Workflow, error) {
+	wf := &Workflow{
+		initial:     initial,
+		transitions: make(map[Status]map[Status]bool),
+	}
+	add := func(st Status) error {
+		if !st.WellFormed() {
+			return fmt.Errorf("malformed status %q", st)
+		}
+		if _, ok := wf.transitions[st]; !ok {
+			wf.transitions[st] = make(map[Status]bool)
+		}
+		return nil
+	}
+	if err := add(initial); err != nil {
+		return nil, err
+	}
+	for from, tos := range transitions {
+		if err := add(from); err != nil {
+			return nil, err
+		}
+		for _, to := range tos {
+			if err := add(to); err != nil {
+				return nil, err
+			}
+			if to != from {
+				wf.transitions[from][to] = true
+			}
+		}
+	}
+	return wf, nil
+}
+
+// DefaultWorkflow returns the workflow enforced by the todo methods: pending todos
+// get assigned, then completed; ongoing todos can be deleted.
+func DefaultWorkflow() *Workflow {
+	wf, err := NewWorkflow(Pending, map[Status][]Status{
+		Pending:  {Assigned, Deleted},
+		Assigned: {Completed, Deleted},
+	})
+	if err != nil {
+		panic(err) // can't happen: all the statuses are well formed
+	}
+	return wf
+}
+
+// ParseWorkflow creates a new Workflow from its textual definition, made of the
+// transitions from each status separated by semicolons, e.g.
+// "todo>in-progress,done; in-progress>blocked,done; blocked>in-progress".
+// The first status is the initial one.
+func ParseWorkflow(spec string) (*Workflow, error) {
+	var initial Status
+	transitions := make(map[Status][]Status)
+	for _, rule := range strings.Split(spec, ";") {
+		rule = strings.TrimSpace(rule)
+		if rule == "" {
+			continue
+		}
+		from, tos, ok := strings.Cut(rule, ">")
+		if !ok {
+			return nil, fmt.Errorf("malformed workflow rule %q: expected from>to,...", rule)
+		}
+		fromSt := Status(strings.TrimSpace(from))
+		if initial == "" {
+			initial = fromSt
+		}
+		for _, to := range strings.Split(tos, ",") {
+			transitions[fromSt] = append(transitions[fromSt], Status(strings.TrimSpace(to)))
+		}
+	}
+	if initial == "" {
+		return nil, fmt.Errorf("empty workflow")
+	}
+	return NewWorkflow(initial, transitions)
+}
+
+// String returns the textual definition of the workflow, as parsed by ParseWorkflow
+func (wf *Workflow) String() string {
+	var rules []string
+	for _, from := range wf.Statuses() {
+		next := wf.Next(from)
+		if len(next) == 0 {
+			continue
+		}
+		tos := make([]string, 0, len(next))
+		for _, to := range next {
+			tos = append(tos, string(to))
+		}
+		rules = append(rules, string(from)+">"+strings.Join(tos, ","))
+	}
+	if len(rules) == 0 {
+		return string(wf.initial) + ">"
+	}
+	return strings.Join(rules, "; ")
+}
+
+// Initial returns the status the tasks start in
+func (wf *Workflow) Initial() Status {
+	return wf.initial
+}
+
+// Statuses returns all the statuses of the workflow: the initial one first,
+// then the others sorted
+func (wf *Workflow) Statuses() []Status {
+	res := make([]Status, 0, len(wf.transitions))
+	for st := range wf.transitions {
+		if st != wf.initial {
+			res = append(res, st)
+		}
+	}
+	sortStatuses(res)
+	return append([]Status{wf.initial}, res...)
+}
+
+// Has returns true if the status belongs to the workflow
+func (wf *Workflow) Has(st Status) bool {
+	_, ok := wf.transitions[st]
+	return ok
+}
+
+// Next returns the statuses the given status can move to, sorted
+func (wf *Workflow) Next(from Status) []Status {
+	res := make([]Status, 0, len(wf.transitions[from]))
+	for to := range wf.transitions[from] {
+		res = append(res, to)
+	}
+	sortStatuses(res)
+	return res
+}
+
+// Done returns the status completing the tasks in the given one: Completed if the workflow moves
+// there from it, or else the first final status other than Deleted it moves to. Completed if there
+// is none, which Check then refuses.
+func (wf *Workflow) Done(from Status) Status {
+	if wf.transitions[from][Completed] {
+		return Completed
+	}
+	for _, to := range wf.Next(from) {
+		if to != Deleted && wf.Final(to) {
+			return to
+		}
+	}
+	return Completed
+}
+
+// Final returns true if the status belongs to the workflow, and can't move to any other
+func (wf *Workflow) Final(st Status) bool {
+	return wf.Has(st) && len(wf.transitions[st]) == 0
+}
+
+// Check returns ErrIllegalTransition if a task can't move from a status to the other.
+// Staying in the same status is always allowed. The tasks in statuses unknown to the
+// workflow, e.g. set by a previous workflow, can only be reset to the initial status.
+// The empty from status checks the status of a new task, which can be any in the workflow.
+func (wf *Workflow) Check(from, to Status) error {
+	switch {
+	case from == "" && wf.Has(to):
+		return nil
+	case from == to:
+		return nil
+	case !wf.Has(from) && to == wf.initial:
+		return nil
+	case wf.transitions[from][to]:
+		return nil
+	default:
+		return ErrIllegalTransition{From: from, To: to}
+	}
+}
+
+func sortStatuses(sts []Status) {
+	sort.Slice(sts, func(i, j int) bool {
+		return sts[i] < sts[j]
+	})
+}
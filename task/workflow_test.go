@@ -0,0 +1,52 @@
+package task
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefaultWorkflow(t *testing.T) {
+	wf := DefaultWorkflow()
+	assert.Equal(t, Pending, wf.Initial())
+	assert.Equal(t, []Status{Pending, Assigned, Completed, Deleted}, wf.Statuses())
+	assert.Equal(t, []Status{Assigned, Deleted}, wf.Next(Pending))
+	assert.True(t, wf.Final(Completed))
+	assert.False(t, wf.Final(Assigned))
+	assert.False(t, wf.Final("unknown"))
+
+	assert.NoError(t, wf.Check(Pending, Assigned))
+	assert.NoError(t, wf.Check(Assigned, Assigned))
+	assert.NoError(t, wf.Check("", Assigned))
+	assert.ErrorIs(t, wf.Check(Pending, Completed), ErrIllegalTransition{From: Pending, To: Completed})
+	assert.ErrorIs(t, wf.Check(Completed, Pending), ErrIllegalTransition{From: Completed, To: Pending})
+	assert.ErrorIs(t, wf.Check("", "done"), ErrIllegalTransition{To: "done"})
+}
+
+func TestParseWorkflow(t *testing.T) {
+	spec := "todo>in-progress,done; in-progress>blocked,done; blocked>in-progress"
+	wf, err := ParseWorkflow(spec)
+	require.NoError(t, err)
+	assert.Equal(t, Status("todo"), wf.Initial())
+	assert.Equal(t, []Status{"todo", "blocked", "done", "in-progress"}, wf.Statuses())
+	assert.True(t, wf.Final("done"))
+	assert.NoError(t, wf.Check("in-progress", "blocked"))
+	assert.NoError(t, wf.Check("blocked", "in-progress"))
+	assert.Error(t, wf.Check("blocked", "done"))
+	// from statuses of previous workflows, only back to the start
+	assert.NoError(t, wf.Check(Assigned, "todo"))
+	assert.Error(t, wf.Check(Assigned, "done"))
+	assert.Equal(t, Status("done"), wf.Done("in-progress"))
+	assert.Equal(t, Completed, wf.Done("blocked"), "none")
+	assert.Equal(t, Completed, DefaultWorkflow().Done(Assigned))
+
+	again, err := ParseWorkflow(wf.String())
+	require.NoError(t, err)
+	assert.Equal(t, wf, again)
+
+	for _, bad := range []string{"", " ; ", "todo", "todo>In Progress", ">done"} {
+		_, err := ParseWorkflow(bad)
+		assert.Error(t, err, bad)
+	}
+}
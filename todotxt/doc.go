@@ -0,0 +1,5 @@
+// Package todotxt converts the tasks from and to the lines of the todo.txt format, see
+// https://github.com/todotxt/todo.txt, keeping the fields the format has room for: the completion,
+// the priority, the creation and completion dates, the tags as projects and contexts, and the due
+// dates and recurrences as the due: and rec: extensions most todo.txt apps support.
+package todotxt
@@ -0,0 +1,220 @@
+package todotxt
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gotestbootcamp/go-todo-app/task"
+)
+
+// dateLayout is the layout of the dates of the todo.txt lines
+const dateLayout = "2006-01-02"
+
+// priorityLetters are the todo.txt priorities of the priorities of the tasks. The priorities
+// after D are parsed as the low one.
+var priorityLetters = map[task.Priority]byte{
+	task.PriorityUrgent: 'A',
+	task.PriorityHigh:   'B',
+	task.PriorityNormal: 'C',
+	task.PriorityLow:    'D',
+}
+
+// recurUnits are the units of the rec: extension, and the frequencies of the recurrence rules
+// they stand for
+var recurUnits = map[string]string{"d": "DAILY", "w": "WEEKLY", "m": "MONTHLY", "y": "YEARLY"}
+
+// recurKeywords are the recurrence rules of task.ParseRecurrence repeating every period
+var recurKeywords = map[string]string{"DAILY": "daily", "WEEKLY": "weekly", "MONTHLY": "monthly", "YEARLY": "yearly"}
+
+// recurRule matches the recurrence rules the rec: extension has room for
+var recurRule = regexp.MustCompile(`^(?:RRULE:)?FREQ=(DAILY|WEEKLY|MONTHLY|YEARLY)(?:;INTERVAL=([1-9][0-9]*))?$`)
+
+// recurExtension matches the values of the rec: extension, like 2w, with a leading + for the
+// strict recurrences
+var recurExtension = regexp.MustCompile(`^\+?([1-9][0-9]*)([dwmy])$`)
+
+// Codec converts the tasks from and to the lines of the todo.txt format
+type Codec struct {
+	// Now returns the current time, the creation time of the tasks without a creation date
+	Now func() time.Time
+	// Location is the time zone of the dates
+	Location *time.Location
+}
+
+// New returns a Codec of the dates in the local time zone
+func New() Codec {
+	return Codec{Now: time.Now, Location: time.Local}
+}
+
+// Format returns the todo.txt line of the task, like
+//
+//	(A) 2026-10-01 Pay rent +finance @home due:2026-11-01
+//
+// The completed tasks start with x and their completion date, the time they were last updated,
+// and keep their priority in the pri: extension. The tags are the projects, with a leading +,
+// and the contexts keep their leading @. The recurrence rules are in the rec: extension,
+// if they repeat every few days, weeks, months or years. The description, the assignee and the
+// other fields the format has no room for are left out.
+func (c Codec) Format(tk task.Task) string {
+	var words []string
+	letter, hasPriority := priorityLetters[tk.Priority]
+	if tk.Status == task.Completed {
+		words = append(words, "x", c.formatDate(tk.Updated))
+	} else if hasPriority {
+		words = append(words, fmt.Sprintf("(%c)", letter))
+	}
+	words = append(words, c.formatDate(tk.Created), strings.Join(strings.Fields(tk.Title), " "))
+	for _, tag := range tk.Tags {
+		words = append(words, "+"+tag)
+	}
+	for _, ctx := range tk.Contexts {
+		words = append(words, "@"+ctx)
+	}
+	if tk.Due != nil {
+		words = append(words, "due:"+c.formatDate(*tk.Due))
+	}
+	if rec, ok := formatRecurrence(tk.Recur); ok {
+		words = append(words, "rec:"+rec)
+	}
+	if tk.Status == task.Completed && hasPriority {
+		words = append(words, fmt.Sprintf("pri:%c", letter))
+	}
+	return strings.Join(words, " ")
+}
+
+// Parse parses a todo.txt line as a task, pending unless completed, as Format writes them. The
+// priorities after D are the low one. The lines without a creation date are created now, or
+// when they were completed if they have a completion date. The other key:value extensions stay
+// in the title. Fails if the task isn't valid.
+func (c Codec) Parse(line string) (task.Task, error) {
+	tk := task.New("")
+	tk.Created = c.Now()
+	tk.Updated = tk.Created
+	words := strings.Fields(line)
+	if len(words) > 0 && words[0] == "x" {
+		tk.Status, words = task.Completed, words[1:]
+		if done, ok := c.parseDate(words); ok {
+			tk.Created, tk.Updated, words = done, done, words[1:]
+		}
+	} else if len(words) > 0 {
+		if pr, ok := parsePriority(words[0]); ok {
+			tk.Priority, words = pr, words[1:]
+		}
+	}
+	if created, ok := c.parseDate(words); ok {
+		tk.Created, words = created, words[1:]
+	}
+
+	var title []string
+	for _, word := range words {
+		key, value, _ := strings.Cut(word, ":")
+		switch {
+		case len(word) > 1 && word[0] == '+':
+			tk.Tags = appendTag(tk.Tags, word[1:])
+		case len(word) > 1 && word[0] == '@':
+			tk.Contexts = appendTag(tk.Contexts, word[1:])
+		case key == "due" && value != "":
+			due, err := time.ParseInLocation(dateLayout, value, c.Location)
+			if err != nil {
+				return tk, fmt.Errorf("invalid due date %q", value)
+			}
+			tk.Due = &due
+		case key == "rec" && value != "":
+			rule, err := parseRecurrence(value)
+			if err != nil {
+				return tk, err
+			}
+			tk.Recur = rule
+		case key == "pri" && len(value) == 1:
+			pr, ok := parsePriority("(" + value + ")")
+			if !ok {
+				return tk, fmt.Errorf("invalid priority %q", value)
+			}
+			tk.Priority = pr
+		default:
+			title = append(title, word)
+		}
+	}
+	tk.Title = strings.Join(title, " ")
+	return tk, tk.Validate()
+}
+
+// formatDate returns the date of the time, in the time zone of the codec
+func (c Codec) formatDate(t time.Time) string {
+	return t.In(c.Location).Format(dateLayout)
+}
+
+// parseDate parses the first of the words as a date, at midnight. False if it isn't one.
+func (c Codec) parseDate(words []string) (time.Time, bool) {
+	if len(words) == 0 {
+		return time.Time{}, false
+	}
+	t, err := time.ParseInLocation(dateLayout, words[0], c.Location)
+	return t, err == nil
+}
+
+// parsePriority parses a priority like (A). False if the word isn't one.
+func parsePriority(word string) (task.Priority, bool) {
+	if len(word) != 3 || word[0] != '(' || word[2] != ')' || word[1] < 'A' || word[1] > 'Z' {
+		return task.PriorityNone, false
+	}
+	for pr, letter := range priorityLetters {
+		if letter == word[1] {
+			return pr, true
+		}
+	}
+	return task.PriorityLow, true
+}
+
+// appendTag appends the tag, or the context, to the others, unless they have it already
+func appendTag(tags []string, tag string) []string {
+	if slices.Contains(tags, tag) {
+		return tags
+	}
+	return append(tags, tag)
+}
+
+// formatRecurrence returns the value of the rec: extension of the recurrence rule, like 2w. False
+// if the rule doesn't repeat every few days, weeks, months or years.
+func formatRecurrence(rule string) (string, bool) {
+	for freq, keyword := range recurKeywords {
+		if rule == keyword {
+			rule = "FREQ=" + freq
+		}
+	}
+	m := recurRule.FindStringSubmatch(rule)
+	if m == nil {
+		return "", false
+	}
+	interval := m[2]
+	if interval == "" {
+		interval = "1"
+	}
+	for unit, freq := range recurUnits {
+		if freq == m[1] {
+			return interval + unit, true
+		}
+	}
+	return "", false
+}
+
+// parseRecurrence returns the recurrence rule of the value of the rec: extension, like 2w
+func parseRecurrence(value string) (string, error) {
+	m := recurExtension.FindStringSubmatch(value)
+	if m == nil {
+		return "", fmt.Errorf("unsupported recurrence %q", value)
+	}
+	freq := recurUnits[m[2]]
+	if m[1] == "1" {
+		return recurKeywords[freq], nil
+	}
+	n, err := strconv.Atoi(m[1])
+	if err != nil {
+		return "", fmt.Errorf("unsupported recurrence %q", value)
+	}
+	return fmt.Sprintf("FREQ=%s;INTERVAL=%d", freq, n), nil
+}
@@ -0,0 +1,94 @@
+package todotxt
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gotestbootcamp/go-todo-app/task"
+)
+
+func TestFormat(t *testing.T) {
+	c := Codec{Now: time.Now, Location: time.UTC}
+	created := time.Date(2026, time.October, 1, 9, 30, 0, 0, time.UTC)
+	due := time.Date(2026, time.November, 1, 17, 0, 0, 0, time.UTC)
+	tk := task.New("Pay  rent")
+	tk.Created, tk.Updated = created, created.Add(48*time.Hour)
+	tk.Priority = task.PriorityUrgent
+	tk.Tags = []string{"finance"}
+	tk.Contexts = []string{"home"}
+	tk.Due = &due
+	tk.Recur = "monthly"
+	tk.Description = "left out"
+	assert.Equal(t, "(A) 2026-10-01 Pay rent +finance @home due:2026-11-01 rec:1m", c.Format(tk))
+
+	tk.Status = task.Completed
+	tk.Recur = "FREQ=WEEKLY;INTERVAL=2"
+	assert.Equal(t, "x 2026-10-03 2026-10-01 Pay rent +finance @home due:2026-11-01 rec:2w pri:A", c.Format(tk))
+
+	tk = task.New("call mom")
+	tk.Created = created
+	tk.Recur = "0 9 * * 1-5"
+	assert.Equal(t, "2026-10-01 call mom", c.Format(tk))
+}
+
+func TestParse(t *testing.T) {
+	now := time.Date(2026, time.October, 14, 10, 30, 0, 0, time.UTC)
+	c := Codec{Now: func() time.Time { return now }, Location: time.UTC}
+	date := func(month time.Month, day int) time.Time {
+		return time.Date(2026, month, day, 0, 0, 0, 0, time.UTC)
+	}
+
+	tk, err := c.Parse("(B) 2026-10-01 Pay rent +finance @home +finance due:2026-11-01 rec:+2w at 10:30")
+	require.NoError(t, err)
+	assert.Equal(t, "Pay rent at 10:30", tk.Title)
+	assert.Equal(t, task.Pending, tk.Status)
+	assert.Equal(t, task.PriorityHigh, tk.Priority)
+	assert.Equal(t, date(time.October, 1), tk.Created)
+	assert.Equal(t, []string{"finance"}, tk.Tags)
+	assert.Equal(t, []string{"home"}, tk.Contexts)
+	require.NotNil(t, tk.Due)
+	assert.Equal(t, date(time.November, 1), *tk.Due)
+	assert.Equal(t, "FREQ=WEEKLY;INTERVAL=2", tk.Recur)
+
+	tk, err = c.Parse("x 2026-10-03 2026-10-01 Pay rent pri:A rec:1m")
+	require.NoError(t, err)
+	assert.Equal(t, task.Completed, tk.Status)
+	assert.Equal(t, task.PriorityUrgent, tk.Priority)
+	assert.Equal(t, date(time.October, 1), tk.Created)
+	assert.Equal(t, date(time.October, 3), tk.Updated)
+	assert.Equal(t, "monthly", tk.Recur)
+
+	tk, err = c.Parse("x 2026-10-03 call mom")
+	require.NoError(t, err)
+	assert.Equal(t, date(time.October, 3), tk.Created)
+	tk, err = c.Parse("(Q) call mom")
+	require.NoError(t, err)
+	assert.Equal(t, task.PriorityLow, tk.Priority)
+	assert.Equal(t, now, tk.Created)
+	tk, err = c.Parse("xylophone lessons (A)")
+	require.NoError(t, err)
+	assert.Equal(t, "xylophone lessons (A)", tk.Title)
+	assert.Equal(t, task.PriorityNone, tk.Priority)
+
+	for _, line := range []string{"", "+finance @home", "call mom due:friday", "call mom rec:1b", "x call mom pri:a"} {
+		_, err := c.Parse(line)
+		assert.Error(t, err, line)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	c := Codec{Now: time.Now, Location: time.UTC}
+	for _, line := range []string{
+		"(C) 2026-10-01 walk the dog +pets @park due:2026-10-02 rec:1d",
+		"x 2026-10-05 2026-10-01 file taxes +finance pri:D",
+		"2026-10-01 read a book rec:3y",
+		"2026-10-01 print the slides @home.office",
+	} {
+		tk, err := c.Parse(line)
+		require.NoError(t, err, line)
+		assert.Equal(t, line, c.Format(tk))
+	}
+}
@@ -0,0 +1,3 @@
+// Package tracing sets up the OpenTelemetry tracing of the server, exporting the spans of the
+// requests, of the calls and of the storage operations to an OTLP collector, like Jaeger or Tempo
+package tracing
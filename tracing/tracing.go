@@ -0,0 +1,60 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Name is the name of the instrumentation, which the tracers of the server are named after
+const Name = "github.com/gotestbootcamp/go-todo-app"
+
+// Tracer returns the tracer of the server, from the global tracer provider. The spans it starts
+// are dropped until Setup installs the provider exporting them.
+func Tracer() trace.Tracer {
+	return otel.Tracer(Name)
+}
+
+// Options are the settings of the exporter of the spans
+type Options struct {
+	// Endpoint is the URL of the OTLP/HTTP collector, e.g. http://localhost:4318
+	Endpoint string
+	// ServiceName is the name the spans are reported under
+	ServiceName string
+	// SampleRatio is the ratio of the traces sampled, from 0 to 1. The traces started by the
+	// callers follow their sampling decision instead.
+	SampleRatio float64
+}
+
+// Setup installs the global tracer provider exporting the spans to the OTLP collector, and the
+// propagation of the trace context and the baggage, from the W3C headers of the requests and
+// the metadata of the calls. Returns the function flushing the spans pending and stopping the
+// export, to call on shutdown.
+func Setup(ctx context.Context, opts Options) (func(context.Context) error, error) {
+	if opts.SampleRatio < 0 || opts.SampleRatio > 1 {
+		return nil, fmt.Errorf("invalid sample ratio %v, want between 0 and 1", opts.SampleRatio)
+	}
+	exp, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(opts.Endpoint))
+	if err != nil {
+		return nil, fmt.Errorf("can't create the OTLP exporter: %w", err)
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(semconv.ServiceName(opts.ServiceName)))
+	if err != nil {
+		return nil, err
+	}
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exp),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(opts.SampleRatio))),
+	)
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	return tp.Shutdown, nil
+}
@@ -0,0 +1,91 @@
+package user
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/gotestbootcamp/go-todo-app/auth"
+	"github.com/gotestbootcamp/go-todo-app/auth/oidc"
+)
+
+// ErrNoEmail is returned when mapping the claims of a user whose email address is not verified
+var ErrNoEmail = errors.New("user: no verified email address")
+
+// ByEmail returns the user with the given email address. False if there's no such user.
+func (dir *Directory) ByEmail(email string) (User, bool) {
+	dir.lock.RLock()
+	defer dir.lock.RUnlock()
+	for _, u := range dir.users {
+		if u.Email != "" && strings.EqualFold(u.Email, email) {
+			return u, true
+		}
+	}
+	return User{}, false
+}
+
+// nameOf returns a valid name for the user with the given claim, e.g. "ann.smith" for
+// "Ann.Smith@example.com"
+func nameOf(claim string) string {
+	claim, _, _ = strings.Cut(strings.ToLower(claim), "@")
+	name := []rune(claim)
+	for i, r := range name {
+		if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '.' || r == '-' || r == '_') {
+			name[i] = '_'
+		}
+	}
+	if len(name) > MaxNameLength {
+		name = name[:MaxNameLength]
+	}
+	return string(name)
+}
+
+// ClaimsMapper returns the mapper of the users logged in with a provider to the users of the
+// directory with the same verified email address, with the scope of their role. If create is true,
+// the unknown users are created, named after the user claim of the provider, unless another user
+// has the same name.
+func (dir *Directory) ClaimsMapper(create bool) auth.ClaimsMapper {
+	return func(p *oidc.Provider, claims oidc.Claims) (auth.Identity, error) {
+		email, ok := claims.Email()
+		if !ok {
+			return auth.Identity{}, ErrNoEmail
+		}
+		u, ok := dir.ByEmail(email)
+		if !ok {
+			if !create {
+				return auth.Identity{}, fmt.Errorf("user: no user with email %q", email)
+			}
+			var err error
+			u, err = dir.Create(User{
+				Name:        nameOf(claims.String(p.UserClaim)),
+				DisplayName: claims.String("name"),
+				Email:       email,
+				Role:        RoleUser,
+			})
+			if err != nil {
+				return auth.Identity{}, err
+			}
+		}
+		return auth.Identity{Name: u.Name, Scope: scopeOf(u)}, nil
+	}
+}
+
+// SessionChecker returns the checker of the sessions of the users of the directory: the sessions
+// of the users deleted end, and the others get the scope of the current role of their user.
+func (dir *Directory) SessionChecker() auth.SessionChecker {
+	return func(id auth.Identity) (auth.Identity, error) {
+		u, ok := dir.Get(id.Name)
+		if !ok {
+			return auth.Identity{}, ErrNoUser{Name: id.Name}
+		}
+		return auth.Identity{Name: u.Name, Scope: scopeOf(u)}, nil
+	}
+}
+
+// scopeOf returns the scope of the user: the admin one for the admins, the write one for the others
+func scopeOf(u User) auth.Scope {
+	if u.Role == RoleAdmin {
+		return auth.ScopeAdmin
+	}
+	return auth.ScopeWrite
+}
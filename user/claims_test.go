@@ -0,0 +1,79 @@
+package user
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gotestbootcamp/go-todo-app/auth"
+	"github.com/gotestbootcamp/go-todo-app/auth/oidc"
+	"github.com/gotestbootcamp/go-todo-app/store"
+)
+
+func TestClaimsMapper(t *testing.T) {
+	mem, err := store.NewMemory()
+	require.NoError(t, err)
+	dir, err := NewDirectory(mem)
+	require.NoError(t, err)
+	_, err = dir.Create(User{Name: "alice", Email: "Alice@example.com", Role: RoleAdmin})
+	require.NoError(t, err)
+	_, err = dir.Create(User{Name: "bob", Email: "bob@example.com", Role: RoleUser})
+	require.NoError(t, err)
+	google := &oidc.Provider{Name: "google", UserClaim: "email"}
+	verified := func(email string) oidc.Claims {
+		return oidc.Claims{"email": email, "email_verified": true, "name": "Some One"}
+	}
+
+	mapper := dir.ClaimsMapper(false)
+	id, err := mapper(google, verified("alice@example.com"))
+	require.NoError(t, err)
+	assert.Equal(t, auth.Identity{Name: "alice", Scope: auth.ScopeAdmin}, id)
+	id, err = mapper(google, verified("bob@example.com"))
+	require.NoError(t, err)
+	assert.Equal(t, auth.Identity{Name: "bob", Scope: auth.ScopeWrite}, id)
+	_, err = mapper(google, oidc.Claims{"email": "bob@example.com"})
+	assert.ErrorIs(t, err, ErrNoEmail, "not verified")
+	_, err = mapper(google, verified("carol@example.com"))
+	assert.Error(t, err, "unknown")
+	assert.Len(t, dir.List(), 2)
+
+	mapper = dir.ClaimsMapper(true)
+	id, err = mapper(google, verified("Carol.Smith+todo@example.com"))
+	require.NoError(t, err)
+	assert.Equal(t, auth.Identity{Name: "carol.smith_todo", Scope: auth.ScopeWrite}, id)
+	carol, ok := dir.Get("carol.smith_todo")
+	require.True(t, ok)
+	assert.Equal(t, User{Name: "carol.smith_todo", DisplayName: "Some One", Email: "Carol.Smith+todo@example.com", Role: RoleUser, Created: carol.Created}, carol)
+	id, err = mapper(google, verified("carol.smith+todo@example.com"))
+	require.NoError(t, err)
+	assert.Equal(t, "carol.smith_todo", id.Name, "known by now")
+
+	github := &oidc.Provider{Name: "github", UserClaim: "login"}
+	claims := verified("bob@elsewhere.example.com")
+	claims["login"] = "bob"
+	_, err = mapper(github, claims)
+	assert.ErrorAs(t, err, &ErrExists{}, "another bob")
+}
+
+func TestSessionChecker(t *testing.T) {
+	mem, err := store.NewMemory()
+	require.NoError(t, err)
+	dir, err := NewDirectory(mem)
+	require.NoError(t, err)
+	_, err = dir.Create(User{Name: "bob", Email: "bob@example.com", Role: RoleAdmin})
+	require.NoError(t, err)
+	check := dir.SessionChecker()
+
+	id, err := check(auth.Identity{Name: "bob", Scope: auth.ScopeAdmin})
+	require.NoError(t, err)
+	assert.Equal(t, auth.Identity{Name: "bob", Scope: auth.ScopeAdmin}, id)
+	_, err = dir.Update(User{Name: "bob", Email: "bob@example.com", Role: RoleUser})
+	require.NoError(t, err)
+	id, err = check(auth.Identity{Name: "bob", Scope: auth.ScopeAdmin})
+	require.NoError(t, err)
+	assert.Equal(t, auth.Identity{Name: "bob", Scope: auth.ScopeWrite}, id, "no admin anymore")
+	require.NoError(t, dir.Delete("bob"))
+	_, err = check(auth.Identity{Name: "bob", Scope: auth.ScopeWrite})
+	assert.ErrorAs(t, err, &ErrNoUser{})
+}
@@ -0,0 +1,5 @@
+// Package user keeps the records of the users of the APIs, who own the todos they create.
+// The users are named like their API keys: the requests authenticated with a key act as the
+// user of the same name. Regular users can change only the todos they own, while the admins,
+// either users with the admin role or clients with the admin scope, can change all of them.
+package user
@@ -0,0 +1,223 @@
+package user
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gotestbootcamp/go-todo-app/auth"
+	"github.com/gotestbootcamp/go-todo-app/store"
+)
+
+// MaxNameLength is the maximum length of the names of the users
+const MaxNameLength = 64
+
+// userPrefix marks the IDs of the items holding the users
+var userPrefix = string(store.MetaID("users/"))
+
+// Role tells what a user can do
+type Role string
+
+const (
+	// RoleUser can change only the todos they own
+	RoleUser Role = "user"
+	// RoleAdmin can change all the todos
+	RoleAdmin Role = "admin"
+)
+
+// Valid returns true if the role is known
+func (r Role) Valid() bool {
+	return r == RoleUser || r == RoleAdmin
+}
+
+// ErrNoUser is returned when there is no user with the given name
+type ErrNoUser struct {
+	Name string
+}
+
+func (e ErrNoUser) Error() string {
+	return fmt.Sprintf("user: no user %q", e.Name)
+}
+
+// ErrExists is returned when creating a user with the name of another
+type ErrExists struct {
+	Name string
+}
+
+func (e ErrExists) Error() string {
+	return fmt.Sprintf("user: user %q already exists", e.Name)
+}
+
+// User is the record of a user
+type User struct {
+	// Name identifies the user, see ValidateName
+	Name        string    `json:"name"`
+	DisplayName string    `json:"display_name,omitempty"`
+	Email       string    `json:"email,omitempty"`
+	Role        Role      `json:"role"`
+	Created     time.Time `json:"created"`
+}
+
+// ValidateName checks the name is usable for a user: a non empty sequence of at most MaxNameLength
+// lowercase letters, digits, dots, dashes and underscores
+func ValidateName(name string) error {
+	if name == "" || len(name) > MaxNameLength {
+		return fmt.Errorf("user: the names must be 1 to %d characters long", MaxNameLength)
+	}
+	for _, r := range name {
+		if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '.' || r == '-' || r == '_') {
+			return fmt.Errorf("user: invalid character %q in name %q", r, name)
+		}
+	}
+	return nil
+}
+
+// Validate checks the user has a valid name and role, and an email address, if any, looking like one
+func (u User) Validate() error {
+	if err := ValidateName(u.Name); err != nil {
+		return err
+	}
+	if !u.Role.Valid() {
+		return fmt.Errorf("user: invalid role %q", u.Role)
+	}
+	if u.Email != "" && (!strings.Contains(u.Email, "@") || strings.ContainsAny(u.Email, " \t\n")) {
+		return fmt.Errorf("user: invalid email %q", u.Email)
+	}
+	return nil
+}
+
+// Directory holds the users, stored as metadata items of the storage. It is safe for concurrent use.
+type Directory struct {
+	st store.Storage
+
+	lock  sync.RWMutex
+	users map[string]User
+}
+
+// NewDirectory creates the directory of the users stored in st
+func NewDirectory(st store.Storage) (*Directory, error) {
+	dir := &Directory{
+		st:    st,
+		users: make(map[string]User),
+	}
+	err := store.Walk(st, func(item store.Item) error {
+		if !strings.HasPrefix(string(item.ID), userPrefix) {
+			return nil
+		}
+		var u User
+		if err := json.Unmarshal(item.Blob, &u); err != nil {
+			return fmt.Errorf("user: can't decode the user %v: %w", item.ID, err)
+		}
+		dir.users[u.Name] = u
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("user: loaded %d users", len(dir.users))
+	return dir, nil
+}
+
+// Create stores the new user, created now, and returns it.
+// Fails with ErrExists if there's another user with the same name.
+func (dir *Directory) Create(u User) (User, error) {
+	if err := u.Validate(); err != nil {
+		return User{}, err
+	}
+	u.Created = time.Now().UTC()
+	blob, err := json.Marshal(u)
+	if err != nil {
+		return User{}, err
+	}
+	dir.lock.Lock()
+	defer dir.lock.Unlock()
+	if _, ok := dir.users[u.Name]; ok {
+		return User{}, ErrExists{Name: u.Name}
+	}
+	if err := dir.st.Create(store.ID(userPrefix+u.Name), blob); err != nil {
+		return User{}, err
+	}
+	dir.users[u.Name] = u
+	log.Printf("user: created user %q, role %s", u.Name, u.Role)
+	return u, nil
+}
+
+// Update replaces the display name, the email and the role of the user with the given ones,
+// and returns the updated user. Fails with ErrNoUser if there's no such user.
+func (dir *Directory) Update(u User) (User, error) {
+	if err := u.Validate(); err != nil {
+		return User{}, err
+	}
+	dir.lock.Lock()
+	defer dir.lock.Unlock()
+	cur, ok := dir.users[u.Name]
+	if !ok {
+		return User{}, ErrNoUser{Name: u.Name}
+	}
+	u.Created = cur.Created
+	blob, err := json.Marshal(u)
+	if err != nil {
+		return User{}, err
+	}
+	if err := dir.st.Save(store.ID(userPrefix+u.Name), blob); err != nil {
+		return User{}, err
+	}
+	dir.users[u.Name] = u
+	log.Printf("user: updated user %q, role %s", u.Name, u.Role)
+	return u, nil
+}
+
+// Delete removes the user with the given name. The todos of the user are left as they are,
+// for the admins to hand over. Fails with ErrNoUser if there's no such user.
+func (dir *Directory) Delete(name string) error {
+	dir.lock.Lock()
+	defer dir.lock.Unlock()
+	if _, ok := dir.users[name]; !ok {
+		return ErrNoUser{Name: name}
+	}
+	if err := dir.st.Delete(store.ID(userPrefix + name)); err != nil {
+		return err
+	}
+	delete(dir.users, name)
+	log.Printf("user: deleted user %q", name)
+	return nil
+}
+
+// Get returns the user with the given name. False if there's no such user.
+func (dir *Directory) Get(name string) (User, bool) {
+	dir.lock.RLock()
+	defer dir.lock.RUnlock()
+	u, ok := dir.users[name]
+	return u, ok
+}
+
+// List returns the users, sorted by name
+func (dir *Directory) List() []User {
+	dir.lock.RLock()
+	defer dir.lock.RUnlock()
+	res := make([]User, 0, len(dir.users))
+	for _, u := range dir.users {
+		res = append(res, u)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Name < res[j].Name
+	})
+	return res
+}
+
+// IsAdmin returns true if the identity may change all the todos: if it has the admin scope, or if
+// it's a user with the admin role. The nil directory has no users.
+func (dir *Directory) IsAdmin(id auth.Identity) bool {
+	if id.Scope == auth.ScopeAdmin {
+		return true
+	}
+	if dir == nil {
+		return false
+	}
+	u, ok := dir.Get(id.Name)
+	return ok && u.Role == RoleAdmin
+}
@@ -0,0 +1,78 @@
+package user
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gotestbootcamp/go-todo-app/auth"
+	"github.com/gotestbootcamp/go-todo-app/store"
+)
+
+func TestDirectory(t *testing.T) {
+	mem, err := store.NewMemory()
+	require.NoError(t, err)
+	dir, err := NewDirectory(mem)
+	require.NoError(t, err)
+
+	alice, err := dir.Create(User{Name: "alice", DisplayName: "Alice", Email: "alice@example.com", Role: RoleUser})
+	require.NoError(t, err)
+	assert.False(t, alice.Created.IsZero())
+	_, err = dir.Create(User{Name: "alice", Role: RoleAdmin})
+	assert.ErrorAs(t, err, &ErrExists{})
+	_, err = dir.Create(User{Name: "bob", Role: RoleAdmin})
+	require.NoError(t, err)
+
+	for name, u := range map[string]User{
+		"no name":      {Role: RoleUser},
+		"invalid name": {Name: "Carol Smith", Role: RoleUser},
+		"no role":      {Name: "carol"},
+		"invalid role": {Name: "carol", Role: "root"},
+		"email":        {Name: "carol", Role: RoleUser, Email: "carol"},
+	} {
+		_, err := dir.Create(u)
+		assert.Error(t, err, name)
+	}
+
+	updated, err := dir.Update(User{Name: "alice", Role: RoleAdmin})
+	require.NoError(t, err)
+	assert.Equal(t, alice.Created, updated.Created)
+	assert.Empty(t, updated.DisplayName)
+	_, err = dir.Update(User{Name: "carol", Role: RoleUser})
+	assert.ErrorAs(t, err, &ErrNoUser{})
+
+	// the users survive restarts
+	reloaded, err := NewDirectory(mem)
+	require.NoError(t, err)
+	assert.Equal(t, dir.List(), reloaded.List())
+	got, ok := reloaded.Get("alice")
+	require.True(t, ok)
+	assert.Equal(t, RoleAdmin, got.Role)
+
+	require.NoError(t, reloaded.Delete("bob"))
+	assert.ErrorAs(t, reloaded.Delete("bob"), &ErrNoUser{})
+	reloaded, err = NewDirectory(mem)
+	require.NoError(t, err)
+	assert.Len(t, reloaded.List(), 1)
+}
+
+func TestIsAdmin(t *testing.T) {
+	mem, err := store.NewMemory()
+	require.NoError(t, err)
+	dir, err := NewDirectory(mem)
+	require.NoError(t, err)
+	_, err = dir.Create(User{Name: "alice", Role: RoleAdmin})
+	require.NoError(t, err)
+	_, err = dir.Create(User{Name: "bob", Role: RoleUser})
+	require.NoError(t, err)
+
+	assert.True(t, dir.IsAdmin(auth.Identity{Name: "alice", Scope: auth.ScopeWrite}))
+	assert.False(t, dir.IsAdmin(auth.Identity{Name: "bob", Scope: auth.ScopeWrite}))
+	assert.False(t, dir.IsAdmin(auth.Identity{Name: "carol", Scope: auth.ScopeWrite}))
+	assert.True(t, dir.IsAdmin(auth.Identity{Name: "carol", Scope: auth.ScopeAdmin}))
+
+	var none *Directory
+	assert.False(t, none.IsAdmin(auth.Identity{Name: "alice", Scope: auth.ScopeWrite}))
+	assert.True(t, none.IsAdmin(auth.Identity{Name: "alice", Scope: auth.ScopeAdmin}))
+}
@@ -0,0 +1,9 @@
+// Package webhook delivers the events of the todos, like their creation, assignment, completion,
+// or their due date passing, to the URLs registered by the admins, as signed JSON payloads.
+// The Watcher tells the events apart watching the changes of the ledger, and the Dispatcher
+// posts each to the webhooks registered for its kind, retrying with exponential backoff while
+// the receiver fails or is unreachable. The Dispatcher keeps a log of the latest deliveries
+// of each webhook, which restarting clears.
+// The receivers check the payloads come from the server with Verify, or computing the HMAC-SHA256
+// of the body with the secret of the webhook, sent hex encoded in the SignatureHeader.
+package webhook
@@ -0,0 +1,146 @@
+package webhook
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/gotestbootcamp/go-todo-app/ledger"
+	"github.com/gotestbootcamp/go-todo-app/model"
+	"github.com/gotestbootcamp/go-todo-app/store"
+	"github.com/gotestbootcamp/go-todo-app/task"
+)
+
+// DefaultInterval is how often the Watcher checks for the todos become overdue by default
+const DefaultInterval = time.Minute
+
+// Event is a kind of event of the todos
+type Event string
+
+const (
+	// Created todos were just created
+	Created Event = "created"
+	// Assigned todos were just handed over to a assignee
+	Assigned Event = "assigned"
+	// Completed todos were just moved to a final status of the workflow other than deleted
+	Completed Event = "completed"
+	// Overdue todos are still active while their due date just passed
+	Overdue Event = "overdue"
+)
+
+// Events are the kinds of events supported
+var Events = []Event{Created, Assigned, Completed, Overdue}
+
+// EventFunc is called by a Watcher with each event, and the todo it's about
+type EventFunc func(ev Event, item ledger.Item)
+
+// Watcher tells the events of the todos of a ledger. Only the events happening while it runs are
+// told: the todos overdue already when it starts are not, so restarting doesn't tell them again.
+type Watcher struct {
+	ld *ledger.Ledger
+	// Interval bounds how late the overdue todos are told, DefaultInterval by default
+	Interval time.Duration
+	// Now returns the current time. Can be replaced to control time in tests.
+	Now func() time.Time
+	// todos are the states of the todos, to tell when they change
+	todos map[store.ID]todoState
+}
+
+// todoState is the state of a todo the events tell the changes of
+type todoState struct {
+	completed bool
+	assignee  string
+}
+
+// NewWatcher creates the watcher of the events of the todos of the ledger
+func NewWatcher(ld *ledger.Ledger) *Watcher {
+	return &Watcher{ld: ld, Interval: DefaultInterval, Now: time.Now, todos: make(map[store.ID]todoState)}
+}
+
+// Run calls fn with the events of the todos until the context is done.
+// Fails with store.ErrUnsupported if the datastore of the ledger can't be watched.
+func (w *Watcher) Run(ctx context.Context, fn EventFunc) error {
+	changes, err := w.ld.Watch(ctx)
+	if err != nil {
+		return err
+	}
+	items, err := w.ld.Filter(func(model.Todo) bool { return true })
+	if err != nil {
+		return err
+	}
+	for _, item := range items {
+		w.todos[item.ID] = w.stateOf(item)
+	}
+	ticker := time.NewTicker(w.Interval)
+	defer ticker.Stop()
+	last := w.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case change, ok := <-changes:
+			if !ok {
+				if ctx.Err() != nil {
+					return nil
+				}
+				// the watcher fell behind the ledger: the events in between are lost
+				slog.Warn("webhook: Watcher: fell behind the changes, resuming")
+				if changes, err = w.ld.Watch(ctx); err != nil {
+					return err
+				}
+				continue
+			}
+			w.changed(change, fn)
+		case <-ticker.C:
+			now := w.Now()
+			w.overdueBetween(last, now, fn)
+			last = now
+		}
+	}
+}
+
+// changed calls fn with the events of the change of the todo
+func (w *Watcher) changed(change ledger.Event, fn EventFunc) {
+	id := change.Item.ID
+	if change.Type == store.EventDeleted {
+		delete(w.todos, id)
+		return
+	}
+	if change.Type == store.EventCreated {
+		fn(Created, change.Item)
+	}
+	state, last := w.stateOf(change.Item), w.todos[id]
+	if state.assignee != "" && state.assignee != last.assignee {
+		fn(Assigned, change.Item)
+	}
+	if state.completed && !last.completed {
+		fn(Completed, change.Item)
+	}
+	w.todos[id] = state
+}
+
+// overdueBetween calls fn with the active todos due after from, up to until
+func (w *Watcher) overdueBetween(from, until time.Time, fn EventFunc) {
+	items, err := w.ld.ListOverdue()
+	if err != nil {
+		slog.Error("webhook: Watcher: failed to check the overdue todos", "error", err)
+		return
+	}
+	for _, item := range items {
+		if due := item.Task.Due; due.After(from) && !due.After(until) {
+			fn(Overdue, item)
+		}
+	}
+}
+
+// stateOf returns the state of the todo: it's completed if in a final status of the workflow
+// other than deleted
+func (w *Watcher) stateOf(item ledger.Item) todoState {
+	if item.Task == nil {
+		return todoState{}
+	}
+	return todoState{
+		completed: item.Task.Status != task.Deleted && w.ld.Workflow().Final(item.Task.Status),
+		assignee:  item.Task.Assignee,
+	}
+}
@@ -0,0 +1,388 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	apiv1 "github.com/gotestbootcamp/go-todo-app/api/v1"
+	"github.com/gotestbootcamp/go-todo-app/ledger"
+	"github.com/gotestbootcamp/go-todo-app/store"
+)
+
+// The headers of the deliveries
+const (
+	// EventHeader is the kind of the event
+	EventHeader = "X-Todo-Event"
+	// DeliveryHeader is the ID of the delivery, the same for its retries
+	DeliveryHeader = "X-Todo-Delivery"
+	// SignatureHeader is the signature of the body, "sha256=" followed by its hex encoded
+	// HMAC-SHA256 keyed with the secret of the webhook
+	SignatureHeader = "X-Todo-Signature"
+)
+
+const (
+	// DefaultAttempts is how many times a delivery is attempted at most by default
+	DefaultAttempts = 5
+	// DefaultBackoff is how long to wait before retrying a delivery the first time by default,
+	// doubling at each retry
+	DefaultBackoff = time.Second
+	// DefaultTimeout bounds each attempt of the deliveries by default
+	DefaultTimeout = 10 * time.Second
+	// MaxLog is how many of the latest deliveries of each webhook are logged
+	MaxLog = 100
+)
+
+// hookPrefix marks the IDs of the items holding the webhooks
+var hookPrefix = string(store.MetaID("webhooks/"))
+
+// ErrNoHook is returned when there is no webhook with the given ID
+type ErrNoHook struct {
+	ID string
+}
+
+func (e ErrNoHook) Error() string {
+	return fmt.Sprintf("webhook: no webhook %q", e.ID)
+}
+
+// Hook is a URL the events of the given kinds are delivered to
+type Hook struct {
+	// ID identifies the webhook, generated when registering it
+	ID  string `json:"id"`
+	URL string `json:"url"`
+	// Events are the kinds of the events delivered
+	Events []Event `json:"events"`
+	// Secret keys the signatures of the deliveries. Generated when registering the webhook, if empty.
+	Secret  string    `json:"secret"`
+	Created time.Time `json:"created"`
+}
+
+// Validate checks the webhook has a absolute http or https URL, and known kinds of events
+func (h Hook) Validate() error {
+	u, err := url.Parse(h.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("webhook: invalid URL %q, want an absolute http or https URL", h.URL)
+	}
+	if len(h.Events) == 0 {
+		return errors.New("webhook: no events to deliver")
+	}
+	for _, ev := range h.Events {
+		if !slices.Contains(Events, ev) {
+			return fmt.Errorf("webhook: unknown event %q", ev)
+		}
+	}
+	return nil
+}
+
+// Delivery is the delivery of a event to a webhook
+type Delivery struct {
+	// ID identifies the delivery, the same for its retries
+	ID    string   `json:"id"`
+	Hook  string   `json:"hook"`
+	Event Event    `json:"event"`
+	Todo  store.ID `json:"todo"`
+	// Time is when the event happened
+	Time time.Time `json:"time"`
+	// Attempts is how many times the delivery was attempted so far
+	Attempts int `json:"attempts"`
+	// Status is the HTTP status code of the response to the last attempt, zero if there was none
+	Status int `json:"status,omitempty"`
+	// Error tells why the last attempt failed
+	Error string `json:"error,omitempty"`
+	// Delivered tells whether the receiver accepted the delivery
+	Delivered bool `json:"delivered"`
+	// Done tells whether the delivery won't be attempted anymore
+	Done bool `json:"done"`
+}
+
+// Payload is the body of the deliveries
+type Payload struct {
+	// ID identifies the delivery, the same for its retries
+	ID    string    `json:"id"`
+	Event Event     `json:"event"`
+	Time  time.Time `json:"time"`
+	// Item is the todo the event is about
+	Item apiv1.Item `json:"item"`
+}
+
+// Dispatcher keeps the webhooks, and delivers them the events
+type Dispatcher struct {
+	st   store.Storage
+	lock sync.RWMutex
+	// hooks are the webhooks by ID
+	hooks map[string]Hook
+	// logs are the latest deliveries of each webhook, oldest first
+	logs map[string][]Delivery
+	// deliveries are the deliveries in progress
+	deliveries sync.WaitGroup
+	// Client sends the deliveries
+	Client *http.Client
+	// Attempts is how many times a delivery is attempted at most, DefaultAttempts by default
+	Attempts int
+	// Backoff is how long to wait before retrying a delivery the first time, doubling at each
+	// retry, DefaultBackoff by default
+	Backoff time.Duration
+	// Now returns the current time. Can be replaced to control time in tests.
+	Now func() time.Time
+}
+
+// NewDispatcher creates the dispatcher of the webhooks kept in the storage
+func NewDispatcher(st store.Storage) (*Dispatcher, error) {
+	d := &Dispatcher{
+		st:       st,
+		hooks:    make(map[string]Hook),
+		logs:     make(map[string][]Delivery),
+		Client:   &http.Client{Timeout: DefaultTimeout},
+		Attempts: DefaultAttempts,
+		Backoff:  DefaultBackoff,
+		Now:      time.Now,
+	}
+	err := store.Walk(st, func(item store.Item) error {
+		if !strings.HasPrefix(string(item.ID), hookPrefix) {
+			return nil
+		}
+		var h Hook
+		if err := json.Unmarshal(item.Blob, &h); err != nil {
+			return fmt.Errorf("webhook: can't decode the webhook %v: %w", item.ID, err)
+		}
+		d.hooks[h.ID] = h
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	slog.Info("webhook: loaded the webhooks", "count", len(d.hooks))
+	return d, nil
+}
+
+// Register stores the new webhook, with a new ID, created now, and returns it
+func (d *Dispatcher) Register(h Hook) (Hook, error) {
+	if err := h.Validate(); err != nil {
+		return Hook{}, err
+	}
+	var err error
+	if h.ID, err = randomHex(8); err != nil {
+		return Hook{}, err
+	}
+	if h.Secret == "" {
+		if h.Secret, err = randomHex(32); err != nil {
+			return Hook{}, err
+		}
+	}
+	h.Created = d.Now().UTC()
+	blob, err := json.Marshal(h)
+	if err != nil {
+		return Hook{}, err
+	}
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if err := d.st.Create(store.ID(hookPrefix+h.ID), blob); err != nil {
+		return Hook{}, err
+	}
+	d.hooks[h.ID] = h
+	slog.Info("webhook: registered", "id", h.ID, "url", h.URL, "events", h.Events)
+	return h, nil
+}
+
+// Get returns the webhook with the given ID, and false if there is none
+func (d *Dispatcher) Get(id string) (Hook, bool) {
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+	h, ok := d.hooks[id]
+	return h, ok
+}
+
+// List returns the webhooks, oldest first
+func (d *Dispatcher) List() []Hook {
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+	res := make([]Hook, 0, len(d.hooks))
+	for _, h := range d.hooks {
+		res = append(res, h)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if !res[i].Created.Equal(res[j].Created) {
+			return res[i].Created.Before(res[j].Created)
+		}
+		return res[i].ID < res[j].ID
+	})
+	return res
+}
+
+// Delete removes the webhook with the given ID, and its deliveries.
+// Fails with ErrNoHook if there is none.
+func (d *Dispatcher) Delete(id string) error {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if _, ok := d.hooks[id]; !ok {
+		return ErrNoHook{ID: id}
+	}
+	if err := d.st.Delete(store.ID(hookPrefix + id)); err != nil {
+		return err
+	}
+	delete(d.hooks, id)
+	delete(d.logs, id)
+	slog.Info("webhook: deleted", "id", id)
+	return nil
+}
+
+// Deliveries returns the latest deliveries to the webhook with the given ID, newest first.
+// Fails with ErrNoHook if there is none.
+func (d *Dispatcher) Deliveries(id string) ([]Delivery, error) {
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+	if _, ok := d.hooks[id]; !ok {
+		return nil, ErrNoHook{ID: id}
+	}
+	res := slices.Clone(d.logs[id])
+	slices.Reverse(res)
+	return res, nil
+}
+
+// Run delivers the events told by the watcher until the context is done, then waits for the
+// deliveries in progress to stop
+func (d *Dispatcher) Run(ctx context.Context, w *Watcher) error {
+	defer d.deliveries.Wait()
+	return w.Run(ctx, func(ev Event, item ledger.Item) {
+		d.Dispatch(ctx, ev, item)
+	})
+}
+
+// Dispatch delivers the event about the todo to the webhooks registered for its kind, in the
+// background, until the context is done
+func (d *Dispatcher) Dispatch(ctx context.Context, ev Event, item ledger.Item) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	for _, h := range d.hooks {
+		if !slices.Contains(h.Events, ev) {
+			continue
+		}
+		id, err := randomHex(16)
+		if err != nil {
+			slog.Error("webhook: can't deliver", "hook", h.ID, "event", ev, "error", err)
+			continue
+		}
+		dl := Delivery{ID: id, Hook: h.ID, Event: ev, Todo: item.ID, Time: d.Now().UTC()}
+		d.logs[h.ID] = append(d.logs[h.ID], dl)
+		if len(d.logs[h.ID]) > MaxLog {
+			d.logs[h.ID] = d.logs[h.ID][len(d.logs[h.ID])-MaxLog:]
+		}
+		payload := Payload{ID: id, Event: ev, Time: dl.Time, Item: item.ToAPIv1()}
+		d.deliveries.Add(1)
+		go func() {
+			defer d.deliveries.Done()
+			d.deliver(ctx, h, dl, payload)
+		}()
+	}
+}
+
+// deliver posts the payload to the webhook, retrying with exponential backoff while it fails,
+// up to Attempts times
+func (d *Dispatcher) deliver(ctx context.Context, h Hook, dl Delivery, payload Payload) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		dl.Error, dl.Done = err.Error(), true
+		d.logDelivery(dl)
+		return
+	}
+	backoff := d.Backoff
+	for {
+		dl.Attempts++
+		var retry bool
+		dl.Status, retry, err = d.post(ctx, h, dl, body)
+		dl.Delivered = err == nil
+		dl.Error = ""
+		if err != nil {
+			dl.Error = err.Error()
+		}
+		dl.Done = dl.Delivered || !retry || dl.Attempts >= d.Attempts || ctx.Err() != nil
+		d.logDelivery(dl)
+		if dl.Done {
+			if dl.Delivered {
+				slog.Info("webhook: delivered", "hook", h.ID, "delivery", dl.ID, "event", dl.Event, "todo", dl.Todo, "attempts", dl.Attempts)
+			} else {
+				slog.Warn("webhook: delivery failed", "hook", h.ID, "delivery", dl.ID, "event", dl.Event, "todo", dl.Todo, "attempts", dl.Attempts, "error", err)
+			}
+			return
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// post sends the delivery once. Returns the HTTP status code of the response, zero if there was
+// none, and whether the failed deliveries can be retried, i.e. unless the receiver rejected it.
+func (d *Dispatcher) post(ctx context.Context, h Hook, dl Delivery, body []byte) (int, bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "todo-webhook")
+	req.Header.Set(EventHeader, string(dl.Event))
+	req.Header.Set(DeliveryHeader, dl.ID)
+	req.Header.Set(SignatureHeader, Sign(h.Secret, body))
+	resp, err := d.Client.Do(req)
+	if err != nil {
+		return 0, true, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+	switch {
+	case resp.StatusCode < 300:
+		return resp.StatusCode, false, nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return resp.StatusCode, true, fmt.Errorf("webhook: the receiver answered %s", resp.Status)
+	}
+	return resp.StatusCode, false, fmt.Errorf("webhook: the receiver rejected the delivery: %s", resp.Status)
+}
+
+// logDelivery updates the delivery in the log of its webhook, unless gone meanwhile
+func (d *Dispatcher) logDelivery(dl Delivery) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	logs := d.logs[dl.Hook]
+	if i := slices.IndexFunc(logs, func(other Delivery) bool { return other.ID == dl.ID }); i >= 0 {
+		logs[i] = dl
+	}
+}
+
+// Sign returns the signature of the body of a delivery with the secret of its webhook, as sent in
+// the SignatureHeader
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify tells whether the signature of the body of a delivery is the one made with the secret
+func Verify(secret string, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, body)), []byte(signature))
+}
+
+// randomHex returns n random bytes, hex encoded
+func randomHex(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
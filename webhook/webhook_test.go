@@ -0,0 +1,212 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gotestbootcamp/go-todo-app/ledger"
+	"github.com/gotestbootcamp/go-todo-app/model"
+	"github.com/gotestbootcamp/go-todo-app/store"
+	"github.com/gotestbootcamp/go-todo-app/task"
+)
+
+func TestDispatcher(t *testing.T) {
+	mem, err := store.NewMemory()
+	require.NoError(t, err)
+	d, err := NewDispatcher(mem)
+	require.NoError(t, err)
+
+	hook, err := d.Register(Hook{URL: "https://example.com/hook", Events: []Event{Created, Overdue}})
+	require.NoError(t, err)
+	assert.NotEmpty(t, hook.ID)
+	assert.Len(t, hook.Secret, 64)
+	assert.False(t, hook.Created.IsZero())
+	other, err := d.Register(Hook{URL: "http://localhost:9000/", Events: []Event{Completed}, Secret: "s3cret"})
+	require.NoError(t, err)
+	assert.Equal(t, "s3cret", other.Secret)
+
+	for name, h := range map[string]Hook{
+		"no URL":        {Events: []Event{Created}},
+		"relative URL":  {URL: "/hook", Events: []Event{Created}},
+		"ftp URL":       {URL: "ftp://example.com/hook", Events: []Event{Created}},
+		"no events":     {URL: "https://example.com/hook"},
+		"unknown event": {URL: "https://example.com/hook", Events: []Event{"deleted"}},
+	} {
+		_, err := d.Register(h)
+		assert.Error(t, err, name)
+	}
+
+	// the webhooks are loaded back from the storage
+	d, err = NewDispatcher(mem)
+	require.NoError(t, err)
+	assert.Equal(t, []Hook{hook, other}, d.List())
+	got, ok := d.Get(hook.ID)
+	assert.True(t, ok)
+	assert.Equal(t, hook, got)
+
+	require.NoError(t, d.Delete(hook.ID))
+	assert.ErrorAs(t, d.Delete(hook.ID), &ErrNoHook{})
+	_, err = d.Deliveries(hook.ID)
+	assert.ErrorAs(t, err, &ErrNoHook{})
+	assert.Equal(t, []Hook{other}, d.List())
+}
+
+func TestDeliver(t *testing.T) {
+	var lock sync.Mutex
+	var received []Payload
+	// the failures to answer on each path before accepting the deliveries
+	failures := map[string]int{"/good": 2}
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+		if failures[r.URL.Path] > 0 {
+			failures[r.URL.Path]--
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		if !Verify("s3cret", body, r.Header.Get(SignatureHeader)) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var payload Payload
+		require.NoError(t, json.Unmarshal(body, &payload))
+		assert.Equal(t, string(payload.Event), r.Header.Get(EventHeader))
+		assert.Equal(t, payload.ID, r.Header.Get(DeliveryHeader))
+		received = append(received, payload)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	mem, err := store.NewMemory()
+	require.NoError(t, err)
+	d, err := NewDispatcher(mem)
+	require.NoError(t, err)
+	d.Backoff = time.Millisecond
+	d.Attempts = 3
+	hook, err := d.Register(Hook{URL: receiver.URL + "/good", Events: []Event{Created}, Secret: "s3cret"})
+	require.NoError(t, err)
+	// signed with the wrong secret
+	rejected, err := d.Register(Hook{URL: receiver.URL + "/other", Events: []Event{Created, Completed}})
+	require.NoError(t, err)
+
+	tk := task.New("pay rent")
+	item := ledger.Item{ID: "1", Todo: &model.Todo{Title: "pay rent"}, Task: &tk}
+	d.Dispatch(context.Background(), Created, item)
+	d.deliveries.Wait()
+
+	require.Len(t, received, 1)
+	assert.Equal(t, Created, received[0].Event)
+	assert.Equal(t, "pay rent", received[0].Item.Todo.Title)
+	deliveries, err := d.Deliveries(hook.ID)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	assert.Equal(t, received[0].ID, deliveries[0].ID)
+	assert.Equal(t, store.ID("1"), deliveries[0].Todo)
+	assert.Equal(t, 3, deliveries[0].Attempts)
+	assert.Equal(t, http.StatusNoContent, deliveries[0].Status)
+	assert.True(t, deliveries[0].Delivered)
+	assert.True(t, deliveries[0].Done)
+	assert.Empty(t, deliveries[0].Error)
+
+	// the deliveries rejected are not retried
+	deliveries, err = d.Deliveries(rejected.ID)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	assert.Equal(t, 1, deliveries[0].Attempts)
+	assert.Equal(t, http.StatusUnauthorized, deliveries[0].Status)
+	assert.False(t, deliveries[0].Delivered)
+	assert.True(t, deliveries[0].Done)
+	assert.Contains(t, deliveries[0].Error, "rejected")
+
+	// up to Attempts times
+	failures["/other"] = 5
+	d.Dispatch(context.Background(), Completed, item)
+	d.deliveries.Wait()
+	deliveries, err = d.Deliveries(rejected.ID)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 2)
+	assert.Equal(t, Completed, deliveries[0].Event)
+	assert.Equal(t, 3, deliveries[0].Attempts)
+	assert.Equal(t, http.StatusServiceUnavailable, deliveries[0].Status)
+	assert.False(t, deliveries[0].Delivered)
+	deliveries, err = d.Deliveries(hook.ID)
+	require.NoError(t, err)
+	assert.Len(t, deliveries, 1)
+}
+
+func TestSign(t *testing.T) {
+	body := []byte(`{"event":"created"}`)
+	sig := Sign("s3cret", body)
+	assert.Regexp(t, "^sha256=[0-9a-f]{64}$", sig)
+	assert.True(t, Verify("s3cret", body, sig))
+	assert.False(t, Verify("other", body, sig))
+	assert.False(t, Verify("s3cret", []byte(`{"event":"overdue"}`), sig))
+}
+
+func TestWatcher(t *testing.T) {
+	mem, err := store.NewMemory()
+	require.NoError(t, err)
+	ld, err := ledger.New(store.NewNotifier(mem))
+	require.NoError(t, err)
+	w := NewWatcher(ld)
+	w.Interval = 10 * time.Millisecond
+	events := make(chan string, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- w.Run(ctx, func(ev Event, item ledger.Item) {
+			events <- string(ev) + " " + item.Task.Title
+		})
+	}()
+	next := func() string {
+		select {
+		case ev := <-events:
+			return ev
+		case <-time.After(time.Second):
+			t.Fatal("no event")
+			return ""
+		}
+	}
+
+	// let the watcher start
+	time.Sleep(20 * time.Millisecond)
+	require.NoError(t, ld.Set("1", model.New("pay rent")))
+	assert.Equal(t, "created pay rent", next())
+	alice := "alice"
+	_, _, err = ld.PatchIf("1", ledger.Patch{Assignee: &alice}, ledger.AnyRevision)
+	require.NoError(t, err)
+	assert.Equal(t, "assigned pay rent", next())
+	_, err = ld.Transition("1", task.Completed)
+	require.NoError(t, err)
+	assert.Equal(t, "completed pay rent", next())
+	// completed once only
+	_, err = ld.TagTodo("1", "home")
+	require.NoError(t, err)
+
+	due := time.Now().Add(50 * time.Millisecond)
+	tk := task.New("call mom")
+	tk.Due = &due
+	_, err = ld.CreateAll(store.DefaultList, []task.Task{tk})
+	require.NoError(t, err)
+	assert.Equal(t, "created call mom", next())
+	assert.Equal(t, "overdue call mom", next())
+
+	select {
+	case ev := <-events:
+		t.Fatalf("unexpected event %q", ev)
+	case <-time.After(50 * time.Millisecond):
+	}
+	cancel()
+	assert.NoError(t, <-done)
+}